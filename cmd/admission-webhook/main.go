@@ -19,10 +19,14 @@ import (
 	"time"
 
 	"github.com/openshift/generic-admission-server/pkg/cmd"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/features"
 	"github.com/pingcap/tidb-operator/pkg/version"
+	"github.com/pingcap/tidb-operator/pkg/webhook/pod"
 	"github.com/pingcap/tidb-operator/pkg/webhook/statefulset"
 	"github.com/pingcap/tidb-operator/pkg/webhook/strategy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/component-base/logs"
 	"k8s.io/klog/v2"
 )
@@ -31,6 +35,8 @@ var (
 	printVersion         bool
 	extraServiceAccounts string
 	minResyncDuration    time.Duration
+	tikvMinCPURequests   string
+	tikvMinMemRequests   string
 )
 
 func init() {
@@ -42,6 +48,8 @@ func init() {
 	flag.BoolVar(&printVersion, "version", false, "Show version and quit")
 	flag.StringVar(&extraServiceAccounts, "extraServiceAccounts", "", "comma-separated, extra Service Accounts the Webhook should control. The full pattern for each common service account is system:serviceaccount:<namespace>:<serviceaccount-name>")
 	flag.DurationVar(&minResyncDuration, "min-resync-duration", 12*time.Hour, "The resync period in reflectors will be random between MinResyncPeriod and 2*MinResyncPeriod.")
+	flag.StringVar(&tikvMinCPURequests, "tikv-min-cpu-requests", "1", "The minimum cpu requests of TiKV derived from spec.tikv.sizing, the requests ratios putting the cpu requests below it are rejected")
+	flag.StringVar(&tikvMinMemRequests, "tikv-min-memory-requests", "2Gi", "The minimum memory requests of TiKV derived from spec.tikv.sizing, the requests ratios putting the memory requests below it are rejected")
 	features.DefaultFeatureGate.AddFlag(flag.CommandLine)
 }
 
@@ -64,6 +72,17 @@ func main() {
 	// We choose a random resync period between MinResyncPeriod and 2 *
 	// MinResyncPeriod, so that our pods started at the same time don't list the apiserver simultaneously.
 
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    tikvMinCPURequests,
+		corev1.ResourceMemory: tikvMinMemRequests,
+	} {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			klog.Fatalf("invalid minimum %s requests of tikv %q: %v", name, value, err)
+		}
+		validation.TiKVRequestsFloor[name] = q
+	}
+
 	ns := os.Getenv("NAMESPACE")
 	if len(ns) < 1 {
 		klog.Fatal("ENV NAMESPACE should be set.")
@@ -71,6 +90,7 @@ func main() {
 
 	statefulSetAdmissionHook := statefulset.NewStatefulSetAdmissionControl()
 	strategyAdmissionHook := strategy.NewStrategyAdmissionHook(&strategy.Registry)
+	podAdmissionHook := pod.NewPodAdmissionControl()
 
-	cmd.RunAdmissionServer(statefulSetAdmissionHook, strategyAdmissionHook)
+	cmd.RunAdmissionServer(statefulSetAdmissionHook, strategyAdmissionHook, podAdmissionHook)
 }
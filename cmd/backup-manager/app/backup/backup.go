@@ -16,6 +16,7 @@ package backup
 import (
 	"bufio"
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -35,8 +36,8 @@ type Options struct {
 	backupUtil.GenericOptions
 }
 
-// backupData generates br args and runs br binary to do the real backup work
-func (bo *Options) backupData(ctx context.Context, backup *v1alpha1.Backup) error {
+// backupData generates br args and runs br binary to do the real backup work, the output of br is captured by toolLog
+func (bo *Options) backupData(ctx context.Context, backup *v1alpha1.Backup, toolLog *backupUtil.ToolLog) error {
 	clusterNamespace := backup.Spec.BR.ClusterNamespace
 	if backup.Spec.BR.ClusterNamespace == "" {
 		clusterNamespace = backup.Namespace
@@ -89,19 +90,21 @@ func (bo *Options) backupData(ctx context.Context, backup *v1alpha1.Backup) erro
 		if strings.Contains(line, "[ERROR]") {
 			errMsg += line
 		}
-
-		klog.Info(strings.Replace(line, "\n", "", -1))
+		if line != "" || err == nil {
+			toolLog.WriteLine(line)
+		}
 		if err != nil || io.EOF == err {
 			break
 		}
 	}
 	tmpErr, _ := ioutil.ReadAll(stdErr)
 	if len(tmpErr) > 0 {
-		klog.Info(string(tmpErr))
+		toolLog.WriteOutput(tmpErr)
 		errMsg += string(tmpErr)
 	}
 	err = cmd.Wait()
 	if err != nil {
+		toolLog.Exit(err)
 		return fmt.Errorf("cluster %s, wait pipe message failed, errMsg %s, err: %v", bo, errMsg, err)
 	}
 
@@ -109,6 +112,23 @@ func (bo *Options) backupData(ctx context.Context, backup *v1alpha1.Backup) erro
 	return nil
 }
 
+// backupClusterSettings captures the cluster settings and saves them to the backup
+func (bo *Options) backupClusterSettings(ctx context.Context, backup *v1alpha1.Backup, db *sql.DB) error {
+	clusterNamespace := backup.Spec.BR.ClusterNamespace
+	if clusterNamespace == "" {
+		clusterNamespace = backup.Namespace
+	}
+	pdCli, err := backupUtil.NewClusterPDClient(backup.Spec.BR.Cluster, clusterNamespace, bo.TLSCluster)
+	if err != nil {
+		return fmt.Errorf("cluster %s, create pd client failed, err: %v", bo, err)
+	}
+	settings, err := bo.CaptureClusterSettings(ctx, pdCli, db)
+	if err != nil {
+		return err
+	}
+	return backupUtil.SaveClusterSettings(ctx, backup.Spec.StorageProvider, settings)
+}
+
 // constructOptions constructs options for BR
 func constructOptions(backup *v1alpha1.Backup) ([]string, error) {
 	args, err := backupUtil.ConstructBRGlobalOptionsForBackup(backup)
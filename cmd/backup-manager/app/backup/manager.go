@@ -262,7 +262,12 @@ func (bm *Manager) performBackup(ctx context.Context, backup *v1alpha1.Backup, d
 	}
 
 	// run br binary to do the real job
-	backupErr := bm.backupData(ctx, backup)
+	toolLog := util.NewToolLog("br", backup.Spec.UploadToolLogs)
+	defer toolLog.Close()
+	backupErr := bm.backupData(ctx, backup, toolLog)
+	if err := toolLog.Upload(ctx, backup.Spec.StorageProvider, "backup-"+backup.Name); err != nil {
+		klog.Warningf("upload the log of br for cluster %s failed, err: %s", bm, err)
+	}
 
 	if db != nil && oldTikvGCTimeDuration < tikvGCTimeDuration {
 		// use another context to revert `tikv_gc_life_time` back.
@@ -291,17 +296,34 @@ func (bm *Manager) performBackup(ctx context.Context, backup *v1alpha1.Backup, d
 	if backupErr != nil {
 		errs = append(errs, backupErr)
 		klog.Errorf("backup cluster %s data failed, err: %s", bm, backupErr)
+		failureSummary := toolLog.FailureSummary()
 		uerr := bm.StatusUpdater.Update(backup, &v1alpha1.BackupCondition{
 			Type:    v1alpha1.BackupFailed,
 			Status:  corev1.ConditionTrue,
 			Reason:  "BackupDataToRemoteFailed",
 			Message: backupErr.Error(),
-		}, nil)
+		}, &controller.BackupUpdateStatus{FailureSummary: &failureSummary})
 		errs = append(errs, uerr)
 		return errorutils.NewAggregate(errs)
 	}
 	klog.Infof("backup cluster %s data to %s success", bm, backupFullPath)
 
+	if backup.Spec.IncludeClusterSettings {
+		if err := bm.backupClusterSettings(ctx, backup, db); err != nil {
+			errs = append(errs, err)
+			klog.Errorf("backup cluster %s settings failed, err: %s", bm, err)
+			uerr := bm.StatusUpdater.Update(backup, &v1alpha1.BackupCondition{
+				Type:    v1alpha1.BackupFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "BackupClusterSettingsFailed",
+				Message: err.Error(),
+			}, nil)
+			errs = append(errs, uerr)
+			return errorutils.NewAggregate(errs)
+		}
+		klog.Infof("backup cluster %s settings to %s success", bm, backupFullPath)
+	}
+
 	backupMeta, err := util.GetBRMetaData(ctx, backup.Spec.StorageProvider)
 	if err != nil {
 		errs = append(errs, err)
@@ -57,6 +57,15 @@ const (
 	// MetaFile is the file name for meta data of backup with BR
 	MetaFile = "backupmeta"
 
+	// ClusterSettingsFile is the file name for the cluster settings captured with the backup
+	ClusterSettingsFile = "settings.json"
+
+	// LogBackupMetaDir is the directory of the metadata of the log backup under the log backup path
+	LogBackupMetaDir = "v1/backupmeta/"
+
+	// ToolLogsDir is the directory under the backup path the full logs of the tools are uploaded to
+	ToolLogsDir = "operator-logs"
+
 	// BR certificate storage path
 	BRCertPath = "/var/lib/br-tls"
 
@@ -59,7 +59,8 @@ func (bo *Options) getDestBucketURI(remotePath string) string {
 	return fmt.Sprintf("%s://%s", bo.StorageType, remotePath)
 }
 
-func (bo *Options) dumpTidbClusterData(ctx context.Context, bfPath string, backup *v1alpha1.Backup) error {
+// dumpTidbClusterData runs dumpling to dump the data to bfPath, the output of dumpling is captured by toolLog
+func (bo *Options) dumpTidbClusterData(ctx context.Context, bfPath string, backup *v1alpha1.Backup, toolLog *backupUtil.ToolLog) error {
 	err := backupUtil.EnsureDirectoryExist(bfPath)
 	if err != nil {
 		return err
@@ -97,7 +98,9 @@ func (bo *Options) dumpTidbClusterData(ctx context.Context, bfPath string, backu
 	klog.Infof("The dump process is ready, command \"%s %s\"", binPath, strings.Join(args_redacted, " "))
 
 	output, err := exec.CommandContext(ctx, binPath, args...).CombinedOutput()
+	toolLog.WriteOutput(output)
 	if err != nil {
+		toolLog.Exit(err)
 		return fmt.Errorf("cluster %s, execute dumpling command %v failed, output: %s, err: %v", bo, args, string(output), err)
 	}
 	return nil
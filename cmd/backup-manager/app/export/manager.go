@@ -259,7 +259,12 @@ func (bm *BackupManager) performBackup(ctx context.Context, backup *v1alpha1.Bac
 		return err
 	}
 
-	backupErr := bm.dumpTidbClusterData(ctx, backupFullPath, backup)
+	toolLog := util.NewToolLog("dumpling", backup.Spec.UploadToolLogs)
+	defer toolLog.Close()
+	backupErr := bm.dumpTidbClusterData(ctx, backupFullPath, backup, toolLog)
+	if err := toolLog.Upload(ctx, backup.Spec.StorageProvider, "backup-"+backup.Name); err != nil {
+		klog.Warningf("upload the log of dumpling for cluster %s failed, err: %s", bm, err)
+	}
 	if oldTikvGCTimeDuration < tikvGCTimeDuration {
 		// use another context to revert `tikv_gc_life_time` back.
 		// `DefaultTerminationGracePeriodSeconds` for a pod is 30, so we use a smaller timeout value here.
@@ -287,12 +292,13 @@ func (bm *BackupManager) performBackup(ctx context.Context, backup *v1alpha1.Bac
 	if backupErr != nil {
 		errs = append(errs, backupErr)
 		klog.Errorf("dump cluster %s data failed, err: %s", bm, backupErr)
+		failureSummary := toolLog.FailureSummary()
 		uerr := bm.StatusUpdater.Update(backup, &v1alpha1.BackupCondition{
 			Type:    v1alpha1.BackupFailed,
 			Status:  corev1.ConditionTrue,
 			Reason:  "DumpTidbClusterFailed",
 			Message: backupErr.Error(),
-		}, nil)
+		}, &controller.BackupUpdateStatus{FailureSummary: &failureSummary})
 		errs = append(errs, uerr)
 		return errorutils.NewAggregate(errs)
 	}
@@ -83,7 +83,8 @@ func (ro *Options) downloadBackupData(ctx context.Context, localPath string, opt
 	return nil
 }
 
-func (ro *Options) loadTidbClusterData(ctx context.Context, restorePath string, restore *v1alpha1.Restore) error {
+// loadTidbClusterData runs lightning to load the data in restorePath, the output of lightning is captured by toolLog
+func (ro *Options) loadTidbClusterData(ctx context.Context, restorePath string, restore *v1alpha1.Restore, toolLog *backupUtil.ToolLog) error {
 	tableFilter := restore.Spec.TableFilter
 
 	if exist := backupUtil.IsDirExist(restorePath); !exist {
@@ -106,6 +107,19 @@ func (ro *Options) loadTidbClusterData(ctx context.Context, restorePath string,
 		args = append(args, "-f", filter)
 	}
 
+	routes, err := backupUtil.ConstructLightningRoutes(restore)
+	if err != nil {
+		return err
+	}
+	if routes != "" {
+		// the route rules can only be set in the config file
+		configPath := filepath.Join(filepath.Dir(restorePath), "lightning-routes.toml")
+		if err := ioutil.WriteFile(configPath, []byte(routes), 0644); err != nil {
+			return fmt.Errorf("cluster %s, write lightning config %s failed, err: %v", ro, configPath, err)
+		}
+		args = append(args, fmt.Sprintf("--config=%s", configPath))
+	}
+
 	if ro.TLSClient {
 		if !ro.SkipClientCA {
 			args = append(args, fmt.Sprintf("--ca=%s", path.Join(util.TiDBClientTLSPath, corev1.ServiceAccountRootCAKey)))
@@ -122,7 +136,9 @@ func (ro *Options) loadTidbClusterData(ctx context.Context, restorePath string,
 	klog.Infof("The lightning process is ready, command \"%s %s\"", binPath, strings.Join(args, " "))
 
 	output, err := exec.CommandContext(ctx, binPath, args...).CombinedOutput()
+	toolLog.WriteOutput(output)
 	if err != nil {
+		toolLog.Exit(err)
 		return fmt.Errorf("cluster %s, execute loader command %v failed, output: %s, err: %v", ro, args, string(output), err)
 	}
 	return nil
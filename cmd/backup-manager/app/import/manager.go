@@ -15,6 +15,7 @@ package _import
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"path/filepath"
 	"time"
@@ -24,6 +25,7 @@ import (
 	bkconstants "github.com/pingcap/tidb-operator/pkg/backup/constants"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	pkgutil "github.com/pingcap/tidb-operator/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	errorutils "k8s.io/apimachinery/pkg/util/errors"
@@ -151,16 +153,45 @@ func (rm *RestoreManager) performRestore(ctx context.Context, restore *v1alpha1.
 	}
 	klog.Infof("get cluster %s commitTs %s success", rm, commitTs)
 
-	err = rm.loadTidbClusterData(ctx, unarchiveDataPath, restore)
+	var db *sql.DB
+	if len(restore.Spec.RenameMapping) > 0 {
+		db, err = rm.openDB(ctx)
+		if err == nil {
+			defer db.Close()
+			if !restore.Spec.Overwrite {
+				err = rm.CheckRenameTargets(ctx, db, restore.Spec.RenameMapping)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			klog.Errorf("check cluster %s rename mapping targets failed, err: %s", rm, err)
+			uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
+				Type:    v1alpha1.RestoreFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "CheckRenameTargetsFailed",
+				Message: err.Error(),
+			}, nil)
+			errs = append(errs, uerr)
+			return errorutils.NewAggregate(errs)
+		}
+	}
+
+	toolLog := util.NewToolLog("lightning", restore.Spec.UploadToolLogs)
+	defer toolLog.Close()
+	err = rm.loadTidbClusterData(ctx, unarchiveDataPath, restore, toolLog)
+	if uerr := toolLog.Upload(ctx, restore.Spec.StorageProvider, "restore-"+restore.Name); uerr != nil {
+		klog.Warningf("upload the log of lightning for cluster %s failed, err: %s", rm, uerr)
+	}
 	if err != nil {
 		errs = append(errs, err)
 		klog.Errorf("restore cluster %s from backup %s failed, err: %s", rm, rm.BackupPath, err)
+		failureSummary := toolLog.FailureSummary()
 		uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
 			Type:    v1alpha1.RestoreFailed,
 			Status:  corev1.ConditionTrue,
 			Reason:  "LoaderBackupDataFailed",
 			Message: fmt.Sprintf("loader backup %s data failed, err: %v", restoreDataPath, err),
-		}, nil)
+		}, &controller.RestoreUpdateStatus{FailureSummary: &failureSummary})
 		errs = append(errs, uerr)
 		return errorutils.NewAggregate(errs)
 	}
@@ -173,8 +204,24 @@ func (rm *RestoreManager) performRestore(ctx context.Context, restore *v1alpha1.
 		TimeCompleted: &metav1.Time{Time: finish},
 		CommitTs:      &commitTs,
 	}
+	if db != nil {
+		tables, err := rm.ListRestoredTables(ctx, db, restore.Spec.RenameMapping)
+		if err != nil {
+			klog.Warningf("list cluster %s restored tables failed, err: %s", rm, err)
+		}
+		updateStatus.RestoredTables = tables
+	}
 	return rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
 		Type:   v1alpha1.RestoreComplete,
 		Status: corev1.ConditionTrue,
 	}, updateStatus)
 }
+
+// openDB opens the connection to the TiDB cluster to restore
+func (rm *RestoreManager) openDB(ctx context.Context) (*sql.DB, error) {
+	dsn, err := rm.GetDSN(rm.TLSClient)
+	if err != nil {
+		return nil, err
+	}
+	return pkgutil.OpenDB(ctx, dsn)
+}
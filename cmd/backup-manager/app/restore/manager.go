@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/util"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	bkconstants "github.com/pingcap/tidb-operator/pkg/backup/constants"
+	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	pkgutil "github.com/pingcap/tidb-operator/pkg/util"
@@ -163,6 +164,28 @@ func (rm *Manager) performRestore(ctx context.Context, restore *v1alpha1.Restore
 		return errorutils.NewAggregate(errs)
 	}
 
+	if restore.Spec.PitrRestoredTs != "" {
+		restoredTs, err := rm.checkLogBackupContinuity(ctx, restore, commitTs)
+		if err != nil {
+			errs = append(errs, err)
+			klog.Errorf("check cluster %s log backup continuity failed, err: %s", rm, err)
+			reason := "CheckLogBackupContinuityFailed"
+			if backuputil.IsLogBackupGapError(err) {
+				reason = "LogBackupNotContinuous"
+			}
+			uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
+				Type:    v1alpha1.RestoreFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  reason,
+				Message: err.Error(),
+			}, nil)
+			errs = append(errs, uerr)
+			return errorutils.NewAggregate(errs)
+		}
+		// the cluster is restored to the restored ts rather than the end of the full backup
+		commitTs = restoredTs
+	}
+
 	var (
 		oldTikvGCTime, tikvGCLifeTime             string
 		oldTikvGCTimeDuration, tikvGCTimeDuration time.Duration
@@ -249,7 +272,29 @@ func (rm *Manager) performRestore(ctx context.Context, restore *v1alpha1.Restore
 		}
 	}
 
-	restoreErr := rm.restoreData(ctx, restore)
+	if len(restore.Spec.RenameMapping) > 0 && !restore.Spec.Overwrite {
+		if db == nil {
+			klog.Warningf("cluster %s is not accessible, skip checking the targets of the rename mapping", rm)
+		} else if err := rm.CheckRenameTargets(ctx, db, restore.Spec.RenameMapping); err != nil {
+			errs = append(errs, err)
+			klog.Errorf("check cluster %s rename mapping targets failed, err: %s", rm, err)
+			uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
+				Type:    v1alpha1.RestoreFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "RenameTargetExists",
+				Message: err.Error(),
+			}, nil)
+			errs = append(errs, uerr)
+			return errorutils.NewAggregate(errs)
+		}
+	}
+
+	toolLog := util.NewToolLog("br", restore.Spec.UploadToolLogs)
+	defer toolLog.Close()
+	restoreErr := rm.restoreData(ctx, restore, toolLog)
+	if err := toolLog.Upload(ctx, restore.Spec.StorageProvider, "restore-"+restore.Name); err != nil {
+		klog.Warningf("upload the log of br for cluster %s failed, err: %s", rm, err)
+	}
 
 	if db != nil && oldTikvGCTimeDuration < tikvGCTimeDuration {
 		// use another context to revert `tikv_gc_life_time` back.
@@ -278,12 +323,13 @@ func (rm *Manager) performRestore(ctx context.Context, restore *v1alpha1.Restore
 	if restoreErr != nil {
 		errs = append(errs, restoreErr)
 		klog.Errorf("restore cluster %s from %s failed, err: %s", rm, restore.Spec.Type, restoreErr)
+		failureSummary := toolLog.FailureSummary()
 		uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
 			Type:    v1alpha1.RestoreFailed,
 			Status:  corev1.ConditionTrue,
 			Reason:  "RestoreDataFromRemoteFailed",
 			Message: restoreErr.Error(),
-		}, nil)
+		}, &controller.RestoreUpdateStatus{FailureSummary: &failureSummary})
 		errs = append(errs, uerr)
 		return errorutils.NewAggregate(errs)
 	}
@@ -296,6 +342,31 @@ func (rm *Manager) performRestore(ctx context.Context, restore *v1alpha1.Restore
 		TimeCompleted: &metav1.Time{Time: finish},
 		CommitTs:      &ts,
 	}
+	if restore.Spec.RestoreClusterSettings {
+		applied, skipped, err := rm.restoreClusterSettings(ctx, restore, db)
+		if err != nil {
+			errs = append(errs, err)
+			klog.Errorf("restore cluster %s settings failed, err: %s", rm, err)
+			uerr := rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
+				Type:    v1alpha1.RestoreFailed,
+				Status:  corev1.ConditionTrue,
+				Reason:  "RestoreClusterSettingsFailed",
+				Message: err.Error(),
+			}, nil)
+			errs = append(errs, uerr)
+			return errorutils.NewAggregate(errs)
+		}
+		klog.Infof("restore cluster %s settings succeed, applied: %v, skipped: %v", rm, applied, skipped)
+		updateStatus.AppliedClusterSettings = applied
+		updateStatus.SkippedClusterSettings = skipped
+	}
+	if len(restore.Spec.RenameMapping) > 0 && db != nil {
+		tables, err := rm.ListRestoredTables(ctx, db, restore.Spec.RenameMapping)
+		if err != nil {
+			klog.Warningf("list cluster %s restored tables failed, err: %s", rm, err)
+		}
+		updateStatus.RestoredTables = tables
+	}
 	return rm.StatusUpdater.Update(restore, &v1alpha1.RestoreCondition{
 		Type:   v1alpha1.RestoreComplete,
 		Status: corev1.ConditionTrue,
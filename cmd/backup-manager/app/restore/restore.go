@@ -16,15 +16,18 @@ package restore
 import (
 	"bufio"
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
 
 	backupUtil "github.com/pingcap/tidb-operator/cmd/backup-manager/app/util"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -34,7 +37,46 @@ type Options struct {
 	backupUtil.GenericOptions
 }
 
-func (ro *Options) restoreData(ctx context.Context, restore *v1alpha1.Restore) error {
+// restoreClusterSettings applies the cluster settings in the backup, it returns the keys of the
+// settings applied and the ones excluded or failed to be applied
+func (ro *Options) restoreClusterSettings(ctx context.Context, restore *v1alpha1.Restore, db *sql.DB) ([]string, []string, error) {
+	clusterNamespace := restore.Spec.BR.ClusterNamespace
+	if clusterNamespace == "" {
+		clusterNamespace = restore.Namespace
+	}
+	settings, err := backupUtil.LoadClusterSettings(ctx, restore.Spec.StorageProvider)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cluster %s, load cluster settings failed, err: %v", ro, err)
+	}
+	pdCli, err := backupUtil.NewClusterPDClient(restore.Spec.BR.Cluster, clusterNamespace, ro.TLSCluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cluster %s, create pd client failed, err: %v", ro, err)
+	}
+	applied, skipped := ro.ApplyClusterSettings(ctx, pdCli, db, settings, restore.Spec.ExcludeClusterSettings)
+	return applied, skipped, nil
+}
+
+// checkLogBackupContinuity checks the log backup of a point-in-time restore continuously covers the ts from the
+// full backup at fullBackupTs to the restored ts, it returns the restored ts. A *LogBackupGapError describing
+// the missing range is returned if there is a gap in the log backup.
+func (ro *Options) checkLogBackupContinuity(ctx context.Context, restore *v1alpha1.Restore, fullBackupTs uint64) (uint64, error) {
+	restoredTs, err := strconv.ParseUint(restore.Spec.PitrRestoredTs, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cluster %s, parse restored ts %s failed, err: %v", ro, restore.Spec.PitrRestoredTs, err)
+	}
+	logProvider := backupUtil.LogBackupStorageProvider(restore.Spec.StorageProvider, restore.Spec.PitrLogBackupPrefix)
+	ranges, err := backupUtil.GetLogBackupRanges(ctx, logProvider)
+	if err != nil {
+		return 0, fmt.Errorf("cluster %s, get the ranges of the log backup failed, err: %v", ro, err)
+	}
+	if err := backuputil.CheckLogBackupContinuity(fullBackupTs, restoredTs, ranges); err != nil {
+		return 0, err
+	}
+	return restoredTs, nil
+}
+
+// restoreData generates br args and runs br binary to do the real restore work, the output of br is captured by toolLog
+func (ro *Options) restoreData(ctx context.Context, restore *v1alpha1.Restore, toolLog *backupUtil.ToolLog) error {
 	clusterNamespace := restore.Spec.BR.ClusterNamespace
 	if restore.Spec.BR.ClusterNamespace == "" {
 		clusterNamespace = restore.Namespace
@@ -54,7 +96,10 @@ func (ro *Options) restoreData(ctx context.Context, restore *v1alpha1.Restore) e
 	args = append(args, dataArgs...)
 
 	var restoreType string
-	if restore.Spec.Type == "" {
+	if restore.Spec.PitrRestoredTs != "" {
+		// the log backup is replayed on the full backup up to the restored ts
+		restoreType = "point"
+	} else if restore.Spec.Type == "" {
 		restoreType = string(v1alpha1.BackupTypeFull)
 	} else {
 		restoreType = string(restore.Spec.Type)
@@ -87,19 +132,21 @@ func (ro *Options) restoreData(ctx context.Context, restore *v1alpha1.Restore) e
 		if strings.Contains(line, "[ERROR]") {
 			errMsg += line
 		}
-		klog.Info(strings.Replace(line, "\n", "", -1))
+		if line != "" || err == nil {
+			toolLog.WriteLine(line)
+		}
 		if err != nil || io.EOF == err {
 			break
 		}
 	}
 	tmpErr, _ := ioutil.ReadAll(stdErr)
 	if len(tmpErr) > 0 {
-		klog.Info(string(tmpErr))
+		toolLog.WriteOutput(tmpErr)
 		errMsg += string(tmpErr)
 	}
-
 	err = cmd.Wait()
 	if err != nil {
+		toolLog.Exit(err)
 		return fmt.Errorf("cluster %s, wait pipe message failed, errMsg %s, err: %v", ro, errMsg, err)
 	}
 	klog.Infof("Restore data for cluster %s successfully", ro)
@@ -0,0 +1,318 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/constants"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// the prefixes of the keys of the cluster settings
+	pdSettingPrefix        = "pd:"
+	variableSettingPrefix  = "variable:"
+	placementSettingPrefix = "placement:"
+)
+
+var (
+	// pdSettingSections are the sections of the PD config captured as the cluster settings
+	pdSettingSections = []string{"schedule", "replication"}
+
+	// excludedSettingWords are the words in the keys of the cluster settings which are never applied,
+	// these settings refer to the addresses or the local paths of the source cluster
+	excludedSettingWords = sets.NewString("url", "urls", "addr", "address", "addresses", "host", "hostname",
+		"port", "socket", "path", "dir", "file")
+	// excludedSettings are the cluster settings which are never applied
+	excludedSettings = sets.NewString(
+		variableSettingPrefix+"tidb_restricted_read_only",
+		variableSettingPrefix+"tidb_super_read_only",
+	)
+
+	variableNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+	createPolicyPattern = regexp.MustCompile(`(?i)^\s*CREATE\s+PLACEMENT\s+POLICY\s+`)
+)
+
+// ClusterSettings are the cluster-level settings captured at backup time
+type ClusterSettings struct {
+	// PDConfig are the items of the scheduling config of PD, the keys are in the form of section.item
+	PDConfig map[string]interface{} `json:"pdConfig,omitempty"`
+	// GlobalVariables are the global system variables of TiDB
+	GlobalVariables map[string]string `json:"globalVariables,omitempty"`
+	// PlacementPolicies are the statements creating the placement policies, the keys are the policy names
+	PlacementPolicies map[string]string `json:"placementPolicies,omitempty"`
+}
+
+// sqlExecer executes the statements, it is implemented by *sql.DB
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewClusterPDClient returns the PD client of the cluster to back up or restore
+func NewClusterPDClient(cluster, namespace string, tlsCluster bool) (pdapi.PDClient, error) {
+	scheme := "http"
+	var tlsConfig *tls.Config
+	if tlsCluster {
+		scheme = "https"
+		rootCAs := x509.NewCertPool()
+		ca, err := ioutil.ReadFile(path.Join(util.ClusterClientTLSPath, corev1.ServiceAccountRootCAKey))
+		if err != nil {
+			return nil, err
+		}
+		if !rootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("failed to append ca certs")
+		}
+		cert, err := tls.LoadX509KeyPair(
+			path.Join(util.ClusterClientTLSPath, corev1.TLSCertKey),
+			path.Join(util.ClusterClientTLSPath, corev1.TLSPrivateKeyKey))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{cert}}
+	}
+	url := fmt.Sprintf("%s://%s-pd.%s:2379", scheme, cluster, namespace)
+	return pdapi.NewPDClient(url, pdapi.DefaultTimeout, tlsConfig), nil
+}
+
+// CaptureClusterSettings captures the cluster settings, the global variables and the placement
+// policies are captured only if db is not nil
+func (bo *GenericOptions) CaptureClusterSettings(ctx context.Context, pdCli pdapi.PDClient, db *sql.DB) (*ClusterSettings, error) {
+	items, err := pdCli.GetConfigItems(pdSettingSections...)
+	if err != nil {
+		return nil, fmt.Errorf("get pd config of cluster %s failed, err: %v", bo, err)
+	}
+	settings := &ClusterSettings{PDConfig: items}
+	if db == nil {
+		klog.Warningf("cluster %s is not accessible, skip capturing the global variables and the placement policies", bo)
+		return settings, nil
+	}
+
+	query := "select variable_name, variable_value from mysql.global_variables"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query global variables of cluster %s failed, sql: %s, err: %v", bo, query, err)
+	}
+	defer rows.Close()
+	settings.GlobalVariables = map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("scan global variables of cluster %s failed, err: %v", bo, err)
+		}
+		settings.GlobalVariables[name] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query global variables of cluster %s failed, err: %v", bo, err)
+	}
+
+	policies, err := bo.capturePlacementPolicies(ctx, db)
+	if err != nil {
+		// placement policies are not supported before TiDB v5.3
+		klog.Warningf("skip capturing the placement policies of cluster %s, err: %v", bo, err)
+	}
+	settings.PlacementPolicies = policies
+	return settings, nil
+}
+
+func (bo *GenericOptions) capturePlacementPolicies(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	query := "select policy_name from information_schema.placement_policies"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query placement policies failed, sql: %s, err: %v", query, err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan placement policies failed, err: %v", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	policies := map[string]string{}
+	for _, name := range names {
+		var policy, create string
+		query := fmt.Sprintf("show create placement policy %s", quoteIdentifier(name))
+		if err := db.QueryRowContext(ctx, query).Scan(&policy, &create); err != nil {
+			return nil, fmt.Errorf("query placement policy %s failed, sql: %s, err: %v", name, query, err)
+		}
+		policies[name] = create
+	}
+	return policies, nil
+}
+
+// SaveClusterSettings saves the cluster settings to the backup
+func SaveClusterSettings(ctx context.Context, provider v1alpha1.StorageProvider, settings *ClusterSettings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	s, err := NewStorageBackend(provider)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return s.WriteAll(ctx, constants.ClusterSettingsFile, data, nil)
+}
+
+// LoadClusterSettings loads the cluster settings from the backup
+func LoadClusterSettings(ctx context.Context, provider v1alpha1.StorageProvider) (*ClusterSettings, error) {
+	s, err := NewStorageBackend(provider)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	exist, err := s.Exists(ctx, constants.ClusterSettingsFile)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, fmt.Errorf("%s not exist, the backup may not include the cluster settings", constants.ClusterSettingsFile)
+	}
+	data, err := s.ReadAll(ctx, constants.ClusterSettingsFile)
+	if err != nil {
+		return nil, err
+	}
+	settings := &ClusterSettings{}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ApplyClusterSettings applies the cluster settings except the excluded ones, it returns the keys of
+// the settings applied and the ones excluded or failed to be applied. The global variables and the
+// placement policies are skipped if db is nil.
+func (bo *GenericOptions) ApplyClusterSettings(ctx context.Context, pdCli pdapi.PDClient, db *sql.DB, settings *ClusterSettings, exclude []string) (applied, skipped []string) {
+	var execer sqlExecer
+	if db != nil {
+		execer = db
+	}
+	return bo.applyClusterSettings(ctx, pdCli, execer, settings, exclude)
+}
+
+func (bo *GenericOptions) applyClusterSettings(ctx context.Context, pdCli pdapi.PDClient, db sqlExecer, settings *ClusterSettings, exclude []string) (applied, skipped []string) {
+	apply := func(key string, fn func() error) {
+		if isClusterSettingExcluded(key, exclude) {
+			klog.Infof("cluster %s skip the excluded setting %s", bo, key)
+			skipped = append(skipped, key)
+			return
+		}
+		if err := fn(); err != nil {
+			klog.Warningf("cluster %s failed to apply setting %s, err: %v", bo, key, err)
+			skipped = append(skipped, key)
+			return
+		}
+		applied = append(applied, key)
+	}
+
+	items := make([]string, 0, len(settings.PDConfig))
+	for item := range settings.PDConfig {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	for _, item := range items {
+		value := settings.PDConfig[item]
+		apply(pdSettingPrefix+item, func() error {
+			return pdCli.SetConfigItems(map[string]interface{}{item: value})
+		})
+	}
+
+	for _, name := range sortedKeys(settings.GlobalVariables) {
+		value := settings.GlobalVariables[name]
+		apply(variableSettingPrefix+name, func() error {
+			if db == nil {
+				return fmt.Errorf("cluster is not accessible")
+			}
+			if !variableNamePattern.MatchString(name) {
+				return fmt.Errorf("invalid variable name")
+			}
+			_, err := db.ExecContext(ctx, fmt.Sprintf("set @@global.%s = ?", name), value)
+			return err
+		})
+	}
+
+	for _, name := range sortedKeys(settings.PlacementPolicies) {
+		create := settings.PlacementPolicies[name]
+		apply(placementSettingPrefix+name, func() error {
+			if db == nil {
+				return fmt.Errorf("cluster is not accessible")
+			}
+			loc := createPolicyPattern.FindStringIndex(create)
+			if loc == nil {
+				return fmt.Errorf("unexpected statement %q", create)
+			}
+			// the existing policies of the cluster are kept
+			_, err := db.ExecContext(ctx, "CREATE PLACEMENT POLICY IF NOT EXISTS "+create[loc[1]:])
+			return err
+		})
+	}
+	return applied, skipped
+}
+
+// isClusterSettingExcluded returns whether the cluster setting is excluded by the built-in rules or the
+// user-specified keys and the glob patterns of them
+func isClusterSettingExcluded(key string, exclude []string) bool {
+	if excludedSettings.Has(key) {
+		return true
+	}
+	name := strings.ToLower(key[strings.Index(key, ":")+1:])
+	if strings.Contains(strings.ReplaceAll(name, "_", "-"), "cluster-id") {
+		return true
+	}
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '-' || r == '_' }) {
+		if excludedSettingWords.Has(word) {
+			return true
+		}
+	}
+	for _, pattern := range exclude {
+		if matched, err := path.Match(pattern, key); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
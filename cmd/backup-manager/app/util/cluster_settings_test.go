@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+)
+
+type fakeExecer struct {
+	statements []string
+	failed     map[string]bool
+}
+
+func (e *fakeExecer) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	statement := query
+	if len(args) > 0 {
+		statement = fmt.Sprintf("%s %v", query, args)
+	}
+	if e.failed[query] {
+		return nil, fmt.Errorf("failed to execute %s", query)
+	}
+	e.statements = append(e.statements, statement)
+	return nil, nil
+}
+
+func TestIsClusterSettingExcluded(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		key      string
+		exclude  []string
+		excluded bool
+	}{
+		{key: "pd:schedule.leader-schedule-limit", excluded: false},
+		{key: "pd:pd-server.metric-storage-url", excluded: true},
+		{key: "pd:pd-server.dashboard-address", excluded: true},
+		{key: "variable:tidb_mem_quota_query", excluded: false},
+		{key: "variable:tidb_cluster_id", excluded: true},
+		{key: "variable:tidb_super_read_only", excluded: true},
+		{key: "variable:tidb_tmp_storage_path", excluded: true},
+		{key: "variable:report_host", excluded: true},
+		{key: "variable:tidb_enable_telemetry", excluded: false},
+		{key: "placement:p1", excluded: false},
+		{key: "placement:p1", exclude: []string{"placement:*"}, excluded: true},
+		{key: "pd:schedule.leader-schedule-limit", exclude: []string{"pd:schedule.*"}, excluded: true},
+		{key: "pd:replication.max-replicas", exclude: []string{"pd:schedule.*"}, excluded: false},
+		{key: "variable:tidb_mem_quota_query", exclude: []string{"variable:tidb_mem_quota_query"}, excluded: true},
+	}
+	for _, tt := range tests {
+		g.Expect(isClusterSettingExcluded(tt.key, tt.exclude)).To(Equal(tt.excluded), "key %s, exclude %v", tt.key, tt.exclude)
+	}
+}
+
+func TestApplyClusterSettings(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	bo := &GenericOptions{Namespace: "ns", ResourceName: "restore"}
+	settings := &ClusterSettings{
+		PDConfig: map[string]interface{}{
+			"schedule.leader-schedule-limit": float64(8),
+			"schedule.max-snapshot-count":    float64(64),
+			"replication.max-replicas":       float64(5),
+		},
+		GlobalVariables: map[string]string{
+			"tidb_mem_quota_query":          "2147483648",
+			"tidb_distsql_scan_concurrency": "30",
+			"tidb_super_read_only":          "ON",
+			"invalid name":                  "1",
+		},
+		PlacementPolicies: map[string]string{
+			"p1": "CREATE PLACEMENT POLICY `p1` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\"",
+		},
+	}
+
+	pdCli := pdapi.NewFakePDClient()
+	pdItems := map[string]interface{}{}
+	pdCli.AddReaction(pdapi.SetConfigItemsActionType, func(action *pdapi.Action) (interface{}, error) {
+		for k, v := range action.ConfigItems {
+			if k == "replication.max-replicas" {
+				return nil, fmt.Errorf("failed to set %s", k)
+			}
+			pdItems[k] = v
+		}
+		return nil, nil
+	})
+	db := &fakeExecer{failed: map[string]bool{"set @@global.tidb_distsql_scan_concurrency = ?": true}}
+
+	applied, skipped := bo.applyClusterSettings(context.TODO(), pdCli, db, settings, []string{"pd:schedule.max-snapshot-count"})
+	g.Expect(applied).To(Equal([]string{
+		"pd:schedule.leader-schedule-limit",
+		"variable:tidb_mem_quota_query",
+		"placement:p1",
+	}))
+	g.Expect(skipped).To(Equal([]string{
+		"pd:replication.max-replicas",
+		"pd:schedule.max-snapshot-count",
+		"variable:invalid name",
+		"variable:tidb_distsql_scan_concurrency",
+		"variable:tidb_super_read_only",
+	}))
+	g.Expect(pdItems).To(Equal(map[string]interface{}{"schedule.leader-schedule-limit": float64(8)}))
+	g.Expect(db.statements).To(Equal([]string{
+		"set @@global.tidb_mem_quota_query = ? [2147483648]",
+		"CREATE PLACEMENT POLICY IF NOT EXISTS `p1` PRIMARY_REGION=\"us-east-1\" REGIONS=\"us-east-1,us-west-1\"",
+	}))
+
+	// the global variables and the placement policies are skipped if the cluster is not accessible
+	applied, skipped = bo.applyClusterSettings(context.TODO(), pdCli, nil, &ClusterSettings{
+		GlobalVariables:   map[string]string{"tidb_mem_quota_query": "1"},
+		PlacementPolicies: map[string]string{"p1": "CREATE PLACEMENT POLICY `p1` FOLLOWERS=4"},
+	}, nil)
+	g.Expect(applied).To(BeEmpty())
+	g.Expect(skipped).To(Equal([]string{"variable:tidb_mem_quota_query", "placement:p1"}))
+}
@@ -25,6 +25,8 @@ import (
 
 	"github.com/go-sql-driver/mysql"
 	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/constants"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -99,3 +101,67 @@ func (bo *GenericOptions) SetTikvGCLifeTime(ctx context.Context, db *sql.DB, gcT
 	}
 	return nil
 }
+
+// CheckRenameTargets returns an error if any target of the rename mapping exists in the cluster,
+// i.e. the table exists or the database contains any table if the target is a database.
+func (bo *GenericOptions) CheckRenameTargets(ctx context.Context, db *sql.DB, mappings []v1alpha1.RestoreRenameMapping) error {
+	for _, m := range mappings {
+		tables, err := bo.listRenameTargetTables(ctx, db, m.Target)
+		if err != nil {
+			return err
+		}
+		if len(tables) > 0 {
+			return fmt.Errorf("rename mapping target %s of cluster %s collides with the existing table %s, set overwrite to restore it anyway", m.Target, bo, tables[0])
+		}
+	}
+	return nil
+}
+
+// ListRestoredTables lists the tables restored by the rename mapping with their target names
+func (bo *GenericOptions) ListRestoredTables(ctx context.Context, db *sql.DB, mappings []v1alpha1.RestoreRenameMapping) ([]v1alpha1.RestoredTable, error) {
+	var restored []v1alpha1.RestoredTable
+	for _, m := range mappings {
+		tables, err := bo.listRenameTargetTables(ctx, db, m.Target)
+		if err != nil {
+			return nil, err
+		}
+		sourceDB, sourceTable, _ := backuputil.SplitRenameName(m.Source)
+		targetDB, _, _ := backuputil.SplitRenameName(m.Target)
+		for _, table := range tables {
+			source := m.Source
+			if sourceTable == "*" {
+				source = sourceDB + "." + table
+			}
+			restored = append(restored, v1alpha1.RestoredTable{Source: source, Target: targetDB + "." + table})
+		}
+	}
+	return restored, nil
+}
+
+// listRenameTargetTables lists the existing tables of the target of the rename mapping
+func (bo *GenericOptions) listRenameTargetTables(ctx context.Context, db *sql.DB, target string) ([]string, error) {
+	targetDB, targetTable, err := backuputil.SplitRenameName(target)
+	if err != nil {
+		return nil, err
+	}
+	query := "select table_name from information_schema.tables where table_schema = ?"
+	args := []interface{}{targetDB}
+	if targetTable != "*" {
+		query += " and table_name = ?"
+		args = append(args, targetTable)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tables of %s in cluster %s failed, sql: %s, err: %v", target, bo, query, err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, fmt.Errorf("scan tables of %s in cluster %s failed, err: %v", target, bo, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
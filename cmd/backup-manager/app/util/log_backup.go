@@ -0,0 +1,158 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/constants"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
+	"gocloud.dev/blob"
+)
+
+const (
+	// the field numbers of max_ts and min_ts in the metadata of the log backup (backup.Metadata of kvproto)
+	logBackupMetaMaxTsField = 4
+	logBackupMetaMinTsField = 5
+)
+
+// LogBackupStorageProvider returns the storage provider of the log backup, the log backup is in the same
+// storage as the full backup, under the prefix
+func LogBackupStorageProvider(provider v1alpha1.StorageProvider, prefix string) v1alpha1.StorageProvider {
+	logProvider := *provider.DeepCopy()
+	if logProvider.S3 != nil {
+		logProvider.S3.Prefix = prefix
+	} else if logProvider.Gcs != nil {
+		logProvider.Gcs.Prefix = prefix
+	} else if logProvider.Azblob != nil {
+		logProvider.Azblob.Prefix = prefix
+	} else if logProvider.Local != nil {
+		logProvider.Local.Prefix = prefix
+	}
+	return logProvider
+}
+
+// genPitrStorageArgs returns the storage args of br for a point-in-time restore, the log backup under the
+// log prefix is the --storage and the full backup is the --full-backup-storage
+func genPitrStorageArgs(provider v1alpha1.StorageProvider, logPrefix, restoredTs string) ([]string, error) {
+	fullArgs, err := genStorageArgs(provider)
+	if err != nil {
+		return nil, err
+	}
+	args, err := genStorageArgs(LogBackupStorageProvider(provider, logPrefix))
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range fullArgs {
+		if strings.HasPrefix(arg, "--storage=") {
+			args = append(args, "--full-backup-storage="+strings.TrimPrefix(arg, "--storage="))
+		}
+	}
+	args = append(args, "--restored-ts="+restoredTs)
+	return args, nil
+}
+
+// GetLogBackupRanges lists the metadata of the log backup in the storage and returns the ts ranges covered by them
+func GetLogBackupRanges(ctx context.Context, provider v1alpha1.StorageProvider) ([]backuputil.LogBackupRange, error) {
+	s, err := NewStorageBackend(provider)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var ranges []backuputil.LogBackupRange
+	iter := s.List(&blob.ListOptions{Prefix: constants.LogBackupMetaDir})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list the metadata of the log backup failed, err: %v", err)
+		}
+		if obj.IsDir || !strings.HasSuffix(obj.Key, ".meta") {
+			continue
+		}
+		data, err := s.ReadAll(ctx, obj.Key)
+		if err != nil {
+			return nil, fmt.Errorf("read the metadata %s of the log backup failed, err: %v", obj.Key, err)
+		}
+		r, err := decodeLogBackupRange(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode the metadata %s of the log backup failed, err: %v", obj.Key, err)
+		}
+		// the metadata without any file does not cover any ts
+		if r.EndTs == 0 {
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// decodeLogBackupRange decodes min_ts and max_ts of the metadata of the log backup.
+// The vendored kvproto predates the log backup, so the fields are read from the wire format directly
+// and the other fields are skipped.
+func decodeLogBackupRange(data []byte) (backuputil.LogBackupRange, error) {
+	var r backuputil.LogBackupRange
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return r, fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		field, wireType := key>>3, key&0x7
+		switch wireType {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return r, fmt.Errorf("invalid varint of field %d", field)
+			}
+			data = data[n:]
+			switch field {
+			case logBackupMetaMaxTsField:
+				r.EndTs = v
+			case logBackupMetaMinTsField:
+				r.StartTs = v
+			}
+		case 1:
+			if len(data) < 8 {
+				return r, fmt.Errorf("invalid fixed64 of field %d", field)
+			}
+			data = data[8:]
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return r, fmt.Errorf("invalid length of field %d", field)
+			}
+			data = data[uint64(n)+l:]
+		case 5:
+			if len(data) < 4 {
+				return r, fmt.Errorf("invalid fixed32 of field %d", field)
+			}
+			data = data[4:]
+		default:
+			return r, fmt.Errorf("unsupported wire type %d of field %d", wireType, field)
+		}
+	}
+	if r.StartTs > r.EndTs {
+		return r, fmt.Errorf("min ts %d is after max ts %d", r.StartTs, r.EndTs)
+	}
+	return r, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// encodeLogBackupMeta encodes a metadata of the log backup with a file, the store id and the ts range
+func encodeLogBackupMeta(minTs, maxTs uint64) []byte {
+	appendVarint := func(b []byte, v uint64) []byte {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return append(b, buf[:binary.PutUvarint(buf, v)]...)
+	}
+	var data []byte
+	// files
+	data = appendVarint(data, 1<<3|2)
+	data = appendVarint(data, 3)
+	data = append(data, "abc"...)
+	// store_id
+	data = appendVarint(data, 2<<3)
+	data = appendVarint(data, 1)
+	data = appendVarint(data, logBackupMetaMaxTsField<<3)
+	data = appendVarint(data, maxTs)
+	data = appendVarint(data, logBackupMetaMinTsField<<3)
+	data = appendVarint(data, minTs)
+	return data
+}
+
+func TestDecodeLogBackupRange(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r, err := decodeLogBackupRange(encodeLogBackupMeta(100, 200))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(r).To(Equal(backuputil.LogBackupRange{StartTs: 100, EndTs: 200}))
+
+	r, err = decodeLogBackupRange(nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(r).To(Equal(backuputil.LogBackupRange{}))
+
+	_, err = decodeLogBackupRange(encodeLogBackupMeta(200, 100))
+	g.Expect(err).To(HaveOccurred())
+
+	data := encodeLogBackupMeta(100, 200)
+	_, err = decodeLogBackupRange(data[:3])
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetLogBackupRanges(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "log-backup")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	metaDir := filepath.Join(dir, "log", "v1", "backupmeta")
+	g.Expect(os.MkdirAll(metaDir, 0755)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(metaDir, "1.meta"), encodeLogBackupMeta(100, 200), 0644)).To(Succeed())
+	g.Expect(ioutil.WriteFile(filepath.Join(metaDir, "2.meta"), encodeLogBackupMeta(300, 400), 0644)).To(Succeed())
+	// the metadata without any file is skipped
+	g.Expect(ioutil.WriteFile(filepath.Join(metaDir, "3.meta"), nil, 0644)).To(Succeed())
+	// the other files are not metadata
+	g.Expect(ioutil.WriteFile(filepath.Join(metaDir, "lock"), []byte("lock"), 0644)).To(Succeed())
+
+	provider := v1alpha1.StorageProvider{
+		Local: &v1alpha1.LocalStorageProvider{
+			VolumeMount: corev1.VolumeMount{MountPath: dir},
+			Prefix:      "full",
+		},
+	}
+	logProvider := LogBackupStorageProvider(provider, "log")
+	g.Expect(logProvider.Local.Prefix).To(Equal("log"))
+	g.Expect(provider.Local.Prefix).To(Equal("full"))
+
+	ranges, err := GetLogBackupRanges(context.Background(), logProvider)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ranges).To(ConsistOf(
+		backuputil.LogBackupRange{StartTs: 100, EndTs: 200},
+		backuputil.LogBackupRange{StartTs: 300, EndTs: 400},
+	))
+	g.Expect(backuputil.CheckLogBackupContinuity(150, 350, ranges)).To(Equal(&backuputil.LogBackupGapError{Start: 200, End: 300}))
+}
@@ -0,0 +1,166 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/constants"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// toolLogTailLines is the number of the last lines of the log kept in the failure summary
+	toolLogTailLines = 50
+	// maxFailureSummaryLength is the max length of the failure summary in bytes
+	maxFailureSummaryLength = 4096
+)
+
+// ToolLog captures the output of a tool run by backup-manager, e.g. BR, dumpling or lightning. It keeps the last
+// lines of the log and the exit diagnostics for the failure summary in the status of the Backup or the Restore,
+// which is kept after the pod of the job is deleted, and the full log in a temporary file if it is uploaded.
+type ToolLog struct {
+	tool    string
+	tail    []string
+	exitErr error
+	file    *os.File
+}
+
+// NewToolLog returns a ToolLog of the tool, the full log is kept only if keepFull is true
+func NewToolLog(tool string, keepFull bool) *ToolLog {
+	l := &ToolLog{tool: tool}
+	if !keepFull {
+		return l
+	}
+	file, err := ioutil.TempFile("", tool+"-*.log")
+	if err != nil {
+		klog.Warningf("failed to create the log file of %s, the log will not be uploaded, err: %v", tool, err)
+		return l
+	}
+	l.file = file
+	return l
+}
+
+// WriteLine logs a line of the output of the tool and captures it
+func (l *ToolLog) WriteLine(line string) {
+	line = strings.TrimRight(line, "\n")
+	klog.Info(line)
+	if len(l.tail) == toolLogTailLines {
+		l.tail = l.tail[1:]
+	}
+	l.tail = append(l.tail, line)
+	if l.file == nil {
+		return
+	}
+	if _, err := l.file.WriteString(line + "\n"); err != nil {
+		klog.Warningf("failed to write the log file of %s, the log will not be uploaded, err: %v", l.tool, err)
+		l.Close()
+	}
+}
+
+// WriteOutput logs the output of the tool line by line and captures it
+func (l *ToolLog) WriteOutput(output []byte) {
+	if len(output) == 0 {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		l.WriteLine(line)
+	}
+}
+
+// Exit records the error the tool exits with
+func (l *ToolLog) Exit(err error) {
+	l.exitErr = err
+}
+
+// FailureSummary returns the exit diagnostics and the last lines of the log truncated to 4KB, it is empty if the
+// tool does not exit with an error
+func (l *ToolLog) FailureSummary() string {
+	if l.exitErr == nil {
+		return ""
+	}
+	head := fmt.Sprintf("%s exited with: %v", l.tool, l.exitErr)
+	if len(l.tail) == 0 {
+		return truncateFailureSummary(head, "")
+	}
+	head += fmt.Sprintf(", the last %d lines of the log:\n", len(l.tail))
+	return truncateFailureSummary(head, strings.Join(l.tail, "\n"))
+}
+
+// truncateFailureSummary truncates the log to keep the summary within maxFailureSummaryLength, the head and
+// the end of the log are kept as the error is usually at the end
+func truncateFailureSummary(head, log string) string {
+	if len(head) >= maxFailureSummaryLength {
+		return strings.ToValidUTF8(head[:maxFailureSummaryLength], "")
+	}
+	if len(head)+len(log) <= maxFailureSummaryLength {
+		return head + log
+	}
+	const ellipsis = "...\n"
+	log = log[len(log)-(maxFailureSummaryLength-len(head)-len(ellipsis)):]
+	// drop the line cut off
+	if i := strings.IndexByte(log, '\n'); i >= 0 {
+		log = log[i+1:]
+	}
+	return head + ellipsis + strings.ToValidUTF8(log, "")
+}
+
+// Upload uploads the full log to operator-logs/<name>-<tool>-<time>.log under the path of the storage, it does
+// nothing if the full log is not kept. The error should only be logged, the upload never fails the backup or
+// the restore.
+func (l *ToolLog) Upload(ctx context.Context, provider v1alpha1.StorageProvider, name string) error {
+	if l.file == nil {
+		return nil
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s, err := NewStorageBackend(provider)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	key := path.Join(constants.ToolLogsDir, fmt.Sprintf("%s-%s-%s.log", name, l.tool, time.Now().UTC().Format("20060102T150405Z")))
+	w, err := s.NewWriter(ctx, key, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, l.file); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	klog.Infof("uploaded the log of %s to %s", l.tool, key)
+	return nil
+}
+
+// Close removes the temporary file of the full log
+func (l *ToolLog) Close() {
+	if l.file == nil {
+		return
+	}
+	l.file.Close()
+	os.Remove(l.file.Name())
+	l.file = nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/cmd/backup-manager/app/constants"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestToolLogFailureSummary(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	l := NewToolLog("br", false)
+	defer l.Close()
+	// the tool succeeds
+	l.WriteLine("[INFO] backup started\n")
+	g.Expect(l.FailureSummary()).To(BeEmpty())
+
+	l.WriteOutput([]byte("[ERROR] backup failed\nError: context canceled\n"))
+	l.Exit(errors.New("exit status 1"))
+	g.Expect(l.FailureSummary()).To(Equal("br exited with: exit status 1, the last 3 lines of the log:\n" +
+		"[INFO] backup started\n[ERROR] backup failed\nError: context canceled"))
+
+	// only the last lines are kept
+	for i := 0; i < 2*toolLogTailLines; i++ {
+		l.WriteLine(fmt.Sprintf("line %d", i))
+	}
+	lines := strings.Split(l.FailureSummary(), "\n")
+	g.Expect(lines).To(HaveLen(toolLogTailLines + 1))
+	g.Expect(lines[0]).To(Equal(fmt.Sprintf("br exited with: exit status 1, the last %d lines of the log:", toolLogTailLines)))
+	g.Expect(lines[1]).To(Equal(fmt.Sprintf("line %d", toolLogTailLines)))
+	g.Expect(lines[toolLogTailLines]).To(Equal(fmt.Sprintf("line %d", 2*toolLogTailLines-1)))
+
+	// the tool exits without output
+	l = NewToolLog("dumpling", false)
+	l.Exit(errors.New("signal: killed"))
+	g.Expect(l.FailureSummary()).To(Equal("dumpling exited with: signal: killed"))
+}
+
+func TestTruncateFailureSummary(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	head := "br exited with: exit status 1\n"
+	g.Expect(truncateFailureSummary(head, "a\nb")).To(Equal(head + "a\nb"))
+
+	line := strings.Repeat("x", 99)
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("%s%d", line[:99-len(fmt.Sprint(i))], i))
+	}
+	summary := truncateFailureSummary(head, strings.Join(lines, "\n"))
+	g.Expect(len(summary)).To(BeNumerically("<=", maxFailureSummaryLength))
+	g.Expect(summary).To(HavePrefix(head + "...\n"))
+	// the lines are not cut off
+	for _, l := range strings.Split(strings.TrimPrefix(summary, head+"...\n"), "\n") {
+		g.Expect(l).To(HaveLen(99))
+	}
+	g.Expect(summary).To(HaveSuffix(lines[99]))
+
+	// a long line is cut off at a valid utf-8 boundary
+	summary = truncateFailureSummary(head, strings.Repeat("日志", 1000))
+	g.Expect(len(summary)).To(BeNumerically("<=", maxFailureSummaryLength))
+	g.Expect(summary).To(HaveSuffix("日志"))
+	g.Expect(strings.ToValidUTF8(summary, "")).To(Equal(summary))
+
+	// the head is too long
+	g.Expect(truncateFailureSummary(strings.Repeat("e", 2*maxFailureSummaryLength), "a")).To(HaveLen(maxFailureSummaryLength))
+}
+
+func TestToolLogUpload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "tool-log")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	g.Expect(os.MkdirAll(filepath.Join(dir, "backup"), 0755)).To(Succeed())
+	provider := v1alpha1.StorageProvider{
+		Local: &v1alpha1.LocalStorageProvider{
+			VolumeMount: corev1.VolumeMount{MountPath: dir},
+			Prefix:      "backup",
+		},
+	}
+	uploaded := func() []string {
+		files, _ := filepath.Glob(filepath.Join(dir, "backup", constants.ToolLogsDir, "*.log"))
+		return files
+	}
+
+	// the upload is disabled
+	l := NewToolLog("br", false)
+	l.WriteLine("[INFO] backup started")
+	g.Expect(l.Upload(context.TODO(), v1alpha1.StorageProvider{}, "backup-test")).To(Succeed())
+	g.Expect(l.Upload(context.TODO(), provider, "backup-test")).To(Succeed())
+	g.Expect(uploaded()).To(BeEmpty())
+	l.Close()
+
+	l = NewToolLog("br", true)
+	name := l.file.Name()
+	for i := 0; i < 2*toolLogTailLines; i++ {
+		l.WriteLine(fmt.Sprintf("line %d", i))
+	}
+	g.Expect(l.Upload(context.TODO(), provider, "backup-test")).To(Succeed())
+	files := uploaded()
+	g.Expect(files).To(HaveLen(1))
+	g.Expect(filepath.Base(files[0])).To(HavePrefix("backup-test-br-"))
+	data, err := ioutil.ReadFile(files[0])
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")).To(HaveLen(2 * toolLogTailLines))
+
+	// the upload fails
+	g.Expect(l.Upload(context.TODO(), v1alpha1.StorageProvider{}, "backup-test")).NotTo(Succeed())
+
+	l.Close()
+	_, err = os.Stat(name)
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+}
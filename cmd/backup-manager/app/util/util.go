@@ -223,11 +223,20 @@ func ConstructBRGlobalOptionsForRestore(restore *v1alpha1.Restore) ([]string, er
 		return nil, fmt.Errorf("no config for br in restore %s/%s", restore.Namespace, restore.Name)
 	}
 	args = append(args, constructBRGlobalOptions(config.BR)...)
-	storageArgs, err := genStorageArgs(restore.Spec.StorageProvider)
+	var storageArgs []string
+	var err error
+	if config.PitrRestoredTs != "" {
+		storageArgs, err = genPitrStorageArgs(restore.Spec.StorageProvider, config.PitrLogBackupPrefix, config.PitrRestoredTs)
+	} else {
+		storageArgs, err = genStorageArgs(restore.Spec.StorageProvider)
+	}
 	if err != nil {
 		return nil, err
 	}
 	args = append(args, storageArgs...)
+	for _, m := range config.RenameMapping {
+		args = append(args, fmt.Sprintf("--rename=%s:%s", m.Source, m.Target))
+	}
 
 	if config.TableFilter != nil && len(config.TableFilter) > 0 {
 		for _, tableFilter := range config.TableFilter {
@@ -253,6 +262,32 @@ func ConstructBRGlobalOptionsForRestore(restore *v1alpha1.Restore) ([]string, er
 	return args, nil
 }
 
+// ConstructLightningRoutes constructs the route rules of TiDB Lightning in TOML from the rename mapping
+// of the restore, it returns an empty string if the rename mapping is not set.
+func ConstructLightningRoutes(restore *v1alpha1.Restore) (string, error) {
+	var sb strings.Builder
+	for _, m := range restore.Spec.RenameMapping {
+		sourceDB, sourceTable, err := util.SplitRenameName(m.Source)
+		if err != nil {
+			return "", err
+		}
+		targetDB, targetTable, err := util.SplitRenameName(m.Target)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("[[routes]]\n")
+		fmt.Fprintf(&sb, "schema-pattern = %q\n", sourceDB)
+		if sourceTable != "*" {
+			fmt.Fprintf(&sb, "table-pattern = %q\n", sourceTable)
+		}
+		fmt.Fprintf(&sb, "target-schema = %q\n", targetDB)
+		if targetTable != "*" {
+			fmt.Fprintf(&sb, "target-table = %q\n", targetTable)
+		}
+	}
+	return sb.String(), nil
+}
+
 // constructBRGlobalOptions constructs BR basic global options.
 func constructBRGlobalOptions(config *v1alpha1.BRConfig) []string {
 	var args []string
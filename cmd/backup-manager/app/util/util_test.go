@@ -316,6 +316,8 @@ func TestConstructBRGlobalOptionsForRestore(t *testing.T) {
 		hasRestoreFilter bool
 		hasTable         bool
 		hasDB            bool
+		hasRename        bool
+		hasPitr          bool
 	}
 
 	tests := []*testcase{
@@ -343,6 +345,15 @@ func TestConstructBRGlobalOptionsForRestore(t *testing.T) {
 			hasTable:         false,
 			hasDB:            true,
 		},
+		{
+			name:             "customize filter and rename mapping",
+			hasRestoreFilter: true,
+			hasRename:        true,
+		},
+		{
+			name:    "point-in-time restore",
+			hasPitr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -355,9 +366,24 @@ func TestConstructBRGlobalOptionsForRestore(t *testing.T) {
 
 			restore.Spec.BR = &v1alpha1.BRConfig{Cluster: "cluster-1", ClusterNamespace: "default"}
 			var expectArgs []string
-			expectArgs = append(expectArgs, "--storage=s3://test1-demo1")
+			if tt.hasPitr {
+				restore.Spec.PitrRestoredTs = "433748128405078017"
+				restore.Spec.PitrLogBackupPrefix = "log"
+				expectArgs = append(expectArgs, "--storage=s3://test1-demo1/log")
+			} else {
+				expectArgs = append(expectArgs, "--storage=s3://test1-demo1")
+			}
 			expectArgs = append(expectArgs, "--s3.provider=ceph")
 			expectArgs = append(expectArgs, "--s3.endpoint=http://10.0.0.1")
+			if tt.hasPitr {
+				expectArgs = append(expectArgs, "--full-backup-storage=s3://test1-demo1")
+				expectArgs = append(expectArgs, "--restored-ts=433748128405078017")
+			}
+
+			if tt.hasRename {
+				restore.Spec.RenameMapping = []v1alpha1.RestoreRenameMapping{{Source: "mysql.user", Target: "mysql.user_restored"}}
+				expectArgs = append(expectArgs, "--rename=mysql.user:mysql.user_restored")
+			}
 
 			if tt.hasRestoreFilter {
 				restore.Spec.TableFilter = customBackupFilter
@@ -385,6 +411,31 @@ func TestConstructBRGlobalOptionsForRestore(t *testing.T) {
 	}
 }
 
+func TestConstructLightningRoutes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	restore := newRestore()
+	routes, err := ConstructLightningRoutes(restore)
+	g.Expect(err).To(Succeed())
+	g.Expect(routes).To(BeEmpty())
+
+	restore.Spec.RenameMapping = []v1alpha1.RestoreRenameMapping{
+		{Source: "app.orders", Target: "app.orders_restored"},
+		{Source: "shop.*", Target: "shop_restored.*"},
+	}
+	routes, err = ConstructLightningRoutes(restore)
+	g.Expect(err).To(Succeed())
+	g.Expect(routes).To(Equal(`[[routes]]
+schema-pattern = "app"
+table-pattern = "orders"
+target-schema = "app"
+target-table = "orders_restored"
+[[routes]]
+schema-pattern = "shop"
+target-schema = "shop_restored"
+`))
+}
+
 func TestGetCommitTsFromMetadata(t *testing.T) {
 	g := NewGomegaWithT(t)
 	tmpdir, err := ioutil.TempDir("", "test-get-commitTs-metadata")
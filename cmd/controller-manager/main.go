@@ -31,6 +31,8 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/controller/backup"
 	"github.com/pingcap/tidb-operator/pkg/controller/backupschedule"
 	"github.com/pingcap/tidb-operator/pkg/controller/dmcluster"
+	"github.com/pingcap/tidb-operator/pkg/controller/fleetstatus"
+	"github.com/pingcap/tidb-operator/pkg/controller/nodemaintenance"
 	"github.com/pingcap/tidb-operator/pkg/controller/restore"
 	"github.com/pingcap/tidb-operator/pkg/controller/tidbcluster"
 	"github.com/pingcap/tidb-operator/pkg/controller/tidbinitializer"
@@ -38,11 +40,14 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/controller/tidbngmonitoring"
 	"github.com/pingcap/tidb-operator/pkg/features"
 	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/pingcap/tidb-operator/pkg/rbac"
 	"github.com/pingcap/tidb-operator/pkg/scheme"
 	"github.com/pingcap/tidb-operator/pkg/upgrader"
 	"github.com/pingcap/tidb-operator/pkg/version"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -67,6 +72,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	// the flags set on the command line take precedence over the config file
+	explicitFlags := sets.NewString()
+	flag.Visit(func(f *flag.Flag) {
+		explicitFlags.Insert(f.Name)
+	})
+	var fileCfg *controller.ControllerManagerConfiguration
+	var fileHash string
+	if cliCfg.ConfigFile != "" {
+		var err error
+		fileCfg, fileHash, err = controller.LoadControllerManagerConfiguration(cliCfg.ConfigFile)
+		if err != nil {
+			klog.Fatalf("failed to load config file: %v", err)
+		}
+		fileCfg.ApplyTo(cliCfg, explicitFlags)
+	}
+
 	logs.InitLogs()
 	defer logs.FlushLogs()
 
@@ -74,6 +95,16 @@ func main() {
 	flag.VisitAll(func(flag *flag.Flag) {
 		klog.V(1).Infof("FLAG: --%s=%q", flag.Name, flag.Value)
 	})
+	if fileCfg != nil {
+		klog.Infof("config file %s of hash %s is loaded", cliCfg.ConfigFile, fileHash)
+	}
+	if _, err := labels.Parse(cliCfg.UpgradeWebhookClusterSelector); err != nil {
+		klog.Fatalf("invalid -upgrade-webhook-cluster-selector %q: %v", cliCfg.UpgradeWebhookClusterSelector, err)
+	}
+	rbacOpts, err := cliCfg.RBACOptions()
+	if err != nil {
+		klog.Fatalf("invalid -capabilities %q: %v", cliCfg.Capabilities, err)
+	}
 
 	metrics.RegisterMetrics()
 
@@ -121,6 +152,17 @@ func main() {
 		klog.Fatalf("failed to get the generic kube-apiserver client: %v", err)
 	}
 
+	// review the permissions of the enabled capabilities on startup, the missing ones are reported
+	// instead of failing in the middle of reconciling
+	missing, err := rbac.CheckPermissions(context.TODO(), kubeCli, ns, rbacOpts)
+	if err != nil {
+		klog.Warningf("failed to check the permissions of tidb-controller-manager: %v", err)
+	}
+	for _, p := range missing {
+		klog.Warningf("tidb-controller-manager is not permitted to %s, which is required by the enabled capabilities", p)
+		metrics.RBACMissingPermissions.WithLabelValues(p.Group, p.Resource, p.Verb).Set(1)
+	}
+
 	// note that kubeCli here must not be the hijacked one
 	var operatorUpgrader upgrader.Interface
 	if cliCfg.ClusterScoped {
@@ -139,6 +181,12 @@ func main() {
 	if err != nil {
 		klog.Fatalf("failed to create Dependencies: %s", err)
 	}
+	deps.LeaderIdentity = hostName
+	deps.ApplyReloadableConfig(cliCfg)
+	if fileCfg != nil {
+		watcher := controller.NewConfigWatcher(cliCfg.ConfigFile, explicitFlags, cliCfg, fileCfg, fileHash, deps.ApplyReloadableConfig)
+		go watcher.Run(wait.NeverStop)
+	}
 
 	onStarted := func(ctx context.Context) {
 		// Upgrade before running any controller logic. If it fails, we wait
@@ -160,15 +208,27 @@ func main() {
 		controllers := []Controller{
 			tidbcluster.NewController(deps),
 			tidbcluster.NewPodController(deps),
-			dmcluster.NewController(deps),
-			backup.NewController(deps),
-			restore.NewController(deps),
-			backupschedule.NewController(deps),
 			tidbinitializer.NewController(deps),
-			tidbmonitor.NewController(deps),
-			tidbngmonitoring.NewController(deps),
+			fleetstatus.NewSummarizer(deps),
+		}
+		if deps.CLIConfig.HasNodePermission() {
+			controllers = append(controllers, nodemaintenance.NewController(deps))
+		}
+		if rbacOpts.Has(rbac.CapabilityDM) {
+			controllers = append(controllers, dmcluster.NewController(deps))
+		}
+		if rbacOpts.Has(rbac.CapabilityBackup) {
+			controllers = append(controllers,
+				backup.NewController(deps),
+				restore.NewController(deps),
+				backupschedule.NewController(deps))
+		}
+		if rbacOpts.Has(rbac.CapabilityMonitor) {
+			controllers = append(controllers,
+				tidbmonitor.NewController(deps),
+				tidbngmonitoring.NewController(deps))
 		}
-		if features.DefaultFeatureGate.Enabled(features.AutoScaling) {
+		if features.DefaultFeatureGate.Enabled(features.AutoScaling) && rbacOpts.Has(rbac.CapabilityAutoScaler) {
 			controllers = append(controllers, autoscaler.NewController(deps))
 		}
 
@@ -238,6 +298,11 @@ func main() {
 	go func() {
 		sig := <-sc
 		klog.Infof("got signal %s to exit", sig)
+		// let the syncs in flight finish their current step, the steps they stop at are written to the
+		// TidbClusters for the next leader to resume from
+		drained, aborted := deps.ShutdownDrain.Drain(cliCfg.ShutdownGracePeriod)
+		klog.Infof("drained %d syncs, %d syncs aborted", drained, aborted)
+		deps.ShutdownDrain.WriteCheckpoints(cli)
 		if err2 := srv.Shutdown(context.Background()); err2 != nil {
 			klog.Fatal("fail to shutdown the HTTP server", err2)
 		}
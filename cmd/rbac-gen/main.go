@@ -0,0 +1,114 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// rbac-gen generates the minimal Roles and ClusterRoles of tidb-operator for the enabled capabilities,
+// e.g. for a namespaced install with backups only:
+//
+//	rbac-gen -cluster-scoped=false -capabilities=backup -namespace=tidb-admin > rbac.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/pingcap/tidb-operator/pkg/rbac"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var (
+	capabilities          string
+	clusterScoped         bool
+	clusterPermissionNode bool
+	clusterPermissionPV   bool
+	clusterPermissionSC   bool
+	name                  string
+	namespace             string
+	serviceAccount        string
+	webhookServiceAccount string
+)
+
+func init() {
+	flag.StringVar(&capabilities, "capabilities", "backup,monitor,dm,autoscaler", "The comma-separated capabilities enabled, supported capabilities: backup, monitor, dm, autoscaler and webhook")
+	flag.BoolVar(&clusterScoped, "cluster-scoped", true, "Whether tidb-operator manages the clusters in all the namespaces")
+	flag.BoolVar(&clusterPermissionNode, "cluster-permission-node", false, "Whether tidb-operator has node permissions even if cluster-scoped is false")
+	flag.BoolVar(&clusterPermissionPV, "cluster-permission-pv", false, "Whether tidb-operator has persistent volume permissions even if cluster-scoped is false")
+	flag.BoolVar(&clusterPermissionSC, "cluster-permission-sc", false, "Whether tidb-operator has storage class permissions even if cluster-scoped is false")
+	flag.StringVar(&name, "name", "tidb-controller-manager", "The name of the generated roles and role bindings")
+	flag.StringVar(&namespace, "namespace", "tidb-admin", "The namespace tidb-operator is installed in")
+	flag.StringVar(&serviceAccount, "service-account", "tidb-controller-manager", "The ServiceAccount of tidb-controller-manager")
+	flag.StringVar(&webhookServiceAccount, "webhook-service-account", "tidb-admission-webhook", "The ServiceAccount of the admission webhook, used if the webhook capability is enabled")
+}
+
+func main() {
+	flag.Parse()
+
+	caps, err := rbac.ParseCapabilities(capabilities)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -capabilities: %v\n", err)
+		os.Exit(1)
+	}
+	var controllerManagerCaps []rbac.Capability
+	webhook := false
+	for _, c := range caps {
+		if c == rbac.CapabilityWebhook {
+			webhook = true
+			continue
+		}
+		controllerManagerCaps = append(controllerManagerCaps, c)
+	}
+
+	clusterResources := sets.NewString()
+	if clusterPermissionNode {
+		clusterResources.Insert("nodes")
+	}
+	if clusterPermissionPV {
+		clusterResources.Insert("persistentvolumes")
+	}
+	if clusterPermissionSC {
+		clusterResources.Insert("storageclasses")
+	}
+	objects := rbac.Roles(name, namespace, serviceAccount, rbac.Options{
+		Capabilities:     controllerManagerCaps,
+		ClusterScoped:    clusterScoped,
+		ClusterResources: clusterResources,
+	})
+	if webhook {
+		// the admission webhook reviews the requests of all the namespaces
+		objects = append(objects, rbac.Roles(name+"-admission-webhook", namespace, webhookServiceAccount, rbac.Options{
+			Capabilities:  []rbac.Capability{rbac.CapabilityWebhook},
+			ClusterScoped: true,
+		})...)
+	}
+
+	if err := write(objects); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+func write(objects []runtime.Object) error {
+	for i, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %T: %v", obj, err)
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(data))
+	}
+	return nil
+}
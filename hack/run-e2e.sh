@@ -404,6 +404,11 @@ else
     tester_args+=(
         --provider="${PROVIDER}"
     )
+    if [ "$PROVIDER" == "kind" ]; then
+        tester_args+=(
+            --kind-cluster-name="${CLUSTER}"
+        )
+    fi
 fi
 
 if [ -n "$REPORT_DIR" ]; then
@@ -61,6 +61,12 @@ const (
 
 	// BackupProtectionFinalizer is the name of finalizer on backups
 	BackupProtectionFinalizer string = "tidb.pingcap.com/backup-protection"
+	// TidbClusterDeletionFinalizer is the name of finalizer on tidbclusters with a deletion policy,
+	// it is removed after the components are torn down in order
+	TidbClusterDeletionFinalizer string = "tidb.pingcap.com/tidbcluster-deletion"
+	// DeletionProtectionFinalizer is the name of finalizer on the objects with the deletion protection,
+	// it is removed after the deletion is confirmed by AnnConfirmDeletionKey
+	DeletionProtectionFinalizer string = "tidb.pingcap.com/deletion-protection"
 
 	// AutoScalingGroupLabelKey describes the autoscaling group of the TiDB
 	AutoScalingGroupLabelKey = "tidb.pingcap.com/autoscaling-group"
@@ -70,6 +76,24 @@ const (
 	AutoComponentLabelKey string = "tidb.pingcap.com/auto-component"
 	// BaseTCLabelKey is label key used for heterogeneous clusters to refer to its base TidbCluster
 	BaseTCLabelKey string = "tidb.pingcap.com/base-tc"
+	// TiDBWarmStandbyLabelKey is label key of the TiDB warm standby pods created during upgrading,
+	// its value is the ordinal of the pod it stands in for
+	TiDBWarmStandbyLabelKey string = "tidb.pingcap.com/warm-standby"
+	// ImagePrepullLabelKey is label key of the pods pre-pulling the new image during upgrading,
+	// its value is the component the image is pre-pulled for
+	ImagePrepullLabelKey string = "tidb.pingcap.com/image-prepull"
+	// ImagePrePullDaemonSetLabelKey is label key of the pods of the DaemonSet pre-pulling the new image before
+	// upgrading, its value is the component the image is pre-pulled for
+	ImagePrePullDaemonSetLabelKey string = "tidb.pingcap.com/image-prepull-daemonset"
+	// HostPortLabelKeyPrefix is the prefix of the label keys of the pods listening on the allocated host
+	// ports, the key is suffixed with the port, e.g. tidb.pingcap.com/host-port-20160
+	HostPortLabelKeyPrefix string = "tidb.pingcap.com/host-port-"
+	// TiDBGroupLabelKey is label key of the objects of a tidb group in spec.tidbGroups, its value is the name
+	// of the group. The pods of the groups are labeled as tidb too, the ones without it are of spec.tidb
+	TiDBGroupLabelKey string = "tidb.pingcap.com/tidb-group"
+	// ZoneLabelKey is label key of the PD and TiKV pods and their per-zone headless services if
+	// spec.networking.topologyAwareRouting is enabled, its value is the zone of the node of the pod
+	ZoneLabelKey string = "tidb.pingcap.com/zone"
 
 	// AnnHATopologyKey defines the High availability topology key
 	AnnHATopologyKey = "pingcap.com/ha-topology-key"
@@ -81,6 +105,9 @@ const (
 	AnnPodNameKey string = "tidb.pingcap.com/pod-name"
 	// AnnPVCDeferDeleting is pvc defer deletion annotation key used in PVC for defer deleting PVC
 	AnnPVCDeferDeleting = "tidb.pingcap.com/pvc-defer-deleting"
+	// AnnPVCPreProvisionedZone is pvc annotation key of the zone planned for the pod whose pvc is pre-provisioned
+	// before the scale-out
+	AnnPVCPreProvisionedZone = "tidb.pingcap.com/pre-provisioned-zone"
 	// AnnPVCPodScheduling is pod scheduling annotation key, it represents whether the pod is scheduling
 	AnnPVCPodScheduling = "tidb.pingcap.com/pod-scheduling"
 	// AnnTiDBPartition is pod annotation which TiDB pod should upgrade to
@@ -89,19 +116,87 @@ const (
 	AnnTiKVPartition string = "tidb.pingcap.com/tikv-partition"
 	// AnnForceUpgradeKey is tc annotation key to indicate whether force upgrade should be done
 	AnnForceUpgradeKey = "tidb.pingcap.com/force-upgrade"
+	// AnnForceDeleteKey is tc annotation key to indicate whether to remove the deletion finalizer
+	// without the ordered teardown, e.g. the teardown is stuck
+	AnnForceDeleteKey = "tidb.pingcap.com/force-delete"
+	// AnnConfirmDeletionKey is the annotation key confirming the deletion of the objects with the deletion
+	// protection, its value must be the name of the object
+	AnnConfirmDeletionKey = "tidb.pingcap.com/confirm-deletion"
 	// AnnPDDeferDeleting is pd pod annotation key  in pod for defer for deleting pod
 	AnnPDDeferDeleting = "tidb.pingcap.com/pd-defer-deleting"
 	// AnnSysctlInit is pod annotation key to indicate whether configuring sysctls with init container
 	AnnSysctlInit = "tidb.pingcap.com/sysctl-init"
 	// AnnEvictLeaderBeginTime is pod annotation key to indicate the begin time for evicting region leader
 	AnnEvictLeaderBeginTime = "tidb.pingcap.com/evictLeaderBeginTime"
+	// AnnChangeRequestID is tc annotation key of the change request ID of the planned upgrade, it is
+	// propagated to the events and the traces of the upgrade and to the objects created for it as a label
+	AnnChangeRequestID = "tidb.pingcap.com/change-request-id"
+	// AnnUpgradePaused is tc annotation key to pause the upgrades in progress, the partitions of the StatefulSets
+	// are kept while it is set to "true" and the upgrades resume from the same ordinals once it is removed
+	AnnUpgradePaused = "tidb.pingcap.com/upgrade-paused"
+	// AnnAllowDowngrade is tc annotation key to allow the images of the components to be changed to a lower
+	// major or minor version, which are refused by the upgraders otherwise
+	AnnAllowDowngrade = "tidb.pingcap.com/allow-downgrade"
+	// AnnTierConfig is the annotation key of the ConfigMap of a component recording the config entries in JSON
+	// merged from the config fragment of the tier of the cluster
+	AnnTierConfig = "tidb.pingcap.com/tier-config"
+	// AnnIncrementalBase is the annotation key of the incremental backups created by a BackupSchedule recording
+	// the base backup of the incremental chain the backup belongs to
+	AnnIncrementalBase = "tidb.pingcap.com/incremental-base"
+	// AnnSchedulingGate is pod annotation key of the gate holding the pod from being scheduled by tidb-scheduler
+	AnnSchedulingGate = "tidb.pingcap.com/scheduling-gate"
+	// AnnExternalProvisioned is pod annotation key set by the external system to "true" once the provisioning for
+	// the pod completes, the gate of the external provisioning of the pod is removed then
+	AnnExternalProvisioned = "tidb.pingcap.com/external-provisioned"
 	// AnnStsLastSyncTimestamp is sts annotation key to indicate the last timestamp the operator sync the sts
 	AnnStsLastSyncTimestamp = "tidb.pingcap.com/sync-timestamp"
+	// AnnShutdownCheckpoint is tc annotation key of the step the previous tidb-controller-manager stopped at
+	// on shutdown, it is read and removed by the next leader
+	AnnShutdownCheckpoint = "tidb.pingcap.com/shutdown-checkpoint"
+	// AnnPDAutoTunedScheduling is tc annotation key of the scheduling params of PD last applied by
+	// spec.pd.autoTuneScheduling in JSON, a param whose live value differs from it is changed by the user
+	AnnPDAutoTunedScheduling = "tidb.pingcap.com/pd-auto-tuned-scheduling"
+	// AnnTiKVSlowStoreLeaderWeights is tc annotation key of the original and the reduced leader weights of the
+	// slow stores by spec.tikv.slowStoreMitigation in JSON, it is written before the leader weights are reduced
+	// so that the original leader weights are restored after the operator restarts
+	AnnTiKVSlowStoreLeaderWeights = "tidb.pingcap.com/tikv-slow-store-leader-weights"
+	// AnnPendingTemplateLabels is sts annotation key of the canonical labels missing in the pod template, they
+	// are applied along with the next rolling update instead of restarting the pods for the labels alone
+	AnnPendingTemplateLabels = "tidb.pingcap.com/pending-template-labels"
+	// AnnNodeMaintenance is node annotation key requesting the maintenance of the node, the pods of the TidbClusters
+	// on the node are drained one component at a time by the safe-removal procedure of each component while its
+	// value is AnnNodeMaintenanceDrainVal
+	AnnNodeMaintenance = "tidb.pingcap.com/maintenance"
+	// AnnNodeMaintenanceStatus is node annotation key of the progress of the maintenance of the node in JSON
+	AnnNodeMaintenanceStatus = "tidb.pingcap.com/maintenance-status"
+	// AnnPodNodeMaintenance is pod annotation key of the node in maintenance the pod is being drained for
+	AnnPodNodeMaintenance = "tidb.pingcap.com/node-maintenance"
+	// AnnRestart is tc annotation key of the comma separated components to restart gracefully, e.g. "tikv,tidb",
+	// it is removed after the restart generations of the components are increased
+	AnnRestart = "tidb.pingcap.com/restart"
+	// AnnRestartGeneration is pod template annotation key of the restart generation of the component, the pods
+	// are restarted by the upgraders of the components after it is increased
+	AnnRestartGeneration = "tidb.pingcap.com/restart-generation"
+	// AnnSecretSyncSource is secret annotation key of the source secret "<namespace>/<name>" the secret is copied
+	// from, the secrets without it are never overwritten by the copies
+	AnnSecretSyncSource = "tidb.pingcap.com/secret-sync-source"
 
 	// AnnForceUpgradeVal is tc annotation value to indicate whether force upgrade should be done
 	AnnForceUpgradeVal = "true"
+	// AnnForceDeleteVal is tc annotation value to indicate whether to remove the deletion finalizer
+	AnnForceDeleteVal = "true"
+	// AnnUpgradePausedVal is tc annotation value to pause the upgrades in progress
+	AnnUpgradePausedVal = "true"
+	// AnnAllowDowngradeVal is tc annotation value to allow the downgrades across the major and minor versions
+	AnnAllowDowngradeVal = "true"
 	// AnnSysctlInitVal is pod annotation value to indicate whether configuring sysctls with init container
 	AnnSysctlInitVal = "true"
+	// AnnSchedulingGateExternalProvisioningVal is pod annotation value of the gate of the external provisioning
+	AnnSchedulingGateExternalProvisioningVal = "external-provisioning"
+	// AnnExternalProvisionedVal is pod annotation value indicating the external provisioning completes
+	AnnExternalProvisionedVal = "true"
+	// AnnNodeMaintenanceDrainVal is node annotation value requesting the pods on the node to be drained
+	AnnNodeMaintenanceDrainVal = "drain"
 
 	// AnnPDDeleteSlots is annotation key of pd delete slots.
 	AnnPDDeleteSlots = "pd.tidb.pingcap.com/delete-slots"
@@ -109,6 +204,10 @@ const (
 	AnnTiDBDeleteSlots = "tidb.tidb.pingcap.com/delete-slots"
 	// AnnTiKVDeleteSlots is annotation key of tikv delete slots.
 	AnnTiKVDeleteSlots = "tikv.tidb.pingcap.com/delete-slots"
+	// AnnTiKVReplaceVolume is tc annotation key of the name of the TiKV pod whose pod and volumes are replaced, the
+	// store of the pod is deleted and the pod is recreated with new volumes by the StatefulSet keeping its ordinal,
+	// it is removed after the new store catches up
+	AnnTiKVReplaceVolume = "tikv.tidb.pingcap.com/replace-volume"
 	// AnnTiFlashDeleteSlots is annotation key of tiflash delete slots.
 	AnnTiFlashDeleteSlots = "tiflash.tidb.pingcap.com/delete-slots"
 	// AnnDMMasterDeleteSlots is annotation key of dm-master delete slots.
@@ -135,6 +234,10 @@ const (
 	DiscoveryLabelVal string = "discovery"
 	// TiDBMonitorVal is Monitor label value
 	TiDBMonitorVal string = "monitor"
+	// ImagePrepullLabelVal is image prepull label value
+	ImagePrepullLabelVal string = "image-prepull"
+	// PDDashboardAuthProxyLabelVal is the label value of the auth proxy of the TiDB Dashboard
+	PDDashboardAuthProxyLabelVal string = "pd-dashboard-proxy"
 
 	// CleanJobLabelVal is clean job label value
 	CleanJobLabelVal string = "clean"
@@ -412,6 +515,13 @@ func (l Label) TiDB() Label {
 	return l.Component(TiDBLabelVal)
 }
 
+// TiDBGroup assigns tidb to component key and the name of the group to the group key in label
+func (l Label) TiDBGroup(name string) Label {
+	l.TiDB()
+	l[TiDBGroupLabelKey] = name
+	return l
+}
+
 // IsTiDB returns whether label is a TiDB component
 func (l Label) IsTiDB() bool {
 	return l[ComponentLabelKey] == TiDBLabelVal
@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // Copyright PingCAP, Inc.
@@ -37,6 +38,7 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BackupScheduleList":            schema_pkg_apis_pingcap_v1alpha1_BackupScheduleList(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BackupScheduleSpec":            schema_pkg_apis_pingcap_v1alpha1_BackupScheduleSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BackupSpec":                    schema_pkg_apis_pingcap_v1alpha1_BackupSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BaseBackupRef":                 schema_pkg_apis_pingcap_v1alpha1_BaseBackupRef(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BasicAuth":                     schema_pkg_apis_pingcap_v1alpha1_BasicAuth(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BasicAutoScalerSpec":           schema_pkg_apis_pingcap_v1alpha1_BasicAutoScalerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BasicAutoScalerStatus":         schema_pkg_apis_pingcap_v1alpha1_BasicAutoScalerStatus(ref),
@@ -47,30 +49,40 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.CommonConfig":                  schema_pkg_apis_pingcap_v1alpha1_CommonConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ComponentSpec":                 schema_pkg_apis_pingcap_v1alpha1_ComponentSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ConfigMapRef":                  schema_pkg_apis_pingcap_v1alpha1_ConfigMapRef(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ConnectivityChecks":            schema_pkg_apis_pingcap_v1alpha1_ConnectivityChecks(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMCluster":                     schema_pkg_apis_pingcap_v1alpha1_DMCluster(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMClusterList":                 schema_pkg_apis_pingcap_v1alpha1_DMClusterList(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMClusterSpec":                 schema_pkg_apis_pingcap_v1alpha1_DMClusterSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMDiscoverySpec":               schema_pkg_apis_pingcap_v1alpha1_DMDiscoverySpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMExperimental":                schema_pkg_apis_pingcap_v1alpha1_DMExperimental(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardAuthProxy":            schema_pkg_apis_pingcap_v1alpha1_DashboardAuthProxy(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardConfig":               schema_pkg_apis_pingcap_v1alpha1_DashboardConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardIngressSpec":          schema_pkg_apis_pingcap_v1alpha1_DashboardIngressSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DeletionPolicy":                schema_pkg_apis_pingcap_v1alpha1_DeletionPolicy(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DiscoverySpec":                 schema_pkg_apis_pingcap_v1alpha1_DiscoverySpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DumplingConfig":                schema_pkg_apis_pingcap_v1alpha1_DumplingConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Experimental":                  schema_pkg_apis_pingcap_v1alpha1_Experimental(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalClusterTargets":        schema_pkg_apis_pingcap_v1alpha1_ExternalClusterTargets(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalConfig":                schema_pkg_apis_pingcap_v1alpha1_ExternalConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalEndpoint":              schema_pkg_apis_pingcap_v1alpha1_ExternalEndpoint(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalTargetGroup":           schema_pkg_apis_pingcap_v1alpha1_ExternalTargetGroup(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover":                      schema_pkg_apis_pingcap_v1alpha1_Failover(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FileLogConfig":                 schema_pkg_apis_pingcap_v1alpha1_FileLogConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FinalBackup":                   schema_pkg_apis_pingcap_v1alpha1_FinalBackup(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Flash":                         schema_pkg_apis_pingcap_v1alpha1_Flash(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FlashCluster":                  schema_pkg_apis_pingcap_v1alpha1_FlashCluster(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FlashLogger":                   schema_pkg_apis_pingcap_v1alpha1_FlashLogger(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FlashProxy":                    schema_pkg_apis_pingcap_v1alpha1_FlashProxy(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FlashSecurity":                 schema_pkg_apis_pingcap_v1alpha1_FlashSecurity(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FlashServerConfig":             schema_pkg_apis_pingcap_v1alpha1_FlashServerConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ForceDeleteStuckPods":          schema_pkg_apis_pingcap_v1alpha1_ForceDeleteStuckPods(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GcsStorageProvider":            schema_pkg_apis_pingcap_v1alpha1_GcsStorageProvider(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HelperSpec":                    schema_pkg_apis_pingcap_v1alpha1_HelperSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HostPortAllocation":            schema_pkg_apis_pingcap_v1alpha1_HostPortAllocation(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.IngressSpec":                   schema_pkg_apis_pingcap_v1alpha1_IngressSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.InitContainerSpec":             schema_pkg_apis_pingcap_v1alpha1_InitContainerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.IsolationRead":                 schema_pkg_apis_pingcap_v1alpha1_IsolationRead(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.JobHistoryLimit":               schema_pkg_apis_pingcap_v1alpha1_JobHistoryLimit(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Log":                           schema_pkg_apis_pingcap_v1alpha1_Log(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec":                 schema_pkg_apis_pingcap_v1alpha1_LogTailerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MasterConfig":                  schema_pkg_apis_pingcap_v1alpha1_MasterConfig(ref),
@@ -80,6 +92,9 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MetadataConfig":                schema_pkg_apis_pingcap_v1alpha1_MetadataConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MonitorContainer":              schema_pkg_apis_pingcap_v1alpha1_MonitorContainer(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NGMonitoringSpec":              schema_pkg_apis_pingcap_v1alpha1_NGMonitoringSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NetworkingSpec":                schema_pkg_apis_pingcap_v1alpha1_NetworkingSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NodeBinding":                   schema_pkg_apis_pingcap_v1alpha1_NodeBinding(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Notification":                  schema_pkg_apis_pingcap_v1alpha1_Notification(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.OpenTracing":                   schema_pkg_apis_pingcap_v1alpha1_OpenTracing(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.OpenTracingReporter":           schema_pkg_apis_pingcap_v1alpha1_OpenTracingReporter(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.OpenTracingSampler":            schema_pkg_apis_pingcap_v1alpha1_OpenTracingSampler(ref),
@@ -107,13 +122,17 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RelabelConfig":                 schema_pkg_apis_pingcap_v1alpha1_RelabelConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RemoteWriteSpec":               schema_pkg_apis_pingcap_v1alpha1_RemoteWriteSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Restore":                       schema_pkg_apis_pingcap_v1alpha1_Restore(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillCheck":             schema_pkg_apis_pingcap_v1alpha1_RestoreDrillCheck(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillSpec":              schema_pkg_apis_pingcap_v1alpha1_RestoreDrillSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreList":                   schema_pkg_apis_pingcap_v1alpha1_RestoreList(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreRenameMapping":          schema_pkg_apis_pingcap_v1alpha1_RestoreRenameMapping(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreSpec":                   schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.S3StorageProvider":             schema_pkg_apis_pingcap_v1alpha1_S3StorageProvider(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.SafeTLSConfig":                 schema_pkg_apis_pingcap_v1alpha1_SafeTLSConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.SecretRef":                     schema_pkg_apis_pingcap_v1alpha1_SecretRef(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Security":                      schema_pkg_apis_pingcap_v1alpha1_Security(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ServiceSpec":                   schema_pkg_apis_pingcap_v1alpha1_ServiceSpec(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing":                        schema_pkg_apis_pingcap_v1alpha1_Sizing(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Status":                        schema_pkg_apis_pingcap_v1alpha1_Status(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StmtSummary":                   schema_pkg_apis_pingcap_v1alpha1_StmtSummary(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageClaim":                  schema_pkg_apis_pingcap_v1alpha1_StorageClaim(ref),
@@ -123,7 +142,10 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiCDCSpec":                     schema_pkg_apis_pingcap_v1alpha1_TiCDCSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBAccessConfig":              schema_pkg_apis_pingcap_v1alpha1_TiDBAccessConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBConfig":                    schema_pkg_apis_pingcap_v1alpha1_TiDBConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBGroup":                     schema_pkg_apis_pingcap_v1alpha1_TiDBGroup(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProbe":                     schema_pkg_apis_pingcap_v1alpha1_TiDBProbe(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProxyProtocol":             schema_pkg_apis_pingcap_v1alpha1_TiDBProxyProtocol(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBScaleInDrain":              schema_pkg_apis_pingcap_v1alpha1_TiDBScaleInDrain(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBServiceSpec":               schema_pkg_apis_pingcap_v1alpha1_TiDBServiceSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSlowLogTailerSpec":         schema_pkg_apis_pingcap_v1alpha1_TiDBSlowLogTailerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSpec":                      schema_pkg_apis_pingcap_v1alpha1_TiDBSpec(ref),
@@ -140,6 +162,7 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVDbConfig":                  schema_pkg_apis_pingcap_v1alpha1_TiKVDbConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVEncryptionConfig":          schema_pkg_apis_pingcap_v1alpha1_TiKVEncryptionConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVGCConfig":                  schema_pkg_apis_pingcap_v1alpha1_TiKVGCConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVHugepages":                 schema_pkg_apis_pingcap_v1alpha1_TiKVHugepages(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVImportConfig":              schema_pkg_apis_pingcap_v1alpha1_TiKVImportConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVMasterKeyConfig":           schema_pkg_apis_pingcap_v1alpha1_TiKVMasterKeyConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVPDConfig":                  schema_pkg_apis_pingcap_v1alpha1_TiKVPDConfig(ref),
@@ -149,12 +172,15 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVReadPoolConfig":            schema_pkg_apis_pingcap_v1alpha1_TiKVReadPoolConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSecurityConfig":            schema_pkg_apis_pingcap_v1alpha1_TiKVSecurityConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVServerConfig":              schema_pkg_apis_pingcap_v1alpha1_TiKVServerConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSlowStoreMitigation":       schema_pkg_apis_pingcap_v1alpha1_TiKVSlowStoreMitigation(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSpec":                      schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVStorageConfig":             schema_pkg_apis_pingcap_v1alpha1_TiKVStorageConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVStorageReadPoolConfig":     schema_pkg_apis_pingcap_v1alpha1_TiKVStorageReadPoolConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVTitanCfConfig":             schema_pkg_apis_pingcap_v1alpha1_TiKVTitanCfConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVTitanDBConfig":             schema_pkg_apis_pingcap_v1alpha1_TiKVTitanDBConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUnifiedReadPoolConfig":     schema_pkg_apis_pingcap_v1alpha1_TiKVUnifiedReadPoolConfig(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeSafetyChecks":       schema_pkg_apis_pingcap_v1alpha1_TiKVUpgradeSafetyChecks(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeStabilization":      schema_pkg_apis_pingcap_v1alpha1_TiKVUpgradeStabilization(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbAutoScalerSpec":            schema_pkg_apis_pingcap_v1alpha1_TidbAutoScalerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbAutoScalerStatus":          schema_pkg_apis_pingcap_v1alpha1_TidbAutoScalerStatus(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbCluster":                   schema_pkg_apis_pingcap_v1alpha1_TidbCluster(ref),
@@ -180,6 +206,7 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TikvAutoScalerSpec":            schema_pkg_apis_pingcap_v1alpha1_TikvAutoScalerSpec(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TikvAutoScalerStatus":          schema_pkg_apis_pingcap_v1alpha1_TikvAutoScalerStatus(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TxnLocalLatches":               schema_pkg_apis_pingcap_v1alpha1_TxnLocalLatches(ref),
+		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook":      schema_pkg_apis_pingcap_v1alpha1_UpgradeCompletionWebhook(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.WorkerConfig":                  schema_pkg_apis_pingcap_v1alpha1_WorkerConfig(ref),
 		"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.WorkerSpec":                    schema_pkg_apis_pingcap_v1alpha1_WorkerSpec(ref),
 		"k8s.io/api/core/v1.AWSElasticBlockStoreVolumeSource":                                      schema_k8sio_api_core_v1_AWSElasticBlockStoreVolumeSource(ref),
@@ -909,12 +936,43 @@ func schema_pkg_apis_pingcap_v1alpha1_BackupScheduleSpec(ref common.ReferenceCal
 							},
 						},
 					},
+					"timeZone": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TimeZone is the IANA time zone name, such as \"Asia/Shanghai\", in which the Schedule is evaluated. On the daylight saving time transitions, a schedule time in the repeated hour runs only once, and a schedule time in the skipped hour runs once after the clock jumps forward. Defaults to the local time zone of tidb-controller-manager.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"restoreDrill": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RestoreDrill periodically restores the most recent backup into an ephemeral TidbCluster to verify the backups are usable.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillSpec"),
+						},
+					},
+					"baseBackupRef": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BaseBackupRef is the full backup taken out of the schedule, such as by another tool, the backups of the schedule are incremental on. Each backup is incremental on the last completed backup of the chain starting from the base, and the base is never deleted by the backup GC of the schedule. Only supported for the backups made by BR.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BaseBackupRef"),
+						},
+					},
+					"jobHistoryLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "JobHistoryLimit is the number of the jobs of the finished backups of the schedule kept, it takes precedence over the one in the backupTemplate.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.JobHistoryLimit"),
+						},
+					},
+					"notification": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notification of the backups created by the schedule, the one in the backupTemplate takes precedence over it.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Notification"),
+						},
+					},
 				},
 				Required: []string{"schedule", "backupTemplate"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BackupSpec", "k8s.io/api/core/v1.LocalObjectReference"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BackupSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BaseBackupRef", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.JobHistoryLimit", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Notification", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillSpec", "k8s.io/api/core/v1.LocalObjectReference"},
 	}
 }
 
@@ -1056,12 +1114,41 @@ func schema_pkg_apis_pingcap_v1alpha1_BackupSpec(ref common.ReferenceCallback) c
 							},
 						},
 					},
+					"includeClusterSettings": {
+						SchemaProps: spec.SchemaProps{
+							Description: "IncludeClusterSettings dumps the scheduling config of PD, the global variables and the placement policies of TiDB into settings.json in the backup after the data is backed up, so that they can be restored with the data. The global variables and the placement policies are dumped only if spec.from is set.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"uploadToolLogs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UploadToolLogs uploads the full log of BR or dumpling to operator-logs/ under the backup path in the same storage when the backup ends, a failure of the upload does not fail the backup.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"affinity": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Affinity of backup Pods",
 							Ref:         ref("k8s.io/api/core/v1.Affinity"),
 						},
 					},
+					"nodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeSelector of backup Pods, defaults to the job node selector of tidb-operator if empty",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
 					"useKMS": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Use KMS to decrypt the secrets",
@@ -1102,11 +1189,71 @@ func schema_pkg_apis_pingcap_v1alpha1_BackupSpec(ref common.ReferenceCallback) c
 							Format:      "",
 						},
 					},
+					"deletionProtection": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeletionProtection protects the Backup from accidental deletion, e.g. the backup data referenced by the retention policies. The Backup being deleted stays terminating and its data is not cleaned until it is annotated with tidb.pingcap.com/confirm-deletion=<name of the Backup>. Optional: Defaults to the -deletion-protection of tidb-controller-manager",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"jobHistoryLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "JobHistoryLimit is the number of the jobs of the finished backups kept, the jobs and the pods of the older ones are deleted. It applies to the backups not created by a BackupSchedule in the namespace, the limit of the most recently created one is used.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.JobHistoryLimit"),
+						},
+					},
+					"ttlSecondsAfterFinished": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TTLSecondsAfterFinished is the ttlSecondsAfterFinished of the backup job, the job and its pods are deleted by Kubernetes after it finishes. The TTL of the job of the most recent failed backup is removed if the jobHistoryLimit applies, so that it is kept for debugging.",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"notification": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notification posts the completion and the failure of the backup to a webhook",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Notification"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.AzblobStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BRConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.CleanOption", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DumplingConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GcsStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LocalStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.S3StorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBAccessConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.AzblobStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BRConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.CleanOption", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DumplingConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GcsStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.JobHistoryLimit", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LocalStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Notification", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.S3StorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBAccessConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_BaseBackupRef(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "BaseBackupRef refers to the full backup the incremental backups of a BackupSchedule are based on, either a Backup or a storage path with the commit ts of the backup.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the Backup in the namespace of the BackupSchedule. Either Name or Path must be set.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"path": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Path is the storage path of the base backup, such as \"s3://bucket/prefix\".",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"commitTs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CommitTs is the commit ts of the base backup at Path, it is required if Path is set.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
 	}
 }
 
@@ -1566,7 +1713,7 @@ func schema_pkg_apis_pingcap_v1alpha1_ComponentSpec(ref common.ReferenceCallback
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -1579,7 +1726,7 @@ func schema_pkg_apis_pingcap_v1alpha1_ComponentSpec(ref common.ReferenceCallback
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -1697,11 +1844,31 @@ func schema_pkg_apis_pingcap_v1alpha1_ComponentSpec(ref common.ReferenceCallback
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount"},
 	}
 }
 
@@ -1731,6 +1898,33 @@ func schema_pkg_apis_pingcap_v1alpha1_ConfigMapRef(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_ConnectivityChecks(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ConnectivityChecks is the deep probe of the connectivity between the components",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"enabled": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Enabled is whether the connectivity is probed",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"interval": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Interval between the probes, in the format of Go Duration. Optional: Defaults to 5m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_DMCluster(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2055,6 +2249,13 @@ func schema_pkg_apis_pingcap_v1alpha1_DMClusterSpec(ref common.ReferenceCallback
 							},
 						},
 					},
+					"deletionProtection": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeletionProtection protects the DMCluster from accidental deletion, the DMCluster being deleted stays terminating with its components running until it is annotated with tidb.pingcap.com/confirm-deletion=<name of the DMCluster>. Optional: Defaults to the -deletion-protection of tidb-controller-manager",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 				},
 			},
 		},
@@ -2126,6 +2327,41 @@ func schema_pkg_apis_pingcap_v1alpha1_DMExperimental(ref common.ReferenceCallbac
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_DashboardAuthProxy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DashboardAuthProxy describes the auth proxy of the TiDB Dashboard",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type of the auth proxy, `basic-auth` or `oauth2-proxy`. `basic-auth` requires ingress-nginx, the secret must contain the key `auth` in the htpasswd format. `oauth2-proxy` deploys oauth2-proxy in front of PD, the keys of the secret are set as the envs of oauth2-proxy, e.g. OAUTH2_PROXY_CLIENT_ID, OAUTH2_PROXY_CLIENT_SECRET and OAUTH2_PROXY_COOKIE_SECRET.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"secretName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SecretName is the name of the secret holding the configuration of the auth proxy",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image of oauth2-proxy Optional: Defaults to quay.io/oauth2-proxy/oauth2-proxy:v7.2.1",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"type", "secretName"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_DashboardConfig(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2183,6 +2419,86 @@ func schema_pkg_apis_pingcap_v1alpha1_DashboardConfig(ref common.ReferenceCallba
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_DashboardIngressSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DashboardIngressSpec describes the Ingress of the TiDB Dashboard",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"host": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Host of the Ingress, the dashboard is served at `<host>/dashboard/`",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"ingressClassName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "IngressClassName of the Ingress",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"annotations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Annotations of the Ingress",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"tlsSecretName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TLSSecretName is the name of the secret holding the certificate of the host, the dashboard is served over HTTP if it is empty",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"authProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AuthProxy authenticates the requests before they reach the dashboard",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardAuthProxy"),
+						},
+					},
+				},
+				Required: []string{"host"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardAuthProxy",
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_DeletionPolicy(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DeletionPolicy is the policy of deleting a TidbCluster",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"finalBackup": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FinalBackup is the backup taken before the TidbCluster is torn down",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FinalBackup"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.FinalBackup"},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_DiscoverySpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2294,6 +2610,48 @@ func schema_pkg_apis_pingcap_v1alpha1_Experimental(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_ExternalClusterTargets(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ExternalClusterTargets are the statically-defined targets of a TiDB cluster not managed by TiDB Operator",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the cluster, it is the value of the `cluster` and `tidb_cluster` labels of the metrics. It must be distinct from the names of the monitored TidbClusters.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"pd": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PD targets of the cluster",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalTargetGroup"),
+						},
+					},
+					"tidb": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TiDB targets of the cluster",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalTargetGroup"),
+						},
+					},
+					"tikv": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TiKV targets of the cluster",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalTargetGroup"),
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalTargetGroup",
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_ExternalConfig(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2366,6 +2724,56 @@ func schema_pkg_apis_pingcap_v1alpha1_ExternalEndpoint(ref common.ReferenceCallb
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_ExternalTargetGroup(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ExternalTargetGroup is a group of the statically-defined targets of a component",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"targets": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Targets are the status addresses of the instances in the form of host:port",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"tlsSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TLSSecret is the name of the Secret in the namespace of the TidbMonitor holding the client certificate `tls.crt`, `tls.key` and the CA `ca.crt` to scrape the targets over HTTPS. The targets are scraped over HTTP if it is empty.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"labels": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Labels are added to the metrics of the targets, the labels set by TidbMonitor can not be overridden",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"targets"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_Failover(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2433,6 +2841,34 @@ func schema_pkg_apis_pingcap_v1alpha1_FileLogConfig(ref common.ReferenceCallback
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_FinalBackup(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "FinalBackup describes the backup taken before deleting a TidbCluster",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"backupScheduleName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackupScheduleName is the name of the BackupSchedule in the namespace of the TidbCluster, its backupTemplate is used to create the final Backup.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout after which the teardown starts even if the final Backup is not complete, in the format of Go Duration. Optional: Defaults to 1h",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"backupScheduleName"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_Flash(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2854,6 +3290,26 @@ func schema_pkg_apis_pingcap_v1alpha1_FlashServerConfig(ref common.ReferenceCall
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_ForceDeleteStuckPods(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ForceDeleteStuckPods is the force deletion of the pods stuck in Terminating on the unreachable nodes. The StatefulSet does not recreate a pod until the pod is deleted, which never happens if the kubelet of its node is unreachable.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"threshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Threshold is how long a pod must have been Terminating past its graceful termination deadline before it is force deleted, in the format of Go Duration. Optional: Defaults to 5m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_GcsStorageProvider(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -2958,6 +3414,33 @@ func schema_pkg_apis_pingcap_v1alpha1_HelperSpec(ref common.ReferenceCallback) c
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_HostPortAllocation(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "HostPortAllocation is the range the host ports of a component are allocated from, each cluster is allocated a distinct slot of the range when it joins the host network.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"base": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Base is the first port of the range Optional: Defaults to 20160",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"maxSlots": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxSlots is the max number of the clusters sharing the range Optional: Defaults to 64",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_IngressSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -3086,6 +3569,33 @@ func schema_pkg_apis_pingcap_v1alpha1_IsolationRead(ref common.ReferenceCallback
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_JobHistoryLimit(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "JobHistoryLimit is the number of the jobs of the finished backups kept, the job of the most recent failed backup is always kept for debugging and the jobs of the backups not finished are never deleted",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"successful": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Successful is the number of the jobs of the complete backups kept. Optional: Defaults to unlimited",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"failed": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Failed is the number of the jobs of the failed backups kept. Optional: Defaults to unlimited",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_Log(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -3497,7 +4007,7 @@ func schema_pkg_apis_pingcap_v1alpha1_MasterSpec(ref common.ReferenceCallback) c
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -3510,7 +4020,7 @@ func schema_pkg_apis_pingcap_v1alpha1_MasterSpec(ref common.ReferenceCallback) c
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -3628,6 +4138,26 @@ func schema_pkg_apis_pingcap_v1alpha1_MasterSpec(ref common.ReferenceCallback) c
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -3715,7 +4245,7 @@ func schema_pkg_apis_pingcap_v1alpha1_MasterSpec(ref common.ReferenceCallback) c
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MasterConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MasterServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MasterConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.MasterServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -3941,7 +4471,7 @@ func schema_pkg_apis_pingcap_v1alpha1_NGMonitoringSpec(ref common.ReferenceCallb
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -3954,7 +4484,7 @@ func schema_pkg_apis_pingcap_v1alpha1_NGMonitoringSpec(ref common.ReferenceCallb
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -4072,72 +4602,205 @@ func schema_pkg_apis_pingcap_v1alpha1_NGMonitoringSpec(ref common.ReferenceCallb
 							},
 						},
 					},
-					"limits": {
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"limits": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"requests": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Requests describes the minimum amount of compute resources required. If Requests is omitted for a container, it defaults to Limits if that is explicitly specified, otherwise to an implementation-defined value. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"baseImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Base image of the component, image tag is now allowed during validation",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storageClassName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageClassName is the persistent volume for ng monitoring. Defaults to Kubernetes default storage class.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storageVolumes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageVolumes configures additional storage for NG Monitoring pods.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume"),
+									},
+								},
+							},
+						},
+					},
+					"config": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Config is the configuration of ng monitoring",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_NetworkingSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NetworkingSpec is the networking of the client traffic to the components",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"topologyAwareRouting": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TopologyAwareRouting makes the clients prefer the PD and TiKV endpoints in their own zones to cut the cross-zone traffic. The PD service and the peer services of PD and TiKV are annotated for the topology aware routing of kube-proxy, and a headless service is created for each zone the PD and TiKV pods are in, which selects the pods by the zones of their nodes. It only affects the clients resolving PD and TiKV through these services. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_NodeBinding(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NodeBinding binds the pod of an ordinal to a node by its name or by a node selector. The pod is not started on the other nodes, so its volumes must be usable on the node.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"ordinal": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Ordinal of the pod",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"nodeName": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
-							Type:        []string{"object"},
-							AdditionalProperties: &spec.SchemaOrBool{
-								Allows: true,
-								Schema: &spec.Schema{
-									SchemaProps: spec.SchemaProps{
-										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
-									},
-								},
-							},
+							Description: "NodeName is the name of the node the pod is bound to",
+							Type:        []string{"string"},
+							Format:      "",
 						},
 					},
-					"requests": {
+					"nodeSelector": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Requests describes the minimum amount of compute resources required. If Requests is omitted for a container, it defaults to Limits if that is explicitly specified, otherwise to an implementation-defined value. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
+							Description: "NodeSelector selects the nodes the pod is bound to by their labels, only one of nodeName and nodeSelector can be set",
 							Type:        []string{"object"},
 							AdditionalProperties: &spec.SchemaOrBool{
 								Allows: true,
 								Schema: &spec.Schema{
 									SchemaProps: spec.SchemaProps{
-										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+										Type:   []string{"string"},
+										Format: "",
 									},
 								},
 							},
 						},
 					},
-					"baseImage": {
+				},
+				Required: []string{"ordinal"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_Notification(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "Notification is the webhook notified of the state transitions of a Backup or a Restore. tidb-operator sends a POST request with a JSON payload of the namespace, the name, the phase, the size, the commit ts, the duration and the error, and any 2xx response means success. The failed requests are retried, and a webhook failing repeatedly is not requested for a while.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"url": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Base image of the component, image tag is now allowed during validation",
+							Description: "URL of the webhook",
 							Type:        []string{"string"},
 							Format:      "",
 						},
 					},
-					"storageClassName": {
+					"authSecretRef": {
 						SchemaProps: spec.SchemaProps{
-							Description: "StorageClassName is the persistent volume for ng monitoring. Defaults to Kubernetes default storage class.",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "AuthSecretRef is the key of the secret in the namespace whose value is sent as the bearer token in the Authorization header, it is never included in the payload",
+							Ref:         ref("k8s.io/api/core/v1.SecretKeySelector"),
 						},
 					},
-					"storageVolumes": {
+					"eventTypes": {
 						SchemaProps: spec.SchemaProps{
-							Description: "StorageVolumes configures additional storage for NG Monitoring pods.",
+							Description: "EventTypes are the types of the events notified, Complete or Failed. Optional: Defaults to all",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
 									SchemaProps: spec.SchemaProps{
-										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume"),
+										Type:   []string{"string"},
+										Format: "",
 									},
 								},
 							},
 						},
 					},
-					"config": {
+					"cloudEvents": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Config is the configuration of ng monitoring",
-							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig"),
+							Description: "CloudEvents sends the payload as the data of a CloudEvent of the version 1.0 in the structured content mode, rather than as the body",
+							Type:        []string{"boolean"},
+							Format:      "",
 						},
 					},
 				},
+				Required: []string{"url"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"k8s.io/api/core/v1.SecretKeySelector"},
 	}
 }
 
@@ -5090,7 +5753,7 @@ func schema_pkg_apis_pingcap_v1alpha1_PDSpec(ref common.ReferenceCallback) commo
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -5103,7 +5766,7 @@ func schema_pkg_apis_pingcap_v1alpha1_PDSpec(ref common.ReferenceCallback) commo
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -5221,6 +5884,26 @@ func schema_pkg_apis_pingcap_v1alpha1_PDSpec(ref common.ReferenceCallback) commo
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -5344,12 +6027,66 @@ func schema_pkg_apis_pingcap_v1alpha1_PDSpec(ref common.ReferenceCallback) commo
 							Format:      "",
 						},
 					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused stops reconciling PD while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"upgradeCompletionWebhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeCompletionWebhook is called when the upgrade of PD is done, PD stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
+						},
+					},
+					"dashboardIngress": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DashboardIngress exposes the TiDB Dashboard served by PD with an Ingress, the Ingress is removed if it is not set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardIngressSpec"),
+						},
+					},
+					"autoTuneScheduling": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AutoTuneScheduling tunes the scheduling params of PD by the number of the stores and the average region size, including the schedule limits, the store limit and the patrol region interval. A param is only applied when it differs from the live value by more than 20%, and it is no longer tuned once it is changed by the user or set in the config. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"memberPriorities": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MemberPriorities are the leader priorities of the PD members by the zones of their nodes, the key is the value of the `topology.kubernetes.io/zone` label of the node, or the `failure-domain.beta.kubernetes.io/zone` label if the former is absent. PD prefers the member with the higher priority as the leader. The priorities are applied to the members after they join and the members in the zones not here have the priority 0. The priorities are left as they are if it is not set.",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"integer"},
+										Format: "int32",
+									},
+								},
+							},
+						},
+					},
+					"nodeBindings": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeBindings pin the pods of the ordinals to the nodes by the required node affinity, so that they come up on the nodes of their local volumes after the whole cluster restarts. Every ordinal in [0, replicas) must be bound. The affinity is injected when the pods are created by the pod mutating webhook of the admission webhook, which must be enabled by `admissionWebhook.mutation.pods`. A pod not on the node of its binding is moved by deleting it once the members are all healthy, one pod at a time, the leader is transferred away first.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NodeBinding"),
+									},
+								},
+							},
+						},
+					},
 				},
 				Required: []string{"replicas"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PDConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DashboardIngressSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NodeBinding", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PDConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -5921,7 +6658,7 @@ func schema_pkg_apis_pingcap_v1alpha1_PumpSpec(ref common.ReferenceCallback) com
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -5934,7 +6671,7 @@ func schema_pkg_apis_pingcap_v1alpha1_PumpSpec(ref common.ReferenceCallback) com
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -6052,6 +6789,26 @@ func schema_pkg_apis_pingcap_v1alpha1_PumpSpec(ref common.ReferenceCallback) com
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -6114,12 +6871,25 @@ func schema_pkg_apis_pingcap_v1alpha1_PumpSpec(ref common.ReferenceCallback) com
 							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig"),
 						},
 					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused stops reconciling Pump while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"upgradeCompletionWebhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeCompletionWebhook is called when the upgrade of Pump is done, Pump stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
+						},
+					},
 				},
 				Required: []string{"replicas"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "github.com/pingcap/tidb-operator/pkg/apis/util/config.GenericConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -6403,6 +7173,98 @@ func schema_pkg_apis_pingcap_v1alpha1_Restore(ref common.ReferenceCallback) comm
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_RestoreDrillCheck(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RestoreDrillCheck is a SQL check run by the restore drill",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name is the name of the check",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"sql": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SQL is the query of the check, it must return at least one row.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"expected": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Expected is the expected value of the first column of the first row returned. If it is not set, the check passes as long as a row is returned.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name", "sql"},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_RestoreDrillSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RestoreDrillSpec describes the drill restoring the most recent completed backup of a BackupSchedule into an ephemeral TidbCluster, which is removed after the verification checks are run. Only one drill of a BackupSchedule runs at a time.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"schedule": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Schedule specifies the cron string used for drill scheduling, it is evaluated in the time zone of the BackupSchedule.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace is the namespace the ephemeral TidbCluster and the Restore are created in. The secrets used to access the backup storage must exist in the namespace. Defaults to the namespace of the BackupSchedule.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"clusterTemplate": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ClusterTemplate is the spec of the ephemeral TidbCluster the backup is restored into. PD, TiKV and TiDB are required and the cpu and memory limits of all the components must be set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterSpec"),
+						},
+					},
+					"checks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Checks are the SQL queries run in the ephemeral TidbCluster after the backup is restored, the drill passes if all the checks pass.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillCheck"),
+									},
+								},
+							},
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout is the max duration of a drill, such as \"2h\". Defaults to 6h.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"schedule", "clusterTemplate"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreDrillCheck", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterSpec",
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_RestoreList(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -6445,6 +7307,34 @@ func schema_pkg_apis_pingcap_v1alpha1_RestoreList(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_RestoreRenameMapping(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RestoreRenameMapping maps a table or a database in the backup to the one it is restored as",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"source": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Source is the 'db.table' in the backup, or 'db.*' to rename the database",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"target": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Target is the 'db.table' the source is restored as, or 'db.*' if the source is a database",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"source", "target"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -6549,6 +7439,21 @@ func schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref common.ReferenceCallback)
 							Ref:         ref("k8s.io/api/core/v1.Affinity"),
 						},
 					},
+					"nodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeSelector of restore Pods, defaults to the job node selector of tidb-operator if empty",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
 					"useKMS": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Use KMS to decrypt the secrets",
@@ -6565,27 +7470,68 @@ func schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref common.ReferenceCallback)
 					},
 					"toolImage": {
 						SchemaProps: spec.SchemaProps{
-							Description: "ToolImage specifies the tool image used in `Restore`, which supports BR and TiDB Lightning images. For examples `spec.toolImage: pingcap/br:v4.0.8` or `spec.toolImage: pingcap/tidb-lightning:v4.0.8` For BR image, if it does not contain tag, Pod will use image 'ToolImage:${TiKV_Version}'.",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "ToolImage specifies the tool image used in `Restore`, which supports BR and TiDB Lightning images. For examples `spec.toolImage: pingcap/br:v4.0.8` or `spec.toolImage: pingcap/tidb-lightning:v4.0.8` For BR image, if it does not contain tag, Pod will use image 'ToolImage:${TiKV_Version}'.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"imagePullSecrets": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImagePullSecrets is an optional list of references to secrets in the same namespace to use for pulling any of the images.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.LocalObjectReference"),
+									},
+								},
+							},
+						},
+					},
+					"tableFilter": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TableFilter means Table filter expression for 'db.table' matching. BR supports this from v4.0.3.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
 						},
 					},
-					"imagePullSecrets": {
+					"renameMapping": {
 						SchemaProps: spec.SchemaProps{
-							Description: "ImagePullSecrets is an optional list of references to secrets in the same namespace to use for pulling any of the images.",
+							Description: "RenameMapping restores the tables or databases matched by the table filter under other names, so that they can be restored into the same cluster. It is not supported if the whole cluster is restored.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
 									SchemaProps: spec.SchemaProps{
-										Ref: ref("k8s.io/api/core/v1.LocalObjectReference"),
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreRenameMapping"),
 									},
 								},
 							},
 						},
 					},
-					"tableFilter": {
+					"overwrite": {
 						SchemaProps: spec.SchemaProps{
-							Description: "TableFilter means Table filter expression for 'db.table' matching. BR supports this from v4.0.3.",
+							Description: "Overwrite allows the targets of RenameMapping to collide with the existing tables, the restore fails if any target exists and it is false.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"restoreClusterSettings": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RestoreClusterSettings applies the cluster settings in settings.json of the backup after the data is restored. The settings referring to the identity, the addresses or the local paths of the source cluster are never applied. The global variables and the placement policies are applied only if spec.to is set.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"excludeClusterSettings": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExcludeClusterSettings are the keys of the cluster settings not to be applied or the glob patterns of them, e.g. pd:schedule.* or variable:tidb_mem_quota_query",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -6597,6 +7543,27 @@ func schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref common.ReferenceCallback)
 							},
 						},
 					},
+					"uploadToolLogs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UploadToolLogs uploads the full log of BR or lightning to operator-logs/ under the backup path in the same storage when the restore ends, a failure of the upload does not fail the restore.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"pitrRestoredTs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PitrRestoredTs is the TSO a point-in-time restore restores the cluster to, the log backup at pitrLogBackupPrefix is replayed on the full backup of the restore up to it. The restore fails before BR runs if the log backup does not cover the range from the full backup to it continuously. Only supported by BR.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"pitrLogBackupPrefix": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PitrLogBackupPrefix is the prefix of the log backup in the bucket of the full backup of the restore, it is required if pitrRestoredTs is set.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 					"podSecurityContext": {
 						SchemaProps: spec.SchemaProps{
 							Description: "PodSecurityContext of the component",
@@ -6614,7 +7581,7 @@ func schema_pkg_apis_pingcap_v1alpha1_RestoreSpec(ref common.ReferenceCallback)
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.AzblobStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BRConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GcsStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LocalStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.S3StorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBAccessConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.AzblobStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.BRConfig", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GcsStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LocalStorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.RestoreRenameMapping", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.S3StorageProvider", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBAccessConfig", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
 	}
 }
 
@@ -6936,6 +7903,51 @@ func schema_pkg_apis_pingcap_v1alpha1_ServiceSpec(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_Sizing(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "Sizing declares the resources of a component by a single size",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"size": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Size of the component, e.g. `cpu: 4` and `memory: 16Gi`, the storage is not derived",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"sizeIs": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SizeIs is what the size is, Limits or Requests. If the size is the limits, the requests are the size multiplied by requestsRatio. If the size is the requests, the limits are the size divided by requestsRatio. Optional: Defaults to Limits",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"requestsRatio": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RequestsRatio is the ratio of the requests to the limits, a decimal in (0, 1], e.g. \"0.5\". The derived cpu is rounded to millicores and the others to integers, the requests are rounded down and the limits are rounded up. Optional: Defaults to \"1\"",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"size"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity",
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_Status(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -7337,7 +8349,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiCDCSpec(ref common.ReferenceCallback) co
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -7350,7 +8362,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiCDCSpec(ref common.ReferenceCallback) co
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -7468,6 +8480,26 @@ func schema_pkg_apis_pingcap_v1alpha1_TiCDCSpec(ref common.ReferenceCallback) co
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -7557,12 +8589,40 @@ func schema_pkg_apis_pingcap_v1alpha1_TiCDCSpec(ref common.ReferenceCallback) co
 							Format:      "",
 						},
 					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused stops reconciling TiCDC while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"upgradeCompletionWebhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeCompletionWebhook is called when the upgrade of TiCDC is done, TiCDC stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
+						},
+					},
+					"changefeedPriorities": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ChangefeedPriorities are the priorities of the changefeeds keyed by the changefeed IDs, the changefeeds not listed have the priority 0. If it is set, the captures are restarted one by one in the ascending order of the highest priority of the changefeeds they own during upgrades, so that the captures owning the critical changefeeds are restarted last.",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"integer"},
+										Format: "int32",
+									},
+								},
+							},
+						},
+					},
 				},
 				Required: []string{"replicas"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.CDCConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.CDCConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -7926,6 +8986,91 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBConfig(ref common.ReferenceCallback) c
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_TiDBGroup(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiDBGroup is a named group of TiDB servers, the fields not set are inherited from spec.tidb. The pods of the group are scaled and upgraded like the ones of spec.tidb, they are not failed over.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the group, it must be a DNS label starting with a letter and must not be \"peer\"",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"replicas": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The desired ready replicas of the group",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"config": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Config of the tidb-servers of the group, spec.tidb.config is used if it is not set",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBConfigWraper"),
+						},
+					},
+					"limits": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"requests": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Requests describes the minimum amount of compute resources required. If Requests is omitted for a container, it defaults to Limits if that is explicitly specified, otherwise to an implementation-defined value. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+									},
+								},
+							},
+						},
+					},
+					"labels": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Labels of the pods of the group, they are merged with spec.tidb.labels",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"service": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Service of the group, spec.tidb.service is used if it is not set",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBServiceSpec"),
+						},
+					},
+				},
+				Required: []string{"name", "replicas"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBServiceSpec", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_TiDBProbe(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -7960,6 +9105,74 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBProbe(ref common.ReferenceCallback) co
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_TiDBProxyProtocol(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiDBProxyProtocol is the PROXY protocol between the load balancer and TiDB",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"enabled": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Enabled sets `proxy-protocol.networks` in the config of TiDB to the trusted networks, annotates the TiDB service for the load balancer of the provider and probes the readiness of TiDB on the status port, which does not speak the PROXY protocol.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"trustedNetworks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TrustedNetworks are the IPs or the CIDRs of the load balancer, the connections from which must send the PROXY protocol header, e.g. the subnets of the load balancer. The clients in the Kubernetes cluster connecting to TiDB directly must not be in them.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"provider": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Provider is the cloud provider of the load balancer, the annotations enabling the PROXY protocol on its load balancer are added to the TiDB service unless they are set in spec.tidb.service.annotations. The load balancer of the services of GCP does not send the PROXY protocol, so none is added for it and the PROXY protocol must be sent by the proxy load balancer in front of TiDB.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_TiDBScaleInDrain(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiDBScaleInDrain is the draining of the connections of the TiDB pod to be removed by the scale-in",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"connectionThreshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConnectionThreshold is the number of the connections at or below which the pod is removed. Optional: Defaults to 0",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout after which the pod is removed even if its connections are above the threshold, in the format of Go Duration. If `graceful-wait-before-shutdown` is set in the config of TiDB and `terminationGracePeriodSeconds` is not set, the termination grace period of the pods is defaulted to cover the graceful wait. Optional: Defaults to 10m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_TiDBServiceSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -8193,7 +9406,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBSpec(ref common.ReferenceCallback) com
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -8206,7 +9419,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBSpec(ref common.ReferenceCallback) com
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -8324,6 +9537,26 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBSpec(ref common.ReferenceCallback) com
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -8477,12 +9710,90 @@ func schema_pkg_apis_pingcap_v1alpha1_TiDBSpec(ref common.ReferenceCallback) com
 							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBInitializer"),
 						},
 					},
+					"warmStandbyUpgrade": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WarmStandbyUpgrade makes the upgrade bring up a standby pod on the new version for each ordinal and wait for it to be ready before the old pod is replaced. The standby pod is removed once the replaced pod is ready again. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused stops reconciling TiDB while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"upgradeCompletionWebhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeCompletionWebhook is called when the upgrade of TiDB is done, TiDB stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
+						},
+					},
+					"scaleInDrain": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ScaleInDrain makes the scale-in of TiDB drain the connections of the pod to be removed first. The pod is removed from the endpoints of the TiDB service by the readiness gate `tidb.pingcap.com/accepting-connections`, and it is removed after its connections fall to the threshold or the timeout elapses. Enabling or disabling it changes the pod template, so the TiDB pods are rolling updated.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBScaleInDrain"),
+						},
+					},
+					"upgradeBatchSize": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeBatchSize is the max number or percentage of TiDB pods upgraded at a time, and the availability budget of the upgrade, the percentage is of `replicas` and rounded down. The pods of a batch are deleted once the partition of the StatefulSet covers them, so that they are recreated on the new revision at the same time, and the next batch is selected only after all of them are ready and healthy. A ready and healthy pod is not upgraded if that would leave less than `replicas - upgradeBatchSize` TiDB pods on either revision ready and healthy, the pods which are not ready or unhealthy are upgraded anyway, as restarting them does not lower the availability. It must resolve to at least 1 pod and not exceed `replicas - 1`, so that at least one TiDB pod serves during the upgrade, and it is capped at that if the replicas are scaled in below it. PD and TiKV are always upgraded one pod at a time. Optional: Defaults to 1",
+							Ref:         ref("k8s.io/apimachinery/pkg/util/intstr.IntOrString"),
+						},
+					},
+					"upgradeReadinessTimeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeReadinessTimeout is the max time an upgraded TiDB pod is given to become ready, counted from the last transition of its Ready condition. The upgrade still waits for the pod once it times out, but a Warning event is emitted and the condition UpgradeStalled of the TidbCluster is set until the upgrade moves on. Optional: Defaults to nil, the upgrade waits for the pod without timeout",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"gracefulShutdownTimeoutSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "GracefulShutdownTimeoutSeconds is the max time the upgrade waits for the connections of the TiDB pods to be drained before they are restarted. The pods stop accepting new connections by the readiness gate `tidb.pingcap.com/accepting-connections`, and they are restarted after their connections fall to upgradeDrainConnectionThreshold or the timeout elapses. The pods which are not ready or unhealthy are restarted without draining. Setting it enables the readiness gate, which changes the pod template, so the TiDB pods are rolling updated. Optional: Defaults to 0, the pods are restarted without draining",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"upgradeDrainConnectionThreshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeDrainConnectionThreshold is the number of the connections at or below which a TiDB pod drained before the upgrade is restarted, it only works with gracefulShutdownTimeoutSeconds. It is independent of the connectionThreshold of spec.tidb.scaleInDrain, which only applies to the scale-in. Optional: Defaults to 0, the pods are restarted after all their connections are closed",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"forceDeleteStuckPods": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ForceDeleteStuckPods force deletes the TiDB pods stuck in Terminating on the unreachable nodes, so that the StatefulSet recreates them elsewhere. A pod is force deleted only if its node is not Ready, it is disabled if it is not set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ForceDeleteStuckPods"),
+						},
+					},
+					"canaryOrdinals": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CanaryOrdinals are the ordinals of the TiDB pods upgraded as the canaries, they must be the highest ordinals, e.g. [4, 5] for 6 replicas. The upgrade of TiDB stops after the canaries are upgraded and the other pods stay on the old revision until it is cleared, the upgrades of the components after TiDB in the upgrade order wait for it to be cleared too.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"integer"},
+										Format: "int32",
+									},
+								},
+							},
+						},
+					},
+					"proxyProtocol": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ProxyProtocol makes TiDB accept the PROXY protocol from the load balancer in front of the TiDB service, so that the source IPs of the clients are preserved. The config of TiDB, the annotations of the service and the readiness probe are set consistently by it, changing it breaks the connections of the clients through the load balancer until both TiDB and the load balancer are rolled.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProxyProtocol"),
+						},
+					},
 				},
 				Required: []string{"replicas"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBInitializer", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProbe", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSlowLogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBTLSClient", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.Lifecycle", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ForceDeleteStuckPods", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBInitializer", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProbe", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBProxyProtocol", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBScaleInDrain", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBServiceSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSlowLogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBTLSClient", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.Lifecycle", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration", "k8s.io/apimachinery/pkg/util/intstr.IntOrString"},
 	}
 }
 
@@ -8681,7 +9992,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiFlashSpec(ref common.ReferenceCallback)
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -8694,7 +10005,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiFlashSpec(ref common.ReferenceCallback)
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -8812,6 +10123,26 @@ func schema_pkg_apis_pingcap_v1alpha1_TiFlashSpec(ref common.ReferenceCallback)
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -8919,12 +10250,25 @@ func schema_pkg_apis_pingcap_v1alpha1_TiFlashSpec(ref common.ReferenceCallback)
 							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover"),
 						},
 					},
+					"paused": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Paused stops reconciling TiFlash while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"upgradeCompletionWebhook": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeCompletionWebhook is called when the upgrade of TiFlash is done, TiFlash stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
+						},
+					},
 				},
 				Required: []string{"replicas", "storageClaims"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.InitContainerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageClaim", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiFlashConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.InitContainerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageClaim", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiFlashConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -9855,6 +11199,36 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVGCConfig(ref common.ReferenceCallback)
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_TiKVHugepages(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiKVHugepages is the hugepages requested by TiKV",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"size": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Size is the size of a hugepage, 2Mi or 1Gi Optional: Defaults to 2Mi",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"amount": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Amount is the total amount of the hugepages, it must be a multiple of the size",
+							Ref:         ref("k8s.io/apimachinery/pkg/api/resource.Quantity"),
+						},
+					},
+				},
+				Required: []string{"amount"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/api/resource.Quantity",
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_TiKVImportConfig(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -10884,6 +12258,54 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVServerConfig(ref common.ReferenceCallb
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_TiKVSlowStoreMitigation(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiKVSlowStoreMitigation is the config of the detection and the mitigation of the slow TiKV stores",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"slowScoreThreshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SlowScoreThreshold is the slow score in [1, 100] at or over which a check of the store is slow Optional: Defaults to 80",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"slowTrendCauseThreshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SlowTrendCauseThreshold is the latency of the disk IO of raftstore in microseconds in the slow trend at or over which a check of the store is slow, the slow trend is reported by TiKV v6.6+. The slow trend is not checked if it is not set.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"consecutiveChecks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConsecutiveChecks is the number of the consecutive slow checks after which the store is slow, and the number of the consecutive normal checks after which the slow store recovers. The stores are checked every 30 seconds at most. Optional: Defaults to 3",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"reduceLeaderWeight": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ReduceLeaderWeight reduces the leader weight of a slow store in PD so that its leaders are moved to the other stores, the original weight is restored after the store recovers. The leader weight of only one store is reduced at a time. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"leaderWeightPercent": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LeaderWeightPercent is the percentage of the original leader weight the leader weight of a slow store is reduced to Optional: Defaults to 10",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -11018,7 +12440,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) com
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -11031,7 +12453,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) com
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -11149,6 +12571,26 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) com
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -11212,119 +12654,216 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) com
 							Format:      "int32",
 						},
 					},
-					"separateRocksDBLog": {
+					"separateRocksDBLog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether output the RocksDB log in a separate sidecar container Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"separateRaftLog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Whether output the Raft log in a separate sidecar container Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"rocksDBLogVolumeName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Optional volume name configuration for rocksdb log.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"raftLogVolumeName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Optional volume name configuration for raft log.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"logTailer": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LogTailer is the configurations of the log tailers for TiKV",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec"),
+						},
+					},
+					"storageClassName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "The storageClassName of the persistent volume for TiKV data storage. Defaults to Kubernetes default storage class.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"dataSubDir": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Subdirectory within the volume to store TiKV Data. By default, the data is stored in the root directory of volume which is mounted at /var/lib/tikv. Specifying this will change the data directory to a subdirectory, e.g. /var/lib/tikv/data if you set the value to \"data\". It's dangerous to change this value for a running cluster as it will upgrade your cluster to use a new storage directory. Defaults to \"\" (volume's root).",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"config": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Config is the Configuration of tikv-servers",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVConfigWraper"),
+						},
+					},
+					"recoverFailover": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RecoverFailover indicates that Operator can recover the failed Pods",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"failover": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Failover is the configurations of failover",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover"),
+						},
+					},
+					"mountClusterClientSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MountClusterClientSecret indicates whether to mount `cluster-client-secret` to the Pod",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"evictLeaderTimeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "EvictLeaderTimeout indicates the timeout to evict tikv leader, in the format of Go Duration. Defaults to 10m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"storageVolumes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageVolumes configure additional storage for TiKV pods.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume"),
+									},
+								},
+							},
+						},
+					},
+					"storeLabels": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StoreLabels configures additional labels for TiKV stores.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"enableNamedStatusPort": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Whether output the RocksDB log in a separate sidecar container Optional: Defaults to false",
+							Description: "EnableNamedStatusPort enables status port(20180) in the Pod spec. If you set it to `true` for an existing cluster, the TiKV cluster will be rolling updated.",
 							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"separateRaftLog": {
+					"paused": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Whether output the Raft log in a separate sidecar container Optional: Defaults to false",
+							Description: "Paused stops reconciling TiKV while its status is still synced. The cluster-level `spec.paused` pauses all components regardless of this field. Optional: Defaults to false",
 							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"rocksDBLogVolumeName": {
+					"upgradeCompletionWebhook": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Optional volume name configuration for rocksdb log.",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "UpgradeCompletionWebhook is called when the upgrade of TiKV is done, TiKV stays in the Upgrade phase until the webhook succeeds or its timeout elapses.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook"),
 						},
 					},
-					"raftLogVolumeName": {
+					"upgradeStabilization": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Optional volume name configuration for raft log.",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "UpgradeStabilization waits for the region scheduling caused by the upgrade to settle before the upgrade of TiKV is complete, it is disabled if it is not set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeStabilization"),
 						},
 					},
-					"logTailer": {
+					"hostPorts": {
 						SchemaProps: spec.SchemaProps{
-							Description: "LogTailer is the configurations of the log tailers for TiKV",
-							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec"),
+							Description: "HostPorts allocates the host ports of TiKV from a range shared by the clusters on the same nodes, it takes effect only if hostNetwork is enabled. The advertise addresses are the IPs of the nodes and the pods using the same host ports are not scheduled to the same node. If it is not set, TiKV listens on 20160 and 20180 in the host network. Note: changing this for an existing cluster changes the addresses of the stores.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HostPortAllocation"),
 						},
 					},
-					"storageClassName": {
+					"autoCleanGhostStores": {
 						SchemaProps: spec.SchemaProps{
-							Description: "The storageClassName of the persistent volume for TiKV data storage. Defaults to Kubernetes default storage class.",
-							Type:        []string{"string"},
+							Description: "AutoCleanGhostStores deletes the ghost stores in status.tikv.ghostStores which have no regions and have been ghosts for longer than 30 minutes. Optional: Defaults to false",
+							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"dataSubDir": {
+					"cpuPinning": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Subdirectory within the volume to store TiKV Data. By default, the data is stored in the root directory of volume which is mounted at /var/lib/tikv. Specifying this will change the data directory to a subdirectory, e.g. /var/lib/tikv/data if you set the value to \"data\". It's dangerous to change this value for a running cluster as it will upgrade your cluster to use a new storage directory. Defaults to \"\" (volume's root).",
-							Type:        []string{"string"},
+							Description: "CPUPinning requires the TiKV pods to be in the Guaranteed QoS class with integer CPUs, so that the static CPU manager policy of kubelet pins exclusive CPUs to TiKV. The thread pools of TiKV are sized by the pinned CPUs unless they are set in the config. Optional: Defaults to false",
+							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"config": {
+					"hugepages": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Config is the Configuration of tikv-servers",
-							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVConfigWraper"),
+							Description: "Hugepages are the hugepages requested by TiKV, they are mounted at /dev/hugepages",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVHugepages"),
 						},
 					},
-					"recoverFailover": {
+					"preProvisionVolumes": {
 						SchemaProps: spec.SchemaProps{
-							Description: "RecoverFailover indicates that Operator can recover the failed Pods",
+							Description: "PreProvisionVolumes pre-creates the PVCs of each new TiKV pod in the zone planned round-robin across the zones of the topology spread constraints before the replicas are increased, and increases the replicas after the PVCs are bound, so that the new stores spread over the zones with the volumes bound on the first consumer. It falls back to the plain scale-out if the StorageClass does not bind the volumes on the first consumer or no zone can be planned. Optional: Defaults to false",
 							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"failover": {
+					"slowStoreMitigation": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Failover is the configurations of failover",
-							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover"),
+							Description: "SlowStoreMitigation detects the slow stores by the slow scores and the slow trends reported to PD and optionally reduces the leader weights of the slow stores until they recover, it is disabled if it is not set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSlowStoreMitigation"),
 						},
 					},
-					"mountClusterClientSecret": {
+					"directProbe": {
 						SchemaProps: spec.SchemaProps{
-							Description: "MountClusterClientSecret indicates whether to mount `cluster-client-secret` to the Pod",
+							Description: "DirectProbe makes the operator probe the gRPC port of each TiKV store reported Up by PD directly. A store is counted as healthy by the upgrade and the failover only if it answers the probe with the store id known to PD, the divergences are reported in status.tikv.divergentStores and by events.",
 							Type:        []string{"boolean"},
 							Format:      "",
 						},
 					},
-					"evictLeaderTimeout": {
+					"forceDeleteStuckPods": {
 						SchemaProps: spec.SchemaProps{
-							Description: "EvictLeaderTimeout indicates the timeout to evict tikv leader, in the format of Go Duration. Defaults to 10m",
-							Type:        []string{"string"},
-							Format:      "",
+							Description: "ForceDeleteStuckPods force deletes the TiKV pods stuck in Terminating on the unreachable nodes, so that the StatefulSet recreates them elsewhere and the failover makes progress. A pod is force deleted only if its node is not Ready and PD considers its store Down with no heartbeat within the threshold, it is disabled if it is not set.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ForceDeleteStuckPods"),
 						},
 					},
-					"storageVolumes": {
+					"nodeBindings": {
 						SchemaProps: spec.SchemaProps{
-							Description: "StorageVolumes configure additional storage for TiKV pods.",
+							Description: "NodeBindings pin the pods of the ordinals to the nodes by the required node affinity, so that they come up on the nodes of their local volumes after the whole cluster restarts. Every ordinal in [0, replicas) must be bound. The affinity is injected when the pods are created by the pod mutating webhook of the admission webhook, which must be enabled by `admissionWebhook.mutation.pods`. A pod not on the node of its binding is moved by deleting it once the stores are all Up, one pod at a time, the leaders of its store are evicted first.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
 									SchemaProps: spec.SchemaProps{
-										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume"),
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NodeBinding"),
 									},
 								},
 							},
 						},
 					},
-					"storeLabels": {
+					"maxTerminationGracePeriodSeconds": {
 						SchemaProps: spec.SchemaProps{
-							Description: "StoreLabels configures additional labels for TiKV stores.",
-							Type:        []string{"array"},
-							Items: &spec.SchemaOrArray{
-								Schema: &spec.Schema{
-									SchemaProps: spec.SchemaProps{
-										Type:   []string{"string"},
-										Format: "",
-									},
-								},
-							},
+							Description: "MaxTerminationGracePeriodSeconds enables deriving the termination grace period of the TiKV pods from the largest leader count of the stores reported by PD, one second per 1000 leaders on top of the default 30 seconds and bounded by this value. The period is computed when the pod template changes, so that it is applied to the StatefulSet before the pods are rolled, it is not used if terminationGracePeriodSeconds is set.",
+							Type:        []string{"integer"},
+							Format:      "int64",
 						},
 					},
-					"enableNamedStatusPort": {
+					"upgradeSafetyChecks": {
 						SchemaProps: spec.SchemaProps{
-							Description: "EnableNamedStatusPort enables status port(20180) in the Pod spec. If you set it to `true` for an existing cluster, the TiKV cluster will be rolling updated.",
-							Type:        []string{"boolean"},
-							Format:      "",
+							Description: "UpgradeSafetyChecks configures the checks done on a TiKV store before it is restarted during upgrade",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeSafetyChecks"),
 						},
 					},
 				},
@@ -11332,7 +12871,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVSpec(ref common.ReferenceCallback) com
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ForceDeleteStuckPods", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HostPortAllocation", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.LogTailerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NodeBinding", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.StorageVolume", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVConfigWraper", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVHugepages", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSlowStoreMitigation", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeSafetyChecks", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVUpgradeStabilization", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.UpgradeCompletionWebhook", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
@@ -11627,6 +13166,53 @@ func schema_pkg_apis_pingcap_v1alpha1_TiKVUnifiedReadPoolConfig(ref common.Refer
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_TiKVUpgradeSafetyChecks(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiKVUpgradeSafetyChecks configures the checks done on a TiKV store before it is restarted during upgrade. The checks are done after the leaders are evicted from the store.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"flushBeforeRestart": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FlushBeforeRestart flushes the store through the TiKV status API before restarting it, which reduces the raft logs to apply after the restart. It is skipped with an event if the TiKV does not serve the API. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"maxApplyLag": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxApplyLag is the max number of raft log entries committed but not yet applied by the peers on the store. If it is set, the operator waits for the apply lag to drop under it after the flush, the wait is bounded by evictLeaderTimeout. It only takes effect when flushBeforeRestart is true.",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func schema_pkg_apis_pingcap_v1alpha1_TiKVUpgradeStabilization(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "TiKVUpgradeStabilization is the final gate of the upgrade of TiKV. After all the stores are upgraded, TiKV stays in the Upgrade phase with the StabilizingAfterUpgrade condition until the number of the pending PD operators and the number of the under-replicated regions return to the ones captured when the upgrade starts.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"maxWait": {
+						SchemaProps: spec.SchemaProps{
+							Description: "MaxWait after which the upgrade is completed even if the regions are not settled, in the format of Go Duration. Optional: Defaults to 30m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_TidbAutoScalerSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -12043,6 +13629,27 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbClusterSpec(ref common.ReferenceCallba
 							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSpec"),
 						},
 					},
+					"tidbGroups": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-list-map-keys": []interface{}{
+									"name",
+								},
+								"x-kubernetes-list-type": "map",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "TiDBGroups are the named groups of TiDB servers sharing the PD and TiKV of the cluster, e.g. a pool for OLTP and a pool for reporting. Each group has its own StatefulSet, Services and ConfigMap named <cluster>-tidb-<group>, and inherits the fields it does not set from spec.tidb, which is the implicit default group and is required. The groups are upgraded one at a time after the default group, in their order in the list.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBGroup"),
+									},
+								},
+							},
+						},
+					},
 					"tikv": {
 						SchemaProps: spec.SchemaProps{
 							Description: "TiKV cluster spec",
@@ -12121,6 +13728,20 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbClusterSpec(ref common.ReferenceCallba
 							},
 						},
 					},
+					"prePullImages": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrePullImages pre-pulls the new image of PD, TiKV, TiFlash, TiDB and TiCDC by a DaemonSet on the nodes running the component when the image is changed, the rolling update of the component waits until the image is pulled on all these nodes. Disable it to upgrade without waiting if a node cannot pull the image. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"tier": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tier is the tier of the cluster, e.g. gold, the config fragments of PD, TiKV and TiDB of the tier in the ConfigMap set by -tier-config-configmap of tidb-controller-manager are merged under the config of the cluster",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 					"configUpdateStrategy": {
 						SchemaProps: spec.SchemaProps{
 							Description: "ConfigUpdateStrategy determines how the configuration change is applied to the cluster. UpdateStrategyInPlace will update the ConfigMap of configuration in-place and an extra rolling-update of the cluster component is needed to reload the configuration change. UpdateStrategyRollingUpdate will create a new ConfigMap with the new configuration and rolling-update the related components to use the new ConfigMap, that is, the new configuration will be applied automatically.",
@@ -12321,11 +13942,71 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbClusterSpec(ref common.ReferenceCallba
 							},
 						},
 					},
+					"deletionPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeletionPolicy enables the graceful deletion of the TidbCluster, the components are torn down in order: TiDB, TiFlash/TiCDC/Pump, TiKV, PD and discovery. If not set, all the resources are removed at once by the garbage collector.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DeletionPolicy"),
+						},
+					},
+					"deletionProtection": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DeletionProtection protects the TidbCluster from accidental deletion, the TidbCluster being deleted stays terminating with its components running until it is annotated with tidb.pingcap.com/confirm-deletion=<name of the TidbCluster>. Optional: Defaults to the -deletion-protection of tidb-controller-manager",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"connectivityChecks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConnectivityChecks probes the connectivity between the components periodically, the result is surfaced in status.connectivity and the ComponentConnectivity condition",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ConnectivityChecks"),
+						},
+					},
+					"allowAdoption": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllowAdoption makes tidb-operator repair the ownerReferences of the StatefulSets, Services, ConfigMaps and PVCs of the cluster which refer to a TidbCluster of the same name but a different UID, e.g. the namespace is restored by Velero, so that they are adopted by the TidbCluster instead of being garbage collected. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"storageWarningThreshold": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StorageWarningThreshold is the percent of the used storage of a pod of PD, TiKV, TiFlash or Pump above which the StoragePressure condition is set Optional: Defaults to 80",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"upgradeOrder": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeOrder is the order in which the components are upgraded, the upgrade of a component waits for the upgrades of the components before it to finish. It must contain all the components deployed. Optional: Defaults to [pd, tiflash, tikv, pump, ticdc, tidb]",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"bootstrapTimeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BootstrapTimeout is the time PD is given to become healthy after the cluster is created, in the format of Go Duration. The BootstrapFailed condition is set and the other components are not synced if PD does not become healthy in time, until PD recovers. Optional: Defaults to 30m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"networking": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Networking is the networking of the client traffic to PD and TiKV",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NetworkingSpec"),
+						},
+					},
 				},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DiscoverySpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HelperSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PDSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PumpSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TLSCluster", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiCDCSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiFlashSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ConnectivityChecks", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DeletionPolicy", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DiscoverySpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.HelperSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NetworkingSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PDSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PumpSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TLSCluster", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiCDCSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBGroup", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiDBSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiFlashSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TiKVSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration"},
 	}
 }
 
@@ -12481,6 +14162,34 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbInitializerSpec(ref common.ReferenceCa
 							Ref: ref("k8s.io/api/core/v1.ResourceRequirements"),
 						},
 					},
+					"tolerations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Tolerations of TiDB initializer Pods, defaults to the job tolerations of tidb-operator if empty",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.Toleration"),
+									},
+								},
+							},
+						},
+					},
+					"nodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodeSelector of TiDB initializer Pods, defaults to the job node selector of tidb-operator if empty",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Allows: true,
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
 					"timezone": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Time zone of TiDB initializer Pods",
@@ -12495,12 +14204,19 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbInitializerSpec(ref common.ReferenceCa
 							Format:      "",
 						},
 					},
+					"tlsCASecretName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TLSCASecretName is the name of secret which stores only the ca.crt of the CA signing the tidb server certificate, e.g. a private CA. The tidb server is verified by it and no client certificate is presented unless TLSClientSecretName is set. Optional: Defaults to nil",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
 				},
 				Required: []string{"image", "cluster"},
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.ResourceRequirements", "k8s.io/api/core/v1.Toleration"},
 	}
 }
 
@@ -12710,6 +14426,19 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbMonitorSpec(ref common.ReferenceCallba
 							},
 						},
 					},
+					"externalTargets": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ExternalTargets are the TiDB clusters not managed by TiDB Operator, e.g. the clusters on VMs, scraped by the statically-defined targets. The metrics are labeled the same as the metrics of the monitored TidbClusters, so the dashboards work unchanged.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalClusterTargets"),
+									},
+								},
+							},
+						},
+					},
 					"prometheus": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Prometheus spec",
@@ -12973,7 +14702,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbMonitorSpec(ref common.ReferenceCallba
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMMonitorSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GrafanaSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.InitializerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PrometheusReloaderSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PrometheusSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ReloaderSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ThanosSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.DMMonitorSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ExternalClusterTargets", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.GrafanaSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.InitializerSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PrometheusReloaderSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.PrometheusSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ReloaderSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.ThanosSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume"},
 	}
 }
 
@@ -13189,7 +14918,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbNGMonitoringSpec(ref common.ReferenceC
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -13202,7 +14931,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbNGMonitoringSpec(ref common.ReferenceC
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -13320,6 +15049,26 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbNGMonitoringSpec(ref common.ReferenceC
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"clusters": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Clusters reference TiDB cluster",
@@ -13365,7 +15114,7 @@ func schema_pkg_apis_pingcap_v1alpha1_TidbNGMonitoringSpec(ref common.ReferenceC
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NGMonitoringSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.NGMonitoringSpec", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TidbClusterRef", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount"},
 	}
 }
 
@@ -13478,6 +15227,34 @@ func schema_pkg_apis_pingcap_v1alpha1_TxnLocalLatches(ref common.ReferenceCallba
 	}
 }
 
+func schema_pkg_apis_pingcap_v1alpha1_UpgradeCompletionWebhook(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "UpgradeCompletionWebhook is the webhook confirming the completion of the upgrade of a component",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"url": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URL of the webhook. tidb-operator sends a POST request with the namespace and the name of the TidbCluster, the component and its revision, and any 2xx response means success. The request is sent from tidb-controller-manager, so only the http and https URLs without user info are allowed, the loopback and link-local addresses (e.g. the cloud metadata endpoints) are refused, and the redirects are not followed. Restrict the egress of tidb-controller-manager by NetworkPolicy if the TidbClusters are created by untrusted users.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout after which the upgrade is completed even if the webhook does not succeed, in the format of Go Duration. Optional: Defaults to 5m",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"url"},
+			},
+		},
+	}
+}
+
 func schema_pkg_apis_pingcap_v1alpha1_WorkerConfig(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -13688,7 +15465,7 @@ func schema_pkg_apis_pingcap_v1alpha1_WorkerSpec(ref common.ReferenceCallback) c
 					},
 					"env": {
 						SchemaProps: spec.SchemaProps{
-							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
+							Description: "List of environment variables to set in the container, like v1.Container.Env. Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters - NAMESPACE - TZ - SERVICE_NAME - PEER_SERVICE_NAME - HEADLESS_SERVICE_NAME - SET_NAME - HOSTNAME - CLUSTER_NAME - POD_NAME - BINLOG_ENABLED - SLOW_LOG_FILE",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -13701,7 +15478,7 @@ func schema_pkg_apis_pingcap_v1alpha1_WorkerSpec(ref common.ReferenceCallback) c
 					},
 					"envFrom": {
 						SchemaProps: spec.SchemaProps{
-							Description: "Extend the use scenarios for env",
+							Description: "Extend the use scenarios for env, like v1.Container.EnvFrom. The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom. Changes to the list roll the pods, but changes to the content of the referenced ConfigMaps and Secrets take effect only after the pods are restarted.",
 							Type:        []string{"array"},
 							Items: &spec.SchemaOrArray{
 								Schema: &spec.Schema{
@@ -13819,6 +15596,26 @@ func schema_pkg_apis_pingcap_v1alpha1_WorkerSpec(ref common.ReferenceCallback) c
 							},
 						},
 					},
+					"prepullNextImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while the current pod is upgrading, so that the next pod starts without waiting for the image. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"sizing": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Sizing declares the resources of the component by a single size, the requests and the limits are derived from it. The requests and the limits set explicitly in the component override the derived ones of the same resources. It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.",
+							Ref:         ref("github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing"),
+						},
+					},
+					"waitForExternalProvisioning": {
+						SchemaProps: spec.SchemaProps{
+							Description: "WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g. the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: \"true\"`. The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the component must be scheduled by tidb-scheduler. Changing it rolls the pods. It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC. Optional: Defaults to false",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
 					"limits": {
 						SchemaProps: spec.SchemaProps{
 							Description: "Limits describes the maximum amount of compute resources allowed. More info: https://kubernetes.io/docs/concepts/configuration/manage-compute-resources-container/",
@@ -13913,7 +15710,7 @@ func schema_pkg_apis_pingcap_v1alpha1_WorkerSpec(ref common.ReferenceCallback) c
 			},
 		},
 		Dependencies: []string{
-			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.WorkerConfigWraper", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
+			"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Failover", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.Sizing", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.TopologySpreadConstraint", "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1.WorkerConfigWraper", "k8s.io/api/core/v1.Affinity", "k8s.io/api/core/v1.Container", "k8s.io/api/core/v1.EnvFromSource", "k8s.io/api/core/v1.EnvVar", "k8s.io/api/core/v1.LocalObjectReference", "k8s.io/api/core/v1.PodDNSConfig", "k8s.io/api/core/v1.PodSecurityContext", "k8s.io/api/core/v1.Toleration", "k8s.io/api/core/v1.Volume", "k8s.io/api/core/v1.VolumeMount", "k8s.io/apimachinery/pkg/api/resource.Quantity"},
 	}
 }
 
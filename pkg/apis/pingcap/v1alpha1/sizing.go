@@ -0,0 +1,94 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"math/big"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ParseRequestsRatio parses the requests ratio of the sizing, it returns 1 if the ratio is not set
+func (s *Sizing) ParseRequestsRatio() (*big.Rat, error) {
+	if s.RequestsRatio == nil {
+		return big.NewRat(1, 1), nil
+	}
+	ratio, ok := new(big.Rat).SetString(*s.RequestsRatio)
+	if !ok {
+		return nil, fmt.Errorf("invalid requests ratio %q", *s.RequestsRatio)
+	}
+	if ratio.Sign() <= 0 || ratio.Cmp(big.NewRat(1, 1)) > 0 {
+		return nil, fmt.Errorf("requests ratio %q is not in (0, 1]", *s.RequestsRatio)
+	}
+	return ratio, nil
+}
+
+// SizedResources returns the resources derived from the sizing, the requests and the limits in explicit
+// override the derived ones of the same resources. The explicit resources are returned as is if sizing is nil.
+func SizedResources(sizing *Sizing, explicit corev1.ResourceRequirements) (corev1.ResourceRequirements, error) {
+	if sizing == nil {
+		return explicit, nil
+	}
+	ratio, err := sizing.ParseRequestsRatio()
+	if err != nil {
+		return explicit, err
+	}
+
+	resources := corev1.ResourceRequirements{
+		Limits:   corev1.ResourceList{},
+		Requests: corev1.ResourceList{},
+	}
+	for name, size := range sizing.Size {
+		if name == corev1.ResourceStorage {
+			continue
+		}
+		switch sizing.SizeIs {
+		case SizeIsRequests:
+			resources.Requests[name] = size.DeepCopy()
+			resources.Limits[name] = scaleQuantity(name, size, new(big.Rat).Inv(ratio), true)
+		default:
+			resources.Limits[name] = size.DeepCopy()
+			resources.Requests[name] = scaleQuantity(name, size, ratio, false)
+		}
+	}
+	for name, q := range explicit.Limits {
+		resources.Limits[name] = q.DeepCopy()
+	}
+	for name, q := range explicit.Requests {
+		resources.Requests[name] = q.DeepCopy()
+	}
+	return resources, nil
+}
+
+// scaleQuantity returns the quantity multiplied by factor, the cpu is rounded to millicores and the others to
+// integers, it is rounded up if roundUp is true or rounded down otherwise
+func scaleQuantity(name corev1.ResourceName, q resource.Quantity, factor *big.Rat, roundUp bool) resource.Quantity {
+	unit := int64(1)
+	if name == corev1.ResourceCPU {
+		unit = 1000
+	}
+	v, _ := new(big.Rat).SetString(q.AsDec().String())
+	v.Mul(v, factor)
+	v.Mul(v, big.NewRat(unit, 1))
+	n := new(big.Int).Quo(v.Num(), v.Denom())
+	if roundUp && new(big.Int).Mul(n, v.Denom()).Cmp(v.Num()) != 0 {
+		n.Add(n, big.NewInt(1))
+	}
+	if name == corev1.ResourceCPU {
+		return *resource.NewMilliQuantity(n.Int64(), q.Format)
+	}
+	return *resource.NewQuantity(n.Int64(), q.Format)
+}
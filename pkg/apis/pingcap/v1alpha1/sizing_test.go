@@ -0,0 +1,102 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+)
+
+func TestSizedResources(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	size := corev1.ResourceList{
+		corev1.ResourceCPU:     resource.MustParse("3"),
+		corev1.ResourceMemory:  resource.MustParse("16Gi"),
+		corev1.ResourceStorage: resource.MustParse("100Gi"),
+	}
+	explicit := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+	}
+
+	tests := []struct {
+		name     string
+		sizing   *Sizing
+		explicit corev1.ResourceRequirements
+		limits   map[corev1.ResourceName]string
+		requests map[corev1.ResourceName]string
+	}{
+		{
+			name:     "no sizing",
+			explicit: explicit,
+			requests: map[corev1.ResourceName]string{corev1.ResourceStorage: "100Gi"},
+		},
+		{
+			name:     "requests equal to the limits by default",
+			sizing:   &Sizing{Size: size},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "16Gi"},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "16Gi"},
+		},
+		{
+			name:     "requests derived from the limits are rounded down",
+			sizing:   &Sizing{Size: size, RequestsRatio: pointer.StringPtr("0.3333")},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "16Gi"},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "999m", corev1.ResourceMemory: "5726050399"},
+		},
+		{
+			name:     "limits derived from the requests are rounded up",
+			sizing:   &Sizing{Size: size, SizeIs: SizeIsRequests, RequestsRatio: pointer.StringPtr("0.7")},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "4286m", corev1.ResourceMemory: "24542670263"},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "16Gi"},
+		},
+		{
+			name:     "explicit resources override the derived ones",
+			sizing:   &Sizing{Size: size, RequestsRatio: pointer.StringPtr("0.5")},
+			explicit: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2500m")}},
+			limits:   map[corev1.ResourceName]string{corev1.ResourceCPU: "3", corev1.ResourceMemory: "16Gi"},
+			requests: map[corev1.ResourceName]string{corev1.ResourceCPU: "2500m", corev1.ResourceMemory: "8Gi"},
+		},
+	}
+	for _, tt := range tests {
+		resources, err := SizedResources(tt.sizing, tt.explicit)
+		g.Expect(err).NotTo(HaveOccurred(), tt.name)
+		g.Expect(resources.Limits).To(HaveLen(len(tt.limits)), tt.name)
+		for name, q := range tt.limits {
+			limit := resources.Limits[name]
+			g.Expect(limit.String()).To(Equal(q), tt.name)
+		}
+		g.Expect(resources.Requests).To(HaveLen(len(tt.requests)), tt.name)
+		for name, q := range tt.requests {
+			request := resources.Requests[name]
+			g.Expect(request.String()).To(Equal(q), tt.name)
+		}
+	}
+
+	// the derivation is deterministic
+	sizing := &Sizing{Size: size, RequestsRatio: pointer.StringPtr("0.37")}
+	first, err := SizedResources(sizing, corev1.ResourceRequirements{})
+	g.Expect(err).NotTo(HaveOccurred())
+	for i := 0; i < 10; i++ {
+		again, err := SizedResources(sizing, corev1.ResourceRequirements{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(again).To(Equal(first))
+	}
+
+	_, err = SizedResources(&Sizing{Size: size, RequestsRatio: pointer.StringPtr("2")}, explicit)
+	g.Expect(err).To(HaveOccurred())
+}
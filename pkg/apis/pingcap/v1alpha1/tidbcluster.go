@@ -16,13 +16,16 @@ package v1alpha1
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 )
@@ -38,6 +41,25 @@ const (
 	defaultEnablePVReclaim    = false
 	// defaultEvictLeaderTimeout is the timeout limit of evict leader
 	defaultEvictLeaderTimeout = 1500 * time.Minute
+	// defaultHugepageSize is the default size of the hugepages requested by TiKV
+	defaultHugepageSize = "2Mi"
+	// defaultTiDBUpgradeBatchSize is the default number of TiDB pods upgraded at a time
+	defaultTiDBUpgradeBatchSize = int32(1)
+	// defaultTiDBUpgradeDrainConnectionThreshold is the default number of the connections at or below which
+	// a drained TiDB pod is restarted during the upgrade
+	defaultTiDBUpgradeDrainConnectionThreshold = int32(0)
+	// DefaultStorageWarningThreshold is the default percent of the used storage above which a pod is under
+	// storage pressure
+	DefaultStorageWarningThreshold = int32(80)
+	// defaultBootstrapTimeout is the default time PD is given to become healthy after the cluster is created
+	defaultBootstrapTimeout = 30 * time.Minute
+
+	// DefaultTiKVServerPort is the port TiKV serves the clients on
+	DefaultTiKVServerPort = int32(20160)
+	// DefaultTiKVStatusPort is the port TiKV serves the status on
+	DefaultTiKVStatusPort = int32(20180)
+	// DefaultHostPortMaxSlots is the default max number of the clusters sharing a range of host ports
+	DefaultHostPortMaxSlots = int32(64)
 )
 
 var (
@@ -152,6 +174,21 @@ func (tc *TidbCluster) TiKVEvictLeaderTimeout() time.Duration {
 	return defaultEvictLeaderTimeout
 }
 
+// TiKVFlushBeforeRestart returns whether to flush a TiKV store before restarting it during upgrade
+func (tc *TidbCluster) TiKVFlushBeforeRestart() bool {
+	return tc.Spec.TiKV != nil && tc.Spec.TiKV.UpgradeSafetyChecks != nil &&
+		tc.Spec.TiKV.UpgradeSafetyChecks.FlushBeforeRestart
+}
+
+// TiKVMaxApplyLag returns the max apply lag to wait for after flushing a TiKV store,
+// nil means no wait.
+func (tc *TidbCluster) TiKVMaxApplyLag() *int64 {
+	if !tc.TiKVFlushBeforeRestart() {
+		return nil
+	}
+	return tc.Spec.TiKV.UpgradeSafetyChecks.MaxApplyLag
+}
+
 // TiFlashImage return the image used by TiFlash.
 //
 // If TiFlash isn't specified, return empty string.
@@ -328,6 +365,20 @@ func (tc *TidbCluster) TiKVScaling() bool {
 	return tc.Status.TiKV.Phase == ScalePhase
 }
 
+// TiKVHostPortsEnabled returns whether the host ports of TiKV are allocated from spec.tikv.hostPorts
+// The ports are passed to TiKV by the startup script in the ConfigMap, which requires spec.tikv.config.
+func (tc *TidbCluster) TiKVHostPortsEnabled() bool {
+	return tc.Spec.TiKV != nil && tc.Spec.TiKV.HostPorts != nil && tc.Spec.TiKV.Config != nil && tc.BaseTiKVSpec().HostNetwork()
+}
+
+// TiKVPorts returns the server port and the status port of TiKV
+func (tc *TidbCluster) TiKVPorts() (server int32, status int32) {
+	if tc.TiKVHostPortsEnabled() && tc.Status.TiKV.HostPorts != nil {
+		return tc.Status.TiKV.HostPorts.Server, tc.Status.TiKV.HostPorts.Status
+	}
+	return DefaultTiKVServerPort, DefaultTiKVStatusPort
+}
+
 func (tc *TidbCluster) TiKVBootStrapped() bool {
 	return tc.Status.TiKV.BootStrapped
 }
@@ -618,6 +669,34 @@ func (tc *TidbCluster) TiDBStsDesiredReplicas() int32 {
 	return tc.Spec.TiDB.Replicas + int32(len(tc.Status.TiDB.FailureMembers))
 }
 
+// TiDBGroupSpec returns the spec of the TiDB servers of the group, the fields not set by the group are
+// inherited from spec.tidb. It returns nil if spec.tidb is not set.
+func (tc *TidbCluster) TiDBGroupSpec(group *TiDBGroup) *TiDBSpec {
+	if tc.Spec.TiDB == nil {
+		return nil
+	}
+	spec := tc.Spec.TiDB.DeepCopy()
+	spec.Replicas = group.Replicas
+	if group.Config != nil {
+		spec.Config = group.Config.DeepCopy()
+	}
+	if len(group.Requests) > 0 || len(group.Limits) > 0 {
+		spec.ResourceRequirements = *group.ResourceRequirements.DeepCopy()
+	}
+	if len(group.Labels) > 0 {
+		if spec.Labels == nil {
+			spec.Labels = map[string]string{}
+		}
+		for k, v := range group.Labels {
+			spec.Labels[k] = v
+		}
+	}
+	if group.Service != nil {
+		spec.Service = group.Service.DeepCopy()
+	}
+	return spec
+}
+
 func (tc *TidbCluster) TiDBStsActualReplicas() int32 {
 	stsStatus := tc.Status.TiDB.StatefulSet
 	if stsStatus == nil {
@@ -777,6 +856,11 @@ func (tidb *TiDBSpec) IsTLSClientEnabled() bool {
 	return tidb.TLSClient != nil && tidb.TLSClient.Enabled
 }
 
+// IsProxyProtocolEnabled returns whether TiDB accepts the PROXY protocol from the load balancer
+func (tidb *TiDBSpec) IsProxyProtocolEnabled() bool {
+	return tidb.ProxyProtocol != nil && tidb.ProxyProtocol.Enabled
+}
+
 func (tidb *TiDBSpec) ShouldSeparateSlowLog() bool {
 	separateSlowLog := tidb.SeparateSlowLog
 	if separateSlowLog == nil {
@@ -801,6 +885,41 @@ func (tidb *TiDBSpec) GetServicePort() int32 {
 	return port
 }
 
+// GetUpgradeBatchSize returns the max number of tidb pods upgraded at a time, which is also the max number of
+// tidb pods allowed to be unavailable during the upgrade, the percentage is of replicas and rounded down, it is
+// kept between 1 and replicas - 1
+func (tidb *TiDBSpec) GetUpgradeBatchSize() int32 {
+	if tidb.UpgradeBatchSize == nil || tidb.Replicas <= 1 {
+		return defaultTiDBUpgradeBatchSize
+	}
+	n, err := intstr.GetValueFromIntOrPercent(tidb.UpgradeBatchSize, int(tidb.Replicas), false)
+	if err != nil || n < 1 {
+		return defaultTiDBUpgradeBatchSize
+	}
+	if int32(n) > tidb.Replicas-1 {
+		return tidb.Replicas - 1
+	}
+	return int32(n)
+}
+
+// GetUpgradeDrainConnectionThreshold returns the number of the connections at or below which a tidb pod
+// drained before the upgrade is restarted
+func (tidb *TiDBSpec) GetUpgradeDrainConnectionThreshold() int32 {
+	if tidb.UpgradeDrainConnectionThreshold == nil || *tidb.UpgradeDrainConnectionThreshold < 0 {
+		return defaultTiDBUpgradeDrainConnectionThreshold
+	}
+	return *tidb.UpgradeDrainConnectionThreshold
+}
+
+// GetUpgradeReadinessTimeout returns the max time an upgraded tidb pod is given to become ready, it is 0 if
+// the upgrade waits for the pod without timeout
+func (tidb *TiDBSpec) GetUpgradeReadinessTimeout() time.Duration {
+	if tidb.UpgradeReadinessTimeout == nil || tidb.UpgradeReadinessTimeout.Duration < 0 {
+		return 0
+	}
+	return tidb.UpgradeReadinessTimeout.Duration
+}
+
 func (tikv *TiKVSpec) ShouldSeparateRocksDBLog() bool {
 	separateRocksDBLog := tikv.SeparateRocksDBLog
 	if separateRocksDBLog == nil {
@@ -824,6 +943,24 @@ func (tikv *TiKVSpec) GetLogTailerSpec() LogTailerSpec {
 	return *tikv.LogTailer
 }
 
+// PageSize returns the size of a hugepage, defaults to 2Mi
+func (h *TiKVHugepages) PageSize() string {
+	if h.Size == "" {
+		return defaultHugepageSize
+	}
+	return h.Size
+}
+
+// ResourceName returns the resource name of the hugepages, e.g. hugepages-2Mi
+func (h *TiKVHugepages) ResourceName() corev1.ResourceName {
+	return corev1.ResourceName(corev1.ResourceHugePagesPrefix + h.PageSize())
+}
+
+// StorageMedium returns the medium of the volume backed by the hugepages, e.g. HugePages-2Mi
+func (h *TiKVHugepages) StorageMedium() corev1.StorageMedium {
+	return corev1.StorageMedium(string(corev1.StorageMediumHugePagesPrefix) + h.PageSize())
+}
+
 func (tikv *TiKVSpec) GetRecoverByUID() types.UID {
 	if tikv.Failover == nil {
 		return ""
@@ -977,3 +1114,239 @@ func (tc *TidbCluster) IsComponentVolumeResizing(compType MemberType) bool {
 	}
 	return false
 }
+
+// IsComponentPaused returns true if the reconciling of component is paused,
+// either by `spec.paused` or by the component's own `paused` field.
+func (tc *TidbCluster) IsComponentPaused(compType MemberType) bool {
+	if tc.Spec.Paused {
+		return true
+	}
+	switch compType {
+	case PDMemberType:
+		return tc.Spec.PD != nil && tc.Spec.PD.Paused
+	case TiKVMemberType:
+		return tc.Spec.TiKV != nil && tc.Spec.TiKV.Paused
+	case TiDBMemberType:
+		return tc.Spec.TiDB != nil && tc.Spec.TiDB.Paused
+	case TiFlashMemberType:
+		return tc.Spec.TiFlash != nil && tc.Spec.TiFlash.Paused
+	case TiCDCMemberType:
+		return tc.Spec.TiCDC != nil && tc.Spec.TiCDC.Paused
+	case PumpMemberType:
+		return tc.Spec.Pump != nil && tc.Spec.Pump.Paused
+	}
+	return false
+}
+
+// ChangeRequestID returns the change request ID of the planned upgrade, empty if not set.
+func (tc *TidbCluster) ChangeRequestID() string {
+	return tc.Annotations[label.AnnChangeRequestID]
+}
+
+// IsUpgradePaused returns whether the upgrades in progress are paused by the annotation tidb.pingcap.com/upgrade-paused
+func (tc *TidbCluster) IsUpgradePaused() bool {
+	return tc.Annotations[label.AnnUpgradePaused] == label.AnnUpgradePausedVal
+}
+
+// IsDowngradeAllowed returns true if the downgrades across the major and minor versions are allowed by the
+// annotation tidb.pingcap.com/allow-downgrade.
+func (tc *TidbCluster) IsDowngradeAllowed() bool {
+	return tc.Annotations[label.AnnAllowDowngrade] == label.AnnAllowDowngradeVal
+}
+
+// UpgradeCompletionWebhook returns the upgrade completion webhook of the component, nil if not set.
+func (tc *TidbCluster) UpgradeCompletionWebhook(compType MemberType) *UpgradeCompletionWebhook {
+	switch compType {
+	case PDMemberType:
+		if tc.Spec.PD != nil {
+			return tc.Spec.PD.UpgradeCompletionWebhook
+		}
+	case TiKVMemberType:
+		if tc.Spec.TiKV != nil {
+			return tc.Spec.TiKV.UpgradeCompletionWebhook
+		}
+	case TiDBMemberType:
+		if tc.Spec.TiDB != nil {
+			return tc.Spec.TiDB.UpgradeCompletionWebhook
+		}
+	case TiFlashMemberType:
+		if tc.Spec.TiFlash != nil {
+			return tc.Spec.TiFlash.UpgradeCompletionWebhook
+		}
+	case TiCDCMemberType:
+		if tc.Spec.TiCDC != nil {
+			return tc.Spec.TiCDC.UpgradeCompletionWebhook
+		}
+	case PumpMemberType:
+		if tc.Spec.Pump != nil {
+			return tc.Spec.Pump.UpgradeCompletionWebhook
+		}
+	}
+	return nil
+}
+
+// ComponentUpgradeInProgress returns true if the component is being upgraded and its upgrade is
+// not frozen by its own `paused` field, the upgrades of other components wait for it to finish.
+func (tc *TidbCluster) ComponentUpgradeInProgress(compType MemberType) bool {
+	if !tc.Spec.Paused && tc.IsComponentPaused(compType) {
+		return false
+	}
+	switch compType {
+	case PDMemberType:
+		return tc.Status.PD.Phase == UpgradePhase
+	case TiKVMemberType:
+		return tc.Status.TiKV.Phase == UpgradePhase
+	case TiDBMemberType:
+		return tc.Status.TiDB.Phase == UpgradePhase
+	case TiFlashMemberType:
+		return tc.Status.TiFlash.Phase == UpgradePhase
+	case TiCDCMemberType:
+		return tc.Status.TiCDC.Phase == UpgradePhase
+	case PumpMemberType:
+		return tc.Status.Pump.Phase == UpgradePhase
+	}
+	return false
+}
+
+// StorageWarningThreshold returns the percent of the used storage above which a pod is under storage pressure
+func (tc *TidbCluster) StorageWarningThreshold() int32 {
+	if tc.Spec.StorageWarningThreshold == nil {
+		return DefaultStorageWarningThreshold
+	}
+	return *tc.Spec.StorageWarningThreshold
+}
+
+// TopologyAwareRouting returns whether the clients prefer the PD and TiKV endpoints in their own zones
+func (tc *TidbCluster) TopologyAwareRouting() bool {
+	return tc.Spec.Networking != nil && tc.Spec.Networking.TopologyAwareRouting
+}
+
+// BootstrapTimeout returns the time PD is given to become healthy after the cluster is created
+func (tc *TidbCluster) BootstrapTimeout() time.Duration {
+	if tc.Spec.BootstrapTimeout != nil {
+		d, err := time.ParseDuration(*tc.Spec.BootstrapTimeout)
+		if err == nil {
+			return d
+		}
+	}
+	return defaultBootstrapTimeout
+}
+
+// DefaultUpgradeOrder is the order in which the components are upgraded if spec.upgradeOrder is not set,
+// TiCDC is upgraded before TiDB as rolling TiDB under an upgrading TiCDC stalls the changefeeds
+var DefaultUpgradeOrder = []MemberType{
+	PDMemberType,
+	TiFlashMemberType,
+	TiKVMemberType,
+	PumpMemberType,
+	TiCDCMemberType,
+	TiDBMemberType,
+}
+
+// UpgradeOrder returns the order in which the components are upgraded, the components missing in
+// spec.upgradeOrder are upgraded after the ones in it by the default order
+func (tc *TidbCluster) UpgradeOrder() []MemberType {
+	if len(tc.Spec.UpgradeOrder) == 0 {
+		return DefaultUpgradeOrder
+	}
+	order := make([]MemberType, 0, len(DefaultUpgradeOrder))
+	listed := map[MemberType]bool{}
+	for _, compType := range tc.Spec.UpgradeOrder {
+		if !listed[compType] {
+			order = append(order, compType)
+			listed[compType] = true
+		}
+	}
+	for _, compType := range DefaultUpgradeOrder {
+		if !listed[compType] {
+			order = append(order, compType)
+		}
+	}
+	return order
+}
+
+// IsUpgradeOrderComponent returns true if the component can be in spec.upgradeOrder
+func IsUpgradeOrderComponent(compType MemberType) bool {
+	for _, t := range DefaultUpgradeOrder {
+		if t == compType {
+			return true
+		}
+	}
+	return false
+}
+
+// UpgradeBlockedBy returns the components before the component in the upgrade order whose upgrades are in
+// progress, the upgrade of the component waits for them to finish. TiDB pinned to its canaries stays in
+// progress until spec.tidb.canaryOrdinals is cleared, so it blocks the components after it in the order only.
+func (tc *TidbCluster) UpgradeBlockedBy(compType MemberType) []MemberType {
+	var blocking []MemberType
+	for _, t := range tc.UpgradeOrder() {
+		if t == compType {
+			break
+		}
+		if tc.ComponentUpgradeInProgress(t) {
+			blocking = append(blocking, t)
+		}
+	}
+	return blocking
+}
+
+// NodeBindings returns the node bindings of the pods of the component, only PD and TiKV support them
+func (tc *TidbCluster) NodeBindings(compType MemberType) []NodeBinding {
+	switch compType {
+	case PDMemberType:
+		if tc.Spec.PD != nil {
+			return tc.Spec.PD.NodeBindings
+		}
+	case TiKVMemberType:
+		if tc.Spec.TiKV != nil {
+			return tc.Spec.TiKV.NodeBindings
+		}
+	}
+	return nil
+}
+
+// NodeBinding returns the node binding of the pod of the ordinal of the component, or nil if it is not bound
+func (tc *TidbCluster) NodeBinding(compType MemberType, ordinal int32) *NodeBinding {
+	bindings := tc.NodeBindings(compType)
+	for i := range bindings {
+		if bindings[i].Ordinal == ordinal {
+			return &bindings[i]
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the node is one of the nodes of the binding
+func (b *NodeBinding) Matches(node *corev1.Node) bool {
+	if b.NodeName != "" {
+		return node.Name == b.NodeName
+	}
+	return labels.SelectorFromSet(b.NodeSelector).Matches(labels.Set(node.Labels))
+}
+
+// NodeSelectorTerm returns the node selector term of the required node affinity selecting the nodes of the binding
+func (b *NodeBinding) NodeSelectorTerm() corev1.NodeSelectorTerm {
+	term := corev1.NodeSelectorTerm{}
+	if b.NodeName != "" {
+		term.MatchFields = []corev1.NodeSelectorRequirement{{
+			Key:      "metadata.name",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{b.NodeName},
+		}}
+		return term
+	}
+	keys := make([]string, 0, len(b.NodeSelector))
+	for k := range b.NodeSelector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+			Key:      k,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{b.NodeSelector[k]},
+		})
+	}
+	return term
+}
@@ -51,6 +51,9 @@ type ComponentAccessor interface {
 	StatefulSetUpdateStrategy() apps.StatefulSetUpdateStrategyType
 	PodManagementPolicy() apps.PodManagementPolicyType
 	TopologySpreadConstraints() []corev1.TopologySpreadConstraint
+	PrepullNextImage() bool
+	Sizing() *Sizing
+	WaitForExternalProvisioning() bool
 }
 
 // Component defines component identity of all components
@@ -331,6 +334,27 @@ func (a *componentAccessorImpl) TerminationGracePeriodSeconds() *int64 {
 	return a.ComponentSpec.TerminationGracePeriodSeconds
 }
 
+func (a *componentAccessorImpl) PrepullNextImage() bool {
+	if a.ComponentSpec == nil || a.ComponentSpec.PrepullNextImage == nil {
+		return false
+	}
+	return *a.ComponentSpec.PrepullNextImage
+}
+
+func (a *componentAccessorImpl) Sizing() *Sizing {
+	if a.ComponentSpec == nil {
+		return nil
+	}
+	return a.ComponentSpec.Sizing
+}
+
+func (a *componentAccessorImpl) WaitForExternalProvisioning() bool {
+	if a.ComponentSpec == nil || a.ComponentSpec.WaitForExternalProvisioning == nil {
+		return false
+	}
+	return *a.ComponentSpec.WaitForExternalProvisioning
+}
+
 func (a *componentAccessorImpl) TopologySpreadConstraints() []corev1.TopologySpreadConstraint {
 	tscs := a.topologySpreadConstraints
 	if a.ComponentSpec != nil && len(a.ComponentSpec.TopologySpreadConstraints) > 0 {
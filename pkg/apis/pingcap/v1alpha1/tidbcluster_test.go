@@ -15,6 +15,7 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	apps "k8s.io/api/apps/v1"
@@ -22,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 )
 
@@ -568,6 +570,196 @@ func TestPDVersion(t *testing.T) {
 	}
 }
 
+func TestIsComponentPaused(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbCluster()
+	g.Expect(tc.IsComponentPaused(PDMemberType)).To(BeFalse())
+	g.Expect(tc.IsComponentPaused(TiKVMemberType)).To(BeFalse())
+	g.Expect(tc.IsComponentPaused(TiDBMemberType)).To(BeFalse())
+
+	tc.Spec.TiKV.Paused = true
+	g.Expect(tc.IsComponentPaused(PDMemberType)).To(BeFalse())
+	g.Expect(tc.IsComponentPaused(TiKVMemberType)).To(BeTrue())
+	g.Expect(tc.IsComponentPaused(TiDBMemberType)).To(BeFalse())
+	// component not deployed
+	g.Expect(tc.IsComponentPaused(TiFlashMemberType)).To(BeFalse())
+
+	tc.Spec.TiKV.Paused = false
+	tc.Spec.Paused = true
+	g.Expect(tc.IsComponentPaused(PDMemberType)).To(BeTrue())
+	g.Expect(tc.IsComponentPaused(TiKVMemberType)).To(BeTrue())
+	g.Expect(tc.IsComponentPaused(TiDBMemberType)).To(BeTrue())
+}
+
+func TestComponentUpgradeInProgress(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbCluster()
+	tc.Status.TiKV.Phase = UpgradePhase
+	g.Expect(tc.ComponentUpgradeInProgress(TiKVMemberType)).To(BeTrue())
+	g.Expect(tc.ComponentUpgradeInProgress(PDMemberType)).To(BeFalse())
+
+	// the upgrade of a paused component is frozen
+	tc.Spec.TiKV.Paused = true
+	g.Expect(tc.ComponentUpgradeInProgress(TiKVMemberType)).To(BeFalse())
+
+	// all components are frozen if the cluster is paused, keep the upgrade in progress
+	tc.Spec.Paused = true
+	g.Expect(tc.ComponentUpgradeInProgress(TiKVMemberType)).To(BeTrue())
+}
+
+func TestUpgradeOrder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbCluster()
+	g.Expect(tc.UpgradeOrder()).To(Equal(DefaultUpgradeOrder))
+	tc.Status.PD.Phase = UpgradePhase
+	tc.Status.TiFlash.Phase = UpgradePhase
+	g.Expect(tc.UpgradeBlockedBy(PDMemberType)).To(BeEmpty())
+	g.Expect(tc.UpgradeBlockedBy(TiKVMemberType)).To(Equal([]MemberType{PDMemberType, TiFlashMemberType}))
+
+	// tidb waits for ticdc by default
+	tc.Status.TiCDC.Phase = UpgradePhase
+	g.Expect(tc.UpgradeBlockedBy(TiDBMemberType)).To(ContainElement(TiCDCMemberType))
+	tc.Status.TiCDC.Phase = NormalPhase
+
+	// the components missing in the custom order are upgraded after the ones in it
+	tc.Spec.UpgradeOrder = []MemberType{TiKVMemberType, TiDBMemberType, PDMemberType}
+	g.Expect(tc.UpgradeOrder()).To(Equal([]MemberType{TiKVMemberType, TiDBMemberType, PDMemberType, TiFlashMemberType, PumpMemberType, TiCDCMemberType}))
+	tc.Status.TiCDC.Phase = UpgradePhase
+	g.Expect(tc.UpgradeBlockedBy(TiDBMemberType)).NotTo(ContainElement(TiCDCMemberType))
+	tc.Status.TiCDC.Phase = NormalPhase
+	g.Expect(tc.UpgradeBlockedBy(TiKVMemberType)).To(BeEmpty())
+	g.Expect(tc.UpgradeBlockedBy(TiFlashMemberType)).To(Equal([]MemberType{PDMemberType}))
+
+	// the paused components do not block the others
+	tc.Spec.PD.Paused = true
+	g.Expect(tc.UpgradeBlockedBy(TiCDCMemberType)).To(Equal([]MemberType{TiFlashMemberType}))
+
+	// tidb pinned to its canaries blocks the components after it in the order only
+	tc = newTidbCluster()
+	tc.Spec.TiDB.CanaryOrdinals = []int32{2}
+	tc.Status.TiDB.Phase = UpgradePhase
+	for _, compType := range []MemberType{PDMemberType, TiFlashMemberType, TiKVMemberType, PumpMemberType, TiCDCMemberType} {
+		g.Expect(tc.UpgradeBlockedBy(compType)).To(BeEmpty())
+	}
+	g.Expect(tc.UpgradeBlockedBy(TiDBMemberType)).To(BeEmpty())
+	tc.Spec.UpgradeOrder = []MemberType{PDMemberType, TiDBMemberType, TiKVMemberType}
+	g.Expect(tc.UpgradeBlockedBy(PDMemberType)).To(BeEmpty())
+	g.Expect(tc.UpgradeBlockedBy(TiKVMemberType)).To(Equal([]MemberType{TiDBMemberType}))
+	g.Expect(tc.UpgradeBlockedBy(TiCDCMemberType)).To(Equal([]MemberType{TiDBMemberType}))
+	tc.Status.TiDB.Phase = NormalPhase
+	g.Expect(tc.UpgradeBlockedBy(TiKVMemberType)).To(BeEmpty())
+}
+
+func TestTiDBUpgradeBatchSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tidb := &TiDBSpec{Replicas: 5}
+	g.Expect(tidb.GetUpgradeBatchSize()).To(Equal(int32(1)))
+
+	// the percentage is rounded down and the batch size is kept between 1 and replicas - 1
+	for _, c := range []struct {
+		size   intstr.IntOrString
+		expect int32
+	}{
+		{size: intstr.FromInt(2), expect: 2},
+		{size: intstr.FromInt(5), expect: 4},
+		{size: intstr.FromInt(0), expect: 1},
+		{size: intstr.FromString("40%"), expect: 2},
+		{size: intstr.FromString("10%"), expect: 1},
+		{size: intstr.FromString("100%"), expect: 4},
+	} {
+		size := c.size
+		tidb.UpgradeBatchSize = &size
+		g.Expect(tidb.GetUpgradeBatchSize()).To(Equal(c.expect), c.size.String())
+	}
+	tidb.Replicas = 1
+	g.Expect(tidb.GetUpgradeBatchSize()).To(Equal(int32(1)))
+}
+
+func TestTiDBUpgradeDrainConnectionThreshold(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tidb := &TiDBSpec{ScaleInDrain: &TiDBScaleInDrain{ConnectionThreshold: 10}}
+	g.Expect(tidb.GetUpgradeDrainConnectionThreshold()).To(Equal(int32(0)))
+	tidb.UpgradeDrainConnectionThreshold = pointer.Int32Ptr(5)
+	g.Expect(tidb.GetUpgradeDrainConnectionThreshold()).To(Equal(int32(5)))
+}
+
+func TestTiDBGroupSpec(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbCluster()
+	tc.Spec.TiDB.Labels = map[string]string{"pool": "default", "team": "db"}
+	tc.Spec.TiDB.Service = &TiDBServiceSpec{ServiceSpec: ServiceSpec{Type: corev1.ServiceTypeClusterIP}}
+	tc.Spec.TiDB.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")}
+
+	// the fields not set are inherited from spec.tidb
+	group := &TiDBGroup{Name: "reporting", Replicas: 2}
+	spec := tc.TiDBGroupSpec(group)
+	g.Expect(spec.Replicas).To(Equal(int32(2)))
+	g.Expect(spec.Labels).To(Equal(tc.Spec.TiDB.Labels))
+	g.Expect(spec.Service).To(Equal(tc.Spec.TiDB.Service))
+	g.Expect(spec.Limits).To(Equal(tc.Spec.TiDB.Limits))
+
+	group.Labels = map[string]string{"pool": "reporting"}
+	group.Service = &TiDBServiceSpec{ServiceSpec: ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}}
+	group.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Gi")}
+	spec = tc.TiDBGroupSpec(group)
+	g.Expect(spec.Labels).To(Equal(map[string]string{"pool": "reporting", "team": "db"}))
+	g.Expect(spec.Service.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+	g.Expect(spec.Limits.Memory().String()).To(Equal("32Gi"))
+	// spec.tidb is not changed
+	g.Expect(tc.Spec.TiDB.Labels["pool"]).To(Equal("default"))
+
+	tc.Spec.TiDB = nil
+	g.Expect(tc.TiDBGroupSpec(group)).To(BeNil())
+}
+
+func TestTiDBUpgradeReadinessTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tidb := &TiDBSpec{}
+	g.Expect(tidb.GetUpgradeReadinessTimeout()).To(BeZero())
+	tidb.UpgradeReadinessTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+	g.Expect(tidb.GetUpgradeReadinessTimeout()).To(Equal(10 * time.Minute))
+	tidb.UpgradeReadinessTimeout = &metav1.Duration{Duration: -time.Minute}
+	g.Expect(tidb.GetUpgradeReadinessTimeout()).To(BeZero())
+}
+
+func TestNodeBinding(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbCluster()
+	tc.Spec.TiKV.NodeBindings = []NodeBinding{
+		{Ordinal: 0, NodeName: "node-a"},
+		{Ordinal: 1, NodeSelector: map[string]string{"rack": "r1", "disk": "ssd"}},
+	}
+	g.Expect(tc.NodeBinding(PDMemberType, 0)).To(BeNil())
+	g.Expect(tc.NodeBinding(TiKVMemberType, 2)).To(BeNil())
+
+	byName := tc.NodeBinding(TiKVMemberType, 0)
+	g.Expect(byName.Matches(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}})).To(BeTrue())
+	g.Expect(byName.Matches(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}})).To(BeFalse())
+	g.Expect(byName.NodeSelectorTerm()).To(Equal(corev1.NodeSelectorTerm{
+		MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-a"}}},
+	}))
+
+	bySelector := tc.NodeBinding(TiKVMemberType, 1)
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{"rack": "r1", "disk": "ssd", "zone": "z1"}}}
+	g.Expect(bySelector.Matches(node)).To(BeTrue())
+	node.Labels["disk"] = "hdd"
+	g.Expect(bySelector.Matches(node)).To(BeFalse())
+	g.Expect(bySelector.NodeSelectorTerm()).To(Equal(corev1.NodeSelectorTerm{
+		MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}},
+			{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"r1"}},
+		},
+	}))
+}
+
 func newTidbCluster() *TidbCluster {
 	return &TidbCluster{
 		TypeMeta: metav1.TypeMeta{
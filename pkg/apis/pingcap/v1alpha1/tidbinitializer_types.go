@@ -91,6 +91,14 @@ type TidbInitializerSpec struct {
 	// +optional
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 
+	// Tolerations of TiDB initializer Pods, defaults to the job tolerations of tidb-operator if empty
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// NodeSelector of TiDB initializer Pods, defaults to the job node selector of tidb-operator if empty
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
 	// Time zone of TiDB initializer Pods
 	// +optional
 	Timezone string `json:"timezone,omitempty"`
@@ -99,6 +107,13 @@ type TidbInitializerSpec struct {
 	// Optional: Defaults to nil
 	// +optional
 	TLSClientSecretName *string `json:"tlsClientSecretName,omitempty"`
+
+	// TLSCASecretName is the name of secret which stores only the ca.crt of the CA signing the tidb server
+	// certificate, e.g. a private CA. The tidb server is verified by it and no client certificate is presented
+	// unless TLSClientSecretName is set.
+	// Optional: Defaults to nil
+	// +optional
+	TLSCASecretName *string `json:"tlsCASecretName,omitempty"`
 }
 
 // +k8s:openapi-gen=true
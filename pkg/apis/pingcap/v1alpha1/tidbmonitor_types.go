@@ -55,6 +55,12 @@ type TidbMonitorSpec struct {
 	// monitored TiDB cluster info
 	Clusters []TidbClusterRef `json:"clusters,omitempty"`
 
+	// ExternalTargets are the TiDB clusters not managed by TiDB Operator, e.g. the clusters on VMs,
+	// scraped by the statically-defined targets. The metrics are labeled the same as the metrics of
+	// the monitored TidbClusters, so the dashboards work unchanged.
+	// +optional
+	ExternalTargets []ExternalClusterTargets `json:"externalTargets,omitempty"`
+
 	// Prometheus spec
 	Prometheus PrometheusSpec `json:"prometheus"`
 
@@ -187,6 +193,43 @@ type TidbMonitorSpec struct {
 	Timezone string `json:"timezone,omitempty"`
 }
 
+// +k8s:openapi-gen=true
+// ExternalClusterTargets are the statically-defined targets of a TiDB cluster not managed by TiDB Operator
+type ExternalClusterTargets struct {
+	// Name of the cluster, it is the value of the `cluster` and `tidb_cluster` labels of the metrics.
+	// It must be distinct from the names of the monitored TidbClusters.
+	Name string `json:"name"`
+
+	// PD targets of the cluster
+	// +optional
+	PD *ExternalTargetGroup `json:"pd,omitempty"`
+
+	// TiDB targets of the cluster
+	// +optional
+	TiDB *ExternalTargetGroup `json:"tidb,omitempty"`
+
+	// TiKV targets of the cluster
+	// +optional
+	TiKV *ExternalTargetGroup `json:"tikv,omitempty"`
+}
+
+// +k8s:openapi-gen=true
+// ExternalTargetGroup is a group of the statically-defined targets of a component
+type ExternalTargetGroup struct {
+	// Targets are the status addresses of the instances in the form of host:port
+	Targets []string `json:"targets"`
+
+	// TLSSecret is the name of the Secret in the namespace of the TidbMonitor holding the client
+	// certificate `tls.crt`, `tls.key` and the CA `ca.crt` to scrape the targets over HTTPS.
+	// The targets are scraped over HTTP if it is empty.
+	// +optional
+	TLSSecret string `json:"tlsSecret,omitempty"`
+
+	// Labels are added to the metrics of the targets, the labels set by TidbMonitor can not be overridden
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
 // PrometheusReloaderSpec is the desired state of prometheus configuration reloader
 type PrometheusReloaderSpec struct {
 	MonitorContainer `json:",inline"`
@@ -305,6 +348,17 @@ type InitializerSpec struct {
 	MonitorContainer `json:",inline"`
 	// +optional
 	Envs map[string]string `json:"envs,omitempty"`
+	// TLSClientSecretName is the name of secret which stores tidb server client certificate, it is mounted to
+	// the initializer of the TiDB cluster and the paths are passed by the envs TIDB_SSL_CA, TIDB_SSL_CERT and
+	// TIDB_SSL_KEY
+	// Optional: Defaults to nil
+	// +optional
+	TLSClientSecretName *string `json:"tlsClientSecretName,omitempty"`
+	// TLSCASecretName is the name of secret which stores only the ca.crt of the CA signing the tidb server
+	// certificate, it takes precedence over the ca.crt in TLSClientSecretName
+	// Optional: Defaults to nil
+	// +optional
+	TLSCASecretName *string `json:"tlsCASecretName,omitempty"`
 }
 
 // ThanosSpec is the desired state of thanos sidecar
@@ -377,6 +431,10 @@ type TidbMonitorStatus struct {
 	DeploymentStorageStatus *DeploymentStorageStatus `json:"deploymentStorageStatus,omitempty"`
 
 	StatefulSet *apps.StatefulSetStatus `json:"statefulSet,omitempty"`
+
+	// StorageUsage is the summary of the storage usage of the Prometheus pods
+	// +optional
+	StorageUsage *StorageUsage `json:"storageUsage,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -20,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/util/config"
 )
@@ -29,6 +30,8 @@ const (
 	TiKVStateUp string = "Up"
 	// TiKVStateDown represents status of Down of TiKV
 	TiKVStateDown string = "Down"
+	// TiKVStateDisconnected represents status of Disconnected of TiKV
+	TiKVStateDisconnected string = "Disconnected"
 	// TiKVStateOffline represents status of Offline of TiKV
 	TiKVStateOffline string = "Offline"
 	// TiKVStateTombstone represents status of Tombstone of TiKV
@@ -168,6 +171,16 @@ type TidbClusterSpec struct {
 	// +optional
 	TiDB *TiDBSpec `json:"tidb,omitempty"`
 
+	// TiDBGroups are the named groups of TiDB servers sharing the PD and TiKV of the cluster, e.g. a pool
+	// for OLTP and a pool for reporting. Each group has its own StatefulSet, Services and ConfigMap named
+	// <cluster>-tidb-<group>, and inherits the fields it does not set from spec.tidb, which is the implicit
+	// default group and is required. The groups are upgraded one at a time after the default group, in
+	// their order in the list.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	TiDBGroups []TiDBGroup `json:"tidbGroups,omitempty"`
+
 	// TiKV cluster spec
 	// +optional
 	TiKV *TiKVSpec `json:"tikv,omitempty"`
@@ -213,6 +226,18 @@ type TidbClusterSpec struct {
 	// +optional
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 
+	// PrePullImages pre-pulls the new image of PD, TiKV, TiFlash, TiDB and TiCDC by a DaemonSet on the nodes
+	// running the component when the image is changed, the rolling update of the component waits until the
+	// image is pulled on all these nodes. Disable it to upgrade without waiting if a node cannot pull the image.
+	// Optional: Defaults to false
+	// +optional
+	PrePullImages bool `json:"prePullImages,omitempty"`
+
+	// Tier is the tier of the cluster, e.g. gold, the config fragments of PD, TiKV and TiDB of the tier in the
+	// ConfigMap set by -tier-config-configmap of tidb-controller-manager are merged under the config of the cluster
+	// +optional
+	Tier string `json:"tier,omitempty"`
+
 	// ConfigUpdateStrategy determines how the configuration change is applied to the cluster.
 	// UpdateStrategyInPlace will update the ConfigMap of configuration in-place and an extra rolling-update of the
 	// cluster component is needed to reload the configuration change.
@@ -323,6 +348,103 @@ type TidbClusterSpec struct {
 	// +listType=map
 	// +listMapKey=topologyKey
 	TopologySpreadConstraints []TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// DeletionPolicy enables the graceful deletion of the TidbCluster, the components are
+	// torn down in order: TiDB, TiFlash/TiCDC/Pump, TiKV, PD and discovery.
+	// If not set, all the resources are removed at once by the garbage collector.
+	// +optional
+	DeletionPolicy *DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DeletionProtection protects the TidbCluster from accidental deletion, the TidbCluster being deleted
+	// stays terminating with its components running until it is annotated with
+	// tidb.pingcap.com/confirm-deletion=<name of the TidbCluster>.
+	// Optional: Defaults to the -deletion-protection of tidb-controller-manager
+	// +optional
+	DeletionProtection *bool `json:"deletionProtection,omitempty"`
+
+	// ConnectivityChecks probes the connectivity between the components periodically, the result is
+	// surfaced in status.connectivity and the ComponentConnectivity condition
+	// +optional
+	ConnectivityChecks *ConnectivityChecks `json:"connectivityChecks,omitempty"`
+
+	// AllowAdoption makes tidb-operator repair the ownerReferences of the StatefulSets, Services,
+	// ConfigMaps and PVCs of the cluster which refer to a TidbCluster of the same name but a
+	// different UID, e.g. the namespace is restored by Velero, so that they are adopted by the
+	// TidbCluster instead of being garbage collected.
+	// Optional: Defaults to false
+	// +optional
+	AllowAdoption bool `json:"allowAdoption,omitempty"`
+
+	// StorageWarningThreshold is the percent of the used storage of a pod of PD, TiKV, TiFlash or Pump
+	// above which the StoragePressure condition is set
+	// Optional: Defaults to 80
+	// +optional
+	StorageWarningThreshold *int32 `json:"storageWarningThreshold,omitempty"`
+
+	// UpgradeOrder is the order in which the components are upgraded, the upgrade of a component waits for
+	// the upgrades of the components before it to finish. It must contain all the components deployed.
+	// Optional: Defaults to [pd, tiflash, tikv, pump, ticdc, tidb]
+	// +optional
+	UpgradeOrder []MemberType `json:"upgradeOrder,omitempty"`
+
+	// BootstrapTimeout is the time PD is given to become healthy after the cluster is created, in the format of
+	// Go Duration. The BootstrapFailed condition is set and the other components are not synced if PD does not
+	// become healthy in time, until PD recovers.
+	// Optional: Defaults to 30m
+	// +optional
+	BootstrapTimeout *string `json:"bootstrapTimeout,omitempty"`
+
+	// Networking is the networking of the client traffic to PD and TiKV
+	// +optional
+	Networking *NetworkingSpec `json:"networking,omitempty"`
+}
+
+// NetworkingSpec is the networking of the client traffic to the components
+// +k8s:openapi-gen=true
+type NetworkingSpec struct {
+	// TopologyAwareRouting makes the clients prefer the PD and TiKV endpoints in their own zones to cut the
+	// cross-zone traffic. The PD service and the peer services of PD and TiKV are annotated for the topology
+	// aware routing of kube-proxy, and a headless service is created for each zone the PD and TiKV pods are
+	// in, which selects the pods by the zones of their nodes. It only affects the clients resolving PD and
+	// TiKV through these services.
+	// Optional: Defaults to false
+	// +optional
+	TopologyAwareRouting bool `json:"topologyAwareRouting,omitempty"`
+}
+
+// ConnectivityChecks is the deep probe of the connectivity between the components
+// +k8s:openapi-gen=true
+type ConnectivityChecks struct {
+	// Enabled is whether the connectivity is probed
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Interval between the probes, in the format of Go Duration.
+	// Optional: Defaults to 5m
+	// +optional
+	Interval *string `json:"interval,omitempty"`
+}
+
+// DeletionPolicy is the policy of deleting a TidbCluster
+// +k8s:openapi-gen=true
+type DeletionPolicy struct {
+	// FinalBackup is the backup taken before the TidbCluster is torn down
+	// +optional
+	FinalBackup *FinalBackup `json:"finalBackup,omitempty"`
+}
+
+// FinalBackup describes the backup taken before deleting a TidbCluster
+// +k8s:openapi-gen=true
+type FinalBackup struct {
+	// BackupScheduleName is the name of the BackupSchedule in the namespace of the TidbCluster,
+	// its backupTemplate is used to create the final Backup.
+	BackupScheduleName string `json:"backupScheduleName"`
+
+	// Timeout after which the teardown starts even if the final Backup is not complete,
+	// in the format of Go Duration.
+	// Optional: Defaults to 1h
+	// +optional
+	Timeout *string `json:"timeout,omitempty"`
 }
 
 // TidbClusterStatus represents the current status of a tidb cluster.
@@ -335,12 +457,86 @@ type TidbClusterStatus struct {
 	TiFlash    TiFlashStatus             `json:"tiflash,omitempty"`
 	TiCDC      TiCDCStatus               `json:"ticdc,omitempty"`
 	AutoScaler *TidbClusterAutoScalerRef `json:"auto-scaler,omitempty"`
+	// TiDBGroups are the status of spec.tidbGroups by the names of the groups
+	// +optional
+	TiDBGroups map[string]TiDBStatus `json:"tidbGroups,omitempty"`
+	// LastReconcileBy is the identity of the tidb-controller-manager instance
+	// which drove the upgrade of the cluster most recently
+	// +optional
+	LastReconcileBy string `json:"lastReconcileBy,omitempty"`
+	// LastReconcileTime is the last time the upgrade of the cluster was driven
+	// +optional
+	// +nullable
+	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+	// Connectivity is the result of the latest connectivity probe
+	// +optional
+	Connectivity *ConnectivityStatus `json:"connectivity,omitempty"`
+	// Restarts are the graceful rolling restarts of the components requested by the annotation
+	// tidb.pingcap.com/restart by the names of the components
+	// +optional
+	Restarts map[string]ComponentRestartStatus `json:"restarts,omitempty"`
+	// ConfigHashes are the hashes of the rendered configs of the components by the names of the components, the
+	// history of the configs is recorded in the ConfigMap <cluster>-config-history
+	// +optional
+	ConfigHashes map[string]string `json:"configHashes,omitempty"`
+	// UpgradeCompletionWebhooks are the progress of calling the upgrade completion webhooks by the names of
+	// the components, an entry is removed after the webhook succeeds or its timeout elapses
+	// +optional
+	UpgradeCompletionWebhooks map[string]UpgradeCompletionWebhookStatus `json:"upgradeCompletionWebhooks,omitempty"`
 	// Represents the latest available observations of a tidb cluster's state.
 	// +optional
 	// +nullable
 	Conditions []TidbClusterCondition `json:"conditions,omitempty"`
 }
 
+// StorageUsage is the summary of the storage usage of the pods of a component
+type StorageUsage struct {
+	// MaxUsedPercent is the highest percent of the used storage among the pods
+	MaxUsedPercent int32 `json:"maxUsedPercent"`
+	// MaxUsedPod is the pod using the highest percent of its storage
+	// +optional
+	MaxUsedPod string `json:"maxUsedPod,omitempty"`
+	// PodsOverThreshold are the pods whose used storage exceeds the warning threshold
+	// +optional
+	PodsOverThreshold []string `json:"podsOverThreshold,omitempty"`
+	// LastUpdateTime is the time the storage usage is collected
+	// +nullable
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// ComponentRestartStatus is the status of the graceful rolling restart of a component
+type ComponentRestartStatus struct {
+	// Generation is the restart generation in the pod template of the component, it is increased by every restart
+	Generation int64 `json:"generation"`
+	// RequestTime is the time the latest restart is requested
+	// +nullable
+	RequestTime metav1.Time `json:"requestTime,omitempty"`
+	// CompletionTime is the time all the pods of the component are restarted by the latest restart
+	// +optional
+	// +nullable
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// UpgradeCompletionWebhookStatus is the progress of calling the upgrade completion webhook of a component, the
+// requests are sent in the background and retried with backoff until the webhook succeeds or its timeout elapses
+type UpgradeCompletionWebhookStatus struct {
+	// Revision is the revision of the component the webhook is called for
+	Revision string `json:"revision"`
+	// Attempts is the number of the requests sent
+	Attempts int32 `json:"attempts"`
+	// LastAttemptTime is the time the last request is sent
+	// +nullable
+	LastAttemptTime metav1.Time `json:"lastAttemptTime,omitempty"`
+	// NextAttemptTime is the time the next request is sent after the last one fails, it is not set while the last
+	// request is in flight
+	// +optional
+	// +nullable
+	NextAttemptTime *metav1.Time `json:"nextAttemptTime,omitempty"`
+	// LastError is the error of the last request which failed
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
 // TidbClusterCondition describes the state of a tidb cluster at a certain point.
 type TidbClusterCondition struct {
 	// Type of the condition.
@@ -362,6 +558,30 @@ type TidbClusterCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
+// ConnectivityStatus is the connectivity matrix between the components
+type ConnectivityStatus struct {
+	// LastProbeTime is the time of the latest probe
+	// +nullable
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+	// Edges are the connectivity from the members of a component to another component
+	// +optional
+	Edges []ConnectivityEdge `json:"edges,omitempty"`
+}
+
+// ConnectivityEdge is the connectivity from the members of a component to another component
+type ConnectivityEdge struct {
+	From MemberType `json:"from"`
+	To   MemberType `json:"to"`
+	// Connected is the number of the members connected
+	Connected int32 `json:"connected"`
+	// Broken are the members failing to connect
+	// +optional
+	Broken []string `json:"broken,omitempty"`
+	// Unknown are the members the connectivity of which can not be probed
+	// +optional
+	Unknown []string `json:"unknown,omitempty"`
+}
+
 // TidbClusterConditionType represents a tidb cluster condition value.
 type TidbClusterConditionType string
 
@@ -374,12 +594,42 @@ const (
 	// - All TiKV stores are up.
 	// - All TiFlash stores are up.
 	TidbClusterReady TidbClusterConditionType = "Ready"
+	// TidbClusterConfigRestartRequired indicates that some TiDB instances have not adopted the config
+	// updated in place and must be restarted to apply it, i.e. the config update strategy of TiDB is
+	// InPlace. It is Unknown after the config is updated until the instances are verified.
+	TidbClusterConfigRestartRequired TidbClusterConditionType = "ConfigRestartRequired"
+	// TidbClusterComponentConnectivity indicates whether the components are able to reach each other,
+	// it is only set if the connectivity checks are enabled.
+	TidbClusterComponentConnectivity TidbClusterConditionType = "ComponentConnectivity"
+	// TidbClusterStoragePressure indicates whether the used storage of any pod of PD, TiKV, TiFlash or Pump
+	// exceeds spec.storageWarningThreshold.
+	TidbClusterStoragePressure TidbClusterConditionType = "StoragePressure"
+	// TidbClusterBootstrapFailed indicates that PD does not become healthy within spec.bootstrapTimeout of the
+	// creation of the cluster, it is removed once PD becomes healthy.
+	TidbClusterBootstrapFailed TidbClusterConditionType = "BootstrapFailed"
+	// TidbClusterUpgradeStalled indicates that an upgraded TiDB pod is not ready longer than
+	// spec.tidb.upgradeReadinessTimeout, it is removed once the upgrade moves on or is done.
+	TidbClusterUpgradeStalled TidbClusterConditionType = "UpgradeStalled"
 )
 
 // The `Type` of the component condition
 const (
 	// ComponentVolumeResizing indicates that any volume of this component is resizing.
 	ComponentVolumeResizing string = "ComponentVolumeResizing"
+	// ComponentPaused indicates that the reconciling of this component is paused.
+	ComponentPaused string = "ComponentPaused"
+	// ComponentUpgradeFinalizing indicates that the upgrade of this component is done and
+	// waiting for the upgrade completion webhook.
+	ComponentUpgradeFinalizing string = "ComponentUpgradeFinalizing"
+	// ComponentStabilizingAfterUpgrade indicates that all the instances of this component are
+	// upgraded and the upgrade is waiting for the region scheduling caused by it to settle.
+	ComponentStabilizingAfterUpgrade string = "StabilizingAfterUpgrade"
+	// ComponentExternalProvisioningTimedOut indicates that some pods of this component are gated
+	// by the external provisioning for longer than -external-provisioning-timeout.
+	ComponentExternalProvisioningTimedOut string = "ExternalProvisioningTimedOut"
+	// ComponentDowngradeBlocked indicates that the image of this component is changed to a lower major
+	// or minor version and the old pod template is kept.
+	ComponentDowngradeBlocked string = "DowngradeBlocked"
 )
 
 // +k8s:openapi-gen=true
@@ -461,6 +711,99 @@ type PDSpec struct {
 	// +optional
 	// +kubebuilder:validation:Enum:="";"v1"
 	StartUpScriptVersion string `json:"startUpScriptVersion,omitempty"`
+
+	// Paused stops reconciling PD while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of PD is done, PD stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
+
+	// DashboardIngress exposes the TiDB Dashboard served by PD with an Ingress,
+	// the Ingress is removed if it is not set.
+	// +optional
+	DashboardIngress *DashboardIngressSpec `json:"dashboardIngress,omitempty"`
+
+	// AutoTuneScheduling tunes the scheduling params of PD by the number of the stores and the average region
+	// size, including the schedule limits, the store limit and the patrol region interval. A param is only
+	// applied when it differs from the live value by more than 20%, and it is no longer tuned once it is changed
+	// by the user or set in the config.
+	// Optional: Defaults to false
+	// +optional
+	AutoTuneScheduling bool `json:"autoTuneScheduling,omitempty"`
+
+	// MemberPriorities are the leader priorities of the PD members by the zones of their nodes, the key is the
+	// value of the `topology.kubernetes.io/zone` label of the node, or the `failure-domain.beta.kubernetes.io/zone`
+	// label if the former is absent. PD prefers the member with the higher priority as the leader. The
+	// priorities are applied to the members after they join and the members in the zones not here have the
+	// priority 0. The priorities are left as they are if it is not set.
+	// +optional
+	MemberPriorities map[string]int32 `json:"memberPriorities,omitempty"`
+
+	// NodeBindings pin the pods of the ordinals to the nodes by the required node affinity, so that they
+	// come up on the nodes of their local volumes after the whole cluster restarts. Every ordinal in
+	// [0, replicas) must be bound. The affinity is injected when the pods are created by the pod mutating
+	// webhook of the admission webhook, which must be enabled by `admissionWebhook.mutation.pods`. A pod not
+	// on the node of its binding is moved by deleting it once the members are all healthy, one pod at a time, the leader is transferred away first.
+	// +optional
+	NodeBindings []NodeBinding `json:"nodeBindings,omitempty"`
+}
+
+// DashboardIngressSpec describes the Ingress of the TiDB Dashboard
+// +k8s:openapi-gen=true
+type DashboardIngressSpec struct {
+	// Host of the Ingress, the dashboard is served at `<host>/dashboard/`
+	Host string `json:"host"`
+
+	// IngressClassName of the Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// Annotations of the Ingress
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLSSecretName is the name of the secret holding the certificate of the host,
+	// the dashboard is served over HTTP if it is empty
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// AuthProxy authenticates the requests before they reach the dashboard
+	// +optional
+	AuthProxy *DashboardAuthProxy `json:"authProxy,omitempty"`
+}
+
+// DashboardAuthProxyType is the type of the auth proxy of the TiDB Dashboard
+type DashboardAuthProxyType string
+
+const (
+	// DashboardAuthProxyBasicAuth authenticates the requests by the basic auth of ingress-nginx
+	DashboardAuthProxyBasicAuth DashboardAuthProxyType = "basic-auth"
+	// DashboardAuthProxyOAuth2Proxy authenticates the requests by an oauth2-proxy Deployment
+	DashboardAuthProxyOAuth2Proxy DashboardAuthProxyType = "oauth2-proxy"
+)
+
+// DashboardAuthProxy describes the auth proxy of the TiDB Dashboard
+// +k8s:openapi-gen=true
+type DashboardAuthProxy struct {
+	// Type of the auth proxy, `basic-auth` or `oauth2-proxy`.
+	// `basic-auth` requires ingress-nginx, the secret must contain the key `auth` in the htpasswd format.
+	// `oauth2-proxy` deploys oauth2-proxy in front of PD, the keys of the secret are set as the envs
+	// of oauth2-proxy, e.g. OAUTH2_PROXY_CLIENT_ID, OAUTH2_PROXY_CLIENT_SECRET and OAUTH2_PROXY_COOKIE_SECRET.
+	// +kubebuilder:validation:Enum:="basic-auth";"oauth2-proxy"
+	Type DashboardAuthProxyType `json:"type"`
+
+	// SecretName is the name of the secret holding the configuration of the auth proxy
+	SecretName string `json:"secretName"`
+
+	// Image of oauth2-proxy
+	// Optional: Defaults to quay.io/oauth2-proxy/oauth2-proxy:v7.2.1
+	// +optional
+	Image *string `json:"image,omitempty"`
 }
 
 // TiKVSpec contains details of TiKV members
@@ -565,6 +908,195 @@ type TiKVSpec struct {
 	// EnableNamedStatusPort enables status port(20180) in the Pod spec.
 	// If you set it to `true` for an existing cluster, the TiKV cluster will be rolling updated.
 	EnableNamedStatusPort bool `json:"enableNamedStatusPort,omitempty"`
+
+	// Paused stops reconciling TiKV while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of TiKV is done, TiKV stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
+
+	// UpgradeStabilization waits for the region scheduling caused by the upgrade to settle before the
+	// upgrade of TiKV is complete, it is disabled if it is not set.
+	// +optional
+	UpgradeStabilization *TiKVUpgradeStabilization `json:"upgradeStabilization,omitempty"`
+
+	// HostPorts allocates the host ports of TiKV from a range shared by the clusters on the same nodes,
+	// it takes effect only if hostNetwork is enabled. The advertise addresses are the IPs of the nodes
+	// and the pods using the same host ports are not scheduled to the same node.
+	// If it is not set, TiKV listens on 20160 and 20180 in the host network.
+	// Note: changing this for an existing cluster changes the addresses of the stores.
+	// +optional
+	HostPorts *HostPortAllocation `json:"hostPorts,omitempty"`
+
+	// AutoCleanGhostStores deletes the ghost stores in status.tikv.ghostStores which have no regions and have
+	// been ghosts for longer than 30 minutes.
+	// Optional: Defaults to false
+	// +optional
+	AutoCleanGhostStores bool `json:"autoCleanGhostStores,omitempty"`
+
+	// CPUPinning requires the TiKV pods to be in the Guaranteed QoS class with integer CPUs, so that the
+	// static CPU manager policy of kubelet pins exclusive CPUs to TiKV. The thread pools of TiKV are sized
+	// by the pinned CPUs unless they are set in the config.
+	// Optional: Defaults to false
+	// +optional
+	CPUPinning bool `json:"cpuPinning,omitempty"`
+
+	// Hugepages are the hugepages requested by TiKV, they are mounted at /dev/hugepages
+	// +optional
+	Hugepages *TiKVHugepages `json:"hugepages,omitempty"`
+
+	// PreProvisionVolumes pre-creates the PVCs of each new TiKV pod in the zone planned round-robin across the
+	// zones of the topology spread constraints before the replicas are increased, and increases the replicas
+	// after the PVCs are bound, so that the new stores spread over the zones with the volumes bound on the first
+	// consumer. It falls back to the plain scale-out if the StorageClass does not bind the volumes on the first
+	// consumer or no zone can be planned.
+	// Optional: Defaults to false
+	// +optional
+	PreProvisionVolumes bool `json:"preProvisionVolumes,omitempty"`
+
+	// SlowStoreMitigation detects the slow stores by the slow scores and the slow trends reported to PD and
+	// optionally reduces the leader weights of the slow stores until they recover, it is disabled if it is not set.
+	// +optional
+	SlowStoreMitigation *TiKVSlowStoreMitigation `json:"slowStoreMitigation,omitempty"`
+
+	// DirectProbe makes the operator probe the gRPC port of each TiKV store reported Up by PD directly.
+	// A store is counted as healthy by the upgrade and the failover only if it answers the probe with
+	// the store id known to PD, the divergences are reported in status.tikv.divergentStores and by events.
+	// +optional
+	DirectProbe bool `json:"directProbe,omitempty"`
+	// ForceDeleteStuckPods force deletes the TiKV pods stuck in Terminating on the unreachable nodes, so that
+	// the StatefulSet recreates them elsewhere and the failover makes progress. A pod is force deleted only if
+	// its node is not Ready and PD considers its store Down with no heartbeat within the threshold, it is
+	// disabled if it is not set.
+	// +optional
+	ForceDeleteStuckPods *ForceDeleteStuckPods `json:"forceDeleteStuckPods,omitempty"`
+
+	// NodeBindings pin the pods of the ordinals to the nodes by the required node affinity, so that they
+	// come up on the nodes of their local volumes after the whole cluster restarts. Every ordinal in
+	// [0, replicas) must be bound. The affinity is injected when the pods are created by the pod mutating
+	// webhook of the admission webhook, which must be enabled by `admissionWebhook.mutation.pods`. A pod not
+	// on the node of its binding is moved by deleting it once the stores are all Up, one pod at a time, the leaders of its store are evicted first.
+	// +optional
+	NodeBindings []NodeBinding `json:"nodeBindings,omitempty"`
+
+	// MaxTerminationGracePeriodSeconds enables deriving the termination grace period of the TiKV pods from the
+	// largest leader count of the stores reported by PD, one second per 1000 leaders on top of the default 30
+	// seconds and bounded by this value. The period is computed when the pod template changes, so that it is
+	// applied to the StatefulSet before the pods are rolled, it is not used if terminationGracePeriodSeconds is set.
+	// +optional
+	MaxTerminationGracePeriodSeconds *int64 `json:"maxTerminationGracePeriodSeconds,omitempty"`
+
+	// UpgradeSafetyChecks configures the checks done on a TiKV store before it is restarted during upgrade
+	// +optional
+	UpgradeSafetyChecks *TiKVUpgradeSafetyChecks `json:"upgradeSafetyChecks,omitempty"`
+}
+
+// TiKVSlowStoreMitigation is the config of the detection and the mitigation of the slow TiKV stores
+// +k8s:openapi-gen=true
+type TiKVSlowStoreMitigation struct {
+	// SlowScoreThreshold is the slow score in [1, 100] at or over which a check of the store is slow
+	// Optional: Defaults to 80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SlowScoreThreshold *int32 `json:"slowScoreThreshold,omitempty"`
+
+	// SlowTrendCauseThreshold is the latency of the disk IO of raftstore in microseconds in the slow trend at or
+	// over which a check of the store is slow, the slow trend is reported by TiKV v6.6+. The slow trend is not
+	// checked if it is not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SlowTrendCauseThreshold *int64 `json:"slowTrendCauseThreshold,omitempty"`
+
+	// ConsecutiveChecks is the number of the consecutive slow checks after which the store is slow, and the
+	// number of the consecutive normal checks after which the slow store recovers. The stores are checked
+	// every 30 seconds at most.
+	// Optional: Defaults to 3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ConsecutiveChecks *int32 `json:"consecutiveChecks,omitempty"`
+
+	// ReduceLeaderWeight reduces the leader weight of a slow store in PD so that its leaders are moved to the
+	// other stores, the original weight is restored after the store recovers. The leader weight of only one
+	// store is reduced at a time.
+	// Optional: Defaults to false
+	// +optional
+	ReduceLeaderWeight bool `json:"reduceLeaderWeight,omitempty"`
+
+	// LeaderWeightPercent is the percentage of the original leader weight the leader weight of a slow store
+	// is reduced to
+	// Optional: Defaults to 10
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	LeaderWeightPercent *int32 `json:"leaderWeightPercent,omitempty"`
+}
+
+// TiKVHugepages is the hugepages requested by TiKV
+// +k8s:openapi-gen=true
+type TiKVHugepages struct {
+	// Size is the size of a hugepage, 2Mi or 1Gi
+	// Optional: Defaults to 2Mi
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// Amount is the total amount of the hugepages, it must be a multiple of the size
+	Amount resource.Quantity `json:"amount"`
+}
+
+// HostPortAllocation is the range the host ports of a component are allocated from, each cluster is
+// allocated a distinct slot of the range when it joins the host network.
+// +k8s:openapi-gen=true
+type HostPortAllocation struct {
+	// Base is the first port of the range
+	// Optional: Defaults to 20160
+	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Base *int32 `json:"base,omitempty"`
+	// MaxSlots is the max number of the clusters sharing the range
+	// Optional: Defaults to 64
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxSlots *int32 `json:"maxSlots,omitempty"`
+}
+
+// TiKVUpgradeStabilization is the final gate of the upgrade of TiKV. After all the stores are upgraded,
+// TiKV stays in the Upgrade phase with the StabilizingAfterUpgrade condition until the number of the
+// pending PD operators and the number of the under-replicated regions return to the ones captured when
+// the upgrade starts.
+// +k8s:openapi-gen=true
+type TiKVUpgradeStabilization struct {
+	// MaxWait after which the upgrade is completed even if the regions are not settled, in the format
+	// of Go Duration.
+	// Optional: Defaults to 30m
+	// +optional
+	MaxWait *string `json:"maxWait,omitempty"`
+}
+
+// TiKVUpgradeSafetyChecks configures the checks done on a TiKV store before it is restarted during upgrade.
+// The checks are done after the leaders are evicted from the store.
+// +k8s:openapi-gen=true
+type TiKVUpgradeSafetyChecks struct {
+	// FlushBeforeRestart flushes the store through the TiKV status API before restarting it,
+	// which reduces the raft logs to apply after the restart. It is skipped with an event if
+	// the TiKV does not serve the API.
+	// Optional: Defaults to false
+	// +optional
+	FlushBeforeRestart bool `json:"flushBeforeRestart,omitempty"`
+
+	// MaxApplyLag is the max number of raft log entries committed but not yet applied by the peers
+	// on the store. If it is set, the operator waits for the apply lag to drop under it after the flush,
+	// the wait is bounded by evictLeaderTimeout.
+	// It only takes effect when flushBeforeRestart is true.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxApplyLag *int64 `json:"maxApplyLag,omitempty"`
 }
 
 // TiFlashSpec contains details of TiFlash members
@@ -621,6 +1153,17 @@ type TiFlashSpec struct {
 	// Failover is the configurations of failover
 	// +optional
 	Failover *Failover `json:"failover,omitempty"`
+
+	// Paused stops reconciling TiFlash while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of TiFlash is done, TiFlash stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
 }
 
 // TiCDCSpec contains details of TiCDC members
@@ -660,6 +1203,24 @@ type TiCDCSpec struct {
 	// Defaults to Kubernetes default storage class.
 	// +optional
 	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Paused stops reconciling TiCDC while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of TiCDC is done, TiCDC stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
+
+	// ChangefeedPriorities are the priorities of the changefeeds keyed by the changefeed IDs, the
+	// changefeeds not listed have the priority 0. If it is set, the captures are restarted one by one
+	// in the ascending order of the highest priority of the changefeeds they own during upgrades,
+	// so that the captures owning the critical changefeeds are restarted last.
+	// +optional
+	ChangefeedPriorities map[string]int32 `json:"changefeedPriorities,omitempty"`
 }
 
 // TiCDCConfig is the configuration of tidbcdc
@@ -800,6 +1361,144 @@ type TiDBSpec struct {
 	//
 	// +optional
 	Initializer *TiDBInitializer `json:"initializer,omitempty"`
+
+	// WarmStandbyUpgrade makes the upgrade bring up a standby pod on the new version for
+	// each ordinal and wait for it to be ready before the old pod is replaced.
+	// The standby pod is removed once the replaced pod is ready again.
+	// Optional: Defaults to false
+	// +optional
+	WarmStandbyUpgrade bool `json:"warmStandbyUpgrade,omitempty"`
+
+	// Paused stops reconciling TiDB while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of TiDB is done, TiDB stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
+
+	// ScaleInDrain makes the scale-in of TiDB drain the connections of the pod to be removed first.
+	// The pod is removed from the endpoints of the TiDB service by the readiness gate
+	// `tidb.pingcap.com/accepting-connections`, and it is removed after its connections fall to the
+	// threshold or the timeout elapses.
+	// Enabling or disabling it changes the pod template, so the TiDB pods are rolling updated.
+	// +optional
+	ScaleInDrain *TiDBScaleInDrain `json:"scaleInDrain,omitempty"`
+
+	// UpgradeBatchSize is the max number or percentage of TiDB pods upgraded at a time, and the availability budget
+	// of the upgrade, the percentage is of `replicas` and rounded down. The pods of a batch are deleted once the
+	// partition of the StatefulSet covers them, so that they are recreated on the new revision at the same time, and
+	// the next batch is selected only after all of them are ready and healthy. A ready and healthy pod is not upgraded
+	// if that would leave less than `replicas - upgradeBatchSize` TiDB pods on either revision ready and healthy, the
+	// pods which are not ready or unhealthy are upgraded anyway, as restarting them does not lower the availability.
+	// It must resolve to at least 1 pod and not exceed `replicas - 1`, so that at least one TiDB pod serves during the
+	// upgrade, and it is capped at that if the replicas are scaled in below it. PD and TiKV are always upgraded one pod
+	// at a time.
+	// Optional: Defaults to 1
+	// +optional
+	UpgradeBatchSize *intstr.IntOrString `json:"upgradeBatchSize,omitempty"`
+
+	// UpgradeReadinessTimeout is the max time an upgraded TiDB pod is given to become ready, counted from the
+	// last transition of its Ready condition. The upgrade still waits for the pod once it times out, but a
+	// Warning event is emitted and the condition UpgradeStalled of the TidbCluster is set until the upgrade moves on.
+	// Optional: Defaults to nil, the upgrade waits for the pod without timeout
+	// +optional
+	UpgradeReadinessTimeout *metav1.Duration `json:"upgradeReadinessTimeout,omitempty"`
+
+	// GracefulShutdownTimeoutSeconds is the max time the upgrade waits for the connections of the TiDB pods to
+	// be drained before they are restarted. The pods stop accepting new connections by the readiness gate
+	// `tidb.pingcap.com/accepting-connections`, and they are restarted after their connections fall to
+	// upgradeDrainConnectionThreshold or the timeout elapses. The pods which are not ready or unhealthy are
+	// restarted without draining.
+	// Setting it enables the readiness gate, which changes the pod template, so the TiDB pods are rolling updated.
+	// Optional: Defaults to 0, the pods are restarted without draining
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	GracefulShutdownTimeoutSeconds int32 `json:"gracefulShutdownTimeoutSeconds,omitempty"`
+	// UpgradeDrainConnectionThreshold is the number of the connections at or below which a TiDB pod drained
+	// before the upgrade is restarted, it only works with gracefulShutdownTimeoutSeconds. It is independent of
+	// the connectionThreshold of spec.tidb.scaleInDrain, which only applies to the scale-in.
+	// Optional: Defaults to 0, the pods are restarted after all their connections are closed
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UpgradeDrainConnectionThreshold *int32 `json:"upgradeDrainConnectionThreshold,omitempty"`
+	// ForceDeleteStuckPods force deletes the TiDB pods stuck in Terminating on the unreachable nodes, so that
+	// the StatefulSet recreates them elsewhere. A pod is force deleted only if its node is not Ready, it is
+	// disabled if it is not set.
+	// +optional
+	ForceDeleteStuckPods *ForceDeleteStuckPods `json:"forceDeleteStuckPods,omitempty"`
+	// CanaryOrdinals are the ordinals of the TiDB pods upgraded as the canaries, they must be the highest
+	// ordinals, e.g. [4, 5] for 6 replicas. The upgrade of TiDB stops after the canaries are upgraded and
+	// the other pods stay on the old revision until it is cleared, the upgrades of the components after TiDB
+	// in the upgrade order wait for it to be cleared too.
+	// +optional
+	CanaryOrdinals []int32 `json:"canaryOrdinals,omitempty"`
+	// ProxyProtocol makes TiDB accept the PROXY protocol from the load balancer in front of the TiDB service,
+	// so that the source IPs of the clients are preserved. The config of TiDB, the annotations of the service
+	// and the readiness probe are set consistently by it, changing it breaks the connections of the clients
+	// through the load balancer until both TiDB and the load balancer are rolled.
+	// +optional
+	ProxyProtocol *TiDBProxyProtocol `json:"proxyProtocol,omitempty"`
+}
+
+// TiDBAcceptingConnections is the condition of the readiness gate of the TiDB pods if
+// spec.tidb.scaleInDrain is enabled, it is False when the pod is drained before the scale-in
+const TiDBAcceptingConnections corev1.PodConditionType = "tidb.pingcap.com/accepting-connections"
+
+// TiDBScaleInDrain is the draining of the connections of the TiDB pod to be removed by the scale-in
+// +k8s:openapi-gen=true
+type TiDBScaleInDrain struct {
+	// ConnectionThreshold is the number of the connections at or below which the pod is removed.
+	// Optional: Defaults to 0
+	// +optional
+	ConnectionThreshold int32 `json:"connectionThreshold,omitempty"`
+
+	// Timeout after which the pod is removed even if its connections are above the threshold, in
+	// the format of Go Duration.
+	// If `graceful-wait-before-shutdown` is set in the config of TiDB and `terminationGracePeriodSeconds`
+	// is not set, the termination grace period of the pods is defaulted to cover the graceful wait.
+	// Optional: Defaults to 10m
+	// +optional
+	Timeout *string `json:"timeout,omitempty"`
+}
+
+// LoadBalancerProvider is the cloud provider of the load balancer of a service
+type LoadBalancerProvider string
+
+const (
+	// LoadBalancerProviderAWS is the Network Load Balancer or the Classic Load Balancer of AWS
+	LoadBalancerProviderAWS LoadBalancerProvider = "aws"
+	// LoadBalancerProviderGCP is the load balancer of GCP
+	LoadBalancerProviderGCP LoadBalancerProvider = "gcp"
+	// LoadBalancerProviderAzure is the load balancer of Azure
+	LoadBalancerProviderAzure LoadBalancerProvider = "azure"
+)
+
+// TiDBProxyProtocol is the PROXY protocol between the load balancer and TiDB
+// +k8s:openapi-gen=true
+type TiDBProxyProtocol struct {
+	// Enabled sets `proxy-protocol.networks` in the config of TiDB to the trusted networks, annotates the
+	// TiDB service for the load balancer of the provider and probes the readiness of TiDB on the status port,
+	// which does not speak the PROXY protocol.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// TrustedNetworks are the IPs or the CIDRs of the load balancer, the connections from which must send
+	// the PROXY protocol header, e.g. the subnets of the load balancer. The clients in the Kubernetes cluster
+	// connecting to TiDB directly must not be in them.
+	// +optional
+	TrustedNetworks []string `json:"trustedNetworks,omitempty"`
+
+	// Provider is the cloud provider of the load balancer, the annotations enabling the PROXY protocol on its
+	// load balancer are added to the TiDB service unless they are set in spec.tidb.service.annotations.
+	// The load balancer of the services of GCP does not send the PROXY protocol, so none is added for it
+	// and the PROXY protocol must be sent by the proxy load balancer in front of TiDB.
+	// +kubebuilder:validation:Enum=aws;gcp;azure
+	// +optional
+	Provider LoadBalancerProvider `json:"provider,omitempty"`
 }
 
 type TiDBInitializer struct {
@@ -869,6 +1568,17 @@ type PumpSpec struct {
 	// +k8s:openapi-gen=false
 	// For backward compatibility with helm chart
 	SetTimeZone *bool `json:"setTimeZone,omitempty"`
+
+	// Paused stops reconciling Pump while its status is still synced.
+	// The cluster-level `spec.paused` pauses all components regardless of this field.
+	// Optional: Defaults to false
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// UpgradeCompletionWebhook is called when the upgrade of Pump is done, Pump stays in the
+	// Upgrade phase until the webhook succeeds or its timeout elapses.
+	// +optional
+	UpgradeCompletionWebhook *UpgradeCompletionWebhook `json:"upgradeCompletionWebhook,omitempty"`
 }
 
 // HelperSpec contains details of helper component
@@ -973,7 +1683,7 @@ type ComponentSpec struct {
 	ConfigUpdateStrategy *ConfigUpdateStrategy `json:"configUpdateStrategy,omitempty"`
 
 	// List of environment variables to set in the container, like v1.Container.Env.
-	// Note that the following env names cannot be used and will be overridden by TiDB Operator builtin envs
+	// Note that the following env names are reserved by TiDB Operator builtin envs, they are rejected when a TidbCluster is created and overridden in existing TidbClusters
 	// - NAMESPACE
 	// - TZ
 	// - SERVICE_NAME
@@ -988,7 +1698,10 @@ type ComponentSpec struct {
 	// +optional
 	Env []corev1.EnvVar `json:"env,omitempty"`
 
-	// Extend the use scenarios for env
+	// Extend the use scenarios for env, like v1.Container.EnvFrom.
+	// The envs in `env` and TiDB Operator builtin envs take precedence over the ones from envFrom.
+	// Changes to the list roll the pods, but changes to the content of the referenced
+	// ConfigMaps and Secrets take effect only after the pods are restarted.
 	// +optional
 	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
 
@@ -1043,6 +1756,109 @@ type ComponentSpec struct {
 	// +listType=map
 	// +listMapKey=topologyKey
 	TopologySpreadConstraints []TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PrepullNextImage pre-pulls the new image on the node of the next pod to be upgraded while
+	// the current pod is upgrading, so that the next pod starts without waiting for the image.
+	// It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC, and is ignored if spec.prePullImages is enabled.
+	// Optional: Defaults to false
+	// +optional
+	PrepullNextImage *bool `json:"prepullNextImage,omitempty"`
+
+	// Sizing declares the resources of the component by a single size, the requests and the limits
+	// are derived from it. The requests and the limits set explicitly in the component override the
+	// derived ones of the same resources.
+	// It is only honored by PD, TiKV, TiFlash, TiDB, TiCDC and Pump.
+	// +optional
+	Sizing *Sizing `json:"sizing,omitempty"`
+
+	// WaitForExternalProvisioning gates the scheduling of each new pod until an external system, e.g.
+	// the IPAM or the volume pre-provisioning, annotates the pod with `tidb.pingcap.com/external-provisioned: "true"`.
+	// The gate is the pod annotation `tidb.pingcap.com/scheduling-gate` enforced by tidb-scheduler, so the
+	// component must be scheduled by tidb-scheduler. Changing it rolls the pods.
+	// It is only honored by PD, TiKV, TiFlash, TiDB and TiCDC.
+	// Optional: Defaults to false
+	// +optional
+	WaitForExternalProvisioning *bool `json:"waitForExternalProvisioning,omitempty"`
+}
+
+// SizeType is what the size of a component is
+type SizeType string
+
+const (
+	// SizeIsLimits means the size is the limits, the requests are derived from the limits
+	SizeIsLimits SizeType = "Limits"
+	// SizeIsRequests means the size is the requests, the limits are derived from the requests
+	SizeIsRequests SizeType = "Requests"
+)
+
+// Sizing declares the resources of a component by a single size
+// +k8s:openapi-gen=true
+type Sizing struct {
+	// Size of the component, e.g. `cpu: 4` and `memory: 16Gi`, the storage is not derived
+	Size corev1.ResourceList `json:"size"`
+
+	// SizeIs is what the size is, Limits or Requests.
+	// If the size is the limits, the requests are the size multiplied by requestsRatio.
+	// If the size is the requests, the limits are the size divided by requestsRatio.
+	// Optional: Defaults to Limits
+	// +kubebuilder:validation:Enum=Limits;Requests
+	// +optional
+	SizeIs SizeType `json:"sizeIs,omitempty"`
+
+	// RequestsRatio is the ratio of the requests to the limits, a decimal in (0, 1], e.g. "0.5".
+	// The derived cpu is rounded to millicores and the others to integers, the requests are rounded
+	// down and the limits are rounded up.
+	// Optional: Defaults to "1"
+	// +optional
+	RequestsRatio *string `json:"requestsRatio,omitempty"`
+}
+
+// UpgradeCompletionWebhook is the webhook confirming the completion of the upgrade of a component
+// +k8s:openapi-gen=true
+type UpgradeCompletionWebhook struct {
+	// URL of the webhook. tidb-operator sends a POST request with the namespace and the name of
+	// the TidbCluster, the component and its revision, and any 2xx response means success.
+	// The request is sent from tidb-controller-manager, so only the http and https URLs without user info
+	// are allowed, the loopback and link-local addresses (e.g. the cloud metadata endpoints) are refused,
+	// and the redirects are not followed. Restrict the egress of tidb-controller-manager by NetworkPolicy
+	// if the TidbClusters are created by untrusted users.
+	URL string `json:"url"`
+
+	// Timeout after which the upgrade is completed even if the webhook does not succeed, in the
+	// format of Go Duration.
+	// Optional: Defaults to 5m
+	// +optional
+	Timeout *string `json:"timeout,omitempty"`
+}
+
+// ForceDeleteStuckPods is the force deletion of the pods stuck in Terminating on the unreachable nodes.
+// The StatefulSet does not recreate a pod until the pod is deleted, which never happens if the kubelet of
+// its node is unreachable.
+// +k8s:openapi-gen=true
+type ForceDeleteStuckPods struct {
+	// Threshold is how long a pod must have been Terminating past its graceful termination deadline before
+	// it is force deleted, in the format of Go Duration.
+	// Optional: Defaults to 5m
+	// +optional
+	Threshold *string `json:"threshold,omitempty"`
+}
+
+// NodeBinding binds the pod of an ordinal to a node by its name or by a node selector.
+// The pod is not started on the other nodes, so its volumes must be usable on the node.
+// +k8s:openapi-gen=true
+type NodeBinding struct {
+	// Ordinal of the pod
+	// +kubebuilder:validation:Minimum=0
+	Ordinal int32 `json:"ordinal"`
+
+	// NodeName is the name of the node the pod is bound to
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// NodeSelector selects the nodes the pod is bound to by their labels,
+	// only one of nodeName and nodeSelector can be set
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 }
 
 // ServiceSpec specifies the service object in k8s
@@ -1091,6 +1907,38 @@ type ServiceSpec struct {
 	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
 }
 
+// TiDBGroup is a named group of TiDB servers, the fields not set are inherited from spec.tidb.
+// The pods of the group are scaled and upgraded like the ones of spec.tidb, they are not failed over.
+// +k8s:openapi-gen=true
+type TiDBGroup struct {
+	// Name of the group, it must be a DNS label starting with a letter and must not be "peer"
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// The desired ready replicas of the group
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+
+	// Config of the tidb-servers of the group, spec.tidb.config is used if it is not set
+	// +optional
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:XPreserveUnknownFields
+	Config *TiDBConfigWraper `json:"config,omitempty"`
+
+	// Resources of the tidb-servers of the group, the ones of spec.tidb are used if neither requests nor
+	// limits are set
+	// +optional
+	corev1.ResourceRequirements `json:",inline"`
+
+	// Labels of the pods of the group, they are merged with spec.tidb.labels
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Service of the group, spec.tidb.service is used if it is not set
+	// +optional
+	Service *TiDBServiceSpec `json:"service,omitempty"`
+}
+
 // TiDBServiceSpec defines `.tidb.service` field of `TidbCluster.spec`.
 // +k8s:openapi-gen=true
 type TiDBServiceSpec struct {
@@ -1136,6 +1984,10 @@ type PDStatus struct {
 	Synced      bool                    `json:"synced"`
 	Phase       MemberPhase             `json:"phase,omitempty"`
 	StatefulSet *apps.StatefulSetStatus `json:"statefulSet,omitempty"`
+	// StaleSince is the time the PD data this status is synced from is fetched, it is set if the
+	// circuit breaker of the PD API of the cluster is open and the status is synced from the cached data.
+	// +optional
+	StaleSince *metav1.Time `json:"staleSince,omitempty"`
 	// Members contains PDs in current TidbCluster
 	Members map[string]PDMember `json:"members,omitempty"`
 	// PeerMembers contains PDs NOT in current TidbCluster
@@ -1144,12 +1996,26 @@ type PDStatus struct {
 	FailureMembers  map[string]PDFailureMember `json:"failureMembers,omitempty"`
 	UnjoinedMembers map[string]UnjoinedMember  `json:"unjoinedMembers,omitempty"`
 	Image           string                     `json:"image,omitempty"`
+	// ImagePrePull is the progress of pre-pulling the new image, it is set if spec.prePullImages is
+	// enabled and cleared after the new image is applied.
+	// +optional
+	ImagePrePull *ImagePrePullStatus `json:"imagePrePull,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
+	// StorageUsage is the summary of the storage usage of the pods
+	// +optional
+	StorageUsage *StorageUsage `json:"storageUsage,omitempty"`
 	// Represents the latest available observations of a component's state.
 	// +optional
 	// +nullable
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// DashboardURL is the URL of the TiDB Dashboard exposed by the Ingress
+	// +optional
+	DashboardURL string `json:"dashboardURL,omitempty"`
 }
 
 // PDMember is PD member
@@ -1160,6 +2026,12 @@ type PDMember struct {
 	ID        string `json:"id"`
 	ClientURL string `json:"clientURL"`
 	Health    bool   `json:"health"`
+	// Zone is the zone of the node of the member, it is set if spec.pd.memberPriorities is set
+	// +optional
+	Zone string `json:"zone,omitempty"`
+	// LeaderPriority is the leader priority of the member in PD, it is set if spec.pd.memberPriorities is set
+	// +optional
+	LeaderPriority int32 `json:"leaderPriority,omitempty"`
 	// Last time the health transitioned from one to another.
 	// TODO: remove nullable, https://github.com/kubernetes/kubernetes/issues/86811
 	// +nullable
@@ -1199,14 +2071,54 @@ type TiDBStatus struct {
 	ResignDDLOwnerRetryCount int32                        `json:"resignDDLOwnerRetryCount,omitempty"`
 	Image                    string                       `json:"image,omitempty"`
 	PasswordInitialized      *bool                        `json:"passwordInitialized,omitempty"`
+	// ImagePrePull is the progress of pre-pulling the new image, it is set if spec.prePullImages is
+	// enabled and cleared after the new image is applied.
+	// +optional
+	ImagePrePull *ImagePrePullStatus `json:"imagePrePull,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
+	// ScaleInDrain is the progress of draining the connections of the pod to be removed by the
+	// scale-in, it is set if spec.tidb.scaleInDrain is enabled and cleared after the pod is removed.
+	// +optional
+	ScaleInDrain *TiDBScaleInDrainStatus `json:"scaleInDrain,omitempty"`
+	// UpgradeDrain is the progress of draining the connections of the pods to be upgraded, it is set if
+	// spec.tidb.gracefulShutdownTimeoutSeconds is set and cleared after the upgrade.
+	// +optional
+	UpgradeDrain *TiDBUpgradeDrainStatus `json:"upgradeDrain,omitempty"`
 	// Represents the latest available observations of a component's state.
 	// +optional
 	// +nullable
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// TiDBScaleInDrainStatus is the progress of draining the connections of a TiDB pod
+type TiDBScaleInDrainStatus struct {
+	// PodName is the name of the pod being drained
+	PodName string `json:"podName"`
+	// Connections is the number of the connections of the pod at the last check, -1 if unknown
+	Connections int32 `json:"connections"`
+	// Deadline after which the pod is removed regardless of its connections
+	Deadline metav1.Time `json:"deadline"`
+}
+
+// TiDBUpgradeDrainStatus is the progress of draining the connections of the TiDB pods upgraded at a time
+type TiDBUpgradeDrainStatus struct {
+	// PodNames are the names of the pods being drained
+	PodNames []string `json:"podNames"`
+	// Connections is the number of the connections of the pods at the last check, -1 if unknown
+	Connections int32 `json:"connections"`
+	// Deadline after which the pods are upgraded regardless of their connections
+	Deadline metav1.Time `json:"deadline"`
+	// Drained is true once the connections of the pods are drained or the deadline passes, the pods are being
+	// upgraded then
+	// +optional
+	Drained bool `json:"drained,omitempty"`
+}
+
 // TiDBMember is TiDB member
 type TiDBMember struct {
 	Name   string `json:"name"`
@@ -1250,6 +2162,29 @@ type EvictLeaderStatus struct {
 	Value         string      `json:"value,omitempty"`
 }
 
+// EvictLeaderProgress is the progress of evicting region leaders from the TiKV store being upgraded
+type EvictLeaderProgress struct {
+	// PodName is the name of the TiKV Pod being upgraded
+	PodName string `json:"podName"`
+	// ActiveLeaderCount is the number of leaders still to be evicted from the store
+	ActiveLeaderCount int32 `json:"activeLeaderCount"`
+	// HibernatedLeaderCount is the number of leaders of hibernated regions on the store.
+	// These leaders are not waited for because the regions serve no requests.
+	HibernatedLeaderCount int32 `json:"hibernatedLeaderCount"`
+	// LastUpdateTime is the last time the leader counts were observed
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// TiKVUpgradeBaseline is the region scheduling state of the cluster captured when the upgrade of TiKV starts
+type TiKVUpgradeBaseline struct {
+	// PendingOperatorCount is the number of the pending PD operators
+	PendingOperatorCount int32 `json:"pendingOperatorCount"`
+	// MissPeerRegionCount is the number of the under-replicated regions
+	MissPeerRegionCount int32 `json:"missPeerRegionCount"`
+	// CaptureTime is the time the baseline is captured
+	CaptureTime metav1.Time `json:"captureTime,omitempty"`
+}
+
 // TiKVStatus is TiKV status
 type TiKVStatus struct {
 	Synced          bool                          `json:"synced,omitempty"`
@@ -1263,14 +2198,105 @@ type TiKVStatus struct {
 	FailoverUID     types.UID                     `json:"failoverUID,omitempty"`
 	Image           string                        `json:"image,omitempty"`
 	EvictLeader     map[string]*EvictLeaderStatus `json:"evictLeader,omitempty"`
+	// StaleSince is the time the PD data this status is synced from is fetched, it is set if the
+	// circuit breaker of the PD API of the cluster is open and the status is synced from the cached data.
+	// +optional
+	StaleSince *metav1.Time `json:"staleSince,omitempty"`
+	// EvictLeaderProgress is the progress of evicting leaders from the store being upgraded,
+	// it is cleared after the upgrade is done.
+	// +optional
+	EvictLeaderProgress *EvictLeaderProgress `json:"evictLeaderProgress,omitempty"`
+	// UpgradeBaseline is the region scheduling state captured when the upgrade starts, it is set if
+	// the upgrade stabilization is enabled and cleared after the upgrade is done.
+	// +optional
+	UpgradeBaseline *TiKVUpgradeBaseline `json:"upgradeBaseline,omitempty"`
+	// HostPorts is the host ports allocated to TiKV from spec.tikv.hostPorts
+	// +optional
+	HostPorts *HostPorts `json:"hostPorts,omitempty"`
+	// PodHostPorts is the host ports in use by each pod in the host network
+	// +optional
+	PodHostPorts map[string]HostPorts `json:"podHostPorts,omitempty"`
+	// GhostStores are the Up or Offline stores in PD whose addresses are the ones of the pods of the cluster
+	// but are not backed by any current or expected pod, the key is the store id
+	// +optional
+	GhostStores map[string]GhostStore `json:"ghostStores,omitempty"`
+	// ImagePrePull is the progress of pre-pulling the new image, it is set if spec.prePullImages is
+	// enabled and cleared after the new image is applied.
+	// +optional
+	ImagePrePull *ImagePrePullStatus `json:"imagePrePull,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+	// VolumePreProvisioning is the progress of pre-provisioning the volumes of the new pods, it is set if
+	// spec.tikv.preProvisionVolumes is enabled and cleared after the scale-out is done.
+	// +optional
+	VolumePreProvisioning *VolumePreProvisioningStatus `json:"volumePreProvisioning,omitempty"`
+	// SlowStores are the stores being checked by spec.tikv.slowStoreMitigation which are slow or have slow
+	// checks, the key is the store id
+	// +optional
+	SlowStores map[string]TiKVSlowStore `json:"slowStores,omitempty"`
+	// DivergentStores are the stores reported Up by PD which fail the direct probe of spec.tikv.directProbe,
+	// the key is the store id
+	// +optional
+	DivergentStores map[string]TiKVDivergentStore `json:"divergentStores,omitempty"`
+	// VolumeReplace is the progress of the replacement of the pod and the volumes requested by the annotation
+	// tikv.tidb.pingcap.com/replace-volume, the latest replacement is kept after it completes.
+	// +optional
+	VolumeReplace *TiKVVolumeReplaceStatus `json:"volumeReplace,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
+	// StorageUsage is the summary of the storage usage of the pods
+	// +optional
+	StorageUsage *StorageUsage `json:"storageUsage,omitempty"`
 	// Represents the latest available observations of a component's state.
 	// +optional
 	// +nullable
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// ImagePrePullStatus is the progress of pre-pulling the new image of a component
+type ImagePrePullStatus struct {
+	// Image is the new image being pre-pulled
+	Image string `json:"image"`
+	// DesiredNodes is the number of the nodes to pre-pull the image on
+	DesiredNodes int32 `json:"desiredNodes"`
+	// ReadyNodes is the number of the nodes which have pulled the image
+	ReadyNodes int32 `json:"readyNodes"`
+}
+
+// UpgradeStatus is the progress of the rolling upgrade of a component
+type UpgradeStatus struct {
+	// StartTime is the time the first pod is upgraded
+	StartTime metav1.Time `json:"startTime"`
+	// UpgradingOrdinal is the ordinal of the pod being upgraded, the smallest one if the pods are
+	// upgraded in batches
+	// +optional
+	UpgradingOrdinal *int32 `json:"upgradingOrdinal,omitempty"`
+	// UpdatedReplicas is the number of the pods on the update revision of the statefulset
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// Replicas is the number of the pods of the statefulset
+	Replicas int32 `json:"replicas"`
+}
+
+// VolumePreProvisioningStatus is the progress of pre-provisioning the volumes of the new pods of a component
+type VolumePreProvisioningStatus struct {
+	// PlannedZones are the zones planned for the new pods whose volumes are pre-provisioned, the key is the
+	// pod name
+	// +optional
+	PlannedZones map[string]string `json:"plannedZones,omitempty"`
+	// FallbackReason is the reason the scale-out falls back to the plain scale-out, it is empty if the
+	// volumes are pre-provisioned
+	// +optional
+	FallbackReason string `json:"fallbackReason,omitempty"`
+}
+
+// HostPorts is the ports a component listens on in the host network
+type HostPorts struct {
+	Server int32 `json:"server"`
+	Status int32 `json:"status"`
+}
+
 // TiFlashStatus is TiFlash status
 type TiFlashStatus struct {
 	Synced          bool                        `json:"synced,omitempty"`
@@ -1282,8 +2308,23 @@ type TiFlashStatus struct {
 	FailureStores   map[string]TiKVFailureStore `json:"failureStores,omitempty"`
 	FailoverUID     types.UID                   `json:"failoverUID,omitempty"`
 	Image           string                      `json:"image,omitempty"`
+	// StaleSince is the time the PD data this status is synced from is fetched, it is set if the
+	// circuit breaker of the PD API of the cluster is open and the status is synced from the cached data.
+	// +optional
+	StaleSince *metav1.Time `json:"staleSince,omitempty"`
+	// ImagePrePull is the progress of pre-pulling the new image, it is set if spec.prePullImages is
+	// enabled and cleared after the new image is applied.
+	// +optional
+	ImagePrePull *ImagePrePullStatus `json:"imagePrePull,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
+	// StorageUsage is the summary of the storage usage of the pods
+	// +optional
+	StorageUsage *StorageUsage `json:"storageUsage,omitempty"`
 	// Represents the latest available observations of a component's state.
 	// +optional
 	// +nullable
@@ -1296,6 +2337,17 @@ type TiCDCStatus struct {
 	Phase       MemberPhase             `json:"phase,omitempty"`
 	StatefulSet *apps.StatefulSetStatus `json:"statefulSet,omitempty"`
 	Captures    map[string]TiCDCCapture `json:"captures,omitempty"`
+	// Owner is the pod name of the owner capture
+	// +optional
+	Owner string `json:"owner,omitempty"`
+	// ImagePrePull is the progress of pre-pulling the new image, it is set if spec.prePullImages is
+	// enabled and cleared after the new image is applied.
+	// +optional
+	ImagePrePull *ImagePrePullStatus `json:"imagePrePull,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
 	// Represents the latest available observations of a component's state.
@@ -1311,6 +2363,9 @@ type TiCDCCapture struct {
 	Version string `json:"version,omitempty"`
 	IsOwner bool   `json:"isOwner,omitempty"`
 	Ready   bool   `json:"ready,omitempty"`
+	// TableCount is the number of the tables replicated by the capture
+	// +optional
+	TableCount int32 `json:"tableCount,omitempty"`
 }
 
 // TiKVStores is either Up/Down/Offline/Tombstone
@@ -1327,6 +2382,95 @@ type TiKVStore struct {
 	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
+// GhostStore is a store in PD whose address is the one of a pod of the cluster but no pod backs it
+type GhostStore struct {
+	ID          string `json:"id"`
+	Address     string `json:"address"`
+	State       string `json:"state"`
+	RegionCount int    `json:"regionCount"`
+	// DetectedAt is the time the store is detected as a ghost store
+	DetectedAt metav1.Time `json:"detectedAt"`
+}
+
+// TiKVDivergentStore is a store whose state in PD disagrees with the direct probe of it
+type TiKVDivergentStore struct {
+	PodName string `json:"podName"`
+	Address string `json:"address"`
+	// Reason is why the probe disagrees with PD, e.g. the store is unreachable or answers with another store id
+	Reason string `json:"reason"`
+	// DetectedAt is the time the divergence is first detected
+	DetectedAt metav1.Time `json:"detectedAt"`
+}
+
+// TiKVVolumeReplacePhase is the phase of the replacement of the pod and the volumes of a TiKV ordinal
+type TiKVVolumeReplacePhase string
+
+const (
+	// TiKVVolumeReplaceDeletingStore is the phase the store of the pod is being deleted from PD
+	TiKVVolumeReplaceDeletingStore TiKVVolumeReplacePhase = "DeletingStore"
+	// TiKVVolumeReplaceDeletingPod is the phase the pod and its PVCs are being deleted
+	TiKVVolumeReplaceDeletingPod TiKVVolumeReplacePhase = "DeletingPod"
+	// TiKVVolumeReplaceWaitingStore is the phase the store of the recreated pod is waiting to register and catch up
+	TiKVVolumeReplaceWaitingStore TiKVVolumeReplacePhase = "WaitingStore"
+	// TiKVVolumeReplaceCompleted is the phase the store of the recreated pod catches up
+	TiKVVolumeReplaceCompleted TiKVVolumeReplacePhase = "Completed"
+)
+
+// TiKVVolumeReplaceStatus is the progress of the replacement of the pod and the volumes of a TiKV ordinal, the pod
+// and its PVCs are recreated by the StatefulSet with the same ordinal after the store of the pod is deleted
+type TiKVVolumeReplaceStatus struct {
+	PodName string                 `json:"podName"`
+	Phase   TiKVVolumeReplacePhase `json:"phase"`
+	// StoreID is the id of the store of the pod before the replacement
+	StoreID string `json:"storeID"`
+	// Forced is true if the store is deleted forcibly as it is Down, so that the new store registers with the
+	// same address before the old one becomes tombstone
+	// +optional
+	Forced bool `json:"forced,omitempty"`
+	// PodUID is the uid of the pod before the replacement, the recreated pod is never deleted
+	PodUID types.UID `json:"podUID,omitempty"`
+	// PVCUIDSet is the uids of the PVCs of the pod before the replacement, the recreated PVCs are never deleted
+	// +optional
+	PVCUIDSet map[types.UID]EmptyStruct `json:"pvcUIDSet,omitempty"`
+	// NewStoreID is the id of the store of the recreated pod
+	// +optional
+	NewStoreID string `json:"newStoreID,omitempty"`
+	// StartTime is the time the replacement starts
+	// +nullable
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the store of the recreated pod catches up
+	// +optional
+	// +nullable
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// TiKVSlowStore is the state of a store checked by spec.tikv.slowStoreMitigation
+type TiKVSlowStore struct {
+	PodName string `json:"podName,omitempty"`
+	// SlowScore is the slow score of the last check
+	SlowScore uint64 `json:"slowScore,omitempty"`
+	// SlowChecks is the number of the consecutive slow checks
+	SlowChecks int32 `json:"slowChecks,omitempty"`
+	// NormalChecks is the number of the consecutive normal checks after the store is slow
+	NormalChecks int32 `json:"normalChecks,omitempty"`
+	// Slow is true if the store has been slow for spec.tikv.slowStoreMitigation.consecutiveChecks checks
+	// and has not recovered
+	Slow bool `json:"slow,omitempty"`
+	// OriginalLeaderWeight is the leader weight before it is reduced, it is set only if the leader weight
+	// of the store is reduced
+	// +optional
+	OriginalLeaderWeight string `json:"originalLeaderWeight,omitempty"`
+	// ReducedLeaderWeight is the leader weight the store is reduced to, the original leader weight is not
+	// restored if the leader weight is changed by the user
+	// +optional
+	ReducedLeaderWeight string `json:"reducedLeaderWeight,omitempty"`
+	// LastCheckTime is the time of the last check
+	LastCheckTime metav1.Time `json:"lastCheckTime,omitempty"`
+	// LastTransitionTime is the time the store becomes slow or recovers
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
 // TiKVFailureStore is the tikv failure store information
 type TiKVFailureStore struct {
 	PodName string `json:"podName,omitempty"`
@@ -1353,8 +2497,15 @@ type PumpStatus struct {
 	Phase       MemberPhase             `json:"phase,omitempty"`
 	StatefulSet *apps.StatefulSetStatus `json:"statefulSet,omitempty"`
 	Members     []*PumpNodeStatus       `json:"members,omitempty"`
+	// Upgrade is the progress of the rolling upgrade, it is set when the first pod is upgraded and cleared
+	// after the update revision of the statefulset becomes the current revision.
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 	// Volumes contains the status of all volumes.
 	Volumes map[StorageVolumeName]*StorageVolumeStatus `json:"volumes,omitempty"`
+	// StorageUsage is the summary of the storage usage of the pods
+	// +optional
+	StorageUsage *StorageUsage `json:"storageUsage,omitempty"`
 	// Represents the latest available observations of a component's state.
 	// +optional
 	// +nullable
@@ -1695,9 +2846,21 @@ type BackupSpec struct {
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 	// TableFilter means Table filter expression for 'db.table' matching. BR supports this from v4.0.3.
 	TableFilter []string `json:"tableFilter,omitempty"`
+	// IncludeClusterSettings dumps the scheduling config of PD, the global variables and the placement policies
+	// of TiDB into settings.json in the backup after the data is backed up, so that they can be restored with
+	// the data. The global variables and the placement policies are dumped only if spec.from is set.
+	// +optional
+	IncludeClusterSettings bool `json:"includeClusterSettings,omitempty"`
+	// UploadToolLogs uploads the full log of BR or dumpling to operator-logs/ under the backup path in the same
+	// storage when the backup ends, a failure of the upload does not fail the backup.
+	// +optional
+	UploadToolLogs bool `json:"uploadToolLogs,omitempty"`
 	// Affinity of backup Pods
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// NodeSelector of backup Pods, defaults to the job node selector of tidb-operator if empty
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// Use KMS to decrypt the secrets
 	UseKMS bool `json:"useKMS,omitempty"`
 	// Specify service account of backup
@@ -1713,6 +2876,93 @@ type BackupSpec struct {
 
 	// PriorityClassName of Backup Job Pods
 	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// DeletionProtection protects the Backup from accidental deletion, e.g. the backup data referenced by
+	// the retention policies. The Backup being deleted stays terminating and its data is not cleaned until
+	// it is annotated with tidb.pingcap.com/confirm-deletion=<name of the Backup>.
+	// Optional: Defaults to the -deletion-protection of tidb-controller-manager
+	// +optional
+	DeletionProtection *bool `json:"deletionProtection,omitempty"`
+
+	// JobHistoryLimit is the number of the jobs of the finished backups kept, the jobs and the pods of the older
+	// ones are deleted. It applies to the backups not created by a BackupSchedule in the namespace, the limit of
+	// the most recently created one is used.
+	// +optional
+	JobHistoryLimit *JobHistoryLimit `json:"jobHistoryLimit,omitempty"`
+
+	// TTLSecondsAfterFinished is the ttlSecondsAfterFinished of the backup job, the job and its pods are deleted
+	// by Kubernetes after it finishes. The TTL of the job of the most recent failed backup is removed if
+	// the jobHistoryLimit applies, so that it is kept for debugging.
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// Notification posts the completion and the failure of the backup to a webhook
+	// +optional
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+// NotificationEventType is the type of the event notified, i.e. the phase a Backup or a Restore transitions to
+type NotificationEventType string
+
+const (
+	// NotificationEventComplete is notified when the Backup or the Restore completes
+	NotificationEventComplete NotificationEventType = "Complete"
+	// NotificationEventFailed is notified when the Backup or the Restore fails
+	NotificationEventFailed NotificationEventType = "Failed"
+)
+
+// Notification is the webhook notified of the state transitions of a Backup or a Restore. tidb-operator sends
+// a POST request with a JSON payload of the namespace, the name, the phase, the size, the commit ts, the duration
+// and the error, and any 2xx response means success. The failed requests are retried, and a webhook failing
+// repeatedly is not requested for a while.
+// +k8s:openapi-gen=true
+type Notification struct {
+	// URL of the webhook
+	URL string `json:"url"`
+
+	// AuthSecretRef is the key of the secret in the namespace whose value is sent as the bearer token in the
+	// Authorization header, it is never included in the payload
+	// +optional
+	AuthSecretRef *corev1.SecretKeySelector `json:"authSecretRef,omitempty"`
+
+	// EventTypes are the types of the events notified, Complete or Failed.
+	// Optional: Defaults to all
+	// +optional
+	EventTypes []NotificationEventType `json:"eventTypes,omitempty"`
+
+	// CloudEvents sends the payload as the data of a CloudEvent of the version 1.0 in the structured content
+	// mode, rather than as the body
+	// +optional
+	CloudEvents bool `json:"cloudEvents,omitempty"`
+}
+
+// NotificationStatus is the result of the notification of the last event
+type NotificationStatus struct {
+	// EventType is the type of the last event notified
+	EventType NotificationEventType `json:"eventType"`
+	// Succeeded is whether the webhook has accepted the notification
+	Succeeded bool `json:"succeeded"`
+	// Attempts is the number of the requests sent for the event
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastAttemptTime is the time the last request is sent
+	// +nullable
+	LastAttemptTime metav1.Time `json:"lastAttemptTime,omitempty"`
+	// Message is the error of the last request failed
+	Message string `json:"message,omitempty"`
+}
+
+// JobHistoryLimit is the number of the jobs of the finished backups kept, the job of the most recent failed
+// backup is always kept for debugging and the jobs of the backups not finished are never deleted
+// +k8s:openapi-gen=true
+type JobHistoryLimit struct {
+	// Successful is the number of the jobs of the complete backups kept.
+	// Optional: Defaults to unlimited
+	// +optional
+	Successful *int32 `json:"successful,omitempty"`
+	// Failed is the number of the jobs of the failed backups kept.
+	// Optional: Defaults to unlimited
+	// +optional
+	Failed *int32 `json:"failed,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -1811,6 +3061,13 @@ type BackupStatus struct {
 	Phase BackupConditionType `json:"phase,omitempty"`
 	// +nullable
 	Conditions []BackupCondition `json:"conditions,omitempty"`
+	// FailureSummary is the exit diagnostics and the last lines of the log of the tool failed, it is truncated
+	// to 4KB and kept after the pod of the backup job is deleted
+	// +optional
+	FailureSummary string `json:"failureSummary,omitempty"`
+	// Notification is the result of the notification of spec.notification
+	// +optional
+	Notification *NotificationStatus `json:"notification,omitempty"`
 }
 
 // +genclient
@@ -1871,6 +3128,142 @@ type BackupScheduleSpec struct {
 	// ImagePullSecrets is an optional list of references to secrets in the same namespace to use for pulling any of the images.
 	// +optional
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// TimeZone is the IANA time zone name, such as "Asia/Shanghai", in which the Schedule is evaluated.
+	// On the daylight saving time transitions, a schedule time in the repeated hour runs only once,
+	// and a schedule time in the skipped hour runs once after the clock jumps forward.
+	// Defaults to the local time zone of tidb-controller-manager.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// RestoreDrill periodically restores the most recent backup into an ephemeral TidbCluster
+	// to verify the backups are usable.
+	// +optional
+	RestoreDrill *RestoreDrillSpec `json:"restoreDrill,omitempty"`
+	// BaseBackupRef is the full backup taken out of the schedule, such as by another tool, the backups of
+	// the schedule are incremental on. Each backup is incremental on the last completed backup of the chain
+	// starting from the base, and the base is never deleted by the backup GC of the schedule.
+	// Only supported for the backups made by BR.
+	// +optional
+	BaseBackupRef *BaseBackupRef `json:"baseBackupRef,omitempty"`
+	// JobHistoryLimit is the number of the jobs of the finished backups of the schedule kept, it takes
+	// precedence over the one in the backupTemplate.
+	// +optional
+	JobHistoryLimit *JobHistoryLimit `json:"jobHistoryLimit,omitempty"`
+	// Notification of the backups created by the schedule, the one in the backupTemplate takes precedence
+	// over it.
+	// +optional
+	Notification *Notification `json:"notification,omitempty"`
+}
+
+// BaseBackupRef refers to the full backup the incremental backups of a BackupSchedule are based on,
+// either a Backup or a storage path with the commit ts of the backup.
+// +k8s:openapi-gen=true
+type BaseBackupRef struct {
+	// Name is the name of the Backup in the namespace of the BackupSchedule.
+	// Either Name or Path must be set.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Path is the storage path of the base backup, such as "s3://bucket/prefix".
+	// +optional
+	Path string `json:"path,omitempty"`
+	// CommitTs is the commit ts of the base backup at Path, it is required if Path is set.
+	// +optional
+	CommitTs string `json:"commitTs,omitempty"`
+}
+
+// RestoreDrillSpec describes the drill restoring the most recent completed backup of a BackupSchedule
+// into an ephemeral TidbCluster, which is removed after the verification checks are run.
+// Only one drill of a BackupSchedule runs at a time.
+// +k8s:openapi-gen=true
+type RestoreDrillSpec struct {
+	// Schedule specifies the cron string used for drill scheduling, it is evaluated in the
+	// time zone of the BackupSchedule.
+	Schedule string `json:"schedule"`
+	// Namespace is the namespace the ephemeral TidbCluster and the Restore are created in.
+	// The secrets used to access the backup storage must exist in the namespace.
+	// Defaults to the namespace of the BackupSchedule.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ClusterTemplate is the spec of the ephemeral TidbCluster the backup is restored into.
+	// PD, TiKV and TiDB are required and the cpu and memory limits of all the components must be set.
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:XPreserveUnknownFields
+	ClusterTemplate TidbClusterSpec `json:"clusterTemplate"`
+	// Checks are the SQL queries run in the ephemeral TidbCluster after the backup is restored,
+	// the drill passes if all the checks pass.
+	// +optional
+	Checks []RestoreDrillCheck `json:"checks,omitempty"`
+	// Timeout is the max duration of a drill, such as "2h". Defaults to 6h.
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// RestoreDrillCheck is a SQL check run by the restore drill
+// +k8s:openapi-gen=true
+type RestoreDrillCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// SQL is the query of the check, it must return at least one row.
+	SQL string `json:"sql"`
+	// Expected is the expected value of the first column of the first row returned.
+	// If it is not set, the check passes as long as a row is returned.
+	// +optional
+	Expected *string `json:"expected,omitempty"`
+}
+
+// RestoreDrillPhase is the phase of a restore drill
+type RestoreDrillPhase string
+
+const (
+	// RestoreDrillRunning means the drill is running
+	RestoreDrillRunning RestoreDrillPhase = "Running"
+	// RestoreDrillPassed means the backup is restored and all the checks pass
+	RestoreDrillPassed RestoreDrillPhase = "Passed"
+	// RestoreDrillFailed means the backup fails to be restored or a check fails
+	RestoreDrillFailed RestoreDrillPhase = "Failed"
+)
+
+const (
+	// BackupScheduleRestoreDrillFailed is the condition type of BackupSchedule indicating
+	// whether the last restore drill fails
+	BackupScheduleRestoreDrillFailed = "RestoreDrillFailed"
+	// BackupScheduleDegraded is the condition type of BackupSchedule indicating whether the
+	// incremental backups are suspended because a link of the incremental chain is missing
+	BackupScheduleDegraded = "Degraded"
+)
+
+// RestoreDrillStatus represents the state of the last restore drill
+type RestoreDrillStatus struct {
+	// Phase is the phase of the last drill
+	Phase RestoreDrillPhase `json:"phase,omitempty"`
+	// Backup is the backup restored by the last drill
+	Backup string `json:"backup,omitempty"`
+	// Cluster is the namespaced name of the ephemeral TidbCluster of the last drill
+	Cluster string `json:"cluster,omitempty"`
+	// ScheduledTime is the time the last drill is scheduled at
+	ScheduledTime *metav1.Time `json:"scheduledTime,omitempty"`
+	// StartTime is the time the last drill starts
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the last drill completes
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Duration is the duration of the last drill
+	Duration string `json:"duration,omitempty"`
+	// Message is the reason the last drill fails
+	Message string `json:"message,omitempty"`
+}
+
+// IncrementalChainStatus represents the chain of the incremental backups of a BackupSchedule
+type IncrementalChainStatus struct {
+	// Base is the name or the path of the base backup
+	Base string `json:"base,omitempty"`
+	// BaseCommitTs is the commit ts of the base backup
+	BaseCommitTs string `json:"baseCommitTs,omitempty"`
+	// Head is the last completed incremental backup of the chain, the chain starts from the base
+	// if it is empty
+	Head string `json:"head,omitempty"`
+	// HeadCommitTs is the commit ts of the head
+	HeadCommitTs string `json:"headCommitTs,omitempty"`
+	// Length is the number of the completed incremental backups of the chain
+	Length int32 `json:"length,omitempty"`
 }
 
 // BackupScheduleStatus represents the current state of a BackupSchedule.
@@ -1881,6 +3274,18 @@ type BackupScheduleStatus struct {
 	LastBackupTime *metav1.Time `json:"lastBackupTime,omitempty"`
 	// AllBackupCleanTime represents the time when all backup entries are cleaned up
 	AllBackupCleanTime *metav1.Time `json:"allBackupCleanTime,omitempty"`
+	// NextBackupTime represents the next time the backup is scheduled.
+	NextBackupTime *metav1.Time `json:"nextBackupTime,omitempty"`
+	// NextBackupTimeInZone represents NextBackupTime in the time zone of the schedule, in RFC3339 format.
+	NextBackupTimeInZone string `json:"nextBackupTimeInZone,omitempty"`
+	// RestoreDrill represents the state of the last restore drill
+	RestoreDrill *RestoreDrillStatus `json:"restoreDrill,omitempty"`
+	// IncrementalChain represents the chain of the incremental backups on spec.baseBackupRef
+	IncrementalChain *IncrementalChainStatus `json:"incrementalChain,omitempty"`
+	// Conditions represent the latest available observations of the BackupSchedule
+	// +optional
+	// +nullable
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -1994,6 +3399,9 @@ type RestoreSpec struct {
 	// Affinity of restore Pods
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// NodeSelector of restore Pods, defaults to the job node selector of tidb-operator if empty
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// Use KMS to decrypt the secrets
 	UseKMS bool `json:"useKMS,omitempty"`
 	// Specify service account of restore
@@ -2008,6 +3416,37 @@ type RestoreSpec struct {
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 	// TableFilter means Table filter expression for 'db.table' matching. BR supports this from v4.0.3.
 	TableFilter []string `json:"tableFilter,omitempty"`
+	// RenameMapping restores the tables or databases matched by the table filter under other names,
+	// so that they can be restored into the same cluster. It is not supported if the whole cluster is restored.
+	// +optional
+	RenameMapping []RestoreRenameMapping `json:"renameMapping,omitempty"`
+	// Overwrite allows the targets of RenameMapping to collide with the existing tables,
+	// the restore fails if any target exists and it is false.
+	// +optional
+	Overwrite bool `json:"overwrite,omitempty"`
+	// RestoreClusterSettings applies the cluster settings in settings.json of the backup after the data is
+	// restored. The settings referring to the identity, the addresses or the local paths of the source cluster
+	// are never applied. The global variables and the placement policies are applied only if spec.to is set.
+	// +optional
+	RestoreClusterSettings bool `json:"restoreClusterSettings,omitempty"`
+	// ExcludeClusterSettings are the keys of the cluster settings not to be applied or the glob patterns
+	// of them, e.g. pd:schedule.* or variable:tidb_mem_quota_query
+	// +optional
+	ExcludeClusterSettings []string `json:"excludeClusterSettings,omitempty"`
+	// UploadToolLogs uploads the full log of BR or lightning to operator-logs/ under the backup path in the
+	// same storage when the restore ends, a failure of the upload does not fail the restore.
+	// +optional
+	UploadToolLogs bool `json:"uploadToolLogs,omitempty"`
+	// PitrRestoredTs is the TSO a point-in-time restore restores the cluster to, the log backup at
+	// pitrLogBackupPrefix is replayed on the full backup of the restore up to it. The restore fails before
+	// BR runs if the log backup does not cover the range from the full backup to it continuously.
+	// Only supported by BR.
+	// +optional
+	PitrRestoredTs string `json:"pitrRestoredTs,omitempty"`
+	// PitrLogBackupPrefix is the prefix of the log backup in the bucket of the full backup of the restore,
+	// it is required if pitrRestoredTs is set.
+	// +optional
+	PitrLogBackupPrefix string `json:"pitrLogBackupPrefix,omitempty"`
 
 	// PodSecurityContext of the component
 	// +optional
@@ -2017,6 +3456,23 @@ type RestoreSpec struct {
 	PriorityClassName string `json:"priorityClassName,omitempty"`
 }
 
+// +k8s:openapi-gen=true
+// RestoreRenameMapping maps a table or a database in the backup to the one it is restored as
+type RestoreRenameMapping struct {
+	// Source is the 'db.table' in the backup, or 'db.*' to rename the database
+	Source string `json:"source"`
+	// Target is the 'db.table' the source is restored as, or 'db.*' if the source is a database
+	Target string `json:"target"`
+}
+
+// RestoredTable is a table restored under the name of its target
+type RestoredTable struct {
+	// Source is the 'db.table' in the backup
+	Source string `json:"source"`
+	// Target is the 'db.table' the table is restored as
+	Target string `json:"target"`
+}
+
 // RestoreStatus represents the current status of a tidb cluster restore.
 type RestoreStatus struct {
 	// TimeStarted is the time at which the restore was started.
@@ -2031,6 +3487,19 @@ type RestoreStatus struct {
 	Phase RestoreConditionType `json:"phase,omitempty"`
 	// +nullable
 	Conditions []RestoreCondition `json:"conditions,omitempty"`
+	// RestoredTables are the tables restored by the rename mapping with their target names
+	// +optional
+	RestoredTables []RestoredTable `json:"restoredTables,omitempty"`
+	// AppliedClusterSettings are the keys of the cluster settings applied
+	// +optional
+	AppliedClusterSettings []string `json:"appliedClusterSettings,omitempty"`
+	// SkippedClusterSettings are the keys of the cluster settings excluded or failed to be applied
+	// +optional
+	SkippedClusterSettings []string `json:"skippedClusterSettings,omitempty"`
+	// FailureSummary is the exit diagnostics and the last lines of the log of the tool failed, it is truncated
+	// to 4KB and kept after the pod of the restore job is deleted
+	// +optional
+	FailureSummary string `json:"failureSummary,omitempty"`
 }
 
 // +k8s:openapi-gen=true
@@ -2231,6 +3700,13 @@ type DMClusterSpec struct {
 	// +listType=map
 	// +listMapKey=topologyKey
 	TopologySpreadConstraints []TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// DeletionProtection protects the DMCluster from accidental deletion, the DMCluster being deleted
+	// stays terminating with its components running until it is annotated with
+	// tidb.pingcap.com/confirm-deletion=<name of the DMCluster>.
+	// Optional: Defaults to the -deletion-protection of tidb-controller-manager
+	// +optional
+	DeletionProtection *bool `json:"deletionProtection,omitempty"`
 }
 
 // DMClusterStatus represents the current status of a dm cluster.
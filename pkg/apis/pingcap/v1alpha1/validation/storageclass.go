@@ -0,0 +1,180 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// zoneLabelKeys are the node label keys of the zones
+var zoneLabelKeys = sets.NewString(corev1.LabelZoneFailureDomainStable, corev1.LabelZoneFailureDomain)
+
+// StorageClassGetter gets the StorageClasses by name, it is implemented by the StorageClassLister
+type StorageClassGetter interface {
+	Get(name string) (*storagev1.StorageClass, error)
+}
+
+// StorageClassRef is a StorageClass referenced by a component of the TidbCluster
+type StorageClassRef struct {
+	Path       *field.Path
+	Name       string
+	MemberType v1alpha1.MemberType
+	Component  v1alpha1.ComponentAccessor
+}
+
+// TidbClusterStorageClassRefs returns the StorageClasses referenced by the components of the TidbCluster,
+// the volumes of the default StorageClass are not included.
+func TidbClusterStorageClassRefs(tc *v1alpha1.TidbCluster) []StorageClassRef {
+	var refs []StorageClassRef
+	add := func(path *field.Path, name *string, memberType v1alpha1.MemberType, component v1alpha1.ComponentAccessor) {
+		if name == nil || *name == "" {
+			return
+		}
+		refs = append(refs, StorageClassRef{Path: path, Name: *name, MemberType: memberType, Component: component})
+	}
+	addVolumes := func(path *field.Path, volumes []v1alpha1.StorageVolume, memberType v1alpha1.MemberType, component v1alpha1.ComponentAccessor) {
+		for i := range volumes {
+			add(path.Index(i).Child("storageClassName"), volumes[i].StorageClassName, memberType, component)
+		}
+	}
+
+	spec := field.NewPath("spec")
+	if tc.Spec.PD != nil {
+		path := spec.Child("pd")
+		add(path.Child("storageClassName"), tc.Spec.PD.StorageClassName, v1alpha1.PDMemberType, tc.BasePDSpec())
+		addVolumes(path.Child("storageVolumes"), tc.Spec.PD.StorageVolumes, v1alpha1.PDMemberType, tc.BasePDSpec())
+	}
+	if tc.Spec.TiKV != nil {
+		path := spec.Child("tikv")
+		add(path.Child("storageClassName"), tc.Spec.TiKV.StorageClassName, v1alpha1.TiKVMemberType, tc.BaseTiKVSpec())
+		addVolumes(path.Child("storageVolumes"), tc.Spec.TiKV.StorageVolumes, v1alpha1.TiKVMemberType, tc.BaseTiKVSpec())
+	}
+	if tc.Spec.TiDB != nil {
+		path := spec.Child("tidb")
+		add(path.Child("storageClassName"), tc.Spec.TiDB.StorageClassName, v1alpha1.TiDBMemberType, tc.BaseTiDBSpec())
+		addVolumes(path.Child("storageVolumes"), tc.Spec.TiDB.StorageVolumes, v1alpha1.TiDBMemberType, tc.BaseTiDBSpec())
+	}
+	if tc.Spec.TiFlash != nil {
+		path := spec.Child("tiflash", "storageClaims")
+		for i := range tc.Spec.TiFlash.StorageClaims {
+			add(path.Index(i).Child("storageClassName"), tc.Spec.TiFlash.StorageClaims[i].StorageClassName, v1alpha1.TiFlashMemberType, tc.BaseTiFlashSpec())
+		}
+	}
+	if tc.Spec.TiCDC != nil {
+		path := spec.Child("ticdc")
+		add(path.Child("storageClassName"), tc.Spec.TiCDC.StorageClassName, v1alpha1.TiCDCMemberType, tc.BaseTiCDCSpec())
+		addVolumes(path.Child("storageVolumes"), tc.Spec.TiCDC.StorageVolumes, v1alpha1.TiCDCMemberType, tc.BaseTiCDCSpec())
+	}
+	if tc.Spec.Pump != nil {
+		add(spec.Child("pump", "storageClassName"), tc.Spec.Pump.StorageClassName, v1alpha1.PumpMemberType, tc.BasePumpSpec())
+	}
+	return refs
+}
+
+// ChangedStorageClassRefs returns the StorageClasses referenced by the TidbCluster that are not referenced
+// at the same paths by the old TidbCluster
+func ChangedStorageClassRefs(old, tc *v1alpha1.TidbCluster) []StorageClassRef {
+	oldNames := map[string]string{}
+	for _, ref := range TidbClusterStorageClassRefs(old) {
+		oldNames[ref.Path.String()] = ref.Name
+	}
+	var refs []StorageClassRef
+	for _, ref := range TidbClusterStorageClassRefs(tc) {
+		if name, ok := oldNames[ref.Path.String()]; !ok || name != ref.Name {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ValidateStorageClasses validates the capabilities of the referenced StorageClasses against the components,
+// the combinations known to break the HA of the components are returned as errors:
+//   - the StorageClass does not exist
+//   - the StorageClass binds the volumes immediately while the component is required to spread over the zones,
+//     the volumes are provisioned in the zones regardless of the affinity and the pods can not be scheduled
+//     against the pre-bound volumes.
+//
+// The combinations that only break some features are returned as warnings:
+//   - the StorageClass does not allow volume expansion, the volumes can not be resized by increasing the
+//     storage requests.
+func ValidateStorageClasses(refs []StorageClassRef, storageClasses StorageClassGetter) (field.ErrorList, []string) {
+	allErrs := field.ErrorList{}
+	var warnings []string
+	if storageClasses == nil {
+		return allErrs, warnings
+	}
+	for _, ref := range refs {
+		sc, err := storageClasses.Get(ref.Name)
+		if errors.IsNotFound(err) {
+			allErrs = append(allErrs, field.NotFound(ref.Path, ref.Name))
+			continue
+		}
+		if err != nil {
+			allErrs = append(allErrs, field.InternalError(ref.Path, fmt.Errorf("failed to get StorageClass %s: %v", ref.Name, err)))
+			continue
+		}
+		immediate := sc.VolumeBindingMode == nil || *sc.VolumeBindingMode == storagev1.VolumeBindingImmediate
+		if immediate && requiresMultipleZones(ref.Component) {
+			allErrs = append(allErrs, field.Invalid(ref.Path, ref.Name,
+				fmt.Sprintf("StorageClass %s binds the volumes immediately while %s is required to spread over the zones, the pods can not be scheduled against the volumes bound in other zones, use a StorageClass with volumeBindingMode %s instead", ref.Name, ref.MemberType, storagev1.VolumeBindingWaitForFirstConsumer)))
+		}
+		if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+			warnings = append(warnings, fmt.Sprintf("%s: StorageClass %s does not allow volume expansion, the volumes can not be resized by increasing the storage requests", ref.Path, ref.Name))
+		}
+	}
+	return allErrs, warnings
+}
+
+// requiresMultipleZones returns whether the pods of the component are required to be scheduled to multiple zones,
+// i.e. by the required node affinity on multiple zones, the required pod anti-affinity or the topology spread
+// constraints over the zones
+func requiresMultipleZones(component v1alpha1.ComponentAccessor) bool {
+	for _, tsc := range component.TopologySpreadConstraints() {
+		if zoneLabelKeys.Has(tsc.TopologyKey) && tsc.WhenUnsatisfiable == corev1.DoNotSchedule {
+			return true
+		}
+	}
+	affinity := component.Affinity()
+	if affinity == nil {
+		return false
+	}
+	if affinity.PodAntiAffinity != nil {
+		for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			if zoneLabelKeys.Has(term.TopologyKey) {
+				return true
+			}
+		}
+	}
+	if affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		zones := sets.NewString()
+		for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if zoneLabelKeys.Has(expr.Key) && expr.Operator == corev1.NodeSelectorOpIn {
+					zones.Insert(expr.Values...)
+				}
+			}
+		}
+		if zones.Len() > 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+)
+
+type fakeStorageClasses map[string]*storagev1.StorageClass
+
+func (f fakeStorageClasses) Get(name string) (*storagev1.StorageClass, error) {
+	sc, ok := f[name]
+	if !ok {
+		return nil, errors.NewNotFound(storagev1.Resource("storageclasses"), name)
+	}
+	return sc, nil
+}
+
+func newFakeStorageClass(name string, mode storagev1.VolumeBindingMode, allowVolumeExpansion bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:           metav1.ObjectMeta{Name: name},
+		VolumeBindingMode:    &mode,
+		AllowVolumeExpansion: pointer.BoolPtr(allowVolumeExpansion),
+	}
+}
+
+func TestValidateStorageClasses(t *testing.T) {
+	g := NewGomegaWithT(t)
+	storageClasses := fakeStorageClasses{
+		"local":      newFakeStorageClass("local", storagev1.VolumeBindingWaitForFirstConsumer, false),
+		"zonal":      newFakeStorageClass("zonal", storagev1.VolumeBindingImmediate, true),
+		"zonal-wffc": newFakeStorageClass("zonal-wffc", storagev1.VolumeBindingWaitForFirstConsumer, true),
+	}
+	newTC := func() *v1alpha1.TidbCluster {
+		return &v1alpha1.TidbCluster{
+			Spec: v1alpha1.TidbClusterSpec{
+				PD:   &v1alpha1.PDSpec{StorageClassName: pointer.StringPtr("zonal-wffc")},
+				TiKV: &v1alpha1.TiKVSpec{StorageClassName: pointer.StringPtr("zonal-wffc")},
+				TiDB: &v1alpha1.TiDBSpec{},
+			},
+		}
+	}
+	validate := func(refs []StorageClassRef) (field.ErrorList, []string) {
+		return ValidateStorageClasses(refs, storageClasses)
+	}
+
+	// valid
+	tc := newTC()
+	errs, warnings := validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(BeEmpty())
+	g.Expect(warnings).To(BeEmpty())
+
+	// missing StorageClass
+	tc.Spec.TiKV.StorageVolumes = []v1alpha1.StorageVolume{{Name: "wal", StorageClassName: pointer.StringPtr("missing")}}
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Type).To(Equal(field.ErrorTypeNotFound))
+	g.Expect(errs[0].Field).To(Equal("spec.tikv.storageVolumes[0].storageClassName"))
+
+	// immediate binding is valid without the affinity over the zones
+	tc = newTC()
+	tc.Spec.TiKV.StorageClassName = pointer.StringPtr("zonal")
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(BeEmpty())
+
+	// immediate binding with the topology spread constraints over the zones
+	tc.Spec.TopologySpreadConstraints = []v1alpha1.TopologySpreadConstraint{{TopologyKey: corev1.LabelZoneFailureDomainStable}}
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Type).To(Equal(field.ErrorTypeInvalid))
+	g.Expect(errs[0].Field).To(Equal("spec.tikv.storageClassName"))
+
+	// immediate binding with the required node affinity on multiple zones
+	tc.Spec.TopologySpreadConstraints = nil
+	tc.Spec.TiKV.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key:      corev1.LabelZoneFailureDomainStable,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{"zone-a", "zone-b"},
+			}}}},
+		},
+	}}
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(HaveLen(1))
+	// a single zone is fine
+	tc.Spec.TiKV.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values = []string{"zone-a"}
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(BeEmpty())
+
+	// immediate binding with the required pod anti-affinity over the zones
+	tc.Spec.TiKV.Affinity = &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{TopologyKey: corev1.LabelZoneFailureDomainStable}},
+	}}
+	errs, _ = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(HaveLen(1))
+
+	// no volume expansion
+	tc = newTC()
+	tc.Spec.PD.StorageClassName = pointer.StringPtr("local")
+	errs, warnings = validate(TidbClusterStorageClassRefs(tc))
+	g.Expect(errs).To(BeEmpty())
+	g.Expect(warnings).To(HaveLen(1))
+	g.Expect(warnings[0]).To(ContainSubstring("spec.pd.storageClassName: StorageClass local does not allow volume expansion"))
+
+	// only the changed StorageClasses are validated on update
+	old := newTC()
+	old.Spec.PD.StorageClassName = pointer.StringPtr("missing")
+	tc = old.DeepCopy()
+	tc.Spec.TiKV.StorageClassName = pointer.StringPtr("local")
+	refs := ChangedStorageClassRefs(old, tc)
+	g.Expect(refs).To(HaveLen(1))
+	g.Expect(refs[0].Path.String()).To(Equal("spec.tikv.storageClassName"))
+	errs, warnings = validate(refs)
+	g.Expect(errs).To(BeEmpty())
+	g.Expect(warnings).To(HaveLen(1))
+
+	// not validated without the StorageClasses
+	errs, warnings = ValidateStorageClasses(TidbClusterStorageClassRefs(old), nil)
+	g.Expect(errs).To(BeEmpty())
+	g.Expect(warnings).To(BeEmpty())
+}
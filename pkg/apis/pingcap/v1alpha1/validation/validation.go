@@ -16,11 +16,14 @@ package validation
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,11 +34,48 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	utilnet "k8s.io/utils/net"
 )
 
+// reservedEnvNames are the env names set by TiDB Operator in the component containers,
+// they are only rejected in new TidbClusters, see validateReservedEnvNames
+var reservedEnvNames = sets.NewString(
+	"NAMESPACE",
+	"TZ",
+	"SERVICE_NAME",
+	"PEER_SERVICE_NAME",
+	"HEADLESS_SERVICE_NAME",
+	"SET_NAME",
+	"HOSTNAME",
+	"CLUSTER_NAME",
+	"POD_NAME",
+	"BINLOG_ENABLED",
+	"SLOW_LOG_FILE",
+)
+
+// reservedMonitorLabels are the labels set by TidbMonitor on the metrics of the clusters, they can not be
+// overridden by the labels of the external targets
+var reservedMonitorLabels = sets.NewString(
+	"job",
+	"instance",
+	"cluster",
+	"tidb_cluster",
+	"component",
+	"kubernetes_namespace",
+)
+
+// TiKVRequestsFloor is the minimum requests of TiKV derived from spec.tikv.sizing, the requests ratios putting
+// the requests of TiKV below it are rejected as undersized requests lead to evictions of TiKV under node pressure.
+// It is set by the flags of the admission webhook.
+var TiKVRequestsFloor = corev1.ResourceList{
+	corev1.ResourceCPU:    resource.MustParse("1"),
+	corev1.ResourceMemory: resource.MustParse("2Gi"),
+}
+
 // ValidateTidbCluster validates a TidbCluster, it performs basic validation for all TidbClusters despite it is legacy
 // or not
 func ValidateTidbCluster(tc *v1alpha1.TidbCluster) field.ErrorList {
@@ -49,6 +89,33 @@ func ValidateTidbCluster(tc *v1alpha1.TidbCluster) field.ErrorList {
 	return allErrs
 }
 
+// TidbClusterWarnings returns the warnings of the settings of a TidbCluster which are valid but may not work as
+// the users expect
+func TidbClusterWarnings(tc *v1alpha1.TidbCluster) []string {
+	var warnings []string
+	if tc.TopologyAwareRouting() {
+		warnings = append(warnings, "spec.networking.topologyAwareRouting: only the clients resolving PD and TiKV through the PD service, "+
+			"the peer services and the per-zone services prefer the endpoints in their own zones, e.g. TiDB connects to the TiKV stores "+
+			"by the addresses they advertise to PD, and kube-proxy does not route the traffic resolved through the headless services")
+	}
+	if tc.Spec.TiDB != nil && tc.Spec.TiDB.IsProxyProtocolEnabled() && tc.Spec.TiDB.ProxyProtocol.Provider == v1alpha1.LoadBalancerProviderGCP {
+		warnings = append(warnings, "spec.tidb.proxyProtocol.provider: the load balancer of the services of GCP does not send the PROXY protocol, "+
+			"the connections from the trusted networks fail unless they are proxied by a load balancer sending it")
+	}
+	return warnings
+}
+
+// TidbClusterUpdateWarnings returns the warnings of the changes of a TidbCluster which are valid but may break the
+// clients, besides the ones of TidbClusterWarnings
+func TidbClusterUpdateWarnings(old, tc *v1alpha1.TidbCluster) []string {
+	var warnings []string
+	if old.Spec.TiDB != nil && tc.Spec.TiDB != nil && old.Spec.TiDB.IsProxyProtocolEnabled() != tc.Spec.TiDB.IsProxyProtocolEnabled() {
+		warnings = append(warnings, "spec.tidb.proxyProtocol.enabled: the connections of the clients through the load balancer break during the transition, "+
+			"as the TiDB pods are rolled one by one and the load balancer is reconfigured independently")
+	}
+	return warnings
+}
+
 // ValidateDMCluster validates a DMCluster, it performs basic validation for all DMClusters despite it is legacy
 // or not
 func ValidateDMCluster(dc *v1alpha1.DMCluster) field.ErrorList {
@@ -84,6 +151,172 @@ func ValidateTidbMonitor(monitor *v1alpha1.TidbMonitor) field.ErrorList {
 	if monitor.Spec.Persistent {
 		allErrs = append(allErrs, validateStorageInfo(monitor.Spec.Storage, field.NewPath("spec"))...)
 	}
+	allErrs = append(allErrs, validateExternalTargets(monitor, field.NewPath("spec", "externalTargets"))...)
+	return allErrs
+}
+
+// ValidateBackupSchedule validates the spec of BackupSchedule
+func ValidateBackupSchedule(bs *v1alpha1.BackupSchedule) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if bs.Spec.TimeZone != "" {
+		if _, err := time.LoadLocation(bs.Spec.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "timeZone"), bs.Spec.TimeZone,
+				fmt.Sprintf("should be an IANA time zone name: %v", err)))
+		}
+	}
+	if bs.Spec.RestoreDrill != nil {
+		allErrs = append(allErrs, validateRestoreDrill(&bs.Spec, field.NewPath("spec", "restoreDrill"))...)
+	}
+	if bs.Spec.BaseBackupRef != nil {
+		allErrs = append(allErrs, validateBaseBackupRef(&bs.Spec, field.NewPath("spec", "baseBackupRef"))...)
+	}
+	allErrs = append(allErrs, ValidateJobHistoryLimit(bs.Spec.JobHistoryLimit, field.NewPath("spec", "jobHistoryLimit"))...)
+	allErrs = append(allErrs, ValidateNotification(bs.Spec.Notification, field.NewPath("spec", "notification"))...)
+	return allErrs
+}
+
+// ValidateNotification validates the webhook is an http or https URL and the event types are known
+func ValidateNotification(notification *v1alpha1.Notification, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if notification == nil {
+		return allErrs
+	}
+	if notification.URL == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("url"), "url of the webhook must be set"))
+	} else if u, err := url.Parse(notification.URL); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), notification.URL, err.Error()))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), notification.URL, "support 'http' and 'https' schemes only"))
+	}
+	if ref := notification.AuthSecretRef; ref != nil {
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("authSecretRef", "name"), "name of the secret must be set"))
+		}
+		if ref.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("authSecretRef", "key"), "key of the secret must be set"))
+		}
+	}
+	for i, t := range notification.EventTypes {
+		if t != v1alpha1.NotificationEventComplete && t != v1alpha1.NotificationEventFailed {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("eventTypes").Index(i), t,
+				[]string{string(v1alpha1.NotificationEventComplete), string(v1alpha1.NotificationEventFailed)}))
+		}
+	}
+	return allErrs
+}
+
+// ValidateJobHistoryLimit validates that the numbers of the jobs kept are not negative
+func ValidateJobHistoryLimit(limit *v1alpha1.JobHistoryLimit, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if limit == nil {
+		return allErrs
+	}
+	if limit.Successful != nil && *limit.Successful < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("successful"), *limit.Successful, "must not be negative"))
+	}
+	if limit.Failed != nil && *limit.Failed < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("failed"), *limit.Failed, "must not be negative"))
+	}
+	return allErrs
+}
+
+// validateBaseBackupRef validates the base backup of the incremental backups of the BackupSchedule,
+// the `--lastbackupts` option of BR is set by the schedule so it can not be set in the template.
+func validateBaseBackupRef(spec *v1alpha1.BackupScheduleSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	ref := spec.BaseBackupRef
+	if spec.BackupTemplate.BR == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "incremental backups are only supported for the backups made by BR"))
+	} else {
+		for i, opt := range spec.BackupTemplate.BR.Options {
+			if strings.HasPrefix(opt, "--lastbackupts") {
+				allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "backupTemplate", "br", "options").Index(i),
+					"--lastbackupts is set by the schedule if baseBackupRef is set"))
+			}
+		}
+	}
+	switch {
+	case ref.Name == "" && ref.Path == "":
+		allErrs = append(allErrs, field.Required(fldPath, "either name or path of the base backup must be set"))
+	case ref.Name != "" && ref.Path != "":
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), ref.Path, "name and path of the base backup can not be set at the same time"))
+	case ref.Path != "":
+		if ref.CommitTs == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("commitTs"), "commitTs of the base backup at path must be set"))
+		} else if _, err := strconv.ParseUint(ref.CommitTs, 10, 64); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("commitTs"), ref.CommitTs, "must be a TSO"))
+		}
+	}
+	return allErrs
+}
+
+// validateRestoreDrill validates the restore drill of the BackupSchedule, the ephemeral cluster
+// must have the resource limits set so that the drills do not starve the other workloads.
+func validateRestoreDrill(spec *v1alpha1.BackupScheduleSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	drill := spec.RestoreDrill
+	if drill.Schedule == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("schedule"), "schedule of the restore drill must be set"))
+	}
+	if spec.BackupTemplate.BR == nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", "restore drill is only supported for the backups made by BR"))
+	}
+	if drill.Timeout != "" {
+		if d, err := time.ParseDuration(drill.Timeout); err != nil || d <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("timeout"), drill.Timeout, "must be a positive duration"))
+		}
+	}
+
+	tplPath := fldPath.Child("clusterTemplate")
+	tpl := &drill.ClusterTemplate
+	if tpl.PD == nil {
+		allErrs = append(allErrs, field.Required(tplPath.Child("pd"), "pd of the drill cluster must be set"))
+	} else {
+		allErrs = append(allErrs, validateResourceLimits(tpl.PD.ResourceRequirements, tplPath.Child("pd"))...)
+	}
+	if tpl.TiKV == nil {
+		allErrs = append(allErrs, field.Required(tplPath.Child("tikv"), "tikv of the drill cluster must be set"))
+	} else {
+		allErrs = append(allErrs, validateResourceLimits(tpl.TiKV.ResourceRequirements, tplPath.Child("tikv"))...)
+	}
+	if tpl.TiDB == nil {
+		allErrs = append(allErrs, field.Required(tplPath.Child("tidb"), "tidb of the drill cluster must be set"))
+	} else {
+		allErrs = append(allErrs, validateResourceLimits(tpl.TiDB.ResourceRequirements, tplPath.Child("tidb"))...)
+	}
+	if tpl.TiFlash != nil {
+		allErrs = append(allErrs, validateResourceLimits(tpl.TiFlash.ResourceRequirements, tplPath.Child("tiflash"))...)
+	}
+	if tpl.TiCDC != nil {
+		allErrs = append(allErrs, validateResourceLimits(tpl.TiCDC.ResourceRequirements, tplPath.Child("ticdc"))...)
+	}
+	if tpl.Pump != nil {
+		allErrs = append(allErrs, validateResourceLimits(tpl.Pump.ResourceRequirements, tplPath.Child("pump"))...)
+	}
+
+	names := sets.NewString()
+	for i, check := range drill.Checks {
+		idxPath := fldPath.Child("checks").Index(i)
+		if check.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "name of the check must be set"))
+		} else if names.Has(check.Name) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), check.Name))
+		}
+		names.Insert(check.Name)
+		if strings.TrimSpace(check.SQL) == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("sql"), "sql of the check must be set"))
+		}
+	}
+	return allErrs
+}
+
+func validateResourceLimits(requirements corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if _, ok := requirements.Limits[name]; !ok {
+			allErrs = append(allErrs, field.Required(fldPath.Child("limits", string(name)), fmt.Sprintf("%s limit must be set", name)))
+		}
+	}
 	return allErrs
 }
 
@@ -93,6 +326,24 @@ func validateAnnotations(anns map[string]string, fldPath *field.Path) field.Erro
 	for _, key := range []string{label.AnnPDDeleteSlots, label.AnnTiDBDeleteSlots, label.AnnTiKVDeleteSlots, label.AnnTiFlashDeleteSlots} {
 		allErrs = append(allErrs, validateDeleteSlots(anns, key, fldPath.Child(key))...)
 	}
+	allErrs = append(allErrs, validateChangeRequestID(anns, fldPath.Child(label.AnnChangeRequestID))...)
+	return allErrs
+}
+
+// validateChangeRequestID checks the change request ID can be used as a label value,
+// as it is set on the objects created for the upgrade
+func validateChangeRequestID(anns map[string]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	value, ok := anns[label.AnnChangeRequestID]
+	if !ok {
+		return allErrs
+	}
+	if value == "" {
+		return append(allErrs, field.Invalid(fldPath, value, "change request ID must not be empty"))
+	}
+	for _, msg := range validation.IsValidLabelValue(value) {
+		allErrs = append(allErrs, field.Invalid(fldPath, value, msg))
+	}
 	return allErrs
 }
 
@@ -118,6 +369,7 @@ func validateTiDBClusterSpec(spec *v1alpha1.TidbClusterSpec, fldPath *field.Path
 	if spec.TiDB != nil {
 		allErrs = append(allErrs, validateTiDBSpec(spec.TiDB, fldPath.Child("tidb"))...)
 	}
+	allErrs = append(allErrs, validateTiDBGroups(spec, fldPath.Child("tidbGroups"))...)
 	if spec.Pump != nil {
 		allErrs = append(allErrs, validatePumpSpec(spec.Pump, fldPath.Child("pump"))...)
 	}
@@ -130,6 +382,27 @@ func validateTiDBClusterSpec(spec *v1alpha1.TidbClusterSpec, fldPath *field.Path
 	if spec.PDAddresses != nil {
 		allErrs = append(allErrs, validatePDAddresses(spec.PDAddresses, fldPath.Child("pdAddresses"))...)
 	}
+	if spec.DeletionPolicy != nil {
+		allErrs = append(allErrs, validateDeletionPolicy(spec.DeletionPolicy, fldPath.Child("deletionPolicy"))...)
+	}
+	if spec.StorageWarningThreshold != nil && (*spec.StorageWarningThreshold < 1 || *spec.StorageWarningThreshold > 100) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("storageWarningThreshold"), *spec.StorageWarningThreshold, "must be between 1 and 100"))
+	}
+	allErrs = append(allErrs, validateUpgradeOrder(spec, fldPath.Child("upgradeOrder"))...)
+	allErrs = append(allErrs, validateTimeDurationStr(spec.BootstrapTimeout, fldPath.Child("bootstrapTimeout"))...)
+	return allErrs
+}
+
+func validateDeletionPolicy(policy *v1alpha1.DeletionPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy.FinalBackup == nil {
+		return allErrs
+	}
+	fbPath := fldPath.Child("finalBackup")
+	if policy.FinalBackup.BackupScheduleName == "" {
+		allErrs = append(allErrs, field.Required(fbPath.Child("backupScheduleName"), "backupScheduleName of the final backup must be set"))
+	}
+	allErrs = append(allErrs, validateTimeDurationStr(policy.FinalBackup.Timeout, fbPath.Child("timeout"))...)
 	return allErrs
 }
 
@@ -148,6 +421,30 @@ func validatePDSpec(spec *v1alpha1.PDSpec, fldPath *field.Path) field.ErrorList
 	if len(spec.StorageVolumes) > 0 {
 		allErrs = append(allErrs, validateStorageVolumes(spec.StorageVolumes, fldPath.Child("storageVolumes"))...)
 	}
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
+	allErrs = append(allErrs, validateDashboardIngress(spec.DashboardIngress, fldPath.Child("dashboardIngress"))...)
+	allErrs = append(allErrs, validateNodeBindings(spec.NodeBindings, spec.Replicas, fldPath.Child("nodeBindings"))...)
+	return allErrs
+}
+
+func validateDashboardIngress(spec *v1alpha1.DashboardIngressSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec == nil {
+		return allErrs
+	}
+	if spec.Host == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("host"), "host of the ingress must be set"))
+	}
+	if auth := spec.AuthProxy; auth != nil {
+		authPath := fldPath.Child("authProxy")
+		if auth.Type != v1alpha1.DashboardAuthProxyBasicAuth && auth.Type != v1alpha1.DashboardAuthProxyOAuth2Proxy {
+			allErrs = append(allErrs, field.NotSupported(authPath.Child("type"), auth.Type,
+				[]string{string(v1alpha1.DashboardAuthProxyBasicAuth), string(v1alpha1.DashboardAuthProxyOAuth2Proxy)}))
+		}
+		if auth.SecretName == "" {
+			allErrs = append(allErrs, field.Required(authPath.Child("secretName"), "secret of the auth proxy must be set"))
+		}
+	}
 	return allErrs
 }
 
@@ -183,6 +480,85 @@ func validateTiKVSpec(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorL
 		allErrs = append(allErrs, validateVolumeName(spec.RocksDBLogVolumeName, spec.StorageVolumes, spec.AdditionalVolumes, spec.AdditionalVolumeMounts, fldPath)...)
 	}
 	allErrs = append(allErrs, validateTimeDurationStr(spec.EvictLeaderTimeout, fldPath.Child("evictLeaderTimeout"))...)
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
+	allErrs = append(allErrs, validateHostPortAllocation(spec.HostPorts, 2, fldPath.Child("hostPorts"))...)
+	allErrs = append(allErrs, validateSizedRequestsFloor(spec.Sizing, spec.ResourceRequirements, TiKVRequestsFloor, fldPath.Child("sizing"))...)
+	allErrs = append(allErrs, validateTiKVCPUPinning(spec, fldPath.Child("cpuPinning"))...)
+	allErrs = append(allErrs, validateTiKVHugepages(spec.Hugepages, fldPath.Child("hugepages"))...)
+	allErrs = append(allErrs, validateForceDeleteStuckPods(spec.ForceDeleteStuckPods, fldPath.Child("forceDeleteStuckPods"))...)
+	allErrs = append(allErrs, validateNodeBindings(spec.NodeBindings, spec.Replicas, fldPath.Child("nodeBindings"))...)
+	if p := spec.MaxTerminationGracePeriodSeconds; p != nil && *p < corev1.DefaultTerminationGracePeriodSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxTerminationGracePeriodSeconds"), *p,
+			fmt.Sprintf("must be no less than the default termination grace period %d", corev1.DefaultTerminationGracePeriodSeconds)))
+	}
+	return allErrs
+}
+
+// validateTiKVCPUPinning validates the TiKV pods are in the Guaranteed QoS class with integer CPUs, which is
+// required by the static CPU manager policy of kubelet to pin exclusive CPUs to TiKV. All the containers of
+// the pods must have the cpu and memory limits, and the requests must be equal to the limits if they are set.
+func validateTiKVCPUPinning(spec *v1alpha1.TiKVSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if !spec.CPUPinning {
+		return allErrs
+	}
+	resources, err := v1alpha1.SizedResources(spec.Sizing, spec.ResourceRequirements)
+	if err != nil {
+		// reported by validateSizing
+		return allErrs
+	}
+	allErrs = append(allErrs, validateGuaranteedResources("the tikv container", resources, fldPath)...)
+	if cpu, ok := resources.Limits[corev1.ResourceCPU]; ok && cpu.MilliValue()%1000 != 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.CPUPinning,
+			fmt.Sprintf("the cpu limits %s of the tikv container is not an integer, the static CPU manager policy only pins exclusive CPUs to the containers with integer CPUs", cpu.String())))
+	}
+	if spec.ShouldSeparateRocksDBLog() || spec.ShouldSeparateRaftLog() {
+		allErrs = append(allErrs, validateGuaranteedResources("the log tailer containers set by logTailer", spec.GetLogTailerSpec().ResourceRequirements, fldPath)...)
+	}
+	for _, c := range spec.AdditionalContainers {
+		allErrs = append(allErrs, validateGuaranteedResources(fmt.Sprintf("the additional container %q", c.Name), c.Resources, fldPath)...)
+	}
+	return allErrs
+}
+
+// validateGuaranteedResources validates the resources of a container meet the Guaranteed QoS class, the requests
+// not set default to the limits
+func validateGuaranteedResources(container string, resources corev1.ResourceRequirements, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		limits, ok := resources.Limits[name]
+		if !ok {
+			allErrs = append(allErrs, field.Invalid(fldPath, true,
+				fmt.Sprintf("the pods are not in the Guaranteed QoS class because %s has no %s limits", container, name)))
+			continue
+		}
+		if requests, ok := resources.Requests[name]; ok && requests.Cmp(limits) != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath, true,
+				fmt.Sprintf("the pods are not in the Guaranteed QoS class because the %s requests %s of %s is not equal to the limits %s", name, requests.String(), container, limits.String())))
+		}
+	}
+	return allErrs
+}
+
+// validateTiKVHugepages validates the size of the hugepages is supported and the amount is a multiple of the size
+func validateTiKVHugepages(hugepages *v1alpha1.TiKVHugepages, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if hugepages == nil {
+		return allErrs
+	}
+	switch hugepages.PageSize() {
+	case "2Mi", "1Gi":
+	default:
+		return append(allErrs, field.NotSupported(fldPath.Child("size"), hugepages.Size, []string{"2Mi", "1Gi"}))
+	}
+	size := resource.MustParse(hugepages.PageSize())
+	if hugepages.Amount.Sign() <= 0 {
+		return append(allErrs, field.Invalid(fldPath.Child("amount"), hugepages.Amount.String(), "must be greater than 0"))
+	}
+	if hugepages.Amount.Value()%size.Value() != 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("amount"), hugepages.Amount.String(),
+			fmt.Sprintf("must be a multiple of the size %s", hugepages.PageSize())))
+	}
 	return allErrs
 }
 
@@ -194,6 +570,7 @@ func validateTiFlashSpec(spec *v1alpha1.TiFlashSpec, fldPath *field.Path) field.
 		allErrs = append(allErrs, field.Invalid(fldPath.Child("spec.StorageClaims"),
 			spec.StorageClaims, "storageClaims should be configured at least one item."))
 	}
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
 	return allErrs
 }
 
@@ -203,6 +580,7 @@ func validateTiCDCSpec(spec *v1alpha1.TiCDCSpec, fldPath *field.Path) field.Erro
 	if len(spec.StorageVolumes) > 0 {
 		allErrs = append(allErrs, validateStorageVolumes(spec.StorageVolumes, fldPath.Child("storageVolumes"))...)
 	}
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
 	return allErrs
 }
 
@@ -257,6 +635,42 @@ func validateTiFlashConfig(config *v1alpha1.TiFlashConfigWraper, path *field.Pat
 	return allErrs
 }
 
+// validateTiDBGroups validates the names of the tidb groups are unique and do not collide with the names
+// of the objects of the default group, which is spec.tidb and is required
+func validateTiDBGroups(spec *v1alpha1.TidbClusterSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(spec.TiDBGroups) == 0 {
+		return allErrs
+	}
+	if spec.TiDB == nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath, "requires spec.tidb, which is the default group"))
+	}
+	names := sets.NewString()
+	for i, group := range spec.TiDBGroups {
+		idxPath := fldPath.Index(i)
+		for _, msg := range validation.IsDNS1123Label(group.Name) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), group.Name, msg))
+		}
+		if group.Name != "" && (group.Name[0] < 'a' || group.Name[0] > 'z') {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), group.Name, "must start with a letter"))
+		}
+		if group.Name == "peer" {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), group.Name, "collides with the headless service of the default group"))
+		}
+		if names.Has(group.Name) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), group.Name))
+		}
+		names.Insert(group.Name)
+		if group.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("replicas"), group.Replicas, "must not be negative"))
+		}
+		if group.Service != nil {
+			allErrs = append(allErrs, validateService(&group.Service.ServiceSpec, idxPath)...)
+		}
+	}
+	return allErrs
+}
+
 func validateTiDBSpec(spec *v1alpha1.TiDBSpec, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	allErrs = append(allErrs, validateComponentSpec(&spec.ComponentSpec, fldPath)...)
@@ -269,12 +683,111 @@ func validateTiDBSpec(spec *v1alpha1.TiDBSpec, fldPath *field.Path) field.ErrorL
 	if spec.ShouldSeparateSlowLog() && spec.SlowLogVolumeName != "" {
 		allErrs = append(allErrs, validateVolumeName(spec.SlowLogVolumeName, spec.StorageVolumes, spec.AdditionalVolumes, spec.AdditionalVolumeMounts, fldPath)...)
 	}
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
+	allErrs = append(allErrs, validateTiDBScaleInDrain(spec.ScaleInDrain, fldPath.Child("scaleInDrain"))...)
+	if spec.GracefulShutdownTimeoutSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("gracefulShutdownTimeoutSeconds"), spec.GracefulShutdownTimeoutSeconds, "must not be negative"))
+	}
+	if spec.UpgradeDrainConnectionThreshold != nil && *spec.UpgradeDrainConnectionThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("upgradeDrainConnectionThreshold"), *spec.UpgradeDrainConnectionThreshold, "must not be negative"))
+	}
+	if spec.UpgradeBatchSize != nil {
+		allErrs = append(allErrs, validateTiDBUpgradeBatchSize(spec.UpgradeBatchSize, spec.Replicas, fldPath.Child("upgradeBatchSize"))...)
+	}
+	if spec.UpgradeReadinessTimeout != nil && spec.UpgradeReadinessTimeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("upgradeReadinessTimeout"), spec.UpgradeReadinessTimeout.Duration.String(), "must be greater than 0"))
+	}
+	allErrs = append(allErrs, validateForceDeleteStuckPods(spec.ForceDeleteStuckPods, fldPath.Child("forceDeleteStuckPods"))...)
+	allErrs = append(allErrs, validateTiDBCanaryOrdinals(spec.CanaryOrdinals, spec.Replicas, fldPath.Child("canaryOrdinals"))...)
+	allErrs = append(allErrs, validateTiDBProxyProtocol(spec.ProxyProtocol, fldPath.Child("proxyProtocol"))...)
+	return allErrs
+}
+
+// validateTiDBProxyProtocol validates the trusted networks are the IPs or the CIDRs TiDB accepts in
+// `proxy-protocol.networks`, they are required if it is enabled, as the connections from all the networks
+// must send the PROXY protocol header by default, including the ones in the Kubernetes cluster
+func validateTiDBProxyProtocol(spec *v1alpha1.TiDBProxyProtocol, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec == nil {
+		return allErrs
+	}
+	if spec.Enabled && len(spec.TrustedNetworks) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("trustedNetworks"), "the networks of the load balancer must be set if the PROXY protocol is enabled"))
+	}
+	for i, network := range spec.TrustedNetworks {
+		if network == "*" || net.ParseIP(network) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("trustedNetworks").Index(i), network, "must be an IP, a CIDR or *"))
+		}
+	}
+	switch spec.Provider {
+	case "", v1alpha1.LoadBalancerProviderAWS, v1alpha1.LoadBalancerProviderGCP, v1alpha1.LoadBalancerProviderAzure:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("provider"), spec.Provider,
+			[]string{string(v1alpha1.LoadBalancerProviderAWS), string(v1alpha1.LoadBalancerProviderGCP), string(v1alpha1.LoadBalancerProviderAzure)}))
+	}
+	return allErrs
+}
+
+// validateTiDBCanaryOrdinals validates the canary ordinals are the highest ordinals of the replicas, as the
+// partition of the StatefulSet only upgrades the pods of the ordinals not less than it
+func validateTiDBCanaryOrdinals(ordinals []int32, replicas int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(ordinals) == 0 {
+		return allErrs
+	}
+	seen := sets.NewInt32()
+	for i, ordinal := range ordinals {
+		if seen.Has(ordinal) {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), ordinal))
+			continue
+		}
+		seen.Insert(ordinal)
+		if ordinal < 0 || ordinal >= replicas {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), ordinal, fmt.Sprintf("must be in the range [0, %d)", replicas)))
+		}
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+	// the distinct ordinals in range are the highest ones if the lowest of them is replicas minus their number
+	if seen.List()[0] != replicas-int32(seen.Len()) {
+		allErrs = append(allErrs, field.Invalid(fldPath, ordinals, fmt.Sprintf("must be the highest ordinals of the %d replicas", replicas)))
+	}
+	return allErrs
+}
+
+// validateTiDBUpgradeBatchSize validates the max number or percentage of the TiDB pods upgraded at a time,
+// it must leave at least one of the replicas serving
+func validateTiDBUpgradeBatchSize(v *intstr.IntOrString, replicas int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch v.Type {
+	case intstr.Int:
+		if v.IntVal < 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath, v.IntVal, "must be greater than 0"))
+		} else if replicas > 1 && v.IntVal > replicas-1 {
+			allErrs = append(allErrs, field.Invalid(fldPath, v.IntVal, fmt.Sprintf("must not exceed replicas - 1 (%d)", replicas-1)))
+		}
+	case intstr.String:
+		percent, err := strconv.Atoi(strings.TrimSuffix(v.StrVal, "%"))
+		if err != nil || !strings.HasSuffix(v.StrVal, "%") {
+			allErrs = append(allErrs, field.Invalid(fldPath, v.StrVal, "must be an integer or a percentage, e.g. 25%"))
+		} else if percent < 1 || percent > 99 {
+			allErrs = append(allErrs, field.Invalid(fldPath, v.StrVal, "must be between 1% and 99%"))
+		} else if n := int32(percent) * replicas / 100; replicas > 1 && n < 1 {
+			// the percentage would be raised to 1 pod silently, which is more than it allows
+			allErrs = append(allErrs, field.Invalid(fldPath, v.StrVal, fmt.Sprintf("must resolve to at least 1 of the %d replicas", replicas)))
+		}
+	}
 	return allErrs
 }
 
 func validatePumpSpec(spec *v1alpha1.PumpSpec, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	allErrs = append(allErrs, validateComponentSpec(&spec.ComponentSpec, fldPath)...)
+	allErrs = append(allErrs, validateUpgradeCompletionWebhook(spec.UpgradeCompletionWebhook, fldPath.Child("upgradeCompletionWebhook"))...)
 	return allErrs
 }
 
@@ -345,7 +858,69 @@ func validateComponentSpec(spec *v1alpha1.ComponentSpec, fldPath *field.Path) fi
 	allErrs := field.ErrorList{}
 	// TODO validate other fields
 	allErrs = append(allErrs, validateEnv(spec.Env, fldPath.Child("env"))...)
+	allErrs = append(allErrs, validateEnvFrom(spec.EnvFrom, fldPath.Child("envFrom"))...)
 	allErrs = append(allErrs, validateAdditionalContainers(spec.AdditionalContainers, fldPath.Child("additionalContainers"))...)
+	allErrs = append(allErrs, validateSizing(spec.Sizing, fldPath.Child("sizing"))...)
+	return allErrs
+}
+
+func validateSizing(sizing *v1alpha1.Sizing, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if sizing == nil {
+		return allErrs
+	}
+	if len(sizing.Size) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("size"), "size must not be empty"))
+	}
+	for name, q := range sizing.Size {
+		if q.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("size").Key(string(name)), q.String(), "must be greater than 0"))
+		}
+	}
+	switch sizing.SizeIs {
+	case "", v1alpha1.SizeIsLimits, v1alpha1.SizeIsRequests:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("sizeIs"), sizing.SizeIs, []string{string(v1alpha1.SizeIsLimits), string(v1alpha1.SizeIsRequests)}))
+	}
+	if _, err := sizing.ParseRequestsRatio(); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("requestsRatio"), *sizing.RequestsRatio, err.Error()))
+	}
+	return allErrs
+}
+
+// validateSizedRequestsFloor validates the requests derived from the sizing are not below the floor, the requests
+// set explicitly are not checked
+func validateSizedRequestsFloor(sizing *v1alpha1.Sizing, explicit corev1.ResourceRequirements, floor corev1.ResourceList, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if sizing == nil {
+		return allErrs
+	}
+	resources, err := v1alpha1.SizedResources(sizing, explicit)
+	if err != nil {
+		// reported by validateSizing
+		return allErrs
+	}
+	ratio := "1"
+	if sizing.RequestsRatio != nil {
+		ratio = *sizing.RequestsRatio
+	}
+	names := make([]string, 0, len(floor))
+	for name := range floor {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := explicit.Requests[corev1.ResourceName(name)]; ok {
+			continue
+		}
+		requests, ok := resources.Requests[corev1.ResourceName(name)]
+		min := floor[corev1.ResourceName(name)]
+		if !ok || requests.Cmp(min) >= 0 {
+			continue
+		}
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("requestsRatio"), ratio,
+			fmt.Sprintf("the derived %s requests %s is below the floor %s", name, requests.String(), min.String())))
+	}
 	return allErrs
 }
 
@@ -416,6 +991,40 @@ func validateEnv(vars []corev1.EnvVar, fldPath *field.Path) field.ErrorList {
 	return allErrs
 }
 
+// validateEnvFrom validates env sources
+func validateEnvFrom(vars []corev1.EnvFromSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, ev := range vars {
+		idxPath := fldPath.Index(i)
+		if len(ev.Prefix) > 0 {
+			for _, msg := range validation.IsEnvVarName(ev.Prefix) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("prefix"), ev.Prefix, msg))
+			}
+		}
+
+		numSources := 0
+		if ev.ConfigMapRef != nil {
+			numSources++
+			for _, msg := range apivalidation.NameIsDNSSubdomain(ev.ConfigMapRef.Name, false) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("configMapRef", "name"), ev.ConfigMapRef.Name, msg))
+			}
+		}
+		if ev.SecretRef != nil {
+			numSources++
+			for _, msg := range apivalidation.NameIsDNSSubdomain(ev.SecretRef.Name, false) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("secretRef", "name"), ev.SecretRef.Name, msg))
+			}
+		}
+		if numSources == 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath, "", "must specify one of: `configMapRef` or `secretRef`"))
+		} else if numSources > 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, "", "may not have more than one field specified at a time"))
+		}
+	}
+	return allErrs
+}
+
 func validateEnvVarValueFrom(ev corev1.EnvVar, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 
@@ -498,6 +1107,33 @@ func ValidateCreateTidbCluster(tc *v1alpha1.TidbCluster) field.ErrorList {
 	return allErrs
 }
 
+// pdForbiddenUpgradeFields are the batched upgrade fields of TiDB, PD is upgraded one pod at a time to keep
+// the quorum of the PD members.
+var pdForbiddenUpgradeFields = []string{"upgradeBatchSize", "maxSurge", "maxUnavailable"}
+
+// ValidateTidbClusterRaw validates the fields of a TidbCluster that are dropped when it is decoded, e.g. the
+// batched upgrade fields of TiDB set on PD by mistake.
+func ValidateTidbClusterRaw(raw []byte) field.ErrorList {
+	allErrs := field.ErrorList{}
+	obj := struct {
+		Spec struct {
+			PD map[string]json.RawMessage `json:"pd,omitempty"`
+		} `json:"spec"`
+	}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec"), string(raw), err.Error()))
+		return allErrs
+	}
+	pdPath := field.NewPath("spec", "pd")
+	for _, name := range pdForbiddenUpgradeFields {
+		if _, ok := obj.Spec.PD[name]; ok {
+			allErrs = append(allErrs, field.Forbidden(pdPath.Child(name),
+				"PD is upgraded one pod at a time to keep the quorum of the PD members, the batched upgrade is only supported by TiDB"))
+		}
+	}
+	return allErrs
+}
+
 // ValidateUpdateTidbCluster validates a new TidbCluster against an existing TidbCluster to be updated
 func ValidateUpdateTidbCluster(old, tc *v1alpha1.TidbCluster) field.ErrorList {
 
@@ -514,10 +1150,44 @@ func ValidateUpdateTidbCluster(old, tc *v1alpha1.TidbCluster) field.ErrorList {
 	return allErrs
 }
 
+// validateReservedEnvNames rejects the env names reserved by TiDB Operator in the components.
+// Existing TidbClusters may use them as they used to be overridden silently, so it is only
+// called for new TidbClusters.
+func validateReservedEnvNames(spec *v1alpha1.TidbClusterSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	validate := func(component *v1alpha1.ComponentSpec, fldPath *field.Path) {
+		for i, ev := range component.Env {
+			if reservedEnvNames.Has(ev.Name) {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Index(i).Child("name"), fmt.Sprintf("%s is reserved by TiDB Operator", ev.Name)))
+			}
+		}
+	}
+	if spec.PD != nil {
+		validate(&spec.PD.ComponentSpec, path.Child("pd", "env"))
+	}
+	if spec.TiKV != nil {
+		validate(&spec.TiKV.ComponentSpec, path.Child("tikv", "env"))
+	}
+	if spec.TiDB != nil {
+		validate(&spec.TiDB.ComponentSpec, path.Child("tidb", "env"))
+	}
+	if spec.TiFlash != nil {
+		validate(&spec.TiFlash.ComponentSpec, path.Child("tiflash", "env"))
+	}
+	if spec.TiCDC != nil {
+		validate(&spec.TiCDC.ComponentSpec, path.Child("ticdc", "env"))
+	}
+	if spec.Pump != nil {
+		validate(&spec.Pump.ComponentSpec, path.Child("pump", "env"))
+	}
+	return allErrs
+}
+
 // For now we limit some validations only in Create phase to keep backward compatibility
 // TODO(aylei): call this in ValidateTidbCluster after we deprecated the old versions of helm chart officially
 func validateNewTidbClusterSpec(spec *v1alpha1.TidbClusterSpec, path *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateReservedEnvNames(spec, path)...)
 	pdSpecified := spec.PD != nil
 	tidbSpecified := spec.TiDB != nil
 	tikvSpecified := spec.TiKV != nil
@@ -683,6 +1353,170 @@ func validateTimeDurationStr(timeStr *string, fldPath *field.Path) field.ErrorLi
 	return allErrs
 }
 
+func validateForceDeleteStuckPods(spec *v1alpha1.ForceDeleteStuckPods, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spec == nil {
+		return allErrs
+	}
+	allErrs = append(allErrs, validateTimeDurationStr(spec.Threshold, fldPath.Child("threshold"))...)
+	return allErrs
+}
+
+// validateNodeBindings validates each binding selects the nodes by either the name or the labels, and the
+// pods of all the ordinals in [0, replicas) are bound. The bindings of the ordinals out of the replicas are
+// allowed so that they are kept across the scale-in and the scale-out.
+func validateNodeBindings(bindings []v1alpha1.NodeBinding, replicas int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(bindings) == 0 {
+		return allErrs
+	}
+	bound := sets.NewInt32()
+	for i, b := range bindings {
+		idxPath := fldPath.Index(i)
+		if b.Ordinal < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("ordinal"), b.Ordinal, "must be non-negative"))
+		} else if bound.Has(b.Ordinal) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("ordinal"), b.Ordinal))
+		}
+		bound.Insert(b.Ordinal)
+		switch {
+		case b.NodeName == "" && len(b.NodeSelector) == 0:
+			allErrs = append(allErrs, field.Required(idxPath, "one of nodeName and nodeSelector must be set"))
+		case b.NodeName != "" && len(b.NodeSelector) > 0:
+			allErrs = append(allErrs, field.Forbidden(idxPath, "only one of nodeName and nodeSelector can be set"))
+		case b.NodeName != "":
+			for _, msg := range validation.IsDNS1123Subdomain(b.NodeName) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("nodeName"), b.NodeName, msg))
+			}
+		default:
+			allErrs = append(allErrs, validateNodeSelectorLabels(b.NodeSelector, idxPath.Child("nodeSelector"))...)
+		}
+	}
+	for ordinal := int32(0); ordinal < replicas; ordinal++ {
+		if !bound.Has(ordinal) {
+			allErrs = append(allErrs, field.Required(fldPath, fmt.Sprintf("the pod of the ordinal %d must be bound", ordinal)))
+		}
+	}
+	return allErrs
+}
+
+func validateNodeSelectorLabels(selector map[string]string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for k, v := range selector {
+		for _, msg := range validation.IsQualifiedName(k) {
+			allErrs = append(allErrs, field.Invalid(fldPath, k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(k), v, msg))
+		}
+	}
+	return allErrs
+}
+
+func validateUpgradeCompletionWebhook(webhook *v1alpha1.UpgradeCompletionWebhook, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if webhook == nil {
+		return allErrs
+	}
+	if webhook.URL == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("url"), "url of the webhook must be set"))
+	} else if u, err := url.Parse(webhook.URL); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, err.Error()))
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, "support 'http' and 'https' schemes only"))
+	} else if u.User != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, "must not contain user info"))
+	} else if host := u.Hostname(); host == "" || host == "localhost" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, "must have a host other than localhost"))
+	} else if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), webhook.URL, "must not be a loopback, link-local or unspecified address"))
+	}
+	allErrs = append(allErrs, validateTimeDurationStr(webhook.Timeout, fldPath.Child("timeout"))...)
+	return allErrs
+}
+
+func validateTiDBScaleInDrain(drain *v1alpha1.TiDBScaleInDrain, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if drain == nil {
+		return allErrs
+	}
+	if drain.ConnectionThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("connectionThreshold"), drain.ConnectionThreshold, "must not be negative"))
+	}
+	allErrs = append(allErrs, validateTimeDurationStr(drain.Timeout, fldPath.Child("timeout"))...)
+	return allErrs
+}
+
+// validateUpgradeOrder validates that the upgrade order lists the upgradable components at most once and
+// contains all the components deployed
+func validateUpgradeOrder(spec *v1alpha1.TidbClusterSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(spec.UpgradeOrder) == 0 {
+		return allErrs
+	}
+	supported := make([]string, 0, len(v1alpha1.DefaultUpgradeOrder))
+	for _, compType := range v1alpha1.DefaultUpgradeOrder {
+		supported = append(supported, compType.String())
+	}
+	listed := map[v1alpha1.MemberType]bool{}
+	for i, compType := range spec.UpgradeOrder {
+		idxPath := fldPath.Index(i)
+		switch {
+		case !v1alpha1.IsUpgradeOrderComponent(compType):
+			allErrs = append(allErrs, field.NotSupported(idxPath, compType, supported))
+		case listed[compType]:
+			allErrs = append(allErrs, field.Duplicate(idxPath, compType))
+		}
+		listed[compType] = true
+	}
+	deployed := map[v1alpha1.MemberType]bool{
+		v1alpha1.PDMemberType:      spec.PD != nil,
+		v1alpha1.TiFlashMemberType: spec.TiFlash != nil,
+		v1alpha1.TiKVMemberType:    spec.TiKV != nil,
+		v1alpha1.PumpMemberType:    spec.Pump != nil,
+		v1alpha1.TiDBMemberType:    spec.TiDB != nil,
+		v1alpha1.TiCDCMemberType:   spec.TiCDC != nil,
+	}
+	var missing []string
+	for _, compType := range v1alpha1.DefaultUpgradeOrder {
+		if deployed[compType] && !listed[compType] {
+			missing = append(missing, compType.String())
+		}
+	}
+	if len(missing) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.UpgradeOrder, fmt.Sprintf("must contain the deployed components %s", strings.Join(missing, ", "))))
+	}
+	return allErrs
+}
+
+// validateHostPortAllocation validates the range of the host ports, each slot of the range has portsPerSlot ports
+func validateHostPortAllocation(hostPorts *v1alpha1.HostPortAllocation, portsPerSlot int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if hostPorts == nil {
+		return allErrs
+	}
+	base := v1alpha1.DefaultTiKVServerPort
+	if hostPorts.Base != nil {
+		base = *hostPorts.Base
+		if base < 1024 || base > 65535 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("base"), base, "must be between 1024 and 65535"))
+			return allErrs
+		}
+	}
+	maxSlots := v1alpha1.DefaultHostPortMaxSlots
+	if hostPorts.MaxSlots != nil {
+		maxSlots = *hostPorts.MaxSlots
+		if maxSlots < 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSlots"), maxSlots, "must be greater than 0"))
+			return allErrs
+		}
+	}
+	if int64(base)+int64(maxSlots)*int64(portsPerSlot)-1 > 65535 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSlots"), maxSlots, fmt.Sprintf("the range starting from %d exceeds 65535", base)))
+	}
+	return allErrs
+}
+
 // validatePromDurationStr validate prometheus duration, Units Supported: y, w, d, h, m, s, ms.
 func validatePromDurationStr(timeStr *string, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
@@ -694,6 +1528,73 @@ func validatePromDurationStr(timeStr *string, fldPath *field.Path) field.ErrorLi
 	return allErrs
 }
 
+// validateExternalTargets validates the external targets of the TidbMonitor, the names of the external clusters
+// must be distinct from the monitored TidbClusters to not mix the metrics of the clusters in the dashboards.
+func validateExternalTargets(monitor *v1alpha1.TidbMonitor, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	// the values of the `cluster` and `tidb_cluster` labels of the monitored TidbClusters
+	clusterLabels := sets.NewString()
+	for _, tcRef := range monitor.Spec.Clusters {
+		ns := tcRef.Namespace
+		if ns == "" {
+			ns = monitor.Namespace
+		}
+		clusterLabels.Insert(tcRef.Name, fmt.Sprintf("%s-%s", ns, tcRef.Name))
+	}
+	names := sets.NewString()
+	for i, targets := range monitor.Spec.ExternalTargets {
+		idxPath := fldPath.Index(i)
+		switch {
+		case targets.Name == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "name of the external cluster must not be empty"))
+		case clusterLabels.Has(targets.Name):
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), targets.Name, "name of the external cluster collides with the labels of the monitored TidbClusters"))
+		case names.Has(targets.Name):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), targets.Name))
+		}
+		names.Insert(targets.Name)
+
+		groups := []struct {
+			name  string
+			group *v1alpha1.ExternalTargetGroup
+		}{{"pd", targets.PD}, {"tidb", targets.TiDB}, {"tikv", targets.TiKV}}
+		for _, g := range groups {
+			if g.group != nil {
+				allErrs = append(allErrs, validateExternalTargetGroup(g.group, idxPath.Child(g.name))...)
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateExternalTargetGroup(group *v1alpha1.ExternalTargetGroup, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if len(group.Targets) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("targets"), "targets must not be empty"))
+	}
+	for i, target := range group.Targets {
+		host, port, err := net.SplitHostPort(target)
+		if err == nil && host == "" {
+			err = fmt.Errorf("empty host")
+		}
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("targets").Index(i), target, fmt.Sprintf("must be in the form of host:port: %v", err)))
+		}
+	}
+	for name := range group.Labels {
+		if !model.LabelName(name).IsValid() {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("labels"), name, "must be a valid Prometheus label name"))
+		}
+		if reservedMonitorLabels.Has(name) {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("labels").Key(name), "the label is set by TidbMonitor"))
+		}
+	}
+	return allErrs
+}
+
 // clusterVersionLessThan2 makes sure that deployed dm cluster version not to be v1.0.x
 func clusterVersionLessThan2(version string) (bool, error) {
 	v, err := semver.NewVersion(version)
@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // Copyright PingCAP, Inc.
@@ -28,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	types "k8s.io/apimachinery/pkg/types"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -305,6 +307,26 @@ func (in *BackupScheduleSpec) DeepCopyInto(out *BackupScheduleSpec) {
 		*out = make([]v1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.RestoreDrill != nil {
+		in, out := &in.RestoreDrill, &out.RestoreDrill
+		*out = new(RestoreDrillSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BaseBackupRef != nil {
+		in, out := &in.BaseBackupRef, &out.BaseBackupRef
+		*out = new(BaseBackupRef)
+		**out = **in
+	}
+	if in.JobHistoryLimit != nil {
+		in, out := &in.JobHistoryLimit, &out.JobHistoryLimit
+		*out = new(JobHistoryLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(Notification)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -329,6 +351,27 @@ func (in *BackupScheduleStatus) DeepCopyInto(out *BackupScheduleStatus) {
 		in, out := &in.AllBackupCleanTime, &out.AllBackupCleanTime
 		*out = (*in).DeepCopy()
 	}
+	if in.NextBackupTime != nil {
+		in, out := &in.NextBackupTime, &out.NextBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RestoreDrill != nil {
+		in, out := &in.RestoreDrill, &out.RestoreDrill
+		*out = new(RestoreDrillStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IncrementalChain != nil {
+		in, out := &in.IncrementalChain, &out.IncrementalChain
+		*out = new(IncrementalChainStatus)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -401,6 +444,13 @@ func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.CleanOption != nil {
 		in, out := &in.CleanOption, &out.CleanOption
 		*out = new(CleanOption)
@@ -411,6 +461,26 @@ func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
 		*out = new(v1.PodSecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DeletionProtection != nil {
+		in, out := &in.DeletionProtection, &out.DeletionProtection
+		*out = new(bool)
+		**out = **in
+	}
+	if in.JobHistoryLimit != nil {
+		in, out := &in.JobHistoryLimit, &out.JobHistoryLimit
+		*out = new(JobHistoryLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(Notification)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -436,6 +506,11 @@ func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Notification != nil {
+		in, out := &in.Notification, &out.Notification
+		*out = new(NotificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -449,6 +524,22 @@ func (in *BackupStatus) DeepCopy() *BackupStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BaseBackupRef) DeepCopyInto(out *BaseBackupRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BaseBackupRef.
+func (in *BaseBackupRef) DeepCopy() *BaseBackupRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BaseBackupRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BasicAuth) DeepCopyInto(out *BasicAuth) {
 	*out = *in
@@ -768,6 +859,27 @@ func (in *CommonConfig) DeepCopy() *CommonConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentRestartStatus) DeepCopyInto(out *ComponentRestartStatus) {
+	*out = *in
+	in.RequestTime.DeepCopyInto(&out.RequestTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentRestartStatus.
+func (in *ComponentRestartStatus) DeepCopy() *ComponentRestartStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentRestartStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 	*out = *in
@@ -901,6 +1013,21 @@ func (in *ComponentSpec) DeepCopyInto(out *ComponentSpec) {
 		*out = make([]TopologySpreadConstraint, len(*in))
 		copy(*out, *in)
 	}
+	if in.PrepullNextImage != nil {
+		in, out := &in.PrepullNextImage, &out.PrepullNextImage
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Sizing != nil {
+		in, out := &in.Sizing, &out.Sizing
+		*out = new(Sizing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WaitForExternalProvisioning != nil {
+		in, out := &in.WaitForExternalProvisioning, &out.WaitForExternalProvisioning
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -935,6 +1062,77 @@ func (in *ConfigMapRef) DeepCopy() *ConfigMapRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectivityChecks) DeepCopyInto(out *ConnectivityChecks) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectivityChecks.
+func (in *ConnectivityChecks) DeepCopy() *ConnectivityChecks {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectivityChecks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectivityEdge) DeepCopyInto(out *ConnectivityEdge) {
+	*out = *in
+	if in.Broken != nil {
+		in, out := &in.Broken, &out.Broken
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Unknown != nil {
+		in, out := &in.Unknown, &out.Unknown
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectivityEdge.
+func (in *ConnectivityEdge) DeepCopy() *ConnectivityEdge {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectivityEdge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectivityStatus) DeepCopyInto(out *ConnectivityStatus) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+	if in.Edges != nil {
+		in, out := &in.Edges, &out.Edges
+		*out = make([]ConnectivityEdge, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectivityStatus.
+func (in *ConnectivityStatus) DeepCopy() *ConnectivityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectivityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CoprocessorCache) DeepCopyInto(out *CoprocessorCache) {
 	*out = *in
@@ -1194,6 +1392,11 @@ func (in *DMClusterSpec) DeepCopyInto(out *DMClusterSpec) {
 		*out = make([]TopologySpreadConstraint, len(*in))
 		copy(*out, *in)
 	}
+	if in.DeletionProtection != nil {
+		in, out := &in.DeletionProtection, &out.DeletionProtection
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -1328,6 +1531,27 @@ func (in *DMSecurityConfig) DeepCopy() *DMSecurityConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardAuthProxy) DeepCopyInto(out *DashboardAuthProxy) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardAuthProxy.
+func (in *DashboardAuthProxy) DeepCopy() *DashboardAuthProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardAuthProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DashboardConfig) DeepCopyInto(out *DashboardConfig) {
 	*out = *in
@@ -1384,6 +1608,39 @@ func (in *DashboardConfig) DeepCopy() *DashboardConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DashboardIngressSpec) DeepCopyInto(out *DashboardIngressSpec) {
+	*out = *in
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AuthProxy != nil {
+		in, out := &in.AuthProxy, &out.AuthProxy
+		*out = new(DashboardAuthProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DashboardIngressSpec.
+func (in *DashboardIngressSpec) DeepCopy() *DashboardIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DashboardIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataResource) DeepCopyInto(out *DataResource) {
 	*out = *in
@@ -1448,6 +1705,27 @@ func (in *DataResourceList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeletionPolicy) DeepCopyInto(out *DeletionPolicy) {
+	*out = *in
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		*out = new(FinalBackup)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeletionPolicy.
+func (in *DeletionPolicy) DeepCopy() *DeletionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DeletionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeploymentStorageStatus) DeepCopyInto(out *DeploymentStorageStatus) {
 	*out = *in
@@ -1528,6 +1806,23 @@ func (in *EmptyStruct) DeepCopy() *EmptyStruct {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictLeaderProgress) DeepCopyInto(out *EvictLeaderProgress) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictLeaderProgress.
+func (in *EvictLeaderProgress) DeepCopy() *EvictLeaderProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictLeaderProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EvictLeaderStatus) DeepCopyInto(out *EvictLeaderStatus) {
 	*out = *in
@@ -1571,6 +1866,37 @@ func (in *Experimental) DeepCopy() *Experimental {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalClusterTargets) DeepCopyInto(out *ExternalClusterTargets) {
+	*out = *in
+	if in.PD != nil {
+		in, out := &in.PD, &out.PD
+		*out = new(ExternalTargetGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TiDB != nil {
+		in, out := &in.TiDB, &out.TiDB
+		*out = new(ExternalTargetGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TiKV != nil {
+		in, out := &in.TiKV, &out.TiKV
+		*out = new(ExternalTargetGroup)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalClusterTargets.
+func (in *ExternalClusterTargets) DeepCopy() *ExternalClusterTargets {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalClusterTargets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalConfig) DeepCopyInto(out *ExternalConfig) {
 	*out = *in
@@ -1609,6 +1935,34 @@ func (in *ExternalEndpoint) DeepCopy() *ExternalEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalTargetGroup) DeepCopyInto(out *ExternalTargetGroup) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalTargetGroup.
+func (in *ExternalTargetGroup) DeepCopy() *ExternalTargetGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalTargetGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Failover) DeepCopyInto(out *Failover) {
 	*out = *in
@@ -1666,6 +2020,27 @@ func (in *FileLogConfig) DeepCopy() *FileLogConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FinalBackup) DeepCopyInto(out *FinalBackup) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FinalBackup.
+func (in *FinalBackup) DeepCopy() *FinalBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(FinalBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Flash) DeepCopyInto(out *Flash) {
 	*out = *in
@@ -2039,12 +2414,33 @@ func (in *FlashUser) DeepCopyInto(out *FlashUser) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlashUser.
-func (in *FlashUser) DeepCopy() *FlashUser {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlashUser.
+func (in *FlashUser) DeepCopy() *FlashUser {
+	if in == nil {
+		return nil
+	}
+	out := new(FlashUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForceDeleteStuckPods) DeepCopyInto(out *ForceDeleteStuckPods) {
+	*out = *in
+	if in.Threshold != nil {
+		in, out := &in.Threshold, &out.Threshold
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForceDeleteStuckPods.
+func (in *ForceDeleteStuckPods) DeepCopy() *ForceDeleteStuckPods {
 	if in == nil {
 		return nil
 	}
-	out := new(FlashUser)
+	out := new(ForceDeleteStuckPods)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -2065,6 +2461,23 @@ func (in *GcsStorageProvider) DeepCopy() *GcsStorageProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GhostStore) DeepCopyInto(out *GhostStore) {
+	*out = *in
+	in.DetectedAt.DeepCopyInto(&out.DetectedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GhostStore.
+func (in *GhostStore) DeepCopy() *GhostStore {
+	if in == nil {
+		return nil
+	}
+	out := new(GhostStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaSpec) DeepCopyInto(out *GrafanaSpec) {
 	*out = *in
@@ -2138,6 +2551,80 @@ func (in *HelperSpec) DeepCopy() *HelperSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPortAllocation) DeepCopyInto(out *HostPortAllocation) {
+	*out = *in
+	if in.Base != nil {
+		in, out := &in.Base, &out.Base
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxSlots != nil {
+		in, out := &in.MaxSlots, &out.MaxSlots
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPortAllocation.
+func (in *HostPortAllocation) DeepCopy() *HostPortAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPortAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPorts) DeepCopyInto(out *HostPorts) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPorts.
+func (in *HostPorts) DeepCopy() *HostPorts {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPorts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePrePullStatus) DeepCopyInto(out *ImagePrePullStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePrePullStatus.
+func (in *ImagePrePullStatus) DeepCopy() *ImagePrePullStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePrePullStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IncrementalChainStatus) DeepCopyInto(out *IncrementalChainStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IncrementalChainStatus.
+func (in *IncrementalChainStatus) DeepCopy() *IncrementalChainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IncrementalChainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
 	*out = *in
@@ -2201,6 +2688,16 @@ func (in *InitializerSpec) DeepCopyInto(out *InitializerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.TLSClientSecretName != nil {
+		in, out := &in.TLSClientSecretName, &out.TLSClientSecretName
+		*out = new(string)
+		**out = **in
+	}
+	if in.TLSCASecretName != nil {
+		in, out := &in.TLSCASecretName, &out.TLSCASecretName
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -2281,6 +2778,32 @@ func (in *IsolationRead) DeepCopy() *IsolationRead {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobHistoryLimit) DeepCopyInto(out *JobHistoryLimit) {
+	*out = *in
+	if in.Successful != nil {
+		in, out := &in.Successful, &out.Successful
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Failed != nil {
+		in, out := &in.Failed, &out.Failed
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobHistoryLimit.
+func (in *JobHistoryLimit) DeepCopy() *JobHistoryLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(JobHistoryLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalStorageProvider) DeepCopyInto(out *LocalStorageProvider) {
 	*out = *in
@@ -2788,6 +3311,22 @@ func (in *NGMonitoringStatus) DeepCopy() *NGMonitoringStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkingSpec) DeepCopyInto(out *NetworkingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkingSpec.
+func (in *NetworkingSpec) DeepCopy() *NetworkingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Networks) DeepCopyInto(out *Networks) {
 	*out = *in
@@ -2809,6 +3348,72 @@ func (in *Networks) DeepCopy() *Networks {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeBinding) DeepCopyInto(out *NodeBinding) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeBinding.
+func (in *NodeBinding) DeepCopy() *NodeBinding {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeBinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notification) DeepCopyInto(out *Notification) {
+	*out = *in
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EventTypes != nil {
+		in, out := &in.EventTypes, &out.EventTypes
+		*out = make([]NotificationEventType, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
+func (in *Notification) DeepCopy() *Notification {
+	if in == nil {
+		return nil
+	}
+	out := new(Notification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationStatus) DeepCopyInto(out *NotificationStatus) {
+	*out = *in
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationStatus.
+func (in *NotificationStatus) DeepCopy() *NotificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObservedStorageVolumeStatus) DeepCopyInto(out *ObservedStorageVolumeStatus) {
 	*out = *in
@@ -3669,6 +4274,30 @@ func (in *PDSpec) DeepCopyInto(out *PDSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DashboardIngress != nil {
+		in, out := &in.DashboardIngress, &out.DashboardIngress
+		*out = new(DashboardIngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MemberPriorities != nil {
+		in, out := &in.MemberPriorities, &out.MemberPriorities
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeBindings != nil {
+		in, out := &in.NodeBindings, &out.NodeBindings
+		*out = make([]NodeBinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -3690,6 +4319,10 @@ func (in *PDStatus) DeepCopyInto(out *PDStatus) {
 		*out = new(appsv1.StatefulSetStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.StaleSince != nil {
+		in, out := &in.StaleSince, &out.StaleSince
+		*out = (*in).DeepCopy()
+	}
 	if in.Members != nil {
 		in, out := &in.Members, &out.Members
 		*out = make(map[string]PDMember, len(*in))
@@ -3719,6 +4352,16 @@ func (in *PDStatus) DeepCopyInto(out *PDStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePullStatus)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -3734,6 +4377,11 @@ func (in *PDStatus) DeepCopyInto(out *PDStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -4304,6 +4952,11 @@ func (in *PumpSpec) DeepCopyInto(out *PumpSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -4336,6 +4989,11 @@ func (in *PumpStatus) DeepCopyInto(out *PumpStatus) {
 			}
 		}
 	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -4351,6 +5009,11 @@ func (in *PumpStatus) DeepCopyInto(out *PumpStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -4567,6 +5230,79 @@ func (in *RestoreCondition) DeepCopy() *RestoreCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreDrillCheck) DeepCopyInto(out *RestoreDrillCheck) {
+	*out = *in
+	if in.Expected != nil {
+		in, out := &in.Expected, &out.Expected
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreDrillCheck.
+func (in *RestoreDrillCheck) DeepCopy() *RestoreDrillCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreDrillCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreDrillSpec) DeepCopyInto(out *RestoreDrillSpec) {
+	*out = *in
+	in.ClusterTemplate.DeepCopyInto(&out.ClusterTemplate)
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]RestoreDrillCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreDrillSpec.
+func (in *RestoreDrillSpec) DeepCopy() *RestoreDrillSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreDrillSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreDrillStatus) DeepCopyInto(out *RestoreDrillStatus) {
+	*out = *in
+	if in.ScheduledTime != nil {
+		in, out := &in.ScheduledTime, &out.ScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreDrillStatus.
+func (in *RestoreDrillStatus) DeepCopy() *RestoreDrillStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreDrillStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreList) DeepCopyInto(out *RestoreList) {
 	*out = *in
@@ -4600,6 +5336,22 @@ func (in *RestoreList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreRenameMapping) DeepCopyInto(out *RestoreRenameMapping) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreRenameMapping.
+func (in *RestoreRenameMapping) DeepCopy() *RestoreRenameMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreRenameMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 	*out = *in
@@ -4644,6 +5396,13 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		*out = new(v1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.ImagePullSecrets != nil {
 		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
 		*out = make([]v1.LocalObjectReference, len(*in))
@@ -4654,6 +5413,16 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RenameMapping != nil {
+		in, out := &in.RenameMapping, &out.RenameMapping
+		*out = make([]RestoreRenameMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeClusterSettings != nil {
+		in, out := &in.ExcludeClusterSettings, &out.ExcludeClusterSettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.PodSecurityContext != nil {
 		in, out := &in.PodSecurityContext, &out.PodSecurityContext
 		*out = new(v1.PodSecurityContext)
@@ -4684,6 +5453,21 @@ func (in *RestoreStatus) DeepCopyInto(out *RestoreStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RestoredTables != nil {
+		in, out := &in.RestoredTables, &out.RestoredTables
+		*out = make([]RestoredTable, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedClusterSettings != nil {
+		in, out := &in.AppliedClusterSettings, &out.AppliedClusterSettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedClusterSettings != nil {
+		in, out := &in.SkippedClusterSettings, &out.SkippedClusterSettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -4697,6 +5481,22 @@ func (in *RestoreStatus) DeepCopy() *RestoreStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoredTable) DeepCopyInto(out *RestoredTable) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoredTable.
+func (in *RestoredTable) DeepCopy() *RestoredTable {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoredTable)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *S3StorageProvider) DeepCopyInto(out *S3StorageProvider) {
 	*out = *in
@@ -4910,6 +5710,34 @@ func (in *ServiceSpec) DeepCopy() *ServiceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Sizing) DeepCopyInto(out *Sizing) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.RequestsRatio != nil {
+		in, out := &in.RequestsRatio, &out.RequestsRatio
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sizing.
+func (in *Sizing) DeepCopy() *Sizing {
+	if in == nil {
+		return nil
+	}
+	out := new(Sizing)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Status) DeepCopyInto(out *Status) {
 	*out = *in
@@ -5050,6 +5878,28 @@ func (in *StorageProvider) DeepCopy() *StorageProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageUsage) DeepCopyInto(out *StorageUsage) {
+	*out = *in
+	if in.PodsOverThreshold != nil {
+		in, out := &in.PodsOverThreshold, &out.PodsOverThreshold
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageUsage.
+func (in *StorageUsage) DeepCopy() *StorageUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageVolume) DeepCopyInto(out *StorageVolume) {
 	*out = *in
@@ -5249,6 +6099,18 @@ func (in *TiCDCSpec) DeepCopyInto(out *TiCDCSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChangefeedPriorities != nil {
+		in, out := &in.ChangefeedPriorities, &out.ChangefeedPriorities
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -5277,6 +6139,16 @@ func (in *TiCDCStatus) DeepCopyInto(out *TiCDCStatus) {
 			(*out)[key] = val
 		}
 	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePullStatus)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -5613,6 +6485,39 @@ func (in *TiDBFailureMember) DeepCopy() *TiDBFailureMember {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBGroup) DeepCopyInto(out *TiDBGroup) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = (*in).DeepCopy()
+	}
+	in.ResourceRequirements.DeepCopyInto(&out.ResourceRequirements)
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(TiDBServiceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBGroup.
+func (in *TiDBGroup) DeepCopy() *TiDBGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiDBInitializer) DeepCopyInto(out *TiDBInitializer) {
 	*out = *in
@@ -5677,6 +6582,65 @@ func (in *TiDBProbe) DeepCopy() *TiDBProbe {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBProxyProtocol) DeepCopyInto(out *TiDBProxyProtocol) {
+	*out = *in
+	if in.TrustedNetworks != nil {
+		in, out := &in.TrustedNetworks, &out.TrustedNetworks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBProxyProtocol.
+func (in *TiDBProxyProtocol) DeepCopy() *TiDBProxyProtocol {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBProxyProtocol)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBScaleInDrain) DeepCopyInto(out *TiDBScaleInDrain) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBScaleInDrain.
+func (in *TiDBScaleInDrain) DeepCopy() *TiDBScaleInDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBScaleInDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBScaleInDrainStatus) DeepCopyInto(out *TiDBScaleInDrainStatus) {
+	*out = *in
+	in.Deadline.DeepCopyInto(&out.Deadline)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBScaleInDrainStatus.
+func (in *TiDBScaleInDrainStatus) DeepCopy() *TiDBScaleInDrainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBScaleInDrainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiDBServiceSpec) DeepCopyInto(out *TiDBServiceSpec) {
 	*out = *in
@@ -5820,6 +6784,46 @@ func (in *TiDBSpec) DeepCopyInto(out *TiDBSpec) {
 		*out = new(TiDBInitializer)
 		**out = **in
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleInDrain != nil {
+		in, out := &in.ScaleInDrain, &out.ScaleInDrain
+		*out = new(TiDBScaleInDrain)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeBatchSize != nil {
+		in, out := &in.UpgradeBatchSize, &out.UpgradeBatchSize
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.UpgradeReadinessTimeout != nil {
+		in, out := &in.UpgradeReadinessTimeout, &out.UpgradeReadinessTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.UpgradeDrainConnectionThreshold != nil {
+		in, out := &in.UpgradeDrainConnectionThreshold, &out.UpgradeDrainConnectionThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ForceDeleteStuckPods != nil {
+		in, out := &in.ForceDeleteStuckPods, &out.ForceDeleteStuckPods
+		*out = new(ForceDeleteStuckPods)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CanaryOrdinals != nil {
+		in, out := &in.CanaryOrdinals, &out.CanaryOrdinals
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProxyProtocol != nil {
+		in, out := &in.ProxyProtocol, &out.ProxyProtocol
+		*out = new(TiDBProxyProtocol)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -5860,6 +6864,16 @@ func (in *TiDBStatus) DeepCopyInto(out *TiDBStatus) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePullStatus)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -5875,6 +6889,16 @@ func (in *TiDBStatus) DeepCopyInto(out *TiDBStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.ScaleInDrain != nil {
+		in, out := &in.ScaleInDrain, &out.ScaleInDrain
+		*out = new(TiDBScaleInDrainStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeDrain != nil {
+		in, out := &in.UpgradeDrain, &out.UpgradeDrain
+		*out = new(TiDBUpgradeDrainStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -5911,6 +6935,28 @@ func (in *TiDBTLSClient) DeepCopy() *TiDBTLSClient {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiDBUpgradeDrainStatus) DeepCopyInto(out *TiDBUpgradeDrainStatus) {
+	*out = *in
+	if in.PodNames != nil {
+		in, out := &in.PodNames, &out.PodNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Deadline.DeepCopyInto(&out.Deadline)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiDBUpgradeDrainStatus.
+func (in *TiDBUpgradeDrainStatus) DeepCopy() *TiDBUpgradeDrainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiDBUpgradeDrainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiFlashCommonConfigWraper) DeepCopyInto(out *TiFlashCommonConfigWraper) {
 	*out = *in
@@ -6045,6 +7091,11 @@ func (in *TiFlashSpec) DeepCopyInto(out *TiFlashSpec) {
 		*out = new(Failover)
 		**out = **in
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -6094,6 +7145,20 @@ func (in *TiFlashStatus) DeepCopyInto(out *TiFlashStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.StaleSince != nil {
+		in, out := &in.StaleSince, &out.StaleSince
+		*out = (*in).DeepCopy()
+	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePullStatus)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -6109,6 +7174,11 @@ func (in *TiFlashStatus) DeepCopyInto(out *TiFlashStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -6872,6 +7942,23 @@ func (in *TiKVDbConfig) DeepCopy() *TiKVDbConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVDivergentStore) DeepCopyInto(out *TiKVDivergentStore) {
+	*out = *in
+	in.DetectedAt.DeepCopyInto(&out.DetectedAt)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVDivergentStore.
+func (in *TiKVDivergentStore) DeepCopy() *TiKVDivergentStore {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVDivergentStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVEncryptionConfig) DeepCopyInto(out *TiKVEncryptionConfig) {
 	*out = *in
@@ -6961,6 +8048,23 @@ func (in *TiKVGCConfig) DeepCopy() *TiKVGCConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVHugepages) DeepCopyInto(out *TiKVHugepages) {
+	*out = *in
+	out.Amount = in.Amount.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVHugepages.
+func (in *TiKVHugepages) DeepCopy() *TiKVHugepages {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVHugepages)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TiKVImportConfig) DeepCopyInto(out *TiKVImportConfig) {
 	*out = *in
@@ -7833,25 +8937,79 @@ func (in *TiKVServerConfig) DeepCopyInto(out *TiKVServerConfig) {
 		*out = new(bool)
 		**out = **in
 	}
-	if in.RequestBatchEnableCrossCommand != nil {
-		in, out := &in.RequestBatchEnableCrossCommand, &out.RequestBatchEnableCrossCommand
-		*out = new(bool)
+	if in.RequestBatchEnableCrossCommand != nil {
+		in, out := &in.RequestBatchEnableCrossCommand, &out.RequestBatchEnableCrossCommand
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RequestBatchWaitDuration != nil {
+		in, out := &in.RequestBatchWaitDuration, &out.RequestBatchWaitDuration
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVServerConfig.
+func (in *TiKVServerConfig) DeepCopy() *TiKVServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVSlowStore) DeepCopyInto(out *TiKVSlowStore) {
+	*out = *in
+	in.LastCheckTime.DeepCopyInto(&out.LastCheckTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVSlowStore.
+func (in *TiKVSlowStore) DeepCopy() *TiKVSlowStore {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVSlowStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVSlowStoreMitigation) DeepCopyInto(out *TiKVSlowStoreMitigation) {
+	*out = *in
+	if in.SlowScoreThreshold != nil {
+		in, out := &in.SlowScoreThreshold, &out.SlowScoreThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SlowTrendCauseThreshold != nil {
+		in, out := &in.SlowTrendCauseThreshold, &out.SlowTrendCauseThreshold
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ConsecutiveChecks != nil {
+		in, out := &in.ConsecutiveChecks, &out.ConsecutiveChecks
+		*out = new(int32)
 		**out = **in
 	}
-	if in.RequestBatchWaitDuration != nil {
-		in, out := &in.RequestBatchWaitDuration, &out.RequestBatchWaitDuration
-		*out = new(string)
+	if in.LeaderWeightPercent != nil {
+		in, out := &in.LeaderWeightPercent, &out.LeaderWeightPercent
+		*out = new(int32)
 		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVServerConfig.
-func (in *TiKVServerConfig) DeepCopy() *TiKVServerConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVSlowStoreMitigation.
+func (in *TiKVSlowStoreMitigation) DeepCopy() *TiKVSlowStoreMitigation {
 	if in == nil {
 		return nil
 	}
-	out := new(TiKVServerConfig)
+	out := new(TiKVSlowStoreMitigation)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -7923,6 +9081,53 @@ func (in *TiKVSpec) DeepCopyInto(out *TiKVSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UpgradeCompletionWebhook != nil {
+		in, out := &in.UpgradeCompletionWebhook, &out.UpgradeCompletionWebhook
+		*out = new(UpgradeCompletionWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeStabilization != nil {
+		in, out := &in.UpgradeStabilization, &out.UpgradeStabilization
+		*out = new(TiKVUpgradeStabilization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostPorts != nil {
+		in, out := &in.HostPorts, &out.HostPorts
+		*out = new(HostPortAllocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hugepages != nil {
+		in, out := &in.Hugepages, &out.Hugepages
+		*out = new(TiKVHugepages)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SlowStoreMitigation != nil {
+		in, out := &in.SlowStoreMitigation, &out.SlowStoreMitigation
+		*out = new(TiKVSlowStoreMitigation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ForceDeleteStuckPods != nil {
+		in, out := &in.ForceDeleteStuckPods, &out.ForceDeleteStuckPods
+		*out = new(ForceDeleteStuckPods)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeBindings != nil {
+		in, out := &in.NodeBindings, &out.NodeBindings
+		*out = make([]NodeBinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxTerminationGracePeriodSeconds != nil {
+		in, out := &in.MaxTerminationGracePeriodSeconds, &out.MaxTerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.UpgradeSafetyChecks != nil {
+		in, out := &in.UpgradeSafetyChecks, &out.UpgradeSafetyChecks
+		*out = new(TiKVUpgradeSafetyChecks)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -7987,6 +9192,73 @@ func (in *TiKVStatus) DeepCopyInto(out *TiKVStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.StaleSince != nil {
+		in, out := &in.StaleSince, &out.StaleSince
+		*out = (*in).DeepCopy()
+	}
+	if in.EvictLeaderProgress != nil {
+		in, out := &in.EvictLeaderProgress, &out.EvictLeaderProgress
+		*out = new(EvictLeaderProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeBaseline != nil {
+		in, out := &in.UpgradeBaseline, &out.UpgradeBaseline
+		*out = new(TiKVUpgradeBaseline)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostPorts != nil {
+		in, out := &in.HostPorts, &out.HostPorts
+		*out = new(HostPorts)
+		**out = **in
+	}
+	if in.PodHostPorts != nil {
+		in, out := &in.PodHostPorts, &out.PodHostPorts
+		*out = make(map[string]HostPorts, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GhostStores != nil {
+		in, out := &in.GhostStores, &out.GhostStores
+		*out = make(map[string]GhostStore, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ImagePrePull != nil {
+		in, out := &in.ImagePrePull, &out.ImagePrePull
+		*out = new(ImagePrePullStatus)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumePreProvisioning != nil {
+		in, out := &in.VolumePreProvisioning, &out.VolumePreProvisioning
+		*out = new(VolumePreProvisioningStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SlowStores != nil {
+		in, out := &in.SlowStores, &out.SlowStores
+		*out = make(map[string]TiKVSlowStore, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.DivergentStores != nil {
+		in, out := &in.DivergentStores, &out.DivergentStores
+		*out = make(map[string]TiKVDivergentStore, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.VolumeReplace != nil {
+		in, out := &in.VolumeReplace, &out.VolumeReplace
+		*out = new(TiKVVolumeReplaceStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make(map[StorageVolumeName]*StorageVolumeStatus, len(*in))
@@ -8002,6 +9274,11 @@ func (in *TiKVStatus) DeepCopyInto(out *TiKVStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -8294,6 +9571,93 @@ func (in *TiKVUnifiedReadPoolConfig) DeepCopy() *TiKVUnifiedReadPoolConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVUpgradeBaseline) DeepCopyInto(out *TiKVUpgradeBaseline) {
+	*out = *in
+	in.CaptureTime.DeepCopyInto(&out.CaptureTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVUpgradeBaseline.
+func (in *TiKVUpgradeBaseline) DeepCopy() *TiKVUpgradeBaseline {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVUpgradeBaseline)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVUpgradeStabilization) DeepCopyInto(out *TiKVUpgradeStabilization) {
+	*out = *in
+	if in.MaxWait != nil {
+		in, out := &in.MaxWait, &out.MaxWait
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVUpgradeStabilization.
+func (in *TiKVUpgradeStabilization) DeepCopy() *TiKVUpgradeStabilization {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVUpgradeStabilization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVUpgradeSafetyChecks) DeepCopyInto(out *TiKVUpgradeSafetyChecks) {
+	*out = *in
+	if in.MaxApplyLag != nil {
+		in, out := &in.MaxApplyLag, &out.MaxApplyLag
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVUpgradeSafetyChecks.
+func (in *TiKVUpgradeSafetyChecks) DeepCopy() *TiKVUpgradeSafetyChecks {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVUpgradeSafetyChecks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TiKVVolumeReplaceStatus) DeepCopyInto(out *TiKVVolumeReplaceStatus) {
+	*out = *in
+	if in.PVCUIDSet != nil {
+		in, out := &in.PVCUIDSet, &out.PVCUIDSet
+		*out = make(map[types.UID]EmptyStruct, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TiKVVolumeReplaceStatus.
+func (in *TiKVVolumeReplaceStatus) DeepCopy() *TiKVVolumeReplaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TiKVVolumeReplaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TidbAutoScalerSpec) DeepCopyInto(out *TidbAutoScalerSpec) {
 	*out = *in
@@ -8571,6 +9935,13 @@ func (in *TidbClusterSpec) DeepCopyInto(out *TidbClusterSpec) {
 		*out = new(TiDBSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TiDBGroups != nil {
+		in, out := &in.TiDBGroups, &out.TiDBGroups
+		*out = make([]TiDBGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.TiKV != nil {
 		in, out := &in.TiKV, &out.TiKV
 		*out = new(TiKVSpec)
@@ -8694,6 +10065,41 @@ func (in *TidbClusterSpec) DeepCopyInto(out *TidbClusterSpec) {
 		*out = make([]TopologySpreadConstraint, len(*in))
 		copy(*out, *in)
 	}
+	if in.DeletionPolicy != nil {
+		in, out := &in.DeletionPolicy, &out.DeletionPolicy
+		*out = new(DeletionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeletionProtection != nil {
+		in, out := &in.DeletionProtection, &out.DeletionProtection
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ConnectivityChecks != nil {
+		in, out := &in.ConnectivityChecks, &out.ConnectivityChecks
+		*out = new(ConnectivityChecks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageWarningThreshold != nil {
+		in, out := &in.StorageWarningThreshold, &out.StorageWarningThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.UpgradeOrder != nil {
+		in, out := &in.UpgradeOrder, &out.UpgradeOrder
+		*out = make([]MemberType, len(*in))
+		copy(*out, *in)
+	}
+	if in.BootstrapTimeout != nil {
+		in, out := &in.BootstrapTimeout, &out.BootstrapTimeout
+		*out = new(string)
+		**out = **in
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(NetworkingSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -8716,11 +10122,48 @@ func (in *TidbClusterStatus) DeepCopyInto(out *TidbClusterStatus) {
 	in.Pump.DeepCopyInto(&out.Pump)
 	in.TiFlash.DeepCopyInto(&out.TiFlash)
 	in.TiCDC.DeepCopyInto(&out.TiCDC)
+	if in.TiDBGroups != nil {
+		in, out := &in.TiDBGroups, &out.TiDBGroups
+		*out = make(map[string]TiDBStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.AutoScaler != nil {
 		in, out := &in.AutoScaler, &out.AutoScaler
 		*out = new(TidbClusterAutoScalerRef)
 		**out = **in
 	}
+	if in.LastReconcileTime != nil {
+		in, out := &in.LastReconcileTime, &out.LastReconcileTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Connectivity != nil {
+		in, out := &in.Connectivity, &out.Connectivity
+		*out = new(ConnectivityStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Restarts != nil {
+		in, out := &in.Restarts, &out.Restarts
+		*out = make(map[string]ComponentRestartStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.ConfigHashes != nil {
+		in, out := &in.ConfigHashes, &out.ConfigHashes
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.UpgradeCompletionWebhooks != nil {
+		in, out := &in.UpgradeCompletionWebhooks, &out.UpgradeCompletionWebhooks
+		*out = make(map[string]UpgradeCompletionWebhookStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]TidbClusterCondition, len(*in))
@@ -8846,11 +10289,30 @@ func (in *TidbInitializerSpec) DeepCopyInto(out *TidbInitializerSpec) {
 		*out = new(v1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.TLSClientSecretName != nil {
 		in, out := &in.TLSClientSecretName, &out.TLSClientSecretName
 		*out = new(string)
 		**out = **in
 	}
+	if in.TLSCASecretName != nil {
+		in, out := &in.TLSCASecretName, &out.TLSCASecretName
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -8966,6 +10428,13 @@ func (in *TidbMonitorSpec) DeepCopyInto(out *TidbMonitorSpec) {
 		*out = make([]TidbClusterRef, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExternalTargets != nil {
+		in, out := &in.ExternalTargets, &out.ExternalTargets
+		*out = make([]ExternalClusterTargets, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Prometheus.DeepCopyInto(&out.Prometheus)
 	if in.Grafana != nil {
 		in, out := &in.Grafana, &out.Grafana
@@ -9114,6 +10583,11 @@ func (in *TidbMonitorStatus) DeepCopyInto(out *TidbMonitorStatus) {
 		*out = new(appsv1.StatefulSetStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.StorageUsage != nil {
+		in, out := &in.StorageUsage, &out.StorageUsage
+		*out = new(StorageUsage)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -9333,6 +10807,70 @@ func (in *UnjoinedMember) DeepCopy() *UnjoinedMember {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCompletionWebhook) DeepCopyInto(out *UpgradeCompletionWebhook) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCompletionWebhook.
+func (in *UpgradeCompletionWebhook) DeepCopy() *UpgradeCompletionWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCompletionWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCompletionWebhookStatus) DeepCopyInto(out *UpgradeCompletionWebhookStatus) {
+	*out = *in
+	in.LastAttemptTime.DeepCopyInto(&out.LastAttemptTime)
+	if in.NextAttemptTime != nil {
+		in, out := &in.NextAttemptTime, &out.NextAttemptTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCompletionWebhookStatus.
+func (in *UpgradeCompletionWebhookStatus) DeepCopy() *UpgradeCompletionWebhookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCompletionWebhookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.UpgradingOrdinal != nil {
+		in, out := &in.UpgradingOrdinal, &out.UpgradingOrdinal
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *User) DeepCopyInto(out *User) {
 	*out = *in
@@ -9364,6 +10902,29 @@ func (in *User) DeepCopy() *User {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumePreProvisioningStatus) DeepCopyInto(out *VolumePreProvisioningStatus) {
+	*out = *in
+	if in.PlannedZones != nil {
+		in, out := &in.PlannedZones, &out.PlannedZones
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumePreProvisioningStatus.
+func (in *VolumePreProvisioningStatus) DeepCopy() *VolumePreProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumePreProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkerConfig) DeepCopyInto(out *WorkerConfig) {
 	*out = *in
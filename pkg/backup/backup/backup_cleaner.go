@@ -187,10 +187,12 @@ func (bc *backupCleaner) makeCleanJob(backup *v1alpha1.Backup) (*batchv1.Job, st
 			Tolerations:       backup.Spec.Tolerations,
 			ImagePullSecrets:  backup.Spec.ImagePullSecrets,
 			Affinity:          backup.Spec.Affinity,
+			NodeSelector:      backup.Spec.NodeSelector,
 			Volumes:           volumes,
 			PriorityClassName: backup.Spec.PriorityClassName,
 		},
 	}
+	controller.SetJobPodDefaults(bc.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
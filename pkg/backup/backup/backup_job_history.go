@@ -0,0 +1,176 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// isBackupFinished returns true if the backup is complete or failed and is not running any more, the jobs of the
+// backups being scheduled, prepared or running are never pruned
+func isBackupFinished(backup *v1alpha1.Backup) bool {
+	switch backup.Status.Phase {
+	case v1alpha1.BackupScheduled, v1alpha1.BackupPrepare, v1alpha1.BackupRunning:
+		return false
+	}
+	return v1alpha1.IsBackupComplete(backup) || v1alpha1.IsBackupFailed(backup)
+}
+
+// backupsToPrune returns the finished backups whose jobs are pruned by the limit, they are the ones older than the
+// most recent limit.Successful complete backups and limit.Failed failed backups. The most recent failed backup is
+// never pruned and is returned as lastFailed.
+func backupsToPrune(backups []*v1alpha1.Backup, limit *v1alpha1.JobHistoryLimit) (pruned []*v1alpha1.Backup, lastFailed *v1alpha1.Backup) {
+	if limit == nil {
+		return nil, nil
+	}
+	finished := make([]*v1alpha1.Backup, 0, len(backups))
+	for _, backup := range backups {
+		if isBackupFinished(backup) {
+			finished = append(finished, backup)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		ti, tj := finished[i].CreationTimestamp, finished[j].CreationTimestamp
+		if ti.Equal(&tj) {
+			return finished[i].Name > finished[j].Name
+		}
+		return tj.Before(&ti)
+	})
+
+	var successful, failed int32
+	for _, backup := range finished {
+		if v1alpha1.IsBackupFailed(backup) {
+			failed++
+			if lastFailed == nil {
+				lastFailed = backup
+				continue
+			}
+			if limit.Failed != nil && failed > *limit.Failed {
+				pruned = append(pruned, backup)
+			}
+			continue
+		}
+		successful++
+		if limit.Successful != nil && successful > *limit.Successful {
+			pruned = append(pruned, backup)
+		}
+	}
+	return pruned, lastFailed
+}
+
+// syncJobHistory deletes the jobs and the pods of the finished backups beyond the job history limit of the group
+// of the backup. The backups created by a BackupSchedule are in the group of the schedule, and the other backups
+// in the namespace are in the same group.
+func (bm *backupManager) syncJobHistory(backup *v1alpha1.Backup) error {
+	ns := backup.GetNamespace()
+	backups, limit, err := bm.jobHistoryGroup(backup)
+	if err != nil {
+		return err
+	}
+	pruned, lastFailed := backupsToPrune(backups, limit)
+
+	var errs []error
+	for _, b := range pruned {
+		job, err := bm.deps.JobLister.Jobs(ns).Get(b.GetBackupJobName())
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if job.DeletionTimestamp != nil || !metav1.IsControlledBy(job, b) {
+			continue
+		}
+		owner := b.DeepCopy()
+		owner.SetGroupVersionKind(controller.BackupControllerKind)
+		if err := bm.deps.JobControl.DeleteJob(owner, job); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		klog.Infof("backup %s/%s: job %s is pruned by the job history limit", ns, b.GetName(), job.GetName())
+	}
+
+	// the job of the most recent failed backup is kept for debugging even if its TTL is set
+	if lastFailed != nil {
+		job, err := bm.deps.JobLister.Jobs(ns).Get(lastFailed.GetBackupJobName())
+		if err == nil && job.Spec.TTLSecondsAfterFinished != nil && job.DeletionTimestamp == nil && metav1.IsControlledBy(job, lastFailed) {
+			update := job.DeepCopy()
+			update.Spec.TTLSecondsAfterFinished = nil
+			if _, err := bm.deps.KubeClientset.BatchV1().Jobs(ns).Update(context.TODO(), update, metav1.UpdateOptions{}); err != nil {
+				errs = append(errs, fmt.Errorf("remove the TTL of job %s/%s failed, err: %v", ns, job.GetName(), err))
+			} else {
+				klog.Infof("backup %s/%s: the TTL of job %s is removed to keep the job of the most recent failed backup", ns, lastFailed.GetName(), job.GetName())
+			}
+		} else if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errorutils.NewAggregate(errs)
+}
+
+// jobHistoryGroup returns the backups in the group of the backup and the job history limit of the group, the limit
+// is nil if it is not set
+func (bm *backupManager) jobHistoryGroup(backup *v1alpha1.Backup) ([]*v1alpha1.Backup, *v1alpha1.JobHistoryLimit, error) {
+	ns := backup.GetNamespace()
+	all, err := bm.deps.BackupLister.Backups(ns).List(labels.Everything())
+	if err != nil {
+		return nil, nil, fmt.Errorf("list backups in namespace %s failed, err: %v", ns, err)
+	}
+
+	if scheduleName, ok := backup.Labels[label.BackupScheduleLabelKey]; ok {
+		var backups []*v1alpha1.Backup
+		for _, b := range all {
+			if b.Labels[label.BackupScheduleLabelKey] == scheduleName {
+				backups = append(backups, b)
+			}
+		}
+		limit := backup.Spec.JobHistoryLimit
+		bs, err := bm.deps.BackupScheduleLister.BackupSchedules(ns).Get(scheduleName)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("get backup schedule %s/%s failed, err: %v", ns, scheduleName, err)
+		}
+		if err == nil && bs.Spec.JobHistoryLimit != nil {
+			limit = bs.Spec.JobHistoryLimit
+		}
+		return backups, limit, nil
+	}
+
+	var backups []*v1alpha1.Backup
+	var latest *v1alpha1.Backup
+	for _, b := range all {
+		if _, ok := b.Labels[label.BackupScheduleLabelKey]; ok {
+			continue
+		}
+		backups = append(backups, b)
+		if b.Spec.JobHistoryLimit != nil && (latest == nil || latest.CreationTimestamp.Before(&b.CreationTimestamp)) {
+			latest = b
+		}
+	}
+	if latest == nil {
+		return backups, nil, nil
+	}
+	return backups, latest.Spec.JobHistoryLimit, nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+func newHistoryBackup(name string, age time.Duration, phase v1alpha1.BackupConditionType) *v1alpha1.Backup {
+	backup := &v1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         corev1.NamespaceDefault,
+			UID:               k8stypes.UID(name),
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+	}
+	backup.Status.Phase = phase
+	backup.Status.Conditions = []v1alpha1.BackupCondition{{Type: phase, Status: corev1.ConditionTrue}}
+	return backup
+}
+
+func newHistoryJob(backup *v1alpha1.Backup, ttl *int32) *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            backup.GetBackupJobName(),
+			Namespace:       backup.Namespace,
+			OwnerReferences: []metav1.OwnerReference{controller.GetBackupOwnerRef(backup)},
+		},
+		Spec: batchv1.JobSpec{TTLSecondsAfterFinished: ttl},
+	}
+	return job
+}
+
+func backupNames(backups []*v1alpha1.Backup) []string {
+	names := []string{}
+	for _, backup := range backups {
+		names = append(names, backup.Name)
+	}
+	return names
+}
+
+func TestBackupsToPrune(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	backups := []*v1alpha1.Backup{
+		newHistoryBackup("complete-1h", time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("complete-2h", 2*time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("failed-3h", 3*time.Hour, v1alpha1.BackupFailed),
+		newHistoryBackup("complete-4h", 4*time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("failed-5h", 5*time.Hour, v1alpha1.BackupFailed),
+		newHistoryBackup("failed-6h", 6*time.Hour, v1alpha1.BackupFailed),
+		// the backups not finished are never pruned however old they are
+		newHistoryBackup("running-7h", 7*time.Hour, v1alpha1.BackupRunning),
+		newHistoryBackup("prepare-8h", 8*time.Hour, v1alpha1.BackupPrepare),
+		newHistoryBackup("scheduled-9h", 9*time.Hour, v1alpha1.BackupScheduled),
+	}
+
+	tests := []struct {
+		name       string
+		limit      *v1alpha1.JobHistoryLimit
+		pruned     []string
+		lastFailed string
+	}{
+		{
+			name:   "no limit",
+			limit:  nil,
+			pruned: []string{},
+		},
+		{
+			name:       "no counts",
+			limit:      &v1alpha1.JobHistoryLimit{},
+			pruned:     []string{},
+			lastFailed: "failed-3h",
+		},
+		{
+			name:       "successful limit",
+			limit:      &v1alpha1.JobHistoryLimit{Successful: pointer.Int32Ptr(1)},
+			pruned:     []string{"complete-2h", "complete-4h"},
+			lastFailed: "failed-3h",
+		},
+		{
+			name:       "failed limit",
+			limit:      &v1alpha1.JobHistoryLimit{Failed: pointer.Int32Ptr(2)},
+			pruned:     []string{"failed-6h"},
+			lastFailed: "failed-3h",
+		},
+		{
+			name:       "the most recent failed backup is kept with the zero limits",
+			limit:      &v1alpha1.JobHistoryLimit{Successful: pointer.Int32Ptr(0), Failed: pointer.Int32Ptr(0)},
+			pruned:     []string{"complete-1h", "complete-2h", "complete-4h", "failed-5h", "failed-6h"},
+			lastFailed: "failed-3h",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pruned, lastFailed := backupsToPrune(backups, tt.limit)
+			g.Expect(backupNames(pruned)).To(Equal(tt.pruned))
+			if tt.lastFailed == "" {
+				g.Expect(lastFailed).To(BeNil())
+			} else {
+				g.Expect(lastFailed.Name).To(Equal(tt.lastFailed))
+			}
+		})
+	}
+}
+
+func TestSyncJobHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewSimpleClientDependencies()
+	bm := NewBackupManager(deps).(*backupManager)
+	backupIndexer := deps.InformerFactory.Pingcap().V1alpha1().Backups().Informer().GetIndexer()
+	scheduleIndexer := deps.InformerFactory.Pingcap().V1alpha1().BackupSchedules().Informer().GetIndexer()
+	jobIndexer := deps.KubeInformerFactory.Batch().V1().Jobs().Informer().GetIndexer()
+
+	ttl := pointer.Int32Ptr(3600)
+	addBackup := func(backup *v1alpha1.Backup, ttl *int32) {
+		g.Expect(backupIndexer.Add(backup)).To(Succeed())
+		job := newHistoryJob(backup, ttl)
+		g.Expect(jobIndexer.Add(job)).To(Succeed())
+		_, err := deps.KubeClientset.BatchV1().Jobs(job.Namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+		g.Expect(err).To(Succeed())
+	}
+	jobExists := func(backup *v1alpha1.Backup) bool {
+		_, err := deps.KubeClientset.BatchV1().Jobs(backup.Namespace).Get(context.TODO(), backup.GetBackupJobName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return false
+		}
+		g.Expect(err).To(Succeed())
+		return true
+	}
+
+	// the backups of the schedule are pruned by the limit of the schedule
+	schedule := &v1alpha1.BackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{Name: "schedule", Namespace: corev1.NamespaceDefault},
+		Spec: v1alpha1.BackupScheduleSpec{
+			JobHistoryLimit: &v1alpha1.JobHistoryLimit{Successful: pointer.Int32Ptr(1), Failed: pointer.Int32Ptr(0)},
+		},
+	}
+	g.Expect(scheduleIndexer.Add(schedule)).To(Succeed())
+	scheduled := []*v1alpha1.Backup{
+		newHistoryBackup("scheduled-complete-1h", time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("scheduled-failed-2h", 2*time.Hour, v1alpha1.BackupFailed),
+		newHistoryBackup("scheduled-complete-3h", 3*time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("scheduled-failed-4h", 4*time.Hour, v1alpha1.BackupFailed),
+		newHistoryBackup("scheduled-running-5h", 5*time.Hour, v1alpha1.BackupRunning),
+	}
+	for _, backup := range scheduled {
+		backup.Labels = map[string]string{label.BackupScheduleLabelKey: schedule.Name}
+		addBackup(backup, ttl)
+	}
+
+	// the standalone backups are not in the group of the schedule
+	standalone := []*v1alpha1.Backup{
+		newHistoryBackup("complete-1h", time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("complete-2h", 2*time.Hour, v1alpha1.BackupComplete),
+		newHistoryBackup("complete-3h", 3*time.Hour, v1alpha1.BackupComplete),
+	}
+	for _, backup := range standalone {
+		addBackup(backup, nil)
+	}
+
+	g.Expect(bm.syncJobHistory(scheduled[0])).To(Succeed())
+	g.Expect(jobExists(scheduled[0])).To(BeTrue())
+	g.Expect(jobExists(scheduled[1])).To(BeTrue())
+	g.Expect(jobExists(scheduled[2])).To(BeFalse())
+	g.Expect(jobExists(scheduled[3])).To(BeFalse())
+	g.Expect(jobExists(scheduled[4])).To(BeTrue())
+	for _, backup := range standalone {
+		g.Expect(jobExists(backup)).To(BeTrue())
+	}
+	// the TTL of the job of the most recent failed backup is removed to keep it for debugging
+	job, err := deps.KubeClientset.BatchV1().Jobs(corev1.NamespaceDefault).Get(context.TODO(), scheduled[1].GetBackupJobName(), metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(job.Spec.TTLSecondsAfterFinished).To(BeNil())
+	job, err = deps.KubeClientset.BatchV1().Jobs(corev1.NamespaceDefault).Get(context.TODO(), scheduled[0].GetBackupJobName(), metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(job.Spec.TTLSecondsAfterFinished).To(Equal(ttl))
+
+	// the standalone backups are pruned by the limit of the most recent one setting it
+	standalone[2].Spec.JobHistoryLimit = &v1alpha1.JobHistoryLimit{Successful: pointer.Int32Ptr(0)}
+	standalone[1].Spec.JobHistoryLimit = &v1alpha1.JobHistoryLimit{Successful: pointer.Int32Ptr(2)}
+	g.Expect(bm.syncJobHistory(standalone[0])).To(Succeed())
+	g.Expect(jobExists(standalone[0])).To(BeTrue())
+	g.Expect(jobExists(standalone[1])).To(BeTrue())
+	g.Expect(jobExists(standalone[2])).To(BeFalse())
+}
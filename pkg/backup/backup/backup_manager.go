@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/backup"
 	"github.com/pingcap/tidb-operator/pkg/backup/constants"
+	"github.com/pingcap/tidb-operator/pkg/backup/notifier"
 	backuputil "github.com/pingcap/tidb-operator/pkg/backup/util"
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	"github.com/pingcap/tidb-operator/pkg/util"
@@ -36,6 +37,7 @@ type backupManager struct {
 	deps          *controller.Dependencies
 	backupCleaner BackupCleaner
 	statusUpdater controller.BackupConditionUpdaterInterface
+	notifier      *notifier.Notifier
 }
 
 // NewBackupManager return backupManager
@@ -45,6 +47,7 @@ func NewBackupManager(deps *controller.Dependencies) backup.BackupManager {
 		deps:          deps,
 		backupCleaner: NewBackupCleaner(deps, statusUpdater),
 		statusUpdater: statusUpdater,
+		notifier:      notifier.New(),
 	}
 }
 
@@ -61,7 +64,18 @@ func (bm *backupManager) Sync(backup *v1alpha1.Backup) error {
 		return nil
 	}
 
-	return bm.syncBackupJob(backup)
+	// the job of a finished backup is never created again after it is pruned
+	if isBackupFinished(backup) {
+		if err := bm.syncJobHistory(backup); err != nil {
+			return err
+		}
+		return bm.syncNotification(backup)
+	}
+
+	if err := bm.syncBackupJob(backup); err != nil {
+		return err
+	}
+	return bm.syncJobHistory(backup)
 }
 
 func (bm *backupManager) UpdateCondition(backup *v1alpha1.Backup, condition *v1alpha1.BackupCondition) error {
@@ -297,6 +311,7 @@ func (bm *backupManager) makeExportJob(backup *v1alpha1.Backup) (*batchv1.Job, s
 			Tolerations:      backup.Spec.Tolerations,
 			ImagePullSecrets: backup.Spec.ImagePullSecrets,
 			Affinity:         backup.Spec.Affinity,
+			NodeSelector:     backup.Spec.NodeSelector,
 			Volumes: append([]corev1.Volume{
 				{
 					Name: label.BackupJobLabelVal,
@@ -310,6 +325,7 @@ func (bm *backupManager) makeExportJob(backup *v1alpha1.Backup) (*batchv1.Job, s
 			PriorityClassName: backup.Spec.PriorityClassName,
 		},
 	}
+	controller.SetJobPodDefaults(bm.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -322,8 +338,9 @@ func (bm *backupManager) makeExportJob(backup *v1alpha1.Backup) (*batchv1.Job, s
 			},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: pointer.Int32Ptr(0),
-			Template:     *podSpec,
+			BackoffLimit:            pointer.Int32Ptr(0),
+			TTLSecondsAfterFinished: backup.Spec.TTLSecondsAfterFinished,
+			Template:                *podSpec,
 		},
 	}
 
@@ -498,10 +515,12 @@ func (bm *backupManager) makeBackupJob(backup *v1alpha1.Backup) (*batchv1.Job, s
 			Tolerations:       backup.Spec.Tolerations,
 			ImagePullSecrets:  backup.Spec.ImagePullSecrets,
 			Affinity:          backup.Spec.Affinity,
+			NodeSelector:      backup.Spec.NodeSelector,
 			Volumes:           volumes,
 			PriorityClassName: backup.Spec.PriorityClassName,
 		},
 	}
+	controller.SetJobPodDefaults(bm.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -514,8 +533,9 @@ func (bm *backupManager) makeBackupJob(backup *v1alpha1.Backup) (*batchv1.Job, s
 			},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: pointer.Int32Ptr(0),
-			Template:     *podSpec,
+			BackoffLimit:            pointer.Int32Ptr(0),
+			TTLSecondsAfterFinished: backup.Spec.TTLSecondsAfterFinished,
+			Template:                *podSpec,
 		},
 	}
 
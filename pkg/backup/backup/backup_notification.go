@@ -0,0 +1,165 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/backup/notifier"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// maxNotificationAttempts is the number of the requests sent for an event, after which its notification is given up
+const maxNotificationAttempts = 10
+
+// PendingNotification returns the event of the finished backup to be notified by spec.notification, it is not
+// pending once the webhook accepts it or the attempts are exhausted
+func PendingNotification(backup *v1alpha1.Backup) (v1alpha1.NotificationEventType, bool) {
+	notification := backup.Spec.Notification
+	if notification == nil {
+		return "", false
+	}
+	var event v1alpha1.NotificationEventType
+	switch backup.Status.Phase {
+	case v1alpha1.BackupComplete:
+		event = v1alpha1.NotificationEventComplete
+	case v1alpha1.BackupFailed:
+		event = v1alpha1.NotificationEventFailed
+	default:
+		return "", false
+	}
+	if len(notification.EventTypes) > 0 {
+		selected := false
+		for _, t := range notification.EventTypes {
+			selected = selected || t == event
+		}
+		if !selected {
+			return "", false
+		}
+	}
+	if status := backup.Status.Notification; status != nil && status.EventType == event &&
+		(status.Succeeded || status.Attempts >= maxNotificationAttempts) {
+		return "", false
+	}
+	return event, true
+}
+
+// syncNotification notifies the webhook of the event of the finished backup and records the result in
+// status.notification, the failed notification is retried by the requeue of the backup
+func (bm *backupManager) syncNotification(backup *v1alpha1.Backup) error {
+	ns := backup.GetNamespace()
+	name := backup.GetName()
+	event, ok := PendingNotification(backup)
+	if !ok {
+		return nil
+	}
+	webhook, err := bm.notificationWebhook(backup)
+	if err != nil {
+		return err
+	}
+
+	attempts, notifyErr := bm.notifier.Notify(webhook, newNotificationPayload(backup, event))
+	status := backup.Status.Notification.DeepCopy()
+	if status == nil || status.EventType != event {
+		status = &v1alpha1.NotificationStatus{EventType: event}
+	}
+	status.Attempts += int32(attempts)
+	if attempts > 0 {
+		status.LastAttemptTime = metav1.Now()
+	}
+	status.Succeeded = notifyErr == nil
+	status.Message = ""
+	if notifyErr != nil {
+		status.Message = notifyErr.Error()
+	}
+	if err := bm.statusUpdater.Update(backup, nil, &controller.BackupUpdateStatus{Notification: status}); err != nil {
+		return err
+	}
+
+	if notifyErr == nil {
+		klog.Infof("backup %s/%s: the %s event is notified", ns, name, event)
+		return nil
+	}
+	if status.Attempts >= maxNotificationAttempts {
+		msg := fmt.Sprintf("give up notifying the %s event after %d attempts: %v", event, status.Attempts, notifyErr)
+		klog.Warningf("backup %s/%s: %s", ns, name, msg)
+		bm.deps.Recorder.Event(backup, corev1.EventTypeWarning, events.BackupNotificationFailed, msg)
+		return nil
+	}
+	return controller.RequeueErrorf("backup %s/%s: failed to notify the %s event: %v", ns, name, event, notifyErr)
+}
+
+// notificationWebhook returns the webhook of spec.notification, the token is read from the secret
+func (bm *backupManager) notificationWebhook(backup *v1alpha1.Backup) (*notifier.Webhook, error) {
+	notification := backup.Spec.Notification
+	webhook := &notifier.Webhook{
+		URL:         notification.URL,
+		CloudEvents: notification.CloudEvents,
+	}
+	if ref := notification.AuthSecretRef; ref != nil {
+		ns := backup.GetNamespace()
+		secret, err := bm.deps.SecretLister.Secrets(ns).Get(ref.Name)
+		if err != nil {
+			return nil, fmt.Errorf("backup %s/%s: failed to get the secret %s of the notification: %v", ns, backup.GetName(), ref.Name, err)
+		}
+		token, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("backup %s/%s: key %s is not found in the secret %s of the notification", ns, backup.GetName(), ref.Key, ref.Name)
+		}
+		webhook.Token = string(token)
+	}
+	return webhook, nil
+}
+
+// newNotificationPayload returns the payload of the event of the backup
+func newNotificationPayload(backup *v1alpha1.Backup, event v1alpha1.NotificationEventType) *notifier.Payload {
+	payload := &notifier.Payload{
+		Kind:      controller.BackupControllerKind.Kind,
+		Namespace: backup.GetNamespace(),
+		Name:      backup.GetName(),
+		UID:       string(backup.GetUID()),
+		Status:    string(event),
+		Size:      backup.Status.BackupSize,
+		CommitTs:  backup.Status.CommitTs,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if br := backup.Spec.BR; br != nil {
+		clusterNamespace := br.ClusterNamespace
+		if clusterNamespace == "" {
+			clusterNamespace = backup.GetNamespace()
+		}
+		payload.Cluster = fmt.Sprintf("%s/%s", clusterNamespace, br.Cluster)
+	}
+	started, completed := backup.Status.TimeStarted, backup.Status.TimeCompleted
+	if !started.IsZero() && !completed.IsZero() && completed.After(started.Time) {
+		payload.Duration = completed.Sub(started.Time).Round(time.Second).String()
+	}
+	_, cond := v1alpha1.GetBackupCondition(&backup.Status, backup.Status.Phase)
+	if cond != nil && !cond.LastTransitionTime.IsZero() {
+		payload.Time = cond.LastTransitionTime.UTC().Format(time.RFC3339)
+	}
+	if event == v1alpha1.NotificationEventFailed {
+		payload.Error = backup.Status.FailureSummary
+		if payload.Error == "" && cond != nil {
+			payload.Error = cond.Message
+		}
+	}
+	return payload
+}
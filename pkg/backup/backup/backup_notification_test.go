@@ -0,0 +1,169 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func newNotificationBackup(phase v1alpha1.BackupConditionType, url string) *v1alpha1.Backup {
+	backup := newHistoryBackup("backup", time.Hour, phase)
+	backup.Spec.BR = &v1alpha1.BRConfig{Cluster: "basic"}
+	backup.Spec.Notification = &v1alpha1.Notification{
+		URL: url,
+		AuthSecretRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "webhook"},
+			Key:                  "token",
+		},
+	}
+	backup.Status.BackupSize = 1024
+	backup.Status.CommitTs = "434343"
+	backup.Status.TimeStarted = metav1.NewTime(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	backup.Status.TimeCompleted = metav1.NewTime(time.Date(2022, 6, 1, 0, 1, 30, 0, time.UTC))
+	backup.Status.Conditions[0].LastTransitionTime = backup.Status.TimeCompleted
+	return backup
+}
+
+func TestPendingNotification(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	backup := newNotificationBackup(v1alpha1.BackupRunning, "https://example.com")
+	_, ok := PendingNotification(backup)
+	g.Expect(ok).To(BeFalse())
+
+	backup = newNotificationBackup(v1alpha1.BackupComplete, "https://example.com")
+	event, ok := PendingNotification(backup)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(event).To(Equal(v1alpha1.NotificationEventComplete))
+
+	// only the selected events are notified
+	backup.Spec.Notification.EventTypes = []v1alpha1.NotificationEventType{v1alpha1.NotificationEventFailed}
+	_, ok = PendingNotification(backup)
+	g.Expect(ok).To(BeFalse())
+	backup = newNotificationBackup(v1alpha1.BackupFailed, "https://example.com")
+	backup.Spec.Notification.EventTypes = []v1alpha1.NotificationEventType{v1alpha1.NotificationEventFailed}
+	event, ok = PendingNotification(backup)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(event).To(Equal(v1alpha1.NotificationEventFailed))
+
+	// the event is not pending once it is notified or given up
+	backup.Status.Notification = &v1alpha1.NotificationStatus{EventType: v1alpha1.NotificationEventFailed, Attempts: 3}
+	_, ok = PendingNotification(backup)
+	g.Expect(ok).To(BeTrue())
+	backup.Status.Notification.Attempts = maxNotificationAttempts
+	_, ok = PendingNotification(backup)
+	g.Expect(ok).To(BeFalse())
+	backup.Status.Notification = &v1alpha1.NotificationStatus{EventType: v1alpha1.NotificationEventFailed, Attempts: 1, Succeeded: true}
+	_, ok = PendingNotification(backup)
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestSyncNotification(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var mu sync.Mutex
+	status := http.StatusBadRequest
+	var bodies [][]byte
+	var authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, body)
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	setStatus := func(s int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = s
+	}
+
+	deps := controller.NewSimpleClientDependencies()
+	bm := NewBackupManager(deps).(*backupManager)
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: corev1.NamespaceDefault},
+		Data:       map[string][]byte{"token": []byte("secret-token")},
+	}
+	g.Expect(deps.KubeInformerFactory.Core().V1().Secrets().Informer().GetIndexer().Add(secret)).To(Succeed())
+	backup := newNotificationBackup(v1alpha1.BackupFailed, server.URL)
+	backup.Status.FailureSummary = "BR exits with 1"
+	_, err := deps.Clientset.PingcapV1alpha1().Backups(backup.Namespace).Create(context.TODO(), backup, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// the rejected notification is recorded and requeued
+	err = bm.syncNotification(backup)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(backup.Status.Notification).NotTo(BeNil())
+	g.Expect(backup.Status.Notification.EventType).To(Equal(v1alpha1.NotificationEventFailed))
+	g.Expect(backup.Status.Notification.Succeeded).To(BeFalse())
+	g.Expect(backup.Status.Notification.Attempts).To(Equal(int32(1)))
+	g.Expect(backup.Status.Notification.Message).To(ContainSubstring("unexpected status 400"))
+
+	// the payload carries the result of the backup but not the token
+	g.Expect(authorizations).To(Equal([]string{"Bearer secret-token"}))
+	g.Expect(string(bodies[0])).NotTo(ContainSubstring("secret-token"))
+	var payload map[string]interface{}
+	g.Expect(json.Unmarshal(bodies[0], &payload)).To(Succeed())
+	g.Expect(payload).To(Equal(map[string]interface{}{
+		"kind":      "Backup",
+		"namespace": corev1.NamespaceDefault,
+		"name":      "backup",
+		"uid":       "backup",
+		"cluster":   "default/basic",
+		"status":    "Failed",
+		"size":      float64(1024),
+		"commitTs":  "434343",
+		"duration":  "1m30s",
+		"error":     "BR exits with 1",
+		"time":      "2022-06-01T00:01:30Z",
+	}))
+
+	// the accepted notification is not sent again
+	setStatus(http.StatusOK)
+	g.Expect(bm.syncNotification(backup)).To(Succeed())
+	g.Expect(backup.Status.Notification.Succeeded).To(BeTrue())
+	g.Expect(backup.Status.Notification.Attempts).To(Equal(int32(2)))
+	g.Expect(backup.Status.Notification.Message).To(BeEmpty())
+	g.Expect(bm.syncNotification(backup)).To(Succeed())
+	g.Expect(bodies).To(HaveLen(2))
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// the notification is given up with an event once the attempts are exhausted
+	setStatus(http.StatusBadRequest)
+	backup.Status.Notification = &v1alpha1.NotificationStatus{EventType: v1alpha1.NotificationEventFailed, Attempts: maxNotificationAttempts - 1}
+	g.Expect(bm.syncNotification(backup)).To(Succeed())
+	g.Expect(backup.Status.Notification.Attempts).To(Equal(int32(maxNotificationAttempts)))
+	g.Expect(backup.Status.Notification.Succeeded).To(BeFalse())
+	g.Expect(recorder.Events).To(HaveLen(1))
+	_, ok := PendingNotification(backup)
+	g.Expect(ok).To(BeFalse())
+}
@@ -180,6 +180,7 @@ func TestBackupManagerDumpling(t *testing.T) {
 	deps := helper.Deps
 	var err error
 
+	deps.CLIConfig.JobNodeSelector = map[string]string{"dedicated": "backup"}
 	bm := NewBackupManager(deps).(*backupManager)
 
 	// create backup
@@ -212,6 +213,8 @@ func TestBackupManagerDumpling(t *testing.T) {
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env1))
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env2Yes))
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(gomega.ContainElement(env2No))
+	// check the job defaults are set
+	g.Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"dedicated": "backup"}))
 }
 
 func TestBackupManagerBR(t *testing.T) {
@@ -221,6 +224,7 @@ func TestBackupManagerBR(t *testing.T) {
 	deps := helper.Deps
 	var err error
 
+	deps.CLIConfig.JobTolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
 	bm := NewBackupManager(deps).(*backupManager)
 
 	// test invalid Backup spec
@@ -268,6 +272,8 @@ func TestBackupManagerBR(t *testing.T) {
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env1))
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env2Yes))
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(gomega.ContainElement(env2No))
+		// check the job defaults are set
+		g.Expect(job.Spec.Template.Spec.Tolerations).To(Equal(deps.CLIConfig.JobTolerations))
 	}
 }
 
@@ -276,6 +282,7 @@ func TestClean(t *testing.T) {
 	helper := newHelper(t)
 	defer helper.Close()
 	deps := helper.Deps
+	deps.CLIConfig.JobNodeSelector = map[string]string{"dedicated": "backup"}
 
 	for _, backup := range genValidBRBackups() {
 		_, err := deps.Clientset.PingcapV1alpha1().Backups(backup.Namespace).Create(context.TODO(), backup, metav1.CreateOptions{})
@@ -301,8 +308,9 @@ func TestClean(t *testing.T) {
 		err = bc.Clean(backup)
 		g.Expect(err).Should(BeNil())
 		helper.hasCondition(backup.Namespace, backup.Name, v1alpha1.BackupClean, "")
-		_, err = deps.KubeClientset.BatchV1().Jobs(backup.Namespace).Get(context.TODO(), backup.GetCleanJobName(), metav1.GetOptions{})
+		cleanJob, err := deps.KubeClientset.BatchV1().Jobs(backup.Namespace).Get(context.TODO(), backup.GetCleanJobName(), metav1.GetOptions{})
 		g.Expect(err).Should(BeNil())
+		g.Expect(cleanJob.Spec.Template.Spec.NodeSelector).To(Equal(deps.CLIConfig.JobNodeSelector))
 
 		// test already have a clean job running
 		g.Eventually(func() error {
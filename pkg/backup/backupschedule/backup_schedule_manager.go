@@ -22,11 +22,14 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/backup"
 	"github.com/pingcap/tidb-operator/pkg/backup/constants"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	"github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -35,15 +38,17 @@ import (
 type nowFn func() time.Time
 
 type backupScheduleManager struct {
-	deps *controller.Dependencies
-	now  nowFn
+	deps       *controller.Dependencies
+	now        nowFn
+	checkDrill restoreDrillChecker
 }
 
 // NewBackupScheduleManager return a *backupScheduleManager
 func NewBackupScheduleManager(deps *controller.Dependencies) backup.BackupScheduleManager {
 	return &backupScheduleManager{
-		deps: deps,
-		now:  time.Now,
+		deps:       deps,
+		now:        time.Now,
+		checkDrill: runRestoreDrillChecks,
 	}
 }
 
@@ -54,10 +59,30 @@ func (bm *backupScheduleManager) Sync(bs *v1alpha1.BackupSchedule) error {
 		return controller.IgnoreErrorf("backupSchedule %s/%s has been paused", bs.GetNamespace(), bs.GetName())
 	}
 
+	if !bm.validate(bs) {
+		return controller.IgnoreErrorf("backupSchedule %s/%s is not valid", bs.GetNamespace(), bs.GetName())
+	}
+
+	// the restore drill is checked again on the periodic resync of the backup schedule,
+	// its failures do not block the backups
+	if err := bm.syncRestoreDrill(bs); err != nil {
+		klog.Errorf("backup schedule %s/%s, sync restore drill failed, err: %v", bs.GetNamespace(), bs.GetName(), err)
+	}
+
+	if err := updateNextBackupTime(bs, bm.now); err != nil {
+		return err
+	}
+
 	if err := bm.canPerformNextBackup(bs); err != nil {
 		return err
 	}
 
+	if bs.Spec.BaseBackupRef != nil {
+		if err := bm.syncIncrementalChain(bs); err != nil {
+			return err
+		}
+	}
+
 	scheduledTime, err := getLastScheduledTime(bs, bm.now)
 	if scheduledTime == nil {
 		return err
@@ -79,6 +104,17 @@ func (bm *backupScheduleManager) Sync(bs *v1alpha1.BackupSchedule) error {
 	return nil
 }
 
+func (bm *backupScheduleManager) validate(bs *v1alpha1.BackupSchedule) bool {
+	errs := v1alpha1validation.ValidateBackupSchedule(bs)
+	if len(errs) > 0 {
+		aggregatedErr := errs.ToAggregate()
+		klog.Errorf("backup schedule %s/%s is not valid and must be fixed first, aggregated error: %v", bs.GetNamespace(), bs.GetName(), aggregatedErr)
+		bm.deps.Recorder.Event(bs, corev1.EventTypeWarning, events.FailedValidation, aggregatedErr.Error())
+		return false
+	}
+	return true
+}
+
 func (bm *backupScheduleManager) deleteLastBackupJob(bs *v1alpha1.BackupSchedule) error {
 	ns := bs.GetNamespace()
 	bsName := bs.GetName()
@@ -130,9 +166,9 @@ func getLastScheduledTime(bs *v1alpha1.BackupSchedule, nowFn nowFn) (*time.Time,
 	ns := bs.GetNamespace()
 	bsName := bs.GetName()
 
-	sched, err := cron.ParseStandard(bs.Spec.Schedule)
+	sched, _, err := parseSchedule(bs)
 	if err != nil {
-		return nil, fmt.Errorf("parse backup schedule %s/%s cron format %s failed, err: %v", ns, bsName, bs.Spec.Schedule, err)
+		return nil, err
 	}
 
 	var earliestTime time.Time
@@ -191,7 +227,109 @@ func getLastScheduledTime(bs *v1alpha1.BackupSchedule, nowFn nowFn) (*time.Time,
 	return &scheduledTime, nil
 }
 
-func buildBackup(bs *v1alpha1.BackupSchedule, timestamp time.Time) *v1alpha1.Backup {
+// updateNextBackupTime records the next time the backup is scheduled after now in status.
+func updateNextBackupTime(bs *v1alpha1.BackupSchedule, nowFn nowFn) error {
+	sched, loc, err := parseSchedule(bs)
+	if err != nil {
+		return err
+	}
+	next := sched.Next(nowFn())
+	if next.IsZero() {
+		bs.Status.NextBackupTime = nil
+		bs.Status.NextBackupTimeInZone = ""
+		return nil
+	}
+	bs.Status.NextBackupTime = &metav1.Time{Time: next.UTC()}
+	bs.Status.NextBackupTimeInZone = next.In(loc).Format(time.RFC3339)
+	return nil
+}
+
+// parseSchedule parses the cron string of the backup schedule, the returned schedule is evaluated
+// in `spec.timeZone` if it is set, otherwise in the local time zone.
+func parseSchedule(bs *v1alpha1.BackupSchedule) (cron.Schedule, *time.Location, error) {
+	return parseCronInZone(bs, bs.Spec.Schedule)
+}
+
+// parseCronInZone parses the cron string in the time zone of the backup schedule.
+func parseCronInZone(bs *v1alpha1.BackupSchedule, cronSpec string) (cron.Schedule, *time.Location, error) {
+	ns := bs.GetNamespace()
+	bsName := bs.GetName()
+
+	sched, err := cron.ParseStandard(cronSpec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse backup schedule %s/%s cron format %s failed, err: %v", ns, bsName, cronSpec, err)
+	}
+	if bs.Spec.TimeZone == "" {
+		return sched, time.Local, nil
+	}
+	loc, err := time.LoadLocation(bs.Spec.TimeZone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load time zone %s of backup schedule %s/%s failed, err: %v", bs.Spec.TimeZone, ns, bsName, err)
+	}
+	return &zonedSchedule{sched: sched, loc: loc}, loc, nil
+}
+
+// zonedSchedule evaluates a cron schedule against the wall clock of a time zone.
+//
+// The wall clock itself has no daylight saving time transitions, which decides the behavior
+// on the transitions:
+//   - when the clock falls back, a wall time in the repeated hour is scheduled only once,
+//     at its first occurrence.
+//   - when the clock springs forward, a wall time in the skipped hour is scheduled once,
+//     shifted forward by the length of the gap (e.g. 02:30 runs at 03:30).
+type zonedSchedule struct {
+	sched cron.Schedule
+	loc   *time.Location
+}
+
+// Next returns the next scheduled time after t, or the zero time if there is none.
+func (s *zonedSchedule) Next(t time.Time) time.Time {
+	wall := toWallClock(t, s.loc)
+	for {
+		wall = s.sched.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		// the first occurrence of a repeated wall time may be earlier than t,
+		// which means it has been scheduled already.
+		if next := fromWallClock(wall, s.loc); next.After(t) {
+			return next
+		}
+	}
+}
+
+// toWallClock returns the wall clock time of t in loc, represented in UTC.
+func toWallClock(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// fromWallClock resolves the wall clock time, represented in UTC, to the time in loc.
+// An ambiguous wall time resolves to its first occurrence, and a nonexistent wall time
+// resolves with the offset before the transition.
+func fromWallClock(wall time.Time, loc *time.Location) time.Time {
+	offsetAt := func(w time.Time) time.Duration {
+		_, offset := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc).Zone()
+		return time.Duration(offset) * time.Second
+	}
+
+	// the offsets a day before and after cover both sides of a transition
+	var resolved time.Time
+	for _, offset := range []time.Duration{offsetAt(wall.AddDate(0, 0, -1)), offsetAt(wall.AddDate(0, 0, 1))} {
+		t := wall.Add(-offset).In(loc)
+		if toWallClock(t, loc).Equal(wall) && (resolved.IsZero() || t.Before(resolved)) {
+			resolved = t
+		}
+	}
+	if resolved.IsZero() {
+		resolved = wall.Add(-offsetAt(wall.AddDate(0, 0, -1))).In(loc)
+	}
+	return resolved
+}
+
+// BuildBackup builds the Backup from the backupTemplate of the BackupSchedule, its name and storage
+// prefix are based on the timestamp.
+func BuildBackup(bs *v1alpha1.BackupSchedule, timestamp time.Time) *v1alpha1.Backup {
 	ns := bs.GetNamespace()
 	bsName := bs.GetName()
 
@@ -229,6 +367,20 @@ func buildBackup(bs *v1alpha1.BackupSchedule, timestamp time.Time) *v1alpha1.Bac
 	if bs.Spec.ImagePullSecrets != nil {
 		backupSpec.ImagePullSecrets = bs.Spec.ImagePullSecrets
 	}
+	if backupSpec.Notification == nil {
+		backupSpec.Notification = bs.Spec.Notification.DeepCopy()
+	}
+
+	annotations := bs.Annotations
+	if chain := bs.Status.IncrementalChain; bs.Spec.BaseBackupRef != nil && chain != nil && backupSpec.BR != nil {
+		// the backup is incremental on the head of the chain, or on the base if no incremental backup completes
+		lastBackupTS := chain.BaseCommitTs
+		if chain.Head != "" {
+			lastBackupTS = chain.HeadCommitTs
+		}
+		backupSpec.BR.Options = append(backupSpec.BR.Options, fmt.Sprintf("--lastbackupts=%s", lastBackupTS))
+		annotations = util.CombineStringMap(map[string]string{label.AnnIncrementalBase: chain.Base}, bs.Annotations)
+	}
 
 	bsLabel := util.CombineStringMap(label.NewBackupSchedule().Instance(bsName).BackupSchedule(bsName), bs.Labels)
 	backup := &v1alpha1.Backup{
@@ -237,7 +389,7 @@ func buildBackup(bs *v1alpha1.BackupSchedule, timestamp time.Time) *v1alpha1.Bac
 			Namespace:   ns,
 			Name:        bs.GetBackupCRDName(timestamp),
 			Labels:      bsLabel,
-			Annotations: bs.Annotations,
+			Annotations: annotations,
 			OwnerReferences: []metav1.OwnerReference{
 				controller.GetBackupScheduleOwnerRef(bs),
 			},
@@ -248,7 +400,7 @@ func buildBackup(bs *v1alpha1.BackupSchedule, timestamp time.Time) *v1alpha1.Bac
 }
 
 func createBackup(bkController controller.BackupControlInterface, bs *v1alpha1.BackupSchedule, timestamp time.Time) (*v1alpha1.Backup, error) {
-	bk := buildBackup(bs, timestamp)
+	bk := BuildBackup(bs, timestamp)
 	return bkController.CreateBackup(bk)
 }
 
@@ -288,7 +440,7 @@ func (bm *backupScheduleManager) backupGCByMaxReservedTime(bs *v1alpha1.BackupSc
 
 	var deleteCount int
 	for _, backup := range backupsList {
-		if backup.CreationTimestamp.Add(reservedTime).After(bm.now()) {
+		if backup.CreationTimestamp.Add(reservedTime).After(bm.now()) || isIncrementalChainLink(bs, backup) {
 			continue
 		}
 		// delete the expired backup
@@ -320,7 +472,7 @@ func (bm *backupScheduleManager) backupGCByMaxBackups(bs *v1alpha1.BackupSchedul
 
 	var deleteCount int
 	for i, backup := range backupsList {
-		if i < int(*bs.Spec.MaxBackups) {
+		if i < int(*bs.Spec.MaxBackups) || isIncrementalChainLink(bs, backup) {
 			continue
 		}
 		// delete the backup
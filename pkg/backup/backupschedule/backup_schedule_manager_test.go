@@ -48,6 +48,14 @@ func TestManager(t *testing.T) {
 	g.Expect(err).Should(BeAssignableToTypeOf(&controller.IgnoreError{}))
 	g.Expect(err.Error()).Should(MatchRegexp(".*has been paused.*"))
 
+	// test invalid time zone
+	bs.Spec.Pause = false
+	bs.Spec.TimeZone = "Asia/Nowhere"
+	err = m.Sync(bs)
+	g.Expect(err).Should(BeAssignableToTypeOf(&controller.IgnoreError{}))
+	g.Expect(err.Error()).Should(MatchRegexp(".*is not valid.*"))
+	bs.Spec.TimeZone = ""
+
 	// test canPerformNextBackup
 	//
 	// test not found last backup
@@ -168,6 +176,121 @@ func TestGetLastScheduledTime(t *testing.T) {
 	g.Expect(getTime).Should(BeNil())
 }
 
+func TestGetLastScheduledTimeInZone(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	g.Expect(err).Should(BeNil())
+
+	type testcase struct {
+		name       string
+		schedule   string
+		lastBackup time.Time
+		now        time.Time
+		expect     []time.Time
+	}
+	tests := []testcase{
+		{
+			name:       "spring forward runs the skipped time once",
+			schedule:   "30 2 * * *",
+			lastBackup: time.Date(2023, 3, 11, 2, 30, 0, 0, loc),
+			now:        time.Date(2023, 3, 13, 3, 0, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2023, 3, 12, 3, 30, 0, 0, loc),
+				time.Date(2023, 3, 13, 2, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:       "fall back skips the repeated time",
+			schedule:   "30 1 * * *",
+			lastBackup: time.Date(2023, 11, 4, 1, 30, 0, 0, loc),
+			now:        time.Date(2023, 11, 6, 2, 0, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2023, 11, 5, 8, 30, 0, 0, time.UTC),
+				time.Date(2023, 11, 6, 1, 30, 0, 0, loc),
+			},
+		},
+		{
+			name:       "hourly schedule across fall back",
+			schedule:   "0 * * * *",
+			lastBackup: time.Date(2023, 11, 5, 0, 0, 0, 0, loc),
+			now:        time.Date(2023, 11, 5, 3, 0, 0, 0, loc),
+			expect: []time.Time{
+				time.Date(2023, 11, 5, 8, 0, 0, 0, time.UTC),
+				time.Date(2023, 11, 5, 10, 0, 0, 0, time.UTC),
+				time.Date(2023, 11, 5, 11, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Log(test.name)
+
+		bs := &v1alpha1.BackupSchedule{
+			Spec: v1alpha1.BackupScheduleSpec{
+				Schedule: test.schedule,
+				TimeZone: loc.String(),
+			},
+			Status: v1alpha1.BackupScheduleStatus{
+				LastBackupTime: &metav1.Time{Time: test.lastBackup},
+			},
+		}
+
+		// step the fake clock minute by minute and record every scheduled backup
+		var scheduled []time.Time
+		for now := test.lastBackup; !now.After(test.now); now = now.Add(time.Minute) {
+			current := now
+			getTime, err := getLastScheduledTime(bs, func() time.Time { return current })
+			g.Expect(err).Should(BeNil())
+			if getTime != nil {
+				scheduled = append(scheduled, *getTime)
+				bs.Status.LastBackupTime.Time = *getTime
+			}
+		}
+		g.Expect(scheduled).Should(HaveLen(len(test.expect)))
+		for i := range test.expect {
+			g.Expect(scheduled[i].Equal(test.expect[i])).Should(BeTrue(), "expect %s, got %s", test.expect[i], scheduled[i])
+		}
+	}
+
+	// test invalid time zone
+	bs := &v1alpha1.BackupSchedule{
+		Spec: v1alpha1.BackupScheduleSpec{
+			Schedule: "0 0 * * *",
+			TimeZone: "Invalid/Zone",
+		},
+	}
+	_, err = getLastScheduledTime(bs, time.Now)
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestUpdateNextBackupTime(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	g.Expect(err).Should(BeNil())
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	nowFn := func() time.Time { return now }
+
+	bs := &v1alpha1.BackupSchedule{
+		Spec: v1alpha1.BackupScheduleSpec{
+			Schedule: "0 0 * * *",
+			TimeZone: loc.String(),
+		},
+	}
+	g.Expect(updateNextBackupTime(bs, nowFn)).Should(Succeed())
+	g.Expect(bs.Status.NextBackupTime.Time).Should(Equal(time.Date(2023, 1, 1, 16, 0, 0, 0, time.UTC)))
+	g.Expect(bs.Status.NextBackupTimeInZone).Should(Equal("2023-01-02T00:00:00+08:00"))
+
+	// without time zone, the schedule is evaluated in the local time zone
+	bs.Spec.TimeZone = ""
+	g.Expect(updateNextBackupTime(bs, nowFn)).Should(Succeed())
+	local := now.In(time.Local)
+	expect := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, time.Local)
+	g.Expect(bs.Status.NextBackupTime.Time.Equal(expect)).Should(BeTrue())
+	g.Expect(bs.Status.NextBackupTimeInZone).Should(Equal(expect.Format(time.RFC3339)))
+}
+
 func TestBuildBackup(t *testing.T) {
 	now := time.Now()
 	var get *v1alpha1.Backup
@@ -198,14 +321,14 @@ func TestBuildBackup(t *testing.T) {
 	}
 
 	// test BR == nil
-	get = buildBackup(bs, now)
+	get = BuildBackup(bs, now)
 	if diff := cmp.Diff(bk, get); diff != "" {
 		t.Errorf("unexpected (-want, +got): %s", diff)
 	}
 	// should keep StorageSize from BackupSchedule
 	bs.Spec.StorageSize = "9527G"
 	bk.Spec.StorageSize = bs.Spec.StorageSize
-	get = buildBackup(bs, now)
+	get = BuildBackup(bs, now)
 	if diff := cmp.Diff(bk, get); diff != "" {
 		t.Errorf("unexpected (-want, +got): %s", diff)
 	}
@@ -214,7 +337,21 @@ func TestBuildBackup(t *testing.T) {
 	bs.Spec.BackupTemplate.BR = &v1alpha1.BRConfig{}
 	bk.Spec.BR = bs.Spec.BackupTemplate.BR.DeepCopy()
 	bk.Spec.StorageSize = "" // no use for BR
-	get = buildBackup(bs, now)
+	get = BuildBackup(bs, now)
+	if diff := cmp.Diff(bk, get); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+
+	// should use the notification of BackupSchedule unless the template has one
+	bs.Spec.Notification = &v1alpha1.Notification{URL: "https://example.com/schedule"}
+	bk.Spec.Notification = bs.Spec.Notification.DeepCopy()
+	get = BuildBackup(bs, now)
+	if diff := cmp.Diff(bk, get); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+	bs.Spec.BackupTemplate.Notification = &v1alpha1.Notification{URL: "https://example.com/template"}
+	bk.Spec.Notification = bs.Spec.BackupTemplate.Notification.DeepCopy()
+	get = BuildBackup(bs, now)
 	if diff := cmp.Diff(bk, get); diff != "" {
 		t.Errorf("unexpected (-want, +got): %s", diff)
 	}
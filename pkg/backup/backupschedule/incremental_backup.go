@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupschedule
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// syncIncrementalChain resolves the base backup of the schedule and advances the head of the incremental chain
+// to the last backup once it completes, the next backup is incremental on the head. The schedule is marked
+// Degraded and no backup is created while the base or the head is missing, instead of creating the incremental
+// backups which can not be restored.
+func (bm *backupScheduleManager) syncIncrementalChain(bs *v1alpha1.BackupSchedule) error {
+	ns := bs.GetNamespace()
+	bsName := bs.GetName()
+
+	base, baseCommitTs, err := bm.resolveBaseBackup(bs)
+	if err != nil {
+		return err
+	}
+	chain := bs.Status.IncrementalChain
+	if chain == nil || chain.Base != base {
+		// a new base starts a new chain
+		chain = &v1alpha1.IncrementalChainStatus{Base: base}
+		bs.Status.IncrementalChain = chain
+	}
+	chain.BaseCommitTs = baseCommitTs
+
+	if bs.Status.LastBackup != "" && bs.Status.LastBackup != chain.Head {
+		last, err := bm.deps.BackupLister.Backups(ns).Get(bs.Status.LastBackup)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("backup schedule %s/%s, get backup %s failed, err: %v", ns, bsName, bs.Status.LastBackup, err)
+		}
+		// the failed backups are not linked, the next backup is incremental on the head again
+		if err == nil && v1alpha1.IsBackupComplete(last) && last.Status.CommitTs != "" && last.Annotations[label.AnnIncrementalBase] == base {
+			chain.Head = last.GetName()
+			chain.HeadCommitTs = last.Status.CommitTs
+			chain.Length++
+		}
+	}
+
+	if chain.Head != "" {
+		_, err := bm.deps.BackupLister.Backups(ns).Get(chain.Head)
+		if errors.IsNotFound(err) {
+			return bm.markIncrementalChainBroken(bs, fmt.Sprintf("the head %s of the incremental chain on %s is missing, set a new baseBackupRef to start a new chain", chain.Head, base))
+		}
+		if err != nil {
+			return fmt.Errorf("backup schedule %s/%s, get backup %s failed, err: %v", ns, bsName, chain.Head, err)
+		}
+	}
+	meta.SetStatusCondition(&bs.Status.Conditions, metav1.Condition{
+		Type:    v1alpha1.BackupScheduleDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  "IncrementalChainLinked",
+		Message: fmt.Sprintf("%d incremental backups on %s", chain.Length, base),
+	})
+	return nil
+}
+
+// resolveBaseBackup returns the name or the path of the base backup and its commit ts
+func (bm *backupScheduleManager) resolveBaseBackup(bs *v1alpha1.BackupSchedule) (string, string, error) {
+	ns := bs.GetNamespace()
+	bsName := bs.GetName()
+
+	ref := bs.Spec.BaseBackupRef
+	if ref.Path != "" {
+		return ref.Path, ref.CommitTs, nil
+	}
+	base, err := bm.deps.BackupLister.Backups(ns).Get(ref.Name)
+	if errors.IsNotFound(err) {
+		return "", "", bm.markIncrementalChainBroken(bs, fmt.Sprintf("the base backup %s is not found", ref.Name))
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("backup schedule %s/%s, get base backup %s failed, err: %v", ns, bsName, ref.Name, err)
+	}
+	if v1alpha1.IsBackupFailed(base) {
+		return "", "", bm.markIncrementalChainBroken(bs, fmt.Sprintf("the base backup %s is failed", ref.Name))
+	}
+	if !v1alpha1.IsBackupComplete(base) || base.Status.CommitTs == "" {
+		return "", "", controller.RequeueErrorf("backup schedule %s/%s, the base backup %s is not complete", ns, bsName, ref.Name)
+	}
+	return base.GetName(), base.Status.CommitTs, nil
+}
+
+// markIncrementalChainBroken marks the schedule Degraded, the event is only emitted when the chain breaks
+func (bm *backupScheduleManager) markIncrementalChainBroken(bs *v1alpha1.BackupSchedule, msg string) error {
+	if !meta.IsStatusConditionTrue(bs.Status.Conditions, v1alpha1.BackupScheduleDegraded) {
+		bm.deps.Recorder.Event(bs, corev1.EventTypeWarning, events.IncrementalChainBroken, msg)
+	}
+	meta.SetStatusCondition(&bs.Status.Conditions, metav1.Condition{
+		Type:    v1alpha1.BackupScheduleDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  events.IncrementalChainBroken,
+		Message: msg,
+	})
+	return controller.IgnoreErrorf("backup schedule %s/%s is degraded, %s", bs.GetNamespace(), bs.GetName(), msg)
+}
+
+// isIncrementalChainLink returns whether the backup is the base or the head of the incremental chain, they are
+// kept by the backup GC of the schedule, the base is not owned by the schedule and the head is the backup the
+// next incremental backup is taken on.
+func isIncrementalChainLink(bs *v1alpha1.BackupSchedule, backup *v1alpha1.Backup) bool {
+	if bs.Spec.BaseBackupRef == nil {
+		return false
+	}
+	if bs.Spec.BaseBackupRef.Name == backup.GetName() {
+		return true
+	}
+	chain := bs.Status.IncrementalChain
+	return chain != nil && chain.Head == backup.GetName()
+}
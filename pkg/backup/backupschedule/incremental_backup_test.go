@@ -0,0 +1,153 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupschedule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestIncrementalBackup(t *testing.T) {
+	g := NewGomegaWithT(t)
+	helper := newHelper(t)
+	defer helper.close()
+	deps := helper.deps
+	m := NewBackupScheduleManager(deps).(*backupScheduleManager)
+
+	now := time.Date(2022, 6, 8, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	bs := &v1alpha1.BackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              "bsname",
+			CreationTimestamp: metav1.Time{Time: now.AddDate(0, 0, -1)},
+		},
+		Spec: v1alpha1.BackupScheduleSpec{
+			Schedule:       "0 0 * * *",
+			TimeZone:       "UTC",
+			BackupTemplate: v1alpha1.BackupSpec{BR: &v1alpha1.BRConfig{Cluster: "basic"}},
+			BaseBackupRef:  &v1alpha1.BaseBackupRef{Name: "weekly-full"},
+		},
+	}
+	recorded := deps.Recorder.(*record.FakeRecorder).Events
+	brokenEvents := func() int {
+		n := 0
+		for len(recorded) > 0 {
+			if e := <-recorded; strings.Contains(e, events.IncrementalChainBroken) {
+				n++
+			}
+		}
+		return n
+	}
+	complete := func(name, commitTs string) {
+		var bk *v1alpha1.Backup
+		g.Eventually(func() (err error) {
+			bk, err = deps.BackupLister.Backups("ns").Get(name)
+			return err
+		}, time.Second*10).Should(Succeed())
+		bk = bk.DeepCopy()
+		v1alpha1.UpdateBackupCondition(&bk.Status, &v1alpha1.BackupCondition{Type: v1alpha1.BackupComplete, Status: corev1.ConditionTrue})
+		bk.Status.CommitTs = commitTs
+		helper.updateBackup(bk)
+	}
+
+	// the schedule is degraded without the base
+	err := m.Sync(bs)
+	g.Expect(err).To(BeAssignableToTypeOf(&controller.IgnoreError{}))
+	g.Expect(meta.IsStatusConditionTrue(bs.Status.Conditions, v1alpha1.BackupScheduleDegraded)).To(BeTrue())
+	g.Expect(brokenEvents()).To(Equal(1))
+	helper.checkBacklist("ns", 0)
+	// the event is not repeated
+	g.Expect(m.Sync(bs)).To(HaveOccurred())
+	g.Expect(brokenEvents()).To(Equal(0))
+
+	// the first incremental backup is on the base
+	helper.createBackup(&v1alpha1.Backup{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "weekly-full"}})
+	complete("weekly-full", "100")
+	g.Expect(m.Sync(bs)).To(Succeed())
+	g.Expect(meta.IsStatusConditionFalse(bs.Status.Conditions, v1alpha1.BackupScheduleDegraded)).To(BeTrue())
+	g.Expect(bs.Status.IncrementalChain).To(Equal(&v1alpha1.IncrementalChainStatus{Base: "weekly-full", BaseCommitTs: "100"}))
+	first, err := deps.Clientset.PingcapV1alpha1().Backups("ns").Get(context.TODO(), bs.Status.LastBackup, metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(first.Spec.BR.Options).To(Equal([]string{"--lastbackupts=100"}))
+	g.Expect(first.Annotations).To(HaveKeyWithValue(label.AnnIncrementalBase, "weekly-full"))
+	g.Expect(bs.Spec.BackupTemplate.BR.Options).To(BeEmpty())
+
+	// the next incremental backup is on the head of the chain
+	helper.checkBacklist("ns", 2)
+	complete(first.Name, "200")
+	now = now.AddDate(0, 0, 1)
+	bs.Spec.MaxBackups = pointer.Int32Ptr(1)
+	g.Expect(m.Sync(bs)).To(Succeed())
+	bs.Spec.MaxBackups = nil
+	g.Expect(bs.Status.IncrementalChain).To(Equal(&v1alpha1.IncrementalChainStatus{
+		Base:         "weekly-full",
+		BaseCommitTs: "100",
+		Head:         first.Name,
+		HeadCommitTs: "200",
+		Length:       1,
+	}))
+	second, err := deps.Clientset.PingcapV1alpha1().Backups("ns").Get(context.TODO(), bs.Status.LastBackup, metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(second.Spec.BR.Options).To(Equal([]string{"--lastbackupts=200"}))
+	// the base and the head are kept by the backup GC
+	for _, name := range []string{"weekly-full", first.Name} {
+		_, err := deps.Clientset.PingcapV1alpha1().Backups("ns").Get(context.TODO(), name, metav1.GetOptions{})
+		g.Expect(err).To(Succeed())
+	}
+	helper.checkBacklist("ns", 3)
+
+	// a failed backup is not linked
+	failed := second.DeepCopy()
+	v1alpha1.UpdateBackupCondition(&failed.Status, &v1alpha1.BackupCondition{Type: v1alpha1.BackupScheduled, Status: corev1.ConditionTrue})
+	v1alpha1.UpdateBackupCondition(&failed.Status, &v1alpha1.BackupCondition{Type: v1alpha1.BackupFailed, Status: corev1.ConditionTrue})
+	helper.updateBackup(failed)
+	now = now.AddDate(0, 0, 1)
+	g.Expect(m.Sync(bs)).To(Succeed())
+	g.Expect(bs.Status.IncrementalChain.Head).To(Equal(first.Name))
+	third, err := deps.Clientset.PingcapV1alpha1().Backups("ns").Get(context.TODO(), bs.Status.LastBackup, metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(third.Spec.BR.Options).To(Equal([]string{"--lastbackupts=200"}))
+
+	// the schedule is degraded if the head is missing
+	complete(third.Name, "")
+	helper.deleteBackup(first)
+	now = now.AddDate(0, 0, 1)
+	err = m.Sync(bs)
+	g.Expect(err).To(BeAssignableToTypeOf(&controller.IgnoreError{}))
+	g.Expect(meta.IsStatusConditionTrue(bs.Status.Conditions, v1alpha1.BackupScheduleDegraded)).To(BeTrue())
+	g.Expect(bs.Status.LastBackup).To(Equal(third.Name))
+
+	// a new base starts a new chain
+	bs.Spec.BaseBackupRef = &v1alpha1.BaseBackupRef{Path: "s3://backup/full-20220611", CommitTs: "300"}
+	g.Expect(m.Sync(bs)).To(Succeed())
+	g.Expect(meta.IsStatusConditionFalse(bs.Status.Conditions, v1alpha1.BackupScheduleDegraded)).To(BeTrue())
+	g.Expect(bs.Status.IncrementalChain).To(Equal(&v1alpha1.IncrementalChainStatus{Base: "s3://backup/full-20220611", BaseCommitTs: "300"}))
+	fourth, err := deps.Clientset.PingcapV1alpha1().Backups("ns").Get(context.TODO(), bs.Status.LastBackup, metav1.GetOptions{})
+	g.Expect(err).To(Succeed())
+	g.Expect(fourth.Spec.BR.Options).To(Equal([]string{"--lastbackupts=300"}))
+}
@@ -0,0 +1,409 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupschedule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultRestoreDrillTimeout = 6 * time.Hour
+	restoreDrillCheckTimeout   = 5 * time.Minute
+)
+
+// restoreDrillChecker runs the checks of the restore drill in the ephemeral TidbCluster
+type restoreDrillChecker func(tc *v1alpha1.TidbCluster, checks []v1alpha1.RestoreDrillCheck) error
+
+// syncRestoreDrill drives the restore drill of the BackupSchedule. A drill creates the ephemeral
+// TidbCluster, restores the most recent completed backup into it once it is ready, runs the checks
+// after the restore is complete and removes the TidbCluster and the Restore at last.
+// The next drill is not started until all the resources of the last one are removed.
+func (bm *backupScheduleManager) syncRestoreDrill(bs *v1alpha1.BackupSchedule) error {
+	if bs.Spec.RestoreDrill == nil {
+		return nil
+	}
+	if status := bs.Status.RestoreDrill; status != nil && status.Phase == v1alpha1.RestoreDrillRunning {
+		return bm.checkRestoreDrill(bs)
+	}
+
+	cleaned, err := bm.cleanRestoreDrill(bs)
+	if err != nil || !cleaned {
+		return err
+	}
+
+	scheduledTime, err := getRestoreDrillScheduledTime(bs, bm.now)
+	if err != nil || scheduledTime == nil {
+		return err
+	}
+	backup, err := bm.getLatestCompletedBackup(bs)
+	if err != nil {
+		return err
+	}
+	if backup == nil {
+		klog.Infof("backup schedule %s/%s has no completed backup, skip the restore drill", bs.GetNamespace(), bs.GetName())
+		return nil
+	}
+	return bm.startRestoreDrill(bs, backup, *scheduledTime)
+}
+
+// getRestoreDrillScheduledTime returns the newest time the drill is scheduled at since the last drill,
+// the missed drills are not run one by one. It returns nil if there is no such time.
+func getRestoreDrillScheduledTime(bs *v1alpha1.BackupSchedule, nowFn nowFn) (*time.Time, error) {
+	sched, _, err := parseCronInZone(bs, bs.Spec.RestoreDrill.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	earliestTime := bs.CreationTimestamp.Time
+	if status := bs.Status.RestoreDrill; status != nil && status.ScheduledTime != nil {
+		earliestTime = status.ScheduledTime.Time
+	}
+	now := nowFn()
+	var scheduledTime *time.Time
+	for i, t := 0, sched.Next(earliestTime); !t.IsZero() && !t.After(now); i, t = i+1, sched.Next(t) {
+		if i > 100 {
+			klog.Warningf("too many missed restore drills of backup schedule %s/%s, run the drill now", bs.GetNamespace(), bs.GetName())
+			now := now
+			return &now, nil
+		}
+		t := t
+		scheduledTime = &t
+	}
+	return scheduledTime, nil
+}
+
+func (bm *backupScheduleManager) getLatestCompletedBackup(bs *v1alpha1.BackupSchedule) (*v1alpha1.Backup, error) {
+	backups, err := bm.getBackupList(bs)
+	if err != nil {
+		return nil, err
+	}
+	var latest *v1alpha1.Backup
+	for _, backup := range backups {
+		if !v1alpha1.IsBackupComplete(backup) {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&backup.CreationTimestamp) {
+			latest = backup
+		}
+	}
+	return latest, nil
+}
+
+func (bm *backupScheduleManager) startRestoreDrill(bs *v1alpha1.BackupSchedule, backup *v1alpha1.Backup, scheduledTime time.Time) error {
+	tc := buildRestoreDrillCluster(bs)
+	if _, err := bm.deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("backup schedule %s/%s, create restore drill cluster %s/%s failed, err: %v", bs.GetNamespace(), bs.GetName(), tc.Namespace, tc.Name, err)
+	}
+
+	bs.Status.RestoreDrill = &v1alpha1.RestoreDrillStatus{
+		Phase:         v1alpha1.RestoreDrillRunning,
+		Backup:        backup.GetName(),
+		Cluster:       fmt.Sprintf("%s/%s", tc.Namespace, tc.Name),
+		ScheduledTime: &metav1.Time{Time: scheduledTime},
+		StartTime:     &metav1.Time{Time: bm.now()},
+	}
+	bm.deps.Recorder.Eventf(bs, corev1.EventTypeNormal, events.RestoreDrillStarted, "restore drill of backup %s into cluster %s/%s is started", backup.GetName(), tc.Namespace, tc.Name)
+	return nil
+}
+
+// checkRestoreDrill checks the progress of the running drill
+func (bm *backupScheduleManager) checkRestoreDrill(bs *v1alpha1.BackupSchedule) error {
+	status := bs.Status.RestoreDrill
+	ns, name := restoreDrillNamespace(bs), restoreDrillName(bs)
+
+	timeout := defaultRestoreDrillTimeout
+	if bs.Spec.RestoreDrill.Timeout != "" {
+		d, err := time.ParseDuration(bs.Spec.RestoreDrill.Timeout)
+		if err != nil {
+			return err
+		}
+		timeout = d
+	}
+	if status.StartTime != nil && bm.now().Sub(status.StartTime.Time) > timeout {
+		return bm.completeRestoreDrill(bs, fmt.Errorf("the drill is not completed in %s", timeout))
+	}
+
+	restore, err := bm.deps.RestoreLister.Restores(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return bm.restoreIntoDrillCluster(bs)
+	}
+	if err != nil {
+		return err
+	}
+
+	if v1alpha1.IsRestoreFailed(restore) {
+		_, condition := v1alpha1.GetRestoreCondition(&restore.Status, v1alpha1.RestoreFailed)
+		return bm.completeRestoreDrill(bs, fmt.Errorf("restore %s/%s failed: %s", ns, name, condition.Message))
+	}
+	if !v1alpha1.IsRestoreComplete(restore) {
+		klog.V(4).Infof("backup schedule %s/%s, waiting for restore drill %s/%s to complete", bs.GetNamespace(), bs.GetName(), ns, name)
+		return nil
+	}
+
+	tc, err := bm.deps.TiDBClusterLister.TidbClusters(ns).Get(name)
+	if err != nil {
+		return err
+	}
+	return bm.completeRestoreDrill(bs, bm.checkDrill(tc, bs.Spec.RestoreDrill.Checks))
+}
+
+// restoreIntoDrillCluster creates the Restore once the ephemeral TidbCluster is ready
+func (bm *backupScheduleManager) restoreIntoDrillCluster(bs *v1alpha1.BackupSchedule) error {
+	status := bs.Status.RestoreDrill
+	ns, name := restoreDrillNamespace(bs), restoreDrillName(bs)
+
+	tc, err := bm.deps.TiDBClusterLister.TidbClusters(ns).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return controller.RequeueErrorf("backup schedule %s/%s, restore drill cluster %s/%s is not found", bs.GetNamespace(), bs.GetName(), ns, name)
+		}
+		return err
+	}
+	if !tc.PDAllMembersReady() || !tc.TiKVAllStoresReady() || !tc.TiDBAllMembersReady() {
+		klog.V(4).Infof("backup schedule %s/%s, waiting for restore drill cluster %s/%s to be ready", bs.GetNamespace(), bs.GetName(), ns, name)
+		return nil
+	}
+
+	backup, err := bm.deps.BackupLister.Backups(bs.GetNamespace()).Get(status.Backup)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return bm.completeRestoreDrill(bs, fmt.Errorf("backup %s is deleted", status.Backup))
+		}
+		return err
+	}
+	restore := buildRestoreDrillRestore(bs, backup)
+	if _, err := bm.deps.Clientset.PingcapV1alpha1().Restores(ns).Create(context.TODO(), restore, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("backup schedule %s/%s, create restore drill %s/%s failed, err: %v", bs.GetNamespace(), bs.GetName(), ns, name, err)
+	}
+	klog.Infof("backup schedule %s/%s, restore backup %s into restore drill cluster %s/%s", bs.GetNamespace(), bs.GetName(), backup.GetName(), ns, name)
+	return nil
+}
+
+// completeRestoreDrill records the result of the drill and removes the resources of the drill
+func (bm *backupScheduleManager) completeRestoreDrill(bs *v1alpha1.BackupSchedule, drillErr error) error {
+	status := bs.Status.RestoreDrill
+	now := bm.now()
+	status.CompletionTime = &metav1.Time{Time: now}
+	if status.StartTime != nil {
+		status.Duration = now.Sub(status.StartTime.Time).Round(time.Second).String()
+	}
+
+	if drillErr != nil {
+		status.Phase = v1alpha1.RestoreDrillFailed
+		status.Message = drillErr.Error()
+		meta.SetStatusCondition(&bs.Status.Conditions, metav1.Condition{
+			Type:    v1alpha1.BackupScheduleRestoreDrillFailed,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DrillFailed",
+			Message: fmt.Sprintf("restore drill of backup %s failed: %v", status.Backup, drillErr),
+		})
+		bm.deps.Recorder.Eventf(bs, corev1.EventTypeWarning, events.RestoreDrillFailed, "restore drill of backup %s failed: %v", status.Backup, drillErr)
+	} else {
+		status.Phase = v1alpha1.RestoreDrillPassed
+		status.Message = ""
+		meta.SetStatusCondition(&bs.Status.Conditions, metav1.Condition{
+			Type:    v1alpha1.BackupScheduleRestoreDrillFailed,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DrillPassed",
+			Message: fmt.Sprintf("restore drill of backup %s passed in %s", status.Backup, status.Duration),
+		})
+		bm.deps.Recorder.Eventf(bs, corev1.EventTypeNormal, events.RestoreDrillPassed, "restore drill of backup %s passed in %s", status.Backup, status.Duration)
+	}
+
+	_, err := bm.cleanRestoreDrill(bs)
+	return err
+}
+
+// cleanRestoreDrill removes the ephemeral TidbCluster, its PVCs and the Restore of the drill,
+// it returns true if all of them are removed.
+func (bm *backupScheduleManager) cleanRestoreDrill(bs *v1alpha1.BackupSchedule) (bool, error) {
+	ns, name := restoreDrillNamespace(bs), restoreDrillName(bs)
+	cleaned := true
+
+	restore, err := bm.deps.RestoreLister.Restores(ns).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil {
+		if restore.Labels[label.BackupScheduleLabelKey] != bs.GetName() {
+			return false, fmt.Errorf("backup schedule %s/%s, restore %s/%s is not created by the restore drill", bs.GetNamespace(), bs.GetName(), ns, name)
+		}
+		cleaned = false
+		if err := bm.deps.Clientset.PingcapV1alpha1().Restores(ns).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	tc, err := bm.deps.TiDBClusterLister.TidbClusters(ns).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if err == nil {
+		if tc.Labels[label.BackupScheduleLabelKey] != bs.GetName() {
+			return false, fmt.Errorf("backup schedule %s/%s, tidb cluster %s/%s is not created by the restore drill", bs.GetNamespace(), bs.GetName(), ns, name)
+		}
+		cleaned = false
+		if err := bm.deps.Clientset.PingcapV1alpha1().TidbClusters(ns).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	// the PVCs are not owned by the TidbCluster and are kept after it is deleted
+	selector, err := label.New().Instance(name).Selector()
+	if err != nil {
+		return false, err
+	}
+	pvcs, err := bm.deps.PVCLister.PersistentVolumeClaims(ns).List(selector)
+	if err != nil {
+		return false, err
+	}
+	for _, pvc := range pvcs {
+		cleaned = false
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		if err := bm.deps.KubeClientset.CoreV1().PersistentVolumeClaims(ns).Delete(context.TODO(), pvc.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+	}
+
+	if !cleaned {
+		klog.Infof("backup schedule %s/%s, waiting for the resources of restore drill %s/%s to be removed", bs.GetNamespace(), bs.GetName(), ns, name)
+	}
+	return cleaned, nil
+}
+
+func restoreDrillName(bs *v1alpha1.BackupSchedule) string {
+	return fmt.Sprintf("%s-drill", bs.GetName())
+}
+
+func restoreDrillNamespace(bs *v1alpha1.BackupSchedule) string {
+	if bs.Spec.RestoreDrill.Namespace != "" {
+		return bs.Spec.RestoreDrill.Namespace
+	}
+	return bs.GetNamespace()
+}
+
+// restoreDrillObjectMeta returns the ObjectMeta of the resources of the drill, they are owned by
+// the BackupSchedule if they are in the same namespace.
+func restoreDrillObjectMeta(bs *v1alpha1.BackupSchedule) metav1.ObjectMeta {
+	objMeta := metav1.ObjectMeta{
+		Name:      restoreDrillName(bs),
+		Namespace: restoreDrillNamespace(bs),
+		Labels:    map[string]string{label.BackupScheduleLabelKey: bs.GetName()},
+	}
+	if objMeta.Namespace == bs.GetNamespace() {
+		objMeta.OwnerReferences = []metav1.OwnerReference{controller.GetBackupScheduleOwnerRef(bs)}
+	}
+	return objMeta
+}
+
+func buildRestoreDrillCluster(bs *v1alpha1.BackupSchedule) *v1alpha1.TidbCluster {
+	spec := *bs.Spec.RestoreDrill.ClusterTemplate.DeepCopy()
+	// the ephemeral cluster is removed along with its data
+	reclaimPolicy := corev1.PersistentVolumeReclaimDelete
+	spec.PVReclaimPolicy = &reclaimPolicy
+	return &v1alpha1.TidbCluster{
+		ObjectMeta: restoreDrillObjectMeta(bs),
+		Spec:       spec,
+	}
+}
+
+func buildRestoreDrillRestore(bs *v1alpha1.BackupSchedule, backup *v1alpha1.Backup) *v1alpha1.Restore {
+	objMeta := restoreDrillObjectMeta(bs)
+	br := backup.Spec.BR.DeepCopy()
+	br.Cluster = objMeta.Name
+	br.ClusterNamespace = objMeta.Namespace
+	br.TimeAgo = ""
+	return &v1alpha1.Restore{
+		ObjectMeta: objMeta,
+		Spec: v1alpha1.RestoreSpec{
+			Type:             backup.Spec.Type,
+			StorageProvider:  *backup.Spec.StorageProvider.DeepCopy(),
+			BR:               br,
+			Env:              backup.Spec.Env,
+			UseKMS:           backup.Spec.UseKMS,
+			ServiceAccount:   backup.Spec.ServiceAccount,
+			ToolImage:        backup.Spec.ToolImage,
+			ImagePullSecrets: backup.Spec.ImagePullSecrets,
+		},
+	}
+}
+
+// runRestoreDrillChecks runs the checks in the ephemeral TidbCluster as root, whose password is
+// not set as the cluster is created without a TidbInitializer.
+func runRestoreDrillChecks(tc *v1alpha1.TidbCluster, checks []v1alpha1.RestoreDrillCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), restoreDrillCheckTimeout)
+	defer cancel()
+	db, err := util.OpenDB(ctx, util.GetDSN(tc, ""))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, check := range checks {
+		if err := runRestoreDrillCheck(ctx, db, check); err != nil {
+			return fmt.Errorf("check %s failed: %v", check.Name, err)
+		}
+	}
+	return nil
+}
+
+func runRestoreDrillCheck(ctx context.Context, db *sql.DB, check v1alpha1.RestoreDrillCheck) error {
+	rows, err := db.QueryContext(ctx, check.SQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("no row is returned")
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	if check.Expected != nil && string(values[0]) != *check.Expected {
+		return fmt.Errorf("%q is returned, expected %q", string(values[0]), *check.Expected)
+	}
+	return nil
+}
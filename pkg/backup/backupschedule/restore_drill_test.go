@@ -0,0 +1,201 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backupschedule
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRestoreDrill(t *testing.T) {
+	g := NewGomegaWithT(t)
+	helper := newHelper(t)
+	defer helper.close()
+	deps := helper.deps
+	m := NewBackupScheduleManager(deps).(*backupScheduleManager)
+	var checkErr error
+	m.checkDrill = func(tc *v1alpha1.TidbCluster, checks []v1alpha1.RestoreDrillCheck) error {
+		return checkErr
+	}
+
+	now := time.Date(2022, 6, 8, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	bs := &v1alpha1.BackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              "bsname",
+			CreationTimestamp: metav1.Time{Time: now.AddDate(0, 0, -2)},
+		},
+		Spec: v1alpha1.BackupScheduleSpec{
+			Schedule:       "0 0 * * *",
+			TimeZone:       "UTC",
+			BackupTemplate: v1alpha1.BackupSpec{BR: &v1alpha1.BRConfig{Cluster: "basic"}},
+			RestoreDrill: &v1alpha1.RestoreDrillSpec{
+				Schedule: "0 4 * * *",
+				ClusterTemplate: v1alpha1.TidbClusterSpec{
+					PD:   &v1alpha1.PDSpec{Replicas: 1},
+					TiKV: &v1alpha1.TiKVSpec{Replicas: 1},
+					TiDB: &v1alpha1.TiDBSpec{Replicas: 1},
+				},
+				Timeout: "2h",
+			},
+		},
+	}
+	getTC := func() (*v1alpha1.TidbCluster, error) {
+		return deps.Clientset.PingcapV1alpha1().TidbClusters("ns").Get(context.TODO(), "bsname-drill", metav1.GetOptions{})
+	}
+	getRestore := func() (*v1alpha1.Restore, error) {
+		return deps.Clientset.PingcapV1alpha1().Restores("ns").Get(context.TODO(), "bsname-drill", metav1.GetOptions{})
+	}
+	waitSynced := func(fn func() error) {
+		t.Helper()
+		g.Eventually(fn, time.Second*10).Should(Succeed())
+	}
+
+	// no drill is started without a completed backup
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill).To(BeNil())
+
+	bk := BuildBackup(bs, now.AddDate(0, 0, -1))
+	bk.Spec.S3 = &v1alpha1.S3StorageProvider{Bucket: "backup", Prefix: "basic"}
+	bk.Status.Conditions = []v1alpha1.BackupCondition{{Type: v1alpha1.BackupComplete, Status: corev1.ConditionTrue}}
+	helper.createBackup(bk)
+
+	// the drill cluster is created
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill.Phase).To(Equal(v1alpha1.RestoreDrillRunning))
+	g.Expect(bs.Status.RestoreDrill.Backup).To(Equal(bk.Name))
+	g.Expect(bs.Status.RestoreDrill.ScheduledTime.Time).To(Equal(time.Date(2022, 6, 8, 4, 0, 0, 0, time.UTC)))
+	tc, err := getTC()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*tc.Spec.PVReclaimPolicy).To(Equal(corev1.PersistentVolumeReclaimDelete))
+	g.Expect(tc.Labels).To(HaveKeyWithValue(label.BackupScheduleLabelKey, "bsname"))
+	g.Expect(tc.OwnerReferences).To(HaveLen(1))
+	waitSynced(func() error {
+		_, err := deps.TiDBClusterLister.TidbClusters("ns").Get("bsname-drill")
+		return err
+	})
+
+	// the restore is not created until the cluster is ready
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	_, err = getRestore()
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	tc.Status.PD.Members = map[string]v1alpha1.PDMember{"pd-0": {Health: true}}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{"1": {State: v1alpha1.TiKVStateUp}}
+	tc.Status.TiDB.Members = map[string]v1alpha1.TiDBMember{"tidb-0": {Health: true}}
+	_, err = deps.Clientset.PingcapV1alpha1().TidbClusters("ns").Update(context.TODO(), tc, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	waitSynced(func() error {
+		tc, _ := deps.TiDBClusterLister.TidbClusters("ns").Get("bsname-drill")
+		if !tc.TiDBAllMembersReady() {
+			return fmt.Errorf("not synced yet")
+		}
+		return nil
+	})
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	restore, err := getRestore()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(restore.Spec.BR.Cluster).To(Equal("bsname-drill"))
+	g.Expect(restore.Spec.BR.ClusterNamespace).To(Equal("ns"))
+	g.Expect(restore.Spec.S3).To(Equal(bk.Spec.S3))
+
+	// the drill fails if the check fails after the restore is complete
+	restore.Status.Conditions = []v1alpha1.RestoreCondition{{Type: v1alpha1.RestoreComplete, Status: corev1.ConditionTrue}}
+	_, err = deps.Clientset.PingcapV1alpha1().Restores("ns").Update(context.TODO(), restore, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	waitSynced(func() error {
+		restore, err := deps.RestoreLister.Restores("ns").Get("bsname-drill")
+		if err != nil || !v1alpha1.IsRestoreComplete(restore) {
+			return fmt.Errorf("not synced yet")
+		}
+		return nil
+	})
+	checkErr = fmt.Errorf("check orders failed")
+	now = now.Add(30 * time.Minute)
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill.Phase).To(Equal(v1alpha1.RestoreDrillFailed))
+	g.Expect(bs.Status.RestoreDrill.Duration).To(Equal("30m0s"))
+	g.Expect(bs.Status.RestoreDrill.Message).To(ContainSubstring("check orders failed"))
+	g.Expect(meta.IsStatusConditionTrue(bs.Status.Conditions, v1alpha1.BackupScheduleRestoreDrillFailed)).To(BeTrue())
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Normal RestoreDrillStarted")))
+	g.Expect(recorder.Events).To(Receive(ContainSubstring("Warning RestoreDrillFailed")))
+
+	// the resources of the drill are removed
+	_, err = getTC()
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	_, err = getRestore()
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	// the next drill is started after the resources are removed and it is scheduled
+	waitSynced(func() error {
+		if _, err := deps.TiDBClusterLister.TidbClusters("ns").Get("bsname-drill"); !errors.IsNotFound(err) {
+			return fmt.Errorf("not synced yet")
+		}
+		if _, err := deps.RestoreLister.Restores("ns").Get("bsname-drill"); !errors.IsNotFound(err) {
+			return fmt.Errorf("not synced yet")
+		}
+		return nil
+	})
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill.Phase).To(Equal(v1alpha1.RestoreDrillFailed))
+	now = now.AddDate(0, 0, 1)
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill.Phase).To(Equal(v1alpha1.RestoreDrillRunning))
+
+	// the drill fails if it times out
+	now = now.Add(3 * time.Hour)
+	g.Expect(m.syncRestoreDrill(bs)).To(Succeed())
+	g.Expect(bs.Status.RestoreDrill.Phase).To(Equal(v1alpha1.RestoreDrillFailed))
+	g.Expect(bs.Status.RestoreDrill.Message).To(ContainSubstring("not completed in 2h0m0s"))
+}
+
+func TestRestoreDrillNotOwned(t *testing.T) {
+	g := NewGomegaWithT(t)
+	helper := newHelper(t)
+	defer helper.close()
+	deps := helper.deps
+	m := NewBackupScheduleManager(deps).(*backupScheduleManager)
+
+	bs := &v1alpha1.BackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bsname"},
+		Spec: v1alpha1.BackupScheduleSpec{
+			RestoreDrill: &v1alpha1.RestoreDrillSpec{Schedule: "0 4 * * *"},
+		},
+	}
+	// the cluster with the same name is not removed if it is not created by the drill
+	tc := &v1alpha1.TidbCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "bsname-drill"}}
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters("ns").Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Eventually(func() error {
+		_, err := deps.TiDBClusterLister.TidbClusters("ns").Get("bsname-drill")
+		return err
+	}, time.Second*10).Should(Succeed())
+
+	g.Expect(m.syncRestoreDrill(bs)).To(MatchError(ContainSubstring("is not created by the restore drill")))
+	_, err = deps.Clientset.PingcapV1alpha1().TidbClusters("ns").Get(context.TODO(), "bsname-drill", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+}
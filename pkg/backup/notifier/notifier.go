@@ -0,0 +1,233 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notifier posts the state transitions of the backups and the restores to the webhooks of their
+// spec.notification.
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// failureThreshold is the number of the consecutive failed notifications after which a webhook is not
+	// requested until the cooldown elapses
+	failureThreshold = 5
+	cooldown         = 5 * time.Minute
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventsTypePrefix  = "com.pingcap.tidb-operator"
+)
+
+// ErrCircuitOpen is returned without requesting the webhook if it fails repeatedly and the cooldown does
+// not elapse yet
+var ErrCircuitOpen = errors.New("the webhook fails repeatedly, circuit breaker is open")
+
+// Payload is the JSON payload of a notification. It is built from the spec and the status of the object
+// notified only, the secrets referenced by them must never be included.
+type Payload struct {
+	// Kind is the kind of the object notified, e.g. Backup or Restore
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	UID       string `json:"uid"`
+	// Cluster is the namespaced name of the TidbCluster backed up or restored, it is empty if it is unknown
+	Cluster string `json:"cluster,omitempty"`
+	// Status is the phase the object transitions to, e.g. Complete or Failed
+	Status   string `json:"status"`
+	Size     int64  `json:"size,omitempty"`
+	CommitTs string `json:"commitTs,omitempty"`
+	// Duration is from the start to the completion of the object, e.g. 1h2m3s
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// Time is when the object transitions to the status, in RFC3339 format
+	Time string `json:"time"`
+}
+
+// Webhook is the webhook a notification is sent to
+type Webhook struct {
+	URL string
+	// Token is sent as the bearer token in the Authorization header if it is not empty
+	Token string
+	// CloudEvents sends the payload as the data of a CloudEvent in the structured content mode
+	CloudEvents bool
+}
+
+// cloudEvent is a CloudEvent of the version 1.0 in the structured content mode
+type cloudEvent struct {
+	SpecVersion     string   `json:"specversion"`
+	ID              string   `json:"id"`
+	Source          string   `json:"source"`
+	Type            string   `json:"type"`
+	Subject         string   `json:"subject"`
+	Time            string   `json:"time"`
+	DataContentType string   `json:"datacontenttype"`
+	Data            *Payload `json:"data"`
+}
+
+// statusError is the error of the non-2xx response of the webhook
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %v", e.code, e.err)
+}
+
+// breaker is the circuit breaker of a webhook
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// Notifier sends the notifications to the webhooks. A notification is retried with backoff on the network
+// errors and the 5xx and 429 responses, and a webhook is not requested for the cooldown after the
+// notifications to it fail for failureThreshold times in a row. It is safe for concurrent use.
+type Notifier struct {
+	client  *http.Client
+	backoff wait.Backoff
+	now     func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New returns a Notifier
+func New() *Notifier {
+	return &Notifier{
+		client: &http.Client{Timeout: requestTimeout},
+		backoff: wait.Backoff{
+			Steps:    3,
+			Duration: time.Second,
+			Factor:   2.0,
+			Jitter:   0.1,
+		},
+		now:      time.Now,
+		breakers: map[string]*breaker{},
+	}
+}
+
+// Notify sends the payload to the webhook and returns the number of the requests sent, which is 0 if the
+// circuit breaker of the webhook is open
+func (n *Notifier) Notify(webhook *Webhook, payload *Payload) (int, error) {
+	if !n.allow(webhook.URL) {
+		return 0, ErrCircuitOpen
+	}
+	body, contentType, err := encode(webhook, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := 0
+	err = retry.OnError(n.backoff, retriable, func() error {
+		attempts++
+		return n.send(webhook, body, contentType)
+	})
+	n.record(webhook.URL, err == nil)
+	return attempts, err
+}
+
+func (n *Notifier) send(webhook *Webhook, body []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if webhook.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+webhook.Token)
+	}
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &statusError{code: res.StatusCode, err: httputil.ReadErrorBody(res.Body)}
+	}
+	return nil
+}
+
+// retriable returns false for the errors of the responses the webhook rejects the notification with, which
+// fail again if retried
+func retriable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (n *Notifier) allow(url string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	b, ok := n.breakers[url]
+	if !ok || b.failures < failureThreshold {
+		return true
+	}
+	// the webhook is requested once the cooldown elapses, and the circuit is opened again if it fails
+	return !n.now().Before(b.openUntil)
+}
+
+func (n *Notifier) record(url string, succeeded bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if succeeded {
+		delete(n.breakers, url)
+		return
+	}
+	b, ok := n.breakers[url]
+	if !ok {
+		b = &breaker{}
+		n.breakers[url] = b
+	}
+	b.failures++
+	if b.failures >= failureThreshold {
+		b.openUntil = n.now().Add(cooldown)
+	}
+}
+
+// encode returns the body and the content type of the request of the payload
+func encode(webhook *Webhook, payload *Payload) ([]byte, string, error) {
+	if !webhook.CloudEvents {
+		body, err := json.Marshal(payload)
+		return body, "application/json", err
+	}
+	kind := strings.ToLower(payload.Kind)
+	event := &cloudEvent{
+		SpecVersion: cloudEventsSpecVersion,
+		// the retries of a notification are deduplicated by the id
+		ID:              fmt.Sprintf("%s-%s", payload.UID, strings.ToLower(payload.Status)),
+		Source:          fmt.Sprintf("/apis/pingcap.com/v1alpha1/namespaces/%s/%ss/%s", payload.Namespace, kind, payload.Name),
+		Type:            fmt.Sprintf("%s.%s.%s", cloudEventsTypePrefix, kind, strings.ToLower(payload.Status)),
+		Subject:         payload.Name,
+		Time:            payload.Time,
+		DataContentType: "application/json",
+		Data:            payload,
+	}
+	body, err := json.Marshal(event)
+	return body, cloudEventsContentType, err
+}
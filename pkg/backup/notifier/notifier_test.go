@@ -0,0 +1,191 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// newServer returns a webhook responding with the statuses in order, and the last one for the rest requests
+func newServer(g *GomegaWithT, statuses ...int) (*httptest.Server, func() []request) {
+	var mu sync.Mutex
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, request{header: r.Header, body: body})
+		status := statuses[len(statuses)-1]
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func newTestNotifier() *Notifier {
+	n := New()
+	n.backoff = wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 2.0}
+	return n
+}
+
+func newPayload() *Payload {
+	return &Payload{
+		Kind:      "Backup",
+		Namespace: "ns",
+		Name:      "backup",
+		UID:       "uid",
+		Cluster:   "ns/basic",
+		Status:    "Failed",
+		Size:      1024,
+		CommitTs:  "434343",
+		Duration:  "1m30s",
+		Error:     "BR exits with 1",
+		Time:      "2022-06-01T00:00:00Z",
+	}
+}
+
+func TestNotifyPayload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server, requests := newServer(g, http.StatusOK)
+	defer server.Close()
+	n := newTestNotifier()
+
+	attempts, err := n.Notify(&Webhook{URL: server.URL, Token: "secret-token"}, newPayload())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempts).To(Equal(1))
+	req := requests()[0]
+	g.Expect(req.header.Get("Content-Type")).To(Equal("application/json"))
+	g.Expect(req.header.Get("Authorization")).To(Equal("Bearer secret-token"))
+	g.Expect(string(req.body)).NotTo(ContainSubstring("secret-token"))
+	var got map[string]interface{}
+	g.Expect(json.Unmarshal(req.body, &got)).To(Succeed())
+	g.Expect(got).To(Equal(map[string]interface{}{
+		"kind":      "Backup",
+		"namespace": "ns",
+		"name":      "backup",
+		"uid":       "uid",
+		"cluster":   "ns/basic",
+		"status":    "Failed",
+		"size":      float64(1024),
+		"commitTs":  "434343",
+		"duration":  "1m30s",
+		"error":     "BR exits with 1",
+		"time":      "2022-06-01T00:00:00Z",
+	}))
+
+	// the payload is the data of the CloudEvent
+	attempts, err = n.Notify(&Webhook{URL: server.URL, CloudEvents: true}, newPayload())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempts).To(Equal(1))
+	req = requests()[1]
+	g.Expect(req.header.Get("Content-Type")).To(Equal("application/cloudevents+json"))
+	g.Expect(req.header.Get("Authorization")).To(BeEmpty())
+	var event cloudEvent
+	g.Expect(json.Unmarshal(req.body, &event)).To(Succeed())
+	g.Expect(event).To(Equal(cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "uid-failed",
+		Source:          "/apis/pingcap.com/v1alpha1/namespaces/ns/backups/backup",
+		Type:            "com.pingcap.tidb-operator.backup.failed",
+		Subject:         "backup",
+		Time:            "2022-06-01T00:00:00Z",
+		DataContentType: "application/json",
+		Data:            newPayload(),
+	}))
+}
+
+func TestNotifyRetry(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the 5xx and 429 responses are retried
+	server, requests := newServer(g, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent)
+	defer server.Close()
+	attempts, err := newTestNotifier().Notify(&Webhook{URL: server.URL}, newPayload())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(attempts).To(Equal(3))
+	g.Expect(requests()).To(HaveLen(3))
+
+	// the notification fails after the retries are exhausted
+	server, requests = newServer(g, http.StatusInternalServerError)
+	defer server.Close()
+	attempts, err = newTestNotifier().Notify(&Webhook{URL: server.URL}, newPayload())
+	g.Expect(err).To(MatchError(ContainSubstring("unexpected status 500")))
+	g.Expect(attempts).To(Equal(3))
+	g.Expect(requests()).To(HaveLen(3))
+
+	// the other responses are not retried
+	server, requests = newServer(g, http.StatusUnauthorized)
+	defer server.Close()
+	attempts, err = newTestNotifier().Notify(&Webhook{URL: server.URL}, newPayload())
+	g.Expect(err).To(MatchError(ContainSubstring("unexpected status 401")))
+	g.Expect(attempts).To(Equal(1))
+	g.Expect(requests()).To(HaveLen(1))
+}
+
+func TestNotifyCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server, requests := newServer(g, http.StatusBadRequest)
+	defer server.Close()
+	now := time.Now()
+	n := newTestNotifier()
+	n.now = func() time.Time { return now }
+	webhook := &Webhook{URL: server.URL}
+
+	for i := 0; i < failureThreshold; i++ {
+		_, err := n.Notify(webhook, newPayload())
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err).NotTo(Equal(ErrCircuitOpen))
+	}
+	attempts, err := n.Notify(webhook, newPayload())
+	g.Expect(err).To(Equal(ErrCircuitOpen))
+	g.Expect(attempts).To(Equal(0))
+	g.Expect(requests()).To(HaveLen(failureThreshold))
+
+	// the webhook is requested once the cooldown elapses, and the circuit is opened again as it fails
+	now = now.Add(cooldown)
+	attempts, err = n.Notify(webhook, newPayload())
+	g.Expect(err).To(MatchError(ContainSubstring("unexpected status 400")))
+	g.Expect(attempts).To(Equal(1))
+	_, err = n.Notify(webhook, newPayload())
+	g.Expect(err).To(Equal(ErrCircuitOpen))
+
+	// the consecutive failures are reset once the webhook succeeds
+	server, _ = newServer(g, http.StatusOK)
+	defer server.Close()
+	n.breakers[server.URL] = &breaker{failures: failureThreshold - 1}
+	_, err = n.Notify(&Webhook{URL: server.URL}, newPayload())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n.breakers).NotTo(HaveKey(server.URL))
+}
@@ -273,6 +273,7 @@ func (rm *restoreManager) makeImportJob(restore *v1alpha1.Restore) (*batchv1.Job
 			Tolerations:      restore.Spec.Tolerations,
 			ImagePullSecrets: restore.Spec.ImagePullSecrets,
 			Affinity:         restore.Spec.Affinity,
+			NodeSelector:     restore.Spec.NodeSelector,
 			Volumes: append([]corev1.Volume{
 				{
 					Name: label.RestoreJobLabelVal,
@@ -286,6 +287,7 @@ func (rm *restoreManager) makeImportJob(restore *v1alpha1.Restore) (*batchv1.Job
 			PriorityClassName: restore.Spec.PriorityClassName,
 		},
 	}
+	controller.SetJobPodDefaults(rm.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -471,10 +473,12 @@ func (rm *restoreManager) makeRestoreJob(restore *v1alpha1.Restore) (*batchv1.Jo
 			Tolerations:       restore.Spec.Tolerations,
 			ImagePullSecrets:  restore.Spec.ImagePullSecrets,
 			Affinity:          restore.Spec.Affinity,
+			NodeSelector:      restore.Spec.NodeSelector,
 			Volumes:           volumes,
 			PriorityClassName: restore.Spec.PriorityClassName,
 		},
 	}
+	controller.SetJobPodDefaults(rm.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -170,6 +170,7 @@ func TestLightningRestore(t *testing.T) {
 	helper.createRestore(restore)
 	helper.CreateSecret(restore)
 
+	deps.CLIConfig.JobNodeSelector = map[string]string{"dedicated": "restore"}
 	m := NewRestoreManager(deps)
 	err = m.Sync(restore)
 	g.Expect(err).Should(BeNil())
@@ -193,6 +194,8 @@ func TestLightningRestore(t *testing.T) {
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env1))
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env2Yes))
 	g.Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(gomega.ContainElement(env2No))
+	// check the job defaults are set
+	g.Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"dedicated": "restore"}))
 }
 
 func TestBRRestore(t *testing.T) {
@@ -200,6 +203,7 @@ func TestBRRestore(t *testing.T) {
 	helper := newHelper(t)
 	defer helper.Close()
 	deps := helper.Deps
+	deps.CLIConfig.JobTolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
 	var err error
 
 	for i, restore := range genValidBRRestores() {
@@ -230,5 +234,7 @@ func TestBRRestore(t *testing.T) {
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env1))
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).To(gomega.ContainElement(env2Yes))
 		g.Expect(job.Spec.Template.Spec.Containers[0].Env).NotTo(gomega.ContainElement(env2No))
+		// check the job defaults are set
+		g.Expect(job.Spec.Template.Spec.Tolerations).To(Equal(deps.CLIConfig.JobTolerations))
 	}
 }
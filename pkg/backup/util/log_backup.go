@@ -0,0 +1,82 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LogBackupRange is the ts range covered by a piece of log backup metadata
+type LogBackupRange struct {
+	StartTs uint64
+	EndTs   uint64
+}
+
+// LogBackupGapError describes a ts range not covered by the log backup
+type LogBackupGapError struct {
+	Start uint64
+	End   uint64
+}
+
+func (e *LogBackupGapError) Error() string {
+	return fmt.Sprintf("log backup is not continuous, missing range [%d, %d]", e.Start, e.End)
+}
+
+// IsLogBackupGapError returns whether err is a LogBackupGapError
+func IsLogBackupGapError(err error) bool {
+	_, ok := err.(*LogBackupGapError)
+	return ok
+}
+
+// CheckLogBackupContinuity checks the log backup ranges continuously cover the ts from the full backup
+// at fullBackupTs to restoredTs. A range starting at the end of the covered range is treated as continuous,
+// overlapped ranges are allowed and the order of ranges does not matter.
+// A *LogBackupGapError describing the first gap is returned if the ranges are not continuous.
+func CheckLogBackupContinuity(fullBackupTs, restoredTs uint64, ranges []LogBackupRange) error {
+	if restoredTs < fullBackupTs {
+		return fmt.Errorf("restored ts %d is before the full backup ts %d", restoredTs, fullBackupTs)
+	}
+
+	sorted := make([]LogBackupRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartTs < sorted[j].StartTs
+	})
+
+	covered := fullBackupTs
+	for _, r := range sorted {
+		if covered >= restoredTs {
+			return nil
+		}
+		if r.EndTs <= covered {
+			continue
+		}
+		if r.StartTs > covered {
+			return &LogBackupGapError{Start: covered, End: minTs(r.StartTs, restoredTs)}
+		}
+		covered = r.EndTs
+	}
+	if covered < restoredTs {
+		return &LogBackupGapError{Start: covered, End: restoredTs}
+	}
+	return nil
+}
+
+func minTs(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckLogBackupContinuity(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type testcase struct {
+		name         string
+		fullBackupTs uint64
+		restoredTs   uint64
+		ranges       []LogBackupRange
+		expectErr    bool
+		expectGap    *LogBackupGapError
+	}
+	tests := []testcase{
+		{
+			name:         "continuous ranges",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			ranges:       []LogBackupRange{{50, 150}, {150, 200}, {200, 300}},
+		},
+		{
+			name:         "overlapped ranges out of order",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			ranges:       []LogBackupRange{{180, 300}, {90, 160}, {120, 200}},
+		},
+		{
+			name:         "restored ts at the end of the ranges",
+			fullBackupTs: 100,
+			restoredTs:   200,
+			ranges:       []LogBackupRange{{100, 150}, {150, 200}},
+		},
+		{
+			name:         "restored ts at the full backup ts",
+			fullBackupTs: 100,
+			restoredTs:   100,
+		},
+		{
+			name:         "gap between ranges",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			ranges:       []LogBackupRange{{50, 150}, {170, 300}},
+			expectErr:    true,
+			expectGap:    &LogBackupGapError{Start: 150, End: 170},
+		},
+		{
+			name:         "gap after the full backup",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			ranges:       []LogBackupRange{{120, 300}},
+			expectErr:    true,
+			expectGap:    &LogBackupGapError{Start: 100, End: 120},
+		},
+		{
+			name:         "gap before the restored ts",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			ranges:       []LogBackupRange{{100, 200}},
+			expectErr:    true,
+			expectGap:    &LogBackupGapError{Start: 200, End: 250},
+		},
+		{
+			name:         "gap after the restored ts is ignored",
+			fullBackupTs: 100,
+			restoredTs:   200,
+			ranges:       []LogBackupRange{{100, 200}, {300, 400}},
+		},
+		{
+			name:         "no log backup",
+			fullBackupTs: 100,
+			restoredTs:   250,
+			expectErr:    true,
+			expectGap:    &LogBackupGapError{Start: 100, End: 250},
+		},
+		{
+			name:         "restored ts before the full backup",
+			fullBackupTs: 100,
+			restoredTs:   50,
+			ranges:       []LogBackupRange{{0, 300}},
+			expectErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Log(test.name)
+		err := CheckLogBackupContinuity(test.fullBackupTs, test.restoredTs, test.ranges)
+		if !test.expectErr {
+			g.Expect(err).Should(BeNil())
+			continue
+		}
+		g.Expect(err).ShouldNot(BeNil())
+		if test.expectGap != nil {
+			g.Expect(IsLogBackupGapError(err)).Should(BeTrue())
+			g.Expect(err).Should(Equal(test.expectGap))
+		} else {
+			g.Expect(IsLogBackupGapError(err)).Should(BeFalse())
+		}
+	}
+}
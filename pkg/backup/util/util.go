@@ -16,12 +16,15 @@ package util
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/Masterminds/semver"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/backup/constants"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	corelisterv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 )
@@ -461,6 +464,16 @@ func ValidateBackup(backup *v1alpha1.Backup, tikvImage string) error {
 	ns := backup.Namespace
 	name := backup.Name
 
+	if errs := v1alpha1validation.ValidateJobHistoryLimit(backup.Spec.JobHistoryLimit, field.NewPath("spec", "jobHistoryLimit")); len(errs) > 0 {
+		return fmt.Errorf("invalid job history limit in spec of %s/%s: %v", ns, name, errs.ToAggregate())
+	}
+	if ttl := backup.Spec.TTLSecondsAfterFinished; ttl != nil && *ttl < 0 {
+		return fmt.Errorf("ttlSecondsAfterFinished %d must not be negative in spec of %s/%s", *ttl, ns, name)
+	}
+	if errs := v1alpha1validation.ValidateNotification(backup.Spec.Notification, field.NewPath("spec", "notification")); len(errs) > 0 {
+		return fmt.Errorf("invalid notification in spec of %s/%s: %v", ns, name, errs.ToAggregate())
+	}
+
 	if backup.Spec.BR == nil {
 		if reason := validateAccessConfig(backup.Spec.From); reason != "" {
 			return fmt.Errorf(reason, ns, name)
@@ -523,6 +536,9 @@ func ValidateRestore(restore *v1alpha1.Restore, tikvImage string) error {
 		if restore.Spec.StorageSize == "" {
 			return fmt.Errorf("missing StorageSize config in spec of %s/%s", ns, name)
 		}
+		if restore.Spec.PitrRestoredTs != "" || restore.Spec.PitrLogBackupPrefix != "" {
+			return fmt.Errorf("point-in-time restore is only supported by BR in spec of %s/%s", ns, name)
+		}
 	} else {
 		if !canSkipSetGCLifeTime(tikvImage) {
 			if reason := validateAccessConfig(restore.Spec.To); reason != "" {
@@ -548,6 +564,15 @@ func ValidateRestore(restore *v1alpha1.Restore, tikvImage string) error {
 			return fmt.Errorf("table should be configured for BR with restore type table in spec of %s/%s", ns, name)
 		}
 
+		if len(restore.Spec.RenameMapping) > 0 && len(restore.Spec.TableFilter) == 0 &&
+			(restore.Spec.Type == "" || restore.Spec.Type == v1alpha1.BackupTypeFull) {
+			return fmt.Errorf("rename mapping is not supported when restoring the whole cluster in spec of %s/%s", ns, name)
+		}
+
+		if err := validatePitr(ns, name, restore); err != nil {
+			return err
+		}
+
 		// validate storage providers
 		if restore.Spec.S3 != nil {
 			if err := validateS3(ns, name, restore.Spec.S3); err != nil {
@@ -563,9 +588,64 @@ func ValidateRestore(restore *v1alpha1.Restore, tikvImage string) error {
 			}
 		}
 	}
+	return validateRenameMapping(ns, name, restore.Spec.RenameMapping)
+}
+
+// validatePitr checks the restored ts and the log backup prefix of a point-in-time restore are configured
+// together and the restored ts is a valid TSO
+func validatePitr(ns, name string, restore *v1alpha1.Restore) error {
+	if restore.Spec.PitrRestoredTs == "" && restore.Spec.PitrLogBackupPrefix == "" {
+		return nil
+	}
+	if restore.Spec.PitrRestoredTs == "" || restore.Spec.PitrLogBackupPrefix == "" {
+		return fmt.Errorf("pitrRestoredTs and pitrLogBackupPrefix should be configured together in spec of %s/%s", ns, name)
+	}
+	if _, err := strconv.ParseUint(restore.Spec.PitrRestoredTs, 10, 64); err != nil {
+		return fmt.Errorf("invalid pitrRestoredTs %s in spec of %s/%s, err: %v", restore.Spec.PitrRestoredTs, ns, name, err)
+	}
+	if restore.Spec.Type != "" && restore.Spec.Type != v1alpha1.BackupTypeFull {
+		return fmt.Errorf("point-in-time restore does not support restore type %s in spec of %s/%s", restore.Spec.Type, ns, name)
+	}
 	return nil
 }
 
+// validateRenameMapping checks whether the sources and the targets of the rename mapping are valid
+// and no target is mapped from more than one source.
+func validateRenameMapping(ns, name string, mappings []v1alpha1.RestoreRenameMapping) error {
+	targets := map[string]string{}
+	for _, m := range mappings {
+		_, sourceTable, err := SplitRenameName(m.Source)
+		if err != nil {
+			return fmt.Errorf("invalid rename mapping source in spec of %s/%s: %v", ns, name, err)
+		}
+		_, targetTable, err := SplitRenameName(m.Target)
+		if err != nil {
+			return fmt.Errorf("invalid rename mapping target in spec of %s/%s: %v", ns, name, err)
+		}
+		if (sourceTable == "*") != (targetTable == "*") {
+			return fmt.Errorf("rename mapping %s to %s should map a database to a database or a table to a table in spec of %s/%s", m.Source, m.Target, ns, name)
+		}
+		if source, ok := targets[m.Target]; ok {
+			return fmt.Errorf("rename mapping target %s collides between %s and %s in spec of %s/%s", m.Target, source, m.Source, ns, name)
+		}
+		targets[m.Target] = m.Source
+	}
+	return nil
+}
+
+// SplitRenameName splits the 'db.table' of the rename mapping of a restore, the table is '*' if it is a database
+func SplitRenameName(name string) (string, string, error) {
+	idx := strings.Index(name, ".")
+	if idx <= 0 || idx == len(name)-1 {
+		return "", "", fmt.Errorf("%q is not in the form of 'db.table'", name)
+	}
+	db, table := name[:idx], name[idx+1:]
+	if strings.ContainsAny(db, "*?") || strings.Contains(table, "?") || (table != "*" && strings.Contains(table, "*")) {
+		return "", "", fmt.Errorf("%q should not contain wildcards other than 'db.*'", name)
+	}
+	return db, table, nil
+}
+
 func validateS3(ns, name string, s3 *v1alpha1.S3StorageProvider) error {
 	configuredForBR := fmt.Sprintf("configured for BR in spec of %s/%s", ns, name)
 	if s3.Bucket == "" {
@@ -581,6 +581,46 @@ func TestValidateRestore(t *testing.T) {
 
 	restore.Spec.S3.Endpoint = "s3://localhost:80"
 	match("")
+
+	// rename mapping
+	restore.Spec.Type = v1alpha1.BackupTypeFull
+	restore.Spec.RenameMapping = []v1alpha1.RestoreRenameMapping{{Source: "db.t", Target: "db.t_restored"}}
+	match("rename mapping is not supported when restoring the whole cluster")
+
+	restore.Spec.TableFilter = []string{"db.t", "app.*"}
+	match("")
+
+	restore.Spec.RenameMapping = append(restore.Spec.RenameMapping, v1alpha1.RestoreRenameMapping{Source: "app.*", Target: "app_restored.*"})
+	match("")
+
+	restore.Spec.RenameMapping[1].Target = "app_restored.t"
+	match("should map a database to a database or a table to a table")
+
+	restore.Spec.RenameMapping[1].Target = "app*.*"
+	match("invalid rename mapping target")
+
+	restore.Spec.RenameMapping[1] = v1alpha1.RestoreRenameMapping{Source: "app.t", Target: "db.t_restored"}
+	match("rename mapping target db.t_restored collides")
+
+	// point-in-time restore
+	restore.Spec.RenameMapping = nil
+	restore.Spec.TableFilter = nil
+	restore.Spec.PitrRestoredTs = "433748128405078017"
+	match("pitrRestoredTs and pitrLogBackupPrefix should be configured together")
+
+	restore.Spec.PitrLogBackupPrefix = "log"
+	match("")
+
+	restore.Spec.PitrRestoredTs = "2022-06-01 00:00:00"
+	match("invalid pitrRestoredTs")
+
+	restore.Spec.PitrRestoredTs = "433748128405078017"
+	restore.Spec.Type = v1alpha1.BackupTypeTable
+	match("point-in-time restore does not support restore type table")
+
+	restore.Spec.Type = v1alpha1.BackupTypeFull
+	restore.Spec.BR = nil
+	match("point-in-time restore is only supported by BR")
 }
 
 func TestGetImageTag(t *testing.T) {
@@ -22,7 +22,6 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	"k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -54,18 +53,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 
 	klog.Info("Starting TidbClusterAutoScaler controller")
 	defer klog.Info("Shutting down tidbclusterAutoScaler controller")
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.TiDBClusterAutoScalerControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 func (c *Controller) processNextWorkItem() bool {
 	key, quit := c.queue.Get()
 	if quit {
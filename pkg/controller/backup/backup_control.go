@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/util/slice"
 )
@@ -43,21 +44,33 @@ type ControlInterface interface {
 // implements the documented semantics for Backup.
 func NewDefaultBackupControl(
 	cli versioned.Interface,
-	backupManager backup.BackupManager) ControlInterface {
+	backupManager backup.BackupManager,
+	recorder record.EventRecorder,
+	deletionProtection bool) ControlInterface {
 	return &defaultBackupControl{
 		cli,
 		backupManager,
+		recorder,
+		deletionProtection,
 	}
 }
 
 type defaultBackupControl struct {
 	cli           versioned.Interface
 	backupManager backup.BackupManager
+	recorder      record.EventRecorder
+	// deletionProtection is the default deletion protection of the backups
+	deletionProtection bool
 }
 
 // UpdateBackup executes the core logic loop for a Backup.
 func (c *defaultBackupControl) UpdateBackup(backup *v1alpha1.Backup) error {
 	backup.SetGroupVersionKind(controller.BackupControllerKind)
+	// the backup data is not cleaned until the deletion of the protected backup is confirmed
+	if stop, err := c.syncDeletionProtection(backup); stop || err != nil {
+		return err
+	}
+
 	if err := c.addProtectionFinalizer(backup); err != nil {
 		return err
 	}
@@ -109,6 +122,21 @@ func (c *defaultBackupControl) removeProtectionFinalizer(backup *v1alpha1.Backup
 	return nil
 }
 
+func (c *defaultBackupControl) syncDeletionProtection(backup *v1alpha1.Backup) (bool, error) {
+	ns := backup.GetNamespace()
+	name := backup.GetName()
+
+	protected := controller.DeletionProtected(backup.Spec.DeletionProtection, c.deletionProtection)
+	return controller.SyncDeletionProtection(backup, "Backup", protected, c.recorder, func(finalizers []string) error {
+		backup.Finalizers = finalizers
+		_, err := c.cli.PingcapV1alpha1().Backups(ns).Update(context.TODO(), backup, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("update backup %s/%s deletion protection finalizers failed, err: %v", ns, name, err)
+		}
+		return nil
+	})
+}
+
 func needToAddFinalizer(backup *v1alpha1.Backup) bool {
 	return backup.DeletionTimestamp == nil && v1alpha1.IsCleanCandidate(backup) && !slice.ContainsString(backup.Finalizers, label.BackupProtectionFinalizer, nil)
 }
@@ -27,12 +27,15 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/backup/backup"
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned/fake"
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 )
 
 func TestBackupControlUpdateBackup(t *testing.T) {
@@ -153,12 +156,70 @@ func TestBackupControlUpdateBackup(t *testing.T) {
 	}
 }
 
+func TestBackupControlDeletionProtection(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cleaned := []v1alpha1.BackupCondition{{Type: v1alpha1.BackupClean, Status: corev1.ConditionTrue}}
+	hasFinalizer := func(backup *v1alpha1.Backup, finalizer string) bool {
+		for _, f := range backup.Finalizers {
+			if f == finalizer {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the operator-level default is off
+	recorder := record.NewFakeRecorder(10)
+	control := NewDefaultBackupControl(&fake.Clientset{}, backup.NewFakeBackupManager(), recorder, false)
+	bk := newBackup()
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(hasFinalizer(bk, label.DeletionProtectionFinalizer)).To(BeFalse())
+	bk = newBackup()
+	bk.Spec.DeletionProtection = pointer.BoolPtr(true)
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(bk.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+
+	// the operator-level default is on
+	control = NewDefaultBackupControl(&fake.Clientset{}, backup.NewFakeBackupManager(), recorder, true)
+	bk = newBackup()
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(bk.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+	// the finalizer is removed if the backup is not protected any more
+	bk.Spec.DeletionProtection = pointer.BoolPtr(false)
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(hasFinalizer(bk, label.DeletionProtectionFinalizer)).To(BeFalse())
+
+	// the deletion is blocked without the confirmation
+	bk = newBackup()
+	bk.Finalizers = []string{label.DeletionProtectionFinalizer, label.BackupProtectionFinalizer}
+	bk.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	bk.Status.Conditions = cleaned
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(bk.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer, label.BackupProtectionFinalizer}))
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DeletionProtected))
+
+	// the deletion is not confirmed by the name of another backup
+	bk.Annotations = map[string]string{label.AnnConfirmDeletionKey: "other-backup"}
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(hasFinalizer(bk, label.DeletionProtectionFinalizer)).To(BeTrue())
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DeletionProtected))
+
+	// the deletion is confirmed
+	bk.Annotations[label.AnnConfirmDeletionKey] = bk.Name
+	g.Expect(control.UpdateBackup(bk)).To(Succeed())
+	g.Expect(bk.Finalizers).To(Equal([]string{label.BackupProtectionFinalizer}))
+	// then the backup is cleaned as usual
+	err := control.UpdateBackup(bk)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(bk.Finalizers).To(BeEmpty())
+}
+
 func newFakeBackupControl() (ControlInterface, cache.Indexer, *backup.FakeBackupManager, *fake.Clientset) {
 	cli := &fake.Clientset{}
 
 	backupInformer := informers.NewSharedInformerFactory(cli, 0).Pingcap().V1alpha1().Backups()
 	backupManager := backup.NewFakeBackupManager()
-	control := NewDefaultBackupControl(cli, backupManager)
+	control := NewDefaultBackupControl(cli, backupManager, record.NewFakeRecorder(10), false)
 
 	return control, backupInformer.Informer().GetIndexer(), backupManager, cli
 }
@@ -27,10 +27,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/util/slice"
 )
 
 // Controller controls backup.
@@ -47,7 +47,7 @@ type Controller struct {
 func NewController(deps *controller.Dependencies) *Controller {
 	c := &Controller{
 		deps:    deps,
-		control: NewDefaultBackupControl(deps.Clientset, backup.NewBackupManager(deps)),
+		control: NewDefaultBackupControl(deps.Clientset, backup.NewBackupManager(deps), deps.Recorder, deps.CLIConfig.DeletionProtection),
 		queue: workqueue.NewNamedRateLimitingQueue(
 			controller.NewControllerRateLimiter(1*time.Second, 100*time.Second),
 			"backup",
@@ -78,19 +78,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting backup controller")
 	defer klog.Info("Shutting down backup controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.BackupControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *Controller) processNextWorkItem() bool {
@@ -154,18 +146,32 @@ func (c *Controller) updateBackup(cur interface{}) {
 		return
 	}
 
-	if v1alpha1.IsBackupInvalid(newBackup) {
-		klog.V(4).Infof("backup %s/%s is invalid, skipping.", ns, name)
+	protected := controller.DeletionProtected(newBackup.Spec.DeletionProtection, c.deps.CLIConfig.DeletionProtection)
+	if protected != slice.ContainsString(newBackup.Finalizers, label.DeletionProtectionFinalizer, nil) {
+		// the deletion protection of the backup is changed, enqueue backup even if it is finished.
+		klog.V(4).Infof("backup %s/%s deletion protection is changed to %t", ns, name, protected)
+		c.enqueueBackup(newBackup)
 		return
 	}
 
-	if v1alpha1.IsBackupComplete(newBackup) {
-		klog.V(4).Infof("backup %s/%s is Complete, skipping.", ns, name)
+	if v1alpha1.IsBackupInvalid(newBackup) {
+		klog.V(4).Infof("backup %s/%s is invalid, skipping.", ns, name)
 		return
 	}
 
-	if v1alpha1.IsBackupFailed(newBackup) {
-		klog.V(4).Infof("backup %s/%s is Failed, skipping.", ns, name)
+	if v1alpha1.IsBackupComplete(newBackup) || v1alpha1.IsBackupFailed(newBackup) {
+		if c.needsJobHistorySync(newBackup) {
+			// the jobs of the finished backups are pruned by the job history limit
+			klog.V(4).Infof("backup %s/%s is finished, enqueue it to sync the job history", ns, name)
+			c.enqueueBackup(newBackup)
+			return
+		}
+		if c.needsNotification(newBackup) {
+			klog.V(4).Infof("backup %s/%s is finished, enqueue it to notify the webhook", ns, name)
+			c.enqueueBackup(newBackup)
+			return
+		}
+		klog.V(4).Infof("backup %s/%s is %s, skipping.", ns, name, newBackup.Status.Phase)
 		return
 	}
 
@@ -203,6 +209,36 @@ func (c *Controller) updateBackup(cur interface{}) {
 	c.enqueueBackup(newBackup)
 }
 
+// needsJobHistorySync returns true if the job of the finished backup exists and may be pruned or kept by the job
+// history limit
+func (c *Controller) needsJobHistorySync(backup *v1alpha1.Backup) bool {
+	ns := backup.GetNamespace()
+	job, err := c.deps.JobLister.Jobs(ns).Get(backup.GetBackupJobName())
+	if err != nil || job.DeletionTimestamp != nil {
+		return false
+	}
+	if backup.Spec.JobHistoryLimit != nil || (v1alpha1.IsBackupFailed(backup) && job.Spec.TTLSecondsAfterFinished != nil) {
+		return true
+	}
+	scheduleName, ok := backup.Labels[label.BackupScheduleLabelKey]
+	if !ok {
+		return false
+	}
+	bs, err := c.deps.BackupScheduleLister.BackupSchedules(ns).Get(scheduleName)
+	return err == nil && bs.Spec.JobHistoryLimit != nil
+}
+
+// needsNotification returns true if the event of the finished backup is to be notified and it is not attempted yet,
+// the failed notifications are retried by the requeue of the sync rather than by the updates of the backup
+func (c *Controller) needsNotification(b *v1alpha1.Backup) bool {
+	event, pending := backup.PendingNotification(b)
+	if !pending {
+		return false
+	}
+	status := b.Status.Notification
+	return status == nil || status.EventType != event
+}
+
 func (c *Controller) deleteJob(obj interface{}) {
 	job, ok := obj.(*batchv1.Job)
 	if !ok {
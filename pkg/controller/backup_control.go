@@ -36,6 +36,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions/pingcap/v1alpha1"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
@@ -101,12 +102,12 @@ func (c *realBackupControl) recordBackupEvent(verb string, backup *v1alpha1.Back
 
 	bsName := backup.GetLabels()[label.BackupScheduleLabelKey]
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Backup %s/%s for backupSchedule/%s successful",
 			strings.ToLower(verb), ns, backupName, bsName)
 		c.recorder.Event(backup, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Backup %s/%s for backupSchedule/%s failed error: %s",
 			strings.ToLower(verb), ns, backupName, bsName, err)
 		c.recorder.Event(backup, corev1.EventTypeWarning, reason, msg)
@@ -23,6 +23,8 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions/pingcap/v1alpha1"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
@@ -47,6 +49,10 @@ type BackupUpdateStatus struct {
 	BackupSize *int64
 	// CommitTs is the snapshot time point of tidb cluster.
 	CommitTs *string
+	// FailureSummary is the exit diagnostics and the last lines of the log of the tool failed.
+	FailureSummary *string
+	// Notification is the result of the notification of the last event.
+	Notification *v1alpha1.NotificationStatus
 }
 
 // BackupConditionUpdaterInterface enables updating Backup conditions.
@@ -79,7 +85,11 @@ func (u *realBackupConditionUpdater) Update(backup *v1alpha1.Backup, condition *
 	// try best effort to guarantee backup is updated.
 	err := retry.OnError(retry.DefaultRetry, func(e error) bool { return e != nil }, func() error {
 		updateBackupStatus(&backup.Status, newStatus)
-		isUpdate = v1alpha1.UpdateBackupCondition(&backup.Status, condition)
+		// the notification is updated without a condition, as the phase of the backup is not changed by it
+		isUpdate = newStatus != nil && newStatus.Notification != nil
+		if condition != nil && v1alpha1.UpdateBackupCondition(&backup.Status, condition) {
+			isUpdate = true
+		}
 		if isUpdate {
 			_, updateErr := u.cli.PingcapV1alpha1().Backups(ns).Update(context.TODO(), backup, metav1.UpdateOptions{})
 			if updateErr == nil {
@@ -97,6 +107,9 @@ func (u *realBackupConditionUpdater) Update(backup *v1alpha1.Backup, condition *
 		}
 		return nil
 	})
+	if err == nil && newStatus != nil && newStatus.FailureSummary != nil && *newStatus.FailureSummary != "" {
+		u.recorder.Event(backup, corev1.EventTypeWarning, events.BackupToolFailed, *newStatus.FailureSummary)
+	}
 	return err
 }
 
@@ -124,6 +137,12 @@ func updateBackupStatus(status *v1alpha1.BackupStatus, newStatus *BackupUpdateSt
 	if newStatus.CommitTs != nil {
 		status.CommitTs = *newStatus.CommitTs
 	}
+	if newStatus.FailureSummary != nil {
+		status.FailureSummary = *newStatus.FailureSummary
+	}
+	if newStatus.Notification != nil {
+		status.Notification = newStatus.Notification
+	}
 }
 
 var _ BackupConditionUpdaterInterface = &realBackupConditionUpdater{}
@@ -64,6 +64,7 @@ func newUpdateBackupStatus() *BackupUpdateStatus {
 	path := "abcd"
 	sizeReadable := "5M"
 	size := int64(5024)
+	summary := "br exited with: exit status 1"
 	return &BackupUpdateStatus{
 		CommitTs:           &ts,
 		TimeCompleted:      &metav1.Time{Time: end},
@@ -71,6 +72,7 @@ func newUpdateBackupStatus() *BackupUpdateStatus {
 		BackupPath:         &path,
 		BackupSizeReadable: &sizeReadable,
 		BackupSize:         &size,
+		FailureSummary:     &summary,
 	}
 }
 
@@ -119,5 +121,6 @@ func newExpectBackupStatus() *v1alpha1.BackupStatus {
 	s.BackupPath = path
 	s.BackupSizeReadable = sizeReadable
 	s.BackupSize = size
+	s.FailureSummary = "br exited with: exit status 1"
 	return s
 }
@@ -23,7 +23,6 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	"k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -70,19 +69,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting backup schedule controller")
 	defer klog.Info("Shutting down backup schedule controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.BackupScheduleControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *Controller) processNextWorkItem() bool {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -109,12 +110,12 @@ func (c *realConfigMapControl) recordConfigMapEvent(verb string, owner runtime.O
 	}
 	cmName := cm.GetName()
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s ConfigMap %s for %s/%s successful",
 			strings.ToLower(verb), cmName, kind, name)
 		c.recorder.Event(owner, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s ConfigMap %s for %s/%s failed error: %s",
 			strings.ToLower(verb), cmName, kind, name, err)
 		c.recorder.Event(owner, corev1.EventTypeWarning, reason, msg)
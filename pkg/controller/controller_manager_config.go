@@ -0,0 +1,311 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pingcap/tidb-operator/pkg/rbac"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ControllerManagerConfigAPIVersion is the apiVersion of the config file of tidb-controller-manager
+	ControllerManagerConfigAPIVersion = "config.tidb.pingcap.com/v1alpha1"
+	// ControllerManagerConfigKind is the kind of the config file of tidb-controller-manager
+	ControllerManagerConfigKind = "ControllerManagerConfiguration"
+)
+
+// ReloadableConfigFields are the fields of ControllerManagerConfiguration applied without restart when the config
+// file changes, the changes of the other fields take effect after tidb-controller-manager restarts
+var ReloadableConfigFields = sets.NewString(
+	"logLevel",
+	"workers",
+	"controllerWorkers",
+	"eventQPSPerCluster",
+	"eventBurstPerCluster",
+)
+
+// ControllerManagerConfiguration is the versioned config of tidb-controller-manager read from the file set by
+// -config. Each field is the flag in its flag tag, see CLIConfig for its meaning, and the flags set on the
+// command line take precedence over the fields.
+type ControllerManagerConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// LogLevel is the verbosity of the logs
+	LogLevel int32 `json:"logLevel" flag:"v"`
+
+	Workers int `json:"workers" flag:"workers"`
+	// ControllerWorkers are the numbers of the workers of the controllers by their names, e.g. tidbcluster
+	ControllerWorkers map[string]int `json:"controllerWorkers,omitempty" flag:"controller-workers"`
+
+	ClusterScoped         bool `json:"clusterScoped" flag:"cluster-scoped"`
+	ClusterPermissionNode bool `json:"clusterPermissionNode" flag:"cluster-permission-node"`
+	ClusterPermissionPV   bool `json:"clusterPermissionPV" flag:"cluster-permission-pv"`
+	ClusterPermissionSC   bool `json:"clusterPermissionSC" flag:"cluster-permission-sc"`
+
+	AutoFailover          bool            `json:"autoFailover" flag:"auto-failover"`
+	PDFailoverPeriod      metav1.Duration `json:"pdFailoverPeriod" flag:"pd-failover-period"`
+	TiKVFailoverPeriod    metav1.Duration `json:"tikvFailoverPeriod" flag:"tikv-failover-period"`
+	TiDBFailoverPeriod    metav1.Duration `json:"tidbFailoverPeriod" flag:"tidb-failover-period"`
+	TiFlashFailoverPeriod metav1.Duration `json:"tiflashFailoverPeriod" flag:"tiflash-failover-period"`
+	MasterFailoverPeriod  metav1.Duration `json:"dmMasterFailoverPeriod" flag:"dm-master-failover-period"`
+	WorkerFailoverPeriod  metav1.Duration `json:"dmWorkerFailoverPeriod" flag:"dm-worker-failover-period"`
+
+	LeaseDuration       metav1.Duration `json:"leaderLeaseDuration" flag:"leader-lease-duration"`
+	RenewDeadline       metav1.Duration `json:"leaderRenewDeadline" flag:"leader-renew-deadline"`
+	RetryPeriod         metav1.Duration `json:"leaderRetryPeriod" flag:"leader-retry-period"`
+	ShutdownGracePeriod metav1.Duration `json:"shutdownGracePeriod" flag:"shutdown-grace-period"`
+	ResyncDuration      metav1.Duration `json:"resyncDuration" flag:"resync-duration"`
+
+	TestMode               bool   `json:"testMode" flag:"test-mode"`
+	TiDBBackupManagerImage string `json:"tidbBackupManagerImage" flag:"tidb-backup-manager-image"`
+	TiDBDiscoveryImage     string `json:"tidbDiscoveryImage" flag:"tidb-discovery-image"`
+
+	Selector       string `json:"selector" flag:"selector"`
+	PodSelector    string `json:"podSelector" flag:"pod-selector"`
+	SecretSelector string `json:"secretSelector" flag:"secret-selector"`
+	OTLPEndpoint   string `json:"otlpEndpoint" flag:"otlp-endpoint"`
+
+	JobTolerations  []corev1.Toleration `json:"jobTolerations,omitempty" flag:"job-tolerations"`
+	JobNodeSelector map[string]string   `json:"jobNodeSelector,omitempty" flag:"job-node-selector"`
+
+	PDCircuitBreakerFailureThreshold int             `json:"pdCircuitBreakerFailureThreshold" flag:"pd-circuit-breaker-failure-threshold"`
+	PDCircuitBreakerOpenDuration     metav1.Duration `json:"pdCircuitBreakerOpenDuration" flag:"pd-circuit-breaker-open-duration"`
+
+	UpgradeFreezeConfigMap        string `json:"upgradeFreezeConfigMap" flag:"upgrade-freeze-configmap"`
+	UpgradeFreezeConfigMapKey     string `json:"upgradeFreezeConfigMapKey" flag:"upgrade-freeze-configmap-key"`
+	UpgradeWebhookClusterSelector string `json:"upgradeWebhookClusterSelector" flag:"upgrade-webhook-cluster-selector"`
+	Capabilities                  string `json:"capabilities" flag:"capabilities"`
+	TierConfigMap                 string `json:"tierConfigMap" flag:"tier-config-configmap"`
+
+	StoreWatchInterval          metav1.Duration `json:"storeWatchInterval" flag:"store-watch-interval"`
+	StoreWatchMaxClusters       int             `json:"storeWatchMaxClusters" flag:"store-watch-max-clusters"`
+	ExternalProvisioningTimeout metav1.Duration `json:"externalProvisioningTimeout" flag:"external-provisioning-timeout"`
+	DeletionProtection          bool            `json:"deletionProtection" flag:"deletion-protection"`
+
+	CoreV1Events         bool    `json:"coreV1Events" flag:"core-v1-events"`
+	EventQPSPerCluster   float64 `json:"eventQPSPerCluster" flag:"event-qps-per-cluster"`
+	EventBurstPerCluster int     `json:"eventBurstPerCluster" flag:"event-burst-per-cluster"`
+
+	NodeMaintenanceConcurrency int `json:"nodeMaintenanceConcurrency" flag:"node-maintenance-concurrency"`
+}
+
+// NewControllerManagerConfiguration returns the config of the fields of the CLIConfig, the config of
+// DefaultCLIConfig is the default one of the config file
+func NewControllerManagerConfiguration(cliCfg *CLIConfig) *ControllerManagerConfiguration {
+	cfg := &ControllerManagerConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: ControllerManagerConfigAPIVersion,
+			Kind:       ControllerManagerConfigKind,
+		},
+	}
+	from := reflect.ValueOf(cliCfg).Elem()
+	to := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < to.NumField(); i++ {
+		f, ok := from.Type().FieldByName(to.Type().Field(i).Name)
+		if !ok {
+			continue
+		}
+		value := from.FieldByIndex(f.Index)
+		if to.Field(i).Type() == reflect.TypeOf(metav1.Duration{}) {
+			value = reflect.ValueOf(metav1.Duration{Duration: value.Interface().(time.Duration)})
+		}
+		to.Field(i).Set(value)
+	}
+	return cfg
+}
+
+// LoadControllerManagerConfiguration reads the config file, the fields absent from the file are defaulted by
+// DefaultCLIConfig. It returns the validated config with the hash of the content of the file.
+func LoadControllerManagerConfiguration(path string) (*ControllerManagerConfiguration, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	cfg := NewControllerManagerConfiguration(DefaultCLIConfig())
+	cfg.APIVersion, cfg.Kind = "", ""
+	if err := yaml.Unmarshal(data, cfg, yaml.DisallowUnknownFields); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if err := cfg.Validate().ToAggregate(); err != nil {
+		return nil, "", fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return cfg, fmt.Sprintf("%x", sha256.Sum256(data))[:16], nil
+}
+
+// Validate validates the config
+func (c *ControllerManagerConfiguration) Validate() field.ErrorList {
+	var errs field.ErrorList
+	if c.APIVersion != ControllerManagerConfigAPIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{ControllerManagerConfigAPIVersion}))
+	}
+	if c.Kind != ControllerManagerConfigKind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{ControllerManagerConfigKind}))
+	}
+	if c.LogLevel < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("logLevel"), c.LogLevel, "must be non-negative"))
+	}
+	if c.Workers < 1 {
+		errs = append(errs, field.Invalid(field.NewPath("workers"), c.Workers, "must be positive"))
+	}
+	for name, workers := range c.ControllerWorkers {
+		path := field.NewPath("controllerWorkers").Key(name)
+		if !controllerNames.Has(name) {
+			errs = append(errs, field.NotSupported(path, name, controllerNames.List()))
+		} else if workers < 1 {
+			errs = append(errs, field.Invalid(path, workers, "must be positive"))
+		}
+	}
+
+	for name, d := range map[string]metav1.Duration{
+		"pdFailoverPeriod":             c.PDFailoverPeriod,
+		"tikvFailoverPeriod":           c.TiKVFailoverPeriod,
+		"tidbFailoverPeriod":           c.TiDBFailoverPeriod,
+		"tiflashFailoverPeriod":        c.TiFlashFailoverPeriod,
+		"dmMasterFailoverPeriod":       c.MasterFailoverPeriod,
+		"dmWorkerFailoverPeriod":       c.WorkerFailoverPeriod,
+		"shutdownGracePeriod":          c.ShutdownGracePeriod,
+		"pdCircuitBreakerOpenDuration": c.PDCircuitBreakerOpenDuration,
+		"storeWatchInterval":           c.StoreWatchInterval,
+		"externalProvisioningTimeout":  c.ExternalProvisioningTimeout,
+	} {
+		if d.Duration < 0 {
+			errs = append(errs, field.Invalid(field.NewPath(name), d.Duration.String(), "must be non-negative"))
+		}
+	}
+	if c.ResyncDuration.Duration <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("resyncDuration"), c.ResyncDuration.Duration.String(), "must be positive"))
+	}
+	// the same as the checks of leaderelection.NewLeaderElector
+	if c.LeaseDuration.Duration <= c.RenewDeadline.Duration {
+		errs = append(errs, field.Invalid(field.NewPath("leaderLeaseDuration"), c.LeaseDuration.Duration.String(), "must be greater than leaderRenewDeadline"))
+	}
+	if float64(c.RenewDeadline.Duration) <= leaderelection.JitterFactor*float64(c.RetryPeriod.Duration) {
+		errs = append(errs, field.Invalid(field.NewPath("leaderRenewDeadline"), c.RenewDeadline.Duration.String(),
+			fmt.Sprintf("must be greater than %v times of leaderRetryPeriod", leaderelection.JitterFactor)))
+	}
+
+	for name, selector := range map[string]string{
+		"selector":                      c.Selector,
+		"podSelector":                   c.PodSelector,
+		"secretSelector":                c.SecretSelector,
+		"upgradeWebhookClusterSelector": c.UpgradeWebhookClusterSelector,
+	} {
+		if _, err := labels.Parse(selector); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(name), selector, err.Error()))
+		}
+	}
+	for name, key := range map[string]string{
+		"upgradeFreezeConfigMap": c.UpgradeFreezeConfigMap,
+		"tierConfigMap":          c.TierConfigMap,
+	} {
+		if key == "" {
+			continue
+		}
+		if ns, n, err := cache.SplitMetaNamespaceKey(key); err != nil || ns == "" || n == "" {
+			errs = append(errs, field.Invalid(field.NewPath(name), key, "must be in the form of <namespace>/<name>"))
+		}
+	}
+	if _, err := rbac.ParseCapabilities(c.Capabilities); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("capabilities"), c.Capabilities, err.Error()))
+	}
+
+	for name, n := range map[string]float64{
+		"pdCircuitBreakerFailureThreshold": float64(c.PDCircuitBreakerFailureThreshold),
+		"storeWatchMaxClusters":            float64(c.StoreWatchMaxClusters),
+		"eventQPSPerCluster":               c.EventQPSPerCluster,
+		"eventBurstPerCluster":             float64(c.EventBurstPerCluster),
+	} {
+		if n < 0 {
+			errs = append(errs, field.Invalid(field.NewPath(name), n, "must be non-negative"))
+		}
+	}
+	if c.NodeMaintenanceConcurrency < 1 {
+		errs = append(errs, field.Invalid(field.NewPath("nodeMaintenanceConcurrency"), c.NodeMaintenanceConcurrency, "must be positive"))
+	}
+	return errs
+}
+
+// ApplyTo sets the fields of the CLIConfig and the log level by the config, except the ones whose flags are set
+// on the command line as the flags take precedence
+func (c *ControllerManagerConfiguration) ApplyTo(cliCfg *CLIConfig, explicitFlags sets.String) {
+	c.applyTo(cliCfg, explicitFlags, nil)
+}
+
+// applyTo applies the fields by their json names to the CLIConfig, all the fields are applied if fields is nil
+func (c *ControllerManagerConfiguration) applyTo(cliCfg *CLIConfig, explicitFlags, fields sets.String) {
+	from := reflect.ValueOf(c).Elem()
+	to := reflect.ValueOf(cliCfg).Elem()
+	for i := 0; i < from.NumField(); i++ {
+		f := from.Type().Field(i)
+		flagName := f.Tag.Get("flag")
+		if flagName == "" || explicitFlags.Has(flagName) || (fields != nil && !fields.Has(configFieldName(f))) {
+			continue
+		}
+		if f.Name == "LogLevel" {
+			setLogLevel(c.LogLevel)
+			continue
+		}
+		value := from.Field(i)
+		if d, ok := value.Interface().(metav1.Duration); ok {
+			value = reflect.ValueOf(d.Duration)
+		}
+		to.FieldByName(f.Name).Set(value)
+	}
+}
+
+// changedFields returns the json names of the fields changed in the other config
+func (c *ControllerManagerConfiguration) changedFields(other *ControllerManagerConfiguration) []string {
+	var changed []string
+	v, o := reflect.ValueOf(c).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.Tag.Get("flag") == "" {
+			continue
+		}
+		if !reflect.DeepEqual(v.Field(i).Interface(), o.Field(i).Interface()) {
+			changed = append(changed, configFieldName(f))
+		}
+	}
+	return changed
+}
+
+func configFieldName(f reflect.StructField) string {
+	return strings.Split(f.Tag.Get("json"), ",")[0]
+}
+
+// setLogLevel sets the verbosity of klog by the -v flag, it is a variable for testing
+var setLogLevel = func(level int32) {
+	if f := flag.CommandLine.Lookup("v"); f != nil {
+		if err := f.Value.Set(strconv.Itoa(int(level))); err != nil {
+			klog.Errorf("failed to set the log level to %d: %v", level, err)
+		}
+	}
+}
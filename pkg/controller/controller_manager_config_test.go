@@ -0,0 +1,245 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestControllerManagerConfigurationFields(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfgType := reflect.TypeOf(ControllerManagerConfiguration{})
+	flags := sets.NewString()
+	for i := 0; i < cfgType.NumField(); i++ {
+		if name := cfgType.Field(i).Tag.Get("flag"); name != "" {
+			g.Expect(flags.Has(name)).To(BeFalse(), "flag %s of field %s is duplicated", name, cfgType.Field(i).Name)
+			flags.Insert(name)
+		}
+	}
+	// all the fields of CLIConfig set by the flags can be set by the config file
+	skipped := sets.NewString("PrintVersion", "ConfigFile", "WaitDuration")
+	cliType := reflect.TypeOf(CLIConfig{})
+	for i := 0; i < cliType.NumField(); i++ {
+		name := cliType.Field(i).Name
+		if skipped.Has(name) {
+			continue
+		}
+		_, ok := cfgType.FieldByName(name)
+		g.Expect(ok).To(BeTrue(), "field %s of CLIConfig is not in the config file", name)
+	}
+
+	// the config of the default CLIConfig is valid and converts back to it
+	cfg := NewControllerManagerConfiguration(DefaultCLIConfig())
+	g.Expect(cfg.Validate()).To(BeEmpty())
+	// WaitDuration is not set by the flags
+	cliCfg := &CLIConfig{WaitDuration: DefaultCLIConfig().WaitDuration}
+	cfg.applyTo(cliCfg, sets.NewString("v"), nil)
+	g.Expect(cliCfg).To(Equal(DefaultCLIConfig()))
+}
+
+func TestLoadControllerManagerConfiguration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "config")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	load := func(content string) (*ControllerManagerConfiguration, string, error) {
+		g.Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return LoadControllerManagerConfiguration(path)
+	}
+
+	cfg, hash, err := load(`
+apiVersion: config.tidb.pingcap.com/v1alpha1
+kind: ControllerManagerConfiguration
+logLevel: 4
+workers: 10
+controllerWorkers:
+  tidbcluster: 20
+tikvFailoverPeriod: 10m
+jobNodeSelector:
+  pool: jobs
+`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hash).To(HaveLen(16))
+	g.Expect(cfg.LogLevel).To(Equal(int32(4)))
+	g.Expect(cfg.Workers).To(Equal(10))
+	g.Expect(cfg.ControllerWorkers).To(Equal(map[string]int{TiDBClusterControllerName: 20}))
+	g.Expect(cfg.TiKVFailoverPeriod).To(Equal(metav1.Duration{Duration: 10 * time.Minute}))
+	g.Expect(cfg.JobNodeSelector).To(Equal(map[string]string{"pool": "jobs"}))
+	// the fields absent from the file are defaulted
+	g.Expect(cfg.PDFailoverPeriod).To(Equal(metav1.Duration{Duration: 5 * time.Minute}))
+	g.Expect(cfg.ClusterScoped).To(BeTrue())
+	g.Expect(cfg.EventBurstPerCluster).To(Equal(25))
+
+	_, hash2, err := load(`
+apiVersion: config.tidb.pingcap.com/v1alpha1
+kind: ControllerManagerConfiguration
+workers: 11
+`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(hash2).NotTo(Equal(hash))
+
+	for content, msg := range map[string]string{
+		"kind: ControllerManagerConfiguration\nworkers: 1":                                                                  "apiVersion",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: Config\nworkers: 1":                                            "kind",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\nworker: 1":                     "unknown field",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\nworkers: 0":                    "workers",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\ncontrollerWorkers:\n  tidb: 1": "controllerWorkers[tidb]",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\nleaderRenewDeadline: 20s":      "leaderLeaseDuration",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\npodSelector: a b":              "podSelector",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\ntierConfigMap: tiers":          "tierConfigMap",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\ncapabilities: backup,foo":      "capabilities",
+		"apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\neventQPSPerCluster: -1":        "eventQPSPerCluster",
+	} {
+		_, _, err := load(content)
+		g.Expect(err).To(HaveOccurred(), content)
+		g.Expect(err.Error()).To(ContainSubstring(msg), content)
+	}
+}
+
+func TestControllerManagerConfigurationApplyTo(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var logLevel int32 = -1
+	defer func(f func(int32)) { setLogLevel = f }(setLogLevel)
+	setLogLevel = func(level int32) { logLevel = level }
+
+	cfg := NewControllerManagerConfiguration(DefaultCLIConfig())
+	cfg.LogLevel = 4
+	cfg.Workers = 10
+	cfg.TiKVFailoverPeriod = metav1.Duration{Duration: 10 * time.Minute}
+	cfg.ClusterScoped = false
+	cfg.JobTolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+
+	// the flags set on the command line take precedence
+	cliCfg := DefaultCLIConfig()
+	cliCfg.Workers = 3
+	cliCfg.ClusterScoped = true
+	cfg.ApplyTo(cliCfg, sets.NewString("workers", "cluster-scoped"))
+	g.Expect(cliCfg.Workers).To(Equal(3))
+	g.Expect(cliCfg.ClusterScoped).To(BeTrue())
+	g.Expect(cliCfg.TiKVFailoverPeriod).To(Equal(10 * time.Minute))
+	g.Expect(cliCfg.JobTolerations).To(Equal(cfg.JobTolerations))
+	g.Expect(logLevel).To(Equal(int32(4)))
+
+	logLevel = -1
+	cliCfg = DefaultCLIConfig()
+	cfg.ApplyTo(cliCfg, sets.NewString("v"))
+	g.Expect(cliCfg.Workers).To(Equal(10))
+	g.Expect(cliCfg.ClusterScoped).To(BeFalse())
+	g.Expect(logLevel).To(Equal(int32(-1)))
+}
+
+func TestConfigWatcher(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var logLevel int32
+	defer func(f func(int32)) { setLogLevel = f }(setLogLevel)
+	setLogLevel = func(level int32) { logLevel = level }
+
+	dir, err := ioutil.TempDir("", "config")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	write := func(content string) {
+		g.Expect(ioutil.WriteFile(path, []byte("apiVersion: config.tidb.pingcap.com/v1alpha1\nkind: ControllerManagerConfiguration\n"+content), 0644)).To(Succeed())
+	}
+
+	write("workers: 4\neventBurstPerCluster: 10\ntikvFailoverPeriod: 10m\n")
+	cfg, hash, err := LoadControllerManagerConfiguration(path)
+	g.Expect(err).NotTo(HaveOccurred())
+	cliCfg := DefaultCLIConfig()
+	cliCfg.EventQPSPerCluster = 2
+	explicitFlags := sets.NewString("event-qps-per-cluster")
+	cfg.ApplyTo(cliCfg, explicitFlags)
+
+	var reloaded []*CLIConfig
+	w := NewConfigWatcher(path, explicitFlags, cliCfg, cfg, hash, func(cliCfg *CLIConfig) {
+		reloaded = append(reloaded, cliCfg)
+	})
+
+	// the unchanged file is not reloaded
+	g.Expect(w.Reload()).To(Succeed())
+	g.Expect(reloaded).To(BeEmpty())
+
+	// the changes of the reloadable fields are applied except the ones of the flags set on the command line,
+	// the changes of the other fields wait for the restart
+	write("logLevel: 5\nworkers: 8\ncontrollerWorkers:\n  backup: 1\neventQPSPerCluster: 5\neventBurstPerCluster: 50\ntikvFailoverPeriod: 20m\n")
+	g.Expect(w.Reload()).To(Succeed())
+	g.Expect(reloaded).To(HaveLen(1))
+	g.Expect(reloaded[0].Workers).To(Equal(8))
+	g.Expect(reloaded[0].ControllerWorkers).To(Equal(map[string]int{BackupControllerName: 1}))
+	g.Expect(reloaded[0].EventQPSPerCluster).To(Equal(float64(2)))
+	g.Expect(reloaded[0].EventBurstPerCluster).To(Equal(50))
+	g.Expect(reloaded[0].TiKVFailoverPeriod).To(Equal(10 * time.Minute))
+	g.Expect(logLevel).To(Equal(int32(5)))
+	g.Expect(cliCfg.Workers).To(Equal(4))
+
+	// the invalid file is not applied
+	write("workers: 0\n")
+	g.Expect(w.Reload()).NotTo(Succeed())
+	g.Expect(reloaded).To(HaveLen(1))
+
+	write("workers: 2\n")
+	g.Expect(w.Reload()).To(Succeed())
+	g.Expect(reloaded).To(HaveLen(2))
+	g.Expect(reloaded[1].Workers).To(Equal(2))
+	g.Expect(reloaded[1].ControllerWorkers).To(BeNil())
+	g.Expect(logLevel).To(Equal(int32(0)))
+}
+
+func TestApplyReloadableConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := NewFakeDependencies()
+	fake := record.NewFakeRecorder(100)
+	deps.Recorder = events.NewReloadableRateLimitedRecorder(fake, 0, 0)
+	cliCfg := DefaultCLIConfig()
+	cliCfg.Workers = 3
+	cliCfg.ControllerWorkers = map[string]int{TiDBClusterControllerName: 7}
+	cliCfg.EventQPSPerCluster = 0.001
+	cliCfg.EventBurstPerCluster = 2
+	deps.ApplyReloadableConfig(cliCfg)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	process := func() bool {
+		<-stopCh
+		return false
+	}
+	deps.WorkerPool.Run(TiDBClusterControllerName, 1, process, stopCh)
+	deps.WorkerPool.Run(BackupControllerName, 1, process, stopCh)
+	g.Expect(deps.WorkerPool.Workers(TiDBClusterControllerName)).To(Equal(7))
+	g.Expect(deps.WorkerPool.Workers(BackupControllerName)).To(Equal(3))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+	for i := 0; i < 5; i++ {
+		deps.Recorder.Event(pod, corev1.EventTypeNormal, events.UpgradeBlocked, "fake")
+	}
+	g.Expect(fake.Events).To(HaveLen(2))
+}
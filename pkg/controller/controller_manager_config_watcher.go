@@ -0,0 +1,112 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// configReloadInterval is the interval the config file is read to reload its changes, the mounted ConfigMaps are
+// updated by replacing the symlinks, so the file is polled instead of watched by inotify
+const configReloadInterval = 10 * time.Second
+
+// ConfigWatcher polls the config file of tidb-controller-manager and applies the changes of ReloadableConfigFields
+// without restart, the changes of the other fields are logged and take effect after tidb-controller-manager
+// restarts. The invalid config files are not applied.
+type ConfigWatcher struct {
+	path          string
+	explicitFlags sets.String
+	cliCfg        *CLIConfig
+	onReload      func(cliCfg *CLIConfig)
+
+	current *ControllerManagerConfiguration
+	hash    string
+}
+
+// NewConfigWatcher returns a ConfigWatcher of the config file loaded with the hash on startup and applied to the
+// CLIConfig. The CLIConfig with the reloadable fields changed by the config file is passed to onReload.
+func NewConfigWatcher(path string, explicitFlags sets.String, cliCfg *CLIConfig, current *ControllerManagerConfiguration,
+	hash string, onReload func(cliCfg *CLIConfig)) *ConfigWatcher {
+	recordConfigHash(hash)
+	return &ConfigWatcher{
+		path:          path,
+		explicitFlags: explicitFlags,
+		cliCfg:        cliCfg,
+		onReload:      onReload,
+		current:       current,
+		hash:          hash,
+	}
+}
+
+// Run reloads the config file periodically until stopCh is closed
+func (w *ConfigWatcher) Run(stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := w.Reload(); err != nil {
+			klog.Errorf("failed to reload config file %s, keep the config of hash %s: %v", w.path, w.hash, err)
+		}
+	}, configReloadInterval, stopCh)
+}
+
+// Reload applies the changes of the reloadable fields of the config file if the file is changed
+func (w *ConfigWatcher) Reload() error {
+	cfg, hash, err := LoadControllerManagerConfiguration(w.path)
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues(metrics.ConfigReloadFailed).Inc()
+		return err
+	}
+	if hash == w.hash {
+		return nil
+	}
+
+	for _, name := range w.current.changedFields(cfg) {
+		if !ReloadableConfigFields.Has(name) {
+			klog.Warningf("field %s of config file %s is changed, it takes effect after tidb-controller-manager restarts", name, w.path)
+		}
+	}
+	cliCfg := *w.cliCfg
+	cfg.applyTo(&cliCfg, w.explicitFlags, ReloadableConfigFields)
+	w.onReload(&cliCfg)
+
+	klog.Infof("config file %s is reloaded, hash: %s -> %s", w.path, w.hash, hash)
+	w.current, w.hash = cfg, hash
+	recordConfigHash(hash)
+	metrics.ConfigReloads.WithLabelValues(metrics.ConfigReloadApplied).Inc()
+	return nil
+}
+
+// ApplyReloadableConfig applies the reloadable fields of the CLIConfig, except the log level, to the running
+// controllers, see ReloadableConfigFields. The CLIConfig of the Dependencies is kept as the one on startup.
+func (deps *Dependencies) ApplyReloadableConfig(cliCfg *CLIConfig) {
+	for _, name := range controllerNames.List() {
+		workers := cliCfg.Workers
+		if n, ok := cliCfg.ControllerWorkers[name]; ok {
+			workers = n
+		}
+		deps.WorkerPool.SetWorkers(name, workers)
+	}
+	if recorder, ok := deps.Recorder.(events.RateLimitedRecorder); ok {
+		recorder.SetRateLimit(cliCfg.EventQPSPerCluster, cliCfg.EventBurstPerCluster)
+	}
+}
+
+func recordConfigHash(hash string) {
+	metrics.ConfigInfo.Reset()
+	metrics.ConfigInfo.WithLabelValues(hash).Set(1)
+}
@@ -20,6 +20,7 @@ import (
 	"regexp"
 
 	"github.com/dustin/go-humanize"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/scheme"
 	"github.com/pingcap/tidb-operator/pkg/util"
@@ -305,6 +306,33 @@ func TiDBPeerMemberName(clusterName string) string {
 	return fmt.Sprintf("%s-tidb-peer", clusterName)
 }
 
+// TiDBGroupMemberName returns the name of the statefulset, the service and the configmap of a tidb group
+func TiDBGroupMemberName(clusterName, group string) string {
+	return fmt.Sprintf("%s-tidb-%s", clusterName, group)
+}
+
+// TiDBGroupPeerMemberName returns the name of the peer service of a tidb group
+func TiDBGroupPeerMemberName(clusterName, group string) string {
+	return fmt.Sprintf("%s-tidb-%s-peer", clusterName, group)
+}
+
+// TiDBSetName returns the name of the tidb statefulset of tc, tc is the copy of the cluster built for a tidb
+// group if it is labeled by label.TiDBGroupLabelKey
+func TiDBSetName(tc *v1alpha1.TidbCluster) string {
+	if group := tc.Labels[label.TiDBGroupLabelKey]; group != "" {
+		return TiDBGroupMemberName(tc.GetName(), group)
+	}
+	return TiDBMemberName(tc.GetName())
+}
+
+// TiDBSetPeerName returns the name of the peer service of the tidb statefulset of tc, see TiDBSetName
+func TiDBSetPeerName(tc *v1alpha1.TidbCluster) string {
+	if group := tc.Labels[label.TiDBGroupLabelKey]; group != "" {
+		return TiDBGroupPeerMemberName(tc.GetName(), group)
+	}
+	return TiDBPeerMemberName(tc.GetName())
+}
+
 // PumpMemberName returns pump member name
 func PumpMemberName(clusterName string) string {
 	return fmt.Sprintf("%s-pump", clusterName)
@@ -261,6 +261,12 @@ func TestTiDBPeerMemberName(t *testing.T) {
 	g.Expect(TiDBPeerMemberName("demo")).To(Equal("demo-tidb-peer"))
 }
 
+func TestTiDBGroupMemberName(t *testing.T) {
+	g := NewGomegaWithT(t)
+	g.Expect(TiDBGroupMemberName("demo", "reporting")).To(Equal("demo-tidb-reporting"))
+	g.Expect(TiDBGroupPeerMemberName("demo", "reporting")).To(Equal("demo-tidb-reporting-peer"))
+}
+
 func TestPumpMemberName(t *testing.T) {
 	g := NewGomegaWithT(t)
 	g.Expect(PumpMemberName("demo")).To(Equal("demo-pump"))
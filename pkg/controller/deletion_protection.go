@@ -0,0 +1,75 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/util/slice"
+)
+
+// DeletionProtected returns whether the object is protected from deletion, the operator-level default
+// -deletion-protection is used if the object does not set it.
+func DeletionProtected(deletionProtection *bool, defaultProtected bool) bool {
+	if deletionProtection != nil {
+		return *deletionProtection
+	}
+	return defaultProtected
+}
+
+// SyncDeletionProtection syncs the deletion protection finalizer of the object of the kind, and returns true if
+// the sync of the object should stop here, i.e. the finalizers are patched and the object is synced again on the
+// update, or the object is being deleted without its deletion confirmed.
+//
+// The finalizer is added to the protected objects and removed from the unprotected ones. Once the protected object
+// is being deleted, the finalizer is only removed after the object is annotated with AnnConfirmDeletionKey set to
+// its name, until then the object stays terminating and a Warning event explains what's required.
+func SyncDeletionProtection(obj runtime.Object, kind string, protected bool, recorder record.EventRecorder, patchFinalizers func([]string) error) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	finalizers := accessor.GetFinalizers()
+	hasFinalizer := slice.ContainsString(finalizers, label.DeletionProtectionFinalizer, nil)
+	if accessor.GetDeletionTimestamp() == nil {
+		if protected && !hasFinalizer {
+			return true, patchFinalizers(append(finalizers, label.DeletionProtectionFinalizer))
+		}
+		if !protected && hasFinalizer {
+			return true, patchFinalizers(slice.RemoveString(finalizers, label.DeletionProtectionFinalizer, nil))
+		}
+		return false, nil
+	}
+	if !hasFinalizer {
+		return false, nil
+	}
+
+	ns := accessor.GetNamespace()
+	name := accessor.GetName()
+	if accessor.GetAnnotations()[label.AnnConfirmDeletionKey] == name {
+		klog.Infof("%s %s/%s: the deletion is confirmed, remove the deletion protection", kind, ns, name)
+		return true, patchFinalizers(slice.RemoveString(finalizers, label.DeletionProtectionFinalizer, nil))
+	}
+	msg := fmt.Sprintf("%s is protected from deletion, annotate it with %s=%s to confirm the deletion", kind, label.AnnConfirmDeletionKey, name)
+	klog.Warningf("%s %s/%s: %s", kind, ns, name, msg)
+	recorder.Event(obj, corev1.EventTypeWarning, events.DeletionProtected, msg)
+	return true, nil
+}
@@ -16,6 +16,7 @@ package controller
 import (
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
@@ -24,13 +25,20 @@ import (
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/dmapi"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/pingcap/tidb-operator/pkg/rbac"
 	"github.com/pingcap/tidb-operator/pkg/scheme"
 	"github.com/pingcap/tidb-operator/pkg/tiflashapi"
 	"github.com/pingcap/tidb-operator/pkg/tikvapi"
+	"github.com/pingcap/tidb-operator/pkg/tracing"
 	utildiscovery "github.com/pingcap/tidb-operator/pkg/util/discovery"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -41,6 +49,7 @@ import (
 	extensionslister "k8s.io/client-go/listers/extensions/v1beta1"
 	networklister "k8s.io/client-go/listers/networking/v1"
 	storagelister "k8s.io/client-go/listers/storage/v1"
+	k8sevents "k8s.io/client-go/tools/events"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -50,10 +59,16 @@ import (
 // CLIConfig is used save all configuration read from command line parameters
 type CLIConfig struct {
 	PrintVersion bool
+	// ConfigFile is the path of the config file in the form of ControllerManagerConfiguration, the flags set
+	// on the command line take precedence over it. It is not read if it is empty.
+	ConfigFile string
 	// The number of workers that are allowed to sync concurrently.
 	// Larger number = more responsive management, but more CPU
 	// (and network) load
 	Workers int
+	// ControllerWorkers are the numbers of the workers of the controllers by their names, the controllers not
+	// in it run Workers workers
+	ControllerWorkers map[string]int
 	// Controls whether operator should manage kubernetes cluster
 	// wide TiDB clusters
 	ClusterScoped bool
@@ -73,6 +88,8 @@ type CLIConfig struct {
 	RenewDeadline         time.Duration
 	RetryPeriod           time.Duration
 	WaitDuration          time.Duration
+	// ShutdownGracePeriod is the time the syncs in flight are allowed to finish their current step on shutdown
+	ShutdownGracePeriod time.Duration
 	// ResyncDuration is the resync time of informer
 	ResyncDuration time.Duration
 	// Defines whether tidb operator run in test mode, test mode is
@@ -83,6 +100,67 @@ type CLIConfig struct {
 	// Selector is used to filter CR labels to decide
 	// what resources should be watched and synced by controller
 	Selector string
+	// PodSelector is the label selector of the pods cached by the controllers,
+	// all the pods are cached if it is empty
+	PodSelector string
+	// SecretSelector is the label selector of the secrets cached by the controllers,
+	// all the secrets are cached if it is empty as the TLS and storage secrets are created by users
+	SecretSelector string
+	// OTLPEndpoint is the OTLP/HTTP endpoint to export the upgrade traces to,
+	// tracing is disabled if it is empty
+	OTLPEndpoint string
+	// JobTolerations are the default tolerations of the pods of Jobs created by tidb-operator,
+	// they are used if the CR of the Job does not set tolerations
+	JobTolerations []corev1.Toleration
+	// JobNodeSelector is the default node selector of the pods of Jobs created by tidb-operator,
+	// it is used if the CR of the Job does not set node selector
+	JobNodeSelector map[string]string
+	// PDCircuitBreakerFailureThreshold is the number of consecutive failures of the PD API of a cluster
+	// opening its circuit breaker, the circuit breaker is disabled if it is not positive
+	PDCircuitBreakerFailureThreshold int
+	// PDCircuitBreakerOpenDuration is the duration the circuit breaker of the PD API stays open
+	PDCircuitBreakerOpenDuration time.Duration
+	// UpgradeFreezeConfigMap is the maintenance-mode ConfigMap in the form of <namespace>/<name>,
+	// the upgrades of all the clusters are frozen while the value of UpgradeFreezeConfigMapKey in it
+	// is UpgradeFrozenValue. It is disabled if it is empty.
+	UpgradeFreezeConfigMap string
+	// UpgradeFreezeConfigMapKey is the key of the value freezing the upgrades in UpgradeFreezeConfigMap
+	UpgradeFreezeConfigMapKey string
+	// UpgradeWebhookClusterSelector is the label selector of the TidbClusters calling their upgrade
+	// webhooks, the other clusters complete upgrades without calling them. All the clusters call
+	// their upgrade webhooks if it is empty.
+	UpgradeWebhookClusterSelector string
+	// Capabilities are the comma-separated optional capabilities enabled, the controllers of the disabled
+	// ones are not started and their permissions are not required, see pkg/rbac
+	Capabilities string
+	// StoreWatchInterval is the interval the TiKV stores of each TidbCluster are polled from PD, the cluster is
+	// synced as soon as one of its stores becomes Disconnected or Down. It is disabled if it is not positive.
+	StoreWatchInterval time.Duration
+	// StoreWatchMaxClusters is the max number of the TidbClusters whose TiKV stores are watched, the others
+	// are synced periodically only
+	StoreWatchMaxClusters int
+	// TierConfigMap is the ConfigMap in the form of <namespace>/<name> mapping the tiers of the TidbClusters to
+	// the config fragments of the components, the fragment of a component is in TOML under the key
+	// <tier>.<component>, e.g. gold.tikv. It must be in the same namespace as UpgradeFreezeConfigMap.
+	// It is disabled if it is empty.
+	TierConfigMap string
+	// ExternalProvisioningTimeout is the time the pods gated by the external provisioning are waited for, the
+	// component is marked ExternalProvisioningTimedOut if the provisioning of any pod does not complete in time
+	ExternalProvisioningTimeout time.Duration
+	// DeletionProtection is the default deletion protection of the TidbClusters, DMClusters and Backups not
+	// setting spec.deletionProtection
+	DeletionProtection bool
+	// CoreV1Events emits the core/v1 events instead of the events.k8s.io/v1 events, for the Kubernetes
+	// clusters not serving events.k8s.io/v1
+	CoreV1Events bool
+	// EventQPSPerCluster is the max number of the events emitted per second for each cluster, the events over
+	// the limit are dropped. The events are not limited if it is not positive.
+	EventQPSPerCluster float64
+	// EventBurstPerCluster is the burst of the events emitted for each cluster
+	EventBurstPerCluster int
+	// NodeMaintenanceConcurrency is the max number of the nodes whose pods are drained for the maintenance
+	// at a time, the other nodes requesting the maintenance wait
+	NodeMaintenanceConcurrency int
 }
 
 // DefaultCLIConfig returns the default command line configuration
@@ -101,10 +179,22 @@ func DefaultCLIConfig() *CLIConfig {
 		RenewDeadline:          10 * time.Second,
 		RetryPeriod:            2 * time.Second,
 		WaitDuration:           5 * time.Second,
+		ShutdownGracePeriod:    20 * time.Second,
 		ResyncDuration:         30 * time.Second,
 		TiDBBackupManagerImage: "pingcap/tidb-backup-manager:latest",
 		TiDBDiscoveryImage:     "pingcap/tidb-operator:latest",
 		Selector:               "",
+		PodSelector:            DefaultPodSelector,
+
+		PDCircuitBreakerFailureThreshold: pdapi.DefaultCircuitBreakerFailureThreshold,
+		PDCircuitBreakerOpenDuration:     pdapi.DefaultCircuitBreakerOpenDuration,
+		UpgradeFreezeConfigMapKey:        DefaultUpgradeFreezeConfigMapKey,
+		Capabilities:                     defaultCapabilities(),
+		StoreWatchMaxClusters:            100,
+		ExternalProvisioningTimeout:      10 * time.Minute,
+		EventQPSPerCluster:               1,
+		EventBurstPerCluster:             25,
+		NodeMaintenanceConcurrency:       1,
 	}
 }
 
@@ -112,7 +202,9 @@ func DefaultCLIConfig() *CLIConfig {
 func (c *CLIConfig) AddFlag(_ *flag.FlagSet) {
 	flag.BoolVar(&c.PrintVersion, "V", false, "Show version and quit")
 	flag.BoolVar(&c.PrintVersion, "version", false, "Show version and quit")
+	flag.StringVar(&c.ConfigFile, "config", c.ConfigFile, "The path of the config file of kind ControllerManagerConfiguration, the flags set on the command line take precedence over it. The changes of logLevel, workers, controllerWorkers, eventQPSPerCluster and eventBurstPerCluster in it are applied without restart")
 	flag.IntVar(&c.Workers, "workers", c.Workers, "The number of workers that are allowed to sync concurrently. Larger number = more responsive management, but more CPU (and network) load")
+	flag.Var(&jsonValue{value: &c.ControllerWorkers}, "controller-workers", "The numbers of the workers in JSON of the controllers by their names, e.g. {\"tidbcluster\":10}, the other controllers run -workers workers")
 	flag.BoolVar(&c.ClusterScoped, "cluster-scoped", c.ClusterScoped, "Whether tidb-operator should manage kubernetes cluster wide TiDB Clusters")
 	flag.BoolVar(&c.ClusterPermissionNode, "cluster-permission-node", c.ClusterPermissionNode, "Whether tidb-operator should have node permissions even if cluster-scoped is false")
 	flag.BoolVar(&c.ClusterPermissionPV, "cluster-permission-pv", c.ClusterPermissionPV, "Whether tidb-operator should have persistent volume permissions even if cluster-scoped is false")
@@ -125,11 +217,32 @@ func (c *CLIConfig) AddFlag(_ *flag.FlagSet) {
 	flag.DurationVar(&c.MasterFailoverPeriod, "dm-master-failover-period", c.MasterFailoverPeriod, "dm-master failover period")
 	flag.DurationVar(&c.WorkerFailoverPeriod, "dm-worker-failover-period", c.WorkerFailoverPeriod, "dm-worker failover period")
 	flag.DurationVar(&c.ResyncDuration, "resync-duration", c.ResyncDuration, "Resync time of informer")
+	flag.DurationVar(&c.ShutdownGracePeriod, "shutdown-grace-period", c.ShutdownGracePeriod, "The time the syncs in flight are allowed to finish their current step on shutdown, it should be less than the terminationGracePeriodSeconds of the pod")
 	flag.BoolVar(&c.TestMode, "test-mode", false, "whether tidb-operator run in test mode")
 	flag.StringVar(&c.TiDBBackupManagerImage, "tidb-backup-manager-image", c.TiDBBackupManagerImage, "The image of backup manager tool")
 	// TODO: actually we just want to use the same image with tidb-controller-manager, but DownwardAPI cannot get image ID, see if there is any better solution
 	flag.StringVar(&c.TiDBDiscoveryImage, "tidb-discovery-image", c.TiDBDiscoveryImage, "The image of the tidb discovery service")
 	flag.StringVar(&c.Selector, "selector", c.Selector, "Selector (label query) to filter on, supports '=', '==', and '!='")
+	flag.StringVar(&c.PodSelector, "pod-selector", c.PodSelector, "Selector (label query) of the pods cached by tidb-operator, all the pods are cached if it is empty")
+	flag.StringVar(&c.SecretSelector, "secret-selector", c.SecretSelector, "Selector (label query) of the secrets cached by tidb-operator, all the secrets are cached if it is empty. The secrets referred by the CRs, e.g. the TLS and storage secrets, must match it")
+	flag.StringVar(&c.OTLPEndpoint, "otlp-endpoint", c.OTLPEndpoint, "The OTLP/HTTP endpoint to export the traces of cluster upgrades to, e.g. http://otel-collector:4318. Tracing is disabled if it is empty")
+	flag.Var(&jsonValue{value: &c.JobTolerations}, "job-tolerations", "The default tolerations in JSON of the pods of backup, restore, clean and initializer Jobs, used if the CR does not set tolerations")
+	flag.Var(&jsonValue{value: &c.JobNodeSelector}, "job-node-selector", "The default node selector in JSON of the pods of backup, restore, clean and initializer Jobs, used if the CR does not set node selector")
+	flag.IntVar(&c.PDCircuitBreakerFailureThreshold, "pd-circuit-breaker-failure-threshold", c.PDCircuitBreakerFailureThreshold, "The number of consecutive failures of the PD API of a cluster opening its circuit breaker, the circuit breaker is disabled if it is 0")
+	flag.DurationVar(&c.PDCircuitBreakerOpenDuration, "pd-circuit-breaker-open-duration", c.PDCircuitBreakerOpenDuration, "The duration the circuit breaker of the PD API of a cluster stays open before PD is probed again")
+	flag.StringVar(&c.UpgradeFreezeConfigMap, "upgrade-freeze-configmap", c.UpgradeFreezeConfigMap, "The maintenance-mode ConfigMap in the form of <namespace>/<name>, the upgrades of all the clusters are paused while the value of -upgrade-freeze-configmap-key in it is \"frozen\". It is disabled if it is empty")
+	flag.StringVar(&c.UpgradeFreezeConfigMapKey, "upgrade-freeze-configmap-key", c.UpgradeFreezeConfigMapKey, "The key of the value freezing the upgrades in the ConfigMap set by -upgrade-freeze-configmap")
+	flag.StringVar(&c.UpgradeWebhookClusterSelector, "upgrade-webhook-cluster-selector", c.UpgradeWebhookClusterSelector, "Selector (label query) of the TidbClusters calling their upgrade webhooks, e.g. env=prod, the other clusters upgrade without calling them. All the clusters call their upgrade webhooks if it is empty")
+	flag.StringVar(&c.Capabilities, "capabilities", c.Capabilities, "The comma-separated optional capabilities enabled, the controllers of the other capabilities are not started and their permissions are not required. Supported capabilities: backup, monitor, dm, autoscaler")
+	flag.StringVar(&c.TierConfigMap, "tier-config-configmap", c.TierConfigMap, "The ConfigMap in the form of <namespace>/<name> mapping the tiers of the TidbClusters to the config fragments in TOML of PD, TiKV and TiDB under the keys <tier>.<component>, e.g. gold.tikv. The fragment is merged under the config of the cluster. It is disabled if it is empty")
+	flag.DurationVar(&c.StoreWatchInterval, "store-watch-interval", c.StoreWatchInterval, "The interval the TiKV stores of each TidbCluster are polled from PD, the cluster is synced as soon as one of its stores becomes Disconnected or Down instead of on the next resync. It is disabled if it is 0")
+	flag.IntVar(&c.StoreWatchMaxClusters, "store-watch-max-clusters", c.StoreWatchMaxClusters, "The max number of the TidbClusters whose TiKV stores are watched by -store-watch-interval, the others are synced periodically only")
+	flag.DurationVar(&c.ExternalProvisioningTimeout, "external-provisioning-timeout", c.ExternalProvisioningTimeout, "The time the pods with spec.<component>.waitForExternalProvisioning are waited for the external provisioning, the component is marked ExternalProvisioningTimedOut if the provisioning of any pod does not complete in time")
+	flag.BoolVar(&c.DeletionProtection, "deletion-protection", c.DeletionProtection, "The default deletion protection of the TidbClusters, DMClusters and Backups not setting spec.deletionProtection, the protected objects being deleted stay terminating until they are annotated with tidb.pingcap.com/confirm-deletion=<name of the object>")
+	flag.BoolVar(&c.CoreV1Events, "core-v1-events", c.CoreV1Events, "Emit the core/v1 events instead of the events.k8s.io/v1 events aggregated into series, for the Kubernetes clusters older than v1.19")
+	flag.Float64Var(&c.EventQPSPerCluster, "event-qps-per-cluster", c.EventQPSPerCluster, "The max number of the events emitted per second for each cluster, the events over the limit are dropped. The events are not limited if it is 0")
+	flag.IntVar(&c.EventBurstPerCluster, "event-burst-per-cluster", c.EventBurstPerCluster, "The burst of the events emitted for each cluster limited by -event-qps-per-cluster")
+	flag.IntVar(&c.NodeMaintenanceConcurrency, "node-maintenance-concurrency", c.NodeMaintenanceConcurrency, "The max number of the nodes annotated with tidb.pingcap.com/maintenance=drain whose pods are drained at a time, the other nodes wait")
 
 	// see https://pkg.go.dev/k8s.io/client-go/tools/leaderelection#LeaderElectionConfig for the config
 	flag.DurationVar(&c.LeaseDuration, "leader-lease-duration", c.LeaseDuration, "leader-lease-duration is the duration that non-leader candidates will wait to force acquire leadership")
@@ -147,6 +260,50 @@ func (c *CLIConfig) HasPVPermission() bool {
 	return c.ClusterScoped || c.ClusterPermissionPV
 }
 
+// PDCircuitBreakerConfig returns the config of the circuit breakers of the PD API.
+func (c *CLIConfig) PDCircuitBreakerConfig() pdapi.CircuitBreakerConfig {
+	return pdapi.CircuitBreakerConfig{
+		FailureThreshold: c.PDCircuitBreakerFailureThreshold,
+		OpenDuration:     c.PDCircuitBreakerOpenDuration,
+	}
+}
+
+// RBACOptions returns the capabilities and the permitted cluster-scoped resources of tidb-controller-manager
+func (c *CLIConfig) RBACOptions() (rbac.Options, error) {
+	caps, err := rbac.ParseCapabilities(c.Capabilities)
+	if err != nil {
+		return rbac.Options{}, err
+	}
+	for _, capability := range caps {
+		if capability == rbac.CapabilityWebhook {
+			return rbac.Options{}, fmt.Errorf("capability %q is not served by tidb-controller-manager", capability)
+		}
+	}
+	clusterResources := sets.NewString()
+	if c.HasNodePermission() {
+		clusterResources.Insert("nodes")
+	}
+	if c.HasPVPermission() {
+		clusterResources.Insert("persistentvolumes")
+	}
+	if c.HasSCPermission() {
+		clusterResources.Insert("storageclasses")
+	}
+	return rbac.Options{
+		Capabilities:     caps,
+		ClusterScoped:    c.ClusterScoped,
+		ClusterResources: clusterResources,
+	}, nil
+}
+
+func defaultCapabilities() string {
+	caps := make([]string, 0, len(rbac.ControllerManagerCapabilities))
+	for _, capability := range rbac.ControllerManagerCapabilities {
+		caps = append(caps, string(capability))
+	}
+	return strings.Join(caps, ",")
+}
+
 // HasSCPermission returns whether the user has permission for storage class operations.
 func (c *CLIConfig) HasSCPermission() bool {
 	return c.ClusterScoped || c.ClusterPermissionSC
@@ -180,6 +337,8 @@ type Controls struct {
 type Dependencies struct {
 	// CLIConfig represents all parameters read from command line
 	CLIConfig *CLIConfig
+	// LeaderIdentity is the identity of this tidb-controller-manager instance in leader election
+	LeaderIdentity string
 	// Operator client interface
 	Clientset versioned.Interface
 	// Kubernetes client interface
@@ -189,31 +348,43 @@ type Dependencies struct {
 	KubeInformerFactory            kubeinformers.SharedInformerFactory
 	LabelFilterKubeInformerFactory kubeinformers.SharedInformerFactory
 	Recorder                       record.EventRecorder
+	// UpgradeTracer records the upgrades of TidbClusters as traces
+	UpgradeTracer tracing.UpgradeTracer
+	// ShutdownDrain lets the syncs in flight finish their current step on shutdown
+	ShutdownDrain *ShutdownDrain
+	// WorkerPool runs the workers of the controllers, their numbers are changed by the reload of the config file
+	WorkerPool *WorkerPool
 
 	// Listers
-	ServiceLister               corelisterv1.ServiceLister
-	EndpointLister              corelisterv1.EndpointsLister
-	PVCLister                   corelisterv1.PersistentVolumeClaimLister
-	PVLister                    corelisterv1.PersistentVolumeLister
-	PodLister                   corelisterv1.PodLister
-	NodeLister                  corelisterv1.NodeLister
-	SecretLister                corelisterv1.SecretLister
-	ConfigMapLister             corelisterv1.ConfigMapLister
-	StatefulSetLister           appslisters.StatefulSetLister
-	DeploymentLister            appslisters.DeploymentLister
-	JobLister                   batchlisters.JobLister
-	IngressLister               networklister.IngressLister
-	IngressV1Beta1Lister        extensionslister.IngressLister // in order to be compatibility with kubernetes which less than v1.19
-	StorageClassLister          storagelister.StorageClassLister
-	TiDBClusterLister           listers.TidbClusterLister
-	TiDBClusterAutoScalerLister listers.TidbClusterAutoScalerLister
-	DMClusterLister             listers.DMClusterLister
-	BackupLister                listers.BackupLister
-	RestoreLister               listers.RestoreLister
-	BackupScheduleLister        listers.BackupScheduleLister
-	TiDBInitializerLister       listers.TidbInitializerLister
-	TiDBMonitorLister           listers.TidbMonitorLister
-	TiDBNGMonitoringLister      listers.TidbNGMonitoringLister
+	ServiceLister   corelisterv1.ServiceLister
+	EndpointLister  corelisterv1.EndpointsLister
+	PVCLister       corelisterv1.PersistentVolumeClaimLister
+	PVLister        corelisterv1.PersistentVolumeLister
+	PodLister       corelisterv1.PodLister
+	NodeLister      corelisterv1.NodeLister
+	SecretLister    corelisterv1.SecretLister
+	ConfigMapLister corelisterv1.ConfigMapLister
+	// UpgradeFreezeConfigMapLister lists the maintenance-mode ConfigMap only, it is nil if
+	// -upgrade-freeze-configmap is not set
+	UpgradeFreezeConfigMapLister corelisterv1.ConfigMapLister
+	StatefulSetLister            appslisters.StatefulSetLister
+	DeploymentLister             appslisters.DeploymentLister
+	JobLister                    batchlisters.JobLister
+	IngressLister                networklister.IngressLister
+	IngressV1Beta1Lister         extensionslister.IngressLister // in order to be compatibility with kubernetes which less than v1.19
+	StorageClassLister           storagelister.StorageClassLister
+	TiDBClusterLister            listers.TidbClusterLister
+	TiDBClusterAutoScalerLister  listers.TidbClusterAutoScalerLister
+	DMClusterLister              listers.DMClusterLister
+	BackupLister                 listers.BackupLister
+	RestoreLister                listers.RestoreLister
+	BackupScheduleLister         listers.BackupScheduleLister
+	TiDBInitializerLister        listers.TidbInitializerLister
+	TiDBMonitorLister            listers.TidbMonitorLister
+	TiDBNGMonitoringLister       listers.TidbNGMonitoringLister
+	// TierConfigMapLister lists the ConfigMap of the config fragments of the tiers, it is nil if
+	// -tier-config-configmap is not set
+	TierConfigMapLister corelisterv1.ConfigMapLister
 
 	// Controls
 	Controls
@@ -230,7 +401,7 @@ func newRealControls(
 	// Shared variables to construct `Dependencies` and some of its fields
 	var (
 		secretLister      = kubeInformerFactory.Core().V1().Secrets().Lister()
-		pdControl         = pdapi.NewDefaultPDControl(secretLister)
+		pdControl         = pdapi.NewDefaultPDControlWithCircuitBreaker(secretLister, cliCfg.PDCircuitBreakerConfig())
 		tikvControl       = tikvapi.NewDefaultTiKVControl(secretLister)
 		tiflashControl    = tiflashapi.NewDefaultTiFlashControl(secretLister)
 		masterControl     = dmapi.NewDefaultMasterControl(secretLister)
@@ -287,6 +458,8 @@ func newDependencies(
 		scLister         storagelister.StorageClassLister
 		ingLister        networklister.IngressLister
 		ingv1beta1Lister extensionslister.IngressLister
+		freezeCMLister   corelisterv1.ConfigMapLister
+		tierCMLister     corelisterv1.ConfigMapLister
 	)
 	if cliCfg.HasNodePermission() {
 		nodeLister = kubeInformerFactory.Core().V1().Nodes().Lister()
@@ -313,6 +486,12 @@ func newDependencies(
 	} else {
 		ingv1beta1Lister = kubeInformerFactory.Extensions().V1beta1().Ingresses().Lister()
 	}
+	if cliCfg.UpgradeFreezeConfigMap != "" {
+		freezeCMLister = kubeInformerFactory.Core().V1().ConfigMaps().Lister()
+	}
+	if cliCfg.TierConfigMap != "" {
+		tierCMLister = kubeInformerFactory.Core().V1().ConfigMaps().Lister()
+	}
 
 	return &Dependencies{
 		CLIConfig:                      cliCfg,
@@ -323,31 +502,36 @@ func newDependencies(
 		KubeInformerFactory:            kubeInformerFactory,
 		LabelFilterKubeInformerFactory: labelFilterKubeInformerFactory,
 		Recorder:                       recorder,
+		UpgradeTracer:                  tracing.NewUpgradeTracer(cliCfg.OTLPEndpoint),
+		ShutdownDrain:                  NewShutdownDrain(),
+		WorkerPool:                     NewWorkerPool(),
 
 		// Listers
-		ServiceLister:               kubeInformerFactory.Core().V1().Services().Lister(),
-		EndpointLister:              kubeInformerFactory.Core().V1().Endpoints().Lister(),
-		PVCLister:                   kubeInformerFactory.Core().V1().PersistentVolumeClaims().Lister(),
-		PVLister:                    pvLister,
-		PodLister:                   kubeInformerFactory.Core().V1().Pods().Lister(),
-		NodeLister:                  nodeLister,
-		SecretLister:                kubeInformerFactory.Core().V1().Secrets().Lister(),
-		ConfigMapLister:             labelFilterKubeInformerFactory.Core().V1().ConfigMaps().Lister(),
-		StatefulSetLister:           kubeInformerFactory.Apps().V1().StatefulSets().Lister(),
-		DeploymentLister:            kubeInformerFactory.Apps().V1().Deployments().Lister(),
-		StorageClassLister:          scLister,
-		JobLister:                   kubeInformerFactory.Batch().V1().Jobs().Lister(),
-		IngressLister:               ingLister,
-		IngressV1Beta1Lister:        ingv1beta1Lister,
-		TiDBClusterLister:           informerFactory.Pingcap().V1alpha1().TidbClusters().Lister(),
-		TiDBClusterAutoScalerLister: informerFactory.Pingcap().V1alpha1().TidbClusterAutoScalers().Lister(),
-		DMClusterLister:             informerFactory.Pingcap().V1alpha1().DMClusters().Lister(),
-		BackupLister:                informerFactory.Pingcap().V1alpha1().Backups().Lister(),
-		RestoreLister:               informerFactory.Pingcap().V1alpha1().Restores().Lister(),
-		BackupScheduleLister:        informerFactory.Pingcap().V1alpha1().BackupSchedules().Lister(),
-		TiDBInitializerLister:       informerFactory.Pingcap().V1alpha1().TidbInitializers().Lister(),
-		TiDBMonitorLister:           informerFactory.Pingcap().V1alpha1().TidbMonitors().Lister(),
-		TiDBNGMonitoringLister:      informerFactory.Pingcap().V1alpha1().TidbNGMonitorings().Lister(),
+		ServiceLister:                kubeInformerFactory.Core().V1().Services().Lister(),
+		EndpointLister:               kubeInformerFactory.Core().V1().Endpoints().Lister(),
+		PVCLister:                    kubeInformerFactory.Core().V1().PersistentVolumeClaims().Lister(),
+		PVLister:                     pvLister,
+		PodLister:                    kubeInformerFactory.Core().V1().Pods().Lister(),
+		NodeLister:                   nodeLister,
+		SecretLister:                 kubeInformerFactory.Core().V1().Secrets().Lister(),
+		ConfigMapLister:              labelFilterKubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+		UpgradeFreezeConfigMapLister: freezeCMLister,
+		TierConfigMapLister:          tierCMLister,
+		StatefulSetLister:            kubeInformerFactory.Apps().V1().StatefulSets().Lister(),
+		DeploymentLister:             kubeInformerFactory.Apps().V1().Deployments().Lister(),
+		StorageClassLister:           scLister,
+		JobLister:                    kubeInformerFactory.Batch().V1().Jobs().Lister(),
+		IngressLister:                ingLister,
+		IngressV1Beta1Lister:         ingv1beta1Lister,
+		TiDBClusterLister:            informerFactory.Pingcap().V1alpha1().TidbClusters().Lister(),
+		TiDBClusterAutoScalerLister:  informerFactory.Pingcap().V1alpha1().TidbClusterAutoScalers().Lister(),
+		DMClusterLister:              informerFactory.Pingcap().V1alpha1().DMClusters().Lister(),
+		BackupLister:                 informerFactory.Pingcap().V1alpha1().Backups().Lister(),
+		RestoreLister:                informerFactory.Pingcap().V1alpha1().Restores().Lister(),
+		BackupScheduleLister:         informerFactory.Pingcap().V1alpha1().BackupSchedules().Lister(),
+		TiDBInitializerLister:        informerFactory.Pingcap().V1alpha1().TidbInitializers().Lister(),
+		TiDBMonitorLister:            informerFactory.Pingcap().V1alpha1().TidbMonitors().Lister(),
+		TiDBNGMonitoringLister:       informerFactory.Pingcap().V1alpha1().TidbNGMonitorings().Lister(),
 	}, nil
 }
 
@@ -380,13 +564,23 @@ func NewDependencies(ns string, cliCfg *CLIConfig, clientset versioned.Interface
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(clientset, cliCfg.ResyncDuration, options...)
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClientset, cliCfg.ResyncDuration, kubeoptions...)
 	labelFilterKubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClientset, cliCfg.ResyncDuration, labelKubeOptions...)
+	informerNS := metav1.NamespaceAll
+	if !cliCfg.ClusterScoped {
+		informerNS = ns
+	}
+	registerKubeInformers(cliCfg, informerNS, kubeInformerFactory, labelFilterKubeInformerFactory)
+	var operatorConfigMaps []string
+	for _, key := range []string{cliCfg.UpgradeFreezeConfigMap, cliCfg.TierConfigMap} {
+		if key != "" {
+			operatorConfigMaps = append(operatorConfigMaps, key)
+		}
+	}
+	if err := registerOperatorConfigMapsInformer(kubeInformerFactory, operatorConfigMaps...); err != nil {
+		return nil, err
+	}
 
 	// Initialize the event recorder
-	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{QPS: 1})
-	eventBroadcaster.StartLogging(klog.V(2).Infof)
-	eventBroadcaster.StartRecordingToSink(&eventv1.EventSinkImpl{
-		Interface: eventv1.New(kubeClientset.CoreV1().RESTClient()).Events("")})
-	recorder := eventBroadcaster.NewRecorder(v1alpha1.Scheme, corev1.EventSource{Component: "tidb-controller-manager"})
+	recorder := newEventRecorder(cliCfg, kubeClientset)
 	deps, err := newDependencies(cliCfg, clientset, kubeClientset, genericCli, informerFactory, kubeInformerFactory, labelFilterKubeInformerFactory, recorder)
 	if err != nil {
 		return nil, err
@@ -395,6 +589,30 @@ func NewDependencies(ns string, cliCfg *CLIConfig, clientset versioned.Interface
 	return deps, nil
 }
 
+// newEventRecorder returns the recorder emitting the events.k8s.io/v1 events, or the core/v1 events if
+// -core-v1-events is set, the events of each cluster are limited by -event-qps-per-cluster, which is changed
+// by the reload of the config file
+func newEventRecorder(cliCfg *CLIConfig, kubeClientset kubernetes.Interface) record.EventRecorder {
+	var recorder record.EventRecorder
+	if cliCfg.CoreV1Events {
+		eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{QPS: 1})
+		eventBroadcaster.StartLogging(klog.V(2).Infof)
+		eventBroadcaster.StartRecordingToSink(&eventv1.EventSinkImpl{
+			Interface: eventv1.New(kubeClientset.CoreV1().RESTClient()).Events("")})
+		recorder = eventBroadcaster.NewRecorder(v1alpha1.Scheme, corev1.EventSource{Component: "tidb-controller-manager"})
+	} else {
+		eventBroadcaster := k8sevents.NewBroadcaster(&k8sevents.EventSinkImpl{Interface: kubeClientset.EventsV1()})
+		eventBroadcaster.StartEventWatcher(func(obj runtime.Object) {
+			if e, ok := obj.(*eventsv1.Event); ok {
+				klog.V(2).Infof("Event(%#v): type: '%v' reason: '%v' action: '%v' %v", e.Regarding, e.Type, e.Reason, e.Action, e.Note)
+			}
+		})
+		eventBroadcaster.StartRecordingToSink(wait.NeverStop)
+		recorder = events.NewEventsV1Recorder(eventBroadcaster.NewRecorder(v1alpha1.Scheme, "tidb-controller-manager"))
+	}
+	return events.NewReloadableRateLimitedRecorder(recorder, cliCfg.EventQPSPerCluster, cliCfg.EventBurstPerCluster)
+}
+
 func newFakeControl(kubeClientset kubernetes.Interface, informerFactory informers.SharedInformerFactory, kubeInformerFactory kubeinformers.SharedInformerFactory) Controls {
 	genericCtrl := NewFakeGenericControl()
 	// Shared variables to construct `Dependencies` and some of its fields
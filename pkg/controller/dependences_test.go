@@ -21,6 +21,7 @@ import (
 
 	. "github.com/onsi/gomega"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/rbac"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -50,3 +51,25 @@ func TestFakeTidbCluster(t *testing.T) {
 		}, time.Second*10).Should(BeNil())
 	}
 }
+
+func TestCLIConfigRBACOptions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfg := DefaultCLIConfig()
+	o, err := cfg.RBACOptions()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(o.Capabilities).To(ConsistOf(rbac.CapabilityCore, rbac.CapabilityBackup, rbac.CapabilityMonitor, rbac.CapabilityDM, rbac.CapabilityAutoScaler))
+	g.Expect(o.ClusterScoped).To(BeTrue())
+
+	cfg.Capabilities = "backup"
+	cfg.ClusterScoped = false
+	cfg.ClusterPermissionSC = true
+	o, err = cfg.RBACOptions()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(o.Capabilities).To(Equal([]rbac.Capability{rbac.CapabilityBackup, rbac.CapabilityCore}))
+	g.Expect(o.ClusterResources.List()).To(Equal([]string{"storageclasses"}))
+
+	cfg.Capabilities = "backup,webhook"
+	_, err = cfg.RBACOptions()
+	g.Expect(err).To(HaveOccurred())
+}
@@ -14,10 +14,14 @@
 package dmcluster
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/defaulting"
 	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/manager"
 	"github.com/pingcap/tidb-operator/pkg/manager/member"
 	v1 "k8s.io/api/core/v1"
@@ -47,7 +51,8 @@ func NewDefaultDMClusterControl(
 	pvcResizer member.PVCResizerInterface,
 	discoveryManager member.TidbDiscoveryManager,
 	conditionUpdater DMClusterConditionUpdater,
-	recorder record.EventRecorder) ControlInterface {
+	recorder record.EventRecorder,
+	deletionProtection bool) ControlInterface {
 	return &defaultDMClusterControl{
 		dcControl,
 		masterMemberManager,
@@ -60,6 +65,7 @@ func NewDefaultDMClusterControl(
 		discoveryManager,
 		conditionUpdater,
 		recorder,
+		deletionProtection,
 	}
 }
 
@@ -75,10 +81,17 @@ type defaultDMClusterControl struct {
 	discoveryManager  member.TidbDiscoveryManager
 	conditionUpdater  DMClusterConditionUpdater
 	recorder          record.EventRecorder
+	// deletionProtection is the default deletion protection of the dmclusters
+	deletionProtection bool
 }
 
 // UpdateStatefulSet executes the core logic loop for a dmcluster.
 func (c *defaultDMClusterControl) UpdateDMCluster(dc *v1alpha1.DMCluster) error {
+	// the components keep running until the deletion of the protected dmcluster is confirmed
+	if stop, err := c.syncDeletionProtection(dc); stop || err != nil {
+		return err
+	}
+
 	c.defaulting(dc)
 	if !c.validate(dc) {
 		return nil // fatal error, no need to retry on invalid object
@@ -105,6 +118,28 @@ func (c *defaultDMClusterControl) UpdateDMCluster(dc *v1alpha1.DMCluster) error
 	return errorutils.NewAggregate(errs)
 }
 
+func (c *defaultDMClusterControl) syncDeletionProtection(dc *v1alpha1.DMCluster) (bool, error) {
+	protected := controller.DeletionProtected(dc.Spec.DeletionProtection, c.deletionProtection)
+	return controller.SyncDeletionProtection(dc, "DMCluster", protected, c.recorder, func(finalizers []string) error {
+		// patch the finalizers only, as the spec of dc is defaulted in reconciliation
+		patch := map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"finalizers":      finalizers,
+				"resourceVersion": dc.ResourceVersion,
+			},
+		}
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+		if _, err := c.dcControl.Patch(dc, data); err != nil {
+			return fmt.Errorf("patch finalizers of dmcluster %s/%s failed, err: %v", dc.Namespace, dc.Name, err)
+		}
+		dc.Finalizers = finalizers
+		return nil
+	})
+}
+
 func (c *defaultDMClusterControl) defaulting(dc *v1alpha1.DMCluster) {
 	defaulting.SetDMClusterDefault(dc)
 }
@@ -114,7 +149,7 @@ func (c *defaultDMClusterControl) validate(dc *v1alpha1.DMCluster) bool {
 	if len(errs) > 0 {
 		aggregatedErr := errs.ToAggregate()
 		klog.Errorf("dm cluster %s/%s is not valid and must be fixed first, aggregated error: %v", dc.GetNamespace(), dc.GetName(), aggregatedErr)
-		c.recorder.Event(dc, v1.EventTypeWarning, "FailedValidation", aggregatedErr.Error())
+		c.recorder.Event(dc, v1.EventTypeWarning, events.FailedValidation, aggregatedErr.Error())
 		return false
 	}
 	return true
@@ -17,12 +17,15 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned/fake"
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mm "github.com/pingcap/tidb-operator/pkg/manager/member"
 	"github.com/pingcap/tidb-operator/pkg/manager/meta"
 	apps "k8s.io/api/apps/v1"
@@ -31,6 +34,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 )
 
 func TestTidbClusterControlUpdateTidbCluster(t *testing.T) {
@@ -237,6 +241,46 @@ func TestDMClusterStatusEquality(t *testing.T) {
 	g.Expect(apiequality.Semantic.DeepEqual(&dcStatus, tcStatusCopy)).To(Equal(false))
 }
 
+func TestDMClusterControlDeletionProtection(t *testing.T) {
+	g := NewGomegaWithT(t)
+	control, _, _, masterMemberManager, _, _, _ := newFakeDMClusterControl()
+	dcControl := control.(*defaultDMClusterControl)
+	recorder := dcControl.recorder.(*record.FakeRecorder)
+	// the components are not synced while the sync stops at the deletion protection
+	masterMemberManager.SetSyncError(fmt.Errorf("dm-master synced"))
+
+	// the operator-level default is off
+	dc := newDMClusterForDMClusterControl()
+	g.Expect(control.UpdateDMCluster(dc)).To(MatchError(ContainSubstring("dm-master synced")))
+	g.Expect(dc.Finalizers).To(BeEmpty())
+	dc.Spec.DeletionProtection = pointer.BoolPtr(true)
+	g.Expect(control.UpdateDMCluster(dc)).To(Succeed())
+	g.Expect(dc.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+
+	// the operator-level default is on
+	dcControl.deletionProtection = true
+	dc = newDMClusterForDMClusterControl()
+	g.Expect(control.UpdateDMCluster(dc)).To(Succeed())
+	g.Expect(dc.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+	g.Expect(control.UpdateDMCluster(dc)).To(MatchError(ContainSubstring("dm-master synced")))
+
+	// the deletion is blocked without the confirmation, the components keep running
+	dc.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	g.Expect(control.UpdateDMCluster(dc)).To(Succeed())
+	g.Expect(dc.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DeletionProtected))
+	// the finalizer is kept even if the dmcluster is not protected any more
+	dc.Spec.DeletionProtection = pointer.BoolPtr(false)
+	g.Expect(control.UpdateDMCluster(dc)).To(Succeed())
+	g.Expect(dc.Finalizers).To(Equal([]string{label.DeletionProtectionFinalizer}))
+	g.Expect(<-recorder.Events).To(ContainSubstring(label.AnnConfirmDeletionKey))
+
+	// the deletion is confirmed
+	dc.Annotations = map[string]string{label.AnnConfirmDeletionKey: dc.Name}
+	g.Expect(control.UpdateDMCluster(dc)).To(Succeed())
+	g.Expect(dc.Finalizers).To(BeEmpty())
+}
+
 func newFakeDMClusterControl() (
 	ControlInterface,
 	*meta.FakeReclaimPolicyManager,
@@ -268,6 +312,7 @@ func newFakeDMClusterControl() (
 		discoveryManager,
 		&dmClusterConditionUpdater{},
 		recorder,
+		false,
 	)
 
 	return control, reclaimPolicyManager, orphanPodCleaner, masterMemberManager, workerMemberManager, pvcCleaner, dcControl
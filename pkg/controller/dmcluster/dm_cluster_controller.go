@@ -27,7 +27,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -57,6 +56,7 @@ func NewController(deps *controller.Dependencies) *Controller {
 			mm.NewTidbDiscoveryManager(deps),
 			&dmClusterConditionUpdater{},
 			deps.Recorder,
+			deps.CLIConfig.DeletionProtection,
 		),
 		queue: workqueue.NewNamedRateLimitingQueue(
 			controller.NewControllerRateLimiter(1*time.Second, 100*time.Second),
@@ -91,19 +91,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting dmcluster controller")
 	defer klog.Info("Shutting down dmcluster controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.DMClusterControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *Controller) processNextWorkItem() bool {
@@ -22,6 +22,7 @@ import (
 	tcinformers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions/pingcap/v1alpha1"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -32,6 +33,7 @@ import (
 // DMClusterControlInterface manages DMClusters
 type DMClusterControlInterface interface {
 	UpdateDMCluster(*v1alpha1.DMCluster, *v1alpha1.DMClusterStatus, *v1alpha1.DMClusterStatus) (*v1alpha1.DMCluster, error)
+	Patch(dc *v1alpha1.DMCluster, data []byte, subresources ...string) (result *v1alpha1.DMCluster, err error)
 }
 
 type realDMClusterControl struct {
@@ -84,6 +86,18 @@ func (c *realDMClusterControl) UpdateDMCluster(dc *v1alpha1.DMCluster, newStatus
 	return updateDC, err
 }
 
+func (c *realDMClusterControl) Patch(dc *v1alpha1.DMCluster, data []byte, subresources ...string) (result *v1alpha1.DMCluster, err error) {
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var patchErr error
+		_, patchErr = c.cli.PingcapV1alpha1().DMClusters(dc.Namespace).Patch(context.TODO(), dc.Name, types.MergePatchType, data, metav1.PatchOptions{}, subresources...)
+		return patchErr
+	})
+	if err != nil {
+		klog.Errorf("failed to patch DMCluster: [%s/%s], error: %v", dc.Namespace, dc.Name, err)
+	}
+	return dc, err
+}
+
 // FakeDMClusterControl is a fake DMClusterControlInterface
 type FakeDMClusterControl struct {
 	DcLister               listers.DMClusterLister
@@ -115,3 +129,8 @@ func (c *FakeDMClusterControl) UpdateDMCluster(dc *v1alpha1.DMCluster, _ *v1alph
 
 	return dc, c.DcIndexer.Update(dc)
 }
+
+// Patch patches the DMCluster
+func (c *FakeDMClusterControl) Patch(dc *v1alpha1.DMCluster, data []byte, subresources ...string) (result *v1alpha1.DMCluster, err error) {
+	return dc, nil
+}
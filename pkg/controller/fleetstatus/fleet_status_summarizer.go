@@ -0,0 +1,212 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fleetstatus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// refreshInterval is the interval to refresh the fleet summary
+	refreshInterval = time.Minute
+	// maxWorstOffenders is the max number of the worst offenders kept in the summary
+	maxWorstOffenders = 10
+
+	// UnknownVersion is the version of the clusters without spec.version
+	UnknownVersion = "unknown"
+	// NotReadyReason is the reason of the offenders whose Ready condition is not true
+	NotReadyReason = "NotReady"
+)
+
+// NamespaceSummary is the rollup of the clusters in a namespace
+type NamespaceSummary struct {
+	Clusters      int
+	ByPhase       map[v1alpha1.MemberPhase]int
+	ByReady       map[corev1.ConditionStatus]int
+	ByVersion     map[string]int
+	FailedBackups int
+}
+
+// Offender is a cluster which has been unhealthy
+type Offender struct {
+	Namespace string
+	Name      string
+	Reason    string
+	Since     time.Time
+}
+
+// FleetSummary is the rollup of all clusters managed by the operator
+type FleetSummary struct {
+	Namespaces map[string]*NamespaceSummary
+	// WorstOffenders are the clusters unhealthy for the longest time, bounded by maxWorstOffenders
+	WorstOffenders []Offender
+}
+
+// Summarizer periodically summarizes the clusters in the informer cache into metrics
+type Summarizer struct {
+	deps *controller.Dependencies
+	now  func() time.Time
+}
+
+// NewSummarizer returns a Summarizer
+func NewSummarizer(deps *controller.Dependencies) *Summarizer {
+	return &Summarizer{
+		deps: deps,
+		now:  time.Now,
+	}
+}
+
+// Run refreshes the fleet summary every refreshInterval until stopCh is closed.
+// The workers is ignored and only here to be run as other controllers.
+func (s *Summarizer) Run(_ int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+
+	klog.Info("Starting fleet status summarizer")
+	defer klog.Info("Shutting down fleet status summarizer")
+
+	wait.Until(s.refresh, refreshInterval, stopCh)
+}
+
+func (s *Summarizer) refresh() {
+	tcs, err := s.deps.TiDBClusterLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("fleet status summarizer: failed to list TidbClusters: %v", err)
+		return
+	}
+	backups, err := s.deps.BackupLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("fleet status summarizer: failed to list Backups: %v", err)
+		return
+	}
+	setMetrics(Summarize(tcs, backups, s.now(), maxWorstOffenders), s.now())
+}
+
+// Summarize rolls up the clusters and backups by namespace and picks at most maxOffenders
+// clusters which have been unhealthy for the longest time.
+func Summarize(tcs []*v1alpha1.TidbCluster, backups []*v1alpha1.Backup, now time.Time, maxOffenders int) *FleetSummary {
+	summary := &FleetSummary{
+		Namespaces: map[string]*NamespaceSummary{},
+	}
+	namespace := func(ns string) *NamespaceSummary {
+		if _, ok := summary.Namespaces[ns]; !ok {
+			summary.Namespaces[ns] = &NamespaceSummary{
+				ByPhase:   map[v1alpha1.MemberPhase]int{},
+				ByReady:   map[corev1.ConditionStatus]int{},
+				ByVersion: map[string]int{},
+			}
+		}
+		return summary.Namespaces[ns]
+	}
+
+	for _, tc := range tcs {
+		ns := namespace(tc.Namespace)
+		ns.Clusters++
+		ns.ByPhase[clusterPhase(tc)]++
+
+		version := tc.Spec.Version
+		if version == "" {
+			version = UnknownVersion
+		}
+		ns.ByVersion[version]++
+
+		ready := corev1.ConditionUnknown
+		since := tc.CreationTimestamp.Time
+		if cond := utiltidbcluster.GetTidbClusterReadyCondition(tc.Status); cond != nil {
+			ready = cond.Status
+			if !cond.LastTransitionTime.IsZero() {
+				since = cond.LastTransitionTime.Time
+			}
+		}
+		ns.ByReady[ready]++
+		if ready != corev1.ConditionTrue {
+			summary.WorstOffenders = append(summary.WorstOffenders, Offender{
+				Namespace: tc.Namespace,
+				Name:      tc.Name,
+				Reason:    NotReadyReason,
+				Since:     since,
+			})
+		}
+	}
+
+	for _, backup := range backups {
+		if v1alpha1.IsBackupFailed(backup) {
+			namespace(backup.Namespace).FailedBackups++
+		}
+	}
+
+	sort.SliceStable(summary.WorstOffenders, func(i, j int) bool {
+		a, b := summary.WorstOffenders[i], summary.WorstOffenders[j]
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	if len(summary.WorstOffenders) > maxOffenders {
+		summary.WorstOffenders = summary.WorstOffenders[:maxOffenders]
+	}
+	return summary
+}
+
+// clusterPhase returns the phase of the cluster, Upgrade takes precedence over Scale
+// if there are components in both phases.
+func clusterPhase(tc *v1alpha1.TidbCluster) v1alpha1.MemberPhase {
+	phase := v1alpha1.NormalPhase
+	for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+		switch status.GetPhase() {
+		case v1alpha1.UpgradePhase:
+			return v1alpha1.UpgradePhase
+		case v1alpha1.ScalePhase:
+			phase = v1alpha1.ScalePhase
+		}
+	}
+	return phase
+}
+
+func setMetrics(summary *FleetSummary, now time.Time) {
+	metrics.FleetClustersByPhase.Reset()
+	metrics.FleetClustersByReady.Reset()
+	metrics.FleetClustersByVersion.Reset()
+	metrics.FleetFailedBackups.Reset()
+	metrics.FleetWorstOffenders.Reset()
+
+	for ns, s := range summary.Namespaces {
+		for phase, n := range s.ByPhase {
+			metrics.FleetClustersByPhase.WithLabelValues(ns, string(phase)).Set(float64(n))
+		}
+		for ready, n := range s.ByReady {
+			metrics.FleetClustersByReady.WithLabelValues(ns, string(ready)).Set(float64(n))
+		}
+		for version, n := range s.ByVersion {
+			metrics.FleetClustersByVersion.WithLabelValues(ns, version).Set(float64(n))
+		}
+		metrics.FleetFailedBackups.WithLabelValues(ns).Set(float64(s.FailedBackups))
+	}
+	for _, o := range summary.WorstOffenders {
+		metrics.FleetWorstOffenders.WithLabelValues(o.Namespace, o.Name, o.Reason).Set(now.Sub(o.Since).Seconds())
+	}
+}
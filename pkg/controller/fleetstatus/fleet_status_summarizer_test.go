@@ -0,0 +1,164 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fleetstatus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSummarize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tcs := []*v1alpha1.TidbCluster{
+		newTidbCluster("ns1", "normal", "v5.4.0", corev1.ConditionTrue, now.Add(-time.Hour)),
+		newTidbCluster("ns1", "upgrading", "v5.4.0", corev1.ConditionFalse, now.Add(-10*time.Minute), func(tc *v1alpha1.TidbCluster) {
+			tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+			tc.Status.PD.Phase = v1alpha1.ScalePhase
+		}),
+		newTidbCluster("ns1", "scaling", "v5.3.0", corev1.ConditionTrue, now.Add(-time.Hour), func(tc *v1alpha1.TidbCluster) {
+			tc.Status.TiDB.Phase = v1alpha1.ScalePhase
+		}),
+		newTidbCluster("ns2", "degraded", "", corev1.ConditionFalse, now.Add(-2*time.Hour)),
+		newTidbCluster("ns2", "new", "v5.4.0", "", now.Add(-time.Minute)),
+	}
+	backups := []*v1alpha1.Backup{
+		newBackup("ns1", "failed", v1alpha1.BackupFailed),
+		newBackup("ns1", "complete", v1alpha1.BackupComplete),
+		newBackup("ns3", "failed", v1alpha1.BackupFailed),
+	}
+
+	summary := Summarize(tcs, backups, now, 10)
+	g.Expect(summary.Namespaces).To(HaveLen(3))
+
+	ns1 := summary.Namespaces["ns1"]
+	g.Expect(ns1.Clusters).To(Equal(3))
+	g.Expect(ns1.ByPhase).To(Equal(map[v1alpha1.MemberPhase]int{
+		v1alpha1.NormalPhase:  1,
+		v1alpha1.UpgradePhase: 1,
+		v1alpha1.ScalePhase:   1,
+	}))
+	g.Expect(ns1.ByReady).To(Equal(map[corev1.ConditionStatus]int{
+		corev1.ConditionTrue:  2,
+		corev1.ConditionFalse: 1,
+	}))
+	g.Expect(ns1.ByVersion).To(Equal(map[string]int{"v5.4.0": 2, "v5.3.0": 1}))
+	g.Expect(ns1.FailedBackups).To(Equal(1))
+
+	ns2 := summary.Namespaces["ns2"]
+	g.Expect(ns2.Clusters).To(Equal(2))
+	g.Expect(ns2.ByReady).To(Equal(map[corev1.ConditionStatus]int{
+		corev1.ConditionFalse:   1,
+		corev1.ConditionUnknown: 1,
+	}))
+	g.Expect(ns2.ByVersion).To(Equal(map[string]int{UnknownVersion: 1, "v5.4.0": 1}))
+	g.Expect(ns2.FailedBackups).To(Equal(0))
+
+	// namespace with backups only
+	g.Expect(summary.Namespaces["ns3"].Clusters).To(Equal(0))
+	g.Expect(summary.Namespaces["ns3"].FailedBackups).To(Equal(1))
+
+	// the offenders are sorted by the time they became unhealthy
+	g.Expect(summary.WorstOffenders).To(Equal([]Offender{
+		{Namespace: "ns2", Name: "degraded", Reason: NotReadyReason, Since: now.Add(-2 * time.Hour)},
+		{Namespace: "ns1", Name: "upgrading", Reason: NotReadyReason, Since: now.Add(-10 * time.Minute)},
+		{Namespace: "ns2", Name: "new", Reason: NotReadyReason, Since: now.Add(-time.Minute)},
+	}))
+
+	// the offenders are bounded
+	summary = Summarize(tcs, backups, now, 1)
+	g.Expect(summary.WorstOffenders).To(HaveLen(1))
+	g.Expect(summary.WorstOffenders[0].Name).To(Equal("degraded"))
+}
+
+func TestSummarizerRefresh(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	deps := controller.NewFakeDependencies()
+	s := NewSummarizer(deps)
+	s.now = func() time.Time { return now }
+
+	tcIndexer := deps.InformerFactory.Pingcap().V1alpha1().TidbClusters().Informer().GetIndexer()
+	for i := 0; i < 3; i++ {
+		tc := newTidbCluster("ns", fmt.Sprintf("tc-%d", i), "v5.4.0", corev1.ConditionTrue, now)
+		g.Expect(tcIndexer.Add(tc)).To(Succeed())
+	}
+	tc := newTidbCluster("ns", "tc-unready", "v5.4.0", corev1.ConditionFalse, now.Add(-time.Minute))
+	g.Expect(tcIndexer.Add(tc)).To(Succeed())
+
+	s.refresh()
+	g.Expect(testutil.ToFloat64(metrics.FleetClustersByReady.WithLabelValues("ns", "True"))).To(Equal(float64(3)))
+	g.Expect(testutil.ToFloat64(metrics.FleetClustersByReady.WithLabelValues("ns", "False"))).To(Equal(float64(1)))
+	g.Expect(testutil.ToFloat64(metrics.FleetClustersByVersion.WithLabelValues("ns", "v5.4.0"))).To(Equal(float64(4)))
+	g.Expect(testutil.ToFloat64(metrics.FleetWorstOffenders.WithLabelValues("ns", "tc-unready", NotReadyReason))).To(Equal(float64(60)))
+}
+
+func newTidbCluster(ns, name, version string, ready corev1.ConditionStatus, since time.Time, changes ...func(*v1alpha1.TidbCluster)) *v1alpha1.TidbCluster {
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         ns,
+			Name:              name,
+			CreationTimestamp: metav1.Time{Time: since},
+		},
+		Spec: v1alpha1.TidbClusterSpec{
+			Version: version,
+			PD:      &v1alpha1.PDSpec{},
+			TiKV:    &v1alpha1.TiKVSpec{},
+			TiDB:    &v1alpha1.TiDBSpec{},
+		},
+		Status: v1alpha1.TidbClusterStatus{
+			PD:   v1alpha1.PDStatus{Phase: v1alpha1.NormalPhase},
+			TiKV: v1alpha1.TiKVStatus{Phase: v1alpha1.NormalPhase},
+			TiDB: v1alpha1.TiDBStatus{Phase: v1alpha1.NormalPhase},
+		},
+	}
+	if ready != "" {
+		tc.Status.Conditions = []v1alpha1.TidbClusterCondition{
+			{
+				Type:               v1alpha1.TidbClusterReady,
+				Status:             ready,
+				LastTransitionTime: metav1.Time{Time: since},
+			},
+		}
+	}
+	for _, change := range changes {
+		change(tc)
+	}
+	return tc
+}
+
+func newBackup(ns, name string, condType v1alpha1.BackupConditionType) *v1alpha1.Backup {
+	return &v1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ns,
+			Name:      name,
+		},
+		Status: v1alpha1.BackupStatus{
+			Conditions: []v1alpha1.BackupCondition{
+				{Type: condType, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -73,12 +74,12 @@ func (c *realGeneralPVCControl) recordPVCEvent(verb string, obj runtime.Object,
 	instanceName := pvc.GetLabels()[label.InstanceLabelKey]
 	kind := obj.GetObjectKind().GroupVersionKind().Kind
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PVC %s/%s for %s/%s successful",
 			strings.ToLower(verb), ns, pvcName, kind, instanceName)
 		c.recorder.Event(obj, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PVC %s/%s for %s/%s failed error: %s",
 			strings.ToLower(verb), ns, pvcName, kind, instanceName, err)
 		c.recorder.Event(obj, corev1.EventTypeWarning, reason, msg)
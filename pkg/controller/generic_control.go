@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/scheme"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -56,6 +57,8 @@ type TypedControlInterface interface {
 	CreateOrUpdateService(controller client.Object, svc *corev1.Service) (*corev1.Service, error)
 	// CreateOrUpdateDeployment create the desired deployment or update the current one to desired state if already existed
 	CreateOrUpdateDeployment(controller client.Object, deploy *appsv1.Deployment) (*appsv1.Deployment, error)
+	// CreateOrUpdateDaemonSet create the desired daemonset or update the current one to desired state if already existed
+	CreateOrUpdateDaemonSet(controller client.Object, ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error)
 	// CreateOrUpdatePVC create the desired pvc or update the current one to desired state if already existed
 	CreateOrUpdatePVC(controller client.Object, pvc *corev1.PersistentVolumeClaim, setOwnerFlag bool) (*corev1.PersistentVolumeClaim, error)
 	// CreateOrUpdateIngress create the desired ingress or update the current one to desired state if already existed
@@ -200,6 +203,22 @@ func (w *typedWrapper) CreateOrUpdateDeployment(controller client.Object, deploy
 	return result.(*appsv1.Deployment), err
 }
 
+func (w *typedWrapper) CreateOrUpdateDaemonSet(controller client.Object, ds *appsv1.DaemonSet) (*appsv1.DaemonSet, error) {
+	result, err := w.GenericControlInterface.CreateOrUpdate(controller, ds, func(existing, desired client.Object) error {
+		existingDS := existing.(*appsv1.DaemonSet)
+		desiredDS := desired.(*appsv1.DaemonSet)
+
+		existingDS.Labels = desiredDS.Labels
+		// the selector is immutable, the pod template is fully owned by the controller
+		existingDS.Spec.Template = desiredDS.Spec.Template
+		return nil
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*appsv1.DaemonSet), nil
+}
+
 func (w *typedWrapper) CreateOrUpdateRole(controller client.Object, role *rbacv1.Role) (*rbacv1.Role, error) {
 	result, err := w.GenericControlInterface.CreateOrUpdate(controller, role, func(existing, desired client.Object) error {
 		existingRole := existing.(*rbacv1.Role)
@@ -252,6 +271,9 @@ func (w *typedWrapper) CreateOrUpdateConfigMap(controller client.Object, cm *cor
 
 		existingCm.Data = desiredCm.Data
 		existingCm.Labels = desiredCm.Labels
+		if existingCm.Annotations == nil && len(desiredCm.Annotations) > 0 {
+			existingCm.Annotations = map[string]string{}
+		}
 		for k, v := range desiredCm.Annotations {
 			existingCm.Annotations[k] = v
 		}
@@ -543,14 +565,14 @@ func (c *realGenericControlInterface) RecordControllerEvent(verb string, control
 		name = accessor.GetObjectMeta().GetName()
 	}
 	if err == nil {
-		reason := fmt.Sprintf("Successfully %s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s %s/%s for controller %s/%s successfully",
 			strings.ToLower(verb),
 			objGVK.Kind, name,
 			controllerGVK.Kind, controllerName)
 		c.recorder.Event(controller, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed to %s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s %s/%s for controller %s/%s failed, error: %s",
 			strings.ToLower(verb),
 			objGVK.Kind, name,
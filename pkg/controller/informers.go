@@ -0,0 +1,188 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// DefaultPodSelector selects the pods created by tidb-operator, including the pods of the backup and restore Jobs
+	DefaultPodSelector = label.ManagedByLabelKey + " in (" + label.TiDBOperator + ",backup-operator,restore-operator)"
+
+	// kubectlLastAppliedConfigAnnotation is set by `kubectl apply` and is never read by the controllers
+	kubectlLastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// tweakListOptionsBySelector returns a func to add the selector to the label selector of the list options
+func tweakListOptionsBySelector(selector string) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		if len(selector) == 0 {
+			return
+		}
+		if len(options.LabelSelector) > 0 {
+			options.LabelSelector += "," + selector
+		} else {
+			options.LabelSelector = selector
+		}
+	}
+}
+
+// registerKubeInformers registers the informers of the pods, secrets and configmaps to the informer factories,
+// so that only the objects matching the selectors are cached, and the fields never read by the controllers
+// are dropped before the objects are cached. It must be called before the informers are got from the factories.
+func registerKubeInformers(cliCfg *CLIConfig, namespace string, kubeInformerFactory, labelFilterKubeInformerFactory kubeinformers.SharedInformerFactory) {
+	podSelector := tweakListOptionsBySelector(cliCfg.PodSelector)
+	kubeInformerFactory.InformerFor(&corev1.Pod{}, func(cli kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return newStrippedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				podSelector(&options)
+				return cli.CoreV1().Pods(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				podSelector(&options)
+				return cli.CoreV1().Pods(namespace).Watch(context.TODO(), options)
+			},
+		}, &corev1.Pod{}, resync)
+	})
+
+	secretSelector := tweakListOptionsBySelector(cliCfg.SecretSelector)
+	kubeInformerFactory.InformerFor(&corev1.Secret{}, func(cli kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return newStrippedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				secretSelector(&options)
+				return cli.CoreV1().Secrets(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				secretSelector(&options)
+				return cli.CoreV1().Secrets(namespace).Watch(context.TODO(), options)
+			},
+		}, &corev1.Secret{}, resync)
+	})
+
+	// the configmaps are always filtered by the label of tidb-operator as before
+	configMapSelector := tweakListOptionsBySelector(label.ManagedByLabelKey + "=" + label.TiDBOperator)
+	labelFilterKubeInformerFactory.InformerFor(&corev1.ConfigMap{}, func(cli kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return newStrippedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				configMapSelector(&options)
+				return cli.CoreV1().ConfigMaps(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				configMapSelector(&options)
+				return cli.CoreV1().ConfigMaps(namespace).Watch(context.TODO(), options)
+			},
+		}, &corev1.ConfigMap{}, resync)
+	})
+}
+
+// newStrippedInformer returns an informer which strips the objects before they are cached.
+// It wraps the ListerWatcher as client-go v0.19 does not support the transform func of the informers.
+func newStrippedInformer(lw *cache.ListWatch, obj runtime.Object, resync time.Duration) cache.SharedIndexInformer {
+	listFunc, watchFunc := lw.ListFunc, lw.WatchFunc
+	lw.ListFunc = func(options metav1.ListOptions) (runtime.Object, error) {
+		list, err := listFunc(options)
+		if err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(item runtime.Object) error {
+			stripObject(item)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	lw.WatchFunc = func(options metav1.ListOptions) (watch.Interface, error) {
+		w, err := watchFunc(options)
+		if err != nil {
+			return nil, err
+		}
+		return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+			if event.Type != watch.Error {
+				stripObject(event.Object)
+			}
+			return event, true
+		}), nil
+	}
+	return cache.NewSharedIndexInformer(lw, obj, resync, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// stripObject drops the managed fields and the large annotations never read by the controllers
+func stripObject(obj runtime.Object) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	accessor.SetManagedFields(nil)
+	if anns := accessor.GetAnnotations(); anns != nil {
+		if _, ok := anns[kubectlLastAppliedConfigAnnotation]; ok {
+			delete(anns, kubectlLastAppliedConfigAnnotation)
+			accessor.SetAnnotations(anns)
+		}
+	}
+}
+
+// registerOperatorConfigMapsInformer registers the informer of the ConfigMaps configuring tidb-operator, e.g. the
+// ConfigMaps set by -upgrade-freeze-configmap and -tier-config-configmap, to the informer factory whatever the
+// namespace of the factory is. The factory has only one informer of ConfigMaps, so the ConfigMaps must be in the
+// same namespace. Only the ConfigMap is cached if there is one, or all the ConfigMaps in the namespace are cached.
+func registerOperatorConfigMapsInformer(kubeInformerFactory kubeinformers.SharedInformerFactory, keys ...string) error {
+	namespace := ""
+	names := []string{}
+	for _, key := range keys {
+		ns, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil || ns == "" || name == "" {
+			return fmt.Errorf("invalid configmap %q, it must be in the form of <namespace>/<name>", key)
+		}
+		if namespace != "" && ns != namespace {
+			return fmt.Errorf("configmaps %v configuring tidb-operator must be in the same namespace", keys)
+		}
+		namespace = ns
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	nameSelector := ""
+	if len(names) == 1 {
+		nameSelector = fields.OneTermEqualSelector("metadata.name", names[0]).String()
+	}
+	kubeInformerFactory.InformerFor(&corev1.ConfigMap{}, func(cli kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return newStrippedInformer(&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = nameSelector
+				return cli.CoreV1().ConfigMaps(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = nameSelector
+				return cli.CoreV1().ConfigMaps(namespace).Watch(context.TODO(), options)
+			},
+		}, &corev1.ConfigMap{}, resync)
+	})
+	return nil
+}
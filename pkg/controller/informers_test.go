@@ -0,0 +1,157 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegisterKubeInformers(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newMeta := func(name string, l map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:          name,
+			Namespace:     metav1.NamespaceDefault,
+			Labels:        l,
+			Annotations:   map[string]string{kubectlLastAppliedConfigAnnotation: "{}", "foo": "bar"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		}
+	}
+	managed := label.New().Instance("demo").Labels()
+	backup := label.NewBackup().Instance("demo").Labels()
+	unmanaged := map[string]string{"app": "other"}
+
+	// a workload with many pods, secrets and configmaps not managed by tidb-operator
+	var objects []runtime.Object
+	for i := 0; i < 3; i++ {
+		objects = append(objects,
+			&corev1.Pod{ObjectMeta: newMeta(fmt.Sprintf("managed-%d", i), managed)},
+			&corev1.Secret{ObjectMeta: newMeta(fmt.Sprintf("managed-%d", i), managed)},
+			&corev1.ConfigMap{ObjectMeta: newMeta(fmt.Sprintf("managed-%d", i), managed)},
+		)
+	}
+	objects = append(objects, &corev1.Pod{ObjectMeta: newMeta("backup", backup)})
+	for i := 0; i < 10; i++ {
+		objects = append(objects,
+			&corev1.Pod{ObjectMeta: newMeta(fmt.Sprintf("unmanaged-%d", i), unmanaged)},
+			&corev1.Secret{ObjectMeta: newMeta(fmt.Sprintf("unmanaged-%d", i), unmanaged)},
+			&corev1.ConfigMap{ObjectMeta: newMeta(fmt.Sprintf("unmanaged-%d", i), unmanaged)},
+		)
+	}
+
+	tests := []struct {
+		name           string
+		secretSelector string
+		podSelector    string
+		expectPods     int
+		expectSecrets  int
+	}{
+		{
+			name:          "default selectors",
+			podSelector:   DefaultPodSelector,
+			expectPods:    4,
+			expectSecrets: 13,
+		},
+		{
+			name:          "no selectors",
+			expectPods:    14,
+			expectSecrets: 13,
+		},
+		{
+			name:           "custom selectors",
+			podSelector:    label.ManagedByLabelKey + "=" + label.TiDBOperator,
+			secretSelector: "app=other",
+			expectPods:     3,
+			expectSecrets:  10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cliCfg := DefaultCLIConfig()
+			cliCfg.PodSelector = tt.podSelector
+			cliCfg.SecretSelector = tt.secretSelector
+			kubeCli := kubefake.NewSimpleClientset(objects...)
+			kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, 0)
+			labelFilterKubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, 0)
+			registerKubeInformers(cliCfg, metav1.NamespaceAll, kubeInformerFactory, labelFilterKubeInformerFactory)
+
+			podLister := kubeInformerFactory.Core().V1().Pods().Lister()
+			secretLister := kubeInformerFactory.Core().V1().Secrets().Lister()
+			configMapLister := labelFilterKubeInformerFactory.Core().V1().ConfigMaps().Lister()
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			kubeInformerFactory.Start(stopCh)
+			labelFilterKubeInformerFactory.Start(stopCh)
+			kubeInformerFactory.WaitForCacheSync(stopCh)
+			labelFilterKubeInformerFactory.WaitForCacheSync(stopCh)
+
+			pods, err := podLister.List(labels.Everything())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(pods).To(HaveLen(tt.expectPods))
+			secrets, err := secretLister.List(labels.Everything())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(secrets).To(HaveLen(tt.expectSecrets))
+			configMaps, err := configMapLister.List(labels.Everything())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(configMaps).To(HaveLen(3))
+
+			// the managed fields and the large annotations are not cached
+			for _, pod := range pods {
+				g.Expect(pod.ManagedFields).To(BeEmpty())
+				g.Expect(pod.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+			}
+			for _, secret := range secrets {
+				g.Expect(secret.ManagedFields).To(BeEmpty())
+			}
+			for _, cm := range configMaps {
+				g.Expect(cm.ManagedFields).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestRegisterUpgradeFreezeInformer(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	kubeCli := kubefake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "maintenance"},
+		Data:       map[string]string{DefaultUpgradeFreezeConfigMapKey: UpgradeFrozenValue},
+	})
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, 0)
+	g.Expect(registerOperatorConfigMapsInformer(kubeInformerFactory, "maintenance")).NotTo(Succeed())
+	g.Expect(registerOperatorConfigMapsInformer(kubeInformerFactory, "ops/maintenance", "default/tiers")).NotTo(Succeed())
+	g.Expect(registerOperatorConfigMapsInformer(kubeInformerFactory, "ops/maintenance")).To(Succeed())
+
+	deps := &Dependencies{
+		CLIConfig:                    &CLIConfig{UpgradeFreezeConfigMap: "ops/maintenance", UpgradeFreezeConfigMapKey: DefaultUpgradeFreezeConfigMapKey},
+		UpgradeFreezeConfigMapLister: kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	kubeInformerFactory.Start(stopCh)
+	kubeInformerFactory.WaitForCacheSync(stopCh)
+	g.Expect(IsUpgradeFrozen(deps)).To(BeTrue())
+}
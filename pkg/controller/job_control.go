@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -95,12 +96,12 @@ func (c *realJobControl) recordJobEvent(verb string, obj runtime.Object, job *ba
 	instanceName := job.GetLabels()[label.InstanceLabelKey]
 	kind := obj.GetObjectKind().GroupVersionKind().Kind
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s job %s/%s for cluster %s %s successful",
 			strings.ToLower(verb), ns, jobName, instanceName, strings.ToLower(kind))
 		c.recorder.Event(obj, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s job %s/%s for cluster %s %s failed error: %s",
 			strings.ToLower(verb), ns, jobName, instanceName, strings.ToLower(kind), err)
 		c.recorder.Event(obj, corev1.EventTypeWarning, reason, msg)
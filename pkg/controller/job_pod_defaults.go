@@ -0,0 +1,59 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SetJobPodDefaults sets the default tolerations and node selector configured by
+// `--job-tolerations` and `--job-node-selector` to the pod of a Job created by tidb-operator,
+// e.g. backup, restore, clean and initializer Jobs. The defaults are only used if the
+// pod has no tolerations or node selector from its CR.
+func SetJobPodDefaults(cfg *CLIConfig, podSpec *corev1.PodSpec) {
+	if len(podSpec.Tolerations) == 0 && len(cfg.JobTolerations) > 0 {
+		podSpec.Tolerations = make([]corev1.Toleration, len(cfg.JobTolerations))
+		for i := range cfg.JobTolerations {
+			cfg.JobTolerations[i].DeepCopyInto(&podSpec.Tolerations[i])
+		}
+	}
+	if len(podSpec.NodeSelector) == 0 && len(cfg.JobNodeSelector) > 0 {
+		podSpec.NodeSelector = make(map[string]string, len(cfg.JobNodeSelector))
+		for k, v := range cfg.JobNodeSelector {
+			podSpec.NodeSelector[k] = v
+		}
+	}
+}
+
+// jsonValue is a flag.Value parsing the flag as JSON into the value
+type jsonValue struct {
+	value interface{}
+}
+
+func (v *jsonValue) String() string {
+	if v.value == nil {
+		return ""
+	}
+	data, err := json.Marshal(v.value)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
+
+func (v *jsonValue) Set(s string) error {
+	return json.Unmarshal([]byte(s), v.value)
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetJobPodDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfg := DefaultCLIConfig()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&jsonValue{value: &cfg.JobTolerations}, "job-tolerations", "")
+	fs.Var(&jsonValue{value: &cfg.JobNodeSelector}, "job-node-selector", "")
+	err := fs.Parse([]string{
+		`-job-tolerations=[{"key":"dedicated","operator":"Equal","value":"backup","effect":"NoSchedule"}]`,
+		`-job-node-selector={"dedicated":"backup"}`,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(fs.Lookup("job-node-selector").Value.String()).To(Equal(`{"dedicated":"backup"}`))
+
+	defaultTolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "backup", Effect: corev1.TaintEffectNoSchedule}}
+	defaultNodeSelector := map[string]string{"dedicated": "backup"}
+	g.Expect(cfg.JobTolerations).To(Equal(defaultTolerations))
+	g.Expect(cfg.JobNodeSelector).To(Equal(defaultNodeSelector))
+
+	type testcase struct {
+		name                 string
+		podSpec              corev1.PodSpec
+		expectedTolerations  []corev1.Toleration
+		expectedNodeSelector map[string]string
+	}
+	tests := []testcase{
+		{
+			name:                 "use defaults",
+			podSpec:              corev1.PodSpec{},
+			expectedTolerations:  defaultTolerations,
+			expectedNodeSelector: defaultNodeSelector,
+		},
+		{
+			name: "override by CR",
+			podSpec: corev1.PodSpec{
+				Tolerations:  []corev1.Toleration{{Key: "cr", Operator: corev1.TolerationOpExists}},
+				NodeSelector: map[string]string{"cr": "true"},
+			},
+			expectedTolerations:  []corev1.Toleration{{Key: "cr", Operator: corev1.TolerationOpExists}},
+			expectedNodeSelector: map[string]string{"cr": "true"},
+		},
+		{
+			name: "override tolerations only",
+			podSpec: corev1.PodSpec{
+				Tolerations: []corev1.Toleration{{Key: "cr", Operator: corev1.TolerationOpExists}},
+			},
+			expectedTolerations:  []corev1.Toleration{{Key: "cr", Operator: corev1.TolerationOpExists}},
+			expectedNodeSelector: defaultNodeSelector,
+		},
+	}
+
+	for _, test := range tests {
+		t.Log(test.name)
+		podSpec := test.podSpec
+		SetJobPodDefaults(cfg, &podSpec)
+		g.Expect(podSpec.Tolerations).To(Equal(test.expectedTolerations))
+		g.Expect(podSpec.NodeSelector).To(Equal(test.expectedNodeSelector))
+	}
+
+	// the defaults are copied
+	podSpec := corev1.PodSpec{}
+	SetJobPodDefaults(cfg, &podSpec)
+	podSpec.NodeSelector["foo"] = "bar"
+	g.Expect(cfg.JobNodeSelector).To(Equal(defaultNodeSelector))
+
+	// nothing is set without defaults
+	podSpec = corev1.PodSpec{}
+	SetJobPodDefaults(DefaultCLIConfig(), &podSpec)
+	g.Expect(podSpec.Tolerations).To(BeNil())
+	g.Expect(podSpec.NodeSelector).To(BeNil())
+}
@@ -0,0 +1,508 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/manager/member"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// recheckInterval is the interval the progress of the nodes in maintenance is checked
+const recheckInterval = 15 * time.Second
+
+// Phase is the phase of the maintenance of a node
+type Phase string
+
+const (
+	// PhasePending means the node waits for the maintenance of the other nodes limited by
+	// -node-maintenance-concurrency
+	PhasePending Phase = "Pending"
+	// PhaseDraining means the pods on the node are being drained
+	PhaseDraining Phase = "Draining"
+	// PhaseCompleted means all the pods on the node are drained, the node can be drained by the external
+	// automation safely
+	PhaseCompleted Phase = "Completed"
+)
+
+// Status is the progress of the maintenance of a node, it is in the annotation tidb.pingcap.com/maintenance-status
+// of the node in JSON
+type Status struct {
+	Phase     Phase        `json:"phase"`
+	Message   string       `json:"message,omitempty"`
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	Pods      []PodStatus  `json:"pods,omitempty"`
+}
+
+// PodStatus is the progress of draining a pod on the node in maintenance
+type PodStatus struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid"`
+	Cluster   string    `json:"cluster"`
+	Component string    `json:"component"`
+	Drained   bool      `json:"drained"`
+	Message   string    `json:"message,omitempty"`
+	// StartTime is the time the pod starts to be drained
+	StartTime metav1.Time `json:"startTime"`
+	// EvictLeaderAdded is whether the evict-leader annotation of the TiKV pod is added by the maintenance, it is
+	// removed if the maintenance is cancelled
+	EvictLeaderAdded bool `json:"evictLeaderAdded,omitempty"`
+}
+
+// Controller drains the pods of the TidbClusters on the nodes annotated with tidb.pingcap.com/maintenance=drain by
+// the safe-removal procedure of each component: the PD leader is transferred to a member on another node, the
+// region leaders of TiKV are evicted and the connections of TiDB are drained. The progress is reported in the
+// annotation tidb.pingcap.com/maintenance-status of the node, whose phase becomes Completed once all the pods
+// are drained. All the state is kept in the annotations of the nodes and the pods, so that the maintenance
+// continues after tidb-controller-manager restarts.
+type Controller struct {
+	deps        *controller.Dependencies
+	queue       workqueue.RateLimitingInterface
+	concurrency int
+
+	mu sync.Mutex
+	// admitted are the nodes started draining by this instance, the status of which may not be in the
+	// informer cache yet
+	admitted sets.String
+}
+
+// NewController returns a Controller, it requires the node permission
+func NewController(deps *controller.Dependencies) *Controller {
+	c := &Controller{
+		deps: deps,
+		queue: workqueue.NewNamedRateLimitingQueue(
+			controller.NewControllerRateLimiter(1*time.Second, 100*time.Second),
+			"node maintenance",
+		),
+		concurrency: deps.CLIConfig.NodeMaintenanceConcurrency,
+		admitted:    sets.NewString(),
+	}
+
+	nodeInformer := deps.KubeInformerFactory.Core().V1().Nodes()
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueNode,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueNode(cur)
+		},
+	})
+	return c
+}
+
+func (c *Controller) enqueueNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	_, requested := node.Annotations[label.AnnNodeMaintenance]
+	_, reported := node.Annotations[label.AnnNodeMaintenanceStatus]
+	if !requested && !reported {
+		return
+	}
+	c.queue.Add(node.Name)
+}
+
+// Run the controller.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.Info("Starting node maintenance controller")
+	defer klog.Info("Shutting down node maintenance controller")
+
+	c.deps.WorkerPool.Run(controller.NodeMaintenanceControllerName, workers, c.processNextWorkItem, stopCh)
+
+	<-stopCh
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	result, err := c.sync(key.(string))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Node maintenance: %v, sync failed %v, requeuing", key.(string), err))
+		c.queue.AddRateLimited(key)
+	} else if result.RequeueAfter > 0 {
+		c.queue.Forget(key)
+		c.queue.AddAfter(key, result.RequeueAfter)
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+func (c *Controller) sync(name string) (reconcile.Result, error) {
+	node, err := c.deps.NodeLister.Get(name)
+	if errors.IsNotFound(err) {
+		c.release(name)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	status, err := getStatus(node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if node.Annotations[label.AnnNodeMaintenance] != label.AnnNodeMaintenanceDrainVal {
+		if status == nil {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, c.cancel(node, status)
+	}
+
+	if status == nil {
+		status = &Status{Phase: PhasePending}
+	}
+	if status.Phase == PhaseCompleted {
+		c.release(name)
+		return reconcile.Result{}, nil
+	}
+
+	if status.Phase == PhasePending {
+		if inMaintenance, ok := c.admit(name); !ok {
+			status.Message = fmt.Sprintf("waiting for the maintenance of %d nodes: %s", len(inMaintenance), strings.Join(inMaintenance, ", "))
+			return reconcile.Result{RequeueAfter: recheckInterval}, c.updateStatus(node, status)
+		}
+		now := metav1.Now()
+		status.Phase = PhaseDraining
+		status.StartTime = &now
+		c.deps.Recorder.Event(node, corev1.EventTypeNormal, events.NodeMaintenanceStarted, "start draining the pods of the TidbClusters on the node")
+	}
+
+	if err := c.drain(node, status); err != nil {
+		if uerr := c.updateStatus(node, status); uerr != nil {
+			klog.Warningf("node maintenance: failed to update the status of node %s, error: %v", name, uerr)
+		}
+		return reconcile.Result{}, err
+	}
+	if status.Phase == PhaseCompleted {
+		c.release(name)
+		c.deps.Recorder.Event(node, corev1.EventTypeNormal, events.NodeMaintenanceCompleted,
+			fmt.Sprintf("all the %d pods of the TidbClusters on the node are drained", len(status.Pods)))
+		return reconcile.Result{}, c.updateStatus(node, status)
+	}
+	return reconcile.Result{RequeueAfter: recheckInterval}, c.updateStatus(node, status)
+}
+
+// admit returns whether the node is allowed to start draining by the concurrency, and the nodes in maintenance
+func (c *Controller) admit(name string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	inMaintenance := sets.NewString(c.admitted.List()...)
+	nodes, err := c.deps.NodeLister.List(labels.Everything())
+	if err != nil {
+		klog.Warningf("node maintenance: failed to list nodes, error: %v", err)
+		return nil, false
+	}
+	for _, node := range nodes {
+		if node.Annotations[label.AnnNodeMaintenance] != label.AnnNodeMaintenanceDrainVal {
+			continue
+		}
+		if status, err := getStatus(node); err == nil && status != nil && status.Phase == PhaseDraining {
+			inMaintenance.Insert(node.Name)
+		}
+	}
+	if inMaintenance.Has(name) || inMaintenance.Len() < c.concurrency {
+		c.admitted.Insert(name)
+		return nil, true
+	}
+	return inMaintenance.List(), false
+}
+
+func (c *Controller) release(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.admitted.Delete(name)
+}
+
+// drain drains the pods on the node one by one, the status is completed once all of them are drained
+func (c *Controller) drain(node *corev1.Node, status *Status) error {
+	pods, err := c.listPods(node)
+	if err != nil {
+		return err
+	}
+	onNode := map[types.UID]*corev1.Pod{}
+	for _, pod := range pods {
+		onNode[pod.UID] = pod
+		if findPodStatus(status, pod) == nil {
+			status.Pods = append(status.Pods, PodStatus{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+				UID:       pod.UID,
+				Cluster:   pod.Labels[label.InstanceLabelKey],
+				Component: pod.Labels[label.ComponentLabelKey],
+				StartTime: metav1.Now(),
+			})
+		}
+	}
+
+	drained := 0
+	for i := range status.Pods {
+		ps := &status.Pods[i]
+		pod, ok := onNode[ps.UID]
+		if !ok && !ps.Drained {
+			ps.Drained = true
+			ps.Message = "the pod is removed from the node"
+		}
+		if !ps.Drained {
+			if err := c.drainPod(node, pod, ps); err != nil {
+				return fmt.Errorf("node maintenance: failed to drain pod %s/%s on node %s, error: %v", ps.Namespace, ps.Name, node.Name, err)
+			}
+		}
+		if ps.Drained {
+			drained++
+		}
+	}
+	status.Message = fmt.Sprintf("%d/%d pods are drained", drained, len(status.Pods))
+	if drained == len(status.Pods) {
+		status.Phase = PhaseCompleted
+	}
+	return nil
+}
+
+// listPods returns the pods of the TidbClusters on the node
+func (c *Controller) listPods(node *corev1.Node) ([]*corev1.Pod, error) {
+	pods, err := c.deps.PodLister.List(labels.SelectorFromSet(label.New().Labels()))
+	if err != nil {
+		return nil, fmt.Errorf("node maintenance: failed to list pods, error: %v", err)
+	}
+	var result []*corev1.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName == node.Name && pod.Labels[label.InstanceLabelKey] != "" {
+			result = append(result, pod)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// drainPod runs a step of the safe-removal procedure of the component of the pod
+func (c *Controller) drainPod(node *corev1.Node, pod *corev1.Pod, ps *PodStatus) error {
+	if pod.DeletionTimestamp != nil {
+		ps.Drained, ps.Message = true, "the pod is being deleted"
+		return nil
+	}
+	tc, err := c.deps.TiDBClusterLister.TidbClusters(pod.Namespace).Get(ps.Cluster)
+	if errors.IsNotFound(err) {
+		ps.Drained, ps.Message = true, "the TidbCluster is not found"
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]interface{}{}
+	if pod.Annotations[label.AnnPodNodeMaintenance] != node.Name {
+		annotations[label.AnnPodNodeMaintenance] = node.Name
+	}
+	if _, ok := pod.Annotations[v1alpha1.EvictLeaderAnnKey]; !ok && ps.Component == label.TiKVLabelVal {
+		annotations[v1alpha1.EvictLeaderAnnKey] = v1alpha1.EvictLeaderValueNone
+		ps.EvictLeaderAdded = true
+	}
+	if len(annotations) > 0 {
+		if err := c.patchPodAnnotations(pod, annotations); err != nil {
+			return err
+		}
+	}
+
+	switch ps.Component {
+	case label.PDLabelVal:
+		ps.Drained, ps.Message = c.transferPDLeader(node, tc, pod)
+	case label.TiKVLabelVal:
+		ps.Drained, ps.Message = c.evictTiKVLeaders(tc, pod)
+	case label.TiDBLabelVal:
+		if _, ok := pod.Labels[label.TiDBWarmStandbyLabelKey]; ok {
+			ps.Drained, ps.Message = true, "the warm standby pod is not drained"
+			return nil
+		}
+		ps.Drained, ps.Message, err = member.DrainTiDBPodConnections(c.deps, tc, pod, ps.StartTime.Time)
+		return err
+	default:
+		ps.Drained, ps.Message = true, fmt.Sprintf("%s has no safe-removal procedure", ps.Component)
+	}
+	return nil
+}
+
+// transferPDLeader transfers the PD leader to a healthy member on another node if the pod is the leader
+func (c *Controller) transferPDLeader(node *corev1.Node, tc *v1alpha1.TidbCluster, pod *corev1.Pod) (bool, string) {
+	pdClient := controller.GetPDClient(c.deps.PDControl, tc)
+	leader, err := pdClient.GetPDLeader()
+	if err != nil {
+		return false, fmt.Sprintf("failed to get the pd leader: %v", err)
+	}
+	if pdMemberPodName(leader.GetName()) != pod.Name {
+		return true, "the pd member is not the leader"
+	}
+
+	var names []string
+	for name := range tc.Status.PD.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		podName := pdMemberPodName(name)
+		if !tc.Status.PD.Members[name].Health || podName == pod.Name {
+			continue
+		}
+		if target, err := c.deps.PodLister.Pods(pod.Namespace).Get(podName); err != nil || target.Spec.NodeName == node.Name {
+			continue
+		}
+		if err := pdClient.TransferPDLeader(name); err != nil {
+			return false, fmt.Sprintf("failed to transfer the pd leader to %s: %v", name, err)
+		}
+		return false, fmt.Sprintf("transferring the pd leader to %s", name)
+	}
+	return false, "no healthy pd member on other nodes to transfer the leader to"
+}
+
+// evictTiKVLeaders waits for the region leaders of the TiKV pod to be evicted by the evict-leader annotation
+func (c *Controller) evictTiKVLeaders(tc *v1alpha1.TidbCluster, pod *corev1.Pod) (bool, string) {
+	_, statusPort := tc.TiKVPorts()
+	kvClient := c.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, pod.Name, statusPort, tc.IsTLSClusterEnabled())
+	count, err := kvClient.GetLeaderCount()
+	if err != nil {
+		return false, fmt.Sprintf("failed to get the leader count: %v", err)
+	}
+	if count > 0 {
+		return false, fmt.Sprintf("%d region leaders left", count)
+	}
+	return true, "all the region leaders are evicted"
+}
+
+// cancel reverts the annotations of the pods added by the maintenance and removes the status from the node
+func (c *Controller) cancel(node *corev1.Node, status *Status) error {
+	for _, ps := range status.Pods {
+		pod, err := c.deps.PodLister.Pods(ps.Namespace).Get(ps.Name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if pod.UID != ps.UID {
+			continue
+		}
+		annotations := map[string]interface{}{}
+		if _, ok := pod.Annotations[label.AnnPodNodeMaintenance]; ok {
+			annotations[label.AnnPodNodeMaintenance] = nil
+		}
+		if _, ok := pod.Annotations[v1alpha1.EvictLeaderAnnKey]; ok && ps.EvictLeaderAdded {
+			annotations[v1alpha1.EvictLeaderAnnKey] = nil
+		}
+		if len(annotations) == 0 {
+			continue
+		}
+		if err := c.patchPodAnnotations(pod, annotations); err != nil {
+			return err
+		}
+	}
+
+	if err := c.patchNodeAnnotations(node, map[string]interface{}{label.AnnNodeMaintenanceStatus: nil}); err != nil {
+		return err
+	}
+	c.release(node.Name)
+	if status.Phase == PhaseDraining {
+		c.deps.Recorder.Event(node, corev1.EventTypeNormal, events.NodeMaintenanceCancelled, "the pods of the TidbClusters on the node are not drained any more")
+	}
+	klog.Infof("node maintenance: the maintenance of node %s is ended", node.Name)
+	return nil
+}
+
+func (c *Controller) updateStatus(node *corev1.Node, status *Status) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if node.Annotations[label.AnnNodeMaintenanceStatus] == string(data) {
+		return nil
+	}
+	return c.patchNodeAnnotations(node, map[string]interface{}{label.AnnNodeMaintenanceStatus: string(data)})
+}
+
+func (c *Controller) patchNodeAnnotations(node *corev1.Node, annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	_, err = c.deps.KubeClientset.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (c *Controller) patchPodAnnotations(pod *corev1.Pod, annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	_, err = c.deps.KubeClientset.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// getStatus returns the status of the maintenance of the node, nil if it is not reported
+func getStatus(node *corev1.Node) (*Status, error) {
+	data, ok := node.Annotations[label.AnnNodeMaintenanceStatus]
+	if !ok {
+		return nil, nil
+	}
+	status := &Status{}
+	if err := json.Unmarshal([]byte(data), status); err != nil {
+		return nil, fmt.Errorf("node maintenance: failed to parse the status of node %s, error: %v", node.Name, err)
+	}
+	return status, nil
+}
+
+func findPodStatus(status *Status, pod *corev1.Pod) *PodStatus {
+	for i := range status.Pods {
+		if status.Pods[i].UID == pod.UID {
+			return &status.Pods[i]
+		}
+	}
+	return nil
+}
+
+// pdMemberPodName returns the name of the pod of the PD member, the member may be named by the FQDN of the pod
+func pdMemberPodName(name string) string {
+	return strings.SplitN(name, ".", 2)[0]
+}
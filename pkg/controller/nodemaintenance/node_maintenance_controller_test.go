@@ -0,0 +1,289 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodemaintenance
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/pingcap/tidb-operator/pkg/tikvapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeCluster struct {
+	g           *GomegaWithT
+	deps        *controller.Dependencies
+	tc          *v1alpha1.TidbCluster
+	pdLeader    string
+	leaderCount map[string]int
+}
+
+func newFakeCluster(g *GomegaWithT, concurrency int) *fakeCluster {
+	deps := controller.NewFakeDependencies()
+	deps.CLIConfig.NodeMaintenanceConcurrency = concurrency
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+		Spec: v1alpha1.TidbClusterSpec{
+			PD:   &v1alpha1.PDSpec{},
+			TiKV: &v1alpha1.TiKVSpec{},
+			TiDB: &v1alpha1.TiDBSpec{},
+		},
+		Status: v1alpha1.TidbClusterStatus{
+			PD: v1alpha1.PDStatus{
+				Members: map[string]v1alpha1.PDMember{
+					"test-pd-0": {Name: "test-pd-0", Health: true},
+					"test-pd-1": {Name: "test-pd-1", Health: true},
+				},
+			},
+		},
+	}
+	g.Expect(deps.InformerFactory.Pingcap().V1alpha1().TidbClusters().Informer().GetIndexer().Add(tc)).To(Succeed())
+
+	fc := &fakeCluster{g: g, deps: deps, tc: tc, pdLeader: "test-pd-0", leaderCount: map[string]int{}}
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetPDLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdpb.Member{Name: fc.pdLeader}, nil
+	})
+	pdClient.AddReaction(pdapi.TransferPDLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+		fc.pdLeader = action.Name
+		return nil, nil
+	})
+	return fc
+}
+
+func (fc *fakeCluster) addNode(name string, annotations map[string]string) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+	_, err := fc.deps.KubeClientset.CoreV1().Nodes().Create(context.TODO(), node, metav1.CreateOptions{})
+	fc.g.Expect(err).NotTo(HaveOccurred())
+}
+
+func (fc *fakeCluster) addPod(name, component, nodeName string, annotations map[string]string) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   metav1.NamespaceDefault,
+			UID:         types.UID(name),
+			Labels:      label.New().Instance("test").Component(component).Labels(),
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{NodeName: nodeName},
+	}
+	_, err := fc.deps.KubeClientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	if component == label.TiKVLabelVal {
+		kvClient := tikvapi.NewFakeTiKVClient()
+		kvClient.AddReaction(tikvapi.GetLeaderCountActionType, func(action *tikvapi.Action) (interface{}, error) {
+			return fc.leaderCount[name], nil
+		})
+		fc.deps.TiKVControl.(*tikvapi.FakeTiKVControl).SetTiKVPodClient(pod.Namespace, "test", name, kvClient)
+	}
+}
+
+// refresh syncs the nodes and the pods in the informer cache with the fake clientset
+func (fc *fakeCluster) refresh() {
+	ctx := context.TODO()
+	nodes, err := fc.deps.KubeClientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	for i := range nodes.Items {
+		fc.g.Expect(fc.deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer().Update(&nodes.Items[i])).To(Succeed())
+	}
+	pods, err := fc.deps.KubeClientset.CoreV1().Pods(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	for i := range pods.Items {
+		fc.g.Expect(fc.deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer().Update(&pods.Items[i])).To(Succeed())
+	}
+}
+
+func (fc *fakeCluster) sync(c *Controller, name string) {
+	fc.refresh()
+	_, err := c.sync(name)
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	fc.refresh()
+}
+
+func (fc *fakeCluster) status(name string) *Status {
+	node, err := fc.deps.NodeLister.Get(name)
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	status, err := getStatus(node)
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	return status
+}
+
+func (fc *fakeCluster) podStatus(node, pod string) PodStatus {
+	for _, ps := range fc.status(node).Pods {
+		if ps.Name == pod {
+			return ps
+		}
+	}
+	fc.g.Expect(false).To(BeTrue(), "pod %s is not in the status of node %s", pod, node)
+	return PodStatus{}
+}
+
+func (fc *fakeCluster) podAnnotations(name string) map[string]string {
+	pod, err := fc.deps.PodLister.Pods(metav1.NamespaceDefault).Get(name)
+	fc.g.Expect(err).NotTo(HaveOccurred())
+	return pod.Annotations
+}
+
+func collectEvents(source <-chan string) []string {
+	done := false
+	recorded := make([]string, 0)
+	for !done {
+		select {
+		case event := <-source:
+			recorded = append(recorded, event)
+		default:
+			done = true
+		}
+	}
+	return recorded
+}
+
+func TestNodeMaintenanceDrain(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fc := newFakeCluster(g, 1)
+	c := NewController(fc.deps)
+	recorder := fc.deps.Recorder.(*record.FakeRecorder)
+	drain := map[string]string{label.AnnNodeMaintenance: label.AnnNodeMaintenanceDrainVal}
+	fc.addNode("node-1", drain)
+	fc.addNode("node-2", nil)
+	fc.addPod("test-pd-0", label.PDLabelVal, "node-1", nil)
+	fc.addPod("test-pd-1", label.PDLabelVal, "node-2", nil)
+	fc.addPod("test-tikv-0", label.TiKVLabelVal, "node-1", nil)
+	fc.addPod("test-tidb-0", label.TiDBLabelVal, "node-1", nil)
+	fc.addPod("test-ticdc-0", label.TiCDCLabelVal, "node-1", nil)
+	fc.leaderCount["test-tikv-0"] = 10
+
+	// the nodes not in maintenance are skipped
+	fc.sync(c, "node-2")
+	g.Expect(fc.status("node-2")).To(BeNil())
+
+	fc.sync(c, "node-1")
+	status := fc.status("node-1")
+	g.Expect(status.Phase).To(Equal(PhaseDraining))
+	g.Expect(status.Pods).To(HaveLen(4))
+	g.Expect(status.Message).To(Equal("2/4 pods are drained"))
+	g.Expect(fc.pdLeader).To(Equal("test-pd-1"))
+	g.Expect(fc.podStatus("node-1", "test-pd-0").Drained).To(BeFalse())
+	g.Expect(fc.podStatus("node-1", "test-pd-0").Message).To(Equal("transferring the pd leader to test-pd-1"))
+	g.Expect(fc.podStatus("node-1", "test-tikv-0").Drained).To(BeFalse())
+	g.Expect(fc.podStatus("node-1", "test-tikv-0").Message).To(Equal("10 region leaders left"))
+	g.Expect(fc.podStatus("node-1", "test-tikv-0").EvictLeaderAdded).To(BeTrue())
+	g.Expect(fc.podStatus("node-1", "test-tidb-0").Drained).To(BeTrue())
+	g.Expect(fc.podStatus("node-1", "test-ticdc-0").Drained).To(BeTrue())
+	g.Expect(fc.podAnnotations("test-tikv-0")).To(Equal(map[string]string{
+		label.AnnPodNodeMaintenance: "node-1",
+		v1alpha1.EvictLeaderAnnKey:  v1alpha1.EvictLeaderValueNone,
+	}))
+	g.Expect(fc.podAnnotations("test-pd-1")).To(BeEmpty())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.NodeMaintenanceStarted))
+
+	// the leaders are moved off the node
+	fc.leaderCount["test-tikv-0"] = 0
+	fc.sync(c, "node-1")
+	status = fc.status("node-1")
+	g.Expect(status.Phase).To(Equal(PhaseCompleted))
+	g.Expect(status.Message).To(Equal("4/4 pods are drained"))
+	g.Expect(fc.podStatus("node-1", "test-pd-0").Message).To(Equal("the pd member is not the leader"))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.NodeMaintenanceCompleted))
+
+	// the completed maintenance is kept until the annotation is removed
+	fc.sync(c, "node-1")
+	g.Expect(fc.status("node-1").Phase).To(Equal(PhaseCompleted))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+}
+
+func TestNodeMaintenanceConcurrency(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fc := newFakeCluster(g, 1)
+	c := NewController(fc.deps)
+	drain := map[string]string{label.AnnNodeMaintenance: label.AnnNodeMaintenanceDrainVal}
+	fc.addNode("node-1", drain)
+	fc.addNode("node-2", drain)
+	fc.addPod("test-tikv-0", label.TiKVLabelVal, "node-1", nil)
+	fc.addPod("test-tikv-1", label.TiKVLabelVal, "node-2", nil)
+	fc.leaderCount["test-tikv-0"] = 10
+	fc.leaderCount["test-tikv-1"] = 10
+
+	fc.sync(c, "node-1")
+	fc.sync(c, "node-2")
+	g.Expect(fc.status("node-1").Phase).To(Equal(PhaseDraining))
+	g.Expect(fc.status("node-2").Phase).To(Equal(PhasePending))
+	g.Expect(fc.status("node-2").Message).To(Equal("waiting for the maintenance of 1 nodes: node-1"))
+	g.Expect(fc.podAnnotations("test-tikv-1")).To(BeEmpty())
+
+	// tidb-controller-manager restarts, the maintenance continues from the status on the nodes
+	c = NewController(fc.deps)
+	fc.sync(c, "node-2")
+	g.Expect(fc.status("node-2").Phase).To(Equal(PhasePending))
+	fc.sync(c, "node-1")
+	g.Expect(fc.status("node-1").Phase).To(Equal(PhaseDraining))
+	g.Expect(fc.podStatus("node-1", "test-tikv-0").EvictLeaderAdded).To(BeTrue())
+
+	fc.leaderCount["test-tikv-0"] = 0
+	fc.sync(c, "node-1")
+	g.Expect(fc.status("node-1").Phase).To(Equal(PhaseCompleted))
+	fc.sync(c, "node-2")
+	g.Expect(fc.status("node-2").Phase).To(Equal(PhaseDraining))
+	g.Expect(fc.podAnnotations("test-tikv-1")).To(HaveKeyWithValue(v1alpha1.EvictLeaderAnnKey, v1alpha1.EvictLeaderValueNone))
+}
+
+func TestNodeMaintenanceCancel(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fc := newFakeCluster(g, 1)
+	c := NewController(fc.deps)
+	recorder := fc.deps.Recorder.(*record.FakeRecorder)
+	fc.addNode("node-1", map[string]string{label.AnnNodeMaintenance: label.AnnNodeMaintenanceDrainVal})
+	fc.addPod("test-tikv-0", label.TiKVLabelVal, "node-1", nil)
+	// evicted by the user before the maintenance
+	fc.addPod("test-tikv-1", label.TiKVLabelVal, "node-1", map[string]string{v1alpha1.EvictLeaderAnnKey: v1alpha1.EvictLeaderValueNone})
+	fc.leaderCount["test-tikv-0"] = 10
+	fc.leaderCount["test-tikv-1"] = 10
+
+	fc.sync(c, "node-1")
+	g.Expect(fc.status("node-1").Phase).To(Equal(PhaseDraining))
+	g.Expect(fc.podStatus("node-1", "test-tikv-1").EvictLeaderAdded).To(BeFalse())
+	collectEvents(recorder.Events)
+
+	node, err := fc.deps.KubeClientset.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	delete(node.Annotations, label.AnnNodeMaintenance)
+	_, err = fc.deps.KubeClientset.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	fc.sync(c, "node-1")
+	g.Expect(fc.status("node-1")).To(BeNil())
+	g.Expect(fc.podAnnotations("test-tikv-0")).To(BeEmpty())
+	g.Expect(fc.podAnnotations("test-tikv-1")).To(Equal(map[string]string{v1alpha1.EvictLeaderAnnKey: v1alpha1.EvictLeaderValueNone}))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.NodeMaintenanceCancelled))
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,8 +40,14 @@ import (
 type PodControlInterface interface {
 	// TODO change this to UpdatePod
 	UpdateMetaInfo(*v1alpha1.TidbCluster, *corev1.Pod) (*corev1.Pod, error)
+	CreatePod(runtime.Object, *corev1.Pod) error
 	DeletePod(runtime.Object, *corev1.Pod) error
+	// ForceDeletePod deletes the Pod with the grace period 0, without waiting for the kubelet to confirm its
+	// containers are terminated
+	ForceDeletePod(runtime.Object, *corev1.Pod) error
 	UpdatePod(runtime.Object, *corev1.Pod) (*corev1.Pod, error)
+	// UpdatePodStatus updates the status of the Pod, e.g. the conditions of its readiness gates
+	UpdatePodStatus(runtime.Object, *corev1.Pod) (*corev1.Pod, error)
 }
 
 type realPodControl struct {
@@ -103,6 +110,26 @@ func (c *realPodControl) UpdatePod(controller runtime.Object, pod *corev1.Pod) (
 	return updatePod, err
 }
 
+// UpdatePodStatus does not retry on conflict, the caller recomputes the status from the latest Pod in the next sync
+func (c *realPodControl) UpdatePodStatus(controller runtime.Object, pod *corev1.Pod) (*corev1.Pod, error) {
+	controllerMo, ok := controller.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a metav1.Object, cannot call setControllerReference", controller)
+	}
+	kind := controller.GetObjectKind().GroupVersionKind().Kind
+	name := controllerMo.GetName()
+	namespace := controllerMo.GetNamespace()
+	podName := pod.GetName()
+
+	updatePod, err := c.kubeCli.CoreV1().Pods(namespace).UpdateStatus(context.TODO(), pod, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("failed to update the status of Pod: [%s/%s], error: %v", namespace, podName, err)
+		return nil, err
+	}
+	klog.Infof("Pod: [%s/%s] status updated successfully, %s: [%s/%s]", namespace, podName, kind, namespace, name)
+	return updatePod, nil
+}
+
 func (c *realPodControl) UpdateMetaInfo(tc *v1alpha1.TidbCluster, pod *corev1.Pod) (*corev1.Pod, error) {
 	ns := pod.GetNamespace()
 	podName := pod.GetName()
@@ -197,6 +224,26 @@ func (c *realPodControl) UpdateMetaInfo(tc *v1alpha1.TidbCluster, pod *corev1.Po
 	return updatePod, err
 }
 
+func (c *realPodControl) CreatePod(controller runtime.Object, pod *corev1.Pod) error {
+	controllerMo, ok := controller.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("%T is not a metav1.Object, cannot call setControllerReference", controller)
+	}
+	kind := controller.GetObjectKind().GroupVersionKind().Kind
+	name := controllerMo.GetName()
+	namespace := controllerMo.GetNamespace()
+
+	podName := pod.GetName()
+	_, err := c.kubeCli.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+	if err != nil {
+		klog.Errorf("failed to create Pod: [%s/%s], %s: %s, %v", namespace, podName, kind, name, err)
+	} else {
+		klog.V(4).Infof("create Pod: [%s/%s] successfully, %s: %s", namespace, podName, kind, name)
+	}
+	c.recordPodEvent("create", kind, name, controller, podName, err)
+	return err
+}
+
 func (c *realPodControl) DeletePod(controller runtime.Object, pod *corev1.Pod) error {
 	controllerMo, ok := controller.(metav1.Object)
 	if !ok {
@@ -211,9 +258,34 @@ func (c *realPodControl) DeletePod(controller runtime.Object, pod *corev1.Pod) e
 	deleteOptions := metav1.DeleteOptions{Preconditions: &preconditions}
 	err := c.kubeCli.CoreV1().Pods(namespace).Delete(context.TODO(), podName, deleteOptions)
 	if err != nil {
-		klog.Errorf("failed to delete Pod: [%s/%s], %s: %s, %v", namespace, podName, kind, namespace, err)
+		klog.Errorf("failed to delete Pod: [%s/%s], %s: %s, %v", namespace, podName, kind, name, err)
+	} else {
+		klog.V(4).Infof("delete Pod: [%s/%s] successfully, %s: %s", namespace, podName, kind, name)
+	}
+	c.recordPodEvent("delete", kind, name, controller, podName, err)
+	return err
+}
+
+func (c *realPodControl) ForceDeletePod(controller runtime.Object, pod *corev1.Pod) error {
+	controllerMo, ok := controller.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("%T is not a metav1.Object, cannot call setControllerReference", controller)
+	}
+	kind := controller.GetObjectKind().GroupVersionKind().Kind
+	name := controllerMo.GetName()
+	namespace := controllerMo.GetNamespace()
+
+	podName := pod.GetName()
+	var gracePeriod int64
+	// the pod stuck in Terminating is not updated by the kubelet, the precondition of its uid makes sure the
+	// pod recreated with the same name is not deleted
+	preconditions := metav1.Preconditions{UID: &pod.UID}
+	deleteOptions := metav1.DeleteOptions{Preconditions: &preconditions, GracePeriodSeconds: &gracePeriod}
+	err := c.kubeCli.CoreV1().Pods(namespace).Delete(context.TODO(), podName, deleteOptions)
+	if err != nil {
+		klog.Errorf("failed to force delete Pod: [%s/%s], %s: %s, %v", namespace, podName, kind, name, err)
 	} else {
-		klog.V(4).Infof("delete Pod: [%s/%s] successfully, %s: %s", namespace, podName, kind, namespace)
+		klog.V(4).Infof("force delete Pod: [%s/%s] successfully, %s: %s", namespace, podName, kind, name)
 	}
 	c.recordPodEvent("delete", kind, name, controller, podName, err)
 	return err
@@ -221,12 +293,12 @@ func (c *realPodControl) DeletePod(controller runtime.Object, pod *corev1.Pod) e
 
 func (c *realPodControl) recordPodEvent(verb, kind, name string, object runtime.Object, podName string, err error) {
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Pod %s in %s %s successful",
 			strings.ToLower(verb), podName, kind, name)
 		c.recorder.Event(object, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Pod %s in %s %s failed error: %s",
 			strings.ToLower(verb), podName, kind, name, err)
 		c.recorder.Event(object, corev1.EventTypeWarning, reason, msg)
@@ -250,6 +322,7 @@ var (
 type FakePodControl struct {
 	PodIndexer        cache.Indexer
 	updatePodTracker  RequestTracker
+	createPodTracker  RequestTracker
 	deletePodTracker  RequestTracker
 	getClusterTracker RequestTracker
 	getMemberTracker  RequestTracker
@@ -265,6 +338,7 @@ func NewFakePodControl(podInformer coreinformers.PodInformer) *FakePodControl {
 		RequestTracker{},
 		RequestTracker{},
 		RequestTracker{},
+		RequestTracker{},
 	}
 }
 
@@ -273,6 +347,11 @@ func (c *FakePodControl) SetUpdatePodError(err error, after int) {
 	c.updatePodTracker.SetError(err).SetAfter(after)
 }
 
+// SetCreatePodError sets the error attributes of createPodTracker
+func (c *FakePodControl) SetCreatePodError(err error, after int) {
+	c.createPodTracker.SetError(err).SetAfter(after)
+}
+
 // SetDeletePodError sets the error attributes of deletePodTracker
 func (c *FakePodControl) SetDeletePodError(err error, after int) {
 	c.deletePodTracker.SetError(err).SetAfter(after)
@@ -328,6 +407,16 @@ func (c *FakePodControl) UpdateMetaInfo(_ *v1alpha1.TidbCluster, pod *corev1.Pod
 	return pod, c.PodIndexer.Update(pod)
 }
 
+func (c *FakePodControl) CreatePod(_ runtime.Object, pod *corev1.Pod) error {
+	defer c.createPodTracker.Inc()
+	if c.createPodTracker.ErrorReady() {
+		defer c.createPodTracker.Reset()
+		return c.createPodTracker.GetError()
+	}
+
+	return c.PodIndexer.Add(pod)
+}
+
 func (c *FakePodControl) DeletePod(_ runtime.Object, pod *corev1.Pod) error {
 	defer c.deletePodTracker.Inc()
 	if c.deletePodTracker.ErrorReady() {
@@ -338,6 +427,16 @@ func (c *FakePodControl) DeletePod(_ runtime.Object, pod *corev1.Pod) error {
 	return c.PodIndexer.Delete(pod)
 }
 
+func (c *FakePodControl) ForceDeletePod(_ runtime.Object, pod *corev1.Pod) error {
+	defer c.deletePodTracker.Inc()
+	if c.deletePodTracker.ErrorReady() {
+		defer c.deletePodTracker.Reset()
+		return c.deletePodTracker.GetError()
+	}
+
+	return c.PodIndexer.Delete(pod)
+}
+
 func (c *FakePodControl) UpdatePod(_ runtime.Object, pod *corev1.Pod) (*corev1.Pod, error) {
 	defer c.updatePodTracker.Inc()
 	if c.updatePodTracker.ErrorReady() {
@@ -348,4 +447,14 @@ func (c *FakePodControl) UpdatePod(_ runtime.Object, pod *corev1.Pod) (*corev1.P
 	return pod, c.PodIndexer.Update(pod)
 }
 
+func (c *FakePodControl) UpdatePodStatus(_ runtime.Object, pod *corev1.Pod) (*corev1.Pod, error) {
+	defer c.updatePodTracker.Inc()
+	if c.updatePodTracker.ErrorReady() {
+		defer c.updatePodTracker.Reset()
+		return nil, c.updatePodTracker.GetError()
+	}
+
+	return pod, c.PodIndexer.Update(pod)
+}
+
 var _ PodControlInterface = &FakePodControl{}
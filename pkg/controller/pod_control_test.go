@@ -333,6 +333,41 @@ func TestPodControlUpdatePodConflictSuccess(t *testing.T) {
 	g.Expect(updatePod.Labels["a"]).To(Equal("b"))
 }
 
+func TestPodControlCreatePod(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTidbCluster()
+	pod := newPod(tc)
+	fakeClient, pdControl, podLister, _, recorder := newFakeClientRecorderAndPDControl()
+	control := NewRealPodControl(fakeClient, pdControl, podLister, recorder)
+	fakeClient.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		create := action.(core.CreateAction)
+		return true, create.GetObject(), nil
+	})
+	err := control.CreatePod(tc, pod)
+	g.Expect(err).To(Succeed())
+
+	events := collectEvents(recorder.Events)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0]).To(ContainSubstring(corev1.EventTypeNormal))
+}
+
+func TestPodControlCreatePodFailed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTidbCluster()
+	pod := newPod(tc)
+	fakeClient, pdControl, podLister, _, recorder := newFakeClientRecorderAndPDControl()
+	control := NewRealPodControl(fakeClient, pdControl, podLister, recorder)
+	fakeClient.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("API server down"))
+	})
+	err := control.CreatePod(tc, pod)
+	g.Expect(err).To(HaveOccurred())
+
+	events := collectEvents(recorder.Events)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0]).To(ContainSubstring(corev1.EventTypeWarning))
+}
+
 func newFakeClientRecorderAndPDControl() (*fake.Clientset, *pdapi.FakePDControl, corelisters.PodLister, cache.Indexer, *record.FakeRecorder) {
 	fakeClient := &fake.Clientset{}
 	kubeCli := kubefake.NewSimpleClientset()
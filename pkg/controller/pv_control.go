@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -210,12 +211,12 @@ func (c *realPVControl) UpdateMetaInfo(obj runtime.Object, pv *corev1.Persistent
 
 func (c *realPVControl) recordPVEvent(verb string, obj runtime.Object, objName, pvName string, err error) {
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PV %s in TidbCluster %s successful",
 			strings.ToLower(verb), pvName, objName)
 		c.recorder.Event(obj, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PV %s in TidbCluster %s failed error: %s",
 			strings.ToLower(verb), pvName, objName, err)
 		c.recorder.Event(obj, corev1.EventTypeWarning, reason, msg)
@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -208,12 +209,12 @@ func (c *realPVCControl) UpdateMetaInfo(controller runtime.Object, pvc *corev1.P
 
 func (c *realPVCControl) recordPVCEvent(verb, kind, name string, object runtime.Object, pvcName string, err error) {
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PVC %s in %s %s successful",
 			strings.ToLower(verb), pvcName, kind, name)
 		c.recorder.Event(object, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s PVC %s in %s %s failed error: %s",
 			strings.ToLower(verb), pvcName, kind, name, err)
 		c.recorder.Event(object, corev1.EventTypeWarning, reason, msg)
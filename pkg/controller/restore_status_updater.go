@@ -21,6 +21,8 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
 	informers "github.com/pingcap/tidb-operator/pkg/client/informers/externalversions/pingcap/v1alpha1"
 	listers "github.com/pingcap/tidb-operator/pkg/client/listers/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
@@ -39,6 +41,14 @@ type RestoreUpdateStatus struct {
 	TimeCompleted *metav1.Time
 	// CommitTs is the snapshot time point of tidb cluster.
 	CommitTs *string
+	// RestoredTables are the tables restored by the rename mapping with their target names.
+	RestoredTables []v1alpha1.RestoredTable
+	// AppliedClusterSettings are the keys of the cluster settings applied.
+	AppliedClusterSettings []string
+	// SkippedClusterSettings are the keys of the cluster settings excluded or failed to be applied.
+	SkippedClusterSettings []string
+	// FailureSummary is the exit diagnostics and the last lines of the log of the tool failed.
+	FailureSummary *string
 }
 
 // RestoreConditionUpdaterInterface enables updating Restore conditions.
@@ -89,6 +99,9 @@ func (u *realRestoreConditionUpdater) Update(restore *v1alpha1.Restore, conditio
 		}
 		return nil
 	})
+	if err == nil && newStatus != nil && newStatus.FailureSummary != nil && *newStatus.FailureSummary != "" {
+		u.recorder.Event(restore, corev1.EventTypeWarning, events.RestoreToolFailed, *newStatus.FailureSummary)
+	}
 	return err
 }
 
@@ -107,6 +120,18 @@ func updateRestoreStatus(status *v1alpha1.RestoreStatus, newStatus *RestoreUpdat
 	if newStatus.CommitTs != nil {
 		status.CommitTs = *newStatus.CommitTs
 	}
+	if newStatus.RestoredTables != nil {
+		status.RestoredTables = newStatus.RestoredTables
+	}
+	if newStatus.AppliedClusterSettings != nil {
+		status.AppliedClusterSettings = newStatus.AppliedClusterSettings
+	}
+	if newStatus.SkippedClusterSettings != nil {
+		status.SkippedClusterSettings = newStatus.SkippedClusterSettings
+	}
+	if newStatus.FailureSummary != nil {
+		status.FailureSummary = *newStatus.FailureSummary
+	}
 }
 
 var _ RestoreConditionUpdaterInterface = &realRestoreConditionUpdater{}
@@ -61,10 +61,12 @@ func newUpdateRestoreStatus() *RestoreUpdateStatus {
 	ts := "421762809912885269"
 	start, _ := time.Parse(time.RFC3339, "2020-12-25T21:46:59Z")
 	end, _ := time.Parse(time.RFC3339, "2020-12-25T21:50:59Z")
+	summary := "br exited with: exit status 1"
 	return &RestoreUpdateStatus{
-		CommitTs:      &ts,
-		TimeCompleted: &metav1.Time{Time: end},
-		TimeStarted:   &metav1.Time{Time: start},
+		CommitTs:       &ts,
+		TimeCompleted:  &metav1.Time{Time: end},
+		TimeStarted:    &metav1.Time{Time: start},
+		FailureSummary: &summary,
 	}
 }
 
@@ -101,5 +103,6 @@ func newExpectRestoreStatus() *v1alpha1.RestoreStatus {
 	s.CommitTs = ts
 	s.TimeStarted = metav1.Time{Time: start}
 	s.TimeCompleted = metav1.Time{Time: end}
+	s.FailureSummary = "br exited with: exit status 1"
 	return s
 }
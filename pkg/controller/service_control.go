@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -118,12 +119,12 @@ func (c *realServiceControl) DeleteService(controller runtime.Object, svc *corev
 func (c *realServiceControl) recordServiceEvent(verb, name, kind string, object runtime.Object, svc *corev1.Service, err error) {
 	svcName := svc.GetName()
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Service %s in %s %s successful",
 			strings.ToLower(verb), svcName, kind, name)
 		c.recorder.Event(object, corev1.EventTypeNormal, reason, msg)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		msg := fmt.Sprintf("%s Service %s in %s %s failed error: %s",
 			strings.ToLower(verb), svcName, kind, name, err)
 		c.recorder.Event(object, corev1.EventTypeWarning, reason, msg)
@@ -218,8 +219,14 @@ func (c *FakeServiceControl) UpdateService(_ runtime.Object, svc *corev1.Service
 }
 
 // DeleteService deletes the service of SvcIndexer
-func (c *FakeServiceControl) DeleteService(_ runtime.Object, _ *corev1.Service) error {
-	return nil
+func (c *FakeServiceControl) DeleteService(_ runtime.Object, svc *corev1.Service) error {
+	defer c.deleteStatefulSetTracker.Inc()
+	if c.deleteStatefulSetTracker.ErrorReady() {
+		defer c.deleteStatefulSetTracker.Reset()
+		return c.deleteStatefulSetTracker.GetError()
+	}
+
+	return c.SvcIndexer.Delete(svc)
 }
 
 var _ ServiceControlInterface = &FakeServiceControl{}
@@ -0,0 +1,180 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ShutdownStepEvictingLeader is the step of a TiKV pod whose leaders are being evicted before it is upgraded
+	ShutdownStepEvictingLeader = "EvictingLeader"
+)
+
+// ShutdownCheckpoint is the step a TidbCluster stops at when tidb-controller-manager shuts down, it is written to
+// the annotation label.AnnShutdownCheckpoint of the TidbCluster for the next leader to resume from
+type ShutdownCheckpoint struct {
+	Component v1alpha1.MemberType `json:"component"`
+	Pod       string              `json:"pod"`
+	StoreID   uint64              `json:"storeID,omitempty"`
+	Step      string              `json:"step"`
+	Time      metav1.Time         `json:"time"`
+}
+
+// GetShutdownCheckpoint returns the checkpoint written to the TidbCluster, it returns nil if there is no checkpoint
+func GetShutdownCheckpoint(tc *v1alpha1.TidbCluster) (*ShutdownCheckpoint, error) {
+	data, ok := tc.Annotations[label.AnnShutdownCheckpoint]
+	if !ok {
+		return nil, nil
+	}
+	cp := &ShutdownCheckpoint{}
+	if err := json.Unmarshal([]byte(data), cp); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %v", label.AnnShutdownCheckpoint, err)
+	}
+	return cp, nil
+}
+
+// ShutdownDrain tracks the syncs in flight so that they can finish their current step when tidb-controller-manager
+// shuts down. Once the drain starts, no sync starts and the syncs in flight check Draining between their steps to
+// stop at a step the next leader can resume from. A nil ShutdownDrain never drains.
+type ShutdownDrain struct {
+	mu          sync.Mutex
+	draining    bool
+	inflight    sets.String
+	checkpoints map[types.NamespacedName]ShutdownCheckpoint
+}
+
+// NewShutdownDrain returns a ShutdownDrain
+func NewShutdownDrain() *ShutdownDrain {
+	return &ShutdownDrain{
+		inflight:    sets.NewString(),
+		checkpoints: map[types.NamespacedName]ShutdownCheckpoint{},
+	}
+}
+
+// Begin marks the sync of the key starts, it returns false if the drain starts and the sync should not start
+func (d *ShutdownDrain) Begin(key string) bool {
+	if d == nil {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.inflight.Insert(key)
+	return true
+}
+
+// End marks the sync of the key is done
+func (d *ShutdownDrain) End(key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight.Delete(key)
+}
+
+// Draining returns whether tidb-controller-manager is shutting down
+func (d *ShutdownDrain) Draining() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// Checkpoint records the step the TidbCluster stops at, it is written to the TidbCluster after the drain
+func (d *ShutdownDrain) Checkpoint(tc *v1alpha1.TidbCluster, cp ShutdownCheckpoint) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cp.Time.IsZero() {
+		cp.Time = metav1.Now()
+	}
+	d.checkpoints[types.NamespacedName{Namespace: tc.Namespace, Name: tc.Name}] = cp
+}
+
+// Drain stops the syncs from starting and waits for the syncs in flight to finish in the grace period, it returns
+// the number of the syncs finished and the number of the syncs still running when the grace period elapses
+func (d *ShutdownDrain) Drain(gracePeriod time.Duration) (drained, aborted int) {
+	d.mu.Lock()
+	d.draining = true
+	total := d.inflight.Len()
+	d.mu.Unlock()
+
+	klog.Infof("draining %d syncs in flight in %s", total, gracePeriod)
+	_ = wait.PollImmediate(100*time.Millisecond, gracePeriod, func() (bool, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.inflight.Len() == 0, nil
+	})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	aborted = d.inflight.Len()
+	drained = total - aborted
+	if aborted > 0 {
+		klog.Warningf("the syncs of %v are aborted after the grace period %s", d.inflight.List(), gracePeriod)
+	}
+	metrics.ShutdownSyncs.WithLabelValues(metrics.ShutdownSyncDrained).Add(float64(drained))
+	metrics.ShutdownSyncs.WithLabelValues(metrics.ShutdownSyncAborted).Add(float64(aborted))
+	return drained, aborted
+}
+
+// WriteCheckpoints writes the checkpoints recorded during the drain to the TidbClusters
+func (d *ShutdownDrain) WriteCheckpoints(cli versioned.Interface) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, cp := range d.checkpoints {
+		data, err := json.Marshal(cp)
+		if err != nil {
+			klog.Errorf("failed to marshal the shutdown checkpoint of TidbCluster %s: %v", key, err)
+			continue
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{label.AnnShutdownCheckpoint: string(data)},
+			},
+		})
+		if err != nil {
+			klog.Errorf("failed to marshal the shutdown checkpoint patch of TidbCluster %s: %v", key, err)
+			continue
+		}
+		_, err = cli.PingcapV1alpha1().TidbClusters(key.Namespace).Patch(context.TODO(), key.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			klog.Errorf("failed to write the shutdown checkpoint of TidbCluster %s: %v", key, err)
+			continue
+		}
+		klog.Infof("wrote the shutdown checkpoint of TidbCluster %s: %s", key, data)
+	}
+}
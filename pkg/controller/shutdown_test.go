@@ -0,0 +1,71 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// a nil drain never drains
+	var nilDrain *ShutdownDrain
+	g.Expect(nilDrain.Begin("ns/tc")).To(BeTrue())
+	g.Expect(nilDrain.Draining()).To(BeFalse())
+
+	d := NewShutdownDrain()
+	g.Expect(d.Begin("ns/tc1")).To(BeTrue())
+	g.Expect(d.Begin("ns/tc2")).To(BeTrue())
+	g.Expect(d.Begin("ns/tc3")).To(BeTrue())
+	d.End("ns/tc3")
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		d.End("ns/tc1")
+	}()
+	drained, aborted := d.Drain(time.Second)
+	g.Expect(drained).To(Equal(1))
+	g.Expect(aborted).To(Equal(1))
+	g.Expect(d.Draining()).To(BeTrue())
+	g.Expect(d.Begin("ns/tc3")).To(BeFalse())
+}
+
+func TestGetShutdownCheckpoint(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := &v1alpha1.TidbCluster{}
+	cp, err := GetShutdownCheckpoint(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cp).To(BeNil())
+
+	tc.Annotations = map[string]string{label.AnnShutdownCheckpoint: `{"component":"tikv","pod":"tc-tikv-1","storeID":4,"step":"EvictingLeader","time":"2022-06-01T00:00:00Z"}`}
+	cp, err = GetShutdownCheckpoint(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cp.Component).To(Equal(v1alpha1.TiKVMemberType))
+	g.Expect(cp.Pod).To(Equal("tc-tikv-1"))
+	g.Expect(cp.StoreID).To(Equal(uint64(4)))
+	g.Expect(cp.Step).To(Equal(ShutdownStepEvictingLeader))
+	g.Expect(cp.Time.Equal(&metav1.Time{Time: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)})).To(BeTrue())
+
+	tc.Annotations[label.AnnShutdownCheckpoint] = "tikv"
+	_, err = GetShutdownCheckpoint(tc)
+	g.Expect(err).To(HaveOccurred())
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pingcap/tidb-operator/pkg/events"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -129,12 +130,12 @@ func (c *realStatefulSetControl) DeleteStatefulSet(controller runtime.Object, se
 func (c *realStatefulSetControl) recordStatefulSetEvent(verb, kind, name string, object runtime.Object, set *apps.StatefulSet, err error) {
 	setName := set.Name
 	if err == nil {
-		reason := fmt.Sprintf("Successful%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		message := fmt.Sprintf("%s StatefulSet %s in %s %s successful",
 			strings.ToLower(verb), setName, kind, name)
 		c.recorder.Event(object, corev1.EventTypeNormal, reason, message)
 	} else {
-		reason := fmt.Sprintf("Failed%s", strings.Title(verb))
+		reason := events.ResultReason(verb, err)
 		message := fmt.Sprintf("%s StatefulSet %s in %s %s failed error: %s",
 			strings.ToLower(verb), setName, kind, name, err)
 		c.recorder.Event(object, corev1.EventTypeWarning, reason, message)
@@ -14,10 +14,14 @@
 package controller
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
 	corelisterv1 "k8s.io/client-go/listers/core/v1"
 )
 
@@ -27,10 +31,41 @@ type CaptureStatus struct {
 	IsOwner bool   `json:"is_owner"`
 }
 
+// ProcessorInfo is a processor of a changefeed running on a capture
+type ProcessorInfo struct {
+	ChangefeedID string `json:"changefeed_id"`
+	CaptureID    string `json:"capture_id"`
+}
+
+// ProcessorDetail is the tables replicated by a processor
+type ProcessorDetail struct {
+	TableIDs []int64 `json:"table_ids"`
+}
+
+type drainCaptureRequest struct {
+	CaptureID string `json:"capture_id"`
+}
+
+type drainCaptureResponse struct {
+	CurrentTableCount int `json:"current_table_count"`
+}
+
 // TiCDCControlInterface is the interface that knows how to manage ticdc captures
 type TiCDCControlInterface interface {
 	// GetStatus returns ticdc's status
 	GetStatus(tc *v1alpha1.TidbCluster, ordinal int32) (*CaptureStatus, error)
+	// GetProcessors returns the processors of all the changefeeds in the cluster, the request
+	// is forwarded to the owner by the capture
+	GetProcessors(tc *v1alpha1.TidbCluster, ordinal int32) ([]ProcessorInfo, error)
+	// GetProcessorTableCount returns the number of the tables replicated by the processor of the
+	// changefeed on the capture
+	GetProcessorTableCount(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error)
+	// DrainCapture moves the tables replicated by the capture to the other captures and returns the
+	// number of the tables left on the capture. retry is true if the owner is not able to serve the
+	// request now, e.g. it is being elected. The table count is 0 if the drain API is not supported.
+	DrainCapture(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (tableCount int, retry bool, err error)
+	// ResignOwner makes the owner capture resign so that another capture becomes the owner
+	ResignOwner(tc *v1alpha1.TidbCluster, ordinal int32) error
 }
 
 // defaultTiCDCControl is default implementation of TiCDCControlInterface.
@@ -63,6 +98,95 @@ func (c *defaultTiCDCControl) GetStatus(tc *v1alpha1.TidbCluster, ordinal int32)
 	return &status, err
 }
 
+func (c *defaultTiCDCControl) GetProcessors(tc *v1alpha1.TidbCluster, ordinal int32) ([]ProcessorInfo, error) {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := c.getBaseURL(tc, ordinal)
+	url := fmt.Sprintf("%s/api/v1/processors", baseURL)
+	body, err := getBodyOK(httpClient, url)
+	if err != nil {
+		return nil, err
+	}
+
+	processors := []ProcessorInfo{}
+	err = json.Unmarshal(body, &processors)
+	return processors, err
+}
+
+func (c *defaultTiCDCControl) GetProcessorTableCount(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error) {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return 0, err
+	}
+
+	baseURL := c.getBaseURL(tc, ordinal)
+	url := fmt.Sprintf("%s/api/v1/processors/%s/%s", baseURL, changefeedID, captureID)
+	body, err := getBodyOK(httpClient, url)
+	if err != nil {
+		return 0, err
+	}
+
+	detail := ProcessorDetail{}
+	err = json.Unmarshal(body, &detail)
+	return len(detail.TableIDs), err
+}
+
+func (c *defaultTiCDCControl) DrainCapture(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (int, bool, error) {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return 0, false, err
+	}
+
+	payload, err := json.Marshal(drainCaptureRequest{CaptureID: captureID})
+	if err != nil {
+		return 0, false, err
+	}
+	baseURL := c.getBaseURL(tc, ordinal)
+	url := fmt.Sprintf("%s/api/v1/captures/drain", baseURL)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer httputil.DeferClose(res.Body)
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		// the versions of TiCDC before v6.2 do not support draining captures
+		return 0, false, nil
+	case res.StatusCode == http.StatusServiceUnavailable:
+		return 0, true, nil
+	case res.StatusCode >= 400:
+		return 0, false, fmt.Errorf("Error response %s:%v URL %s", string(body), res.StatusCode, url)
+	}
+
+	resp := drainCaptureResponse{}
+	err = json.Unmarshal(body, &resp)
+	return resp.CurrentTableCount, false, err
+}
+
+func (c *defaultTiCDCControl) ResignOwner(tc *v1alpha1.TidbCluster, ordinal int32) error {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return err
+	}
+
+	baseURL := c.getBaseURL(tc, ordinal)
+	url := fmt.Sprintf("%s/api/v1/owner/resign", baseURL)
+	_, err = httputil.PostBodyOK(httpClient, url, nil)
+	return err
+}
+
 func (c *defaultTiCDCControl) getBaseURL(tc *v1alpha1.TidbCluster, ordinal int32) string {
 	if c.testURL != "" {
 		return c.testURL
@@ -78,7 +202,11 @@ func (c *defaultTiCDCControl) getBaseURL(tc *v1alpha1.TidbCluster, ordinal int32
 
 // FakeTiCDCControl is a fake implementation of TiCDCControlInterface.
 type FakeTiCDCControl struct {
-	getStatus func(tc *v1alpha1.TidbCluster, ordinal int32) (*CaptureStatus, error)
+	getStatus     func(tc *v1alpha1.TidbCluster, ordinal int32) (*CaptureStatus, error)
+	getProcessors func(tc *v1alpha1.TidbCluster, ordinal int32) ([]ProcessorInfo, error)
+	getTableCount func(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error)
+	drainCapture  func(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (int, bool, error)
+	resignOwner   func(tc *v1alpha1.TidbCluster, ordinal int32) error
 }
 
 // NewFakeTiCDCControl returns a FakeTiCDCControl instance
@@ -97,3 +225,51 @@ func (c *FakeTiCDCControl) GetStatus(tc *v1alpha1.TidbCluster, ordinal int32) (*
 	}
 	return c.getStatus(tc, ordinal)
 }
+
+// MockGetProcessors mocks the processors returned by FakeTiCDCControl
+func (c *FakeTiCDCControl) MockGetProcessors(mockfunc func(tc *v1alpha1.TidbCluster, ordinal int32) ([]ProcessorInfo, error)) {
+	c.getProcessors = mockfunc
+}
+
+func (c *FakeTiCDCControl) GetProcessors(tc *v1alpha1.TidbCluster, ordinal int32) ([]ProcessorInfo, error) {
+	if c.getProcessors == nil {
+		return nil, fmt.Errorf("undefined")
+	}
+	return c.getProcessors(tc, ordinal)
+}
+
+// MockGetProcessorTableCount mocks the table counts of the processors returned by FakeTiCDCControl
+func (c *FakeTiCDCControl) MockGetProcessorTableCount(mockfunc func(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error)) {
+	c.getTableCount = mockfunc
+}
+
+func (c *FakeTiCDCControl) GetProcessorTableCount(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error) {
+	if c.getTableCount == nil {
+		return 0, fmt.Errorf("undefined")
+	}
+	return c.getTableCount(tc, ordinal, changefeedID, captureID)
+}
+
+// MockDrainCapture mocks the draining of the captures of FakeTiCDCControl
+func (c *FakeTiCDCControl) MockDrainCapture(mockfunc func(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (int, bool, error)) {
+	c.drainCapture = mockfunc
+}
+
+func (c *FakeTiCDCControl) DrainCapture(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (int, bool, error) {
+	if c.drainCapture == nil {
+		return 0, false, fmt.Errorf("undefined")
+	}
+	return c.drainCapture(tc, ordinal, captureID)
+}
+
+// MockResignOwner mocks the resignation of the owner of FakeTiCDCControl
+func (c *FakeTiCDCControl) MockResignOwner(mockfunc func(tc *v1alpha1.TidbCluster, ordinal int32) error) {
+	c.resignOwner = mockfunc
+}
+
+func (c *FakeTiCDCControl) ResignOwner(tc *v1alpha1.TidbCluster, ordinal int32) error {
+	if c.resignOwner == nil {
+		return fmt.Errorf("undefined")
+	}
+	return c.resignOwner(tc, ordinal)
+}
@@ -15,14 +15,18 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
 	"github.com/pingcap/tidb/config"
+	"github.com/prometheus/common/expfmt"
 	corelisterv1 "k8s.io/client-go/listers/core/v1"
 )
 
@@ -31,12 +35,21 @@ const (
 	// NotDDLOwnerError is the error message which was returned when the tidb node is not a ddl owner
 	NotDDLOwnerError = "This node is not a ddl owner, can't be resigned."
 	timeout          = 5 * time.Second
+
+	// tidbConnectionsMetric is the gauge of the client connections of a TiDB instance
+	tidbConnectionsMetric = "tidb_server_connections"
 )
 
 type DBInfo struct {
 	IsOwner bool `json:"is_owner"`
 }
 
+// TiDBConnectivity is the connectivity from a TiDB instance to PD and TiKV, a nil error means connected
+type TiDBConnectivity struct {
+	PD   error
+	TiKV error
+}
+
 // TiDBControlInterface is the interface that knows how to manage tidb peers
 type TiDBControlInterface interface {
 	// GetHealth returns tidb's health info
@@ -45,6 +58,11 @@ type TiDBControlInterface interface {
 	GetInfo(tc *v1alpha1.TidbCluster, ordinal int32) (*DBInfo, error)
 	// GetSettings return the TiDB instance settings
 	GetSettings(tc *v1alpha1.TidbCluster, ordinal int32) (*config.Config, error)
+	// GetConnectivity probes the connectivity from the TiDB instance to PD and TiKV, the error is
+	// returned if the instance itself can not be probed
+	GetConnectivity(tc *v1alpha1.TidbCluster, ordinal int32) (*TiDBConnectivity, error)
+	// GetConnectionCount returns the number of the client connections of the TiDB instance scraped from its metrics
+	GetConnectionCount(tc *v1alpha1.TidbCluster, ordinal int32) (int32, error)
 }
 
 // defaultTiDBControl is default implementation of TiDBControlInterface.
@@ -137,6 +155,71 @@ func (c *defaultTiDBControl) GetSettings(tc *v1alpha1.TidbCluster, ordinal int32
 	return &info, nil
 }
 
+func (c *defaultTiDBControl) GetConnectivity(tc *v1alpha1.TidbCluster, ordinal int32) (*TiDBConnectivity, error) {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := c.getBaseURL(tc, ordinal)
+	connectivity := &TiDBConnectivity{}
+	// the info of all the TiDB instances is loaded from PD
+	if _, err := getBodyOK(httpClient, fmt.Sprintf("%s/info/all", baseURL)); err != nil {
+		if isTransportError(err) {
+			return nil, err
+		}
+		connectivity.PD = err
+	}
+	// the DDL history is read from TiKV in a transaction
+	if _, err := getBodyOK(httpClient, fmt.Sprintf("%s/ddl/history?limit=1", baseURL)); err != nil {
+		if isTransportError(err) {
+			return nil, err
+		}
+		connectivity.TiKV = err
+	}
+	return connectivity, nil
+}
+
+func (c *defaultTiDBControl) GetConnectionCount(tc *v1alpha1.TidbCluster, ordinal int32) (int32, error) {
+	httpClient, err := c.getHTTPClient(tc)
+	if err != nil {
+		return 0, err
+	}
+
+	baseURL := c.getBaseURL(tc, ordinal)
+	body, err := getBodyOK(httpClient, fmt.Sprintf("%s/metrics", baseURL))
+	if err != nil {
+		return 0, err
+	}
+	return parseConnectionCount(body)
+}
+
+// parseConnectionCount sums the samples of tidbConnectionsMetric in the metrics of the text format, the newer
+// versions of TiDB export a sample per resource group
+func parseConnectionCount(metrics []byte) (int32, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(string(metrics)))
+	if err != nil {
+		return 0, err
+	}
+	family, ok := families[tidbConnectionsMetric]
+	if !ok {
+		return 0, fmt.Errorf("metric %s not found", tidbConnectionsMetric)
+	}
+	var count float64
+	for _, m := range family.GetMetric() {
+		count += m.GetGauge().GetValue()
+	}
+	return int32(count), nil
+}
+
+// isTransportError returns whether the request fails before a response is received, e.g. the
+// instance is down or the TLS handshake fails
+func isTransportError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
 func getBodyOK(httpClient *http.Client, apiURL string) ([]byte, error) {
 	res, err := httpClient.Get(apiURL)
 	if err != nil {
@@ -160,12 +243,11 @@ func (c *defaultTiDBControl) getBaseURL(tc *v1alpha1.TidbCluster, ordinal int32)
 		return c.testURL
 	}
 
-	tcName := tc.GetName()
 	ns := tc.GetNamespace()
 	scheme := tc.Scheme()
-	hostName := fmt.Sprintf("%s-%d", TiDBMemberName(tcName), ordinal)
+	hostName := fmt.Sprintf("%s-%d", TiDBSetName(tc), ordinal)
 
-	return fmt.Sprintf("%s://%s.%s.%s:10080", scheme, hostName, TiDBPeerMemberName(tcName), ns)
+	return fmt.Sprintf("%s://%s.%s.%s:10080", scheme, hostName, TiDBSetPeerName(tc), ns)
 }
 
 // FakeTiDBControl is a fake implementation of TiDBControlInterface.
@@ -174,6 +256,8 @@ type FakeTiDBControl struct {
 	tiDBInfo     *DBInfo
 	getInfoError error
 	tidbConfig   *config.Config
+	connectivity map[string]*TiDBConnectivity
+	connections  map[string][]int32
 }
 
 // NewFakeTiDBControl returns a FakeTiDBControl instance
@@ -187,7 +271,7 @@ func (c *FakeTiDBControl) SetHealth(healthInfo map[string]bool) {
 }
 
 func (c *FakeTiDBControl) GetHealth(tc *v1alpha1.TidbCluster, ordinal int32) (bool, error) {
-	podName := fmt.Sprintf("%s-%d", TiDBMemberName(tc.GetName()), ordinal)
+	podName := fmt.Sprintf("%s-%d", TiDBSetName(tc), ordinal)
 	if c.healthInfo == nil {
 		return false, nil
 	}
@@ -201,6 +285,45 @@ func (c *FakeTiDBControl) GetInfo(tc *v1alpha1.TidbCluster, ordinal int32) (*DBI
 	return c.tiDBInfo, c.getInfoError
 }
 
+// SetSettings sets the settings returned by GetSettings
+func (c *FakeTiDBControl) SetSettings(tidbConfig *config.Config) {
+	c.tidbConfig = tidbConfig
+}
+
 func (c *FakeTiDBControl) GetSettings(tc *v1alpha1.TidbCluster, ordinal int32) (*config.Config, error) {
 	return c.tidbConfig, c.getInfoError
 }
+
+// SetConnectivity sets the connectivity returned by GetConnectivity keyed by the pod name
+func (c *FakeTiDBControl) SetConnectivity(connectivity map[string]*TiDBConnectivity) {
+	c.connectivity = connectivity
+}
+
+func (c *FakeTiDBControl) GetConnectivity(tc *v1alpha1.TidbCluster, ordinal int32) (*TiDBConnectivity, error) {
+	podName := fmt.Sprintf("%s-%d", TiDBSetName(tc), ordinal)
+	if connectivity, ok := c.connectivity[podName]; ok {
+		return connectivity, nil
+	}
+	return nil, fmt.Errorf("undefined")
+}
+
+// SetConnectionCount sets the numbers of the connections returned by GetConnectionCount of the pod, a number is
+// returned by each call in turn and the last one is returned ever after
+func (c *FakeTiDBControl) SetConnectionCount(podName string, counts ...int32) {
+	if c.connections == nil {
+		c.connections = map[string][]int32{}
+	}
+	c.connections[podName] = counts
+}
+
+func (c *FakeTiDBControl) GetConnectionCount(tc *v1alpha1.TidbCluster, ordinal int32) (int32, error) {
+	podName := fmt.Sprintf("%s-%d", TiDBSetName(tc), ordinal)
+	counts := c.connections[podName]
+	if len(counts) == 0 {
+		return 0, fmt.Errorf("undefined")
+	}
+	if len(counts) > 1 {
+		c.connections[podName] = counts[1:]
+	}
+	return counts[0], nil
+}
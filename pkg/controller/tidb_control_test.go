@@ -268,6 +268,113 @@ func TestSettings(t *testing.T) {
 	}
 }
 
+func TestConnectivity(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cases := []struct {
+		caseName   string
+		pdFailed   bool
+		tikvFailed bool
+	}{
+		{caseName: "connected"},
+		{caseName: "pd unreachable", pdFailed: true, tikvFailed: true},
+		{caseName: "tikv unreachable", tikvFailed: true},
+	}
+
+	for _, c := range cases {
+		svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+			g.Expect(request.Method).To(Equal("GET"), "check method")
+			switch request.URL.Path {
+			case "/info/all":
+				if c.pdFailed {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			case "/ddl/history":
+				if c.tikvFailed {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			default:
+				t.Errorf("unexpected url %s", request.URL.Path)
+			}
+		})
+		defer svc.Close()
+
+		fakeClient := &fake.Clientset{}
+		informer := kubeinformers.NewSharedInformerFactory(fakeClient, 0)
+		control := NewDefaultTiDBControl(informer.Core().V1().Secrets().Lister())
+		control.testURL = svc.URL
+		tc := getTidbCluster()
+		result, err := control.GetConnectivity(tc, 0)
+		g.Expect(err).NotTo(HaveOccurred(), c.caseName)
+		g.Expect(result.PD != nil).To(Equal(c.pdFailed), c.caseName)
+		g.Expect(result.TiKV != nil).To(Equal(c.tikvFailed), c.caseName)
+	}
+
+	// the instance is not reachable
+	fakeClient := &fake.Clientset{}
+	informer := kubeinformers.NewSharedInformerFactory(fakeClient, 0)
+	control := NewDefaultTiDBControl(informer.Core().V1().Secrets().Lister())
+	control.testURL = "http://127.0.0.1:1"
+	_, err := control.GetConnectivity(getTidbCluster(), 0)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestConnectionCount(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cases := []struct {
+		caseName string
+		metrics  string
+		count    int32
+		failed   bool
+	}{
+		{
+			caseName: "single sample",
+			metrics: `# HELP tidb_server_connections Number of connections.
+# TYPE tidb_server_connections gauge
+tidb_server_connections 12
+`,
+			count: 12,
+		},
+		{
+			caseName: "a sample per resource group",
+			metrics: `# HELP tidb_server_connections Number of connections.
+# TYPE tidb_server_connections gauge
+tidb_server_connections{resource_group="default"} 3
+tidb_server_connections{resource_group="rg1"} 4
+`,
+			count: 7,
+		},
+		{
+			caseName: "metric not found",
+			metrics: `# TYPE tidb_server_handle_query_duration_seconds_count counter
+tidb_server_handle_query_duration_seconds_count 1
+`,
+			failed: true,
+		},
+	}
+
+	for _, c := range cases {
+		svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+			g.Expect(request.URL.Path).To(Equal("/metrics"), "check url")
+			w.Write([]byte(c.metrics))
+		})
+		defer svc.Close()
+
+		fakeClient := &fake.Clientset{}
+		informer := kubeinformers.NewSharedInformerFactory(fakeClient, 0)
+		control := NewDefaultTiDBControl(informer.Core().V1().Secrets().Lister())
+		control.testURL = svc.URL
+		count, err := control.GetConnectionCount(getTidbCluster(), 0)
+		if c.failed {
+			g.Expect(err).To(HaveOccurred(), c.caseName)
+			continue
+		}
+		g.Expect(err).NotTo(HaveOccurred(), c.caseName)
+		g.Expect(count).To(Equal(c.count), c.caseName)
+	}
+}
+
 func TestGetHTTPClient(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -29,7 +29,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
@@ -113,19 +112,11 @@ func (c *PodController) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting tidbcluster pod controller")
 	defer klog.Info("Shutting down tidbcluster pod controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.TiDBClusterPodControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
-func (c *PodController) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *PodController) processNextWorkItem() bool {
@@ -261,7 +252,8 @@ func (c *PodController) syncTiKVPod(ctx context.Context, pod *corev1.Pod, tc *v1
 
 		if value == v1alpha1.EvictLeaderValueDeletePod {
 			tlsEnabled := tc.IsTLSClusterEnabled()
-			kvClient := c.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, pod.Name, tlsEnabled)
+			_, statusPort := tc.TiKVPorts()
+			kvClient := c.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, pod.Name, statusPort, tlsEnabled)
 			leaderCount, err := kvClient.GetLeaderCount()
 			if err != nil {
 				return reconcile.Result{}, perrors.Annotatef(err, "failed to get leader count for pod %s/%s", pod.Namespace, pod.Name)
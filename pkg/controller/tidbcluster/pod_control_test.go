@@ -41,6 +41,19 @@ func (c *kvClient) GetLeaderCount() (int, error) {
 	return int(count), nil
 }
 
+func (c *kvClient) GetLeaderStats() (*tikvapi.LeaderStats, error) {
+	count := int(atomic.LoadInt32(&c.leaderCount))
+	return &tikvapi.LeaderStats{RegionCount: count, LeaderCount: count}, nil
+}
+
+func (c *kvClient) FlushStore() error {
+	return nil
+}
+
+func (c *kvClient) GetApplyLag() (int64, error) {
+	return 0, nil
+}
+
 func TestPodControllerSync(t *testing.T) {
 	interval := time.Millisecond * 100
 	timeout := time.Minute * 1
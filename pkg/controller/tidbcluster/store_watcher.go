@@ -0,0 +1,161 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// storeWatcher polls the TiKV stores of the TidbClusters from PD at a short interval, a cluster is enqueued as
+// soon as one of its stores becomes Disconnected or Down, so that the failover and the status updates begin
+// without waiting for the next resync. PD has no API to watch the stores, and the PD client goes through the PD
+// service, so the polls survive the changes of the PD leader.
+type storeWatcher struct {
+	deps        *controller.Dependencies
+	interval    time.Duration
+	maxClusters int
+	enqueue     func(key string)
+
+	lock    sync.Mutex
+	watches map[string]*storeWatch
+}
+
+// storeWatch is the watch of the stores of a TidbCluster
+type storeWatch struct {
+	uid    types.UID
+	stopCh chan struct{}
+	// states are the states of the stores by the store IDs in the last successful poll
+	states map[string]string
+}
+
+func newStoreWatcher(deps *controller.Dependencies, enqueue func(key string)) *storeWatcher {
+	return &storeWatcher{
+		deps:        deps,
+		interval:    deps.CLIConfig.StoreWatchInterval,
+		maxClusters: deps.CLIConfig.StoreWatchMaxClusters,
+		enqueue:     enqueue,
+		watches:     map[string]*storeWatch{},
+	}
+}
+
+// sync starts the watch of the stores of the cluster if the cluster has TiKV, or stops it otherwise
+func (w *storeWatcher) sync(key string, tc *v1alpha1.TidbCluster) {
+	if w.interval <= 0 {
+		return
+	}
+	if tc == nil || tc.Spec.TiKV == nil || tc.DeletionTimestamp != nil {
+		w.stop(key)
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if watch, ok := w.watches[key]; ok {
+		if watch.uid == tc.UID {
+			return
+		}
+		// the cluster is recreated with the same name
+		w.stopLocked(key)
+	}
+	if w.maxClusters > 0 && len(w.watches) >= w.maxClusters {
+		klog.V(4).Infof("store watcher: the stores of %d clusters are watched, TidbCluster %s is synced periodically only", len(w.watches), key)
+		return
+	}
+	watch := &storeWatch{uid: tc.UID, stopCh: make(chan struct{}), states: map[string]string{}}
+	w.watches[key] = watch
+	metrics.StoreWatcherWatchedClusters.Set(float64(len(w.watches)))
+	go wait.Until(func() { w.poll(key, watch) }, w.interval, watch.stopCh)
+	klog.Infof("store watcher: start watching the stores of TidbCluster %s", key)
+}
+
+// stop stops the watch of the stores of the cluster
+func (w *storeWatcher) stop(key string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.stopLocked(key)
+}
+
+func (w *storeWatcher) stopLocked(key string) {
+	watch, ok := w.watches[key]
+	if !ok {
+		return
+	}
+	close(watch.stopCh)
+	delete(w.watches, key)
+	metrics.StoreWatcherWatchedClusters.Set(float64(len(w.watches)))
+	klog.Infof("store watcher: stop watching the stores of TidbCluster %s", key)
+}
+
+// stopAll stops the watches of all the clusters
+func (w *storeWatcher) stopAll() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for key := range w.watches {
+		w.stopLocked(key)
+	}
+}
+
+// poll gets the stores of the cluster from PD and enqueues the cluster if any store transitions to Disconnected
+// or Down since the last successful poll
+func (w *storeWatcher) poll(key string, watch *storeWatch) {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	tc, err := w.deps.TiDBClusterLister.TidbClusters(ns).Get(name)
+	if err != nil {
+		// the watch is stopped by the sync of the deleted cluster
+		return
+	}
+	storesInfo, err := controller.GetPDClient(w.deps.PDControl, tc).GetStores()
+	if err != nil {
+		// e.g. PD is electing a new leader, the watch restarts from the states of the last successful poll
+		klog.V(4).Infof("store watcher: failed to get the stores of TidbCluster %s, error: %v", key, err)
+		metrics.StoreWatcherRestarts.WithLabelValues(ns, name).Inc()
+		return
+	}
+
+	states := map[string]string{}
+	transitioned := ""
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil {
+			continue
+		}
+		id := strconv.FormatUint(store.Store.GetId(), 10)
+		state := store.Store.StateName
+		states[id] = state
+		if state != v1alpha1.TiKVStateDisconnected && state != v1alpha1.TiKVStateDown {
+			continue
+		}
+		if last, ok := watch.states[id]; ok && last != state {
+			transitioned = id
+		}
+	}
+	watch.states = states
+	if transitioned != "" {
+		klog.Infof("store watcher: store %s of TidbCluster %s becomes %s, enqueue the cluster", transitioned, key, states[transitioned])
+		metrics.StoreWatcherEnqueues.WithLabelValues(ns, name).Inc()
+		w.enqueue(key)
+	}
+}
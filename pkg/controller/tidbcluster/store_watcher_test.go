@@ -0,0 +1,126 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestStoreWatcherSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := controller.NewFakeDependencies()
+	deps.CLIConfig.StoreWatchInterval = time.Hour
+	deps.CLIConfig.StoreWatchMaxClusters = 1
+	w := newStoreWatcher(deps, func(string) {})
+	defer w.stopAll()
+
+	tc := newTidbCluster()
+	key := "default/test-pd"
+	w.sync(key, tc)
+	g.Expect(w.watches).To(HaveKey(key))
+	stopCh := w.watches[key].stopCh
+
+	// the watch is kept for the same cluster
+	w.sync(key, tc)
+	g.Expect(w.watches[key].stopCh).To(Equal(stopCh))
+
+	// the watch is restarted for the recreated cluster
+	tc.UID = types.UID("recreated")
+	w.sync(key, tc)
+	g.Expect(stopCh).To(BeClosed())
+	g.Expect(w.watches[key].uid).To(Equal(types.UID("recreated")))
+
+	// the number of the watched clusters is limited
+	other := newTidbCluster()
+	other.Name = "other"
+	w.sync("default/other", other)
+	g.Expect(w.watches).NotTo(HaveKey("default/other"))
+
+	// the watch is stopped for the cluster without TiKV and the deleted cluster
+	stopCh = w.watches[key].stopCh
+	tc.Spec.TiKV = nil
+	w.sync(key, tc)
+	g.Expect(stopCh).To(BeClosed())
+	g.Expect(w.watches).To(BeEmpty())
+	w.sync("default/other", other)
+	w.stop("default/other")
+	g.Expect(w.watches).To(BeEmpty())
+
+	// disabled
+	deps.CLIConfig.StoreWatchInterval = 0
+	w = newStoreWatcher(deps, func(string) {})
+	w.sync(key, newTidbCluster())
+	g.Expect(w.watches).To(BeEmpty())
+}
+
+func TestStoreWatcherPoll(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := controller.NewFakeDependencies()
+	var enqueued []string
+	w := newStoreWatcher(deps, func(key string) { enqueued = append(enqueued, key) })
+
+	tc := newTidbCluster()
+	g.Expect(deps.InformerFactory.Pingcap().V1alpha1().TidbClusters().Informer().GetIndexer().Add(tc)).To(Succeed())
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	states := map[uint64]string{1: v1alpha1.TiKVStateUp, 2: v1alpha1.TiKVStateUp}
+	var pdErr error
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		if pdErr != nil {
+			return nil, pdErr
+		}
+		stores := &pdapi.StoresInfo{}
+		for id, state := range states {
+			stores.Stores = append(stores.Stores, &pdapi.StoreInfo{
+				Store: &pdapi.MetaStore{Store: &metapb.Store{Id: id}, StateName: state},
+			})
+		}
+		return stores, nil
+	})
+
+	key := "default/test-pd"
+	watch := &storeWatch{uid: tc.UID, states: map[string]string{}}
+	w.poll(key, watch)
+	g.Expect(watch.states).To(Equal(map[string]string{"1": v1alpha1.TiKVStateUp, "2": v1alpha1.TiKVStateUp}))
+	g.Expect(enqueued).To(BeEmpty())
+
+	// the cluster is enqueued when a store becomes Disconnected
+	states[2] = v1alpha1.TiKVStateDisconnected
+	w.poll(key, watch)
+	g.Expect(enqueued).To(Equal([]string{key}))
+
+	// the states are kept when PD is unavailable, e.g. the PD leader changes
+	pdErr = fmt.Errorf("no leader")
+	w.poll(key, watch)
+	g.Expect(watch.states).To(HaveKeyWithValue("2", v1alpha1.TiKVStateDisconnected))
+	g.Expect(enqueued).To(HaveLen(1))
+
+	// the transition to Down is detected after PD recovers
+	pdErr = nil
+	states[2] = v1alpha1.TiKVStateDown
+	w.poll(key, watch)
+	g.Expect(enqueued).To(HaveLen(2))
+
+	// no transition
+	w.poll(key, watch)
+	g.Expect(enqueued).To(HaveLen(2))
+}
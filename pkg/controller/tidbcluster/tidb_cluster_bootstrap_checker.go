@@ -0,0 +1,218 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// TidbClusterBootstrapChecker checks whether PD of the TidbCluster becomes healthy within
+// spec.bootstrapTimeout of the creation of the TidbCluster.
+type TidbClusterBootstrapChecker interface {
+	// Check sets the BootstrapFailed condition with the last failure of the PD pods if PD fails to
+	// bootstrap in time, and removes it once PD becomes healthy. It returns true if PD fails to
+	// bootstrap, so that the components depending on PD should not be synced.
+	Check(*v1alpha1.TidbCluster) bool
+}
+
+type tidbClusterBootstrapChecker struct {
+	deps *controller.Dependencies
+	now  func() time.Time
+}
+
+// NewTidbClusterBootstrapChecker returns a TidbClusterBootstrapChecker
+func NewTidbClusterBootstrapChecker(deps *controller.Dependencies) TidbClusterBootstrapChecker {
+	return &tidbClusterBootstrapChecker{deps: deps, now: time.Now}
+}
+
+var _ TidbClusterBootstrapChecker = &tidbClusterBootstrapChecker{}
+
+func (c *tidbClusterBootstrapChecker) Check(tc *v1alpha1.TidbCluster) bool {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	current := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterBootstrapFailed)
+
+	bootstrapped := tc.Spec.PD == nil || pdBootstrapped(tc)
+	if bootstrapped || c.now().Sub(tc.CreationTimestamp.Time) < tc.BootstrapTimeout() {
+		if current != nil {
+			utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterBootstrapFailed)
+		}
+		if current != nil && bootstrapped {
+			klog.Infof("tidbcluster: [%s/%s] PD recovers from the bootstrap failure", ns, tcName)
+			c.deps.Recorder.Event(tc, corev1.EventTypeNormal, events.BootstrapRecovered, "PD becomes healthy, the other components are synced again")
+		}
+		return false
+	}
+
+	message := fmt.Sprintf("PD is not healthy within %s of the creation of the cluster", tc.BootstrapTimeout())
+	if failure := c.pdFailure(tc); failure != "" {
+		message = fmt.Sprintf("%s, the last failure: %s", message, failure)
+	}
+	if current == nil || current.Status != corev1.ConditionTrue {
+		klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, message)
+		c.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.BootstrapFailed, message)
+	}
+	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterBootstrapFailed, corev1.ConditionTrue, utiltidbcluster.PDBootstrapTimedOut, message)
+	// the message is updated with the last failure without changing the transition time
+	if current != nil && current.Status == corev1.ConditionTrue && current.Message != message {
+		cond.LastTransitionTime = current.LastTransitionTime
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterBootstrapFailed)
+	}
+	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
+	return true
+}
+
+// pdBootstrapped returns true if PD is healthy or has ever been, the components depending on PD are only created
+// after PD becomes healthy
+func pdBootstrapped(tc *v1alpha1.TidbCluster) bool {
+	if tc.PDIsAvailable() {
+		return true
+	}
+	for _, memberType := range []v1alpha1.MemberType{
+		v1alpha1.TiKVMemberType,
+		v1alpha1.TiFlashMemberType,
+		v1alpha1.TiDBMemberType,
+		v1alpha1.PumpMemberType,
+		v1alpha1.TiCDCMemberType,
+	} {
+		if componentCreated(tc, memberType) {
+			return true
+		}
+	}
+	return false
+}
+
+// pdFailure returns the failure of the first PD pod whose containers are waiting or terminated or which is
+// unschedulable, or the most recent warning event of the PD pods, their PVCs and the StatefulSet of PD if the
+// statuses of the pods tell nothing, e.g. the PVCs are pending on a bad StorageClass
+func (c *tidbClusterBootstrapChecker) pdFailure(tc *v1alpha1.TidbCluster) string {
+	ns := tc.GetNamespace()
+	selector, err := label.New().Instance(tc.GetInstanceName()).PD().Selector()
+	if err != nil {
+		return ""
+	}
+	pods, err := c.deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to list the PD pods: %v", ns, tc.GetName(), err)
+		return ""
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	for _, pod := range pods {
+		if failure := podFailure(pod); failure != "" {
+			return failure
+		}
+	}
+
+	objects := map[string]sets.String{
+		"Pod":                   sets.NewString(),
+		"PersistentVolumeClaim": sets.NewString(),
+		"StatefulSet":           sets.NewString(controller.PDMemberName(tc.GetName())),
+	}
+	for _, pod := range pods {
+		objects["Pod"].Insert(pod.Name)
+	}
+	pvcs, err := c.deps.PVCLister.PersistentVolumeClaims(ns).List(selector)
+	if err == nil {
+		for _, pvc := range pvcs {
+			objects["PersistentVolumeClaim"].Insert(pvc.Name)
+		}
+	}
+	eventList, err := c.deps.KubeClientset.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to list the events: %v", ns, tc.GetName(), err)
+		return ""
+	}
+	var last *corev1.Event
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		names, ok := objects[event.InvolvedObject.Kind]
+		if event.Type != corev1.EventTypeWarning || !ok || !names.Has(event.InvolvedObject.Name) {
+			continue
+		}
+		if last == nil || eventTime(last).Before(eventTime(event)) {
+			last = event
+		}
+	}
+	if last == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s: %s: %s", last.InvolvedObject.Kind, last.InvolvedObject.Name, last.Reason, last.Message)
+}
+
+// eventTime returns the time the event is last observed, the events.k8s.io/v1 events only have the event time
+func eventTime(event *corev1.Event) time.Time {
+	if event.LastTimestamp.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.LastTimestamp.Time
+}
+
+// podFailure returns why the pod is not running or keeps restarting from its status
+func podFailure(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return fmt.Sprintf("Pod %s: %s: %s", pod.Name, cond.Reason, cond.Message)
+		}
+	}
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing" {
+			return fmt.Sprintf("Pod %s: container %s is waiting: %s: %s", pod.Name, status.Name, waiting.Reason, waiting.Message)
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil && !status.Ready {
+			return fmt.Sprintf("Pod %s: container %s terminated with exit code %d: %s: %s", pod.Name, status.Name, terminated.ExitCode, terminated.Reason, terminated.Message)
+		}
+	}
+	return ""
+}
+
+// bootstrapFailed returns true if the TidbCluster is not being deleted and fails to bootstrap
+func bootstrapFailed(tc *v1alpha1.TidbCluster) bool {
+	if tc.DeletionTimestamp != nil {
+		return false
+	}
+	cond := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterBootstrapFailed)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+type FakeTidbClusterBootstrapChecker struct {
+	failed bool
+}
+
+func NewFakeTidbClusterBootstrapChecker() *FakeTidbClusterBootstrapChecker {
+	return &FakeTidbClusterBootstrapChecker{}
+}
+
+func (c *FakeTidbClusterBootstrapChecker) SetFailed(failed bool) {
+	c.failed = failed
+}
+
+func (c *FakeTidbClusterBootstrapChecker) Check(_ *v1alpha1.TidbCluster) bool {
+	return c.failed
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestTidbClusterBootstrapChecker(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	pvcIndexer := deps.KubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer()
+	checker := NewTidbClusterBootstrapChecker(deps).(*tidbClusterBootstrapChecker)
+	created := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := created
+	checker.now = func() time.Time { return now }
+
+	tc := newTidbClusterForTidbClusterControl()
+	tc.CreationTimestamp = metav1.NewTime(created)
+	pdLabels := label.New().Instance(tc.GetInstanceName()).PD()
+	condition := func() *v1alpha1.TidbClusterCondition {
+		return utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterBootstrapFailed)
+	}
+	collect := func() []string {
+		var recorded []string
+		for len(recorder.Events) > 0 {
+			recorded = append(recorded, <-recorder.Events)
+		}
+		return recorded
+	}
+
+	// PD is given the bootstrap timeout after the cluster is created
+	now = created.Add(29 * time.Minute)
+	g.Expect(checker.Check(tc)).To(BeFalse())
+	g.Expect(condition()).To(BeNil())
+
+	// the failure of the PD pods is reported after the timeout
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pd-pd-0", Namespace: tc.Namespace, Labels: pdLabels},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name: "pd",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+				Reason:  "ImagePullBackOff",
+				Message: `Back-off pulling image "pingcap/pd:v3.0.8"`,
+			}},
+		}}},
+	}
+	g.Expect(podIndexer.Add(pod)).To(Succeed())
+	now = created.Add(31 * time.Minute)
+	g.Expect(checker.Check(tc)).To(BeTrue())
+	cond := condition()
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal(utiltidbcluster.PDBootstrapTimedOut))
+	g.Expect(cond.Message).To(Equal(`PD is not healthy within 30m0s of the creation of the cluster, the last failure: Pod test-pd-pd-0: container pd is waiting: ImagePullBackOff: Back-off pulling image "pingcap/pd:v3.0.8"`))
+	recorded := collect()
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.BootstrapFailed))
+
+	// the message is updated with the last failure from the events if the pods tell nothing, the transition time
+	// is kept and the event is not emitted again
+	transition := cond.LastTransitionTime
+	g.Expect(podIndexer.Delete(pod)).To(Succeed())
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "pd-test-pd-pd-0", Namespace: tc.Namespace, Labels: pdLabels},
+	}
+	g.Expect(pvcIndexer.Add(pvc)).To(Succeed())
+	for _, event := range []*corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pvc-1", Namespace: tc.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "PersistentVolumeClaim", Name: pvc.Name},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "ProvisioningFailed",
+			Message:        `storageclass.storage.k8s.io "fast" not found`,
+			LastTimestamp:  metav1.NewTime(created.Add(20 * time.Minute)),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pvc-0", Namespace: tc.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "PersistentVolumeClaim", Name: pvc.Name},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "ProvisioningFailed",
+			Message:        "the earlier failure",
+			LastTimestamp:  metav1.NewTime(created.Add(10 * time.Minute)),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "other", Namespace: tc.Namespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "PersistentVolumeClaim", Name: "other"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "ProvisioningFailed",
+			Message:        "not a PVC of PD",
+			LastTimestamp:  metav1.NewTime(created.Add(30 * time.Minute)),
+		},
+	} {
+		_, err := deps.KubeClientset.CoreV1().Events(tc.Namespace).Create(context.TODO(), event, metav1.CreateOptions{})
+		g.Expect(err).To(Succeed())
+	}
+	now = created.Add(time.Hour)
+	g.Expect(checker.Check(tc)).To(BeTrue())
+	cond = condition()
+	g.Expect(cond.Message).To(Equal(`PD is not healthy within 30m0s of the creation of the cluster, the last failure: PersistentVolumeClaim pd-test-pd-pd-0: ProvisioningFailed: storageclass.storage.k8s.io "fast" not found`))
+	g.Expect(cond.LastTransitionTime).To(Equal(transition))
+	g.Expect(collect()).To(BeEmpty())
+
+	// the condition is removed if the timeout is extended
+	tc.Spec.BootstrapTimeout = pointer.StringPtr("2h")
+	g.Expect(checker.Check(tc)).To(BeFalse())
+	g.Expect(condition()).To(BeNil())
+	g.Expect(collect()).To(BeEmpty())
+	now = created.Add(3 * time.Hour)
+	g.Expect(checker.Check(tc)).To(BeTrue())
+	g.Expect(collect()).To(HaveLen(1))
+
+	// the condition is cleared once PD becomes healthy
+	tc.Status.PD.Members = map[string]v1alpha1.PDMember{
+		"test-pd-pd-0": {Name: "test-pd-pd-0", Health: true},
+		"test-pd-pd-1": {Name: "test-pd-pd-1", Health: true},
+		"test-pd-pd-2": {Name: "test-pd-pd-2", Health: false},
+	}
+	tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{ReadyReplicas: 2}
+	g.Expect(checker.Check(tc)).To(BeFalse())
+	g.Expect(condition()).To(BeNil())
+	recorded = collect()
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.BootstrapRecovered))
+
+	// the cluster bootstrapped is never failed again however long PD is unhealthy
+	tc.Status.PD.Members = nil
+	tc.Status.PD.StatefulSet = nil
+	tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{}
+	g.Expect(checker.Check(tc)).To(BeFalse())
+	g.Expect(condition()).To(BeNil())
+	g.Expect(collect()).To(BeEmpty())
+}
+
+func TestPodFailure(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name    string
+		status  corev1.PodStatus
+		failure string
+	}{
+		{
+			name:    "running",
+			status:  corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "pd", Ready: true}}},
+			failure: "",
+		},
+		{
+			name: "creating",
+			status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "pd",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+			}}},
+			failure: "",
+		},
+		{
+			name: "unschedulable",
+			status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Reason:  "Unschedulable",
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}}},
+			failure: "Pod test-pd-pd-0: Unschedulable: 0/3 nodes are available: 3 Insufficient cpu.",
+		},
+		{
+			name: "crashing init container",
+			status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{
+				Name: "init",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Reason:   "Error",
+					Message:  "permission denied",
+				}},
+			}}},
+			failure: "Pod test-pd-pd-0: container init terminated with exit code 1: Error: permission denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pd-pd-0"}, Status: tt.status}
+			g.Expect(podFailure(pod)).To(Equal(tt.failure))
+		})
+	}
+}
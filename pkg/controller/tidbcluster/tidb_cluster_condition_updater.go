@@ -14,6 +14,9 @@
 package tidbcluster
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
 	appsv1 "k8s.io/api/apps/v1"
@@ -33,21 +36,41 @@ var _ TidbClusterConditionUpdater = &tidbClusterConditionUpdater{}
 
 func (u *tidbClusterConditionUpdater) Update(tc *v1alpha1.TidbCluster) error {
 	u.updateReadyCondition(tc)
+	u.updateStoragePressureCondition(tc)
 	// in the future, we may return error when we need to Kubernetes API, etc.
 	return nil
 }
 
-func allStatefulSetsAreUpToDate(tc *v1alpha1.TidbCluster) bool {
+// statefulSetsNotUpToDate returns the components whose statefulsets are not up to date, the ones
+// paused by their own `paused` field are returned separately as their rolling updates are frozen
+// on purpose rather than in progress.
+func statefulSetsNotUpToDate(tc *v1alpha1.TidbCluster) (inProgress []string, paused []string) {
 	isUpToDate := func(status *appsv1.StatefulSetStatus, requireExist bool) bool {
 		if status == nil {
 			return !requireExist
 		}
 		return status.CurrentRevision == status.UpdateRevision
 	}
-	return (isUpToDate(tc.Status.PD.StatefulSet, false)) &&
-		(isUpToDate(tc.Status.TiKV.StatefulSet, false)) &&
-		(isUpToDate(tc.Status.TiDB.StatefulSet, false)) &&
-		isUpToDate(tc.Status.TiFlash.StatefulSet, false)
+	components := []struct {
+		memberType v1alpha1.MemberType
+		status     *appsv1.StatefulSetStatus
+	}{
+		{v1alpha1.PDMemberType, tc.Status.PD.StatefulSet},
+		{v1alpha1.TiKVMemberType, tc.Status.TiKV.StatefulSet},
+		{v1alpha1.TiDBMemberType, tc.Status.TiDB.StatefulSet},
+		{v1alpha1.TiFlashMemberType, tc.Status.TiFlash.StatefulSet},
+	}
+	for _, c := range components {
+		if isUpToDate(c.status, false) {
+			continue
+		}
+		if !tc.Spec.Paused && tc.IsComponentPaused(c.memberType) {
+			paused = append(paused, c.memberType.String())
+		} else {
+			inProgress = append(inProgress, c.memberType.String())
+		}
+	}
+	return inProgress, paused
 }
 
 func (u *tidbClusterConditionUpdater) updateReadyCondition(tc *v1alpha1.TidbCluster) {
@@ -55,10 +78,14 @@ func (u *tidbClusterConditionUpdater) updateReadyCondition(tc *v1alpha1.TidbClus
 	reason := ""
 	message := ""
 
+	inProgress, paused := statefulSetsNotUpToDate(tc)
 	switch {
-	case !allStatefulSetsAreUpToDate(tc):
+	case len(inProgress) > 0:
 		reason = utiltidbcluster.StatfulSetNotUpToDate
 		message = "Statefulset(s) are in progress"
+	case len(paused) > 0:
+		reason = utiltidbcluster.PausedStatefulSetNotUpToDate
+		message = fmt.Sprintf("Statefulset(s) of paused component(s) %s are not up to date", strings.Join(paused, ", "))
 	case tc.Spec.PD != nil && !tc.PDAllMembersReady():
 		reason = utiltidbcluster.PDUnhealthy
 		message = "PD(s) are not healthy"
@@ -79,3 +106,44 @@ func (u *tidbClusterConditionUpdater) updateReadyCondition(tc *v1alpha1.TidbClus
 	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterReady, status, reason, message)
 	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
 }
+
+// updateStoragePressureCondition sets the StoragePressure condition by the storage usage of the components, the
+// condition is not set until the storage usage of any component is collected
+func (u *tidbClusterConditionUpdater) updateStoragePressureCondition(tc *v1alpha1.TidbCluster) {
+	usages := []*v1alpha1.StorageUsage{
+		tc.Status.PD.StorageUsage,
+		tc.Status.TiKV.StorageUsage,
+		tc.Status.TiFlash.StorageUsage,
+		tc.Status.Pump.StorageUsage,
+	}
+	collected := false
+	var pods []string
+	for _, usage := range usages {
+		if usage == nil {
+			continue
+		}
+		collected = true
+		pods = append(pods, usage.PodsOverThreshold...)
+	}
+	if !collected {
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterStoragePressure)
+		return
+	}
+
+	status := v1.ConditionFalse
+	reason := utiltidbcluster.StorageUnderThreshold
+	message := fmt.Sprintf("The used storage of all the pods is below %d%%", tc.StorageWarningThreshold())
+	if len(pods) > 0 {
+		status = v1.ConditionTrue
+		reason = utiltidbcluster.StorageOverThreshold
+		message = fmt.Sprintf("The used storage of pod(s) %s exceeds %d%%", strings.Join(pods, ", "), tc.StorageWarningThreshold())
+	}
+	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterStoragePressure, status, reason, message)
+	// the message is updated with the pods without changing the transition time
+	current := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterStoragePressure)
+	if current != nil && current.Status == status && current.Reason == reason && current.Message != message {
+		cond.LastTransitionTime = current.LastTransitionTime
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterStoragePressure)
+	}
+	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
+}
@@ -341,6 +341,61 @@ func TestTidbClusterConditionUpdater_Ready(t *testing.T) {
 			wantReason:  utiltidbcluster.Ready,
 			wantMessage: "TiDB cluster is fully up and running",
 		},
+		{
+			name: "statefulset of paused component not up to date",
+			tc: &v1alpha1.TidbCluster{
+				Spec: v1alpha1.TidbClusterSpec{
+					PD: &v1alpha1.PDSpec{
+						Replicas: 1,
+					},
+					TiKV: &v1alpha1.TiKVSpec{
+						Replicas: 1,
+						Paused:   true,
+					},
+					TiDB: &v1alpha1.TiDBSpec{
+						Replicas: 1,
+					},
+				},
+				Status: v1alpha1.TidbClusterStatus{
+					PD: v1alpha1.PDStatus{
+						Members: map[string]v1alpha1.PDMember{
+							"pd-0": {
+								Health: true,
+							},
+						},
+						StatefulSet: &appsv1.StatefulSetStatus{
+							CurrentRevision: "2",
+							UpdateRevision:  "2",
+						},
+					},
+					TiDB: v1alpha1.TiDBStatus{
+						Members: map[string]v1alpha1.TiDBMember{
+							"tidb-0": {
+								Health: true,
+							},
+						},
+						StatefulSet: &appsv1.StatefulSetStatus{
+							CurrentRevision: "2",
+							UpdateRevision:  "2",
+						},
+					},
+					TiKV: v1alpha1.TiKVStatus{
+						Stores: map[string]v1alpha1.TiKVStore{
+							"tikv-0": {
+								State: "Up",
+							},
+						},
+						StatefulSet: &appsv1.StatefulSetStatus{
+							CurrentRevision: "1",
+							UpdateRevision:  "2",
+						},
+					},
+				},
+			},
+			wantStatus:  v1.ConditionFalse,
+			wantReason:  utiltidbcluster.PausedStatefulSetNotUpToDate,
+			wantMessage: "Statefulset(s) of paused component(s) tikv are not up to date",
+		},
 	}
 
 	for _, tt := range tests {
@@ -360,3 +415,74 @@ func TestTidbClusterConditionUpdater_Ready(t *testing.T) {
 		})
 	}
 }
+
+func TestTidbClusterConditionUpdater_StoragePressure(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      v1alpha1.TidbClusterStatus
+		wantCond    bool
+		wantStatus  v1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:     "storage usage not collected",
+			status:   v1alpha1.TidbClusterStatus{},
+			wantCond: false,
+		},
+		{
+			name: "all pods below the threshold",
+			status: v1alpha1.TidbClusterStatus{
+				PD:   v1alpha1.PDStatus{StorageUsage: &v1alpha1.StorageUsage{MaxUsedPercent: 20, MaxUsedPod: "demo-pd-0"}},
+				TiKV: v1alpha1.TiKVStatus{StorageUsage: &v1alpha1.StorageUsage{MaxUsedPercent: 75, MaxUsedPod: "demo-tikv-1"}},
+			},
+			wantCond:    true,
+			wantStatus:  v1.ConditionFalse,
+			wantReason:  utiltidbcluster.StorageUnderThreshold,
+			wantMessage: "The used storage of all the pods is below 80%",
+		},
+		{
+			name: "pods over the threshold",
+			status: v1alpha1.TidbClusterStatus{
+				TiKV: v1alpha1.TiKVStatus{StorageUsage: &v1alpha1.StorageUsage{
+					MaxUsedPercent:    92,
+					MaxUsedPod:        "demo-tikv-1",
+					PodsOverThreshold: []string{"demo-tikv-0", "demo-tikv-1"},
+				}},
+				Pump: v1alpha1.PumpStatus{StorageUsage: &v1alpha1.StorageUsage{
+					MaxUsedPercent:    85,
+					MaxUsedPod:        "demo-pump-0",
+					PodsOverThreshold: []string{"demo-pump-0"},
+				}},
+			},
+			wantCond:    true,
+			wantStatus:  v1.ConditionTrue,
+			wantReason:  utiltidbcluster.StorageOverThreshold,
+			wantMessage: "The used storage of pod(s) demo-tikv-0, demo-tikv-1, demo-pump-0 exceeds 80%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &v1alpha1.TidbCluster{Status: tt.status}
+			conditionUpdater := &tidbClusterConditionUpdater{}
+			conditionUpdater.Update(tc)
+			cond := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterStoragePressure)
+			if !tt.wantCond {
+				if cond != nil {
+					t.Errorf("unexpected condition: %v", cond)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.wantStatus, cond.Status); diff != "" {
+				t.Errorf("unexpected status (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantReason, cond.Reason); diff != "" {
+				t.Errorf("unexpected reason (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.wantMessage, cond.Message); diff != "" {
+				t.Errorf("unexpected message (-want, +got): %s", diff)
+			}
+		})
+	}
+}
@@ -18,12 +18,14 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/defaulting"
 	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/manager"
 	"github.com/pingcap/tidb-operator/pkg/manager/member"
 	"github.com/pingcap/tidb-operator/pkg/metrics"
 	v1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	storagelister "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
@@ -45,6 +47,8 @@ func NewDefaultTidbClusterControl(
 	tidbMemberManager manager.Manager,
 	reclaimPolicyManager manager.Manager,
 	metaManager manager.Manager,
+	adoptionManager manager.Manager,
+	restartManager manager.Manager,
 	orphanPodsCleaner member.OrphanPodsCleaner,
 	pvcCleaner member.PVCCleanerInterface,
 	pvcResizer member.PVCResizerInterface,
@@ -54,6 +58,9 @@ func NewDefaultTidbClusterControl(
 	discoveryManager member.TidbDiscoveryManager,
 	tidbClusterStatusManager manager.Manager,
 	conditionUpdater TidbClusterConditionUpdater,
+	deleter TidbClusterDeleter,
+	bootstrapChecker TidbClusterBootstrapChecker,
+	storageClassLister storagelister.StorageClassLister,
 	recorder record.EventRecorder) ControlInterface {
 	return &defaultTidbClusterControl{
 		tcControl:                tcControl,
@@ -62,6 +69,8 @@ func NewDefaultTidbClusterControl(
 		tidbMemberManager:        tidbMemberManager,
 		reclaimPolicyManager:     reclaimPolicyManager,
 		metaManager:              metaManager,
+		adoptionManager:          adoptionManager,
+		restartManager:           restartManager,
 		orphanPodsCleaner:        orphanPodsCleaner,
 		pvcCleaner:               pvcCleaner,
 		pvcResizer:               pvcResizer,
@@ -71,6 +80,9 @@ func NewDefaultTidbClusterControl(
 		discoveryManager:         discoveryManager,
 		tidbClusterStatusManager: tidbClusterStatusManager,
 		conditionUpdater:         conditionUpdater,
+		deleter:                  deleter,
+		bootstrapChecker:         bootstrapChecker,
+		storageClassLister:       storageClassLister,
 		recorder:                 recorder,
 	}
 }
@@ -82,6 +94,8 @@ type defaultTidbClusterControl struct {
 	tidbMemberManager        manager.Manager
 	reclaimPolicyManager     manager.Manager
 	metaManager              manager.Manager
+	adoptionManager          manager.Manager
+	restartManager           manager.Manager
 	orphanPodsCleaner        member.OrphanPodsCleaner
 	pvcCleaner               member.PVCCleanerInterface
 	pvcResizer               member.PVCResizerInterface
@@ -91,11 +105,19 @@ type defaultTidbClusterControl struct {
 	discoveryManager         member.TidbDiscoveryManager
 	tidbClusterStatusManager manager.Manager
 	conditionUpdater         TidbClusterConditionUpdater
+	deleter                  TidbClusterDeleter
+	bootstrapChecker         TidbClusterBootstrapChecker
+	storageClassLister       storagelister.StorageClassLister
 	recorder                 record.EventRecorder
 }
 
 // UpdateStatefulSet executes the core logic loop for a tidbcluster.
 func (c *defaultTidbClusterControl) UpdateTidbCluster(tc *v1alpha1.TidbCluster) error {
+	// the TidbCluster being torn down in order is not reconciled any more
+	if deleting, err := c.deleter.Sync(tc); deleting || err != nil {
+		return err
+	}
+
 	c.defaulting(tc)
 	if !c.validate(tc) {
 		return nil // fatal error, no need to retry on invalid object
@@ -124,21 +146,59 @@ func (c *defaultTidbClusterControl) UpdateTidbCluster(tc *v1alpha1.TidbCluster)
 
 func (c *defaultTidbClusterControl) validate(tc *v1alpha1.TidbCluster) bool {
 	errs := v1alpha1validation.ValidateTidbCluster(tc)
+	// the StorageClasses are validated before the components are created as the webhook may be not deployed
+	if c.storageClassLister != nil {
+		var refs []v1alpha1validation.StorageClassRef
+		for _, ref := range v1alpha1validation.TidbClusterStorageClassRefs(tc) {
+			if !componentCreated(tc, ref.MemberType) {
+				refs = append(refs, ref)
+			}
+		}
+		scErrs, warnings := v1alpha1validation.ValidateStorageClasses(refs, c.storageClassLister)
+		errs = append(errs, scErrs...)
+		for _, warning := range warnings {
+			c.recorder.Event(tc, v1.EventTypeWarning, events.StorageClassWarning, warning)
+		}
+	}
 	if len(errs) > 0 {
 		aggregatedErr := errs.ToAggregate()
 		klog.Errorf("tidb cluster %s/%s is not valid and must be fixed first, aggregated error: %v", tc.GetNamespace(), tc.GetName(), aggregatedErr)
-		c.recorder.Event(tc, v1.EventTypeWarning, "FailedValidation", aggregatedErr.Error())
+		c.recorder.Event(tc, v1.EventTypeWarning, events.FailedValidation, aggregatedErr.Error())
 		return false
 	}
 	return true
 }
 
+// componentCreated returns whether the StatefulSet of the component is created
+func componentCreated(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) bool {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return tc.Status.PD.StatefulSet != nil
+	case v1alpha1.TiKVMemberType:
+		return tc.Status.TiKV.StatefulSet != nil
+	case v1alpha1.TiDBMemberType:
+		return tc.Status.TiDB.StatefulSet != nil
+	case v1alpha1.TiFlashMemberType:
+		return tc.Status.TiFlash.StatefulSet != nil
+	case v1alpha1.TiCDCMemberType:
+		return tc.Status.TiCDC.StatefulSet != nil
+	case v1alpha1.PumpMemberType:
+		return tc.Status.Pump.StatefulSet != nil
+	}
+	return false
+}
+
 func (c *defaultTidbClusterControl) defaulting(tc *v1alpha1.TidbCluster) {
 	defaulting.SetTidbClusterDefault(tc)
 }
 
 func (c *defaultTidbClusterControl) updateTidbCluster(tc *v1alpha1.TidbCluster) error {
 	c.recordMetrics(tc)
+	// repairing the ownerReferences of the objects restored from a backup of the namespace before they are synced
+	if err := c.adoptionManager.Sync(tc); err != nil {
+		return err
+	}
+
 	// syncing all PVs managed by operator's reclaim policy to Retain
 	if err := c.reclaimPolicyManager.Sync(tc); err != nil {
 		return err
@@ -161,6 +221,12 @@ func (c *defaultTidbClusterControl) updateTidbCluster(tc *v1alpha1.TidbCluster)
 		return err
 	}
 
+	// handling the graceful rolling restarts of the components requested by the annotation, the pods are
+	// restarted by the upgraders of the components below
+	if err := c.restartManager.Sync(tc); err != nil {
+		return err
+	}
+
 	// works that should be done to make the pd cluster current state match the desired state:
 	//   - create or update the pd service
 	//   - create or update the pd headless service
@@ -172,8 +238,15 @@ func (c *defaultTidbClusterControl) updateTidbCluster(tc *v1alpha1.TidbCluster)
 	//   - upgrade the pd cluster
 	//   - scale out/in the pd cluster
 	//   - failover the pd cluster
-	if err := c.pdMemberManager.Sync(tc); err != nil {
-		return err
+	pdErr := c.pdMemberManager.Sync(tc)
+
+	// the components depending on PD are not synced if PD fails to bootstrap in time until PD recovers,
+	// so that they do not retry in vain forever
+	if c.bootstrapChecker.Check(tc) {
+		return pdErr
+	}
+	if pdErr != nil {
+		return pdErr
 	}
 
 	// works that should be done to make the tiflash cluster current state match the desired state:
@@ -230,6 +303,7 @@ func (c *defaultTidbClusterControl) updateTidbCluster(tc *v1alpha1.TidbCluster)
 	//   - label.StoreIDLabelKey
 	//   - label.MemberIDLabelKey
 	//   - label.NamespaceLabelKey
+	// and ensuring the canonical labels on the objects of the cluster
 	if err := c.metaManager.Sync(tc); err != nil {
 		return err
 	}
@@ -329,6 +329,8 @@ func newFakeTidbClusterControl() (
 		tidbMemberManager,
 		reclaimPolicyManager,
 		metaManager,
+		meta.NewFakeAdoptionManager(),
+		mm.NewFakeRestartManager(),
 		orphanPodCleaner,
 		pvcCleaner,
 		pvcResizer,
@@ -338,6 +340,9 @@ func newFakeTidbClusterControl() (
 		discoveryManager,
 		statusManager,
 		&tidbClusterConditionUpdater{},
+		NewTidbClusterDeleter(controller.NewFakeDependencies()),
+		NewFakeTidbClusterBootstrapChecker(),
+		nil,
 		recorder,
 	)
 
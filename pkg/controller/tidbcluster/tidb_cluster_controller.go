@@ -26,12 +26,18 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
+const (
+	// bootstrapFailedMinResyncInterval and bootstrapFailedMaxResyncInterval are the bounds of the backoff of the
+	// resyncs of the tidbclusters failing to bootstrap
+	bootstrapFailedMinResyncInterval = time.Minute
+	bootstrapFailedMaxResyncInterval = 10 * time.Minute
+)
+
 // Controller controls tidbclusters.
 type Controller struct {
 	deps *controller.Dependencies
@@ -40,6 +46,10 @@ type Controller struct {
 	control ControlInterface
 	// tidbclusters that need to be synced.
 	queue workqueue.RateLimitingInterface
+	// storeWatcher enqueues the tidbclusters whose TiKV stores become Disconnected or Down
+	storeWatcher *storeWatcher
+	// bootstrapBackoff is the backoff of the resyncs of the tidbclusters failing to bootstrap
+	bootstrapBackoff workqueue.RateLimiter
 }
 
 // NewController creates a tidbcluster controller.
@@ -48,33 +58,46 @@ func NewController(deps *controller.Dependencies) *Controller {
 		deps: deps,
 		control: NewDefaultTidbClusterControl(
 			deps.TiDBClusterControl,
-			mm.NewPDMemberManager(deps, mm.NewPDScaler(deps), mm.NewPDUpgrader(deps), mm.NewPDFailover(deps)),
-			mm.NewTiKVMemberManager(deps, mm.NewTiKVFailover(deps), mm.NewTiKVScaler(deps), mm.NewTiKVUpgrader(deps)),
-			mm.NewTiDBMemberManager(deps, mm.NewTiDBScaler(deps), mm.NewTiDBUpgrader(deps), mm.NewTiDBFailover(deps)),
+			mm.NewPDMemberManager(deps, mm.NewPDScaler(deps), mm.NewTracingUpgrader(deps, v1alpha1.PDMemberType, mm.NewPDUpgrader(deps)), mm.NewPDFailover(deps)),
+			mm.NewTiKVMemberManager(deps, mm.NewTiKVFailover(deps), mm.NewTiKVScaler(deps), mm.NewTracingTiKVUpgrader(deps, mm.NewTiKVUpgrader(deps))),
+			mm.NewTiDBMemberManager(deps, mm.NewTiDBScaler(deps), mm.NewTracingUpgrader(deps, v1alpha1.TiDBMemberType, mm.NewTiDBUpgrader(deps)), mm.NewTiDBFailover(deps)),
 			meta.NewReclaimPolicyManager(deps),
 			meta.NewMetaManager(deps),
+			meta.NewAdoptionManager(deps),
+			mm.NewRestartManager(deps),
 			mm.NewOrphanPodsCleaner(deps),
 			mm.NewRealPVCCleaner(deps),
 			mm.NewPVCResizer(deps),
 			mm.NewPumpMemberManager(deps, mm.NewPumpScaler(deps)),
-			mm.NewTiFlashMemberManager(deps, mm.NewTiFlashFailover(deps), mm.NewTiFlashScaler(deps), mm.NewTiFlashUpgrader(deps)),
-			mm.NewTiCDCMemberManager(deps, mm.NewTiCDCScaler(deps), mm.NewTiCDCUpgrader(deps)),
+			mm.NewTiFlashMemberManager(deps, mm.NewTiFlashFailover(deps), mm.NewTiFlashScaler(deps), mm.NewTracingUpgrader(deps, v1alpha1.TiFlashMemberType, mm.NewTiFlashUpgrader(deps))),
+			mm.NewTiCDCMemberManager(deps, mm.NewTiCDCScaler(deps), mm.NewTracingUpgrader(deps, v1alpha1.TiCDCMemberType, mm.NewTiCDCUpgrader(deps))),
 			mm.NewTidbDiscoveryManager(deps),
 			mm.NewTidbClusterStatusManager(deps),
 			&tidbClusterConditionUpdater{},
+			NewTidbClusterDeleter(deps),
+			NewTidbClusterBootstrapChecker(deps),
+			deps.StorageClassLister,
 			deps.Recorder,
 		),
 		queue: workqueue.NewNamedRateLimitingQueue(
 			controller.NewControllerRateLimiter(1*time.Second, 100*time.Second),
 			"tidbcluster",
 		),
+		bootstrapBackoff: workqueue.NewItemExponentialFailureRateLimiter(bootstrapFailedMinResyncInterval, bootstrapFailedMaxResyncInterval),
 	}
+	c.storeWatcher = newStoreWatcher(deps, func(key string) { c.queue.Add(key) })
 
 	tidbClusterInformer := deps.InformerFactory.Pingcap().V1alpha1().TidbClusters()
 	statefulsetInformer := deps.KubeInformerFactory.Apps().V1().StatefulSets()
 	tidbClusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: c.enqueueTidbCluster,
 		UpdateFunc: func(old, cur interface{}) {
+			// the periodic resyncs of the tidbclusters failing to bootstrap are skipped, they are resynced
+			// with the backoff
+			oldTC, curTC := old.(*v1alpha1.TidbCluster), cur.(*v1alpha1.TidbCluster)
+			if oldTC.ResourceVersion == curTC.ResourceVersion && bootstrapFailed(curTC) {
+				return
+			}
 			c.enqueueTidbCluster(cur)
 		},
 		DeleteFunc: c.enqueueTidbCluster,
@@ -94,23 +117,16 @@ func NewController(deps *controller.Dependencies) *Controller {
 func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	defer c.storeWatcher.stopAll()
 
 	klog.Info("Starting tidbcluster controller")
 	defer klog.Info("Shutting down tidbcluster controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.TiDBClusterControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-// worker runs a worker goroutine that invokes processNextWorkItem until the the controller's queue is closed
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *Controller) processNextWorkItem() bool {
@@ -119,6 +135,12 @@ func (c *Controller) processNextWorkItem() bool {
 		return false
 	}
 	defer c.queue.Done(key)
+	if !c.deps.ShutdownDrain.Begin(key.(string)) {
+		// tidb-controller-manager is shutting down, the key is left to the next leader
+		c.queue.AddRateLimited(key)
+		return false
+	}
+	defer c.deps.ShutdownDrain.End(key.(string))
 	if err := c.sync(key.(string)); err != nil {
 		if perrors.Find(err, controller.IsRequeueError) != nil {
 			klog.Infof("TidbCluster: %v, still need sync: %v, requeuing", key.(string), err)
@@ -146,17 +168,31 @@ func (c *Controller) sync(key string) error {
 	tc, err := c.deps.TiDBClusterLister.TidbClusters(ns).Get(name)
 	if errors.IsNotFound(err) {
 		klog.Infof("TidbCluster has been deleted %v", key)
+		c.storeWatcher.stop(key)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
+	c.storeWatcher.sync(key, tc)
 
-	return c.syncTidbCluster(tc.DeepCopy())
+	tc = tc.DeepCopy()
+	err = c.syncTidbCluster(tc)
+	if bootstrapFailed(tc) {
+		// the errors are not retried by the rate limiter as the components depending on PD are not synced
+		delay := c.bootstrapBackoff.When(key)
+		klog.Infof("TidbCluster: %v, fails to bootstrap, resync after %v, err: %v", key, delay, err)
+		c.queue.AddAfter(key, delay)
+		return nil
+	}
+	c.bootstrapBackoff.Forget(key)
+	return err
 }
 
 func (c *Controller) syncTidbCluster(tc *v1alpha1.TidbCluster) error {
-	return c.control.UpdateTidbCluster(tc)
+	err := c.control.UpdateTidbCluster(tc)
+	c.deps.UpgradeTracer.SyncCluster(tc)
+	return err
 }
 
 // enqueueTidbCluster enqueues the given tidbcluster in the work queue.
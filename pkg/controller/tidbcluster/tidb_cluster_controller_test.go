@@ -200,6 +200,7 @@ func TestTidbClusterControllerSync(t *testing.T) {
 		name                     string
 		addTcToIndexer           bool
 		errWhenUpdateTidbCluster bool
+		bootstrapFailed          bool
 		errExpectFn              func(*GomegaWithT, error)
 	}
 
@@ -207,6 +208,9 @@ func TestTidbClusterControllerSync(t *testing.T) {
 		t.Log(test.name)
 
 		tc := newTidbCluster()
+		if test.bootstrapFailed {
+			tc.Status.Conditions = []v1alpha1.TidbClusterCondition{{Type: v1alpha1.TidbClusterBootstrapFailed, Status: corev1.ConditionTrue}}
+		}
 		fakeDeps := controller.NewFakeDependencies()
 		tcc := NewController(fakeDeps)
 		tcIndexer := fakeDeps.InformerFactory.Pingcap().V1alpha1().TidbClusters().Informer().GetIndexer()
@@ -228,6 +232,12 @@ func TestTidbClusterControllerSync(t *testing.T) {
 		if test.errExpectFn != nil {
 			test.errExpectFn(g, err)
 		}
+		// the tidbcluster failing to bootstrap is resynced with the backoff
+		if test.bootstrapFailed {
+			g.Expect(tcc.bootstrapBackoff.NumRequeues(key)).To(Equal(1))
+		} else {
+			g.Expect(tcc.bootstrapBackoff.NumRequeues(key)).To(Equal(0))
+		}
 	}
 
 	tests := []testcase{
@@ -256,6 +266,15 @@ func TestTidbClusterControllerSync(t *testing.T) {
 				g.Expect(strings.Contains(err.Error(), "update tidb cluster failed")).To(Equal(true))
 			},
 		},
+		{
+			name:                     "tidb cluster fails to bootstrap",
+			addTcToIndexer:           true,
+			errWhenUpdateTidbCluster: true,
+			bootstrapFailed:          true,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+		},
 	}
 
 	for i := range tests {
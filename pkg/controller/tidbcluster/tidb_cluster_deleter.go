@@ -0,0 +1,292 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/backup/backupschedule"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/util/slice"
+)
+
+const defaultFinalBackupTimeout = time.Hour
+
+// TidbClusterDeleter protects the TidbClusters with the deletion protection, and tears down the
+// TidbClusters with a deletion policy in order.
+type TidbClusterDeleter interface {
+	// Sync syncs the deletion protection finalizer, adds the deletion finalizer to the TidbCluster
+	// with a deletion policy, and tears down the TidbCluster once it is being deleted. It returns true
+	// if the TidbCluster is being torn down or its deletion is not confirmed yet, so that the
+	// TidbCluster should not be reconciled any more.
+	Sync(*v1alpha1.TidbCluster) (bool, error)
+}
+
+type tidbClusterDeleter struct {
+	deps *controller.Dependencies
+}
+
+// NewTidbClusterDeleter returns a TidbClusterDeleter
+func NewTidbClusterDeleter(deps *controller.Dependencies) TidbClusterDeleter {
+	return &tidbClusterDeleter{deps: deps}
+}
+
+var _ TidbClusterDeleter = &tidbClusterDeleter{}
+
+// teardownStep is the components removed in a step of the teardown, the next step
+// starts after all the pods of the components are gone.
+type teardownStep []v1alpha1.MemberType
+
+// teardownSteps is the order of the teardown, the stores are not removed from PD
+// as the whole cluster is deleted.
+var teardownSteps = []teardownStep{
+	{v1alpha1.TiDBMemberType},
+	{v1alpha1.TiFlashMemberType, v1alpha1.TiCDCMemberType, v1alpha1.PumpMemberType},
+	{v1alpha1.TiKVMemberType},
+	{v1alpha1.PDMemberType},
+}
+
+func (d *tidbClusterDeleter) Sync(tc *v1alpha1.TidbCluster) (bool, error) {
+	protected := controller.DeletionProtected(tc.Spec.DeletionProtection, d.deps.CLIConfig.DeletionProtection)
+	patchFinalizers := func(finalizers []string) error { return d.patchFinalizers(tc, finalizers) }
+	if stop, err := controller.SyncDeletionProtection(tc, "TidbCluster", protected, d.deps.Recorder, patchFinalizers); stop || err != nil {
+		return true, err
+	}
+
+	hasFinalizer := slice.ContainsString(tc.Finalizers, label.TidbClusterDeletionFinalizer, nil)
+	if tc.DeletionTimestamp == nil {
+		if tc.Spec.DeletionPolicy != nil && !hasFinalizer {
+			return false, d.patchFinalizers(tc, append(tc.Finalizers, label.TidbClusterDeletionFinalizer))
+		}
+		if tc.Spec.DeletionPolicy == nil && hasFinalizer {
+			return false, d.patchFinalizers(tc, slice.RemoveString(tc.Finalizers, label.TidbClusterDeletionFinalizer, nil))
+		}
+		return false, nil
+	}
+	if !hasFinalizer {
+		return false, nil
+	}
+
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if tc.Annotations[label.AnnForceDeleteKey] == label.AnnForceDeleteVal {
+		klog.Warningf("tidbcluster: [%s/%s] is force deleted, skip the ordered teardown", ns, tcName)
+		return true, d.removeFinalizer(tc)
+	}
+
+	if err := d.syncFinalBackup(tc); err != nil {
+		return true, err
+	}
+	for _, step := range teardownSteps {
+		if err := d.teardown(tc, step); err != nil {
+			return true, err
+		}
+	}
+	if err := d.teardownDiscovery(tc); err != nil {
+		return true, err
+	}
+
+	klog.Infof("tidbcluster: [%s/%s] is torn down", ns, tcName)
+	return true, d.removeFinalizer(tc)
+}
+
+// syncFinalBackup creates the final Backup from the BackupSchedule, and returns nil after the
+// Backup is complete or failed, or its timeout elapses.
+func (d *tidbClusterDeleter) syncFinalBackup(tc *v1alpha1.TidbCluster) error {
+	if tc.Spec.DeletionPolicy == nil || tc.Spec.DeletionPolicy.FinalBackup == nil {
+		return nil
+	}
+	fb := tc.Spec.DeletionPolicy.FinalBackup
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	timeout := defaultFinalBackupTimeout
+	if fb.Timeout != nil {
+		if dur, err := time.ParseDuration(*fb.Timeout); err == nil {
+			timeout = dur
+		}
+	}
+	if time.Since(tc.DeletionTimestamp.Time) > timeout {
+		msg := fmt.Sprintf("final backup is not complete in %s, tear down the cluster", timeout)
+		klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+		d.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.FinalBackupTimeout, msg)
+		return nil
+	}
+
+	backupName := finalBackupName(tcName)
+	backup, err := d.deps.BackupLister.Backups(ns).Get(backupName)
+	if errors.IsNotFound(err) {
+		bs, err := d.deps.BackupScheduleLister.BackupSchedules(ns).Get(fb.BackupScheduleName)
+		if err != nil {
+			return fmt.Errorf("get backup schedule %s/%s for the final backup of tidbcluster %s failed: %v", ns, fb.BackupScheduleName, tcName, err)
+		}
+		backup = backupschedule.BuildBackup(bs, tc.DeletionTimestamp.Time)
+		backup.Name = backupName
+		// the final backup is kept after the TidbCluster and the BackupSchedule are deleted,
+		// and is not garbage collected by the BackupSchedule
+		backup.Labels = label.NewBackup().Instance(tcName).Labels()
+		backup.OwnerReferences = nil
+		if _, err := d.deps.BackupControl.CreateBackup(backup); err != nil {
+			return err
+		}
+		return controller.RequeueErrorf("tidbcluster: [%s/%s] final backup %s is created", ns, tcName, backupName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if v1alpha1.IsBackupFailed(backup) || v1alpha1.IsBackupInvalid(backup) {
+		msg := fmt.Sprintf("final backup %s failed, tear down the cluster", backupName)
+		klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+		d.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.FinalBackupFailed, msg)
+		return nil
+	}
+	if !v1alpha1.IsBackupComplete(backup) {
+		return controller.RequeueErrorf("tidbcluster: [%s/%s] waiting for the final backup %s to complete", ns, tcName, backupName)
+	}
+	return nil
+}
+
+// teardown deletes the statefulsets of the components, and returns nil after their pods are gone.
+// The statefulsets of the tidb groups are torn down with tidb, their pods are labeled as tidb too.
+func (d *tidbClusterDeleter) teardown(tc *v1alpha1.TidbCluster, step teardownStep) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	var remaining []v1alpha1.MemberType
+	for _, memberType := range step {
+		setsGone := true
+		for _, setName := range statefulSetNames(tc, memberType) {
+			set, err := d.deps.StatefulSetLister.StatefulSets(ns).Get(setName)
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			setsGone = false
+			if set.DeletionTimestamp == nil {
+				if err := d.deps.StatefulSetControl.DeleteStatefulSet(tc, set); err != nil {
+					return err
+				}
+			}
+		}
+		if !setsGone {
+			remaining = append(remaining, memberType)
+			continue
+		}
+
+		selector, err := label.New().Instance(tcName).Component(memberType.String()).Selector()
+		if err != nil {
+			return err
+		}
+		pods, err := d.deps.PodLister.Pods(ns).List(selector)
+		if err != nil {
+			return err
+		}
+		if len(pods) > 0 {
+			remaining = append(remaining, memberType)
+		}
+	}
+	if len(remaining) > 0 {
+		return controller.RequeueErrorf("tidbcluster: [%s/%s] waiting for %v to be torn down", ns, tcName, remaining)
+	}
+	return nil
+}
+
+func (d *tidbClusterDeleter) teardownDiscovery(tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	name := controller.DiscoveryMemberName(tc.GetName())
+	deploy, err := d.deps.DeploymentLister.Deployments(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if deploy.DeletionTimestamp == nil {
+		if err := d.deps.KubeClientset.AppsV1().Deployments(ns).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return controller.RequeueErrorf("tidbcluster: [%s/%s] waiting for discovery to be torn down", ns, tc.GetName())
+}
+
+func (d *tidbClusterDeleter) removeFinalizer(tc *v1alpha1.TidbCluster) error {
+	return d.patchFinalizers(tc, slice.RemoveString(tc.Finalizers, label.TidbClusterDeletionFinalizer, nil))
+}
+
+// patchFinalizers patches the finalizers only, as the spec of tc has been defaulted in reconciliation.
+func (d *tidbClusterDeleter) patchFinalizers(tc *v1alpha1.TidbCluster, finalizers []string) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": tc.ResourceVersion,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	_, err = d.deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Patch(context.TODO(), tc.Name, types.MergePatchType, data, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("patch finalizers of tidbcluster %s/%s failed, err: %v", tc.Namespace, tc.Name, err)
+	}
+	tc.Finalizers = finalizers
+	return nil
+}
+
+func finalBackupName(tcName string) string {
+	return fmt.Sprintf("%s-final-backup", tcName)
+}
+
+// statefulSetNames returns the names of the statefulsets of the component, including the ones of
+// the tidb groups for tidb.
+func statefulSetNames(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) []string {
+	names := []string{statefulSetName(tc.GetName(), memberType)}
+	if memberType == v1alpha1.TiDBMemberType {
+		for _, group := range tc.Spec.TiDBGroups {
+			names = append(names, controller.TiDBGroupMemberName(tc.GetName(), group.Name))
+		}
+	}
+	return names
+}
+
+func statefulSetName(tcName string, memberType v1alpha1.MemberType) string {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return controller.PDMemberName(tcName)
+	case v1alpha1.TiKVMemberType:
+		return controller.TiKVMemberName(tcName)
+	case v1alpha1.TiFlashMemberType:
+		return controller.TiFlashMemberName(tcName)
+	case v1alpha1.TiCDCMemberType:
+		return controller.TiCDCMemberName(tcName)
+	case v1alpha1.PumpMemberType:
+		return controller.PumpMemberName(tcName)
+	default:
+		return controller.TiDBMemberName(tcName)
+	}
+}
@@ -0,0 +1,343 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tidbcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestTidbClusterDeleterFinalizer(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	deleter := NewTidbClusterDeleter(deps)
+	tc := newTidbClusterForTidbClusterControl()
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{}
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// the finalizer is added with the deletion policy
+	deleting, err := deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deleting).To(BeFalse())
+	got, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Get(context.TODO(), tc.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got.Finalizers).To(ConsistOf(label.TidbClusterDeletionFinalizer))
+
+	// the finalizer is removed without the deletion policy
+	tc = got.DeepCopy()
+	tc.Spec.DeletionPolicy = nil
+	deleting, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deleting).To(BeFalse())
+	got, err = deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Get(context.TODO(), tc.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got.Finalizers).To(BeEmpty())
+
+	// the TidbCluster being deleted without the finalizer is reconciled as before
+	tc = got.DeepCopy()
+	now := metav1.Now()
+	tc.DeletionTimestamp = &now
+	deleting, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deleting).To(BeFalse())
+
+	// the finalizer is removed directly if force deleted
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{}
+	tc.Finalizers = []string{label.TidbClusterDeletionFinalizer}
+	tc.Annotations = map[string]string{label.AnnForceDeleteKey: label.AnnForceDeleteVal}
+	indexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	g.Expect(indexer.Add(newStatefulSetForDeleter(tc, controller.TiDBMemberName(tc.Name)))).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(tc.Finalizers).To(BeEmpty())
+}
+
+func TestTidbClusterDeleterDeletionProtection(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	deleter := NewTidbClusterDeleter(deps)
+	tc := newTidbClusterForTidbClusterControl()
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	getFinalizers := func() []string {
+		got, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Get(context.TODO(), tc.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		return got.Finalizers
+	}
+
+	// the operator-level default is off
+	stop, err := deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeFalse())
+	g.Expect(getFinalizers()).To(BeEmpty())
+
+	// the operator-level default is on
+	deps.CLIConfig.DeletionProtection = true
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeTrue())
+	g.Expect(getFinalizers()).To(ConsistOf(label.DeletionProtectionFinalizer))
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeFalse())
+
+	// the spec overrides the operator-level default
+	tc.Spec.DeletionProtection = pointer.BoolPtr(false)
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeTrue())
+	g.Expect(getFinalizers()).To(BeEmpty())
+	deps.CLIConfig.DeletionProtection = false
+	tc.Spec.DeletionProtection = pointer.BoolPtr(true)
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{}
+	_, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(getFinalizers()).To(ConsistOf(label.DeletionProtectionFinalizer, label.TidbClusterDeletionFinalizer))
+
+	// the deletion is blocked without the confirmation, the components are not torn down
+	now := metav1.Now()
+	tc.DeletionTimestamp = &now
+	setIndexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	g.Expect(setIndexer.Add(newStatefulSetForDeleter(tc, controller.TiDBMemberName(tc.Name)))).To(Succeed())
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeTrue())
+	g.Expect(getFinalizers()).To(ConsistOf(label.DeletionProtectionFinalizer, label.TidbClusterDeletionFinalizer))
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DeletionProtected))
+
+	// the force deletion does not bypass the deletion protection
+	tc.Annotations = map[string]string{label.AnnForceDeleteKey: label.AnnForceDeleteVal}
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeTrue())
+	g.Expect(getFinalizers()).To(ConsistOf(label.DeletionProtectionFinalizer, label.TidbClusterDeletionFinalizer))
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DeletionProtected))
+
+	// the deletion is confirmed, then the TidbCluster is deleted as usual
+	tc.Annotations = map[string]string{label.AnnConfirmDeletionKey: tc.Name}
+	stop, err = deleter.Sync(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stop).To(BeTrue())
+	g.Expect(getFinalizers()).To(ConsistOf(label.TidbClusterDeletionFinalizer))
+	stop, err = deleter.Sync(tc)
+	g.Expect(stop).To(BeTrue())
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+}
+
+func TestTidbClusterDeleterTeardown(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	deleter := NewTidbClusterDeleter(deps)
+	tc := newTidbClusterForTidbClusterControl()
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{}
+	tc.Finalizers = []string{label.TidbClusterDeletionFinalizer}
+	now := metav1.Now()
+	tc.DeletionTimestamp = &now
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	setIndexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	tidbSet := newStatefulSetForDeleter(tc, controller.TiDBMemberName(tc.Name))
+	tikvSet := newStatefulSetForDeleter(tc, controller.TiKVMemberName(tc.Name))
+	pdSet := newStatefulSetForDeleter(tc, controller.PDMemberName(tc.Name))
+	for _, set := range []*apps.StatefulSet{tidbSet, tikvSet, pdSet} {
+		g.Expect(setIndexer.Add(set)).To(Succeed())
+	}
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	tikvPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pd-tikv-0",
+			Namespace: tc.Namespace,
+			Labels:    label.New().Instance(tc.Name).TiKV().Labels(),
+		},
+	}
+	g.Expect(podIndexer.Add(tikvPod)).To(Succeed())
+
+	// TiDB is torn down first
+	deleting, err := deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+
+	// TiKV is torn down after TiDB is gone
+	g.Expect(setIndexer.Delete(tidbSet)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tikv]"))
+
+	// wait for the pods of TiKV after the statefulset is deleted
+	g.Expect(setIndexer.Delete(tikvSet)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tikv]"))
+
+	// PD is torn down after TiKV is gone
+	g.Expect(podIndexer.Delete(tikvPod)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[pd]"))
+
+	// the finalizer is removed after all the components are gone
+	g.Expect(setIndexer.Delete(pdSet)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tc.Finalizers).To(BeEmpty())
+}
+
+func TestTidbClusterDeleterTeardownTiDBGroups(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	deleter := NewTidbClusterDeleter(deps)
+	tc := newTidbClusterForTidbClusterControl()
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{}
+	tc.Spec.TiDBGroups = []v1alpha1.TiDBGroup{{Name: "oltp", Replicas: 1}}
+	tc.Finalizers = []string{label.TidbClusterDeletionFinalizer}
+	now := metav1.Now()
+	tc.DeletionTimestamp = &now
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	setIndexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	groupSet := newStatefulSetForDeleter(tc, controller.TiDBGroupMemberName(tc.Name, "oltp"))
+	tikvSet := newStatefulSetForDeleter(tc, controller.TiKVMemberName(tc.Name))
+	for _, set := range []*apps.StatefulSet{groupSet, tikvSet} {
+		g.Expect(setIndexer.Add(set)).To(Succeed())
+	}
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	groupPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      groupSet.Name + "-0",
+			Namespace: tc.Namespace,
+			Labels:    label.New().Instance(tc.Name).TiDBGroup("oltp").Labels(),
+		},
+	}
+	g.Expect(podIndexer.Add(groupPod)).To(Succeed())
+
+	// the statefulsets of the groups are torn down with TiDB
+	deleting, err := deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+
+	// wait for the pods of the groups after the statefulsets are deleted
+	g.Expect(setIndexer.Delete(groupSet)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+
+	// TiKV is torn down after the pods of the groups are gone
+	g.Expect(podIndexer.Delete(groupPod)).To(Succeed())
+	deleting, err = deleter.Sync(tc)
+	g.Expect(deleting).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("[tikv]"))
+}
+
+func TestTidbClusterDeleterFinalBackup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	deleter := NewTidbClusterDeleter(deps)
+	tc := newTidbClusterForTidbClusterControl()
+	tc.Spec.DeletionPolicy = &v1alpha1.DeletionPolicy{
+		FinalBackup: &v1alpha1.FinalBackup{BackupScheduleName: "bs"},
+	}
+	tc.Finalizers = []string{label.TidbClusterDeletionFinalizer}
+	now := metav1.Now()
+	tc.DeletionTimestamp = &now
+	_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	setIndexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	g.Expect(setIndexer.Add(newStatefulSetForDeleter(tc, controller.TiDBMemberName(tc.Name)))).To(Succeed())
+
+	// the BackupSchedule does not exist
+	_, err = deleter.Sync(tc)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(controller.IsRequeueError(err)).To(BeFalse())
+
+	bs := &v1alpha1.BackupSchedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bs",
+			Namespace: tc.Namespace,
+		},
+		Spec: v1alpha1.BackupScheduleSpec{
+			BackupTemplate: v1alpha1.BackupSpec{
+				BR: &v1alpha1.BRConfig{Cluster: tc.Name},
+			},
+		},
+	}
+	g.Expect(deps.InformerFactory.Pingcap().V1alpha1().BackupSchedules().Informer().GetIndexer().Add(bs)).To(Succeed())
+
+	// the final backup is created and the teardown waits for it
+	_, err = deleter.Sync(tc)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	backup, err := deps.BackupLister.Backups(tc.Namespace).Get(finalBackupName(tc.Name))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(backup.Spec.BR.Cluster).To(Equal(tc.Name))
+	g.Expect(backup.OwnerReferences).To(BeEmpty())
+	g.Expect(backup.Labels).NotTo(HaveKey(label.BackupScheduleLabelKey))
+
+	_, err = deleter.Sync(tc)
+	g.Expect(err.Error()).To(ContainSubstring("waiting for the final backup"))
+
+	// the teardown starts after the final backup is complete
+	backup = backup.DeepCopy()
+	backup.Status.Conditions = []v1alpha1.BackupCondition{{Type: v1alpha1.BackupComplete, Status: corev1.ConditionTrue}}
+	backup.Status.Phase = v1alpha1.BackupComplete
+	g.Expect(deps.InformerFactory.Pingcap().V1alpha1().Backups().Informer().GetIndexer().Update(backup)).To(Succeed())
+	_, err = deleter.Sync(tc)
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+
+	// the teardown starts after the timeout
+	backup.Status.Conditions = nil
+	backup.Status.Phase = v1alpha1.BackupRunning
+	g.Expect(deps.InformerFactory.Pingcap().V1alpha1().Backups().Informer().GetIndexer().Update(backup)).To(Succeed())
+	tc.Spec.DeletionPolicy.FinalBackup.Timeout = pointer.StringPtr("10m")
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	tc.DeletionTimestamp = &past
+	_, err = deleter.Sync(tc)
+	g.Expect(err.Error()).To(ContainSubstring("[tidb]"))
+}
+
+func newStatefulSetForDeleter(tc *v1alpha1.TidbCluster, name string) *apps.StatefulSet {
+	return &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: tc.Namespace,
+		},
+	}
+}
@@ -19,11 +19,11 @@ import (
 
 	perrors "github.com/pingcap/errors"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/monitor/monitor"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -39,9 +39,10 @@ type Controller struct {
 
 // NewController creates a tidbmonitor controller.
 func NewController(deps *controller.Dependencies) *Controller {
+	secretSync := mngerutils.NewSecretSync(deps)
 	c := &Controller{
 		deps:    deps,
-		control: NewDefaultTidbMonitorControl(deps, monitor.NewMonitorManager(deps)),
+		control: NewDefaultTidbMonitorControl(deps, monitor.NewMonitorManager(deps, secretSync)),
 		queue: workqueue.NewNamedRateLimitingQueue(
 			controller.NewControllerRateLimiter(1*time.Second, 100*time.Second),
 			"tidbmonitor",
@@ -54,6 +55,7 @@ func NewController(deps *controller.Dependencies) *Controller {
 	controller.WatchForController(statefulsetInformer.Informer(), c.queue, func(ns, name string) (runtime.Object, error) {
 		return c.deps.TiDBMonitorLister.TidbMonitors(ns).Get(name)
 	}, nil)
+	secretSync.Watch(deps.KubeInformerFactory.Core().V1().Secrets().Informer(), c.queue)
 
 	return c
 }
@@ -65,18 +67,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting tidbmonitor controller")
 	defer klog.Info("Shutting down tidbmonitor controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.TiDBMonitorControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 // processNextWorkItem dequeues items, processes them, and marks them done. It enforces that the syncHandler is never
 // invoked concurrently with the same key.
 func (c *Controller) processNextWorkItem() bool {
@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/defaulting"
 	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/manager"
 
 	v1 "k8s.io/api/core/v1"
@@ -181,7 +182,7 @@ func (c *defaultTiDBNGMonitoringControl) validate(tngm *v1alpha1.TidbNGMonitorin
 	if len(errs) > 0 {
 		aggregatedErr := errs.ToAggregate()
 		klog.Errorf("tidb ng monitoring %s/%s is not valid and must be fixed first, aggregated error: %v", tngm.GetNamespace(), tngm.GetName(), aggregatedErr)
-		c.recorder.Event(tngm, v1.EventTypeWarning, "FailedValidation", aggregatedErr.Error())
+		c.recorder.Event(tngm, v1.EventTypeWarning, events.FailedValidation, aggregatedErr.Error())
 		return false
 	}
 	return true
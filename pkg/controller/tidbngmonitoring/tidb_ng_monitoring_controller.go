@@ -25,7 +25,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -78,18 +77,11 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	klog.Info("Starting tidbngmonitor controller")
 	defer klog.Info("Shutting down tidbngmonitor controller")
 
-	for i := 0; i < workers; i++ {
-		go wait.Until(c.worker, time.Second, stopCh)
-	}
+	c.deps.WorkerPool.Run(controller.TiDBNGMonitoringControllerName, workers, c.processNextWorkItem, stopCh)
 
 	<-stopCh
 }
 
-func (c *Controller) worker() {
-	for c.processNextWorkItem() {
-	}
-}
-
 func (c *Controller) processNextWorkItem() bool {
 	keyIface, quit := c.queue.Get()
 	if quit {
@@ -0,0 +1,50 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultUpgradeFreezeConfigMapKey is the default key of the value freezing the upgrades
+	// in the maintenance-mode ConfigMap
+	DefaultUpgradeFreezeConfigMapKey = "upgrade"
+	// UpgradeFrozenValue is the value in the maintenance-mode ConfigMap freezing the upgrades
+	UpgradeFrozenValue = "frozen"
+)
+
+// IsUpgradeFrozen returns whether the upgrades of all the clusters are frozen by the maintenance-mode
+// ConfigMap set by -upgrade-freeze-configmap. The upgrades are not frozen if the ConfigMap does not exist.
+func IsUpgradeFrozen(deps *Dependencies) bool {
+	if deps.UpgradeFreezeConfigMapLister == nil {
+		return false
+	}
+	ns, name, err := cache.SplitMetaNamespaceKey(deps.CLIConfig.UpgradeFreezeConfigMap)
+	if err != nil {
+		return false
+	}
+	cm, err := deps.UpgradeFreezeConfigMapLister.ConfigMaps(ns).Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Warningf("failed to get upgrade freeze configmap %s/%s: %v", ns, name, err)
+		}
+		return false
+	}
+	return strings.TrimSpace(cm.Data[deps.CLIConfig.UpgradeFreezeConfigMapKey]) == UpgradeFrozenValue
+}
@@ -0,0 +1,144 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// The names of the controllers, they are the keys of the worker counts of the controllers in -controller-workers
+const (
+	TiDBClusterControllerName           = "tidbcluster"
+	TiDBClusterPodControllerName        = "tidbclusterpod"
+	TiDBInitializerControllerName       = "tidbinitializer"
+	NodeMaintenanceControllerName       = "nodemaintenance"
+	DMClusterControllerName             = "dmcluster"
+	BackupControllerName                = "backup"
+	RestoreControllerName               = "restore"
+	BackupScheduleControllerName        = "backupschedule"
+	TiDBMonitorControllerName           = "tidbmonitor"
+	TiDBNGMonitoringControllerName      = "tidbngmonitoring"
+	TiDBClusterAutoScalerControllerName = "tidbclusterautoscaler"
+)
+
+var controllerNames = sets.NewString(
+	TiDBClusterControllerName,
+	TiDBClusterPodControllerName,
+	TiDBInitializerControllerName,
+	NodeMaintenanceControllerName,
+	DMClusterControllerName,
+	BackupControllerName,
+	RestoreControllerName,
+	BackupScheduleControllerName,
+	TiDBMonitorControllerName,
+	TiDBNGMonitoringControllerName,
+	TiDBClusterAutoScalerControllerName,
+)
+
+// WorkerPool runs the workers of the controllers, the number of the workers of a running controller follows
+// SetWorkers, so that the worker counts can be changed by the live reload of the config file without restart
+type WorkerPool struct {
+	lock    sync.Mutex
+	sizes   map[string]int
+	workers map[string]*controllerWorkers
+}
+
+// controllerWorkers are the running workers of a controller, each of them stops once its channel is closed
+type controllerWorkers struct {
+	process func() bool
+	stops   []chan struct{}
+}
+
+// NewWorkerPool returns a WorkerPool
+func NewWorkerPool() *WorkerPool {
+	return &WorkerPool{
+		sizes:   map[string]int{},
+		workers: map[string]*controllerWorkers{},
+	}
+}
+
+// Run starts the workers of the controller calling process until it returns false, i.e. the queue of the
+// controller is shut down, or stopCh is closed. The number of the workers is the one set by SetWorkers for the
+// controller, or workers if it is not set.
+func (p *WorkerPool) Run(name string, workers int, process func() bool, stopCh <-chan struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if size, ok := p.sizes[name]; ok {
+		workers = size
+	}
+	w := &controllerWorkers{process: process}
+	p.workers[name] = w
+	w.resize(workers)
+
+	go func() {
+		<-stopCh
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		w.resize(0)
+		if p.workers[name] == w {
+			delete(p.workers, name)
+		}
+	}()
+}
+
+// SetWorkers sets the number of the workers of the controller, the workers over the number stop after the items
+// they are processing
+func (p *WorkerPool) SetWorkers(name string, workers int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.sizes[name] = workers
+	if w, ok := p.workers[name]; ok && len(w.stops) != workers {
+		klog.Infof("resize the workers of controller %s from %d to %d", name, len(w.stops), workers)
+		w.resize(workers)
+	}
+}
+
+// Workers returns the number of the running workers of the controller
+func (p *WorkerPool) Workers(name string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if w, ok := p.workers[name]; ok {
+		return len(w.stops)
+	}
+	return 0
+}
+
+func (w *controllerWorkers) resize(workers int) {
+	for len(w.stops) < workers {
+		stop := make(chan struct{})
+		w.stops = append(w.stops, stop)
+		go wait.Until(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if !w.process() {
+					return
+				}
+			}
+		}, time.Second, stop)
+	}
+	for len(w.stops) > workers {
+		last := len(w.stops) - 1
+		close(w.stops[last])
+		w.stops = w.stops[:last]
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestWorkerPool(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	var processed, busy, maxBusy int32
+	release := make(chan struct{})
+	process := func() bool {
+		key, quit := queue.Get()
+		if quit {
+			return false
+		}
+		defer queue.Done(key)
+		n := atomic.AddInt32(&busy, 1)
+		for {
+			m := atomic.LoadInt32(&maxBusy)
+			if n <= m || atomic.CompareAndSwapInt32(&maxBusy, m, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&busy, -1)
+		atomic.AddInt32(&processed, 1)
+		return true
+	}
+
+	p := NewWorkerPool()
+	// the worker count set before the controller runs takes precedence over the default one
+	p.SetWorkers(TiDBClusterControllerName, 2)
+	stopCh := make(chan struct{})
+	p.Run(TiDBClusterControllerName, 5, process, stopCh)
+	g.Expect(p.Workers(TiDBClusterControllerName)).To(Equal(2))
+
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		queue.Add(key)
+	}
+	g.Eventually(func() int32 { return atomic.LoadInt32(&busy) }, time.Second).Should(Equal(int32(2)))
+	g.Consistently(func() int32 { return atomic.LoadInt32(&busy) }, 100*time.Millisecond).Should(Equal(int32(2)))
+
+	// the workers are added to the running controller
+	p.SetWorkers(TiDBClusterControllerName, 4)
+	g.Expect(p.Workers(TiDBClusterControllerName)).To(Equal(4))
+	g.Eventually(func() int32 { return atomic.LoadInt32(&busy) }, time.Second).Should(Equal(int32(4)))
+
+	// the workers over the count stop after their current items
+	p.SetWorkers(TiDBClusterControllerName, 1)
+	g.Expect(p.Workers(TiDBClusterControllerName)).To(Equal(1))
+	close(release)
+	g.Eventually(func() int32 { return atomic.LoadInt32(&processed) }, time.Second).Should(Equal(int32(6)))
+	g.Expect(atomic.LoadInt32(&maxBusy)).To(Equal(int32(4)))
+
+	close(stopCh)
+	g.Eventually(func() int { return p.Workers(TiDBClusterControllerName) }, time.Second).Should(Equal(0))
+}
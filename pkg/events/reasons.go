@@ -0,0 +1,380 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events is the taxonomy of the events emitted by tidb-operator and the recorders emitting them.
+// Every event is emitted with one of the reasons here, the action of the event is derived from its reason,
+// so that the events of the same kind are aggregated into one series and can be filtered by reason or action.
+package events
+
+import "strings"
+
+// The actions are what tidb-operator does when the events are emitted, they are the action of the
+// events.k8s.io/v1 events
+const (
+	ActionCreate       = "Create"
+	ActionUpdate       = "Update"
+	ActionDelete       = "Delete"
+	ActionPatch        = "Patch"
+	ActionValidate     = "Validate"
+	ActionSync         = "Sync"
+	ActionScaleIn      = "ScaleIn"
+	ActionScaleOut     = "ScaleOut"
+	ActionUpgrade      = "Upgrade"
+	ActionFailover     = "Failover"
+	ActionBackup       = "Backup"
+	ActionRestore      = "Restore"
+	ActionRestoreDrill = "RestoreDrill"
+	ActionSchedule     = "Schedule"
+	ActionDrain        = "Drain"
+	ActionMitigate     = "Mitigate"
+)
+
+// The reasons of the results of creating, updating, deleting and patching the objects
+const (
+	SuccessfulCreate = "SuccessfulCreate"
+	SuccessfulUpdate = "SuccessfulUpdate"
+	SuccessfulDelete = "SuccessfulDelete"
+	SuccessfulPatch  = "SuccessfulPatch"
+	FailedCreate     = "FailedCreate"
+	FailedUpdate     = "FailedUpdate"
+	FailedDelete     = "FailedDelete"
+	FailedPatch      = "FailedPatch"
+)
+
+// The reasons of syncing and validating the clusters
+const (
+	// FailedSync is the reason the sync of an object fails
+	FailedSync = "FailedSync"
+	// FailedValidation is the reason an object is invalid
+	FailedValidation = "FailedValidation"
+	// StorageClassWarning is the reason a StorageClass referenced by a cluster breaks some features
+	StorageClassWarning = "StorageClassWarning"
+	// InvalidVolumeMounts is the reason the StatefulSet of a component is not updated as it mounts the volumes
+	// that do not exist
+	InvalidVolumeMounts = "InvalidVolumeMounts"
+	// StatefulSetTemplateDrifted is the reason the pod template of a StatefulSet does not match its last
+	// applied config
+	StatefulSetTemplateDrifted = "StatefulSetTemplateDrifted"
+	// GhostStore is the reason a store in PD is not backed by any pod
+	GhostStore = "GhostStore"
+	// GhostStoreDeleted is the reason a ghost store is deleted from PD
+	GhostStoreDeleted = "GhostStoreDeleted"
+	// FailedSetStoreLabels is the reason the labels of a store fail to be set
+	FailedSetStoreLabels = "FailedSetStoreLabels"
+	// PDSchedulingAutoTuned is the reason the scheduling params of PD are tuned by the size of the cluster
+	PDSchedulingAutoTuned = "PDSchedulingAutoTuned"
+	// PDMemberLeaderPrioritySet is the reason the leader priority of a PD member is set by the zone of its node
+	PDMemberLeaderPrioritySet = "PDMemberLeaderPrioritySet"
+	// OwnerReferencesRepaired is the reason the stale ownerReferences of the objects of a cluster are repaired,
+	// e.g. after the namespace is restored
+	OwnerReferencesRepaired = "OwnerReferencesRepaired"
+	// BootstrapFailed is the reason PD of a cluster does not become healthy within spec.bootstrapTimeout of the
+	// creation of the cluster
+	BootstrapFailed = "BootstrapFailed"
+	// BootstrapRecovered is the reason PD of a cluster failing to bootstrap becomes healthy
+	BootstrapRecovered = "BootstrapRecovered"
+	// StoreDivergent is the reason a store reported Up by PD fails the direct probe of it
+	StoreDivergent = "StoreDivergent"
+	// StoreConverged is the reason a divergent store passes the direct probe of it again
+	StoreConverged = "StoreConverged"
+)
+
+// The reasons of scaling the components
+const (
+	// FailedScaleIn is the reason a component can not be scaled in safely
+	FailedScaleIn = "FailedScaleIn"
+	// VolumePreProvisioningFallback is the reason the scale-out of TiKV falls back to the plain scale-out
+	VolumePreProvisioningFallback = "VolumePreProvisioningFallback"
+	// TiDBScaleInDrainTimeout is the reason the TiDB pod is removed by the scale-in before its connections
+	// are drained
+	TiDBScaleInDrainTimeout = "TiDBScaleInDrainTimeout"
+	// UpgradePartitionClamped is the reason the partition of an in-progress upgrade is reset to the replicas of
+	// the StatefulSet being scaled in
+	UpgradePartitionClamped = "UpgradePartitionClamped"
+)
+
+// The reasons of upgrading the components
+const (
+	// UpgradeUpToDate is the reason a component is up to date after the upgrade
+	UpgradeUpToDate = "UpgradeUpToDate"
+	// UpgradePaused is the reason the upgrade of a component is paused by the annotation of the cluster
+	UpgradePaused = "UpgradePaused"
+	// UpgradeCanaryPinned is the reason the upgrade of TiDB stops after the canaries in spec.tidb.canaryOrdinals
+	// are upgraded
+	UpgradeCanaryPinned = "UpgradeCanaryPinned"
+	// UpgradeForced is the reason a pod is upgraded bypassing the readiness and health checks of the upgrade
+	UpgradeForced = "UpgradeForced"
+	// UpgradeCompletionWebhookTimeout is the reason the upgrade completion webhook does not succeed in time
+	UpgradeCompletionWebhookTimeout = "UpgradeCompletionWebhookTimeout"
+	// TiKVUpgradeStabilizationTimeout is the reason the region scheduling does not settle in time after
+	// the upgrade of TiKV
+	TiKVUpgradeStabilizationTimeout = "TiKVUpgradeStabilizationTimeout"
+	// TiKVFlushNotSupported is the reason a TiKV store is restarted without the flush of
+	// spec.tikv.upgradeSafetyChecks as the TiKV does not serve the API
+	TiKVFlushNotSupported = "TiKVFlushNotSupported"
+	// TierConfigChanged is the reason the config fragment of the tier of a cluster is changed
+	TierConfigChanged = "TierConfigChanged"
+	// ChangefeedsMoved is the reason the changefeeds are moved off the TiCDC pod being upgraded
+	ChangefeedsMoved = "ChangefeedsMoved"
+	// RestartRequested is the reason the graceful rolling restart of a component is requested by the annotation
+	RestartRequested = "RestartRequested"
+	// RestartRefused is the reason the restart of the components is refused, e.g. while the cluster is upgrading
+	RestartRefused = "RestartRefused"
+	// RestartCompleted is the reason all the pods of a component are restarted
+	RestartCompleted = "RestartCompleted"
+	// TiDBUpgradeDrainTimeout is the reason the TiDB pods are upgraded before their connections are drained
+	TiDBUpgradeDrainTimeout = "TiDBUpgradeDrainTimeout"
+	// DowngradeBlocked is the reason the image of a component is not changed to a lower major or minor version
+	DowngradeBlocked = "DowngradeBlocked"
+	// UpgradeBlocked is the reason the upgrade of a component waits for the upgrades or the scaling of the
+	// components
+	UpgradeBlocked = "UpgradeBlocked"
+	// UpgradePodSelected is the reason a pod is selected to be upgraded
+	UpgradePodSelected = "UpgradePodSelected"
+	// UpgradePodUnhealthy is the reason the upgrade waits for an upgraded pod failing the readiness or the health check
+	UpgradePodUnhealthy = "UpgradePodUnhealthy"
+	// UpgradeReadinessTimeout is the reason an upgraded pod is not ready within the upgrade readiness timeout
+	UpgradeReadinessTimeout = "UpgradeReadinessTimeout"
+)
+
+// The reasons of the failover of the components
+const (
+	// Unhealthy is the reason a member of a component is unhealthy
+	Unhealthy = "Unhealthy"
+	// PDMemberUnhealthy is the reason a PD member is unhealthy
+	PDMemberUnhealthy = "PDMemberUnhealthy"
+	// PDPeerMemberUnhealthy is the reason a PD member of the peer cluster is unhealthy
+	PDPeerMemberUnhealthy = "PDPeerMemberUnhealthy"
+	// PDMemberDeleted is the reason a failed PD member is deleted from the PD cluster
+	PDMemberDeleted = "PDMemberDeleted"
+	// MasterMemberUnhealthy is the reason a dm-master member is unhealthy
+	MasterMemberUnhealthy = "MasterMemberUnhealthy"
+	// DMMasterMemberDeleted is the reason a failed dm-master member is deleted from the dm-master cluster
+	DMMasterMemberDeleted = "DMMasterMemberDeleted"
+	// StuckPodForceDeleted is the reason a pod stuck in Terminating on an unreachable node is force deleted
+	StuckPodForceDeleted = "StuckPodForceDeleted"
+)
+
+// The reasons of deleting the clusters and the backups
+const (
+	// DeletionProtected is the reason the deletion of a protected object is not confirmed
+	DeletionProtected = "DeletionProtected"
+	// FinalBackupTimeout is the reason the final backup of a TidbCluster being deleted does not complete in time
+	FinalBackupTimeout = "FinalBackupTimeout"
+	// FinalBackupFailed is the reason the final backup of a TidbCluster being deleted fails
+	FinalBackupFailed = "FinalBackupFailed"
+)
+
+// The reasons of the backups and the restore drills of the BackupSchedules
+const (
+	// IncrementalChainBroken is the reason the incremental backups of a BackupSchedule start a new chain
+	IncrementalChainBroken = "IncrementalChainBroken"
+	// RestoreDrillStarted is the reason a restore drill is started
+	RestoreDrillStarted = "RestoreDrillStarted"
+	// RestoreDrillFailed is the reason a restore drill fails
+	RestoreDrillFailed = "RestoreDrillFailed"
+	// RestoreDrillPassed is the reason a restore drill passes
+	RestoreDrillPassed = "RestoreDrillPassed"
+)
+
+// The reasons of the failures of the backups and the restores
+const (
+	// BackupToolFailed is the reason the tool of a backup, e.g. BR or dumpling, exits with an error
+	BackupToolFailed = "BackupToolFailed"
+	// RestoreToolFailed is the reason the tool of a restore, e.g. BR or lightning, exits with an error
+	RestoreToolFailed = "RestoreToolFailed"
+	// BackupNotificationFailed is the reason the notification of a backup is given up after the retries fail
+	BackupNotificationFailed = "BackupNotificationFailed"
+)
+
+// The reasons of tidb-scheduler
+const (
+	// FailedScheduling is the reason a pod is not schedulable by the predicates of tidb-scheduler
+	FailedScheduling = "FailedScheduling"
+)
+
+// The reasons of the maintenance of the nodes
+const (
+	// NodeMaintenanceStarted is the reason the pods on a node start to be drained for the maintenance of the node
+	NodeMaintenanceStarted = "NodeMaintenanceStarted"
+	// NodeMaintenanceCompleted is the reason all the pods on a node are drained for the maintenance of the node
+	NodeMaintenanceCompleted = "NodeMaintenanceCompleted"
+	// NodeMaintenanceCancelled is the reason the maintenance of a node is cancelled before it completes
+	NodeMaintenanceCancelled = "NodeMaintenanceCancelled"
+)
+
+// The reasons of the mitigation of the slow TiKV stores
+const (
+	// SlowStoreDetected is the reason a store is slow for the consecutive checks
+	SlowStoreDetected = "SlowStoreDetected"
+	// SlowStoreRecovered is the reason a slow store is normal for the consecutive checks
+	SlowStoreRecovered = "SlowStoreRecovered"
+	// SlowStoreLeaderWeightReduced is the reason the leader weight of a slow store is reduced
+	SlowStoreLeaderWeightReduced = "SlowStoreLeaderWeightReduced"
+	// SlowStoreLeaderWeightRestored is the reason the original leader weight of a store is restored
+	SlowStoreLeaderWeightRestored = "SlowStoreLeaderWeightRestored"
+	// FailedSetStoreWeight is the reason the leader weight of a store fails to be reduced or restored
+	FailedSetStoreWeight = "FailedSetStoreWeight"
+)
+
+// The reasons of the storage usage of the components
+const (
+	// StoragePressure is the reason the used storage of a pod exceeds the warning threshold
+	StoragePressure = "StoragePressure"
+	// StoragePressureRelieved is the reason the used storage of a pod falls below the warning threshold
+	StoragePressureRelieved = "StoragePressureRelieved"
+)
+
+// The reasons of the node bindings of the pods
+const (
+	// NodeBindingInvalid is the reason the binding of an ordinal selects no existing node
+	NodeBindingInvalid = "NodeBindingInvalid"
+	// NodeBindingMove is the reason a pod not on the nodes of its binding is moved
+	NodeBindingMove = "NodeBindingMove"
+)
+
+// The reasons of the replacement of the pod and the volumes of a TiKV ordinal
+const (
+	// VolumeReplaceRefused is the reason the replacement requested by the annotation is refused, e.g. the pod is
+	// not a TiKV pod of the cluster or the cluster does not have enough healthy replicas
+	VolumeReplaceRefused = "VolumeReplaceRefused"
+	// VolumeReplaceStarted is the reason the replacement of the pod and the volumes of a TiKV ordinal starts
+	VolumeReplaceStarted = "VolumeReplaceStarted"
+	// VolumeReplaceCompleted is the reason the store of the replaced TiKV pod catches up
+	VolumeReplaceCompleted = "VolumeReplaceCompleted"
+)
+
+// reasonActions maps the reasons to the actions, every reason must be here
+var reasonActions = map[string]string{
+	SuccessfulCreate: ActionCreate,
+	SuccessfulUpdate: ActionUpdate,
+	SuccessfulDelete: ActionDelete,
+	SuccessfulPatch:  ActionPatch,
+	FailedCreate:     ActionCreate,
+	FailedUpdate:     ActionUpdate,
+	FailedDelete:     ActionDelete,
+	FailedPatch:      ActionPatch,
+
+	FailedSync:                 ActionSync,
+	FailedValidation:           ActionValidate,
+	StorageClassWarning:        ActionValidate,
+	InvalidVolumeMounts:        ActionValidate,
+	StatefulSetTemplateDrifted: ActionSync,
+	GhostStore:                 ActionSync,
+	GhostStoreDeleted:          ActionDelete,
+	FailedSetStoreLabels:       ActionSync,
+	PDSchedulingAutoTuned:      ActionUpdate,
+	PDMemberLeaderPrioritySet:  ActionUpdate,
+	OwnerReferencesRepaired:    ActionUpdate,
+	BootstrapFailed:            ActionSync,
+	BootstrapRecovered:         ActionSync,
+	StoreDivergent:             ActionSync,
+	StoreConverged:             ActionSync,
+
+	FailedScaleIn:                 ActionScaleIn,
+	VolumePreProvisioningFallback: ActionScaleOut,
+	TiDBScaleInDrainTimeout:       ActionScaleIn,
+	UpgradePartitionClamped:       ActionScaleIn,
+
+	UpgradeUpToDate:                 ActionUpgrade,
+	UpgradePaused:                   ActionUpgrade,
+	UpgradeCanaryPinned:             ActionUpgrade,
+	UpgradeForced:                   ActionUpgrade,
+	UpgradeCompletionWebhookTimeout: ActionUpgrade,
+	TiKVUpgradeStabilizationTimeout: ActionUpgrade,
+	TiKVFlushNotSupported:           ActionUpgrade,
+	TierConfigChanged:               ActionUpgrade,
+	ChangefeedsMoved:                ActionUpgrade,
+	RestartRequested:                ActionUpgrade,
+	RestartRefused:                  ActionUpgrade,
+	RestartCompleted:                ActionUpgrade,
+	TiDBUpgradeDrainTimeout:         ActionUpgrade,
+	DowngradeBlocked:                ActionUpgrade,
+	UpgradeBlocked:                  ActionUpgrade,
+	UpgradePodSelected:              ActionUpgrade,
+	UpgradePodUnhealthy:             ActionUpgrade,
+	UpgradeReadinessTimeout:         ActionUpgrade,
+
+	Unhealthy:             ActionFailover,
+	PDMemberUnhealthy:     ActionFailover,
+	PDPeerMemberUnhealthy: ActionFailover,
+	PDMemberDeleted:       ActionFailover,
+	MasterMemberUnhealthy: ActionFailover,
+	DMMasterMemberDeleted: ActionFailover,
+	StuckPodForceDeleted:  ActionFailover,
+
+	DeletionProtected:  ActionDelete,
+	FinalBackupTimeout: ActionDelete,
+	FinalBackupFailed:  ActionDelete,
+
+	IncrementalChainBroken: ActionBackup,
+	RestoreDrillStarted:    ActionRestoreDrill,
+	RestoreDrillFailed:     ActionRestoreDrill,
+	RestoreDrillPassed:     ActionRestoreDrill,
+
+	BackupToolFailed:         ActionBackup,
+	RestoreToolFailed:        ActionRestore,
+	BackupNotificationFailed: ActionBackup,
+
+	FailedScheduling: ActionSchedule,
+
+	NodeMaintenanceStarted:   ActionDrain,
+	NodeMaintenanceCompleted: ActionDrain,
+	NodeMaintenanceCancelled: ActionDrain,
+
+	SlowStoreDetected:             ActionMitigate,
+	SlowStoreRecovered:            ActionMitigate,
+	SlowStoreLeaderWeightReduced:  ActionMitigate,
+	SlowStoreLeaderWeightRestored: ActionMitigate,
+	FailedSetStoreWeight:          ActionMitigate,
+
+	StoragePressure:         ActionSync,
+	StoragePressureRelieved: ActionSync,
+
+	NodeBindingInvalid: ActionValidate,
+	NodeBindingMove:    ActionSync,
+
+	VolumeReplaceRefused:   ActionValidate,
+	VolumeReplaceStarted:   ActionFailover,
+	VolumeReplaceCompleted: ActionFailover,
+}
+
+// ActionOf returns the action of the reason, it is ActionSync for the reasons not in the taxonomy
+func ActionOf(reason string) string {
+	if action, ok := reasonActions[reason]; ok {
+		return action
+	}
+	return ActionSync
+}
+
+// ResultReason returns the reason of the result of the verb applied to an object, the verbs other than
+// create, delete and patch are taken as update
+func ResultReason(verb string, err error) string {
+	switch strings.ToLower(verb) {
+	case "create":
+		return pick(err, SuccessfulCreate, FailedCreate)
+	case "delete":
+		return pick(err, SuccessfulDelete, FailedDelete)
+	case "patch":
+		return pick(err, SuccessfulPatch, FailedPatch)
+	default:
+		return pick(err, SuccessfulUpdate, FailedUpdate)
+	}
+}
+
+func pick(err error, successful, failed string) string {
+	if err != nil {
+		return failed
+	}
+	return successful
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReasonActions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "reasons.go", nil, 0)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				if strings.HasPrefix(name.Name, "Action") {
+					continue
+				}
+				g.Expect(reasonActions).To(HaveKey(name.Name), "reason %s has no action", name.Name)
+			}
+		}
+	}
+
+	g.Expect(ActionOf(FailedScaleIn)).To(Equal(ActionScaleIn))
+	g.Expect(ActionOf("Unknown")).To(Equal(ActionSync))
+}
+
+func TestResultReason(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		verb   string
+		err    error
+		expect string
+	}{
+		{verb: "create", expect: SuccessfulCreate},
+		{verb: "Create", err: fmt.Errorf("fake"), expect: FailedCreate},
+		{verb: "delete", expect: SuccessfulDelete},
+		{verb: "delete", err: fmt.Errorf("fake"), expect: FailedDelete},
+		{verb: "patch", expect: SuccessfulPatch},
+		{verb: "patch", err: fmt.Errorf("fake"), expect: FailedPatch},
+		{verb: "update", expect: SuccessfulUpdate},
+		{verb: "apply", err: fmt.Errorf("fake"), expect: FailedUpdate},
+	}
+	for _, tt := range tests {
+		g.Expect(ResultReason(tt.verb, tt.err)).To(Equal(tt.expect), "verb %s, err %v", tt.verb, tt.err)
+	}
+}
+
+// TestNoFreeFormReasons checks that the events emitted by the operator use the reasons in the taxonomy, the reason
+// of each Event, Eventf and AnnotatedEventf call must be a constant of this package or a variable named reason,
+// which is computed by ResultReason or passed through from such a constant.
+func TestNoFreeFormReasons(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	reasonArg := map[string]int{"Event": 2, "Eventf": 2, "AnnotatedEventf": 3}
+	var violations []string
+	for _, root := range []string{"../../pkg", "../../cmd"} {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path == "../../pkg/apis" || path == "../../pkg/client" || path == "../../pkg/events" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return nil
+			}
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				sel, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				idx, ok := reasonArg[sel.Sel.Name]
+				if !ok || len(call.Args) <= idx {
+					return true
+				}
+				if !isTaxonomyReason(call.Args[idx]) {
+					violations = append(violations, fmt.Sprintf("%s: free-form reason in %s", fset.Position(call.Pos()), sel.Sel.Name))
+				}
+				return true
+			})
+			return nil
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(violations).To(BeEmpty())
+}
+
+func isTaxonomyReason(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		return ok && pkg.Name == "events" && reasonActions[e.Sel.Name] != ""
+	case *ast.Ident:
+		return e.Name == "reason"
+	}
+	return false
+}
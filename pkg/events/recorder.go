@@ -0,0 +1,295 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sevents "k8s.io/client-go/tools/events"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// eventsV1Recorder emits the events.k8s.io/v1 events through the record.EventRecorder interface used by the
+// controllers, the action of each event is derived from its reason. The events of the same regarding object,
+// reason, action and type are aggregated into a series by the events.k8s.io/v1 broadcaster.
+type eventsV1Recorder struct {
+	recorder k8sevents.EventRecorder
+}
+
+var _ record.EventRecorder = &eventsV1Recorder{}
+
+// NewEventsV1Recorder returns a record.EventRecorder emitting the events.k8s.io/v1 events by the recorder
+func NewEventsV1Recorder(recorder k8sevents.EventRecorder) record.EventRecorder {
+	return &eventsV1Recorder{recorder: recorder}
+}
+
+func (r *eventsV1Recorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.recorder.Eventf(object, nil, eventtype, reason, ActionOf(reason), "%s", message)
+}
+
+func (r *eventsV1Recorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.recorder.Eventf(object, nil, eventtype, reason, ActionOf(reason), messageFmt, args...)
+}
+
+// AnnotatedEventf emits the event with the annotations appended to the note, as the events.k8s.io/v1
+// events are aggregated into series regardless of their annotations
+func (r *eventsV1Recorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	note := fmt.Sprintf(messageFmt, args...)
+	if len(annotations) > 0 {
+		note = fmt.Sprintf("%s, annotations: %v", note, annotations)
+	}
+	r.recorder.Eventf(object, nil, eventtype, reason, ActionOf(reason), "%s", note)
+}
+
+// rateLimiterIdleTimeout is the min time a cluster emits no events before its limiter is evicted, the limiter
+// is kept until its bucket is full again if that takes longer, so that an evicted limiter is recreated as it was
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// rateLimitedRecorder limits the rate of the events of each cluster, the events over the limit are dropped,
+// so that a cluster in trouble does not flood the events of the others. The limiters of the clusters emitting
+// no events, e.g. the deleted ones, are evicted once they are idle.
+type rateLimitedRecorder struct {
+	recorder record.EventRecorder
+	qps      rate.Limit
+	burst    int
+	now      func() time.Time
+
+	lock      sync.Mutex
+	limiters  map[string]*clusterLimiter
+	lastPrune time.Time
+}
+
+// clusterLimiter is the limiter of the events of a cluster
+type clusterLimiter struct {
+	limiter   *rate.Limiter
+	lastEvent time.Time
+	// droppedWarnings is the number of the Warning events dropped since the last event emitted
+	droppedWarnings int
+}
+
+var _ record.EventRecorder = &rateLimitedRecorder{}
+
+// RateLimitedRecorder is a record.EventRecorder whose rate limit of the events of each cluster can be changed
+// while it is running
+type RateLimitedRecorder interface {
+	record.EventRecorder
+	// SetRateLimit sets the max number of the events emitted per second and the burst for each cluster, the
+	// events are not limited if qps is not positive
+	SetRateLimit(qps float64, burst int)
+}
+
+var _ RateLimitedRecorder = &rateLimitedRecorder{}
+
+// NewRateLimitedRecorder returns a record.EventRecorder emitting at most qps events per second with the burst
+// for each cluster by the recorder. The recorder is returned as is if qps is not positive.
+func NewRateLimitedRecorder(recorder record.EventRecorder, qps float64, burst int) record.EventRecorder {
+	if qps <= 0 {
+		return recorder
+	}
+	return NewReloadableRateLimitedRecorder(recorder, qps, burst)
+}
+
+// NewReloadableRateLimitedRecorder returns a RateLimitedRecorder emitting at most qps events per second with the
+// burst for each cluster by the recorder, the events are not limited until SetRateLimit if qps is not positive
+func NewReloadableRateLimitedRecorder(recorder record.EventRecorder, qps float64, burst int) RateLimitedRecorder {
+	r := &rateLimitedRecorder{
+		recorder: recorder,
+		now:      time.Now,
+		limiters: map[string]*clusterLimiter{},
+	}
+	r.SetRateLimit(qps, burst)
+	return r
+}
+
+func (r *rateLimitedRecorder) SetRateLimit(qps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.qps == rate.Limit(qps) && r.burst == burst {
+		return
+	}
+	r.qps = rate.Limit(qps)
+	r.burst = burst
+	// the limiters are created with the new limit on the next events
+	for key, l := range r.limiters {
+		logDroppedWarnings(key, l)
+	}
+	r.limiters = map[string]*clusterLimiter{}
+}
+
+func (r *rateLimitedRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *rateLimitedRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *rateLimitedRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *rateLimitedRecorder) allow(object runtime.Object, eventtype, reason string) bool {
+	key := clusterKey(object)
+	now := r.now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.qps <= 0 {
+		return true
+	}
+	r.prune(now)
+	l, ok := r.limiters[key]
+	if !ok {
+		l = &clusterLimiter{limiter: rate.NewLimiter(r.qps, r.burst)}
+		r.limiters[key] = l
+	}
+	l.lastEvent = now
+
+	if l.limiter.AllowN(now, 1) {
+		logDroppedWarnings(key, l)
+		return true
+	}
+	metrics.EventsDropped.WithLabelValues(eventtype).Inc()
+	if eventtype != corev1.EventTypeWarning {
+		klog.V(4).Infof("event %s of cluster %s is dropped by the rate limit", reason, key)
+		return false
+	}
+	l.droppedWarnings++
+	if l.droppedWarnings == 1 {
+		klog.Warningf("warning event %s of cluster %s is dropped by the rate limit, the following ones are counted until an event of the cluster is emitted", reason, key)
+	}
+	return false
+}
+
+// prune evicts the limiters of the clusters idle for long enough, it runs at most once in rateLimiterIdleTimeout
+func (r *rateLimitedRecorder) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < rateLimiterIdleTimeout {
+		return
+	}
+	r.lastPrune = now
+	idle := rateLimiterIdleTimeout
+	if refill := time.Duration(float64(r.burst) / float64(r.qps) * float64(time.Second)); refill > idle {
+		idle = refill
+	}
+	for key, l := range r.limiters {
+		if now.Sub(l.lastEvent) >= idle {
+			logDroppedWarnings(key, l)
+			delete(r.limiters, key)
+		}
+	}
+}
+
+// logDroppedWarnings logs the number of the Warning events of the cluster dropped since the last event emitted
+func logDroppedWarnings(key string, l *clusterLimiter) {
+	if l.droppedWarnings > 0 {
+		klog.Warningf("%d warning events of cluster %s were dropped by the rate limit", l.droppedWarnings, key)
+		l.droppedWarnings = 0
+	}
+}
+
+// dedupRecorder drops the events repeating the type, the reason and the message of an event of the same object
+// emitted within the interval, so that the events emitted in every sync of a step waiting for something do not
+// flood the events of the namespace
+type dedupRecorder struct {
+	recorder record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	lock    sync.Mutex
+	emitted map[string]time.Time
+}
+
+var _ record.EventRecorder = &dedupRecorder{}
+
+// NewDedupRecorder returns a record.EventRecorder emitting the same event of an object at most once in the interval
+func NewDedupRecorder(recorder record.EventRecorder, interval time.Duration) record.EventRecorder {
+	return &dedupRecorder{
+		recorder: recorder,
+		interval: interval,
+		now:      time.Now,
+		emitted:  map[string]time.Time{},
+	}
+}
+
+func (r *dedupRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, eventtype, reason, message) {
+		r.recorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *dedupRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason, fmt.Sprintf(messageFmt, args...)) {
+		r.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *dedupRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, eventtype, reason, fmt.Sprintf(messageFmt, args...)) {
+		r.recorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *dedupRecorder) allow(object runtime.Object, eventtype, reason, message string) bool {
+	var ns, name string
+	if accessor, err := meta.Accessor(object); err == nil {
+		ns, name = accessor.GetNamespace(), accessor.GetName()
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s/%s", ns, name, eventtype, reason, message)
+	now := r.now()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for k, t := range r.emitted {
+		if now.Sub(t) >= r.interval {
+			delete(r.emitted, k)
+		}
+	}
+	if _, ok := r.emitted[key]; ok {
+		klog.V(4).Infof("event %s of %s/%s is dropped as it is emitted within %s", reason, ns, name, r.interval)
+		return false
+	}
+	r.emitted[key] = now
+	return true
+}
+
+// clusterKey returns the key of the cluster the object belongs to, it is the instance of the object in the
+// form of <namespace>/<instance>, or <namespace>/<name> if the object is not labeled with the instance
+func clusterKey(object runtime.Object) string {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return ""
+	}
+	if instance := accessor.GetLabels()[label.InstanceLabelKey]; instance != "" {
+		return fmt.Sprintf("%s/%s", accessor.GetNamespace(), instance)
+	}
+	return fmt.Sprintf("%s/%s", accessor.GetNamespace(), accessor.GetName())
+}
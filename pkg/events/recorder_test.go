@@ -0,0 +1,168 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sevents "k8s.io/client-go/tools/events"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventsV1Recorder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fake := k8sevents.NewFakeRecorder(10)
+	recorder := NewEventsV1Recorder(fake)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+
+	recorder.Event(pod, corev1.EventTypeWarning, FailedScaleIn, "tikv-0 is not removable")
+	g.Expect(<-fake.Events).To(Equal("Warning FailedScaleIn tikv-0 is not removable"))
+
+	recorder.Eventf(pod, corev1.EventTypeNormal, SuccessfulCreate, "create pod %s", "pod")
+	g.Expect(<-fake.Events).To(Equal("Normal SuccessfulCreate create pod pod"))
+
+	recorder.AnnotatedEventf(pod, map[string]string{"id": "1"}, corev1.EventTypeNormal, UpgradeUpToDate, "%s is up to date", "tidb")
+	g.Expect(<-fake.Events).To(Equal("Normal UpgradeUpToDate tidb is up to date, annotations: map[id:1]"))
+}
+
+func TestRateLimitedRecorder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fake := record.NewFakeRecorder(100)
+	g.Expect(NewRateLimitedRecorder(fake, 0, 10)).To(BeIdenticalTo(fake))
+
+	recorder := NewRateLimitedRecorder(fake, 0.001, 2)
+	newPod := func(cluster, name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      name,
+			Labels:    map[string]string{label.InstanceLabelKey: cluster},
+		}}
+	}
+	for i := 0; i < 5; i++ {
+		recorder.Event(newPod("a", "a-tikv-0"), corev1.EventTypeWarning, FailedSync, "fake")
+		recorder.Eventf(newPod("b", "b-tikv-0"), corev1.EventTypeWarning, FailedSync, "fake %d", i)
+	}
+	// the events of each cluster are limited separately
+	g.Expect(fake.Events).To(HaveLen(4))
+
+	g.Expect(clusterKey(newPod("a", "a-pd-0"))).To(Equal("ns/a"))
+	g.Expect(clusterKey(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}})).To(Equal("ns/pod"))
+}
+
+func TestRateLimitedRecorderPrune(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fake := record.NewFakeRecorder(100)
+	recorder := NewRateLimitedRecorder(fake, 1, 2).(*rateLimitedRecorder)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+	newPod := func(cluster string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      cluster + "-tikv-0",
+			Labels:    map[string]string{label.InstanceLabelKey: cluster},
+		}}
+	}
+
+	warnings := testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(corev1.EventTypeWarning))
+	normals := testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(corev1.EventTypeNormal))
+	for i := 0; i < 5; i++ {
+		recorder.Event(newPod("a"), corev1.EventTypeWarning, FailedSync, "fake")
+		recorder.Event(newPod("b"), corev1.EventTypeNormal, UpgradeBlocked, "fake")
+	}
+	g.Expect(fake.Events).To(HaveLen(4))
+	// the dropped events are counted by their type
+	g.Expect(testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(corev1.EventTypeWarning)) - warnings).To(Equal(float64(3)))
+	g.Expect(testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(corev1.EventTypeNormal)) - normals).To(Equal(float64(3)))
+	g.Expect(recorder.limiters["ns/a"].droppedWarnings).To(Equal(3))
+	g.Expect(recorder.limiters["ns/b"].droppedWarnings).To(Equal(0))
+
+	// the count of the dropped warnings is reset once an event is emitted
+	now = now.Add(time.Second)
+	recorder.Event(newPod("a"), corev1.EventTypeWarning, FailedSync, "fake")
+	g.Expect(fake.Events).To(HaveLen(5))
+	g.Expect(recorder.limiters["ns/a"].droppedWarnings).To(Equal(0))
+
+	// the limiters of the idle clusters are evicted
+	now = now.Add(rateLimiterIdleTimeout - time.Second)
+	recorder.Event(newPod("a"), corev1.EventTypeWarning, FailedSync, "fake")
+	g.Expect(recorder.limiters).To(HaveLen(1))
+	g.Expect(recorder.limiters).To(HaveKey("ns/a"))
+	g.Expect(fake.Events).To(HaveLen(6))
+}
+
+func TestReloadableRateLimitedRecorder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fake := record.NewFakeRecorder(100)
+	recorder := NewReloadableRateLimitedRecorder(fake, 0, 10)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+	emit := func(n int) {
+		for i := 0; i < n; i++ {
+			recorder.Event(pod, corev1.EventTypeWarning, FailedSync, "fake")
+		}
+	}
+
+	// the events are not limited until the rate limit is set
+	emit(5)
+	g.Expect(fake.Events).To(HaveLen(5))
+
+	recorder.SetRateLimit(0.001, 2)
+	emit(5)
+	g.Expect(fake.Events).To(HaveLen(7))
+
+	// the new burst applies to the next events
+	recorder.SetRateLimit(0.001, 3)
+	emit(5)
+	g.Expect(fake.Events).To(HaveLen(10))
+
+	recorder.SetRateLimit(0, 0)
+	emit(5)
+	g.Expect(fake.Events).To(HaveLen(15))
+}
+
+func TestDedupRecorder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fake := record.NewFakeRecorder(100)
+	recorder := NewDedupRecorder(fake, time.Minute).(*dedupRecorder)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}}
+
+	for i := 0; i < 3; i++ {
+		recorder.Eventf(pod, corev1.EventTypeNormal, UpgradeBlocked, "%s waits for %s", "tidb", "pd")
+		recorder.AnnotatedEventf(pod, map[string]string{"id": "1"}, corev1.EventTypeNormal, UpgradeBlocked, "%s waits for %s", "tidb", "tikv")
+		recorder.Event(other, corev1.EventTypeNormal, UpgradeBlocked, "tidb waits for pd")
+	}
+	// the same event of an object is emitted once in the interval
+	g.Expect(fake.Events).To(HaveLen(3))
+
+	now = now.Add(30 * time.Second)
+	recorder.Event(pod, corev1.EventTypeNormal, UpgradeBlocked, "tidb waits for pd")
+	g.Expect(fake.Events).To(HaveLen(3))
+	now = now.Add(30 * time.Second)
+	recorder.Event(pod, corev1.EventTypeNormal, UpgradeBlocked, "tidb waits for pd")
+	g.Expect(fake.Events).To(HaveLen(4))
+}
@@ -0,0 +1,260 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/apis/util/config"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// configHistoryLimit is the max number of the config records of a component kept in the config history
+const configHistoryLimit = 50
+
+// ConfigHistoryRecord is a record of the rendered config of a component in the config history
+type ConfigHistoryRecord struct {
+	// Hash is the sha256 sum of the data of the ConfigMap of the config
+	Hash string `json:"hash"`
+	// ConfigMap is the name of the ConfigMap of the config
+	ConfigMap string `json:"configMap"`
+	// Revision is the revision of the StatefulSet rolled out with the config, it is empty until the StatefulSet
+	// refers to the ConfigMap
+	Revision string `json:"revision,omitempty"`
+	// Time is the time the config is changed
+	Time metav1.Time `json:"time"`
+	// Diff is the changes of the config entries against the previous record in the form of <key>: <old> -> <new>,
+	// it is empty for the first record or if the config of the previous record is pruned
+	Diff []string `json:"diff,omitempty"`
+}
+
+// ConfigHistoryName returns the name of the ConfigMap of the config history of the cluster, the records of each
+// component are kept in JSON under the key of the component, e.g. tikv
+func ConfigHistoryName(tcName string) string {
+	return fmt.Sprintf("%s-config-history", tcName)
+}
+
+// recordConfigHistory appends the record of the new ConfigMap of the component to the config history if its data
+// is changed since the last record, and sets the hash of the config in status.configHashes. It is called before the
+// new ConfigMap is applied, so that the config of the last record is still in the cache to compute the diff. The
+// revision of the last record is filled once the StatefulSet refers to its ConfigMap.
+func recordConfigHistory(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	cm *corev1.ConfigMap, set *apps.StatefulSet) error {
+	if cm == nil {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	hash, err := mngerutils.Sha256Sum(cm.Data)
+	if err != nil {
+		return err
+	}
+
+	// the history is read and written through the API server rather than the cache, as it is updated by the
+	// components one after another in a sync
+	historyName := ConfigHistoryName(tc.GetName())
+	history, err := deps.KubeClientset.CoreV1().ConfigMaps(ns).Get(context.TODO(), historyName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		history = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get config history %s/%s, error: %v", ns, historyName, err)
+	}
+	records, err := configHistoryRecords(history, memberType)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	var last *ConfigHistoryRecord
+	if len(records) > 0 {
+		last = &records[len(records)-1]
+	}
+	if last != nil && last.Hash == hash {
+		if last.Revision == "" && statefulSetRefersTo(set, last.ConfigMap) {
+			last.Revision = set.Status.UpdateRevision
+			changed = true
+		}
+	} else {
+		record := ConfigHistoryRecord{
+			Hash:      hash,
+			ConfigMap: cm.Name,
+			Time:      metav1.Now(),
+		}
+		if last != nil {
+			previous, err := configOfRecord(deps.ConfigMapLister, ns, last)
+			if err != nil {
+				klog.Warningf("tidbcluster: [%s/%s] no diff of the %s config in the history: %v", ns, tc.GetName(), memberType, err)
+			} else {
+				record.Diff = diffConfigData(previous, cm.Data)
+			}
+		}
+		records = append(records, record)
+		if len(records) > configHistoryLimit {
+			records = records[len(records)-configHistoryLimit:]
+		}
+		changed = true
+	}
+
+	if changed {
+		data, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		if err := saveConfigHistory(deps, tc, history, memberType, string(data)); err != nil {
+			return err
+		}
+	}
+	if tc.Status.ConfigHashes == nil {
+		tc.Status.ConfigHashes = map[string]string{}
+	}
+	tc.Status.ConfigHashes[memberType.String()] = hash
+	return nil
+}
+
+func saveConfigHistory(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, history *corev1.ConfigMap,
+	memberType v1alpha1.MemberType, data string) error {
+	ns := tc.GetNamespace()
+	if history == nil {
+		history = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            ConfigHistoryName(tc.GetName()),
+				Namespace:       ns,
+				Labels:          label.New().Instance(tc.GetInstanceName()),
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+			},
+			Data: map[string]string{memberType.String(): data},
+		}
+		if _, err := deps.KubeClientset.CoreV1().ConfigMaps(ns).Create(context.TODO(), history, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create config history %s/%s, error: %v", ns, history.Name, err)
+		}
+		return nil
+	}
+	update := history.DeepCopy()
+	if update.Data == nil {
+		update.Data = map[string]string{}
+	}
+	update.Data[memberType.String()] = data
+	// the conflicts are retried by the next sync, so that the records of the other components are never overwritten
+	if _, err := deps.KubeClientset.CoreV1().ConfigMaps(ns).Update(context.TODO(), update, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update config history %s/%s, error: %v", ns, history.Name, err)
+	}
+	return nil
+}
+
+func configHistoryRecords(history *corev1.ConfigMap, memberType v1alpha1.MemberType) ([]ConfigHistoryRecord, error) {
+	if history == nil {
+		return nil, nil
+	}
+	data, ok := history.Data[memberType.String()]
+	if !ok {
+		return nil, nil
+	}
+	var records []ConfigHistoryRecord
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, fmt.Errorf("invalid %s records in config history %s/%s, error: %v", memberType, history.Namespace, history.Name, err)
+	}
+	return records, nil
+}
+
+// statefulSetRefersTo returns true if the pod template of the StatefulSet refers to the ConfigMap and the status of
+// the StatefulSet is observed, so that its update revision is the one with the ConfigMap
+func statefulSetRefersTo(set *apps.StatefulSet, cmName string) bool {
+	if set == nil || set.Status.UpdateRevision == "" || set.Status.ObservedGeneration < set.Generation {
+		return false
+	}
+	return mngerutils.FindConfigMapVolume(&set.Spec.Template.Spec, func(name string) bool {
+		return name == cmName
+	}) != ""
+}
+
+// ConfigForHash returns the data of the ConfigMap of the config of the component with the hash in the config
+// history. The config is reconstructed from the revisioned ConfigMaps of the component, it fails if the record is
+// rotated out of the history, the ConfigMap is deleted, or the ConfigMap is updated in place by a later config.
+func ConfigForHash(cmLister corelisters.ConfigMapLister, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, hash string) (map[string]string, error) {
+	ns := tc.GetNamespace()
+	historyName := ConfigHistoryName(tc.GetName())
+	history, err := cmLister.ConfigMaps(ns).Get(historyName)
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("config history %s/%s does not exist", ns, historyName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config history %s/%s, error: %v", ns, historyName, err)
+	}
+	records, err := configHistoryRecords(history, memberType)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Hash == hash {
+			return configOfRecord(cmLister, ns, &records[i])
+		}
+	}
+	return nil, fmt.Errorf("%s config %s is not in config history %s/%s, only the last %d configs are kept", memberType, hash, ns, historyName, configHistoryLimit)
+}
+
+// configOfRecord returns the data of the ConfigMap of the record if it is still the config of the record
+func configOfRecord(cmLister corelisters.ConfigMapLister, ns string, record *ConfigHistoryRecord) (map[string]string, error) {
+	cm, err := cmLister.ConfigMaps(ns).Get(record.ConfigMap)
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("config %s is pruned, ConfigMap %s/%s does not exist", record.Hash, ns, record.ConfigMap)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap %s/%s, error: %v", ns, record.ConfigMap, err)
+	}
+	hash, err := mngerutils.Sha256Sum(cm.Data)
+	if err != nil {
+		return nil, err
+	}
+	if hash != record.Hash {
+		return nil, fmt.Errorf("config %s is pruned, ConfigMap %s/%s is overwritten by config %s", record.Hash, ns, record.ConfigMap, hash)
+	}
+	return cm.Data, nil
+}
+
+// diffConfigData returns the changes of the entries between the data of two ConfigMaps. The TOML values are
+// compared by the flattened keys prefixed by the data keys, e.g. config-file.log.level, and the other values,
+// e.g. the startup scripts, are compared by their digests.
+func diffConfigData(old, cur map[string]string) []string {
+	return diffTierConfig(configDataEntries(old), configDataEntries(cur))
+}
+
+func configDataEntries(data map[string]string) map[string]string {
+	entries := map[string]string{}
+	for key, value := range data {
+		if !strings.HasSuffix(key, "-script") {
+			c := config.New(map[string]interface{}{})
+			if err := c.UnmarshalTOML([]byte(value)); err == nil {
+				flattenConfig(key, c.Inner(), entries)
+				continue
+			}
+		}
+		sum, err := mngerutils.Sha256Sum(value)
+		if err != nil {
+			continue
+		}
+		entries[key] = sum[0:7]
+	}
+	return entries
+}
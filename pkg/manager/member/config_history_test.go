@@ -0,0 +1,175 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiffConfigData(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	old := map[string]string{
+		"config-file": `
+[log]
+level = "info"
+[raftstore]
+capacity = "10GB"
+sync-log = true
+`,
+		"startup-script": "#!/bin/sh\nexec /tikv-server\n",
+	}
+	cur := map[string]string{
+		"config-file": `
+[log]
+level = "debug"
+[raftstore]
+capacity = "10GB"
+[storage]
+reserve-space = "1GB"
+`,
+		"startup-script": "#!/bin/sh\nexec /tikv-server --config /etc/tikv/tikv.toml\n",
+	}
+	oldScript, err := mngerutils.Sha256Sum(old["startup-script"])
+	g.Expect(err).To(Succeed())
+	curScript, err := mngerutils.Sha256Sum(cur["startup-script"])
+	g.Expect(err).To(Succeed())
+
+	g.Expect(diffConfigData(old, cur)).To(Equal([]string{
+		`config-file.log.level: "info" -> "debug"`,
+		`config-file.raftstore.sync-log: true -> <unset>`,
+		`config-file.storage.reserve-space: <unset> -> "1GB"`,
+		fmt.Sprintf("startup-script: %s -> %s", oldScript[0:7], curScript[0:7]),
+	}))
+	g.Expect(diffConfigData(old, old)).To(BeEmpty())
+}
+
+func TestRecordConfigHistory(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	cmIndexer := deps.LabelFilterKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer()
+	tc := newTidbClusterForTiKV()
+	ns := tc.GetNamespace()
+	newConfigMap := func(level string, index int) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tikv", Namespace: ns},
+			Data: map[string]string{
+				"config-file": fmt.Sprintf("[log]\nlevel = %q\n[server]\ngrpc-concurrency = %d\n", level, index),
+			},
+		}
+		g.Expect(mngerutils.AddConfigMapDigestSuffix(cm)).To(Succeed())
+		return cm
+	}
+	records := func() []ConfigHistoryRecord {
+		history, err := deps.KubeClientset.CoreV1().ConfigMaps(ns).Get(context.TODO(), ConfigHistoryName(tc.GetName()), metav1.GetOptions{})
+		g.Expect(err).To(Succeed())
+		g.Expect(cmIndexer.Update(history)).To(Succeed())
+		records, err := configHistoryRecords(history, v1alpha1.TiKVMemberType)
+		g.Expect(err).To(Succeed())
+		return records
+	}
+
+	// the first config is recorded without the diff
+	first := newConfigMap("info", 0)
+	g.Expect(recordConfigHistory(deps, tc, v1alpha1.TiKVMemberType, first, nil)).To(Succeed())
+	g.Expect(cmIndexer.Add(first)).To(Succeed())
+	history := records()
+	g.Expect(history).To(HaveLen(1))
+	g.Expect(history[0].ConfigMap).To(Equal(first.Name))
+	g.Expect(history[0].Revision).To(BeEmpty())
+	g.Expect(history[0].Diff).To(BeEmpty())
+	g.Expect(tc.Status.ConfigHashes[v1alpha1.TiKVMemberType.String()]).To(Equal(history[0].Hash))
+
+	// the revision is filled once the StatefulSet refers to the ConfigMap, and the same config is not recorded again
+	set := &apps.StatefulSet{
+		Spec: apps.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: first.Name},
+			}},
+		}}}}},
+		Status: apps.StatefulSetStatus{UpdateRevision: "test-tikv-1"},
+	}
+	g.Expect(recordConfigHistory(deps, tc, v1alpha1.TiKVMemberType, first, set)).To(Succeed())
+	history = records()
+	g.Expect(history).To(HaveLen(1))
+	g.Expect(history[0].Revision).To(Equal("test-tikv-1"))
+
+	// the changes are recorded with the diff against the previous config
+	second := newConfigMap("debug", 0)
+	g.Expect(recordConfigHistory(deps, tc, v1alpha1.TiKVMemberType, second, set)).To(Succeed())
+	g.Expect(cmIndexer.Add(second)).To(Succeed())
+	history = records()
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history[1].ConfigMap).To(Equal(second.Name))
+	g.Expect(history[1].Revision).To(BeEmpty())
+	g.Expect(history[1].Diff).To(Equal([]string{`config-file.log.level: "info" -> "debug"`}))
+	g.Expect(tc.Status.ConfigHashes[v1alpha1.TiKVMemberType.String()]).To(Equal(history[1].Hash))
+
+	// the configs are reconstructed by the hashes
+	data, err := ConfigForHash(deps.ConfigMapLister, tc, v1alpha1.TiKVMemberType, history[0].Hash)
+	g.Expect(err).To(Succeed())
+	g.Expect(data).To(Equal(first.Data))
+
+	// the records of the other components are kept
+	pd := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pd", Namespace: ns},
+		Data:       map[string]string{"config-file": "[log]\nlevel = \"info\"\n"},
+	}
+	g.Expect(recordConfigHistory(deps, tc, v1alpha1.PDMemberType, pd, nil)).To(Succeed())
+	g.Expect(records()).To(HaveLen(2))
+	g.Expect(tc.Status.ConfigHashes).To(HaveLen(2))
+
+	// only the last configHistoryLimit records are kept
+	for i := 1; i <= configHistoryLimit; i++ {
+		cm := newConfigMap("debug", i)
+		g.Expect(recordConfigHistory(deps, tc, v1alpha1.TiKVMemberType, cm, nil)).To(Succeed())
+		g.Expect(cmIndexer.Add(cm)).To(Succeed())
+	}
+	rotated := history
+	history = records()
+	g.Expect(history).To(HaveLen(configHistoryLimit))
+	g.Expect(history[0].Hash).NotTo(Equal(rotated[0].Hash))
+	g.Expect(history[0].Hash).NotTo(Equal(rotated[1].Hash))
+	g.Expect(history[configHistoryLimit-1].Diff).To(Equal([]string{
+		fmt.Sprintf("config-file.server.grpc-concurrency: %d -> %d", configHistoryLimit-1, configHistoryLimit),
+	}))
+
+	// the configs rotated out of the history, deleted or overwritten in place can not be reconstructed
+	_, err = ConfigForHash(deps.ConfigMapLister, tc, v1alpha1.TiKVMemberType, rotated[0].Hash)
+	g.Expect(err).To(MatchError(ContainSubstring("is not in config history default/test-config-history, only the last 50 configs are kept")))
+
+	deleted := history[0]
+	g.Expect(cmIndexer.Delete(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: deleted.ConfigMap, Namespace: ns}})).To(Succeed())
+	_, err = ConfigForHash(deps.ConfigMapLister, tc, v1alpha1.TiKVMemberType, deleted.Hash)
+	g.Expect(err).To(MatchError(fmt.Sprintf("config %s is pruned, ConfigMap default/%s does not exist", deleted.Hash, deleted.ConfigMap)))
+
+	overwritten := history[1]
+	cm := newConfigMap("warn", 0)
+	cm.Name = overwritten.ConfigMap
+	g.Expect(cmIndexer.Update(cm)).To(Succeed())
+	_, err = ConfigForHash(deps.ConfigMapLister, tc, v1alpha1.TiKVMemberType, overwritten.Hash)
+	g.Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("config %s is pruned, ConfigMap default/%s is overwritten by config", overwritten.Hash, overwritten.ConfigMap))))
+}
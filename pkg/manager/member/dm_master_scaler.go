@@ -158,7 +158,7 @@ func (s *masterScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, new
 	klog.Infof("dm-master scale in: set pvc %s/%s annotation: %s to %s",
 		ns, pvcName, label.AnnPVCDeferDeleting, now)
 
-	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 	return nil
 }
 
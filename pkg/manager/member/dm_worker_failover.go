@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
@@ -75,7 +76,7 @@ func (f *workerFailover) Failover(dc *v1alpha1.DMCluster) error {
 						CreatedAt: metav1.Now(),
 					}
 					msg := fmt.Sprintf("worker[%s/%s] is Offline", ns, worker.Name)
-					f.deps.Recorder.Event(dc, corev1.EventTypeWarning, unHealthEventReason, fmt.Sprintf(unHealthEventMsgPattern, "worker", podName, msg))
+					f.deps.Recorder.Event(dc, corev1.EventTypeWarning, events.Unhealthy, fmt.Sprintf(unHealthEventMsgPattern, "worker", podName, msg))
 				}
 			}
 		}
@@ -117,7 +117,7 @@ func (s *workerScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, new
 	klog.Infof("dm-worker scale in: set pvc %s/%s annotation: %s to %s",
 		ns, pvcName, label.AnnPVCDeferDeleting, now)
 
-	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 	return nil
 }
 
@@ -0,0 +1,359 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/util"
+
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// prepullNextImage creates a pod with the new image on the node of the pod to be upgraded after the
+// given ordinal, so that the image is pulled while the pod of the ordinal is upgrading.
+// Pre-pulling is best effort, the failures are logged and never block the upgrade.
+func prepullNextImage(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, newSet *apps.StatefulSet, ordinal int32) {
+	spec := baseComponentSpec(tc, memberType)
+	if spec == nil || !spec.PrepullNextImage() {
+		return
+	}
+	if tc.Spec.PrePullImages {
+		// the new image is already pulled on all the nodes of the component by the pre-pull DaemonSet
+		return
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	next, ok := nextUpgradeOrdinal(newSet, ordinal)
+	if !ok {
+		return
+	}
+	container := imagePrepullContainer(newSet, memberType)
+	if container == nil {
+		return
+	}
+	podName := fmt.Sprintf("%s-%d", newSet.GetName(), next)
+	pod, err := deps.PodLister.Pods(ns).Get(podName)
+	if err != nil {
+		klog.V(4).Infof("tidbcluster: [%s/%s] skip pre-pulling image for pod %s: %v", ns, tcName, podName, err)
+		return
+	}
+	if pod.Spec.NodeName == "" {
+		return
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container.Name && c.Image == container.Image {
+			// the pod already runs the new image
+			return
+		}
+	}
+
+	name := imagePrepullPodName(newSet.GetName(), next)
+	_, err = deps.PodLister.Pods(ns).Get(name)
+	if err == nil {
+		return
+	}
+	if !errors.IsNotFound(err) {
+		klog.Warningf("tidbcluster: [%s/%s] failed to get image prepull pod %s: %v", ns, tcName, name, err)
+		return
+	}
+	prepullPod := newImagePrepullPod(tc, memberType, newSet, container, name, pod.Spec.NodeName)
+	if err := deps.PodControl.CreatePod(tc, prepullPod); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to create image prepull pod %s: %v", ns, tcName, name, err)
+		return
+	}
+	klog.Infof("tidbcluster: [%s/%s] pre-pull image %s on node %s for pod %s", ns, tcName, container.Image, pod.Spec.NodeName, podName)
+}
+
+// cleanupImagePrepullPods deletes the image prepull pods of the component once its upgrade is finished,
+// they are kept during the upgrade so that the image is pre-pulled only once for each pod.
+func cleanupImagePrepullPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) error {
+	if tc.ComponentUpgradeInProgress(memberType) {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	selector, err := label.New().Instance(tc.GetInstanceName()).Component(label.ImagePrepullLabelVal).Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("cleanupImagePrepullPods: failed to list pods for cluster %s/%s, selector %s, error: %s", ns, tc.GetName(), selector, err)
+	}
+	for _, pod := range pods {
+		if pod.Labels[label.ImagePrepullLabelKey] != memberType.String() || pod.DeletionTimestamp != nil {
+			continue
+		}
+		klog.Infof("tidbcluster: [%s/%s] delete %s image prepull pod %s", ns, tc.GetName(), memberType, pod.GetName())
+		if err := deps.PodControl.DeletePod(tc, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextUpgradeOrdinal returns the ordinal of the pod upgraded after the given ordinal,
+// the pods are upgraded in the descending order of the ordinals.
+func nextUpgradeOrdinal(set *apps.StatefulSet, ordinal int32) (int32, bool) {
+	if set.Spec.Replicas == nil {
+		return 0, false
+	}
+	podOrdinals := helper.GetPodOrdinals(*set.Spec.Replicas, set).List()
+	for i := len(podOrdinals) - 1; i >= 0; i-- {
+		if podOrdinals[i] < ordinal {
+			return podOrdinals[i], true
+		}
+	}
+	return 0, false
+}
+
+// imagePrepullContainer returns the main container of the new pod template
+func imagePrepullContainer(set *apps.StatefulSet, memberType v1alpha1.MemberType) *corev1.Container {
+	containers := set.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == memberType.String() {
+			return &containers[i]
+		}
+	}
+	if len(containers) > 0 {
+		return &containers[0]
+	}
+	return nil
+}
+
+// newImagePrepullPod builds a short-lived pod pinned to the node, it only pulls the image and exits.
+func newImagePrepullPod(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, set *apps.StatefulSet, container *corev1.Container, name, nodeName string) *corev1.Pod {
+	podSelector := imagePrepullSelector(tc, memberType, label.ImagePrepullLabelKey)
+	template := newImagePrepullPodTemplate(tc, set, container, podSelector, []string{"/bin/sh", "-c", "exit 0"})
+	template.Spec.NodeName = nodeName
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       tc.GetNamespace(),
+			Labels:          template.Labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: template.Spec,
+	}
+}
+
+// imagePrepullSelector returns the labels selecting the image prepull pods of the component, labelKey
+// tells the prepull pods of the next pod to upgrade from the pods of the pre-pull DaemonSet
+func imagePrepullSelector(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, labelKey string) map[string]string {
+	podSelector := label.New().Instance(tc.GetInstanceName()).Component(label.ImagePrepullLabelVal).Labels()
+	podSelector[labelKey] = memberType.String()
+	return podSelector
+}
+
+// newImagePrepullPodTemplate builds the pod template pulling the image of the container with the pull secrets
+// and the tolerations of the StatefulSet, it is shared by the image prepull pods and the pre-pull DaemonSets.
+func newImagePrepullPodTemplate(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, container *corev1.Container, podSelector map[string]string, command []string) corev1.PodTemplateSpec {
+	podLabels := util.CombineStringMap(podSelector, changeRequestLabels(tc))
+	template := set.Spec.Template.Spec
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: podLabels,
+		},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets: template.ImagePullSecrets,
+			Tolerations:      template.Tolerations,
+			Containers: []corev1.Container{
+				{
+					Name:            "prepull",
+					Image:           container.Image,
+					ImagePullPolicy: container.ImagePullPolicy,
+					Command:         command,
+				},
+			},
+		},
+	}
+}
+
+func imagePrepullPodName(setName string, ordinal int32) string {
+	return fmt.Sprintf("%s-prepull-%d", setName, ordinal)
+}
+
+// prePullImage pre-pulls the new image of the component by a DaemonSet on the nodes running the component if
+// spec.prePullImages is enabled and the image is changed. The pod template of newSet is kept as the old one until
+// the image is pulled on all these nodes, and the DaemonSet is deleted once the new image is applied.
+func prePullImage(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, oldSet, newSet *apps.StatefulSet) error {
+	status := imagePrePullStatus(tc, memberType)
+	if status == nil {
+		return nil
+	}
+	oldContainer := imagePrepullContainer(oldSet, memberType)
+	newContainer := imagePrepullContainer(newSet, memberType)
+	if !tc.Spec.PrePullImages || oldContainer == nil || newContainer == nil || oldContainer.Image == newContainer.Image {
+		return cleanupImagePrePullDaemonSet(deps, tc, memberType, newSet, status)
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	nodes, err := componentNodeNames(deps, tc, memberType)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return cleanupImagePrePullDaemonSet(deps, tc, memberType, newSet, status)
+	}
+	ds, err := deps.TypedControl.CreateOrUpdateDaemonSet(tc, newImagePrePullDaemonSet(tc, memberType, newSet, newContainer, nodes))
+	if err != nil {
+		return fmt.Errorf("prePullImage: failed to sync %s image pre-pull daemonset for cluster %s/%s, error: %v", memberType, ns, tcName, err)
+	}
+	*status = &v1alpha1.ImagePrePullStatus{
+		Image:        newContainer.Image,
+		DesiredNodes: ds.Status.DesiredNumberScheduled,
+		ReadyNodes:   ds.Status.NumberReady,
+	}
+	if ds.Status.ObservedGeneration >= ds.Generation && ds.Status.DesiredNumberScheduled > 0 &&
+		ds.Status.NumberReady >= ds.Status.DesiredNumberScheduled {
+		return nil
+	}
+
+	klog.Infof("tidbcluster: [%s/%s] wait for %s image %s to be pre-pulled, %d/%d nodes are ready", ns, tcName,
+		memberType, newContainer.Image, ds.Status.NumberReady, ds.Status.DesiredNumberScheduled)
+	_, podSpec, err := GetLastAppliedConfig(oldSet)
+	if err != nil {
+		return err
+	}
+	newSet.Spec.Template.Spec = *podSpec
+	return nil
+}
+
+// cleanupImagePrePullDaemonSet deletes the image pre-pull DaemonSet of the component if it is created
+func cleanupImagePrePullDaemonSet(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, set *apps.StatefulSet, status **v1alpha1.ImagePrePullStatus) error {
+	if *status == nil {
+		return nil
+	}
+	ds := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      imagePrePullDaemonSetName(set.GetName()),
+			Namespace: tc.GetNamespace(),
+		},
+	}
+	if err := deps.TypedControl.Delete(tc, ds); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cleanupImagePrePullDaemonSet: failed to delete daemonset %s for cluster %s/%s, error: %v", ds.GetName(), tc.GetNamespace(), tc.GetName(), err)
+	}
+	klog.Infof("tidbcluster: [%s/%s] delete %s image pre-pull daemonset %s", tc.GetNamespace(), tc.GetName(), memberType, ds.GetName())
+	*status = nil
+	return nil
+}
+
+// componentNodeNames returns the names of the nodes running the pods of the component
+func componentNodeNames(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) ([]string, error) {
+	selector, err := label.New().Instance(tc.GetInstanceName()).Component(memberType.String()).Selector()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := deps.PodLister.Pods(tc.GetNamespace()).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("componentNodeNames: failed to list pods for cluster %s/%s, selector %s, error: %v", tc.GetNamespace(), tc.GetName(), selector, err)
+	}
+	nodes := sets.NewString()
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			nodes.Insert(pod.Spec.NodeName)
+		}
+	}
+	return nodes.List(), nil
+}
+
+// newImagePrePullDaemonSet builds a DaemonSet restricted to the nodes, its pods pull the image and sleep.
+func newImagePrePullDaemonSet(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, set *apps.StatefulSet, container *corev1.Container, nodes []string) *apps.DaemonSet {
+	dsLabels := label.New().Instance(tc.GetInstanceName()).Component(label.ImagePrepullLabelVal)
+	// the change request ID is left out of the selector as the selector of a DaemonSet is immutable
+	podSelector := imagePrepullSelector(tc, memberType, label.ImagePrePullDaemonSetLabelKey)
+	template := newImagePrepullPodTemplate(tc, set, container, podSelector,
+		[]string{"/bin/sh", "-c", "trap 'exit 0' TERM; while true; do sleep 3600 & wait $!; done"})
+	template.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchFields: []corev1.NodeSelectorRequirement{
+							{
+								Key:      "metadata.name",
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   nodes,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            imagePrePullDaemonSetName(set.GetName()),
+			Namespace:       tc.GetNamespace(),
+			Labels:          dsLabels.Labels(),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: apps.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: podSelector},
+			Template: template,
+		},
+	}
+}
+
+func imagePrePullDaemonSetName(setName string) string {
+	return fmt.Sprintf("%s-image-prepull", setName)
+}
+
+// imagePrePullStatus returns the image pre-pull status of the component
+func imagePrePullStatus(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) **v1alpha1.ImagePrePullStatus {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return &tc.Status.PD.ImagePrePull
+	case v1alpha1.TiKVMemberType:
+		return &tc.Status.TiKV.ImagePrePull
+	case v1alpha1.TiFlashMemberType:
+		return &tc.Status.TiFlash.ImagePrePull
+	case v1alpha1.TiDBMemberType:
+		return &tc.Status.TiDB.ImagePrePull
+	case v1alpha1.TiCDCMemberType:
+		return &tc.Status.TiCDC.ImagePrePull
+	}
+	return nil
+}
+
+func baseComponentSpec(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) v1alpha1.ComponentAccessor {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return tc.BasePDSpec()
+	case v1alpha1.TiKVMemberType:
+		return tc.BaseTiKVSpec()
+	case v1alpha1.TiFlashMemberType:
+		return tc.BaseTiFlashSpec()
+	case v1alpha1.TiDBMemberType:
+		return tc.BaseTiDBSpec()
+	case v1alpha1.TiCDCMemberType:
+		return tc.BaseTiCDCSpec()
+	}
+	return nil
+}
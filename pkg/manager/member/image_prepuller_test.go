@@ -0,0 +1,219 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPrepullNextImage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.PrepullNextImage = pointer.BoolPtr(true)
+	newSet := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.TiDBMemberName(tc.Name),
+			Namespace: tc.Namespace,
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: pointer.Int32Ptr(3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "tidb", Image: "pingcap/tidb:v2"}},
+				},
+			},
+		},
+	}
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for i := int32(0); i < 3; i++ {
+		image := "pingcap/tidb:v1"
+		if i == 2 {
+			image = "pingcap/tidb:v2"
+		}
+		g.Expect(podIndexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      tidbPodName(tc.Name, i),
+				Namespace: tc.Namespace,
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   fmt.Sprintf("node-%d", i),
+				Containers: []corev1.Container{{Name: "tidb", Image: image}},
+			},
+		})).To(Succeed())
+	}
+	listPrepullPods := func() []*corev1.Pod {
+		selector, err := label.New().Instance(tc.Name).Component(label.ImagePrepullLabelVal).Selector()
+		g.Expect(err).NotTo(HaveOccurred())
+		pods, err := deps.PodLister.Pods(tc.Namespace).List(selector)
+		g.Expect(err).NotTo(HaveOccurred())
+		return pods
+	}
+
+	// the image is pre-pulled on the node of the next pod
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 2)
+	pods := listPrepullPods()
+	g.Expect(pods).To(HaveLen(1))
+	g.Expect(pods[0].Name).To(Equal(imagePrepullPodName(newSet.Name, 1)))
+	g.Expect(pods[0].Spec.NodeName).To(Equal("node-1"))
+	g.Expect(pods[0].Spec.Containers[0].Image).To(Equal("pingcap/tidb:v2"))
+	g.Expect(pods[0].Labels[label.ImagePrepullLabelKey]).To(Equal(v1alpha1.TiDBMemberType.String()))
+
+	// the image is pre-pulled only once
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 2)
+	g.Expect(listPrepullPods()).To(HaveLen(1))
+
+	// no pod is upgraded after the last one
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 0)
+	g.Expect(listPrepullPods()).To(HaveLen(1))
+
+	// no pre-pulling for the pod running the new image
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 3)
+	g.Expect(listPrepullPods()).To(HaveLen(1))
+
+	// the prepull pods are kept during the upgrade
+	tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+	g.Expect(cleanupImagePrepullPods(deps, tc, v1alpha1.TiDBMemberType)).To(Succeed())
+	g.Expect(listPrepullPods()).To(HaveLen(1))
+
+	// the prepull pods of the other components are kept
+	tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+	g.Expect(cleanupImagePrepullPods(deps, tc, v1alpha1.TiKVMemberType)).To(Succeed())
+	g.Expect(listPrepullPods()).To(HaveLen(1))
+
+	// the prepull pods are deleted after the upgrade
+	g.Expect(cleanupImagePrepullPods(deps, tc, v1alpha1.TiDBMemberType)).To(Succeed())
+	g.Expect(listPrepullPods()).To(BeEmpty())
+
+	// the images are pre-pulled by the DaemonSets before upgrading
+	tc.Spec.PrePullImages = true
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 2)
+	g.Expect(listPrepullPods()).To(BeEmpty())
+
+	// no pre-pulling if it is not enabled
+	tc.Spec.PrePullImages = false
+	tc.Spec.TiDB.PrepullNextImage = nil
+	prepullNextImage(deps, tc, v1alpha1.TiDBMemberType, newSet, 2)
+	pods, err := deps.PodLister.Pods(tc.Namespace).List(labels.Everything())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pods).To(HaveLen(3))
+}
+
+func TestPrePullImage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	genericControl := deps.GenericControl.(*controller.FakeGenericControl)
+	tc := newTidbClusterForTiKV()
+	tc.Spec.PrePullImages = true
+	tc.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+	metav1.SetMetaDataAnnotation(&tc.ObjectMeta, label.AnnChangeRequestID, "CR-1234")
+	newSet := func(image string) *apps.StatefulSet {
+		return &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      controller.TiKVMemberName(tc.Name),
+				Namespace: tc.Namespace,
+			},
+			Spec: apps.StatefulSetSpec{
+				Replicas: pointer.Int32Ptr(3),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						ImagePullSecrets: tc.Spec.ImagePullSecrets,
+						Containers:       []corev1.Container{{Name: "tikv", Image: image}},
+					},
+				},
+			},
+		}
+	}
+	oldSet := newSet("pingcap/tikv:v1")
+	g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for i := int32(0); i < 3; i++ {
+		g.Expect(podIndexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TikvPodName(tc.Name, i),
+				Namespace: tc.Namespace,
+				Labels:    label.New().Instance(tc.Name).TiKV().Labels(),
+			},
+			Spec: corev1.PodSpec{NodeName: fmt.Sprintf("node-%d", i%2)},
+		})).To(Succeed())
+	}
+	getDaemonSet := func() (*apps.DaemonSet, error) {
+		ds := &apps.DaemonSet{}
+		key := client.ObjectKey{Namespace: tc.Namespace, Name: imagePrePullDaemonSetName(oldSet.Name)}
+		return ds, genericControl.FakeCli.Get(context.TODO(), key, ds)
+	}
+
+	// the upgrade waits for the image to be pre-pulled
+	set := newSet("pingcap/tikv:v2")
+	g.Expect(prePullImage(deps, tc, v1alpha1.TiKVMemberType, oldSet, set)).To(Succeed())
+	g.Expect(set.Spec.Template.Spec.Containers[0].Image).To(Equal("pingcap/tikv:v1"))
+	g.Expect(tc.Status.TiKV.ImagePrePull).To(Equal(&v1alpha1.ImagePrePullStatus{Image: "pingcap/tikv:v2"}))
+	ds, err := getDaemonSet()
+	g.Expect(err).NotTo(HaveOccurred())
+	podSpec := ds.Spec.Template.Spec
+	g.Expect(podSpec.Containers[0].Image).To(Equal("pingcap/tikv:v2"))
+	g.Expect(podSpec.ImagePullSecrets).To(Equal(tc.Spec.ImagePullSecrets))
+	g.Expect(podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields[0].Values).
+		To(Equal([]string{"node-0", "node-1"}))
+	g.Expect(ds.Spec.Template.Labels[label.ImagePrePullDaemonSetLabelKey]).To(Equal(v1alpha1.TiKVMemberType.String()))
+	g.Expect(ds.Spec.Template.Labels[label.AnnChangeRequestID]).To(Equal("CR-1234"))
+	g.Expect(ds.Spec.Selector.MatchLabels).NotTo(HaveKey(label.AnnChangeRequestID))
+
+	ds.Status = apps.DaemonSetStatus{DesiredNumberScheduled: 2, NumberReady: 1}
+	g.Expect(genericControl.FakeCli.Status().Update(context.TODO(), ds)).To(Succeed())
+	set = newSet("pingcap/tikv:v2")
+	g.Expect(prePullImage(deps, tc, v1alpha1.TiKVMemberType, oldSet, set)).To(Succeed())
+	g.Expect(set.Spec.Template.Spec.Containers[0].Image).To(Equal("pingcap/tikv:v1"))
+	g.Expect(tc.Status.TiKV.ImagePrePull.ReadyNodes).To(Equal(int32(1)))
+	g.Expect(tc.Status.TiKV.ImagePrePull.DesiredNodes).To(Equal(int32(2)))
+
+	// the upgrade starts after the image is pulled on all the nodes
+	ds.Status.NumberReady = 2
+	g.Expect(genericControl.FakeCli.Status().Update(context.TODO(), ds)).To(Succeed())
+	set = newSet("pingcap/tikv:v2")
+	g.Expect(prePullImage(deps, tc, v1alpha1.TiKVMemberType, oldSet, set)).To(Succeed())
+	g.Expect(set.Spec.Template.Spec.Containers[0].Image).To(Equal("pingcap/tikv:v2"))
+
+	// the daemonset is deleted after the new image is applied
+	oldSet = newSet("pingcap/tikv:v2")
+	g.Expect(prePullImage(deps, tc, v1alpha1.TiKVMemberType, oldSet, newSet("pingcap/tikv:v2"))).To(Succeed())
+	g.Expect(tc.Status.TiKV.ImagePrePull).To(BeNil())
+	_, err = getDaemonSet()
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+
+	// no pre-pulling if it is not enabled
+	tc.Spec.PrePullImages = false
+	set = newSet("pingcap/tikv:v3")
+	g.Expect(prePullImage(deps, tc, v1alpha1.TiKVMemberType, oldSet, set)).To(Succeed())
+	g.Expect(set.Spec.Template.Spec.Containers[0].Image).To(Equal("pingcap/tikv:v3"))
+	_, err = getDaemonSet()
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+// syncPDNodeBindings moves a PD pod not on the nodes of its binding in spec.pd.nodeBindings by deleting it, so
+// that it is recreated and pinned to the nodes by the pod webhook. A pod is moved only if all the members are
+// healthy, and the leader is transferred away from the pod before it is deleted.
+func syncPDNodeBindings(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if len(tc.NodeBindings(v1alpha1.PDMemberType)) == 0 {
+		return nil
+	}
+	if tc.Status.PD.Phase != v1alpha1.NormalPhase || !tc.PDAllPodsStarted() || !tc.PDAllMembersReady() {
+		klog.V(4).Infof("tidbcluster: [%s/%s] pd is not healthy, skip moving the pods to the nodes of their bindings", ns, tcName)
+		return nil
+	}
+	pod, binding, err := nextPodToMove(deps, tc, v1alpha1.PDMemberType, tc.PDStsDesiredReplicas())
+	if err != nil || pod == nil {
+		return err
+	}
+
+	ordinal := binding.Ordinal
+	pdName := PdName(tcName, ordinal, ns, tc.Spec.ClusterDomain)
+	if leader := tc.Status.PD.Leader.Name; leader == pdName || leader == pod.Name {
+		targetName := ""
+		names := make([]string, 0, len(tc.Status.PD.Members))
+		for name, member := range tc.Status.PD.Members {
+			if member.Health && name != pdName && name != pod.Name {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			targetName = names[0]
+		}
+		if targetName == "" {
+			return fmt.Errorf("syncPDNodeBindings: pd pod %s/%s is the leader and no healthy member to transfer the leader to", ns, pod.Name)
+		}
+		if err := requeueIfPDCircuitOpen(controller.GetPDClient(deps.PDControl, tc).TransferPDLeader(targetName)); err != nil {
+			return err
+		}
+		klog.Infof("tidbcluster: [%s/%s] transfer pd leader from %s to %s before moving it to the nodes of its binding", ns, tcName, pod.Name, targetName)
+		return nil
+	}
+
+	msg := fmt.Sprintf("move pd pod %s from node %s to the nodes of its binding", pod.Name, pod.Spec.NodeName)
+	klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+	if err := deps.PodControl.DeletePod(tc, pod); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	deps.Recorder.Event(tc, corev1.EventTypeNormal, events.NodeBindingMove, msg)
+	return nil
+}
+
+// syncTiKVNodeBindings moves a TiKV pod not on the nodes of its binding in spec.tikv.nodeBindings. A pod is moved
+// only if all the stores are Up and no leader is being evicted, the leaders of its store are evicted by the pod
+// controller before it deletes the pod, and the eviction ends after the recreated pod is ready.
+func syncTiKVNodeBindings(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if len(tc.NodeBindings(v1alpha1.TiKVMemberType)) == 0 {
+		return nil
+	}
+	if tc.Status.TiKV.Phase != v1alpha1.NormalPhase || !tc.TiKVAllPodsStarted() || !tc.TiKVAllStoresReady() ||
+		len(tc.Status.TiKV.EvictLeader) > 0 {
+		klog.V(4).Infof("tidbcluster: [%s/%s] tikv is not healthy, skip moving the pods to the nodes of their bindings", ns, tcName)
+		return nil
+	}
+	pod, _, err := nextPodToMove(deps, tc, v1alpha1.TiKVMemberType, tc.TiKVStsDesiredReplicas())
+	if err != nil || pod == nil {
+		return err
+	}
+	for _, key := range v1alpha1.EvictLeaderAnnKeys {
+		if _, ok := pod.Annotations[key]; ok {
+			klog.V(4).Infof("tidbcluster: [%s/%s] the leaders of tikv pod %s are being evicted, skip moving it", ns, tcName, pod.Name)
+			return nil
+		}
+	}
+
+	msg := fmt.Sprintf("move tikv pod %s from node %s to the nodes of its binding", pod.Name, pod.Spec.NodeName)
+	klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+	pod = pod.DeepCopy()
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[v1alpha1.EvictLeaderAnnKey] = v1alpha1.EvictLeaderValueDeletePod
+	if _, err := deps.KubeClientset.CoreV1().Pods(ns).Update(context.TODO(), pod, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("syncTiKVNodeBindings: add leader eviction annotation to pod %s/%s failed: %v", ns, pod.Name, err)
+	}
+	deps.Recorder.Event(tc, corev1.EventTypeNormal, events.NodeBindingMove, msg)
+	return nil
+}
+
+// nextPodToMove returns the first pod of the component by name which is not on the nodes of its binding, it
+// returns nil if any of the replicas is absent or not ready. The bindings selecting no existing node are
+// reported by events and the pods of them are not moved.
+func nextPodToMove(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, replicas int32) (*corev1.Pod, *v1alpha1.NodeBinding, error) {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if deps.NodeLister == nil {
+		klog.V(4).Infof("tidbcluster: [%s/%s] node lister is unavailable, skip moving the %s pods to the nodes of their bindings", ns, tcName, memberType)
+		return nil, nil, nil
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).Component(memberType.String()).Selector()
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("nextPodToMove: failed to list pods for cluster %s/%s, selector %s, error: %v", ns, tcName, selector, err)
+	}
+	var pods []*corev1.Pod
+	for _, pod := range all {
+		// the tidb warm standby pods and the pods of the tidb groups are not the replicas of the StatefulSet
+		if !isTiDBWarmStandbyPod(pod) && tidbGroupOf(pod) == "" {
+			pods = append(pods, pod)
+		}
+	}
+	if len(pods) != int(replicas) {
+		klog.V(4).Infof("tidbcluster: [%s/%s] %d of %d %s pods exist, skip moving the pods to the nodes of their bindings", ns, tcName, len(pods), replicas, memberType)
+		return nil, nil, nil
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !podutil.IsPodReady(pod) {
+			klog.V(4).Infof("tidbcluster: [%s/%s] %s pod %s is not ready, skip moving the pods to the nodes of their bindings", ns, tcName, memberType, pod.Name)
+			return nil, nil, nil
+		}
+	}
+
+	for _, pod := range pods {
+		ordinal, err := util.GetOrdinalFromPodName(pod.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		binding := tc.NodeBinding(memberType, ordinal)
+		if binding == nil {
+			continue
+		}
+		node, err := deps.NodeLister.Get(pod.Spec.NodeName)
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, nil, err
+		}
+		if node != nil && binding.Matches(node) {
+			continue
+		}
+		exist, err := nodeBindingExists(deps, binding)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exist {
+			msg := fmt.Sprintf("the binding of %s pod %s selects no existing node, the pod is not moved", memberType, pod.Name)
+			klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+			deps.Recorder.Event(tc, corev1.EventTypeWarning, events.NodeBindingInvalid, msg)
+			continue
+		}
+		return pod, binding, nil
+	}
+	return nil, nil, nil
+}
+
+// nodeBindingExists returns true if the binding selects any existing node
+func nodeBindingExists(deps *controller.Dependencies, binding *v1alpha1.NodeBinding) (bool, error) {
+	if binding.NodeName != "" {
+		_, err := deps.NodeLister.Get(binding.NodeName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	nodes, err := deps.NodeLister.List(labels.SelectorFromSet(binding.NodeSelector))
+	if err != nil {
+		return false, err
+	}
+	return len(nodes) > 0, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// addNodeBindingPods adds the nodes and the ready pods of the component on them to the informers and the clientset
+func addNodeBindingPods(g *GomegaWithT, deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podNodes map[string]string, nodeLabels map[string]map[string]string) cache.Indexer {
+	nodeIndexer := deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for name, l := range nodeLabels {
+		g.Expect(nodeIndexer.Add(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: l}})).To(Succeed())
+	}
+	for name, node := range podNodes {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: tc.Namespace,
+				Labels:    label.New().Instance(tc.GetInstanceName()).Component(memberType.String()).Labels(),
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		g.Expect(podIndexer.Add(pod)).To(Succeed())
+		_, err := deps.KubeClientset.CoreV1().Pods(tc.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	return podIndexer
+}
+
+func TestSyncPDNodeBindings(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForPD()
+	tc.Status.PD.Phase = v1alpha1.NormalPhase
+	tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{Replicas: 3}
+	tc.Status.PD.Members = map[string]v1alpha1.PDMember{}
+	for i := 0; i < 3; i++ {
+		name := PdPodName(tc.Name, int32(i))
+		tc.Status.PD.Members[name] = v1alpha1.PDMember{Name: name, Health: true}
+	}
+	tc.Status.PD.Leader = v1alpha1.PDMember{Name: "test-pd-1", Health: true}
+	podIndexer := addNodeBindingPods(g, deps, tc, v1alpha1.PDMemberType,
+		map[string]string{"test-pd-0": "node-a", "test-pd-1": "node-c", "test-pd-2": "node-c"},
+		map[string]map[string]string{"node-a": nil, "node-b": {"disk": "ssd"}, "node-c": nil})
+	var transferredTo string
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.TransferPDLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+		transferredTo = action.Name
+		return nil, nil
+	})
+	podNames := func() []string {
+		var names []string
+		for _, obj := range podIndexer.List() {
+			names = append(names, obj.(*corev1.Pod).Name)
+		}
+		return names
+	}
+
+	// nothing is moved without the bindings
+	g.Expect(syncPDNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(HaveLen(3))
+
+	// the leader is transferred away from the pod not on the nodes of its binding before it is moved
+	tc.Spec.PD.NodeBindings = []v1alpha1.NodeBinding{
+		{Ordinal: 0, NodeName: "node-a"},
+		{Ordinal: 1, NodeSelector: map[string]string{"disk": "ssd"}},
+		{Ordinal: 2, NodeName: "node-c"},
+	}
+	g.Expect(syncPDNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(transferredTo).To(Equal("test-pd-0"))
+	g.Expect(podNames()).To(HaveLen(3))
+
+	// nothing is moved while a member is unhealthy
+	tc.Status.PD.Leader = v1alpha1.PDMember{Name: "test-pd-0", Health: true}
+	tc.Status.PD.Members["test-pd-2"] = v1alpha1.PDMember{Name: "test-pd-2", Health: false}
+	g.Expect(syncPDNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(HaveLen(3))
+
+	tc.Status.PD.Members["test-pd-2"] = v1alpha1.PDMember{Name: "test-pd-2", Health: true}
+	g.Expect(syncPDNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(ConsistOf("test-pd-0", "test-pd-2"))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.NodeBindingMove))
+	g.Expect(recorded[0]).To(ContainSubstring("test-pd-1"))
+
+	// nothing is moved until the moved pod is recreated
+	tc.Spec.PD.NodeBindings[2].NodeName = "node-b"
+	g.Expect(syncPDNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(HaveLen(2))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+}
+
+func TestSyncTiKVNodeBindings(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForTiKV()
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{Replicas: 3}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("%d", i+1)
+		tc.Status.TiKV.Stores[id] = v1alpha1.TiKVStore{ID: id, PodName: TikvPodName(tc.Name, int32(i)), State: v1alpha1.TiKVStateUp}
+	}
+	addNodeBindingPods(g, deps, tc, v1alpha1.TiKVMemberType,
+		map[string]string{"test-tikv-0": "node-a", "test-tikv-1": "node-a", "test-tikv-2": "node-c"},
+		map[string]map[string]string{"node-a": nil, "node-b": nil, "node-c": nil})
+	evicting := func() []string {
+		pods, err := deps.KubeClientset.CoreV1().Pods(tc.Namespace).List(context.TODO(), metav1.ListOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, pod := range pods.Items {
+			if pod.Annotations[v1alpha1.EvictLeaderAnnKey] == v1alpha1.EvictLeaderValueDeletePod {
+				names = append(names, pod.Name)
+			}
+		}
+		return names
+	}
+
+	// the binding selecting no existing node is reported and its pod is not moved
+	tc.Spec.TiKV.NodeBindings = []v1alpha1.NodeBinding{
+		{Ordinal: 0, NodeName: "node-d"},
+		{Ordinal: 1, NodeName: "node-b"},
+		{Ordinal: 2, NodeName: "node-c"},
+	}
+	tc.Status.TiKV.Stores["2"] = v1alpha1.TiKVStore{ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateDown}
+	g.Expect(syncTiKVNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(evicting()).To(BeEmpty())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the leaders of the store are evicted before the pod is deleted once all the stores are Up
+	tc.Status.TiKV.Stores["2"] = v1alpha1.TiKVStore{ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp}
+	g.Expect(syncTiKVNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(evicting()).To(ConsistOf("test-tikv-1"))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(2))
+	g.Expect(recorded[0]).To(ContainSubstring(events.NodeBindingInvalid))
+	g.Expect(recorded[0]).To(ContainSubstring("test-tikv-0"))
+	g.Expect(recorded[1]).To(ContainSubstring(events.NodeBindingMove))
+	g.Expect(recorded[1]).To(ContainSubstring("test-tikv-1"))
+
+	// nothing is moved while the leaders of a store are being evicted
+	tc.Spec.TiKV.NodeBindings[2].NodeName = "node-b"
+	tc.Status.TiKV.EvictLeader = map[string]*v1alpha1.EvictLeaderStatus{"test-tikv-1": {Value: v1alpha1.EvictLeaderValueDeletePod}}
+	g.Expect(syncTiKVNodeBindings(deps, tc)).To(Succeed())
+	g.Expect(evicting()).To(ConsistOf("test-tikv-1"))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+}
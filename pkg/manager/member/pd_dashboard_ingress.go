@@ -0,0 +1,368 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultOAuth2ProxyImage = "quay.io/oauth2-proxy/oauth2-proxy:v7.2.1"
+	oauth2ProxyPort         = 4180
+
+	// dashboardPath is the path the TiDB Dashboard is served at by PD, the path is not rewritten
+	// by the Ingress so that the session cookie of the dashboard keeps working
+	dashboardPath = "/dashboard"
+
+	ingressNginxBackendProtocolAnn = "nginx.ingress.kubernetes.io/backend-protocol"
+	ingressNginxAuthTypeAnn        = "nginx.ingress.kubernetes.io/auth-type"
+	ingressNginxAuthSecretAnn      = "nginx.ingress.kubernetes.io/auth-secret"
+	ingressNginxAuthRealmAnn       = "nginx.ingress.kubernetes.io/auth-realm"
+)
+
+// syncDashboardIngress reconciles the Ingress of the TiDB Dashboard, and the oauth2-proxy
+// Deployment and Service in front of PD if it is enabled. They are removed if disabled.
+func (m *pdMemberManager) syncDashboardIngress(tc *v1alpha1.TidbCluster) error {
+	if tc.IsComponentPaused(v1alpha1.PDMemberType) {
+		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd dashboard ingress", tc.GetNamespace(), tc.GetName())
+		return nil
+	}
+
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	spec := tc.Spec.PD.DashboardIngress
+	if spec == nil || spec.AuthProxy == nil || spec.AuthProxy.Type != v1alpha1.DashboardAuthProxyOAuth2Proxy {
+		if err := m.removeDashboardAuthProxy(tc); err != nil {
+			return err
+		}
+	}
+	if spec == nil {
+		tc.Status.PD.DashboardURL = ""
+		return m.removeDashboardIngress(tc)
+	}
+
+	backendName := controller.PDMemberName(tcName)
+	backendPort := 2379
+	if spec.AuthProxy != nil && spec.AuthProxy.Type == v1alpha1.DashboardAuthProxyOAuth2Proxy {
+		if _, err := m.deps.TypedControl.CreateOrUpdateService(tc, getDashboardAuthProxyService(tc)); err != nil {
+			return fmt.Errorf("syncDashboardIngress: failed to sync auth proxy service for cluster %s/%s, error: %s", ns, tcName, err)
+		}
+		if _, err := m.deps.TypedControl.CreateOrUpdateDeployment(tc, getDashboardAuthProxyDeployment(tc)); err != nil {
+			return fmt.Errorf("syncDashboardIngress: failed to sync auth proxy deployment for cluster %s/%s, error: %s", ns, tcName, err)
+		}
+		backendName = dashboardAuthProxyName(tcName)
+		backendPort = oauth2ProxyPort
+	}
+
+	var err error
+	if m.deps.IngressV1Beta1Lister != nil {
+		_, err = m.deps.TypedControl.CreateOrUpdateIngressV1beta1(tc, getDashboardIngressV1beta1(tc, backendName, backendPort))
+	} else {
+		_, err = m.deps.TypedControl.CreateOrUpdateIngress(tc, getDashboardIngress(tc, backendName, backendPort))
+	}
+	if err != nil {
+		return fmt.Errorf("syncDashboardIngress: failed to sync ingress for cluster %s/%s, error: %s", ns, tcName, err)
+	}
+
+	scheme := "http"
+	if spec.TLSSecretName != "" {
+		scheme = "https"
+	}
+	tc.Status.PD.DashboardURL = fmt.Sprintf("%s://%s%s/", scheme, spec.Host, dashboardPath)
+	return nil
+}
+
+func (m *pdMemberManager) removeDashboardIngress(tc *v1alpha1.TidbCluster) error {
+	var (
+		err     error
+		ingress client.Object
+	)
+	ns := tc.GetNamespace()
+	name := dashboardIngressName(tc.GetName())
+	if m.deps.IngressV1Beta1Lister != nil {
+		ingress, err = m.deps.IngressV1Beta1Lister.Ingresses(ns).Get(name)
+	} else {
+		ingress, err = m.deps.IngressLister.Ingresses(ns).Get(name)
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	klog.Infof("tidbcluster: [%s/%s] delete pd dashboard ingress %s", ns, tc.GetName(), name)
+	return m.deps.TypedControl.Delete(tc, ingress)
+}
+
+func (m *pdMemberManager) removeDashboardAuthProxy(tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	name := dashboardAuthProxyName(tc.GetName())
+
+	deploy, err := m.deps.DeploymentLister.Deployments(ns).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		klog.Infof("tidbcluster: [%s/%s] delete pd dashboard auth proxy deployment %s", ns, tc.GetName(), name)
+		if err := m.deps.TypedControl.Delete(tc, deploy); err != nil {
+			return err
+		}
+	}
+
+	svc, err := m.deps.ServiceLister.Services(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	klog.Infof("tidbcluster: [%s/%s] delete pd dashboard auth proxy service %s", ns, tc.GetName(), name)
+	return m.deps.TypedControl.Delete(tc, svc)
+}
+
+// getDashboardIngressPath returns the path routed to the backend, all the requests are routed to
+// oauth2-proxy as it serves its own endpoints under /oauth2
+func getDashboardIngressPath(tc *v1alpha1.TidbCluster) string {
+	if auth := tc.Spec.PD.DashboardIngress.AuthProxy; auth != nil && auth.Type == v1alpha1.DashboardAuthProxyOAuth2Proxy {
+		return "/"
+	}
+	return dashboardPath
+}
+
+func getDashboardIngressAnnotations(tc *v1alpha1.TidbCluster) map[string]string {
+	spec := tc.Spec.PD.DashboardIngress
+	anns := map[string]string{}
+	if tc.IsTLSClusterEnabled() && (spec.AuthProxy == nil || spec.AuthProxy.Type != v1alpha1.DashboardAuthProxyOAuth2Proxy) {
+		anns[ingressNginxBackendProtocolAnn] = "HTTPS"
+	}
+	if spec.AuthProxy != nil && spec.AuthProxy.Type == v1alpha1.DashboardAuthProxyBasicAuth {
+		anns[ingressNginxAuthTypeAnn] = "basic"
+		anns[ingressNginxAuthSecretAnn] = spec.AuthProxy.SecretName
+		anns[ingressNginxAuthRealmAnn] = "Authentication Required"
+	}
+	// the annotations in the spec take precedence
+	for k, v := range spec.Annotations {
+		anns[k] = v
+	}
+	return anns
+}
+
+func getDashboardIngress(tc *v1alpha1.TidbCluster, backendName string, backendPort int) *networkingv1.Ingress {
+	spec := tc.Spec.PD.DashboardIngress
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            dashboardIngressName(tc.GetName()),
+			Namespace:       tc.GetNamespace(),
+			Labels:          label.New().Instance(tc.GetInstanceName()).PD().Labels(),
+			Annotations:     getDashboardIngressAnnotations(tc),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: spec.IngressClassName,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: spec.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     getDashboardIngressPath(tc),
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: backendName,
+											Port: networkingv1.ServiceBackendPort{
+												Number: int32(backendPort),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      []string{spec.Host},
+				SecretName: spec.TLSSecretName,
+			},
+		}
+	}
+	return ingress
+}
+
+func getDashboardIngressV1beta1(tc *v1alpha1.TidbCluster, backendName string, backendPort int) *extensionsv1beta1.Ingress {
+	spec := tc.Spec.PD.DashboardIngress
+	pathType := extensionsv1beta1.PathTypePrefix
+	ingress := &extensionsv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            dashboardIngressName(tc.GetName()),
+			Namespace:       tc.GetNamespace(),
+			Labels:          label.New().Instance(tc.GetInstanceName()).PD().Labels(),
+			Annotations:     getDashboardIngressAnnotations(tc),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: extensionsv1beta1.IngressSpec{
+			IngressClassName: spec.IngressClassName,
+			Rules: []extensionsv1beta1.IngressRule{
+				{
+					Host: spec.Host,
+					IngressRuleValue: extensionsv1beta1.IngressRuleValue{
+						HTTP: &extensionsv1beta1.HTTPIngressRuleValue{
+							Paths: []extensionsv1beta1.HTTPIngressPath{
+								{
+									Path:     getDashboardIngressPath(tc),
+									PathType: &pathType,
+									Backend: extensionsv1beta1.IngressBackend{
+										ServiceName: backendName,
+										ServicePort: intstr.FromInt(backendPort),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		ingress.Spec.TLS = []extensionsv1beta1.IngressTLS{
+			{
+				Hosts:      []string{spec.Host},
+				SecretName: spec.TLSSecretName,
+			},
+		}
+	}
+	return ingress
+}
+
+func getDashboardAuthProxyLabels(tc *v1alpha1.TidbCluster) label.Label {
+	return label.New().Instance(tc.GetInstanceName()).Component(label.PDDashboardAuthProxyLabelVal)
+}
+
+func getDashboardAuthProxyService(tc *v1alpha1.TidbCluster) *corev1.Service {
+	proxyLabels := getDashboardAuthProxyLabels(tc)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            dashboardAuthProxyName(tc.GetName()),
+			Namespace:       tc.GetNamespace(),
+			Labels:          proxyLabels.Labels(),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       oauth2ProxyPort,
+					TargetPort: intstr.FromInt(oauth2ProxyPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: proxyLabels.Labels(),
+		},
+	}
+}
+
+// getDashboardAuthProxyDeployment builds the oauth2-proxy Deployment, the configuration
+// other than the upstream is read from the envs in the secret of the auth proxy.
+func getDashboardAuthProxyDeployment(tc *v1alpha1.TidbCluster) *appsv1.Deployment {
+	auth := tc.Spec.PD.DashboardIngress.AuthProxy
+	proxyLabels := getDashboardAuthProxyLabels(tc)
+	image := defaultOAuth2ProxyImage
+	if auth.Image != nil {
+		image = *auth.Image
+	}
+
+	scheme := "http"
+	args := []string{
+		fmt.Sprintf("--http-address=0.0.0.0:%d", oauth2ProxyPort),
+		"--reverse-proxy=true",
+	}
+	if tc.IsTLSClusterEnabled() {
+		scheme = "https"
+		args = append(args, "--ssl-upstream-insecure-skip-verify=true")
+	}
+	args = append(args, fmt.Sprintf("--upstream=%s://%s:2379%s/", scheme, controller.PDMemberName(tc.GetName()), dashboardPath))
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            dashboardAuthProxyName(tc.GetName()),
+			Namespace:       tc.GetNamespace(),
+			Labels:          proxyLabels.Labels(),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32Ptr(1),
+			Selector: proxyLabels.LabelSelector(),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: proxyLabels.Labels(),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "oauth2-proxy",
+							Image:           image,
+							ImagePullPolicy: tc.Spec.ImagePullPolicy,
+							Args:            args,
+							EnvFrom: []corev1.EnvFromSource{
+								{
+									SecretRef: &corev1.SecretEnvSource{
+										LocalObjectReference: corev1.LocalObjectReference{Name: auth.SecretName},
+									},
+								},
+							},
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          "http",
+									ContainerPort: oauth2ProxyPort,
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+						},
+					},
+					ImagePullSecrets: tc.Spec.ImagePullSecrets,
+				},
+			},
+		},
+	}
+}
+
+func dashboardIngressName(tcName string) string {
+	return fmt.Sprintf("%s-dashboard", controller.PDMemberName(tcName))
+}
+
+func dashboardAuthProxyName(tcName string) string {
+	return fmt.Sprintf("%s-dashboard-proxy", controller.PDMemberName(tcName))
+}
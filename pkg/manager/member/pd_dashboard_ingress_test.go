@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+func TestPDMemberManagerSyncDashboardIngress(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	m := &pdMemberManager{deps: deps}
+	cli := deps.GenericControl.(*controller.FakeGenericControl).FakeCli
+	tc := newTidbClusterForPD()
+	tc.Spec.PD.DashboardIngress = &v1alpha1.DashboardIngressSpec{
+		Host:             "dashboard.example.com",
+		IngressClassName: pointer.StringPtr("nginx"),
+		TLSSecretName:    "dashboard-tls",
+		Annotations:      map[string]string{"foo": "bar"},
+		AuthProxy: &v1alpha1.DashboardAuthProxy{
+			Type:       v1alpha1.DashboardAuthProxyBasicAuth,
+			SecretName: "dashboard-auth",
+		},
+	}
+	ingressKey := types.NamespacedName{Namespace: tc.Namespace, Name: dashboardIngressName(tc.Name)}
+	proxyKey := types.NamespacedName{Namespace: tc.Namespace, Name: dashboardAuthProxyName(tc.Name)}
+
+	// the ingress routes the dashboard path to PD with basic auth
+	g.Expect(m.syncDashboardIngress(tc)).To(Succeed())
+	ingress := &networkingv1.Ingress{}
+	g.Expect(cli.Get(context.TODO(), ingressKey, ingress)).To(Succeed())
+	g.Expect(ingress.Spec.IngressClassName).To(Equal(pointer.StringPtr("nginx")))
+	g.Expect(ingress.Spec.TLS).To(Equal([]networkingv1.IngressTLS{{Hosts: []string{"dashboard.example.com"}, SecretName: "dashboard-tls"}}))
+	path := ingress.Spec.Rules[0].HTTP.Paths[0]
+	g.Expect(path.Path).To(Equal("/dashboard"))
+	g.Expect(path.Backend.Service.Name).To(Equal(controller.PDMemberName(tc.Name)))
+	g.Expect(path.Backend.Service.Port.Number).To(Equal(int32(2379)))
+	g.Expect(ingress.Annotations).To(HaveKeyWithValue("foo", "bar"))
+	g.Expect(ingress.Annotations).To(HaveKeyWithValue(ingressNginxAuthTypeAnn, "basic"))
+	g.Expect(ingress.Annotations).To(HaveKeyWithValue(ingressNginxAuthSecretAnn, "dashboard-auth"))
+	g.Expect(tc.Status.PD.DashboardURL).To(Equal("https://dashboard.example.com/dashboard/"))
+
+	// the ingress routes all the requests to oauth2-proxy
+	tc.Spec.PD.DashboardIngress.TLSSecretName = ""
+	tc.Spec.PD.DashboardIngress.AuthProxy = &v1alpha1.DashboardAuthProxy{
+		Type:       v1alpha1.DashboardAuthProxyOAuth2Proxy,
+		SecretName: "dashboard-oauth2",
+	}
+	g.Expect(m.syncDashboardIngress(tc)).To(Succeed())
+	g.Expect(cli.Get(context.TODO(), ingressKey, ingress)).To(Succeed())
+	path = ingress.Spec.Rules[0].HTTP.Paths[0]
+	g.Expect(path.Path).To(Equal("/"))
+	g.Expect(path.Backend.Service.Name).To(Equal(dashboardAuthProxyName(tc.Name)))
+	g.Expect(path.Backend.Service.Port.Number).To(Equal(int32(oauth2ProxyPort)))
+	g.Expect(tc.Status.PD.DashboardURL).To(Equal("http://dashboard.example.com/dashboard/"))
+	deploy := &appsv1.Deployment{}
+	g.Expect(cli.Get(context.TODO(), proxyKey, deploy)).To(Succeed())
+	container := deploy.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(Equal(defaultOAuth2ProxyImage))
+	g.Expect(container.Args).To(ContainElement("--upstream=http://test-pd:2379/dashboard/"))
+	g.Expect(container.EnvFrom[0].SecretRef.Name).To(Equal("dashboard-oauth2"))
+	svc := &corev1.Service{}
+	g.Expect(cli.Get(context.TODO(), proxyKey, svc)).To(Succeed())
+
+	// the ingress and the auth proxy are removed when disabled
+	g.Expect(deps.KubeInformerFactory.Networking().V1().Ingresses().Informer().GetIndexer().Add(ingress)).To(Succeed())
+	g.Expect(deps.KubeInformerFactory.Apps().V1().Deployments().Informer().GetIndexer().Add(deploy)).To(Succeed())
+	g.Expect(deps.KubeInformerFactory.Core().V1().Services().Informer().GetIndexer().Add(svc)).To(Succeed())
+	tc.Spec.PD.DashboardIngress = nil
+	g.Expect(m.syncDashboardIngress(tc)).To(Succeed())
+	g.Expect(errors.IsNotFound(cli.Get(context.TODO(), ingressKey, ingress))).To(BeTrue())
+	g.Expect(errors.IsNotFound(cli.Get(context.TODO(), proxyKey, deploy))).To(BeTrue())
+	g.Expect(errors.IsNotFound(cli.Get(context.TODO(), proxyKey, svc))).To(BeTrue())
+	g.Expect(tc.Status.PD.DashboardURL).To(BeEmpty())
+}
@@ -86,12 +86,17 @@ func (m *pdMemberManager) Sync(tc *v1alpha1.TidbCluster) error {
 		return err
 	}
 
+	// Sync PD Dashboard Ingress
+	if err := m.syncDashboardIngress(tc); err != nil {
+		return err
+	}
+
 	// Sync PD StatefulSet
 	return m.syncPDStatefulSetForTidbCluster(tc)
 }
 
 func (m *pdMemberManager) syncPDServiceForTidbCluster(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.PDMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -118,7 +123,8 @@ func (m *pdMemberManager) syncPDServiceForTidbCluster(tc *v1alpha1.TidbCluster)
 	if err != nil {
 		return err
 	}
-	if !equal {
+	annotated := syncTopologyAwareRoutingAnnotations(newSvc, oldSvc)
+	if !equal || annotated {
 		svc := *oldSvc
 		svc.Spec = newSvc.Spec
 		// TODO add unit test
@@ -135,7 +141,7 @@ func (m *pdMemberManager) syncPDServiceForTidbCluster(tc *v1alpha1.TidbCluster)
 }
 
 func (m *pdMemberManager) syncPDHeadlessServiceForTidbCluster(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.PDMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd headless service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -144,7 +150,7 @@ func (m *pdMemberManager) syncPDHeadlessServiceForTidbCluster(tc *v1alpha1.TidbC
 	tcName := tc.GetName()
 
 	newSvc := getNewPDHeadlessServiceForTidbCluster(tc)
-	oldSvc, err := m.deps.ServiceLister.Services(ns).Get(controller.PDPeerMemberName(tcName))
+	oldSvcTmp, err := m.deps.ServiceLister.Services(ns).Get(controller.PDPeerMemberName(tcName))
 	if errors.IsNotFound(err) {
 		err = controller.SetServiceLastAppliedConfigAnnotation(newSvc)
 		if err != nil {
@@ -156,22 +162,26 @@ func (m *pdMemberManager) syncPDHeadlessServiceForTidbCluster(tc *v1alpha1.TidbC
 		return fmt.Errorf("syncPDHeadlessServiceForTidbCluster: failed to get svc %s for cluster %s/%s, error: %s", controller.PDPeerMemberName(tcName), ns, tcName, err)
 	}
 
+	oldSvc := oldSvcTmp.DeepCopy()
+
 	equal, err := controller.ServiceEqual(newSvc, oldSvc)
 	if err != nil {
 		return err
 	}
-	if !equal {
+	annotated := syncTopologyAwareRoutingAnnotations(newSvc, oldSvc)
+	if !equal || annotated {
 		svc := *oldSvc
 		svc.Spec = newSvc.Spec
 		err = controller.SetServiceLastAppliedConfigAnnotation(&svc)
 		if err != nil {
 			return err
 		}
-		_, err = m.deps.ServiceControl.UpdateService(tc, &svc)
-		return err
+		if _, err = m.deps.ServiceControl.UpdateService(tc, &svc); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return syncZoneServices(m.deps, tc, newSvc)
 }
 
 func (m *pdMemberManager) syncPDStatefulSetForTidbCluster(tc *v1alpha1.TidbCluster) error {
@@ -190,11 +200,17 @@ func (m *pdMemberManager) syncPDStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		klog.Errorf("failed to sync TidbCluster: [%s/%s]'s status, error: %v", ns, tcName, err)
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.PDMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.PDMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for pd statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	if err := syncExternalProvisioningGates(m.deps, tc, v1alpha1.PDMemberType); err != nil {
+		return err
+	}
+
 	cm, err := m.syncPDConfigMap(tc, oldPDSet)
 	if err != nil {
 		return err
@@ -238,6 +254,12 @@ func (m *pdMemberManager) syncPDStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		return err
 	}
 
+	// the failure of moving the pods to the nodes of their bindings does not fail the sync, they are retried in
+	// the next sync
+	if err := syncPDNodeBindings(m.deps, tc); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to move the pd pods to the nodes of their bindings: %v", tc.Namespace, tc.Name, err)
+	}
+
 	if m.deps.CLIConfig.AutoFailover {
 		if m.shouldRecover(tc) {
 			m.failover.Recover(tc)
@@ -248,13 +270,25 @@ func (m *pdMemberManager) syncPDStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		}
 	}
 
+	if err := cleanupImagePrepullPods(m.deps, tc, v1alpha1.PDMemberType); err != nil {
+		return err
+	}
+
+	if _, err := keepTemplateIfDowngrade(m.deps, tc, v1alpha1.PDMemberType, oldPDSet, newPDSet); err != nil {
+		return err
+	}
+
+	if err := prePullImage(m.deps, tc, v1alpha1.PDMemberType, oldPDSet, newPDSet); err != nil {
+		return err
+	}
+
 	if !templateEqual(newPDSet, oldPDSet) || tc.Status.PD.Phase == v1alpha1.UpgradePhase {
 		if err := m.upgrader.Upgrade(tc, oldPDSet, newPDSet); err != nil {
 			return err
 		}
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdatePDSTS", newPDSet, oldPDSet)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newPDSet, oldPDSet)
 }
 
 // shouldRecover checks whether we should perform recovery operation.
@@ -309,6 +343,7 @@ func (m *pdMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set *a
 		return err
 	}
 
+	oldPhase := tc.Status.PD.Phase
 	// Scaling takes precedence over upgrading.
 	if tc.PDStsDesiredReplicas() != *set.Spec.Replicas {
 		tc.Status.PD.Phase = v1alpha1.ScalePhase
@@ -317,10 +352,15 @@ func (m *pdMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set *a
 	} else {
 		tc.Status.PD.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.PD.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.PDMemberType, oldPhase, tc.Status.PD.Phase, tc.Status.PD.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.PDMemberType, oldPhase, tc.Status.PD.Phase, tc.Status.PD.StatefulSet)
+	syncUpgradeStatus(m.deps, tc, v1alpha1.PDMemberType, set, nil)
 
 	pdClient := controller.GetPDClient(m.deps.PDControl, tc)
 
+	var staleSince *metav1.Time
 	healthInfo, err := pdClient.GetHealth()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.PD.Synced = false
 		// get endpoints info
@@ -336,12 +376,14 @@ func (m *pdMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set *a
 	}
 
 	cluster, err := pdClient.GetCluster()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.PD.Synced = false
 		return err
 	}
 	tc.Status.ClusterID = strconv.FormatUint(cluster.Id, 10)
 	leader, err := pdClient.GetPDLeader()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.PD.Synced = false
 		return err
@@ -394,7 +436,21 @@ func (m *pdMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set *a
 		}
 	}
 
+	// the leader priorities are not applied from the cached PD data, and the failure of applying them does not
+	// fail the sync of the pd status
+	if staleSince == nil {
+		if err := syncPDMemberPriorities(m.deps, pdClient, tc, pdStatus); err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] failed to set the leader priorities of pd members: %v", ns, tcName, err)
+		}
+		if member, ok := pdStatus[leader.GetName()]; ok {
+			tc.Status.PD.Leader = member
+		}
+	}
+
+	tc.Status.PD.StorageUsage = SyncVolumeStorageUsage(m.deps, tc, v1alpha1.PDMemberType.String(), tc.Status.PD.StorageUsage, set, tc.StorageWarningThreshold())
+
 	tc.Status.PD.Synced = true
+	tc.Status.PD.StaleSince = staleSince
 	tc.Status.PD.Members = pdStatus
 	tc.Status.PD.PeerMembers = peerPDStatus
 	tc.Status.PD.Image = ""
@@ -414,10 +470,17 @@ func (m *pdMemberManager) syncPDConfigMap(tc *v1alpha1.TidbCluster, set *apps.St
 	if tc.Spec.PD.Config == nil {
 		return nil, nil
 	}
-	newCm, err := getPDConfigMap(tc)
+	tierTC, tierEntries, err := withTierConfig(m.deps, tc, v1alpha1.PDMemberType)
 	if err != nil {
 		return nil, err
 	}
+	newCm, err := getPDConfigMap(tierTC)
+	if err != nil {
+		return nil, err
+	}
+	if err := setTierConfigAnnotation(newCm, tierEntries); err != nil {
+		return nil, err
+	}
 
 	var inUseName string
 	if set != nil {
@@ -430,6 +493,10 @@ func (m *pdMemberManager) syncPDConfigMap(tc *v1alpha1.TidbCluster, set *apps.St
 	if err != nil {
 		return nil, err
 	}
+	recordTierConfigChange(m.deps, tc, v1alpha1.PDMemberType, inUseName, newCm)
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.PDMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -480,6 +547,7 @@ func (m *pdMemberManager) getNewPDServiceForTidbCluster(tc *v1alpha1.TidbCluster
 			pdService.Spec.Ports[0].Name = *svcSpec.PortName
 		}
 	}
+	setTopologyAwareRouting(tc, pdService)
 	return pdService
 }
 
@@ -491,7 +559,7 @@ func getNewPDHeadlessServiceForTidbCluster(tc *v1alpha1.TidbCluster) *corev1.Ser
 	pdSelector := label.New().Instance(instanceName).PD()
 	pdLabels := pdSelector.Copy().UsedByPeer().Labels()
 
-	return &corev1.Service{
+	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            svcName,
 			Namespace:       ns,
@@ -518,6 +586,8 @@ func getNewPDHeadlessServiceForTidbCluster(tc *v1alpha1.TidbCluster) *corev1.Ser
 			PublishNotReadyAddresses: true,
 		},
 	}
+	setTopologyAwareRouting(tc, svc)
+	return svc
 }
 
 func (m *pdMemberManager) pdStatefulSetIsUpgrading(set *apps.StatefulSet, tc *v1alpha1.TidbCluster) (bool, error) {
@@ -552,6 +622,10 @@ func getNewPDSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (
 	ns := tc.Namespace
 	tcName := tc.Name
 	basePDSpec := tc.BasePDSpec()
+	resources, err := v1alpha1.SizedResources(basePDSpec.Sizing(), tc.Spec.PD.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for pd, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
 	instanceName := tc.GetInstanceName()
 	pdConfigMap := controller.MemberConfigMapName(tc, v1alpha1.PDMemberType)
 	if cm != nil {
@@ -667,12 +741,13 @@ func getNewPDSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (
 					SecurityContext: &corev1.SecurityContext{
 						Privileged: &privileged,
 					},
+					EnvFrom: basePDSpec.EnvFrom(),
 					// Init container resourceRequirements should be equal to app container.
 					// Scheduling is done based on effective requests/limits,
 					// which means init containers can reserve resources for
 					// initialization that are not used during the life of the Pod.
 					// ref:https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#resources
-					Resources: controller.ContainerResource(tc.Spec.PD.ResourceRequirements),
+					Resources: controller.ContainerResource(resources),
 				})
 			}
 		}
@@ -694,6 +769,8 @@ func getNewPDSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (
 	stsLabels := label.New().Instance(instanceName).PD()
 	podLabels := util.CombineStringMap(stsLabels, basePDSpec.Labels())
 	podAnnotations := util.CombineStringMap(controller.AnnProm(2379), basePDSpec.Annotations())
+	podAnnotations = util.CombineStringMap(podAnnotations, externalProvisioningAnnotations(basePDSpec))
+	podAnnotations = util.CombineStringMap(podAnnotations, restartGenerationAnnotations(tc, v1alpha1.PDMemberType))
 	stsAnnotations := getStsAnnotations(tc.Annotations, label.PDLabelVal)
 
 	deleteSlotsNumber, err := util.GetDeleteSlotsNumber(stsAnnotations)
@@ -719,7 +796,7 @@ func getNewPDSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.PD.ResourceRequirements),
+		Resources:    controller.ContainerResource(resources),
 	}
 	env := []corev1.EnvVar{
 		{
@@ -0,0 +1,114 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// nodeZoneLabels are the labels of the zone of a node, the former takes precedence
+var nodeZoneLabels = []string{"topology.kubernetes.io/zone", corev1.LabelZoneFailureDomain}
+
+// syncPDMemberPriorities sets the leader priorities of the PD members of the cluster by the zones of their nodes
+// in spec.pd.memberPriorities and records the zones and the priorities in the status of the members. The live
+// priorities are compared with the desired ones on every sync, so that they are re-applied to the members which
+// replace the old ones. The members whose pods are not scheduled or whose nodes have no zone are skipped.
+func syncPDMemberPriorities(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, members map[string]v1alpha1.PDMember) error {
+	priorities := tc.Spec.PD.MemberPriorities
+	if len(priorities) == 0 {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if deps.NodeLister == nil {
+		klog.V(4).Infof("tidbcluster: [%s/%s] node lister is unavailable, skip setting the leader priorities of pd members", ns, tcName)
+		return nil
+	}
+
+	membersInfo, err := pdCli.GetMembers()
+	if err != nil {
+		return err
+	}
+	live := map[string]int32{}
+	for _, member := range membersInfo.Members {
+		live[member.GetName()] = member.GetLeaderPriority()
+	}
+
+	var errs []error
+	for _, name := range sets.StringKeySet(members).List() {
+		current, ok := live[name]
+		if !ok {
+			continue
+		}
+		member := members[name]
+		member.LeaderPriority = current
+		zone, err := pdMemberZone(deps, ns, name)
+		if err != nil {
+			errs = append(errs, err)
+		} else if zone != "" {
+			member.Zone = zone
+			if desired := priorities[zone]; desired != current {
+				if err := pdCli.SetMemberLeaderPriority(name, desired); err != nil {
+					errs = append(errs, err)
+				} else {
+					member.LeaderPriority = desired
+					klog.Infof("tidbcluster: [%s/%s] set the leader priority of pd member %s in zone %s from %d to %d", ns, tcName, name, zone, current, desired)
+					deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.PDMemberLeaderPrioritySet, "the leader priority of pd member %s in zone %s is set from %d to %d", name, zone, current, desired)
+				}
+			}
+		}
+		members[name] = member
+	}
+	return errorutils.NewAggregate(errs)
+}
+
+// pdMemberZone returns the zone of the node of the PD member, it is empty if the pod is not scheduled or the node
+// has no zone
+func pdMemberZone(deps *controller.Dependencies, ns, name string) (string, error) {
+	// the name of the member is the name of the pod or its FQDN
+	podName := strings.Split(name, ".")[0]
+	pod, err := deps.PodLister.Pods(ns).Get(podName)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if pod.Spec.NodeName == "" {
+		return "", nil
+	}
+	node, err := deps.NodeLister.Get(pod.Spec.NodeName)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, key := range nodeZoneLabels {
+		if zone, ok := node.Labels[key]; ok {
+			return zone, nil
+		}
+	}
+	return "", nil
+}
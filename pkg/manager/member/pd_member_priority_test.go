@@ -0,0 +1,124 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSyncPDMemberPriorities(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	nodeIndexer := deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for node, labels := range map[string]map[string]string{
+		"node-a": {"topology.kubernetes.io/zone": "a"},
+		"node-b": {"topology.kubernetes.io/zone": "b", corev1.LabelZoneFailureDomain: "legacy-b"},
+		"node-c": {corev1.LabelZoneFailureDomain: "c"},
+		"node-x": {},
+	} {
+		g.Expect(nodeIndexer.Add(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: node, Labels: labels}})).To(Succeed())
+	}
+	setPodNode := func(pod, node string) {
+		g.Expect(podIndexer.Update(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: pod, Namespace: corev1.NamespaceDefault},
+			Spec:       corev1.PodSpec{NodeName: node},
+		})).To(Succeed())
+	}
+	setPodNode("test-pd-0", "node-a")
+	setPodNode("test-pd-1", "node-b")
+	setPodNode("test-pd-2", "node-c")
+	setPodNode("test-pd-3", "node-x")
+	setPodNode("test-pd-4", "")
+
+	tc := newTidbClusterForPD()
+	pdClient := pdapi.NewFakePDClient()
+	live := map[string]int32{"test-pd-0": 0, "test-pd-1": 0, "test-pd-2": 3, "test-pd-3": 0, "test-pd-4": 0}
+	pdClient.AddReaction(pdapi.GetMembersActionType, func(action *pdapi.Action) (interface{}, error) {
+		info := &pdapi.MembersInfo{}
+		for name, priority := range live {
+			info.Members = append(info.Members, &pdpb.Member{Name: name, LeaderPriority: priority})
+		}
+		return info, nil
+	})
+	var set []string
+	pdClient.AddReaction(pdapi.SetMemberLeaderPriorityActionType, func(action *pdapi.Action) (interface{}, error) {
+		live[action.Name] = action.Priority
+		set = append(set, action.Name)
+		return nil, nil
+	})
+	newMembers := func() map[string]v1alpha1.PDMember {
+		members := map[string]v1alpha1.PDMember{}
+		// test-pd-5 has not joined
+		for _, name := range []string{"test-pd-0", "test-pd-1", "test-pd-2", "test-pd-3", "test-pd-4", "test-pd-5"} {
+			members[name] = v1alpha1.PDMember{Name: name, Health: true}
+		}
+		return members
+	}
+
+	// the priorities are left as they are if they are not set
+	members := newMembers()
+	g.Expect(syncPDMemberPriorities(deps, pdClient, tc, members)).To(Succeed())
+	g.Expect(members).To(Equal(newMembers()))
+	g.Expect(set).To(BeEmpty())
+
+	// the members in the zones not in the priorities have the priority 0, the members without zones are skipped
+	tc.Spec.PD.MemberPriorities = map[string]int32{"a": 10, "b": 5}
+	g.Expect(syncPDMemberPriorities(deps, pdClient, tc, members)).To(Succeed())
+	g.Expect(set).To(Equal([]string{"test-pd-0", "test-pd-1", "test-pd-2"}))
+	g.Expect(live).To(Equal(map[string]int32{"test-pd-0": 10, "test-pd-1": 5, "test-pd-2": 0, "test-pd-3": 0, "test-pd-4": 0}))
+	g.Expect(members["test-pd-0"].Zone).To(Equal("a"))
+	g.Expect(members["test-pd-0"].LeaderPriority).To(Equal(int32(10)))
+	g.Expect(members["test-pd-1"].Zone).To(Equal("b"))
+	g.Expect(members["test-pd-2"].Zone).To(Equal("c"))
+	g.Expect(members["test-pd-2"].LeaderPriority).To(Equal(int32(0)))
+	g.Expect(members["test-pd-3"].Zone).To(BeEmpty())
+	g.Expect(members["test-pd-4"].Zone).To(BeEmpty())
+	g.Expect(members["test-pd-5"]).To(Equal(v1alpha1.PDMember{Name: "test-pd-5", Health: true}))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(3))
+	g.Expect(recorded[0]).To(ContainSubstring(events.PDMemberLeaderPrioritySet))
+	g.Expect(recorded[0]).To(ContainSubstring("the leader priority of pd member test-pd-0 in zone a is set from 0 to 10"))
+
+	// nothing is set if the live priorities are the desired ones
+	set = nil
+	members = newMembers()
+	g.Expect(syncPDMemberPriorities(deps, pdClient, tc, members)).To(Succeed())
+	g.Expect(set).To(BeEmpty())
+	g.Expect(members["test-pd-1"].LeaderPriority).To(Equal(int32(5)))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the priority is re-applied to the member replacing the old one, which is on a node in another zone
+	live["test-pd-1"] = 0
+	setPodNode("test-pd-1", "node-a")
+	members = newMembers()
+	g.Expect(syncPDMemberPriorities(deps, pdClient, tc, members)).To(Succeed())
+	g.Expect(set).To(Equal([]string{"test-pd-1"}))
+	g.Expect(live["test-pd-1"]).To(Equal(int32(10)))
+	g.Expect(members["test-pd-1"].Zone).To(Equal("a"))
+	g.Expect(members["test-pd-1"].LeaderPriority).To(Equal(int32(10)))
+	g.Expect(collectEvents(recorder.Events)).To(HaveLen(1))
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -101,7 +102,7 @@ func (s *pdScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 	pdClient := controller.GetPDClient(s.deps.PDControl, tc)
 	leader, err := pdClient.GetPDLeader()
 	if err != nil {
-		return err
+		return requeueIfPDCircuitOpen(err)
 	}
 	// If the PD pod was PD leader during scale-in, we would transfer PD leader first
 	// If the PD StatefulSet would be scale-in to zero and no other members in the PD cluster,
@@ -120,14 +121,14 @@ func (s *pdScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 				err = pdClient.TransferPDLeader(PdPodName(tcName, targetOrdinal))
 			}
 			if err != nil {
-				return err
+				return requeueIfPDCircuitOpen(err)
 			}
 		} else {
 			for _, member := range tc.Status.PD.PeerMembers {
 				if member.Health && member.Name != memberName {
 					err = pdClient.TransferPDLeader(member.Name)
 					if err != nil {
-						return err
+						return requeueIfPDCircuitOpen(err)
 					}
 					return controller.RequeueErrorf("tc[%s/%s]'s pd pod[%s/%s] is transferring pd leader,can't scale-in now", ns, tcName, ns, memberName)
 				}
@@ -138,7 +139,7 @@ func (s *pdScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 	err = pdClient.DeleteMember(memberName)
 	if err != nil {
 		klog.Errorf("pdScaler.ScaleIn: failed to delete member %s, %v", memberName, err)
-		return err
+		return requeueIfPDCircuitOpen(err)
 	}
 	klog.Infof("pdScaler.ScaleIn: delete member %s successfully", memberName)
 
@@ -158,7 +159,7 @@ func (s *pdScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 		}
 	}
 
-	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 	return nil
 }
 
@@ -178,7 +179,7 @@ func (s *pdScaler) preCheckUpMembers(tc *v1alpha1.TidbCluster, podName string) b
 	if upComponents != 0 && tc.Spec.PD.Replicas == 0 {
 		errMsg := fmt.Sprintf("The PD is in use by TidbCluster [%s/%s], can't scale in PD, podname %s", tc.GetNamespace(), tc.GetName(), podName)
 		klog.Error(errMsg)
-		s.deps.Recorder.Event(tc, v1.EventTypeWarning, "FailedScaleIn", errMsg)
+		s.deps.Recorder.Event(tc, v1.EventTypeWarning, events.FailedScaleIn, errMsg)
 		return false
 	}
 
@@ -0,0 +1,277 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	pdRegionScheduleLimit  = "schedule.region-schedule-limit"
+	pdReplicaScheduleLimit = "schedule.replica-schedule-limit"
+	pdLeaderScheduleLimit  = "schedule.leader-schedule-limit"
+	pdPatrolRegionInterval = "schedule.patrol-region-interval"
+	// pdStoreLimit is the add-peer and remove-peer store limit of all stores, it is not a config item of PD
+	pdStoreLimit = "store-limit"
+
+	// defaultRegionSizeMiB is the average region size assumed if the stores have no regions, it is the default
+	// region-split-size of TiKV
+	defaultRegionSizeMiB = 96
+	// pdSchedulingTuneThreshold is the relative difference between the live value and the recommended value of a
+	// param over which the recommended value is applied
+	pdSchedulingTuneThreshold = 0.2
+)
+
+// pdSchedulingParam is a scheduling param of PD tuned by spec.pd.autoTuneScheduling
+type pdSchedulingParam struct {
+	name string
+	// unit is the unit of the value, the patrol region interval is in milliseconds
+	unit string
+	// recommend returns the recommended value by the number of the stores and the average region size in MiB
+	recommend func(stores int, avgRegionSizeMiB float64) float64
+}
+
+var pdSchedulingParams = []pdSchedulingParam{
+	{name: pdRegionScheduleLimit, recommend: recommendedRegionScheduleLimit},
+	{name: pdReplicaScheduleLimit, recommend: recommendedReplicaScheduleLimit},
+	{name: pdLeaderScheduleLimit, recommend: recommendedLeaderScheduleLimit},
+	{name: pdPatrolRegionInterval, unit: "ms", recommend: recommendedPatrolRegionInterval},
+	{name: pdStoreLimit, recommend: recommendedStoreLimit},
+}
+
+// regionSizeFactor is how many times the default region size is the average region size, the schedules of the
+// larger regions move more data, so that fewer of them run at the same time. It is in [0.25, 4].
+func regionSizeFactor(avgRegionSizeMiB float64) float64 {
+	if avgRegionSizeMiB <= 0 {
+		return 1
+	}
+	return clampFloat(defaultRegionSizeMiB/avgRegionSizeMiB, 0.25, 4)
+}
+
+// recommendedRegionScheduleLimit is 32 region schedules per store scaled by the region size factor,
+// in [2048, 16384], so that it is the default 2048 of PD for the clusters up to 64 stores
+func recommendedRegionScheduleLimit(stores int, avgRegionSizeMiB float64) float64 {
+	return clampFloat(math.Round(32*float64(stores)*regionSizeFactor(avgRegionSizeMiB)), 2048, 16384)
+}
+
+// recommendedReplicaScheduleLimit is 2 replica schedules per store, in [64, 512], so that it is the default 64
+// of PD for the clusters up to 32 stores
+func recommendedReplicaScheduleLimit(stores int, _ float64) float64 {
+	return clampFloat(float64(2*stores), 64, 512)
+}
+
+// recommendedLeaderScheduleLimit is a leader schedule per 4 stores, in [4, 64], so that it is the default 4 of PD
+// for the clusters up to 16 stores
+func recommendedLeaderScheduleLimit(stores int, _ float64) float64 {
+	return clampFloat(float64(stores/4), 4, 64)
+}
+
+// recommendedPatrolRegionInterval is 300ms divided by the number of the stores, in [2ms, 10ms], so that it is
+// the default 10ms of PD for the clusters up to 30 stores and the regions of the larger clusters are patrolled
+// in about the same time
+func recommendedPatrolRegionInterval(stores int, _ float64) float64 {
+	if stores <= 0 {
+		return 10
+	}
+	return clampFloat(math.Round(300/float64(stores)), 2, 10)
+}
+
+// recommendedStoreLimit is 15 operators per minute scaled by the square root of the number of the stores over 3
+// and the region size factor, in [15, 200], so that it is the default 15 of PD for the clusters of 3 stores with
+// the default region size
+func recommendedStoreLimit(stores int, avgRegionSizeMiB float64) float64 {
+	return clampFloat(math.Round(15*math.Sqrt(float64(stores)/3)*regionSizeFactor(avgRegionSizeMiB)), 15, 200)
+}
+
+func clampFloat(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// syncPDSchedulingAutoTune applies the recommended scheduling params of PD by the number of the Up stores and
+// their average region size if spec.pd.autoTuneScheduling is enabled. A param is applied only if its live value
+// differs from the recommended value by more than pdSchedulingTuneThreshold, and it is skipped if it is set in
+// spec.pd.config or its live value is not the one last applied by the operator in label.AnnPDAutoTunedScheduling,
+// which means it is changed by the user.
+func syncPDSchedulingAutoTune(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, storesInfo *pdapi.StoresInfo) error {
+	if tc.Spec.PD == nil || !tc.Spec.PD.AutoTuneScheduling || tc.IsComponentPaused(v1alpha1.PDMemberType) {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	stores, avgRegionSizeMiB := pdSchedulingClusterSize(storesInfo)
+	if stores == 0 {
+		return nil
+	}
+	live, err := livePDSchedulingParams(pdCli)
+	if err != nil {
+		return err
+	}
+	applied := map[string]float64{}
+	if data, ok := tc.Annotations[label.AnnPDAutoTunedScheduling]; ok {
+		if err := json.Unmarshal([]byte(data), &applied); err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] ignore the invalid annotation %s: %v", ns, tcName, label.AnnPDAutoTunedScheduling, err)
+			applied = map[string]float64{}
+		}
+	}
+
+	changes := map[string]float64{}
+	var diff []string
+	for _, param := range pdSchedulingParams {
+		cur, ok := live[param.name]
+		if !ok {
+			continue
+		}
+		if tc.Spec.PD.Config != nil && tc.Spec.PD.Config.Get(param.name) != nil {
+			continue
+		}
+		if last, ok := applied[param.name]; ok && last != cur {
+			klog.V(4).Infof("tidbcluster: [%s/%s] %s of pd is changed by the user from %v to %v, skip tuning it", ns, tcName, param.name, last, cur)
+			continue
+		}
+		recommended := param.recommend(stores, avgRegionSizeMiB)
+		if math.Abs(cur-recommended) <= recommended*pdSchedulingTuneThreshold {
+			continue
+		}
+		changes[param.name] = recommended
+		diff = append(diff, fmt.Sprintf("%s: %s%s -> %s%s", param.name, formatFloat(cur), param.unit, formatFloat(recommended), param.unit))
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if err := applyPDSchedulingParams(pdCli, changes); err != nil {
+		return err
+	}
+	for name, value := range changes {
+		applied[name] = value
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{label.AnnPDAutoTunedScheduling: string(data)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := deps.TiDBClusterControl.Patch(tc, patch); err != nil {
+		return err
+	}
+	if tc.Annotations == nil {
+		tc.Annotations = map[string]string{}
+	}
+	tc.Annotations[label.AnnPDAutoTunedScheduling] = string(data)
+
+	sort.Strings(diff)
+	msg := fmt.Sprintf("tune the scheduling of pd for %d stores with the average region size %sMiB: %s",
+		stores, formatFloat(math.Round(avgRegionSizeMiB)), strings.Join(diff, ", "))
+	klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+	deps.Recorder.Event(tc, corev1.EventTypeNormal, events.PDSchedulingAutoTuned, msg)
+	return nil
+}
+
+// pdSchedulingClusterSize returns the number of the Up stores and their average region size in MiB, the average
+// region size is defaultRegionSizeMiB if the stores have no regions
+func pdSchedulingClusterSize(storesInfo *pdapi.StoresInfo) (int, float64) {
+	stores := 0
+	var regionCount, regionSize int64
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil || store.Status == nil || store.Store.StateName != v1alpha1.TiKVStateUp {
+			continue
+		}
+		stores++
+		regionCount += int64(store.Status.RegionCount)
+		regionSize += store.Status.RegionSize
+	}
+	if regionCount == 0 || regionSize == 0 {
+		return stores, defaultRegionSizeMiB
+	}
+	return stores, float64(regionSize) / float64(regionCount)
+}
+
+// livePDSchedulingParams returns the live values of the scheduling params of PD, the store limit is the minimum
+// add-peer store limit of the stores. The params PD does not return are absent.
+func livePDSchedulingParams(pdCli pdapi.PDClient) (map[string]float64, error) {
+	items, err := pdCli.GetConfigItems("schedule")
+	if err != nil {
+		return nil, err
+	}
+	live := map[string]float64{}
+	for _, name := range []string{pdRegionScheduleLimit, pdReplicaScheduleLimit, pdLeaderScheduleLimit} {
+		if v, ok := items[name].(float64); ok {
+			live[name] = v
+		}
+	}
+	if v, ok := items[pdPatrolRegionInterval].(string); ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", pdPatrolRegionInterval, v, err)
+		}
+		live[pdPatrolRegionInterval] = float64(interval.Milliseconds())
+	}
+
+	limits, err := pdCli.GetStoresLimit()
+	if err != nil {
+		return nil, err
+	}
+	for _, limit := range limits {
+		if cur, ok := live[pdStoreLimit]; !ok || limit.AddPeer < cur {
+			live[pdStoreLimit] = limit.AddPeer
+		}
+	}
+	return live, nil
+}
+
+// applyPDSchedulingParams sets the scheduling params of PD to the values
+func applyPDSchedulingParams(pdCli pdapi.PDClient, values map[string]float64) error {
+	items := map[string]interface{}{}
+	for name, value := range values {
+		switch name {
+		case pdStoreLimit:
+			if err := pdCli.SetAllStoresLimit(value); err != nil {
+				return err
+			}
+		case pdPatrolRegionInterval:
+			items[name] = fmt.Sprintf("%dms", int64(value))
+		default:
+			items[name] = uint64(value)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return pdCli.SetConfigItems(items)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
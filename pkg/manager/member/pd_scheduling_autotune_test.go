@@ -0,0 +1,246 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestPDSchedulingFormulas(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name             string
+		stores           int
+		avgRegionSizeMiB float64
+		regionLimit      float64
+		replicaLimit     float64
+		leaderLimit      float64
+		patrolInterval   float64
+		storeLimit       float64
+	}{
+		{
+			name:             "small cluster keeps the defaults of pd",
+			stores:           3,
+			avgRegionSizeMiB: 96,
+			regionLimit:      2048,
+			replicaLimit:     64,
+			leaderLimit:      4,
+			patrolInterval:   10,
+			storeLimit:       15,
+		},
+		{
+			name:             "no regions",
+			stores:           3,
+			avgRegionSizeMiB: 0,
+			regionLimit:      2048,
+			replicaLimit:     64,
+			leaderLimit:      4,
+			patrolInterval:   10,
+			storeLimit:       15,
+		},
+		{
+			name:             "100 stores",
+			stores:           100,
+			avgRegionSizeMiB: 96,
+			regionLimit:      3200,
+			replicaLimit:     200,
+			leaderLimit:      25,
+			patrolInterval:   3,
+			storeLimit:       87,
+		},
+		{
+			name:             "100 stores with small regions",
+			stores:           100,
+			avgRegionSizeMiB: 48,
+			regionLimit:      6400,
+			replicaLimit:     200,
+			leaderLimit:      25,
+			patrolInterval:   3,
+			storeLimit:       173,
+		},
+		{
+			name:             "100 stores with large regions",
+			stores:           100,
+			avgRegionSizeMiB: 192,
+			regionLimit:      2048,
+			replicaLimit:     200,
+			leaderLimit:      25,
+			patrolInterval:   3,
+			storeLimit:       43,
+		},
+		{
+			name:             "1000 stores with tiny regions are capped",
+			stores:           1000,
+			avgRegionSizeMiB: 1,
+			regionLimit:      16384,
+			replicaLimit:     512,
+			leaderLimit:      64,
+			patrolInterval:   2,
+			storeLimit:       200,
+		},
+	}
+	for _, tt := range tests {
+		g.Expect(recommendedRegionScheduleLimit(tt.stores, tt.avgRegionSizeMiB)).To(Equal(tt.regionLimit), tt.name)
+		g.Expect(recommendedReplicaScheduleLimit(tt.stores, tt.avgRegionSizeMiB)).To(Equal(tt.replicaLimit), tt.name)
+		g.Expect(recommendedLeaderScheduleLimit(tt.stores, tt.avgRegionSizeMiB)).To(Equal(tt.leaderLimit), tt.name)
+		g.Expect(recommendedPatrolRegionInterval(tt.stores, tt.avgRegionSizeMiB)).To(Equal(tt.patrolInterval), tt.name)
+		g.Expect(recommendedStoreLimit(tt.stores, tt.avgRegionSizeMiB)).To(Equal(tt.storeLimit), tt.name)
+	}
+}
+
+func TestPDSchedulingClusterSize(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newStore := func(state string, regionCount int, regionSize int64) *pdapi.StoreInfo {
+		return &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{}, StateName: state},
+			Status: &pdapi.StoreStatus{RegionCount: regionCount, RegionSize: regionSize},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		stores           []*pdapi.StoreInfo
+		expectStores     int
+		expectRegionSize float64
+	}{
+		{
+			name:             "no stores",
+			expectRegionSize: defaultRegionSizeMiB,
+		},
+		{
+			name:             "no regions",
+			stores:           []*pdapi.StoreInfo{newStore(v1alpha1.TiKVStateUp, 0, 0)},
+			expectStores:     1,
+			expectRegionSize: defaultRegionSizeMiB,
+		},
+		{
+			name: "only up stores are counted",
+			stores: []*pdapi.StoreInfo{
+				newStore(v1alpha1.TiKVStateUp, 100, 4800),
+				newStore(v1alpha1.TiKVStateUp, 300, 19200),
+				newStore(v1alpha1.TiKVStateDown, 100, 100),
+				{Store: &pdapi.MetaStore{Store: &metapb.Store{}, StateName: v1alpha1.TiKVStateUp}},
+			},
+			expectStores:     2,
+			expectRegionSize: 60,
+		},
+	}
+	for _, tt := range tests {
+		stores, regionSize := pdSchedulingClusterSize(&pdapi.StoresInfo{Stores: tt.stores})
+		g.Expect(stores).To(Equal(tt.expectStores), tt.name)
+		g.Expect(regionSize).To(Equal(tt.expectRegionSize), tt.name)
+	}
+}
+
+func TestSyncPDSchedulingAutoTune(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForPD()
+	pdClient := pdapi.NewFakePDClient()
+	items := map[string]interface{}{
+		pdRegionScheduleLimit:  float64(2048),
+		pdReplicaScheduleLimit: float64(64),
+		pdLeaderScheduleLimit:  float64(4),
+		pdPatrolRegionInterval: "10ms",
+	}
+	limits := map[uint64]pdapi.StoreLimit{1: {AddPeer: 15, RemovePeer: 15}, 2: {AddPeer: 20, RemovePeer: 20}}
+	pdClient.AddReaction(pdapi.GetConfigItemsActionType, func(action *pdapi.Action) (interface{}, error) {
+		return items, nil
+	})
+	pdClient.AddReaction(pdapi.SetConfigItemsActionType, func(action *pdapi.Action) (interface{}, error) {
+		// pd returns the numbers in JSON as float64
+		for k, v := range action.ConfigItems {
+			if n, ok := v.(uint64); ok {
+				items[k] = float64(n)
+			} else {
+				items[k] = v
+			}
+		}
+		return nil, nil
+	})
+	pdClient.AddReaction(pdapi.GetStoresLimitActionType, func(action *pdapi.Action) (interface{}, error) {
+		return limits, nil
+	})
+	pdClient.AddReaction(pdapi.SetAllStoresLimitActionType, func(action *pdapi.Action) (interface{}, error) {
+		for id := range limits {
+			limits[id] = pdapi.StoreLimit{AddPeer: action.Rate, RemovePeer: action.Rate}
+		}
+		return nil, nil
+	})
+	storesInfo := &pdapi.StoresInfo{}
+	for i := 0; i < 100; i++ {
+		storesInfo.Stores = append(storesInfo.Stores, &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{}, StateName: v1alpha1.TiKVStateUp},
+			Status: &pdapi.StoreStatus{RegionCount: 1000, RegionSize: 96000},
+		})
+	}
+	recorder := deps.Recorder.(*record.FakeRecorder)
+
+	// disabled
+	g.Expect(syncPDSchedulingAutoTune(deps, pdClient, tc, storesInfo)).To(Succeed())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnPDAutoTunedScheduling))
+
+	// the params differing from the recommended values by more than the threshold are applied, the region
+	// schedule limit set in the config is not tuned
+	tc.Spec.PD.AutoTuneScheduling = true
+	tc.Spec.PD.Config = v1alpha1.NewPDConfig()
+	tc.Spec.PD.Config.Set(pdRegionScheduleLimit, 4096)
+	g.Expect(syncPDSchedulingAutoTune(deps, pdClient, tc, storesInfo)).To(Succeed())
+	g.Expect(items).To(Equal(map[string]interface{}{
+		pdRegionScheduleLimit:  float64(2048),
+		pdReplicaScheduleLimit: float64(200),
+		pdLeaderScheduleLimit:  float64(25),
+		pdPatrolRegionInterval: "3ms",
+	}))
+	g.Expect(limits[1].AddPeer).To(Equal(float64(87)))
+	g.Expect(tc.Annotations[label.AnnPDAutoTunedScheduling]).To(MatchJSON(`{
+		"schedule.replica-schedule-limit": 200,
+		"schedule.leader-schedule-limit": 25,
+		"schedule.patrol-region-interval": 3,
+		"store-limit": 87
+	}`))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.PDSchedulingAutoTuned))
+	g.Expect(recorded[0]).To(ContainSubstring("for 100 stores with the average region size 96MiB"))
+	g.Expect(recorded[0]).To(ContainSubstring("schedule.patrol-region-interval: 10ms -> 3ms"))
+	g.Expect(recorded[0]).To(ContainSubstring("store-limit: 15 -> 87"))
+
+	// nothing is applied if the live values are the recommended ones
+	g.Expect(syncPDSchedulingAutoTune(deps, pdClient, tc, storesInfo)).To(Succeed())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the params changed by the user are not tuned any more
+	items[pdLeaderScheduleLimit] = float64(8)
+	storesInfo.Stores = storesInfo.Stores[:50]
+	g.Expect(syncPDSchedulingAutoTune(deps, pdClient, tc, storesInfo)).To(Succeed())
+	g.Expect(items[pdLeaderScheduleLimit]).To(Equal(float64(8)))
+	g.Expect(items[pdReplicaScheduleLimit]).To(Equal(float64(100)))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).NotTo(ContainSubstring(pdLeaderScheduleLimit))
+	g.Expect(recorded[0]).To(ContainSubstring("schedule.replica-schedule-limit: 200 -> 100"))
+}
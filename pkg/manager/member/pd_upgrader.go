@@ -22,18 +22,21 @@ import (
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 )
 
 type pdUpgrader struct {
-	deps *controller.Dependencies
+	deps     *controller.Dependencies
+	recorder record.EventRecorder
 }
 
 // NewPDUpgrader returns a pdUpgrader
 func NewPDUpgrader(deps *controller.Dependencies) Upgrader {
 	return &pdUpgrader{
-		deps: deps,
+		deps:     deps,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -47,9 +50,13 @@ func (u *pdUpgrader) gracefulUpgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Stat
 	if !tc.Status.PD.Synced {
 		return fmt.Errorf("tidbcluster: [%s/%s]'s pd status sync failed, can not to be upgraded", ns, tcName)
 	}
-	if tc.PDScaling() {
-		klog.Infof("TidbCluster: [%s/%s]'s pd status is %v, can not upgrade pd",
-			ns, tcName, tc.Status.PD.Phase)
+	if frozen, err := keepTemplateIfUpgradeFrozen(u.deps, tc, v1alpha1.PDMemberType, oldSet, newSet); frozen {
+		return err
+	}
+	if blocking := upgradeBlockedBy(tc, v1alpha1.PDMemberType); blocking != "" || tc.PDScaling() {
+		klog.Infof("TidbCluster: [%s/%s]'s components upgraded before pd [%s] are upgrading, pd status is %v, can not upgrade pd",
+			ns, tcName, blocking, tc.Status.PD.Phase)
+		recordUpgradeBlocked(u.recorder, tc, v1alpha1.PDMemberType, upgradeBlockedReason(v1alpha1.PDMemberType, blocking, tc.Status.PD.Phase))
 		_, podSpec, err := GetLastAppliedConfig(oldSet)
 		if err != nil {
 			return err
@@ -58,6 +65,7 @@ func (u *pdUpgrader) gracefulUpgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Stat
 		return nil
 	}
 
+	recordLastReconcileBy(u.deps, tc, tc.Status.PD.Phase != v1alpha1.UpgradePhase)
 	tc.Status.PD.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -94,14 +102,20 @@ func (u *pdUpgrader) gracefulUpgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Stat
 
 		if revision == tc.Status.PD.StatefulSet.UpdateRevision {
 			if !podutil.IsPodReady(pod) {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.PDMemberType, podName, "is not ready")
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded pd pod: [%s] is not ready", ns, tcName, podName)
 			}
 			if member, exist := tc.Status.PD.Members[PdName(tc.Name, i, tc.Namespace, tc.Spec.ClusterDomain)]; !exist || !member.Health {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.PDMemberType, podName, "is not a healthy pd member")
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s pd upgraded pod: [%s] is not ready", ns, tcName, podName)
 			}
 			continue
 		}
 
+		if upgradePaused(u.recorder, tc, v1alpha1.PDMemberType, i) {
+			return nil
+		}
+		syncUpgradeStatus(u.deps, tc, v1alpha1.PDMemberType, oldSet, &i)
 		return u.upgradePDPod(tc, i, newSet)
 	}
 
@@ -139,12 +153,17 @@ func (u *pdUpgrader) upgradePDPod(tc *v1alpha1.TidbCluster, ordinal int32, newSe
 		}
 	}
 
+	if ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition {
+		recordUpgradePodSelected(u.recorder, tc, v1alpha1.PDMemberType, upgradePodName, tc.Status.PD.StatefulSet)
+	}
+	recordLastReconcileBy(u.deps, tc, ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition)
 	mngerutils.SetUpgradePartition(newSet, ordinal)
+	prepullNextImage(u.deps, tc, v1alpha1.PDMemberType, newSet, ordinal)
 	return nil
 }
 
 func (u *pdUpgrader) transferPDLeaderTo(tc *v1alpha1.TidbCluster, targetName string) error {
-	return controller.GetPDClient(u.deps.PDControl, tc).TransferPDLeader(targetName)
+	return requeueIfPDCircuitOpen(controller.GetPDClient(u.deps.PDControl, tc).TransferPDLeader(targetName))
 }
 
 // choosePDToTransferFromMembers choose a pd to transfer leader from members
@@ -152,6 +171,9 @@ func (u *pdUpgrader) transferPDLeaderTo(tc *v1alpha1.TidbCluster, targetName str
 // Assume that current leader ordinal is x, and range is [0, n]
 //	1. Find the max suitable ordinal in (x, n], because they have been upgraded
 //	2. If no suitable ordinal, find the min suitable ordinal in [0, x) to reduce the count of transfer
+//
+// The suitable member with the highest leader priority is preferred, so that the leader stays in the zones
+// preferred by spec.pd.memberPriorities, the order above breaks the ties.
 func choosePDToTransferFromMembers(tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, ordinal int32) string {
 	tcName := tc.GetName()
 	ordinals := helper.GetPodOrdinals(*newSet.Spec.Replicas, newSet)
@@ -172,26 +194,25 @@ func choosePDToTransferFromMembers(tc *v1alpha1.TidbCluster, newSet *apps.Statef
 		ordinal = helper.GetMaxPodOrdinal(*newSet.Spec.Replicas, newSet)
 	}
 
-	targetName := ""
+	var candidates []string
 	list := ordinals.List()
 
-	// find the max ordinal which is larger than ordinal
+	// the ordinals which are larger than ordinal from the max one
 	for i := len(list) - 1; i >= 0 && list[i] > ordinal; i-- {
-		curName := genPDName(list[i])
-		if pred(curName) {
-			targetName = curName
-			break
-		}
+		candidates = append(candidates, genPDName(list[i]))
+	}
+	// the ordinals which are less than ordinal from the min one
+	for i := 0; i < len(list) && list[i] < ordinal; i++ {
+		candidates = append(candidates, genPDName(list[i]))
 	}
 
-	if targetName == "" {
-		// find the min ordinal which is less than ordinal
-		for i := 0; i < len(list) && list[i] < ordinal; i++ {
-			curName := genPDName(list[i])
-			if pred(curName) {
-				targetName = curName
-				break
-			}
+	targetName := ""
+	for _, curName := range candidates {
+		if !pred(curName) {
+			continue
+		}
+		if targetName == "" || tc.Status.PD.Members[curName].LeaderPriority > tc.Status.PD.Members[targetName].LeaderPriority {
+			targetName = curName
 		}
 	}
 
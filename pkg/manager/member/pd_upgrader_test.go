@@ -16,10 +16,12 @@ package member
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
 
@@ -29,6 +31,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	podinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 )
 
@@ -102,6 +105,27 @@ func TestPDUpgraderUpgrade(t *testing.T) {
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
 				g.Expect(tc.Status.PD.Phase).To(Equal(v1alpha1.UpgradePhase))
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+				g.Expect(tc.Status.LastReconcileTime).NotTo(BeNil())
+				g.Expect(tc.Status.PD.Upgrade).NotTo(BeNil())
+				g.Expect(tc.Status.PD.Upgrade.UpgradingOrdinal).To(Equal(pointer.Int32Ptr(1)))
+				g.Expect(tc.Status.PD.Upgrade.Replicas).To(Equal(int32(3)))
+			},
+		},
+		{
+			name: "normal upgrade paused by annotation",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Synced = true
+				tc.Annotations = map[string]string{label.AnnUpgradePaused: label.AnnUpgradePausedVal}
+			},
+			changePods:        nil,
+			changeOldSet:      nil,
+			transferLeaderErr: false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.PD.Phase).To(Equal(v1alpha1.UpgradePhase))
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
 			},
 		},
 		{
@@ -249,6 +273,24 @@ func TestPDUpgraderUpgrade(t *testing.T) {
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
 			},
 		},
+		{
+			name: "last reconcile time is kept while waiting for leader transfer",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Synced = true
+				tc.Status.PD.Phase = v1alpha1.UpgradePhase
+				tc.Status.PD.Leader = v1alpha1.PDMember{Name: PdPodName(upgradeTcName, 1), Health: true}
+				tc.Status.LastReconcileTime = &metav1.Time{Time: time.Unix(1000, 0)}
+			},
+			changePods:        nil,
+			transferLeaderErr: false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+				g.Expect(tc.Status.LastReconcileTime).To(Equal(&metav1.Time{Time: time.Unix(1000, 0)}))
+			},
+		},
 		{
 			name: "pd sync failed",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
@@ -389,6 +431,26 @@ func TestChoosePDToTransferFromMembers(t *testing.T) {
 			ordinal:          0,
 			expectTargetName: "",
 		},
+		{
+			name: "the member with the higher leader priority is preferred",
+			changeFn: func(tc *v1alpha1.TidbCluster, ss *apps.StatefulSet) {
+				tc.Status.PD.Members[PdName(tc.Name, 0, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: true, LeaderPriority: 5}
+				tc.Status.PD.Members[PdName(tc.Name, 1, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: true, LeaderPriority: 5}
+				tc.Status.PD.Members[PdName(tc.Name, 2, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: true}
+			},
+			ordinal:          1,
+			expectTargetName: "upgrader-pd-0",
+		},
+		{
+			name: "the unhealthy member with the higher leader priority is not chosen",
+			changeFn: func(tc *v1alpha1.TidbCluster, ss *apps.StatefulSet) {
+				tc.Status.PD.Members[PdName(tc.Name, 0, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: false, LeaderPriority: 5}
+				tc.Status.PD.Members[PdName(tc.Name, 1, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: true, LeaderPriority: 5}
+				tc.Status.PD.Members[PdName(tc.Name, 2, tc.Namespace, tc.Spec.ClusterDomain)] = v1alpha1.PDMember{Health: true}
+			},
+			ordinal:          1,
+			expectTargetName: "upgrader-pd-2",
+		},
 	}
 
 	for _, testcase := range cases {
@@ -411,9 +473,89 @@ func TestChoosePDToTransferFromMembers(t *testing.T) {
 	}
 }
 
+func TestPDUpgraderTransferLeaderBeforeUpgrade(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	b := NewTCBuilder().
+		WithPDPhase(v1alpha1.UpgradePhase).
+		WithPDLeader(1).
+		WithPodRevisions(v1alpha1.PDMemberType, upgradeCurrentRevision, upgradeCurrentRevision, upgradeUpdateRevision)
+	tc := b.Build()
+	deps := controller.NewFakeDependencies()
+	upgrader := NewPDUpgrader(deps).(*pdUpgrader)
+	pdClient := b.Wire(deps)
+	var target string
+	pdClient.AddReaction(pdapi.TransferPDLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+		target = action.Name
+		return nil, nil
+	})
+
+	oldSet := b.StatefulSet(v1alpha1.PDMemberType)
+	oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+	newSet := oldSet.DeepCopy()
+
+	// the leader is transferred away from the next pod to upgrade
+	err := upgrader.Upgrade(tc, oldSet, newSet)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(target).To(Equal(PdPodName(upgradeTcName, 2)))
+	g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+
+	// the pod is upgraded once the leader is elsewhere
+	tc.Status.PD.Leader = tc.Status.PD.Members[PdPodName(upgradeTcName, 2)]
+	err = upgrader.Upgrade(tc, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+}
+
+func TestPDUpgraderEvents(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	b := NewTCBuilder().
+		WithPDPhase(v1alpha1.ScalePhase).
+		WithPDLeader(2).
+		WithPodRevisions(v1alpha1.PDMemberType, upgradeCurrentRevision, upgradeUpdateRevision, upgradeUpdateRevision)
+	tc := b.Build()
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	upgrader := NewPDUpgrader(deps)
+	b.Wire(deps)
+	oldSet := b.StatefulSet(v1alpha1.PDMemberType)
+	upgrade := func() error {
+		return upgrader.Upgrade(tc, oldSet, oldSet.DeepCopy())
+	}
+
+	// the upgrade waiting for the scaling is recorded once in the requeue loop
+	g.Expect(upgrade()).To(Succeed())
+	g.Expect(upgrade()).To(Succeed())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.UpgradeBlocked))
+	g.Expect(recorded[0]).To(ContainSubstring("pd status is Scale"))
+
+	// the upgraded pod failing the health check is recorded as a warning
+	tc.Status.PD.Phase = v1alpha1.UpgradePhase
+	name := PdPodName(upgradeTcName, 1)
+	tc.Status.PD.Members[name] = v1alpha1.PDMember{Name: name, Health: false}
+	g.Expect(controller.IsRequeueError(upgrade())).To(BeTrue())
+	g.Expect(controller.IsRequeueError(upgrade())).To(BeTrue())
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(HavePrefix(corev1.EventTypeWarning + " " + events.UpgradePodUnhealthy))
+	g.Expect(recorded[0]).To(ContainSubstring("upgraded pd pod " + name + " is not a healthy pd member"))
+
+	// the pod the partition moves to is recorded
+	tc.Status.PD.Members[name] = v1alpha1.PDMember{Name: name, Health: true}
+	g.Expect(upgrade()).To(Succeed())
+	g.Expect(upgrade()).To(Succeed())
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.UpgradePodSelected))
+	g.Expect(recorded[0]).To(ContainSubstring("pd pod upgrader-pd-0 is selected to be upgraded to revision 2"))
+}
+
 func newPDUpgrader() (Upgrader, *pdapi.FakePDControl, *controller.FakePodControl, podinformers.PodInformer) {
 	fakeDeps := controller.NewFakeDependencies()
-	pdUpgrader := &pdUpgrader{deps: fakeDeps}
+	pdUpgrader := NewPDUpgrader(fakeDeps).(*pdUpgrader)
 	pdControl := fakeDeps.PDControl.(*pdapi.FakePDControl)
 	podControl := fakeDeps.PodControl.(*controller.FakePodControl)
 	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
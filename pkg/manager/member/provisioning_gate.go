@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// externalProvisioningAnnotations returns the pod annotations gating the scheduling of the new pods of the
+// component until the external provisioning completes, they are nil if the component does not wait for it
+func externalProvisioningAnnotations(spec v1alpha1.ComponentAccessor) map[string]string {
+	if !spec.WaitForExternalProvisioning() {
+		return nil
+	}
+	return map[string]string{label.AnnSchedulingGate: label.AnnSchedulingGateExternalProvisioningVal}
+}
+
+// syncExternalProvisioningGates removes the gates of the pods of the component whose external provisioning
+// completes, i.e. the external system annotates the pod with `tidb.pingcap.com/external-provisioned: "true"`.
+// The gates are kept on the pods waiting longer than -external-provisioning-timeout, so that no pod starts
+// without the provisioning, and the component is marked ExternalProvisioningTimedOut instead. All the gates
+// are removed if the component does not wait for the external provisioning any more.
+func syncExternalProvisioningGates(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	spec := baseComponentSpec(tc, memberType)
+	if spec == nil {
+		return nil
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).Component(memberType.String()).Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("syncExternalProvisioningGates: failed to list pods of %s for tc %s/%s, error: %v", memberType, ns, tcName, err)
+	}
+
+	var timedOut []string
+	now := time.Now()
+	for _, pod := range pods {
+		if pod.Annotations[label.AnnSchedulingGate] != label.AnnSchedulingGateExternalProvisioningVal {
+			continue
+		}
+		if spec.WaitForExternalProvisioning() && pod.Annotations[label.AnnExternalProvisioned] != label.AnnExternalProvisionedVal {
+			if now.Sub(pod.CreationTimestamp.Time) > deps.CLIConfig.ExternalProvisioningTimeout {
+				timedOut = append(timedOut, pod.GetName())
+			}
+			continue
+		}
+		pod = pod.DeepCopy()
+		delete(pod.Annotations, label.AnnSchedulingGate)
+		if _, err := deps.PodControl.UpdatePod(tc, pod); err != nil {
+			return fmt.Errorf("syncExternalProvisioningGates: failed to remove the scheduling gate of pod %s/%s, error: %v", ns, pod.GetName(), err)
+		}
+		klog.Infof("tidbcluster: [%s/%s] the scheduling gate of the external provisioning of pod %s is removed", ns, tcName, pod.GetName())
+	}
+
+	for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+		if status.GetMemberType() != memberType {
+			continue
+		}
+		if len(timedOut) == 0 {
+			status.RemoveCondition(v1alpha1.ComponentExternalProvisioningTimedOut)
+			return nil
+		}
+		sort.Strings(timedOut)
+		status.SetCondition(metav1.Condition{
+			Type:    v1alpha1.ComponentExternalProvisioningTimedOut,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ExternalProvisioningTimedOut",
+			Message: fmt.Sprintf("the external provisioning of pods %s does not complete in %s", strings.Join(timedOut, ", "), deps.CLIConfig.ExternalProvisioningTimeout),
+		})
+		return nil
+	}
+	return nil
+}
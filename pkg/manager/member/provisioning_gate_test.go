@@ -0,0 +1,94 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestExternalProvisioningAnnotations(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tc := newTidbClusterForTiDB()
+
+	sts, err := getNewTiDBSetForTidbCluster(tc, nil)
+	g.Expect(err).To(Succeed())
+	g.Expect(sts.Spec.Template.Annotations).NotTo(HaveKey(label.AnnSchedulingGate))
+
+	tc.Spec.TiDB.WaitForExternalProvisioning = pointer.BoolPtr(true)
+	sts, err = getNewTiDBSetForTidbCluster(tc, nil)
+	g.Expect(err).To(Succeed())
+	g.Expect(sts.Spec.Template.Annotations).To(HaveKeyWithValue(label.AnnSchedulingGate, label.AnnSchedulingGateExternalProvisioningVal))
+}
+
+func TestSyncExternalProvisioningGates(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := controller.NewFakeDependencies()
+	deps.CLIConfig.ExternalProvisioningTimeout = time.Minute
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.WaitForExternalProvisioning = pointer.BoolPtr(true)
+
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	addPod := func(ordinal int32, age time.Duration, annotations map[string]string) {
+		g.Expect(podIndexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              tidbPodName(tc.Name, ordinal),
+				Namespace:         tc.Namespace,
+				Labels:            label.New().Instance(tc.Name).TiDB().Labels(),
+				Annotations:       annotations,
+				CreationTimestamp: metav1.Time{Time: time.Now().Add(-age)},
+			},
+		})).To(Succeed())
+	}
+	gated := func(ordinal int32) bool {
+		pod, err := deps.PodLister.Pods(tc.Namespace).Get(tidbPodName(tc.Name, ordinal))
+		g.Expect(err).To(Succeed())
+		_, ok := pod.Annotations[label.AnnSchedulingGate]
+		return ok
+	}
+	gate := label.AnnSchedulingGate
+	gateVal := label.AnnSchedulingGateExternalProvisioningVal
+	// provisioned
+	addPod(0, time.Hour, map[string]string{gate: gateVal, label.AnnExternalProvisioned: label.AnnExternalProvisionedVal})
+	// waiting for the provisioning
+	addPod(1, time.Second, map[string]string{gate: gateVal})
+	// timed out
+	addPod(2, time.Hour, map[string]string{gate: gateVal})
+
+	g.Expect(syncExternalProvisioningGates(deps, tc, v1alpha1.TiDBMemberType)).To(Succeed())
+	g.Expect(gated(0)).To(BeFalse())
+	g.Expect(gated(1)).To(BeTrue())
+	g.Expect(gated(2)).To(BeTrue())
+	cond := meta.FindStatusCondition(tc.Status.TiDB.Conditions, v1alpha1.ComponentExternalProvisioningTimedOut)
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(cond.Message).To(ContainSubstring(tidbPodName(tc.Name, 2)))
+	g.Expect(cond.Message).NotTo(ContainSubstring(tidbPodName(tc.Name, 1)))
+
+	// all the gates are removed if the component does not wait for the provisioning any more
+	tc.Spec.TiDB.WaitForExternalProvisioning = nil
+	g.Expect(syncExternalProvisioningGates(deps, tc, v1alpha1.TiDBMemberType)).To(Succeed())
+	g.Expect(gated(1)).To(BeFalse())
+	g.Expect(gated(2)).To(BeFalse())
+	g.Expect(meta.FindStatusCondition(tc.Status.TiDB.Conditions, v1alpha1.ComponentExternalProvisioningTimedOut)).To(BeNil())
+}
@@ -37,6 +37,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -52,8 +53,9 @@ type binlogClient interface {
 }
 
 type pumpMemberManager struct {
-	deps   *controller.Dependencies
-	scaler Scaler
+	deps     *controller.Dependencies
+	scaler   Scaler
+	recorder record.EventRecorder
 	// only use for test
 	binlogClient binlogClient
 }
@@ -61,8 +63,9 @@ type pumpMemberManager struct {
 // NewPumpMemberManager returns a controller to reconcile pump clusters
 func NewPumpMemberManager(deps *controller.Dependencies, scaler Scaler) manager.Manager {
 	return &pumpMemberManager{
-		deps:   deps,
-		scaler: scaler,
+		deps:     deps,
+		scaler:   scaler,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -90,7 +93,9 @@ func (m *pumpMemberManager) syncPumpStatefulSetForTidbCluster(tc *v1alpha1.TidbC
 		return err
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.PumpMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.PumpMemberType) {
 		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for pump statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -116,18 +121,37 @@ func (m *pumpMemberManager) syncPumpStatefulSetForTidbCluster(tc *v1alpha1.TidbC
 		return err
 	}
 
-	// Wait for PD & TiKV upgrading done
-	if tc.Status.TiFlash.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.PD.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiKV.Phase == v1alpha1.UpgradePhase {
-		klog.Infof("TidbCluster: [%s/%s]'s tiflash status is %s, "+
-			"pd status is %s, tikv status is %s, can not upgrade pump",
-			tc.Namespace, tc.Name,
-			tc.Status.TiFlash.Phase, tc.Status.PD.Phase, tc.Status.TiKV.Phase)
+	// Wait for the components upgraded before pump
+	if blocking := upgradeBlockedBy(tc, v1alpha1.PumpMemberType); blocking != "" {
+		klog.Infof("TidbCluster: [%s/%s]'s components upgraded before pump [%s] are upgrading, can not upgrade pump",
+			tc.Namespace, tc.Name, blocking)
 		return nil
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdatePumpSTS", newSet, oldSet)
+	pausePumpUpgrade(m.recorder, tc, oldSet, newSet)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newSet, oldSet)
+}
+
+// pausePumpUpgrade keeps the pump pods not upgraded yet by the partition if the upgrades of tc are paused by the
+// annotation tidb.pingcap.com/upgrade-paused. Pump is upgraded by the StatefulSet controller in the descending order
+// of the ordinals, the partition is removed and the upgrade resumes once the annotation is removed.
+func pausePumpUpgrade(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) {
+	if !tc.IsUpgradePaused() || newSet.Spec.UpdateStrategy.Type != apps.RollingUpdateStatefulSetStrategyType {
+		return
+	}
+	partition := *oldSet.Spec.Replicas
+	if templateEqual(newSet, oldSet) {
+		if rolling := oldSet.Spec.UpdateStrategy.RollingUpdate; rolling != nil && rolling.Partition != nil && *rolling.Partition > 0 {
+			partition = *rolling.Partition
+		} else if mngerutils.StatefulSetIsUpgrading(oldSet) {
+			partition = *oldSet.Spec.Replicas - oldSet.Status.UpdatedReplicas
+		} else {
+			return
+		}
+	}
+	if partition > 0 && upgradePaused(recorder, tc, v1alpha1.PumpMemberType, partition-1) {
+		mngerutils.SetUpgradePartition(newSet, partition)
+	}
 }
 
 func (p *pumpMemberManager) buildBinlogClient(tc *v1alpha1.TidbCluster, control pdapi.PDControlInterface) (client binlogClient, err error) {
@@ -180,11 +204,24 @@ func (m *pumpMemberManager) syncTiDBClusterStatus(tc *v1alpha1.TidbCluster, set
 		return err
 	}
 
+	oldPhase := tc.Status.Pump.Phase
 	if upgrading {
 		tc.Status.Pump.Phase = v1alpha1.UpgradePhase
 	} else {
 		tc.Status.Pump.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.Pump.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.PumpMemberType, oldPhase, tc.Status.Pump.Phase, tc.Status.Pump.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.PumpMemberType, oldPhase, tc.Status.Pump.Phase, tc.Status.Pump.StatefulSet)
+	// pump is upgraded by the statefulset controller in the descending order of the ordinals
+	var upgradingOrdinal *int32
+	if upgrading && set.Spec.Replicas != nil {
+		ordinal := *set.Spec.Replicas - set.Status.UpdatedReplicas - 1
+		if rolling := set.Spec.UpdateStrategy.RollingUpdate; ordinal >= 0 && (rolling == nil || rolling.Partition == nil || ordinal >= *rolling.Partition) {
+			upgradingOrdinal = &ordinal
+		}
+	}
+	syncUpgradeStatus(m.deps, tc, v1alpha1.PumpMemberType, set, upgradingOrdinal)
+	tc.Status.Pump.StorageUsage = SyncVolumeStorageUsage(m.deps, tc, v1alpha1.PumpMemberType.String(), tc.Status.Pump.StorageUsage, set, tc.StorageWarningThreshold())
 
 	client, err := m.buildBinlogClient(tc, m.deps.PDControl)
 	if err != nil {
@@ -203,7 +240,7 @@ func (m *pumpMemberManager) syncTiDBClusterStatus(tc *v1alpha1.TidbCluster, set
 }
 
 func (m *pumpMemberManager) syncHeadlessService(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.PumpMemberType) {
 		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for pump headless service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -264,6 +301,9 @@ func (m *pumpMemberManager) syncConfigMap(tc *v1alpha1.TidbCluster, set *appsv1.
 	if err != nil {
 		return nil, err
 	}
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.PumpMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -334,6 +374,10 @@ func getNewPumpConfigMap(tc *v1alpha1.TidbCluster) (*corev1.ConfigMap, error) {
 
 func getNewPumpStatefulSet(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (*appsv1.StatefulSet, error) {
 	spec := tc.BasePumpSpec()
+	resources, err := v1alpha1.SizedResources(spec.Sizing(), tc.Spec.Pump.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for pump, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
 	objMeta, stsLabels := getPumpMeta(tc, controller.PumpMemberName)
 	replicas := tc.Spec.Pump.Replicas
 	storageClass := tc.Spec.Pump.StorageClassName
@@ -397,7 +441,7 @@ func getNewPumpStatefulSet(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (*app
 				Name:          "pump",
 				ContainerPort: 8250,
 			}},
-			Resources:    controller.ContainerResource(tc.Spec.Pump.ResourceRequirements),
+			Resources:    controller.ContainerResource(resources),
 			Env:          util.AppendEnv(envs, spec.Env()),
 			EnvFrom:      spec.EnvFrom(),
 			VolumeMounts: volumeMounts,
@@ -19,6 +19,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/apis/util/config"
 	"github.com/pingcap/tidb-operator/pkg/controller"
@@ -437,6 +438,7 @@ func newFakePumpMemberManager() (*pumpMemberManager, *pumpFakeControls, *pumpFak
 	pmm := &pumpMemberManager{
 		deps:         fakeDeps,
 		scaler:       NewFakePumpScaler(),
+		recorder:     fakeDeps.Recorder,
 		binlogClient: &fakeBinlogClient{},
 	}
 	controls := &pumpFakeControls{
@@ -452,6 +454,55 @@ func newFakePumpMemberManager() (*pumpMemberManager, *pumpFakeControls, *pumpFak
 	return pmm, controls, indexers
 }
 
+func TestPausePumpUpgrade(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForPump()
+	cm, err := getNewPumpConfigMap(tc)
+	g.Expect(err).To(Succeed())
+	oldSet, err := getNewPumpStatefulSet(tc, cm)
+	g.Expect(err).To(Succeed())
+	g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+	partition := func(set *appsv1.StatefulSet) *int32 {
+		if set.Spec.UpdateStrategy.RollingUpdate == nil {
+			return nil
+		}
+		return set.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
+
+	// not paused
+	newSet := oldSet.DeepCopy()
+	newSet.Spec.Template.Spec.Containers[0].Image = "pump-test-image:new"
+	pausePumpUpgrade(deps.Recorder, tc, oldSet, newSet)
+	g.Expect(partition(newSet)).To(BeNil())
+
+	// the upgrade is not started if paused before the template changes
+	tc.Annotations = map[string]string{label.AnnUpgradePaused: label.AnnUpgradePausedVal}
+	pausePumpUpgrade(deps.Recorder, tc, oldSet, newSet)
+	g.Expect(partition(newSet)).To(Equal(pointer.Int32Ptr(3)))
+
+	// the pods not upgraded yet are kept if paused in the middle of the upgrade
+	newSet = oldSet.DeepCopy()
+	oldSet.Status = appsv1.StatefulSetStatus{Replicas: 3, UpdatedReplicas: 1, CurrentRevision: "1", UpdateRevision: "2"}
+	pausePumpUpgrade(deps.Recorder, tc, oldSet, newSet)
+	g.Expect(partition(newSet)).To(Equal(pointer.Int32Ptr(2)))
+
+	// the partition is kept until the annotation is removed
+	mngerutils.SetUpgradePartition(oldSet, 2)
+	g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+	oldSet.Status.UpdatedReplicas = 2
+	newSet = oldSet.DeepCopy()
+	newSet.Spec.UpdateStrategy.RollingUpdate = nil
+	pausePumpUpgrade(deps.Recorder, tc, oldSet, newSet)
+	g.Expect(partition(newSet)).To(Equal(pointer.Int32Ptr(2)))
+
+	tc.Annotations = nil
+	newSet.Spec.UpdateStrategy.RollingUpdate = nil
+	pausePumpUpgrade(deps.Recorder, tc, oldSet, newSet)
+	g.Expect(partition(newSet)).To(BeNil())
+}
+
 func newTidbClusterForPump() *v1alpha1.TidbCluster {
 	updateStrategy := v1alpha1.ConfigUpdateStrategyInPlace
 	return &v1alpha1.TidbCluster{
@@ -206,7 +206,7 @@ func (s *pumpScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 					return err
 				}
 			}
-			setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+			s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 			return nil
 		} else {
 			return controller.RequeueErrorf("Pump %s/%s is still in cluster, state: %s", ns, podName, node.State)
@@ -242,7 +242,7 @@ func (s *pumpScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 			}
 		}
 
-		setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+		s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 		return nil
 	}
 	return fmt.Errorf("Pump %s/%s not found in cluster", ns, podName)
@@ -0,0 +1,259 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/manager"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// restartableMemberTypes are the components which can be restarted by the annotation tidb.pingcap.com/restart, they
+// are restarted by their upgraders, e.g. with the leaders evicted from the TiKV stores
+var restartableMemberTypes = []v1alpha1.MemberType{
+	v1alpha1.PDMemberType,
+	v1alpha1.TiKVMemberType,
+	v1alpha1.TiFlashMemberType,
+	v1alpha1.TiDBMemberType,
+	v1alpha1.TiCDCMemberType,
+}
+
+type restartManager struct {
+	deps *controller.Dependencies
+}
+
+// NewRestartManager returns a manager restarting the components of the TidbCluster gracefully on request
+func NewRestartManager(deps *controller.Dependencies) manager.Manager {
+	return &restartManager{
+		deps: deps,
+	}
+}
+
+// Sync handles the graceful rolling restarts of the components requested by the annotation tidb.pingcap.com/restart,
+// e.g. "tikv,tidb". The restart generations of the components in status.restarts are increased and the annotation
+// is removed, then the member managers set the generations in the pod templates, so that the pods are restarted
+// by the upgraders of the components like the other changes of the templates. The restarts are refused while the
+// cluster is being upgraded. The completion of the restarts is recorded when the StatefulSets are up to date.
+func (m *restartManager) Sync(tc *v1alpha1.TidbCluster) error {
+	if err := m.syncRestartGenerations(tc); err != nil {
+		return err
+	}
+	m.syncRestartCompletions(tc)
+
+	value, ok := tc.Annotations[label.AnnRestart]
+	if !ok {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	var requested []v1alpha1.MemberType
+	var invalid []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		memberType := v1alpha1.MemberType(name)
+		if !isRestartable(memberType) || !componentDeployed(tc, memberType) {
+			invalid = append(invalid, name)
+			continue
+		}
+		requested = append(requested, memberType)
+	}
+	if len(invalid) > 0 {
+		m.deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.RestartRefused, "components %s can not be restarted, they are unknown or not deployed", strings.Join(invalid, ","))
+	}
+	upgrading := upgradingComponents(tc)
+	if len(requested) > 0 && len(upgrading) > 0 {
+		m.deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.RestartRefused, "the restart of %s is refused while %s is upgrading", value, strings.Join(upgrading, ","))
+		requested = nil
+	}
+
+	// the annotation is removed before the generations are increased, so that a request is never handled twice
+	if err := removeRestartAnnotation(m.deps, tc); err != nil {
+		return err
+	}
+	now := metav1.Now()
+	for _, memberType := range requested {
+		if tc.Status.Restarts == nil {
+			tc.Status.Restarts = map[string]v1alpha1.ComponentRestartStatus{}
+		}
+		restart := tc.Status.Restarts[memberType.String()]
+		restart.Generation++
+		restart.RequestTime = now
+		restart.CompletionTime = nil
+		tc.Status.Restarts[memberType.String()] = restart
+		klog.Infof("tidbcluster: [%s/%s] restart %s gracefully, restart generation: %d", ns, tcName, memberType, restart.Generation)
+		m.deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.RestartRequested, "%s is restarted gracefully, restart generation: %d", memberType, restart.Generation)
+	}
+	return nil
+}
+
+// syncRestartGenerations recovers the restart generations of the components from their StatefulSets, which are
+// greater than the ones in the status if the status fails to be updated after the StatefulSets are, so that the
+// pods are not restarted again with the generations in the status.
+func (m *restartManager) syncRestartGenerations(tc *v1alpha1.TidbCluster) error {
+	for _, memberType := range restartableMemberTypes {
+		set, err := m.deps.StatefulSetLister.StatefulSets(tc.GetNamespace()).Get(restartStatefulSetName(tc, memberType))
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		generation, err := templateRestartGeneration(set.Spec.Template.Annotations)
+		if err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] invalid restart generation of %s: %v", tc.GetNamespace(), tc.GetName(), memberType, err)
+			continue
+		}
+		if generation <= tc.Status.Restarts[memberType.String()].Generation {
+			continue
+		}
+		if tc.Status.Restarts == nil {
+			tc.Status.Restarts = map[string]v1alpha1.ComponentRestartStatus{}
+		}
+		restart := tc.Status.Restarts[memberType.String()]
+		restart.Generation = generation
+		restart.CompletionTime = nil
+		tc.Status.Restarts[memberType.String()] = restart
+	}
+	return nil
+}
+
+// syncRestartCompletions records the completion time of the restarts whose generations are set in the StatefulSets
+// and all the pods are updated.
+func (m *restartManager) syncRestartCompletions(tc *v1alpha1.TidbCluster) {
+	for name, restart := range tc.Status.Restarts {
+		if restart.CompletionTime != nil {
+			continue
+		}
+		memberType := v1alpha1.MemberType(name)
+		set, err := m.deps.StatefulSetLister.StatefulSets(tc.GetNamespace()).Get(restartStatefulSetName(tc, memberType))
+		if err != nil {
+			continue
+		}
+		generation, err := templateRestartGeneration(set.Spec.Template.Annotations)
+		if err != nil || generation != restart.Generation {
+			continue
+		}
+		if mngerutils.StatefulSetIsUpgrading(set) || componentPhase(tc, memberType) == v1alpha1.UpgradePhase {
+			continue
+		}
+		now := metav1.Now()
+		restart.CompletionTime = &now
+		tc.Status.Restarts[name] = restart
+		klog.Infof("tidbcluster: [%s/%s] the restart of %s completes, restart generation: %d", tc.GetNamespace(), tc.GetName(), memberType, restart.Generation)
+		m.deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.RestartCompleted, "the restart of %s completes, restart generation: %d", memberType, restart.Generation)
+	}
+}
+
+// restartGenerationAnnotations returns the pod annotations of the restart generation of the component, they are nil
+// if the component is never restarted
+func restartGenerationAnnotations(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) map[string]string {
+	generation := tc.Status.Restarts[memberType.String()].Generation
+	if generation == 0 {
+		return nil
+	}
+	return map[string]string{label.AnnRestartGeneration: strconv.FormatInt(generation, 10)}
+}
+
+func templateRestartGeneration(annotations map[string]string) (int64, error) {
+	value, ok := annotations[label.AnnRestartGeneration]
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// upgradingComponents returns the components being upgraded for the other reasons than the restarts
+func upgradingComponents(tc *v1alpha1.TidbCluster) []string {
+	var upgrading []string
+	for _, memberType := range restartableMemberTypes {
+		if !componentDeployed(tc, memberType) || componentPhase(tc, memberType) != v1alpha1.UpgradePhase {
+			continue
+		}
+		if restart, ok := tc.Status.Restarts[memberType.String()]; ok && restart.CompletionTime == nil {
+			continue
+		}
+		upgrading = append(upgrading, memberType.String())
+	}
+	return upgrading
+}
+
+func isRestartable(memberType v1alpha1.MemberType) bool {
+	for _, t := range restartableMemberTypes {
+		if t == memberType {
+			return true
+		}
+	}
+	return false
+}
+
+func componentDeployed(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) bool {
+	for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+		if status.GetMemberType() == memberType {
+			return true
+		}
+	}
+	return false
+}
+
+func restartStatefulSetName(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) string {
+	return fmt.Sprintf("%s-%s", tc.GetName(), memberType)
+}
+
+func removeRestartAnnotation(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{label.AnnRestart: nil},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := deps.TiDBClusterControl.Patch(tc, patch); err != nil {
+		return err
+	}
+	delete(tc.Annotations, label.AnnRestart)
+	return nil
+}
+
+type FakeRestartManager struct {
+	err error
+}
+
+func NewFakeRestartManager() *FakeRestartManager {
+	return &FakeRestartManager{}
+}
+
+func (m *FakeRestartManager) SetSyncError(err error) {
+	m.err = err
+}
+
+func (m *FakeRestartManager) Sync(_ *v1alpha1.TidbCluster) error {
+	return m.err
+}
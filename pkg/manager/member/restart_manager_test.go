@@ -0,0 +1,133 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestRestartManagerSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	indexer := deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer()
+	m := NewRestartManager(deps)
+	tc := newTidbClusterForTiKV()
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+
+	// nothing to restart
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.Restarts).To(BeNil())
+
+	// the generations of the components requested are increased and the annotation is removed
+	tc.Annotations = map[string]string{label.AnnRestart: "tikv, tidb,tiflash"}
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnRestart))
+	g.Expect(tc.Status.Restarts).To(HaveLen(2))
+	g.Expect(tc.Status.Restarts["tikv"].Generation).To(Equal(int64(1)))
+	g.Expect(tc.Status.Restarts["tikv"].RequestTime).NotTo(BeZero())
+	g.Expect(tc.Status.Restarts["tikv"].CompletionTime).To(BeNil())
+	g.Expect(tc.Status.Restarts["tidb"].Generation).To(Equal(int64(1)))
+	g.Expect(restartGenerationAnnotations(tc, v1alpha1.TiKVMemberType)).To(Equal(map[string]string{label.AnnRestartGeneration: "1"}))
+	g.Expect(restartGenerationAnnotations(tc, v1alpha1.PDMemberType)).To(BeNil())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(3))
+	g.Expect(recorded[0]).To(ContainSubstring(events.RestartRefused))
+	g.Expect(recorded[0]).To(ContainSubstring("components tiflash can not be restarted"))
+	g.Expect(recorded[1]).To(ContainSubstring(events.RestartRequested))
+	g.Expect(recorded[1]).To(ContainSubstring("tikv is restarted gracefully, restart generation: 1"))
+	g.Expect(recorded[2]).To(ContainSubstring(events.RestartRequested))
+
+	// the change of the restart generation changes the pod template
+	oldSet := newRestartStatefulSet(tc, v1alpha1.TiKVMemberType, 0)
+	g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+	newSet := newRestartStatefulSet(tc, v1alpha1.TiKVMemberType, 1)
+	g.Expect(templateEqual(newSet, oldSet)).To(BeFalse())
+	g.Expect(templateEqual(oldSet.DeepCopy(), oldSet)).To(BeTrue())
+
+	// the restart is not completed until the pods are updated
+	set := newRestartStatefulSet(tc, v1alpha1.TiKVMemberType, 1)
+	set.Status.UpdateRevision = "new"
+	g.Expect(indexer.Add(set)).To(Succeed())
+	tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.Restarts["tikv"].CompletionTime).To(BeNil())
+
+	// the restart is refused while the other components are upgrading, but not the restarts in flight
+	tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+	delete(tc.Status.Restarts, "tidb")
+	tc.Annotations[label.AnnRestart] = "tikv"
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnRestart))
+	g.Expect(tc.Status.Restarts["tikv"].Generation).To(Equal(int64(1)))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.RestartRefused))
+	g.Expect(recorded[0]).To(ContainSubstring("the restart of tikv is refused while tidb is upgrading"))
+
+	// the completion is recorded after the pods are updated
+	tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	set.Status.CurrentRevision = "new"
+	g.Expect(indexer.Update(set)).To(Succeed())
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.Restarts["tikv"].CompletionTime).NotTo(BeNil())
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.RestartCompleted))
+	g.Expect(recorded[0]).To(ContainSubstring("the restart of tikv completes, restart generation: 1"))
+
+	// the generation in the StatefulSet is recovered if the status is lost
+	set = newRestartStatefulSet(tc, v1alpha1.TiKVMemberType, 3)
+	set.Status.UpdateRevision = "newer"
+	g.Expect(indexer.Update(set)).To(Succeed())
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(tc.Status.Restarts["tikv"].Generation).To(Equal(int64(3)))
+	g.Expect(tc.Status.Restarts["tikv"].CompletionTime).To(BeNil())
+}
+
+func newRestartStatefulSet(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, generation int64) *apps.StatefulSet {
+	set := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      restartStatefulSetName(tc, memberType),
+			Namespace: tc.Namespace,
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: pointer.Int32Ptr(3),
+		},
+		Status: apps.StatefulSetStatus{
+			Replicas:        3,
+			CurrentRevision: "old",
+			UpdateRevision:  "old",
+		},
+	}
+	if generation > 0 {
+		set.Spec.Template.Annotations = map[string]string{label.AnnRestartGeneration: strconv.FormatInt(generation, 10)}
+	}
+	return set
+}
@@ -21,8 +21,11 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/features"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -70,7 +73,7 @@ func (s *generalScaler) deleteDeferDeletingPVC(controller runtime.Object, member
 	}
 	if len(pvcs) == 0 {
 		klog.Infof("%s %s/%s list pvc not found, selector: %s", kind, ns, meta.GetName(), selector)
-		podName := ordinalPodName(memberType, meta.GetName(), ordinal)
+		podName := memberPodName(meta, memberType, ordinal)
 		skipReason[podName] = skipReasonScalerPVCNotFound
 		return skipReason, nil
 	}
@@ -148,17 +151,38 @@ func resetReplicas(newSet *apps.StatefulSet, oldSet *apps.StatefulSet) {
 	}
 }
 
-func setReplicasAndDeleteSlots(newSet *apps.StatefulSet, replicas int32, deleteSlots sets.Int32) {
+// setScaleInReplicas sets the replicas and the delete slots of the statefulset of the cluster being scaled in,
+// an event is recorded if the partition of the in-progress upgrade is reset as it exceeds the new replicas
+func (s *generalScaler) setScaleInReplicas(meta metav1.Object, newSet *apps.StatefulSet, replicas int32, deleteSlots sets.Int32) {
+	var partition int32
+	if ru := newSet.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		partition = *ru.Partition
+	}
+	if !setReplicasAndDeleteSlots(newSet, replicas, deleteSlots) {
+		return
+	}
+	if obj, ok := meta.(runtime.Object); ok {
+		s.deps.Recorder.Eventf(obj, corev1.EventTypeNormal, events.UpgradePartitionClamped,
+			"the upgrade partition of statefulset %s is reset from %d to %d as it is scaled in to %d replicas",
+			newSet.GetName(), partition, *newSet.Spec.UpdateStrategy.RollingUpdate.Partition, replicas)
+	}
+}
+
+// setReplicasAndDeleteSlots sets the replicas and the delete slots of the statefulset, it returns true if the
+// partition of an in-progress upgrade is reset as it exceeds the new replicas
+func setReplicasAndDeleteSlots(newSet *apps.StatefulSet, replicas int32, deleteSlots sets.Int32) bool {
 	oldReplicas := *newSet.Spec.Replicas
 	*newSet.Spec.Replicas = replicas
 	if features.DefaultFeatureGate.Enabled(features.AdvancedStatefulSet) {
 		helper.SetDeleteSlots(newSet, deleteSlots)
 		klog.Infof("scale statefulset: %s/%s replicas from %d to %d (delete slots: %v)",
 			newSet.GetNamespace(), newSet.GetName(), oldReplicas, replicas, deleteSlots.List())
-		return
+	} else {
+		klog.Infof("scale statefulset: %s/%s replicas from %d to %d",
+			newSet.GetNamespace(), newSet.GetName(), oldReplicas, replicas)
 	}
-	klog.Infof("scale statefulset: %s/%s replicas from %d to %d",
-		newSet.GetNamespace(), newSet.GetName(), oldReplicas, replicas)
+	// the partition of an in-progress upgrade may exceed the replicas after scaling in
+	return mngerutils.ClampUpgradePartition(newSet)
 }
 
 func ordinalPVCName(memberType v1alpha1.MemberType, setName string, ordinal int32) string {
@@ -169,6 +193,15 @@ func ordinalPodName(memberType v1alpha1.MemberType, tcName string, ordinal int32
 	return fmt.Sprintf("%s-%s-%d", tcName, memberType, ordinal)
 }
 
+// memberPodName returns the name of the pod of the member at the ordinal, the tidb pods of a tidb group are named
+// after the statefulset of the group
+func memberPodName(meta metav1.Object, memberType v1alpha1.MemberType, ordinal int32) string {
+	if tc, ok := meta.(*v1alpha1.TidbCluster); ok && memberType == v1alpha1.TiDBMemberType {
+		return tidbSetPodName(tc, ordinal)
+	}
+	return ordinalPodName(memberType, meta.GetName(), ordinal)
+}
+
 // scaleOne calculates desired replicas and delete slots from actual/desired
 // stateful sets by allowing only one pod to be deleted or created
 // it returns following values:
@@ -0,0 +1,231 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+)
+
+// storageUsageCollectInterval is the min interval between two collections of the volume stats from the kubelets,
+// the store stats are collected on every sync as they are fetched from PD anyway
+var storageUsageCollectInterval = time.Minute
+
+// PodStorageUsage is the used storage of a pod, the usage is unknown if the capacity is 0
+type PodStorageUsage struct {
+	Pod           string
+	CapacityBytes uint64
+	UsedBytes     uint64
+}
+
+func (u PodStorageUsage) known() bool {
+	return u.CapacityBytes > 0
+}
+
+func (u PodStorageUsage) usedRatio() float64 {
+	return float64(u.UsedBytes) / float64(u.CapacityBytes)
+}
+
+func (u PodStorageUsage) usedPercent() int32 {
+	return int32(u.UsedBytes * 100 / u.CapacityBytes)
+}
+
+func (u PodStorageUsage) overThreshold(threshold int32) bool {
+	return u.UsedBytes*100 > uint64(threshold)*u.CapacityBytes
+}
+
+// storeStorageUsages returns the storage usages of the pods of the stores reported by PD, the usages of the stores
+// without the stats are unknown
+func storeStorageUsages(storesInfo *pdapi.StoresInfo, stores map[string]v1alpha1.TiKVStore) []PodStorageUsage {
+	var usages []PodStorageUsage
+	for _, info := range storesInfo.Stores {
+		if info.Store == nil {
+			continue
+		}
+		store, ok := stores[strconv.FormatUint(info.Store.GetId(), 10)]
+		if !ok || store.PodName == "" {
+			continue
+		}
+		usage := PodStorageUsage{Pod: store.PodName}
+		if info.Status != nil && info.Status.Capacity > 0 {
+			usage.CapacityBytes = uint64(info.Status.Capacity)
+			if info.Status.Available < info.Status.Capacity {
+				usage.UsedBytes = uint64(info.Status.Capacity - info.Status.Available)
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// kubeletStatsSummary is the part of the stats summary of the kubelet with the stats of the volumes of the pods
+type kubeletStatsSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			Name          string  `json:"name"`
+			CapacityBytes *uint64 `json:"capacityBytes,omitempty"`
+			UsedBytes     *uint64 `json:"usedBytes,omitempty"`
+			PVCRef        *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef,omitempty"`
+		} `json:"volume,omitempty"`
+	} `json:"pods"`
+}
+
+// getKubeletStatsSummary gets the stats summary of the kubelet of the node through the proxy of the API server
+var getKubeletStatsSummary = func(deps *controller.Dependencies, node string) (*kubeletStatsSummary, error) {
+	restClient, ok := deps.KubeClientset.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil {
+		return nil, fmt.Errorf("the client of the kubelet proxy is unavailable")
+	}
+	data, err := restClient.Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	summary := &kubeletStatsSummary{}
+	if err := json.Unmarshal(data, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// VolumeStorageUsages returns the storage usages of the pods of the StatefulSet from the volume stats of the
+// kubelets, the usage of a pod is the one of its persistent volume with the highest used percent. The usages of
+// the pods which are not scheduled or whose stats fail to be collected are unknown.
+func VolumeStorageUsages(deps *controller.Dependencies, set *apps.StatefulSet) []PodStorageUsage {
+	selector, err := metav1.LabelSelectorAsSelector(set.Spec.Selector)
+	if err != nil {
+		klog.Warningf("statefulset %s/%s: invalid selector: %v", set.Namespace, set.Name, err)
+		return nil
+	}
+	pods, err := deps.PodLister.Pods(set.Namespace).List(selector)
+	if err != nil {
+		klog.Warningf("statefulset %s/%s: failed to list the pods: %v", set.Namespace, set.Name, err)
+		return nil
+	}
+
+	summaries := map[string]*kubeletStatsSummary{}
+	var usages []PodStorageUsage
+	for _, pod := range pods {
+		usage := PodStorageUsage{Pod: pod.Name}
+		node := pod.Spec.NodeName
+		if node == "" {
+			usages = append(usages, usage)
+			continue
+		}
+		summary, ok := summaries[node]
+		if !ok {
+			summary, err = getKubeletStatsSummary(deps, node)
+			if err != nil {
+				klog.Warningf("statefulset %s/%s: failed to get the volume stats from the kubelet of node %s: %v", set.Namespace, set.Name, node, err)
+			}
+			summaries[node] = summary
+		}
+		if summary != nil {
+			for _, podStats := range summary.Pods {
+				if podStats.PodRef.Namespace != pod.Namespace || podStats.PodRef.Name != pod.Name {
+					continue
+				}
+				for _, volume := range podStats.Volumes {
+					if volume.PVCRef == nil || volume.CapacityBytes == nil || volume.UsedBytes == nil || *volume.CapacityBytes == 0 {
+						continue
+					}
+					v := PodStorageUsage{Pod: pod.Name, CapacityBytes: *volume.CapacityBytes, UsedBytes: *volume.UsedBytes}
+					if !usage.known() || v.usedRatio() > usage.usedRatio() {
+						usage = v
+					}
+				}
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+// SyncVolumeStorageUsage returns the storage usage of the pods of the StatefulSet collected from the kubelets, the
+// previous usage is returned if it is collected within storageUsageCollectInterval
+func SyncVolumeStorageUsage(deps *controller.Dependencies, obj runtime.Object, component string, previous *v1alpha1.StorageUsage, set *apps.StatefulSet, threshold int32) *v1alpha1.StorageUsage {
+	if previous != nil && time.Since(previous.LastUpdateTime.Time) < storageUsageCollectInterval {
+		return previous
+	}
+	return SyncStorageUsage(deps.Recorder, obj, component, previous, VolumeStorageUsages(deps, set), threshold)
+}
+
+// SyncStorageUsage summarizes the storage usages of the pods of the component and records the events when the pods
+// cross the threshold. The pods whose usages are unknown keep their previous states, so that the events are only
+// emitted on the crossings rather than the gaps of the stats, and the previous summary is kept if none of the
+// usages is known.
+func SyncStorageUsage(recorder record.EventRecorder, obj runtime.Object, component string, previous *v1alpha1.StorageUsage, usages []PodStorageUsage, threshold int32) *v1alpha1.StorageUsage {
+	wasOver := sets.NewString()
+	if previous != nil {
+		wasOver.Insert(previous.PodsOverThreshold...)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Pod < usages[j].Pod
+	})
+	var max *PodStorageUsage
+	over := sets.NewString()
+	for i := range usages {
+		usage := usages[i]
+		if !usage.known() {
+			if wasOver.Has(usage.Pod) {
+				over.Insert(usage.Pod)
+			}
+			continue
+		}
+		if max == nil || usage.usedRatio() > max.usedRatio() {
+			max = &usages[i]
+		}
+		if usage.overThreshold(threshold) {
+			over.Insert(usage.Pod)
+			if !wasOver.Has(usage.Pod) {
+				recorder.Eventf(obj, corev1.EventTypeWarning, events.StoragePressure, "the used storage of %s pod %s is %d%%, above the warning threshold %d%%", component, usage.Pod, usage.usedPercent(), threshold)
+			}
+		} else if wasOver.Has(usage.Pod) {
+			recorder.Eventf(obj, corev1.EventTypeNormal, events.StoragePressureRelieved, "the used storage of %s pod %s is %d%%, below the warning threshold %d%%", component, usage.Pod, usage.usedPercent(), threshold)
+		}
+	}
+	if max == nil {
+		return previous
+	}
+	return &v1alpha1.StorageUsage{
+		MaxUsedPercent:    max.usedPercent(),
+		MaxUsedPod:        max.Pod,
+		PodsOverThreshold: over.List(),
+		LastUpdateTime:    metav1.Now(),
+	}
+}
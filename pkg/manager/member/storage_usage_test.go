@@ -0,0 +1,205 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/tikv/pd/pkg/typeutil"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+const gib = uint64(1) << 30
+
+func TestStoreStorageUsages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newStore := func(id uint64, capacity, available uint64) *pdapi.StoreInfo {
+		store := &pdapi.StoreInfo{Store: &pdapi.MetaStore{Store: &metapb.Store{Id: id}}}
+		if capacity > 0 {
+			store.Status = &pdapi.StoreStatus{Capacity: typeutil.ByteSize(capacity), Available: typeutil.ByteSize(available)}
+		}
+		return store
+	}
+	storesInfo := &pdapi.StoresInfo{Stores: []*pdapi.StoreInfo{
+		// 1TiB disk with 700GiB used
+		newStore(1, 1024*gib, 324*gib),
+		// 100GiB disk with 85GiB used
+		newStore(2, 100*gib, 15*gib),
+		// no stats reported
+		newStore(3, 0, 0),
+		// not a store of the cluster
+		newStore(4, 100*gib, 0),
+	}}
+	stores := map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "demo-tikv-0"},
+		"2": {ID: "2", PodName: "demo-tikv-1"},
+		"3": {ID: "3", PodName: "demo-tikv-2"},
+	}
+	g.Expect(storeStorageUsages(storesInfo, stores)).To(Equal([]PodStorageUsage{
+		{Pod: "demo-tikv-0", CapacityBytes: 1024 * gib, UsedBytes: 700 * gib},
+		{Pod: "demo-tikv-1", CapacityBytes: 100 * gib, UsedBytes: 85 * gib},
+		{Pod: "demo-tikv-2"},
+	}))
+}
+
+func TestSyncStorageUsage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	recorder := record.NewFakeRecorder(100)
+	tc := newTidbClusterForTiKV()
+	threshold := tc.StorageWarningThreshold()
+
+	// nothing is reported until the usage of any pod is known
+	g.Expect(SyncStorageUsage(recorder, tc, "tikv", nil, []PodStorageUsage{{Pod: "demo-tikv-0"}}, threshold)).To(BeNil())
+
+	// the pod with the highest percent rather than the most used bytes is the max one on the mixed-size disks
+	usages := []PodStorageUsage{
+		{Pod: "demo-tikv-0", CapacityBytes: 1024 * gib, UsedBytes: 700 * gib},
+		{Pod: "demo-tikv-1", CapacityBytes: 100 * gib, UsedBytes: 85 * gib},
+		{Pod: "demo-tikv-2", CapacityBytes: 2048 * gib, UsedBytes: 1600 * gib},
+	}
+	usage := SyncStorageUsage(recorder, tc, "tikv", nil, usages, threshold)
+	g.Expect(usage.MaxUsedPercent).To(Equal(int32(85)))
+	g.Expect(usage.MaxUsedPod).To(Equal("demo-tikv-1"))
+	g.Expect(usage.PodsOverThreshold).To(Equal([]string{"demo-tikv-1"}))
+	g.Expect(usage.LastUpdateTime.IsZero()).To(BeFalse())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.StoragePressure))
+	g.Expect(recorded[0]).To(ContainSubstring("the used storage of tikv pod demo-tikv-1 is 85%, above the warning threshold 80%"))
+
+	// no events are recorded while the pods stay on the same side of the threshold
+	usage = SyncStorageUsage(recorder, tc, "tikv", usage, usages, threshold)
+	g.Expect(usage.PodsOverThreshold).To(Equal([]string{"demo-tikv-1"}))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the pods whose usages are unknown keep their states
+	usages[1] = PodStorageUsage{Pod: "demo-tikv-1"}
+	usages[2].UsedBytes = 1700 * gib
+	usage = SyncStorageUsage(recorder, tc, "tikv", usage, usages, threshold)
+	g.Expect(usage.MaxUsedPercent).To(Equal(int32(83)))
+	g.Expect(usage.MaxUsedPod).To(Equal("demo-tikv-2"))
+	g.Expect(usage.PodsOverThreshold).To(Equal([]string{"demo-tikv-1", "demo-tikv-2"}))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring("the used storage of tikv pod demo-tikv-2 is 83%"))
+
+	// the previous usage is kept if none of the usages is known
+	previous := usage
+	usage = SyncStorageUsage(recorder, tc, "tikv", previous, []PodStorageUsage{{Pod: "demo-tikv-0"}}, threshold)
+	g.Expect(usage).To(Equal(previous))
+
+	// the pods falling below the threshold are relieved, and the pods scaled in are dropped silently
+	usages = []PodStorageUsage{
+		{Pod: "demo-tikv-0", CapacityBytes: 1024 * gib, UsedBytes: 700 * gib},
+		{Pod: "demo-tikv-2", CapacityBytes: 4096 * gib, UsedBytes: 1700 * gib},
+	}
+	usage = SyncStorageUsage(recorder, tc, "tikv", usage, usages, threshold)
+	g.Expect(usage.MaxUsedPercent).To(Equal(int32(68)))
+	g.Expect(usage.MaxUsedPod).To(Equal("demo-tikv-0"))
+	g.Expect(usage.PodsOverThreshold).To(BeEmpty())
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.StoragePressureRelieved))
+	g.Expect(recorded[0]).To(ContainSubstring("the used storage of tikv pod demo-tikv-2 is 41%, below the warning threshold 80%"))
+
+	// the threshold of the cluster is respected
+	tc.Spec.StorageWarningThreshold = pointer.Int32Ptr(60)
+	usage = SyncStorageUsage(recorder, tc, "tikv", usage, usages, tc.StorageWarningThreshold())
+	g.Expect(usage.PodsOverThreshold).To(Equal([]string{"demo-tikv-0"}))
+	g.Expect(collectEvents(recorder.Events)).To(HaveLen(1))
+}
+
+func TestVolumeStorageUsages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	selector := map[string]string{"app": "pd"}
+	set := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-pd", Namespace: corev1.NamespaceDefault},
+		Spec:       apps.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: selector}},
+	}
+	for i, node := range []string{"node-1", "node-1", "node-2", ""} {
+		g.Expect(podIndexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("demo-pd-%d", i), Namespace: corev1.NamespaceDefault, Labels: selector},
+			Spec:       corev1.PodSpec{NodeName: node},
+		})).To(Succeed())
+	}
+
+	summaries := map[string]string{
+		"node-1": `{"pods": [
+			{"podRef": {"name": "demo-pd-0", "namespace": "default"}, "volume": [
+				{"name": "pd", "capacityBytes": 10737418240, "usedBytes": 2147483648, "pvcRef": {"name": "pd-demo-pd-0", "namespace": "default"}},
+				{"name": "pd-log", "capacityBytes": 1073741824, "usedBytes": 536870912, "pvcRef": {"name": "pd-log-demo-pd-0", "namespace": "default"}},
+				{"name": "config", "capacityBytes": 1073741824, "usedBytes": 1073741824}
+			]},
+			{"podRef": {"name": "demo-pd-1", "namespace": "default"}, "volume": [
+				{"name": "pd", "capacityBytes": 107374182400, "usedBytes": 10737418240, "pvcRef": {"name": "pd-demo-pd-1", "namespace": "default"}}
+			]},
+			{"podRef": {"name": "demo-pd-0", "namespace": "other"}, "volume": [
+				{"name": "pd", "capacityBytes": 10, "usedBytes": 10, "pvcRef": {"name": "pd-demo-pd-0", "namespace": "other"}}
+			]}
+		]}`,
+	}
+	nodes := []string{}
+	original := getKubeletStatsSummary
+	defer func() {
+		getKubeletStatsSummary = original
+	}()
+	getKubeletStatsSummary = func(_ *controller.Dependencies, node string) (*kubeletStatsSummary, error) {
+		nodes = append(nodes, node)
+		data, ok := summaries[node]
+		if !ok {
+			return nil, fmt.Errorf("kubelet of node %s is unavailable", node)
+		}
+		summary := &kubeletStatsSummary{}
+		return summary, json.Unmarshal([]byte(data), summary)
+	}
+
+	usages := VolumeStorageUsages(deps, set)
+	g.Expect(usages).To(ConsistOf(
+		// the persistent volume with the highest used percent
+		PodStorageUsage{Pod: "demo-pd-0", CapacityBytes: gib, UsedBytes: gib / 2},
+		PodStorageUsage{Pod: "demo-pd-1", CapacityBytes: 100 * gib, UsedBytes: 10 * gib},
+		// the stats fail to be collected
+		PodStorageUsage{Pod: "demo-pd-2"},
+		// not scheduled
+		PodStorageUsage{Pod: "demo-pd-3"},
+	))
+	// the stats summary of a node is only fetched once
+	g.Expect(nodes).To(ConsistOf("node-1", "node-2"))
+
+	// the previous usage is kept within the collect interval
+	tc := newTidbClusterForPD()
+	usage := SyncVolumeStorageUsage(deps, tc, "pd", nil, set, tc.StorageWarningThreshold())
+	g.Expect(usage.MaxUsedPercent).To(Equal(int32(50)))
+	g.Expect(usage.MaxUsedPod).To(Equal("demo-pd-0"))
+	nodes = nil
+	g.Expect(SyncVolumeStorageUsage(deps, tc, "pd", usage, set, tc.StorageWarningThreshold())).To(BeIdenticalTo(usage))
+	g.Expect(nodes).To(BeEmpty())
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+const defaultForceDeleteStuckPodsThreshold = 5 * time.Minute
+
+// syncTiKVStuckPods force deletes the TiKV pods stuck in Terminating on the unreachable nodes if
+// spec.tikv.forceDeleteStuckPods is set. Besides the node, all the stores of a pod must be Down in PD and send
+// no heartbeat within the threshold, the pods are left as they are if the stores can not be got from PD.
+func syncTiKVStuckPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	spec := tc.Spec.TiKV.ForceDeleteStuckPods
+	if spec == nil {
+		return nil
+	}
+	// the stale stores cached on the failure of PD are not trusted either
+	storesInfo, err := controller.GetPDClient(deps.PDControl, tc).GetStores()
+	if err != nil {
+		return fmt.Errorf("syncTiKVStuckPods: failed to get the stores of cluster %s/%s, error: %v", tc.GetNamespace(), tc.GetName(), err)
+	}
+	resolver, err := newTiKVStoreResolver(deps.PodLister, tc)
+	if err != nil {
+		return err
+	}
+	podStores := map[string][]*pdapi.StoreInfo{}
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil {
+			continue
+		}
+		if podName, ok := resolver.podName(store.Store.Address); ok {
+			podStores[podName] = append(podStores[podName], store)
+		}
+	}
+	return forceDeleteStuckPods(deps, tc, v1alpha1.TiKVMemberType, spec, func(pod *corev1.Pod, threshold time.Duration, now time.Time) string {
+		return tikvStoresUnsafeReason(podStores[pod.Name], threshold, now)
+	})
+}
+
+// syncTiDBStuckPods force deletes the TiDB pods stuck in Terminating on the unreachable nodes if
+// spec.tidb.forceDeleteStuckPods is set
+func syncTiDBStuckPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	spec := tc.Spec.TiDB.ForceDeleteStuckPods
+	if spec == nil {
+		return nil
+	}
+	return forceDeleteStuckPods(deps, tc, v1alpha1.TiDBMemberType, spec, nil)
+}
+
+// forceDeleteStuckPods force deletes the pods of the component which are safe to be force deleted by
+// stuckPodUnsafeReason, and by storesUnsafeReason if it is not nil
+func forceDeleteStuckPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, spec *v1alpha1.ForceDeleteStuckPods,
+	storesUnsafeReason func(pod *corev1.Pod, threshold time.Duration, now time.Time) string) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if deps.NodeLister == nil {
+		klog.V(4).Infof("tidbcluster: [%s/%s] node lister is unavailable, skip force deleting the stuck pods of %s", ns, tcName, memberType)
+		return nil
+	}
+	threshold := defaultForceDeleteStuckPodsThreshold
+	if spec.Threshold != nil {
+		if d, err := time.ParseDuration(*spec.Threshold); err == nil && d > 0 {
+			threshold = d
+		}
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).Component(memberType.String()).Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("forceDeleteStuckPods: failed to list pods for cluster %s/%s, selector %s, error: %v", ns, tcName, selector, err)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	var errs []error
+	now := time.Now()
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil {
+			continue
+		}
+		var node *corev1.Node
+		if pod.Spec.NodeName != "" {
+			node, err = deps.NodeLister.Get(pod.Spec.NodeName)
+			if errors.IsNotFound(err) {
+				node = nil
+			} else if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		reason := stuckPodUnsafeReason(pod, node, threshold, now)
+		if reason == "" && storesUnsafeReason != nil {
+			reason = storesUnsafeReason(pod, threshold, now)
+		}
+		if reason != "" {
+			klog.V(4).Infof("tidbcluster: [%s/%s] %s pod %s is Terminating but not force deleted: %s", ns, tcName, memberType, pod.Name, reason)
+			continue
+		}
+
+		msg := fmt.Sprintf("force delete %s pod %s stuck in Terminating since %s on the unreachable node %s", memberType, pod.Name, pod.DeletionTimestamp.Format(time.RFC3339), pod.Spec.NodeName)
+		klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+		if err := deps.PodControl.ForceDeletePod(tc, pod); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+		deps.Recorder.Event(tc, corev1.EventTypeWarning, events.StuckPodForceDeleted, msg)
+	}
+	return errorutils.NewAggregate(errs)
+}
+
+// stuckPodUnsafeReason returns why the pod is not safe to be force deleted, it is empty only if the pod has been
+// Terminating for longer than the threshold past its graceful termination deadline and its node is known to be
+// not Ready. The kubelet of a Ready node is able to terminate the pod by itself, force deleting the pod then may
+// run two pods of the same identity at a time.
+func stuckPodUnsafeReason(pod *corev1.Pod, node *corev1.Node, threshold time.Duration, now time.Time) string {
+	if pod.DeletionTimestamp == nil {
+		return "it is not Terminating"
+	}
+	if stuck := now.Sub(pod.DeletionTimestamp.Time); stuck <= threshold {
+		return fmt.Sprintf("it has been Terminating for %s past its deadline, not longer than %s", stuck.Round(time.Second), threshold)
+	}
+	if pod.Spec.NodeName == "" {
+		return "it is not scheduled"
+	}
+	if node == nil {
+		// the pods on the deleted nodes are deleted by the pod garbage collector of Kubernetes
+		return fmt.Sprintf("its node %s is not found", pod.Spec.NodeName)
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type != corev1.NodeReady {
+			continue
+		}
+		if cond.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("its node %s is Ready", node.Name)
+		}
+		return ""
+	}
+	return fmt.Sprintf("its node %s has no Ready condition", node.Name)
+}
+
+// tikvStoresUnsafeReason returns why the pod of the stores is not safe to be force deleted, it is empty only if
+// the pod has stores, all of which are Down in PD and send no heartbeat within the threshold
+func tikvStoresUnsafeReason(stores []*pdapi.StoreInfo, threshold time.Duration, now time.Time) string {
+	if len(stores) == 0 {
+		return "its store is not found in PD"
+	}
+	for _, store := range stores {
+		id := store.Store.GetId()
+		if store.Store.StateName != v1alpha1.TiKVStateDown {
+			return fmt.Sprintf("its store %d is %s in PD", id, store.Store.StateName)
+		}
+		if store.Status == nil || store.Status.LastHeartbeatTS.IsZero() {
+			return fmt.Sprintf("the last heartbeat of its store %d is unknown", id)
+		}
+		if now.Sub(store.Status.LastHeartbeatTS) <= threshold {
+			return fmt.Sprintf("its store %d sent a heartbeat at %s", id, store.Status.LastHeartbeatTS.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
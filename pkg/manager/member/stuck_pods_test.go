@@ -0,0 +1,290 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestStuckPodUnsafeReason(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	threshold := 5 * time.Minute
+	newNode := func(conditions ...corev1.NodeCondition) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{Conditions: conditions},
+		}
+	}
+	ready := func(status corev1.ConditionStatus) corev1.NodeCondition {
+		return corev1.NodeCondition{Type: corev1.NodeReady, Status: status}
+	}
+	newPod := func(terminating time.Duration, nodeName string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tikv-0"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+		if terminating > 0 {
+			deletion := metav1.NewTime(now.Add(-terminating))
+			pod.DeletionTimestamp = &deletion
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		node   *corev1.Node
+		reason string
+	}{
+		{
+			name:   "not terminating",
+			pod:    newPod(0, "node-1"),
+			node:   newNode(ready(corev1.ConditionUnknown)),
+			reason: "it is not Terminating",
+		},
+		{
+			name:   "terminating within the threshold",
+			pod:    newPod(5*time.Minute, "node-1"),
+			node:   newNode(ready(corev1.ConditionUnknown)),
+			reason: "it has been Terminating for 5m0s past its deadline, not longer than 5m0s",
+		},
+		{
+			name:   "not scheduled",
+			pod:    newPod(10*time.Minute, ""),
+			reason: "it is not scheduled",
+		},
+		{
+			name:   "node not found",
+			pod:    newPod(10*time.Minute, "node-1"),
+			reason: "its node node-1 is not found",
+		},
+		{
+			name:   "node ready",
+			pod:    newPod(10*time.Minute, "node-1"),
+			node:   newNode(corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionTrue}, ready(corev1.ConditionTrue)),
+			reason: "its node node-1 is Ready",
+		},
+		{
+			name:   "node without ready condition",
+			pod:    newPod(10*time.Minute, "node-1"),
+			node:   newNode(),
+			reason: "its node node-1 has no Ready condition",
+		},
+		{
+			name:   "node unreachable",
+			pod:    newPod(10*time.Minute, "node-1"),
+			node:   newNode(ready(corev1.ConditionUnknown)),
+			reason: "",
+		},
+		{
+			name:   "node not ready",
+			pod:    newPod(10*time.Minute, "node-1"),
+			node:   newNode(ready(corev1.ConditionFalse)),
+			reason: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(stuckPodUnsafeReason(tt.pod, tt.node, threshold, now)).To(Equal(tt.reason))
+		})
+	}
+}
+
+func TestTiKVStoresUnsafeReason(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	threshold := 5 * time.Minute
+	newStore := func(id uint64, state string, heartbeat time.Time) *pdapi.StoreInfo {
+		return &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{Id: id}, StateName: state},
+			Status: &pdapi.StoreStatus{LastHeartbeatTS: heartbeat},
+		}
+	}
+	down := newStore(1, v1alpha1.TiKVStateDown, now.Add(-time.Hour))
+
+	tests := []struct {
+		name   string
+		stores []*pdapi.StoreInfo
+		reason string
+	}{
+		{
+			name:   "no store",
+			reason: "its store is not found in PD",
+		},
+		{
+			name:   "store up",
+			stores: []*pdapi.StoreInfo{newStore(1, v1alpha1.TiKVStateUp, now.Add(-time.Hour))},
+			reason: "its store 1 is Up in PD",
+		},
+		{
+			name:   "store disconnected",
+			stores: []*pdapi.StoreInfo{newStore(1, v1alpha1.TiKVStateDisconnected, now.Add(-time.Hour))},
+			reason: "its store 1 is Disconnected in PD",
+		},
+		{
+			name:   "store down but sending heartbeats",
+			stores: []*pdapi.StoreInfo{newStore(1, v1alpha1.TiKVStateDown, now.Add(-time.Minute))},
+			reason: "its store 1 sent a heartbeat at 2022-06-01T11:59:00Z",
+		},
+		{
+			name:   "unknown heartbeat",
+			stores: []*pdapi.StoreInfo{newStore(1, v1alpha1.TiKVStateDown, time.Time{})},
+			reason: "the last heartbeat of its store 1 is unknown",
+		},
+		{
+			name:   "one of the stores up",
+			stores: []*pdapi.StoreInfo{down, newStore(2, v1alpha1.TiKVStateUp, now)},
+			reason: "its store 2 is Up in PD",
+		},
+		{
+			name:   "store down",
+			stores: []*pdapi.StoreInfo{down},
+			reason: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(tikvStoresUnsafeReason(tt.stores, threshold, now)).To(Equal(tt.reason))
+		})
+	}
+}
+
+func TestSyncTiKVStuckPods(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	nodeIndexer := deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	tc := newTidbClusterForTiKV()
+
+	for name, status := range map[string]corev1.ConditionStatus{"node-1": corev1.ConditionTrue, "node-2": corev1.ConditionUnknown} {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+		}
+		g.Expect(nodeIndexer.Add(node)).To(Succeed())
+	}
+	deletion := metav1.NewTime(time.Now().Add(-time.Hour))
+	for name, node := range map[string]string{"test-tikv-0": "node-1", "test-tikv-1": "node-2", "test-tikv-2": "node-2"} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         tc.Namespace,
+				Labels:            label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+				DeletionTimestamp: &deletion,
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+		}
+		g.Expect(podIndexer.Add(pod)).To(Succeed())
+	}
+	states := map[uint64]string{1: v1alpha1.TiKVStateDown, 2: v1alpha1.TiKVStateDown, 3: v1alpha1.TiKVStateUp}
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		stores := &pdapi.StoresInfo{}
+		for id, state := range states {
+			stores.Stores = append(stores.Stores, &pdapi.StoreInfo{
+				Store: &pdapi.MetaStore{
+					Store:     &metapb.Store{Id: id, Address: fmt.Sprintf("test-tikv-%d.test-tikv-peer.default.svc:20160", id-1)},
+					StateName: state,
+				},
+				Status: &pdapi.StoreStatus{LastHeartbeatTS: time.Now().Add(-time.Hour)},
+			})
+		}
+		return stores, nil
+	})
+	podNames := func() []string {
+		var names []string
+		for _, obj := range podIndexer.List() {
+			names = append(names, obj.(*corev1.Pod).Name)
+		}
+		return names
+	}
+
+	// nothing is force deleted unless enabled
+	g.Expect(syncTiKVStuckPods(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(HaveLen(3))
+
+	// only the pod on the unreachable node with the store down is force deleted
+	tc.Spec.TiKV.ForceDeleteStuckPods = &v1alpha1.ForceDeleteStuckPods{}
+	g.Expect(syncTiKVStuckPods(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(ConsistOf("test-tikv-0", "test-tikv-2"))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.StuckPodForceDeleted))
+	g.Expect(recorded[0]).To(ContainSubstring("test-tikv-1"))
+
+	// the pods are left as they are if the stores can not be got from PD
+	states[3] = v1alpha1.TiKVStateDown
+	tc.Spec.TiKV.ForceDeleteStuckPods.Threshold = pointer.StringPtr("2h")
+	g.Expect(syncTiKVStuckPods(deps, tc)).To(Succeed())
+	g.Expect(podNames()).To(HaveLen(2))
+	tc.Spec.TiKV.ForceDeleteStuckPods.Threshold = nil
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		return nil, fmt.Errorf("pd is unavailable")
+	})
+	g.Expect(syncTiKVStuckPods(deps, tc)).NotTo(Succeed())
+	g.Expect(podNames()).To(HaveLen(2))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+}
+
+func TestSyncTiDBStuckPods(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	nodeIndexer := deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.ForceDeleteStuckPods = &v1alpha1.ForceDeleteStuckPods{Threshold: pointer.StringPtr("10m")}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}},
+	}
+	g.Expect(nodeIndexer.Add(node)).To(Succeed())
+	deletion := metav1.NewTime(time.Now().Add(-5 * time.Minute))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-tidb-0",
+			Namespace:         tc.Namespace,
+			Labels:            label.New().Instance(tc.GetInstanceName()).TiDB().Labels(),
+			DeletionTimestamp: &deletion,
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+	}
+	g.Expect(podIndexer.Add(pod)).To(Succeed())
+
+	// the pod is not force deleted within the threshold
+	g.Expect(syncTiDBStuckPods(deps, tc)).To(Succeed())
+	g.Expect(podIndexer.List()).To(HaveLen(1))
+
+	tc.Spec.TiDB.ForceDeleteStuckPods.Threshold = pointer.StringPtr("1m")
+	g.Expect(syncTiDBStuckPods(deps, tc)).To(Succeed())
+	g.Expect(podIndexer.List()).To(BeEmpty())
+}
@@ -0,0 +1,522 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// upgradeCurrentRevision and upgradeUpdateRevision are the revisions of the StatefulSets built by
+	// TCBuilder, the pods on upgradeUpdateRevision are upgraded
+	upgradeCurrentRevision = "1"
+	upgradeUpdateRevision  = "2"
+)
+
+// TCBuilder builds the TidbCluster fixtures of the upgrader tests, e.g. a cluster with the PD leader
+// on the last PD pod and the last TiKV pod upgraded:
+//
+//	b := NewTCBuilder().WithPDLeader(2).WithTiKVPhase(v1alpha1.UpgradePhase).
+//		WithPodRevisions(v1alpha1.TiKVMemberType, "1", "1", "2")
+//	tc := b.Build()
+//	pdClient := b.Wire(deps)
+//
+// The status of the cluster, the StatefulSets, the pods and the fake PD client built are consistent
+// with each other. By default PD, TiKV and TiDB have 3 healthy replicas on upgradeCurrentRevision
+// and the PD leader is the first PD member, TiFlash and TiCDC are deployed by WithReplicas.
+type TCBuilder struct {
+	tc        *v1alpha1.TidbCluster
+	pdLeader  int32
+	revisions map[v1alpha1.MemberType][]string
+	unready   map[v1alpha1.MemberType]sets.Int32
+	unhealthy map[v1alpha1.MemberType]sets.Int32
+}
+
+// NewTCBuilder returns a TCBuilder of the cluster named upgradeTcName
+func NewTCBuilder() *TCBuilder {
+	tc := &v1alpha1.TidbCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "TidbCluster",
+			APIVersion: "pingcap.com/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      upgradeTcName,
+			Namespace: corev1.NamespaceDefault,
+			UID:       types.UID(upgradeTcName),
+			Labels:    label.New().Instance(upgradeInstanceName),
+		},
+		Spec: v1alpha1.TidbClusterSpec{
+			PD: &v1alpha1.PDSpec{
+				ComponentSpec: v1alpha1.ComponentSpec{Image: "pd-test-image"},
+				Replicas:      3,
+			},
+			TiKV: &v1alpha1.TiKVSpec{
+				ComponentSpec: v1alpha1.ComponentSpec{Image: "tikv-test-image"},
+				Replicas:      3,
+			},
+			TiDB: &v1alpha1.TiDBSpec{
+				ComponentSpec: v1alpha1.ComponentSpec{Image: "tidb-test-image"},
+				Replicas:      3,
+			},
+		},
+		Status: v1alpha1.TidbClusterStatus{
+			PD:   v1alpha1.PDStatus{Synced: true, Phase: v1alpha1.NormalPhase},
+			TiKV: v1alpha1.TiKVStatus{Synced: true, BootStrapped: true, Phase: v1alpha1.NormalPhase},
+			TiDB: v1alpha1.TiDBStatus{Phase: v1alpha1.NormalPhase},
+		},
+	}
+	b := &TCBuilder{
+		tc:        tc,
+		revisions: map[v1alpha1.MemberType][]string{},
+		unready:   map[v1alpha1.MemberType]sets.Int32{},
+		unhealthy: map[v1alpha1.MemberType]sets.Int32{},
+	}
+	for _, memberType := range []v1alpha1.MemberType{v1alpha1.PDMemberType, v1alpha1.TiKVMemberType, v1alpha1.TiDBMemberType} {
+		b.WithReplicas(memberType, 3)
+	}
+	return b
+}
+
+// WithReplicas sets the replicas of the component, all the pods are on upgradeCurrentRevision
+func (b *TCBuilder) WithReplicas(memberType v1alpha1.MemberType, replicas int32) *TCBuilder {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		b.tc.Spec.PD.Replicas = replicas
+	case v1alpha1.TiKVMemberType:
+		b.tc.Spec.TiKV.Replicas = replicas
+	case v1alpha1.TiDBMemberType:
+		b.tc.Spec.TiDB.Replicas = replicas
+	case v1alpha1.TiFlashMemberType:
+		if b.tc.Spec.TiFlash == nil {
+			b.tc.Spec.TiFlash = &v1alpha1.TiFlashSpec{ComponentSpec: v1alpha1.ComponentSpec{Image: "tiflash-test-image"}}
+			b.tc.Status.TiFlash = v1alpha1.TiFlashStatus{Synced: true, Phase: v1alpha1.NormalPhase}
+		}
+		b.tc.Spec.TiFlash.Replicas = replicas
+	case v1alpha1.TiCDCMemberType:
+		if b.tc.Spec.TiCDC == nil {
+			b.tc.Spec.TiCDC = &v1alpha1.TiCDCSpec{ComponentSpec: v1alpha1.ComponentSpec{Image: "ticdc-test-image"}}
+			b.tc.Status.TiCDC = v1alpha1.TiCDCStatus{Synced: true, Phase: v1alpha1.NormalPhase}
+		}
+		b.tc.Spec.TiCDC.Replicas = replicas
+	default:
+		panic(fmt.Sprintf("unsupported member type %s", memberType))
+	}
+	revisions := make([]string, replicas)
+	for i := range revisions {
+		revisions[i] = upgradeCurrentRevision
+	}
+	b.revisions[memberType] = revisions
+	return b
+}
+
+// WithPDPhase sets the phase of PD
+func (b *TCBuilder) WithPDPhase(phase v1alpha1.MemberPhase) *TCBuilder {
+	b.tc.Status.PD.Phase = phase
+	return b
+}
+
+// WithTiKVPhase sets the phase of TiKV
+func (b *TCBuilder) WithTiKVPhase(phase v1alpha1.MemberPhase) *TCBuilder {
+	b.tc.Status.TiKV.Phase = phase
+	return b
+}
+
+// WithTiDBPhase sets the phase of TiDB
+func (b *TCBuilder) WithTiDBPhase(phase v1alpha1.MemberPhase) *TCBuilder {
+	b.tc.Status.TiDB.Phase = phase
+	return b
+}
+
+// WithTiFlashPhase sets the phase of TiFlash
+func (b *TCBuilder) WithTiFlashPhase(phase v1alpha1.MemberPhase) *TCBuilder {
+	b.tc.Status.TiFlash.Phase = phase
+	return b
+}
+
+// WithTiCDCPhase sets the phase of TiCDC
+func (b *TCBuilder) WithTiCDCPhase(phase v1alpha1.MemberPhase) *TCBuilder {
+	b.tc.Status.TiCDC.Phase = phase
+	return b
+}
+
+// WithPDLeader sets the PD member of the ordinal as the leader
+func (b *TCBuilder) WithPDLeader(ordinal int32) *TCBuilder {
+	b.pdLeader = ordinal
+	return b
+}
+
+// WithPodRevisions sets the controller revision of each pod of the component in the order of the
+// ordinals, the pods on upgradeUpdateRevision are upgraded
+func (b *TCBuilder) WithPodRevisions(memberType v1alpha1.MemberType, revisions ...string) *TCBuilder {
+	if int32(len(revisions)) != b.replicas(memberType) {
+		panic(fmt.Sprintf("%d revisions for %d %s replicas", len(revisions), b.replicas(memberType), memberType))
+	}
+	b.revisions[memberType] = revisions
+	return b
+}
+
+// WithUpgradedPods moves the pods of the component at the ordinals to upgradeUpdateRevision
+func (b *TCBuilder) WithUpgradedPods(memberType v1alpha1.MemberType, ordinals ...int32) *TCBuilder {
+	for _, ordinal := range ordinals {
+		b.revisions[memberType][ordinal] = upgradeUpdateRevision
+	}
+	return b
+}
+
+// WithUnreadyPods makes the pods of the component at the ordinals not ready, their members stay healthy
+func (b *TCBuilder) WithUnreadyPods(memberType v1alpha1.MemberType, ordinals ...int32) *TCBuilder {
+	b.unready[memberType] = sets.NewInt32(ordinals...)
+	return b
+}
+
+// WithUnhealthyMembers makes the members of the component at the ordinals unhealthy, the TiKV stores
+// are Down, their pods stay ready
+func (b *TCBuilder) WithUnhealthyMembers(memberType v1alpha1.MemberType, ordinals ...int32) *TCBuilder {
+	b.unhealthy[memberType] = sets.NewInt32(ordinals...)
+	return b
+}
+
+// Build returns the TidbCluster, a new copy is returned on each call
+func (b *TCBuilder) Build() *v1alpha1.TidbCluster {
+	tc := b.tc.DeepCopy()
+	tcName := tc.GetName()
+
+	tc.Status.PD.StatefulSet = b.statefulSetStatus(v1alpha1.PDMemberType)
+	tc.Status.PD.Members = map[string]v1alpha1.PDMember{}
+	for i := int32(0); i < b.replicas(v1alpha1.PDMemberType); i++ {
+		name := PdPodName(tcName, i)
+		tc.Status.PD.Members[name] = v1alpha1.PDMember{Name: name, ID: strconv.Itoa(int(i) + 1), Health: b.healthy(v1alpha1.PDMemberType, i)}
+	}
+	tc.Status.PD.Leader = tc.Status.PD.Members[PdPodName(tcName, b.pdLeader)]
+
+	tc.Status.TiKV.StatefulSet = b.statefulSetStatus(v1alpha1.TiKVMemberType)
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+	for i := int32(0); i < b.replicas(v1alpha1.TiKVMemberType); i++ {
+		id := strconv.Itoa(int(i) + 1)
+		tc.Status.TiKV.Stores[id] = v1alpha1.TiKVStore{
+			ID:          id,
+			PodName:     TikvPodName(tcName, i),
+			LeaderCount: 10,
+			State:       b.storeState(i),
+		}
+	}
+
+	tc.Status.TiDB.StatefulSet = b.statefulSetStatus(v1alpha1.TiDBMemberType)
+	tc.Status.TiDB.Members = map[string]v1alpha1.TiDBMember{}
+	for i := int32(0); i < b.replicas(v1alpha1.TiDBMemberType); i++ {
+		name := tidbPodName(tcName, i)
+		tc.Status.TiDB.Members[name] = v1alpha1.TiDBMember{Name: name, Health: b.healthy(v1alpha1.TiDBMemberType, i)}
+	}
+
+	if tc.Spec.TiFlash != nil {
+		tc.Status.TiFlash.StatefulSet = b.statefulSetStatus(v1alpha1.TiFlashMemberType)
+		tc.Status.TiFlash.Stores = map[string]v1alpha1.TiKVStore{}
+		for i := int32(0); i < b.replicas(v1alpha1.TiFlashMemberType); i++ {
+			id := b.TiFlashStoreID(i)
+			tc.Status.TiFlash.Stores[id] = v1alpha1.TiKVStore{
+				ID:          id,
+				PodName:     TiFlashPodName(tcName, i),
+				LeaderCount: 10,
+				State:       v1alpha1.TiKVStateUp,
+			}
+		}
+	}
+
+	if tc.Spec.TiCDC != nil {
+		tc.Status.TiCDC.StatefulSet = b.statefulSetStatus(v1alpha1.TiCDCMemberType)
+		tc.Status.TiCDC.Captures = map[string]v1alpha1.TiCDCCapture{}
+		for i := int32(0); i < b.replicas(v1alpha1.TiCDCMemberType); i++ {
+			name := ticdcPodName(tcName, i)
+			tc.Status.TiCDC.Captures[name] = v1alpha1.TiCDCCapture{PodName: name}
+		}
+	}
+	return tc
+}
+
+// TiFlashStoreID returns the store ID of the TiFlash pod of the ordinal, the IDs of the TiFlash stores
+// follow the ones of the TiKV stores
+func (b *TCBuilder) TiFlashStoreID(ordinal int32) string {
+	return strconv.Itoa(int(b.replicas(v1alpha1.TiKVMemberType) + ordinal + 1))
+}
+
+// StatefulSet returns the StatefulSet of the component before the upgrade, its template is the last
+// applied one and the partition of its rolling update is the replicas
+func (b *TCBuilder) StatefulSet(memberType v1alpha1.MemberType) *apps.StatefulSet {
+	replicas := b.replicas(memberType)
+	set := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", b.tc.GetName(), memberType),
+			Namespace: b.tc.GetNamespace(),
+			Labels:    b.labels(memberType),
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: pointer.Int32Ptr(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  memberType.String(),
+							Image: fmt.Sprintf("%s-test-image", memberType),
+						},
+					},
+				},
+			},
+			UpdateStrategy: apps.StatefulSetUpdateStrategy{
+				Type: apps.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{
+					Partition: pointer.Int32Ptr(replicas),
+				},
+			},
+		},
+		Status: *b.statefulSetStatus(memberType),
+	}
+	if err := mngerutils.SetStatefulSetLastAppliedConfigAnnotation(set); err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Pods returns the pods of the component labeled with their revisions, they are ready unless set by
+// WithUnreadyPods
+func (b *TCBuilder) Pods(memberType v1alpha1.MemberType) []*corev1.Pod {
+	var pods []*corev1.Pod
+	for i, revision := range b.revisions[memberType] {
+		l := b.labels(memberType)
+		l[apps.ControllerRevisionHashLabelKey] = revision
+		switch memberType {
+		case v1alpha1.TiKVMemberType:
+			l[label.StoreIDLabelKey] = strconv.Itoa(i + 1)
+		case v1alpha1.TiFlashMemberType:
+			l[label.StoreIDLabelKey] = b.TiFlashStoreID(int32(i))
+		}
+		ready := corev1.ConditionTrue
+		if b.unready[memberType].Has(int32(i)) {
+			ready = corev1.ConditionFalse
+		}
+		pods = append(pods, &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%s-%d", b.tc.GetName(), memberType, i),
+				Namespace: b.tc.GetNamespace(),
+				Labels:    l,
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{
+						Type:   corev1.PodReady,
+						Status: ready,
+					},
+				},
+			},
+		})
+	}
+	return pods
+}
+
+// Wire adds the pods of all the components to the PodLister of the fake dependencies and returns the
+// fake PD client of the cluster serving the leader, the members and the stores of the cluster built.
+// More reactions can be added to the returned client.
+func (b *TCBuilder) Wire(deps *controller.Dependencies) *pdapi.FakePDClient {
+	tc := b.Build()
+	indexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for _, memberType := range b.memberTypes() {
+		for _, pod := range b.Pods(memberType) {
+			indexer.Add(pod)
+		}
+	}
+
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetPDLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdpb.Member{Name: tc.Status.PD.Leader.Name}, nil
+	})
+	pdClient.AddReaction(pdapi.GetHealthActionType, func(action *pdapi.Action) (interface{}, error) {
+		health := &pdapi.HealthInfo{}
+		for i := int32(0); i < b.replicas(v1alpha1.PDMemberType); i++ {
+			health.Healths = append(health.Healths, pdapi.MemberHealth{Name: PdPodName(tc.GetName(), i), MemberID: uint64(i) + 1, Health: b.healthy(v1alpha1.PDMemberType, i)})
+		}
+		return health, nil
+	})
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		stores := &pdapi.StoresInfo{}
+		for i := int32(0); i < b.replicas(v1alpha1.TiKVMemberType); i++ {
+			stores.Stores = append(stores.Stores, &pdapi.StoreInfo{
+				Store: &pdapi.MetaStore{
+					Store: &metapb.Store{
+						Id:      uint64(i) + 1,
+						Address: fmt.Sprintf("%s.%s-tikv-peer.%s.svc:20160", TikvPodName(tc.GetName(), i), tc.GetName(), tc.GetNamespace()),
+					},
+					StateName: b.storeState(i),
+				},
+				Status: &pdapi.StoreStatus{LeaderCount: 10},
+			})
+		}
+		stores.Count = len(stores.Stores)
+		return stores, nil
+	})
+	return pdClient
+}
+
+// memberTypes returns the components deployed
+func (b *TCBuilder) memberTypes() []v1alpha1.MemberType {
+	memberTypes := []v1alpha1.MemberType{v1alpha1.PDMemberType, v1alpha1.TiKVMemberType, v1alpha1.TiDBMemberType}
+	if b.tc.Spec.TiFlash != nil {
+		memberTypes = append(memberTypes, v1alpha1.TiFlashMemberType)
+	}
+	if b.tc.Spec.TiCDC != nil {
+		memberTypes = append(memberTypes, v1alpha1.TiCDCMemberType)
+	}
+	return memberTypes
+}
+
+func (b *TCBuilder) replicas(memberType v1alpha1.MemberType) int32 {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return b.tc.Spec.PD.Replicas
+	case v1alpha1.TiKVMemberType:
+		return b.tc.Spec.TiKV.Replicas
+	case v1alpha1.TiDBMemberType:
+		return b.tc.Spec.TiDB.Replicas
+	case v1alpha1.TiFlashMemberType:
+		return b.tc.Spec.TiFlash.Replicas
+	case v1alpha1.TiCDCMemberType:
+		return b.tc.Spec.TiCDC.Replicas
+	}
+	panic(fmt.Sprintf("unsupported member type %s", memberType))
+}
+
+func (b *TCBuilder) healthy(memberType v1alpha1.MemberType, ordinal int32) bool {
+	return !b.unhealthy[memberType].Has(ordinal)
+}
+
+func (b *TCBuilder) storeState(ordinal int32) string {
+	if b.healthy(v1alpha1.TiKVMemberType, ordinal) {
+		return v1alpha1.TiKVStateUp
+	}
+	return v1alpha1.TiKVStateDown
+}
+
+func (b *TCBuilder) labels(memberType v1alpha1.MemberType) label.Label {
+	return label.New().Instance(upgradeInstanceName).Component(memberType.String())
+}
+
+func (b *TCBuilder) statefulSetStatus(memberType v1alpha1.MemberType) *apps.StatefulSetStatus {
+	status := &apps.StatefulSetStatus{
+		Replicas:        b.replicas(memberType),
+		ReadyReplicas:   b.replicas(memberType) - int32(b.unready[memberType].Len()),
+		CurrentRevision: upgradeCurrentRevision,
+		UpdateRevision:  upgradeUpdateRevision,
+	}
+	for _, revision := range b.revisions[memberType] {
+		switch revision {
+		case upgradeCurrentRevision:
+			status.CurrentReplicas++
+		case upgradeUpdateRevision:
+			status.UpdatedReplicas++
+		}
+	}
+	return status
+}
+
+func TestTCBuilder(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	b := NewTCBuilder().
+		WithReplicas(v1alpha1.PDMemberType, 5).
+		WithPDLeader(4).
+		WithTiKVPhase(v1alpha1.UpgradePhase).
+		WithPodRevisions(v1alpha1.TiKVMemberType, upgradeCurrentRevision, upgradeCurrentRevision, upgradeUpdateRevision)
+	tc := b.Build()
+	g.Expect(tc.Status.TiKV.Phase).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(tc.Status.PD.Members).To(HaveLen(5))
+	g.Expect(tc.Status.PD.Leader.Name).To(Equal(PdPodName(upgradeTcName, 4)))
+	g.Expect(tc.Status.TiKV.StatefulSet.CurrentReplicas).To(Equal(int32(2)))
+	g.Expect(tc.Status.TiKV.StatefulSet.UpdatedReplicas).To(Equal(int32(1)))
+
+	set := b.StatefulSet(v1alpha1.TiKVMemberType)
+	g.Expect(set.Name).To(Equal(controller.TiKVMemberName(upgradeTcName)))
+	g.Expect(set.Status).To(Equal(*tc.Status.TiKV.StatefulSet))
+
+	deps := controller.NewFakeDependencies()
+	pdClient := b.Wire(deps)
+	pod, err := deps.PodLister.Pods(tc.Namespace).Get(TikvPodName(upgradeTcName, 2))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pod.Labels[apps.ControllerRevisionHashLabelKey]).To(Equal(upgradeUpdateRevision))
+	g.Expect(pod.Labels[label.StoreIDLabelKey]).To(Equal("3"))
+	leader, err := pdClient.GetPDLeader()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(leader.Name).To(Equal(tc.Status.PD.Leader.Name))
+	stores, err := pdClient.GetStores()
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, store := range stores.Stores {
+		g.Expect(getTiKVStore(store)).To(Equal(&v1alpha1.TiKVStore{
+			ID:          fmt.Sprintf("%d", store.Store.GetId()),
+			PodName:     tc.Status.TiKV.Stores[fmt.Sprintf("%d", store.Store.GetId())].PodName,
+			IP:          fmt.Sprintf("%s.%s-tikv-peer.%s.svc", tc.Status.TiKV.Stores[fmt.Sprintf("%d", store.Store.GetId())].PodName, upgradeTcName, tc.Namespace),
+			LeaderCount: 10,
+			State:       v1alpha1.TiKVStateUp,
+		}))
+	}
+
+	b = NewTCBuilder().
+		WithUpgradedPods(v1alpha1.TiDBMemberType, 2).
+		WithUnreadyPods(v1alpha1.TiDBMemberType, 1).
+		WithUnhealthyMembers(v1alpha1.TiDBMemberType, 0).
+		WithUnhealthyMembers(v1alpha1.TiKVMemberType, 0)
+	tc = b.Build()
+	g.Expect(tc.Status.TiDB.StatefulSet.UpdatedReplicas).To(Equal(int32(1)))
+	g.Expect(tc.Status.TiDB.StatefulSet.ReadyReplicas).To(Equal(int32(2)))
+	g.Expect(tc.Status.TiDB.Members[tidbPodName(upgradeTcName, 0)].Health).To(BeFalse())
+	g.Expect(tc.Status.TiDB.Members[tidbPodName(upgradeTcName, 1)].Health).To(BeTrue())
+	g.Expect(tc.Status.TiKV.Stores["1"].State).To(Equal(v1alpha1.TiKVStateDown))
+	pods := b.Pods(v1alpha1.TiDBMemberType)
+	g.Expect(pods[2].Labels[apps.ControllerRevisionHashLabelKey]).To(Equal(upgradeUpdateRevision))
+	g.Expect(podutil.IsPodReady(pods[0])).To(BeTrue())
+	g.Expect(podutil.IsPodReady(pods[1])).To(BeFalse())
+
+	b = NewTCBuilder().
+		WithReplicas(v1alpha1.TiFlashMemberType, 2).
+		WithReplicas(v1alpha1.TiCDCMemberType, 2).
+		WithUpgradedPods(v1alpha1.TiFlashMemberType, 1)
+	tc = b.Build()
+	g.Expect(tc.Status.TiFlash.StatefulSet.UpdatedReplicas).To(Equal(int32(1)))
+	g.Expect(tc.Status.TiFlash.Stores).To(HaveKey(b.TiFlashStoreID(1)))
+	g.Expect(tc.Status.TiFlash.Stores[b.TiFlashStoreID(1)].PodName).To(Equal(TiFlashPodName(upgradeTcName, 1)))
+	g.Expect(tc.Status.TiCDC.Captures).To(HaveLen(2))
+	g.Expect(b.StatefulSet(v1alpha1.TiCDCMemberType).Name).To(Equal(controller.TiCDCMemberName(upgradeTcName)))
+	deps = controller.NewFakeDependencies()
+	b.Wire(deps)
+	pod, err = deps.PodLister.Pods(tc.Namespace).Get(TiFlashPodName(upgradeTcName, 1))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(pod.Labels[label.StoreIDLabelKey]).To(Equal("5"))
+	g.Expect(pod.Labels[apps.ControllerRevisionHashLabelKey]).To(Equal(upgradeUpdateRevision))
+}
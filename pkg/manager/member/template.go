@@ -17,6 +17,8 @@ import (
 	"bytes"
 	"fmt"
 	"text/template"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 )
 
 type CommonModel struct {
@@ -281,10 +283,10 @@ done
 ARGS="--pd=${result} \
 {{ else }}
 ARGS="--pd={{ .PDAddress }} \{{ end }}
---advertise-addr=${POD_NAME}.${HEADLESS_SERVICE_NAME}.${NAMESPACE}.svc{{ .FormatClusterDomain }}:20160 \
---addr=0.0.0.0:20160 \
---status-addr=0.0.0.0:20180 \{{if .EnableAdvertiseStatusAddr }}
---advertise-status-addr={{ .AdvertiseStatusAddr }}:20180 \{{end}}
+--advertise-addr={{ if .AdvertiseHostIP }}${HOST_IP}{{ else }}${POD_NAME}.${HEADLESS_SERVICE_NAME}.${NAMESPACE}.svc{{ .FormatClusterDomain }}{{ end }}:{{ .ServerPort }} \
+--addr=0.0.0.0:{{ .ServerPort }} \
+--status-addr=0.0.0.0:{{ .StatusPort }} \{{if .EnableAdvertiseStatusAddr }}
+--advertise-status-addr={{ .AdvertiseStatusAddr }}:{{ .StatusPort }} \{{end}}
 --data-dir={{ .DataDir }} \
 --capacity=${CAPACITY} \
 --config=/etc/tikv/tikv.toml
@@ -307,9 +309,20 @@ type TiKVStartScriptModel struct {
 	AdvertiseStatusAddr       string
 	DataDir                   string
 	PDAddress                 string
+	// AdvertiseHostIP advertises the IP of the node in the host network, it is passed in HOST_IP
+	AdvertiseHostIP bool
+	// ServerPort and StatusPort default to 20160 and 20180
+	ServerPort int32
+	StatusPort int32
 }
 
 func RenderTiKVStartScript(model *TiKVStartScriptModel) (string, error) {
+	if model.ServerPort == 0 {
+		model.ServerPort = v1alpha1.DefaultTiKVServerPort
+	}
+	if model.StatusPort == 0 {
+		model.StatusPort = v1alpha1.DefaultTiKVStatusPort
+	}
 	return renderTemplateFunc(tikvStartScriptTpl, model)
 }
 
@@ -378,6 +391,8 @@ for i in range(0, 10):
     try:
 {{- if and .TLS .SkipCA }}
         conn = MySQLdb.connect(host=host, port=port, user='root', charset='utf8mb4',connect_timeout=5, ssl={'cert': '{{ .CertPath }}', 'key': '{{ .KeyPath }}'})
+{{- else if and .TLS (not .CertPath) }}
+        conn = MySQLdb.connect(host=host, port=port, user='root', charset='utf8mb4',connect_timeout=5, ssl={'ca': '{{ .CAPath }}'})
 {{- else if .TLS }}
         conn = MySQLdb.connect(host=host, port=port, user='root', charset='utf8mb4',connect_timeout=5, ssl={'ca': '{{ .CAPath }}', 'cert': '{{ .CertPath }}', 'key': '{{ .KeyPath }}'})
 {{- else }}
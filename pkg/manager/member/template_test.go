@@ -301,6 +301,9 @@ func TestRenderTiKVStartScript(t *testing.T) {
 		result              string
 		clusterDomain       string
 		acrossK8s           bool
+		advertiseHostIP     bool
+		serverPort          int32
+		statusPort          int32
 	}{
 		{
 			name:                "disable AdvertiseAddr",
@@ -648,6 +651,63 @@ if [ ! -z "${STORE_LABELS:-}" ]; then
   ARGS="${ARGS}${LABELS}"
 fi
 
+echo "starting tikv-server ..."
+echo "/tikv-server ${ARGS}"
+exec /tikv-server ${ARGS}
+`,
+		},
+		{
+			name:                "advertise host IP",
+			enableAdvertiseAddr: true,
+			advertiseAddr:       "${HOST_IP}",
+			advertiseHostIP:     true,
+			serverPort:          30000,
+			statusPort:          30001,
+			result: `#!/bin/sh
+
+# This script is used to start tikv containers in kubernetes cluster
+
+# Use DownwardAPIVolumeFiles to store informations of the cluster:
+# https://kubernetes.io/docs/tasks/inject-data-application/downward-api-volume-expose-pod-information/#the-downward-api
+#
+#   runmode="normal/debug"
+#
+
+set -uo pipefail
+
+ANNOTATIONS="/etc/podinfo/annotations"
+
+if [[ ! -f "${ANNOTATIONS}" ]]
+then
+    echo "${ANNOTATIONS} does't exist, exiting."
+    exit 1
+fi
+source ${ANNOTATIONS} 2>/dev/null
+
+runmode=${runmode:-normal}
+if [[ X${runmode} == Xdebug ]]
+then
+	echo "entering debug mode."
+	tail -f /dev/null
+fi
+
+# Use HOSTNAME if POD_NAME is unset for backward compatibility.
+POD_NAME=${POD_NAME:-$HOSTNAME}
+ARGS="--pd=http://${CLUSTER_NAME}-pd:2379 \
+--advertise-addr=${HOST_IP}:30000 \
+--addr=0.0.0.0:30000 \
+--status-addr=0.0.0.0:30001 \
+--advertise-status-addr=${HOST_IP}:30001 \
+--data-dir=/var/lib/tikv \
+--capacity=${CAPACITY} \
+--config=/etc/tikv/tikv.toml
+"
+
+if [ ! -z "${STORE_LABELS:-}" ]; then
+  LABELS=" --labels ${STORE_LABELS} "
+  ARGS="${ARGS}${LABELS}"
+fi
+
 echo "starting tikv-server ..."
 echo "/tikv-server ${ARGS}"
 exec /tikv-server ${ARGS}
@@ -666,6 +726,9 @@ exec /tikv-server ${ARGS}
 				EnableAdvertiseStatusAddr: tt.enableAdvertiseAddr,
 				AdvertiseStatusAddr:       tt.advertiseAddr,
 				DataDir:                   filepath.Join(tikvDataVolumeMountPath, tt.dataSubDir),
+				AdvertiseHostIP:           tt.advertiseHostIP,
+				ServerPort:                tt.serverPort,
+				StatusPort:                tt.statusPort,
 			}
 			script, err := RenderTiKVStartScript(&model)
 			if err != nil {
@@ -1506,6 +1569,38 @@ if permit_host != '%%':
 conn.cursor().execute("flush privileges;")
 conn.commit()
 conn.close()
+`,
+		},
+		{
+			name: "tls with ca only",
+			model: &TiDBInitStartScriptModel{
+				ClusterName:     "test",
+				PermitHost:      "%",
+				TLS:             true,
+				CAPath:          "/var/lib/tidb-server-ca/ca.crt",
+				TiDBServicePort: 4000,
+			},
+			result: `import os, sys, time, MySQLdb
+host = 'test-tidb'
+permit_host = '%'
+port = 4000
+retry_count = 0
+for i in range(0, 10):
+    try:
+        conn = MySQLdb.connect(host=host, port=port, user='root', charset='utf8mb4',connect_timeout=5, ssl={'ca': '/var/lib/tidb-server-ca/ca.crt'})
+    except MySQLdb.OperationalError as e:
+        print(e)
+        retry_count += 1
+        time.sleep(1)
+        continue
+    break
+if retry_count == 10:
+    sys.exit(1)
+if permit_host != '%%':
+    conn.cursor().execute("update mysql.user set Host=%s where User='root';", (permit_host,))
+conn.cursor().execute("flush privileges;")
+conn.commit()
+conn.close()
 `,
 		},
 		{
@@ -118,6 +118,9 @@ func (m *ticdcMemberManager) syncTiCDCConfigMap(tc *v1alpha1.TidbCluster, set *a
 	if err != nil {
 		return nil, err
 	}
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.TiCDCMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -153,11 +156,17 @@ func (m *ticdcMemberManager) syncStatefulSet(tc *v1alpha1.TidbCluster) error {
 			ns, tcName, err)
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.TiCDCMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.TiCDCMemberType) {
 		klog.Infof("TidbCluster %s/%s is paused, skip syncing ticdc statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	if err := syncExternalProvisioningGates(m.deps, tc, v1alpha1.TiCDCMemberType); err != nil {
+		return err
+	}
+
 	cm, err := m.syncTiCDCConfigMap(tc, oldSts)
 	if err != nil {
 		return err
@@ -193,13 +202,25 @@ func (m *ticdcMemberManager) syncStatefulSet(tc *v1alpha1.TidbCluster) error {
 		return err
 	}
 
+	if err := cleanupImagePrepullPods(m.deps, tc, v1alpha1.TiCDCMemberType); err != nil {
+		return err
+	}
+
+	if _, err := keepTemplateIfDowngrade(m.deps, tc, v1alpha1.TiCDCMemberType, oldSts, newSts); err != nil {
+		return err
+	}
+
+	if err := prePullImage(m.deps, tc, v1alpha1.TiCDCMemberType, oldSts, newSts); err != nil {
+		return err
+	}
+
 	if !templateEqual(newSts, oldSts) || tc.Status.TiCDC.Phase == v1alpha1.UpgradePhase {
 		if err := m.ticdcUpgrader.Upgrade(tc, oldSts, newSts); err != nil {
 			return err
 		}
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdateTiCDCSTS", newSts, oldSts)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newSts, oldSts)
 }
 
 func (m *ticdcMemberManager) syncTiCDCStatus(tc *v1alpha1.TidbCluster, sts *apps.StatefulSet) error {
@@ -217,14 +238,20 @@ func (m *ticdcMemberManager) syncTiCDCStatus(tc *v1alpha1.TidbCluster, sts *apps
 		tc.Status.TiCDC.Synced = false
 		return err
 	}
+	oldPhase := tc.Status.TiCDC.Phase
 	if upgrading {
 		tc.Status.TiCDC.Phase = v1alpha1.UpgradePhase
 	} else {
 		tc.Status.TiCDC.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.TiCDC.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.TiCDCMemberType, oldPhase, tc.Status.TiCDC.Phase, tc.Status.TiCDC.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.TiCDCMemberType, oldPhase, tc.Status.TiCDC.Phase, tc.Status.TiCDC.StatefulSet)
+	syncUpgradeStatus(m.deps, tc, v1alpha1.TiCDCMemberType, sts, nil)
 
 	ticdcCaptures := map[string]v1alpha1.TiCDCCapture{}
 	allCapturesReady := true
+	owner := ""
+	var readyOrdinal *int32
 	for id := range helper.GetPodOrdinals(tc.Status.TiCDC.StatefulSet.Replicas, sts) {
 		podName := fmt.Sprintf("%s-%d", controller.TiCDCMemberName(tc.GetName()), id)
 
@@ -247,19 +274,60 @@ func (m *ticdcMemberManager) syncTiCDCStatus(tc *v1alpha1.TidbCluster, sts *apps
 			capture.Version = status.Version
 			capture.IsOwner = status.IsOwner
 			capture.Ready = true
+			if status.IsOwner {
+				owner = podName
+			}
+			if readyOrdinal == nil {
+				ordinal := int32(id)
+				readyOrdinal = &ordinal
+			}
 		}
 
 		ticdcCaptures[podName] = capture
 	}
 
+	if readyOrdinal != nil {
+		m.syncCaptureTableCounts(tc, *readyOrdinal, ticdcCaptures)
+	}
+
 	tc.Status.TiCDC.Synced = len(ticdcCaptures) == int(tc.TiCDCDeployDesiredReplicas()) && allCapturesReady
 	tc.Status.TiCDC.Captures = ticdcCaptures
+	tc.Status.TiCDC.Owner = owner
 
 	return nil
 }
 
+// syncCaptureTableCounts sets the number of the tables replicated by each capture from the processors of
+// the changefeeds, the counts are left unset if the processors are not available
+func (m *ticdcMemberManager) syncCaptureTableCounts(tc *v1alpha1.TidbCluster, ordinal int32, captures map[string]v1alpha1.TiCDCCapture) {
+	processors, err := m.deps.CDCControl.GetProcessors(tc, ordinal)
+	if err != nil {
+		klog.Warningf("Failed to get the processors of [%s/%s], error: %v", tc.GetNamespace(), tc.GetName(), err)
+		return
+	}
+	podNames := map[string]string{}
+	for podName, capture := range captures {
+		podNames[capture.ID] = podName
+	}
+	for _, processor := range processors {
+		podName, ok := podNames[processor.CaptureID]
+		if !ok {
+			continue
+		}
+		count, err := m.deps.CDCControl.GetProcessorTableCount(tc, ordinal, processor.ChangefeedID, processor.CaptureID)
+		if err != nil {
+			klog.Warningf("Failed to get the tables of the changefeed %s on Pod %s of [%s/%s], error: %v",
+				processor.ChangefeedID, podName, tc.GetNamespace(), tc.GetName(), err)
+			continue
+		}
+		capture := captures[podName]
+		capture.TableCount += int32(count)
+		captures[podName] = capture
+	}
+}
+
 func (m *ticdcMemberManager) syncCDCHeadlessService(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.TiCDCMemberType) {
 		klog.Infof("TidbCluster %s/%s is paused, skip syncing ticdc service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -337,10 +405,16 @@ func getNewTiCDCStatefulSet(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (*ap
 	tcName := tc.GetName()
 
 	baseTiCDCSpec := tc.BaseTiCDCSpec()
+	resources, err := v1alpha1.SizedResources(baseTiCDCSpec.Sizing(), tc.Spec.TiCDC.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for ticdc, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
 	stsLabels := labelTiCDC(tc)
 	stsName := controller.TiCDCMemberName(tcName)
 	podLabels := util.CombineStringMap(stsLabels, baseTiCDCSpec.Labels())
 	podAnnotations := util.CombineStringMap(controller.AnnProm(8301), baseTiCDCSpec.Annotations())
+	podAnnotations = util.CombineStringMap(podAnnotations, externalProvisioningAnnotations(baseTiCDCSpec))
+	podAnnotations = util.CombineStringMap(podAnnotations, restartGenerationAnnotations(tc, v1alpha1.TiCDCMemberType))
 	stsAnnotations := getStsAnnotations(tc.Annotations, label.TiCDCLabelVal)
 	headlessSvcName := controller.TiCDCPeerMemberName(tcName)
 
@@ -472,7 +546,7 @@ done
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.TiCDC.ResourceRequirements),
+		Resources:    controller.ContainerResource(resources),
 		Env:          util.AppendEnv(envs, baseTiCDCSpec.Env()),
 		EnvFrom:      baseTiCDCSpec.EnvFrom(),
 	}
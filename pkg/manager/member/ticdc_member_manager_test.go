@@ -492,6 +492,51 @@ func TestTiCDCMemberManagerSyncTidbClusterStatus(t *testing.T) {
 				g.Expect(tc.Status.TiCDC.Synced).To(BeFalse())
 			},
 		},
+		{
+			name: "owner and table counts of captures",
+			updateSts: func(sts *apps.StatefulSet) {
+				sts.Status = apps.StatefulSetStatus{
+					Replicas: 3,
+				}
+			},
+			beforeSyncStatus: func(tc *v1alpha1.TidbCluster, m *ticdcMemberManager, indexer *fakeIndexers) {
+				for i := int32(0); i < 3; i++ {
+					indexer.pod.Add(&corev1.Pod{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      ordinalPodName(v1alpha1.TiCDCMemberType, tc.GetName(), i),
+							Namespace: metav1.NamespaceDefault,
+							Labels:    label.New().Instance(tc.GetInstanceName()).TiCDC().Labels(),
+						},
+					})
+				}
+
+				cdcControl := m.deps.CDCControl.(*controller.FakeTiCDCControl)
+				cdcControl.MockGetStatus(func(tc *v1alpha1.TidbCluster, ordinal int32) (*controller.CaptureStatus, error) {
+					return &controller.CaptureStatus{ID: fmt.Sprintf("capture-%d", ordinal), IsOwner: ordinal == 2}, nil
+				})
+				cdcControl.MockGetProcessors(func(tc *v1alpha1.TidbCluster, ordinal int32) ([]controller.ProcessorInfo, error) {
+					return []controller.ProcessorInfo{
+						{ChangefeedID: "cf-1", CaptureID: "capture-0"},
+						{ChangefeedID: "cf-2", CaptureID: "capture-0"},
+						{ChangefeedID: "cf-1", CaptureID: "capture-2"},
+					}, nil
+				})
+				cdcControl.MockGetProcessorTableCount(func(tc *v1alpha1.TidbCluster, ordinal int32, changefeedID, captureID string) (int, error) {
+					if changefeedID == "cf-1" {
+						return 2, nil
+					}
+					return 1, nil
+				})
+			},
+			errExpectFn: errExpectNil,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster) {
+				g.Expect(tc.Status.TiCDC.Owner).To(Equal(ordinalPodName(v1alpha1.TiCDCMemberType, tc.GetName(), 2)))
+				g.Expect(tc.Status.TiCDC.Captures[ordinalPodName(v1alpha1.TiCDCMemberType, tc.GetName(), 0)].TableCount).To(Equal(int32(3)))
+				g.Expect(tc.Status.TiCDC.Captures[ordinalPodName(v1alpha1.TiCDCMemberType, tc.GetName(), 1)].TableCount).To(Equal(int32(0)))
+				g.Expect(tc.Status.TiCDC.Captures[ordinalPodName(v1alpha1.TiCDCMemberType, tc.GetName(), 2)].TableCount).To(Equal(int32(2)))
+				g.Expect(tc.Status.TiCDC.Synced).To(BeTrue())
+			},
+		},
 	}
 
 	for i := range tests {
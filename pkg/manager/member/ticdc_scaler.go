@@ -92,18 +92,67 @@ func (s *ticdcScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newS
 	}
 
 	// when scaling in TiCDC pods, we let the "capture info" in PD's etcd to be deleted automatically when shutting down the TiCDC process or after TTL expired.
+	tc, _ := meta.(*v1alpha1.TidbCluster)
+	if err := s.drainCapture(tc, ordinal, podName); err != nil {
+		return err
+	}
 
 	pvcs, err := util.ResolvePVCFromPod(pod, s.deps.PVCLister)
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("ticdcScaler.ScaleIn: failed to get pvcs for pod %s/%s in tc %s/%s, error: %s", ns, pod.Name, ns, tcName, err)
 	}
-	tc, _ := meta.(*v1alpha1.TidbCluster)
 	for _, pvc := range pvcs {
 		if err := addDeferDeletingAnnoToPVC(tc, pvc, s.deps.PVCControl); err != nil {
 			return err
 		}
 	}
 
-	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
+	return nil
+}
+
+// drainCapture moves the tables replicated by the capture to the other captures before the pod is removed.
+// If the capture is the owner, it resigns first because the owner can not be drained. The scaling in is
+// requeued until no table is left on the capture.
+func (s *ticdcScaler) drainCapture(tc *v1alpha1.TidbCluster, ordinal int32, podName string) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	capture, ok := tc.Status.TiCDC.Captures[podName]
+	if !ok || !capture.Ready {
+		klog.Infof("ticdcScaler.ScaleIn: capture of pod %s in tc %s/%s is not ready, skip draining", podName, ns, tcName)
+		return nil
+	}
+	readyCaptures := 0
+	for _, c := range tc.Status.TiCDC.Captures {
+		if c.Ready {
+			readyCaptures++
+		}
+	}
+	if readyCaptures <= 1 {
+		// there is no other capture to move the tables to
+		klog.Infof("ticdcScaler.ScaleIn: pod %s is the only capture in tc %s/%s, skip draining", podName, ns, tcName)
+		return nil
+	}
+
+	if capture.IsOwner {
+		if err := s.deps.CDCControl.ResignOwner(tc, ordinal); err != nil {
+			return fmt.Errorf("ticdcScaler.ScaleIn: failed to resign the owner %s in tc %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		return controller.RequeueErrorf("ticdc.ScaleIn, cluster %s/%s owner %s resigned, wait for the new owner to be elected", ns, tcName, podName)
+	}
+
+	tableCount, retry, err := s.deps.CDCControl.DrainCapture(tc, ordinal, capture.ID)
+	if err != nil {
+		return fmt.Errorf("ticdcScaler.ScaleIn: failed to drain capture %s in tc %s/%s, error: %s", podName, ns, tcName, err)
+	}
+	if retry {
+		return controller.RequeueErrorf("ticdc.ScaleIn, cluster %s/%s owner is not ready to drain capture %s, wait for next round", ns, tcName, podName)
+	}
+	capture.TableCount = int32(tableCount)
+	tc.Status.TiCDC.Captures[podName] = capture
+	if tableCount > 0 {
+		return controller.RequeueErrorf("ticdc.ScaleIn, cluster %s/%s draining capture %s, %d tables left, wait for next round", ns, tcName, podName, tableCount)
+	}
+	klog.Infof("ticdcScaler.ScaleIn: capture %s in tc %s/%s is drained", podName, ns, tcName)
 	return nil
 }
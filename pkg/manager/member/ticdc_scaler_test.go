@@ -311,6 +311,117 @@ func TestTiCDCScalerScaleIn(t *testing.T) {
 	}
 }
 
+func TestTiCDCScalerScaleInDrainCapture(t *testing.T) {
+	type testcase struct {
+		name          string
+		isOwner       bool
+		otherCapture  bool
+		tableCount    int
+		retry         bool
+		drainErr      bool
+		expectResign  bool
+		expectDrain   bool
+		errExpectFn   func(*GomegaWithT, error)
+		changed       bool
+		expectedCount int32
+	}
+
+	tests := []testcase{
+		{
+			name:         "owner on the last ordinal resigns first",
+			isOwner:      true,
+			otherCapture: true,
+			expectResign: true,
+			errExpectFn:  errExpectRequeue,
+		},
+		{
+			name:          "tables left on the capture",
+			otherCapture:  true,
+			tableCount:    3,
+			expectDrain:   true,
+			errExpectFn:   errExpectRequeue,
+			expectedCount: 3,
+		},
+		{
+			name:         "owner not ready to drain",
+			otherCapture: true,
+			retry:        true,
+			expectDrain:  true,
+			errExpectFn:  errExpectRequeue,
+		},
+		{
+			name:         "failed to drain",
+			otherCapture: true,
+			drainErr:     true,
+			expectDrain:  true,
+			errExpectFn:  errExpectNotNil,
+		},
+		{
+			name:         "drained",
+			otherCapture: true,
+			expectDrain:  true,
+			errExpectFn:  errExpectNil,
+			changed:      true,
+		},
+		{
+			name:        "the only capture",
+			isOwner:     true,
+			errExpectFn: errExpectNil,
+			changed:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			tc := newTidbClusterForPD()
+			oldSet := newStatefulSetForPDScale()
+			newSet := oldSet.DeepCopy()
+			newSet.Spec.Replicas = pointer.Int32Ptr(3)
+
+			podName := ticdcPodName(tc.GetName(), 4)
+			tc.Status.TiCDC.Captures = map[string]v1alpha1.TiCDCCapture{
+				podName: {PodName: podName, ID: "capture-4", IsOwner: tt.isOwner, Ready: true},
+			}
+			if tt.otherCapture {
+				otherPodName := ticdcPodName(tc.GetName(), 3)
+				tc.Status.TiCDC.Captures[otherPodName] = v1alpha1.TiCDCCapture{PodName: otherPodName, ID: "capture-3", Ready: true}
+			}
+
+			scaler, _, podIndexer, _ := newFakeTiCDCScaler()
+			podIndexer.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: corev1.NamespaceDefault},
+			})
+			cdcControl := scaler.deps.CDCControl.(*controller.FakeTiCDCControl)
+			resigned, drained := false, false
+			cdcControl.MockResignOwner(func(tc *v1alpha1.TidbCluster, ordinal int32) error {
+				g.Expect(ordinal).To(Equal(int32(4)))
+				resigned = true
+				return nil
+			})
+			cdcControl.MockDrainCapture(func(tc *v1alpha1.TidbCluster, ordinal int32, captureID string) (int, bool, error) {
+				g.Expect(captureID).To(Equal("capture-4"))
+				drained = true
+				if tt.drainErr {
+					return 0, false, fmt.Errorf("drain failed")
+				}
+				return tt.tableCount, tt.retry, nil
+			})
+
+			err := scaler.ScaleIn(tc, oldSet, newSet)
+			tt.errExpectFn(g, err)
+			g.Expect(resigned).To(Equal(tt.expectResign))
+			g.Expect(drained).To(Equal(tt.expectDrain))
+			g.Expect(tc.Status.TiCDC.Captures[podName].TableCount).To(Equal(tt.expectedCount))
+			if tt.changed {
+				g.Expect(int(*newSet.Spec.Replicas)).To(Equal(4))
+			} else {
+				g.Expect(int(*newSet.Spec.Replicas)).To(Equal(5))
+			}
+		})
+	}
+}
+
 func newFakeTiCDCScaler(resyncDuration ...time.Duration) (*ticdcScaler, cache.Indexer, cache.Indexer, *controller.FakePVCControl) {
 	fakeDeps := controller.NewFakeDependencies()
 	if len(resyncDuration) > 0 {
@@ -15,25 +15,35 @@ package member
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	"github.com/pingcap/tidb-operator/pkg/util"
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/utils/pointer"
 )
 
 type ticdcUpgrader struct {
-	deps *controller.Dependencies
+	deps     *controller.Dependencies
+	recorder record.EventRecorder
 }
 
 // NewTiCDCUpgrader returns a ticdc Upgrader
 func NewTiCDCUpgrader(deps *controller.Dependencies) Upgrader {
 	return &ticdcUpgrader{
-		deps: deps,
+		deps:     deps,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -47,17 +57,13 @@ func (u *ticdcUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulS
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
-	if tc.Status.PD.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiKV.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiFlash.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.Pump.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiDB.Phase == v1alpha1.UpgradePhase {
-		klog.Infof("TidbCluster: [%s/%s]'s pd status is %s, "+
-			"tikv status is %s, tiflash status is %s, pump status is %s, "+
-			"tidb status is %s, can not upgrade ticdc",
-			ns, tcName,
-			tc.Status.PD.Phase, tc.Status.TiKV.Phase, tc.Status.TiFlash.Phase,
-			tc.Status.Pump.Phase, tc.Status.TiDB.Phase)
+	if frozen, err := keepTemplateIfUpgradeFrozen(u.deps, tc, v1alpha1.TiCDCMemberType, oldSet, newSet); frozen {
+		return err
+	}
+
+	if blocking := upgradeBlockedBy(tc, v1alpha1.TiCDCMemberType); blocking != "" {
+		klog.Infof("TidbCluster: [%s/%s]'s components upgraded before ticdc [%s] are upgrading, can not upgrade ticdc",
+			ns, tcName, blocking)
 		_, podSpec, err := GetLastAppliedConfig(oldSet)
 		if err != nil {
 			return err
@@ -66,6 +72,7 @@ func (u *ticdcUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulS
 		return nil
 	}
 
+	recordLastReconcileBy(u.deps, tc, tc.Status.TiCDC.Phase != v1alpha1.UpgradePhase)
 	tc.Status.TiCDC.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -75,6 +82,10 @@ func (u *ticdcUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulS
 		return nil
 	}
 
+	if len(tc.Spec.TiCDC.ChangefeedPriorities) > 0 && tc.BaseTiCDCSpec().StatefulSetUpdateStrategy() != apps.OnDeleteStatefulSetStrategyType {
+		return u.upgradeByChangefeedPriority(tc, oldSet, newSet)
+	}
+
 	if oldSet.Spec.UpdateStrategy.Type == apps.OnDeleteStatefulSetStrategyType || oldSet.Spec.UpdateStrategy.RollingUpdate == nil {
 		// Manually bypass tidb-operator to modify statefulset directly, such as modify ticdc statefulset's RollingUpdate strategy to OnDelete strategy,
 		// or set RollingUpdate to nil, skip tidb-operator's rolling update logic in order to speed up the upgrade in the test environment occasionally.
@@ -108,9 +119,142 @@ func (u *ticdcUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulS
 			}
 			continue
 		}
+		if upgradePaused(u.recorder, tc, v1alpha1.TiCDCMemberType, i) {
+			return nil
+		}
+		syncUpgradeStatus(u.deps, tc, v1alpha1.TiCDCMemberType, oldSet, &i)
+		recordLastReconcileBy(u.deps, tc, i < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition)
 		mngerutils.SetUpgradePartition(newSet, i)
+		prepullNextImage(u.deps, tc, v1alpha1.TiCDCMemberType, newSet, i)
 		return nil
 	}
 
 	return nil
 }
+
+// upgradeByChangefeedPriority restarts the captures one by one in the ascending order of the highest
+// priority of the changefeeds they own. As the StatefulSet controller upgrades the pods in the descending
+// order of the ordinals with the RollingUpdate strategy, the OnDelete strategy is used during the upgrade
+// and the pods are deleted by tidb-operator. The RollingUpdate strategy is restored once all the pods are
+// upgraded, so that the StatefulSet controller completes the update revision.
+func (u *ticdcUpgrader) upgradeByChangefeedPriority(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	var pending []*corev1.Pod
+	for _, i := range helper.GetPodOrdinals(*oldSet.Spec.Replicas, oldSet).List() {
+		podName := ticdcPodName(tcName, i)
+		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if err != nil {
+			return fmt.Errorf("ticdcUpgrader.Upgrade: failed to get pod %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		revision, exist := pod.Labels[apps.ControllerRevisionHashLabelKey]
+		if !exist {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s ticdc pod: [%s] has no label: %s", ns, tcName, podName, apps.ControllerRevisionHashLabelKey)
+		}
+		if revision != tc.Status.TiCDC.StatefulSet.UpdateRevision {
+			pending = append(pending, pod)
+			continue
+		}
+		if !podutil.IsPodReady(pod) {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded ticdc pod: [%s] is not ready", ns, tcName, podName)
+		}
+		if _, exist := tc.Status.TiCDC.Captures[podName]; !exist {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s ticdc upgraded pod: [%s] is not ready", ns, tcName, podName)
+		}
+	}
+
+	if len(pending) == 0 {
+		newSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
+			Type:          apps.RollingUpdateStatefulSetStrategyType,
+			RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: pointer.Int32Ptr(0)},
+		}
+		return nil
+	}
+	newSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{Type: apps.OnDeleteStatefulSetStrategyType}
+	if oldSet.Spec.UpdateStrategy.Type != apps.OnDeleteStatefulSetStrategyType {
+		// the pods are deleted after the OnDelete strategy is applied, otherwise they may be
+		// recreated with the current revision
+		return nil
+	}
+
+	changefeeds, err := u.getCaptureChangefeeds(tc, oldSet)
+	if err != nil {
+		return err
+	}
+	priorities := tc.Spec.TiCDC.ChangefeedPriorities
+	capturePriority := func(pod *corev1.Pod) int32 {
+		// the captures without changefeeds are restarted first
+		priority := int32(math.MinInt32)
+		for _, id := range changefeeds[pod.Name] {
+			if p := priorities[id]; p > priority {
+				priority = p
+			}
+		}
+		return priority
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		pi, pj := capturePriority(pending[i]), capturePriority(pending[j])
+		if pi != pj {
+			return pi < pj
+		}
+		// keep the descending order of the ordinals for the same priority
+		return pending[i].Name > pending[j].Name
+	})
+
+	pod := pending[0]
+	ordinal, err := util.GetOrdinalFromPodName(pod.Name)
+	if err != nil {
+		return err
+	}
+	if upgradePaused(u.recorder, tc, v1alpha1.TiCDCMemberType, ordinal) {
+		return nil
+	}
+	syncUpgradeStatus(u.deps, tc, v1alpha1.TiCDCMemberType, oldSet, &ordinal)
+	moved := make([]string, 0, len(changefeeds[pod.Name]))
+	for _, id := range changefeeds[pod.Name] {
+		moved = append(moved, fmt.Sprintf("%s (priority %d)", id, priorities[id]))
+	}
+	recordLastReconcileBy(u.deps, tc, true)
+	if len(moved) == 0 {
+		u.deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.ChangefeedsMoved, "restart ticdc pod %s for upgrade, no changefeed is running on it", pod.Name)
+	} else {
+		u.deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.ChangefeedsMoved, "restart ticdc pod %s for upgrade, changefeeds moved off it: %s", pod.Name, strings.Join(moved, ", "))
+	}
+	klog.Infof("tidbcluster: [%s/%s] restart ticdc pod %s for upgrade by changefeed priority", ns, tcName, pod.Name)
+	return u.deps.PodControl.DeletePod(tc, pod)
+}
+
+// getCaptureChangefeeds returns the IDs of the changefeeds running on each capture keyed by the pod name
+func (u *ticdcUpgrader) getCaptureChangefeeds(tc *v1alpha1.TidbCluster, set *apps.StatefulSet) (map[string][]string, error) {
+	podNames := map[string]string{}
+	var ordinal *int32
+	for podName, capture := range tc.Status.TiCDC.Captures {
+		podNames[capture.ID] = podName
+		if capture.Ready && ordinal == nil {
+			i, err := util.GetOrdinalFromPodName(podName)
+			if err != nil {
+				return nil, err
+			}
+			ordinal = &i
+		}
+	}
+	if ordinal == nil {
+		return nil, controller.RequeueErrorf("tidbcluster: [%s/%s] has no ready ticdc capture to get the changefeeds", tc.GetNamespace(), tc.GetName())
+	}
+
+	processors, err := u.deps.CDCControl.GetProcessors(tc, *ordinal)
+	if err != nil {
+		return nil, fmt.Errorf("tidbcluster: [%s/%s] failed to get the processors of ticdc, error: %v", tc.GetNamespace(), tc.GetName(), err)
+	}
+	changefeeds := map[string][]string{}
+	for _, processor := range processors {
+		if podName, ok := podNames[processor.CaptureID]; ok {
+			changefeeds[podName] = append(changefeeds[podName], processor.ChangefeedID)
+		}
+	}
+	for _, ids := range changefeeds {
+		sort.Strings(ids)
+	}
+	return changefeeds, nil
+}
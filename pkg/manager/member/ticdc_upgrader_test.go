@@ -14,9 +14,9 @@
 package member
 
 import (
+	"fmt"
 	"testing"
 
-	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
@@ -24,8 +24,6 @@ import (
 	. "github.com/onsi/gomega"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	podinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/utils/pointer"
 )
@@ -47,11 +45,14 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 	testFn := func(test *testcase, t *testing.T) {
 		t.Log(test.name)
 		upgrader, podInformer := newTiCDCUpgrader()
-		tc := newTidbClusterForTiCDCUpgrader()
+		b := NewTCBuilder().
+			WithReplicas(v1alpha1.TiCDCMemberType, 2).
+			WithUpgradedPods(v1alpha1.TiCDCMemberType, 1)
+		tc := b.Build()
 		if test.changeFn != nil {
 			test.changeFn(tc)
 		}
-		pods := getTiCDCPods()
+		pods := b.Pods(v1alpha1.TiCDCMemberType)
 		if test.invalidPod {
 			pods[1].Labels = nil
 		}
@@ -65,7 +66,8 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 			podInformer.Informer().GetIndexer().Add(pod)
 		}
 
-		oldSet := newStatefulSetForTiCDCUpgrader()
+		oldSet := b.StatefulSet(v1alpha1.TiCDCMemberType)
+		oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(1)
 		newSet := oldSet.DeepCopy()
 		if test.changeOldSet != nil {
 			test.changeOldSet(oldSet)
@@ -149,8 +151,6 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 		{
 			name: "upgrade revision equals current revision",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
 				tc.Status.TiCDC.StatefulSet.UpdateRevision = tc.Status.TiCDC.StatefulSet.CurrentRevision
 			},
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -191,14 +191,6 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 			name: "ticdc can not upgrade when pd is upgrading",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
 				tc.Status.PD.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.Pump.Phase = v1alpha1.NormalPhase
-				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
-				tc.Status.TiCDC.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			errorExpect: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -209,15 +201,7 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 		{
 			name: "ticdc can not upgrade when tiflash is upgrading",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.Pump.Phase = v1alpha1.NormalPhase
-				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
-				tc.Status.TiCDC.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			errorExpect: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -228,15 +212,7 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 		{
 			name: "ticdc can not upgrade when tikv is upgrading",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
 				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.Pump.Phase = v1alpha1.NormalPhase
-				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
-				tc.Status.TiCDC.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			errorExpect: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -247,15 +223,7 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 		{
 			name: "ticdc can not upgrade when pump is upgrading",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
 				tc.Status.Pump.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
-				tc.Status.TiCDC.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			errorExpect: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -264,17 +232,20 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "ticdc can not upgrade when tidb is upgrading",
+			name: "ticdc is upgraded before tidb by default",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.Pump.Phase = v1alpha1.NormalPhase
 				tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiCDC.Synced = true
 			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+			errorExpect: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.TiCDC.Phase).To(Equal(v1alpha1.UpgradePhase))
+			},
+		},
+		{
+			name: "ticdc can not upgrade when tidb before it in spec.upgradeOrder is upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+				tc.Spec.UpgradeOrder = []v1alpha1.MemberType{v1alpha1.PDMemberType, v1alpha1.TiKVMemberType, v1alpha1.TiDBMemberType, v1alpha1.TiCDCMemberType}
 			},
 			errorExpect: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
@@ -290,140 +261,117 @@ func TestTiCDCUpgrader_Upgrade(t *testing.T) {
 
 }
 
-func newTiCDCUpgrader() (Upgrader, podinformers.PodInformer) {
-	fakeDeps := controller.NewFakeDependencies()
-	upgrader := &ticdcUpgrader{fakeDeps}
-	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
-	return upgrader, podInformer
-}
+func TestTiCDCUpgraderUpgradeByChangefeedPriority(t *testing.T) {
+	g := NewGomegaWithT(t)
 
-func newStatefulSetForTiCDCUpgrader() *apps.StatefulSet {
-	return &apps.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "upgrader-ticdc",
-			Namespace: metav1.NamespaceDefault,
-		},
-		Spec: apps.StatefulSetSpec{
-			Replicas: pointer.Int32Ptr(2),
-			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "ticdc",
-							Image: "ticdc-test-image",
-						},
-					},
-				},
-			},
-			UpdateStrategy: apps.StatefulSetUpdateStrategy{Type: apps.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{
-					Partition: pointer.Int32Ptr(1),
-				},
-			},
-		},
-		Status: apps.StatefulSetStatus{
-			CurrentRevision: "1",
-			UpdateRevision:  "2",
-			ReadyReplicas:   2,
-			Replicas:        2,
-			CurrentReplicas: 1,
-			UpdatedReplicas: 1,
-		},
+	type testcase struct {
+		name           string
+		updatedPods    []int32
+		oldSetOnDelete bool
+		processorsErr  bool
+		errorExpect    bool
+		expectDeleted  string
+		expectStrategy apps.StatefulSetUpdateStrategyType
 	}
-}
 
-func newTidbClusterForTiCDCUpgrader() *v1alpha1.TidbCluster {
-	return &v1alpha1.TidbCluster{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "TidbCluster",
-			APIVersion: "pingcap.com/v1alpha1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "upgrader",
-			Namespace: corev1.NamespaceDefault,
-			UID:       types.UID("upgrader"),
+	testFn := func(test *testcase) {
+		t.Log(test.name)
+		fakeDeps := controller.NewFakeDependencies()
+		upgrader := &ticdcUpgrader{deps: fakeDeps, recorder: fakeDeps.Recorder}
+		podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
+		cdcControl := fakeDeps.CDCControl.(*controller.FakeTiCDCControl)
+		cdcControl.MockGetProcessors(func(tc *v1alpha1.TidbCluster, ordinal int32) ([]controller.ProcessorInfo, error) {
+			if test.processorsErr {
+				return nil, fmt.Errorf("failed to get processors")
+			}
+			return []controller.ProcessorInfo{
+				{ChangefeedID: "high", CaptureID: "capture-1"},
+				{ChangefeedID: "low", CaptureID: "capture-0"},
+			}, nil
+		})
+
+		b := NewTCBuilder().
+			WithReplicas(v1alpha1.TiCDCMemberType, 2).
+			WithUpgradedPods(v1alpha1.TiCDCMemberType, test.updatedPods...)
+		tc := b.Build()
+		tc.Spec.TiCDC.ChangefeedPriorities = map[string]int32{"high": 10, "low": 1}
+		tc.Status.TiCDC.Captures = map[string]v1alpha1.TiCDCCapture{
+			"upgrader-ticdc-0": {PodName: "upgrader-ticdc-0", ID: "capture-0", Ready: true},
+			"upgrader-ticdc-1": {PodName: "upgrader-ticdc-1", ID: "capture-1", Ready: true},
+		}
+		pods := b.Pods(v1alpha1.TiCDCMemberType)
+		for _, pod := range pods {
+			podInformer.Informer().GetIndexer().Add(pod)
+		}
+
+		oldSet := b.StatefulSet(v1alpha1.TiCDCMemberType)
+		if test.oldSetOnDelete {
+			oldSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{Type: apps.OnDeleteStatefulSetStrategyType}
+		}
+		newSet := oldSet.DeepCopy()
+		mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+
+		err := upgrader.Upgrade(tc, oldSet, newSet)
+		if test.errorExpect {
+			g.Expect(err).To(HaveOccurred())
+		} else {
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+		g.Expect(newSet.Spec.UpdateStrategy.Type).To(Equal(test.expectStrategy))
+		if test.expectStrategy == apps.RollingUpdateStatefulSetStrategyType {
+			g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+		}
+		for _, pod := range pods {
+			_, err := podInformer.Lister().Pods(pod.Namespace).Get(pod.Name)
+			if pod.Name == test.expectDeleted {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		}
+	}
+
+	tests := []*testcase{
+		{
+			name:           "switch to OnDelete before deleting pods",
+			expectStrategy: apps.OnDeleteStatefulSetStrategyType,
 		},
-		Spec: v1alpha1.TidbClusterSpec{
-			PD: &v1alpha1.PDSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "pd-test-image",
-				},
-				Replicas:         3,
-				StorageClassName: pointer.StringPtr("my-storage-class"),
-			},
-			TiKV: &v1alpha1.TiKVSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "tikv-test-image",
-				},
-				Replicas:         3,
-				StorageClassName: pointer.StringPtr("my-storage-class"),
-			},
-			TiCDC: &v1alpha1.TiCDCSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "ticdc-test-image",
-				},
-				Replicas: 2,
-			},
+		{
+			name:           "delete the pod with the lowest priority first",
+			oldSetOnDelete: true,
+			expectDeleted:  "upgrader-ticdc-0",
+			expectStrategy: apps.OnDeleteStatefulSetStrategyType,
 		},
-		Status: v1alpha1.TidbClusterStatus{
-			TiCDC: v1alpha1.TiCDCStatus{
-				Phase: v1alpha1.NormalPhase,
-				StatefulSet: &apps.StatefulSetStatus{
-					CurrentReplicas: 1,
-					UpdatedReplicas: 1,
-					CurrentRevision: "1",
-					UpdateRevision:  "2",
-					Replicas:        2,
-				},
-				Captures: map[string]v1alpha1.TiCDCCapture{
-					"upgrader-ticdc-0": {
-						PodName: "upgrader-ticdc-0",
-					},
-					"upgrader-ticdc-1": {
-						PodName: "upgrader-ticdc-1",
-					},
-				},
-			},
+		{
+			name:           "delete the pod with the highest priority last",
+			updatedPods:    []int32{0},
+			oldSetOnDelete: true,
+			expectDeleted:  "upgrader-ticdc-1",
+			expectStrategy: apps.OnDeleteStatefulSetStrategyType,
 		},
-	}
-}
-
-func getTiCDCPods() []*corev1.Pod {
-	lc := label.New().Instance(upgradeInstanceName).TiCDC().Labels()
-	lc[apps.ControllerRevisionHashLabelKey] = "1"
-	lu := label.New().Instance(upgradeInstanceName).TiCDC().Labels()
-	lu[apps.ControllerRevisionHashLabelKey] = "2"
-	pods := []*corev1.Pod{
 		{
-			TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      ticdcPodName(upgradeTcName, 0),
-				Namespace: corev1.NamespaceDefault,
-				Labels:    lc,
-			},
-			Status: corev1.PodStatus{
-				Conditions: []corev1.PodCondition{
-					{
-						Type:   corev1.PodReady,
-						Status: corev1.ConditionTrue},
-				},
-			},
+			name:           "failed to get processors",
+			oldSetOnDelete: true,
+			processorsErr:  true,
+			errorExpect:    true,
+			expectStrategy: apps.OnDeleteStatefulSetStrategyType,
 		},
 		{
-			TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      ticdcPodName(upgradeTcName, 1),
-				Namespace: corev1.NamespaceDefault,
-				Labels:    lu,
-			},
-			Status: corev1.PodStatus{
-				Conditions: []corev1.PodCondition{
-					{
-						Type:   corev1.PodReady,
-						Status: corev1.ConditionTrue},
-				},
-			},
+			name:           "restore RollingUpdate after all pods are upgraded",
+			updatedPods:    []int32{0, 1},
+			oldSetOnDelete: true,
+			expectStrategy: apps.RollingUpdateStatefulSetStrategyType,
 		},
 	}
-	return pods
+
+	for _, test := range tests {
+		testFn(test)
+	}
+}
+
+func newTiCDCUpgrader() (Upgrader, podinformers.PodInformer) {
+	fakeDeps := controller.NewFakeDependencies()
+	upgrader := &ticdcUpgrader{deps: fakeDeps, recorder: fakeDeps.Recorder}
+	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
+	return upgrader, podInformer
 }
@@ -0,0 +1,164 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// configUpdatedReason is the reason of the ConfigRestartRequired condition if the config is updated in
+	// place and the TiDB instances have not been verified yet
+	configUpdatedReason = "ConfigUpdated"
+	// configNotAdoptedReason is the reason of the ConfigRestartRequired condition if some TiDB instances
+	// serve the config different from the one in spec
+	configNotAdoptedReason = "ConfigNotAdopted"
+	// configAdoptedReason is the reason of the ConfigRestartRequired condition if all the TiDB instances
+	// have adopted the config in spec
+	configAdoptedReason = "ConfigAdopted"
+
+	// tidbConfigReloadCheckInterval is the min interval between the checks of the config served by the TiDB
+	// instances, it also gives the kubelet time to sync the ConfigMap updated in place to the pods
+	tidbConfigReloadCheckInterval = time.Minute
+)
+
+// recordTiDBConfigUpdatedInPlace sets the ConfigRestartRequired condition to Unknown if the ConfigMap in use
+// is about to be updated in place, so that syncTiDBConfigReload verifies the TiDB instances after the change.
+func recordTiDBConfigUpdatedInPlace(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, inUseName string, cm *corev1.ConfigMap) {
+	if cm == nil || inUseName == "" || cm.Name != inUseName || tc.BaseTiDBSpec().ConfigUpdateStrategy() != v1alpha1.ConfigUpdateStrategyInPlace {
+		return
+	}
+	inUse, err := deps.ConfigMapLister.ConfigMaps(tc.GetNamespace()).Get(inUseName)
+	if err != nil || apiequality.Semantic.DeepEqual(inUse.Data, cm.Data) {
+		return
+	}
+	setTiDBConfigRestartRequired(tc, corev1.ConditionUnknown, configUpdatedReason,
+		"the config is updated in place, the TiDB instances are being verified")
+}
+
+// syncTiDBConfigReload verifies that the TiDB instances have adopted the config updated in place, as the
+// ConfigMap is updated without restarting the pods if the config update strategy is InPlace and most of the
+// items only take effect after a restart. It only runs after recordTiDBConfigUpdatedInPlace observes a change
+// or while some instances have not adopted it, at most once per tidbConfigReloadCheckInterval. The items set
+// in spec are the sentinels compared with the config served by the status port of each healthy instance, and
+// the ConfigRestartRequired condition is set if any instance does not adopt them. The items missing in the
+// served config can not be verified and are skipped.
+func (m *tidbMemberManager) syncTiDBConfigReload(tc *v1alpha1.TidbCluster) {
+	if tc.Spec.TiDB.Config == nil || tc.BaseTiDBSpec().ConfigUpdateStrategy() != v1alpha1.ConfigUpdateStrategyInPlace {
+		return
+	}
+	cond := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterConfigRestartRequired)
+	if cond == nil || cond.Status == corev1.ConditionFalse || time.Since(cond.LastUpdateTime.Time) < tidbConfigReloadCheckInterval {
+		return
+	}
+	desired := map[string]string{}
+	flattenConfig("", tc.Spec.TiDB.Config.Inner(), desired)
+
+	var notAdopted, unknown []string
+	for name, member := range tc.Status.TiDB.Members {
+		if !member.Health {
+			continue
+		}
+		ordinal, err := util.GetOrdinalFromPodName(name)
+		if err != nil {
+			continue
+		}
+		settings, err := m.deps.TiDBControl.GetSettings(tc, ordinal)
+		if err != nil || settings == nil {
+			klog.Warningf("tidbcluster: [%s/%s] failed to get the config of %s: %v", tc.Namespace, tc.Name, name, err)
+			unknown = append(unknown, name)
+			continue
+		}
+		data, err := json.Marshal(settings)
+		if err != nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		served := map[string]interface{}{}
+		if err := json.Unmarshal(data, &served); err != nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		actual := map[string]string{}
+		flattenConfig("", served, actual)
+		var keys []string
+		for key, value := range desired {
+			if v, ok := actual[key]; ok && v != value {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			notAdopted = append(notAdopted, fmt.Sprintf("%s (%s)", name, strings.Join(keys, ", ")))
+		}
+	}
+
+	if len(notAdopted) > 0 {
+		sort.Strings(notAdopted)
+		msg := fmt.Sprintf("TiDB instances have not adopted the config updated in place, restart them to apply it: %s", strings.Join(notAdopted, "; "))
+		klog.Warningf("tidbcluster: [%s/%s] %s", tc.Namespace, tc.Name, msg)
+		setTiDBConfigRestartRequired(tc, corev1.ConditionTrue, configNotAdoptedReason, msg)
+		return
+	}
+	if len(unknown) > 0 {
+		// checked again after the interval
+		sort.Strings(unknown)
+		setTiDBConfigRestartRequired(tc, cond.Status, cond.Reason,
+			fmt.Sprintf("failed to get the config of the TiDB instances: %s", strings.Join(unknown, ", ")))
+		return
+	}
+	setTiDBConfigRestartRequired(tc, corev1.ConditionFalse, configAdoptedReason, "all the TiDB instances have adopted the config")
+}
+
+// setTiDBConfigRestartRequired sets the ConfigRestartRequired condition, its message and last update time are
+// refreshed on each check even if its status and reason are not changed.
+func setTiDBConfigRestartRequired(tc *v1alpha1.TidbCluster, status corev1.ConditionStatus, reason, msg string) {
+	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterConfigRestartRequired, status, reason, msg)
+	if cur := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterConfigRestartRequired); cur != nil && cur.Status == status {
+		cond.LastTransitionTime = cur.LastTransitionTime
+	}
+	utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterConfigRestartRequired)
+	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
+}
+
+// flattenConfig flattens the nested config into the dotted keys, the values are encoded in JSON
+// so that the numbers decoded from TOML and JSON are compared equally.
+func flattenConfig(prefix string, config map[string]interface{}, out map[string]string) {
+	for key, value := range config {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if table, ok := value.(map[string]interface{}); ok {
+			flattenConfig(key, table, out)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		out[key] = string(data)
+	}
+}
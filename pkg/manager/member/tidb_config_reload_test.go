@@ -0,0 +1,129 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	"github.com/pingcap/tidb/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncTiDBConfigReload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	m := &tidbMemberManager{deps: deps}
+	tidbControl := deps.TiDBControl.(*controller.FakeTiDBControl)
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	tc.Spec.TiDB.Config.Set("token-limit", 2000)
+	tc.Spec.TiDB.Config.Set("log.level", "warn")
+	tc.Spec.TiDB.Config.Set("unknown-item", true)
+	tc.Status.TiDB.Members = map[string]v1alpha1.TiDBMember{
+		"test-tidb-0": {Name: "test-tidb-0", Health: true},
+		"test-tidb-1": {Name: "test-tidb-1", Health: false},
+	}
+	condition := func() *v1alpha1.TidbClusterCondition {
+		return utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterConfigRestartRequired)
+	}
+	// elapse makes the next check due
+	elapse := func() {
+		for i := range tc.Status.Conditions {
+			tc.Status.Conditions[i].LastUpdateTime = metav1.NewTime(time.Now().Add(-tidbConfigReloadCheckInterval))
+		}
+	}
+	settings := config.NewConfig()
+	settings.TokenLimit = 1000
+	settings.Log.Level = "warn"
+	tidbControl.SetSettings(settings)
+
+	// the instances are not checked if the config is not updated in place
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition()).To(BeNil())
+
+	// the instances are checked after the interval since the config is updated
+	setTiDBConfigRestartRequired(tc, corev1.ConditionUnknown, configUpdatedReason, "updated")
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionUnknown))
+	elapse()
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(condition().Reason).To(Equal(configNotAdoptedReason))
+	g.Expect(condition().Message).To(ContainSubstring("test-tidb-0 (token-limit)"))
+	g.Expect(condition().Message).NotTo(ContainSubstring("test-tidb-1"))
+
+	// the config is adopted after the restart and found at the next check
+	settings.TokenLimit = 2000
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionTrue))
+	elapse()
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionFalse))
+	g.Expect(condition().Reason).To(Equal(configAdoptedReason))
+
+	// the instances are not checked again until the next change
+	settings.TokenLimit = 1000
+	elapse()
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionFalse))
+
+	// the config not served is not reported as adopted
+	setTiDBConfigRestartRequired(tc, corev1.ConditionUnknown, configUpdatedReason, "updated")
+	tidbControl.SetSettings(nil)
+	elapse()
+	m.syncTiDBConfigReload(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(condition().Message).To(ContainSubstring("failed to get the config of the TiDB instances: test-tidb-0"))
+}
+
+func TestRecordTiDBConfigUpdatedInPlace(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	inUse, err := getTiDBConfigMap(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.LabelFilterKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(inUse)).To(Succeed())
+	condition := func() *v1alpha1.TidbClusterCondition {
+		return utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterConfigRestartRequired)
+	}
+
+	// the same config
+	recordTiDBConfigUpdatedInPlace(deps, tc, inUse.Name, inUse.DeepCopy())
+	g.Expect(condition()).To(BeNil())
+
+	// the config is updated in place
+	tc.Spec.TiDB.Config.Set("token-limit", 2000)
+	cm, err := getTiDBConfigMap(tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	cm.Name = inUse.Name
+	recordTiDBConfigUpdatedInPlace(deps, tc, inUse.Name, cm)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(condition().Reason).To(Equal(configUpdatedReason))
+
+	// the config is rolled out with the new pods
+	tc.Status.Conditions = nil
+	strategy := v1alpha1.ConfigUpdateStrategyRollingUpdate
+	tc.Spec.TiDB.ConfigUpdateStrategy = &strategy
+	recordTiDBConfigUpdatedInPlace(deps, tc, inUse.Name, cm)
+	g.Expect(condition()).To(BeNil())
+}
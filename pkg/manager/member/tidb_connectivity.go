@@ -0,0 +1,185 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultConnectivityCheckInterval = 5 * time.Minute
+	// storeHeartbeatTimeout is the age of the last heartbeat after which a store is considered
+	// disconnected from PD, the stores send the heartbeats every 10s by default
+	storeHeartbeatTimeout = time.Minute
+
+	// connectivityBrokenReason is the reason of the ComponentConnectivity condition if some members
+	// fail to reach another component
+	connectivityBrokenReason = "ConnectivityBroken"
+	// connectivityUnknownReason is the reason of the ComponentConnectivity condition if none of the
+	// members can be probed, e.g. the client certificate of the cluster is not available
+	connectivityUnknownReason = "ConnectivityUnknown"
+	// connectedReason is the reason of the ComponentConnectivity condition if all the members probed
+	// reach the other components
+	connectedReason = "Connected"
+)
+
+// syncConnectivity probes the connectivity between the components if the connectivity checks are
+// enabled. Each healthy TiDB instance is asked through its status port whether it reaches PD and
+// TiKV, and PD is asked for the recency of the heartbeats of the TiKV stores. The result is kept in
+// status.connectivity and the ComponentConnectivity condition names the first broken edge. The probe
+// runs at most once per interval.
+func (m *tidbMemberManager) syncConnectivity(tc *v1alpha1.TidbCluster) {
+	checks := tc.Spec.ConnectivityChecks
+	if checks == nil || !checks.Enabled {
+		tc.Status.Connectivity = nil
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterComponentConnectivity)
+		return
+	}
+	interval := defaultConnectivityCheckInterval
+	if checks.Interval != nil {
+		if d, err := time.ParseDuration(*checks.Interval); err == nil && d > 0 {
+			interval = d
+		}
+	}
+	if tc.Status.Connectivity != nil && time.Since(tc.Status.Connectivity.LastProbeTime.Time) < interval {
+		return
+	}
+
+	edges := m.probeTiDBConnectivity(tc)
+	edges = append(edges, m.probeStoreHeartbeats(tc))
+	tc.Status.Connectivity = &v1alpha1.ConnectivityStatus{
+		LastProbeTime: metav1.Now(),
+		Edges:         edges,
+	}
+
+	var connected int32
+	for _, edge := range edges {
+		connected += edge.Connected
+		if len(edge.Broken) > 0 {
+			msg := fmt.Sprintf("%s can not reach %s: %s", edge.From, edge.To, strings.Join(edge.Broken, ", "))
+			klog.Warningf("tidbcluster: [%s/%s] %s", tc.Namespace, tc.Name, msg)
+			setConnectivityCondition(tc, corev1.ConditionFalse, connectivityBrokenReason, msg)
+			return
+		}
+	}
+	if connected == 0 {
+		setConnectivityCondition(tc, corev1.ConditionUnknown, connectivityUnknownReason, "none of the members can be probed")
+		return
+	}
+	setConnectivityCondition(tc, corev1.ConditionTrue, connectedReason, "all the members probed reach the other components")
+}
+
+// setConnectivityCondition sets the ComponentConnectivity condition, the message is updated even if the
+// status and the reason do not change as it names the broken edge
+func setConnectivityCondition(tc *v1alpha1.TidbCluster, status corev1.ConditionStatus, reason, msg string) {
+	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterComponentConnectivity, status, reason, msg)
+	current := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterComponentConnectivity)
+	if current != nil && current.Status == status && current.Reason == reason && current.Message != msg {
+		cond.LastTransitionTime = current.LastTransitionTime
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterComponentConnectivity)
+	}
+	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
+}
+
+// probeTiDBConnectivity returns the edges from TiDB to PD and TiKV, the unhealthy instances and the ones
+// that can not be probed are unknown
+func (m *tidbMemberManager) probeTiDBConnectivity(tc *v1alpha1.TidbCluster) []v1alpha1.ConnectivityEdge {
+	toPD := v1alpha1.ConnectivityEdge{From: v1alpha1.TiDBMemberType, To: v1alpha1.PDMemberType}
+	toTiKV := v1alpha1.ConnectivityEdge{From: v1alpha1.TiDBMemberType, To: v1alpha1.TiKVMemberType}
+
+	names := make([]string, 0, len(tc.Status.TiDB.Members))
+	for name := range tc.Status.TiDB.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ordinal, err := util.GetOrdinalFromPodName(name)
+		if err != nil || !tc.Status.TiDB.Members[name].Health {
+			toPD.Unknown = append(toPD.Unknown, name)
+			toTiKV.Unknown = append(toTiKV.Unknown, name)
+			continue
+		}
+		connectivity, err := m.deps.TiDBControl.GetConnectivity(tc, ordinal)
+		if err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] failed to probe the connectivity of %s: %v", tc.Namespace, tc.Name, name, err)
+			toPD.Unknown = append(toPD.Unknown, name)
+			toTiKV.Unknown = append(toTiKV.Unknown, name)
+			continue
+		}
+		addToEdge(tc, &toPD, name, connectivity.PD)
+		addToEdge(tc, &toTiKV, name, connectivity.TiKV)
+	}
+	return []v1alpha1.ConnectivityEdge{toPD, toTiKV}
+}
+
+// probeStoreHeartbeats returns the edge from TiKV to PD, a store is disconnected if PD has not received
+// its heartbeat for a while
+func (m *tidbMemberManager) probeStoreHeartbeats(tc *v1alpha1.TidbCluster) v1alpha1.ConnectivityEdge {
+	edge := v1alpha1.ConnectivityEdge{From: v1alpha1.TiKVMemberType, To: v1alpha1.PDMemberType}
+
+	ids := make([]string, 0, len(tc.Status.TiKV.Stores))
+	for id := range tc.Status.TiKV.Stores {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return edge
+	}
+	sort.Strings(ids)
+
+	heartbeats := map[string]time.Time{}
+	storesInfo, err := controller.GetPDClient(m.deps.PDControl, tc).GetStores()
+	if err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to get the heartbeats of the stores: %v", tc.Namespace, tc.Name, err)
+	} else {
+		for _, store := range storesInfo.Stores {
+			if store.Store == nil || store.Status == nil {
+				continue
+			}
+			heartbeats[fmt.Sprintf("%d", store.Store.GetId())] = store.Status.LastHeartbeatTS
+		}
+	}
+	for _, id := range ids {
+		name := tc.Status.TiKV.Stores[id].PodName
+		heartbeat, ok := heartbeats[id]
+		switch {
+		case !ok || heartbeat.IsZero():
+			edge.Unknown = append(edge.Unknown, name)
+		case time.Since(heartbeat) > storeHeartbeatTimeout:
+			addToEdge(tc, &edge, name, fmt.Errorf("the last heartbeat is at %s", heartbeat.Format(time.RFC3339)))
+		default:
+			addToEdge(tc, &edge, name, nil)
+		}
+	}
+	return edge
+}
+
+func addToEdge(tc *v1alpha1.TidbCluster, edge *v1alpha1.ConnectivityEdge, name string, err error) {
+	if err == nil {
+		edge.Connected++
+		return
+	}
+	klog.Warningf("tidbcluster: [%s/%s] %s can not reach %s: %v", tc.Namespace, tc.Name, name, edge.To, err)
+	edge.Broken = append(edge.Broken, name)
+}
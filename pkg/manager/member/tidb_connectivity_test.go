@@ -0,0 +1,131 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestSyncConnectivity(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	m := &tidbMemberManager{deps: deps}
+	tidbControl := deps.TiDBControl.(*controller.FakeTiDBControl)
+	tc := newTidbClusterForTiDB()
+	tc.Status.TiDB.Members = map[string]v1alpha1.TiDBMember{
+		"test-tidb-0": {Name: "test-tidb-0", Health: true},
+		"test-tidb-1": {Name: "test-tidb-1", Health: true},
+		"test-tidb-2": {Name: "test-tidb-2", Health: false},
+	}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0"},
+		"2": {ID: "2", PodName: "test-tikv-1"},
+	}
+	heartbeats := map[uint64]time.Time{1: time.Now(), 2: time.Now()}
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetStoresActionType, func(action *pdapi.Action) (interface{}, error) {
+		stores := &pdapi.StoresInfo{}
+		for id, heartbeat := range heartbeats {
+			stores.Stores = append(stores.Stores, &pdapi.StoreInfo{
+				Store:  &pdapi.MetaStore{Store: &metapb.Store{Id: id}},
+				Status: &pdapi.StoreStatus{LastHeartbeatTS: heartbeat},
+			})
+		}
+		return stores, nil
+	})
+	condition := func() *v1alpha1.TidbClusterCondition {
+		return utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterComponentConnectivity)
+	}
+	probe := func() {
+		// skip the rate limiting
+		tc.Status.Connectivity = nil
+		m.syncConnectivity(tc)
+	}
+
+	// disabled
+	m.syncConnectivity(tc)
+	g.Expect(tc.Status.Connectivity).To(BeNil())
+	g.Expect(condition()).To(BeNil())
+
+	// none of the TiDB instances can be probed
+	tc.Spec.ConnectivityChecks = &v1alpha1.ConnectivityChecks{Enabled: true}
+	heartbeats = map[uint64]time.Time{}
+	probe()
+	g.Expect(condition().Status).To(Equal(corev1.ConditionUnknown))
+	g.Expect(tc.Status.Connectivity.Edges).To(HaveLen(3))
+	g.Expect(tc.Status.Connectivity.Edges[0].Unknown).To(ConsistOf("test-tidb-0", "test-tidb-1", "test-tidb-2"))
+	g.Expect(tc.Status.Connectivity.Edges[2].Unknown).To(ConsistOf("test-tikv-0", "test-tikv-1"))
+
+	// connected
+	tidbControl.SetConnectivity(map[string]*controller.TiDBConnectivity{
+		"test-tidb-0": {},
+		"test-tidb-1": {},
+	})
+	heartbeats = map[uint64]time.Time{1: time.Now(), 2: time.Now()}
+	probe()
+	g.Expect(condition().Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(tc.Status.Connectivity.Edges[0]).To(Equal(v1alpha1.ConnectivityEdge{
+		From: v1alpha1.TiDBMemberType, To: v1alpha1.PDMemberType, Connected: 2, Unknown: []string{"test-tidb-2"},
+	}))
+	g.Expect(tc.Status.Connectivity.Edges[2].Connected).To(Equal(int32(2)))
+
+	// TiDB can not reach TiKV
+	tidbControl.SetConnectivity(map[string]*controller.TiDBConnectivity{
+		"test-tidb-0": {},
+		"test-tidb-1": {TiKV: fmt.Errorf("region unavailable")},
+	})
+	probe()
+	g.Expect(condition().Status).To(Equal(corev1.ConditionFalse))
+	g.Expect(condition().Reason).To(Equal(connectivityBrokenReason))
+	g.Expect(condition().Message).To(Equal("tidb can not reach tikv: test-tidb-1"))
+
+	// the first broken edge is named
+	heartbeats[2] = time.Now().Add(-10 * time.Minute)
+	tidbControl.SetConnectivity(map[string]*controller.TiDBConnectivity{
+		"test-tidb-0": {PD: fmt.Errorf("pd unavailable"), TiKV: fmt.Errorf("region unavailable")},
+		"test-tidb-1": {},
+	})
+	probe()
+	g.Expect(condition().Message).To(Equal("tidb can not reach pd: test-tidb-0"))
+	g.Expect(tc.Status.Connectivity.Edges[2].Broken).To(ConsistOf("test-tikv-1"))
+
+	// rate limited
+	tc.Spec.ConnectivityChecks.Interval = pointer.StringPtr("1h")
+	tc.Status.Connectivity.LastProbeTime = metav1.NewTime(time.Now().Add(-time.Minute))
+	tidbControl.SetConnectivity(map[string]*controller.TiDBConnectivity{
+		"test-tidb-0": {},
+		"test-tidb-1": {},
+	})
+	m.syncConnectivity(tc)
+	g.Expect(condition().Status).To(Equal(corev1.ConditionFalse))
+
+	// disabled again
+	tc.Spec.ConnectivityChecks.Enabled = false
+	m.syncConnectivity(tc)
+	g.Expect(tc.Status.Connectivity).To(BeNil())
+	g.Expect(condition()).To(BeNil())
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
@@ -90,7 +91,7 @@ func (f *tidbFailover) Failover(tc *v1alpha1.TidbCluster) error {
 				CreatedAt: metav1.Now(),
 			}
 			msg := fmt.Sprintf("tidb[%s] is unhealthy", tidbMember.Name)
-			f.deps.Recorder.Event(tc, corev1.EventTypeWarning, unHealthEventReason, fmt.Sprintf(unHealthEventMsgPattern, "tidb", tidbMember.Name, msg))
+			f.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.Unhealthy, fmt.Sprintf(unHealthEventMsgPattern, "tidb", tidbMember.Name, msg))
 			break
 		}
 	}
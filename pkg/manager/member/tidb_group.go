@@ -0,0 +1,306 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+)
+
+// syncTiDBGroups syncs the statefulsets, the services and the configmaps of the groups in spec.tidbGroups and
+// refreshes their status. The groups are scaled and upgraded by the scaler and the upgrader of the default group
+// of spec.tidb, they are upgraded one at a time in the order of the list after the default group is upgraded, the
+// template of a group is kept until the groups before it finish their upgrades.
+func (m *tidbMemberManager) syncTiDBGroups(tc *v1alpha1.TidbCluster) error {
+	if len(tc.Spec.TiDBGroups) == 0 && len(tc.Status.TiDBGroups) == 0 {
+		return nil
+	}
+	if tc.IsComponentPaused(v1alpha1.TiDBMemberType) {
+		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for tidb groups", tc.GetNamespace(), tc.GetName())
+		return nil
+	}
+	if tc.Status.TiDBGroups == nil {
+		tc.Status.TiDBGroups = map[string]v1alpha1.TiDBStatus{}
+	}
+
+	upgradable := tc.Status.TiDB.Phase != v1alpha1.UpgradePhase &&
+		upgradeBlockedBy(tc, v1alpha1.TiDBMemberType) == "" &&
+		!controller.IsUpgradeFrozen(m.deps) &&
+		!tc.IsUpgradePaused()
+	groups := sets.NewString()
+	// the groups after a failed one are still scaled and their status is refreshed, the first error is returned
+	var firstErr error
+	for i := range tc.Spec.TiDBGroups {
+		group := &tc.Spec.TiDBGroups[i]
+		groups.Insert(group.Name)
+		upgrading, err := m.syncTiDBGroup(tc, group, upgradable)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if upgrading || err != nil {
+			upgradable = false
+		}
+	}
+	if err := m.cleanupTiDBGroups(tc, groups); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// syncTiDBGroup syncs a tidb group and returns whether the group is upgrading, the new template of the group is
+// applied only if upgradable is true
+func (m *tidbMemberManager) syncTiDBGroup(tc *v1alpha1.TidbCluster, group *v1alpha1.TiDBGroup, upgradable bool) (bool, error) {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	setName := controller.TiDBGroupMemberName(tcName, group.Name)
+	groupTC := tidbGroupCluster(tc, group)
+	defer func() {
+		tc.Status.TiDBGroups[group.Name] = groupTC.Status.TiDB
+	}()
+
+	if err := m.syncTiDBHeadlessService(tc, getNewTiDBGroupHeadlessService(groupTC, group.Name)); err != nil {
+		return false, err
+	}
+	if svc := getNewTiDBGroupService(groupTC, group.Name); svc != nil {
+		if err := m.syncTiDBClientService(tc, svc); err != nil {
+			return false, err
+		}
+	}
+
+	oldSetTmp, err := m.deps.StatefulSetLister.StatefulSets(ns).Get(setName)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, fmt.Errorf("syncTiDBGroup: failed to get sts %s for cluster %s/%s, error: %s", setName, ns, tcName, err)
+	}
+	setNotExist := errors.IsNotFound(err)
+	oldSet := oldSetTmp.DeepCopy()
+
+	if err := m.syncTidbClusterStatus(groupTC, oldSet); err != nil {
+		return false, err
+	}
+
+	cm, err := m.syncTiDBGroupConfigMap(tc, groupTC, group.Name, oldSet)
+	if err != nil {
+		return false, err
+	}
+	newSet, err := getNewTiDBGroupSet(groupTC, group.Name, cm)
+	if err != nil {
+		return false, err
+	}
+
+	if setNotExist {
+		if err := mngerutils.SetStatefulSetLastAppliedConfigAnnotation(newSet); err != nil {
+			return false, err
+		}
+		if err := m.deps.StatefulSetControl.CreateStatefulSet(tc, newSet); err != nil {
+			return false, err
+		}
+		groupTC.Status.TiDB.StatefulSet = &apps.StatefulSetStatus{}
+		return false, nil
+	}
+
+	if err := m.scaler.Scale(groupTC, oldSet, newSet); err != nil {
+		return false, err
+	}
+	if err := cleanupTiDBWarmStandbyPods(m.deps, groupTC); err != nil {
+		return false, err
+	}
+
+	changed := !templateEqual(newSet, oldSet)
+	if changed && !upgradable {
+		klog.Infof("TidbCluster: [%s/%s]'s tidb group %s waits for the upgrade of the default group or the groups before it",
+			ns, tcName, group.Name)
+		spec, _, err := GetLastAppliedConfig(oldSet)
+		if err != nil {
+			return false, err
+		}
+		newSet.Spec.Template = spec.Template
+		changed = false
+	}
+	if changed || groupTC.Status.TiDB.Phase == v1alpha1.UpgradePhase {
+		if err := m.tidbUpgrader.Upgrade(groupTC, oldSet, newSet); err != nil {
+			return true, err
+		}
+	}
+	if err := mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newSet, oldSet); err != nil {
+		return false, err
+	}
+	return changed || groupTC.Status.TiDB.Phase == v1alpha1.UpgradePhase, nil
+}
+
+// syncTiDBGroupConfigMap syncs the configmap of a tidb group
+func (m *tidbMemberManager) syncTiDBGroupConfigMap(tc, groupTC *v1alpha1.TidbCluster, groupName string, set *apps.StatefulSet) (*corev1.ConfigMap, error) {
+	newCm, err := getTiDBConfigMap(groupTC)
+	if err != nil || newCm == nil {
+		return nil, err
+	}
+	name := controller.TiDBGroupMemberName(tc.GetName(), groupName)
+	newCm.Name = name
+	newCm.Labels = tidbGroupSelector(tc, groupName).Labels()
+
+	var inUseName string
+	if set != nil {
+		inUseName = mngerutils.FindConfigMapVolume(&set.Spec.Template.Spec, func(cmName string) bool {
+			return strings.HasPrefix(cmName, name)
+		})
+	}
+	err = mngerutils.UpdateConfigMapIfNeed(m.deps.ConfigMapLister, groupTC.BaseTiDBSpec().ConfigUpdateStrategy(), inUseName, newCm)
+	if err != nil {
+		return nil, err
+	}
+	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
+}
+
+// cleanupTiDBGroups deletes the statefulsets and the services of the groups removed from spec.tidbGroups and
+// drops their status
+func (m *tidbMemberManager) cleanupTiDBGroups(tc *v1alpha1.TidbCluster, groups sets.String) error {
+	ns := tc.GetNamespace()
+	for name := range tc.Status.TiDBGroups {
+		if !groups.Has(name) {
+			delete(tc.Status.TiDBGroups, name)
+		}
+	}
+	if len(tc.Status.TiDBGroups) == 0 {
+		tc.Status.TiDBGroups = nil
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiDB().Selector()
+	if err != nil {
+		return err
+	}
+	setList, err := m.deps.StatefulSetLister.StatefulSets(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("cleanupTiDBGroups: failed to list sts for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, set := range setList {
+		name := tidbGroupOf(set)
+		if name == "" || groups.Has(name) || !metav1.IsControlledBy(set, tc) {
+			continue
+		}
+		for _, svcName := range []string{
+			controller.TiDBGroupMemberName(tc.GetName(), name),
+			controller.TiDBGroupPeerMemberName(tc.GetName(), name),
+		} {
+			svc, err := m.deps.ServiceLister.Services(ns).Get(svcName)
+			if errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("cleanupTiDBGroups: failed to get svc %s for cluster %s/%s, error: %v", svcName, ns, tc.GetName(), err)
+			}
+			if !metav1.IsControlledBy(svc, tc) {
+				continue
+			}
+			if err := m.deps.ServiceControl.DeleteService(tc, svc); err != nil {
+				return err
+			}
+		}
+		klog.Infof("TidbCluster: [%s/%s] deletes the statefulset %s of the removed tidb group %s", ns, tc.GetName(), set.Name, name)
+		if err := m.deps.StatefulSetControl.DeleteStatefulSet(tc, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tidbGroupCluster returns a copy of tc whose spec.tidb and status.tidb are the ones of the group, the objects of
+// the group are built, scaled and upgraded from it by the builders, the scaler and the upgrader of the default
+// group. It is labeled by the group, so that the tidb pods and their addresses are named after the statefulset
+// of the group, and the delete slots of the default group are dropped.
+func tidbGroupCluster(tc *v1alpha1.TidbCluster, group *v1alpha1.TiDBGroup) *v1alpha1.TidbCluster {
+	groupTC := tc.DeepCopy()
+	if groupTC.Labels == nil {
+		groupTC.Labels = map[string]string{}
+	}
+	groupTC.Labels[label.TiDBGroupLabelKey] = group.Name
+	delete(groupTC.Annotations, label.AnnTiDBDeleteSlots)
+	groupTC.Spec.TiDB = tc.TiDBGroupSpec(group)
+	groupTC.Status.TiDB = groupTC.Status.TiDBGroups[group.Name]
+	return groupTC
+}
+
+// tidbGroupOf returns the name of the tidb group of the object, it is empty for the pods of spec.tidb and the
+// cluster itself
+func tidbGroupOf(obj metav1.Object) string {
+	return obj.GetLabels()[label.TiDBGroupLabelKey]
+}
+
+func tidbGroupSelector(tc *v1alpha1.TidbCluster, groupName string) label.Label {
+	return label.New().Instance(tc.GetInstanceName()).TiDBGroup(groupName)
+}
+
+func getNewTiDBGroupHeadlessService(groupTC *v1alpha1.TidbCluster, groupName string) *corev1.Service {
+	svc := getNewTiDBHeadlessServiceForTidbCluster(groupTC)
+	selector := tidbGroupSelector(groupTC, groupName)
+	svc.Name = controller.TiDBGroupPeerMemberName(groupTC.GetName(), groupName)
+	svc.Labels = selector.Copy().UsedByPeer().Labels()
+	svc.Spec.Selector = selector.Labels()
+	return svc
+}
+
+func getNewTiDBGroupService(groupTC *v1alpha1.TidbCluster, groupName string) *corev1.Service {
+	svc := getNewTiDBServiceOrNil(groupTC)
+	if svc == nil {
+		return nil
+	}
+	selector := tidbGroupSelector(groupTC, groupName)
+	svc.Name = controller.TiDBGroupMemberName(groupTC.GetName(), groupName)
+	svc.Labels = util.CombineStringMap(selector.Copy().UsedByEndUser().Labels(), groupTC.Spec.TiDB.Service.Labels)
+	svc.Spec.Selector = selector.Labels()
+	return svc
+}
+
+// getNewTiDBGroupSet builds the statefulset of a tidb group, its pods are labeled as tidb and by the group. The
+// partition is kept by the upgrader like the one of the default group, the delete slots and the failover of the
+// default group do not apply
+func getNewTiDBGroupSet(groupTC *v1alpha1.TidbCluster, groupName string, cm *corev1.ConfigMap) (*apps.StatefulSet, error) {
+	set, err := getNewTiDBSetForTidbCluster(groupTC, cm)
+	if err != nil {
+		return nil, err
+	}
+	selector := tidbGroupSelector(groupTC, groupName)
+	headlessSvcName := controller.TiDBGroupPeerMemberName(groupTC.GetName(), groupName)
+
+	set.Name = controller.TiDBGroupMemberName(groupTC.GetName(), groupName)
+	set.Labels = util.CombineStringMap(selector.Copy().Labels(), changeRequestLabels(groupTC))
+	set.Annotations = nil
+	set.Spec.Replicas = pointer.Int32Ptr(groupTC.Spec.TiDB.Replicas)
+	set.Spec.Selector = selector.LabelSelector()
+	set.Spec.Template.Labels = util.CombineStringMap(selector.Copy().Labels(), groupTC.BaseTiDBSpec().Labels())
+	set.Spec.ServiceName = headlessSvcName
+	for i := range set.Spec.Template.Spec.Containers {
+		c := &set.Spec.Template.Spec.Containers[i]
+		if c.Name != v1alpha1.TiDBMemberType.String() {
+			continue
+		}
+		for j := range c.Env {
+			if c.Env[j].Name == "HEADLESS_SERVICE_NAME" {
+				c.Env[j].Value = headlessSvcName
+			}
+		}
+	}
+	return set, nil
+}
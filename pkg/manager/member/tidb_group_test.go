@@ -0,0 +1,192 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newTidbClusterWithTiDBGroups() *v1alpha1.TidbCluster {
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	tc.Spec.TiDB.Service = &v1alpha1.TiDBServiceSpec{ServiceSpec: v1alpha1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}
+	tc.Spec.TiDBGroups = []v1alpha1.TiDBGroup{
+		{Name: "oltp", Replicas: 2, Labels: map[string]string{"workload": "oltp"}},
+		{Name: "olap", Replicas: 1},
+	}
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"tikv-0": {PodName: "tikv-0", State: v1alpha1.TiKVStateUp},
+	}
+	tc.Status.TiKV.StatefulSet = &apps.StatefulSetStatus{ReadyReplicas: 1}
+	return tc
+}
+
+func TestTiDBGroupsSyncCreate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterWithTiDBGroups()
+	tc.Annotations = map[string]string{label.AnnChangeRequestID: "CR-1024"}
+	tmm, _, _, _ := newFakeTiDBMemberManager()
+	g.Expect(tmm.Sync(tc)).To(Succeed())
+
+	for _, group := range tc.Spec.TiDBGroups {
+		name := controller.TiDBGroupMemberName(tc.Name, group.Name)
+		set, err := tmm.deps.StatefulSetLister.StatefulSets(tc.Namespace).Get(name)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(set.Labels[label.AnnChangeRequestID]).To(Equal("CR-1024"))
+		g.Expect(*set.Spec.Replicas).To(Equal(group.Replicas))
+		g.Expect(set.Spec.ServiceName).To(Equal(controller.TiDBGroupPeerMemberName(tc.Name, group.Name)))
+		g.Expect(set.Spec.Selector.MatchLabels[label.ComponentLabelKey]).To(Equal(label.TiDBLabelVal))
+		g.Expect(set.Spec.Selector.MatchLabels[label.TiDBGroupLabelKey]).To(Equal(group.Name))
+		g.Expect(set.Spec.Template.Labels[label.TiDBGroupLabelKey]).To(Equal(group.Name))
+		// the pods are upgraded by the upgrader which moves the partition
+		g.Expect(*set.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(group.Replicas))
+		for k, v := range group.Labels {
+			g.Expect(set.Spec.Template.Labels[k]).To(Equal(v))
+		}
+		c := findContainerByName(set, v1alpha1.TiDBMemberType.String())
+		g.Expect(c).NotTo(BeNil())
+		g.Expect(c.Env).To(ContainElement(corev1.EnvVar{Name: "HEADLESS_SERVICE_NAME", Value: set.Spec.ServiceName}))
+
+		svc, err := tmm.deps.ServiceLister.Services(tc.Namespace).Get(name)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(svc.Spec.Selector).To(Equal(set.Spec.Selector.MatchLabels))
+		peer, err := tmm.deps.ServiceLister.Services(tc.Namespace).Get(set.Spec.ServiceName)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(peer.Spec.Selector).To(Equal(set.Spec.Selector.MatchLabels))
+
+		cmName := mngerutils.FindConfigMapVolume(&set.Spec.Template.Spec, func(cmName string) bool {
+			return strings.HasPrefix(cmName, name)
+		})
+		g.Expect(cmName).NotTo(BeEmpty())
+		g.Expect(tc.Status.TiDBGroups).To(HaveKey(group.Name))
+	}
+
+	// the service of the cluster selects the pods of the groups too
+	svc, err := tmm.deps.ServiceLister.Services(tc.Namespace).Get(controller.TiDBMemberName(tc.Name))
+	g.Expect(err).NotTo(HaveOccurred())
+	set, err := tmm.deps.StatefulSetLister.StatefulSets(tc.Namespace).Get(controller.TiDBGroupMemberName(tc.Name, "oltp"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(set.Spec.Template.Labels))).To(BeTrue())
+}
+
+func TestTiDBGroupsSyncStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterWithTiDBGroups()
+	tmm, _, tidbControl, indexers := newFakeTiDBMemberManager()
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+
+	setName := controller.TiDBGroupMemberName(tc.Name, "oltp")
+	set, err := tmm.deps.StatefulSetLister.StatefulSets(tc.Namespace).Get(setName)
+	g.Expect(err).NotTo(HaveOccurred())
+	set = set.DeepCopy()
+	set.Status.Replicas = 2
+	g.Expect(indexers.set.Update(set)).To(Succeed())
+
+	pod := &corev1.Pod{}
+	pod.Name = setName + "-0"
+	pod.Namespace = tc.Namespace
+	pod.Labels = tidbGroupSelector(tc, "oltp").Labels()
+	pod.Spec.NodeName = "node-1"
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	g.Expect(indexers.pod.Add(pod)).To(Succeed())
+	// the health of the members is checked by the status API of tidb like the default group
+	tidbControl.SetHealth(map[string]bool{pod.Name: true, setName + "-1": false})
+
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	status := tc.Status.TiDBGroups["oltp"]
+	g.Expect(status.Phase).To(Equal(v1alpha1.NormalPhase))
+	g.Expect(status.Image).To(Equal(tc.TiDBImage()))
+	g.Expect(status.Members).To(HaveLen(2))
+	g.Expect(status.Members[pod.Name].Health).To(BeTrue())
+	g.Expect(status.Members[pod.Name].NodeName).To(Equal("node-1"))
+	g.Expect(status.Members[setName+"-1"].Health).To(BeFalse())
+	g.Expect(tc.Status.TiDBGroups["olap"].Members).To(BeEmpty())
+	// the pods of the groups are not the members of the default group
+	g.Expect(tc.Status.TiDB.Members).NotTo(HaveKey(pod.Name))
+
+	// the group is scaled by the scaler one pod at a time
+	tc.Spec.TiDBGroups[0].Replicas = 4
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(tc.Status.TiDBGroups["oltp"].Phase).To(Equal(v1alpha1.ScalePhase))
+	set, err = tmm.deps.StatefulSetLister.StatefulSets(tc.Namespace).Get(setName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*set.Spec.Replicas).To(Equal(int32(3)))
+}
+
+func TestTiDBGroupsUpgradeOneAtATime(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterWithTiDBGroups()
+	tmm, _, _, _ := newFakeTiDBMemberManager()
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+
+	groupImage := func(group string) string {
+		set, err := tmm.deps.StatefulSetLister.StatefulSets(tc.Namespace).Get(controller.TiDBGroupMemberName(tc.Name, group))
+		g.Expect(err).NotTo(HaveOccurred())
+		return findContainerByName(set, v1alpha1.TiDBMemberType.String()).Image
+	}
+	oldImage := tc.TiDBImage()
+	tc.Spec.TiDB.Image = "tidb:v2"
+
+	// the groups wait for the upgrade of the default group
+	tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(groupImage("oltp")).To(Equal(oldImage))
+	g.Expect(groupImage("olap")).To(Equal(oldImage))
+
+	// the first group is upgraded and the second one waits for it
+	tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(groupImage("oltp")).To(Equal("tidb:v2"))
+	g.Expect(groupImage("olap")).To(Equal(oldImage))
+
+	// the first group is upgraded by the upgrader
+	g.Expect(tc.Status.TiDBGroups["oltp"].Phase).To(Equal(v1alpha1.UpgradePhase))
+
+	// the second group is upgraded after the first one is done
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(groupImage("olap")).To(Equal("tidb:v2"))
+}
+
+func TestTiDBGroupsCleanup(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterWithTiDBGroups()
+	tmm, _, _, _ := newFakeTiDBMemberManager()
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(tc.Status.TiDBGroups).To(HaveKey("olap"))
+
+	tc.Spec.TiDBGroups = tc.Spec.TiDBGroups[:1]
+	g.Expect(tmm.syncTiDBGroups(tc)).To(Succeed())
+	g.Expect(tc.Status.TiDBGroups).NotTo(HaveKey("olap"))
+	g.Expect(tc.Status.TiDBGroups).To(HaveKey("oltp"))
+	for _, name := range []string{controller.TiDBGroupMemberName(tc.Name, "olap"), controller.TiDBGroupPeerMemberName(tc.Name, "olap")} {
+		_, err := tmm.deps.ServiceLister.Services(tc.Namespace).Get(name)
+		expectErrIsNotFound(g, err)
+	}
+	_, err := tmm.deps.ServiceLister.Services(tc.Namespace).Get(controller.TiDBGroupMemberName(tc.Name, "oltp"))
+	g.Expect(err).NotTo(HaveOccurred())
+}
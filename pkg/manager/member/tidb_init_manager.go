@@ -174,21 +174,36 @@ func (m *tidbInitManager) syncTiDBInitConfigMap(ti *v1alpha1.TidbInitializer, tc
 	if err != nil {
 		return err
 	}
-	if exist {
+	if exist && ti.Status.Phase == v1alpha1.InitializePhaseCompleted {
 		return nil
 	}
 
-	tlsClientEnabled, skipCA := false, false
-	if tc.Spec.TiDB.IsTLSClientEnabled() && !tc.SkipTLSWhenConnectTiDB() {
-		tlsClientEnabled = true
-		skipCA = tc.Spec.TiDB.TLSClient.SkipInternalClientCA
-	}
 	tidbSvcPort := tc.Spec.TiDB.GetServicePort()
-	newCm, err := getTiDBInitConfigMap(ti, tlsClientEnabled, skipCA, tidbSvcPort)
+	newCm, err := getTiDBInitConfigMap(ti, getTiDBInitTLS(ti, tc), tidbSvcPort)
 	if err != nil {
 		return err
 	}
 
+	if exist {
+		if apiequality.Semantic.DeepEqual(cm.Data, newCm.Data) {
+			return nil
+		}
+		// the TLS of the tidb cluster is changed before the initialization completes, e.g. TLS is enabled after
+		// the initializer ran, so the start script is rendered again and the Job is recreated with it
+		klog.Infof("TidbInitializer %s/%s: the TLS of tidbcluster %s is changed, recreate the initializer", ns, ti.Name, tc.Name)
+		if _, err := m.deps.TypedControl.CreateOrUpdateConfigMap(ti, newCm); err != nil {
+			return err
+		}
+		job, err := m.deps.JobLister.Jobs(ns).Get(name)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("TiDBInitializer %s/%s get job %s failed, err: %v", ns, ti.Name, name, err)
+		}
+		return m.deps.JobControl.DeleteJob(ti, job)
+	}
+
 	err = m.deps.TypedControl.Create(ti, newCm)
 	if errors.IsAlreadyExists(err) {
 		klog.Infof("Configmap %s/%s already exists", newCm.Namespace, newCm.Name)
@@ -254,25 +269,42 @@ func (m *tidbInitManager) makeTiDBInitJob(ti *v1alpha1.TidbInitializer) (*batchv
 	var vms []corev1.VolumeMount
 	var vs []corev1.Volume
 
-	if tc.Spec.TiDB.IsTLSClientEnabled() && !tc.SkipTLSWhenConnectTiDB() {
-		secretName := util.TiDBClientTLSSecretName(tcName)
-		if ti.Spec.TLSClientSecretName != nil {
-			secretName = *ti.Spec.TLSClientSecretName
+	if tls := getTiDBInitTLS(ti, tc); tls != nil {
+		if tls.caSecret != "" {
+			if err := util.CheckCASecret(m.deps.SecretLister, ns, tls.caSecret); err != nil {
+				return nil, fmt.Errorf("makeTiDBInitJob: invalid TLS secret for TidbInitializer %s/%s, error: %v", ns, ti.Name, err)
+			}
 		}
-
-		vms = append(vms, corev1.VolumeMount{
-			Name:      "tidb-client-tls",
-			ReadOnly:  true,
-			MountPath: util.TiDBClientTLSPath,
-		})
-		vs = append(vs, corev1.Volume{
-			Name: "tidb-client-tls",
-			VolumeSource: corev1.VolumeSource{
-				Secret: &corev1.SecretVolumeSource{
-					SecretName: secretName,
+		if tls.clientSecret != "" {
+			vms = append(vms, corev1.VolumeMount{
+				Name:      "tidb-client-tls",
+				ReadOnly:  true,
+				MountPath: util.TiDBClientTLSPath,
+			})
+			vs = append(vs, corev1.Volume{
+				Name: "tidb-client-tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: tls.clientSecret,
+					},
 				},
-			},
-		})
+			})
+		}
+		if tls.caSecret != "" && tls.caSecret != tls.clientSecret {
+			vms = append(vms, corev1.VolumeMount{
+				Name:      "tidb-server-ca",
+				ReadOnly:  true,
+				MountPath: util.TiDBServerCAPath,
+			})
+			vs = append(vs, corev1.Volume{
+				Name: "tidb-server-ca",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: tls.caSecret,
+					},
+				},
+			})
+		}
 	}
 	vms = append(vms, corev1.VolumeMount{
 		Name:      startKey,
@@ -384,6 +416,8 @@ func (m *tidbInitManager) makeTiDBInitJob(ti *v1alpha1.TidbInitializer) (*batchv
 			},
 			RestartPolicy: corev1.RestartPolicyNever,
 			Volumes:       vs,
+			Tolerations:   ti.Spec.Tolerations,
+			NodeSelector:  ti.Spec.NodeSelector,
 		},
 	}
 
@@ -395,6 +429,7 @@ func (m *tidbInitManager) makeTiDBInitJob(ti *v1alpha1.TidbInitializer) (*batchv
 		podSpec.Spec.Containers[0].Resources = *ti.Spec.Resources
 		podSpec.Spec.InitContainers[0].Resources = *ti.Spec.Resources
 	}
+	controller.SetJobPodDefaults(m.deps.CLIConfig, &podSpec.Spec)
 
 	job := &batchv1.Job{
 		ObjectMeta: meta,
@@ -407,7 +442,39 @@ func (m *tidbInitManager) makeTiDBInitJob(ti *v1alpha1.TidbInitializer) (*batchv
 	return job, nil
 }
 
-func getTiDBInitConfigMap(ti *v1alpha1.TidbInitializer, tlsClientEnabled bool, skipCA bool, tidbSvcPort int32) (*corev1.ConfigMap, error) {
+// tidbInitTLS is the secrets the initializer connects to the tidb server with
+type tidbInitTLS struct {
+	// clientSecret is the secret of the client certificate, no client certificate is presented if it is empty
+	clientSecret string
+	// caSecret is the secret of the ca.crt, the tidb server is not verified if it is empty
+	caSecret string
+}
+
+// getTiDBInitTLS returns the secrets the initializer connects to the tidb server with, it returns nil if the
+// initializer connects to the tidb server without TLS
+func getTiDBInitTLS(ti *v1alpha1.TidbInitializer, tc *v1alpha1.TidbCluster) *tidbInitTLS {
+	if !tc.Spec.TiDB.IsTLSClientEnabled() || tc.SkipTLSWhenConnectTiDB() {
+		return nil
+	}
+	tls := &tidbInitTLS{}
+	if ti.Spec.TLSCASecretName != nil {
+		tls.caSecret = *ti.Spec.TLSCASecretName
+		if ti.Spec.TLSClientSecretName != nil {
+			tls.clientSecret = *ti.Spec.TLSClientSecretName
+		}
+		return tls
+	}
+	tls.clientSecret = util.TiDBClientTLSSecretName(tc.Name)
+	if ti.Spec.TLSClientSecretName != nil {
+		tls.clientSecret = *ti.Spec.TLSClientSecretName
+	}
+	if !tc.Spec.TiDB.TLSClient.SkipInternalClientCA {
+		tls.caSecret = tls.clientSecret
+	}
+	return tls
+}
+
+func getTiDBInitConfigMap(ti *v1alpha1.TidbInitializer, tls *tidbInitTLS, tidbSvcPort int32) (*corev1.ConfigMap, error) {
 	var initSQL, passwdSet bool
 
 	permitHost := ti.GetPermitHost()
@@ -434,12 +501,20 @@ func getTiDBInitConfigMap(ti *v1alpha1.TidbInitializer, tlsClientEnabled bool, s
 		PasswordSet:     passwdSet,
 		TiDBServicePort: tidbSvcPort,
 	}
-	if tlsClientEnabled {
+	if tls != nil {
 		initModel.TLS = true
-		initModel.SkipCA = skipCA
-		initModel.CAPath = path.Join(util.TiDBClientTLSPath, corev1.ServiceAccountRootCAKey)
-		initModel.CertPath = path.Join(util.TiDBClientTLSPath, corev1.TLSCertKey)
-		initModel.KeyPath = path.Join(util.TiDBClientTLSPath, corev1.TLSPrivateKeyKey)
+		if tls.clientSecret != "" {
+			initModel.CertPath = path.Join(util.TiDBClientTLSPath, corev1.TLSCertKey)
+			initModel.KeyPath = path.Join(util.TiDBClientTLSPath, corev1.TLSPrivateKeyKey)
+		}
+		switch tls.caSecret {
+		case "":
+			initModel.SkipCA = true
+		case tls.clientSecret:
+			initModel.CAPath = path.Join(util.TiDBClientTLSPath, corev1.ServiceAccountRootCAKey)
+		default:
+			initModel.CAPath = path.Join(util.TiDBServerCAPath, corev1.ServiceAccountRootCAKey)
+		}
 	}
 	startScript, err := RenderTiDBInitStartScript(initModel)
 	if err != nil {
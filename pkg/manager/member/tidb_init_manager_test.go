@@ -25,6 +25,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestTiDBInitManagerSync(t *testing.T) {
@@ -128,6 +130,166 @@ func TestTiDBInitManagerSync(t *testing.T) {
 	}
 }
 
+func TestMakeTiDBInitJobDefaults(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tim, _, indexers := newFakeTiDBInitManager()
+	g.Expect(indexers.tc.Add(newTidbClusterForTiDB())).To(Succeed())
+	tim.deps.CLIConfig.JobTolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+	tim.deps.CLIConfig.JobNodeSelector = map[string]string{"dedicated": "init"}
+
+	ti := newTidbInitializerForTiDB()
+	job, err := tim.makeTiDBInitJob(ti)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(job.Spec.Template.Spec.Tolerations).To(Equal(tim.deps.CLIConfig.JobTolerations))
+	g.Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(tim.deps.CLIConfig.JobNodeSelector))
+
+	// the settings of TidbInitializer take precedence
+	ti.Spec.Tolerations = []corev1.Toleration{{Key: "init", Operator: corev1.TolerationOpExists}}
+	ti.Spec.NodeSelector = map[string]string{"init": "true"}
+	job, err = tim.makeTiDBInitJob(ti)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(job.Spec.Template.Spec.Tolerations).To(Equal(ti.Spec.Tolerations))
+	g.Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(ti.Spec.NodeSelector))
+}
+
+func TestMakeTiDBInitJobTLS(t *testing.T) {
+	secretVolume := func(name, secretName string) corev1.Volume {
+		return corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+			},
+		}
+	}
+	tests := []struct {
+		name        string
+		tlsClient   bool
+		tlsCASecret *string
+		secrets     []*corev1.Secret
+		wantErr     bool
+		wantVolumes []corev1.Volume
+		wantSSL     string
+	}{
+		{
+			name:    "tls disabled",
+			wantSSL: "connect_timeout=5, charset='utf8mb4')",
+		},
+		{
+			name:        "tls with the client certificate",
+			tlsClient:   true,
+			secrets:     []*corev1.Secret{newTLSSecret("test-tidb-client-secret", corev1.ServiceAccountRootCAKey, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)},
+			wantVolumes: []corev1.Volume{secretVolume("tidb-client-tls", "test-tidb-client-secret")},
+			wantSSL:     "ssl={'ca': '/var/lib/tidb-client-tls/ca.crt', 'cert': '/var/lib/tidb-client-tls/tls.crt', 'key': '/var/lib/tidb-client-tls/tls.key'})",
+		},
+		{
+			name:        "tls with the private ca only",
+			tlsClient:   true,
+			tlsCASecret: pointer.StringPtr("private-ca"),
+			secrets:     []*corev1.Secret{newTLSSecret("private-ca", corev1.ServiceAccountRootCAKey)},
+			wantVolumes: []corev1.Volume{secretVolume("tidb-server-ca", "private-ca")},
+			wantSSL:     "ssl={'ca': '/var/lib/tidb-server-ca/ca.crt'})",
+		},
+		{
+			name:        "ca secret not found",
+			tlsClient:   true,
+			tlsCASecret: pointer.StringPtr("private-ca"),
+			wantErr:     true,
+		},
+		{
+			name:        "ca secret without ca.crt",
+			tlsClient:   true,
+			tlsCASecret: pointer.StringPtr("private-ca"),
+			secrets:     []*corev1.Secret{newTLSSecret("private-ca", corev1.TLSCertKey)},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			tim, _, indexers := newFakeTiDBInitManager()
+			tc := newTidbClusterForTiDB()
+			if tt.tlsClient {
+				tc.Spec.TiDB.TLSClient = &v1alpha1.TiDBTLSClient{Enabled: true}
+			}
+			g.Expect(indexers.tc.Add(tc)).To(Succeed())
+			for _, secret := range tt.secrets {
+				g.Expect(indexers.secret.Add(secret)).To(Succeed())
+			}
+			ti := newTidbInitializerForTiDB()
+			ti.Spec.TLSCASecretName = tt.tlsCASecret
+
+			job, err := tim.makeTiDBInitJob(ti)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			for _, v := range job.Spec.Template.Spec.Volumes {
+				if v.Secret != nil {
+					g.Expect(tt.wantVolumes).To(ContainElement(v))
+				}
+			}
+			for _, v := range tt.wantVolumes {
+				g.Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(v))
+			}
+
+			cm, err := getTiDBInitConfigMap(ti, getTiDBInitTLS(ti, tc), tc.Spec.TiDB.GetServicePort())
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(cm.Data[startKey]).To(ContainSubstring(tt.wantSSL))
+		})
+	}
+}
+
+func TestSyncTiDBInitConfigMapTLSEnabledLater(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tim, _, indexers := newFakeTiDBInitManager()
+	tc := newTidbClusterForTiDB()
+	ti := newTidbInitializerForTiDB()
+	g.Expect(indexers.tc.Add(tc)).To(Succeed())
+	g.Expect(tim.syncTiDBInitConfigMap(ti, tc)).To(Succeed())
+	job, err := tim.makeTiDBInitJob(ti)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(indexers.job.Add(job)).To(Succeed())
+
+	// TLS is enabled after the initializer ran and failed
+	ti.Status.Phase = v1alpha1.InitializePhaseFailed
+	tc = tc.DeepCopy()
+	tc.Spec.TiDB.TLSClient = &v1alpha1.TiDBTLSClient{Enabled: true}
+	jobControl := tim.deps.JobControl.(*controller.FakeJobControl)
+	jobControl.SetDeleteJobError(errors.NewInternalError(fmt.Errorf("API server failed")), 0)
+	g.Expect(tim.syncTiDBInitConfigMap(ti, tc)).NotTo(Succeed()) // the job is deleted to be recreated
+	cm := &corev1.ConfigMap{}
+	exist, err := tim.deps.TypedControl.Exist(client.ObjectKey{Namespace: ti.Namespace, Name: job.Name}, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(exist).To(BeTrue())
+	g.Expect(cm.Data[startKey]).To(ContainSubstring("ssl="))
+	g.Expect(tim.syncTiDBInitConfigMap(ti, tc)).To(Succeed())
+
+	// nothing is changed after the initialization completes
+	ti.Status.Phase = v1alpha1.InitializePhaseCompleted
+	tc.Spec.TiDB.TLSClient = nil
+	g.Expect(tim.syncTiDBInitConfigMap(ti, tc)).To(Succeed())
+	exist, err = tim.deps.TypedControl.Exist(client.ObjectKey{Namespace: ti.Namespace, Name: job.Name}, cm)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(exist).To(BeTrue())
+	g.Expect(cm.Data[startKey]).To(ContainSubstring("ssl="))
+}
+
+func newTLSSecret(name string, keys ...string) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: corev1.NamespaceDefault},
+		Data:       map[string][]byte{},
+	}
+	for _, k := range keys {
+		secret.Data[k] = []byte(k)
+	}
+	return secret
+}
+
 func newFakeTiDBInitManager() (*tidbInitManager, *tidbMemberManager, *fakeIndexers) {
 	tmm, _, _, indexers := newFakeTiDBMemberManager()
 	indexers.job = tmm.deps.KubeInformerFactory.Batch().V1().Jobs().Informer().GetIndexer()
@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/manager"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/util"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -118,7 +119,12 @@ func (m *tidbMemberManager) Sync(tc *v1alpha1.TidbCluster) error {
 	}
 
 	// Sync TiDB StatefulSet
-	return m.syncTiDBStatefulSetForTidbCluster(tc)
+	err := m.syncTiDBStatefulSetForTidbCluster(tc)
+	// the groups are scaled and their status is refreshed even if the default group waits for its upgrade
+	if groupErr := m.syncTiDBGroups(tc); err == nil {
+		err = groupErr
+	}
+	return err
 }
 
 func (m *tidbMemberManager) checkTLSClientCert(tc *v1alpha1.TidbCluster) error {
@@ -143,16 +149,20 @@ func (m *tidbMemberManager) checkTLSClientCert(tc *v1alpha1.TidbCluster) error {
 }
 
 func (m *tidbMemberManager) syncTiDBHeadlessServiceForTidbCluster(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.TiDBMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for tidb headless service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	return m.syncTiDBHeadlessService(tc, getNewTiDBHeadlessServiceForTidbCluster(tc))
+}
+
+// syncTiDBHeadlessService creates the headless service or updates its spec if it changes
+func (m *tidbMemberManager) syncTiDBHeadlessService(tc *v1alpha1.TidbCluster, newSvc *corev1.Service) error {
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
-	newSvc := getNewTiDBHeadlessServiceForTidbCluster(tc)
-	oldSvcTmp, err := m.deps.ServiceLister.Services(ns).Get(controller.TiDBPeerMemberName(tcName))
+	oldSvcTmp, err := m.deps.ServiceLister.Services(ns).Get(newSvc.Name)
 	if errors.IsNotFound(err) {
 		err = controller.SetServiceLastAppliedConfigAnnotation(newSvc)
 		if err != nil {
@@ -161,7 +171,7 @@ func (m *tidbMemberManager) syncTiDBHeadlessServiceForTidbCluster(tc *v1alpha1.T
 		return m.deps.ServiceControl.CreateService(tc, newSvc)
 	}
 	if err != nil {
-		return fmt.Errorf("syncTiDBHeadlessServiceForTidbCluster: failed to get svc %s for cluster %s/%s, error: %s", controller.TiDBPeerMemberName(tcName), ns, tcName, err)
+		return fmt.Errorf("syncTiDBHeadlessServiceForTidbCluster: failed to get svc %s for cluster %s/%s, error: %s", newSvc.Name, ns, tcName, err)
 	}
 
 	oldSvc := oldSvcTmp.DeepCopy()
@@ -199,11 +209,17 @@ func (m *tidbMemberManager) syncTiDBStatefulSetForTidbCluster(tc *v1alpha1.TidbC
 		return err
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.TiDBMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.TiDBMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for tidb statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	if err := syncExternalProvisioningGates(m.deps, tc, v1alpha1.TiDBMemberType); err != nil {
+		return err
+	}
+
 	cm, err := m.syncTiDBConfigMap(tc, oldTiDBSet)
 	if err != nil {
 		return err
@@ -236,6 +252,11 @@ func (m *tidbMemberManager) syncTiDBStatefulSetForTidbCluster(tc *v1alpha1.TidbC
 		return err
 	}
 
+	// the failure of force deleting the stuck pods does not fail the sync, they are retried in the next sync
+	if err := syncTiDBStuckPods(m.deps, tc); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to force delete the stuck pods of tidb: %v", tc.Namespace, tc.Name, err)
+	}
+
 	if m.deps.CLIConfig.AutoFailover {
 		if m.shouldRecover(tc) {
 			m.tidbFailover.Recover(tc)
@@ -246,13 +267,29 @@ func (m *tidbMemberManager) syncTiDBStatefulSetForTidbCluster(tc *v1alpha1.TidbC
 		}
 	}
 
+	if err := cleanupTiDBWarmStandbyPods(m.deps, tc); err != nil {
+		return err
+	}
+
+	if err := cleanupImagePrepullPods(m.deps, tc, v1alpha1.TiDBMemberType); err != nil {
+		return err
+	}
+
+	if _, err := keepTemplateIfDowngrade(m.deps, tc, v1alpha1.TiDBMemberType, oldTiDBSet, newTiDBSet); err != nil {
+		return err
+	}
+
+	if err := prePullImage(m.deps, tc, v1alpha1.TiDBMemberType, oldTiDBSet, newTiDBSet); err != nil {
+		return err
+	}
+
 	if !templateEqual(newTiDBSet, oldTiDBSet) || tc.Status.TiDB.Phase == v1alpha1.UpgradePhase {
 		if err := m.tidbUpgrader.Upgrade(tc, oldTiDBSet, newTiDBSet); err != nil {
 			return err
 		}
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdateTiDBSTS", newTiDBSet, oldTiDBSet)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newTiDBSet, oldTiDBSet)
 }
 
 func (m *tidbMemberManager) syncInitializer(tc *v1alpha1.TidbCluster) {
@@ -393,7 +430,7 @@ func (m *tidbMemberManager) shouldRecover(tc *v1alpha1.TidbCluster) bool {
 }
 
 func (m *tidbMemberManager) syncTiDBService(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.TiDBMemberType) {
 		klog.V(4).Infof("tidb cluster %s/%s is paused, skip syncing for tidb service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -403,7 +440,11 @@ func (m *tidbMemberManager) syncTiDBService(tc *v1alpha1.TidbCluster) error {
 	if newSvc == nil {
 		return nil
 	}
+	return m.syncTiDBClientService(tc, newSvc)
+}
 
+// syncTiDBClientService creates the client service or updates it if its spec, labels or annotations change
+func (m *tidbMemberManager) syncTiDBClientService(tc *v1alpha1.TidbCluster, newSvc *corev1.Service) error {
 	ns := newSvc.Namespace
 
 	oldSvcTmp, err := m.deps.ServiceLister.Services(ns).Get(newSvc.Name)
@@ -473,10 +514,17 @@ func (m *tidbMemberManager) syncTiDBConfigMap(tc *v1alpha1.TidbCluster, set *app
 	if tc.Spec.TiDB.Config == nil {
 		return nil, nil
 	}
-	newCm, err := getTiDBConfigMap(tc)
+	tierTC, tierEntries, err := withTierConfig(m.deps, tc, v1alpha1.TiDBMemberType)
+	if err != nil {
+		return nil, err
+	}
+	newCm, err := getTiDBConfigMap(tierTC)
 	if err != nil {
 		return nil, err
 	}
+	if err := setTierConfigAnnotation(newCm, tierEntries); err != nil {
+		return nil, err
+	}
 
 	var inUseName string
 	if set != nil {
@@ -491,6 +539,11 @@ func (m *tidbMemberManager) syncTiDBConfigMap(tc *v1alpha1.TidbCluster, set *app
 	if err != nil {
 		return nil, err
 	}
+	recordTierConfigChange(m.deps, tc, v1alpha1.TiDBMemberType, inUseName, newCm)
+	recordTiDBConfigUpdatedInPlace(m.deps, tc, inUseName, newCm)
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.TiDBMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -514,6 +567,9 @@ func getTiDBConfigMap(tc *v1alpha1.TidbCluster) (*corev1.ConfigMap, error) {
 		config.Set("security.ssl-cert", path.Join(serverCertPath, corev1.TLSCertKey))
 		config.Set("security.ssl-key", path.Join(serverCertPath, corev1.TLSPrivateKeyKey))
 	}
+	if tc.Spec.TiDB.IsProxyProtocolEnabled() {
+		config.Set("proxy-protocol.networks", strings.Join(tc.Spec.TiDB.ProxyProtocol.TrustedNetworks, ","))
+	}
 	confText, err := config.MarshalTOML()
 	if err != nil {
 		return nil, err
@@ -623,9 +679,25 @@ func getNewTiDBServiceOrNil(tc *v1alpha1.TidbCluster) *corev1.Service {
 	if svcSpec.ClusterIP != nil {
 		tidbSvc.Spec.ClusterIP = *svcSpec.ClusterIP
 	}
+	if tc.Spec.TiDB.IsProxyProtocolEnabled() {
+		// the annotations set by the users take precedence over the ones of the provider
+		tidbSvc.Annotations = util.CombineStringMap(tidbSvc.Annotations, proxyProtocolAnnotations[tc.Spec.TiDB.ProxyProtocol.Provider])
+	}
 	return tidbSvc
 }
 
+// proxyProtocolAnnotations are the annotations of the services making the load balancers of the providers send
+// the PROXY protocol, the one of Azure sends it through the Private Link Service of the load balancer
+var proxyProtocolAnnotations = map[v1alpha1.LoadBalancerProvider]map[string]string{
+	v1alpha1.LoadBalancerProviderAWS: {
+		"service.beta.kubernetes.io/aws-load-balancer-proxy-protocol": "*",
+	},
+	v1alpha1.LoadBalancerProviderAzure: {
+		"service.beta.kubernetes.io/azure-pls-create":         "true",
+		"service.beta.kubernetes.io/azure-pls-proxy-protocol": "true",
+	},
+}
+
 func getNewTiDBHeadlessServiceForTidbCluster(tc *v1alpha1.TidbCluster) *corev1.Service {
 	ns := tc.Namespace
 	tcName := tc.Name
@@ -663,6 +735,10 @@ func getNewTiDBSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 	setName := controller.TiDBMemberName(tcName)
 	headlessSvcName := controller.TiDBPeerMemberName(tcName)
 	baseTiDBSpec := tc.BaseTiDBSpec()
+	resources, err := v1alpha1.SizedResources(baseTiDBSpec.Sizing(), tc.Spec.TiDB.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for tidb, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
 	instanceName := tc.GetInstanceName()
 	tidbConfigMap := controller.MemberConfigMapName(tc, v1alpha1.TiDBMemberType)
 	if cm != nil {
@@ -746,12 +822,13 @@ func getNewTiDBSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 					SecurityContext: &corev1.SecurityContext{
 						Privileged: &privileged,
 					},
+					EnvFrom: baseTiDBSpec.EnvFrom(),
 					// Init container resourceRequirements should be equal to app container.
 					// Scheduling is done based on effective requests/limits,
 					// which means init containers can reserve resources for
 					// initialization that are not used during the life of the Pod.
 					// ref:https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#resources
-					Resources: controller.ContainerResource(tc.Spec.TiDB.ResourceRequirements),
+					Resources: controller.ContainerResource(resources),
 				})
 			}
 		}
@@ -815,6 +892,7 @@ func getNewTiDBSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			ImagePullPolicy: tc.HelperImagePullPolicy(),
 			Resources:       controller.ContainerResource(tc.Spec.TiDB.GetSlowLogTailerSpec().ResourceRequirements),
 			VolumeMounts:    []corev1.VolumeMount{slowQueryLogVolumeMount},
+			EnvFrom:         baseTiDBSpec.EnvFrom(),
 			Command: []string{
 				"sh",
 				"-c",
@@ -880,7 +958,7 @@ func getNewTiDBSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.TiDB.ResourceRequirements),
+		Resources:    controller.ContainerResource(resources),
 		Env:          util.AppendEnv(envs, baseTiDBSpec.Env()),
 		EnvFrom:      baseTiDBSpec.EnvFrom(),
 		ReadinessProbe: &corev1.Probe{
@@ -911,10 +989,14 @@ func getNewTiDBSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 	if podSpec.ServiceAccountName == "" {
 		podSpec.ServiceAccountName = tc.Spec.ServiceAccount
 	}
+	podSpec.ReadinessGates = append(podSpec.ReadinessGates, tidbReadinessGates(tc)...)
+	podSpec.TerminationGracePeriodSeconds = tidbTerminationGracePeriodSeconds(tc, podSpec.TerminationGracePeriodSeconds)
 
 	stsLabels := label.New().Instance(instanceName).TiDB()
 	podLabels := util.CombineStringMap(stsLabels, baseTiDBSpec.Labels())
 	podAnnotations := util.CombineStringMap(controller.AnnProm(10080), baseTiDBSpec.Annotations())
+	podAnnotations = util.CombineStringMap(podAnnotations, externalProvisioningAnnotations(baseTiDBSpec))
+	podAnnotations = util.CombineStringMap(podAnnotations, restartGenerationAnnotations(tc, v1alpha1.TiDBMemberType))
 	stsAnnotations := getStsAnnotations(tc.Annotations, label.TiDBLabelVal)
 
 	deleteSlotsNumber, err := util.GetDeleteSlotsNumber(stsAnnotations)
@@ -973,18 +1055,26 @@ func (m *tidbMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set
 		return err
 	}
 
+	oldPhase := tc.Status.TiDB.Phase
 	if tc.TiDBStsDesiredReplicas() != *set.Spec.Replicas {
 		tc.Status.TiDB.Phase = v1alpha1.ScalePhase
-	} else if upgrading && tc.Status.TiKV.Phase != v1alpha1.UpgradePhase &&
-		tc.Status.PD.Phase != v1alpha1.UpgradePhase && tc.Status.Pump.Phase != v1alpha1.UpgradePhase {
+	} else if upgrading && upgradeBlockedBy(tc, v1alpha1.TiDBMemberType) == "" {
 		tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
 	} else {
 		tc.Status.TiDB.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.TiDB.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.TiDBMemberType, oldPhase, tc.Status.TiDB.Phase, tc.Status.TiDB.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.TiDBMemberType, oldPhase, tc.Status.TiDB.Phase, tc.Status.TiDB.StatefulSet)
+	syncUpgradeStatus(m.deps, tc, v1alpha1.TiDBMemberType, set, nil)
+	if tc.Status.TiDB.Phase != v1alpha1.UpgradePhase {
+		// the drained pods accept the connections again if the upgrade is done or cancelled
+		tc.Status.TiDB.UpgradeDrain = nil
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+	}
 
 	tidbStatus := map[string]v1alpha1.TiDBMember{}
 	for id := range helper.GetPodOrdinals(tc.Status.TiDB.StatefulSet.Replicas, set) {
-		name := fmt.Sprintf("%s-%d", controller.TiDBMemberName(tc.GetName()), id)
+		name := tidbSetPodName(tc, int32(id))
 		health, err := m.deps.TiDBControl.GetHealth(tc, int32(id))
 		if err != nil {
 			return err
@@ -1011,10 +1101,17 @@ func (m *tidbMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, set
 			// Update assigned node if pod exists and is scheduled
 			newTidbMember.NodeName = pod.Spec.NodeName
 		}
+		if pod != nil {
+			if err := syncTiDBAcceptingConnections(m.deps, tc, pod); err != nil {
+				return fmt.Errorf("syncTidbClusterStatus: failed to make pod %s accept connections for cluster %s/%s, error: %s", name, tc.GetNamespace(), tc.GetName(), err)
+			}
+		}
 		tidbStatus[name] = newTidbMember
 	}
 
 	tc.Status.TiDB.Members = tidbStatus
+	m.syncTiDBConfigReload(tc)
+	m.syncConnectivity(tc)
 	tc.Status.TiDB.Image = ""
 	c := findContainerByName(set, "tidb")
 	if c != nil {
@@ -1039,6 +1136,9 @@ func tidbStatefulSetIsUpgrading(podLister corelisters.PodLister, set *apps.State
 		return false, fmt.Errorf("tidbStatefulSetIsUpgrading: failed to get pods for cluster %s/%s, selector %s, error: %s", tc.GetNamespace(), tc.GetInstanceName(), selector, err)
 	}
 	for _, pod := range tidbPods {
+		if isTiDBWarmStandbyPod(pod) || tidbGroupOf(pod) != tidbGroupOf(tc) {
+			continue
+		}
 		revisionHash, exist := pod.Labels[apps.ControllerRevisionHashLabelKey]
 		if !exist {
 			return false, nil
@@ -1065,9 +1165,16 @@ func buildTiDBReadinessProbHandler(tc *v1alpha1.TidbCluster) corev1.Handler {
 	}
 
 	// fall to default case v1alpha1.TCPProbeType
+	port := 4000
+	if tc.Spec.TiDB.IsProxyProtocolEnabled() {
+		// the probes of the kubelet may come from the trusted networks, e.g. the subnets shared by the nodes and
+		// the load balancer, where the MySQL port refuses the connections without the PROXY protocol header,
+		// while the status port does not speak it
+		port = 10080
+	}
 	return corev1.Handler{
 		TCPSocket: &corev1.TCPSocketAction{
-			Port: intstr.FromInt(4000),
+			Port: intstr.FromInt(port),
 		},
 	}
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/apis/util/toml"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
@@ -40,6 +41,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 )
 
@@ -240,6 +242,36 @@ func TestTiDBMemberManagerSyncUpdate(t *testing.T) {
 				g.Expect(set.Spec.Template.Spec.Containers).To(HaveLen(2))
 			},
 		},
+		{
+			name: "tikv is paused",
+			modify: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiDB.Replicas = 5
+				tc.Spec.TiKV.Paused = true
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			},
+			errWhenUpdateStatefulSet: false,
+			err:                      false,
+			expectStatefulSetFn: func(g *GomegaWithT, set *apps.StatefulSet, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(int(*set.Spec.Replicas)).To(Equal(4))
+			},
+		},
+		{
+			name: "tidb is paused",
+			modify: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiDB.Replicas = 5
+				tc.Spec.TiDB.Paused = true
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			},
+			errWhenUpdateStatefulSet: false,
+			err:                      false,
+			expectStatefulSetFn: func(g *GomegaWithT, set *apps.StatefulSet, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(int(*set.Spec.Replicas)).To(Equal(3))
+			},
+		},
 	}
 
 	for i := range tests {
@@ -357,6 +389,7 @@ func TestTiDBMemberManagerSyncTidbClusterStatus(t *testing.T) {
 		healthInfo  map[string]bool
 		errExpectFn func(*GomegaWithT, error)
 		tcExpectFn  func(*GomegaWithT, *v1alpha1.TidbCluster)
+		eventsFn    func(*GomegaWithT, []string)
 	}
 	spec := apps.StatefulSetSpec{
 		Replicas: pointer.Int32Ptr(3),
@@ -397,6 +430,9 @@ func TestTiDBMemberManagerSyncTidbClusterStatus(t *testing.T) {
 		if test.tcExpectFn != nil {
 			test.tcExpectFn(g, tc)
 		}
+		if test.eventsFn != nil {
+			test.eventsFn(g, collectEvents(pmm.deps.Recorder.(*record.FakeRecorder).Events))
+		}
 	}
 	tests := []testcase{
 		{
@@ -414,6 +450,46 @@ func TestTiDBMemberManagerSyncTidbClusterStatus(t *testing.T) {
 				g.Expect(tc.Status.TiDB.StatefulSet.Replicas).To(Equal(int32(3)))
 			},
 		},
+		{
+			name: "upgrade is finished",
+			updateTC: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+			},
+			updateSts: func(sts *apps.StatefulSet) {
+				sts.Status.CurrentRevision = "tidb-2"
+				sts.Status.UpdateRevision = "tidb-2"
+			},
+			upgradingFn: func(lister corelisters.PodLister, set *apps.StatefulSet, cluster *v1alpha1.TidbCluster) (bool, error) {
+				return false, nil
+			},
+			healthInfo:  map[string]bool{},
+			errExpectFn: nil,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster) {
+				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.NormalPhase))
+			},
+			eventsFn: func(g *GomegaWithT, recorded []string) {
+				g.Expect(recorded).To(HaveLen(1))
+				g.Expect(recorded[0]).To(ContainSubstring(events.UpgradeUpToDate))
+			},
+		},
+		{
+			name: "statefulset is idle",
+			updateTC: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+			},
+			updateSts: func(sts *apps.StatefulSet) {
+				sts.Status.CurrentRevision = "tidb-2"
+				sts.Status.UpdateRevision = "tidb-2"
+			},
+			upgradingFn: func(lister corelisters.PodLister, set *apps.StatefulSet, cluster *v1alpha1.TidbCluster) (bool, error) {
+				return false, nil
+			},
+			healthInfo:  map[string]bool{},
+			errExpectFn: nil,
+			eventsFn: func(g *GomegaWithT, recorded []string) {
+				g.Expect(recorded).To(BeEmpty())
+			},
+		},
 		{
 			name:     "statefulset is upgrading",
 			updateTC: nil,
@@ -457,6 +533,21 @@ func TestTiDBMemberManagerSyncTidbClusterStatus(t *testing.T) {
 				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.NormalPhase))
 			},
 		},
+		{
+			name: "statefulset is upgrading while paused tikv is in upgrade phase",
+			updateTC: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+				tc.Spec.TiKV.Paused = true
+			},
+			upgradingFn: func(lister corelisters.PodLister, set *apps.StatefulSet, cluster *v1alpha1.TidbCluster) (bool, error) {
+				return true, nil
+			},
+			healthInfo:  map[string]bool{},
+			errExpectFn: nil,
+			tcExpectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster) {
+				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.UpgradePhase))
+			},
+		},
 		{
 			name:     "statefulset is not upgrading",
 			updateTC: nil,
@@ -1219,6 +1310,80 @@ func TestGetNewTiDBSetForTidbCluster(t *testing.T) {
 				g.Expect(sts.Spec.Template.Spec.Containers[1].ReadinessProbe.PeriodSeconds).To(Equal(int32(2)))
 			},
 		},
+		{
+			name: "tidb spec env and envFrom",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD: &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							Env: []corev1.EnvVar{
+								{Name: "CLUSTER_NAME", Value: "overridden"},
+								{Name: "HTTP_PROXY", Value: "http://proxy:3128"},
+							},
+							EnvFrom: []corev1.EnvFromSource{
+								{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+								{Prefix: "PROXY_", ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"}}},
+							},
+						},
+					},
+					TiKV: &v1alpha1.TiKVSpec{},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				envFrom := []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+					{Prefix: "PROXY_", ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"}}},
+				}
+				// envFrom is rendered in both slowlog tailer and tidb container
+				g.Expect(sts.Spec.Template.Spec.Containers[0].Name).To(Equal(v1alpha1.SlowLogTailerMemberType.String()))
+				g.Expect(sts.Spec.Template.Spec.Containers[0].EnvFrom).To(Equal(envFrom))
+				g.Expect(sts.Spec.Template.Spec.Containers[1].EnvFrom).To(Equal(envFrom))
+
+				// builtin envs take precedence over env, and env is kept in the container
+				// to take precedence over envFrom
+				envs := map[string]string{}
+				for _, env := range sts.Spec.Template.Spec.Containers[1].Env {
+					g.Expect(envs).NotTo(HaveKey(env.Name))
+					envs[env.Name] = env.Value
+				}
+				g.Expect(envs).To(HaveKeyWithValue("CLUSTER_NAME", "tc"))
+				g.Expect(envs).To(HaveKeyWithValue("HTTP_PROXY", "http://proxy:3128"))
+			},
+		},
+		{
+			name: "tidb spec scaleInDrain",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD: &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{
+						ScaleInDrain: &v1alpha1.TiDBScaleInDrain{},
+						Config: func() *v1alpha1.TiDBConfigWraper {
+							c := v1alpha1.NewTiDBConfig()
+							c.Set("graceful-wait-before-shutdown", 60)
+							return c
+						}(),
+					},
+					TiKV: &v1alpha1.TiKVSpec{},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				g.Expect(sts.Spec.Template.Spec.ReadinessGates).To(Equal([]corev1.PodReadinessGate{
+					{ConditionType: v1alpha1.TiDBAcceptingConnections},
+				}))
+				g.Expect(sts.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(90)))
+			},
+		},
 		// TODO add more tests
 	}
 
@@ -2491,6 +2656,71 @@ func TestBuildTiDBProbeHandler(t *testing.T) {
 	g.Expect(get).Should(Equal(defaultHandler))
 }
 
+func TestTiDBProxyProtocol(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	tc.Spec.TiDB.Config.Set("proxy-protocol.networks", "10.0.0.1")
+	tc.Spec.TiDB.Service = &v1alpha1.TiDBServiceSpec{
+		ServiceSpec: v1alpha1.ServiceSpec{
+			Type:        corev1.ServiceTypeLoadBalancer,
+			Annotations: map[string]string{"service.beta.kubernetes.io/azure-pls-create": "false"},
+		},
+	}
+	render := func() (string, *corev1.Service, *corev1.Probe) {
+		cm, err := getTiDBConfigMap(tc)
+		g.Expect(err).NotTo(HaveOccurred())
+		set, err := getNewTiDBSetForTidbCluster(tc, cm)
+		g.Expect(err).NotTo(HaveOccurred())
+		var probe *corev1.Probe
+		for _, c := range set.Spec.Template.Spec.Containers {
+			if c.Name == v1alpha1.TiDBMemberType.String() {
+				probe = c.ReadinessProbe
+			}
+		}
+		return cm.Data["config-file"], getNewTiDBServiceOrNil(tc), probe
+	}
+
+	// the config set by the users is kept and nothing is changed if it is disabled
+	tc.Spec.TiDB.ProxyProtocol = &v1alpha1.TiDBProxyProtocol{
+		TrustedNetworks: []string{"10.0.0.0/16", "192.168.1.1"},
+		Provider:        v1alpha1.LoadBalancerProviderAWS,
+	}
+	config, svc, probe := render()
+	g.Expect(config).To(ContainSubstring(`networks = "10.0.0.1"`))
+	g.Expect(svc.Annotations).To(Equal(map[string]string{"service.beta.kubernetes.io/azure-pls-create": "false"}))
+	g.Expect(probe.TCPSocket.Port).To(Equal(intstr.FromInt(4000)))
+
+	tc.Spec.TiDB.ProxyProtocol.Enabled = true
+	config, svc, probe = render()
+	g.Expect(config).To(ContainSubstring("[proxy-protocol]\n  networks = \"10.0.0.0/16,192.168.1.1\"\n"))
+	g.Expect(svc.Annotations).To(Equal(map[string]string{
+		"service.beta.kubernetes.io/azure-pls-create":                 "false",
+		"service.beta.kubernetes.io/aws-load-balancer-proxy-protocol": "*",
+	}))
+	g.Expect(probe.TCPSocket.Port).To(Equal(intstr.FromInt(10080)))
+
+	// the annotations set by the users take precedence over the ones of the provider
+	tc.Spec.TiDB.ProxyProtocol.Provider = v1alpha1.LoadBalancerProviderAzure
+	_, svc, _ = render()
+	g.Expect(svc.Annotations).To(Equal(map[string]string{
+		"service.beta.kubernetes.io/azure-pls-create":         "false",
+		"service.beta.kubernetes.io/azure-pls-proxy-protocol": "true",
+	}))
+
+	// no annotation is added for GCP
+	tc.Spec.TiDB.ProxyProtocol.Provider = v1alpha1.LoadBalancerProviderGCP
+	_, svc, _ = render()
+	g.Expect(svc.Annotations).To(Equal(map[string]string{"service.beta.kubernetes.io/azure-pls-create": "false"}))
+
+	// the command probe is on the status port already
+	tc.Spec.TiDB.ReadinessProbe = &v1alpha1.TiDBProbe{Type: pointer.StringPtr(v1alpha1.CommandProbeType)}
+	_, _, probe = render()
+	g.Expect(probe.TCPSocket).To(BeNil())
+	g.Expect(probe.Exec.Command).To(ContainElement("http://127.0.0.1:10080/status"))
+}
+
 func newTidbClusterForTiDB() *v1alpha1.TidbCluster {
 	return &v1alpha1.TidbCluster{
 		TypeMeta: metav1.TypeMeta{
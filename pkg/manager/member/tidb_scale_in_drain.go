@@ -0,0 +1,253 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultTiDBScaleInDrainTimeout = 10 * time.Minute
+
+	// tidbGracefulWaitBeforeShutdown is the config item of TiDB in seconds, TiDB keeps serving the existing
+	// connections and reports unhealthy for that long after it receives SIGTERM
+	tidbGracefulWaitBeforeShutdown = "graceful-wait-before-shutdown"
+	// defaultTerminationGracePeriodSeconds is the default termination grace period of the pods in Kubernetes
+	defaultTerminationGracePeriodSeconds = 30
+
+	tidbScaleInDrainConditionReason = "ScaleInDrain"
+)
+
+// tidbScaleInDrainTimeout returns the timeout of draining the connections of the TiDB pod to be removed
+func tidbScaleInDrainTimeout(tc *v1alpha1.TidbCluster) time.Duration {
+	if drain := tc.Spec.TiDB.ScaleInDrain; drain != nil && drain.Timeout != nil {
+		if d, err := time.ParseDuration(*drain.Timeout); err == nil {
+			return d
+		}
+	}
+	return defaultTiDBScaleInDrainTimeout
+}
+
+// tidbReadinessGates returns the readiness gates of the TiDB pods, the pods are removed from the endpoints of
+// the TiDB service when they are drained before the scale-in or the upgrade
+func tidbReadinessGates(tc *v1alpha1.TidbCluster) []corev1.PodReadinessGate {
+	if tc.Spec.TiDB.ScaleInDrain == nil && tc.Spec.TiDB.GracefulShutdownTimeoutSeconds <= 0 {
+		return nil
+	}
+	return []corev1.PodReadinessGate{{ConditionType: v1alpha1.TiDBAcceptingConnections}}
+}
+
+// tidbTerminationGracePeriodSeconds returns the termination grace period of the TiDB pods covering the
+// graceful-wait-before-shutdown of TiDB if the connections are drained before the scale-in and the period
+// is not set explicitly, nil means the default of Kubernetes
+func tidbTerminationGracePeriodSeconds(tc *v1alpha1.TidbCluster, period *int64) *int64 {
+	if period != nil || tc.Spec.TiDB.ScaleInDrain == nil || tc.Spec.TiDB.Config == nil {
+		return period
+	}
+	v := tc.Spec.TiDB.Config.Get(tidbGracefulWaitBeforeShutdown)
+	if v == nil {
+		return nil
+	}
+	wait, err := v.AsInt()
+	if err != nil || wait <= 0 {
+		return nil
+	}
+	seconds := wait + defaultTerminationGracePeriodSeconds
+	return &seconds
+}
+
+// hasTiDBAcceptingConnectionsGate returns whether the pod has the readiness gate of accepting the connections
+func hasTiDBAcceptingConnectionsGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == v1alpha1.TiDBAcceptingConnections {
+			return true
+		}
+	}
+	return false
+}
+
+// setTiDBAcceptingConnections sets the condition of the readiness gate of accepting the connections of the pod,
+// the pods without the readiness gate are skipped
+func setTiDBAcceptingConnections(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, pod *corev1.Pod, accepting bool) error {
+	if !hasTiDBAcceptingConnectionsGate(pod) {
+		return nil
+	}
+	status := corev1.ConditionTrue
+	reason := ""
+	if !accepting {
+		status = corev1.ConditionFalse
+		reason = tidbScaleInDrainConditionReason
+	}
+	newPod := pod.DeepCopy()
+	found := false
+	for i := range newPod.Status.Conditions {
+		cond := &newPod.Status.Conditions[i]
+		if cond.Type != v1alpha1.TiDBAcceptingConnections {
+			continue
+		}
+		if cond.Status == status {
+			return nil
+		}
+		cond.Status = status
+		cond.Reason = reason
+		cond.LastTransitionTime = metav1.Now()
+		found = true
+		break
+	}
+	if !found {
+		newPod.Status.Conditions = append(newPod.Status.Conditions, corev1.PodCondition{
+			Type:               v1alpha1.TiDBAcceptingConnections,
+			Status:             status,
+			Reason:             reason,
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+	_, err := deps.PodControl.UpdatePodStatus(tc, newPod)
+	return err
+}
+
+// syncTiDBAcceptingConnections makes the TiDB pod accept the connections unless it is being drained or deleted
+func syncTiDBAcceptingConnections(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, pod *corev1.Pod) error {
+	if pod.DeletionTimestamp != nil {
+		return nil
+	}
+	if _, ok := pod.Annotations[label.AnnPodNodeMaintenance]; ok {
+		return nil
+	}
+	if drain := tc.Status.TiDB.ScaleInDrain; drain != nil && drain.PodName == pod.Name {
+		return nil
+	}
+	if drain := tc.Status.TiDB.UpgradeDrain; drain != nil && sets.NewString(drain.PodNames...).Has(pod.Name) {
+		return nil
+	}
+	return setTiDBAcceptingConnections(deps, tc, pod, true)
+}
+
+// DrainTiDBPodConnections stops the TiDB pod accepting new connections for the maintenance of its node, and returns
+// whether its connections fall to the threshold of spec.tidb.scaleInDrain or the timeout elapses since the draining
+// starts. The connections of the pods without the readiness gate are closed by the graceful shutdown of TiDB only.
+func DrainTiDBPodConnections(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, pod *corev1.Pod, since time.Time) (bool, string, error) {
+	drain := tc.Spec.TiDB.ScaleInDrain
+	if drain == nil || !hasTiDBAcceptingConnectionsGate(pod) {
+		return true, "the connections are closed by the graceful shutdown of tidb", nil
+	}
+	if err := setTiDBAcceptingConnections(deps, tc, pod, false); err != nil {
+		return false, "", err
+	}
+	ordinal, err := util.GetOrdinalFromPodName(pod.Name)
+	if err != nil {
+		return false, "", err
+	}
+	var msg string
+	count, err := deps.TiDBControl.GetConnectionCount(tc, ordinal)
+	if err != nil {
+		msg = fmt.Sprintf("failed to get the connections: %v", err)
+	} else {
+		msg = fmt.Sprintf("%d connections left", count)
+		if count <= drain.ConnectionThreshold {
+			return true, msg, nil
+		}
+	}
+	if time.Since(since) > tidbScaleInDrainTimeout(tc) {
+		return true, "the draining times out, " + msg, nil
+	}
+	return false, msg, nil
+}
+
+// drainConnections stops the pod accepting new connections and waits for its connections to fall to the threshold
+// or the timeout to elapse, a RequeueError is returned while draining
+func (s *tidbScaler) drainConnections(tc *v1alpha1.TidbCluster, ordinal int32, pod *corev1.Pod) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	drain := tc.Spec.TiDB.ScaleInDrain
+	if drain == nil {
+		tc.Status.TiDB.ScaleInDrain = nil
+		return nil
+	}
+
+	// the pod may be recreated while draining, so that the condition is always set
+	if err := setTiDBAcceptingConnections(s.deps, tc, pod, false); err != nil {
+		return fmt.Errorf("tidbScaler.ScaleIn: failed to stop pod %s in tc %s/%s accepting connections, error: %s", pod.Name, ns, tcName, err)
+	}
+	status := tc.Status.TiDB.ScaleInDrain
+	if status == nil || status.PodName != pod.Name {
+		timeout := tidbScaleInDrainTimeout(tc)
+		tc.Status.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrainStatus{
+			PodName:     pod.Name,
+			Connections: -1,
+			Deadline:    metav1.NewTime(time.Now().Add(timeout)),
+		}
+		return controller.RequeueErrorf("tidb.ScaleIn, cluster %s/%s start draining the connections of %s in %s, wait for next round", ns, tcName, pod.Name, timeout)
+	}
+
+	count, err := s.deps.TiDBControl.GetConnectionCount(tc, ordinal)
+	if err != nil {
+		klog.Warningf("tidbScaler.ScaleIn: failed to get the connections of pod %s in tc %s/%s, error: %s", pod.Name, ns, tcName, err)
+		status.Connections = -1
+	} else {
+		status.Connections = count
+		if count <= drain.ConnectionThreshold {
+			klog.Infof("tidbScaler.ScaleIn: pod %s in tc %s/%s is drained, %d connections left", pod.Name, ns, tcName, count)
+			return nil
+		}
+	}
+	if time.Now().After(status.Deadline.Time) {
+		msg := fmt.Sprintf("the connections of pod %s are not drained before %s, %d connections left, remove it anyway",
+			pod.Name, status.Deadline.Format(time.RFC3339), status.Connections)
+		klog.Warningf("tidbScaler.ScaleIn: tc %s/%s %s", ns, tcName, msg)
+		s.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.TiDBScaleInDrainTimeout, msg)
+		return nil
+	}
+	return controller.RequeueErrorf("tidb.ScaleIn, cluster %s/%s draining the connections of %s, %d connections left, wait for next round", ns, tcName, pod.Name, status.Connections)
+}
+
+// syncScaleInDrainStatus clears the draining status once the drained pod is removed or the scale-in is cancelled
+func (s *tidbScaler) syncScaleInDrainStatus(tc *v1alpha1.TidbCluster, set *apps.StatefulSet) error {
+	status := tc.Status.TiDB.ScaleInDrain
+	if status == nil {
+		return nil
+	}
+	pod, err := s.deps.PodLister.Pods(tc.GetNamespace()).Get(status.PodName)
+	if errors.IsNotFound(err) {
+		tc.Status.TiDB.ScaleInDrain = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ordinal, err := util.GetOrdinalFromPodName(pod.Name)
+	if err != nil {
+		return err
+	}
+	if pod.DeletionTimestamp == nil && helper.GetPodOrdinals(*set.Spec.Replicas, set).Has(ordinal) {
+		klog.Infof("tidbScaler: the scale-in of pod %s in tc %s/%s is cancelled, stop draining it", pod.Name, tc.GetNamespace(), tc.GetName())
+		tc.Status.TiDB.ScaleInDrain = nil
+		return setTiDBAcceptingConnections(s.deps, tc, pod, true)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func newTiDBPodForScaleInDrain(tc *v1alpha1.TidbCluster, ordinal int32) *corev1.Pod {
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tidbPodName(tc.GetName(), ordinal),
+			Namespace: corev1.NamespaceDefault,
+			Labels:    map[string]string{},
+		},
+		Spec: corev1.PodSpec{
+			ReadinessGates: tidbReadinessGates(tc),
+		},
+	}
+	readyPodFunc(pod)
+	return pod
+}
+
+func tidbAcceptingConnectionsStatus(g *GomegaWithT, scaler *tidbScaler, podName string) corev1.ConditionStatus {
+	pod, err := scaler.deps.PodLister.Pods(corev1.NamespaceDefault).Get(podName)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1alpha1.TiDBAcceptingConnections {
+			return cond.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+func TestTiDBScalerScaleInDrain(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{ConnectionThreshold: 2}
+	oldSet := newStatefulSetForPDScale()
+	scaler, _, podIndexer, _ := newFakeTiDBScaler()
+	pod := newTiDBPodForScaleInDrain(tc, 4)
+	podIndexer.Add(pod)
+	tidbControl := scaler.deps.TiDBControl.(*controller.FakeTiDBControl)
+	tidbControl.SetConnectionCount(pod.Name, 10, 5, 1)
+
+	scaleIn := func() (int32, error) {
+		newSet := oldSet.DeepCopy()
+		newSet.Spec.Replicas = pointer.Int32Ptr(3)
+		err := scaler.ScaleIn(tc, oldSet, newSet)
+		return *newSet.Spec.Replicas, err
+	}
+
+	// the pod stops accepting connections and the draining starts
+	replicas, err := scaleIn()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(replicas).To(Equal(int32(5)))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, pod.Name)).To(Equal(corev1.ConditionFalse))
+	status := tc.Status.TiDB.ScaleInDrain
+	g.Expect(status).NotTo(BeNil())
+	g.Expect(status.PodName).To(Equal(pod.Name))
+	g.Expect(status.Connections).To(Equal(int32(-1)))
+	g.Expect(status.Deadline.Time).To(BeTemporally("~", time.Now().Add(defaultTiDBScaleInDrainTimeout), time.Minute))
+
+	// the connections are above the threshold
+	for _, count := range []int32{10, 5} {
+		replicas, err = scaleIn()
+		g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+		g.Expect(replicas).To(Equal(int32(5)))
+		g.Expect(tc.Status.TiDB.ScaleInDrain.Connections).To(Equal(count))
+	}
+
+	// the connections fall to the threshold
+	replicas, err = scaleIn()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(replicas).To(Equal(int32(4)))
+	g.Expect(tc.Status.TiDB.ScaleInDrain.Connections).To(Equal(int32(1)))
+
+	// the status is kept until the pod is removed
+	oldSet.Spec.Replicas = pointer.Int32Ptr(4)
+	g.Expect(scaler.Scale(tc, oldSet, oldSet.DeepCopy())).To(Succeed())
+	g.Expect(tc.Status.TiDB.ScaleInDrain).NotTo(BeNil())
+	podIndexer.Delete(pod)
+	g.Expect(scaler.Scale(tc, oldSet, oldSet.DeepCopy())).To(Succeed())
+	g.Expect(tc.Status.TiDB.ScaleInDrain).To(BeNil())
+}
+
+func TestTiDBScalerScaleInDrainTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{}
+	oldSet := newStatefulSetForPDScale()
+	scaler, _, podIndexer, _ := newFakeTiDBScaler()
+	pod := newTiDBPodForScaleInDrain(tc, 4)
+	podIndexer.Add(pod)
+	scaler.deps.TiDBControl.(*controller.FakeTiDBControl).SetConnectionCount(pod.Name, 10)
+	tc.Status.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrainStatus{
+		PodName:     pod.Name,
+		Connections: 10,
+		Deadline:    metav1.NewTime(time.Now().Add(-time.Second)),
+	}
+
+	newSet := oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(4)
+	g.Expect(scaler.ScaleIn(tc, oldSet, newSet)).To(Succeed())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(4)))
+	recorded := collectEvents(scaler.deps.Recorder.(*record.FakeRecorder).Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.TiDBScaleInDrainTimeout))
+	g.Expect(recorded[0]).To(ContainSubstring("10 connections left"))
+}
+
+func TestTiDBScalerScaleInDrainCancelled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{}
+	oldSet := newStatefulSetForPDScale()
+	scaler, _, podIndexer, _ := newFakeTiDBScaler()
+	pod := newTiDBPodForScaleInDrain(tc, 4)
+	pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
+		Type:   v1alpha1.TiDBAcceptingConnections,
+		Status: corev1.ConditionFalse,
+	})
+	podIndexer.Add(pod)
+	tc.Status.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrainStatus{
+		PodName:     pod.Name,
+		Connections: 10,
+		Deadline:    metav1.NewTime(time.Now().Add(time.Minute)),
+	}
+
+	// the replicas are back to 5 while the pod is being drained
+	g.Expect(scaler.Scale(tc, oldSet, oldSet.DeepCopy())).To(Succeed())
+	g.Expect(tc.Status.TiDB.ScaleInDrain).To(BeNil())
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, pod.Name)).To(Equal(corev1.ConditionTrue))
+}
+
+func TestSyncTiDBAcceptingConnections(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{}
+	scaler, _, podIndexer, _ := newFakeTiDBScaler()
+	serving := newTiDBPodForScaleInDrain(tc, 0)
+	draining := newTiDBPodForScaleInDrain(tc, 1)
+	withoutGate := newTiDBPodForScaleInDrain(tc, 2)
+	withoutGate.Spec.ReadinessGates = nil
+	inMaintenance := newTiDBPodForScaleInDrain(tc, 3)
+	inMaintenance.Annotations = map[string]string{label.AnnPodNodeMaintenance: "node-1"}
+	upgradeDraining := newTiDBPodForScaleInDrain(tc, 4)
+	for _, pod := range []*corev1.Pod{serving, draining, withoutGate, inMaintenance, upgradeDraining} {
+		podIndexer.Add(pod)
+	}
+	tc.Status.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrainStatus{PodName: draining.Name}
+	tc.Status.TiDB.UpgradeDrain = &v1alpha1.TiDBUpgradeDrainStatus{PodNames: []string{upgradeDraining.Name}}
+
+	for _, pod := range []*corev1.Pod{serving, draining, withoutGate, inMaintenance, upgradeDraining} {
+		g.Expect(syncTiDBAcceptingConnections(scaler.deps, tc, pod)).To(Succeed())
+	}
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, serving.Name)).To(Equal(corev1.ConditionTrue))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, draining.Name)).To(Equal(corev1.ConditionUnknown))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, withoutGate.Name)).To(Equal(corev1.ConditionUnknown))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, inMaintenance.Name)).To(Equal(corev1.ConditionUnknown))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, upgradeDraining.Name)).To(Equal(corev1.ConditionUnknown))
+}
+
+func TestDrainTiDBPodConnections(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{}
+	scaler, _, podIndexer, _ := newFakeTiDBScaler()
+	pod := newTiDBPodForScaleInDrain(tc, 0)
+	podIndexer.Add(pod)
+	scaler.deps.TiDBControl.(*controller.FakeTiDBControl).SetConnectionCount(pod.Name, 10, 0)
+
+	// the connections are not drained
+	withoutGate := pod.DeepCopy()
+	withoutGate.Spec.ReadinessGates = nil
+	drained, _, err := DrainTiDBPodConnections(scaler.deps, tc, withoutGate, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drained).To(BeTrue())
+
+	drained, msg, err := DrainTiDBPodConnections(scaler.deps, tc, pod, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drained).To(BeFalse())
+	g.Expect(msg).To(Equal("10 connections left"))
+	g.Expect(tidbAcceptingConnectionsStatus(g, scaler, pod.Name)).To(Equal(corev1.ConditionFalse))
+
+	drained, msg, err = DrainTiDBPodConnections(scaler.deps, tc, pod, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drained).To(BeTrue())
+	g.Expect(msg).To(Equal("0 connections left"))
+
+	// the draining times out
+	scaler.deps.TiDBControl.(*controller.FakeTiDBControl).SetConnectionCount(pod.Name, 10)
+	drained, msg, err = DrainTiDBPodConnections(scaler.deps, tc, pod, time.Now().Add(-2*defaultTiDBScaleInDrainTimeout))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drained).To(BeTrue())
+	g.Expect(msg).To(Equal("the draining times out, 10 connections left"))
+}
+
+func TestTiDBTerminationGracePeriodSeconds(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForPD()
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	tc.Spec.TiDB.Config.Set(tidbGracefulWaitBeforeShutdown, 60)
+
+	// the connections are not drained
+	g.Expect(tidbTerminationGracePeriodSeconds(tc, nil)).To(BeNil())
+
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{}
+	g.Expect(tidbTerminationGracePeriodSeconds(tc, nil)).To(Equal(pointer.Int64Ptr(90)))
+	// the period set explicitly is kept
+	g.Expect(tidbTerminationGracePeriodSeconds(tc, pointer.Int64Ptr(10))).To(Equal(pointer.Int64Ptr(10)))
+
+	tc.Spec.TiDB.Config.Del(tidbGracefulWaitBeforeShutdown)
+	g.Expect(tidbTerminationGracePeriodSeconds(tc, nil)).To(BeNil())
+}
@@ -44,6 +44,9 @@ func (s *tidbScaler) Scale(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 	} else if scaling < 0 {
 		return s.ScaleIn(meta, oldSet, newSet)
 	}
+	if tc, ok := meta.(*v1alpha1.TidbCluster); ok {
+		return s.syncScaleInDrainStatus(tc, oldSet)
+	}
 	return nil
 }
 
@@ -60,7 +63,7 @@ func (s *tidbScaler) ScaleOut(meta metav1.Object, oldSet *apps.StatefulSet, newS
 	skipReason, err := s.deleteDeferDeletingPVC(obj, v1alpha1.TiDBMemberType, ordinal)
 	if err != nil {
 		return err
-	} else if len(skipReason) != 1 || skipReason[ordinalPodName(v1alpha1.TiDBMemberType, meta.GetName(), ordinal)] != skipReasonScalerPVCNotFound {
+	} else if len(skipReason) != 1 || skipReason[memberPodName(meta, v1alpha1.TiDBMemberType, ordinal)] != skipReasonScalerPVCNotFound {
 		// wait for all PVCs to be deleted
 		return controller.RequeueErrorf("tidbScaler.ScaleOut, cluster %s/%s ready to scale out, skip reason %v, wait for next round", meta.GetNamespace(), meta.GetName(), skipReason)
 	}
@@ -81,7 +84,7 @@ func (s *tidbScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 	var podName string
 	switch meta.(type) {
 	case *v1alpha1.TidbCluster:
-		podName = ordinalPodName(v1alpha1.TiDBMemberType, tcName, ordinal)
+		podName = memberPodName(meta, v1alpha1.TiDBMemberType, ordinal)
 	default:
 		klog.Errorf("tidbScaler.ScaleIn: failed to convert cluster %s/%s", meta.GetNamespace(), meta.GetName())
 		return nil
@@ -91,17 +94,21 @@ func (s *tidbScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 		return fmt.Errorf("tidbScaler.ScaleIn: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
 	}
 
+	tc, _ := meta.(*v1alpha1.TidbCluster)
+	if err := s.drainConnections(tc, ordinal, pod); err != nil {
+		return err
+	}
+
 	pvcs, err := util.ResolvePVCFromPod(pod, s.deps.PVCLister)
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("tidbScaler.ScaleIn: failed to get pvcs for pod %s/%s in tc %s/%s, error: %s", ns, pod.Name, ns, tcName, err)
 	}
-	tc, _ := meta.(*v1alpha1.TidbCluster)
 	for _, pvc := range pvcs {
 		if err := addDeferDeletingAnnoToPVC(tc, pvc, s.deps.PVCControl); err != nil {
 			return err
 		}
 	}
 
-	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+	s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 	return nil
 }
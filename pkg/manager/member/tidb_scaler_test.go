@@ -19,10 +19,12 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
@@ -311,6 +313,62 @@ func TestTiDBScalerScaleIn(t *testing.T) {
 	}
 }
 
+func TestTiDBScalerScaleInWithUpgradePartition(t *testing.T) {
+	tests := []struct {
+		name              string
+		partition         int32
+		expectedPartition int32
+		expectedEvents    []string
+	}{
+		{
+			name:              "partition exceeds replicas after scaling in",
+			partition:         5,
+			expectedPartition: 4,
+			expectedEvents:    []string{"Normal UpgradePartitionClamped the upgrade partition of statefulset scaler is reset from 5 to 4 as it is scaled in to 4 replicas"},
+		},
+		{
+			name:              "partition is within replicas after scaling in",
+			partition:         3,
+			expectedPartition: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			tc := newTidbClusterForPD()
+			tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+
+			oldSet := newStatefulSetForPDScale()
+			oldSet.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{Partition: pointer.Int32Ptr(tt.partition)}
+			newSet := oldSet.DeepCopy()
+			newSet.Spec.Replicas = pointer.Int32Ptr(3)
+
+			pod := &corev1.Pod{
+				TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              tidbPodName(tc.GetName(), 4),
+					Namespace:         corev1.NamespaceDefault,
+					CreationTimestamp: metav1.Time{Time: time.Now().Add(-1 * time.Hour)},
+					Labels:            map[string]string{},
+				},
+			}
+			readyPodFunc(pod)
+			scaler, _, podIndexer, _ := newFakeTiDBScaler()
+			podIndexer.Add(pod)
+
+			err := scaler.ScaleIn(tc, oldSet, newSet)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(*newSet.Spec.Replicas).To(Equal(int32(4)))
+			g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(tt.expectedPartition))
+			// the partition of the old statefulset is not changed
+			g.Expect(*oldSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(tt.partition))
+			recorder := scaler.deps.Recorder.(*record.FakeRecorder)
+			g.Expect(collectEvents(recorder.Events)).To(ConsistOf(tt.expectedEvents))
+		})
+	}
+}
+
 func newFakeTiDBScaler(resyncDuration ...time.Duration) (*tidbScaler, cache.Indexer, cache.Indexer, *controller.FakePVCControl) {
 	fakeDeps := controller.NewFakeDependencies()
 	if len(resyncDuration) > 0 {
@@ -15,25 +15,43 @@ package member
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 )
 
+// tidbMemberRegisterTimeout is how long the upgrade waits for a ready pod on the update revision to be registered in
+// the status of the TiDB members, e.g. a pod created by a scale-out along with the upgrade is registered only after
+// the status of the StatefulSet counts it, and the pod is regarded as upgraded once the timeout elapses
+const tidbMemberRegisterTimeout = 3 * time.Minute
+
 type tidbUpgrader struct {
-	deps *controller.Dependencies
+	deps     *controller.Dependencies
+	recorder record.EventRecorder
 }
 
 // NewTiDBUpgrader returns a tidb Upgrader
 func NewTiDBUpgrader(deps *controller.Dependencies) Upgrader {
 	return &tidbUpgrader{
-		deps: deps,
+		deps:     deps,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -47,17 +65,16 @@ func (u *tidbUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSe
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
-	if tc.Status.PD.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiKV.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.TiFlash.Phase == v1alpha1.UpgradePhase ||
-		tc.Status.Pump.Phase == v1alpha1.UpgradePhase ||
-		tc.TiDBScaling() {
-		klog.Infof("TidbCluster: [%s/%s]'s pd status is %s, "+
-			"tikv status is %s, tiflash status is %s, pump status is %s, "+
+	if frozen, err := keepTemplateIfUpgradeFrozen(u.deps, tc, v1alpha1.TiDBMemberType, oldSet, newSet); frozen {
+		return err
+	}
+
+	if blocking := upgradeBlockedBy(tc, v1alpha1.TiDBMemberType); blocking != "" || tc.TiDBScaling() {
+		klog.Infof("TidbCluster: [%s/%s]'s components upgraded before tidb [%s] are upgrading, "+
 			"tidb status is %s, can not upgrade tidb",
-			ns, tcName,
-			tc.Status.PD.Phase, tc.Status.TiKV.Phase, tc.Status.TiFlash.Phase,
-			tc.Status.Pump.Phase, tc.Status.TiDB.Phase)
+			ns, tcName, blocking, tc.Status.TiDB.Phase)
+		reason := upgradeBlockedReason(v1alpha1.TiDBMemberType, blocking, tc.Status.TiDB.Phase)
+		recordUpgradeBlocked(u.recorder, tc, v1alpha1.TiDBMemberType, reason)
 		_, podSpec, err := GetLastAppliedConfig(oldSet)
 		if err != nil {
 			return err
@@ -66,6 +83,7 @@ func (u *tidbUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSe
 		return nil
 	}
 
+	recordLastReconcileBy(u.deps, tc, tc.Status.TiDB.Phase != v1alpha1.UpgradePhase)
 	tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -86,11 +104,26 @@ func (u *tidbUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSe
 	}
 
 	mngerutils.SetUpgradePartition(newSet, *oldSet.Spec.UpdateStrategy.RollingUpdate.Partition)
+	if reason := tidbForceUpgradeReason(tc); reason != "" {
+		tc.Status.TiDB.UpgradeDrain = nil
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+		return u.forceUpgrade(tc, oldSet, newSet, reason)
+	}
+	batchSize := tc.Spec.TiDB.GetUpgradeBatchSize()
+	canaries := sets.NewInt32(tc.Spec.TiDB.CanaryOrdinals...)
+	// pinned is true if the upgrade reaches a pod which is not a canary
+	pinned := false
+	// batch is the ordinals of the pods to be upgraded in descending order, including the ones being upgraded
+	var batch []int32
 	podOrdinals := helper.GetPodOrdinals(*oldSet.Spec.Replicas, oldSet).List()
-	for _i := len(podOrdinals) - 1; _i >= 0; _i-- {
+	for _i := len(podOrdinals) - 1; _i >= 0 && int32(len(batch)) < batchSize; _i-- {
 		i := podOrdinals[_i]
-		podName := tidbPodName(tcName, i)
+		podName := tidbSetPodName(tc, i)
 		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if errors.IsNotFound(err) && batchSize > 1 {
+			// the pods of a batch deleted by the upgrade are recreated by the StatefulSet controller
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pod: [%s] is being recreated", ns, tcName, podName)
+		}
 		if err != nil {
 			return fmt.Errorf("tidbUpgrader.Upgrade: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
 		}
@@ -101,21 +134,485 @@ func (u *tidbUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSe
 
 		if revision == tc.Status.TiDB.StatefulSet.UpdateRevision {
 			if !podutil.IsPodReady(pod) {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.TiDBMemberType, podName, "is not ready")
+				if u.syncUpgradeStalled(tc, pod, time.Now()) {
+					return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded tidb pod: [%s] is not ready within the upgrade readiness timeout %s",
+						ns, tcName, podName, tc.Spec.TiDB.GetUpgradeReadinessTimeout())
+				}
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded tidb pod: [%s] is not ready", ns, tcName, podName)
 			}
-			if member, exist := tc.Status.TiDB.Members[podName]; !exist || !member.Health {
+			if member, exist := tc.Status.TiDB.Members[podName]; !exist {
+				if wait := tidbMemberRegisterWait(pod, time.Now()); wait > 0 {
+					return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pod: [%s] on the update revision is not registered yet, wait for it up to %s",
+						ns, tcName, podName, wait.Round(time.Second))
+				}
+				klog.Warningf("tidbUpgrader.Upgrade: pod %s in tc %s/%s is ready but not registered within %s, regard it as upgraded", podName, ns, tcName, tidbMemberRegisterTimeout)
+			} else if !member.Health {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.TiDBMemberType, podName, "is not a healthy tidb member")
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb upgraded pod: [%s] is not ready", ns, tcName, podName)
 			}
+			if tc.Spec.TiDB.WarmStandbyUpgrade {
+				// the upgraded pod has taken over the traffic, retire its standby
+				if err := u.deleteWarmStandbyPod(tc, i); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if canaries.Len() > 0 && !canaries.Has(i) {
+			pinned = true
+			break
+		}
+		batch = append(batch, i)
+	}
+	// the upgraded pods are ready
+	utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+	if len(batch) == 0 {
+		tc.Status.TiDB.UpgradeDrain = nil
+		if pinned {
+			u.recordCanaryPinned(tc)
+		}
+		return nil
+	}
+	if upgradePaused(u.recorder, tc, v1alpha1.TiDBMemberType, batch[len(batch)-1]) {
+		// the pods being drained accept the connections again while the upgrade is paused
+		tc.Status.TiDB.UpgradeDrain = nil
+		return nil
+	}
+	syncUpgradeStatus(u.deps, tc, v1alpha1.TiDBMemberType, oldSet, &batch[len(batch)-1])
+
+	partition := *oldSet.Spec.UpdateStrategy.RollingUpdate.Partition
+	if batchSize > 1 && batch[0] >= partition {
+		// the pods covered by the partition are the batch being upgraded, the next batch is selected only after
+		// all of them are upgraded, ready and healthy
+		var rolling []int32
+		for _, i := range batch {
+			if i >= partition {
+				rolling = append(rolling, i)
+			}
+		}
+		return u.rollTiDBBatch(tc, oldSet, rolling)
+	}
+
+	if err := u.checkTiDBUpgradeBudget(tc, oldSet, batch); err != nil {
+		return err
+	}
+	if tc.Spec.TiDB.WarmStandbyUpgrade {
+		for _, i := range batch {
+			if err := u.ensureWarmStandbyPod(tc, newSet, i); err != nil {
+				return err
+			}
+		}
+	}
+	if err := u.drainConnections(tc, batch); err != nil {
+		return err
+	}
+	// the forced upgrades are recorded by the UpgradeForced events instead
+	if ordinal := batch[len(batch)-1]; ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition {
+		recordUpgradePodSelected(u.recorder, tc, v1alpha1.TiDBMemberType, tidbSetPodName(tc, ordinal), tc.Status.TiDB.StatefulSet)
+	}
+	return u.upgradeTiDBPod(tc, batch[len(batch)-1], newSet)
+}
+
+// rollTiDBBatch deletes the pods of the batch covered by the partition, so that the StatefulSet controller recreates
+// them on the update revision at the same time, while it replaces only one pod at a time by itself. The pods are
+// drained before the partition covers them, and the availability budget is checked again before they are deleted.
+func (u *tidbUpgrader) rollTiDBBatch(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, batch []int32) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if err := u.checkTiDBUpgradeBudget(tc, set, batch); err != nil {
+		return err
+	}
+	for _, i := range batch {
+		podName := tidbSetPodName(tc, i)
+		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		klog.Infof("tidbUpgrader.Upgrade: delete pod %s in tc %s/%s to upgrade it with the batch %v", podName, ns, tcName, batch)
+		if err := u.deps.PodControl.DeletePod(tc, pod); err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to delete pod %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+	}
+	return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pods %v are being upgraded, wait for them to be ready and healthy", ns, tcName, batch)
+}
+
+// tidbMemberRegisterWait returns how long the upgrade still waits for the ready pod to be registered in the status
+// of the TiDB members, counted from when the pod got ready, or from its creation if that is unknown
+func tidbMemberRegisterWait(pod *corev1.Pod, now time.Time) time.Duration {
+	since := podReadyTransitionTime(pod)
+	if since.IsZero() {
+		return tidbMemberRegisterTimeout
+	}
+	return tidbMemberRegisterTimeout - now.Sub(since)
+}
+
+// podReadyTransitionTime returns the last transition time of the Ready condition of the pod, or the creation time of
+// the pod if that is unknown
+func podReadyTransitionTime(pod *corev1.Pod) time.Time {
+	if _, cond := podutil.GetPodCondition(&pod.Status, corev1.PodReady); cond != nil && !cond.LastTransitionTime.IsZero() {
+		return cond.LastTransitionTime.Time
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// syncUpgradeStalled sets the condition UpgradeStalled of the TidbCluster and emits an UpgradeReadinessTimeout event
+// if the upgraded pod is not ready longer than spec.tidb.upgradeReadinessTimeout, it returns true if the pod times out.
+// The partition is not moved in either case, the condition only tells why the upgrade does not move on.
+func (u *tidbUpgrader) syncUpgradeStalled(tc *v1alpha1.TidbCluster, pod *corev1.Pod, now time.Time) bool {
+	timeout := tc.Spec.TiDB.GetUpgradeReadinessTimeout()
+	since := podReadyTransitionTime(pod)
+	if timeout <= 0 || since.IsZero() || now.Sub(since) < timeout {
+		return false
+	}
+
+	message := fmt.Sprintf("upgraded tidb pod %s is not ready since %s, longer than the upgrade readiness timeout %s, the upgrade waits for it",
+		pod.Name, since.Format(time.RFC3339), timeout)
+	current := utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+	if current == nil || current.Status != corev1.ConditionTrue || current.Message != message {
+		klog.Warningf("tidbcluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), message)
+		recordUpgradeEvent(u.recorder, tc, corev1.EventTypeWarning, events.UpgradeReadinessTimeout, message)
+	}
+	cond := utiltidbcluster.NewTidbClusterCondition(v1alpha1.TidbClusterUpgradeStalled, corev1.ConditionTrue, utiltidbcluster.TiDBPodNotReadyTimedOut, message)
+	// the message is updated for another pod timing out without changing the transition time
+	if current != nil && current.Status == corev1.ConditionTrue && current.Message != message {
+		cond.LastTransitionTime = current.LastTransitionTime
+		utiltidbcluster.RemoveTidbClusterCondition(&tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+	}
+	utiltidbcluster.SetTidbClusterCondition(&tc.Status, *cond)
+	return true
+}
+
+// recordCanaryPinned records that the upgrade stops after the canaries in spec.tidb.canaryOrdinals are upgraded
+func (u *tidbUpgrader) recordCanaryPinned(tc *v1alpha1.TidbCluster) {
+	msg := fmt.Sprintf("tidb upgrade is pinned to the canary ordinals %v, the other pods stay on the old revision and the upgrades of the other components wait until spec.tidb.canaryOrdinals is cleared",
+		sets.NewInt32(tc.Spec.TiDB.CanaryOrdinals...).List())
+	klog.Infof("TidbCluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), msg)
+	recordUpgradeEvent(u.recorder, tc, corev1.EventTypeNormal, events.UpgradeCanaryPinned, msg)
+}
+
+// tidbForceUpgradeReason returns why the upgrade of TiDB bypasses the readiness and health checks of the pods, it
+// is empty unless the annotation tidb.pingcap.com/force-upgrade is set or all the TiDB members are unhealthy, e.g.
+// all the pods crash on a bad config, which is fixed only by rolling out the new config without the checks.
+func tidbForceUpgradeReason(tc *v1alpha1.TidbCluster) string {
+	if NeedForceUpgrade(tc.Annotations) {
+		return fmt.Sprintf("annotation %s is set", label.AnnForceUpgradeKey)
+	}
+	if len(tc.Status.TiDB.Members) == 0 {
+		return ""
+	}
+	for _, member := range tc.Status.TiDB.Members {
+		if member.Health {
+			return ""
+		}
+	}
+	return "all the tidb members are unhealthy"
+}
+
+// forceUpgrade upgrades the TiDB pods one at a time in descending order of the ordinals without waiting for the
+// upgraded pods to be ready and healthy. The StatefulSet controller does not replace a pod while a pod of a higher
+// ordinal is not ready, so the pod is deleted once the partition covers it and recreated on the update revision.
+func (u *tidbUpgrader) forceUpgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet, reason string) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	canaries := sets.NewInt32(tc.Spec.TiDB.CanaryOrdinals...)
+	podOrdinals := helper.GetPodOrdinals(*oldSet.Spec.Replicas, oldSet).List()
+	for _i := len(podOrdinals) - 1; _i >= 0; _i-- {
+		i := podOrdinals[_i]
+		podName := tidbSetPodName(tc, i)
+		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if errors.IsNotFound(err) {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pod: [%s] is being recreated", ns, tcName, podName)
+		}
+		if err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		if pod.Labels[apps.ControllerRevisionHashLabelKey] == tc.Status.TiDB.StatefulSet.UpdateRevision {
+			continue
+		}
+		if canaries.Len() > 0 && !canaries.Has(i) {
+			u.recordCanaryPinned(tc)
+			return nil
+		}
+		if upgradePaused(u.recorder, tc, v1alpha1.TiDBMemberType, i) {
+			return nil
+		}
+		syncUpgradeStatus(u.deps, tc, v1alpha1.TiDBMemberType, oldSet, &i)
+
+		if *oldSet.Spec.UpdateStrategy.RollingUpdate.Partition > i {
+			// the pod is deleted after the partition covering it is applied, so that it is recreated on the
+			// update revision
+			return u.upgradeTiDBPod(tc, i, newSet)
+		}
+		if pod.DeletionTimestamp != nil {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pod: [%s] is terminating", ns, tcName, podName)
+		}
+		msg := fmt.Sprintf("force upgrade tidb pod %s bypassing the readiness and health checks as %s", podName, reason)
+		klog.Warningf("TidbCluster: [%s/%s] %s", ns, tcName, msg)
+		recordUpgradeEvent(u.recorder, tc, corev1.EventTypeWarning, events.UpgradeForced, msg)
+		if err := u.deps.PodControl.DeletePod(tc, pod); err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to delete pod %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb pod: [%s] is force upgraded, wait for it to be recreated", ns, tcName, podName)
+	}
+	return nil
+}
+
+// drainConnections stops the pods of the batch accepting new connections and waits for their connections to fall
+// to spec.tidb.upgradeDrainConnectionThreshold or spec.tidb.gracefulShutdownTimeoutSeconds to elapse, a
+// RequeueError is returned while draining.
+// The pods which are not ready or unhealthy are not drained, so that a crashed member never blocks the upgrade.
+func (u *tidbUpgrader) drainConnections(tc *v1alpha1.TidbCluster, batch []int32) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	timeout := time.Duration(tc.Spec.TiDB.GracefulShutdownTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		tc.Status.TiDB.UpgradeDrain = nil
+		return nil
+	}
+
+	// the pods being drained are not ready as they are removed from the endpoints by the readiness gate
+	draining := sets.NewString()
+	if status := tc.Status.TiDB.UpgradeDrain; status != nil {
+		draining.Insert(status.PodNames...)
+	}
+	var pods []*corev1.Pod
+	var ordinals []int32
+	for _, i := range batch {
+		podName := tidbSetPodName(tc, i)
+		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if member, exist := tc.Status.TiDB.Members[podName]; !exist || !member.Health || (!podutil.IsPodReady(pod) && !draining.Has(podName)) {
+			klog.Infof("tidbUpgrader.Upgrade: pod %s in tc %s/%s is not ready or unhealthy, upgrade it without draining", podName, ns, tcName)
+			continue
+		}
+		pods = append(pods, pod)
+		ordinals = append(ordinals, i)
+	}
+	if len(pods) == 0 {
+		tc.Status.TiDB.UpgradeDrain = nil
+		return nil
+	}
+
+	podNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Name)
+	}
+	status := tc.Status.TiDB.UpgradeDrain
+	if status == nil || !sets.NewString(status.PodNames...).Equal(sets.NewString(podNames...)) {
+		status = &v1alpha1.TiDBUpgradeDrainStatus{
+			PodNames:    podNames,
+			Connections: -1,
+			Deadline:    metav1.NewTime(time.Now().Add(timeout)),
+		}
+		tc.Status.TiDB.UpgradeDrain = status
+	}
+	if status.Drained {
+		return nil
+	}
+	// the condition is set on every round in case the pods are recreated while draining
+	for _, pod := range pods {
+		if err := setTiDBAcceptingConnections(u.deps, tc, pod, false); err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to stop pod %s in tc %s/%s accepting connections, error: %s", pod.Name, ns, tcName, err)
+		}
+	}
+
+	threshold := tc.Spec.TiDB.GetUpgradeDrainConnectionThreshold()
+	drained := true
+	status.Connections = 0
+	for i, ordinal := range ordinals {
+		count, err := u.deps.TiDBControl.GetConnectionCount(tc, ordinal)
+		if err != nil {
+			klog.Warningf("tidbUpgrader.Upgrade: failed to get the connections of pod %s in tc %s/%s, error: %s", podNames[i], ns, tcName, err)
+			status.Connections = -1
+			drained = false
 			continue
 		}
-		return u.upgradeTiDBPod(tc, i, newSet)
+		if status.Connections >= 0 {
+			status.Connections += count
+		}
+		if count > threshold {
+			drained = false
+		}
+	}
+	if drained {
+		klog.Infof("tidbUpgrader.Upgrade: pods %v in tc %s/%s are drained, %d connections left", podNames, ns, tcName, status.Connections)
+		status.Drained = true
+		return nil
 	}
+	if time.Now().After(status.Deadline.Time) {
+		msg := fmt.Sprintf("the connections of pods %v are not drained before %s, %d connections left, upgrade them anyway",
+			podNames, status.Deadline.Format(time.RFC3339), status.Connections)
+		klog.Warningf("tidbUpgrader.Upgrade: tc %s/%s %s", ns, tcName, msg)
+		u.recorder.Event(tc, corev1.EventTypeWarning, events.TiDBUpgradeDrainTimeout, msg)
+		status.Drained = true
+		return nil
+	}
+	return controller.RequeueErrorf("tidbcluster: [%s/%s] draining the connections of tidb pods %v before the upgrade, %d connections left, wait for next round",
+		ns, tcName, podNames, status.Connections)
+}
 
+// checkTiDBUpgradeBudget returns a RequeueError if upgrading the batch would leave less than replicas minus
+// spec.tidb.upgradeBatchSize TiDB pods available, it is the only availability check of the upgrade. A pod is
+// available if it is ready and healthy, the pods on both revisions are counted. Only the available pods of the batch are taken down by
+// the upgrade, so the batch of the pods which are not available is always upgraded.
+func (u *tidbUpgrader) checkTiDBUpgradeBudget(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, batch []int32) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	minAvailable := *set.Spec.Replicas - tc.Spec.TiDB.GetUpgradeBatchSize()
+	upgrading := sets.NewInt32(batch...)
+	var available, lost int32
+	for _, i := range helper.GetPodOrdinals(*set.Spec.Replicas, set).List() {
+		podName := tidbSetPodName(tc, i)
+		pod, err := u.deps.PodLister.Pods(ns).Get(podName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to get pods %s for cluster %s/%s, error: %s", podName, ns, tcName, err)
+		}
+		if pod.DeletionTimestamp != nil || !podutil.IsPodReady(pod) {
+			continue
+		}
+		if member, exist := tc.Status.TiDB.Members[podName]; exist && !member.Health {
+			continue
+		}
+		available++
+		if upgrading.Has(i) {
+			lost++
+		}
+	}
+	if lost > 0 && available-lost < minAvailable {
+		reason := fmt.Sprintf("%d of %d tidb pods are available, upgrading %d available pods would leave less than %d pods available",
+			available, *set.Spec.Replicas, lost, minAvailable)
+		recordUpgradeBlocked(u.recorder, tc, v1alpha1.TiDBMemberType, reason)
+		return controller.RequeueErrorf("tidbcluster: [%s/%s] can not upgrade tidb, %s", ns, tcName, reason)
+	}
+	return nil
+}
+
+// ensureWarmStandbyPod creates the standby pod for the given ordinal if it does not exist,
+// and returns a RequeueError until the standby pod is ready.
+func (u *tidbUpgrader) ensureWarmStandbyPod(tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, ordinal int32) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	standbyName := tidbWarmStandbyPodName(controller.TiDBSetName(tc), ordinal)
+
+	standby, err := u.deps.PodLister.Pods(ns).Get(standbyName)
+	if errors.IsNotFound(err) {
+		if err := u.deps.PodControl.CreatePod(tc, newTiDBWarmStandbyPod(tc, newSet, ordinal)); err != nil {
+			return fmt.Errorf("tidbUpgrader.Upgrade: failed to create warm standby pod %s for cluster %s/%s, error: %s", standbyName, ns, tcName, err)
+		}
+		return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb warm standby pod: [%s] is created, wait for it to be ready", ns, tcName, standbyName)
+	}
+	if err != nil {
+		return fmt.Errorf("tidbUpgrader.Upgrade: failed to get warm standby pod %s for cluster %s/%s, error: %s", standbyName, ns, tcName, err)
+	}
+	if !podutil.IsPodReady(standby) {
+		return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tidb warm standby pod: [%s] is not ready", ns, tcName, standbyName)
+	}
 	return nil
 }
 
+func (u *tidbUpgrader) deleteWarmStandbyPod(tc *v1alpha1.TidbCluster, ordinal int32) error {
+	ns := tc.GetNamespace()
+	standbyName := tidbWarmStandbyPodName(controller.TiDBSetName(tc), ordinal)
+
+	standby, err := u.deps.PodLister.Pods(ns).Get(standbyName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("tidbUpgrader.Upgrade: failed to get warm standby pod %s for cluster %s/%s, error: %s", standbyName, ns, tc.GetName(), err)
+	}
+	return u.deps.PodControl.DeletePod(tc, standby)
+}
+
+// cleanupTiDBWarmStandbyPods deletes all the warm standby pods of the TidbCluster once the upgrade
+// is finished or the warm standby upgrade is aborted, they are kept during a warm standby upgrade.
+func cleanupTiDBWarmStandbyPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	if tc.Status.TiDB.Phase == v1alpha1.UpgradePhase && tc.Spec.TiDB.WarmStandbyUpgrade {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiDB().Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := deps.PodLister.Pods(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("cleanupTiDBWarmStandbyPods: failed to list pods for cluster %s/%s, selector %s, error: %s", ns, tc.GetName(), selector, err)
+	}
+	for _, pod := range pods {
+		if !isTiDBWarmStandbyPod(pod) || tidbGroupOf(pod) != tidbGroupOf(tc) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		klog.Infof("tidbcluster: [%s/%s] delete tidb warm standby pod %s", ns, tc.GetName(), pod.GetName())
+		if err := deps.PodControl.DeletePod(tc, pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTiDBWarmStandbyPod builds a standalone pod from the new TiDB pod template, it joins the
+// headless and client services of TiDB so that it can serve while the old pod is replaced.
+// It is labeled by label.TiDBWarmStandbyLabelKey with the ordinal it stands in for, the pods
+// with the label are not counted as the TiDB members.
+func newTiDBWarmStandbyPod(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, ordinal int32) *corev1.Pod {
+	name := tidbWarmStandbyPodName(controller.TiDBSetName(tc), ordinal)
+	template := set.Spec.Template.DeepCopy()
+
+	podLabels := util.CombineStringMap(template.Labels, changeRequestLabels(tc))
+	podLabels[label.TiDBWarmStandbyLabelKey] = strconv.Itoa(int(ordinal))
+
+	podSpec := template.Spec
+	podSpec.Hostname = name
+	podSpec.Subdomain = controller.TiDBSetPeerName(tc)
+	// volumes from volumeClaimTemplates are owned by the statefulset pods,
+	// the short-lived standby pod uses emptyDir instead.
+	for _, vct := range set.Spec.VolumeClaimTemplates {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: vct.Name,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       tc.GetNamespace(),
+			Labels:          podLabels,
+			Annotations:     template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: podSpec,
+	}
+}
+
+func tidbWarmStandbyPodName(setName string, ordinal int32) string {
+	return fmt.Sprintf("%s-standby-%d", setName, ordinal)
+}
+
+func isTiDBWarmStandbyPod(pod *corev1.Pod) bool {
+	_, ok := pod.Labels[label.TiDBWarmStandbyLabelKey]
+	return ok
+}
+
 func (u *tidbUpgrader) upgradeTiDBPod(tc *v1alpha1.TidbCluster, ordinal int32, newSet *apps.StatefulSet) error {
+	recordLastReconcileBy(u.deps, tc, ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition)
 	mngerutils.SetUpgradePartition(newSet, ordinal)
+	prepullNextImage(u.deps, tc, v1alpha1.TiDBMemberType, newSet, ordinal)
 	return nil
 }
 
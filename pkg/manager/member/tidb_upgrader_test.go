@@ -14,19 +14,29 @@
 package member
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	utiltidbcluster "github.com/pingcap/tidb-operator/pkg/util/tidbcluster"
 
 	. "github.com/onsi/gomega"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	podinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 )
 
@@ -37,10 +47,13 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 		name                    string
 		changeFn                func(*v1alpha1.TidbCluster)
 		changePods              func(pods []*corev1.Pod)
+		extraPods               []*corev1.Pod
 		getLastAppliedConfigErr bool
 		errorExpect             bool
 		changeOldSet            func(set *apps.StatefulSet)
+		changeNewSet            func(set *apps.StatefulSet)
 		expectFn                func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet)
+		expectPodsFn            func(g *GomegaWithT, podLister corelisters.PodLister)
 	}
 
 	testFn := func(test *testcase, t *testing.T) {
@@ -54,6 +67,7 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 		if test.changePods != nil {
 			test.changePods(pods)
 		}
+		pods = append(pods, test.extraPods...)
 		for _, pod := range pods {
 			podInformer.Informer().GetIndexer().Add(pod)
 		}
@@ -64,6 +78,9 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 		}
 
 		newSet := oldSet.DeepCopy()
+		if test.changeNewSet != nil {
+			test.changeNewSet(newSet)
+		}
 		if test.getLastAppliedConfigErr {
 			oldSet.SetAnnotations(map[string]string{LastAppliedConfigAnnotation: "fake apply config"})
 		} else {
@@ -76,6 +93,9 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 			g.Expect(err).NotTo(HaveOccurred())
 		}
 		test.expectFn(g, tc, newSet)
+		if test.expectPodsFn != nil {
+			test.expectPodsFn(g, podInformer.Lister())
+		}
 	}
 
 	tests := []*testcase{
@@ -89,6 +109,8 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
 				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.UpgradePhase))
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+				g.Expect(tc.Status.LastReconcileBy).To(Equal("tidb-controller-manager-0"))
+				g.Expect(tc.Status.LastReconcileTime).NotTo(BeNil())
 			},
 		},
 		{
@@ -109,6 +131,48 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 			},
 			errorExpect: true,
 		},
+		{
+			name: "last reconcile time is kept while waiting for notReady pod",
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					pod.Status = *new(corev1.PodStatus)
+				}
+			},
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+				tc.Status.LastReconcileBy = "tidb-controller-manager-0"
+				tc.Status.LastReconcileTime = &metav1.Time{Time: time.Unix(1000, 0)}
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+				g.Expect(tc.Status.LastReconcileTime).To(Equal(&metav1.Time{Time: time.Unix(1000, 0)}))
+			},
+			errorExpect: true,
+		},
+		{
+			name: "last reconcile time is stamped when another instance takes over",
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					pod.Status = *new(corev1.PodStatus)
+				}
+			},
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+				tc.Status.LastReconcileBy = "tidb-controller-manager-1"
+				tc.Status.LastReconcileTime = &metav1.Time{Time: time.Unix(1000, 0)}
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.LastReconcileBy).To(Equal("tidb-controller-manager-0"))
+				g.Expect(tc.Status.LastReconcileTime.After(time.Unix(1000, 0))).To(BeTrue())
+			},
+			errorExpect: true,
+		},
 		{
 			name: "modify oldSet update strategy to OnDelete",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
@@ -152,6 +216,7 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 			getLastAppliedConfigErr: false,
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+				g.Expect(tc.Status.LastReconcileBy).To(BeEmpty())
 			},
 		},
 		{
@@ -165,6 +230,19 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
 			},
 		},
+		{
+			name: "paused tikv is in upgrade phase",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+				tc.Spec.TiKV.Paused = true
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.UpgradePhase))
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+			},
+		},
 		{
 			name: "tiflash is upgrading",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
@@ -185,6 +263,38 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
 			},
 		},
+		{
+			name: "ticdc is upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiCDC.Phase = v1alpha1.UpgradePhase
+			},
+			changeNewSet: func(set *apps.StatefulSet) {
+				set.Spec.Template.Spec.Containers[0].Image = "tidb-test-image:new"
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.TiDB.Phase).NotTo(Equal(v1alpha1.UpgradePhase))
+				g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("tidb-test-image"))
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+			},
+		},
+		{
+			name: "ticdc upgraded after tidb by spec.upgradeOrder is upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Status.TiCDC.Phase = v1alpha1.UpgradePhase
+				tc.Spec.UpgradeOrder = []v1alpha1.MemberType{v1alpha1.PDMemberType, v1alpha1.TiKVMemberType, v1alpha1.TiDBMemberType, v1alpha1.TiCDCMemberType}
+			},
+			changeNewSet: func(set *apps.StatefulSet) {
+				set.Spec.Template.Spec.Containers[0].Image = "tidb-test-image:new"
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.UpgradePhase))
+				g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("tidb-test-image:new"))
+			},
+		},
 		{
 			name: "upgrade revision equals current revision",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
@@ -227,6 +337,117 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
 			},
 		},
+		{
+			name: "the other pod is not ready",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			},
+			changePods: func(pods []*corev1.Pod) {
+				pods[1].Labels[apps.ControllerRevisionHashLabelKey] = "1"
+				pods[0].Status = *new(corev1.PodStatus)
+			},
+			changeOldSet: func(set *apps.StatefulSet) {
+				set.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			errorExpect: true,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+			},
+		},
+		{
+			name: "the pod to be upgraded is not ready",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+			},
+			changePods: func(pods []*corev1.Pod) {
+				pods[1].Labels[apps.ControllerRevisionHashLabelKey] = "1"
+				pods[1].Status = *new(corev1.PodStatus)
+			},
+			changeOldSet: func(set *apps.StatefulSet) {
+				set.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+			},
+		},
+		{
+			name: "warm standby pod is created before upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Spec.TiDB.WarmStandbyUpgrade = true
+				tc.Annotations = map[string]string{label.AnnChangeRequestID: "CR-1024"}
+			},
+			getLastAppliedConfigErr: false,
+			errorExpect:             true,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(tc.Status.TiDB.Phase).To(Equal(v1alpha1.UpgradePhase))
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+			},
+			expectPodsFn: func(g *GomegaWithT, podLister corelisters.PodLister) {
+				standby, err := podLister.Pods(corev1.NamespaceDefault).Get(tidbWarmStandbyPodName(controller.TiDBMemberName(upgradeTcName), 0))
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(standby.Labels[label.TiDBWarmStandbyLabelKey]).To(Equal("0"))
+				g.Expect(standby.Labels[label.AnnChangeRequestID]).To(Equal("CR-1024"))
+				// the standby pod is selected by the services of TiDB
+				selector, err := label.New().Instance(upgradeInstanceName).TiDB().Selector()
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(selector.Matches(labels.Set(standby.Labels))).To(BeTrue())
+				g.Expect(standby.Spec.Hostname).To(Equal(standby.Name))
+				g.Expect(standby.Spec.Subdomain).To(Equal(controller.TiDBPeerMemberName(upgradeTcName)))
+			},
+		},
+		{
+			name: "warm standby pod is not ready",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Spec.TiDB.WarmStandbyUpgrade = true
+			},
+			extraPods:               []*corev1.Pod{newTiDBWarmStandbyPodForUpgrader(0, false)},
+			getLastAppliedConfigErr: false,
+			errorExpect:             true,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+			},
+		},
+		{
+			name: "warm standby pod is ready",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Spec.TiDB.WarmStandbyUpgrade = true
+			},
+			extraPods:               []*corev1.Pod{newTiDBWarmStandbyPodForUpgrader(0, true)},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+			},
+		},
+		{
+			name: "warm standby pod of upgraded pod is retired",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Spec.TiDB.WarmStandbyUpgrade = true
+			},
+			extraPods: []*corev1.Pod{
+				newTiDBWarmStandbyPodForUpgrader(0, true),
+				newTiDBWarmStandbyPodForUpgrader(1, true),
+			},
+			getLastAppliedConfigErr: false,
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet) {
+				g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+			},
+			expectPodsFn: func(g *GomegaWithT, podLister corelisters.PodLister) {
+				_, err := podLister.Pods(corev1.NamespaceDefault).Get(tidbWarmStandbyPodName(controller.TiDBMemberName(upgradeTcName), 1))
+				g.Expect(errors.IsNotFound(err)).To(BeTrue())
+				_, err = podLister.Pods(corev1.NamespaceDefault).Get(tidbWarmStandbyPodName(controller.TiDBMemberName(upgradeTcName), 0))
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -235,9 +456,710 @@ func TestTiDBUpgrader_Upgrade(t *testing.T) {
 
 }
 
+func TestTiDBUpgraderUpgradeBatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type testcase struct {
+		name           string
+		replicas       int32
+		batchSize      int32
+		batchPercent   *intstr.IntOrString
+		partition      int32
+		upgraded       []int32
+		unhealthy      []int32
+		unready        []int32
+		unhealthyReady []int32
+		paused         bool
+		canaries       []int32
+		missing        []int32
+		errorExpect    bool
+		expectPart     int32
+		expectDeleted  []int32
+	}
+
+	testFn := func(test *testcase) {
+		t.Log(test.name)
+		upgrader, _, _ := newTiDBUpgrader()
+		replicas := test.replicas
+		if replicas == 0 {
+			replicas = 5
+		}
+		b := NewTCBuilder().
+			WithReplicas(v1alpha1.TiDBMemberType, replicas).
+			WithUpgradedPods(v1alpha1.TiDBMemberType, test.upgraded...).
+			WithUnreadyPods(v1alpha1.TiDBMemberType, append(test.unready, test.unhealthy...)...).
+			WithUnhealthyMembers(v1alpha1.TiDBMemberType, append(test.unhealthyReady, test.unhealthy...)...)
+		deps := upgrader.(*tidbUpgrader).deps
+		b.Wire(deps)
+		tc := b.Build()
+		podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+		for _, i := range test.missing {
+			g.Expect(podIndexer.Delete(b.Pods(v1alpha1.TiDBMemberType)[i])).To(Succeed())
+		}
+		if test.batchPercent != nil {
+			tc.Spec.TiDB.UpgradeBatchSize = test.batchPercent
+		} else {
+			batchSize := intstr.FromInt(int(test.batchSize))
+			tc.Spec.TiDB.UpgradeBatchSize = &batchSize
+		}
+		if test.paused {
+			tc.Annotations = map[string]string{label.AnnUpgradePaused: label.AnnUpgradePausedVal}
+		}
+		tc.Spec.TiDB.CanaryOrdinals = test.canaries
+
+		oldSet := b.StatefulSet(v1alpha1.TiDBMemberType)
+		oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(test.partition)
+		newSet := oldSet.DeepCopy()
+
+		err := upgrader.Upgrade(tc, oldSet, newSet)
+		if test.errorExpect {
+			g.Expect(controller.IsRequeueError(err)).To(BeTrue(), test.name)
+		} else {
+			g.Expect(err).NotTo(HaveOccurred(), test.name)
+		}
+		g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(test.expectPart)), test.name)
+		missing := sets.NewInt32(test.missing...)
+		deleted := sets.NewInt32(test.expectDeleted...)
+		for i := int32(0); i < replicas; i++ {
+			if missing.Has(i) {
+				continue
+			}
+			_, err := deps.PodLister.Pods(tc.GetNamespace()).Get(tidbPodName(tc.GetName(), i))
+			if deleted.Has(i) {
+				g.Expect(errors.IsNotFound(err)).To(BeTrue(), "%s: pod %d is not deleted", test.name, i)
+			} else {
+				g.Expect(err).NotTo(HaveOccurred(), "%s: pod %d is deleted", test.name, i)
+			}
+		}
+	}
+
+	tests := []*testcase{
+		{
+			name:       "the first batch",
+			batchSize:  2,
+			partition:  5,
+			expectPart: 3,
+		},
+		{
+			name:       "the next batch after the upgraded pods are healthy",
+			batchSize:  2,
+			partition:  3,
+			upgraded:   []int32{4, 3},
+			expectPart: 1,
+		},
+		{
+			name:       "the last batch is smaller than the batch size",
+			batchSize:  3,
+			partition:  1,
+			upgraded:   []int32{4, 3, 2, 1},
+			expectPart: 0,
+		},
+		{
+			name:        "an upgraded pod of the batch is unhealthy",
+			batchSize:   2,
+			partition:   3,
+			upgraded:    []int32{4, 3},
+			unhealthy:   []int32{3},
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:          "the pod being upgraded counts in the batch",
+			batchSize:     2,
+			partition:     3,
+			upgraded:      []int32{4},
+			unhealthy:     []int32{3},
+			errorExpect:   true,
+			expectPart:    3,
+			expectDeleted: []int32{3},
+		},
+		{
+			name:          "the pods of the batch covered by the partition are deleted",
+			batchSize:     3,
+			partition:     2,
+			upgraded:      []int32{4},
+			errorExpect:   true,
+			expectPart:    2,
+			expectDeleted: []int32{3, 2},
+		},
+		{
+			name:        "the next batch waits for the deleted pods of the batch to be recreated",
+			batchSize:   2,
+			partition:   2,
+			upgraded:    []int32{4},
+			missing:     []int32{3},
+			errorExpect: true,
+			expectPart:  2,
+		},
+		{
+			name:        "the next batch waits for the recreated pods of the batch to be ready",
+			batchSize:   2,
+			partition:   3,
+			upgraded:    []int32{4, 3},
+			unready:     []int32{4},
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:        "the pods of the batch are not deleted if the budget is exhausted after the partition is moved",
+			batchSize:   2,
+			partition:   3,
+			upgraded:    []int32{4},
+			unready:     []int32{1, 0},
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:        "upgrading the batch leaves too few ready pods",
+			batchSize:   2,
+			partition:   4,
+			upgraded:    []int32{4},
+			unready:     []int32{0},
+			errorExpect: true,
+			expectPart:  4,
+		},
+		{
+			name:       "the pods of the batch which are not ready do not count",
+			batchSize:  2,
+			partition:  4,
+			upgraded:   []int32{4},
+			unready:    []int32{3},
+			expectPart: 2,
+		},
+		{
+			name:        "too few healthy pods",
+			batchSize:   2,
+			partition:   4,
+			upgraded:    []int32{4},
+			unhealthy:   []int32{2, 1, 0},
+			errorExpect: true,
+			expectPart:  4,
+		},
+		{
+			name:         "the batch scaled from the percentage of replicas",
+			batchPercent: intstrPtr(intstr.FromString("40%")),
+			partition:    5,
+			expectPart:   3,
+		},
+		{
+			name:         "the batch of the number set by upgradeBatchSize",
+			batchPercent: intstrPtr(intstr.FromInt(3)),
+			partition:    4,
+			upgraded:     []int32{4},
+			expectPart:   1,
+		},
+		{
+			name:         "the batch size never takes down all the pods",
+			batchPercent: intstrPtr(intstr.FromInt(5)),
+			partition:    5,
+			expectPart:   1,
+		},
+		{
+			name:         "an upgraded pod of the batch fails readiness",
+			batchPercent: intstrPtr(intstr.FromString("40%")),
+			partition:    3,
+			upgraded:     []int32{4, 3},
+			unready:      []int32{4},
+			errorExpect:  true,
+			expectPart:   3,
+		},
+		{
+			name:           "the ready pods which are unhealthy are not available",
+			batchSize:      1,
+			partition:      4,
+			upgraded:       []int32{4},
+			unhealthyReady: []int32{0},
+			errorExpect:    true,
+			expectPart:     4,
+		},
+		{
+			name:       "the batch is capped at replicas - 1",
+			replicas:   2,
+			batchSize:  2,
+			partition:  2,
+			expectPart: 1,
+		},
+		{
+			name:        "the next batch waits for the capped batch",
+			replicas:    2,
+			batchSize:   2,
+			partition:   1,
+			upgraded:    []int32{1},
+			unready:     []int32{1},
+			errorExpect: true,
+			expectPart:  1,
+		},
+		{
+			name:       "the next batch is not upgraded while the upgrade is paused",
+			batchSize:  2,
+			partition:  3,
+			upgraded:   []int32{4, 3},
+			paused:     true,
+			expectPart: 3,
+		},
+		{
+			name:       "the batch is cut at the canaries",
+			batchSize:  3,
+			partition:  5,
+			canaries:   []int32{4, 3},
+			expectPart: 3,
+		},
+		{
+			name:       "the upgrade stops after the canaries are upgraded",
+			batchSize:  2,
+			partition:  3,
+			upgraded:   []int32{4, 3},
+			canaries:   []int32{4, 3},
+			expectPart: 3,
+		},
+		{
+			name:       "the upgrade continues from the partition after the canaries are cleared",
+			batchSize:  2,
+			partition:  3,
+			upgraded:   []int32{4, 3},
+			expectPart: 1,
+		},
+	}
+
+	for _, test := range tests {
+		testFn(test)
+	}
+}
+
+func TestTiDBUpgraderScaleOut(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the cluster is scaled out from 3 to 4 pods along with the upgrade, the new pod 3 is created on the update
+	// revision and it is registered in the status of the members only after the status of the StatefulSet counts it
+	type testcase struct {
+		name string
+		// readySince is how long the new pod has been ready, it is not ready if nil
+		readySince *time.Duration
+		// created is how long ago the new pod was created
+		created     time.Duration
+		registered  bool
+		healthy     bool
+		errorExpect bool
+		expectPart  int32
+	}
+	duration := func(d time.Duration) *time.Duration { return &d }
+
+	testFn := func(test *testcase) {
+		t.Log(test.name)
+		upgrader, _, podInformer := newTiDBUpgrader()
+		b := NewTCBuilder().
+			WithReplicas(v1alpha1.TiDBMemberType, 4).
+			WithUpgradedPods(v1alpha1.TiDBMemberType, 3)
+		b.Wire(upgrader.(*tidbUpgrader).deps)
+		tc := b.Build()
+
+		podName := tidbPodName(upgradeTcName, 3)
+		now := time.Now()
+		pod := b.Pods(v1alpha1.TiDBMemberType)[3]
+		pod.CreationTimestamp = metav1.NewTime(now.Add(-test.created))
+		pod.Status.Conditions = nil
+		if test.readySince != nil {
+			pod.Status.Conditions = []corev1.PodCondition{{
+				Type:               corev1.PodReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(-*test.readySince)),
+			}}
+		}
+		g.Expect(podInformer.Informer().GetIndexer().Update(pod)).To(Succeed())
+		delete(tc.Status.TiDB.Members, podName)
+		if test.registered {
+			tc.Status.TiDB.Members[podName] = v1alpha1.TiDBMember{Name: podName, Health: test.healthy}
+		}
+
+		oldSet := b.StatefulSet(v1alpha1.TiDBMemberType)
+		oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(3)
+		newSet := oldSet.DeepCopy()
+
+		err := upgrader.Upgrade(tc, oldSet, newSet)
+		if test.errorExpect {
+			g.Expect(controller.IsRequeueError(err)).To(BeTrue(), test.name)
+		} else {
+			g.Expect(err).NotTo(HaveOccurred(), test.name)
+		}
+		g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(test.expectPart)), test.name)
+	}
+
+	tests := []*testcase{
+		{
+			name:        "the new pod is not ready",
+			created:     time.Minute,
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:        "the new pod is ready but not registered yet",
+			readySince:  duration(time.Minute),
+			created:     2 * time.Minute,
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:       "the new pod is registered and healthy",
+			readySince: duration(time.Minute),
+			created:    2 * time.Minute,
+			registered: true,
+			healthy:    true,
+			expectPart: 2,
+		},
+		{
+			name:        "the new pod is registered but unhealthy",
+			readySince:  duration(time.Hour),
+			created:     time.Hour,
+			registered:  true,
+			errorExpect: true,
+			expectPart:  3,
+		},
+		{
+			name:       "the new pod is not registered within the timeout",
+			readySince: duration(tidbMemberRegisterTimeout + time.Minute),
+			created:    time.Hour,
+			expectPart: 2,
+		},
+		{
+			name:        "the timeout is counted from when the new pod got ready",
+			readySince:  duration(time.Minute),
+			created:     time.Hour,
+			errorExpect: true,
+			expectPart:  3,
+		},
+	}
+
+	for _, test := range tests {
+		testFn(test)
+	}
+}
+
+func TestTiDBUpgraderForceUpgrade(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type setup struct {
+		b           *TCBuilder
+		upgrader    Upgrader
+		podInformer podinformers.PodInformer
+		recorder    *record.FakeRecorder
+		tc          *v1alpha1.TidbCluster
+	}
+	// newSetup returns 3 TiDB pods on the old revision, the crashed ones are neither ready nor healthy
+	newSetup := func(crashed ...int32) *setup {
+		upgrader, _, podInformer := newTiDBUpgrader()
+		b := NewTCBuilder().
+			WithUnreadyPods(v1alpha1.TiDBMemberType, crashed...).
+			WithUnhealthyMembers(v1alpha1.TiDBMemberType, crashed...)
+		b.Wire(upgrader.(*tidbUpgrader).deps)
+		recorder := upgrader.(*tidbUpgrader).deps.Recorder.(*record.FakeRecorder)
+		return &setup{b: b, upgrader: upgrader, podInformer: podInformer, recorder: recorder, tc: b.Build()}
+	}
+	upgrade := func(s *setup, partition int32) (*apps.StatefulSet, error) {
+		oldSet := s.b.StatefulSet(v1alpha1.TiDBMemberType)
+		oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(partition)
+		newSet := oldSet.DeepCopy()
+		return newSet, s.upgrader.Upgrade(s.tc, oldSet, newSet)
+	}
+	// recreate recreates the pod on the update revision, it crashes as before
+	recreate := func(s *setup, ordinal int32) {
+		_, err := s.podInformer.Lister().Pods(corev1.NamespaceDefault).Get(tidbPodName(upgradeTcName, ordinal))
+		g.Expect(errors.IsNotFound(err)).To(BeTrue())
+		pod := s.b.WithUpgradedPods(v1alpha1.TiDBMemberType, ordinal).Pods(v1alpha1.TiDBMemberType)[ordinal]
+		g.Expect(s.podInformer.Informer().GetIndexer().Add(pod)).To(Succeed())
+	}
+
+	// all crashed: the pods are rolled one by one in descending order of the ordinals without the checks
+	s := newSetup(0, 1, 2)
+	newSet, err := upgrade(s, 3)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+	newSet, err = upgrade(s, 2)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+	recorded := collectEvents(s.recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.UpgradeForced))
+	g.Expect(recorded[0]).To(ContainSubstring("all the tidb members are unhealthy"))
+	// the recreated pod is not waited for
+	recreate(s, 2)
+	newSet, err = upgrade(s, 2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+
+	// partially crashed: the upgrade waits for the upgraded pod to be healthy unless the annotation is set
+	s = newSetup(1, 2)
+	newSet, err = upgrade(s, 3)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+	g.Expect(s.upgrader.(*tidbUpgrader).deps.PodControl.DeletePod(s.tc, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: tidbPodName(upgradeTcName, 2), Namespace: corev1.NamespaceDefault},
+	})).To(Succeed())
+	recreate(s, 2)
+	newSet, err = upgrade(s, 2)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(2)))
+	g.Expect(collectEvents(s.recorder.Events)).NotTo(ContainElement(ContainSubstring(events.UpgradeForced)))
+
+	s.tc.Annotations = map[string]string{label.AnnForceUpgradeKey: label.AnnForceUpgradeVal}
+	newSet, err = upgrade(s, 2)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+	newSet, err = upgrade(s, 1)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+	recorded = collectEvents(s.recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(fmt.Sprintf("annotation %s is set", label.AnnForceUpgradeKey)))
+	_, err = s.podInformer.Lister().Pods(corev1.NamespaceDefault).Get(tidbPodName(upgradeTcName, 1))
+	g.Expect(errors.IsNotFound(err)).To(BeTrue())
+}
+
+func TestTiDBUpgraderDrainConnections(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	upgrader, tidbControl, podInformer := newTiDBUpgrader()
+	recorder := upgrader.(*tidbUpgrader).deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForTiDBUpgrader()
+	tc.Status.PD.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tc.Spec.TiDB.GracefulShutdownTimeoutSeconds = 60
+	g.Expect(tidbReadinessGates(tc)).To(Equal([]corev1.PodReadinessGate{{ConditionType: v1alpha1.TiDBAcceptingConnections}}))
+	pods := getTiDBPods()
+	pods[0].Spec.ReadinessGates = tidbReadinessGates(tc)
+	for _, pod := range pods {
+		g.Expect(podInformer.Informer().GetIndexer().Add(pod)).To(Succeed())
+	}
+	podName := tidbPodName(upgradeTcName, 0)
+
+	upgrade := func() (*apps.StatefulSet, error) {
+		oldSet := newStatefulSetForTiDBUpgrader()
+		newSet := oldSet.DeepCopy()
+		g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+		return newSet, upgrader.Upgrade(tc, oldSet, newSet)
+	}
+	acceptingConnections := func() corev1.ConditionStatus {
+		pod, err := podInformer.Lister().Pods(corev1.NamespaceDefault).Get(podName)
+		g.Expect(err).NotTo(HaveOccurred())
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1alpha1.TiDBAcceptingConnections {
+				return cond.Status
+			}
+		}
+		return corev1.ConditionUnknown
+	}
+
+	// the pod stops accepting new connections and is not upgraded until its connections are drained
+	tidbControl.SetConnectionCount(podName, 5, 0)
+	newSet, err := upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.PodNames).To(Equal([]string{podName}))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Connections).To(Equal(int32(5)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Drained).To(BeFalse())
+	g.Expect(acceptingConnections()).To(Equal(corev1.ConditionFalse))
+
+	// the pod being drained is not ready, but it is still drained rather than upgraded at once
+	pod, err := podInformer.Lister().Pods(corev1.NamespaceDefault).Get(podName)
+	g.Expect(err).NotTo(HaveOccurred())
+	pod = pod.DeepCopy()
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	g.Expect(podInformer.Informer().GetIndexer().Update(pod)).To(Succeed())
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Connections).To(Equal(int32(0)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Drained).To(BeTrue())
+
+	// the drained pod is not checked again until it is upgraded
+	tidbControl.SetConnectionCount(podName)
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+
+	// the pod is upgraded after the timeout even if its connections are not drained
+	tidbControl.SetConnectionCount(podName, 3)
+	tc.Status.TiDB.UpgradeDrain = &v1alpha1.TiDBUpgradeDrainStatus{
+		PodNames: []string{podName},
+		Deadline: metav1.NewTime(time.Now().Add(-time.Second)),
+	}
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(2))
+	g.Expect(recorded[0]).To(ContainSubstring(events.UpgradePodSelected))
+	g.Expect(recorded[1]).To(ContainSubstring(events.TiDBUpgradeDrainTimeout))
+	g.Expect(recorded[1]).To(ContainSubstring("3 connections left, upgrade them anyway"))
+
+	// the unhealthy pod is upgraded without draining
+	tc.Status.TiDB.UpgradeDrain = nil
+	tc.Status.TiDB.Members[podName] = v1alpha1.TiDBMember{Name: podName, Health: false}
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain).To(BeNil())
+
+	// the pods are not drained by default
+	tc.Spec.TiDB.GracefulShutdownTimeoutSeconds = 0
+	g.Expect(tidbReadinessGates(tc)).To(BeNil())
+	tc.Status.TiDB.Members[podName] = v1alpha1.TiDBMember{Name: podName, Health: true}
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain).To(BeNil())
+
+	// the pod is drained to upgradeDrainConnectionThreshold, the threshold of the scale-in is not used
+	tc.Spec.TiDB.GracefulShutdownTimeoutSeconds = 60
+	tc.Spec.TiDB.ScaleInDrain = &v1alpha1.TiDBScaleInDrain{ConnectionThreshold: 10}
+	pod = pod.DeepCopy()
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	g.Expect(podInformer.Informer().GetIndexer().Update(pod)).To(Succeed())
+	tidbControl.SetConnectionCount(podName, 5, 5)
+	_, err = upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Drained).To(BeFalse())
+	tc.Spec.TiDB.UpgradeDrainConnectionThreshold = pointer.Int32Ptr(5)
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	g.Expect(tc.Status.TiDB.UpgradeDrain.Drained).To(BeTrue())
+}
+
+func TestTiDBUpgraderReadinessTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	upgrader, _, podInformer := newTiDBUpgrader()
+	recorder := upgrader.(*tidbUpgrader).deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForTiDBUpgrader()
+	tc.Status.PD.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	pods := getTiDBPods()
+	// the upgraded pod has been not ready for 20 minutes
+	pods[1].Status.Conditions[0].Status = corev1.ConditionFalse
+	pods[1].Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-20 * time.Minute))
+	for _, pod := range pods {
+		g.Expect(podInformer.Informer().GetIndexer().Add(pod)).To(Succeed())
+	}
+
+	upgrade := func() (*apps.StatefulSet, error) {
+		oldSet := newStatefulSetForTiDBUpgrader()
+		newSet := oldSet.DeepCopy()
+		g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)).To(Succeed())
+		return newSet, upgrader.Upgrade(tc, oldSet, newSet)
+	}
+	stalled := func() *v1alpha1.TidbClusterCondition {
+		return utiltidbcluster.GetTidbClusterCondition(tc.Status, v1alpha1.TidbClusterUpgradeStalled)
+	}
+
+	// the upgrade waits for the pod without timeout by default
+	newSet, err := upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+	g.Expect(stalled()).To(BeNil())
+
+	// the pod is not ready within the timeout yet
+	tc.Spec.TiDB.UpgradeReadinessTimeout = &metav1.Duration{Duration: 30 * time.Minute}
+	_, err = upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(stalled()).To(BeNil())
+	g.Expect(collectEvents(recorder.Events)).NotTo(ContainElement(ContainSubstring(events.UpgradeReadinessTimeout)))
+
+	// the upgrade is stalled once the pod times out, the partition does not move
+	tc.Spec.TiDB.UpgradeReadinessTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+	newSet, err = upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring("is not ready within the upgrade readiness timeout 10m0s"))
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(1)))
+	cond := stalled()
+	g.Expect(cond).NotTo(BeNil())
+	g.Expect(cond.Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(cond.Reason).To(Equal(utiltidbcluster.TiDBPodNotReadyTimedOut))
+	g.Expect(cond.Message).To(ContainSubstring(pods[1].Name))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.UpgradeReadinessTimeout))
+
+	// the event is emitted once while the pod stays not ready
+	_, err = upgrade()
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(stalled().LastTransitionTime).To(Equal(cond.LastTransitionTime))
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the condition is removed once the pod gets ready and the upgrade moves on
+	pod := pods[1].DeepCopy()
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	pod.Status.Conditions[0].LastTransitionTime = metav1.Now()
+	g.Expect(podInformer.Informer().GetIndexer().Update(pod)).To(Succeed())
+	newSet, err = upgrade()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(pointer.Int32Ptr(0)))
+	g.Expect(stalled()).To(BeNil())
+}
+
+func TestCleanupTiDBWarmStandbyPods(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type testcase struct {
+		name          string
+		changeFn      func(*v1alpha1.TidbCluster)
+		expectDeleted bool
+	}
+
+	testFn := func(test *testcase) {
+		t.Log(test.name)
+		fakeDeps := controller.NewFakeDependencies()
+		podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
+		pods := append(getTiDBPods(), newTiDBWarmStandbyPodForUpgrader(0, true), newTiDBWarmStandbyPodForUpgrader(1, false))
+		for _, pod := range pods {
+			podInformer.Informer().GetIndexer().Add(pod)
+		}
+		tc := newTidbClusterForTiDBUpgrader()
+		tc.Spec.TiDB.WarmStandbyUpgrade = true
+		tc.Status.TiDB.Phase = v1alpha1.UpgradePhase
+		test.changeFn(tc)
+
+		err := cleanupTiDBWarmStandbyPods(fakeDeps, tc)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		for _, ordinal := range []int32{0, 1} {
+			_, err := podInformer.Lister().Pods(corev1.NamespaceDefault).Get(tidbWarmStandbyPodName(controller.TiDBMemberName(upgradeTcName), ordinal))
+			g.Expect(errors.IsNotFound(err)).To(Equal(test.expectDeleted))
+			_, err = podInformer.Lister().Pods(corev1.NamespaceDefault).Get(tidbPodName(upgradeTcName, ordinal))
+			g.Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	tests := []*testcase{
+		{
+			name:          "warm standby upgrade is in progress",
+			changeFn:      func(tc *v1alpha1.TidbCluster) {},
+			expectDeleted: false,
+		},
+		{
+			name: "warm standby upgrade is turned off during upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiDB.WarmStandbyUpgrade = false
+			},
+			expectDeleted: true,
+		},
+		{
+			name: "upgrade is finished",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiDB.Phase = v1alpha1.NormalPhase
+			},
+			expectDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		testFn(test)
+	}
+}
+
 func newTiDBUpgrader() (Upgrader, *controller.FakeTiDBControl, podinformers.PodInformer) {
 	fakeDeps := controller.NewFakeDependencies()
-	upgrader := &tidbUpgrader{fakeDeps}
+	fakeDeps.LeaderIdentity = "tidb-controller-manager-0"
+	upgrader := NewTiDBUpgrader(fakeDeps)
 	tidbControl := fakeDeps.TiDBControl.(*controller.FakeTiDBControl)
 	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
 	return upgrader, tidbControl, podInformer
@@ -252,6 +1174,9 @@ func newStatefulSetForTiDBUpgrader() *apps.StatefulSet {
 		Spec: apps.StatefulSetSpec{
 			Replicas: pointer.Int32Ptr(2),
 			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: label.New().Instance(upgradeInstanceName).TiDB().Labels(),
+				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
@@ -374,3 +1299,32 @@ func getTiDBPods() []*corev1.Pod {
 	}
 	return pods
 }
+
+func newTiDBWarmStandbyPodForUpgrader(ordinal int32, ready bool) *corev1.Pod {
+	l := label.New().Instance(upgradeInstanceName).TiDB().Labels()
+	l[label.TiDBWarmStandbyLabelKey] = fmt.Sprint(ordinal)
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tidbWarmStandbyPodName(controller.TiDBMemberName(upgradeTcName), ordinal),
+			Namespace: corev1.NamespaceDefault,
+			Labels:    l,
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{
+					Type:   corev1.PodReady,
+					Status: status,
+				},
+			},
+		},
+	}
+}
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
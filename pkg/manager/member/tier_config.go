@@ -0,0 +1,226 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/apis/util/config"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+// withTierConfig returns the cluster whose config of the component is merged over the config fragment of the tier
+// of the cluster in the ConfigMap set by -tier-config-configmap, the config of the cluster wins. The entries merged
+// from the fragment are returned by the keys, they are nil if -tier-config-configmap is not set. The cluster is
+// returned as is if the tier of the cluster or the fragment is not set, or the config of the component is nil.
+// The returned cluster shares the other fields with tc, it is only used to render the config.
+func withTierConfig(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) (*v1alpha1.TidbCluster, map[string]string, error) {
+	var userConfig *config.GenericConfig
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		if tc.Spec.PD.Config != nil {
+			userConfig = tc.Spec.PD.Config.GenericConfig
+		}
+	case v1alpha1.TiKVMemberType:
+		if tc.Spec.TiKV.Config != nil {
+			userConfig = tc.Spec.TiKV.Config.GenericConfig
+		}
+	case v1alpha1.TiDBMemberType:
+		if tc.Spec.TiDB.Config != nil {
+			userConfig = tc.Spec.TiDB.Config.GenericConfig
+		}
+	default:
+		return nil, nil, fmt.Errorf("tier config is not supported by %s", memberType)
+	}
+	if userConfig == nil || deps.TierConfigMapLister == nil {
+		return tc, nil, nil
+	}
+	entries := map[string]string{}
+	fragment, err := tierConfigFragment(deps, tc, memberType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fragment == nil {
+		return tc, entries, nil
+	}
+
+	merged := mergeConfig(fragment, userConfig)
+	flattenConfig("", fragment.Inner(), entries)
+	for key := range entries {
+		if userConfig.Get(key) != nil {
+			delete(entries, key)
+		}
+	}
+
+	out := *tc
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		spec := *tc.Spec.PD
+		spec.Config = &v1alpha1.PDConfigWraper{GenericConfig: merged}
+		out.Spec.PD = &spec
+	case v1alpha1.TiKVMemberType:
+		spec := *tc.Spec.TiKV
+		spec.Config = &v1alpha1.TiKVConfigWraper{GenericConfig: merged}
+		out.Spec.TiKV = &spec
+	case v1alpha1.TiDBMemberType:
+		spec := *tc.Spec.TiDB
+		spec.Config = &v1alpha1.TiDBConfigWraper{GenericConfig: merged}
+		out.Spec.TiDB = &spec
+	}
+	return &out, entries, nil
+}
+
+// tierConfigFragment returns the config fragment of the component of the tier of the cluster, it is nil if the
+// tier, the ConfigMap or the fragment is not set
+func tierConfigFragment(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) (*config.GenericConfig, error) {
+	if tc.Spec.Tier == "" {
+		return nil, nil
+	}
+	ns, name, err := cache.SplitMetaNamespaceKey(deps.CLIConfig.TierConfigMap)
+	if err != nil {
+		return nil, err
+	}
+	cm, err := deps.TierConfigMapLister.ConfigMaps(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tier configmap %s/%s, error: %v", ns, name, err)
+	}
+	key := fmt.Sprintf("%s.%s", tc.Spec.Tier, memberType)
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, nil
+	}
+	fragment := config.New(map[string]interface{}{})
+	if err := fragment.UnmarshalTOML([]byte(data)); err != nil {
+		return nil, fmt.Errorf("invalid config fragment %s in tier configmap %s/%s, error: %v", key, ns, name, err)
+	}
+	return fragment, nil
+}
+
+// mergeConfig returns the config merging the overlay over the base, the tables are merged recursively and the
+// other values in the overlay win
+func mergeConfig(base, overlay *config.GenericConfig) *config.GenericConfig {
+	merged := base.DeepCopy()
+	if merged.MP == nil {
+		merged.MP = map[string]interface{}{}
+	}
+	mergeConfigTable(merged.MP, overlay.DeepCopy().Inner())
+	return merged
+}
+
+func mergeConfigTable(base, overlay map[string]interface{}) {
+	for k, v := range overlay {
+		table, ok := v.(map[string]interface{})
+		baseTable, baseOK := base[k].(map[string]interface{})
+		if ok && baseOK {
+			mergeConfigTable(baseTable, table)
+			continue
+		}
+		base[k] = v
+	}
+}
+
+// setTierConfigAnnotation records the config entries merged from the tier in the ConfigMap of the component, the
+// entries are always recorded if -tier-config-configmap is set so that the removed entries are recorded too
+func setTierConfigAnnotation(cm *corev1.ConfigMap, entries map[string]string) error {
+	if cm == nil || entries == nil {
+		return nil
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[label.AnnTierConfig] = string(data)
+	return nil
+}
+
+// recordTierConfigChange emits an event with the diff of the config entries merged from the tier between the
+// ConfigMap in use and the new one, the new config is rolled out by the config update strategy of the component
+func recordTierConfigChange(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	inUseName string, cm *corev1.ConfigMap) {
+	if cm == nil || inUseName == "" || deps.TierConfigMapLister == nil {
+		return
+	}
+	if cm.Name != inUseName {
+		// the change is recorded when the new ConfigMap is created for the rolling update
+		if _, err := deps.ConfigMapLister.ConfigMaps(tc.GetNamespace()).Get(cm.Name); err == nil {
+			return
+		}
+	}
+	inUse, err := deps.ConfigMapLister.ConfigMaps(tc.GetNamespace()).Get(inUseName)
+	if err != nil {
+		return
+	}
+	old := map[string]string{}
+	if data, ok := inUse.Annotations[label.AnnTierConfig]; ok {
+		if err := json.Unmarshal([]byte(data), &old); err != nil {
+			return
+		}
+	}
+	cur := map[string]string{}
+	if data, ok := cm.Annotations[label.AnnTierConfig]; ok {
+		if err := json.Unmarshal([]byte(data), &cur); err != nil {
+			return
+		}
+	}
+	diff := diffTierConfig(old, cur)
+	if len(diff) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%s config of tier %q is changed and rolled out by the config update strategy: %s",
+		memberType, tc.Spec.Tier, strings.Join(diff, ", "))
+	recordUpgradeEvent(deps.Recorder, tc, corev1.EventTypeNormal, events.TierConfigChanged, msg)
+}
+
+// diffTierConfig returns the changes of the config entries in the form of <key>: <old> -> <new> sorted by the keys
+func diffTierConfig(old, cur map[string]string) []string {
+	keys := map[string]struct{}{}
+	for k := range old {
+		keys[k] = struct{}{}
+	}
+	for k := range cur {
+		keys[k] = struct{}{}
+	}
+	var diff []string
+	for k := range keys {
+		o, oldOK := old[k]
+		c, curOK := cur[k]
+		if oldOK == curOK && o == c {
+			continue
+		}
+		if !oldOK {
+			o = "<unset>"
+		}
+		if !curOK {
+			c = "<unset>"
+		}
+		diff = append(diff, fmt.Sprintf("%s: %s -> %s", k, o, c))
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func newTierConfigDependencies(g *GomegaWithT, data map[string]string) *controller.Dependencies {
+	deps := controller.NewFakeDependencies()
+	deps.CLIConfig.TierConfigMap = "ops/tiers"
+	deps.TierConfigMapLister = deps.KubeInformerFactory.Core().V1().ConfigMaps().Lister()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "tiers"},
+		Data:       data,
+	}
+	g.Expect(deps.KubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(cm)).To(Succeed())
+	return deps
+}
+
+func TestWithTierConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := newTierConfigDependencies(g, map[string]string{
+		"gold.tidb": `
+mem-quota-query = 4294967296
+[performance]
+txn-total-size-limit = 10737418240
+max-procs = 8
+`,
+		"gold.tikv": `
+[raftstore]
+inspect-interval = "100ms"
+`,
+		"gold.pd": `
+[schedule]
+leader-schedule-limit = 8
+`,
+	})
+
+	tc := newTidbClusterForTiDB()
+	tc.Spec.Tier = "gold"
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	tc.Spec.TiDB.Config.Set("performance.max-procs", 16)
+	tc.Spec.TiDB.Config.Set("log.level", "warn")
+	tc.Spec.TiKV.Config = v1alpha1.NewTiKVConfig()
+	tc.Spec.PD.Config = v1alpha1.NewPDConfig()
+	tc.Spec.PD.Config.Set("schedule.leader-schedule-limit", 4)
+
+	// the user config wins
+	tierTC, entries, err := withTierConfig(deps, tc, v1alpha1.TiDBMemberType)
+	g.Expect(err).To(Succeed())
+	config := tierTC.Spec.TiDB.Config
+	g.Expect(config.Get("mem-quota-query").MustInt()).To(Equal(int64(4294967296)))
+	g.Expect(config.Get("performance.txn-total-size-limit").MustInt()).To(Equal(int64(10737418240)))
+	g.Expect(config.Get("performance.max-procs").MustInt()).To(Equal(int64(16)))
+	g.Expect(config.Get("log.level").MustString()).To(Equal("warn"))
+	g.Expect(entries).To(Equal(map[string]string{
+		"mem-quota-query":                  "4294967296",
+		"performance.txn-total-size-limit": "10737418240",
+	}))
+	// the cluster is not modified
+	g.Expect(tc.Spec.TiDB.Config.Get("mem-quota-query")).To(BeNil())
+
+	tierTC, entries, err = withTierConfig(deps, tc, v1alpha1.TiKVMemberType)
+	g.Expect(err).To(Succeed())
+	g.Expect(tierTC.Spec.TiKV.Config.Get("raftstore.inspect-interval").MustString()).To(Equal("100ms"))
+	g.Expect(entries).To(HaveLen(1))
+
+	tierTC, entries, err = withTierConfig(deps, tc, v1alpha1.PDMemberType)
+	g.Expect(err).To(Succeed())
+	g.Expect(tierTC.Spec.PD.Config.Get("schedule.leader-schedule-limit").MustInt()).To(Equal(int64(4)))
+	g.Expect(entries).To(BeEmpty())
+
+	// no fragment of the tier
+	tc.Spec.Tier = "bronze"
+	tierTC, entries, err = withTierConfig(deps, tc, v1alpha1.TiDBMemberType)
+	g.Expect(err).To(Succeed())
+	g.Expect(tierTC).To(BeIdenticalTo(tc))
+	g.Expect(entries).To(BeEmpty())
+
+	// tier config is disabled
+	tc.Spec.Tier = "gold"
+	deps.TierConfigMapLister = nil
+	tierTC, entries, err = withTierConfig(deps, tc, v1alpha1.TiDBMemberType)
+	g.Expect(err).To(Succeed())
+	g.Expect(tierTC).To(BeIdenticalTo(tc))
+	g.Expect(entries).To(BeNil())
+}
+
+func TestWithTierConfigInvalidFragment(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := newTierConfigDependencies(g, map[string]string{"gold.tidb": "[performance"})
+	tc := newTidbClusterForTiDB()
+	tc.Spec.Tier = "gold"
+	tc.Spec.TiDB.Config = v1alpha1.NewTiDBConfig()
+	_, _, err := withTierConfig(deps, tc, v1alpha1.TiDBMemberType)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("invalid config fragment gold.tidb"))
+}
+
+func TestRecordTierConfigChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+	deps := newTierConfigDependencies(g, nil)
+	tc := newTidbClusterForTiDB()
+	tc.Spec.Tier = "silver"
+
+	inUse := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   tc.Namespace,
+		Name:        "test-tidb-aaaa",
+		Annotations: map[string]string{label.AnnTierConfig: `{"mem-quota-query":"4294967296","performance.max-procs":"8"}`},
+	}}
+	g.Expect(deps.LabelFilterKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(inUse)).To(Succeed())
+
+	newCm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: tc.Namespace, Name: "test-tidb-bbbb"}}
+	g.Expect(setTierConfigAnnotation(newCm, map[string]string{
+		"mem-quota-query":                  "2147483648",
+		"performance.txn-total-size-limit": "1073741824",
+	})).To(Succeed())
+	recordTierConfigChange(deps, tc, v1alpha1.TiDBMemberType, inUse.Name, newCm)
+	recorded := collectEvents(deps.Recorder.(*record.FakeRecorder).Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.TierConfigChanged))
+	g.Expect(recorded[0]).To(ContainSubstring("mem-quota-query: 4294967296 -> 2147483648, performance.max-procs: 8 -> <unset>, performance.txn-total-size-limit: <unset> -> 1073741824"))
+
+	// no change
+	newCm.Annotations = inUse.Annotations
+	recordTierConfigChange(deps, tc, v1alpha1.TiDBMemberType, inUse.Name, newCm)
+	g.Expect(collectEvents(deps.Recorder.(*record.FakeRecorder).Events)).To(BeEmpty())
+}
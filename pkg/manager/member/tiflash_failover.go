@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -89,7 +90,7 @@ func (f *tiflashFailover) Failover(tc *v1alpha1.TidbCluster) error {
 						CreatedAt: metav1.Now(),
 					}
 					msg := fmt.Sprintf("store [%s] is Down", store.ID)
-					f.deps.Recorder.Event(tc, corev1.EventTypeWarning, unHealthEventReason, fmt.Sprintf(unHealthEventMsgPattern, "tiflash", podName, msg))
+					f.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.Unhealthy, fmt.Sprintf(unHealthEventMsgPattern, "tiflash", podName, msg))
 				}
 			}
 		}
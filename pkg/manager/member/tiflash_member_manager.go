@@ -104,7 +104,7 @@ func (m *tiflashMemberManager) enablePlacementRules(tc *v1alpha1.TidbCluster) er
 }
 
 func (m *tiflashMemberManager) syncHeadlessService(tc *v1alpha1.TidbCluster) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.TiFlashMemberType) {
 		klog.V(4).Infof("tiflash cluster %s/%s is paused, skip syncing for tiflash service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -161,11 +161,17 @@ func (m *tiflashMemberManager) syncStatefulSet(tc *v1alpha1.TidbCluster) error {
 		return err
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.TiFlashMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.TiFlashMemberType) {
 		klog.V(4).Infof("tiflash cluster %s/%s is paused, skip syncing for tiflash statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	if err := syncExternalProvisioningGates(m.deps, tc, v1alpha1.TiFlashMemberType); err != nil {
+		return err
+	}
+
 	cm, err := m.syncConfigMap(tc, oldSet)
 	if err != nil {
 		return err
@@ -223,13 +229,25 @@ func (m *tiflashMemberManager) syncStatefulSet(tc *v1alpha1.TidbCluster) error {
 		}
 	}
 
+	if err := cleanupImagePrepullPods(m.deps, tc, v1alpha1.TiFlashMemberType); err != nil {
+		return err
+	}
+
+	if _, err := keepTemplateIfDowngrade(m.deps, tc, v1alpha1.TiFlashMemberType, oldSet, newSet); err != nil {
+		return err
+	}
+
+	if err := prePullImage(m.deps, tc, v1alpha1.TiFlashMemberType, oldSet, newSet); err != nil {
+		return err
+	}
+
 	if !templateEqual(newSet, oldSet) || tc.Status.TiFlash.Phase == v1alpha1.UpgradePhase {
 		if err := m.upgrader.Upgrade(tc, oldSet, newSet); err != nil {
 			return err
 		}
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdateTiFlashSTS", newSet, oldSet)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newSet, oldSet)
 }
 
 func (m *tiflashMemberManager) syncConfigMap(tc *v1alpha1.TidbCluster, set *apps.StatefulSet) (*corev1.ConfigMap, error) {
@@ -249,6 +267,9 @@ func (m *tiflashMemberManager) syncConfigMap(tc *v1alpha1.TidbCluster, set *apps
 	if err != nil {
 		return nil, err
 	}
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.TiFlashMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -306,6 +327,10 @@ func getNewStatefulSet(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (*apps.St
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 	baseTiFlashSpec := tc.BaseTiFlashSpec()
+	resources, err := v1alpha1.SizedResources(baseTiFlashSpec.Sizing(), tc.Spec.TiFlash.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for tiflash, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
 	spec := tc.Spec.TiFlash
 
 	tiflashConfigMap := controller.MemberConfigMapName(tc, v1alpha1.TiFlashMemberType)
@@ -379,12 +404,13 @@ func getNewStatefulSet(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap) (*apps.St
 					SecurityContext: &corev1.SecurityContext{
 						Privileged: &privileged,
 					},
+					EnvFrom: baseTiFlashSpec.EnvFrom(),
 					// Init container resourceRequirements should be equal to app container.
 					// Scheduling is done based on effective requests/limits,
 					// which means init containers can reserve resources for
 					// initialization that are not used during the life of the Pod.
 					// ref:https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#resources
-					Resources: controller.ContainerResource(tc.Spec.TiFlash.ResourceRequirements),
+					Resources: controller.ContainerResource(resources),
 				})
 			}
 		}
@@ -447,6 +473,7 @@ sed -i s/PD_ADDR/${result}/g /data0/proxy.toml
 			script,
 		},
 		Env:          initEnv,
+		EnvFrom:      baseTiFlashSpec.EnvFrom(),
 		VolumeMounts: initVolMounts,
 	}
 	if spec.Initializer != nil {
@@ -459,8 +486,10 @@ sed -i s/PD_ADDR/${result}/g /data0/proxy.toml
 	podLabels := util.CombineStringMap(stsLabels, baseTiFlashSpec.Labels())
 	podAnnotations := util.CombineStringMap(controller.AnnProm(8234), baseTiFlashSpec.Annotations())
 	podAnnotations = util.CombineStringMap(controller.AnnAdditionalProm("tiflash.proxy", 20292), podAnnotations)
+	podAnnotations = util.CombineStringMap(podAnnotations, externalProvisioningAnnotations(baseTiFlashSpec))
+	podAnnotations = util.CombineStringMap(podAnnotations, restartGenerationAnnotations(tc, v1alpha1.TiFlashMemberType))
 	stsAnnotations := getStsAnnotations(tc.Annotations, label.TiFlashLabelVal)
-	capacity := controller.TiKVCapacity(tc.Spec.TiFlash.Limits)
+	capacity := controller.TiKVCapacity(resources.Limits)
 	headlessSvcName := controller.TiFlashPeerMemberName(tcName)
 
 	deleteSlotsNumber, err := util.GetDeleteSlotsNumber(stsAnnotations)
@@ -535,7 +564,7 @@ sed -i s/PD_ADDR/${result}/g /data0/proxy.toml
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.TiFlash.ResourceRequirements),
+		Resources:    controller.ContainerResource(resources),
 	}
 	podSpec := baseTiFlashSpec.BuildPodSpec()
 	if baseTiFlashSpec.HostNetwork() {
@@ -667,6 +696,7 @@ func (m *tiflashMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, s
 	if err != nil {
 		return err
 	}
+	oldPhase := tc.Status.TiFlash.Phase
 	if tc.TiFlashStsDesiredReplicas() != *set.Spec.Replicas {
 		tc.Status.TiFlash.Phase = v1alpha1.ScalePhase
 	} else if upgrading {
@@ -674,6 +704,9 @@ func (m *tiflashMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, s
 	} else {
 		tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.TiFlash.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.TiFlashMemberType, oldPhase, tc.Status.TiFlash.Phase, tc.Status.TiFlash.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.TiFlashMemberType, oldPhase, tc.Status.TiFlash.Phase, tc.Status.TiFlash.StatefulSet)
+	syncUpgradeStatus(m.deps, tc, v1alpha1.TiFlashMemberType, set, nil)
 
 	previousStores := tc.Status.TiFlash.Stores
 	previousPeerStores := tc.Status.TiFlash.PeerStores
@@ -682,8 +715,10 @@ func (m *tiflashMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, s
 	tombstoneStores := map[string]v1alpha1.TiKVStore{}
 
 	pdCli := controller.GetPDClient(m.deps.PDControl, tc)
+	var staleSince *metav1.Time
 	// This only returns Up/Down/Offline stores
 	storesInfo, err := pdCli.GetStores()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.TiFlash.Synced = false
 		klog.Warningf("Fail to GetStores for TidbCluster %s/%s: %s", tc.Namespace, tc.Name, err)
@@ -719,8 +754,14 @@ func (m *tiflashMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, s
 		}
 	}
 
+	// the storage usage is not collected from the cached PD data
+	if staleSince == nil {
+		tc.Status.TiFlash.StorageUsage = SyncStorageUsage(m.deps.Recorder, tc, v1alpha1.TiFlashMemberType.String(), tc.Status.TiFlash.StorageUsage, storeStorageUsages(storesInfo, stores), tc.StorageWarningThreshold())
+	}
+
 	// this returns all tombstone stores
 	tombstoneStoresInfo, err := pdCli.GetTombStoneStores()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.TiFlash.Synced = false
 		klog.Warningf("Fail to GetTombStoneStores for TidbCluster %s/%s", tc.Namespace, tc.Name)
@@ -738,6 +779,7 @@ func (m *tiflashMemberManager) syncTidbClusterStatus(tc *v1alpha1.TidbCluster, s
 	}
 
 	tc.Status.TiFlash.Synced = true
+	tc.Status.TiFlash.StaleSince = staleSince
 	tc.Status.TiFlash.Stores = stores
 	tc.Status.TiFlash.PeerStores = peerStores
 	tc.Status.TiFlash.TombstoneStores = tombstoneStores
@@ -1707,6 +1707,48 @@ func TestGetNewTiFlashSetForTidbCluster(t *testing.T) {
 				}))
 			},
 		},
+		{
+			name: "tiflash spec envFrom",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					TiDB: &v1alpha1.TiDBSpec{},
+					TiFlash: &v1alpha1.TiFlashSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							EnvFrom: []corev1.EnvFromSource{
+								{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+							},
+						},
+						StorageClaims: []v1alpha1.StorageClaim{
+							{
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{
+										corev1.ResourceStorage: resource.MustParse("100Gi"),
+									},
+								},
+							},
+						},
+					},
+					PD:   &v1alpha1.PDSpec{},
+					TiKV: &v1alpha1.TiKVSpec{},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				envFrom := []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+				}
+				// envFrom is rendered in the init container, the log tailers and tiflash container
+				g.Expect(MapInitContainers(&sts.Spec.Template.Spec)["init"].EnvFrom).To(Equal(envFrom))
+				g.Expect(sts.Spec.Template.Spec.Containers).To(HaveLen(4))
+				for _, c := range sts.Spec.Template.Spec.Containers {
+					g.Expect(c.EnvFrom).To(Equal(envFrom), c.Name)
+				}
+			},
+		},
 		// TODO add more tests
 	}
 
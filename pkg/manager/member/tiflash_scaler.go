@@ -96,7 +96,7 @@ func (s *tiflashScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, ne
 			if state != v1alpha1.TiKVStateOffline {
 				if err := controller.GetPDClient(s.deps.PDControl, tc).DeleteStore(id); err != nil {
 					klog.Errorf("tiflash scale in: failed to delete store %d, %v", id, err)
-					return err
+					return requeueIfPDCircuitOpen(err)
 				}
 				klog.Infof("tiflash scale in: delete store %d for tiflash %s/%s successfully", id, ns, podName)
 			}
@@ -117,7 +117,7 @@ func (s *tiflashScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, ne
 			if err != nil {
 				return err
 			}
-			setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+			s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 			return nil
 		}
 	}
@@ -147,7 +147,7 @@ func (s *tiflashScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, ne
 		if err != nil {
 			return err
 		}
-		setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+		s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 		return nil
 	}
 	return fmt.Errorf("tiflash %s/%s no store found in cluster", ns, podName)
@@ -24,6 +24,7 @@ import (
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 )
@@ -35,13 +36,15 @@ var (
 )
 
 type tiflashUpgrader struct {
-	deps *controller.Dependencies
+	deps     *controller.Dependencies
+	recorder record.EventRecorder
 }
 
 // NewTiFlashUpgrader returns a tiflash Upgrader
 func NewTiFlashUpgrader(deps *controller.Dependencies) Upgrader {
 	return &tiflashUpgrader{
-		deps: deps,
+		deps:     deps,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -49,11 +52,14 @@ func (u *tiflashUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Statefu
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 
-	if tc.Status.PD.Phase == v1alpha1.UpgradePhase ||
-		tc.TiFlashScaling() {
-		klog.Infof("TidbCluster: [%s/%s]'s pd status is %s, tiflash status is %s, can not upgrade tiflash",
-			ns, tcName,
-			tc.Status.PD.Phase, tc.Status.TiFlash.Phase)
+	if frozen, err := keepTemplateIfUpgradeFrozen(u.deps, tc, v1alpha1.TiFlashMemberType, oldSet, newSet); frozen {
+		return err
+	}
+
+	if blocking := upgradeBlockedBy(tc, v1alpha1.TiFlashMemberType); blocking != "" || tc.TiFlashScaling() {
+		klog.Infof("TidbCluster: [%s/%s]'s components upgraded before tiflash [%s] are upgrading, "+
+			"tiflash status is %s, can not upgrade tiflash",
+			ns, tcName, blocking, tc.Status.TiFlash.Phase)
 		_, podSpec, err := GetLastAppliedConfig(oldSet)
 		if err != nil {
 			return err
@@ -66,6 +72,7 @@ func (u *tiflashUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Statefu
 		return fmt.Errorf("cluster: [%s/%s]'s TiFlash status is not synced, can not upgrade", ns, tcName)
 	}
 
+	recordLastReconcileBy(u.deps, tc, tc.Status.TiFlash.Phase != v1alpha1.UpgradePhase)
 	tc.Status.TiFlash.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -91,6 +98,10 @@ func (u *tiflashUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Statefu
 		i := podOrdinals[_i]
 		store := getTiFlashStoreByOrdinal(tc.GetName(), tc.Status.TiFlash, i)
 		if store == nil {
+			if upgradePaused(u.recorder, tc, v1alpha1.TiFlashMemberType, i) {
+				return nil
+			}
+			syncUpgradeStatus(u.deps, tc, v1alpha1.TiFlashMemberType, oldSet, &i)
 			mngerutils.SetUpgradePartition(newSet, i)
 			continue
 		}
@@ -126,7 +137,13 @@ func (u *tiflashUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.Statefu
 			continue
 		}
 
+		if upgradePaused(u.recorder, tc, v1alpha1.TiFlashMemberType, i) {
+			return nil
+		}
+		syncUpgradeStatus(u.deps, tc, v1alpha1.TiFlashMemberType, oldSet, &i)
+		recordLastReconcileBy(u.deps, tc, i < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition)
 		mngerutils.SetUpgradePartition(newSet, i)
+		prepullNextImage(u.deps, tc, v1alpha1.TiFlashMemberType, newSet, i)
 		return nil
 	}
 
@@ -15,14 +15,11 @@ package member
 
 import (
 	"fmt"
-	"strconv"
 	"testing"
 
 	. "github.com/onsi/gomega"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	podinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/utils/pointer"
 
@@ -42,6 +39,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		changeFn     func(*v1alpha1.TidbCluster, *tiflashapi.FakeTiFlashControl)
 		changeOldSet func(set *apps.StatefulSet)
 		changePods   func(pods []*corev1.Pod, tc *v1alpha1.TidbCluster, old, new *apps.StatefulSet)
+		upgraded     []int32
 		updatePodErr bool
 		errExpectFn  func(*GomegaWithT, error)
 		expectFn     func(*GomegaWithT, *v1alpha1.TidbCluster, *apps.StatefulSet, map[string]*corev1.Pod)
@@ -51,18 +49,23 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		t.Log(test.name)
 		upgrader, _, tiflashControl, podControl, podInformer := newTiFlashUpgrader()
 
-		tc := newTidbClusterForTiFlashUpgrader()
+		b := NewTCBuilder().
+			WithReplicas(v1alpha1.TiFlashMemberType, 3).
+			WithTiFlashPhase(v1alpha1.UpgradePhase).
+			WithUpgradedPods(v1alpha1.TiFlashMemberType, test.upgraded...)
+
+		tc := b.Build()
 		if test.changeFn != nil {
 			test.changeFn(tc, tiflashControl)
 		}
 
-		oldSet := oldStatefulSetForTiFlashUpgrader()
+		oldSet := b.StatefulSet(v1alpha1.TiFlashMemberType)
 		if test.changeOldSet != nil {
 			test.changeOldSet(oldSet)
 		}
-		newSet := newStatefulSetForTiFlashUpgrader()
+		newSet := b.StatefulSet(v1alpha1.TiFlashMemberType)
 
-		tiflashPods := getTiFlashPods(oldSet)
+		tiflashPods := b.Pods(v1alpha1.TiFlashMemberType)
 		if test.changePods != nil {
 			test.changePods(tiflashPods, tc, oldSet, newSet)
 		}
@@ -92,7 +95,6 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 			name:     "modify oldSet update strategy to OnDelete",
 			changeFn: nil,
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
 					Type: apps.OnDeleteStatefulSetStrategyType,
 				}
@@ -111,7 +113,6 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 			name:     "set oldSet's RollingUpdate strategy to nil",
 			changeFn: nil,
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
 					Type: apps.RollingUpdateStatefulSetStrategyType,
 				}
@@ -129,21 +130,16 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "to upgrade the pod which ordinal is 2",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				// set leader to 0
-				store := tc.Status.TiFlash.Stores["3"]
+				store := tc.Status.TiFlash.Stores[NewTCBuilder().TiFlashStoreID(2)]
 				store.LeaderCount = 0
-				tc.Status.TiFlash.Stores["3"] = store
+				tc.Status.TiFlash.Stores[NewTCBuilder().TiFlashStoreID(2)] = store
 				fakeClient := NewFakeTiKVClient(tiflashControl, tc, "upgrader-tiflash-2")
 				fakeClient.AddReaction(tiflashapi.GetStoreStatusActionType, func(action *tiflashapi.Action) (interface{}, error) {
 					return tiflashapi.Running, nil
 				})
 			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-			},
 			changePods:   nil,
 			updatePodErr: false,
 			errExpectFn: func(g *GomegaWithT, err error) {
@@ -157,15 +153,10 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "to upgrade the pod which ordinal is 1",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiFlash.Synced = true
-				tc.Status.TiFlash.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiFlash.StatefulSet.UpdatedReplicas = 1
 				// set leader to 0
-				store := tc.Status.TiFlash.Stores["2"]
+				store := tc.Status.TiFlash.Stores[NewTCBuilder().TiFlashStoreID(1)]
 				store.LeaderCount = 0
-				tc.Status.TiFlash.Stores["2"] = store
+				tc.Status.TiFlash.Stores[NewTCBuilder().TiFlashStoreID(1)] = store
 				fakeClient := NewFakeTiKVClient(tiflashControl, tc, "upgrader-tiflash-2")
 				fakeClient.AddReaction(tiflashapi.GetStoreStatusActionType, func(action *tiflashapi.Action) (interface{}, error) {
 					return tiflashapi.Running, nil
@@ -175,10 +166,8 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 					return tiflashapi.Running, nil
 				})
 			},
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods:   nil,
@@ -193,9 +182,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "newSet template changed",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
 				oldSet.Spec.Template.Spec.Containers[0].Image = "old-image"
@@ -214,9 +201,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "update revision equals current revision",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				tc.Status.TiFlash.StatefulSet.UpdateRevision = tc.Status.TiFlash.StatefulSet.CurrentRevision
 			},
 			changePods:   nil,
@@ -234,10 +219,23 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
 				tc.Status.PD.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+			changePods:   nil,
+			updatePodErr: false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(tc.Status.TiFlash.Phase).To(Equal(v1alpha1.NormalPhase))
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+			},
+		},
+		{
+			name: "tiflash can not upgrade when tikv ordered before it is upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
+				tc.Spec.UpgradeOrder = []v1alpha1.MemberType{v1alpha1.PDMemberType, v1alpha1.TiKVMemberType, v1alpha1.TiFlashMemberType}
+				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
 			},
 			changePods:   nil,
 			updatePodErr: false,
@@ -249,12 +247,27 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
 			},
 		},
+		{
+			name: "tiflash ordered before pd upgrades when pd is upgrading",
+			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
+				tc.Spec.UpgradeOrder = []v1alpha1.MemberType{v1alpha1.TiFlashMemberType, v1alpha1.PDMemberType, v1alpha1.TiKVMemberType}
+				tc.Status.PD.Phase = v1alpha1.UpgradePhase
+				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
+			},
+			changePods:   nil,
+			updatePodErr: false,
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(tc.Status.TiFlash.Phase).To(Equal(v1alpha1.UpgradePhase))
+			},
+		},
 		{
 			name: "get last apply config error",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
 				tc.Status.PD.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
 				oldSet.SetAnnotations(map[string]string{LastAppliedConfigAnnotation: "fake apply config"})
@@ -272,9 +285,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version less than v5.1.2",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v4.0.0"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -303,9 +314,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version is invalid",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v4.0.0-dev12"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -334,9 +343,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version greater than v5.1.2 and tiflash is running",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v5.1.2"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -365,9 +372,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version nightly and tiflash is running",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "nightly"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -396,9 +401,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version latest and tiflash is running",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "latest"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -427,9 +430,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version greater than v5.1.2 and tiflash isn't running",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v5.1.2"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -459,9 +460,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version greater than v5.1.2, version is dirty-release and tiflash isn't running",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v5.2.0-20200909"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -491,9 +490,7 @@ func TestTiFlashUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tiflash version greater than v5.1.2 and get store status failed",
 			changeFn: func(tc *v1alpha1.TidbCluster, tiflashControl *tiflashapi.FakeTiFlashControl) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiFlash.Phase = v1alpha1.NormalPhase
-				tc.Status.TiFlash.Synced = true
 				version := "v5.1.2"
 				tc.Spec.TiFlash.BaseImage = "base-image"
 				tc.Spec.TiFlash.Version = &version
@@ -533,141 +530,7 @@ func newTiFlashUpgrader() (Upgrader, *pdapi.FakePDControl, *tiflashapi.FakeTiFla
 	tiflashControl := fakeDeps.TiFlashControl.(*tiflashapi.FakeTiFlashControl)
 	podControl := fakeDeps.PodControl.(*controller.FakePodControl)
 	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
-	return &tiflashUpgrader{deps: fakeDeps}, pdControl, tiflashControl, podControl, podInformer
-}
-
-func newStatefulSetForTiFlashUpgrader() *apps.StatefulSet {
-	return &apps.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "upgrader-tiflash",
-			Namespace: metav1.NamespaceDefault,
-			Labels:    label.New().Instance(upgradeInstanceName).TiFlash().Labels(),
-		},
-		Spec: apps.StatefulSetSpec{
-			Replicas: pointer.Int32Ptr(3),
-			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "tiflash",
-							Image: "tiflash-test-image",
-						},
-					},
-				},
-			},
-			UpdateStrategy: apps.StatefulSetUpdateStrategy{
-				Type: apps.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{
-					Partition: pointer.Int32Ptr(3),
-				}},
-		},
-	}
-}
-
-func oldStatefulSetForTiFlashUpgrader() *apps.StatefulSet {
-	set := newStatefulSetForTiFlashUpgrader()
-	set.Status = apps.StatefulSetStatus{
-		Replicas:        3,
-		CurrentReplicas: 3,
-		UpdatedReplicas: 0,
-		CurrentRevision: "1",
-		UpdateRevision:  "2",
-	}
-	return set
-}
-
-func newTidbClusterForTiFlashUpgrader() *v1alpha1.TidbCluster {
-	return &v1alpha1.TidbCluster{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "TidbCluster",
-			APIVersion: "pingcap.com/v1alpha1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      upgradeTcName,
-			Namespace: corev1.NamespaceDefault,
-			UID:       types.UID(upgradeTcName),
-			Labels:    label.New().Instance(upgradeInstanceName).TiFlash().Labels(),
-		},
-		Spec: v1alpha1.TidbClusterSpec{
-			PD: &v1alpha1.PDSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "pd-test-image",
-				},
-				Replicas:         3,
-				StorageClassName: pointer.StringPtr("my-storage-class"),
-			},
-			TiFlash: &v1alpha1.TiFlashSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "tiflash-test-image",
-				},
-				Replicas: 3,
-			},
-		},
-		Status: v1alpha1.TidbClusterStatus{
-			TiFlash: v1alpha1.TiFlashStatus{
-				Synced: true,
-				Phase:  v1alpha1.UpgradePhase,
-				StatefulSet: &apps.StatefulSetStatus{
-					Replicas:        3,
-					CurrentReplicas: 3,
-					UpdatedReplicas: 0,
-					CurrentRevision: "1",
-					UpdateRevision:  "2",
-				},
-				Stores: map[string]v1alpha1.TiKVStore{
-					"1": {
-						ID:          "1",
-						PodName:     TiFlashPodName(upgradeTcName, 0),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-					"2": {
-						ID:          "2",
-						PodName:     TiFlashPodName(upgradeTcName, 1),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-					"3": {
-						ID:          "3",
-						PodName:     TiFlashPodName(upgradeTcName, 2),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-				},
-			},
-		},
-	}
-}
-
-func getTiFlashPods(set *apps.StatefulSet) []*corev1.Pod {
-	var pods []*corev1.Pod
-	for i := 0; i < int(set.Status.Replicas); i++ {
-		l := label.New().Instance(upgradeInstanceName).TiFlash().Labels()
-		if i+1 <= int(set.Status.CurrentReplicas) {
-			l[apps.ControllerRevisionHashLabelKey] = set.Status.CurrentRevision
-		} else {
-			l[apps.ControllerRevisionHashLabelKey] = set.Status.UpdateRevision
-		}
-		l[label.StoreIDLabelKey] = strconv.Itoa(i + 1)
-		pods = append(pods, &corev1.Pod{
-			TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      TiFlashPodName(upgradeTcName, int32(i)),
-				Namespace: corev1.NamespaceDefault,
-				Labels:    l,
-			},
-			Status: corev1.PodStatus{
-				Phase: corev1.PodRunning,
-				Conditions: []corev1.PodCondition{
-					{
-						Type:   corev1.PodReady,
-						Status: corev1.ConditionTrue,
-					},
-				},
-			},
-		})
-	}
-	return pods
+	return &tiflashUpgrader{deps: fakeDeps, recorder: fakeDeps.Recorder}, pdControl, tiflashControl, podControl, podInformer
 }
 
 // NewFakeTiKVClient creates a fake tikvclient that is set as the tikv client
@@ -67,6 +67,9 @@ func buildTiFlashSidecarContainers(tc *v1alpha1.TidbCluster) ([]corev1.Container
 		return nil, err
 	}
 	containers = append(containers, buildSidecarContainer("clusterlog", path, image, pullPolicy, resource))
+	for i := range containers {
+		containers[i].EnvFrom = spec.EnvFrom
+	}
 	return containers, nil
 }
 
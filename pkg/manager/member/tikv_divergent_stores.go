@@ -0,0 +1,116 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// syncTiKVDivergentStores probes the stores of the cluster reported Up by PD directly if spec.tikv.directProbe is
+// enabled, and records the ones which are unreachable or answer with another store id in status.tikv.divergentStores.
+// The stores are probed once per sync, the upgrade and the failover decide the health of the stores by the result.
+func syncTiKVDivergentStores(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, storesInfo *pdapi.StoresInfo, stores map[string]v1alpha1.TiKVStore) {
+	if !tc.Spec.TiKV.DirectProbe {
+		tc.Status.TiKV.DivergentStores = nil
+		return
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+
+	type probe struct {
+		id      string
+		podName string
+		address string
+		reason  string
+	}
+	var probes []*probe
+	for _, info := range storesInfo.Stores {
+		if info.Store == nil || info.Store.StateName != v1alpha1.TiKVStateUp {
+			continue
+		}
+		id := fmt.Sprintf("%d", info.Store.GetId())
+		store, ok := stores[id]
+		if !ok {
+			continue
+		}
+		probes = append(probes, &probe{id: id, podName: store.PodName, address: info.Store.GetAddress()})
+	}
+	sort.Slice(probes, func(i, j int) bool {
+		return probes[i].podName < probes[j].podName
+	})
+
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p *probe) {
+			defer wg.Done()
+			storeID, err := deps.TiKVControl.ProbeStore(ns, tcName, p.address, tc.IsTLSClusterEnabled())
+			if err != nil {
+				p.reason = fmt.Sprintf("unreachable: %v", err)
+			} else if id := fmt.Sprintf("%d", storeID); id != p.id {
+				p.reason = fmt.Sprintf("answers with store id %s", id)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	previous := tc.Status.TiKV.DivergentStores
+	divergent := map[string]v1alpha1.TiKVDivergentStore{}
+	for _, p := range probes {
+		old, exist := previous[p.id]
+		if p.reason == "" {
+			if exist {
+				klog.Infof("tidbcluster: [%s/%s] tikv store %s of pod %s passes the direct probe again", ns, tcName, p.id, p.podName)
+				deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.StoreConverged, "store %s of pod %s passes the direct probe again", p.id, p.podName)
+			}
+			continue
+		}
+		store := v1alpha1.TiKVDivergentStore{
+			PodName:    p.podName,
+			Address:    p.address,
+			Reason:     p.reason,
+			DetectedAt: metav1.Now(),
+		}
+		if exist {
+			store.DetectedAt = old.DetectedAt
+		} else {
+			klog.Warningf("tidbcluster: [%s/%s] tikv store %s of pod %s is Up in PD but %s", ns, tcName, p.id, p.podName, p.reason)
+			deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.StoreDivergent, "store %s of pod %s is Up in PD but %s", p.id, p.podName, p.reason)
+		}
+		divergent[p.id] = store
+	}
+	if len(divergent) == 0 {
+		divergent = nil
+	}
+	tc.Status.TiKV.DivergentStores = divergent
+}
+
+// tikvStoreHealthy returns true if the store is Up in PD and does not diverge from PD by the direct probe
+func tikvStoreHealthy(tc *v1alpha1.TidbCluster, store v1alpha1.TiKVStore) bool {
+	if store.State != v1alpha1.TiKVStateUp {
+		return false
+	}
+	_, divergent := tc.Status.TiKV.DivergentStores[store.ID]
+	return !divergent
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/pingcap/tidb-operator/pkg/tikvapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSyncTiKVDivergentStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newStore := func(id uint64, address, state string) *pdapi.StoreInfo {
+		return &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{Id: id, Address: address}, StateName: state},
+			Status: &pdapi.StoreStatus{},
+		}
+	}
+	addr := func(ordinal string) string {
+		return "test-tikv-" + ordinal + ".test-tikv-peer.default.svc:20160"
+	}
+	storesInfo := &pdapi.StoresInfo{Stores: []*pdapi.StoreInfo{
+		newStore(1, addr("0"), v1alpha1.TiKVStateUp),
+		newStore(2, addr("1"), v1alpha1.TiKVStateUp),
+		newStore(3, addr("2"), v1alpha1.TiKVStateUp),
+		// the stores Down in PD are left to failover
+		newStore(4, addr("3"), v1alpha1.TiKVStateDown),
+	}}
+	stores := map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0", State: v1alpha1.TiKVStateUp},
+		"2": {ID: "2", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp},
+		"3": {ID: "3", PodName: "test-tikv-2", State: v1alpha1.TiKVStateUp},
+		"4": {ID: "4", PodName: "test-tikv-3", State: v1alpha1.TiKVStateDown},
+	}
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tikvControl := deps.TiKVControl.(*tikvapi.FakeTiKVControl)
+	tc := newTidbClusterForTiKV()
+
+	// the stores are not probed unless enabled
+	syncTiKVDivergentStores(deps, tc, storesInfo, stores)
+	g.Expect(tc.Status.TiKV.DivergentStores).To(BeNil())
+
+	// the unreachable store and the store answering with another id diverge from PD
+	tc.Spec.TiKV.DirectProbe = true
+	tikvControl.SetStoreProbe(addr("0"), 1, nil)
+	tikvControl.SetStoreProbe(addr("2"), 5, nil)
+	syncTiKVDivergentStores(deps, tc, storesInfo, stores)
+	g.Expect(tc.Status.TiKV.DivergentStores).To(HaveLen(2))
+	g.Expect(tc.Status.TiKV.DivergentStores["2"].PodName).To(Equal("test-tikv-1"))
+	g.Expect(tc.Status.TiKV.DivergentStores["2"].Reason).To(HavePrefix("unreachable: "))
+	g.Expect(tc.Status.TiKV.DivergentStores["3"].Reason).To(Equal("answers with store id 5"))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(2))
+	g.Expect(recorded[0]).To(ContainSubstring(events.StoreDivergent))
+	g.Expect(tikvStoreHealthy(tc, stores["1"])).To(BeTrue())
+	g.Expect(tikvStoreHealthy(tc, stores["2"])).To(BeFalse())
+	g.Expect(tikvStoreHealthy(tc, stores["4"])).To(BeFalse())
+
+	// the detection time is kept and the events are emitted once
+	detectedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+	divergent := tc.Status.TiKV.DivergentStores["2"]
+	divergent.DetectedAt = detectedAt
+	tc.Status.TiKV.DivergentStores["2"] = divergent
+	tikvControl.SetStoreProbe(addr("2"), 3, nil)
+	syncTiKVDivergentStores(deps, tc, storesInfo, stores)
+	g.Expect(tc.Status.TiKV.DivergentStores).To(HaveLen(1))
+	g.Expect(tc.Status.TiKV.DivergentStores["2"].DetectedAt).To(Equal(detectedAt))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.StoreConverged))
+
+	// the stores converge with PD
+	tikvControl.SetStoreProbe(addr("1"), 2, nil)
+	syncTiKVDivergentStores(deps, tc, storesInfo, stores)
+	g.Expect(tc.Status.TiKV.DivergentStores).To(BeNil())
+	g.Expect(collectEvents(recorder.Events)).To(HaveLen(1))
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -60,7 +61,13 @@ func (f *tikvFailover) Failover(tc *v1alpha1.TidbCluster) error {
 			// (before it enters into Offline/Tombstone state)
 			continue
 		}
+		down, msg := store.State == v1alpha1.TiKVStateDown, fmt.Sprintf("store[%s] is Down", store.ID)
 		deadline := store.LastTransitionTime.Add(f.deps.CLIConfig.TiKVFailoverPeriod)
+		// the store which is Up in PD but fails the direct probe is failed over as a Down store
+		if divergent, ok := tc.Status.TiKV.DivergentStores[storeID]; ok && store.State == v1alpha1.TiKVStateUp {
+			down, msg = true, fmt.Sprintf("store[%s] is Up in PD but %s", store.ID, divergent.Reason)
+			deadline = divergent.DetectedAt.Add(f.deps.CLIConfig.TiKVFailoverPeriod)
+		}
 		exist := false
 		for _, failureStore := range tc.Status.TiKV.FailureStores {
 			if failureStore.PodName == podName {
@@ -68,7 +75,7 @@ func (f *tikvFailover) Failover(tc *v1alpha1.TidbCluster) error {
 				break
 			}
 		}
-		if store.State == v1alpha1.TiKVStateDown && time.Now().After(deadline) {
+		if down && time.Now().After(deadline) {
 			if tc.Spec.TiKV.MaxFailoverCount != nil && *tc.Spec.TiKV.MaxFailoverCount > 0 {
 				if tc.Status.TiKV.FailoverUID == "" {
 					tc.Status.TiKV.FailoverUID = uuid.NewUUID()
@@ -87,8 +94,7 @@ func (f *tikvFailover) Failover(tc *v1alpha1.TidbCluster) error {
 						StoreID:   store.ID,
 						CreatedAt: metav1.Now(),
 					}
-					msg := fmt.Sprintf("store[%s] is Down", store.ID)
-					f.deps.Recorder.Event(tc, corev1.EventTypeWarning, unHealthEventReason, fmt.Sprintf(unHealthEventMsgPattern, "tikv", podName, msg))
+					f.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.Unhealthy, fmt.Sprintf(unHealthEventMsgPattern, "tikv", podName, msg))
 				}
 			}
 		}
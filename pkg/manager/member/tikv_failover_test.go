@@ -69,6 +69,35 @@ func TestTiKVFailoverFailover(t *testing.T) {
 				g.Expect(tc.Status.TiKV.FailoverUID).To(BeEmpty())
 			},
 		},
+		{
+			name: "tikv store is Up in PD but fails the direct probe",
+			update: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+					"1": {
+						ID:                 "1",
+						State:              v1alpha1.TiKVStateUp,
+						PodName:            "tikv-1",
+						LastTransitionTime: metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+					},
+					"2": {
+						ID:                 "2",
+						State:              v1alpha1.TiKVStateUp,
+						PodName:            "tikv-2",
+						LastTransitionTime: metav1.Time{Time: time.Now().Add(-2 * time.Hour)},
+					},
+				}
+				tc.Status.TiKV.DivergentStores = map[string]v1alpha1.TiKVDivergentStore{
+					"1": {PodName: "tikv-1", Reason: "unreachable", DetectedAt: metav1.Time{Time: time.Now().Add(-70 * time.Minute)}},
+					"2": {PodName: "tikv-2", Reason: "unreachable", DetectedAt: metav1.Time{Time: time.Now().Add(-30 * time.Minute)}},
+				}
+			},
+			err: false,
+			expectFn: func(t *testing.T, tc *v1alpha1.TidbCluster) {
+				g := NewGomegaWithT(t)
+				g.Expect(len(tc.Status.TiKV.FailureStores)).To(Equal(1))
+				g.Expect(tc.Status.TiKV.FailureStores["1"].PodName).To(Equal("tikv-1"))
+			},
+		},
 		{
 			name: "deadline not exceed",
 			update: func(tc *v1alpha1.TidbCluster) {
@@ -0,0 +1,136 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// ghostStoreSafetyWindow is how long a store has been a ghost store before it is deleted by
+// spec.tikv.autoCleanGhostStores
+var ghostStoreSafetyWindow = 30 * time.Minute
+
+// syncTiKVGhostStores detects the ghost stores of the cluster and deletes the ones which are safe to delete if
+// spec.tikv.autoCleanGhostStores is enabled. A ghost store is an Up or Offline store whose address is exactly the
+// address of a pod of the cluster in the peer service, but the pod neither exists nor is expected by the cluster or
+// the StatefulSet. The stores of the heterogeneous clusters, the clusters in the other Kubernetes clusters and the
+// stores in the host network never match the addresses of the pods of the cluster, so they are never ghost stores.
+func syncTiKVGhostStores(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, set *apps.StatefulSet, storesInfo *pdapi.StoresInfo) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if tc.AcrossK8s() && tc.Spec.ClusterDomain == "" {
+		// the addresses of the pods of the clusters in the other Kubernetes clusters may be the same
+		tc.Status.TiKV.GhostStores = nil
+		return nil
+	}
+
+	pattern, err := regexp.Compile("^" + fmt.Sprintf(tikvStoreLimitPattern, tcName, tcName, ns, controller.FormatClusterDomainForRegex(tc.Spec.ClusterDomain)) + "$")
+	if err != nil {
+		return err
+	}
+	backed, err := tikvBackingPods(deps, tc, set)
+	if err != nil {
+		return err
+	}
+
+	previous := tc.Status.TiKV.GhostStores
+	ghosts := map[string]v1alpha1.GhostStore{}
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil || store.Status == nil {
+			continue
+		}
+		state := store.Store.StateName
+		if state != v1alpha1.TiKVStateUp && state != v1alpha1.TiKVStateOffline {
+			continue
+		}
+		address := store.Store.GetAddress()
+		if !pattern.MatchString(address) || backed.Has(tikvStorePodName(address)) {
+			continue
+		}
+
+		ghost := v1alpha1.GhostStore{
+			ID:          strconv.FormatUint(store.Store.GetId(), 10),
+			Address:     address,
+			State:       state,
+			RegionCount: store.Status.RegionCount,
+			DetectedAt:  metav1.Now(),
+		}
+		if old, ok := previous[ghost.ID]; ok {
+			ghost.DetectedAt = old.DetectedAt
+		} else {
+			klog.Warningf("tidbcluster: [%s/%s] store %s at %s is not backed by any pod", ns, tcName, ghost.ID, address)
+			deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.GhostStore, "store %s at %s is %s in PD but not backed by any pod", ghost.ID, address, state)
+		}
+
+		if tc.Spec.TiKV.AutoCleanGhostStores && ghost.RegionCount == 0 && time.Since(ghost.DetectedAt.Time) > ghostStoreSafetyWindow {
+			id := store.Store.GetId()
+			if err := pdCli.DeleteStore(id); err != nil {
+				klog.Errorf("tidbcluster: [%s/%s] failed to delete ghost store %d, error: %v", ns, tcName, id, err)
+			} else {
+				klog.Infof("tidbcluster: [%s/%s] deleted ghost store %d at %s", ns, tcName, id, address)
+				deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.GhostStoreDeleted, "ghost store %d at %s is deleted", id, address)
+			}
+		}
+		ghosts[ghost.ID] = ghost
+	}
+
+	if len(ghosts) == 0 {
+		ghosts = nil
+	}
+	tc.Status.TiKV.GhostStores = ghosts
+	return nil
+}
+
+// tikvBackingPods returns the names of the pods which may back the stores of the cluster, they are the current pods
+// and the pods expected by the cluster and the StatefulSet
+func tikvBackingPods(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, set *apps.StatefulSet) (sets.String, error) {
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := deps.PodLister.Pods(tc.GetNamespace()).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("tikvBackingPods: failed to list pods for cluster %s/%s, error: %v", tc.GetNamespace(), tc.GetName(), err)
+	}
+	names := sets.NewString()
+	for _, pod := range pods {
+		names.Insert(pod.Name)
+	}
+
+	ordinals, err := util.GetPodOrdinals(tc, v1alpha1.TiKVMemberType)
+	if err != nil {
+		return nil, err
+	}
+	ordinals = ordinals.Union(helper.GetPodOrdinals(*set.Spec.Replicas, set))
+	for _, ordinal := range ordinals.List() {
+		names.Insert(TikvPodName(tc.GetName(), ordinal))
+	}
+	return names, nil
+}
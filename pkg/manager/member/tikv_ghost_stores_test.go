@@ -0,0 +1,130 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestSyncTiKVGhostStores(t *testing.T) {
+	newStore := func(id uint64, address, state string, regions int) *pdapi.StoreInfo {
+		return &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{Id: id, Address: address}, StateName: state},
+			Status: &pdapi.StoreStatus{RegionCount: regions},
+		}
+	}
+	stores := &pdapi.StoresInfo{Stores: []*pdapi.StoreInfo{
+		// backed by a pod
+		newStore(1, "test-tikv-0.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateUp, 10),
+		// the pod is expected and being recreated
+		newStore(2, "test-tikv-2.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateUp, 10),
+		// ghost stores
+		newStore(4, "test-tikv-5.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateUp, 0),
+		newStore(5, "test-tikv-6.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateOffline, 3),
+		// down stores are left to failover
+		newStore(6, "test-tikv-7.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateDown, 0),
+		// the stores of a heterogeneous cluster and the cluster in another Kubernetes cluster
+		newStore(7, "test-hetero-tikv-5.test-hetero-tikv-peer.default.svc:20160", v1alpha1.TiKVStateUp, 0),
+		newStore(8, "test-tikv-5.test-tikv-peer.default.svc.cluster2.local:20160", v1alpha1.TiKVStateUp, 0),
+		newStore(9, "mytest-tikv-5.test-tikv-peer.default.svc:20160", v1alpha1.TiKVStateUp, 0),
+	}}
+
+	setup := func(g *GomegaWithT) (*controller.Dependencies, *v1alpha1.TidbCluster, *apps.StatefulSet) {
+		deps := controller.NewFakeDependencies()
+		tc := newTidbClusterForTiKV()
+		set := &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tikv", Namespace: corev1.NamespaceDefault},
+			Spec:       apps.StatefulSetSpec{Replicas: pointer.Int32Ptr(3)},
+		}
+		for _, name := range []string{"test-tikv-0", "test-tikv-1"} {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: corev1.NamespaceDefault,
+				Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+			}}
+			g.Expect(deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer().Add(pod)).To(Succeed())
+		}
+		return deps, tc, set
+	}
+
+	t.Run("detect", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		deps, tc, set := setup(g)
+		pdCli := pdapi.NewFakePDClient()
+
+		g.Expect(syncTiKVGhostStores(deps, pdCli, tc, set, stores)).To(Succeed())
+		g.Expect(tc.Status.TiKV.GhostStores).To(HaveLen(2))
+		g.Expect(tc.Status.TiKV.GhostStores).To(HaveKey("4"))
+		g.Expect(tc.Status.TiKV.GhostStores["5"].State).To(Equal(v1alpha1.TiKVStateOffline))
+		g.Expect(tc.Status.TiKV.GhostStores["5"].RegionCount).To(Equal(3))
+		events := collectEvents(deps.Recorder.(*record.FakeRecorder).Events)
+		g.Expect(events).To(HaveLen(2))
+
+		// the events are emitted once and the stores are never deleted without autoCleanGhostStores
+		detectedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+		for id, ghost := range tc.Status.TiKV.GhostStores {
+			ghost.DetectedAt = detectedAt
+			tc.Status.TiKV.GhostStores[id] = ghost
+		}
+		g.Expect(syncTiKVGhostStores(deps, pdCli, tc, set, stores)).To(Succeed())
+		g.Expect(tc.Status.TiKV.GhostStores["4"].DetectedAt).To(Equal(detectedAt))
+		g.Expect(collectEvents(deps.Recorder.(*record.FakeRecorder).Events)).To(BeEmpty())
+	})
+
+	t.Run("auto clean", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		deps, tc, set := setup(g)
+		tc.Spec.TiKV.AutoCleanGhostStores = true
+		pdCli := pdapi.NewFakePDClient()
+		var deleted []uint64
+		pdCli.AddReaction(pdapi.DeleteStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+			deleted = append(deleted, action.ID)
+			return nil, nil
+		})
+
+		// in the safety window
+		g.Expect(syncTiKVGhostStores(deps, pdCli, tc, set, stores)).To(Succeed())
+		g.Expect(deleted).To(BeEmpty())
+
+		// only the ghost store without regions is deleted after the safety window
+		for id, ghost := range tc.Status.TiKV.GhostStores {
+			ghost.DetectedAt = metav1.NewTime(time.Now().Add(-ghostStoreSafetyWindow - time.Minute))
+			tc.Status.TiKV.GhostStores[id] = ghost
+		}
+		g.Expect(syncTiKVGhostStores(deps, pdCli, tc, set, stores)).To(Succeed())
+		g.Expect(deleted).To(Equal([]uint64{4}))
+	})
+
+	t.Run("across kubernetes without cluster domain", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		deps, tc, set := setup(g)
+		tc.Spec.AcrossK8s = true
+
+		g.Expect(syncTiKVGhostStores(deps, pdapi.NewFakePDClient(), tc, set, stores)).To(Succeed())
+		g.Expect(tc.Status.TiKV.GhostStores).To(BeNil())
+	})
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// allocateTiKVHostPorts allocates a slot of spec.tikv.hostPorts to TiKV, a slot is a server port and a status port.
+// The slot is allocated once and kept in the status, the slots of the other clusters are skipped. The search starts
+// from a slot derived from the name of the cluster, so the clusters reconciled at the same time rarely pick the same
+// slot, and the host ports of the pods are declared so the scheduler never co-schedules the pods of such clusters.
+func allocateTiKVHostPorts(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	if !tc.TiKVHostPortsEnabled() {
+		tc.Status.TiKV.HostPorts = nil
+		return nil
+	}
+	if tc.Status.TiKV.HostPorts != nil {
+		return nil
+	}
+
+	tcs, err := deps.TiDBClusterLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("allocateTiKVHostPorts: failed to list tidbclusters, error: %v", err)
+	}
+	used := sets.NewInt32()
+	for _, other := range tcs {
+		if other.Namespace == tc.Namespace && other.Name == tc.Name {
+			continue
+		}
+		if other.Spec.TiKV == nil || !other.BaseTiKVSpec().HostNetwork() {
+			continue
+		}
+		server, status := other.TiKVPorts()
+		used.Insert(server, status)
+	}
+
+	base := v1alpha1.DefaultTiKVServerPort
+	if tc.Spec.TiKV.HostPorts.Base != nil {
+		base = *tc.Spec.TiKV.HostPorts.Base
+	}
+	maxSlots := v1alpha1.DefaultHostPortMaxSlots
+	if tc.Spec.TiKV.HostPorts.MaxSlots != nil {
+		maxSlots = *tc.Spec.TiKV.HostPorts.MaxSlots
+	}
+	h := fnv.New32a()
+	h.Write([]byte(tc.Namespace + "/" + tc.Name))
+	start := int32(h.Sum32() % uint32(maxSlots))
+	for i := int32(0); i < maxSlots; i++ {
+		server := base + (start+i)%maxSlots*2
+		if used.Has(server) || used.Has(server+1) {
+			continue
+		}
+		tc.Status.TiKV.HostPorts = &v1alpha1.HostPorts{Server: server, Status: server + 1}
+		klog.Infof("tidbcluster: [%s/%s] allocated host ports %d and %d to tikv", tc.Namespace, tc.Name, server, server+1)
+		return nil
+	}
+	return fmt.Errorf("tidbcluster: [%s/%s] no free host ports for tikv in the %d slots from %d", tc.Namespace, tc.Name, maxSlots, base)
+}
+
+// hostPortsAntiAffinity adds the required anti-affinity preventing the pods listening on the host port from being
+// scheduled to the same node, the pods are labeled with label.HostPortLabelKeyPrefix suffixed with the port.
+// The affinity is copied before it is changed.
+func hostPortsAntiAffinity(affinity *corev1.Affinity, port int32) *corev1.Affinity {
+	affinity = affinity.DeepCopy()
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: hostPortLabelKey(port), Operator: metav1.LabelSelectorOpExists},
+			},
+		},
+		TopologyKey: corev1.LabelHostname,
+	})
+	return affinity
+}
+
+func hostPortLabelKey(port int32) string {
+	return label.HostPortLabelKeyPrefix + strconv.Itoa(int(port))
+}
+
+// syncTiKVPodHostPorts records the host ports in use by each pod of TiKV in the host network
+func syncTiKVPodHostPorts(podLister corelisters.PodLister, tc *v1alpha1.TidbCluster) error {
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return err
+	}
+	pods, err := podLister.Pods(tc.Namespace).List(selector)
+	if err != nil {
+		return fmt.Errorf("syncTiKVPodHostPorts: failed to list pods for cluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
+	podHostPorts := map[string]v1alpha1.HostPorts{}
+	for _, pod := range pods {
+		if !pod.Spec.HostNetwork {
+			continue
+		}
+		var ports v1alpha1.HostPorts
+		for _, c := range pod.Spec.Containers {
+			if c.Name != v1alpha1.TiKVMemberType.String() {
+				continue
+			}
+			for _, p := range c.Ports {
+				switch p.Name {
+				case "server":
+					ports.Server = p.ContainerPort
+				case "status":
+					ports.Status = p.ContainerPort
+				}
+			}
+		}
+		if ports.Status == 0 {
+			// the status port is not declared if the host ports are not allocated and the named status port is disabled
+			ports.Status = v1alpha1.DefaultTiKVStatusPort
+		}
+		podHostPorts[pod.Name] = ports
+	}
+	if len(podHostPorts) == 0 {
+		podHostPorts = nil
+	}
+	tc.Status.TiKV.PodHostPorts = podHostPorts
+	return nil
+}
+
+// tikvStoreResolver resolves the pods of the TiKV stores from their addresses, the stores advertise the addresses
+// of the pods in the peer service, or the addresses of the nodes if the host ports of TiKV are allocated
+type tikvStoreResolver struct {
+	pattern *regexp.Regexp
+	// hostAddrs are the addresses of the nodes the pods listen on
+	hostAddrs map[string]string
+}
+
+func newTiKVStoreResolver(podLister corelisters.PodLister, tc *v1alpha1.TidbCluster) (*tikvStoreResolver, error) {
+	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.Name, tc.Namespace, controller.FormatClusterDomainForRegex(tc.Spec.ClusterDomain)))
+	if err != nil {
+		return nil, err
+	}
+	r := &tikvStoreResolver{pattern: pattern}
+	if !tc.TiKVHostPortsEnabled() {
+		return r, nil
+	}
+
+	selector, err := label.New().Instance(tc.GetInstanceName()).TiKV().Selector()
+	if err != nil {
+		return nil, err
+	}
+	pods, err := podLister.Pods(tc.Namespace).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("newTiKVStoreResolver: failed to list pods for cluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
+	server, _ := tc.TiKVPorts()
+	r.hostAddrs = map[string]string{}
+	for _, pod := range pods {
+		if pod.Status.HostIP == "" {
+			continue
+		}
+		r.hostAddrs[net.JoinHostPort(pod.Status.HostIP, strconv.Itoa(int(server)))] = pod.Name
+	}
+	return r, nil
+}
+
+// podName returns the pod of the store at the address, it returns false if the store is not a store of the cluster
+func (r *tikvStoreResolver) podName(address string) (string, bool) {
+	if podName, ok := r.hostAddrs[address]; ok {
+		return podName, true
+	}
+	if r.pattern.MatchString(address) {
+		return tikvStorePodName(address), true
+	}
+	return "", false
+}
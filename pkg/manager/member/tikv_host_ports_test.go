@@ -0,0 +1,127 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newTidbClusterForHostPorts(name string) *v1alpha1.TidbCluster {
+	return &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: corev1.NamespaceDefault},
+		Spec: v1alpha1.TidbClusterSpec{
+			TiKV: &v1alpha1.TiKVSpec{
+				ComponentSpec: v1alpha1.ComponentSpec{HostNetwork: pointer.BoolPtr(true)},
+				Config:        v1alpha1.NewTiKVConfig(),
+				HostPorts:     &v1alpha1.HostPortAllocation{Base: pointer.Int32Ptr(30000), MaxSlots: pointer.Int32Ptr(2)},
+			},
+		},
+	}
+}
+
+func TestAllocateTiKVHostPorts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	indexer := deps.InformerFactory.Pingcap().V1alpha1().TidbClusters().Informer().GetIndexer()
+
+	tc1 := newTidbClusterForHostPorts("tc1")
+	g.Expect(allocateTiKVHostPorts(deps, tc1)).To(Succeed())
+	g.Expect(tc1.Status.TiKV.HostPorts).NotTo(BeNil())
+	g.Expect(tc1.Status.TiKV.HostPorts.Server).To(BeElementOf(int32(30000), int32(30002)))
+	g.Expect(tc1.Status.TiKV.HostPorts.Status).To(Equal(tc1.Status.TiKV.HostPorts.Server + 1))
+	g.Expect(indexer.Add(tc1)).To(Succeed())
+
+	// the allocated ports are kept
+	allocated := *tc1.Status.TiKV.HostPorts
+	g.Expect(allocateTiKVHostPorts(deps, tc1)).To(Succeed())
+	g.Expect(*tc1.Status.TiKV.HostPorts).To(Equal(allocated))
+
+	// the other slot is allocated to the second cluster
+	tc2 := newTidbClusterForHostPorts("tc2")
+	g.Expect(allocateTiKVHostPorts(deps, tc2)).To(Succeed())
+	g.Expect(tc2.Status.TiKV.HostPorts.Server).NotTo(Equal(allocated.Server))
+	g.Expect(indexer.Add(tc2)).To(Succeed())
+
+	// no free slots
+	tc3 := newTidbClusterForHostPorts("tc3")
+	g.Expect(allocateTiKVHostPorts(deps, tc3)).NotTo(Succeed())
+	g.Expect(tc3.Status.TiKV.HostPorts).To(BeNil())
+
+	// released if the host network is disabled
+	tc1.Spec.TiKV.HostNetwork = pointer.BoolPtr(false)
+	g.Expect(allocateTiKVHostPorts(deps, tc1)).To(Succeed())
+	g.Expect(tc1.Status.TiKV.HostPorts).To(BeNil())
+}
+
+func TestTiKVStoreResolver(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForHostPorts("tc")
+	tc.Status.TiKV.HostPorts = &v1alpha1.HostPorts{Server: 30000, Status: 30001}
+	indexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	for name, hostIP := range map[string]string{"tc-tikv-0": "10.0.0.1", "tc-tikv-1": "10.0.0.2", "tc-tikv-2": ""} {
+		g.Expect(indexer.Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: tc.Namespace,
+				Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+			},
+			Spec: corev1.PodSpec{
+				HostNetwork: true,
+				Containers: []corev1.Container{{
+					Name: "tikv",
+					Ports: []corev1.ContainerPort{
+						{Name: "server", ContainerPort: 30000, HostPort: 30000},
+						{Name: "status", ContainerPort: 30001, HostPort: 30001},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{HostIP: hostIP},
+		})).To(Succeed())
+	}
+
+	resolver, err := newTiKVStoreResolver(deps.PodLister, tc)
+	g.Expect(err).NotTo(HaveOccurred())
+	tests := []struct {
+		address string
+		podName string
+		ok      bool
+	}{
+		{address: "10.0.0.1:30000", podName: "tc-tikv-0", ok: true},
+		{address: "10.0.0.2:30000", podName: "tc-tikv-1", ok: true},
+		{address: "10.0.0.2:20160"},
+		{address: "10.0.0.3:30000"},
+		{address: "tc-tikv-2.tc-tikv-peer.default.svc:20160", podName: "tc-tikv-2", ok: true},
+		{address: "other-tikv-0.other-tikv-peer.default.svc:20160"},
+	}
+	for _, tt := range tests {
+		podName, ok := resolver.podName(tt.address)
+		g.Expect(ok).To(Equal(tt.ok), tt.address)
+		g.Expect(podName).To(Equal(tt.podName), tt.address)
+	}
+
+	g.Expect(syncTiKVPodHostPorts(deps.PodLister, tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.PodHostPorts).To(HaveLen(3))
+	g.Expect(tc.Status.TiKV.PodHostPorts).To(HaveKeyWithValue("tc-tikv-0", v1alpha1.HostPorts{Server: 30000, Status: 30001}))
+}
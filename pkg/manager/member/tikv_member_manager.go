@@ -18,13 +18,13 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/manager"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
@@ -46,6 +46,11 @@ import (
 const (
 	// tikvDataVolumeMountPath is the mount path for tikv data volume
 	tikvDataVolumeMountPath = "/var/lib/tikv"
+	// tikvHugepagesMountPath is the mount path for the hugepages requested by tikv
+	tikvHugepagesMountPath = "/dev/hugepages"
+	// tikvLeadersPerGraceSecond is how many leaders of a store extend the termination grace period of the tikv
+	// pods by one second
+	tikvLeadersPerGraceSecond = 1000
 
 	// tikvClusterCertPath is where the cert for inter-cluster communication stored (if any)
 	tikvClusterCertPath = "/var/lib/tikv-tls"
@@ -116,7 +121,7 @@ func (m *tikvMemberManager) Sync(tc *v1alpha1.TidbCluster) error {
 }
 
 func (m *tikvMemberManager) syncServiceForTidbCluster(tc *v1alpha1.TidbCluster, svcConfig SvcConfig) error {
-	if tc.Spec.Paused {
+	if tc.IsComponentPaused(v1alpha1.TiKVMemberType) {
 		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for tikv service", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
@@ -143,7 +148,8 @@ func (m *tikvMemberManager) syncServiceForTidbCluster(tc *v1alpha1.TidbCluster,
 	if err != nil {
 		return err
 	}
-	if !equal {
+	annotated := syncTopologyAwareRoutingAnnotations(newSvc, oldSvc)
+	if !equal || annotated {
 		svc := *oldSvc
 		svc.Spec = newSvc.Spec
 		// TODO add unit test
@@ -152,10 +158,14 @@ func (m *tikvMemberManager) syncServiceForTidbCluster(tc *v1alpha1.TidbCluster,
 			return err
 		}
 		svc.Spec.ClusterIP = oldSvc.Spec.ClusterIP
-		_, err = m.deps.ServiceControl.UpdateService(tc, &svc)
-		return err
+		if _, err = m.deps.ServiceControl.UpdateService(tc, &svc); err != nil {
+			return err
+		}
 	}
 
+	if svcConfig.Headless {
+		return syncZoneServices(m.deps, tc, newSvc)
+	}
 	return nil
 }
 
@@ -175,11 +185,25 @@ func (m *tikvMemberManager) syncStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		return err
 	}
 
-	if tc.Spec.Paused {
+	syncComponentPausedCondition(tc, v1alpha1.TiKVMemberType)
+
+	if tc.IsComponentPaused(v1alpha1.TiKVMemberType) {
 		klog.V(4).Infof("tikv cluster %s/%s is paused, skip syncing for tikv statefulset", tc.GetNamespace(), tc.GetName())
 		return nil
 	}
 
+	if err := syncExternalProvisioningGates(m.deps, tc, v1alpha1.TiKVMemberType); err != nil {
+		return err
+	}
+
+	if err := resumeTiKVFromShutdownCheckpoint(m.deps, tc); err != nil {
+		return err
+	}
+
+	if err := allocateTiKVHostPorts(m.deps, tc); err != nil {
+		return err
+	}
+
 	cm, err := m.syncTiKVConfigMap(tc, oldSet)
 	if err != nil {
 		return err
@@ -199,6 +223,7 @@ func (m *tikvMemberManager) syncStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 	if err != nil {
 		return err
 	}
+	setTiKVTerminationGracePeriodSeconds(tc, oldSet, newSet)
 	if setNotExist {
 		err = mngerutils.SetStatefulSetLastAppliedConfigAnnotation(newSet)
 		if err != nil {
@@ -225,6 +250,21 @@ func (m *tikvMemberManager) syncStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		return err
 	}
 
+	// the failure of force deleting the stuck pods does not fail the sync, they are retried in the next sync
+	if err := syncTiKVStuckPods(m.deps, tc); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to force delete the stuck pods of tikv: %v", tc.Namespace, tc.Name, err)
+	}
+
+	// the failure of moving the pods to the nodes of their bindings does not fail the sync either
+	if err := syncTiKVNodeBindings(m.deps, tc); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to move the tikv pods to the nodes of their bindings: %v", tc.Namespace, tc.Name, err)
+	}
+
+	// the replacement of the volumes is resumed from its phase in the next sync if it fails
+	if err := syncTiKVVolumeReplace(m.deps, tc); err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] failed to replace the volumes of tikv: %v", tc.Namespace, tc.Name, err)
+	}
+
 	// Perform failover logic if necessary. Note that this will only update
 	// TidbCluster status. The actual scaling performs in next sync loop (if a
 	// new replica needs to be added).
@@ -236,13 +276,25 @@ func (m *tikvMemberManager) syncStatefulSetForTidbCluster(tc *v1alpha1.TidbClust
 		}
 	}
 
+	if err := cleanupImagePrepullPods(m.deps, tc, v1alpha1.TiKVMemberType); err != nil {
+		return err
+	}
+
+	if _, err := keepTemplateIfDowngrade(m.deps, tc, v1alpha1.TiKVMemberType, oldSet, newSet); err != nil {
+		return err
+	}
+
+	if err := prePullImage(m.deps, tc, v1alpha1.TiKVMemberType, oldSet, newSet); err != nil {
+		return err
+	}
+
 	if !templateEqual(newSet, oldSet) || tc.Status.TiKV.Phase == v1alpha1.UpgradePhase {
 		if err := m.upgrader.Upgrade(tc, oldSet, newSet); err != nil {
 			return err
 		}
 	}
 
-	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, "FailedUpdateTiKVSTS", newSet, oldSet)
+	return mngerutils.UpdateStatefulSetWithPrecheck(m.deps, tc, newSet, oldSet)
 }
 
 func (m *tikvMemberManager) syncTiKVConfigMap(tc *v1alpha1.TidbCluster, set *apps.StatefulSet) (*corev1.ConfigMap, error) {
@@ -250,10 +302,17 @@ func (m *tikvMemberManager) syncTiKVConfigMap(tc *v1alpha1.TidbCluster, set *app
 	if tc.Spec.TiKV.Config == nil {
 		return nil, nil
 	}
-	newCm, err := getTikVConfigMap(tc)
+	tierTC, tierEntries, err := withTierConfig(m.deps, tc, v1alpha1.TiKVMemberType)
+	if err != nil {
+		return nil, err
+	}
+	newCm, err := getTikVConfigMap(tierTC)
 	if err != nil {
 		return nil, err
 	}
+	if err := setTierConfigAnnotation(newCm, tierEntries); err != nil {
+		return nil, err
+	}
 
 	var inUseName string
 	if set != nil {
@@ -266,6 +325,10 @@ func (m *tikvMemberManager) syncTiKVConfigMap(tc *v1alpha1.TidbCluster, set *app
 	if err != nil {
 		return nil, err
 	}
+	recordTierConfigChange(m.deps, tc, v1alpha1.TiKVMemberType, inUseName, newCm)
+	if err := recordConfigHistory(m.deps, tc, v1alpha1.TiKVMemberType, newCm, set); err != nil {
+		return nil, err
+	}
 	return m.deps.TypedControl.CreateOrUpdateConfigMap(tc, newCm)
 }
 
@@ -307,6 +370,7 @@ func getNewServiceForTidbCluster(tc *v1alpha1.TidbCluster, svcConfig SvcConfig)
 	} else {
 		svc.Spec.Type = controller.GetServiceType(tc.Spec.Services, v1alpha1.TiKVMemberType.String())
 	}
+	setTopologyAwareRouting(tc, &svc)
 	return &svc
 }
 
@@ -314,6 +378,23 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 	ns := tc.GetNamespace()
 	tcName := tc.GetName()
 	baseTiKVSpec := tc.BaseTiKVSpec()
+	resources, err := v1alpha1.SizedResources(baseTiKVSpec.Sizing(), tc.Spec.TiKV.ResourceRequirements)
+	if err != nil {
+		return nil, fmt.Errorf("cannot derive resources for tikv, tidbcluster %s/%s, error: %v", tc.Namespace, tc.Name, err)
+	}
+	hugepages := tc.Spec.TiKV.Hugepages
+	if hugepages != nil {
+		// the requests of hugepages must be equal to the limits
+		resources = *resources.DeepCopy()
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		if resources.Requests == nil {
+			resources.Requests = corev1.ResourceList{}
+		}
+		resources.Limits[hugepages.ResourceName()] = hugepages.Amount.DeepCopy()
+		resources.Requests[hugepages.ResourceName()] = hugepages.Amount.DeepCopy()
+	}
 
 	tikvConfigMap := controller.MemberConfigMapName(tc, v1alpha1.TiKVMemberType)
 	if cm != nil {
@@ -331,6 +412,9 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 		{Name: "config", ReadOnly: true, MountPath: "/etc/tikv"},
 		{Name: "startup-script", ReadOnly: true, MountPath: "/usr/local/bin"},
 	}
+	if hugepages != nil {
+		volMounts = append(volMounts, corev1.VolumeMount{Name: "hugepages", MountPath: tikvHugepagesMountPath})
+	}
 	volMounts = append(volMounts, tc.Spec.TiKV.AdditionalVolumeMounts...)
 	if tc.IsTLSClusterEnabled() {
 		volMounts = append(volMounts, corev1.VolumeMount{
@@ -362,6 +446,13 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			}},
 		},
 	}
+	if hugepages != nil {
+		vols = append(vols, corev1.Volume{
+			Name: "hugepages", VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: hugepages.StorageMedium()},
+			},
+		})
+	}
 	if tc.IsTLSClusterEnabled() {
 		vols = append(vols, corev1.Volume{
 			Name: "tikv-tls", VolumeSource: corev1.VolumeSource{
@@ -405,12 +496,13 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 					SecurityContext: &corev1.SecurityContext{
 						Privileged: &privileged,
 					},
+					EnvFrom: baseTiKVSpec.EnvFrom(),
 					// Init container resourceRequirements should be equal to app container.
 					// Scheduling is done based on effective requests/limits,
 					// which means init containers can reserve resources for
 					// initialization that are not used during the life of the Pod.
 					// ref:https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#resources
-					Resources: controller.ContainerResource(tc.Spec.TiKV.ResourceRequirements),
+					Resources: controller.ContainerResource(resources),
 				})
 			}
 		}
@@ -431,9 +523,12 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 	stsLabels := labelTiKV(tc)
 	podLabels := util.CombineStringMap(stsLabels.Labels(), baseTiKVSpec.Labels())
 	setName := controller.TiKVMemberName(tcName)
-	podAnnotations := util.CombineStringMap(controller.AnnProm(20180), baseTiKVSpec.Annotations())
+	serverPort, statusPort := tc.TiKVPorts()
+	podAnnotations := util.CombineStringMap(controller.AnnProm(statusPort), baseTiKVSpec.Annotations())
+	podAnnotations = util.CombineStringMap(podAnnotations, externalProvisioningAnnotations(baseTiKVSpec))
+	podAnnotations = util.CombineStringMap(podAnnotations, restartGenerationAnnotations(tc, v1alpha1.TiKVMemberType))
 	stsAnnotations := getStsAnnotations(tc.Annotations, label.TiKVLabelVal)
-	capacity := controller.TiKVCapacity(tc.Spec.TiKV.Limits)
+	capacity := controller.TiKVCapacity(resources.Limits)
 	headlessSvcName := controller.TiKVPeerMemberName(tcName)
 
 	deleteSlotsNumber, err := util.GetDeleteSlotsNumber(stsAnnotations)
@@ -484,6 +579,7 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			ImagePullPolicy: tc.HelperImagePullPolicy(),
 			Resources:       controller.ContainerResource(tc.Spec.TiKV.GetLogTailerSpec().ResourceRequirements),
 			VolumeMounts:    []corev1.VolumeMount{rocksDBLogVolumeMount},
+			EnvFrom:         baseTiKVSpec.EnvFrom(),
 			Command: []string{
 				"sh",
 				"-c",
@@ -533,6 +629,7 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			ImagePullPolicy: tc.HelperImagePullPolicy(),
 			Resources:       controller.ContainerResource(tc.Spec.TiKV.GetLogTailerSpec().ResourceRequirements),
 			VolumeMounts:    []corev1.VolumeMount{raftLogVolumeMount},
+			EnvFrom:         baseTiKVSpec.EnvFrom(),
 			Command: []string{
 				"sh",
 				"-c",
@@ -578,18 +675,19 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          "server",
-				ContainerPort: int32(20160),
+				ContainerPort: serverPort,
 				Protocol:      corev1.ProtocolTCP,
 			},
 		},
 		VolumeMounts: volMounts,
-		Resources:    controller.ContainerResource(tc.Spec.TiKV.ResourceRequirements),
+		Resources:    controller.ContainerResource(resources),
 	}
 
-	if tc.Spec.TiKV.EnableNamedStatusPort {
+	// the status port is declared for the scheduler to check the conflicts of the allocated host ports
+	if tc.Spec.TiKV.EnableNamedStatusPort || tc.TiKVHostPortsEnabled() {
 		kvStatusPort := corev1.ContainerPort{
 			Name:          "status",
-			ContainerPort: int32(20180),
+			ContainerPort: statusPort,
 			Protocol:      corev1.ProtocolTCP,
 		}
 
@@ -607,6 +705,21 @@ func getNewTiKVSetForTidbCluster(tc *v1alpha1.TidbCluster, cm *corev1.ConfigMap)
 			},
 		})
 	}
+	if tc.TiKVHostPortsEnabled() {
+		for i := range tikvContainer.Ports {
+			tikvContainer.Ports[i].HostPort = tikvContainer.Ports[i].ContainerPort
+		}
+		env = append(env, corev1.EnvVar{
+			Name: "HOST_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.hostIP",
+				},
+			},
+		})
+		podLabels[hostPortLabelKey(serverPort)] = "true"
+		podSpec.Affinity = hostPortsAntiAffinity(podSpec.Affinity, serverPort)
+	}
 	tikvContainer.Env = util.AppendEnv(env, baseTiKVSpec.Env())
 	tikvContainer.EnvFrom = baseTiKVSpec.EnvFrom()
 	containers = append(containers, tikvContainer)
@@ -720,6 +833,14 @@ func getTikVConfigMap(tc *v1alpha1.TidbCluster) (*corev1.ConfigMap, error) {
 		scriptModel.EnableAdvertiseStatusAddr = true
 	}
 
+	if tc.TiKVHostPortsEnabled() {
+		scriptModel.ServerPort, scriptModel.StatusPort = tc.TiKVPorts()
+		scriptModel.AdvertiseHostIP = true
+		if scriptModel.EnableAdvertiseStatusAddr {
+			scriptModel.AdvertiseStatusAddr = "${HOST_IP}"
+		}
+	}
+
 	scriptModel.PDAddress = tc.Scheme() + "://${CLUSTER_NAME}-pd:2379"
 	if tc.AcrossK8s() {
 		scriptModel.PDAddress = tc.Scheme() + "://${CLUSTER_NAME}-pd:2379" // get pd addr from discovery in startup script
@@ -771,14 +892,19 @@ func (m *tikvMemberManager) syncTiKVClusterStatus(tc *v1alpha1.TidbCluster, set
 		}
 	}
 
+	oldPhase := tc.Status.TiKV.Phase
 	// Scaling takes precedence over upgrading.
 	if tc.TiKVStsDesiredReplicas() != *set.Spec.Replicas {
 		tc.Status.TiKV.Phase = v1alpha1.ScalePhase
-	} else if upgrading && tc.Status.PD.Phase != v1alpha1.UpgradePhase {
+	} else if upgrading && !tc.ComponentUpgradeInProgress(v1alpha1.PDMemberType) {
 		tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
 	} else {
 		tc.Status.TiKV.Phase = v1alpha1.NormalPhase
 	}
+	tc.Status.TiKV.Phase = stabilizeTiKVAfterUpgrade(m.deps, tc, oldPhase, tc.Status.TiKV.Phase)
+	tc.Status.TiKV.Phase = finalizeUpgrade(m.deps, tc, v1alpha1.TiKVMemberType, oldPhase, tc.Status.TiKV.Phase, tc.Status.TiKV.StatefulSet)
+	recordUpgradeUpToDate(m.deps.Recorder, tc, v1alpha1.TiKVMemberType, oldPhase, tc.Status.TiKV.Phase, tc.Status.TiKV.StatefulSet)
+	syncUpgradeStatus(m.deps, tc, v1alpha1.TiKVMemberType, set, nil)
 
 	previousStores := tc.Status.TiKV.Stores
 	previousPeerStores := tc.Status.TiKV.PeerStores
@@ -787,8 +913,10 @@ func (m *tikvMemberManager) syncTiKVClusterStatus(tc *v1alpha1.TidbCluster, set
 	tombstoneStores := map[string]v1alpha1.TiKVStore{}
 
 	pdCli := controller.GetPDClient(m.deps.PDControl, tc)
+	var staleSince *metav1.Time
 	// This only returns Up/Down/Offline stores
 	storesInfo, err := pdCli.GetStores()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		if pdapi.IsTiKVNotBootstrappedError(err) {
 			klog.Infof("TiKV of Cluster %s/%s not bootstrapped yet", tc.Namespace, tc.Name)
@@ -800,7 +928,7 @@ func (m *tikvMemberManager) syncTiKVClusterStatus(tc *v1alpha1.TidbCluster, set
 		return err
 	}
 
-	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.Name, tc.Namespace, controller.FormatClusterDomainForRegex(tc.Spec.ClusterDomain)))
+	resolver, err := newTiKVStoreResolver(m.deps.PodLister, tc)
 	if err != nil {
 		return err
 	}
@@ -823,7 +951,8 @@ func (m *tikvMemberManager) syncTiKVClusterStatus(tc *v1alpha1.TidbCluster, set
 		// In theory, the external tikv can join the cluster, and the operator would only manage the internal tikv.
 		// So we check the store owner to make sure it.
 		if store.Store != nil {
-			if pattern.Match([]byte(store.Store.Address)) {
+			if podName, ok := resolver.podName(store.Store.Address); ok {
+				status.PodName = podName
 				stores[status.ID] = *status
 			} else if util.MatchLabelFromStoreLabels(store.Store.Labels, label.TiKVLabelVal) {
 				peerStores[status.ID] = *status
@@ -831,24 +960,54 @@ func (m *tikvMemberManager) syncTiKVClusterStatus(tc *v1alpha1.TidbCluster, set
 		}
 	}
 
+	// the ghost stores are not detected from the cached PD data
+	if staleSince == nil {
+		if err := syncTiKVGhostStores(m.deps, pdCli, tc, set, storesInfo); err != nil {
+			tc.Status.TiKV.Synced = false
+			return err
+		}
+		// the failure of tuning the scheduling of pd does not fail the sync of the tikv status
+		if err := syncPDSchedulingAutoTune(m.deps, pdCli, tc, storesInfo); err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] failed to tune the scheduling of pd: %v", tc.Namespace, tc.Name, err)
+		}
+		// the failure of mitigating the slow stores does not fail the sync of the tikv status either
+		if err := syncTiKVSlowStores(m.deps, pdCli, tc, storesInfo, stores); err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] failed to mitigate the slow stores of tikv: %v", tc.Namespace, tc.Name, err)
+		}
+		syncTiKVDivergentStores(m.deps, tc, storesInfo, stores)
+		tc.Status.TiKV.StorageUsage = SyncStorageUsage(m.deps.Recorder, tc, v1alpha1.TiKVMemberType.String(), tc.Status.TiKV.StorageUsage, storeStorageUsages(storesInfo, stores), tc.StorageWarningThreshold())
+	}
+
 	// this returns all tombstone stores
 	tombstoneStoresInfo, err := pdCli.GetTombStoneStores()
+	err = acceptStalePDData(err, &staleSince)
 	if err != nil {
 		tc.Status.TiKV.Synced = false
 		return err
 	}
 	for _, store := range tombstoneStoresInfo.Stores {
-		if store.Store != nil && !pattern.Match([]byte(store.Store.Address)) {
+		if store.Store == nil {
+			continue
+		}
+		podName, ok := resolver.podName(store.Store.Address)
+		if !ok {
 			continue
 		}
 		status := getTiKVStore(store)
 		if status == nil {
 			continue
 		}
+		status.PodName = podName
 		tombstoneStores[status.ID] = *status
 	}
 
+	if err := syncTiKVPodHostPorts(m.deps.PodLister, tc); err != nil {
+		tc.Status.TiKV.Synced = false
+		return err
+	}
+
 	tc.Status.TiKV.Synced = true
+	tc.Status.TiKV.StaleSince = staleSince
 	tc.Status.TiKV.Stores = stores
 	tc.Status.TiKV.PeerStores = peerStores
 	tc.Status.TiKV.TombstoneStores = tombstoneStores
@@ -867,17 +1026,22 @@ func getTiKVStore(store *pdapi.StoreInfo) *v1alpha1.TiKVStore {
 	}
 	storeID := fmt.Sprintf("%d", store.Store.GetId())
 	ip := strings.Split(store.Store.GetAddress(), ":")[0]
-	podName := strings.Split(ip, ".")[0]
 
 	return &v1alpha1.TiKVStore{
 		ID:          storeID,
-		PodName:     podName,
+		PodName:     tikvStorePodName(store.Store.GetAddress()),
 		IP:          ip,
 		LeaderCount: int32(store.Status.LeaderCount),
 		State:       store.Store.StateName,
 	}
 }
 
+// tikvStorePodName returns the pod name in the address of the store in the peer service
+func tikvStorePodName(address string) string {
+	ip := strings.Split(address, ":")[0]
+	return strings.Split(ip, ".")[0]
+}
+
 func (m *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TidbCluster) (int, error) {
 	if m.deps.NodeLister == nil {
 		klog.V(4).Infof("Node lister is unavailable, skip setting store labels for TiKV of TiDB cluster %s/%s. This may be caused by no relevant permissions", tc.Namespace, tc.Name)
@@ -909,21 +1073,23 @@ func (m *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TidbCluster) (int
 		return setCount, nil
 	}
 
-	pattern, err := regexp.Compile(fmt.Sprintf(tikvStoreLimitPattern, tc.Name, tc.Name, tc.Namespace, controller.FormatClusterDomainForRegex(tc.Spec.ClusterDomain)))
+	resolver, err := newTiKVStoreResolver(m.deps.PodLister, tc)
 	if err != nil {
 		return -1, err
 	}
 	for _, store := range storesInfo.Stores {
 		// In theory, the external tikv can join the cluster, and the operator would only manage the internal tikv.
 		// So we check the store owner to make sure it.
-		if store.Store != nil && !pattern.Match([]byte(store.Store.Address)) {
+		if store.Store == nil {
 			continue
 		}
-		status := getTiKVStore(store)
-		if status == nil {
+		podName, ok := resolver.podName(store.Store.Address)
+		if !ok {
+			continue
+		}
+		if getTiKVStore(store) == nil {
 			continue
 		}
-		podName := status.PodName
 
 		pod, err := m.deps.PodLister.Pods(ns).Get(podName)
 		if err != nil {
@@ -942,7 +1108,7 @@ func (m *tikvMemberManager) setStoreLabelsForTiKV(tc *v1alpha1.TidbCluster) (int
 			if err != nil {
 				msg := fmt.Sprintf("failed to set labels %v for store (id: %d, pod: %s/%s): %v ",
 					ls, store.Store.Id, ns, podName, err)
-				m.deps.Recorder.Event(tc, corev1.EventTypeWarning, FailedSetStoreLabels, msg)
+				m.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.FailedSetStoreLabels, msg)
 				continue
 			}
 			if set {
@@ -995,6 +1161,43 @@ func tikvStatefulSetIsUpgrading(podLister corelisters.PodLister, pdControl pdapi
 	return false, nil
 }
 
+// setTiKVTerminationGracePeriodSeconds sets the termination grace period of the TiKV pods derived from the leader
+// counts of the stores if spec.tikv.maxTerminationGracePeriodSeconds is set. The applied period is kept unless the
+// template changes otherwise, so that the changes of the leader counts do not roll the pods by themselves, and the
+// new period is in the template updated together with the partition of the upgrade.
+func setTiKVTerminationGracePeriodSeconds(tc *v1alpha1.TidbCluster, oldSet, newSet *apps.StatefulSet) {
+	limit := tc.Spec.TiKV.MaxTerminationGracePeriodSeconds
+	if limit == nil || tc.BaseTiKVSpec().TerminationGracePeriodSeconds() != nil {
+		return
+	}
+	podSpec := &newSet.Spec.Template.Spec
+	if oldSet != nil {
+		if _, oldPodSpec, err := GetLastAppliedConfig(oldSet); err == nil {
+			podSpec.TerminationGracePeriodSeconds = oldPodSpec.TerminationGracePeriodSeconds
+			if templateEqual(newSet, oldSet) {
+				return
+			}
+		}
+	}
+	podSpec.TerminationGracePeriodSeconds = tikvTerminationGracePeriodSeconds(tc, *limit)
+}
+
+// tikvTerminationGracePeriodSeconds returns the termination grace period covering the shutdown of the store with
+// the most leaders, one second per tikvLeadersPerGraceSecond leaders on top of the default period, bounded by limit
+func tikvTerminationGracePeriodSeconds(tc *v1alpha1.TidbCluster, limit int64) *int64 {
+	var maxLeaderCount int32
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.LeaderCount > maxLeaderCount {
+			maxLeaderCount = store.LeaderCount
+		}
+	}
+	seconds := defaultTerminationGracePeriodSeconds + int64(maxLeaderCount)/tikvLeadersPerGraceSecond
+	if seconds > limit {
+		seconds = limit
+	}
+	return &seconds
+}
+
 type FakeTiKVMemberManager struct {
 	err error
 }
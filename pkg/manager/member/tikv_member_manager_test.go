@@ -26,11 +26,13 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/apis/util/toml"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -388,6 +390,29 @@ func TestTiKVMemberManagerSyncUpdate(t *testing.T) {
 			},
 			expectTidbClusterFn: nil,
 		},
+		{
+			name: "tikv is paused",
+			modify: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.Replicas = 5
+				tc.Spec.TiKV.Paused = true
+				tc.Status.PD.Phase = v1alpha1.NormalPhase
+			},
+			pdStores:                     &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}},
+			tombstoneStores:              &pdapi.StoresInfo{Count: 0, Stores: []*pdapi.StoreInfo{}},
+			errWhenUpdateStatefulSet:     false,
+			errWhenUpdateTiKVPeerService: false,
+			errWhenGetStores:             false,
+			err:                          false,
+			expectTiKVPeerServiceFn:      nil,
+			expectStatefulSetFn: func(g *GomegaWithT, set *apps.StatefulSet, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(int(*set.Spec.Replicas)).To(Equal(3))
+			},
+			expectTidbClusterFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster) {
+				g.Expect(tc.Status.TiKV.StatefulSet.ObservedGeneration).To(Equal(int64(1)))
+				g.Expect(meta.IsStatusConditionTrue(tc.Status.TiKV.Conditions, v1alpha1.ComponentPaused)).To(BeTrue())
+			},
+		},
 	}
 
 	for i := range tests {
@@ -2048,6 +2073,160 @@ func TestGetNewTiKVSetForTidbCluster(t *testing.T) {
 				g.Expect(sts.Spec.Template.Spec.Containers[1].Command[2]).To(ContainSubstring("raftdb.info"))
 			},
 		},
+		{
+			name: "tikv spec envFrom",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD:   &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{},
+					TiKV: &v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							EnvFrom: []corev1.EnvFromSource{
+								{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+							},
+						},
+						SeparateRocksDBLog: pointer.BoolPtr(true),
+						SeparateRaftLog:    pointer.BoolPtr(true),
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				// envFrom is rendered in the log tailers and tikv container
+				g.Expect(sts.Spec.Template.Spec.Containers).To(HaveLen(3))
+				for _, c := range sts.Spec.Template.Spec.Containers {
+					g.Expect(c.EnvFrom).To(Equal([]corev1.EnvFromSource{
+						{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "license"}}},
+					}), c.Name)
+				}
+			},
+		},
+		{
+			name: "tikv host ports are allocated",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD:   &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{},
+					TiKV: &v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							HostNetwork: &enable,
+						},
+						Config:    v1alpha1.NewTiKVConfig(),
+						HostPorts: &v1alpha1.HostPortAllocation{},
+					},
+				},
+				Status: v1alpha1.TidbClusterStatus{
+					TiKV: v1alpha1.TiKVStatus{HostPorts: &v1alpha1.HostPorts{Server: 20164, Status: 20165}},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				podSpec := sts.Spec.Template.Spec
+				g.Expect(podSpec.DNSPolicy).To(Equal(corev1.DNSClusterFirstWithHostNet))
+				g.Expect(podSpec.Containers[0].Ports).To(Equal([]corev1.ContainerPort{
+					{Name: "server", ContainerPort: 20164, HostPort: 20164, Protocol: corev1.ProtocolTCP},
+					{Name: "status", ContainerPort: 20165, HostPort: 20165, Protocol: corev1.ProtocolTCP},
+				}))
+				g.Expect(podSpec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+					Name:      "HOST_IP",
+					ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"}},
+				}))
+				g.Expect(sts.Spec.Template.Labels).To(HaveKeyWithValue("tidb.pingcap.com/host-port-20164", "true"))
+				g.Expect(sts.Spec.Template.Annotations).To(HaveKeyWithValue("prometheus.io/port", "20165"))
+				terms := podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+				g.Expect(terms).To(HaveLen(1))
+				g.Expect(terms[0].TopologyKey).To(Equal(corev1.LabelHostname))
+				g.Expect(terms[0].LabelSelector.MatchExpressions[0].Key).To(Equal("tidb.pingcap.com/host-port-20164"))
+			},
+		},
+		{
+			name: "tikv resources derived from sizing",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD:   &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{},
+					TiKV: &v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							Sizing: &v1alpha1.Sizing{
+								Size: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("4"),
+									corev1.ResourceMemory: resource.MustParse("16Gi"),
+								},
+								SizeIs:        v1alpha1.SizeIsLimits,
+								RequestsRatio: pointer.StringPtr("0.5"),
+							},
+						},
+						ResourceRequirements: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceStorage: resource.MustParse("100Gi"),
+							},
+						},
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				resources := sts.Spec.Template.Spec.Containers[0].Resources
+				g.Expect(resources.Limits.Cpu().String()).To(Equal("4"))
+				g.Expect(resources.Limits.Memory().String()).To(Equal("16Gi"))
+				g.Expect(resources.Requests.Cpu().String()).To(Equal("2"))
+				g.Expect(resources.Requests.Memory().String()).To(Equal("8Gi"))
+				g.Expect(resources.Requests).NotTo(HaveKey(corev1.ResourceStorage))
+				g.Expect(sts.Spec.Template.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CAPACITY", Value: "0"}))
+			},
+		},
+		{
+			name: "tikv hugepages",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tc",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					PD:   &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{},
+					TiKV: &v1alpha1.TiKVSpec{
+						ResourceRequirements: corev1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("8"),
+								corev1.ResourceMemory: resource.MustParse("32Gi"),
+							},
+						},
+						CPUPinning: true,
+						Hugepages: &v1alpha1.TiKVHugepages{
+							Amount: resource.MustParse("4Gi"),
+						},
+					},
+				},
+			},
+			testSts: func(sts *apps.StatefulSet) {
+				g := NewGomegaWithT(t)
+				podSpec := sts.Spec.Template.Spec
+				resources := podSpec.Containers[0].Resources
+				g.Expect(resources.Limits.Cpu().String()).To(Equal("8"))
+				g.Expect(resources.Limits).To(HaveKeyWithValue(corev1.ResourceName("hugepages-2Mi"), resource.MustParse("4Gi")))
+				g.Expect(resources.Requests).To(HaveKeyWithValue(corev1.ResourceName("hugepages-2Mi"), resource.MustParse("4Gi")))
+				g.Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "hugepages", MountPath: "/dev/hugepages"}))
+				g.Expect(podSpec.Volumes).To(ContainElement(corev1.Volume{
+					Name: "hugepages",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{Medium: "HugePages-2Mi"},
+					},
+				}))
+			},
+		},
 		// TODO add more tests
 	}
 
@@ -2494,6 +2673,67 @@ func TestGetTiKVConfigMap(t *testing.T) {
 [raftstore]
   sync-log = false
   raft-base-tick-interval = "1s"
+`,
+				},
+			},
+		},
+		{
+			name: "cpu pinning",
+			tc: v1alpha1.TidbCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.TidbClusterSpec{
+					TiKV: &v1alpha1.TiKVSpec{
+						ComponentSpec: v1alpha1.ComponentSpec{
+							ConfigUpdateStrategy: &updateStrategy,
+							Sizing: &v1alpha1.Sizing{
+								Size: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("10"),
+									corev1.ResourceMemory: resource.MustParse("40Gi"),
+								},
+							},
+						},
+						CPUPinning: true,
+						Config: mustTiKVConfig(&v1alpha1.TiKVConfig{
+							Storage: &v1alpha1.TiKVStorageConfig{
+								SchedulerWorkerPoolSize: pointer.Int64Ptr(2),
+							},
+						}),
+					},
+					PD:   &v1alpha1.PDSpec{},
+					TiDB: &v1alpha1.TiDBSpec{},
+				},
+			},
+			expected: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-tikv",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app.kubernetes.io/name":       "tidb-cluster",
+						"app.kubernetes.io/managed-by": "tidb-operator",
+						"app.kubernetes.io/instance":   "foo",
+						"app.kubernetes.io/component":  "tikv",
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion:         "pingcap.com/v1alpha1",
+							Kind:               "TidbCluster",
+							Name:               "foo",
+							Controller:         pointer.BoolPtr(true),
+							BlockOwnerDeletion: pointer.BoolPtr(true),
+						},
+					},
+				},
+				Data: map[string]string{
+					"startup-script": "",
+					"config-file": `[readpool]
+  [readpool.unified]
+    max-thread-count = 8
+
+[storage]
+  scheduler-worker-pool-size = 2
 `,
 				},
 			},
@@ -2632,3 +2872,59 @@ func mustTiKVConfig(x interface{}) *v1alpha1.TiKVConfigWraper {
 
 	return c
 }
+
+func TestSetTiKVTerminationGracePeriodSeconds(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := newTidbClusterForTiKV()
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", LeaderCount: 50000},
+		"2": {ID: "2", LeaderCount: 120000},
+	}
+	newSet := func(image string) *apps.StatefulSet {
+		return &apps.StatefulSet{
+			Spec: apps.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "tikv", Image: image}}},
+				},
+			},
+		}
+	}
+
+	// the period is left as it is without the limit
+	set := newSet("tikv:v1")
+	setTiKVTerminationGracePeriodSeconds(tc, nil, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(BeNil())
+
+	// the period covers the store with the most leaders when the statefulset is created
+	tc.Spec.TiKV.MaxTerminationGracePeriodSeconds = pointer.Int64Ptr(600)
+	setTiKVTerminationGracePeriodSeconds(tc, nil, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(150)))
+	g.Expect(mngerutils.SetStatefulSetLastAppliedConfigAnnotation(set)).To(Succeed())
+	oldSet := set
+
+	// the applied period is kept if the template does not change otherwise
+	tc.Status.TiKV.Stores["2"] = v1alpha1.TiKVStore{ID: "2", LeaderCount: 300000}
+	set = newSet("tikv:v1")
+	setTiKVTerminationGracePeriodSeconds(tc, oldSet, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(150)))
+	g.Expect(templateEqual(set, oldSet)).To(BeTrue())
+
+	// the period is computed again for the upgrade
+	set = newSet("tikv:v2")
+	setTiKVTerminationGracePeriodSeconds(tc, oldSet, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(330)))
+
+	// the period is bounded by the limit
+	tc.Spec.TiKV.MaxTerminationGracePeriodSeconds = pointer.Int64Ptr(200)
+	set = newSet("tikv:v2")
+	setTiKVTerminationGracePeriodSeconds(tc, oldSet, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(200)))
+
+	// the period set explicitly takes precedence
+	tc.Spec.TiKV.TerminationGracePeriodSeconds = pointer.Int64Ptr(60)
+	set = newSet("tikv:v2")
+	set.Spec.Template.Spec.TerminationGracePeriodSeconds = pointer.Int64Ptr(60)
+	setTiKVTerminationGracePeriodSeconds(tc, oldSet, set)
+	g.Expect(set.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(pointer.Int64Ptr(60)))
+}
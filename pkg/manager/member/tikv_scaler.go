@@ -21,6 +21,8 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -47,6 +49,9 @@ func (s *tikvScaler) Scale(meta metav1.Object, oldSet *apps.StatefulSet, newSet
 	} else if scaling < 0 {
 		return s.ScaleIn(meta, oldSet, newSet)
 	}
+	if tc, ok := meta.(*v1alpha1.TidbCluster); ok {
+		tc.Status.TiKV.VolumePreProvisioning = nil
+	}
 	// we only sync auto scaler annotations when we are finishing syncing scaling
 	return nil
 }
@@ -59,15 +64,27 @@ func (s *tikvScaler) ScaleOut(meta metav1.Object, oldSet *apps.StatefulSet, newS
 		return fmt.Errorf("cluster[%s/%s] can't conver to runtime.Object", meta.GetNamespace(), meta.GetName())
 	}
 	klog.Infof("scaling out tikv statefulset %s/%s, ordinal: %d (replicas: %d, delete slots: %v)", oldSet.Namespace, oldSet.Name, ordinal, replicas, deleteSlots.List())
-	var pvcName string
-	switch meta.(type) {
-	case *v1alpha1.TidbCluster:
-		pvcName = fmt.Sprintf("tikv-%s-tikv-%d", meta.GetName(), ordinal)
-	default:
+	tc, ok := meta.(*v1alpha1.TidbCluster)
+	if !ok {
 		return fmt.Errorf("tikv.ScaleOut, failed to convert cluster %s/%s", meta.GetNamespace(), meta.GetName())
 	}
-	_, err := s.deps.PVCLister.PersistentVolumeClaims(meta.GetNamespace()).Get(pvcName)
+	pvcName := fmt.Sprintf("tikv-%s-tikv-%d", meta.GetName(), ordinal)
+	pvc, err := s.deps.PVCLister.PersistentVolumeClaims(meta.GetNamespace()).Get(pvcName)
 	if err == nil {
+		_, preProvisioned := pvc.Annotations[label.AnnPVCPreProvisionedZone]
+		_, deferDeleting := pvc.Annotations[label.AnnPVCDeferDeleting]
+		if preProvisioned && !deferDeleting {
+			// the replicas are increased after the pre-provisioned volumes are bound in the planned zone
+			bound, err := s.preProvisionedVolumesBound(tc, newSet, ordinal)
+			if err != nil {
+				return err
+			}
+			if !bound {
+				return controller.RequeueErrorf("tikv.ScaleOut, cluster %s/%s wait for the pre-provisioned pvcs of ordinal %d to be bound", meta.GetNamespace(), meta.GetName(), ordinal)
+			}
+			setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+			return nil
+		}
 		_, err = s.deleteDeferDeletingPVC(obj, v1alpha1.TiKVMemberType, ordinal)
 		if err != nil {
 			return err
@@ -76,6 +93,15 @@ func (s *tikvScaler) ScaleOut(meta metav1.Object, oldSet *apps.StatefulSet, newS
 	} else if !errors.IsNotFound(err) {
 		return fmt.Errorf("tikv.ScaleOut, cluster %s/%s failed to fetch pvc informaiton, err:%v", meta.GetNamespace(), meta.GetName(), err)
 	}
+	if tc.Spec.TiKV != nil && tc.Spec.TiKV.PreProvisionVolumes {
+		provisioned, err := s.preProvisionVolumes(tc, newSet, ordinal)
+		if err != nil {
+			return err
+		}
+		if provisioned {
+			return controller.RequeueErrorf("tikv.ScaleOut, cluster %s/%s pre-provisioned the pvcs of ordinal %d, wait for them to be bound", meta.GetNamespace(), meta.GetName(), ordinal)
+		}
+	}
 	setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
 	return nil
 }
@@ -119,7 +145,7 @@ func (s *tikvScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 			if state != v1alpha1.TiKVStateOffline {
 				if err := controller.GetPDClient(s.deps.PDControl, tc).DeleteStore(id); err != nil {
 					klog.Errorf("tikvScaler.ScaleIn: failed to delete store %d, %v", id, err)
-					return err
+					return requeueIfPDCircuitOpen(err)
 				}
 				klog.Infof("tikvScaler.ScaleIn: delete store %d for tikv %s/%s successfully", id, ns, podName)
 			}
@@ -154,7 +180,7 @@ func (s *tikvScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 					return err
 				}
 
-				setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+				s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 				return nil
 			} else {
 				klog.Warningf("TiKV %s/%s store %s in status is not equal with store %s in label",
@@ -197,7 +223,7 @@ func (s *tikvScaler) ScaleIn(meta metav1.Object, oldSet *apps.StatefulSet, newSe
 			}
 		}
 
-		setReplicasAndDeleteSlots(newSet, replicas, deleteSlots)
+		s.setScaleInReplicas(meta, newSet, replicas, deleteSlots)
 		return nil
 	}
 	return fmt.Errorf("TiKV %s/%s not found in cluster", ns, podName)
@@ -215,6 +241,9 @@ func (s *tikvScaler) preCheckUpStores(tc *v1alpha1.TidbCluster, podName string)
 
 	storesInfo, err := pdClient.GetStores()
 	if err != nil {
+		if pdapi.IsCircuitOpen(err) {
+			return false, requeueIfPDCircuitOpen(err)
+		}
 		return false, fmt.Errorf("failed to get stores info in TidbCluster %s/%s", tc.GetNamespace(), tc.GetName())
 	}
 	// filter out TiFlash
@@ -236,19 +265,19 @@ func (s *tikvScaler) preCheckUpStores(tc *v1alpha1.TidbCluster, podName string)
 
 	config, err := pdClient.GetConfig()
 	if err != nil {
-		return false, err
+		return false, requeueIfPDCircuitOpen(err)
 	}
 	maxReplicas := *(config.Replication.MaxReplicas)
 	if upNumber < int(maxReplicas) {
 		errMsg := fmt.Sprintf("the number of stores in Up state of TidbCluster [%s/%s] is %d, less than MaxReplicas in PD configuration(%d), can't scale in TiKV, podname %s ", tc.GetNamespace(), tc.GetName(), upNumber, maxReplicas, podName)
 		klog.Error(errMsg)
-		s.deps.Recorder.Event(tc, v1.EventTypeWarning, "FailedScaleIn", errMsg)
+		s.deps.Recorder.Event(tc, v1.EventTypeWarning, events.FailedScaleIn, errMsg)
 		return false, nil
 	} else if upNumber == int(maxReplicas) {
 		if storeState == v1alpha1.TiKVStateUp {
 			errMsg := fmt.Sprintf("can't scale in TiKV of TidbCluster [%s/%s], cause the number of up stores is equal to MaxReplicas in PD configuration(%d), and the store in Pod %s which is going to be deleted is up too", tc.GetNamespace(), tc.GetName(), maxReplicas, podName)
 			klog.Error(errMsg)
-			s.deps.Recorder.Event(tc, v1.EventTypeWarning, "FailedScaleIn", errMsg)
+			s.deps.Recorder.Event(tc, v1.EventTypeWarning, events.FailedScaleIn, errMsg)
 			return false, nil
 		}
 	}
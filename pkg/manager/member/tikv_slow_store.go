@@ -0,0 +1,312 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultSlowScoreThreshold           = 80
+	defaultSlowStoreConsecutiveChecks   = 3
+	defaultSlowStoreLeaderWeightPercent = 10
+	// maxLeaderWeightReducedStores is the max number of the stores whose leader weights are reduced at the same
+	// time, a slow disk is usually on one store and the slowness of many stores is not mitigated by moving the
+	// leaders among them
+	maxLeaderWeightReducedStores = 1
+)
+
+// slowStoreCheckInterval is the min interval between two checks of a store, so that the consecutive checks
+// span some time however often the cluster is synced
+var slowStoreCheckInterval = 30 * time.Second
+
+// slowStoreLeaderWeight is the leader weight of a store before and after it is reduced
+type slowStoreLeaderWeight struct {
+	Original float64 `json:"original"`
+	Reduced  float64 `json:"reduced"`
+}
+
+// syncTiKVSlowStores checks the stores of the cluster by spec.tikv.slowStoreMitigation. A check of a store is slow
+// if its slow score or the cause value of its slow trend reaches the threshold, the store is slow after the
+// consecutive slow checks and recovers after the consecutive normal checks. If reduceLeaderWeight is enabled, the
+// leader weight of a slow store is reduced and the original weight is restored after the store recovers or the
+// mitigation is disabled. The leader weights are recorded in label.AnnTiKVSlowStoreLeaderWeights before they are
+// reduced, and a leader weight changed by the user after it is reduced is not restored.
+func syncTiKVSlowStores(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, storesInfo *pdapi.StoresInfo, stores map[string]v1alpha1.TiKVStore) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	mitigation := tc.Spec.TiKV.SlowStoreMitigation
+
+	weights := map[string]slowStoreLeaderWeight{}
+	if data, ok := tc.Annotations[label.AnnTiKVSlowStoreLeaderWeights]; ok {
+		if err := json.Unmarshal([]byte(data), &weights); err != nil {
+			klog.Warningf("tidbcluster: [%s/%s] ignore the invalid annotation %s: %v", ns, tcName, label.AnnTiKVSlowStoreLeaderWeights, err)
+			weights = map[string]slowStoreLeaderWeight{}
+		}
+	}
+	if mitigation == nil && len(weights) == 0 {
+		tc.Status.TiKV.SlowStores = nil
+		metrics.TiKVSlowStores.DeleteLabelValues(ns, tcName)
+		return nil
+	}
+
+	infos := map[string]*pdapi.StoreInfo{}
+	for _, store := range storesInfo.Stores {
+		if store.Store == nil || store.Status == nil {
+			continue
+		}
+		infos[strconv.FormatUint(store.Store.GetId(), 10)] = store
+	}
+
+	now := metav1.Now()
+	slowStores := map[string]v1alpha1.TiKVSlowStore{}
+	if mitigation != nil {
+		for _, id := range sets.StringKeySet(stores).List() {
+			info, ok := infos[id]
+			if !ok {
+				continue
+			}
+			slowStores[id] = checkSlowStore(deps, tc, mitigation, id, stores[id].PodName, tc.Status.TiKV.SlowStores[id], info.Status, now)
+		}
+	}
+	reduceLeaderWeight := mitigation != nil && mitigation.ReduceLeaderWeight
+
+	var errs []error
+	changed := false
+	for _, id := range sets.StringKeySet(weights).List() {
+		if reduceLeaderWeight && slowStores[id].Slow {
+			continue
+		}
+		info, ok := infos[id]
+		if !ok {
+			klog.Infof("tidbcluster: [%s/%s] store %s whose leader weight is reduced is deleted", ns, tcName, id)
+		} else if err := restoreSlowStoreLeaderWeight(deps, pdCli, tc, info, weights[id]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(weights, id)
+		changed = true
+	}
+
+	if reduceLeaderWeight {
+		percent := int32(defaultSlowStoreLeaderWeightPercent)
+		if mitigation.LeaderWeightPercent != nil {
+			percent = *mitigation.LeaderWeightPercent
+		}
+		for _, id := range sets.StringKeySet(slowStores).List() {
+			if len(weights) >= maxLeaderWeightReducedStores {
+				break
+			}
+			if _, ok := weights[id]; ok || !slowStores[id].Slow || infos[id].Store.StateName != v1alpha1.TiKVStateUp {
+				continue
+			}
+			status := infos[id].Status
+			weight := slowStoreLeaderWeight{Original: status.LeaderWeight, Reduced: status.LeaderWeight * float64(percent) / 100}
+			// the weights are recorded before the leader weight is reduced, so that it is restored even if the
+			// operator restarts right after reducing it
+			weights[id] = weight
+			if err := patchSlowStoreLeaderWeights(deps, tc, weights); err != nil {
+				delete(weights, id)
+				errs = append(errs, err)
+				break
+			}
+			if err := reduceSlowStoreLeaderWeight(deps, pdCli, tc, infos[id], weight); err != nil {
+				delete(weights, id)
+				changed = true
+				errs = append(errs, err)
+			}
+		}
+	}
+	if changed {
+		if err := patchSlowStoreLeaderWeights(deps, tc, weights); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for id, s := range slowStores {
+		s.OriginalLeaderWeight = ""
+		s.ReducedLeaderWeight = ""
+		slowStores[id] = s
+	}
+	for id, weight := range weights {
+		s, ok := slowStores[id]
+		if !ok {
+			s.PodName = stores[id].PodName
+		}
+		s.OriginalLeaderWeight = formatFloat(weight.Original)
+		s.ReducedLeaderWeight = formatFloat(weight.Reduced)
+		slowStores[id] = s
+	}
+	slow := 0
+	for id, s := range slowStores {
+		if s.Slow {
+			slow++
+		} else if s.SlowChecks == 0 && s.OriginalLeaderWeight == "" {
+			delete(slowStores, id)
+		}
+	}
+	if len(slowStores) == 0 {
+		slowStores = nil
+	}
+	tc.Status.TiKV.SlowStores = slowStores
+	metrics.TiKVSlowStores.WithLabelValues(ns, tcName).Set(float64(slow))
+	return errorutils.NewAggregate(errs)
+}
+
+// checkSlowStore checks the store if it is not checked in slowStoreCheckInterval and returns its new state
+func checkSlowStore(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, mitigation *v1alpha1.TiKVSlowStoreMitigation,
+	id, podName string, s v1alpha1.TiKVSlowStore, status *pdapi.StoreStatus, now metav1.Time) v1alpha1.TiKVSlowStore {
+	s.PodName = podName
+	if !s.LastCheckTime.IsZero() && now.Sub(s.LastCheckTime.Time) < slowStoreCheckInterval {
+		return s
+	}
+	s.LastCheckTime = now
+	s.SlowScore = status.SlowScore
+
+	if isSlowStoreCheck(mitigation, status) {
+		s.SlowChecks++
+		s.NormalChecks = 0
+	} else {
+		s.SlowChecks = 0
+		if s.Slow {
+			s.NormalChecks++
+		}
+	}
+
+	checks := int32(defaultSlowStoreConsecutiveChecks)
+	if mitigation.ConsecutiveChecks != nil {
+		checks = *mitigation.ConsecutiveChecks
+	}
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	switch {
+	case !s.Slow && s.SlowChecks >= checks:
+		s.Slow = true
+		s.LastTransitionTime = now
+		klog.Warningf("tidbcluster: [%s/%s] store %s of pod %s is slow for %d checks, slow score: %d", ns, tcName, id, podName, s.SlowChecks, s.SlowScore)
+		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.SlowStoreDetected, "store %s of pod %s is slow for %d checks, slow score: %d", id, podName, s.SlowChecks, s.SlowScore)
+		metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionDetect, metrics.SlowStoreActionSucceeded).Inc()
+	case s.Slow && s.NormalChecks >= checks:
+		s.Slow = false
+		s.NormalChecks = 0
+		s.LastTransitionTime = now
+		klog.Infof("tidbcluster: [%s/%s] slow store %s of pod %s recovers", ns, tcName, id, podName)
+		deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.SlowStoreRecovered, "store %s of pod %s recovers after %d normal checks", id, podName, checks)
+		metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionRecover, metrics.SlowStoreActionSucceeded).Inc()
+	}
+	return s
+}
+
+// isSlowStoreCheck returns true if the slow score or the cause value of the slow trend of the store reaches
+// the threshold, the slow score is 0 if it is not reported by TiKV
+func isSlowStoreCheck(mitigation *v1alpha1.TiKVSlowStoreMitigation, status *pdapi.StoreStatus) bool {
+	threshold := uint64(defaultSlowScoreThreshold)
+	if mitigation.SlowScoreThreshold != nil {
+		threshold = uint64(*mitigation.SlowScoreThreshold)
+	}
+	if status.SlowScore >= threshold {
+		return true
+	}
+	return mitigation.SlowTrendCauseThreshold != nil && status.SlowTrend != nil &&
+		status.SlowTrend.CauseValue >= float64(*mitigation.SlowTrendCauseThreshold)
+}
+
+// reduceSlowStoreLeaderWeight reduces the leader weight of the store and keeps its region weight
+func reduceSlowStoreLeaderWeight(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, info *pdapi.StoreInfo, weight slowStoreLeaderWeight) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	id := info.Store.GetId()
+	if err := pdCli.SetStoreWeight(id, weight.Reduced, info.Status.RegionWeight); err != nil {
+		klog.Errorf("tidbcluster: [%s/%s] failed to reduce the leader weight of slow store %d, error: %v", ns, tcName, id, err)
+		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.FailedSetStoreWeight, "failed to reduce the leader weight of slow store %d: %v", id, err)
+		metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionReduceLeaderWeight, metrics.SlowStoreActionFailed).Inc()
+		return err
+	}
+	klog.Infof("tidbcluster: [%s/%s] reduced the leader weight of slow store %d from %s to %s", ns, tcName, id, formatFloat(weight.Original), formatFloat(weight.Reduced))
+	deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.SlowStoreLeaderWeightReduced, "the leader weight of slow store %d is reduced from %s to %s",
+		id, formatFloat(weight.Original), formatFloat(weight.Reduced))
+	metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionReduceLeaderWeight, metrics.SlowStoreActionSucceeded).Inc()
+	return nil
+}
+
+// restoreSlowStoreLeaderWeight restores the original leader weight of the store unless the leader weight is
+// changed by the user after it is reduced
+func restoreSlowStoreLeaderWeight(deps *controller.Dependencies, pdCli pdapi.PDClient, tc *v1alpha1.TidbCluster, info *pdapi.StoreInfo, weight slowStoreLeaderWeight) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	id := info.Store.GetId()
+	if info.Status.LeaderWeight != weight.Reduced {
+		klog.Infof("tidbcluster: [%s/%s] leader weight of store %d is changed by the user from %s to %s, skip restoring it",
+			ns, tcName, id, formatFloat(weight.Reduced), formatFloat(info.Status.LeaderWeight))
+		return nil
+	}
+	if err := pdCli.SetStoreWeight(id, weight.Original, info.Status.RegionWeight); err != nil {
+		klog.Errorf("tidbcluster: [%s/%s] failed to restore the leader weight of store %d, error: %v", ns, tcName, id, err)
+		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.FailedSetStoreWeight, "failed to restore the leader weight of store %d: %v", id, err)
+		metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionRestoreLeaderWeight, metrics.SlowStoreActionFailed).Inc()
+		return err
+	}
+	klog.Infof("tidbcluster: [%s/%s] restored the leader weight of store %d to %s", ns, tcName, id, formatFloat(weight.Original))
+	deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.SlowStoreLeaderWeightRestored, "the leader weight of store %d is restored to %s", id, formatFloat(weight.Original))
+	metrics.TiKVSlowStoreMitigations.WithLabelValues(ns, tcName, metrics.SlowStoreActionRestoreLeaderWeight, metrics.SlowStoreActionSucceeded).Inc()
+	return nil
+}
+
+// patchSlowStoreLeaderWeights records the leader weights in label.AnnTiKVSlowStoreLeaderWeights, the annotation
+// is removed if there are no leader weights
+func patchSlowStoreLeaderWeights(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, weights map[string]slowStoreLeaderWeight) error {
+	var value *string
+	if len(weights) > 0 {
+		data, err := json.Marshal(weights)
+		if err != nil {
+			return err
+		}
+		s := string(data)
+		value = &s
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{label.AnnTiKVSlowStoreLeaderWeights: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := deps.TiDBClusterControl.Patch(tc, patch); err != nil {
+		return err
+	}
+	if value == nil {
+		delete(tc.Annotations, label.AnnTiKVSlowStoreLeaderWeights)
+		return nil
+	}
+	if tc.Annotations == nil {
+		tc.Annotations = map[string]string{}
+	}
+	tc.Annotations[label.AnnTiKVSlowStoreLeaderWeights] = *value
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestSyncTiKVSlowStores(t *testing.T) {
+	g := NewGomegaWithT(t)
+	defer func(interval time.Duration) { slowStoreCheckInterval = interval }(slowStoreCheckInterval)
+	slowStoreCheckInterval = 0
+
+	newStore := func(id uint64, slowScore uint64) *pdapi.StoreInfo {
+		return &pdapi.StoreInfo{
+			Store:  &pdapi.MetaStore{Store: &metapb.Store{Id: id}, StateName: v1alpha1.TiKVStateUp},
+			Status: &pdapi.StoreStatus{LeaderWeight: 1, RegionWeight: 2, SlowScore: slowScore},
+		}
+	}
+	storesInfo := &pdapi.StoresInfo{Stores: []*pdapi.StoreInfo{newStore(1, 1), newStore(2, 90), newStore(3, 1)}}
+	storesInfo.Stores[2].Status.SlowTrend = &pdapi.StoreSlowTrend{CauseValue: 200000}
+	stores := map[string]v1alpha1.TiKVStore{
+		"1": {ID: "1", PodName: "test-tikv-0"},
+		"2": {ID: "2", PodName: "test-tikv-1"},
+		"3": {ID: "3", PodName: "test-tikv-2"},
+	}
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForTiKV()
+	pdClient := pdapi.NewFakePDClient()
+	weights := map[uint64]pdapi.StoreWeight{}
+	pdClient.AddReaction(pdapi.SetStoreWeightActionType, func(action *pdapi.Action) (interface{}, error) {
+		weights[action.ID] = action.Weight
+		storesInfo.Stores[action.ID-1].Status.LeaderWeight = action.Weight.Leader
+		return nil, nil
+	})
+
+	// disabled
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores).To(BeNil())
+
+	// the stores are slow after the consecutive slow checks, the slow trend is checked if the threshold is set
+	tc.Spec.TiKV.SlowStoreMitigation = &v1alpha1.TiKVSlowStoreMitigation{
+		ConsecutiveChecks:       pointer.Int32Ptr(2),
+		SlowTrendCauseThreshold: pointer.Int64Ptr(100000),
+		ReduceLeaderWeight:      true,
+	}
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores).To(HaveLen(2))
+	g.Expect(tc.Status.TiKV.SlowStores["2"].SlowChecks).To(Equal(int32(1)))
+	g.Expect(tc.Status.TiKV.SlowStores["2"].SlowScore).To(Equal(uint64(90)))
+	g.Expect(tc.Status.TiKV.SlowStores["3"].Slow).To(BeFalse())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+	g.Expect(weights).To(BeEmpty())
+
+	// the leader weight of only one slow store is reduced
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores["2"].Slow).To(BeTrue())
+	g.Expect(tc.Status.TiKV.SlowStores["2"].PodName).To(Equal("test-tikv-1"))
+	g.Expect(tc.Status.TiKV.SlowStores["2"].OriginalLeaderWeight).To(Equal("1"))
+	g.Expect(tc.Status.TiKV.SlowStores["2"].ReducedLeaderWeight).To(Equal("0.1"))
+	g.Expect(tc.Status.TiKV.SlowStores["3"].Slow).To(BeTrue())
+	g.Expect(tc.Status.TiKV.SlowStores["3"].OriginalLeaderWeight).To(BeEmpty())
+	g.Expect(weights).To(Equal(map[uint64]pdapi.StoreWeight{2: {Leader: 0.1, Region: 2}}))
+	g.Expect(tc.Annotations[label.AnnTiKVSlowStoreLeaderWeights]).To(MatchJSON(`{"2":{"original":1,"reduced":0.1}}`))
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(3))
+	g.Expect(recorded[0]).To(ContainSubstring(events.SlowStoreDetected))
+	g.Expect(recorded[0]).To(ContainSubstring("store 2 of pod test-tikv-1 is slow for 2 checks, slow score: 90"))
+	g.Expect(recorded[1]).To(ContainSubstring(events.SlowStoreDetected))
+	g.Expect(recorded[2]).To(ContainSubstring(events.SlowStoreLeaderWeightReduced))
+	g.Expect(recorded[2]).To(ContainSubstring("the leader weight of slow store 2 is reduced from 1 to 0.1"))
+
+	// the stores are not checked again in the check interval
+	slowStoreCheckInterval = time.Hour
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores["2"].SlowChecks).To(Equal(int32(2)))
+	slowStoreCheckInterval = 0
+
+	// the original leader weight is restored after the store recovers, then the leader weight of the other slow
+	// store is reduced
+	storesInfo.Stores[1].Status.SlowScore = 1
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores["2"].Slow).To(BeTrue())
+	g.Expect(tc.Status.TiKV.SlowStores["2"].NormalChecks).To(Equal(int32(1)))
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(tc.Status.TiKV.SlowStores).NotTo(HaveKey("2"))
+	g.Expect(tc.Status.TiKV.SlowStores["3"].OriginalLeaderWeight).To(Equal("1"))
+	g.Expect(weights).To(Equal(map[uint64]pdapi.StoreWeight{2: {Leader: 1, Region: 2}, 3: {Leader: 0.1, Region: 2}}))
+	g.Expect(tc.Annotations[label.AnnTiKVSlowStoreLeaderWeights]).To(MatchJSON(`{"3":{"original":1,"reduced":0.1}}`))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(3))
+	g.Expect(recorded[0]).To(ContainSubstring(events.SlowStoreRecovered))
+	g.Expect(recorded[1]).To(ContainSubstring(events.SlowStoreLeaderWeightRestored))
+	g.Expect(recorded[1]).To(ContainSubstring("the leader weight of store 2 is restored to 1"))
+	g.Expect(recorded[2]).To(ContainSubstring(events.SlowStoreLeaderWeightReduced))
+
+	// the leader weight changed by the user is not restored after the mitigation is disabled
+	storesInfo.Stores[2].Status.LeaderWeight = 0.5
+	delete(weights, 3)
+	tc.Spec.TiKV.SlowStoreMitigation = nil
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(weights).NotTo(HaveKey(uint64(3)))
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnTiKVSlowStoreLeaderWeights))
+	g.Expect(tc.Status.TiKV.SlowStores).To(BeNil())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	// the leader weight recorded in the annotation is restored after the operator restarts without the status
+	storesInfo.Stores[0].Status.LeaderWeight = 0.1
+	tc.Annotations[label.AnnTiKVSlowStoreLeaderWeights] = `{"1":{"original":1,"reduced":0.1}}`
+	g.Expect(syncTiKVSlowStores(deps, pdClient, tc, storesInfo, stores)).To(Succeed())
+	g.Expect(weights[1]).To(Equal(pdapi.StoreWeight{Leader: 1, Region: 2}))
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnTiKVSlowStoreLeaderWeights))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.SlowStoreLeaderWeightRestored))
+}
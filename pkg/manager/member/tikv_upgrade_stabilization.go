@@ -0,0 +1,113 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const defaultTiKVUpgradeStabilizationMaxWait = 30 * time.Minute
+
+// captureTiKVUpgradeBaseline records the region scheduling state of the cluster when the upgrade of TiKV
+// starts, it is the state the cluster should return to before the upgrade is complete
+func captureTiKVUpgradeBaseline(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	if tc.Spec.TiKV.UpgradeStabilization == nil || tc.Status.TiKV.UpgradeBaseline != nil {
+		return nil
+	}
+	stats, err := controller.GetPDClient(deps.PDControl, tc).GetRegionSchedulingStats()
+	if err != nil {
+		return fmt.Errorf("cluster: [%s/%s] failed to capture the region scheduling state before upgrading tikv: %v", tc.GetNamespace(), tc.GetName(), err)
+	}
+	tc.Status.TiKV.UpgradeBaseline = &v1alpha1.TiKVUpgradeBaseline{
+		PendingOperatorCount: int32(stats.PendingOperatorCount),
+		MissPeerRegionCount:  int32(stats.MissPeerRegionCount),
+		CaptureTime:          metav1.Now(),
+	}
+	klog.Infof("cluster: [%s/%s] captured the baseline before upgrading tikv, pending operators: %d, under-replicated regions: %d",
+		tc.GetNamespace(), tc.GetName(), stats.PendingOperatorCount, stats.MissPeerRegionCount)
+	return nil
+}
+
+// stabilizeTiKVAfterUpgrade returns the phase of TiKV after the upgrade stabilization is considered.
+// Once all the stores are upgraded, i.e. the phase changes from UpgradePhase to NormalPhase, TiKV stays in
+// UpgradePhase with the StabilizingAfterUpgrade condition until the number of the pending PD operators and
+// the number of the under-replicated regions return to the baseline or the max wait elapses.
+func stabilizeTiKVAfterUpgrade(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, oldPhase, newPhase v1alpha1.MemberPhase) v1alpha1.MemberPhase {
+	status := &tc.Status.TiKV
+	baseline := status.UpgradeBaseline
+	stabilization := tc.Spec.TiKV.UpgradeStabilization
+	if stabilization == nil || baseline == nil || oldPhase != v1alpha1.UpgradePhase || newPhase != v1alpha1.NormalPhase {
+		if stabilization == nil || newPhase == v1alpha1.NormalPhase {
+			status.UpgradeBaseline = nil
+		}
+		status.RemoveCondition(v1alpha1.ComponentStabilizingAfterUpgrade)
+		return newPhase
+	}
+
+	if meta.FindStatusCondition(status.Conditions, v1alpha1.ComponentStabilizingAfterUpgrade) == nil {
+		status.SetCondition(metav1.Condition{
+			Type:    v1alpha1.ComponentStabilizingAfterUpgrade,
+			Status:  metav1.ConditionTrue,
+			Reason:  "WaitingForRegions",
+			Message: "Waiting for the region scheduling caused by the upgrade to settle",
+		})
+	}
+	cond := meta.FindStatusCondition(status.Conditions, v1alpha1.ComponentStabilizingAfterUpgrade)
+	complete := func() v1alpha1.MemberPhase {
+		status.UpgradeBaseline = nil
+		status.RemoveCondition(v1alpha1.ComponentStabilizingAfterUpgrade)
+		return newPhase
+	}
+
+	maxWait := defaultTiKVUpgradeStabilizationMaxWait
+	if stabilization.MaxWait != nil {
+		if d, err := time.ParseDuration(*stabilization.MaxWait); err == nil {
+			maxWait = d
+		}
+	}
+	if time.Since(cond.LastTransitionTime.Time) > maxWait {
+		msg := fmt.Sprintf("the region scheduling of tikv does not settle in %s after the upgrade, complete the upgrade", maxWait)
+		klog.Warningf("tidbcluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), msg)
+		recordUpgradeEvent(deps.Recorder, tc, corev1.EventTypeWarning, events.TiKVUpgradeStabilizationTimeout, msg)
+		return complete()
+	}
+
+	stats, err := controller.GetPDClient(deps.PDControl, tc).GetRegionSchedulingStats()
+	if err != nil {
+		klog.Infof("tidbcluster: [%s/%s] failed to get the region scheduling state after upgrading tikv: %v", tc.GetNamespace(), tc.GetName(), err)
+		return v1alpha1.UpgradePhase
+	}
+	if int32(stats.PendingOperatorCount) <= baseline.PendingOperatorCount && int32(stats.MissPeerRegionCount) <= baseline.MissPeerRegionCount {
+		klog.Infof("tidbcluster: [%s/%s] the region scheduling of tikv settles after the upgrade", tc.GetNamespace(), tc.GetName())
+		return complete()
+	}
+
+	status.SetCondition(metav1.Condition{
+		Type:   v1alpha1.ComponentStabilizingAfterUpgrade,
+		Status: metav1.ConditionTrue,
+		Reason: "WaitingForRegions",
+		Message: fmt.Sprintf("%d pending operators and %d under-replicated regions, the baseline is %d and %d",
+			stats.PendingOperatorCount, stats.MissPeerRegionCount, baseline.PendingOperatorCount, baseline.MissPeerRegionCount),
+	})
+	return v1alpha1.UpgradePhase
+}
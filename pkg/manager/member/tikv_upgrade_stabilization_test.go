@@ -0,0 +1,195 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestCaptureTiKVUpgradeBaseline(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForPD()
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetRegionSchedulingStatsActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.RegionSchedulingStats{PendingOperatorCount: 2, MissPeerRegionCount: 1}, nil
+	})
+
+	// disabled
+	g.Expect(captureTiKVUpgradeBaseline(deps, tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.UpgradeBaseline).To(BeNil())
+
+	tc.Spec.TiKV.UpgradeStabilization = &v1alpha1.TiKVUpgradeStabilization{}
+	g.Expect(captureTiKVUpgradeBaseline(deps, tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.UpgradeBaseline.PendingOperatorCount).To(Equal(int32(2)))
+	g.Expect(tc.Status.TiKV.UpgradeBaseline.MissPeerRegionCount).To(Equal(int32(1)))
+
+	// the baseline captured is kept
+	pdClient.AddReaction(pdapi.GetRegionSchedulingStatsActionType, func(action *pdapi.Action) (interface{}, error) {
+		return nil, fmt.Errorf("unavailable")
+	})
+	g.Expect(captureTiKVUpgradeBaseline(deps, tc)).To(Succeed())
+	g.Expect(tc.Status.TiKV.UpgradeBaseline.PendingOperatorCount).To(Equal(int32(2)))
+
+	tc.Status.TiKV.UpgradeBaseline = nil
+	g.Expect(captureTiKVUpgradeBaseline(deps, tc)).NotTo(Succeed())
+}
+
+func TestStabilizeTiKVAfterUpgrade(t *testing.T) {
+	baseline := &v1alpha1.TiKVUpgradeBaseline{PendingOperatorCount: 1, MissPeerRegionCount: 0}
+
+	tests := []struct {
+		name            string
+		stabilization   *v1alpha1.TiKVUpgradeStabilization
+		baseline        *v1alpha1.TiKVUpgradeBaseline
+		oldPhase        v1alpha1.MemberPhase
+		newPhase        v1alpha1.MemberPhase
+		stats           *pdapi.RegionSchedulingStats
+		statsErr        bool
+		stabilizingFor  time.Duration
+		expectPhase     v1alpha1.MemberPhase
+		expectBaseline  bool
+		expectCondition bool
+		expectEvent     bool
+	}{
+		{
+			name:        "disabled",
+			baseline:    baseline,
+			oldPhase:    v1alpha1.UpgradePhase,
+			newPhase:    v1alpha1.NormalPhase,
+			expectPhase: v1alpha1.NormalPhase,
+		},
+		{
+			name:           "still upgrading",
+			stabilization:  &v1alpha1.TiKVUpgradeStabilization{},
+			baseline:       baseline,
+			oldPhase:       v1alpha1.UpgradePhase,
+			newPhase:       v1alpha1.UpgradePhase,
+			expectPhase:    v1alpha1.UpgradePhase,
+			expectBaseline: true,
+		},
+		{
+			name:          "no baseline",
+			stabilization: &v1alpha1.TiKVUpgradeStabilization{},
+			oldPhase:      v1alpha1.UpgradePhase,
+			newPhase:      v1alpha1.NormalPhase,
+			expectPhase:   v1alpha1.NormalPhase,
+		},
+		{
+			name:          "settled",
+			stabilization: &v1alpha1.TiKVUpgradeStabilization{},
+			baseline:      baseline,
+			oldPhase:      v1alpha1.UpgradePhase,
+			newPhase:      v1alpha1.NormalPhase,
+			stats:         &pdapi.RegionSchedulingStats{PendingOperatorCount: 1, MissPeerRegionCount: 0},
+			expectPhase:   v1alpha1.NormalPhase,
+		},
+		{
+			name:            "pending operators",
+			stabilization:   &v1alpha1.TiKVUpgradeStabilization{},
+			baseline:        baseline,
+			oldPhase:        v1alpha1.UpgradePhase,
+			newPhase:        v1alpha1.NormalPhase,
+			stats:           &pdapi.RegionSchedulingStats{PendingOperatorCount: 5, MissPeerRegionCount: 0},
+			expectPhase:     v1alpha1.UpgradePhase,
+			expectBaseline:  true,
+			expectCondition: true,
+		},
+		{
+			name:            "under-replicated regions",
+			stabilization:   &v1alpha1.TiKVUpgradeStabilization{MaxWait: pointer.StringPtr("1h")},
+			baseline:        baseline,
+			oldPhase:        v1alpha1.UpgradePhase,
+			newPhase:        v1alpha1.NormalPhase,
+			stats:           &pdapi.RegionSchedulingStats{PendingOperatorCount: 0, MissPeerRegionCount: 3},
+			stabilizingFor:  40 * time.Minute,
+			expectPhase:     v1alpha1.UpgradePhase,
+			expectBaseline:  true,
+			expectCondition: true,
+		},
+		{
+			name:            "failed to get stats",
+			stabilization:   &v1alpha1.TiKVUpgradeStabilization{},
+			baseline:        baseline,
+			oldPhase:        v1alpha1.UpgradePhase,
+			newPhase:        v1alpha1.NormalPhase,
+			statsErr:        true,
+			expectPhase:     v1alpha1.UpgradePhase,
+			expectBaseline:  true,
+			expectCondition: true,
+		},
+		{
+			name:           "max wait elapses",
+			stabilization:  &v1alpha1.TiKVUpgradeStabilization{},
+			baseline:       baseline,
+			oldPhase:       v1alpha1.UpgradePhase,
+			newPhase:       v1alpha1.NormalPhase,
+			stats:          &pdapi.RegionSchedulingStats{PendingOperatorCount: 5, MissPeerRegionCount: 3},
+			stabilizingFor: 40 * time.Minute,
+			expectPhase:    v1alpha1.NormalPhase,
+			expectEvent:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			deps := controller.NewFakeDependencies()
+			tc := newTidbClusterForPD()
+			tc.Spec.TiKV.UpgradeStabilization = tt.stabilization
+			tc.Status.TiKV.UpgradeBaseline = tt.baseline.DeepCopy()
+			if tt.stabilizingFor > 0 {
+				tc.Status.TiKV.SetCondition(metav1.Condition{
+					Type:               v1alpha1.ComponentStabilizingAfterUpgrade,
+					Status:             metav1.ConditionTrue,
+					Reason:             "WaitingForRegions",
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-tt.stabilizingFor)),
+				})
+			}
+			pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+			pdClient.AddReaction(pdapi.GetRegionSchedulingStatsActionType, func(action *pdapi.Action) (interface{}, error) {
+				if tt.statsErr {
+					return nil, fmt.Errorf("unavailable")
+				}
+				return tt.stats, nil
+			})
+
+			phase := stabilizeTiKVAfterUpgrade(deps, tc, tt.oldPhase, tt.newPhase)
+			g.Expect(phase).To(Equal(tt.expectPhase))
+			g.Expect(tc.Status.TiKV.UpgradeBaseline != nil).To(Equal(tt.expectBaseline))
+			g.Expect(meta.IsStatusConditionTrue(tc.Status.TiKV.Conditions, v1alpha1.ComponentStabilizingAfterUpgrade)).To(Equal(tt.expectCondition))
+			recorded := collectEvents(deps.Recorder.(*record.FakeRecorder).Events)
+			if tt.expectEvent {
+				g.Expect(recorded).To(HaveLen(1))
+				g.Expect(recorded[0]).To(ContainSubstring(events.TiKVUpgradeStabilizationTimeout))
+			} else {
+				g.Expect(recorded).To(BeEmpty())
+			}
+		})
+	}
+}
@@ -14,19 +14,24 @@
 package member
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	"github.com/pingcap/tidb-operator/pkg/tikvapi"
 
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 )
@@ -34,6 +39,8 @@ import (
 const (
 	// EvictLeaderBeginTime is the key of evict Leader begin time
 	EvictLeaderBeginTime = "evictLeaderBeginTime"
+	// FlushStoreBeginTime is the key of the time when the store is flushed before restart
+	FlushStoreBeginTime = "flushStoreBeginTime"
 )
 
 type TiKVUpgrader interface {
@@ -41,13 +48,15 @@ type TiKVUpgrader interface {
 }
 
 type tikvUpgrader struct {
-	deps *controller.Dependencies
+	deps     *controller.Dependencies
+	recorder record.EventRecorder
 }
 
 // NewTiKVUpgrader returns a tikv Upgrader
 func NewTiKVUpgrader(deps *controller.Dependencies) TiKVUpgrader {
 	return &tikvUpgrader{
-		deps: deps,
+		deps:     deps,
+		recorder: newUpgradeRecorder(deps),
 	}
 }
 
@@ -58,8 +67,12 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 	var status *v1alpha1.TiKVStatus
 	switch meta := meta.(type) {
 	case *v1alpha1.TidbCluster:
+		if frozen, err := keepTemplateIfUpgradeFrozen(u.deps, meta, v1alpha1.TiKVMemberType, oldSet, newSet); frozen {
+			return err
+		}
 		if ready, reason := isTiKVReadyToUpgrade(meta); !ready {
 			klog.Infof("TidbCluster: [%s/%s], can not upgrade tikv because: %s", ns, tcName, reason)
+			recordUpgradeBlocked(u.recorder, meta, v1alpha1.TiKVMemberType, reason)
 			_, podSpec, err := GetLastAppliedConfig(oldSet)
 			if err != nil {
 				return err
@@ -73,11 +86,17 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 	}
 
 	tc, _ := meta.(*v1alpha1.TidbCluster)
+	if status.Phase != v1alpha1.UpgradePhase {
+		if err := captureTiKVUpgradeBaseline(u.deps, tc); err != nil {
+			return err
+		}
+	}
 
 	// upgrade tikv without evicting leader when only one tikv is exist
 	// NOTE: If `TiKVStatus.Synced`` is false, it's acceptable to use old record about peer stores
 	if *oldSet.Spec.Replicas < 2 && len(tc.Status.TiKV.PeerStores) == 0 {
 		klog.Infof("TiKV statefulset replicas are less than 2, skip evicting region leader for tc %s/%s", ns, tcName)
+		recordLastReconcileBy(u.deps, tc, status.Phase != v1alpha1.UpgradePhase)
 		status.Phase = v1alpha1.UpgradePhase
 		mngerutils.SetUpgradePartition(newSet, 0)
 		return nil
@@ -87,6 +106,7 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 		return fmt.Errorf("cluster: [%s/%s]'s tikv status sync failed, can not to be upgraded", ns, tcName)
 	}
 
+	recordLastReconcileBy(u.deps, tc, status.Phase != v1alpha1.UpgradePhase)
 	status.Phase = v1alpha1.UpgradePhase
 	if !templateEqual(newSet, oldSet) {
 		return nil
@@ -112,6 +132,10 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 		i := podOrdinals[_i]
 		store := getStoreByOrdinal(meta.GetName(), *status, i)
 		if store == nil {
+			if upgradePaused(u.recorder, tc, v1alpha1.TiKVMemberType, i) {
+				return nil
+			}
+			syncUpgradeStatus(u.deps, tc, v1alpha1.TiKVMemberType, oldSet, &i)
 			mngerutils.SetUpgradePartition(newSet, i)
 			continue
 		}
@@ -128,11 +152,17 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 		if revision == status.StatefulSet.UpdateRevision {
 
 			if !podutil.IsPodReady(pod) {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.TiKVMemberType, podName, "is not ready")
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded tikv pod: [%s] is not ready", ns, tcName, podName)
 			}
 			if store.State != v1alpha1.TiKVStateUp {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.TiKVMemberType, podName, fmt.Sprintf("has store %s in state %s", store.ID, store.State))
 				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded tikv pod: [%s] is not all ready", ns, tcName, podName)
 			}
+			if !tikvStoreHealthy(tc, *store) {
+				recordUpgradedPodUnhealthy(u.recorder, tc, v1alpha1.TiKVMemberType, podName, fmt.Sprintf("has store %s failing the direct probe", store.ID))
+				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s upgraded tikv pod: [%s] is Up in PD but fails the direct probe", ns, tcName, podName)
+			}
 
 			// If pods recreated successfully, endEvictLeader for the store on this Pod.
 			storeID, err := strconv.ParseUint(store.ID, 10, 64)
@@ -145,9 +175,20 @@ func (u *tikvUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, new
 			continue
 		}
 
+		// the stores failing the direct probe are not counted as healthy, upgrading another store may lose the quorum of regions
+		for id, divergent := range status.DivergentStores {
+			if divergent.PodName != podName {
+				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tikv store %s of pod [%s] is Up in PD but %s, can not upgrade tikv pod [%s]", ns, tcName, id, divergent.PodName, divergent.Reason, podName)
+			}
+		}
+		if upgradePaused(u.recorder, tc, v1alpha1.TiKVMemberType, i) {
+			return nil
+		}
+		syncUpgradeStatus(u.deps, tc, v1alpha1.TiKVMemberType, oldSet, &i)
 		return u.upgradeTiKVPod(tc, i, newSet)
 	}
 
+	status.EvictLeaderProgress = nil
 	return nil
 }
 
@@ -170,14 +211,34 @@ func (u *tikvUpgrader) upgradeTiKVPod(tc *v1alpha1.TidbCluster, ordinal int32, n
 
 	_, evicting := upgradePod.Annotations[EvictLeaderBeginTime]
 	if !evicting {
+		if u.deps.ShutdownDrain.Draining() {
+			return controller.RequeueErrorf("tidbcluster: [%s/%s] tidb-controller-manager is shutting down, do not evict leader from tikv pod: [%s]", ns, tcName, upgradePodName)
+		}
 		return u.beginEvictLeader(tc, storeID, upgradePod)
 	}
 
 	if u.readyToUpgrade(upgradePod, tc) {
+		// the store is flushed after its leaders are evicted, the flush is gated like the eviction
+		if tc.TiKVFlushBeforeRestart() {
+			if _, flushing := upgradePod.Annotations[FlushStoreBeginTime]; !flushing {
+				return u.beginFlushStore(tc, storeID, upgradePod)
+			}
+			if !u.storeFlushed(upgradePod, tc) {
+				return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tikv pod: [%s] is flushing store", ns, tcName, upgradePodName)
+			}
+		}
+		if ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition {
+			recordUpgradePodSelected(u.recorder, tc, v1alpha1.TiKVMemberType, upgradePodName, tc.Status.TiKV.StatefulSet)
+		}
+		recordLastReconcileBy(u.deps, tc, ordinal < *newSet.Spec.UpdateStrategy.RollingUpdate.Partition)
 		mngerutils.SetUpgradePartition(newSet, ordinal)
+		prepullNextImage(u.deps, tc, v1alpha1.TiKVMemberType, newSet, ordinal)
 		return nil
 	}
 
+	if u.deps.ShutdownDrain.Draining() {
+		checkpointTiKVEvictingLeader(u.deps, tc, upgradePodName, storeID)
+	}
 	return controller.RequeueErrorf("tidbcluster: [%s/%s]'s tikv pod: [%s] is evicting leader", ns, tcName, upgradePodName)
 }
 
@@ -197,22 +258,47 @@ func (u *tikvUpgrader) readyToUpgrade(upgradePod *corev1.Pod, tc *v1alpha1.TidbC
 	}
 
 	tlsEnabled := tc.IsTLSClusterEnabled()
-	leaderCount, err := u.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, upgradePod.Name, tlsEnabled).GetLeaderCount()
+	_, statusPort := tc.TiKVPorts()
+	stats, err := u.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, upgradePod.Name, statusPort, tlsEnabled).GetLeaderStats()
 	if err != nil {
 		klog.Warningf("Fail to get region leader count for Pod %s/%s, error: %v", upgradePod.Namespace, upgradePod.Name, err)
 		return false
 	}
 
-	if leaderCount == 0 {
-		klog.Infof("Region leader count is 0 for Pod %s/%s", upgradePod.Namespace, upgradePod.Name)
+	// Leaders of hibernated regions are not moved by the evict-leader scheduler until the regions
+	// are woken up, and the regions serve no requests, so only the active leaders are waited for.
+	activeLeaderCount, hibernatedLeaderCount := stats.ActiveLeaderCount(), stats.HibernatedLeaderCount()
+	setEvictLeaderProgress(tc, upgradePod.Name, int32(activeLeaderCount), int32(hibernatedLeaderCount))
+
+	if activeLeaderCount == 0 {
+		klog.Infof("Region leader count is %d (active: 0, hibernated: %d) for Pod %s/%s",
+			stats.LeaderCount, hibernatedLeaderCount, upgradePod.Namespace, upgradePod.Name)
 		return true
 	}
 
-	klog.Infof("Region leader count is %d for Pod %s/%s", leaderCount, upgradePod.Namespace, upgradePod.Name)
+	klog.Infof("Region leader count is %d (active: %d, hibernated: %d) for Pod %s/%s",
+		stats.LeaderCount, activeLeaderCount, hibernatedLeaderCount, upgradePod.Namespace, upgradePod.Name)
 
 	return false
 }
 
+// setEvictLeaderProgress records the leader counts of the pod being upgraded in tc status. The
+// LastUpdateTime is only updated if the counts change, so that polling the same counts does not
+// update tc status in every reconciliation.
+func setEvictLeaderProgress(tc *v1alpha1.TidbCluster, podName string, activeLeaderCount, hibernatedLeaderCount int32) {
+	progress := tc.Status.TiKV.EvictLeaderProgress
+	if progress != nil && progress.PodName == podName &&
+		progress.ActiveLeaderCount == activeLeaderCount && progress.HibernatedLeaderCount == hibernatedLeaderCount {
+		return
+	}
+	tc.Status.TiKV.EvictLeaderProgress = &v1alpha1.EvictLeaderProgress{
+		PodName:               podName,
+		ActiveLeaderCount:     activeLeaderCount,
+		HibernatedLeaderCount: hibernatedLeaderCount,
+		LastUpdateTime:        metav1.Now(),
+	}
+}
+
 func (u *tikvUpgrader) beginEvictLeader(tc *v1alpha1.TidbCluster, storeID uint64, pod *corev1.Pod) error {
 	ns := tc.GetNamespace()
 	podName := pod.GetName()
@@ -220,7 +306,7 @@ func (u *tikvUpgrader) beginEvictLeader(tc *v1alpha1.TidbCluster, storeID uint64
 	if err != nil {
 		klog.Errorf("tikv upgrader: failed to begin evict leader: %d, %s/%s, %v",
 			storeID, ns, podName, err)
-		return err
+		return requeueIfPDCircuitOpen(err)
 	}
 	klog.Infof("tikv upgrader: begin evict leader: %d, %s/%s successfully", storeID, ns, podName)
 	if pod.Annotations == nil {
@@ -232,13 +318,128 @@ func (u *tikvUpgrader) beginEvictLeader(tc *v1alpha1.TidbCluster, storeID uint64
 	if err != nil {
 		klog.Errorf("tikv upgrader: failed to set pod %s/%s annotation %s to %s, %v",
 			ns, podName, EvictLeaderBeginTime, now, err)
+		if u.deps.ShutdownDrain.Draining() {
+			// the next leader does not know the scheduler without the annotation, roll it back before exiting
+			if err := endEvictLeaderbyStoreID(u.deps, tc, storeID); err != nil {
+				klog.Errorf("tikv upgrader: failed to roll back evict leader: %d, %s/%s on shutdown, %v", storeID, ns, podName, err)
+			}
+		}
 		return err
 	}
 	klog.Infof("tikv upgrader: set pod %s/%s annotation %s to %s successfully",
 		ns, podName, EvictLeaderBeginTime, now)
+	if u.deps.ShutdownDrain.Draining() {
+		checkpointTiKVEvictingLeader(u.deps, tc, podName, storeID)
+	}
 	return nil
 }
 
+func checkpointTiKVEvictingLeader(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, podName string, storeID uint64) {
+	deps.ShutdownDrain.Checkpoint(tc, controller.ShutdownCheckpoint{
+		Component: v1alpha1.TiKVMemberType,
+		Pod:       podName,
+		StoreID:   storeID,
+		Step:      controller.ShutdownStepEvictingLeader,
+	})
+}
+
+// resumeTiKVFromShutdownCheckpoint reads the checkpoint the previous leader wrote on shutdown. If the pod evicting
+// leaders is still to be upgraded, the upgrade resumes from it; otherwise the evict-leader scheduler of its store is
+// removed right away instead of at the end of the upgrade. The checkpoint is removed once it is handled.
+func resumeTiKVFromShutdownCheckpoint(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	cp, err := controller.GetShutdownCheckpoint(tc)
+	if err != nil {
+		klog.Warningf("tidbcluster: [%s/%s] ignore the shutdown checkpoint: %v", ns, tcName, err)
+	} else if cp == nil || cp.Component != v1alpha1.TiKVMemberType {
+		return nil
+	} else if cp.Step == controller.ShutdownStepEvictingLeader {
+		resumable := false
+		if pod, err := deps.PodLister.Pods(ns).Get(cp.Pod); err == nil && tc.TiKVUpgrading() && tc.Status.TiKV.StatefulSet != nil {
+			_, evicting := pod.Annotations[EvictLeaderBeginTime]
+			resumable = evicting && pod.Labels[apps.ControllerRevisionHashLabelKey] != tc.Status.TiKV.StatefulSet.UpdateRevision
+		}
+		if resumable {
+			klog.Infof("tidbcluster: [%s/%s] resume the upgrade of tikv from pod %s evicting leader since %s", ns, tcName, cp.Pod, cp.Time)
+		} else if err := endEvictLeaderbyStoreID(deps, tc, cp.StoreID); err != nil {
+			return err
+		}
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, label.AnnShutdownCheckpoint))
+	if _, err := deps.TiDBClusterControl.Patch(tc, patch); err != nil {
+		return err
+	}
+	delete(tc.Annotations, label.AnnShutdownCheckpoint)
+	return nil
+}
+
+// beginFlushStore flushes the store through the TiKV status API and records the begin time of the flush in the
+// pod annotation. The flush is skipped with an event if the TiKV does not serve the API, so that the upgrade is
+// not blocked by it.
+func (u *tikvUpgrader) beginFlushStore(tc *v1alpha1.TidbCluster, storeID uint64, pod *corev1.Pod) error {
+	ns := tc.GetNamespace()
+	podName := pod.GetName()
+	_, statusPort := tc.TiKVPorts()
+	err := u.deps.TiKVControl.GetTiKVPodClient(ns, tc.GetName(), podName, statusPort, tc.IsTLSClusterEnabled()).FlushStore()
+	if errors.Is(err, tikvapi.ErrNotSupported) {
+		klog.Warningf("tikv upgrader: flush store: %d, %s/%s is not supported, restart it without the flush", storeID, ns, podName)
+		u.recorder.Eventf(tc, corev1.EventTypeWarning, events.TiKVFlushNotSupported,
+			"tikv pod %s does not serve the flush API, it is restarted without the flush", podName)
+	} else if err != nil {
+		klog.Errorf("tikv upgrader: failed to flush store: %d, %s/%s, %v", storeID, ns, podName, err)
+		return err
+	} else {
+		klog.Infof("tikv upgrader: flush store: %d, %s/%s successfully", storeID, ns, podName)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	now := time.Now().Format(time.RFC3339)
+	pod.Annotations[FlushStoreBeginTime] = now
+	_, err = u.deps.PodControl.UpdatePod(tc, pod)
+	if err != nil {
+		klog.Errorf("tikv upgrader: failed to set pod %s/%s annotation %s to %s, %v",
+			ns, podName, FlushStoreBeginTime, now, err)
+		return err
+	}
+	return nil
+}
+
+// storeFlushed returns whether the apply lag of the flushed store drops under spec.tikv.upgradeSafetyChecks.maxApplyLag,
+// the wait shares the timeout of evicting leaders.
+func (u *tikvUpgrader) storeFlushed(upgradePod *corev1.Pod, tc *v1alpha1.TidbCluster) bool {
+	maxApplyLag := tc.TiKVMaxApplyLag()
+	if maxApplyLag == nil {
+		return true
+	}
+
+	flushBeginTime, err := time.Parse(time.RFC3339, upgradePod.Annotations[FlushStoreBeginTime])
+	if err != nil {
+		klog.Errorf("parse annotation:[%s] to time failed.", FlushStoreBeginTime)
+		return false
+	}
+	timeout := tc.TiKVEvictLeaderTimeout()
+	if time.Now().After(flushBeginTime.Add(timeout)) {
+		klog.Infof("Wait for apply lag timeout (threshold: %v) for Pod %s/%s", timeout, upgradePod.Namespace, upgradePod.Name)
+		return true
+	}
+
+	_, statusPort := tc.TiKVPorts()
+	applyLag, err := u.deps.TiKVControl.GetTiKVPodClient(tc.Namespace, tc.Name, upgradePod.Name, statusPort, tc.IsTLSClusterEnabled()).GetApplyLag()
+	if errors.Is(err, tikvapi.ErrNotSupported) {
+		klog.Infof("Apply lag is not reported by Pod %s/%s, do not wait for it", upgradePod.Namespace, upgradePod.Name)
+		return true
+	}
+	if err != nil {
+		klog.Warningf("Fail to get apply lag for Pod %s/%s, error: %v", upgradePod.Namespace, upgradePod.Name, err)
+		return false
+	}
+	klog.Infof("Apply lag is %d (max: %d) for Pod %s/%s", applyLag, *maxApplyLag, upgradePod.Namespace, upgradePod.Name)
+	return applyLag <= *maxApplyLag
+}
+
 // endEvictLeaderForAllStore end evict leader for all stores of a tc
 func endEvictLeaderForAllStore(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
 	storeIDs := make([]uint64, 0, len(tc.Status.TiKV.Stores)+len(tc.Status.TiKV.TombstoneStores))
@@ -304,7 +505,7 @@ func endEvictLeaderbyStoreID(deps *controller.Dependencies, tc *v1alpha1.TidbClu
 	err := controller.GetPDClient(deps.PDControl, tc).EndEvictLeader(storeID)
 	if err != nil {
 		klog.Errorf("tikv: failed to end evict leader for store: %d of %s/%s, error: %v", storeID, tc.Namespace, tc.Name, err)
-		return err
+		return requeueIfPDCircuitOpen(err)
 	}
 	klog.Infof("tikv: end evict leader for store: %d of %s/%s successfully", storeID, tc.Namespace, tc.Name)
 	return nil
@@ -321,11 +522,8 @@ func getStoreByOrdinal(name string, status v1alpha1.TiKVStatus, ordinal int32) *
 }
 
 func isTiKVReadyToUpgrade(tc *v1alpha1.TidbCluster) (bool, string) {
-	if tc.Status.TiFlash.Phase == v1alpha1.UpgradePhase {
-		return false, fmt.Sprintf("tiflash status is %s", tc.Status.TiFlash.Phase)
-	}
-	if tc.Status.PD.Phase == v1alpha1.UpgradePhase {
-		return false, fmt.Sprintf("pd status is %s", tc.Status.PD.Phase)
+	if blocking := upgradeBlockedBy(tc, v1alpha1.TiKVMemberType); blocking != "" {
+		return false, fmt.Sprintf("the components upgraded before tikv [%s] are upgrading", blocking)
 	}
 	if tc.TiKVScaling() {
 		return false, fmt.Sprintf("tikv status is %s", tc.Status.TiKV.Phase)
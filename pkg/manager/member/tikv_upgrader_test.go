@@ -14,8 +14,8 @@
 package member
 
 import (
+	"context"
 	"fmt"
-	"strconv"
 	"testing"
 	"time"
 
@@ -30,7 +30,6 @@ import (
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	podinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/utils/pointer"
 )
@@ -48,10 +47,14 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		changeFn            func(*v1alpha1.TidbCluster)
 		changeOldSet        func(set *apps.StatefulSet)
 		changePods          func([]*corev1.Pod)
+		upgraded            []int32
 		beginEvictLeaderErr bool
 		endEvictLeaderErr   bool
 		getLeaderCountErr   bool
 		leaderCount         int
+		leaderStats         *tikvapi.LeaderStats
+		flushStoreErr       error
+		applyLag            int64
 		podName             string
 		updatePodErr        bool
 		errExpectFn         func(*GomegaWithT, error)
@@ -60,20 +63,23 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 
 	testFn := func(test *testcase, t *testing.T) {
 		t.Log(test.name)
-		upgrader, pdControl, podControl, podInformer, tikvControl := newTiKVUpgrader()
+		upgrader, _, podControl, podInformer, tikvControl := newTiKVUpgrader()
+		b := NewTCBuilder().
+			WithTiKVPhase(v1alpha1.UpgradePhase).
+			WithUpgradedPods(v1alpha1.TiKVMemberType, test.upgraded...)
+		pdClient := b.Wire(upgrader.(*tikvUpgrader).deps)
 
-		tc := newTidbClusterForTiKVUpgrader()
+		tc := b.Build()
 		if test.changeFn != nil {
 			test.changeFn(tc)
 		}
 
-		oldSet := oldStatefulSetForTiKVUpgrader()
+		oldSet := b.StatefulSet(v1alpha1.TiKVMemberType)
 		if test.changeOldSet != nil {
 			test.changeOldSet(oldSet)
 		}
-		newSet := newStatefulSetForTiKVUpgrader()
+		newSet := b.StatefulSet(v1alpha1.TiKVMemberType)
 
-		pdClient := controller.NewFakePDClient(pdControl, tc)
 		if test.beginEvictLeaderErr {
 			pdClient.AddReaction(pdapi.BeginEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
 				return nil, fmt.Errorf("failed to begin evict leader")
@@ -97,6 +103,17 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 				return test.leaderCount, nil
 			})
 		}
+		if test.leaderStats != nil {
+			tikvClient.AddReaction(tikvapi.GetLeaderStatsActionType, func(action *tikvapi.Action) (interface{}, error) {
+				return test.leaderStats, nil
+			})
+		}
+		tikvClient.AddReaction(tikvapi.FlushStoreActionType, func(action *tikvapi.Action) (interface{}, error) {
+			return nil, test.flushStoreErr
+		})
+		tikvClient.AddReaction(tikvapi.GetApplyLagActionType, func(action *tikvapi.Action) (interface{}, error) {
+			return test.applyLag, nil
+		})
 		if test.endEvictLeaderErr {
 			pdClient.AddReaction(pdapi.EndEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
 				return nil, fmt.Errorf("failed to end evict leader")
@@ -107,12 +124,12 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			})
 		}
 
-		tikvPods := getTiKVPods(oldSet)
+		tikvPods := b.Pods(v1alpha1.TiKVMemberType)
 		if test.changePods != nil {
 			test.changePods(tikvPods)
 		}
 		for _, pod := range tikvPods {
-			podInformer.Informer().GetIndexer().Add(pod)
+			podInformer.Informer().GetIndexer().Update(pod)
 		}
 
 		if test.updatePodErr {
@@ -137,7 +154,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			name:     "modify oldSet update strategy to OnDelete",
 			changeFn: nil,
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
 					Type: apps.OnDeleteStatefulSetStrategyType,
 				}
@@ -158,7 +174,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			name:     "set oldSet's RollingUpdate strategy to nil",
 			changeFn: nil,
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Spec.UpdateStrategy = apps.StatefulSetUpdateStrategy{
 					Type: apps.RollingUpdateStatefulSetStrategyType,
 				}
@@ -178,17 +193,12 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		{
 			name: "to upgrade the pod which ordinal is 2",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
 				// set leader to 0
 				store := tc.Status.TiKV.Stores["3"]
 				store.LeaderCount = 0
 				tc.Status.TiKV.Stores["3"] = store
 			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-			},
 			changePods: func(pods []*corev1.Pod) {
 				for _, pod := range pods {
 					if pod.GetName() == TikvPodName(upgradeTcName, 2) {
@@ -207,23 +217,34 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
 			},
 		},
+		{
+			name: "tikv can not upgrade when another store fails the direct probe",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+				tc.Status.TiKV.DivergentStores = map[string]v1alpha1.TiKVDivergentStore{
+					"1": {PodName: TikvPodName(upgradeTcName, 0), Reason: "unreachable"},
+				}
+			},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+				g.Expect(err.Error()).To(ContainSubstring("is Up in PD but unreachable"))
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+				_, evicting := pods[TikvPodName(upgradeTcName, 2)].Annotations[EvictLeaderBeginTime]
+				g.Expect(evicting).To(BeFalse())
+			},
+		},
 		{
 			name: "to upgrade the pod which ordinal is 1",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
 				// set leader to 0
 				store := tc.Status.TiKV.Stores["2"]
 				store.LeaderCount = 0
 				tc.Status.TiKV.Stores["2"] = store
 			},
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -242,14 +263,14 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
 				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+				// the partition is moved
+				g.Expect(tc.Status.LastReconcileTime).NotTo(BeNil())
 			},
 		},
 		{
 			name: "newSet template changed",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
 				oldSet.Spec.Template.Spec.Containers[0].Image = "old-image"
@@ -270,9 +291,7 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		{
 			name: "update revision equals current revision",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
 				tc.Status.TiKV.StatefulSet.UpdateRevision = tc.Status.TiKV.StatefulSet.CurrentRevision
 			},
 			changePods:          nil,
@@ -292,10 +311,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			changeFn: func(tc *v1alpha1.TidbCluster) {
 				tc.Status.PD.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			changePods:          nil,
 			beginEvictLeaderErr: false,
@@ -314,10 +329,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			changeFn: func(tc *v1alpha1.TidbCluster) {
 				tc.Status.TiFlash.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			changePods:          nil,
 			beginEvictLeaderErr: false,
@@ -334,12 +345,7 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		{
 			name: "tikv can not upgrade when it is scaling",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
 				tc.Status.TiKV.Phase = v1alpha1.ScalePhase
-				tc.Status.TiKV.Synced = true
-			},
-			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 			},
 			changePods:          nil,
 			beginEvictLeaderErr: false,
@@ -358,7 +364,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			changeFn: func(tc *v1alpha1.TidbCluster) {
 				tc.Status.PD.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiKV.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Synced = true
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
 				oldSet.SetAnnotations(map[string]string{LastAppliedConfigAnnotation: "fake apply config"})
@@ -376,18 +381,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "begin evict leaders on store[2]",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "begin evict leaders on store[2]",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods:          nil,
@@ -404,18 +400,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "waiting leader count equals to 0",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "waiting leader count equals to 0",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -439,19 +426,247 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name:              "get leader count error",
-			getLeaderCountErr: true,
+			name:     "upgrade when the remaining leaders are hibernated",
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			// the 100 hibernated peers include all the 90 followers, so the 10 leaders are hibernated
+			leaderStats: &tikvapi.LeaderStats{RegionCount: 100, LeaderCount: 10, HibernatedPeerCount: 100},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+				progress := tc.Status.TiKV.EvictLeaderProgress
+				g.Expect(progress).NotTo(BeNil())
+				g.Expect(progress.PodName).To(Equal("upgrader-tikv-1"))
+				g.Expect(progress.ActiveLeaderCount).To(Equal(int32(0)))
+				g.Expect(progress.HibernatedLeaderCount).To(Equal(int32(10)))
+			},
+		},
+		{
+			name:     "waiting active leaders while some leaders are hibernated",
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			podName:     "upgrader-tikv-1",
+			leaderStats: &tikvapi.LeaderStats{RegionCount: 100, LeaderCount: 10, HibernatedPeerCount: 94},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(Equal("tidbcluster: [default/upgrader]'s tikv pod: [upgrader-tikv-1] is evicting leader"))
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+				progress := tc.Status.TiKV.EvictLeaderProgress
+				g.Expect(progress).NotTo(BeNil())
+				g.Expect(progress.ActiveLeaderCount).To(Equal(int32(6)))
+				g.Expect(progress.HibernatedLeaderCount).To(Equal(int32(4)))
+			},
+		},
+		{
+			name: "evict leader progress is kept if the leader counts do not change",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
+				tc.Status.TiKV.EvictLeaderProgress = &v1alpha1.EvictLeaderProgress{
+					PodName:               "upgrader-tikv-1",
+					ActiveLeaderCount:     6,
+					HibernatedLeaderCount: 4,
+					LastUpdateTime:        metav1.Time{Time: time.Unix(1000, 0)},
+				}
 			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			podName:     "upgrader-tikv-1",
+			leaderStats: &tikvapi.LeaderStats{RegionCount: 100, LeaderCount: 10, HibernatedPeerCount: 94},
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(Equal("tidbcluster: [default/upgrader]'s tikv pod: [upgrader-tikv-1] is evicting leader"))
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+				progress := tc.Status.TiKV.EvictLeaderProgress
+				g.Expect(progress).NotTo(BeNil())
+				g.Expect(progress.ActiveLeaderCount).To(Equal(int32(6)))
+				g.Expect(progress.HibernatedLeaderCount).To(Equal(int32(4)))
+				g.Expect(progress.LastUpdateTime).To(Equal(metav1.Time{Time: time.Unix(1000, 0)}))
+			},
+		},
+		{
+			name: "flush store after leaders are evicted",
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.UpgradeSafetyChecks = &v1alpha1.TiKVUpgradeSafetyChecks{
+					FlushBeforeRestart: true,
+					MaxApplyLag:        pointer.Int64Ptr(100),
+				}
+			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Add(-1 * time.Minute).Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+				_, exist := pods[TikvPodName(upgradeTcName, 1)].Annotations[FlushStoreBeginTime]
+				g.Expect(exist).To(BeTrue())
+			},
+		},
+		{
+			name:          "failed to flush store",
+			flushStoreErr: fmt.Errorf("failed to flush store"),
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.UpgradeSafetyChecks = &v1alpha1.TiKVUpgradeSafetyChecks{
+					FlushBeforeRestart: true,
+					MaxApplyLag:        pointer.Int64Ptr(100),
+				}
+			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Add(-1 * time.Minute).Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+				_, exist := pods[TikvPodName(upgradeTcName, 1)].Annotations[FlushStoreBeginTime]
+				g.Expect(exist).To(BeFalse())
+			},
+		},
+		{
+			name:          "skip the flush if it is not supported by the tikv",
+			flushStoreErr: fmt.Errorf("fake: %w", tikvapi.ErrNotSupported),
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.UpgradeSafetyChecks = &v1alpha1.TiKVUpgradeSafetyChecks{
+					FlushBeforeRestart: true,
+					MaxApplyLag:        pointer.Int64Ptr(100),
+				}
+			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Add(-1 * time.Minute).Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+				_, exist := pods[TikvPodName(upgradeTcName, 1)].Annotations[FlushStoreBeginTime]
+				g.Expect(exist).To(BeTrue())
+			},
+		},
+		{
+			name:     "waiting apply lag after flushing store",
+			applyLag: 500,
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.UpgradeSafetyChecks = &v1alpha1.TiKVUpgradeSafetyChecks{
+					FlushBeforeRestart: true,
+					MaxApplyLag:        pointer.Int64Ptr(100),
+				}
+			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), FlushStoreBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(err.Error()).To(Equal("tidbcluster: [default/upgrader]'s tikv pod: [upgrader-tikv-1] is flushing store"))
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+			},
+		},
+		{
+			name:     "upgrade when apply lag drops after flushing store",
+			applyLag: 50,
+			changeFn: func(tc *v1alpha1.TidbCluster) {
+				tc.Spec.TiKV.UpgradeSafetyChecks = &v1alpha1.TiKVUpgradeSafetyChecks{
+					FlushBeforeRestart: true,
+					MaxApplyLag:        pointer.Int64Ptr(100),
+				}
+			},
+			upgraded: []int32{2},
+			changeOldSet: func(oldSet *apps.StatefulSet) {
+				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
+			},
+			changePods: func(pods []*corev1.Pod) {
+				for _, pod := range pods {
+					if pod.GetName() == TikvPodName(upgradeTcName, 1) {
+						pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), FlushStoreBeginTime: time.Now().Format(time.RFC3339)}
+					}
+				}
+			},
+			podName: "upgrader-tikv-1",
+			errExpectFn: func(g *GomegaWithT, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+			},
+			expectFn: func(g *GomegaWithT, tc *v1alpha1.TidbCluster, newSet *apps.StatefulSet, pods map[string]*corev1.Pod) {
+				g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(1)))
+			},
+		},
+		{
+			name:              "get leader count error",
+			getLeaderCountErr: true,
+			upgraded:          []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -476,18 +691,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		},
 
 		{
-			name: "failed to begin evict leaders on store[2]",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "failed to begin evict leaders on store[2]",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods:          nil,
@@ -504,18 +710,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "evict leaders time out",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "evict leaders time out",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -540,15 +737,11 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		{
 			name: "fast evict leaders when TiKV Stores are less than 2",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
 				tc.Status.TiKV.StatefulSet.Replicas = 1
 				tc.Status.TiKV.StatefulSet.CurrentReplicas = 1
 				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Status.CurrentReplicas = 1
 				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.Replicas = pointer.Int32Ptr(1)
@@ -576,8 +769,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 		{
 			name: "can't fast evict leaders when current TiKV Stores are less than 2 but peer stores is exist",
 			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
 				tc.Status.TiKV.Synced = false
 				tc.Status.TiKV.StatefulSet.Replicas = 1
 				tc.Status.TiKV.StatefulSet.CurrentReplicas = 1
@@ -585,7 +776,6 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 				tc.Status.TiKV.PeerStores = map[string]v1alpha1.TiKVStore{"peer-0": {ID: "peer-0", State: v1alpha1.TiKVStateUp}}
 			},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
 				oldSet.Status.CurrentReplicas = 1
 				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.Replicas = pointer.Int32Ptr(1)
@@ -611,18 +801,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "end leader evict failed",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "end leader evict failed",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -643,18 +824,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "update pod failed after begin evict leaders on store[2]",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "update pod failed after begin evict leaders on store[2]",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods:          nil,
@@ -669,18 +841,9 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 			},
 		},
 		{
-			name: "update pod failed",
-			changeFn: func(tc *v1alpha1.TidbCluster) {
-				tc.Status.PD.Phase = v1alpha1.NormalPhase
-				tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
-				tc.Status.TiKV.Synced = true
-				tc.Status.TiKV.StatefulSet.CurrentReplicas = 2
-				tc.Status.TiKV.StatefulSet.UpdatedReplicas = 1
-			},
+			name:     "update pod failed",
+			upgraded: []int32{2},
 			changeOldSet: func(oldSet *apps.StatefulSet) {
-				mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
-				oldSet.Status.CurrentReplicas = 2
-				oldSet.Status.UpdatedReplicas = 1
 				oldSet.Spec.UpdateStrategy.RollingUpdate.Partition = pointer.Int32Ptr(2)
 			},
 			changePods: func(pods []*corev1.Pod) {
@@ -707,147 +870,110 @@ func TestTiKVUpgraderUpgrade(t *testing.T) {
 	}
 }
 
-func newTiKVUpgrader() (TiKVUpgrader, *pdapi.FakePDControl, *controller.FakePodControl, podinformers.PodInformer, *tikvapi.FakeTiKVControl) {
-	fakeDeps := controller.NewFakeDependencies()
-	pdControl := fakeDeps.PDControl.(*pdapi.FakePDControl)
-	tikvControl := fakeDeps.TiKVControl.(*tikvapi.FakeTiKVControl)
-	podControl := fakeDeps.PodControl.(*controller.FakePodControl)
-	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
-	return &tikvUpgrader{deps: fakeDeps}, pdControl, podControl, podInformer, tikvControl
-}
+func TestTiKVUpgraderShutdownDrain(t *testing.T) {
+	g := NewGomegaWithT(t)
 
-func newStatefulSetForTiKVUpgrader() *apps.StatefulSet {
-	return &apps.StatefulSet{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "upgrader-tikv",
-			Namespace: metav1.NamespaceDefault,
-			Labels:    label.New().Instance(upgradeInstanceName).TiKV().Labels(),
-		},
-		Spec: apps.StatefulSetSpec{
-			Replicas: pointer.Int32Ptr(3),
-			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "tikv",
-							Image: "tikv-test-image",
-						},
-					},
-				},
-			},
-			UpdateStrategy: apps.StatefulSetUpdateStrategy{
-				Type: apps.RollingUpdateStatefulSetStrategyType,
-				RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{
-					Partition: pointer.Int32Ptr(3),
-				}},
-		},
-	}
-}
+	for _, evicting := range []bool{false, true} {
+		upgrader, _, _, podInformer, tikvControl := newTiKVUpgrader()
+		deps := upgrader.(*tikvUpgrader).deps
+		b := NewTCBuilder().WithTiKVPhase(v1alpha1.UpgradePhase)
+		pdClient := b.Wire(deps)
+		tc := b.Build()
+		_, err := deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		oldSet := b.StatefulSet(v1alpha1.TiKVMemberType)
+		newSet := b.StatefulSet(v1alpha1.TiKVMemberType)
+		for _, pod := range b.Pods(v1alpha1.TiKVMemberType) {
+			if evicting && pod.Name == TikvPodName(upgradeTcName, 2) {
+				pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+				podInformer.Informer().GetIndexer().Update(pod)
+			}
+		}
+		pdClient.AddReaction(pdapi.BeginEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+			return nil, fmt.Errorf("no leaders should be evicted on shutdown")
+		})
+		tikvClient := controller.NewFakeTiKVClient(tikvControl, tc, TikvPodName(upgradeTcName, 2))
+		tikvClient.AddReaction(tikvapi.GetLeaderStatsActionType, func(action *tikvapi.Action) (interface{}, error) {
+			return &tikvapi.LeaderStats{RegionCount: 100, LeaderCount: 10}, nil
+		})
 
-func oldStatefulSetForTiKVUpgrader() *apps.StatefulSet {
-	set := newStatefulSetForTiKVUpgrader()
-	set.Status = apps.StatefulSetStatus{
-		Replicas:        3,
-		CurrentReplicas: 3,
-		UpdatedReplicas: 0,
-		CurrentRevision: "1",
-		UpdateRevision:  "2",
+		deps.ShutdownDrain.Drain(0)
+		err = upgrader.Upgrade(tc, oldSet, newSet)
+		g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+		g.Expect(*newSet.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+
+		deps.ShutdownDrain.WriteCheckpoints(deps.Clientset)
+		tc, err = deps.Clientset.PingcapV1alpha1().TidbClusters(tc.Namespace).Get(context.TODO(), tc.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		cp, err := controller.GetShutdownCheckpoint(tc)
+		g.Expect(err).NotTo(HaveOccurred())
+		if !evicting {
+			// the leaders are not evicted, there is nothing to resume
+			g.Expect(cp).To(BeNil())
+			continue
+		}
+		g.Expect(cp).NotTo(BeNil())
+		g.Expect(cp.Component).To(Equal(v1alpha1.TiKVMemberType))
+		g.Expect(cp.Pod).To(Equal(TikvPodName(upgradeTcName, 2)))
+		g.Expect(cp.StoreID).To(Equal(uint64(3)))
+		g.Expect(cp.Step).To(Equal(controller.ShutdownStepEvictingLeader))
 	}
-	return set
 }
 
-func newTidbClusterForTiKVUpgrader() *v1alpha1.TidbCluster {
-	return &v1alpha1.TidbCluster{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "TidbCluster",
-			APIVersion: "pingcap.com/v1alpha1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      upgradeTcName,
-			Namespace: corev1.NamespaceDefault,
-			UID:       types.UID(upgradeTcName),
-			Labels:    label.New().Instance(upgradeInstanceName).TiKV().Labels(),
-		},
-		Spec: v1alpha1.TidbClusterSpec{
-			PD: &v1alpha1.PDSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "pd-test-image",
-				},
-				Replicas:         3,
-				StorageClassName: pointer.StringPtr("my-storage-class"),
-			},
-			TiKV: &v1alpha1.TiKVSpec{
-				ComponentSpec: v1alpha1.ComponentSpec{
-					Image: "tikv-test-image",
-				},
-				Replicas:         3,
-				StorageClassName: pointer.StringPtr("my-storage-class"),
-			},
-		},
-		Status: v1alpha1.TidbClusterStatus{
-			TiKV: v1alpha1.TiKVStatus{
-				Synced:       true,
-				BootStrapped: true,
-				Phase:        v1alpha1.UpgradePhase,
-				StatefulSet: &apps.StatefulSetStatus{
-					Replicas:        3,
-					CurrentReplicas: 3,
-					UpdatedReplicas: 0,
-					CurrentRevision: "1",
-					UpdateRevision:  "2",
-				},
-				Stores: map[string]v1alpha1.TiKVStore{
-					"1": {
-						ID:          "1",
-						PodName:     TikvPodName(upgradeTcName, 0),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-					"2": {
-						ID:          "2",
-						PodName:     TikvPodName(upgradeTcName, 1),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-					"3": {
-						ID:          "3",
-						PodName:     TikvPodName(upgradeTcName, 2),
-						LeaderCount: 10,
-						State:       "Up",
-					},
-				},
-			},
-		},
+func TestResumeTiKVFromShutdownCheckpoint(t *testing.T) {
+	tests := []struct {
+		name           string
+		evicting       bool
+		revision       string
+		expectEndEvict bool
+	}{
+		{name: "resume from the pod evicting leader", evicting: true, revision: "1"},
+		{name: "the pod is upgraded", evicting: true, revision: "2", expectEndEvict: true},
+		{name: "the pod is not evicting leader", revision: "1", expectEndEvict: true},
 	}
-}
 
-func getTiKVPods(set *apps.StatefulSet) []*corev1.Pod {
-	var pods []*corev1.Pod
-	for i := 0; i < int(set.Status.Replicas); i++ {
-		l := label.New().Instance(upgradeInstanceName).TiKV().Labels()
-		if i+1 <= int(set.Status.CurrentReplicas) {
-			l[apps.ControllerRevisionHashLabelKey] = set.Status.CurrentRevision
-		} else {
-			l[apps.ControllerRevisionHashLabelKey] = set.Status.UpdateRevision
-		}
-		l[label.StoreIDLabelKey] = strconv.Itoa(i + 1)
-		pods = append(pods, &corev1.Pod{
-			TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      TikvPodName(upgradeTcName, int32(i)),
-				Namespace: corev1.NamespaceDefault,
-				Labels:    l,
-			},
-			Status: corev1.PodStatus{
-				Phase: corev1.PodRunning,
-				Conditions: []corev1.PodCondition{
-					{
-						Type:   corev1.PodReady,
-						Status: corev1.ConditionTrue,
-					},
-				},
-			},
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			upgrader, _, _, podInformer, _ := newTiKVUpgrader()
+			deps := upgrader.(*tikvUpgrader).deps
+			b := NewTCBuilder().
+				WithTiKVPhase(v1alpha1.UpgradePhase).
+				WithPodRevisions(v1alpha1.TiKVMemberType, upgradeCurrentRevision, upgradeCurrentRevision, tt.revision)
+			pdClient := b.Wire(deps)
+			tc := b.Build()
+			tc.Annotations = map[string]string{
+				label.AnnShutdownCheckpoint: `{"component":"tikv","pod":"upgrader-tikv-2","storeID":3,"step":"EvictingLeader","time":"2022-06-01T00:00:00Z"}`,
+			}
+			for _, pod := range b.Pods(v1alpha1.TiKVMemberType) {
+				if tt.evicting && pod.Name == TikvPodName(upgradeTcName, 2) {
+					pod.Annotations = map[string]string{EvictLeaderBeginTime: time.Now().Format(time.RFC3339)}
+					podInformer.Informer().GetIndexer().Update(pod)
+				}
+			}
+			var endEvictStoreID interface{}
+			pdClient.AddReaction(pdapi.EndEvictLeaderActionType, func(action *pdapi.Action) (interface{}, error) {
+				endEvictStoreID = action.ID
+				return nil, nil
+			})
+
+			g.Expect(resumeTiKVFromShutdownCheckpoint(deps, tc)).To(Succeed())
+			g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnShutdownCheckpoint))
+			if tt.expectEndEvict {
+				g.Expect(endEvictStoreID).To(Equal(uint64(3)))
+			} else {
+				g.Expect(endEvictStoreID).To(BeNil())
+			}
 		})
 	}
-	return pods
+}
+
+func newTiKVUpgrader() (TiKVUpgrader, *pdapi.FakePDControl, *controller.FakePodControl, podinformers.PodInformer, *tikvapi.FakeTiKVControl) {
+	fakeDeps := controller.NewFakeDependencies()
+	pdControl := fakeDeps.PDControl.(*pdapi.FakePDControl)
+	tikvControl := fakeDeps.TiKVControl.(*tikvapi.FakeTiKVControl)
+	podControl := fakeDeps.PodControl.(*controller.FakePodControl)
+	podInformer := fakeDeps.KubeInformerFactory.Core().V1().Pods()
+	return NewTiKVUpgrader(fakeDeps).(*tikvUpgrader), pdControl, podControl, podInformer, tikvControl
 }
@@ -0,0 +1,206 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// annSelectedNode is the pvc annotation key of the node the volume is provisioned for, it is set by the
+	// scheduler for the volumes bound on the first consumer and respected by the provisioners
+	annSelectedNode = "volume.kubernetes.io/selected-node"
+	// annDefaultStorageClass is the StorageClass annotation key marking the default StorageClass
+	annDefaultStorageClass = "storageclass.kubernetes.io/is-default-class"
+)
+
+// preProvisionVolumes pre-creates the PVCs of the new TiKV pod of the ordinal in the zone planned for it, the
+// volumes are provisioned for a node in the zone so that the pod follows the volumes into the zone. It returns
+// false if the pre-provisioning falls back to the plain scale-out, the reason is recorded in the status and
+// a Warning event.
+func (s *tikvScaler) preProvisionVolumes(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, ordinal int32) (bool, error) {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	podName := ordinalPodName(v1alpha1.TiKVMemberType, tcName, ordinal)
+
+	zone, node, reason, err := s.planVolumeZone(tc, set, ordinal)
+	if err != nil {
+		return false, err
+	}
+	if reason != "" {
+		msg := fmt.Sprintf("the volumes of pod %s are not pre-provisioned, fall back to the plain scale-out: %s", podName, reason)
+		klog.Warningf("tikvScaler.ScaleOut: tidbcluster %s/%s %s", ns, tcName, msg)
+		s.deps.Recorder.Event(tc, corev1.EventTypeWarning, events.VolumePreProvisioningFallback, msg)
+		volumePreProvisioningStatus(tc).FallbackReason = reason
+		return false, nil
+	}
+
+	for i := range set.Spec.VolumeClaimTemplates {
+		pvc := newPreProvisionedPVC(set, &set.Spec.VolumeClaimTemplates[i], ordinal, zone, node)
+		if err := s.deps.PVCControl.CreatePVC(tc, pvc); err != nil && !errors.IsAlreadyExists(err) {
+			return false, fmt.Errorf("tikvScaler.ScaleOut: failed to pre-provision pvc %s/%s in zone %s, error: %v", ns, pvc.Name, zone, err)
+		}
+		klog.Infof("tikvScaler.ScaleOut: pre-provision pvc %s/%s in zone %s for node %s", ns, pvc.Name, zone, node)
+	}
+	status := volumePreProvisioningStatus(tc)
+	if status.PlannedZones == nil {
+		status.PlannedZones = map[string]string{}
+	}
+	status.PlannedZones[podName] = zone
+	status.FallbackReason = ""
+	return true, nil
+}
+
+// planVolumeZone plans the zone of the new TiKV pod of the ordinal round-robin across the zones of the first
+// topology spread constraint, and the node in the zone the volumes are provisioned for. The reason is returned
+// instead if the volumes can not be pre-provisioned.
+func (s *tikvScaler) planVolumeZone(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, ordinal int32) (string, string, string, error) {
+	tscs := tc.BaseTiKVSpec().TopologySpreadConstraints()
+	if len(tscs) == 0 {
+		return "", "", "no topology spread constraints are set", nil
+	}
+	if s.deps.NodeLister == nil || s.deps.StorageClassLister == nil {
+		return "", "", "no permission to list the nodes or the StorageClasses", nil
+	}
+
+	for i := range set.Spec.VolumeClaimTemplates {
+		sc, err := s.volumeStorageClass(&set.Spec.VolumeClaimTemplates[i])
+		if err != nil {
+			return "", "", "", err
+		}
+		if sc == nil {
+			return "", "", fmt.Sprintf("the StorageClass of volume %s is not found", set.Spec.VolumeClaimTemplates[i].Name), nil
+		}
+		if sc.VolumeBindingMode == nil || *sc.VolumeBindingMode != storagev1.VolumeBindingWaitForFirstConsumer {
+			return "", "", fmt.Sprintf("StorageClass %s does not bind the volumes on the first consumer", sc.Name), nil
+		}
+	}
+
+	topologyKey := tscs[0].TopologyKey
+	nodes, err := s.deps.NodeLister.List(labels.Everything())
+	if err != nil {
+		return "", "", "", fmt.Errorf("tikvScaler.ScaleOut: failed to list nodes, error: %v", err)
+	}
+	// the first schedulable node by name of each zone
+	zoneNodes := map[string]string{}
+	for _, node := range nodes {
+		zone, ok := node.Labels[topologyKey]
+		if !ok || node.Spec.Unschedulable {
+			continue
+		}
+		if name, ok := zoneNodes[zone]; !ok || node.Name < name {
+			zoneNodes[zone] = node.Name
+		}
+	}
+	if len(zoneNodes) == 0 {
+		return "", "", fmt.Sprintf("no schedulable nodes are labeled with %s", topologyKey), nil
+	}
+	zones := make([]string, 0, len(zoneNodes))
+	for zone := range zoneNodes {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	zone := zones[int(ordinal)%len(zones)]
+	return zone, zoneNodes[zone], "", nil
+}
+
+// volumeStorageClass returns the StorageClass of the volume claim template, it is the default StorageClass if the
+// template does not set it, nil is returned if it is not found
+func (s *tikvScaler) volumeStorageClass(template *corev1.PersistentVolumeClaim) (*storagev1.StorageClass, error) {
+	if template.Spec.StorageClassName != nil {
+		sc, err := s.deps.StorageClassLister.Get(*template.Spec.StorageClassName)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tikvScaler.ScaleOut: failed to get StorageClass %s, error: %v", *template.Spec.StorageClassName, err)
+		}
+		return sc, nil
+	}
+	scs, err := s.deps.StorageClassLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("tikvScaler.ScaleOut: failed to list StorageClasses, error: %v", err)
+	}
+	for _, sc := range scs {
+		if sc.Annotations[annDefaultStorageClass] == "true" {
+			return sc, nil
+		}
+	}
+	return nil, nil
+}
+
+// preProvisionedVolumesBound returns whether all the pre-provisioned PVCs of the new TiKV pod of the ordinal
+// are bound
+func (s *tikvScaler) preProvisionedVolumesBound(tc *v1alpha1.TidbCluster, set *apps.StatefulSet, ordinal int32) (bool, error) {
+	for i := range set.Spec.VolumeClaimTemplates {
+		pvcName := statefulSetPVCName(set, &set.Spec.VolumeClaimTemplates[i], ordinal)
+		pvc, err := s.deps.PVCLister.PersistentVolumeClaims(tc.GetNamespace()).Get(pvcName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("tikvScaler.ScaleOut: failed to get pvc %s/%s, error: %v", tc.GetNamespace(), pvcName, err)
+		}
+		if pvc.Status.Phase != corev1.ClaimBound {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func volumePreProvisioningStatus(tc *v1alpha1.TidbCluster) *v1alpha1.VolumePreProvisioningStatus {
+	if tc.Status.TiKV.VolumePreProvisioning == nil {
+		tc.Status.TiKV.VolumePreProvisioning = &v1alpha1.VolumePreProvisioningStatus{}
+	}
+	return tc.Status.TiKV.VolumePreProvisioning
+}
+
+// statefulSetPVCName returns the name of the PVC of the volume claim template the StatefulSet creates for the
+// pod of the ordinal
+func statefulSetPVCName(set *apps.StatefulSet, template *corev1.PersistentVolumeClaim, ordinal int32) string {
+	return fmt.Sprintf("%s-%s-%d", template.Name, set.Name, ordinal)
+}
+
+// newPreProvisionedPVC builds the PVC the StatefulSet would create for the pod of the ordinal, it is provisioned
+// for the node in the planned zone.
+func newPreProvisionedPVC(set *apps.StatefulSet, template *corev1.PersistentVolumeClaim, ordinal int32, zone, node string) *corev1.PersistentVolumeClaim {
+	pvc := template.DeepCopy()
+	pvc.Name = statefulSetPVCName(set, template, ordinal)
+	pvc.Namespace = set.Namespace
+	if pvc.Labels == nil {
+		pvc.Labels = map[string]string{}
+	}
+	if set.Spec.Selector != nil {
+		for k, v := range set.Spec.Selector.MatchLabels {
+			pvc.Labels[k] = v
+		}
+	}
+	if pvc.Annotations == nil {
+		pvc.Annotations = map[string]string{}
+	}
+	pvc.Annotations[annSelectedNode] = node
+	pvc.Annotations[label.AnnPVCPreProvisionedZone] = zone
+	return pvc
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestTiKVScalerScaleOutPreProvisionVolumes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newScaler := func(bindingMode storagev1.VolumeBindingMode) (*tikvScaler, *v1alpha1.TidbCluster, *apps.StatefulSet) {
+		scaler, _, _, _, _ := newFakeTiKVScaler()
+		deps := scaler.deps
+		for i, zone := range []string{"zone-b", "zone-a", "zone-c"} {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("node-%d", i),
+				Labels: map[string]string{corev1.LabelZoneFailureDomainStable: zone},
+			}}
+			g.Expect(deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node)).To(Succeed())
+		}
+		sc := &storagev1.StorageClass{
+			ObjectMeta:        metav1.ObjectMeta{Name: "zonal"},
+			VolumeBindingMode: &bindingMode,
+		}
+		g.Expect(deps.KubeInformerFactory.Storage().V1().StorageClasses().Informer().GetIndexer().Add(sc)).To(Succeed())
+
+		tc := newTidbClusterForPD()
+		tc.Status.TiKV.BootStrapped = true
+		tc.Spec.TiKV.PreProvisionVolumes = true
+		tc.Spec.TopologySpreadConstraints = []v1alpha1.TopologySpreadConstraint{{TopologyKey: corev1.LabelZoneFailureDomainStable}}
+
+		set := newStatefulSetForPDScale()
+		set.Name = controller.TiKVMemberName(tc.Name)
+		set.Spec.Selector = &metav1.LabelSelector{MatchLabels: label.New().Instance(tc.Name).TiKV()}
+		set.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "tikv"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: pointer.StringPtr("zonal")},
+		}}
+		return scaler, tc, set
+	}
+
+	// the pvcs are pre-provisioned in the planned zone before the replicas are increased
+	scaler, tc, oldSet := newScaler(storagev1.VolumeBindingWaitForFirstConsumer)
+	newSet := oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(7)
+	err := scaler.ScaleOut(tc, oldSet, newSet)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(5)))
+	pvcName := fmt.Sprintf("tikv-%s-5", oldSet.Name)
+	pvc, err := scaler.deps.PVCLister.PersistentVolumeClaims(tc.Namespace).Get(pvcName)
+	g.Expect(err).NotTo(HaveOccurred())
+	// the ordinal 5 is planned to the third of the sorted zones
+	g.Expect(pvc.Annotations[label.AnnPVCPreProvisionedZone]).To(Equal("zone-c"))
+	g.Expect(pvc.Annotations[annSelectedNode]).To(Equal("node-2"))
+	g.Expect(pvc.Labels).To(HaveKeyWithValue(label.ComponentLabelKey, label.TiKVLabelVal))
+	g.Expect(tc.Status.TiKV.VolumePreProvisioning.PlannedZones).To(Equal(map[string]string{
+		ordinalPodName(v1alpha1.TiKVMemberType, tc.Name, 5): "zone-c",
+	}))
+
+	// the replicas are not increased until the pvcs are bound
+	newSet = oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(7)
+	err = scaler.ScaleOut(tc, oldSet, newSet)
+	g.Expect(controller.IsRequeueError(err)).To(BeTrue())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(5)))
+
+	pvc = pvc.DeepCopy()
+	pvc.Status.Phase = corev1.ClaimBound
+	g.Expect(scaler.deps.KubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer().Update(pvc)).To(Succeed())
+	newSet = oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(7)
+	g.Expect(scaler.ScaleOut(tc, oldSet, newSet)).To(Succeed())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(6)))
+
+	// the status is cleared after the scale-out is done
+	g.Expect(scaler.Scale(tc, newSet, newSet.DeepCopy())).To(Succeed())
+	g.Expect(tc.Status.TiKV.VolumePreProvisioning).To(BeNil())
+
+	// fall back to the plain scale-out if the volumes are bound immediately
+	scaler, tc, oldSet = newScaler(storagev1.VolumeBindingImmediate)
+	newSet = oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(7)
+	g.Expect(scaler.ScaleOut(tc, oldSet, newSet)).To(Succeed())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(6)))
+	g.Expect(tc.Status.TiKV.VolumePreProvisioning.FallbackReason).To(ContainSubstring("does not bind the volumes on the first consumer"))
+	g.Expect(<-scaler.deps.Recorder.(*record.FakeRecorder).Events).To(ContainSubstring(events.VolumePreProvisioningFallback))
+
+	// fall back to the plain scale-out without the topology spread constraints
+	scaler, tc, oldSet = newScaler(storagev1.VolumeBindingWaitForFirstConsumer)
+	tc.Spec.TopologySpreadConstraints = nil
+	newSet = oldSet.DeepCopy()
+	newSet.Spec.Replicas = pointer.Int32Ptr(7)
+	g.Expect(scaler.ScaleOut(tc, oldSet, newSet)).To(Succeed())
+	g.Expect(*newSet.Spec.Replicas).To(Equal(int32(6)))
+	g.Expect(tc.Status.TiKV.VolumePreProvisioning.FallbackReason).To(ContainSubstring("no topology spread constraints"))
+}
@@ -0,0 +1,282 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// syncTiKVVolumeReplace replaces the pod and the volumes of the TiKV pod named by the annotation
+// tikv.tidb.pingcap.com/replace-volume, e.g. if its volume is corrupt, so that its data is re-replicated by raft
+// and the ordinal is kept. The store of the pod is deleted, or deleted forcibly if it is Down, then the pod and its
+// PVCs are deleted and recreated by the StatefulSet. The annotation is removed after the new store of the pod is Up
+// and catches up. The progress is recorded in status.tikv.volumeReplace, so the replacement resumes from its phase
+// after the operator restarts, and only one replacement runs at a time.
+func syncTiKVVolumeReplace(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	podName, requested := tc.Annotations[label.AnnTiKVReplaceVolume]
+
+	replace := tc.Status.TiKV.VolumeReplace
+	if replace != nil && replace.CompletionTime == nil {
+		if requested && podName != replace.PodName {
+			msg := fmt.Sprintf("the replacement of the volumes of %s is refused while the volumes of %s are being replaced", podName, replace.PodName)
+			klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+			deps.Recorder.Event(tc, corev1.EventTypeWarning, events.VolumeReplaceRefused, msg)
+			if err := removeTiKVReplaceVolumeAnnotation(deps, tc); err != nil {
+				return err
+			}
+		}
+		return continueTiKVVolumeReplace(deps, tc, replace)
+	}
+	if !requested {
+		return nil
+	}
+
+	ordinal, err := util.GetOrdinalFromPodName(podName)
+	if err != nil || TikvPodName(tcName, ordinal) != podName || !tc.TiKVStsDesiredOrdinals(true).Has(ordinal) {
+		msg := fmt.Sprintf("the replacement of the volumes of %s is refused, it is not a tikv pod of the cluster", podName)
+		klog.Warningf("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+		deps.Recorder.Event(tc, corev1.EventTypeWarning, events.VolumeReplaceRefused, msg)
+		return removeTiKVReplaceVolumeAnnotation(deps, tc)
+	}
+	// the replacement waits with the annotation kept until the cluster is healthy enough
+	if reason, err := tikvVolumeReplaceBlocker(deps, tc, podName); err != nil || reason != "" {
+		if reason != "" {
+			msg := fmt.Sprintf("the replacement of the volumes of %s is waiting, %s", podName, reason)
+			klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+			deps.Recorder.Event(tc, corev1.EventTypeWarning, events.VolumeReplaceRefused, msg)
+		}
+		return err
+	}
+
+	pod, err := deps.PodLister.Pods(ns).Get(podName)
+	if err != nil {
+		return fmt.Errorf("syncTiKVVolumeReplace: failed to get pod %s/%s, error: %v", ns, podName, err)
+	}
+	pvcSelector, err := GetPVCSelectorForPod(tc, v1alpha1.TiKVMemberType, ordinal)
+	if err != nil {
+		return fmt.Errorf("syncTiKVVolumeReplace: failed to get PVC selector for pod %s/%s, error: %v", ns, podName, err)
+	}
+	pvcs, err := deps.PVCLister.PersistentVolumeClaims(ns).List(pvcSelector)
+	if err != nil {
+		return fmt.Errorf("syncTiKVVolumeReplace: failed to get PVCs for pod %s/%s, error: %v", ns, podName, err)
+	}
+	pvcUIDSet := map[types.UID]v1alpha1.EmptyStruct{}
+	for _, pvc := range pvcs {
+		pvcUIDSet[pvc.UID] = v1alpha1.EmptyStruct{}
+	}
+	tc.Status.TiKV.VolumeReplace = &v1alpha1.TiKVVolumeReplaceStatus{
+		PodName:   podName,
+		Phase:     v1alpha1.TiKVVolumeReplaceDeletingStore,
+		StoreID:   tikvStoreIDOfPod(tc, podName),
+		PodUID:    pod.UID,
+		PVCUIDSet: pvcUIDSet,
+		StartTime: metav1.Now(),
+	}
+	msg := fmt.Sprintf("replace the pod and the volumes of %s, store %s is deleted", podName, tc.Status.TiKV.VolumeReplace.StoreID)
+	klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+	deps.Recorder.Event(tc, corev1.EventTypeNormal, events.VolumeReplaceStarted, msg)
+	return nil
+}
+
+// tikvVolumeReplaceBlocker returns why the replacement of the volumes of the pod can not start, it is empty if the
+// store of the pod exists, TiKV is not upgrading or scaling, all the other stores are Up and they are not less
+// than the max replicas of PD, so that the data of the store can be re-replicated from the other stores.
+func tikvVolumeReplaceBlocker(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, podName string) (string, error) {
+	if tc.Status.TiKV.Phase != v1alpha1.NormalPhase {
+		return fmt.Sprintf("tikv is in %s phase", tc.Status.TiKV.Phase), nil
+	}
+	if tikvStoreIDOfPod(tc, podName) == "" {
+		return "the pod has no store", nil
+	}
+	upStores := 0
+	for _, store := range tc.Status.TiKV.Stores {
+		if store.PodName == podName {
+			continue
+		}
+		if store.State != v1alpha1.TiKVStateUp {
+			return fmt.Sprintf("store %s of pod %s is %s", store.ID, store.PodName, store.State), nil
+		}
+		upStores++
+	}
+	config, err := controller.GetPDClient(deps.PDControl, tc).GetConfig()
+	if err != nil {
+		return "", requeueIfPDCircuitOpen(err)
+	}
+	if config.Replication == nil || config.Replication.MaxReplicas == nil {
+		return "", fmt.Errorf("tikvVolumeReplaceBlocker: max replicas of PD of cluster %s/%s is unknown", tc.GetNamespace(), tc.GetName())
+	}
+	if maxReplicas := *config.Replication.MaxReplicas; uint64(upStores) < maxReplicas {
+		return fmt.Sprintf("%d other stores are Up, less than the max replicas %d", upStores, maxReplicas), nil
+	}
+	return "", nil
+}
+
+// continueTiKVVolumeReplace moves the replacement in progress forward by one phase if it is ready to, the steps are
+// idempotent, so a step interrupted by the operator restarts is done again in the next sync.
+func continueTiKVVolumeReplace(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, replace *v1alpha1.TiKVVolumeReplaceStatus) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	if tc.Status.TiKV.StaleSince != nil || len(tc.Status.TiKV.Stores) == 0 {
+		klog.V(4).Infof("tidbcluster: [%s/%s] the stores are not synced from PD, skip replacing the volumes of %s", ns, tcName, replace.PodName)
+		return nil
+	}
+	pdClient := controller.GetPDClient(deps.PDControl, tc)
+
+	switch replace.Phase {
+	case v1alpha1.TiKVVolumeReplaceDeletingStore:
+		store, exist := tc.Status.TiKV.Stores[replace.StoreID]
+		if !exist || replace.Forced {
+			// the store is tombstone or removed from PD, or its address can be reused by the new store
+			replace.Phase = v1alpha1.TiKVVolumeReplaceDeletingPod
+			return nil
+		}
+		id, err := strconv.ParseUint(replace.StoreID, 10, 64)
+		if err != nil {
+			return err
+		}
+		switch store.State {
+		case v1alpha1.TiKVStateDown:
+			if err := pdClient.ForceDeleteStore(id); err != nil {
+				return requeueIfPDCircuitOpen(err)
+			}
+			klog.Infof("tidbcluster: [%s/%s] store %d of pod %s is Down, deleted it forcibly", ns, tcName, id, replace.PodName)
+			replace.Forced = true
+			replace.Phase = v1alpha1.TiKVVolumeReplaceDeletingPod
+		case v1alpha1.TiKVStateUp:
+			if err := pdClient.DeleteStore(id); err != nil {
+				return requeueIfPDCircuitOpen(err)
+			}
+			klog.Infof("tidbcluster: [%s/%s] deleted store %d of pod %s, waiting for it to be tombstone", ns, tcName, id, replace.PodName)
+		default:
+			klog.V(4).Infof("tidbcluster: [%s/%s] store %d of pod %s is %s, waiting for it to be tombstone", ns, tcName, id, replace.PodName, store.State)
+		}
+		return nil
+
+	case v1alpha1.TiKVVolumeReplaceDeletingPod:
+		// The pod is recreated by the StatefulSet with the PVCs being deleted if it is created before they are
+		// gone, it pends on the missing PVCs and is deleted by the OrphanPodsCleaner, then the StatefulSet
+		// recreates both of them.
+		pod, err := deps.PodLister.Pods(ns).Get(replace.PodName)
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("continueTiKVVolumeReplace: failed to get pod %s/%s, error: %v", ns, replace.PodName, err)
+		}
+		if pod != nil && pod.UID == replace.PodUID && pod.DeletionTimestamp == nil {
+			if err := deps.PodControl.DeletePod(tc, pod); err != nil {
+				return err
+			}
+		}
+		ordinal, err := util.GetOrdinalFromPodName(replace.PodName)
+		if err != nil {
+			return err
+		}
+		pvcSelector, err := GetPVCSelectorForPod(tc, v1alpha1.TiKVMemberType, ordinal)
+		if err != nil {
+			return fmt.Errorf("continueTiKVVolumeReplace: failed to get PVC selector for pod %s/%s, error: %v", ns, replace.PodName, err)
+		}
+		pvcs, err := deps.PVCLister.PersistentVolumeClaims(ns).List(pvcSelector)
+		if err != nil {
+			return fmt.Errorf("continueTiKVVolumeReplace: failed to get PVCs for pod %s/%s, error: %v", ns, replace.PodName, err)
+		}
+		for _, pvc := range pvcs {
+			if _, ok := replace.PVCUIDSet[pvc.UID]; !ok || pvc.DeletionTimestamp != nil {
+				continue
+			}
+			if err := deps.PVCControl.DeletePVC(tc, pvc); err != nil {
+				return err
+			}
+		}
+		klog.Infof("tidbcluster: [%s/%s] deleted pod %s and its PVCs, waiting for the new store", ns, tcName, replace.PodName)
+		replace.Phase = v1alpha1.TiKVVolumeReplaceWaitingStore
+		return nil
+
+	case v1alpha1.TiKVVolumeReplaceWaitingStore:
+		newStoreID := ""
+		for id, store := range tc.Status.TiKV.Stores {
+			if store.PodName == replace.PodName && id != replace.StoreID && store.State == v1alpha1.TiKVStateUp {
+				newStoreID = id
+			}
+		}
+		if newStoreID == "" {
+			klog.V(4).Infof("tidbcluster: [%s/%s] the new store of pod %s is not Up", ns, tcName, replace.PodName)
+			return nil
+		}
+		id, err := strconv.ParseUint(newStoreID, 10, 64)
+		if err != nil {
+			return err
+		}
+		info, err := pdClient.GetStore(id)
+		if err != nil {
+			return requeueIfPDCircuitOpen(err)
+		}
+		if info.Status == nil || info.Status.RegionCount == 0 || info.Status.ReceivingSnapCount > 0 || info.Status.ApplyingSnapCount > 0 {
+			klog.V(4).Infof("tidbcluster: [%s/%s] the new store %s of pod %s is catching up", ns, tcName, newStoreID, replace.PodName)
+			return nil
+		}
+
+		if tc.Annotations[label.AnnTiKVReplaceVolume] == replace.PodName {
+			if err := removeTiKVReplaceVolumeAnnotation(deps, tc); err != nil {
+				return err
+			}
+		}
+		now := metav1.Now()
+		replace.NewStoreID = newStoreID
+		replace.Phase = v1alpha1.TiKVVolumeReplaceCompleted
+		replace.CompletionTime = &now
+		msg := fmt.Sprintf("the pod and the volumes of %s are replaced, store %s is replaced by store %s", replace.PodName, replace.StoreID, newStoreID)
+		klog.Infof("tidbcluster: [%s/%s] %s", ns, tcName, msg)
+		deps.Recorder.Event(tc, corev1.EventTypeNormal, events.VolumeReplaceCompleted, msg)
+	}
+	return nil
+}
+
+// tikvStoreIDOfPod returns the id of the store of the pod in the stores of the cluster, it is empty if not found
+func tikvStoreIDOfPod(tc *v1alpha1.TidbCluster, podName string) string {
+	for id, store := range tc.Status.TiKV.Stores {
+		if store.PodName == podName {
+			return id
+		}
+	}
+	return ""
+}
+
+func removeTiKVReplaceVolumeAnnotation(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{label.AnnTiKVReplaceVolume: nil},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := deps.TiDBClusterControl.Patch(tc, patch); err != nil {
+		return err
+	}
+	delete(tc.Annotations, label.AnnTiKVReplaceVolume)
+	return nil
+}
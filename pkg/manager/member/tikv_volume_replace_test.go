@@ -0,0 +1,179 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// addVolumeReplacePod adds the pod and its PVC of the uid to the informers
+func addVolumeReplacePod(g *GomegaWithT, deps *controller.Dependencies, tc *v1alpha1.TidbCluster, podName string, uid types.UID) (cache.Indexer, cache.Indexer) {
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	pvcIndexer := deps.KubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer()
+	g.Expect(podIndexer.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: tc.Namespace,
+			UID:       uid,
+			Labels:    label.New().Instance(tc.GetInstanceName()).TiKV().Labels(),
+		},
+	})).To(Succeed())
+	pvcLabels := label.New().Instance(tc.GetInstanceName()).TiKV()
+	pvcLabels[label.AnnPodNameKey] = podName
+	g.Expect(pvcIndexer.Add(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tikv-" + podName,
+			Namespace: tc.Namespace,
+			UID:       uid,
+			Labels:    pvcLabels,
+		},
+	})).To(Succeed())
+	return podIndexer, pvcIndexer
+}
+
+func TestSyncTiKVVolumeReplace(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForTiKV()
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.Stores = map[string]v1alpha1.TiKVStore{}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("%d", i+1)
+		tc.Status.TiKV.Stores[id] = v1alpha1.TiKVStore{ID: id, PodName: TikvPodName(tc.Name, int32(i)), State: v1alpha1.TiKVStateUp}
+	}
+	podIndexer, pvcIndexer := addVolumeReplacePod(g, deps, tc, "test-tikv-1", "old")
+
+	var maxReplicas uint64 = 3
+	var deleted, forceDeleted []uint64
+	regionCount := 0
+	pdClient := controller.NewFakePDClient(deps.PDControl.(*pdapi.FakePDControl), tc)
+	pdClient.AddReaction(pdapi.GetConfigActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.PDConfigFromAPI{Replication: &pdapi.PDReplicationConfig{MaxReplicas: &maxReplicas}}, nil
+	})
+	pdClient.AddReaction(pdapi.DeleteStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+		deleted = append(deleted, action.ID)
+		return nil, nil
+	})
+	pdClient.AddReaction(pdapi.ForceDeleteStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+		forceDeleted = append(forceDeleted, action.ID)
+		return nil, nil
+	})
+	pdClient.AddReaction(pdapi.GetStoreActionType, func(action *pdapi.Action) (interface{}, error) {
+		return &pdapi.StoreInfo{Status: &pdapi.StoreStatus{RegionCount: regionCount}}, nil
+	})
+
+	// the pod which is not a tikv pod of the cluster is refused and the annotation is removed
+	tc.Annotations = map[string]string{label.AnnTiKVReplaceVolume: "test-tikv-5"}
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnTiKVReplaceVolume))
+	g.Expect(tc.Status.TiKV.VolumeReplace).To(BeNil())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.VolumeReplaceRefused))
+
+	// the replacement waits with the annotation kept if the other stores are less than the max replicas
+	tc.Annotations = map[string]string{label.AnnTiKVReplaceVolume: "test-tikv-1"}
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(tc.Annotations).To(HaveKey(label.AnnTiKVReplaceVolume))
+	g.Expect(tc.Status.TiKV.VolumeReplace).To(BeNil())
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring("less than the max replicas 3"))
+
+	maxReplicas = 2
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	replace := tc.Status.TiKV.VolumeReplace
+	g.Expect(replace).NotTo(BeNil())
+	g.Expect(replace.PodName).To(Equal("test-tikv-1"))
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceDeletingStore))
+	g.Expect(replace.StoreID).To(Equal("2"))
+	g.Expect(replace.PodUID).To(Equal(types.UID("old")))
+	g.Expect(replace.PVCUIDSet).To(HaveKey(types.UID("old")))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.VolumeReplaceStarted))
+
+	// the replacement of another pod is refused while one is in progress, and the Up store is deleted
+	tc.Annotations[label.AnnTiKVReplaceVolume] = "test-tikv-0"
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnTiKVReplaceVolume))
+	g.Expect(tc.Status.TiKV.VolumeReplace.PodName).To(Equal("test-tikv-1"))
+	g.Expect(deleted).To(Equal([]uint64{2}))
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceDeletingStore))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring("test-tikv-0 is refused"))
+
+	// the pod and its PVC are deleted after the store is tombstone
+	tc.Status.TiKV.TombstoneStores = map[string]v1alpha1.TiKVStore{"2": tc.Status.TiKV.Stores["2"]}
+	delete(tc.Status.TiKV.Stores, "2")
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceDeletingPod))
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceWaitingStore))
+	g.Expect(podIndexer.List()).To(BeEmpty())
+	g.Expect(pvcIndexer.List()).To(BeEmpty())
+
+	// the recreated pod and PVC are never deleted
+	replace.Phase = v1alpha1.TiKVVolumeReplaceDeletingPod
+	addVolumeReplacePod(g, deps, tc, "test-tikv-1", "new")
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(podIndexer.List()).To(HaveLen(1))
+	g.Expect(pvcIndexer.List()).To(HaveLen(1))
+
+	// the replacement completes after the new store is Up and catches up
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceWaitingStore))
+	tc.Status.TiKV.Stores["4"] = v1alpha1.TiKVStore{ID: "4", PodName: "test-tikv-1", State: v1alpha1.TiKVStateUp}
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceWaitingStore))
+	regionCount = 10
+	tc.Annotations[label.AnnTiKVReplaceVolume] = "test-tikv-1"
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceCompleted))
+	g.Expect(replace.NewStoreID).To(Equal("4"))
+	g.Expect(replace.CompletionTime).NotTo(BeNil())
+	g.Expect(tc.Annotations).NotTo(HaveKey(label.AnnTiKVReplaceVolume))
+	recorded = collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(ContainSubstring(events.VolumeReplaceCompleted))
+	g.Expect(forceDeleted).To(BeEmpty())
+
+	// the Down store is deleted forcibly and the pod is deleted without waiting for the store to be tombstone
+	tc.Status.TiKV.Stores["4"] = v1alpha1.TiKVStore{ID: "4", PodName: "test-tikv-1", State: v1alpha1.TiKVStateDown}
+	tc.Annotations[label.AnnTiKVReplaceVolume] = "test-tikv-1"
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	replace = tc.Status.TiKV.VolumeReplace
+	g.Expect(replace.StoreID).To(Equal("4"))
+	g.Expect(replace.CompletionTime).To(BeNil())
+	g.Expect(syncTiKVVolumeReplace(deps, tc)).To(Succeed())
+	g.Expect(forceDeleted).To(Equal([]uint64{4}))
+	g.Expect(replace.Forced).To(BeTrue())
+	g.Expect(replace.Phase).To(Equal(v1alpha1.TiKVVolumeReplaceDeletingPod))
+}
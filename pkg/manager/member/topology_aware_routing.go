@@ -0,0 +1,229 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	errorutils "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+// topologyAwareRoutingAnnotations make kube-proxy prefer the endpoints in the zone of the client, the former is
+// respected by Kubernetes 1.23 to 1.26 and the latter by 1.27 and later. The internalTrafficPolicy of the services
+// is left as it is, since Local drops the traffic from the nodes without a local endpoint rather than preferring
+// the zone.
+var topologyAwareRoutingAnnotations = map[string]string{
+	"service.kubernetes.io/topology-aware-hints": "auto",
+	"service.kubernetes.io/topology-mode":        "Auto",
+}
+
+// invalidServiceNameChars are the characters a zone may have but a service name may not
+var invalidServiceNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// setTopologyAwareRouting annotates svc for the topology aware routing if spec.networking.topologyAwareRouting
+// is enabled
+func setTopologyAwareRouting(tc *v1alpha1.TidbCluster, svc *corev1.Service) {
+	if !tc.TopologyAwareRouting() {
+		return
+	}
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	for k, v := range topologyAwareRoutingAnnotations {
+		svc.Annotations[k] = v
+	}
+}
+
+// syncTopologyAwareRoutingAnnotations sets the topology aware routing annotations of svc to the ones of newSvc,
+// which are removed from svc if newSvc does not have them, it returns true if svc is changed. The other annotations
+// are synced by the spec of the services, or not at all, as they are.
+func syncTopologyAwareRoutingAnnotations(newSvc, svc *corev1.Service) bool {
+	changed := false
+	for k := range topologyAwareRoutingAnnotations {
+		v, ok := newSvc.Annotations[k]
+		if old, exist := svc.Annotations[k]; ok == exist && v == old {
+			continue
+		}
+		changed = true
+		if !ok {
+			delete(svc.Annotations, k)
+			continue
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = map[string]string{}
+		}
+		svc.Annotations[k] = v
+	}
+	return changed
+}
+
+// syncZoneServices creates a headless service for each zone the pods selected by peerSvc are in if
+// spec.networking.topologyAwareRouting is enabled, so that the clients may resolve the pods in their own zones.
+// The pods are labeled with the zones of their nodes and selected by the labels, the pods not scheduled yet or
+// whose nodes have no zone are left out. The services of the zones without pods are deleted, and all of them
+// are deleted once it is disabled.
+func syncZoneServices(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, peerSvc *corev1.Service) error {
+	ns := tc.GetNamespace()
+	tcName := tc.GetName()
+	svcs, err := deps.ServiceLister.Services(ns).List(labels.SelectorFromSet(peerSvc.Labels))
+	if err != nil {
+		return fmt.Errorf("syncZoneServices: failed to list the services of %s for cluster %s/%s, error: %v", peerSvc.Name, ns, tcName, err)
+	}
+	existing := map[string]*corev1.Service{}
+	for _, svc := range svcs {
+		if zone := svc.Labels[label.ZoneLabelKey]; zone != "" {
+			existing[zone] = svc
+		}
+	}
+
+	desired := map[string]*corev1.Service{}
+	if tc.TopologyAwareRouting() {
+		if deps.NodeLister == nil {
+			klog.V(4).Infof("tidbcluster: [%s/%s] node lister is unavailable, skip syncing the zone services of %s", ns, tcName, peerSvc.Name)
+			return nil
+		}
+		zones, err := labelPodZones(deps, tc, peerSvc)
+		if err != nil {
+			return err
+		}
+		for _, zone := range zones {
+			svc := newZoneService(peerSvc, zone)
+			if errs := validation.IsDNS1035Label(svc.Name); len(errs) > 0 {
+				klog.Warningf("tidbcluster: [%s/%s] skip creating the service %s of zone %s: %s", ns, tcName, svc.Name, zone, strings.Join(errs, ", "))
+				continue
+			}
+			desired[zone] = svc
+		}
+	}
+
+	var errs []error
+	for zone, svc := range desired {
+		old, ok := existing[zone]
+		if !ok {
+			if err := controller.SetServiceLastAppliedConfigAnnotation(svc); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := deps.ServiceControl.CreateService(tc, svc); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		equal, err := controller.ServiceEqual(svc, old)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if equal {
+			continue
+		}
+		updated := old.DeepCopy()
+		updated.Spec = svc.Spec
+		if err := controller.SetServiceLastAppliedConfigAnnotation(updated); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := deps.ServiceControl.UpdateService(tc, updated); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for zone, svc := range existing {
+		if _, ok := desired[zone]; ok {
+			continue
+		}
+		klog.Infof("tidbcluster: [%s/%s] delete the service %s of zone %s", ns, tcName, svc.Name, zone)
+		if err := deps.ServiceControl.DeleteService(tc, svc); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errorutils.NewAggregate(errs)
+}
+
+// labelPodZones labels the pods selected by peerSvc with the zones of their nodes and returns the zones
+func labelPodZones(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, peerSvc *corev1.Service) ([]string, error) {
+	ns := tc.GetNamespace()
+	pods, err := deps.PodLister.Pods(ns).List(labels.SelectorFromSet(peerSvc.Spec.Selector))
+	if err != nil {
+		return nil, fmt.Errorf("labelPodZones: failed to list the pods of %s for cluster %s/%s, error: %v", peerSvc.Name, ns, tc.GetName(), err)
+	}
+	zones := map[string]struct{}{}
+	var errs []error
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		node, err := deps.NodeLister.Get(pod.Spec.NodeName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		zone := ""
+		for _, key := range nodeZoneLabels {
+			if z, ok := node.Labels[key]; ok {
+				zone = z
+				break
+			}
+		}
+		if zone == "" {
+			continue
+		}
+		zones[zone] = struct{}{}
+		if pod.Labels[label.ZoneLabelKey] == zone {
+			continue
+		}
+		labeled := pod.DeepCopy()
+		if labeled.Labels == nil {
+			labeled.Labels = map[string]string{}
+		}
+		labeled.Labels[label.ZoneLabelKey] = zone
+		if _, err := deps.PodControl.UpdatePod(tc, labeled); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var list []string
+	for zone := range zones {
+		list = append(list, zone)
+	}
+	return list, errorutils.NewAggregate(errs)
+}
+
+// newZoneService returns the headless service of the pods selected by peerSvc in the zone, named after peerSvc
+// and the zone
+func newZoneService(peerSvc *corev1.Service, zone string) *corev1.Service {
+	suffix := strings.Trim(invalidServiceNameChars.ReplaceAllString(strings.ToLower(zone), "-"), "-")
+	svc := &corev1.Service{
+		ObjectMeta: *peerSvc.ObjectMeta.DeepCopy(),
+		Spec:       *peerSvc.Spec.DeepCopy(),
+	}
+	svc.Name = fmt.Sprintf("%s-%s", peerSvc.Name, suffix)
+	svc.Annotations = nil
+	svc.Labels[label.ZoneLabelKey] = zone
+	svc.Spec.Selector[label.ZoneLabelKey] = zone
+	svc.Spec.ClusterIP = corev1.ClusterIPNone
+	svc.Spec.PublishNotReadyAddresses = true
+	return svc
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestSyncZoneServices(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	nodeIndexer := deps.KubeInformerFactory.Core().V1().Nodes().Informer().GetIndexer()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	tc := newTidbClusterForTiKV()
+	svcConfig := SvcConfig{
+		Name:       "peer",
+		Port:       20160,
+		Headless:   true,
+		SvcLabel:   func(l label.Label) label.Label { return l.TiKV() },
+		MemberName: controller.TiKVPeerMemberName,
+	}
+	peerSvc := getNewServiceForTidbCluster(tc, svcConfig)
+
+	for name, zone := range map[string]string{"node-1": "us-west-2a", "node-2": "US_West_2b", "node-3": ""} {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if zone != "" {
+			node.Labels["topology.kubernetes.io/zone"] = zone
+		}
+		g.Expect(nodeIndexer.Add(node)).To(Succeed())
+	}
+	for name, node := range map[string]string{"test-tikv-0": "node-1", "test-tikv-1": "node-2", "test-tikv-2": "node-3", "test-tikv-3": ""} {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: tc.Namespace, Labels: peerSvc.Spec.Selector},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+		g.Expect(podIndexer.Add(pod)).To(Succeed())
+	}
+	zoneServices := func() map[string]*corev1.Service {
+		svcs, err := deps.ServiceLister.Services(tc.Namespace).List(labels.Everything())
+		g.Expect(err).To(Succeed())
+		zones := map[string]*corev1.Service{}
+		for _, svc := range svcs {
+			if zone, ok := svc.Labels[label.ZoneLabelKey]; ok {
+				zones[zone] = svc
+			}
+		}
+		return zones
+	}
+
+	// no service is created unless enabled
+	g.Expect(syncZoneServices(deps, tc, peerSvc)).To(Succeed())
+	g.Expect(zoneServices()).To(BeEmpty())
+	g.Expect(peerSvc.Annotations).NotTo(HaveKey("service.kubernetes.io/topology-mode"))
+
+	// the pods are labeled with the zones of their nodes and selected by the services of the zones
+	tc.Spec.Networking = &v1alpha1.NetworkingSpec{TopologyAwareRouting: true}
+	peerSvc = getNewServiceForTidbCluster(tc, svcConfig)
+	g.Expect(peerSvc.Annotations).To(HaveKeyWithValue("service.kubernetes.io/topology-mode", "Auto"))
+	g.Expect(syncZoneServices(deps, tc, peerSvc)).To(Succeed())
+	svcs := zoneServices()
+	g.Expect(svcs).To(HaveLen(2))
+	g.Expect(svcs["us-west-2a"].Name).To(Equal("test-tikv-peer-us-west-2a"))
+	g.Expect(svcs["US_West_2b"].Name).To(Equal("test-tikv-peer-us-west-2b"))
+	g.Expect(svcs["us-west-2a"].Spec.Selector).To(HaveKeyWithValue(label.ZoneLabelKey, "us-west-2a"))
+	g.Expect(svcs["us-west-2a"].Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+	g.Expect(svcs["us-west-2a"].Annotations).NotTo(HaveKey("service.kubernetes.io/topology-mode"))
+	for name, zone := range map[string]string{"test-tikv-0": "us-west-2a", "test-tikv-1": "US_West_2b", "test-tikv-2": "", "test-tikv-3": ""} {
+		pod, err := deps.PodLister.Pods(tc.Namespace).Get(name)
+		g.Expect(err).To(Succeed())
+		g.Expect(pod.Labels[label.ZoneLabelKey]).To(Equal(zone))
+	}
+
+	// the services of the zones without pods are deleted
+	g.Expect(podIndexer.Delete(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-tikv-1", Namespace: tc.Namespace}})).To(Succeed())
+	g.Expect(syncZoneServices(deps, tc, peerSvc)).To(Succeed())
+	svcs = zoneServices()
+	g.Expect(svcs).To(HaveLen(1))
+	g.Expect(svcs).To(HaveKey("us-west-2a"))
+
+	// all of the services are deleted and the annotations are removed once disabled
+	tc.Spec.Networking.TopologyAwareRouting = false
+	newSvc := getNewServiceForTidbCluster(tc, svcConfig)
+	g.Expect(syncTopologyAwareRoutingAnnotations(newSvc, peerSvc)).To(BeTrue())
+	g.Expect(peerSvc.Annotations).NotTo(HaveKey("service.kubernetes.io/topology-aware-hints"))
+	g.Expect(peerSvc.Annotations).NotTo(HaveKey("service.kubernetes.io/topology-mode"))
+	g.Expect(syncTopologyAwareRoutingAnnotations(newSvc, peerSvc)).To(BeFalse())
+	g.Expect(syncZoneServices(deps, tc, newSvc)).To(Succeed())
+	g.Expect(zoneServices()).To(BeEmpty())
+}
@@ -0,0 +1,316 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultUpgradeCompletionWebhookTimeout = 5 * time.Minute
+	upgradeCompletionWebhookRequestTimeout = 10 * time.Second
+	// upgradeCompletionWebhookMinBackoff and upgradeCompletionWebhookMaxBackoff bound the backoff between the
+	// requests after the webhook fails, it is doubled by each failure
+	upgradeCompletionWebhookMinBackoff = 5 * time.Second
+	upgradeCompletionWebhookMaxBackoff = time.Minute
+	// upgradeCompletionWebhookResultTTL is how long the result of a request is kept if no sync takes it, e.g.
+	// the cluster is deleted while the request is in flight
+	upgradeCompletionWebhookResultTTL = 10 * time.Minute
+)
+
+// upgradeCompletionWebhooks sends the requests to the upgrade completion webhooks of all the clusters
+var upgradeCompletionWebhooks = newUpgradeCompletionWebhookSender(newUpgradeCompletionWebhookClient())
+
+// upgradeCompletionRequest is the body of the request sent to the upgrade completion webhook
+type upgradeCompletionRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Component string `json:"component"`
+	Revision  string `json:"revision"`
+	// ChangeRequestID is the change request ID of the upgrade, see label.AnnChangeRequestID
+	ChangeRequestID string `json:"changeRequestID,omitempty"`
+}
+
+// finalizeUpgrade returns the phase of the component after its upgrade completion webhook is considered.
+// Once the upgrade of the component is done, i.e. the phase changes from UpgradePhase to NormalPhase,
+// the component stays in UpgradePhase with the ComponentUpgradeFinalizing condition until the webhook
+// succeeds or its timeout elapses, the time of the condition is when finalizing starts.
+// The requests are sent in the background so that a slow webhook never blocks the sync, the result of a
+// request is taken by the next sync, and a failed request is retried with backoff. The progress is kept in
+// status.upgradeCompletionWebhooks, a request in flight is sent again if its result is lost, e.g. after
+// tidb-controller-manager restarts.
+func finalizeUpgrade(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	oldPhase, newPhase v1alpha1.MemberPhase, setStatus *apps.StatefulSetStatus) v1alpha1.MemberPhase {
+	var status v1alpha1.ComponentStatus
+	for _, s := range v1alpha1.ComponentStatusFromTC(tc) {
+		if s.GetMemberType() == memberType {
+			status = s
+		}
+	}
+	if status == nil {
+		return newPhase
+	}
+
+	webhook := tc.UpgradeCompletionWebhook(memberType)
+	if webhook != nil && !upgradeWebhookSelected(deps, tc) {
+		klog.V(4).Infof("tidbcluster: [%s/%s] does not match -upgrade-webhook-cluster-selector, skip the %s upgrade completion webhook", tc.GetNamespace(), tc.GetName(), memberType)
+		webhook = nil
+	}
+	if webhook == nil || oldPhase != v1alpha1.UpgradePhase || newPhase != v1alpha1.NormalPhase {
+		completeUpgradeFinalizing(tc, status, memberType)
+		return newPhase
+	}
+
+	if meta.FindStatusCondition(status.GetConditions(), v1alpha1.ComponentUpgradeFinalizing) == nil {
+		status.SetCondition(metav1.Condition{
+			Type:    v1alpha1.ComponentUpgradeFinalizing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "WaitingForWebhook",
+			Message: "Waiting for the upgrade completion webhook",
+		})
+	}
+	cond := meta.FindStatusCondition(status.GetConditions(), v1alpha1.ComponentUpgradeFinalizing)
+
+	timeout := defaultUpgradeCompletionWebhookTimeout
+	if webhook.Timeout != nil {
+		if d, err := time.ParseDuration(*webhook.Timeout); err == nil {
+			timeout = d
+		}
+	}
+	if time.Since(cond.LastTransitionTime.Time) > timeout {
+		msg := fmt.Sprintf("%s upgrade completion webhook does not succeed in %s, complete the upgrade", memberType, timeout)
+		klog.Warningf("tidbcluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), msg)
+		recordUpgradeEvent(deps.Recorder, tc, corev1.EventTypeWarning, events.UpgradeCompletionWebhookTimeout, msg)
+		completeUpgradeFinalizing(tc, status, memberType)
+		return newPhase
+	}
+
+	var revision string
+	if setStatus != nil {
+		revision = setStatus.UpdateRevision
+	}
+	progress, ok := tc.Status.UpgradeCompletionWebhooks[memberType.String()]
+	if !ok || progress.Revision != revision {
+		progress = v1alpha1.UpgradeCompletionWebhookStatus{Revision: revision}
+	}
+	key := upgradeCompletionWebhookKey(tc, memberType, &progress)
+
+	if progress.Attempts > 0 && progress.NextAttemptTime == nil {
+		done, err := upgradeCompletionWebhooks.take(key)
+		if !done {
+			return v1alpha1.UpgradePhase
+		}
+		if err == nil {
+			klog.Infof("tidbcluster: [%s/%s] %s upgrade completion webhook succeeded", tc.GetNamespace(), tc.GetName(), memberType)
+			completeUpgradeFinalizing(tc, status, memberType)
+			return newPhase
+		}
+		klog.Infof("tidbcluster: [%s/%s] %s upgrade completion webhook does not succeed: %v", tc.GetNamespace(), tc.GetName(), memberType, err)
+		next := metav1.NewTime(progress.LastAttemptTime.Add(upgradeCompletionWebhookBackoff(progress.Attempts)))
+		progress.NextAttemptTime = &next
+		progress.LastError = err.Error()
+		setUpgradeCompletionWebhookStatus(tc, memberType, progress)
+		status.SetCondition(metav1.Condition{
+			Type:    v1alpha1.ComponentUpgradeFinalizing,
+			Status:  metav1.ConditionTrue,
+			Reason:  "WebhookFailed",
+			Message: err.Error(),
+		})
+		return v1alpha1.UpgradePhase
+	}
+	if progress.NextAttemptTime != nil && time.Now().Before(progress.NextAttemptTime.Time) {
+		return v1alpha1.UpgradePhase
+	}
+
+	progress.Attempts++
+	progress.LastAttemptTime = metav1.Now()
+	progress.NextAttemptTime = nil
+	setUpgradeCompletionWebhookStatus(tc, memberType, progress)
+	upgradeCompletionWebhooks.send(upgradeCompletionWebhookKey(tc, memberType, &progress), webhook.URL, &upgradeCompletionRequest{
+		Namespace:       tc.GetNamespace(),
+		Name:            tc.GetName(),
+		Component:       memberType.String(),
+		Revision:        revision,
+		ChangeRequestID: tc.ChangeRequestID(),
+	})
+	return v1alpha1.UpgradePhase
+}
+
+// completeUpgradeFinalizing removes the ComponentUpgradeFinalizing condition and the progress of the webhook
+func completeUpgradeFinalizing(tc *v1alpha1.TidbCluster, status v1alpha1.ComponentStatus, memberType v1alpha1.MemberType) {
+	status.RemoveCondition(v1alpha1.ComponentUpgradeFinalizing)
+	delete(tc.Status.UpgradeCompletionWebhooks, memberType.String())
+	if len(tc.Status.UpgradeCompletionWebhooks) == 0 {
+		tc.Status.UpgradeCompletionWebhooks = nil
+	}
+}
+
+func setUpgradeCompletionWebhookStatus(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, progress v1alpha1.UpgradeCompletionWebhookStatus) {
+	if tc.Status.UpgradeCompletionWebhooks == nil {
+		tc.Status.UpgradeCompletionWebhooks = map[string]v1alpha1.UpgradeCompletionWebhookStatus{}
+	}
+	tc.Status.UpgradeCompletionWebhooks[memberType.String()] = progress
+}
+
+// upgradeCompletionWebhookKey identifies the request of the attempt, so that the result of a request sent before
+// the upgrade of another revision or before the status is lost is never taken
+func upgradeCompletionWebhookKey(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, progress *v1alpha1.UpgradeCompletionWebhookStatus) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%d", tc.GetNamespace(), tc.GetName(), tc.GetUID(), memberType, progress.Revision, progress.Attempts)
+}
+
+// upgradeCompletionWebhookBackoff returns the backoff after the failed attempts
+func upgradeCompletionWebhookBackoff(attempts int32) time.Duration {
+	backoff := upgradeCompletionWebhookMinBackoff
+	for i := int32(1); i < attempts && backoff < upgradeCompletionWebhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > upgradeCompletionWebhookMaxBackoff {
+		backoff = upgradeCompletionWebhookMaxBackoff
+	}
+	return backoff
+}
+
+// upgradeWebhookSelected returns whether the labels of the cluster match the selector of the clusters
+// calling their upgrade webhooks, the selector is validated on the startup of tidb-controller-manager
+func upgradeWebhookSelected(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) bool {
+	selector, err := labels.Parse(deps.CLIConfig.UpgradeWebhookClusterSelector)
+	if err != nil {
+		klog.Errorf("invalid -upgrade-webhook-cluster-selector %q: %v", deps.CLIConfig.UpgradeWebhookClusterSelector, err)
+		return false
+	}
+	return selector.Matches(labels.Set(tc.GetLabels()))
+}
+
+// upgradeCompletionWebhookResult is the result of a request, the request is in flight until done is set
+type upgradeCompletionWebhookResult struct {
+	done bool
+	err  error
+	time time.Time
+}
+
+// upgradeCompletionWebhookSender sends the requests to the upgrade completion webhooks in the background and keeps
+// their results by the keys of the requests until they are taken
+type upgradeCompletionWebhookSender struct {
+	client *http.Client
+
+	lock    sync.Mutex
+	results map[string]*upgradeCompletionWebhookResult
+}
+
+func newUpgradeCompletionWebhookSender(client *http.Client) *upgradeCompletionWebhookSender {
+	return &upgradeCompletionWebhookSender{
+		client:  client,
+		results: map[string]*upgradeCompletionWebhookResult{},
+	}
+}
+
+// newUpgradeCompletionWebhookClient returns the client of the webhooks, it refuses to connect to the loopback and
+// link-local addresses after the host is resolved and does not follow the redirects
+func newUpgradeCompletionWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: upgradeCompletionWebhookRequestTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+				return fmt.Errorf("address %s is not allowed for the upgrade completion webhook", address)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   upgradeCompletionWebhookRequestTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// send sends the request in the background, the request of the key is sent only once
+func (s *upgradeCompletionWebhookSender) send(key, url string, request *upgradeCompletionRequest) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k, r := range s.results {
+		if r.done && time.Since(r.time) > upgradeCompletionWebhookResultTTL {
+			delete(s.results, k)
+		}
+	}
+	if _, ok := s.results[key]; ok {
+		return
+	}
+	s.results[key] = &upgradeCompletionWebhookResult{time: time.Now()}
+	go func() {
+		err := s.call(url, request)
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if r, ok := s.results[key]; ok {
+			r.done, r.err, r.time = true, err, time.Now()
+		}
+	}()
+}
+
+// take returns whether the request of the key is done and its error, the result is removed once it is done. A
+// request never sent, e.g. its result is lost after a restart, is done with an error so that it is sent again.
+func (s *upgradeCompletionWebhookSender) take(key string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r, ok := s.results[key]
+	if !ok {
+		return true, fmt.Errorf("the result of the request is lost")
+	}
+	if !r.done {
+		return false, nil
+	}
+	delete(s.results, key)
+	return true, r.err
+}
+
+func (s *upgradeCompletionWebhookSender) call(url string, request *upgradeCompletionRequest) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	res, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %v", res.Status, httputil.ReadErrorBody(res.Body))
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestFinalizeUpgrade(t *testing.T) {
+	var lock sync.Mutex
+	var requests []upgradeCompletionRequest
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		var req upgradeCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil {
+			requests = append(requests, req)
+		}
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	setStatus := &apps.StatefulSetStatus{CurrentRevision: "rev-2", UpdateRevision: "rev-2"}
+
+	tests := []struct {
+		name           string
+		webhook        *v1alpha1.UpgradeCompletionWebhook
+		selector       string
+		labels         map[string]string
+		oldPhase       v1alpha1.MemberPhase
+		newPhase       v1alpha1.MemberPhase
+		statusCode     int
+		finalizingFor  time.Duration
+		expectPhase    v1alpha1.MemberPhase
+		expectRequest  bool
+		expectFinalize bool
+		expectEvent    bool
+	}{
+		{
+			name:        "no webhook",
+			oldPhase:    v1alpha1.UpgradePhase,
+			newPhase:    v1alpha1.NormalPhase,
+			expectPhase: v1alpha1.NormalPhase,
+		},
+		{
+			name:        "still upgrading",
+			webhook:     &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			oldPhase:    v1alpha1.UpgradePhase,
+			newPhase:    v1alpha1.UpgradePhase,
+			expectPhase: v1alpha1.UpgradePhase,
+		},
+		{
+			name:        "not upgraded",
+			webhook:     &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			oldPhase:    v1alpha1.NormalPhase,
+			newPhase:    v1alpha1.NormalPhase,
+			expectPhase: v1alpha1.NormalPhase,
+		},
+		{
+			name:          "webhook succeeds",
+			webhook:       &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			oldPhase:      v1alpha1.UpgradePhase,
+			newPhase:      v1alpha1.NormalPhase,
+			statusCode:    http.StatusNoContent,
+			expectPhase:   v1alpha1.NormalPhase,
+			expectRequest: true,
+		},
+		{
+			name:           "webhook fails",
+			webhook:        &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			oldPhase:       v1alpha1.UpgradePhase,
+			newPhase:       v1alpha1.NormalPhase,
+			statusCode:     http.StatusServiceUnavailable,
+			expectPhase:    v1alpha1.UpgradePhase,
+			expectRequest:  true,
+			expectFinalize: true,
+		},
+		{
+			name:           "webhook fails within timeout",
+			webhook:        &v1alpha1.UpgradeCompletionWebhook{URL: server.URL, Timeout: pointer.StringPtr("10m")},
+			oldPhase:       v1alpha1.UpgradePhase,
+			newPhase:       v1alpha1.NormalPhase,
+			statusCode:     http.StatusServiceUnavailable,
+			finalizingFor:  6 * time.Minute,
+			expectPhase:    v1alpha1.UpgradePhase,
+			expectRequest:  true,
+			expectFinalize: true,
+		},
+		{
+			name:          "cluster selected",
+			webhook:       &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			selector:      "env=prod",
+			labels:        map[string]string{"env": "prod"},
+			oldPhase:      v1alpha1.UpgradePhase,
+			newPhase:      v1alpha1.NormalPhase,
+			statusCode:    http.StatusOK,
+			expectPhase:   v1alpha1.NormalPhase,
+			expectRequest: true,
+		},
+		{
+			name:        "cluster not selected",
+			webhook:     &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			selector:    "env=prod",
+			labels:      map[string]string{"env": "dev"},
+			oldPhase:    v1alpha1.UpgradePhase,
+			newPhase:    v1alpha1.NormalPhase,
+			statusCode:  http.StatusServiceUnavailable,
+			expectPhase: v1alpha1.NormalPhase,
+		},
+		{
+			name:          "timeout",
+			webhook:       &v1alpha1.UpgradeCompletionWebhook{URL: server.URL},
+			oldPhase:      v1alpha1.UpgradePhase,
+			newPhase:      v1alpha1.NormalPhase,
+			statusCode:    http.StatusServiceUnavailable,
+			finalizingFor: 6 * time.Minute,
+			expectPhase:   v1alpha1.NormalPhase,
+			expectEvent:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			lock.Lock()
+			requests = nil
+			statusCode = tt.statusCode
+			lock.Unlock()
+			defer useUpgradeCompletionWebhookSender(newUpgradeCompletionWebhookSender(server.Client()))()
+			deps := controller.NewFakeDependencies()
+			deps.CLIConfig.UpgradeWebhookClusterSelector = tt.selector
+			tc := newTidbClusterForTiDB()
+			tc.Labels = tt.labels
+			tc.Spec.TiDB.UpgradeCompletionWebhook = tt.webhook
+			if tt.finalizingFor > 0 {
+				tc.Status.TiDB.SetCondition(metav1.Condition{
+					Type:               v1alpha1.ComponentUpgradeFinalizing,
+					Status:             metav1.ConditionTrue,
+					Reason:             "WaitingForWebhook",
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-tt.finalizingFor)),
+				})
+			}
+
+			phase := finalizeUpgrade(deps, tc, v1alpha1.TiDBMemberType, tt.oldPhase, tt.newPhase, setStatus)
+			if tt.expectRequest {
+				// the request is sent in the background and its result is taken by the next sync
+				g.Expect(phase).To(Equal(v1alpha1.UpgradePhase))
+				waitUpgradeCompletionWebhooks(g)
+				phase = finalizeUpgrade(deps, tc, v1alpha1.TiDBMemberType, tt.oldPhase, tt.newPhase, setStatus)
+			}
+			g.Expect(phase).To(Equal(tt.expectPhase))
+			g.Expect(meta.IsStatusConditionTrue(tc.Status.TiDB.Conditions, v1alpha1.ComponentUpgradeFinalizing)).To(Equal(tt.expectFinalize))
+			lock.Lock()
+			defer lock.Unlock()
+			if tt.expectRequest {
+				g.Expect(requests).To(Equal([]upgradeCompletionRequest{{
+					Namespace: tc.Namespace,
+					Name:      tc.Name,
+					Component: "tidb",
+					Revision:  "rev-2",
+				}}))
+			} else {
+				g.Expect(requests).To(BeEmpty())
+			}
+			recorded := collectEvents(deps.Recorder.(*record.FakeRecorder).Events)
+			if tt.expectEvent {
+				g.Expect(recorded).To(HaveLen(1))
+				g.Expect(recorded[0]).To(ContainSubstring(events.UpgradeCompletionWebhookTimeout))
+			} else {
+				g.Expect(recorded).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestFinalizeUpgradeRetry(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	defer useUpgradeCompletionWebhookSender(newUpgradeCompletionWebhookSender(server.Client()))()
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForTiDB()
+	tc.Spec.TiDB.UpgradeCompletionWebhook = &v1alpha1.UpgradeCompletionWebhook{URL: server.URL}
+	setStatus := &apps.StatefulSetStatus{CurrentRevision: "rev-2", UpdateRevision: "rev-2"}
+	finalize := func() v1alpha1.MemberPhase {
+		return finalizeUpgrade(deps, tc, v1alpha1.TiDBMemberType, v1alpha1.UpgradePhase, v1alpha1.NormalPhase, setStatus)
+	}
+	progress := func() v1alpha1.UpgradeCompletionWebhookStatus {
+		return tc.Status.UpgradeCompletionWebhooks[v1alpha1.TiDBMemberType.String()]
+	}
+
+	// the first request fails and the next one is scheduled after the backoff
+	g.Expect(finalize()).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(progress().Attempts).To(Equal(int32(1)))
+	g.Expect(progress().NextAttemptTime).To(BeNil())
+	waitUpgradeCompletionWebhooks(g)
+	g.Expect(finalize()).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(progress().NextAttemptTime).NotTo(BeNil())
+	g.Expect(progress().NextAttemptTime.Sub(progress().LastAttemptTime.Time)).To(Equal(upgradeCompletionWebhookMinBackoff))
+	g.Expect(progress().LastError).To(ContainSubstring("503"))
+
+	// no request is sent within the backoff
+	g.Expect(finalize()).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(progress().Attempts).To(Equal(int32(1)))
+
+	// the request is retried after the backoff and succeeds
+	p := progress()
+	past := metav1.NewTime(time.Now().Add(-time.Second))
+	p.NextAttemptTime = &past
+	tc.Status.UpgradeCompletionWebhooks[v1alpha1.TiDBMemberType.String()] = p
+	g.Expect(finalize()).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(progress().Attempts).To(Equal(int32(2)))
+	waitUpgradeCompletionWebhooks(g)
+	g.Expect(finalize()).To(Equal(v1alpha1.NormalPhase))
+	g.Expect(tc.Status.UpgradeCompletionWebhooks).To(BeNil())
+	g.Expect(atomic.LoadInt32(&attempts)).To(Equal(int32(2)))
+
+	// the request whose result is lost is retried
+	setUpgradeCompletionWebhookStatus(tc, v1alpha1.TiDBMemberType, v1alpha1.UpgradeCompletionWebhookStatus{
+		Revision:        "rev-2",
+		Attempts:        3,
+		LastAttemptTime: metav1.Now(),
+	})
+	g.Expect(finalize()).To(Equal(v1alpha1.UpgradePhase))
+	g.Expect(progress().LastError).To(ContainSubstring("lost"))
+	g.Expect(progress().NextAttemptTime).NotTo(BeNil())
+}
+
+func TestUpgradeCompletionWebhookBackoff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(upgradeCompletionWebhookBackoff(1)).To(Equal(5 * time.Second))
+	g.Expect(upgradeCompletionWebhookBackoff(2)).To(Equal(10 * time.Second))
+	g.Expect(upgradeCompletionWebhookBackoff(4)).To(Equal(40 * time.Second))
+	g.Expect(upgradeCompletionWebhookBackoff(5)).To(Equal(time.Minute))
+	g.Expect(upgradeCompletionWebhookBackoff(100)).To(Equal(time.Minute))
+}
+
+func TestUpgradeCompletionWebhookClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the loopback address is refused
+	_, err := newUpgradeCompletionWebhookClient().Post(server.URL, "application/json", nil)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("is not allowed for the upgrade completion webhook"))
+
+	// the redirects are not followed
+	redirect := httptest.NewServer(http.RedirectHandler("http://169.254.169.254/latest/meta-data", http.StatusFound))
+	defer redirect.Close()
+	client := newUpgradeCompletionWebhookClient()
+	client.Transport = redirect.Client().Transport
+	res, err := client.Post(redirect.URL, "application/json", nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.StatusCode).To(Equal(http.StatusFound))
+	res.Body.Close()
+}
+
+// useUpgradeCompletionWebhookSender replaces the sender of the webhooks, the returned func restores it
+func useUpgradeCompletionWebhookSender(sender *upgradeCompletionWebhookSender) func() {
+	old := upgradeCompletionWebhooks
+	upgradeCompletionWebhooks = sender
+	return func() {
+		upgradeCompletionWebhooks = old
+	}
+}
+
+// waitUpgradeCompletionWebhooks waits for the requests in flight to be done
+func waitUpgradeCompletionWebhooks(g *GomegaWithT) {
+	g.Eventually(func() bool {
+		upgradeCompletionWebhooks.lock.Lock()
+		defer upgradeCompletionWebhooks.lock.Unlock()
+		for _, r := range upgradeCompletionWebhooks.results {
+			if !r.done {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/tracing"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+const (
+	// upgradeReasonImageChanged is the reason of the upgrades changing the image of the component
+	upgradeReasonImageChanged = "ImageChanged"
+	// upgradeReasonSpecChanged is the reason of the upgrades changing other fields of the pod template
+	upgradeReasonSpecChanged = "SpecChanged"
+)
+
+type tracingUpgrader struct {
+	Upgrader
+	deps       *controller.Dependencies
+	memberType v1alpha1.MemberType
+}
+
+// NewTracingUpgrader wraps the upgrader of the component to record its upgrades with deps.UpgradeTracer
+func NewTracingUpgrader(deps *controller.Dependencies, memberType v1alpha1.MemberType, upgrader Upgrader) Upgrader {
+	return &tracingUpgrader{
+		Upgrader:   upgrader,
+		deps:       deps,
+		memberType: memberType,
+	}
+}
+
+func (u *tracingUpgrader) Upgrade(tc *v1alpha1.TidbCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
+	err := u.Upgrader.Upgrade(tc, oldSet, newSet)
+	traceUpgrade(u.deps, tc, u.memberType, oldSet, newSet, err == nil)
+	return err
+}
+
+type tracingTiKVUpgrader struct {
+	TiKVUpgrader
+	deps *controller.Dependencies
+}
+
+// NewTracingTiKVUpgrader wraps the tikv upgrader to record its upgrades with deps.UpgradeTracer
+func NewTracingTiKVUpgrader(deps *controller.Dependencies, upgrader TiKVUpgrader) TiKVUpgrader {
+	return &tracingTiKVUpgrader{
+		TiKVUpgrader: upgrader,
+		deps:         deps,
+	}
+}
+
+func (u *tracingTiKVUpgrader) Upgrade(meta metav1.Object, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
+	err := u.TiKVUpgrader.Upgrade(meta, oldSet, newSet)
+	if tc, ok := meta.(*v1alpha1.TidbCluster); ok {
+		traceUpgrade(u.deps, tc, v1alpha1.TiKVMemberType, oldSet, newSet, err == nil)
+	}
+	return err
+}
+
+// traceUpgrade records the progress of the upgrade of a component after the upgrader runs.
+// The span of a pod starts when the partition allows it to be recreated and ends when it is
+// ready with the update revision. newSet is applied only if the upgrader succeeds.
+func traceUpgrade(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	oldSet *apps.StatefulSet, newSet *apps.StatefulSet, applied bool) {
+	if componentPhase(tc, memberType) != v1alpha1.UpgradePhase {
+		return
+	}
+	tracer := deps.UpgradeTracer
+
+	if !templateEqual(newSet, oldSet) {
+		fromImage, toImage := mainContainerImage(oldSet, memberType), mainContainerImage(newSet, memberType)
+		reason := upgradeReasonSpecChanged
+		if fromImage != toImage {
+			reason = upgradeReasonImageChanged
+		}
+		attrs := []tracing.Attribute{
+			tracing.String("image.from", fromImage),
+			tracing.String("image.to", toImage),
+			tracing.String("upgrade.reason", reason),
+		}
+		if id := tc.ChangeRequestID(); id != "" {
+			attrs = append(attrs, tracing.String("change-request.id", id))
+		}
+		tracer.StartComponent(tc, memberType, attrs...)
+		return
+	}
+
+	updateRevision := oldSet.Status.UpdateRevision
+	if updateRevision == oldSet.Status.CurrentRevision {
+		tracer.EndComponent(tc, memberType)
+		return
+	}
+
+	set := oldSet
+	if applied {
+		set = newSet
+	}
+	partition := int32(0)
+	if rollingUpdate := set.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil && rollingUpdate.Partition != nil {
+		partition = *rollingUpdate.Partition
+	}
+	for ordinal := range helper.GetPodOrdinals(*oldSet.Spec.Replicas, oldSet) {
+		podName := fmt.Sprintf("%s-%d", oldSet.Name, ordinal)
+		pod, err := deps.PodLister.Pods(tc.Namespace).Get(podName)
+		if err != nil {
+			continue
+		}
+		revision := pod.Labels[apps.ControllerRevisionHashLabelKey]
+		if revision == updateRevision && podutil.IsPodReady(pod) {
+			tracer.EndPod(tc, memberType, podName)
+			continue
+		}
+		if ordinal >= partition {
+			tracer.StartPod(tc, memberType, podName,
+				tracing.Int("pod.ordinal", int64(ordinal)),
+				tracing.String("revision.from", revision),
+				tracing.String("revision.to", updateRevision))
+		}
+	}
+}
+
+func componentPhase(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) v1alpha1.MemberPhase {
+	for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+		if status.GetMemberType() == memberType {
+			return status.GetPhase()
+		}
+	}
+	return ""
+}
+
+func mainContainerImage(set *apps.StatefulSet, memberType v1alpha1.MemberType) string {
+	for _, c := range set.Spec.Template.Spec.Containers {
+		if c.Name == memberType.String() {
+			return c.Image
+		}
+	}
+	return ""
+}
@@ -14,10 +14,28 @@
 package member
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 )
 
+// upgradeEventInterval is the interval in which the same event of an upgrade step is emitted at most once
+const upgradeEventInterval = 5 * time.Minute
+
 // Upgrader implements the logic for upgrading the tidb cluster.
 type Upgrader interface {
 	// Upgrade upgrade the cluster
@@ -28,3 +46,288 @@ type Upgrader interface {
 type DMUpgrader interface {
 	Upgrade(*v1alpha1.DMCluster, *apps.StatefulSet, *apps.StatefulSet) error
 }
+
+// recordLastReconcileBy records the tidb-controller-manager instance driving the upgrade in tc status,
+// which helps to correlate the upgrade behavior with the operator logs across operator failovers.
+// The status is only stamped if the instance changes or acted is true, i.e. the upgrader starts the
+// upgrade or moves the partition, so that tc status is not updated in every reconciliation.
+func recordLastReconcileBy(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, acted bool) {
+	if !acted && tc.Status.LastReconcileBy == deps.LeaderIdentity {
+		return
+	}
+	now := metav1.Now()
+	tc.Status.LastReconcileBy = deps.LeaderIdentity
+	tc.Status.LastReconcileTime = &now
+}
+
+// recordUpgradeUpToDate emits an UpgradeUpToDate event once the phase of a component leaves
+// UpgradePhase with the update revision equal to the current revision. An idle component
+// stays in NormalPhase and never emits it, so that the event is not repeated.
+func recordUpgradeUpToDate(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	oldPhase, newPhase v1alpha1.MemberPhase, status *apps.StatefulSetStatus) {
+	if oldPhase != v1alpha1.UpgradePhase || newPhase != v1alpha1.NormalPhase {
+		return
+	}
+	if status == nil || status.UpdateRevision != status.CurrentRevision {
+		return
+	}
+	msg := fmt.Sprintf("%s is up to date at revision %s", memberType, status.UpdateRevision)
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradeUpToDate, msg)
+}
+
+// recordUpgradeEvent emits an event of the upgrade, the change request ID of the upgrade is
+// appended to the message and set as the annotation of the event if it is set in tc.
+func recordUpgradeEvent(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, eventType, reason, msg string) {
+	anns := changeRequestLabels(tc)
+	if anns == nil {
+		recorder.Event(tc, eventType, reason, msg)
+		return
+	}
+	recorder.AnnotatedEventf(tc, anns, eventType, reason, "%s, change request %s", msg, tc.ChangeRequestID())
+}
+
+// changeRequestLabels returns the change request ID set in tc keyed by label.AnnChangeRequestID, or nil if it is
+// not set. It is set on the events and the objects created for the upgrade, e.g. the prepull pods, the warm standby
+// pods and the StatefulSets of the TiDB groups, so that they are correlatable to the change request.
+func changeRequestLabels(tc *v1alpha1.TidbCluster) map[string]string {
+	id := tc.ChangeRequestID()
+	if id == "" {
+		return nil
+	}
+	return map[string]string{label.AnnChangeRequestID: id}
+}
+
+// newUpgradeRecorder returns the recorder of the events of the upgrade steps, the steps waiting for something
+// are retried in every sync, so the same event is emitted at most once in upgradeEventInterval
+func newUpgradeRecorder(deps *controller.Dependencies) record.EventRecorder {
+	return events.NewDedupRecorder(deps.Recorder, upgradeEventInterval)
+}
+
+// upgradeBlockedReason returns why the upgrade of a component waits, the components in blocking are upgrading
+// and the component is scaling if blocking is empty
+func upgradeBlockedReason(memberType v1alpha1.MemberType, blocking string, phase v1alpha1.MemberPhase) string {
+	if blocking != "" {
+		return fmt.Sprintf("the components upgraded before %s [%s] are upgrading", memberType, blocking)
+	}
+	return fmt.Sprintf("%s status is %s", memberType, phase)
+}
+
+// recordUpgradeBlocked emits an UpgradeBlocked event with why the upgrade of a component waits
+func recordUpgradeBlocked(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, reason string) {
+	msg := fmt.Sprintf("can not upgrade %s because %s", memberType, reason)
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradeBlocked, msg)
+}
+
+// recordUpgradePodSelected emits an UpgradePodSelected event when the partition of the upgrade moves to the pod
+func recordUpgradePodSelected(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	podName string, status *apps.StatefulSetStatus) {
+	msg := fmt.Sprintf("%s pod %s is selected to be upgraded", memberType, podName)
+	if status != nil && status.UpdateRevision != "" {
+		msg = fmt.Sprintf("%s to revision %s", msg, status.UpdateRevision)
+	}
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradePodSelected, msg)
+}
+
+// recordUpgradedPodUnhealthy emits an UpgradePodUnhealthy event when the upgrade waits for an upgraded pod failing
+// the readiness or the health check
+func recordUpgradedPodUnhealthy(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podName, reason string) {
+	msg := fmt.Sprintf("upgraded %s pod %s %s, the upgrade waits for it", memberType, podName, reason)
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeWarning, events.UpgradePodUnhealthy, msg)
+}
+
+// keepTemplateIfUpgradeFrozen keeps the pod template of the old statefulset as the upgraders do while
+// the upgrade can not proceed if the upgrades of all the clusters are frozen by the maintenance-mode
+// ConfigMap. The upgrade resumes in the next sync after the ConfigMap is changed.
+func keepTemplateIfUpgradeFrozen(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	oldSet *apps.StatefulSet, newSet *apps.StatefulSet) (bool, error) {
+	if !controller.IsUpgradeFrozen(deps) {
+		return false, nil
+	}
+	klog.Infof("TidbCluster: [%s/%s]'s upgrades are frozen by configmap %s, can not upgrade %s",
+		tc.GetNamespace(), tc.GetName(), deps.CLIConfig.UpgradeFreezeConfigMap, memberType)
+	_, podSpec, err := GetLastAppliedConfig(oldSet)
+	if err != nil {
+		return true, err
+	}
+	newSet.Spec.Template.Spec = *podSpec
+	return true, nil
+}
+
+// keepTemplateIfDowngrade keeps the pod template of the old statefulset if the image of the component is changed to a
+// lower major or minor version than the image recorded in its status, which may corrupt the data written by the newer
+// version, unless the downgrade is allowed by the annotation tidb.pingcap.com/allow-downgrade. The member managers call
+// it in every sync before the upgrade, so the DowngradeBlocked condition of the component is removed once the image is
+// changed back or the downgrade is allowed. The images whose tags are not semver, e.g. nightly, are not checked.
+func keepTemplateIfDowngrade(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType,
+	oldSet *apps.StatefulSet, newSet *apps.StatefulSet) (bool, error) {
+	var status v1alpha1.ComponentStatus
+	for _, s := range v1alpha1.ComponentStatusFromTC(tc) {
+		if s.GetMemberType() == memberType {
+			status = s
+		}
+	}
+	if status == nil {
+		return false, nil
+	}
+
+	currentImage := statusImage(tc, memberType)
+	if currentImage == "" {
+		if c := findContainerByName(oldSet, memberType.String()); c != nil {
+			currentImage = c.Image
+		}
+	}
+	var targetImage string
+	if c := findContainerByName(newSet, memberType.String()); c != nil {
+		targetImage = c.Image
+	}
+	cond := meta.FindStatusCondition(status.GetConditions(), v1alpha1.ComponentDowngradeBlocked)
+	if tc.IsDowngradeAllowed() || !isDowngrade(currentImage, targetImage) {
+		if cond != nil {
+			status.RemoveCondition(v1alpha1.ComponentDowngradeBlocked)
+		}
+		return false, nil
+	}
+
+	msg := fmt.Sprintf("%s can not be downgraded from %s to %s, set annotation %s to %q to allow it",
+		memberType, currentImage, targetImage, label.AnnAllowDowngrade, label.AnnAllowDowngradeVal)
+	klog.Warningf("TidbCluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), msg)
+	if cond == nil {
+		recordUpgradeEvent(deps.Recorder, tc, corev1.EventTypeWarning, events.DowngradeBlocked, msg)
+	}
+	status.SetCondition(metav1.Condition{
+		Type:    v1alpha1.ComponentDowngradeBlocked,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DowngradeBlocked",
+		Message: msg,
+	})
+	_, podSpec, err := GetLastAppliedConfig(oldSet)
+	if err != nil {
+		return true, err
+	}
+	newSet.Spec.Template.Spec = *podSpec
+	return true, nil
+}
+
+// statusImage returns the image of the component recorded in tc status, it is empty for the components without it.
+func statusImage(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) string {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return tc.Status.PD.Image
+	case v1alpha1.TiKVMemberType:
+		return tc.Status.TiKV.Image
+	case v1alpha1.TiDBMemberType:
+		return tc.Status.TiDB.Image
+	case v1alpha1.TiFlashMemberType:
+		return tc.Status.TiFlash.Image
+	}
+	return ""
+}
+
+// isDowngrade returns true if the tag of the target image is a lower major or minor version than the tag of the
+// current image. It returns false if any of the tags is not semver.
+func isDowngrade(currentImage, targetImage string) bool {
+	if currentImage == "" || targetImage == "" || currentImage == targetImage {
+		return false
+	}
+	_, currentTag := parseImage(currentImage)
+	_, targetTag := parseImage(targetImage)
+	current, err := semver.NewVersion(currentTag)
+	if err != nil {
+		return false
+	}
+	target, err := semver.NewVersion(targetTag)
+	if err != nil {
+		return false
+	}
+	if target.Major() != current.Major() {
+		return target.Major() < current.Major()
+	}
+	return target.Minor() < current.Minor()
+}
+
+// upgradePaused returns true if the upgrades of tc are paused by the annotation tidb.pingcap.com/upgrade-paused.
+// The upgraders check it right before they move the partition down to the ordinal, so the partition of the old
+// statefulset is kept and the upgrade resumes from the ordinal with the checks of the pods redone once the
+// annotation is removed. The UpgradePaused event is emitted by the recorder of the upgrader, which is the one of
+// newUpgradeRecorder, so it is not repeated in every sync while the upgrade is paused.
+func upgradePaused(recorder record.EventRecorder, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, ordinal int32) bool {
+	if !tc.IsUpgradePaused() {
+		return false
+	}
+	msg := fmt.Sprintf("%s upgrade is paused by annotation %s before upgrading the pod of ordinal %d", memberType, label.AnnUpgradePaused, ordinal)
+	klog.Infof("TidbCluster: [%s/%s] %s", tc.GetNamespace(), tc.GetName(), msg)
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradePaused, msg)
+	return true
+}
+
+// syncUpgradeStatus records the progress of the rolling upgrade of the component in its status. The upgraders call it
+// with the ordinal of the pod to upgrade and the member managers call it with nil ordinal to refresh the number of the
+// pods on the update revision, which are counted by the revision labels of the pods of set. The progress is cleared
+// once the update revision of the statefulset becomes the current revision.
+func syncUpgradeStatus(deps *controller.Dependencies, tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, set *apps.StatefulSet, ordinal *int32) {
+	upgrade, setStatus := componentUpgradeStatus(tc, memberType)
+	if upgrade == nil {
+		return
+	}
+	if setStatus == nil || setStatus.UpdateRevision == setStatus.CurrentRevision {
+		*upgrade = nil
+		return
+	}
+	if *upgrade == nil {
+		if ordinal == nil {
+			// the upgrade is not started by the upgrader yet
+			return
+		}
+		*upgrade = &v1alpha1.UpgradeStatus{StartTime: metav1.Now()}
+	}
+	if ordinal != nil {
+		(*upgrade).UpgradingOrdinal = pointer.Int32Ptr(*ordinal)
+	}
+
+	replicas := int32(0)
+	if set.Spec.Replicas != nil {
+		replicas = *set.Spec.Replicas
+	}
+	updated := int32(0)
+	for _, i := range helper.GetPodOrdinals(replicas, set).List() {
+		pod, err := deps.PodLister.Pods(tc.GetNamespace()).Get(fmt.Sprintf("%s-%d", set.GetName(), i))
+		if err != nil {
+			continue
+		}
+		if pod.Labels[apps.ControllerRevisionHashLabelKey] == setStatus.UpdateRevision {
+			updated++
+		}
+	}
+	(*upgrade).UpdatedReplicas = updated
+	(*upgrade).Replicas = replicas
+}
+
+// componentUpgradeStatus returns the upgrade progress and the statefulset status in the status of the component
+func componentUpgradeStatus(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) (**v1alpha1.UpgradeStatus, *apps.StatefulSetStatus) {
+	switch memberType {
+	case v1alpha1.PDMemberType:
+		return &tc.Status.PD.Upgrade, tc.Status.PD.StatefulSet
+	case v1alpha1.TiKVMemberType:
+		return &tc.Status.TiKV.Upgrade, tc.Status.TiKV.StatefulSet
+	case v1alpha1.TiDBMemberType:
+		return &tc.Status.TiDB.Upgrade, tc.Status.TiDB.StatefulSet
+	case v1alpha1.TiFlashMemberType:
+		return &tc.Status.TiFlash.Upgrade, tc.Status.TiFlash.StatefulSet
+	case v1alpha1.TiCDCMemberType:
+		return &tc.Status.TiCDC.Upgrade, tc.Status.TiCDC.StatefulSet
+	case v1alpha1.PumpMemberType:
+		return &tc.Status.Pump.Upgrade, tc.Status.Pump.StatefulSet
+	}
+	return nil, nil
+}
+
+// upgradeBlockedBy returns the components before the component in the upgrade order of the cluster whose upgrades
+// are in progress joined by commas, the component may be upgraded if it is empty
+func upgradeBlockedBy(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) string {
+	blocking := tc.UpgradeBlockedBy(memberType)
+	names := make([]string, 0, len(blocking))
+	for _, t := range blocking {
+		names = append(names, t.String())
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,294 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+)
+
+func TestRecordLastReconcileBy(t *testing.T) {
+	lastTime := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name        string
+		lastBy      string
+		acted       bool
+		expectStamp bool
+	}{
+		{
+			name:        "first reconciliation",
+			lastBy:      "",
+			acted:       false,
+			expectStamp: true,
+		},
+		{
+			name:        "same instance without action",
+			lastBy:      "tidb-controller-manager-0",
+			acted:       false,
+			expectStamp: false,
+		},
+		{
+			name:        "same instance with action",
+			lastBy:      "tidb-controller-manager-0",
+			acted:       true,
+			expectStamp: true,
+		},
+		{
+			name:        "another instance takes over",
+			lastBy:      "tidb-controller-manager-1",
+			acted:       false,
+			expectStamp: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			deps := controller.NewFakeDependencies()
+			deps.LeaderIdentity = "tidb-controller-manager-0"
+			tc := &v1alpha1.TidbCluster{}
+			tc.Status.LastReconcileBy = tt.lastBy
+			tc.Status.LastReconcileTime = lastTime.DeepCopy()
+
+			recordLastReconcileBy(deps, tc, tt.acted)
+			g.Expect(tc.Status.LastReconcileBy).To(Equal("tidb-controller-manager-0"))
+			if tt.expectStamp {
+				g.Expect(tc.Status.LastReconcileTime.After(lastTime.Time)).To(BeTrue())
+			} else {
+				g.Expect(*tc.Status.LastReconcileTime).To(Equal(lastTime))
+			}
+		})
+	}
+}
+
+func TestRecordUpgradeEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	recorder := record.NewFakeRecorder(10)
+	tc := &v1alpha1.TidbCluster{}
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradeUpToDate, "tidb is up to date")
+	g.Expect(<-recorder.Events).To(Equal("Normal UpgradeUpToDate tidb is up to date"))
+	g.Expect(changeRequestLabels(tc)).To(BeNil())
+
+	tc.Annotations = map[string]string{label.AnnChangeRequestID: "CR-1024"}
+	g.Expect(changeRequestLabels(tc)).To(Equal(map[string]string{label.AnnChangeRequestID: "CR-1024"}))
+	recordUpgradeEvent(recorder, tc, corev1.EventTypeNormal, events.UpgradeUpToDate, "tidb is up to date")
+	g.Expect(<-recorder.Events).To(Equal("Normal UpgradeUpToDate tidb is up to date, change request CR-1024"))
+}
+
+func TestUpgradePaused(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	upgradeRecorder := newUpgradeRecorder(deps)
+	tc := newTidbClusterForPDUpgrader()
+	g.Expect(upgradePaused(upgradeRecorder, tc, v1alpha1.TiKVMemberType, 2)).To(BeFalse())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+
+	tc.Annotations = map[string]string{label.AnnUpgradePaused: "false"}
+	g.Expect(upgradePaused(upgradeRecorder, tc, v1alpha1.TiKVMemberType, 2)).To(BeFalse())
+
+	tc.Annotations[label.AnnUpgradePaused] = label.AnnUpgradePausedVal
+	g.Expect(upgradePaused(upgradeRecorder, tc, v1alpha1.TiKVMemberType, 2)).To(BeTrue())
+	recorded := collectEvents(recorder.Events)
+	g.Expect(recorded).To(HaveLen(1))
+	g.Expect(recorded[0]).To(Equal("Normal UpgradePaused tikv upgrade is paused by annotation tidb.pingcap.com/upgrade-paused before upgrading the pod of ordinal 2"))
+
+	// the event is not repeated in the following syncs while the upgrade is paused
+	g.Expect(upgradePaused(upgradeRecorder, tc, v1alpha1.TiKVMemberType, 2)).To(BeTrue())
+	g.Expect(collectEvents(recorder.Events)).To(BeEmpty())
+}
+
+func TestSyncUpgradeStatus(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	podIndexer := deps.KubeInformerFactory.Core().V1().Pods().Informer().GetIndexer()
+	tc := newTidbClusterForPDUpgrader()
+	set := newStatefulSetForPDUpgrader()
+	tc.Status.PD.StatefulSet = &apps.StatefulSetStatus{CurrentRevision: "1", UpdateRevision: "2"}
+	pods := map[int32]*corev1.Pod{}
+	for i := int32(0); i < 3; i++ {
+		pods[i] = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", set.Name, i),
+			Namespace: set.Namespace,
+			Labels:    map[string]string{apps.ControllerRevisionHashLabelKey: "1"},
+		}}
+		g.Expect(podIndexer.Add(pods[i])).To(Succeed())
+	}
+
+	// the member managers do not start the progress
+	syncUpgradeStatus(deps, tc, v1alpha1.PDMemberType, set, nil)
+	g.Expect(tc.Status.PD.Upgrade).To(BeNil())
+
+	// the upgrader starts the progress before upgrading the first pod
+	syncUpgradeStatus(deps, tc, v1alpha1.PDMemberType, set, pointer.Int32Ptr(2))
+	g.Expect(tc.Status.PD.Upgrade).NotTo(BeNil())
+	started := tc.Status.PD.Upgrade.StartTime
+	g.Expect(started.IsZero()).To(BeFalse())
+	g.Expect(tc.Status.PD.Upgrade.UpgradingOrdinal).To(Equal(pointer.Int32Ptr(2)))
+	g.Expect(tc.Status.PD.Upgrade.UpdatedReplicas).To(Equal(int32(0)))
+	g.Expect(tc.Status.PD.Upgrade.Replicas).To(Equal(int32(3)))
+
+	// the pods on the update revision are counted and the start time is kept
+	pods[2].Labels[apps.ControllerRevisionHashLabelKey] = "2"
+	g.Expect(podIndexer.Update(pods[2])).To(Succeed())
+	syncUpgradeStatus(deps, tc, v1alpha1.PDMemberType, set, nil)
+	g.Expect(tc.Status.PD.Upgrade.UpdatedReplicas).To(Equal(int32(1)))
+	syncUpgradeStatus(deps, tc, v1alpha1.PDMemberType, set, pointer.Int32Ptr(1))
+	g.Expect(tc.Status.PD.Upgrade.UpgradingOrdinal).To(Equal(pointer.Int32Ptr(1)))
+	g.Expect(tc.Status.PD.Upgrade.StartTime).To(Equal(started))
+
+	// the progress is cleared after the upgrade is done
+	tc.Status.PD.StatefulSet.CurrentRevision = "2"
+	syncUpgradeStatus(deps, tc, v1alpha1.PDMemberType, set, nil)
+	g.Expect(tc.Status.PD.Upgrade).To(BeNil())
+}
+
+func TestKeepTemplateIfUpgradeFrozen(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := newTidbClusterForPDUpgrader()
+	newSet := newStatefulSetForPDUpgrader()
+	oldSet := newSet.DeepCopy()
+	oldSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:old"
+	mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+
+	// disabled
+	frozen, err := keepTemplateIfUpgradeFrozen(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(frozen).To(BeFalse())
+
+	deps.CLIConfig.UpgradeFreezeConfigMap = "ops/maintenance"
+	deps.UpgradeFreezeConfigMapLister = deps.KubeInformerFactory.Core().V1().ConfigMaps().Lister()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "maintenance"},
+		Data:       map[string]string{controller.DefaultUpgradeFreezeConfigMapKey: "open"},
+	}
+	indexer := deps.KubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer()
+
+	// the configmap does not exist
+	frozen, err = keepTemplateIfUpgradeFrozen(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(frozen).To(BeFalse())
+
+	// not frozen
+	g.Expect(indexer.Add(cm)).To(Succeed())
+	frozen, err = keepTemplateIfUpgradeFrozen(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(frozen).To(BeFalse())
+	g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("pd-test-image"))
+
+	// frozen, the template of the old statefulset is kept
+	cm.Data[controller.DefaultUpgradeFreezeConfigMapKey] = " frozen\n"
+	g.Expect(indexer.Update(cm)).To(Succeed())
+	frozen, err = keepTemplateIfUpgradeFrozen(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(frozen).To(BeTrue())
+	g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("pd-test-image:old"))
+}
+
+func TestIsDowngrade(t *testing.T) {
+	tests := []struct {
+		current string
+		target  string
+		expect  bool
+	}{
+		{current: "pingcap/tikv:v5.4.0", target: "pingcap/tikv:v4.0.9", expect: true},
+		{current: "pingcap/tikv:v5.4.0", target: "pingcap/tikv:v5.3.1", expect: true},
+		{current: "pingcap/tikv:v5.4.0", target: "pingcap/tikv:v5.4.0-20220101", expect: false},
+		{current: "pingcap/tikv:v5.4.2", target: "pingcap/tikv:v5.4.0", expect: false},
+		{current: "pingcap/tikv:v5.4.0", target: "pingcap/tikv:v6.1.0", expect: false},
+		{current: "pingcap/tikv:v5.4.0", target: "localhost:5000/pingcap/tikv:v5.4.0", expect: false},
+		{current: "pingcap/tikv:v5.4.0", target: "pingcap/tikv:nightly", expect: false},
+		{current: "pingcap/tikv:latest", target: "pingcap/tikv:v4.0.9", expect: false},
+		{current: "pingcap/tikv", target: "pingcap/tikv:v4.0.9", expect: false},
+		{current: "", target: "pingcap/tikv:v4.0.9", expect: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.current+"->"+tt.target, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			g.Expect(isDowngrade(tt.current, tt.target)).To(Equal(tt.expect))
+		})
+	}
+}
+
+func TestKeepTemplateIfDowngrade(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	tc := newTidbClusterForPDUpgrader()
+	tc.Status.PD.Image = "pd-test-image:v5.4.0"
+	newSet := newStatefulSetForPDUpgrader()
+	newSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:v4.0.9"
+	oldSet := newSet.DeepCopy()
+	oldSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:v5.4.0"
+	mngerutils.SetStatefulSetLastAppliedConfigAnnotation(oldSet)
+	blocked := func() bool {
+		return meta.IsStatusConditionTrue(tc.Status.PD.Conditions, v1alpha1.ComponentDowngradeBlocked)
+	}
+
+	// the downgrade is blocked and the template of the old statefulset is kept
+	kept, err := keepTemplateIfDowngrade(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept).To(BeTrue())
+	g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("pd-test-image:v5.4.0"))
+	g.Expect(blocked()).To(BeTrue())
+	g.Expect(recorder.Events).To(HaveLen(1))
+	g.Expect(<-recorder.Events).To(ContainSubstring(events.DowngradeBlocked))
+
+	// the event is not repeated while the downgrade is blocked
+	newSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:v5.3.0"
+	kept, err = keepTemplateIfDowngrade(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept).To(BeTrue())
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	// the condition is removed once the image is changed back
+	newSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:v5.4.1"
+	kept, err = keepTemplateIfDowngrade(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept).To(BeFalse())
+	g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("pd-test-image:v5.4.1"))
+	g.Expect(blocked()).To(BeFalse())
+
+	// the downgrade is allowed by the annotation
+	tc.Annotations = map[string]string{label.AnnAllowDowngrade: label.AnnAllowDowngradeVal}
+	newSet.Spec.Template.Spec.Containers[0].Image = "pd-test-image:v4.0.9"
+	kept, err = keepTemplateIfDowngrade(deps, tc, v1alpha1.PDMemberType, oldSet, newSet)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kept).To(BeFalse())
+	g.Expect(newSet.Spec.Template.Spec.Containers[0].Image).To(Equal("pd-test-image:v4.0.9"))
+	g.Expect(blocked()).To(BeFalse())
+	g.Expect(recorder.Events).To(BeEmpty())
+}
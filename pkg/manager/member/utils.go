@@ -27,10 +27,10 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/apis/util/toml"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -99,7 +99,11 @@ func templateEqual(new *apps.StatefulSet, old *apps.StatefulSet) bool {
 			klog.Errorf("unmarshal PodTemplate: [%s/%s]'s applied config failed,error: %v", old.GetNamespace(), old.GetName(), err)
 			return false
 		}
-		return apiequality.Semantic.DeepEqual(oldStsSpec.Template.Spec, new.Spec.Template.Spec)
+		// the pods are restarted if the restart generation changes
+		if oldStsSpec.Template.Annotations[label.AnnRestartGeneration] != new.Spec.Template.Annotations[label.AnnRestartGeneration] {
+			return false
+		}
+		return util.PodSpecEqual(oldStsSpec.Template.Spec, new.Spec.Template.Spec)
 	}
 	return false
 }
@@ -129,6 +133,12 @@ func tidbPodName(tcName string, ordinal int32) string {
 	return fmt.Sprintf("%s-%d", controller.TiDBMemberName(tcName), ordinal)
 }
 
+// tidbSetPodName returns the name of the tidb pod of tc at the ordinal, the pods of a tidb group are named
+// after the statefulset of the group
+func tidbSetPodName(tc *v1alpha1.TidbCluster, ordinal int32) string {
+	return fmt.Sprintf("%s-%d", controller.TiDBSetName(tc), ordinal)
+}
+
 func ticdcPodName(tcName string, ordinal int32) string {
 	return fmt.Sprintf("%s-%d", controller.TiCDCMemberName(tcName), ordinal)
 }
@@ -232,6 +242,11 @@ func getTikVConfigMapForTiKVSpec(tikvSpec *v1alpha1.TiKVSpec, tc *v1alpha1.TidbC
 		config.Set("security.cert-path", path.Join(tikvClusterCertPath, corev1.TLSCertKey))
 		config.Set("security.key-path", path.Join(tikvClusterCertPath, corev1.TLSPrivateKeyKey))
 	}
+	if tikvSpec.CPUPinning {
+		if err := setTiKVCPUPinningConfig(config, tikvSpec); err != nil {
+			return nil, err
+		}
+	}
 	confText, err := config.MarshalTOML()
 	if err != nil {
 		return nil, err
@@ -249,6 +264,43 @@ func getTikVConfigMapForTiKVSpec(tikvSpec *v1alpha1.TiKVSpec, tc *v1alpha1.TidbC
 	return cm, nil
 }
 
+// setTiKVCPUPinningConfig sizes the thread pools of TiKV by the CPUs pinned to TiKV, which are the cpu limits
+// of TiKV in the Guaranteed QoS class. The CPUs of the node may be counted by the old versions of TiKV, and
+// the pinned CPUs are not shared, so the sizes are set explicitly. The thread pools set in the config are kept.
+func setTiKVCPUPinningConfig(config *v1alpha1.TiKVConfigWraper, tikvSpec *v1alpha1.TiKVSpec) error {
+	resources, err := v1alpha1.SizedResources(tikvSpec.Sizing, tikvSpec.ResourceRequirements)
+	if err != nil {
+		return err
+	}
+	cpu, ok := resources.Limits[corev1.ResourceCPU]
+	if !ok || cpu.Value() <= 0 {
+		return nil
+	}
+	cpus := cpu.Value()
+
+	// the same as the defaults of TiKV but by the pinned CPUs
+	readPoolSize := cpus * 8 / 10
+	if readPoolSize < 4 {
+		readPoolSize = 4
+	}
+	schedulerPoolSize := int64(8)
+	if cpus < 16 {
+		schedulerPoolSize = cpus
+		if schedulerPoolSize > 4 {
+			schedulerPoolSize = 4
+		}
+	}
+	for key, value := range map[string]int64{
+		"readpool.unified.max-thread-count":  readPoolSize,
+		"storage.scheduler-worker-pool-size": schedulerPoolSize,
+	} {
+		if config.Get(key) == nil {
+			config.Set(key, value)
+		}
+	}
+	return nil
+}
+
 // shouldRecover checks whether we should perform recovery operation.
 func shouldRecover(tc *v1alpha1.TidbCluster, component string, podLister corelisters.PodLister) bool {
 	var stores map[string]v1alpha1.TiKVStore
@@ -295,6 +347,9 @@ func shouldRecover(tc *v1alpha1.TidbCluster, component string, podLister corelis
 				if v.State != v1alpha1.TiKVStateUp {
 					return false
 				}
+				if component == label.TiKVLabelVal && !tikvStoreHealthy(tc, v) {
+					return false
+				}
 			}
 		}
 		if !exist {
@@ -422,7 +477,7 @@ func GetPVCSelectorForPod(controller runtime.Object, memberType v1alpha1.MemberT
 	var l label.Label
 	switch controller.(type) {
 	case *v1alpha1.TidbCluster:
-		podName = ordinalPodName(memberType, meta.GetName(), ordinal)
+		podName = memberPodName(meta, memberType, ordinal)
 		l = label.New().Instance(meta.GetName())
 		l[label.AnnPodNameKey] = podName
 	case *v1alpha1.DMCluster:
@@ -479,3 +534,48 @@ func TiKVStoreIDFromStatus(tc *v1alpha1.TidbCluster, podName string) (uint64, er
 	}
 	return 0, ErrNotFoundStoreID
 }
+
+// syncComponentPausedCondition reflects whether the reconciling of the component is paused
+// in the component's status conditions.
+func syncComponentPausedCondition(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) {
+	for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+		if status.GetMemberType() != memberType {
+			continue
+		}
+		if !tc.IsComponentPaused(memberType) {
+			status.RemoveCondition(v1alpha1.ComponentPaused)
+			return
+		}
+		status.SetCondition(metav1.Condition{
+			Type:    v1alpha1.ComponentPaused,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Paused",
+			Message: "Reconciling is paused, only the status is synced",
+		})
+		return
+	}
+}
+
+// acceptStalePDData accepts the data returned by the PD client along with err if the data is cached
+// because the circuit breaker of the PD API is open, staleSince is set to the earliest time the accepted
+// data is fetched from PD. Other errors are returned as is.
+func acceptStalePDData(err error, staleSince **metav1.Time) error {
+	cachedAt, ok := pdapi.StaleSince(err)
+	if !ok {
+		return err
+	}
+	if *staleSince == nil || cachedAt.Before((*staleSince).Time) {
+		t := metav1.NewTime(cachedAt)
+		*staleSince = &t
+	}
+	return nil
+}
+
+// requeueIfPDCircuitOpen translates the error of the PD API whose circuit breaker is open into a
+// RequeueError, so that the operation is retried after the circuit is closed instead of failing the sync.
+func requeueIfPDCircuitOpen(err error) error {
+	if pdapi.IsCircuitOpen(err) {
+		return controller.RequeueErrorf("%v", err)
+	}
+	return err
+}
@@ -26,6 +26,8 @@ import (
 	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -839,3 +841,29 @@ func TestTiKVLessThanV50(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptStalePDData(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cachedAt := time.Now().Add(-time.Minute)
+	staleErr := &pdapi.ErrCircuitOpen{Namespace: "ns", TCName: "test", CachedAt: cachedAt}
+	openErr := &pdapi.ErrCircuitOpen{Namespace: "ns", TCName: "test"}
+	otherErr := fmt.Errorf("timeout")
+
+	var staleSince *metav1.Time
+	g.Expect(acceptStalePDData(nil, &staleSince)).To(Succeed())
+	g.Expect(staleSince).To(BeNil())
+	g.Expect(acceptStalePDData(otherErr, &staleSince)).To(Equal(otherErr))
+	g.Expect(acceptStalePDData(openErr, &staleSince)).To(Equal(openErr))
+	g.Expect(staleSince).To(BeNil())
+
+	// the earliest time of the cached data is kept
+	g.Expect(acceptStalePDData(staleErr, &staleSince)).To(Succeed())
+	g.Expect(staleSince.Time).To(Equal(cachedAt))
+	g.Expect(acceptStalePDData(&pdapi.ErrCircuitOpen{CachedAt: time.Now()}, &staleSince)).To(Succeed())
+	g.Expect(staleSince.Time).To(Equal(cachedAt))
+
+	g.Expect(controller.IsRequeueError(requeueIfPDCircuitOpen(openErr))).To(BeTrue())
+	g.Expect(requeueIfPDCircuitOpen(otherErr)).To(Equal(otherErr))
+	g.Expect(requeueIfPDCircuitOpen(nil)).To(BeNil())
+}
@@ -0,0 +1,194 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	"github.com/pingcap/tidb-operator/pkg/manager"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+type adoptionManager struct {
+	deps *controller.Dependencies
+}
+
+// NewAdoptionManager returns a manager repairing the stale ownerReferences of the objects of the TidbCluster
+func NewAdoptionManager(deps *controller.Dependencies) manager.Manager {
+	return &adoptionManager{
+		deps: deps,
+	}
+}
+
+// Sync repairs the ownerReferences of the StatefulSets, Services, ConfigMaps and PVCs of the TidbCluster which
+// refer to a TidbCluster of the same name but a different UID if spec.allowAdoption is enabled. The objects
+// restored from a backup of the namespace, e.g. by Velero, refer to the UID of the TidbCluster backed up, so
+// that they are not synced by the TidbCluster restored and are deleted by the garbage collector.
+func (m *adoptionManager) Sync(tc *v1alpha1.TidbCluster) error {
+	if !tc.Spec.AllowAdoption {
+		return nil
+	}
+	ns := tc.GetNamespace()
+	selector := labels.SelectorFromSet(labels.Set{label.InstanceLabelKey: tc.GetInstanceName()})
+	ctx := context.TODO()
+	var repaired []string
+
+	sets, err := m.deps.StatefulSetLister.StatefulSets(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("adoptionManager.Sync: failed to list statefulsets for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, set := range sets {
+		ok, err := repairOwnerReferences(tc, "statefulset", set, func(patch []byte) error {
+			_, err := m.deps.KubeClientset.AppsV1().StatefulSets(ns).Patch(ctx, set.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			repaired = append(repaired, "statefulset "+set.Name)
+		}
+	}
+
+	svcs, err := m.deps.ServiceLister.Services(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("adoptionManager.Sync: failed to list services for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, svc := range svcs {
+		ok, err := repairOwnerReferences(tc, "service", svc, func(patch []byte) error {
+			_, err := m.deps.KubeClientset.CoreV1().Services(ns).Patch(ctx, svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			repaired = append(repaired, "service "+svc.Name)
+		}
+	}
+
+	cms, err := m.deps.ConfigMapLister.ConfigMaps(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("adoptionManager.Sync: failed to list configmaps for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, cm := range cms {
+		ok, err := repairOwnerReferences(tc, "configmap", cm, func(patch []byte) error {
+			_, err := m.deps.KubeClientset.CoreV1().ConfigMaps(ns).Patch(ctx, cm.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			repaired = append(repaired, "configmap "+cm.Name)
+		}
+	}
+
+	pvcs, err := m.deps.PVCLister.PersistentVolumeClaims(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("adoptionManager.Sync: failed to list pvcs for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, pvc := range pvcs {
+		ok, err := repairOwnerReferences(tc, "pvc", pvc, func(patch []byte) error {
+			_, err := m.deps.KubeClientset.CoreV1().PersistentVolumeClaims(ns).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if ok {
+			repaired = append(repaired, "pvc "+pvc.Name)
+		}
+	}
+
+	if len(repaired) > 0 {
+		m.deps.Recorder.Eventf(tc, corev1.EventTypeNormal, events.OwnerReferencesRepaired,
+			"repaired the ownerReferences referring to a stale uid of the TidbCluster: %s", strings.Join(repaired, ", "))
+	}
+	return nil
+}
+
+// adoptedOwnerReferences returns the ownerReferences of the object with the ones referring to a TidbCluster of the
+// same name but a different UID replaced by the TidbCluster, and whether any of them is replaced
+func adoptedOwnerReferences(tc *v1alpha1.TidbCluster, obj metav1.Object) ([]metav1.OwnerReference, bool) {
+	refs := make([]metav1.OwnerReference, 0, len(obj.GetOwnerReferences()))
+	stale := false
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err == nil && gv.Group == v1alpha1.SchemeGroupVersion.Group && ref.Kind == v1alpha1.TiDBClusterKind &&
+			ref.Name == tc.GetName() && ref.UID != tc.GetUID() {
+			ref.UID = tc.GetUID()
+			stale = true
+		}
+		refs = append(refs, ref)
+	}
+	return refs, stale
+}
+
+// repairOwnerReferences patches the ownerReferences of the object repaired by adoptedOwnerReferences, the patch
+// fails if the object is changed after it is listed
+func repairOwnerReferences(tc *v1alpha1.TidbCluster, kind string, obj metav1.Object, patchFn func([]byte) error) (bool, error) {
+	refs, stale := adoptedOwnerReferences(tc, obj)
+	if !stale {
+		return false, nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": refs,
+			"resourceVersion": obj.GetResourceVersion(),
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := patchFn(patch); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("adoptionManager.Sync: failed to repair the ownerReferences of %s %s/%s, error: %v", kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	klog.Infof("tidbcluster: [%s/%s] repaired the ownerReferences of %s %s referring to a stale uid", tc.GetNamespace(), tc.GetName(), kind, obj.GetName())
+	return true, nil
+}
+
+var _ manager.Manager = &adoptionManager{}
+
+type FakeAdoptionManager struct {
+	err error
+}
+
+func NewFakeAdoptionManager() *FakeAdoptionManager {
+	return &FakeAdoptionManager{}
+}
+
+func (m *FakeAdoptionManager) SetSyncError(err error) {
+	m.err = err
+}
+
+func (m *FakeAdoptionManager) Sync(_ *v1alpha1.TidbCluster) error {
+	return m.err
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meta
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// newRestoredObjectMeta returns the metadata of an object restored from a backup of the namespace, which refers
+// to the TidbCluster backed up
+func newRestoredObjectMeta(name string, uid types.UID) metav1.ObjectMeta {
+	ref := controller.GetOwnerRef(&v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: uid},
+	})
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       metav1.NamespaceDefault,
+		Labels:          label.New().Instance("test").Labels(),
+		OwnerReferences: []metav1.OwnerReference{ref},
+	}
+}
+
+func TestAdoptedOwnerReferences(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: "new"},
+	}
+	stale := newRestoredObjectMeta("test-pd", "old").OwnerReferences[0]
+	otherCluster := stale
+	otherCluster.Name = "other"
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "test", UID: "old"}
+
+	tests := []struct {
+		name     string
+		refs     []metav1.OwnerReference
+		expected []metav1.OwnerReference
+		stale    bool
+	}{
+		{
+			name:     "no owner",
+			expected: []metav1.OwnerReference{},
+		},
+		{
+			name:     "owned by the TidbCluster",
+			refs:     []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+			expected: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		{
+			name:     "owned by another kind of the same name",
+			refs:     []metav1.OwnerReference{other},
+			expected: []metav1.OwnerReference{other},
+		},
+		{
+			name:     "owned by another TidbCluster",
+			refs:     []metav1.OwnerReference{otherCluster},
+			expected: []metav1.OwnerReference{otherCluster},
+		},
+		{
+			name:     "owned by the TidbCluster backed up",
+			refs:     []metav1.OwnerReference{stale, other},
+			expected: []metav1.OwnerReference{controller.GetOwnerRef(tc), other},
+			stale:    true,
+		},
+	}
+	for _, tt := range tests {
+		obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{OwnerReferences: tt.refs}}
+		refs, stale := adoptedOwnerReferences(tc, obj)
+		g.Expect(refs).To(Equal(tt.expected), tt.name)
+		g.Expect(stale).To(Equal(tt.stale), tt.name)
+	}
+}
+
+func TestAdoptionManagerSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: "new"},
+	}
+	ctx := context.TODO()
+	kubeCli := deps.KubeClientset
+	m := NewAdoptionManager(deps)
+
+	set := &apps.StatefulSet{ObjectMeta: newRestoredObjectMeta("test-tikv", "old")}
+	svc := &corev1.Service{ObjectMeta: newRestoredObjectMeta("test-tikv-peer", "old")}
+	cm := &corev1.ConfigMap{ObjectMeta: newRestoredObjectMeta("test-tikv", "old")}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: newRestoredObjectMeta("tikv-test-tikv-0", "old")}
+	// created by the TidbCluster restored
+	pdSet := &apps.StatefulSet{ObjectMeta: newRestoredObjectMeta("test-pd", "new")}
+	// not owned by the TidbCluster, e.g. created by the helm release of the same name
+	otherCM := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-other",
+		Namespace: metav1.NamespaceDefault,
+		Labels:    label.New().Instance("test").Labels(),
+	}}
+
+	for _, s := range []*apps.StatefulSet{set, pdSet} {
+		_, err := kubeCli.AppsV1().StatefulSets(s.Namespace).Create(ctx, s, metav1.CreateOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer().Add(s)).To(Succeed())
+	}
+	_, err := kubeCli.CoreV1().Services(svc.Namespace).Create(ctx, svc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Core().V1().Services().Informer().GetIndexer().Add(svc)).To(Succeed())
+	for _, c := range []*corev1.ConfigMap{cm, otherCM} {
+		_, err := kubeCli.CoreV1().ConfigMaps(c.Namespace).Create(ctx, c, metav1.CreateOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(deps.LabelFilterKubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(c)).To(Succeed())
+	}
+	_, err = kubeCli.CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer().Add(pvc)).To(Succeed())
+
+	recorder := deps.Recorder.(*record.FakeRecorder)
+	ownerUIDs := func() []types.UID {
+		var uids []types.UID
+		s, err := kubeCli.AppsV1().StatefulSets(set.Namespace).Get(ctx, set.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		uids = append(uids, s.OwnerReferences[0].UID)
+		v, err := kubeCli.CoreV1().Services(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		uids = append(uids, v.OwnerReferences[0].UID)
+		c, err := kubeCli.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		uids = append(uids, c.OwnerReferences[0].UID)
+		p, err := kubeCli.CoreV1().PersistentVolumeClaims(pvc.Namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+		uids = append(uids, p.OwnerReferences[0].UID)
+		return uids
+	}
+
+	// the adoption is disabled
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(ownerUIDs()).To(Equal([]types.UID{"old", "old", "old", "old"}))
+	g.Expect(recorder.Events).To(BeEmpty())
+
+	tc.Spec.AllowAdoption = true
+	g.Expect(m.Sync(tc)).To(Succeed())
+	g.Expect(ownerUIDs()).To(Equal([]types.UID{"new", "new", "new", "new"}))
+	updatedPDSet, err := kubeCli.AppsV1().StatefulSets(pdSet.Namespace).Get(ctx, pdSet.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedPDSet).To(Equal(pdSet))
+	updatedCM, err := kubeCli.CoreV1().ConfigMaps(otherCM.Namespace).Get(ctx, otherCM.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedCM).To(Equal(otherCM))
+	g.Expect(recorder.Events).To(HaveLen(1))
+	event := <-recorder.Events
+	g.Expect(event).To(ContainSubstring(events.OwnerReferencesRepaired))
+	g.Expect(event).To(ContainSubstring("statefulset test-tikv, service test-tikv-peer, configmap test-tikv, pvc tikv-test-tikv-0"))
+}
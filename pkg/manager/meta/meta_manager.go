@@ -20,6 +20,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	"github.com/pingcap/tidb-operator/pkg/manager"
+	"github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog/v2"
@@ -107,7 +108,8 @@ func (m *metaManager) Sync(tc *v1alpha1.TidbCluster) error {
 		}
 	}
 
-	return nil
+	// ensure the canonical labels on the objects created by older versions of tidb-operator
+	return utils.ReconcileLabels(m.deps, tc)
 }
 
 var _ manager.Manager = &metaManager{}
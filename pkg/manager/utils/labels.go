@@ -0,0 +1,250 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/pingcap/tidb-operator/pkg/util"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// canonicalLabelKeys are the keys of the labels recommended by kubernetes that tidb-operator sets on the
+// objects of a TidbCluster, the objects created by older versions of tidb-operator may lack some of them
+var canonicalLabelKeys = []string{
+	label.NameLabelKey,
+	label.ManagedByLabelKey,
+	label.InstanceLabelKey,
+	label.ComponentLabelKey,
+}
+
+// canonicalLabels returns the canonical labels of an object of the TidbCluster, the component is the one
+// in the current labels of the object and it is left out if the object has no component
+func canonicalLabels(tc *v1alpha1.TidbCluster, current map[string]string) map[string]string {
+	l := label.New().Instance(tc.GetInstanceName())
+	if component := current[label.ComponentLabelKey]; component != "" {
+		l = l.Component(component)
+	}
+	return l.Labels()
+}
+
+// missingLabels returns the labels in desired which are missing or different in current
+func missingLabels(desired, current map[string]string) map[string]string {
+	missing := map[string]string{}
+	for k, v := range desired {
+		if cur, ok := current[k]; !ok || cur != v {
+			missing[k] = v
+		}
+	}
+	return missing
+}
+
+// ReconcileLabels ensures the canonical labels on the StatefulSets, Services, ConfigMaps, PVCs and Jobs of the
+// TidbCluster. The labels are patched to the metadata of the objects in place, the pod templates of the
+// StatefulSets are left to UpdateStatefulSet, which applies the labels along with the next rolling update.
+// The objects are selected by the instance label only, which is in the selectors since the first version.
+func ReconcileLabels(deps *controller.Dependencies, tc *v1alpha1.TidbCluster) error {
+	ns := tc.GetNamespace()
+	selector := labels.SelectorFromSet(labels.Set{label.InstanceLabelKey: tc.GetInstanceName()})
+	ctx := context.TODO()
+
+	sets, err := deps.StatefulSetLister.StatefulSets(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("ReconcileLabels: failed to list statefulsets for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	pendingTemplates := 0
+	for _, set := range sets {
+		if !metav1.IsControlledBy(set, tc) {
+			continue
+		}
+		if _, ok := set.Annotations[label.AnnPendingTemplateLabels]; ok {
+			pendingTemplates++
+		}
+		if err := patchLabels(tc, "statefulset", set, func(patch []byte) error {
+			_, err := deps.KubeClientset.AppsV1().StatefulSets(ns).Patch(ctx, set.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	metrics.ClusterPendingTemplateLabels.WithLabelValues(ns, tc.GetName()).Set(float64(pendingTemplates))
+
+	svcs, err := deps.ServiceLister.Services(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("ReconcileLabels: failed to list services for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, svc := range svcs {
+		if !metav1.IsControlledBy(svc, tc) {
+			continue
+		}
+		if err := patchLabels(tc, "service", svc, func(patch []byte) error {
+			_, err := deps.KubeClientset.CoreV1().Services(ns).Patch(ctx, svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	cms, err := deps.ConfigMapLister.ConfigMaps(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("ReconcileLabels: failed to list configmaps for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, cm := range cms {
+		if !metav1.IsControlledBy(cm, tc) {
+			continue
+		}
+		if err := patchLabels(tc, "configmap", cm, func(patch []byte) error {
+			_, err := deps.KubeClientset.CoreV1().ConfigMaps(ns).Patch(ctx, cm.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	// the PVCs are created by the StatefulSets and not owned by the TidbCluster
+	pvcs, err := deps.PVCLister.PersistentVolumeClaims(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("ReconcileLabels: failed to list pvcs for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, pvc := range pvcs {
+		if !isTidbClusterPVC(pvc) {
+			continue
+		}
+		if err := patchLabels(tc, "pvc", pvc, func(patch []byte) error {
+			_, err := deps.KubeClientset.CoreV1().PersistentVolumeClaims(ns).Patch(ctx, pvc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	jobs, err := deps.JobLister.Jobs(ns).List(selector)
+	if err != nil {
+		return fmt.Errorf("ReconcileLabels: failed to list jobs for cluster %s/%s, error: %v", ns, tc.GetName(), err)
+	}
+	for _, job := range jobs {
+		if !metav1.IsControlledBy(job, tc) {
+			continue
+		}
+		if err := patchLabels(tc, "job", job, func(patch []byte) error {
+			_, err := deps.KubeClientset.BatchV1().Jobs(ns).Patch(ctx, job.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTidbClusterPVC returns whether the PVC is created for a component of the TidbCluster, the PVCs of the other
+// applications in the same helm release have the same instance label but never the name label of TidbCluster
+func isTidbClusterPVC(pvc *corev1.PersistentVolumeClaim) bool {
+	if pvc.Labels[label.ComponentLabelKey] == "" {
+		return false
+	}
+	name, ok := pvc.Labels[label.NameLabelKey]
+	return !ok || name == label.New()[label.NameLabelKey]
+}
+
+// patchLabels patches the canonical labels missing in the object by the patch function
+func patchLabels(tc *v1alpha1.TidbCluster, kind string, obj metav1.Object, patchFn func([]byte) error) error {
+	missing := missingLabels(canonicalLabels(tc, obj.GetLabels()), obj.GetLabels())
+	if len(missing) == 0 {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": missing,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := patchFn(patch); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("ReconcileLabels: failed to patch labels %v to %s %s/%s, error: %v", missing, kind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	klog.Infof("tidbcluster: [%s/%s] patched the canonical labels %v to %s %s", tc.GetNamespace(), tc.GetName(), missing, kind, obj.GetName())
+	return nil
+}
+
+// deferTemplateLabels keeps the canonical labels of the pod template of the StatefulSet unchanged if they are the
+// only change of the template, e.g. the StatefulSet is created by an older version of tidb-operator, as changing
+// the labels of the pod template restarts the pods. The keys of the labels are recorded in the annotation
+// label.AnnPendingTemplateLabels of the StatefulSet instead, the labels are applied along with the next rolling
+// update and the annotation is dropped then.
+func deferTemplateLabels(newSet, oldSet *apps.StatefulSet) {
+	pending := missingLabels(canonicalTemplateLabels(newSet.Spec.Template.Labels), oldSet.Spec.Template.Labels)
+	if len(pending) == 0 {
+		return
+	}
+
+	candidate := newSet.DeepCopy()
+	for k := range pending {
+		if v, ok := oldSet.Spec.Template.Labels[k]; ok {
+			candidate.Spec.Template.Labels[k] = v
+		} else {
+			delete(candidate.Spec.Template.Labels, k)
+		}
+	}
+	// only compare the specs, the annotations are compared by UpdateStatefulSet
+	candidate.Annotations = map[string]string{}
+	for k, v := range oldSet.Annotations {
+		if k != LastAppliedConfigAnnotation && k != label.AnnStsLastSyncTimestamp {
+			candidate.Annotations[k] = v
+		}
+	}
+	if !util.StatefulSetEqual(*candidate, *oldSet) {
+		return
+	}
+
+	keys := make([]string, 0, len(pending))
+	for k := range pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	newSet.Spec.Template.Labels = candidate.Spec.Template.Labels
+	if newSet.Annotations == nil {
+		newSet.Annotations = map[string]string{}
+	}
+	newSet.Annotations[label.AnnPendingTemplateLabels] = strings.Join(keys, ",")
+}
+
+// canonicalTemplateLabels returns the canonical labels in the labels of the pod template
+func canonicalTemplateLabels(templateLabels map[string]string) map[string]string {
+	l := map[string]string{}
+	for _, k := range canonicalLabelKeys {
+		if v, ok := templateLabels[k]; ok {
+			l[k] = v
+		}
+	}
+	return l
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// legacyLabels are the labels of the objects created by an older version of tidb-operator
+func legacyLabels(component string) map[string]string {
+	return map[string]string{
+		label.InstanceLabelKey:  "test",
+		label.ComponentLabelKey: component,
+	}
+}
+
+func newLegacyStatefulSet(tc *v1alpha1.TidbCluster, image string) *apps.StatefulSet {
+	replicas := int32(3)
+	return &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-tikv",
+			Namespace:       metav1.NamespaceDefault,
+			Labels:          legacyLabels(label.TiKVLabelVal),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: legacyLabels(label.TiKVLabelVal)},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: legacyLabels(label.TiKVLabelVal)},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "tikv", Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func TestReconcileLabels(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: "test"},
+	}
+	ctx := context.TODO()
+	kubeCli := deps.KubeClientset
+
+	set := newLegacyStatefulSet(tc, "tikv:v2")
+	set.Annotations = map[string]string{label.AnnPendingTemplateLabels: label.ManagedByLabelKey}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name:            "test-tikv-peer",
+		Namespace:       metav1.NamespaceDefault,
+		Labels:          legacyLabels(label.TiKVLabelVal),
+		OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+	}}
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "tikv-test-tikv-0",
+		Namespace: metav1.NamespaceDefault,
+		Labels:    legacyLabels(label.TiKVLabelVal),
+	}}
+	// not owned by the TidbCluster, e.g. created by the helm release of the same name
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test-other",
+		Namespace: metav1.NamespaceDefault,
+		Labels:    map[string]string{label.InstanceLabelKey: "test"},
+	}}
+
+	_, err := kubeCli.AppsV1().StatefulSets(set.Namespace).Create(ctx, set, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Apps().V1().StatefulSets().Informer().GetIndexer().Add(set)).To(Succeed())
+	_, err = kubeCli.CoreV1().Services(svc.Namespace).Create(ctx, svc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Core().V1().Services().Informer().GetIndexer().Add(svc)).To(Succeed())
+	_, err = kubeCli.CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(ctx, pvc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Core().V1().PersistentVolumeClaims().Informer().GetIndexer().Add(pvc)).To(Succeed())
+	_, err = kubeCli.CoreV1().ConfigMaps(cm.Namespace).Create(ctx, cm, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deps.KubeInformerFactory.Core().V1().ConfigMaps().Informer().GetIndexer().Add(cm)).To(Succeed())
+
+	g.Expect(ReconcileLabels(deps, tc)).To(Succeed())
+
+	expected := label.New().Instance("test").TiKV().Labels()
+	updatedSet, err := kubeCli.AppsV1().StatefulSets(set.Namespace).Get(ctx, set.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedSet.Labels).To(Equal(expected))
+	// the pod template is untouched
+	g.Expect(updatedSet.Spec.Template).To(Equal(set.Spec.Template))
+	updatedSvc, err := kubeCli.CoreV1().Services(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedSvc.Labels).To(Equal(expected))
+	updatedPVC, err := kubeCli.CoreV1().PersistentVolumeClaims(pvc.Namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedPVC.Labels).To(Equal(expected))
+	updatedCM, err := kubeCli.CoreV1().ConfigMaps(cm.Namespace).Get(ctx, cm.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updatedCM.Labels).To(Equal(cm.Labels))
+}
+
+func TestUpdateStatefulSetDefersTemplateLabels(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: "test"},
+	}
+	newSet := func(image string) *apps.StatefulSet {
+		set := newLegacyStatefulSet(tc, image)
+		set.Labels = label.New().Instance("test").TiKV().Labels()
+		set.Spec.Template.Labels = label.New().Instance("test").TiKV().Labels()
+		return set
+	}
+
+	old := newLegacyStatefulSet(tc, "tikv:v2")
+	g.Expect(SetStatefulSetLastAppliedConfigAnnotation(old)).To(Succeed())
+	g.Expect(deps.StatefulSetControl.CreateStatefulSet(tc, old)).To(Succeed())
+
+	// the canonical labels alone never restart the pods
+	g.Expect(UpdateStatefulSet(deps.StatefulSetControl, tc, newSet("tikv:v2"), old.DeepCopy())).To(Succeed())
+	updated, err := deps.StatefulSetLister.StatefulSets(old.Namespace).Get(old.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template).To(Equal(old.Spec.Template))
+	g.Expect(updated.Annotations).To(HaveKeyWithValue(label.AnnPendingTemplateLabels, "app.kubernetes.io/managed-by,app.kubernetes.io/name"))
+
+	// nothing to update in the next sync
+	desired := newSet("tikv:v2")
+	deferTemplateLabels(desired, updated)
+	g.Expect(desired.Spec.Template).To(Equal(old.Spec.Template))
+	g.Expect(UpdateStatefulSet(deps.StatefulSetControl, tc, newSet("tikv:v2"), updated.DeepCopy())).To(Succeed())
+	again, err := deps.StatefulSetLister.StatefulSets(old.Namespace).Get(old.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(again.Spec.Template).To(Equal(old.Spec.Template))
+	g.Expect(again.Annotations).To(HaveKey(label.AnnPendingTemplateLabels))
+
+	// the labels are applied along with the next rolling update
+	g.Expect(UpdateStatefulSet(deps.StatefulSetControl, tc, newSet("tikv:v3"), updated.DeepCopy())).To(Succeed())
+	updated, err = deps.StatefulSetLister.StatefulSets(old.Namespace).Get(old.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("tikv:v3"))
+	g.Expect(updated.Spec.Template.Labels).To(Equal(label.New().Instance("test").TiKV().Labels()))
+	g.Expect(updated.Annotations).NotTo(HaveKey(label.AnnPendingTemplateLabels))
+}
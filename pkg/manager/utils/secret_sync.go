@@ -0,0 +1,149 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// SecretSync copies the secrets referred by the owners, e.g. the TLS secrets of the TidbClusters referred by a
+// TidbMonitor, to the namespaces of the owners and keeps the copies up to date with the sources. The owners which
+// sync a source are enqueued when the source changes if the SecretSync watches the secrets.
+//
+// The copies are labeled with the labels of the sources, so that they are cached if the sources are, annotated
+// with the sources and controlled by the owners. A secret which exists without the annotation of the same source
+// or is controlled by another object is never overwritten.
+type SecretSync struct {
+	deps *controller.Dependencies
+
+	lock sync.RWMutex
+	// owners are the keys of the owners which sync the source secrets by the keys of the sources
+	owners map[string]sets.String
+}
+
+// NewSecretSync returns a SecretSync.
+func NewSecretSync(deps *controller.Dependencies) *SecretSync {
+	return &SecretSync{
+		deps:   deps,
+		owners: map[string]sets.String{},
+	}
+}
+
+// Watch adds the keys of the owners to the queue when the source secrets they sync change.
+func (s *SecretSync) Watch(informer cache.SharedIndexInformer, q workqueue.Interface) {
+	enqueueFn := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("Couldn't get key for object %+v: %v", obj, err))
+			return
+		}
+		s.lock.RLock()
+		defer s.lock.RUnlock()
+		for owner := range s.owners[key] {
+			q.Add(owner)
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueueFn,
+		UpdateFunc: func(_, cur interface{}) {
+			enqueueFn(cur)
+		},
+		DeleteFunc: enqueueFn,
+	})
+}
+
+// Sync copies the source secret to the target secret controlled by the owner and returns the target. The source
+// is only watched and returned if it is the target.
+func (s *SecretSync) Sync(owner metav1.Object, ownerRef metav1.OwnerReference, source, target types.NamespacedName) (*corev1.Secret, error) {
+	s.watch(owner, source)
+
+	src, err := s.deps.SecretLister.Secrets(source.Namespace).Get(source.Name)
+	if err != nil {
+		return nil, fmt.Errorf("get secret %s failed, err: %v", source, err)
+	}
+	if source == target {
+		return src, nil
+	}
+
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            target.Name,
+			Namespace:       target.Namespace,
+			Labels:          map[string]string{},
+			Annotations:     map[string]string{label.AnnSecretSyncSource: source.String()},
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Type: src.Type,
+		Data: src.Data,
+	}
+	for k, v := range src.Labels {
+		desired.Labels[k] = v
+	}
+
+	existing, err := s.deps.SecretLister.Secrets(target.Namespace).Get(target.Name)
+	if errors.IsNotFound(err) {
+		created, err := s.deps.KubeClientset.CoreV1().Secrets(target.Namespace).Create(context.TODO(), desired, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("create secret %s copied from %s failed, err: %v", target, source, err)
+		}
+		klog.Infof("secret %s is copied from %s for %s/%s", target, source, owner.GetNamespace(), owner.GetName())
+		return created, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get secret %s failed, err: %v", target, err)
+	}
+
+	if existing.Annotations[label.AnnSecretSyncSource] != source.String() || !metav1.IsControlledBy(existing, owner) {
+		return nil, fmt.Errorf("secret %s exists and is not copied from %s for %s/%s, refuse to overwrite it",
+			target, source, owner.GetNamespace(), owner.GetName())
+	}
+	if apiequality.Semantic.DeepEqual(existing.Data, desired.Data) && apiequality.Semantic.DeepEqual(existing.Labels, desired.Labels) {
+		return existing, nil
+	}
+	update := existing.DeepCopy()
+	update.Labels = desired.Labels
+	update.Data = desired.Data
+	updated, err := s.deps.KubeClientset.CoreV1().Secrets(target.Namespace).Update(context.TODO(), update, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("update secret %s copied from %s failed, err: %v", target, source, err)
+	}
+	klog.Infof("secret %s is updated from %s for %s/%s", target, source, owner.GetNamespace(), owner.GetName())
+	return updated, nil
+}
+
+func (s *SecretSync) watch(owner metav1.Object, source types.NamespacedName) {
+	key := source.String()
+	ownerKey := fmt.Sprintf("%s/%s", owner.GetNamespace(), owner.GetName())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.owners[key] == nil {
+		s.owners[key] = sets.NewString()
+	}
+	s.owners[key].Insert(ownerKey)
+}
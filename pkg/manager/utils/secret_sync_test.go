@@ -0,0 +1,126 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestSecretSync(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	secretSync := NewSecretSync(deps)
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	secretSync.Watch(deps.KubeInformerFactory.Core().V1().Secrets().Informer(), queue)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	deps.KubeInformerFactory.Start(stopCh)
+	deps.KubeInformerFactory.WaitForCacheSync(stopCh)
+
+	secrets := deps.KubeClientset.CoreV1()
+	cached := func(ns, name, resourceVersion string) func() bool {
+		return func() bool {
+			secret, err := deps.SecretLister.Secrets(ns).Get(name)
+			return err == nil && (resourceVersion == "" || secret.ResourceVersion == resourceVersion)
+		}
+	}
+	tm := &v1alpha1.TidbMonitor{ObjectMeta: metav1.ObjectMeta{Name: "monitor", Namespace: "ns2", UID: "monitor-uid"}}
+	source := types.NamespacedName{Namespace: "ns1", Name: "tc-cluster-client-secret"}
+	target := types.NamespacedName{Namespace: "ns2", Name: "tidbmonitor-monitor-ns1-tc-cluster-client-secret"}
+
+	src := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      source.Name,
+			Namespace: source.Namespace,
+			Labels:    map[string]string{"app": "tc"},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{corev1.TLSCertKey: []byte("cert")},
+	}
+	_, err := secrets.Secrets(source.Namespace).Create(context.TODO(), src, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Eventually(cached(source.Namespace, source.Name, ""), 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+	// the source is copied to the target
+	copied, err := secretSync.Sync(tm, controller.GetTiDBMonitorOwnerRef(tm), source, target)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(copied.Namespace).To(Equal(target.Namespace))
+	g.Expect(copied.Name).To(Equal(target.Name))
+	g.Expect(copied.Labels).To(Equal(map[string]string{"app": "tc"}))
+	g.Expect(copied.Annotations).To(HaveKeyWithValue(label.AnnSecretSyncSource, "ns1/tc-cluster-client-secret"))
+	g.Expect(metav1.IsControlledBy(copied, tm)).To(BeTrue())
+	g.Expect(copied.Type).To(Equal(corev1.SecretTypeTLS))
+	g.Expect(copied.Data).To(Equal(src.Data))
+	g.Eventually(cached(target.Namespace, target.Name, ""), 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+	// the owner is enqueued when the source changes, and the target is updated
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		queue.Done(item)
+	}
+	src.Data = map[string][]byte{corev1.TLSCertKey: []byte("renewed-cert")}
+	src, err = secrets.Secrets(source.Namespace).Update(context.TODO(), src, metav1.UpdateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Eventually(queue.Len, 5*time.Second, 10*time.Millisecond).Should(Equal(1))
+	item, _ := queue.Get()
+	g.Expect(item).To(Equal("ns2/monitor"))
+	queue.Done(item)
+	g.Eventually(cached(source.Namespace, source.Name, src.ResourceVersion), 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+	_, err = secretSync.Sync(tm, controller.GetTiDBMonitorOwnerRef(tm), source, target)
+	g.Expect(err).NotTo(HaveOccurred())
+	copied, err = secrets.Secrets(target.Namespace).Get(context.TODO(), target.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(copied.Data).To(Equal(src.Data))
+
+	// the source is returned if it is the target
+	got, err := secretSync.Sync(tm, controller.GetTiDBMonitorOwnerRef(tm), source, source)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(got.Namespace).To(Equal(source.Namespace))
+	g.Expect(got.Data).To(Equal(src.Data))
+
+	// a secret copied for another owner is not overwritten
+	other := &v1alpha1.TidbMonitor{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns2", UID: "other-uid"}}
+	_, err = secretSync.Sync(other, controller.GetTiDBMonitorOwnerRef(other), source, target)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("refuse to overwrite it"))
+
+	// a secret not copied by the SecretSync is not overwritten
+	conflict := types.NamespacedName{Namespace: "ns2", Name: "user-secret"}
+	_, err = secrets.Secrets(conflict.Namespace).Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: conflict.Name, Namespace: conflict.Namespace},
+		Data:       map[string][]byte{"user": []byte("data")},
+	}, metav1.CreateOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Eventually(cached(conflict.Namespace, conflict.Name, ""), 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+	_, err = secretSync.Sync(tm, controller.GetTiDBMonitorOwnerRef(tm), source, conflict)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring("refuse to overwrite it"))
+	userSecret, err := secrets.Secrets(conflict.Namespace).Get(context.TODO(), conflict.Name, metav1.GetOptions{})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(userSecret.Data).To(Equal(map[string][]byte{"user": []byte("data")}))
+	g.Expect(userSecret.Annotations).NotTo(HaveKey(label.AnnSecretSyncSource))
+}
@@ -14,15 +14,21 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/util"
 
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
@@ -57,6 +63,70 @@ func SetStatefulSetLastAppliedConfigAnnotation(set *apps.StatefulSet) error {
 	return nil
 }
 
+// StatefulSetTemplateDrifted returns whether the pod template of the StatefulSet is changed by others
+// after tidb-operator updates it, e.g. by `kubectl rollout undo` which restores the template of an old
+// ControllerRevision but keeps the last applied config annotation. As the live template is defaulted by
+// the API server, it is drifted if it is not a superset of the last applied template.
+func StatefulSetTemplateDrifted(set *apps.StatefulSet) (bool, error) {
+	lastAppliedConfig, ok := set.Annotations[LastAppliedConfigAnnotation]
+	if !ok {
+		return false, nil
+	}
+	applied := apps.StatefulSetSpec{}
+	if err := json.Unmarshal([]byte(lastAppliedConfig), &applied); err != nil {
+		return false, err
+	}
+	// the pod template annotation is kept by UpdateStatefulSet for backward compatibility
+	delete(applied.Template.Annotations, LastAppliedConfigAnnotation)
+
+	var appliedTemplate, liveTemplate interface{}
+	if err := convertByJSON(applied.Template, &appliedTemplate); err != nil {
+		return false, err
+	}
+	if err := convertByJSON(set.Spec.Template, &liveTemplate); err != nil {
+		return false, err
+	}
+	return !jsonSubset(appliedTemplate, liveTemplate), nil
+}
+
+func convertByJSON(in interface{}, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// jsonSubset returns whether all the fields in sub are set in sup with the same values
+func jsonSubset(sub, sup interface{}) bool {
+	switch sub := sub.(type) {
+	case map[string]interface{}:
+		m, ok := sup.(map[string]interface{})
+		if !ok {
+			return len(sub) == 0 && sup == nil
+		}
+		for k, v := range sub {
+			if !jsonSubset(v, m[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, ok := sup.([]interface{})
+		if !ok || len(l) != len(sub) {
+			return false
+		}
+		for i := range sub {
+			if !jsonSubset(sub[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(sub, sup)
+	}
+}
+
 func notExistMount(sts *apps.StatefulSet, oldSTS *apps.StatefulSet) map[string]corev1.VolumeMount {
 	volumes := make(map[string]struct{})
 	for _, v := range sts.Spec.Template.Spec.Volumes {
@@ -87,7 +157,6 @@ func notExistMount(sts *apps.StatefulSet, oldSTS *apps.StatefulSet) map[string]c
 func UpdateStatefulSetWithPrecheck(
 	deps *controller.Dependencies,
 	tc *v1alpha1.TidbCluster,
-	reason string,
 	newTiDBSet *apps.StatefulSet,
 	oldTiDBSet *apps.StatefulSet,
 ) error {
@@ -97,10 +166,18 @@ func UpdateStatefulSetWithPrecheck(
 	// Emit event and return error here to let the user be aware of this and fix it in the spec
 	notExistMount := notExistMount(newTiDBSet, oldTiDBSet)
 	if len(notExistMount) > 0 {
-		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, reason, "contains volumeMounts that do not have matched volume: %v", notExistMount)
+		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.InvalidVolumeMounts, "statefulset %s contains volumeMounts that do not have matched volume: %v", newTiDBSet.Name, notExistMount)
 		return fmt.Errorf("contains volumeMounts that do not have matched volume: %v", notExistMount)
 	}
 
+	if drifted, err := StatefulSetTemplateDrifted(oldTiDBSet); err != nil {
+		klog.Errorf("failed to check the pod template of statefulset %s/%s, error: %v", oldTiDBSet.Namespace, oldTiDBSet.Name, err)
+	} else if drifted {
+		deps.Recorder.Eventf(tc, corev1.EventTypeWarning, events.StatefulSetTemplateDrifted,
+			"the pod template of statefulset %s does not match its last applied config, it may be changed by `kubectl rollout undo`, revision %s is running, reapply the desired template",
+			oldTiDBSet.Name, oldTiDBSet.Status.UpdateRevision)
+	}
+
 	return UpdateStatefulSet(deps.StatefulSetControl, tc, newTiDBSet, oldTiDBSet)
 }
 
@@ -113,11 +190,24 @@ func UpdateStatefulSet(setCtl controller.StatefulSetControlInterface, object run
 	if oldSet.Annotations == nil {
 		oldSet.Annotations = map[string]string{}
 	}
+	// the canonical labels missing in the pod template alone never trigger a rolling update
+	deferTemplateLabels(newSet, oldSet)
+
+	// The pod template may be changed by others, e.g. `kubectl rollout undo`, then the
+	// last applied config annotation is not the baseline any more and the desired
+	// template has to be applied again.
+	drifted, err := StatefulSetTemplateDrifted(oldSet)
+	if err != nil {
+		return err
+	}
+	if drifted {
+		klog.Warningf("the pod template of statefulset %s/%s is changed by others, reapply the desired template", oldSet.Namespace, oldSet.Name)
+	}
 
 	// Check if an upgrade is needed.
 	// If not, early return.
-	if util.StatefulSetEqual(*newSet, *oldSet) && !isOrphan {
-		return nil
+	if util.StatefulSetEqual(*newSet, *oldSet) && !isOrphan && !drifted {
+		return migrateLastAppliedTemplate(setCtl, object, newSet, oldSet)
 	}
 
 	set := *oldSet
@@ -148,7 +238,7 @@ func UpdateStatefulSet(setCtl controller.StatefulSetControlInterface, object run
 		set.Annotations[label.AnnStsLastSyncTimestamp] = v
 	}
 
-	err := SetStatefulSetLastAppliedConfigAnnotation(&set)
+	err = SetStatefulSetLastAppliedConfigAnnotation(&set)
 	if err != nil {
 		return err
 	}
@@ -158,8 +248,74 @@ func UpdateStatefulSet(setCtl controller.StatefulSetControlInterface, object run
 	return err
 }
 
-// SetUpgradePartition set statefulSet's rolling update partition
+// migrateLastAppliedTemplate rewrites the pod template in the last applied config annotation to the
+// desired one if they are only different in the fields defaulted by the API server, e.g. the applied
+// config is written by an older version of tidb-operator. Only the annotation is updated, the pod
+// template of the StatefulSet is kept to avoid a new revision and the rolling update.
+func migrateLastAppliedTemplate(setCtl controller.StatefulSetControlInterface, object runtime.Object, newSet, oldSet *apps.StatefulSet) error {
+	applied := apps.StatefulSetSpec{}
+	if err := json.Unmarshal([]byte(oldSet.Annotations[LastAppliedConfigAnnotation]), &applied); err != nil {
+		return err
+	}
+	podConfig, hasPodConfig := applied.Template.Annotations[LastAppliedConfigAnnotation]
+	delete(applied.Template.Annotations, LastAppliedConfigAnnotation)
+	if apiequality.Semantic.DeepEqual(applied.Template, newSet.Spec.Template) {
+		return nil
+	}
+
+	applied.Template = *newSet.Spec.Template.DeepCopy()
+	if hasPodConfig {
+		if applied.Template.Annotations == nil {
+			applied.Template.Annotations = map[string]string{}
+		}
+		applied.Template.Annotations[LastAppliedConfigAnnotation] = podConfig
+	}
+	setApply, err := util.Encode(applied)
+	if err != nil {
+		return err
+	}
+	set := oldSet.DeepCopy()
+	set.Annotations[LastAppliedConfigAnnotation] = setApply
+	klog.Infof("migrate the last applied pod template of statefulset %s/%s without rolling update", set.Namespace, set.Name)
+	_, err = setCtl.UpdateStatefulSet(object, set)
+	return err
+}
+
+// SetUpgradePartition set statefulSet's rolling update partition, the partition never
+// exceeds the max replica count of the statefulset.
 func SetUpgradePartition(set *apps.StatefulSet, upgradeOrdinal int32) {
+	if maxPartition := maxUpgradePartition(set); upgradeOrdinal > maxPartition {
+		klog.Infof("clamp %s/%s partition %d to the max replica count %d", set.GetNamespace(), set.GetName(), upgradeOrdinal, maxPartition)
+		upgradeOrdinal = maxPartition
+	}
 	set.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{Partition: &upgradeOrdinal}
 	klog.Infof("set %s/%s partition to %d", set.GetNamespace(), set.GetName(), upgradeOrdinal)
 }
+
+// ClampUpgradePartition resets statefulSet's rolling update partition to the max replica count
+// if the partition exceeds it, e.g. the statefulset is scaled in while it is being upgraded.
+// It returns true if the partition is reset.
+func ClampUpgradePartition(set *apps.StatefulSet) bool {
+	rollingUpdate := set.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil {
+		return false
+	}
+	partition := *rollingUpdate.Partition
+	maxPartition := maxUpgradePartition(set)
+	if partition <= maxPartition {
+		return false
+	}
+	set.Spec.UpdateStrategy.RollingUpdate = &apps.RollingUpdateStatefulSetStrategy{Partition: &maxPartition}
+	klog.Infof("reset %s/%s partition from %d to %d as its replicas shrink", set.GetNamespace(), set.GetName(), partition, maxPartition)
+	return true
+}
+
+// maxUpgradePartition returns the max replica count of the statefulset, i.e. the max pod ordinal plus one,
+// a partition greater than it is meaningless.
+func maxUpgradePartition(set *apps.StatefulSet) int32 {
+	if set.Spec.Replicas == nil {
+		return math.MaxInt32
+	}
+	maxReplicaCount, _ := helper.GetMaxReplicaCountAndDeleteSlots(*set.Spec.Replicas, helper.GetDeleteSlots(set))
+	return maxReplicaCount
+}
@@ -14,12 +14,18 @@
 package utils
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/pingcap/advanced-statefulset/client/apis/apps/v1/helper"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/util"
 	apps "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestStatefulSetIsUpgrading(t *testing.T) {
@@ -96,6 +102,29 @@ func TestStatefulSetIsUpgrading(t *testing.T) {
 	}
 }
 
+func TestSetUpgradePartition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	replicas := int32(3)
+	set := &apps.StatefulSet{Spec: apps.StatefulSetSpec{Replicas: &replicas}}
+	SetUpgradePartition(set, 2)
+	g.Expect(*set.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+	SetUpgradePartition(set, 5)
+	g.Expect(*set.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(3)))
+
+	// delete slots make the max pod ordinal greater than replicas
+	set.Annotations = map[string]string{helper.DeleteSlotsAnn: "[1]"}
+	SetUpgradePartition(set, 5)
+	g.Expect(*set.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(4)))
+
+	// scale in
+	replicas = 2
+	set.Annotations = nil
+	g.Expect(ClampUpgradePartition(set)).To(BeTrue())
+	g.Expect(*set.Spec.UpdateStrategy.RollingUpdate.Partition).To(Equal(int32(2)))
+	g.Expect(ClampUpgradePartition(set)).To(BeFalse())
+}
+
 func TestNotExistMount(t *testing.T) {
 	oldSTS := &apps.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -167,3 +196,151 @@ func TestNotExistMount(t *testing.T) {
 	mp = notExistMount(newSTS, oldSTS)
 	g.Expect(mp).ShouldNot(BeEmpty())
 }
+
+func TestUpdateStatefulSetAfterRolloutUndo(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+	}
+	newTemplate := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "tikv"}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "tikv", Image: image}},
+			},
+		}
+	}
+	newSet := func(image string) *apps.StatefulSet {
+		replicas := int32(3)
+		return &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-tikv", Namespace: metav1.NamespaceDefault},
+			Spec: apps.StatefulSetSpec{
+				Replicas: &replicas,
+				Template: newTemplate(image),
+			},
+		}
+	}
+
+	// the statefulset is upgraded to v2 by tidb-operator
+	applied := newSet("tikv:v2")
+	g.Expect(SetStatefulSetLastAppliedConfigAnnotation(applied)).To(Succeed())
+	g.Expect(deps.StatefulSetControl.CreateStatefulSet(tc, applied)).To(Succeed())
+
+	// the live template is defaulted by the API server
+	live := applied.DeepCopy()
+	live.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	live.Spec.Template.Spec.Containers[0].TerminationMessagePath = corev1.TerminationMessagePathDefault
+	live.Status.CurrentRevision = "test-tikv-1"
+	live.Status.UpdateRevision = "test-tikv-2"
+	drifted, err := StatefulSetTemplateDrifted(live)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drifted).To(BeFalse())
+
+	// nothing is updated
+	g.Expect(UpdateStatefulSetWithPrecheck(deps, tc, newSet("tikv:v2"), live)).To(Succeed())
+	updated, err := deps.StatefulSetLister.StatefulSets(live.Namespace).Get(live.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template.Spec.RestartPolicy).To(BeEmpty())
+
+	// `kubectl rollout undo` restores the template of revision 1 and keeps the annotation,
+	// the statefulset controller then makes revision 1 the update revision again
+	undone := live.DeepCopy()
+	revision1 := newTemplate("tikv:v1")
+	revision1.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	undone.Spec.Template = revision1
+	undone.Status.UpdateRevision = "test-tikv-1"
+	drifted, err = StatefulSetTemplateDrifted(undone)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drifted).To(BeTrue())
+
+	// the desired template is applied again and the baseline is re-established
+	g.Expect(UpdateStatefulSetWithPrecheck(deps, tc, newSet("tikv:v2"), undone)).To(Succeed())
+	updated, err = deps.StatefulSetLister.StatefulSets(undone.Namespace).Get(undone.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("tikv:v2"))
+	drifted, err = StatefulSetTemplateDrifted(updated)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drifted).To(BeFalse())
+
+	events := collectEvents(deps.Recorder.(*record.FakeRecorder).Events)
+	g.Expect(events).To(HaveLen(1))
+	g.Expect(events[0]).To(ContainSubstring("StatefulSetTemplateDrifted"))
+	g.Expect(events[0]).To(ContainSubstring("test-tikv-1"))
+}
+
+func collectEvents(source <-chan string) []string {
+	done := false
+	events := make([]string, 0)
+	for !done {
+		select {
+		case event := <-source:
+			events = append(events, event)
+		default:
+			done = true
+		}
+	}
+	return events
+}
+
+func TestUpdateStatefulSetWithDefaultedLastAppliedConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	deps := controller.NewFakeDependencies()
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, UID: "test"},
+	}
+	newSet := func(image string) *apps.StatefulSet {
+		replicas := int32(3)
+		return &apps.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test-tikv",
+				Namespace:       metav1.NamespaceDefault,
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(tc)},
+			},
+			Spec: apps.StatefulSetSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "tikv"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "tikv", Image: image}},
+					},
+				},
+			},
+		}
+	}
+
+	// the applied config contains the fields defaulted by the API server, e.g. it is
+	// written by an older version from the defaulted template
+	old := newSet("tikv:v2")
+	enableServiceLinks := true
+	old.Spec.Template.Spec.EnableServiceLinks = &enableServiceLinks
+	old.Spec.Template.Spec.Containers[0].TerminationMessagePath = corev1.TerminationMessagePathDefault
+	old.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+	g.Expect(SetStatefulSetLastAppliedConfigAnnotation(old)).To(Succeed())
+	g.Expect(deps.StatefulSetControl.CreateStatefulSet(tc, old)).To(Succeed())
+
+	desired := newSet("tikv:v2")
+	g.Expect(util.StatefulSetEqual(*desired, *old)).To(BeTrue())
+
+	// only the annotation is migrated, the live template is kept to avoid a rolling update
+	g.Expect(UpdateStatefulSet(deps.StatefulSetControl, tc, desired, old)).To(Succeed())
+	updated, err := deps.StatefulSetLister.StatefulSets(old.Namespace).Get(old.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template).To(Equal(old.Spec.Template))
+	applied := apps.StatefulSetSpec{}
+	g.Expect(json.Unmarshal([]byte(updated.Annotations[LastAppliedConfigAnnotation]), &applied)).To(Succeed())
+	g.Expect(applied.Template).To(Equal(desired.Spec.Template))
+	drifted, err := StatefulSetTemplateDrifted(updated)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(drifted).To(BeFalse())
+
+	// the real changes are still applied
+	desired = newSet("tikv:v3")
+	g.Expect(util.StatefulSetEqual(*desired, *updated)).To(BeFalse())
+	g.Expect(UpdateStatefulSet(deps.StatefulSetControl, tc, desired, updated.DeepCopy())).To(Succeed())
+	updated, err = deps.StatefulSetLister.StatefulSets(old.Namespace).Get(old.Name)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(updated.Spec.Template.Spec.Containers[0].Image).To(Equal("tikv:v3"))
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// ConfigReloadApplied is the result of the reloads of the config file applied
+	ConfigReloadApplied = "applied"
+	// ConfigReloadFailed is the result of the reloads of the config file failing to read or validate
+	ConfigReloadFailed = "failed"
+)
+
+var ConfigInfo = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "tidb_operator",
+		Subsystem: "config",
+		Name:      "info",
+		Help:      "The hash of the active config file of tidb-controller-manager, set to 1 for the active hash only",
+	}, []string{LabelHash})
+
+var ConfigReloads = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "tidb_operator",
+		Subsystem: "config",
+		Name:      "reloads_total",
+		Help:      "Reloads of the config file of tidb-controller-manager, by whether the config is applied",
+	}, []string{LabelResult})
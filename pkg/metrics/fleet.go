@@ -0,0 +1,60 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	FleetClustersByPhase = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "fleet",
+			Name:      "clusters_by_phase",
+			Help:      "Number of TidbClusters in each phase",
+		}, []string{LabelNamespace, LabelPhase})
+
+	FleetClustersByReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "fleet",
+			Name:      "clusters_by_ready",
+			Help:      "Number of TidbClusters by the status of Ready condition",
+		}, []string{LabelNamespace, LabelStatus})
+
+	FleetClustersByVersion = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "fleet",
+			Name:      "clusters_by_version",
+			Help:      "Number of TidbClusters running each version",
+		}, []string{LabelNamespace, LabelVersion})
+
+	FleetFailedBackups = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "fleet",
+			Name:      "failed_backups",
+			Help:      "Number of failed Backups",
+		}, []string{LabelNamespace})
+
+	FleetWorstOffenders = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "fleet",
+			Name:      "worst_offender_seconds",
+			Help:      "Seconds since the TidbClusters that have been unhealthy for the longest time became unhealthy",
+		}, []string{LabelNamespace, LabelName, LabelReason})
+)
@@ -20,6 +20,24 @@ import (
 // RegisterMetrics registers all metrics of tidb-operator.
 func RegisterMetrics() {
 	prometheus.MustRegister(ClusterSpecReplicas)
+	prometheus.MustRegister(ClusterPendingTemplateLabels)
+	prometheus.MustRegister(ConfigInfo)
+	prometheus.MustRegister(ConfigReloads)
+	prometheus.MustRegister(EventsDropped)
+	prometheus.MustRegister(FleetClustersByPhase)
+	prometheus.MustRegister(FleetClustersByReady)
+	prometheus.MustRegister(FleetClustersByVersion)
+	prometheus.MustRegister(FleetFailedBackups)
+	prometheus.MustRegister(FleetWorstOffenders)
+	prometheus.MustRegister(PDCircuitBreakerState)
+	prometheus.MustRegister(PDCircuitBreakerRejectedRequests)
+	prometheus.MustRegister(RBACMissingPermissions)
+	prometheus.MustRegister(ShutdownSyncs)
+	prometheus.MustRegister(StoreWatcherWatchedClusters)
+	prometheus.MustRegister(StoreWatcherRestarts)
+	prometheus.MustRegister(StoreWatcherEnqueues)
+	prometheus.MustRegister(TiKVSlowStores)
+	prometheus.MustRegister(TiKVSlowStoreMitigations)
 }
 
 // Label constants.
@@ -27,4 +45,15 @@ const (
 	LabelNamespace = "namespace"
 	LabelName      = "name"
 	LabelComponent = "component"
+	LabelPhase     = "phase"
+	LabelStatus    = "status"
+	LabelVersion   = "version"
+	LabelReason    = "reason"
+	LabelGroup     = "group"
+	LabelResource  = "resource"
+	LabelVerb      = "verb"
+	LabelResult    = "result"
+	LabelAction    = "action"
+	LabelHash      = "hash"
+	LabelType      = "type"
 )
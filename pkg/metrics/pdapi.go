@@ -0,0 +1,36 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	PDCircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "pd_api",
+			Name:      "circuit_breaker_state",
+			Help:      "State of the PD API circuit breaker of each TidbCluster, 0 for closed, 1 for half-open and 2 for open",
+		}, []string{LabelNamespace, LabelName})
+
+	PDCircuitBreakerRejectedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "pd_api",
+			Name:      "circuit_breaker_rejected_requests_total",
+			Help:      "Number of PD API requests rejected by the open circuit breaker of each TidbCluster",
+		}, []string{LabelNamespace, LabelName})
+)
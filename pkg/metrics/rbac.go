@@ -0,0 +1,26 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var RBACMissingPermissions = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "tidb_operator",
+		Subsystem: "rbac",
+		Name:      "missing_permissions",
+		Help:      "Permissions required by the enabled capabilities but not granted to tidb-controller-manager, set to 1 for each missing verb on startup",
+	}, []string{LabelGroup, LabelResource, LabelVerb})
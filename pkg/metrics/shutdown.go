@@ -0,0 +1,33 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// ShutdownSyncDrained is the result of the syncs finished in the grace period of the shutdown
+	ShutdownSyncDrained = "drained"
+	// ShutdownSyncAborted is the result of the syncs still running when the grace period of the shutdown elapses
+	ShutdownSyncAborted = "aborted"
+)
+
+var ShutdownSyncs = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "tidb_operator",
+		Subsystem: "shutdown",
+		Name:      "syncs_total",
+		Help:      "Syncs in flight when tidb-controller-manager shuts down, by whether they finish in the grace period",
+	}, []string{LabelResult})
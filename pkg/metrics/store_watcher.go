@@ -0,0 +1,44 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	StoreWatcherWatchedClusters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "store_watcher",
+			Name:      "watched_clusters",
+			Help:      "Number of TidbClusters whose TiKV stores are watched",
+		})
+
+	StoreWatcherRestarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "store_watcher",
+			Name:      "restarts_total",
+			Help:      "Number of times the watch of the TiKV stores of each TidbCluster restarts after failing to get the stores from PD",
+		}, []string{LabelNamespace, LabelName})
+
+	StoreWatcherEnqueues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "store_watcher",
+			Name:      "enqueues_total",
+			Help:      "Number of times each TidbCluster is enqueued because its TiKV stores become Disconnected or Down",
+		}, []string{LabelNamespace, LabelName})
+)
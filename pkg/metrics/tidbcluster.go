@@ -25,4 +25,12 @@ var (
 			Name:      "spec_replicas",
 			Help:      "Desired replicas of each component in TidbCluster",
 		}, []string{LabelNamespace, LabelName, LabelComponent})
+
+	ClusterPendingTemplateLabels = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "cluster",
+			Name:      "pending_template_label_migrations",
+			Help:      "StatefulSets of each TidbCluster whose pod templates are waiting for the next rolling update to get the canonical labels",
+		}, []string{LabelNamespace, LabelName})
 )
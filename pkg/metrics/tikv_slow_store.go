@@ -0,0 +1,52 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// SlowStoreActionDetect is the action a store is detected as slow
+	SlowStoreActionDetect = "detect"
+	// SlowStoreActionRecover is the action a slow store is detected as recovered
+	SlowStoreActionRecover = "recover"
+	// SlowStoreActionReduceLeaderWeight is the action the leader weight of a slow store is reduced
+	SlowStoreActionReduceLeaderWeight = "reduce_leader_weight"
+	// SlowStoreActionRestoreLeaderWeight is the action the original leader weight of a store is restored
+	SlowStoreActionRestoreLeaderWeight = "restore_leader_weight"
+
+	// SlowStoreActionSucceeded is the result of the actions which succeed
+	SlowStoreActionSucceeded = "succeeded"
+	// SlowStoreActionFailed is the result of the actions which fail
+	SlowStoreActionFailed = "failed"
+)
+
+var (
+	TiKVSlowStores = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "tikv",
+			Name:      "slow_stores",
+			Help:      "Number of the slow TiKV stores of each TidbCluster detected by spec.tikv.slowStoreMitigation",
+		}, []string{LabelNamespace, LabelName})
+
+	TiKVSlowStoreMitigations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "tidb_operator",
+			Subsystem: "tikv",
+			Name:      "slow_store_mitigations_total",
+			Help:      "Number of the mitigation actions taken on the slow TiKV stores of each TidbCluster",
+		}, []string{LabelNamespace, LabelName, LabelAction, LabelResult})
+)
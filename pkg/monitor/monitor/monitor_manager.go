@@ -23,6 +23,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	v1alpha1validation "github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1/validation"
 	"github.com/pingcap/tidb-operator/pkg/controller"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/features"
 	"github.com/pingcap/tidb-operator/pkg/manager/member"
 	"github.com/pingcap/tidb-operator/pkg/manager/meta"
@@ -38,6 +39,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	discoverycachedmemory "k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/klog/v2"
@@ -48,21 +50,21 @@ type MonitorManager struct {
 	deps               *controller.Dependencies
 	pvManager          monitor.MonitorManager
 	discoveryInterface discovery.CachedDiscoveryInterface
+	secretSync         *mngerutils.SecretSync
 }
 
 const (
-	FailedSync          = "FailedSync"
-	SuccessSync         = "SuccessSync"
 	prometheusComponent = "prometheus"
 	grafanaComponent    = "grafana"
 	componentPrefix     = "/topology"
 )
 
-func NewMonitorManager(deps *controller.Dependencies) *MonitorManager {
+func NewMonitorManager(deps *controller.Dependencies, secretSync *mngerutils.SecretSync) *MonitorManager {
 	return &MonitorManager{
 		deps:               deps,
 		pvManager:          meta.NewReclaimPolicyManager(deps),
 		discoveryInterface: discoverycachedmemory.NewMemCacheClient(deps.KubeClientset.Discovery()),
+		secretSync:         secretSync,
 	}
 }
 
@@ -70,7 +72,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 	if monitor.DeletionTimestamp != nil {
 		return nil
 	}
-	if len(monitor.Spec.Clusters) < 1 && (monitor.Spec.DM == nil || len(monitor.Spec.DM.Clusters) < 1) {
+	if len(monitor.Spec.Clusters) < 1 && (monitor.Spec.DM == nil || len(monitor.Spec.DM.Clusters) < 1) && len(monitor.Spec.ExternalTargets) < 1 {
 		klog.Errorf("tm[%s/%s] does not configure the target tidbcluster", monitor.Namespace, monitor.Name)
 		return nil
 	}
@@ -79,6 +81,10 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 	if !m.validate(monitor) {
 		return nil // fatal error, no need to retry on invalid object
 	}
+	if err := m.checkInitializerTLS(monitor); err != nil {
+		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, events.FailedSync, err.Error())
+		return err
+	}
 
 	var firstTc *v1alpha1.TidbCluster
 	assetStore := NewStore(m.deps.SecretLister)
@@ -93,7 +99,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 		// If cluster enable tls
 		if tc.IsTLSClusterEnabled() {
 			tcTlsSecretName := util.ClusterClientTLSSecretName(tc.Name)
-			err := assetStore.addTLSAssets(tc.Namespace, tcTlsSecretName)
+			err := m.syncTLSAssets(monitor, assetStore, tc.Namespace, tcTlsSecretName)
 			if err != nil {
 				return err
 			}
@@ -123,7 +129,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 			// If cluster enable tls
 			if dc.IsTLSClusterEnabled() {
 				dmTlsSecretName := util.DMClientTLSSecretName(dcRef.Name)
-				err := assetStore.addTLSAssets(dcRef.Namespace, dmTlsSecretName)
+				err := m.syncTLSAssets(monitor, assetStore, dcRef.Namespace, dmTlsSecretName)
 				if err != nil {
 					return err
 				}
@@ -131,6 +137,16 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 		}
 	}
 
+	for _, targets := range monitor.Spec.ExternalTargets {
+		for _, group := range []*v1alpha1.ExternalTargetGroup{targets.PD, targets.TiDB, targets.TiKV} {
+			if group != nil && group.TLSSecret != "" {
+				if err := m.syncTLSAssets(monitor, assetStore, monitor.Namespace, group.TLSSecret); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// create or update tls asset secret
 	err := m.syncAssetSecret(monitor, assetStore)
 	if err != nil {
@@ -145,7 +161,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 	// Sync Service
 	if err := m.syncTidbMonitorService(monitor); err != nil {
 		message := fmt.Sprintf("Sync TidbMonitor[%s/%s] Service failed, err: %v", monitor.Namespace, monitor.Name, err)
-		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, FailedSync, message)
+		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, events.FailedSync, message)
 		return err
 	}
 	klog.V(4).Infof("tm[%s/%s]'s service synced", monitor.Namespace, monitor.Name)
@@ -153,7 +169,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 	// Sync Statefulset
 	if err := m.syncTidbMonitorStatefulset(firstTc, firstDc, monitor, assetStore); err != nil {
 		message := fmt.Sprintf("Sync TidbMonitor[%s/%s] Statefulset failed, err:%v", monitor.Namespace, monitor.Name, err)
-		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, FailedSync, message)
+		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, events.FailedSync, message)
 		return err
 	}
 
@@ -173,7 +189,7 @@ func (m *MonitorManager) SyncMonitor(monitor *v1alpha1.TidbMonitor) error {
 	// Sync Ingress
 	if err := m.syncIngress(monitor); err != nil {
 		message := fmt.Sprintf("Sync TidbMonitor[%s/%s] Ingress failed,err:%v", monitor.Namespace, monitor.Name, err)
-		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, FailedSync, message)
+		m.deps.Recorder.Event(monitor, corev1.EventTypeWarning, events.FailedSync, message)
 		return err
 	}
 	klog.V(4).Infof("tm[%s/%s]'s ingress synced", monitor.Namespace, monitor.Name)
@@ -197,6 +213,7 @@ func (m *MonitorManager) syncTidbMonitorStatus(monitor *v1alpha1.TidbMonitor) er
 		return err
 	}
 	monitor.Status.StatefulSet = &sts.Status
+	monitor.Status.StorageUsage = member.SyncVolumeStorageUsage(m.deps, monitor, "monitor", monitor.Status.StorageUsage, sts, v1alpha1.DefaultStorageWarningThreshold)
 	return nil
 }
 
@@ -658,12 +675,28 @@ func (c *MonitorManager) validate(tidbmonitor *v1alpha1.TidbMonitor) bool {
 	if len(errs) > 0 {
 		aggregatedErr := errs.ToAggregate()
 		klog.Errorf("tidbmonitor %s/%s is not valid and must be fixed first, aggregated error: %v", tidbmonitor.GetNamespace(), tidbmonitor.GetName(), aggregatedErr)
-		c.deps.Recorder.Event(tidbmonitor, corev1.EventTypeWarning, "FailedValidation", aggregatedErr.Error())
+		c.deps.Recorder.Event(tidbmonitor, corev1.EventTypeWarning, events.FailedValidation, aggregatedErr.Error())
 		return false
 	}
 	return true
 }
 
+// checkInitializerTLS checks the secret of the ca.crt for the initializer to verify the TiDB servers, which is
+// TLSCASecretName or TLSClientSecretName if the former is not set
+func (m *MonitorManager) checkInitializerTLS(monitor *v1alpha1.TidbMonitor) error {
+	name := monitor.Spec.Initializer.TLSCASecretName
+	if name == nil {
+		name = monitor.Spec.Initializer.TLSClientSecretName
+	}
+	if name == nil {
+		return nil
+	}
+	if err := util.CheckCASecret(m.deps.SecretLister, monitor.Namespace, *name); err != nil {
+		return fmt.Errorf("tm[%s/%s] has invalid TLS secret for initializer: %v", monitor.Namespace, monitor.Name, err)
+	}
+	return nil
+}
+
 func (m *MonitorManager) syncTidbMonitorPV(tm *v1alpha1.TidbMonitor) error {
 	ns := tm.GetNamespace()
 	instanceName := tm.Name
@@ -721,6 +754,22 @@ func (m *MonitorManager) patchPVClaimRef(pv *corev1.PersistentVolume, patchPvcNa
 	return nil
 }
 
+// syncTLSAssets adds the assets of the TLS secret of a target to the store, the secret of a target in another
+// namespace is copied to the namespace of the monitor, and the monitor is synced again when the secret changes
+func (m *MonitorManager) syncTLSAssets(monitor *v1alpha1.TidbMonitor, store *Store, ns, secretName string) error {
+	source := types.NamespacedName{Namespace: ns, Name: secretName}
+	target := source
+	if ns != monitor.Namespace {
+		target = types.NamespacedName{Namespace: monitor.Namespace, Name: GetTLSSecretCopyName(monitor.Name, ns, secretName)}
+	}
+	secret, err := m.secretSync.Sync(monitor, controller.GetTiDBMonitorOwnerRef(monitor), source, target)
+	if err != nil {
+		return fmt.Errorf("sync tm[%s/%s]'s tls secret failed, err: %v", monitor.Namespace, monitor.Name, err)
+	}
+	store.addTLSAssets(ns, secretName, secret)
+	return nil
+}
+
 func (m *MonitorManager) syncAssetSecret(monitor *v1alpha1.TidbMonitor, store *Store) error {
 	ns := monitor.Namespace
 	name := monitor.Name
@@ -21,6 +21,7 @@ import (
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/controller"
 	"github.com/pingcap/tidb-operator/pkg/manager/meta"
+	mngerutils "github.com/pingcap/tidb-operator/pkg/manager/utils"
 	"github.com/prometheus/common/model"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -688,6 +689,7 @@ func newFakeTidbMonitorManager() *MonitorManager {
 	return &MonitorManager{deps: fakeDeps,
 		pvManager:          meta.NewReclaimPolicyManager(fakeDeps),
 		discoveryInterface: discoverycachedmemory.NewMemCacheClient(discoveryClient),
+		secretSync:         mngerutils.NewSecretSync(fakeDeps),
 	}
 
 }
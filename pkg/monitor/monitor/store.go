@@ -17,6 +17,7 @@ import (
 	"fmt"
 
 	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	corelisterv1 "k8s.io/client-go/listers/core/v1"
 )
 
@@ -42,17 +43,12 @@ func NewStore(secretLister corelisterv1.SecretLister) *Store {
 	}
 }
 
-// addTLSAssets processes the given Secret and adds the referenced CA, certificate and key to the store.
-func (s *Store) addTLSAssets(ns string, secretName string) error {
-	secret, err := s.secretLister.Secrets(ns).Get(secretName)
-	if err != nil {
-		rerr := fmt.Errorf("get secret [%s/%s] failed, err: %v", ns, secretName, err)
-		return rerr
-	}
+// addTLSAssets adds the CA, certificate and key in the given Secret to the store as the ones of the referenced
+// Secret, the given Secret is the referenced one or its copy.
+func (s *Store) addTLSAssets(ns string, secretName string, secret *corev1.Secret) {
 	for key, value := range secret.Data {
-		s.TLSAssets[TLSAssetKey{"secret", secret.Namespace, secret.Name, key}] = TLSAsset(value)
+		s.TLSAssets[TLSAssetKey{"secret", ns, secretName, key}] = TLSAsset(value)
 	}
-	return nil
 }
 
 // AddBasicAuth processes the given *BasicAuth and adds the referenced credentials to the store.
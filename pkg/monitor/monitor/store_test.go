@@ -90,8 +90,7 @@ func TestStoreAddTLSAssets(t *testing.T) {
 	}
 	err := tmm.deps.SecretControl.Create(ns, secret)
 	g.Expect(err).NotTo(HaveOccurred())
-	err = store.addTLSAssets(ns, secret.Name)
-	g.Expect(err).NotTo(HaveOccurred())
+	store.addTLSAssets(ns, secret.Name, secret)
 	m := make(map[TLSAssetKey]TLSAsset)
 	m[TLSAssetKey{"secret", secret.Namespace, secret.Name, "password"}] = "password"
 	m[TLSAssetKey{"secret", secret.Namespace, secret.Name, "username"}] = "username"
@@ -18,6 +18,7 @@ import (
 	"path"
 	"strings"
 
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
 	"github.com/pingcap/tidb-operator/pkg/util"
 	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
@@ -79,7 +80,10 @@ type MonitorConfigModel struct {
 	RemoteWriteCfg            *yaml.MapItem
 	EnableAlertRules          bool
 	EnableExternalRuleConfigs bool
-	shards                    int32
+	ExternalTargets           []v1alpha1.ExternalClusterTargets
+	// ExternalTargetsNamespace is the namespace of the TLS secrets of the external targets
+	ExternalTargetsNamespace string
+	shards                   int32
 }
 
 // ClusterRegexInfo is the monitor cluster info
@@ -103,6 +107,7 @@ func newPrometheusConfig(cmodel *MonitorConfigModel) yaml.MapSlice {
 	scrapeJobs = append(scrapeJobs, scrapeJob("lightning", lightningPattern, cmodel, buildAddressRelabelConfigByComponent("lightning"))...)
 	scrapeJobs = append(scrapeJobs, scrapeJob(dmWorker, dmWorkerPattern, cmodel, buildAddressRelabelConfigByComponent(dmWorker))...)
 	scrapeJobs = append(scrapeJobs, scrapeJob(dmMaster, dmMasterPattern, cmodel, buildAddressRelabelConfigByComponent(dmMaster))...)
+	scrapeJobs = append(scrapeJobs, externalScrapeJobs(cmodel)...)
 	cfg := yaml.MapSlice{}
 	globalItems := yaml.MapSlice{
 		{Key: "evaluation_interval", Value: "15s"},
@@ -443,6 +448,60 @@ func scrapeJob(jobName string, componentPattern string, cmodel *MonitorConfigMod
 
 }
 
+// externalScrapeJobs returns the scrape jobs of the statically-defined targets of the clusters not managed
+// by TiDB Operator, the `cluster`, `tidb_cluster` and `component` labels are set the same as the scrape jobs
+// of the monitored TidbClusters so the dashboards work unchanged.
+func externalScrapeJobs(cmodel *MonitorConfigModel) []yaml.MapSlice {
+	var scrapeJobs []yaml.MapSlice
+	for _, targets := range cmodel.ExternalTargets {
+		groups := []struct {
+			component string
+			group     *v1alpha1.ExternalTargetGroup
+		}{{pdPattern, targets.PD}, {tidbPattern, targets.TiDB}, {tikvPattern, targets.TiKV}}
+		for _, g := range groups {
+			if g.group == nil || len(g.group.Targets) == 0 {
+				continue
+			}
+			labels := model.LabelSet{}
+			for name, value := range g.group.Labels {
+				labels[model.LabelName(name)] = model.LabelValue(value)
+			}
+			labels["cluster"] = model.LabelValue(targets.Name)
+			labels["tidb_cluster"] = model.LabelValue(targets.Name)
+			labels["component"] = model.LabelValue(g.component)
+
+			scheme := "http"
+			tlsConfig := yaml.MapSlice{
+				{Key: "insecure_skip_verify", Value: true},
+			}
+			if g.group.TLSSecret != "" {
+				scheme = "https"
+				tlsConfig = yaml.MapSlice{
+					{Key: "ca_file", Value: path.Join(util.ClusterAssetsTLSPath, TLSAssetKey{"secret", cmodel.ExternalTargetsNamespace, g.group.TLSSecret, corev1.ServiceAccountRootCAKey}.String())},
+					{Key: "cert_file", Value: path.Join(util.ClusterAssetsTLSPath, TLSAssetKey{"secret", cmodel.ExternalTargetsNamespace, g.group.TLSSecret, corev1.TLSCertKey}.String())},
+					{Key: "key_file", Value: path.Join(util.ClusterAssetsTLSPath, TLSAssetKey{"secret", cmodel.ExternalTargetsNamespace, g.group.TLSSecret, corev1.TLSPrivateKeyKey}.String())},
+				}
+			}
+
+			scrapeJobs = append(scrapeJobs, yaml.MapSlice{
+				{Key: "job_name", Value: fmt.Sprintf("external-%s-%s", targets.Name, g.component)},
+				{Key: "honor_labels", Value: true},
+				{Key: "scrape_interval", Value: "15s"},
+				{Key: "scheme", Value: scheme},
+				{Key: "static_configs", Value: []yaml.MapSlice{
+					{
+						{Key: "targets", Value: g.group.Targets},
+						{Key: "labels", Value: labels},
+					},
+				}},
+				{Key: "tls_config", Value: tlsConfig},
+				{Key: "relabel_configs", Value: appendShardingRelabelConfigRules(nil, uint64(cmodel.shards))},
+			})
+		}
+	}
+	return scrapeJobs
+}
+
 func isDMJob(jobName string) bool {
 	if jobName == dmMaster || jobName == dmWorker {
 		return true
@@ -3056,3 +3056,88 @@ func TestScrapeJob(t *testing.T) {
 		},
 	}))
 }
+
+func TestExternalScrapeJobs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	model := &MonitorConfigModel{
+		ExternalTargets: []v1alpha1.ExternalClusterTargets{
+			{
+				Name: "legacy",
+				PD: &v1alpha1.ExternalTargetGroup{
+					Targets:   []string{"10.0.0.1:2379", "10.0.0.2:2379"},
+					TLSSecret: "legacy-client-tls",
+					Labels:    map[string]string{"dc": "east"},
+				},
+				TiKV: &v1alpha1.ExternalTargetGroup{
+					Targets: []string{"10.0.0.3:20180"},
+				},
+			},
+		},
+		ExternalTargetsNamespace: "ns1",
+		shards:                   1,
+	}
+	out, err := yaml.Marshal(externalScrapeJobs(model))
+	g.Expect(err).NotTo(HaveOccurred())
+	expected := `- job_name: external-legacy-pd
+  honor_labels: true
+  scrape_interval: 15s
+  scheme: https
+  static_configs:
+  - targets:
+    - 10.0.0.1:2379
+    - 10.0.0.2:2379
+    labels:
+      cluster: legacy
+      component: pd
+      dc: east
+      tidb_cluster: legacy
+  tls_config:
+    ca_file: /var/lib/cluster-assets-tls/secret_ns1_legacy-client-tls_ca.crt
+    cert_file: /var/lib/cluster-assets-tls/secret_ns1_legacy-client-tls_tls.crt
+    key_file: /var/lib/cluster-assets-tls/secret_ns1_legacy-client-tls_tls.key
+  relabel_configs:
+  - source_labels:
+    - __address__
+    action: hashmod
+    target_label: __tmp_hash
+    modulus: 1
+  - source_labels:
+    - __tmp_hash
+    regex: $(SHARD)
+    action: keep
+- job_name: external-legacy-tikv
+  honor_labels: true
+  scrape_interval: 15s
+  scheme: http
+  static_configs:
+  - targets:
+    - 10.0.0.3:20180
+    labels:
+      cluster: legacy
+      component: tikv
+      tidb_cluster: legacy
+  tls_config:
+    insecure_skip_verify: true
+  relabel_configs:
+  - source_labels:
+    - __address__
+    action: hashmod
+    target_label: __tmp_hash
+    modulus: 1
+  - source_labels:
+    - __tmp_hash
+    regex: $(SHARD)
+    action: keep
+`
+	g.Expect(string(out)).To(Equal(expected))
+
+	// the external scrape jobs follow the scrape jobs of the monitored clusters
+	model.ClusterInfos = []ClusterRegexInfo{{Name: "basic", Namespace: "ns1"}}
+	for _, item := range newPrometheusConfig(model) {
+		if item.Key == "scrape_configs" {
+			jobs := item.Value.([]yaml.MapSlice)
+			g.Expect(jobs[len(jobs)-1][0].Value).To(Equal("external-legacy-tikv"))
+			g.Expect(jobs[len(jobs)-2][0].Value).To(Equal("external-legacy-pd"))
+		}
+	}
+}
@@ -48,6 +48,11 @@ func GetTLSAssetsSecretName(name string) string {
 	return fmt.Sprintf("tidbmonitor-%s-tls-assets", name)
 }
 
+// GetTLSSecretCopyName returns the name of the copy of the TLS secret of a target in another namespace
+func GetTLSSecretCopyName(name, ns, secretName string) string {
+	return fmt.Sprintf("tidbmonitor-%s-%s-%s", name, ns, secretName)
+}
+
 func GetMonitorObjectName(monitor *v1alpha1.TidbMonitor) string {
 	return fmt.Sprintf("%s-monitor", monitor.Name)
 }
@@ -157,8 +162,12 @@ func getPromConfigMap(monitor *v1alpha1.TidbMonitor, monitorClusterInfos []Clust
 		DMClusterInfos:   dmClusterInfos,
 		ExternalLabels:   buildExternalLabels(monitor),
 		EnableAlertRules: monitor.Spec.EnableAlertRules,
+		ExternalTargets:  monitor.Spec.ExternalTargets,
 		shards:           shard,
 	}
+	if len(model.ExternalTargets) > 0 {
+		model.ExternalTargetsNamespace = monitor.Namespace
+	}
 
 	if monitor.Spec.AlertmanagerURL != nil {
 		model.AlertmanagerURL = *monitor.Spec.AlertmanagerURL
@@ -387,6 +396,9 @@ func getMonitorInitContainer(monitor *v1alpha1.TidbMonitor, tc *v1alpha1.TidbClu
 		container.Env = append(container.Env, getGrafanaEnvs()...)
 	}
 
+	container.VolumeMounts = append(container.VolumeMounts, getInitializerTLSVolumeMounts(monitor)...)
+	container.Env = util.AppendOverwriteEnv(container.Env, getInitializerTLSEnvs(monitor))
+
 	var envOverrides []core.EnvVar
 	for k, v := range monitor.Spec.Initializer.Envs {
 		envOverrides = append(envOverrides, core.EnvVar{
@@ -832,6 +844,80 @@ func getMonitorReloaderContainer(monitor *v1alpha1.TidbMonitor) core.Container {
 	return c
 }
 
+// getInitializerTLSVolumes returns the volumes of the secrets the initializer connects to the TiDB servers with
+func getInitializerTLSVolumes(monitor *v1alpha1.TidbMonitor) []core.Volume {
+	var volumes []core.Volume
+	if name := monitor.Spec.Initializer.TLSClientSecretName; name != nil {
+		volumes = append(volumes, core.Volume{
+			Name: "tidb-client-tls",
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: *name,
+				},
+			},
+		})
+	}
+	if name := monitor.Spec.Initializer.TLSCASecretName; name != nil {
+		volumes = append(volumes, core.Volume{
+			Name: "tidb-server-ca",
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: *name,
+				},
+			},
+		})
+	}
+	return volumes
+}
+
+func getInitializerTLSVolumeMounts(monitor *v1alpha1.TidbMonitor) []core.VolumeMount {
+	var mounts []core.VolumeMount
+	if monitor.Spec.Initializer.TLSClientSecretName != nil {
+		mounts = append(mounts, core.VolumeMount{
+			Name:      "tidb-client-tls",
+			ReadOnly:  true,
+			MountPath: util.TiDBClientTLSPath,
+		})
+	}
+	if monitor.Spec.Initializer.TLSCASecretName != nil {
+		mounts = append(mounts, core.VolumeMount{
+			Name:      "tidb-server-ca",
+			ReadOnly:  true,
+			MountPath: util.TiDBServerCAPath,
+		})
+	}
+	return mounts
+}
+
+// getInitializerTLSEnvs returns the envs of the paths of the TLS files for the initializer to connect to the TiDB
+// servers, the ca.crt of TLSCASecretName takes precedence over the one of TLSClientSecretName
+func getInitializerTLSEnvs(monitor *v1alpha1.TidbMonitor) []core.EnvVar {
+	var envs []core.EnvVar
+	if monitor.Spec.Initializer.TLSClientSecretName != nil {
+		envs = append(envs, []core.EnvVar{
+			{
+				Name:  "TIDB_SSL_CA",
+				Value: path.Join(util.TiDBClientTLSPath, core.ServiceAccountRootCAKey),
+			},
+			{
+				Name:  "TIDB_SSL_CERT",
+				Value: path.Join(util.TiDBClientTLSPath, core.TLSCertKey),
+			},
+			{
+				Name:  "TIDB_SSL_KEY",
+				Value: path.Join(util.TiDBClientTLSPath, core.TLSPrivateKeyKey),
+			},
+		}...)
+	}
+	if monitor.Spec.Initializer.TLSCASecretName != nil {
+		envs = util.AppendOverwriteEnv(envs, []core.EnvVar{{
+			Name:  "TIDB_SSL_CA",
+			Value: path.Join(util.TiDBServerCAPath, core.ServiceAccountRootCAKey),
+		}})
+	}
+	return envs
+}
+
 func getMonitorVolumes(monitor *v1alpha1.TidbMonitor) []core.Volume {
 	volumes := []core.Volume{}
 	if !monitor.Spec.Persistent {
@@ -893,6 +979,7 @@ func getMonitorVolumes(monitor *v1alpha1.TidbMonitor) []core.Volume {
 			EmptyDir: &core.EmptyDirVolumeSource{},
 		},
 	})
+	volumes = append(volumes, getInitializerTLSVolumes(monitor)...)
 	// add additional volumes
 	if monitor.Spec.AdditionalVolumes != nil {
 		volumes = append(volumes, monitor.Spec.AdditionalVolumes...)
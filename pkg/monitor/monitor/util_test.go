@@ -1578,6 +1578,36 @@ func TestGetMonitorThanosSidecarContainer(t *testing.T) {
 	}
 }
 
+func TestGetMonitorInitContainerTLS(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	monitor := &v1alpha1.TidbMonitor{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+	}
+	container := getMonitorInitContainer(monitor, nil)
+	for _, env := range container.Env {
+		g.Expect(env.Name).NotTo(HavePrefix("TIDB_SSL_"))
+	}
+	g.Expect(getInitializerTLSVolumes(monitor)).To(BeEmpty())
+
+	monitor.Spec.Initializer.TLSClientSecretName = pointer.StringPtr("client-secret")
+	container = getMonitorInitContainer(monitor, nil)
+	g.Expect(container.Env).To(ContainElements(
+		corev1.EnvVar{Name: "TIDB_SSL_CA", Value: "/var/lib/tidb-client-tls/ca.crt"},
+		corev1.EnvVar{Name: "TIDB_SSL_CERT", Value: "/var/lib/tidb-client-tls/tls.crt"},
+		corev1.EnvVar{Name: "TIDB_SSL_KEY", Value: "/var/lib/tidb-client-tls/tls.key"},
+	))
+	g.Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "tidb-client-tls", ReadOnly: true, MountPath: "/var/lib/tidb-client-tls"}))
+
+	// the private ca takes precedence
+	monitor.Spec.Initializer.TLSCASecretName = pointer.StringPtr("private-ca")
+	container = getMonitorInitContainer(monitor, nil)
+	g.Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "TIDB_SSL_CA", Value: "/var/lib/tidb-server-ca/ca.crt"}))
+	g.Expect(container.Env).NotTo(ContainElement(corev1.EnvVar{Name: "TIDB_SSL_CA", Value: "/var/lib/tidb-client-tls/ca.crt"}))
+	g.Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "tidb-server-ca", ReadOnly: true, MountPath: "/var/lib/tidb-server-ca"}))
+	g.Expect(getInitializerTLSVolumes(monitor)).To(HaveLen(2))
+}
+
 func TestBuildExternalLabels(t *testing.T) {
 	g := NewGomegaWithT(t)
 
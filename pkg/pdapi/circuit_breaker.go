@@ -0,0 +1,402 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultCircuitBreakerFailureThreshold is the default number of consecutive failures opening the circuit
+	DefaultCircuitBreakerFailureThreshold = 5
+	// DefaultCircuitBreakerOpenDuration is the default duration the circuit stays open before it is probed
+	DefaultCircuitBreakerOpenDuration = 30 * time.Second
+)
+
+// CircuitBreakerConfig is the config of the circuit breaker of the PD API of each cluster
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures or timeouts opening the circuit,
+	// the circuit breaker is disabled if it is not positive
+	FailureThreshold int
+	// OpenDuration is the duration the circuit stays open before a request is allowed to probe PD
+	OpenDuration time.Duration
+}
+
+// ErrCircuitOpen is returned by the PD client instead of sending the request if the circuit of the
+// cluster is open. The reads used by the status syncs return the data cached before the circuit is
+// opened along with the error, see StaleSince.
+type ErrCircuitOpen struct {
+	Namespace  Namespace
+	TCName     string
+	RetryAfter time.Duration
+	// CachedAt is the time the data returned along with the error is fetched from PD,
+	// it is zero if no data is returned
+	CachedAt time.Time
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit breaker of PD API for cluster %s/%s is open, retry after %s", e.Namespace, e.TCName, e.RetryAfter)
+}
+
+// IsCircuitOpen returns whether err is an ErrCircuitOpen
+func IsCircuitOpen(err error) bool {
+	var e *ErrCircuitOpen
+	return errors.As(err, &e)
+}
+
+// StaleSince returns the time the stale data returned along with err is fetched from PD,
+// false if err is not an ErrCircuitOpen carrying the cached data.
+func StaleSince(err error) (time.Time, bool) {
+	var e *ErrCircuitOpen
+	if !errors.As(err, &e) || e.CachedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return e.CachedAt, true
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+type cachedResult struct {
+	value    interface{}
+	cachedAt time.Time
+}
+
+// circuitBreaker stops sending requests to the PD of a cluster after consecutive failures.
+// Once the circuit is open, all the requests are rejected until OpenDuration elapses, then the
+// circuit is half-open and only one request is sent to probe PD. The circuit is closed if the
+// probe succeeds, otherwise it is opened again.
+type circuitBreaker struct {
+	namespace Namespace
+	tcName    string
+	config    CircuitBreakerConfig
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	cache    map[string]cachedResult
+}
+
+func newCircuitBreaker(namespace Namespace, tcName string, config CircuitBreakerConfig) *circuitBreaker {
+	b := &circuitBreaker{
+		namespace: namespace,
+		tcName:    tcName,
+		config:    config,
+		now:       time.Now,
+		cache:     map[string]cachedResult{},
+	}
+	metrics.PDCircuitBreakerState.WithLabelValues(string(namespace), tcName).Set(float64(circuitClosed))
+	return b
+}
+
+// allow returns an ErrCircuitOpen if the request should not be sent
+func (b *circuitBreaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		elapsed := b.now().Sub(b.openedAt)
+		if elapsed < b.config.OpenDuration {
+			return b.reject(b.config.OpenDuration - elapsed)
+		}
+		b.setState(circuitHalfOpen)
+		fallthrough
+	case circuitHalfOpen:
+		if b.probing {
+			return b.reject(0)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// done records the result of a request allowed by the circuit breaker
+func (b *circuitBreaker) done(err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.probing = false
+	if !isPDUnavailableError(err) {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+	}
+}
+
+func (b *circuitBreaker) reject(retryAfter time.Duration) *ErrCircuitOpen {
+	metrics.PDCircuitBreakerRejectedRequests.WithLabelValues(string(b.namespace), b.tcName).Inc()
+	return &ErrCircuitOpen{Namespace: b.namespace, TCName: b.tcName, RetryAfter: retryAfter}
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state == state {
+		return
+	}
+	klog.Infof("circuit breaker of PD API for cluster %s/%s changes from %s to %s", b.namespace, b.tcName, b.state, state)
+	b.state = state
+	metrics.PDCircuitBreakerState.WithLabelValues(string(b.namespace), b.tcName).Set(float64(state))
+}
+
+// call sends the request if the circuit allows it
+func (b *circuitBreaker) call(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.done(err)
+	return err
+}
+
+// read sends the request if the circuit allows it and caches the result by key,
+// the cached result is returned along with the ErrCircuitOpen if the request is rejected.
+func (b *circuitBreaker) read(key string, fn func() (interface{}, error)) (interface{}, error) {
+	if err := b.allow(); err != nil {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		cached, ok := b.cache[key]
+		if !ok {
+			return nil, err
+		}
+		cerr := err.(*ErrCircuitOpen)
+		cerr.CachedAt = cached.cachedAt
+		return cached.value, cerr
+	}
+	value, err := fn()
+	b.done(err)
+	if err == nil {
+		b.mutex.Lock()
+		b.cache[key] = cachedResult{value: value, cachedAt: b.now()}
+		b.mutex.Unlock()
+	}
+	return value, err
+}
+
+// isPDUnavailableError returns whether err shows that PD can not serve the request,
+// i.e. the request fails to be sent, times out or PD responds with a server error.
+// Other errors are responded by PD and do not count as failures of the circuit.
+func isPDUnavailableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "Error response 5")
+}
+
+// circuitBreakerPDClient sends the requests of the PD client through the circuit breaker of the cluster
+type circuitBreakerPDClient struct {
+	PDClient
+	breaker *circuitBreaker
+}
+
+var _ PDClient = &circuitBreakerPDClient{}
+
+func (c *circuitBreakerPDClient) GetHealth() (*HealthInfo, error) {
+	v, err := c.breaker.read("health", func() (interface{}, error) { return c.PDClient.GetHealth() })
+	info, _ := v.(*HealthInfo)
+	return info, err
+}
+
+func (c *circuitBreakerPDClient) GetConfig() (config *PDConfigFromAPI, err error) {
+	err = c.breaker.call(func() error {
+		config, err = c.PDClient.GetConfig()
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) GetCluster() (*metapb.Cluster, error) {
+	v, err := c.breaker.read("cluster", func() (interface{}, error) { return c.PDClient.GetCluster() })
+	cluster, _ := v.(*metapb.Cluster)
+	return cluster, err
+}
+
+func (c *circuitBreakerPDClient) GetMembers() (*MembersInfo, error) {
+	v, err := c.breaker.read("members", func() (interface{}, error) { return c.PDClient.GetMembers() })
+	info, _ := v.(*MembersInfo)
+	return info, err
+}
+
+func (c *circuitBreakerPDClient) GetStores() (*StoresInfo, error) {
+	v, err := c.breaker.read("stores", func() (interface{}, error) { return c.PDClient.GetStores() })
+	info, _ := v.(*StoresInfo)
+	return info, err
+}
+
+func (c *circuitBreakerPDClient) GetTombStoneStores() (*StoresInfo, error) {
+	v, err := c.breaker.read("tombstoneStores", func() (interface{}, error) { return c.PDClient.GetTombStoneStores() })
+	info, _ := v.(*StoresInfo)
+	return info, err
+}
+
+func (c *circuitBreakerPDClient) GetStore(storeID uint64) (store *StoreInfo, err error) {
+	err = c.breaker.call(func() error {
+		store, err = c.PDClient.GetStore(storeID)
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) SetStoreLabels(storeID uint64, labels map[string]string) (set bool, err error) {
+	err = c.breaker.call(func() error {
+		set, err = c.PDClient.SetStoreLabels(storeID, labels)
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) UpdateReplicationConfig(config PDReplicationConfig) error {
+	return c.breaker.call(func() error { return c.PDClient.UpdateReplicationConfig(config) })
+}
+
+func (c *circuitBreakerPDClient) DeleteStore(storeID uint64) error {
+	return c.breaker.call(func() error { return c.PDClient.DeleteStore(storeID) })
+}
+
+func (c *circuitBreakerPDClient) ForceDeleteStore(storeID uint64) error {
+	return c.breaker.call(func() error { return c.PDClient.ForceDeleteStore(storeID) })
+}
+
+func (c *circuitBreakerPDClient) SetStoreState(storeID uint64, state string) error {
+	return c.breaker.call(func() error { return c.PDClient.SetStoreState(storeID, state) })
+}
+
+func (c *circuitBreakerPDClient) DeleteMember(name string) error {
+	return c.breaker.call(func() error { return c.PDClient.DeleteMember(name) })
+}
+
+func (c *circuitBreakerPDClient) DeleteMemberByID(memberID uint64) error {
+	return c.breaker.call(func() error { return c.PDClient.DeleteMemberByID(memberID) })
+}
+
+func (c *circuitBreakerPDClient) BeginEvictLeader(storeID uint64) error {
+	return c.breaker.call(func() error { return c.PDClient.BeginEvictLeader(storeID) })
+}
+
+func (c *circuitBreakerPDClient) EndEvictLeader(storeID uint64) error {
+	return c.breaker.call(func() error { return c.PDClient.EndEvictLeader(storeID) })
+}
+
+func (c *circuitBreakerPDClient) GetEvictLeaderSchedulers() (schedulers []string, err error) {
+	err = c.breaker.call(func() error {
+		schedulers, err = c.PDClient.GetEvictLeaderSchedulers()
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) GetRegionSchedulingStats() (stats *RegionSchedulingStats, err error) {
+	err = c.breaker.call(func() error {
+		stats, err = c.PDClient.GetRegionSchedulingStats()
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) GetConfigItems(sections ...string) (items map[string]interface{}, err error) {
+	err = c.breaker.call(func() error {
+		items, err = c.PDClient.GetConfigItems(sections...)
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) SetConfigItems(items map[string]interface{}) error {
+	return c.breaker.call(func() error { return c.PDClient.SetConfigItems(items) })
+}
+
+func (c *circuitBreakerPDClient) GetStoresLimit() (limits map[uint64]StoreLimit, err error) {
+	err = c.breaker.call(func() error {
+		limits, err = c.PDClient.GetStoresLimit()
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) SetAllStoresLimit(rate float64) error {
+	return c.breaker.call(func() error { return c.PDClient.SetAllStoresLimit(rate) })
+}
+
+func (c *circuitBreakerPDClient) SetStoreWeight(storeID uint64, leader, region float64) error {
+	return c.breaker.call(func() error { return c.PDClient.SetStoreWeight(storeID, leader, region) })
+}
+
+func (c *circuitBreakerPDClient) SetMemberLeaderPriority(name string, priority int32) error {
+	return c.breaker.call(func() error { return c.PDClient.SetMemberLeaderPriority(name, priority) })
+}
+
+func (c *circuitBreakerPDClient) GetEvictLeaderSchedulersForStores(storeIDs ...uint64) (schedulers map[uint64]string, err error) {
+	err = c.breaker.call(func() error {
+		schedulers, err = c.PDClient.GetEvictLeaderSchedulersForStores(storeIDs...)
+		return err
+	})
+	return
+}
+
+func (c *circuitBreakerPDClient) GetPDLeader() (*pdpb.Member, error) {
+	v, err := c.breaker.read("leader", func() (interface{}, error) { return c.PDClient.GetPDLeader() })
+	leader, _ := v.(*pdpb.Member)
+	return leader, err
+}
+
+func (c *circuitBreakerPDClient) TransferPDLeader(name string) error {
+	return c.breaker.call(func() error { return c.PDClient.TransferPDLeader(name) })
+}
+
+func (c *circuitBreakerPDClient) GetAutoscalingPlans(strategy Strategy) (plans []Plan, err error) {
+	err = c.breaker.call(func() error {
+		plans, err = c.PDClient.GetAutoscalingPlans(strategy)
+		return err
+	})
+	return
+}
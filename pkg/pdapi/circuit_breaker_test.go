@@ -0,0 +1,130 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pdapi
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCircuitBreakerPDClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Now()
+	fakeClient := NewFakePDClient()
+	breaker := newCircuitBreaker("ns", "test", CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute})
+	breaker.now = func() time.Time { return now }
+	client := &circuitBreakerPDClient{PDClient: fakeClient, breaker: breaker}
+	state := func() float64 {
+		return testutil.ToFloat64(metrics.PDCircuitBreakerState.WithLabelValues("ns", "test"))
+	}
+
+	var pdErr error
+	health := &HealthInfo{Healths: []MemberHealth{{Name: "pd-0", Health: true}}}
+	fakeClient.AddReaction(GetHealthActionType, func(action *Action) (interface{}, error) {
+		if pdErr != nil {
+			return nil, pdErr
+		}
+		return health, nil
+	})
+	deleted := 0
+	fakeClient.AddReaction(DeleteStoreActionType, func(action *Action) (interface{}, error) {
+		deleted++
+		return nil, pdErr
+	})
+	fetchedAt := now
+	info, err := client.GetHealth()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info).To(Equal(health))
+
+	// the errors responded by PD do not open the circuit
+	pdErr = errors.New("Error response 404 URL http://test-pd:2379/pd/api/v1/store/1")
+	for i := 0; i < 3; i++ {
+		g.Expect(client.DeleteStore(1)).To(MatchError(pdErr))
+	}
+	g.Expect(breaker.state).To(Equal(circuitClosed))
+
+	// the circuit is opened after consecutive timeouts
+	pdErr = &url.Error{Op: "Get", URL: "http://test-pd:2379", Err: errors.New("timeout")}
+	now = now.Add(time.Second)
+	_, err = client.GetHealth()
+	g.Expect(err).To(MatchError(pdErr))
+	g.Expect(breaker.state).To(Equal(circuitClosed))
+	g.Expect(client.DeleteStore(1)).To(MatchError(pdErr))
+	g.Expect(breaker.state).To(Equal(circuitOpen))
+	g.Expect(state()).To(Equal(float64(circuitOpen)))
+
+	// the reads return the cached data and the mutating calls are rejected while the circuit is open
+	deleted = 0
+	info, err = client.GetHealth()
+	g.Expect(IsCircuitOpen(err)).To(BeTrue())
+	g.Expect(info).To(Equal(health))
+	staleSince, ok := StaleSince(err)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(staleSince).To(Equal(fetchedAt))
+	err = client.DeleteStore(1)
+	g.Expect(IsCircuitOpen(err)).To(BeTrue())
+	_, ok = StaleSince(err)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(deleted).To(Equal(0))
+
+	// the failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	g.Expect(client.DeleteStore(1)).To(MatchError(pdErr))
+	g.Expect(deleted).To(Equal(1))
+	g.Expect(breaker.state).To(Equal(circuitOpen))
+	g.Expect(IsCircuitOpen(client.DeleteStore(1))).To(BeTrue())
+
+	// only one probe is sent when the circuit is half-open
+	now = now.Add(time.Minute)
+	g.Expect(breaker.allow()).To(Succeed())
+	g.Expect(breaker.state).To(Equal(circuitHalfOpen))
+	g.Expect(state()).To(Equal(float64(circuitHalfOpen)))
+	g.Expect(IsCircuitOpen(client.DeleteStore(1))).To(BeTrue())
+
+	// the succeeded probe closes the circuit
+	pdErr = nil
+	breaker.done(nil)
+	g.Expect(breaker.state).To(Equal(circuitClosed))
+	g.Expect(state()).To(Equal(float64(circuitClosed)))
+	g.Expect(client.DeleteStore(1)).To(Succeed())
+	_, err = client.GetHealth()
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestDefaultPDControlCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	pdc := NewDefaultPDControl(nil)
+	_, ok := pdc.GetPDClient("ns", "test", false).(*circuitBreakerPDClient)
+	g.Expect(ok).To(BeFalse())
+
+	pdc = NewDefaultPDControlWithCircuitBreaker(nil, CircuitBreakerConfig{})
+	_, ok = pdc.GetPDClient("ns", "test", false).(*circuitBreakerPDClient)
+	g.Expect(ok).To(BeFalse())
+
+	// the clients of a cluster share the circuit breaker
+	pdc = NewDefaultPDControlWithCircuitBreaker(nil, CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute})
+	client := pdc.GetPDClient("ns", "test", false).(*circuitBreakerPDClient)
+	peerClient := pdc.GetPDClient("ns", "test", false, SpecifyClient("http://test-pd-0.test-pd-peer.ns:2379", "test-pd-0")).(*circuitBreakerPDClient)
+	otherClient := pdc.GetPDClient("ns", "other", false).(*circuitBreakerPDClient)
+	g.Expect(peerClient.breaker).To(BeIdenticalTo(client.breaker))
+	g.Expect(otherClient.breaker).NotTo(BeIdenticalTo(client.breaker))
+}
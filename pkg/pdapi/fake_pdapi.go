@@ -31,6 +31,7 @@ const (
 	GetTombStoneStoresActionType                ActionType = "GetTombStoneStores"
 	GetStoreActionType                          ActionType = "GetStore"
 	DeleteStoreActionType                       ActionType = "DeleteStore"
+	ForceDeleteStoreActionType                  ActionType = "ForceDeleteStore"
 	SetStoreStateActionType                     ActionType = "SetStoreState"
 	DeleteMemberByIDActionType                  ActionType = "DeleteMemberByID"
 	DeleteMemberActionType                      ActionType = "DeleteMember "
@@ -43,6 +44,13 @@ const (
 	GetPDLeaderActionType                       ActionType = "GetPDLeader"
 	TransferPDLeaderActionType                  ActionType = "TransferPDLeader"
 	GetAutoscalingPlansActionType               ActionType = "GetAutoscalingPlans"
+	GetRegionSchedulingStatsActionType          ActionType = "GetRegionSchedulingStats"
+	GetConfigItemsActionType                    ActionType = "GetConfigItems"
+	SetConfigItemsActionType                    ActionType = "SetConfigItems"
+	GetStoresLimitActionType                    ActionType = "GetStoresLimit"
+	SetAllStoresLimitActionType                 ActionType = "SetAllStoresLimit"
+	SetStoreWeightActionType                    ActionType = "SetStoreWeight"
+	SetMemberLeaderPriorityActionType           ActionType = "SetMemberLeaderPriority"
 )
 
 type NotFoundReaction struct {
@@ -58,6 +66,11 @@ type Action struct {
 	Name        string
 	Labels      map[string]string
 	Replication PDReplicationConfig
+	Sections    []string
+	ConfigItems map[string]interface{}
+	Rate        float64
+	Weight      StoreWeight
+	Priority    int32
 }
 
 type Reaction func(action *Action) (interface{}, error)
@@ -161,6 +174,15 @@ func (c *FakePDClient) DeleteStore(id uint64) error {
 	return nil
 }
 
+func (c *FakePDClient) ForceDeleteStore(id uint64) error {
+	if reaction, ok := c.reactions[ForceDeleteStoreActionType]; ok {
+		action := &Action{ID: id}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
 func (c *FakePDClient) SetStoreState(id uint64, state string) error {
 	if reaction, ok := c.reactions[SetStoreStateActionType]; ok {
 		action := &Action{ID: id}
@@ -235,6 +257,78 @@ func (c *FakePDClient) GetEvictLeaderSchedulers() ([]string, error) {
 	return nil, nil
 }
 
+func (c *FakePDClient) GetRegionSchedulingStats() (*RegionSchedulingStats, error) {
+	if reaction, ok := c.reactions[GetRegionSchedulingStatsActionType]; ok {
+		action := &Action{}
+		result, err := reaction(action)
+		if err != nil {
+			return nil, err
+		}
+		return result.(*RegionSchedulingStats), nil
+	}
+	return &RegionSchedulingStats{}, nil
+}
+
+func (c *FakePDClient) GetConfigItems(sections ...string) (map[string]interface{}, error) {
+	action := &Action{Sections: sections}
+	result, err := c.fakeAPI(GetConfigItemsActionType, action)
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[string]interface{}), nil
+}
+
+func (c *FakePDClient) SetConfigItems(items map[string]interface{}) error {
+	if reaction, ok := c.reactions[SetConfigItemsActionType]; ok {
+		action := &Action{ConfigItems: items}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+func (c *FakePDClient) GetStoresLimit() (map[uint64]StoreLimit, error) {
+	action := &Action{}
+	result, err := c.fakeAPI(GetStoresLimitActionType, action)
+	if err != nil {
+		return nil, err
+	}
+	return result.(map[uint64]StoreLimit), nil
+}
+
+func (c *FakePDClient) SetAllStoresLimit(rate float64) error {
+	if reaction, ok := c.reactions[SetAllStoresLimitActionType]; ok {
+		action := &Action{Rate: rate}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+// StoreWeight is the leader weight and the region weight set by SetStoreWeight
+type StoreWeight struct {
+	Leader float64
+	Region float64
+}
+
+func (c *FakePDClient) SetStoreWeight(storeID uint64, leader, region float64) error {
+	if reaction, ok := c.reactions[SetStoreWeightActionType]; ok {
+		action := &Action{ID: storeID, Weight: StoreWeight{Leader: leader, Region: region}}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
+func (c *FakePDClient) SetMemberLeaderPriority(name string, priority int32) error {
+	if reaction, ok := c.reactions[SetMemberLeaderPriorityActionType]; ok {
+		action := &Action{Name: name, Priority: priority}
+		_, err := reaction(action)
+		return err
+	}
+	return nil
+}
+
 func (c *FakePDClient) GetEvictLeaderSchedulersForStores(storeIDs ...uint64) (map[uint64]string, error) {
 	if reaction, ok := c.reactions[GetEvictLeaderSchedulersActionType]; ok {
 		action := &Action{}
@@ -144,6 +144,11 @@ type defaultPDControl struct {
 
 	etcdmutex     sync.Mutex
 	pdEtcdClients map[string]PDEtcdClient
+
+	// breakerConfig is the config of the circuit breakers, they are disabled if it is nil
+	breakerConfig *CircuitBreakerConfig
+	breakerMutex  sync.Mutex
+	breakers      map[string]*circuitBreaker
 }
 
 type noOpClose struct {
@@ -159,6 +164,17 @@ func NewDefaultPDControl(secretLister corelisterv1.SecretLister) PDControlInterf
 	return &defaultPDControl{secretLister: secretLister, pdClients: map[string]PDClient{}, pdEtcdClients: map[string]PDEtcdClient{}}
 }
 
+// NewDefaultPDControlWithCircuitBreaker returns a defaultPDControl instance whose PD clients send the
+// requests through the circuit breaker of each cluster, see ErrCircuitOpen
+func NewDefaultPDControlWithCircuitBreaker(secretLister corelisterv1.SecretLister, config CircuitBreakerConfig) PDControlInterface {
+	pdc := &defaultPDControl{secretLister: secretLister, pdClients: map[string]PDClient{}, pdEtcdClients: map[string]PDEtcdClient{}}
+	if config.FailureThreshold > 0 {
+		pdc.breakerConfig = &config
+		pdc.breakers = map[string]*circuitBreaker{}
+	}
+	return pdc
+}
+
 // NewDefaultPDControl returns a defaultPDControl instance
 func NewDefaultPDControlByCli(kubeCli kubernetes.Interface) PDControlInterface {
 	return &defaultPDControl{pdClients: map[string]PDClient{}, pdEtcdClients: map[string]PDEtcdClient{}}
@@ -216,6 +232,27 @@ func (pdc *defaultPDControl) GetPDEtcdClient(namespace Namespace, tcName string,
 
 // GetPDClient provides a PDClient of real pd cluster, if the PDClient not existing, it will create new one.
 func (pdc *defaultPDControl) GetPDClient(namespace Namespace, tcName string, tlsEnabled bool, opts ...Option) PDClient {
+	pdClient := pdc.getPDClient(namespace, tcName, tlsEnabled, opts...)
+	if pdc.breakerConfig == nil {
+		return pdClient
+	}
+	return &circuitBreakerPDClient{PDClient: pdClient, breaker: pdc.getCircuitBreaker(namespace, tcName)}
+}
+
+// getCircuitBreaker returns the circuit breaker of the cluster, the PD clients of a cluster,
+// including the ones of the peer members, share the same circuit breaker
+func (pdc *defaultPDControl) getCircuitBreaker(namespace Namespace, tcName string) *circuitBreaker {
+	pdc.breakerMutex.Lock()
+	defer pdc.breakerMutex.Unlock()
+
+	key := fmt.Sprintf("%s/%s", namespace, tcName)
+	if _, ok := pdc.breakers[key]; !ok {
+		pdc.breakers[key] = newCircuitBreaker(namespace, tcName, *pdc.breakerConfig)
+	}
+	return pdc.breakers[key]
+}
+
+func (pdc *defaultPDControl) getPDClient(namespace Namespace, tcName string, tlsEnabled bool, opts ...Option) PDClient {
 	config := &clientConfig{}
 
 	config.tlsEnable = tlsEnabled
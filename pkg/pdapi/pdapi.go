@@ -71,6 +71,9 @@ type PDClient interface {
 	UpdateReplicationConfig(config PDReplicationConfig) error
 	// DeleteStore deletes a TiKV store from cluster
 	DeleteStore(storeID uint64) error
+	// ForceDeleteStore deletes a TiKV store whose data is physically destroyed from cluster, so that a new store
+	// can register with its address before it becomes tombstone
+	ForceDeleteStore(storeID uint64) error
 	// SetStoreState sets store to specified state.
 	SetStoreState(storeID uint64, state string) error
 	// DeleteMember deletes a PD member from cluster
@@ -92,12 +95,28 @@ type PDClient interface {
 	TransferPDLeader(name string) error
 	// GetAutoscalingPlans returns the scaling plan for the cluster
 	GetAutoscalingPlans(strategy Strategy) ([]Plan, error)
+	// GetRegionSchedulingStats returns the number of the pending operators and the regions missing peers
+	GetRegionSchedulingStats() (*RegionSchedulingStats, error)
+	// GetConfigItems returns the items of the config of the sections, the keys are in the form of section.item
+	GetConfigItems(sections ...string) (map[string]interface{}, error)
+	// SetConfigItems sets the items of the config, the keys are in the form of section.item
+	SetConfigItems(items map[string]interface{}) error
+	// GetStoresLimit returns the store limits of all stores by store ID
+	GetStoresLimit() (map[uint64]StoreLimit, error)
+	// SetAllStoresLimit sets the add-peer and remove-peer store limits of all stores to the rate per minute
+	SetAllStoresLimit(rate float64) error
+	// SetStoreWeight sets the leader weight and the region weight of the store
+	SetStoreWeight(storeID uint64, leader, region float64) error
+	// SetMemberLeaderPriority sets the leader priority of the PD member, the member with the higher priority is
+	// preferred as the leader
+	SetMemberLeaderPriority(name string, priority int32) error
 }
 
 var (
 	healthPrefix           = "pd/api/v1/health"
 	membersPrefix          = "pd/api/v1/members"
 	storesPrefix           = "pd/api/v1/stores"
+	storesLimitPrefix      = "pd/api/v1/stores/limit"
 	storePrefix            = "pd/api/v1/store"
 	configPrefix           = "pd/api/v1/config"
 	clusterIDPrefix        = "pd/api/v1/cluster"
@@ -105,6 +124,8 @@ var (
 	pdLeaderPrefix         = "pd/api/v1/leader"
 	pdLeaderTransferPrefix = "pd/api/v1/leader/transfer"
 	pdReplicationPrefix    = "pd/api/v1/config/replicate"
+	operatorsPrefix        = "pd/api/v1/operators"
+	missPeerRegionsPrefix  = "pd/api/v1/regions/check/miss-peer"
 	// evictLeaderSchedulerConfigPrefix is the prefix of evict-leader-scheduler
 	// config API, available since PD v3.1.0.
 	evictLeaderSchedulerConfigPrefix = "pd/api/v1/scheduler-config/evict-leader-scheduler/list"
@@ -160,16 +181,32 @@ type StoreStatus struct {
 	Available          typeutil.ByteSize `json:"available"`
 	LeaderCount        int               `json:"leader_count"`
 	RegionCount        int               `json:"region_count"`
+	RegionSize         int64             `json:"region_size"`
 	SendingSnapCount   uint32            `json:"sending_snap_count"`
 	ReceivingSnapCount uint32            `json:"receiving_snap_count"`
 	ApplyingSnapCount  uint32            `json:"applying_snap_count"`
 	IsBusy             bool              `json:"is_busy"`
+	LeaderWeight       float64           `json:"leader_weight"`
+	RegionWeight       float64           `json:"region_weight"`
+	// SlowScore is in [1, 100], the higher the slower the store is, it is reported by TiKV v5.2+
+	SlowScore uint64 `json:"slow_score"`
+	// SlowTrend is reported by TiKV v6.6+
+	SlowTrend *StoreSlowTrend `json:"slow_trend,omitempty"`
 
 	StartTS         time.Time         `json:"start_ts"`
 	LastHeartbeatTS time.Time         `json:"last_heartbeat_ts"`
 	Uptime          typeutil.Duration `json:"uptime"`
 }
 
+// StoreSlowTrend is the trend of the latency of a store, the cause is the latency of the disk IO of raftstore in
+// microseconds and the result is the QPS of the store
+type StoreSlowTrend struct {
+	CauseValue  float64 `json:"cause_value"`
+	CauseRate   float64 `json:"cause_rate"`
+	ResultValue float64 `json:"result_value"`
+	ResultRate  float64 `json:"result_rate"`
+}
+
 // StoreInfo is a single store info returned from PD RESTful interface
 type StoreInfo struct {
 	Store  *MetaStore   `json:"store"`
@@ -346,6 +383,14 @@ func (c *pdClient) GetStore(storeID uint64) (*StoreInfo, error) {
 }
 
 func (c *pdClient) DeleteStore(storeID uint64) error {
+	return c.deleteStore(storeID, false)
+}
+
+func (c *pdClient) ForceDeleteStore(storeID uint64) error {
+	return c.deleteStore(storeID, true)
+}
+
+func (c *pdClient) deleteStore(storeID uint64, force bool) error {
 	var exist bool
 	stores, err := c.GetStores()
 	if err != nil {
@@ -361,6 +406,9 @@ func (c *pdClient) DeleteStore(storeID uint64) error {
 		return nil
 	}
 	apiURL := fmt.Sprintf("%s/%s/%d", c.url, storePrefix, storeID)
+	if force {
+		apiURL += "?force=true"
+	}
 	req, err := http.NewRequest("DELETE", apiURL, nil)
 	if err != nil {
 		return err
@@ -753,3 +801,155 @@ func IsTiKVNotBootstrappedError(err error) bool {
 	_, ok := err.(*TiKVNotBootstrappedError)
 	return ok
 }
+
+// RegionSchedulingStats is the region scheduling state of the cluster
+type RegionSchedulingStats struct {
+	// PendingOperatorCount is the number of the operators not finished yet
+	PendingOperatorCount int
+	// MissPeerRegionCount is the number of the under-replicated regions
+	MissPeerRegionCount int
+}
+
+func (c *pdClient) GetRegionSchedulingStats() (*RegionSchedulingStats, error) {
+	body, err := httputil.GetBodyOK(c.httpClient, fmt.Sprintf("%s/%s", c.url, operatorsPrefix))
+	if err != nil {
+		return nil, err
+	}
+	var operators []json.RawMessage
+	if err := json.Unmarshal(body, &operators); err != nil {
+		return nil, err
+	}
+
+	body, err = httputil.GetBodyOK(c.httpClient, fmt.Sprintf("%s/%s", c.url, missPeerRegionsPrefix))
+	if err != nil {
+		return nil, err
+	}
+	regions := &struct {
+		Count int `json:"count"`
+	}{}
+	if err := json.Unmarshal(body, regions); err != nil {
+		return nil, err
+	}
+	return &RegionSchedulingStats{
+		PendingOperatorCount: len(operators),
+		MissPeerRegionCount:  regions.Count,
+	}, nil
+}
+
+func (c *pdClient) GetConfigItems(sections ...string) (map[string]interface{}, error) {
+	apiURL := fmt.Sprintf("%s/%s", c.url, configPrefix)
+	body, err := httputil.GetBodyOK(c.httpClient, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+	items := map[string]interface{}{}
+	for _, section := range sections {
+		data, ok := config[section]
+		if !ok {
+			continue
+		}
+		sectionItems := map[string]interface{}{}
+		if err := json.Unmarshal(data, &sectionItems); err != nil {
+			return nil, fmt.Errorf("config section %s is not an object: %v", section, err)
+		}
+		for item, value := range sectionItems {
+			items[section+"."+item] = value
+		}
+	}
+	return items, nil
+}
+
+func (c *pdClient) SetConfigItems(items map[string]interface{}) error {
+	apiURL := fmt.Sprintf("%s/%s", c.url, configPrefix)
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set config items: %v", res.StatusCode, err)
+}
+
+// StoreLimit is the store limit of a store, the rates are the number of the operators per minute
+type StoreLimit struct {
+	AddPeer    float64 `json:"add-peer"`
+	RemovePeer float64 `json:"remove-peer"`
+}
+
+func (c *pdClient) GetStoresLimit() (map[uint64]StoreLimit, error) {
+	body, err := httputil.GetBodyOK(c.httpClient, fmt.Sprintf("%s/%s", c.url, storesLimitPrefix))
+	if err != nil {
+		return nil, err
+	}
+	limits := map[uint64]StoreLimit{}
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func (c *pdClient) SetAllStoresLimit(rate float64) error {
+	apiURL := fmt.Sprintf("%s/%s", c.url, storesLimitPrefix)
+	data, err := json.Marshal(map[string]interface{}{"rate": rate})
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set the limit of all stores: %v", res.StatusCode, err)
+}
+
+func (c *pdClient) SetStoreWeight(storeID uint64, leader, region float64) error {
+	apiURL := fmt.Sprintf("%s/%s/%d/weight", c.url, storePrefix, storeID)
+	// pd requires both of the weights
+	data, err := json.Marshal(map[string]interface{}{"leader": leader, "region": region})
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set the weight of store %d: %v", res.StatusCode, storeID, err)
+}
+
+func (c *pdClient) SetMemberLeaderPriority(name string, priority int32) error {
+	apiURL := fmt.Sprintf("%s/%s/name/%s", c.url, membersPrefix, name)
+	data, err := json.Marshal(map[string]interface{}{"leader-priority": priority})
+	if err != nil {
+		return err
+	}
+	res, err := c.httpClient.Post(apiURL, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+	err = httputil.ReadErrorBody(res.Body)
+	return fmt.Errorf("failed %v to set the leader priority of member %s: %v", res.StatusCode, name, err)
+}
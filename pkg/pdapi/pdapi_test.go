@@ -120,6 +120,98 @@ func TestGetConfig(t *testing.T) {
 
 }
 
+func TestConfigItems(t *testing.T) {
+	g := NewGomegaWithT(t)
+	config := `{"cluster-version":"5.4.0","schedule":{"leader-schedule-limit":4,"max-store-down-time":"30m0s"},"replication":{"max-replicas":3}}`
+
+	var posted map[string]interface{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", configPrefix)), "check url")
+		if request.Method == "POST" {
+			g.Expect(json.NewDecoder(request.Body).Decode(&posted)).To(Succeed())
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.Write([]byte(config))
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	items, err := pdClient.GetConfigItems("schedule", "replication", "not-exist")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(items).To(Equal(map[string]interface{}{
+		"schedule.leader-schedule-limit": float64(4),
+		"schedule.max-store-down-time":   "30m0s",
+		"replication.max-replicas":       float64(3),
+	}))
+
+	_, err = pdClient.GetConfigItems("cluster-version")
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(pdClient.SetConfigItems(map[string]interface{}{"schedule.leader-schedule-limit": 8})).To(Succeed())
+	g.Expect(posted).To(Equal(map[string]interface{}{"schedule.leader-schedule-limit": float64(8)}))
+}
+
+func TestStoresLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+	limits := `{"1":{"add-peer":15,"remove-peer":15},"4":{"add-peer":30,"remove-peer":20}}`
+
+	var posted map[string]interface{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", storesLimitPrefix)), "check url")
+		if request.Method == "POST" {
+			g.Expect(json.NewDecoder(request.Body).Decode(&posted)).To(Succeed())
+			return
+		}
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		w.Write([]byte(limits))
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	result, err := pdClient.GetStoresLimit()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal(map[uint64]StoreLimit{
+		1: {AddPeer: 15, RemovePeer: 15},
+		4: {AddPeer: 30, RemovePeer: 20},
+	}))
+
+	g.Expect(pdClient.SetAllStoresLimit(40)).To(Succeed())
+	g.Expect(posted).To(Equal(map[string]interface{}{"rate": float64(40)}))
+}
+
+func TestSetStoreWeight(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var posted map[string]interface{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("POST"), "check method")
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/4/weight", storePrefix)), "check url")
+		g.Expect(json.NewDecoder(request.Body).Decode(&posted)).To(Succeed())
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.SetStoreWeight(4, 0.1, 1)).To(Succeed())
+	g.Expect(posted).To(Equal(map[string]interface{}{"leader": 0.1, "region": float64(1)}))
+}
+
+func TestSetMemberLeaderPriority(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var posted map[string]interface{}
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("POST"), "check method")
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/name/pd-1", membersPrefix)), "check url")
+		g.Expect(json.NewDecoder(request.Body).Decode(&posted)).To(Succeed())
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.SetMemberLeaderPriority("pd-1", 5)).To(Succeed())
+	g.Expect(posted).To(Equal(map[string]interface{}{"leader-priority": float64(5)}))
+}
+
 func TestGetCluster(t *testing.T) {
 	g := NewGomegaWithT(t)
 	cluster := &metapb.Cluster{Id: 1, MaxPeerCount: 100}
@@ -634,6 +726,38 @@ func TestDeleteStore(t *testing.T) {
 	}
 }
 
+func TestForceDeleteStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+	storeID := uint64(1)
+	stores := &StoresInfo{
+		Count: 1,
+		Stores: []*StoreInfo{{
+			Store:  &MetaStore{Store: &metapb.Store{Id: storeID, State: metapb.StoreState_Up}},
+			Status: &StoreStatus{},
+		}},
+	}
+	storesBytes, err := json.Marshal(stores)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		if request.Method == "GET" {
+			g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s", storesPrefix)), "check url")
+			w.WriteHeader(http.StatusOK)
+			w.Write(storesBytes)
+			return
+		}
+		g.Expect(request.Method).To(Equal("DELETE"), "check method")
+		g.Expect(request.URL.Path).To(Equal(fmt.Sprintf("/%s/%d", storePrefix, storeID)), "check url")
+		g.Expect(request.URL.Query().Get("force")).To(Equal("true"), "check query")
+		w.WriteHeader(http.StatusOK)
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	g.Expect(pdClient.ForceDeleteStore(storeID)).To(Succeed())
+}
+
 func TestGetEvictLeaderSchedulersForStores(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -754,6 +878,29 @@ func checkNoError(t *testing.T, results []reflect.Value) {
 }
 
 // TestGeneric is a generic test to test methods of PD Client.
+func TestGetRegionSchedulingStats(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	svc := getClientServer(func(w http.ResponseWriter, request *http.Request) {
+		g.Expect(request.Method).To(Equal("GET"), "check method")
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		switch request.URL.Path {
+		case fmt.Sprintf("/%s", operatorsPrefix):
+			w.Write([]byte(`[{"desc":"transfer-leader","region_id":2},{"desc":"add-peer","region_id":3}]`))
+		case fmt.Sprintf("/%s", missPeerRegionsPrefix):
+			w.Write([]byte(`{"count":5,"regions":[]}`))
+		default:
+			t.Fatalf("unexpected path %s", request.URL.Path)
+		}
+	})
+	defer svc.Close()
+
+	pdClient := NewPDClient(svc.URL, DefaultTimeout, &tls.Config{})
+	stats, err := pdClient.GetRegionSchedulingStats()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(stats).To(Equal(&RegionSchedulingStats{PendingOperatorCount: 2, MissPeerRegionCount: 5}))
+}
+
 func TestGeneric(t *testing.T) {
 	tests := []struct {
 		name        string
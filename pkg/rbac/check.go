@@ -0,0 +1,77 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MissingPermission is a verb on a resource not allowed for tidb-operator
+type MissingPermission struct {
+	Group    string
+	Resource string
+	Verb     string
+}
+
+func (p MissingPermission) String() string {
+	group := p.Group
+	if group == "" {
+		group = "core"
+	}
+	return fmt.Sprintf("%s %s/%s", p.Verb, group, p.Resource)
+}
+
+// CheckPermissions reviews each verb of the permissions required by the options with
+// SelfSubjectAccessReviews and returns the ones not allowed. The namespaced permissions are reviewed
+// in the namespace unless the install manages all the namespaces.
+func CheckPermissions(ctx context.Context, cli kubernetes.Interface, namespace string, o Options) ([]MissingPermission, error) {
+	var missing []MissingPermission
+	for _, p := range o.Permissions() {
+		ns := namespace
+		if o.ClusterScoped || p.ClusterResource {
+			ns = metav1.NamespaceAll
+		}
+		resource, subresource := p.Resource, ""
+		if i := strings.Index(p.Resource, "/"); i >= 0 {
+			resource, subresource = p.Resource[:i], p.Resource[i+1:]
+		}
+		for _, verb := range p.Verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   ns,
+						Verb:        verb,
+						Group:       p.Group,
+						Resource:    resource,
+						Subresource: subresource,
+					},
+				},
+			}
+			result, err := cli.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to review the access of %s: %v", MissingPermission{p.Group, p.Resource, verb}, err)
+			}
+			if !result.Status.Allowed {
+				missing = append(missing, MissingPermission{Group: p.Group, Resource: p.Resource, Verb: verb})
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var reviews []authorizationv1.ResourceAttributes
+	var reviewErr error
+	cli := fake.NewSimpleClientset()
+	cli.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if reviewErr != nil {
+			return true, nil, reviewErr
+		}
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attrs := *review.Spec.ResourceAttributes
+		reviews = append(reviews, attrs)
+		// everything is allowed except deleting the restores and updating the status of tidbngmonitorings
+		review.Status.Allowed = !(attrs.Resource == "restores" && attrs.Verb == "delete") &&
+			!(attrs.Resource == "tidbngmonitorings" && attrs.Subresource == "status")
+		return true, review, nil
+	})
+
+	o := Options{Capabilities: []Capability{CapabilityCore, CapabilityBackup, CapabilityMonitor}}
+	missing, err := CheckPermissions(context.TODO(), cli, "tidb-admin", o)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(missing).To(ConsistOf(
+		MissingPermission{Group: "pingcap.com", Resource: "restores", Verb: "delete"},
+		MissingPermission{Group: "pingcap.com", Resource: "tidbngmonitorings/status", Verb: "update"},
+	))
+	g.Expect(missing[0].String()).To(Equal("delete pingcap.com/restores"))
+
+	verbs := 0
+	for _, p := range o.Permissions() {
+		verbs += len(p.Verbs)
+	}
+	g.Expect(reviews).To(HaveLen(verbs))
+	for _, attrs := range reviews {
+		g.Expect(attrs.Namespace).To(Equal("tidb-admin"))
+	}
+
+	reviews = nil
+	o.ClusterScoped = true
+	_, err = CheckPermissions(context.TODO(), cli, "tidb-admin", o)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, attrs := range reviews {
+		g.Expect(attrs.Namespace).To(BeEmpty())
+	}
+
+	reviewErr = fmt.Errorf("unavailable")
+	_, err = CheckPermissions(context.TODO(), cli, "tidb-admin", o)
+	g.Expect(err).To(HaveOccurred())
+}
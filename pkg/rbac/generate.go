@@ -0,0 +1,79 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Rules returns the policy rules of the permissions, one rule for each resource
+func Rules(permissions []Permission) []rbacv1.PolicyRule {
+	rules := make([]rbacv1.PolicyRule, 0, len(permissions))
+	for _, p := range permissions {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{p.Group},
+			Resources: []string{p.Resource},
+			Verbs:     p.Verbs,
+		})
+	}
+	return rules
+}
+
+// Roles returns the minimal roles and their bindings granting the permissions of the options to the
+// ServiceAccount. A ClusterRole is returned for the installs managing all the namespaces, otherwise a
+// Role in the namespace and a ClusterRole for the permitted cluster-scoped resources are returned.
+func Roles(name, namespace, serviceAccount string, o Options) []runtime.Object {
+	var namespaced, cluster []Permission
+	for _, p := range o.Permissions() {
+		if o.ClusterScoped || p.ClusterResource {
+			cluster = append(cluster, p)
+		} else {
+			namespaced = append(namespaced, p)
+		}
+	}
+
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: serviceAccount, Namespace: namespace}}
+	var objects []runtime.Object
+	if len(cluster) > 0 {
+		objects = append(objects,
+			&rbacv1.ClusterRole{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Rules:      Rules(cluster),
+			},
+			&rbacv1.ClusterRoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Subjects:   subjects,
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: name},
+			})
+	}
+	if len(namespaced) > 0 {
+		objects = append(objects,
+			&rbacv1.Role{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Rules:      Rules(namespaced),
+			},
+			&rbacv1.RoleBinding{
+				TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Subjects:   subjects,
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+			})
+	}
+	return objects
+}
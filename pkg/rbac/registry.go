@@ -0,0 +1,224 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac is the registry of the permissions tidb-operator requires for each of its capabilities.
+// It is shared by the controllers checking the permissions on startup and cmd/rbac-gen generating the
+// minimal Roles and ClusterRoles of an install, so a permission added here is both granted and checked.
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Capability is a feature of tidb-operator requiring its own permissions
+type Capability string
+
+const (
+	// CapabilityCore is the management of TidbClusters and TidbInitializers, it is always enabled
+	CapabilityCore Capability = "core"
+	// CapabilityBackup is the management of Backups, Restores and BackupSchedules
+	CapabilityBackup Capability = "backup"
+	// CapabilityMonitor is the management of TidbMonitors and TidbNGMonitorings
+	CapabilityMonitor Capability = "monitor"
+	// CapabilityDM is the management of DMClusters
+	CapabilityDM Capability = "dm"
+	// CapabilityAutoScaler is the management of TidbClusterAutoScalers
+	CapabilityAutoScaler Capability = "autoscaler"
+	// CapabilityWebhook is the admission webhook, its permissions are granted to the ServiceAccount of
+	// the admission webhook instead of tidb-controller-manager
+	CapabilityWebhook Capability = "webhook"
+)
+
+// ControllerManagerCapabilities are the optional capabilities served by tidb-controller-manager
+var ControllerManagerCapabilities = []Capability{CapabilityBackup, CapabilityMonitor, CapabilityDM, CapabilityAutoScaler}
+
+var allCapabilities = sets.NewString(
+	string(CapabilityCore),
+	string(CapabilityBackup),
+	string(CapabilityMonitor),
+	string(CapabilityDM),
+	string(CapabilityAutoScaler),
+	string(CapabilityWebhook),
+)
+
+// Permission is the verbs allowed on a resource
+type Permission struct {
+	Group string
+	// Resource may contain the subresource, e.g. statefulsets/status
+	Resource string
+	Verbs    []string
+	// ClusterResource is whether the resource is cluster-scoped, it is only granted to the installs
+	// managing all the namespaces or permitted to access the resource explicitly
+	ClusterResource bool
+}
+
+var (
+	verbsRead   = []string{"get", "list", "watch"}
+	verbsWatch  = []string{"list", "watch"}
+	verbsUpdate = []string{"get", "update", "patch"}
+	verbsAll    = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+)
+
+// registry is the permissions of each capability, the permissions of the informer caches shared by all
+// the controllers are in CapabilityCore
+var registry = map[Capability][]Permission{
+	CapabilityCore: {
+		{Group: "", Resource: "events", Verbs: []string{"create", "patch", "update"}},
+		{Group: "events.k8s.io", Resource: "events", Verbs: []string{"create", "patch", "update"}},
+		{Group: "", Resource: "services", Verbs: verbsAll},
+		// endpoints are the lock of the leader election
+		{Group: "", Resource: "endpoints", Verbs: []string{"get", "list", "watch", "create", "update"}},
+		{Group: "", Resource: "configmaps", Verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"}},
+		{Group: "", Resource: "secrets", Verbs: []string{"get", "list", "watch", "create", "update", "delete"}},
+		{Group: "", Resource: "persistentvolumeclaims", Verbs: verbsAll},
+		{Group: "", Resource: "pods", Verbs: []string{"get", "list", "watch", "update", "patch", "delete"}},
+		// the readiness gate conditions of the TiDB pods drained before scale-in
+		{Group: "", Resource: "pods/status", Verbs: []string{"update"}},
+		// the ServiceAccount, Role and RoleBinding of the discovery service
+		{Group: "", Resource: "serviceaccounts", Verbs: []string{"get", "create", "update", "delete"}},
+		{Group: "rbac.authorization.k8s.io", Resource: "roles", Verbs: []string{"get", "create", "update", "delete", "escalate"}},
+		{Group: "rbac.authorization.k8s.io", Resource: "rolebindings", Verbs: []string{"get", "create", "update", "delete"}},
+		{Group: "apps", Resource: "statefulsets", Verbs: verbsAll},
+		{Group: "apps", Resource: "deployments", Verbs: verbsAll},
+		{Group: "apps", Resource: "controllerrevisions", Verbs: verbsAll},
+		{Group: "apps.pingcap.com", Resource: "statefulsets", Verbs: verbsAll},
+		{Group: "apps.pingcap.com", Resource: "statefulsets/status", Verbs: verbsAll},
+		// the jobs of the TidbInitializers, and the backup, restore and clean jobs
+		{Group: "batch", Resource: "jobs", Verbs: []string{"get", "list", "watch", "create", "update", "delete"}},
+		{Group: "networking.k8s.io", Resource: "ingresses", Verbs: verbsAll},
+		{Group: "extensions", Resource: "ingresses", Verbs: verbsAll},
+		{Group: "pingcap.com", Resource: "tidbclusters", Verbs: []string{"get", "list", "watch", "update", "patch"}},
+		{Group: "pingcap.com", Resource: "tidbinitializers", Verbs: []string{"get", "list", "watch", "update", "patch"}},
+		{Group: "pingcap.com", Resource: "backups", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "restores", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "backupschedules", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "tidbmonitors", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "tidbngmonitorings", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "dmclusters", Verbs: verbsWatch},
+		{Group: "pingcap.com", Resource: "tidbclusterautoscalers", Verbs: verbsWatch},
+		{Group: "", Resource: "nodes", Verbs: []string{"get", "list", "watch", "patch"}, ClusterResource: true},
+		{Group: "", Resource: "persistentvolumes", Verbs: []string{"get", "list", "watch", "update", "patch"}, ClusterResource: true},
+		{Group: "storage.k8s.io", Resource: "storageclasses", Verbs: verbsRead, ClusterResource: true},
+	},
+	CapabilityBackup: {
+		{Group: "pingcap.com", Resource: "backups", Verbs: []string{"get", "create", "update", "patch", "delete"}},
+		{Group: "pingcap.com", Resource: "restores", Verbs: []string{"get", "create", "update", "patch", "delete"}},
+		{Group: "pingcap.com", Resource: "backupschedules", Verbs: verbsUpdate},
+		// the clusters of the restore drills
+		{Group: "pingcap.com", Resource: "tidbclusters", Verbs: []string{"create", "delete"}},
+	},
+	CapabilityMonitor: {
+		{Group: "pingcap.com", Resource: "tidbmonitors", Verbs: verbsUpdate},
+		{Group: "pingcap.com", Resource: "tidbngmonitorings", Verbs: verbsUpdate},
+		{Group: "pingcap.com", Resource: "tidbngmonitorings/status", Verbs: []string{"update"}},
+		// the ClusterRole and ClusterRoleBinding of Prometheus in the installs managing all the namespaces
+		{Group: "rbac.authorization.k8s.io", Resource: "clusterroles", Verbs: []string{"get", "create", "update", "delete", "escalate"}, ClusterResource: true},
+		{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings", Verbs: []string{"get", "create", "update", "delete"}, ClusterResource: true},
+	},
+	CapabilityDM: {
+		{Group: "pingcap.com", Resource: "dmclusters", Verbs: verbsUpdate},
+	},
+	CapabilityAutoScaler: {
+		{Group: "pingcap.com", Resource: "tidbclusterautoscalers", Verbs: verbsUpdate},
+		// the heterogeneous clusters scaled out
+		{Group: "pingcap.com", Resource: "tidbclusters", Verbs: []string{"create", "delete"}},
+	},
+	CapabilityWebhook: {
+		{Group: "", Resource: "events", Verbs: []string{"create", "patch", "update"}},
+		{Group: "", Resource: "persistentvolumeclaims", Verbs: []string{"get", "list", "watch", "create", "update", "delete"}},
+		{Group: "", Resource: "pods", Verbs: []string{"get", "list", "watch", "update"}},
+		{Group: "", Resource: "secrets", Verbs: []string{"get", "list"}},
+		{Group: "", Resource: "configmaps", Verbs: []string{"get", "list"}},
+		{Group: "apps", Resource: "statefulsets", Verbs: []string{"get", "list", "watch", "update"}},
+		{Group: "apps.pingcap.com", Resource: "statefulsets", Verbs: verbsAll},
+		{Group: "pingcap.com", Resource: "tidbclusters", Verbs: []string{"get", "list", "watch", "update"}},
+		{Group: "pingcap.com", Resource: "dmclusters", Verbs: []string{"get", "list", "watch", "update"}},
+	},
+}
+
+// ParseCapabilities parses the comma-separated capabilities, CapabilityCore is always included
+func ParseCapabilities(s string) ([]Capability, error) {
+	caps := sets.NewString(string(CapabilityCore))
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !allCapabilities.Has(c) {
+			return nil, fmt.Errorf("unknown capability %q, supported capabilities: %s", c, strings.Join(allCapabilities.List(), ","))
+		}
+		caps.Insert(c)
+	}
+	var result []Capability
+	for _, c := range caps.List() {
+		result = append(result, Capability(c))
+	}
+	return result, nil
+}
+
+// Options is the feature set of an install of tidb-operator
+type Options struct {
+	Capabilities []Capability
+	// ClusterScoped is whether tidb-operator manages the clusters in all the namespaces
+	ClusterScoped bool
+	// ClusterResources are the cluster-scoped resources, e.g. nodes, permitted in a namespaced install
+	ClusterResources sets.String
+}
+
+// Has returns whether the capability is enabled
+func (o Options) Has(c Capability) bool {
+	for _, enabled := range o.Capabilities {
+		if enabled == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions required by the options, the permissions of the same resource
+// are merged and the result is sorted by the group and the resource
+func (o Options) Permissions() []Permission {
+	type resource struct {
+		group, resource string
+		cluster         bool
+	}
+	verbs := map[resource]sets.String{}
+	for _, c := range o.Capabilities {
+		for _, p := range registry[c] {
+			if p.ClusterResource && !o.ClusterScoped && !o.ClusterResources.Has(p.Resource) {
+				continue
+			}
+			key := resource{group: p.Group, resource: p.Resource, cluster: p.ClusterResource}
+			if verbs[key] == nil {
+				verbs[key] = sets.NewString()
+			}
+			verbs[key].Insert(p.Verbs...)
+		}
+	}
+
+	result := make([]Permission, 0, len(verbs))
+	for r, v := range verbs {
+		result = append(result, Permission{Group: r.group, Resource: r.resource, Verbs: v.List(), ClusterResource: r.cluster})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
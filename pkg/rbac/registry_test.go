@@ -0,0 +1,100 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caps, err := ParseCapabilities("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(caps).To(Equal([]Capability{CapabilityCore}))
+
+	caps, err = ParseCapabilities("monitor, backup,backup")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(caps).To(Equal([]Capability{CapabilityBackup, CapabilityCore, CapabilityMonitor}))
+
+	_, err = ParseCapabilities("backup,unknown")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestPermissions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	find := func(permissions []Permission, group, resource string) *Permission {
+		for i := range permissions {
+			if permissions[i].Group == group && permissions[i].Resource == resource {
+				return &permissions[i]
+			}
+		}
+		return nil
+	}
+
+	core := Options{Capabilities: []Capability{CapabilityCore}}.Permissions()
+	g.Expect(find(core, "pingcap.com", "backups").Verbs).To(Equal([]string{"list", "watch"}))
+	g.Expect(find(core, "", "nodes")).To(BeNil())
+	g.Expect(find(core, "rbac.authorization.k8s.io", "clusterroles")).To(BeNil())
+
+	backup := Options{Capabilities: []Capability{CapabilityCore, CapabilityBackup}}.Permissions()
+	g.Expect(find(backup, "pingcap.com", "backups").Verbs).To(Equal([]string{"create", "delete", "get", "list", "patch", "update", "watch"}))
+	g.Expect(find(backup, "pingcap.com", "tidbclusters").Verbs).To(ContainElements("create", "delete", "get", "update"))
+	for i := 1; i < len(backup); i++ {
+		prev, cur := backup[i-1], backup[i]
+		g.Expect(prev.Group < cur.Group || prev.Group == cur.Group && prev.Resource < cur.Resource).To(BeTrue())
+	}
+
+	namespaced := Options{
+		Capabilities:     []Capability{CapabilityCore, CapabilityMonitor},
+		ClusterResources: sets.NewString("nodes"),
+	}.Permissions()
+	g.Expect(find(namespaced, "", "nodes")).NotTo(BeNil())
+	g.Expect(find(namespaced, "", "persistentvolumes")).To(BeNil())
+	g.Expect(find(namespaced, "rbac.authorization.k8s.io", "clusterroles")).To(BeNil())
+
+	clusterScoped := Options{Capabilities: []Capability{CapabilityCore, CapabilityMonitor}, ClusterScoped: true}.Permissions()
+	g.Expect(find(clusterScoped, "", "persistentvolumes")).NotTo(BeNil())
+	g.Expect(find(clusterScoped, "rbac.authorization.k8s.io", "clusterroles")).NotTo(BeNil())
+}
+
+func TestRoles(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	objects := Roles("tidb-controller-manager", "tidb-admin", "sa", Options{Capabilities: []Capability{CapabilityCore}, ClusterScoped: true})
+	g.Expect(objects).To(HaveLen(2))
+	g.Expect(objects[0]).To(BeAssignableToTypeOf(&rbacv1.ClusterRole{}))
+	g.Expect(objects[1].(*rbacv1.ClusterRoleBinding).Subjects).To(Equal([]rbacv1.Subject{{Kind: "ServiceAccount", Name: "sa", Namespace: "tidb-admin"}}))
+
+	objects = Roles("tidb-controller-manager", "tidb-admin", "sa", Options{
+		Capabilities:     []Capability{CapabilityCore},
+		ClusterResources: sets.NewString("storageclasses"),
+	})
+	g.Expect(objects).To(HaveLen(4))
+	clusterRole := objects[0].(*rbacv1.ClusterRole)
+	g.Expect(clusterRole.Rules).To(Equal([]rbacv1.PolicyRule{{
+		APIGroups: []string{"storage.k8s.io"},
+		Resources: []string{"storageclasses"},
+		Verbs:     []string{"get", "list", "watch"},
+	}}))
+	role := objects[2].(*rbacv1.Role)
+	g.Expect(role.Namespace).To(Equal("tidb-admin"))
+	g.Expect(len(role.Rules)).To(Equal(len(Options{Capabilities: []Capability{CapabilityCore}}.Permissions())))
+	g.Expect(objects[3].(*rbacv1.RoleBinding).RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "tidb-controller-manager"}))
+}
@@ -74,3 +74,19 @@ type CreateUpdateStrategy interface {
 	// ValidateUpdate validates an update request for existing resource
 	ValidateUpdate(ctx context.Context, obj, old runtime.Object) field.ErrorList
 }
+
+// RawValidationStrategy is implemented by the strategies validating the fields of the raw resources that are
+// dropped when the resources are decoded.
+type RawValidationStrategy interface {
+	// ValidateRaw validates a new or updated resource before it is decoded
+	ValidateRaw(ctx context.Context, raw []byte) field.ErrorList
+}
+
+// WarningStrategy is implemented by the strategies warning the clients of the resources that are valid but
+// known to break some features, the warnings do not reject the requests.
+type WarningStrategy interface {
+	// WarningsOnCreate returns the warnings of a new resource
+	WarningsOnCreate(ctx context.Context, obj runtime.Object) []string
+	// WarningsOnUpdate returns the warnings of an update request for existing resource
+	WarningsOnUpdate(ctx context.Context, obj, old runtime.Object) []string
+}
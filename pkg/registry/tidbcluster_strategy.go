@@ -25,7 +25,13 @@ import (
 )
 
 // +k8s:deepcopy-gen=false
-type TidbClusterStrategy struct{}
+type TidbClusterStrategy struct {
+	// StorageClasses gets the StorageClasses referenced by the TidbClusters, they are validated on creation
+	// and on the changes of the storageClassNames. They are not validated if it is nil.
+	StorageClasses validation.StorageClassGetter
+}
+
+var _ WarningStrategy = TidbClusterStrategy{}
 
 func (TidbClusterStrategy) NewObject() runtime.Object {
 	return &v1alpha1.TidbCluster{}
@@ -41,22 +47,49 @@ func (TidbClusterStrategy) PrepareForUpdate(ctx context.Context, obj, old runtim
 	// no op to not affect the cluster managed by old versions of the helm chart
 }
 
-func (TidbClusterStrategy) Validate(ctx context.Context, obj runtime.Object) field.ErrorList {
+func (s TidbClusterStrategy) Validate(ctx context.Context, obj runtime.Object) field.ErrorList {
 	if tc, ok := castTidbCluster(obj); ok {
-		return validation.ValidateCreateTidbCluster(tc)
+		allErrs := validation.ValidateCreateTidbCluster(tc)
+		scErrs, _ := validation.ValidateStorageClasses(validation.TidbClusterStorageClassRefs(tc), s.StorageClasses)
+		return append(allErrs, scErrs...)
 	}
 	return field.ErrorList{}
 }
 
-func (TidbClusterStrategy) ValidateUpdate(ctx context.Context, obj, old runtime.Object) field.ErrorList {
+func (s TidbClusterStrategy) ValidateUpdate(ctx context.Context, obj, old runtime.Object) field.ErrorList {
 	oldTc, oldOk := castTidbCluster(old)
 	tc, ok := castTidbCluster(obj)
 	if ok && oldOk {
-		return validation.ValidateUpdateTidbCluster(oldTc, tc)
+		allErrs := validation.ValidateUpdateTidbCluster(oldTc, tc)
+		scErrs, _ := validation.ValidateStorageClasses(validation.ChangedStorageClassRefs(oldTc, tc), s.StorageClasses)
+		return append(allErrs, scErrs...)
 	}
 	return field.ErrorList{}
 }
 
+func (s TidbClusterStrategy) ValidateRaw(ctx context.Context, raw []byte) field.ErrorList {
+	return validation.ValidateTidbClusterRaw(raw)
+}
+
+func (s TidbClusterStrategy) WarningsOnCreate(ctx context.Context, obj runtime.Object) []string {
+	if tc, ok := castTidbCluster(obj); ok {
+		_, warnings := validation.ValidateStorageClasses(validation.TidbClusterStorageClassRefs(tc), s.StorageClasses)
+		return append(warnings, validation.TidbClusterWarnings(tc)...)
+	}
+	return nil
+}
+
+func (s TidbClusterStrategy) WarningsOnUpdate(ctx context.Context, obj, old runtime.Object) []string {
+	oldTc, oldOk := castTidbCluster(old)
+	tc, ok := castTidbCluster(obj)
+	if ok && oldOk {
+		_, warnings := validation.ValidateStorageClasses(validation.ChangedStorageClassRefs(oldTc, tc), s.StorageClasses)
+		warnings = append(warnings, validation.TidbClusterWarnings(tc)...)
+		return append(warnings, validation.TidbClusterUpdateWarnings(oldTc, tc)...)
+	}
+	return nil
+}
+
 func castTidbCluster(obj runtime.Object) (*v1alpha1.TidbCluster, bool) {
 	tc, ok := obj.(*v1alpha1.TidbCluster)
 	if !ok {
@@ -20,6 +20,7 @@ import (
 
 	"github.com/pingcap/tidb-operator/pkg/apis/label"
 	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
+	"github.com/pingcap/tidb-operator/pkg/events"
 	"github.com/pingcap/tidb-operator/pkg/features"
 	"github.com/pingcap/tidb-operator/pkg/scheduler/predicates"
 	apiv1 "k8s.io/api/core/v1"
@@ -96,6 +97,19 @@ func (s *scheduler) Filter(args *schedulerapi.ExtenderArgs) (*schedulerapi.Exten
 		if _, ok := pod.Annotations[label.AnnFailTiDBScheduler]; ok {
 			return nil, FailureError{PodName: pod.Name}
 		}
+		// the pod is held until the gate is removed, e.g. by tidb-controller-manager once the external
+		// provisioning of the pod completes
+		if gate, ok := pod.Annotations[label.AnnSchedulingGate]; ok {
+			klog.Infof("pod %s/%s is gated by %q, skip scheduling", ns, podName, gate)
+			failedNodes := schedulerapi.FailedNodesMap{}
+			for _, node := range kubeNodes {
+				failedNodes[node.Name] = fmt.Sprintf("pod is gated by %q", gate)
+			}
+			return &schedulerapi.ExtenderFilterResult{
+				Nodes:       &apiv1.NodeList{},
+				FailedNodes: failedNodes,
+			}, nil
+		}
 	}
 
 	var instanceName string
@@ -130,7 +144,7 @@ func (s *scheduler) Filter(args *schedulerapi.ExtenderArgs) (*schedulerapi.Exten
 		kubeNodes, err = predicate.Filter(instanceName, pod, kubeNodes)
 		klog.Infof("leaving predicate: %s, nodes: %v", predicate.Name(), predicates.GetNodeNames(kubeNodes))
 		if err != nil {
-			s.recorder.Event(pod, apiv1.EventTypeWarning, predicate.Name(), err.Error())
+			s.recorder.Eventf(pod, apiv1.EventTypeWarning, events.FailedScheduling, "%s: %v", predicate.Name(), err)
 			if len(kubeNodes) == 0 {
 				break
 			}
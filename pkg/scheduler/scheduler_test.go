@@ -177,6 +177,43 @@ func TestSchedulerFilter(t *testing.T) {
 				g.Expect(result.Nodes.Items[0].Name).To(Equal("node-1"))
 			},
 		},
+		{
+			name: "pod is gated",
+			args: &schedulerapi.ExtenderArgs{
+				Pod: &apiv1.Pod{
+					TypeMeta: metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod-1",
+						Namespace: corev1.NamespaceDefault,
+						Labels: map[string]string{
+							label.InstanceLabelKey:  "tc-1",
+							label.ComponentLabelKey: "tikv",
+						},
+						Annotations: map[string]string{
+							label.AnnSchedulingGate: label.AnnSchedulingGateExternalProvisioningVal,
+						},
+					},
+				},
+				Nodes: &apiv1.NodeList{
+					TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+					ListMeta: metav1.ListMeta{ResourceVersion: "9999"},
+					Items: []apiv1.Node{
+						{
+							TypeMeta: metav1.TypeMeta{Kind: "Node", APIVersion: "v1"},
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "node-1",
+							},
+						},
+					},
+				},
+			},
+			predicate: &predicates.FakePredicate{},
+			expectFn: func(g *GomegaWithT, result *schedulerapi.ExtenderFilterResult, err error) {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(result.Nodes.Items).To(BeEmpty())
+				g.Expect(result.FailedNodes).To(HaveKeyWithValue("node-1", `pod is gated by "external-provisioning"`))
+			},
+		},
 	}
 
 	for i := range tests {
@@ -21,6 +21,9 @@ type ActionType string
 
 const (
 	GetLeaderCountActionType ActionType = "GetLeaderCount"
+	GetLeaderStatsActionType ActionType = "GetLeaderStats"
+	FlushStoreActionType     ActionType = "FlushStore"
+	GetApplyLagActionType    ActionType = "GetApplyLag"
 )
 
 type NotFoundReaction struct {
@@ -72,3 +75,36 @@ func (c *FakeTiKVClient) GetLeaderCount() (int, error) {
 	}
 	return result.(int), nil
 }
+
+// GetLeaderStats returns the result of the GetLeaderStats reaction if added,
+// otherwise the stats of a store without hibernated regions built from the GetLeaderCount reaction.
+func (c *FakeTiKVClient) GetLeaderStats() (*LeaderStats, error) {
+	if _, ok := c.reactions[GetLeaderStatsActionType]; !ok {
+		leaderCount, err := c.GetLeaderCount()
+		if err != nil {
+			return nil, err
+		}
+		return &LeaderStats{RegionCount: leaderCount, LeaderCount: leaderCount}, nil
+	}
+	action := &Action{}
+	result, err := c.fakeAPI(GetLeaderStatsActionType, action)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*LeaderStats), nil
+}
+
+func (c *FakeTiKVClient) FlushStore() error {
+	action := &Action{}
+	_, err := c.fakeAPI(FlushStoreActionType, action)
+	return err
+}
+
+func (c *FakeTiKVClient) GetApplyLag() (int64, error) {
+	action := &Action{}
+	result, err := c.fakeAPI(GetApplyLagActionType, action)
+	if err != nil {
+		return 0, err
+	}
+	return result.(int64), nil
+}
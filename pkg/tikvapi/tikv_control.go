@@ -14,20 +14,27 @@
 package tikvapi
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"sync"
 
+	"github.com/pingcap/kvproto/pkg/debugpb"
 	"github.com/pingcap/tidb-operator/pkg/pdapi"
 	"github.com/pingcap/tidb-operator/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	corelisterv1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 )
 
 // TiKVControlInterface is an interface that knows how to manage and get client for TiKV
 type TiKVControlInterface interface {
-	// GetTiKVPodClient provides TiKVClient of the TiKV cluster.
-	GetTiKVPodClient(namespace string, tcName string, podName string, tlsEnabled bool) TiKVClient
+	// GetTiKVPodClient provides TiKVClient of the TiKV cluster, statusPort is the port TiKV serves the status on.
+	GetTiKVPodClient(namespace string, tcName string, podName string, statusPort int32, tlsEnabled bool) TiKVClient
+	// ProbeStore calls the debug service on the gRPC address of a TiKV store directly, bypassing PD,
+	// and returns the id the store answers with.
+	ProbeStore(namespace string, tcName string, address string, tlsEnabled bool) (uint64, error)
 }
 
 // defaultTiKVControl is the default implementation of TiKVControlInterface.
@@ -42,7 +49,7 @@ func NewDefaultTiKVControl(secretLister corelisterv1.SecretLister) TiKVControlIn
 	return &defaultTiKVControl{secretLister: secretLister, tikvClients: map[string]TiKVClient{}}
 }
 
-func (tc *defaultTiKVControl) GetTiKVPodClient(namespace string, tcName string, podName string, tlsEnabled bool) TiKVClient {
+func (tc *defaultTiKVControl) GetTiKVPodClient(namespace string, tcName string, podName string, statusPort int32, tlsEnabled bool) TiKVClient {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
 
@@ -55,13 +62,37 @@ func (tc *defaultTiKVControl) GetTiKVPodClient(namespace string, tcName string,
 		tlsConfig, err = pdapi.GetTLSConfig(tc.secretLister, pdapi.Namespace(namespace), util.ClusterClientTLSSecretName(tcName))
 		if err != nil {
 			klog.Errorf("Unable to get tls config for TiKV cluster %q, tikv client may not work: %v", tcName, err)
-			return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme), DefaultTimeout, tlsConfig, true)
+			return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme, statusPort), DefaultTimeout, tlsConfig, true)
 		}
 
-		return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme), DefaultTimeout, tlsConfig, true)
+		return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme, statusPort), DefaultTimeout, tlsConfig, true)
 	}
 
-	return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme), DefaultTimeout, tlsConfig, true)
+	return NewTiKVClient(TiKVPodClientURL(namespace, tcName, podName, scheme, statusPort), DefaultTimeout, tlsConfig, true)
+}
+
+func (tc *defaultTiKVControl) ProbeStore(namespace string, tcName string, address string, tlsEnabled bool) (uint64, error) {
+	opt := grpc.WithInsecure()
+	if tlsEnabled {
+		tlsConfig, err := pdapi.GetTLSConfig(tc.secretLister, pdapi.Namespace(namespace), util.ClusterClientTLSSecretName(tcName))
+		if err != nil {
+			return 0, fmt.Errorf("unable to get tls config for TiKV cluster %q: %v", tcName, err)
+		}
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, opt, grpc.WithBlock())
+	if err != nil {
+		return 0, fmt.Errorf("dial %s: %v", address, err)
+	}
+	defer conn.Close()
+	resp, err := debugpb.NewDebugClient(conn).GetStoreInfo(ctx, &debugpb.GetStoreInfoRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("get store info from %s: %v", address, err)
+	}
+	return resp.GetStoreId(), nil
 }
 
 func tikvPodClientKey(schema, namespace, clusterName, podName string) string {
@@ -69,20 +100,27 @@ func tikvPodClientKey(schema, namespace, clusterName, podName string) string {
 }
 
 // TiKVPodClientURL builds the url of tikv pod client
-func TiKVPodClientURL(namespace, clusterName, podName, scheme string) string {
-	return fmt.Sprintf("%s://%s.%s-tikv-peer.%s:20180", scheme, podName, clusterName, namespace)
+func TiKVPodClientURL(namespace, clusterName, podName, scheme string, statusPort int32) string {
+	return fmt.Sprintf("%s://%s.%s-tikv-peer.%s:%d", scheme, podName, clusterName, namespace, statusPort)
 }
 
 // FakeTiKVControl implements a fake version of TiKVControlInterface.
 type FakeTiKVControl struct {
 	defaultTiKVControl
 	tikvPodClients map[string]TiKVClient
+	storeProbes    map[string]fakeStoreProbe
+}
+
+type fakeStoreProbe struct {
+	storeID uint64
+	err     error
 }
 
 func NewFakeTiKVControl(secretLister corelisterv1.SecretLister) *FakeTiKVControl {
 	return &FakeTiKVControl{
 		defaultTiKVControl: defaultTiKVControl{secretLister: secretLister, tikvClients: map[string]TiKVClient{}},
 		tikvPodClients:     map[string]TiKVClient{},
+		storeProbes:        map[string]fakeStoreProbe{},
 	}
 }
 
@@ -90,6 +128,19 @@ func (ftc *FakeTiKVControl) SetTiKVPodClient(namespace, tcName, podName string,
 	ftc.tikvPodClients[tikvPodClientKey("http", namespace, tcName, podName)] = tikvPodClient
 }
 
-func (ftc *FakeTiKVControl) GetTiKVPodClient(namespace, tcName, podName string, tlsEnabled bool) TiKVClient {
+func (ftc *FakeTiKVControl) GetTiKVPodClient(namespace, tcName, podName string, statusPort int32, tlsEnabled bool) TiKVClient {
 	return ftc.tikvPodClients[tikvPodClientKey("http", namespace, tcName, podName)]
 }
+
+// SetStoreProbe sets the result of probing the store on the address, the stores without results are unreachable
+func (ftc *FakeTiKVControl) SetStoreProbe(address string, storeID uint64, err error) {
+	ftc.storeProbes[address] = fakeStoreProbe{storeID: storeID, err: err}
+}
+
+func (ftc *FakeTiKVControl) ProbeStore(namespace, tcName, address string, tlsEnabled bool) (uint64, error) {
+	probe, ok := ftc.storeProbes[address]
+	if !ok {
+		return 0, fmt.Errorf("dial %s: context deadline exceeded", address)
+	}
+	return probe.storeID, probe.err
+}
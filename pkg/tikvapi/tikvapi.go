@@ -15,13 +15,17 @@ package tikvapi
 
 import (
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	dto "github.com/prometheus/client_model/go"
+	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
 	"github.com/prometheus/prom2json"
 	"k8s.io/klog/v2"
 )
@@ -30,12 +34,25 @@ const (
 	DefaultTimeout        = 5 * time.Second
 	metricNameRegionCount = "tikv_raftstore_region_count"
 	labelNameLeaderCount  = "leader"
+	labelNameRegionCount  = "region"
 	metricsPrefix         = "metrics"
+
+	metricNameHibernatedPeerState = "tikv_raftstore_hibernated_peer_state"
+	labelNameHibernated           = "hibernated"
+
+	flushPrefix    = "debug/flush"
+	applyLagPrefix = "debug/apply_lag"
 )
 
+// ErrNotSupported is returned by the APIs which are not served by the TiKV in use
+var ErrNotSupported = errors.New("not supported by the tikv")
+
 // TiKVClient provides tikv server's api
 type TiKVClient interface {
 	GetLeaderCount() (int, error)
+	GetLeaderStats() (*LeaderStats, error)
+	FlushStore() error
+	GetApplyLag() (int64, error)
 }
 
 // tikvClient is default implementation of TiKVClient
@@ -44,15 +61,142 @@ type tikvClient struct {
 	httpClient *http.Client
 }
 
+// LeaderStats is the breakdown of the regions on a TiKV store
+type LeaderStats struct {
+	// RegionCount is the number of regions which have a peer on the store
+	RegionCount int
+	// LeaderCount is the number of region leaders on the store
+	LeaderCount int
+	// HibernatedPeerCount is the number of hibernated peers on the store,
+	// including both leaders and followers
+	HibernatedPeerCount int
+}
+
+// HibernatedLeaderCount returns the number of leaders known to be hibernated.
+// TiKV does not report hibernated leaders directly, so the hibernated peers that
+// can not be followers are counted, which is a lower bound of the hibernated leaders.
+func (s *LeaderStats) HibernatedLeaderCount() int {
+	n := s.HibernatedPeerCount - (s.RegionCount - s.LeaderCount)
+	if n < 0 {
+		return 0
+	}
+	if n > s.LeaderCount {
+		return s.LeaderCount
+	}
+	return n
+}
+
+// ActiveLeaderCount returns the number of leaders which are not known to be hibernated
+func (s *LeaderStats) ActiveLeaderCount() int {
+	return s.LeaderCount - s.HibernatedLeaderCount()
+}
+
 // GetLeaderCount gets region leader count from the URL
 func (c *tikvClient) GetLeaderCount() (int, error) {
 	apiURL := fmt.Sprintf("%s/%s", c.url, metricsPrefix)
+	fms := c.fetchMetricFamilies(apiURL)
+	value, found := findMetricValue(fms, metricNameRegionCount, "type", labelNameLeaderCount)
+	if !found {
+		return 0, fmt.Errorf("metric %s{type=\"%s\"} not found for %s", metricNameRegionCount, labelNameLeaderCount, apiURL)
+	}
+	return strconv.Atoi(value)
+}
+
+// GetLeaderStats gets the region, leader and hibernated peer counts from the URL.
+// The hibernated peer count is 0 if hibernate region is disabled or not supported by the TiKV.
+func (c *tikvClient) GetLeaderStats() (*LeaderStats, error) {
+	apiURL := fmt.Sprintf("%s/%s", c.url, metricsPrefix)
+	fms := c.fetchMetricFamilies(apiURL)
+
+	stats := &LeaderStats{}
+	for _, m := range []struct {
+		name, label, labelValue string
+		into                    *int
+		optional                bool
+	}{
+		{metricNameRegionCount, "type", labelNameLeaderCount, &stats.LeaderCount, false},
+		{metricNameRegionCount, "type", labelNameRegionCount, &stats.RegionCount, false},
+		{metricNameHibernatedPeerState, "state", labelNameHibernated, &stats.HibernatedPeerCount, true},
+	} {
+		value, found := findMetricValue(fms, m.name, m.label, m.labelValue)
+		if !found {
+			if m.optional {
+				continue
+			}
+			return nil, fmt.Errorf("metric %s{%s=\"%s\"} not found for %s", m.name, m.label, m.labelValue, apiURL)
+		}
+		// gauges may be reported as float
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse metric %s{%s=\"%s\"} value %q failed: %v", m.name, m.label, m.labelValue, value, err)
+		}
+		*m.into = int(f)
+	}
+	return stats, nil
+}
+
+// FlushStore triggers the store to sync the raft logs and flush the memtables, so that less raft logs
+// need to be applied after it restarts. It returns ErrNotSupported if the TiKV does not serve the API.
+func (c *tikvClient) FlushStore() error {
+	apiURL := fmt.Sprintf("%s/%s", c.url, flushPrefix)
+	_, err := c.doBodyOK(apiURL, "POST")
+	return err
+}
+
+// ApplyLagInfo is the apply lag reported by the TiKV status API
+type ApplyLagInfo struct {
+	// ApplyLag is the total number of raft log entries committed but not yet applied
+	// by the peers on the store
+	ApplyLag int64 `json:"apply_lag"`
+}
+
+// GetApplyLag gets the apply lag of the peers on the store. It returns ErrNotSupported if the TiKV does
+// not serve the API.
+func (c *tikvClient) GetApplyLag() (int64, error) {
+	apiURL := fmt.Sprintf("%s/%s", c.url, applyLagPrefix)
+	body, err := c.doBodyOK(apiURL, "GET")
+	if err != nil {
+		return 0, err
+	}
+	info := &ApplyLagInfo{}
+	if err := json.Unmarshal(body, info); err != nil {
+		return 0, fmt.Errorf("unmarshal apply lag from %s failed: %v", apiURL, err)
+	}
+	return info.ApplyLag, nil
+}
+
+// doBodyOK returns the body or an error if the response is not okay, ErrNotSupported is returned if
+// the API is not found.
+func (c *tikvClient) doBodyOK(apiURL, method string) ([]byte, error) {
+	req, err := http.NewRequest(method, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httputil.DeferClose(res.Body)
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", method, apiURL, ErrNotSupported)
+	}
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("error response %v URL %s, body response: %s", res.StatusCode, apiURL, string(body))
+	}
+	return body, nil
+}
+
+func (c *tikvClient) fetchMetricFamilies(apiURL string) []*prom2json.Family {
 	transport := c.httpClient.Transport
 	mfChan := make(chan *dto.MetricFamily, 1024)
 
 	go func() {
 		if err := prom2json.FetchMetricFamilies(apiURL, mfChan, transport); err != nil {
-			klog.Errorf("Fail to get region leader count from %s, error: %v", apiURL, err)
+			klog.Errorf("Fail to get metrics from %s, error: %v", apiURL, err)
 		}
 	}()
 
@@ -61,17 +205,20 @@ func (c *tikvClient) GetLeaderCount() (int, error) {
 		fm := prom2json.NewFamily(mfc)
 		fms = append(fms, fm)
 	}
+	return fms
+}
+
+func findMetricValue(fms []*prom2json.Family, name, label, labelValue string) (string, bool) {
 	for _, fm := range fms {
-		if fm.Name == metricNameRegionCount {
+		if fm.Name == name {
 			for _, m := range fm.Metrics {
-				if m, ok := m.(prom2json.Metric); ok && m.Labels["type"] == labelNameLeaderCount {
-					return strconv.Atoi(m.Value)
+				if m, ok := m.(prom2json.Metric); ok && m.Labels[label] == labelValue {
+					return m.Value, true
 				}
 			}
 		}
 	}
-
-	return 0, fmt.Errorf("metric %s{type=\"%s\"} not found for %s", metricNameRegionCount, labelNameLeaderCount, apiURL)
+	return "", false
 }
 
 // NewTiKVClient returns a new TiKVClient
@@ -152,48 +152,60 @@ func (o *InfoOptions) Run() error {
 	return nil
 }
 
+// effectiveRequests returns the requests of the component, including the ones derived from the sizing
+func effectiveRequests(sizing *v1alpha1.Sizing, explicit v1.ResourceRequirements) v1.ResourceList {
+	resources, err := v1alpha1.SizedResources(sizing, explicit)
+	if err != nil {
+		return explicit.Requests
+	}
+	return resources.Requests
+}
+
 // go template is lacking type checking and hard to maintain, in this
 // case we just render manually
 func renderTidbCluster(tc *v1alpha1.TidbCluster, svc *v1.Service, podList *v1.PodList) (string, error) {
 	var pdCPU resource.Quantity
 	var pdMemory resource.Quantity
 	var pdStorage resource.Quantity
-	if tc.Spec.PD != nil && tc.Spec.PD.Requests != nil {
-		if cpu := tc.Spec.PD.Requests.Cpu(); cpu != nil {
+	if tc.Spec.PD != nil {
+		requests := effectiveRequests(tc.Spec.PD.Sizing, tc.Spec.PD.ResourceRequirements)
+		if cpu := requests.Cpu(); cpu != nil {
 			pdCPU = *cpu
 		}
-		if mem := tc.Spec.PD.Requests.Memory(); mem != nil {
+		if mem := requests.Memory(); mem != nil {
 			pdMemory = *mem
 		}
-		if st, ok := tc.Spec.PD.Requests[v1.ResourceStorage]; ok {
+		if st, ok := requests[v1.ResourceStorage]; ok {
 			pdStorage = st
 		}
 	}
 	var tikvCPU resource.Quantity
 	var tikvMemory resource.Quantity
 	var tikvStorage resource.Quantity
-	if tc.Spec.TiKV != nil && tc.Spec.TiKV.Requests != nil {
-		if cpu := tc.Spec.TiKV.Requests.Cpu(); cpu != nil {
+	if tc.Spec.TiKV != nil {
+		requests := effectiveRequests(tc.Spec.TiKV.Sizing, tc.Spec.TiKV.ResourceRequirements)
+		if cpu := requests.Cpu(); cpu != nil {
 			tikvCPU = *cpu
 		}
-		if mem := tc.Spec.TiKV.Requests.Memory(); mem != nil {
+		if mem := requests.Memory(); mem != nil {
 			tikvMemory = *mem
 		}
-		if st, ok := tc.Spec.TiKV.Requests[v1.ResourceStorage]; ok {
+		if st, ok := requests[v1.ResourceStorage]; ok {
 			tikvStorage = st
 		}
 	}
 	var tidbCPU resource.Quantity
 	var tidbMemory resource.Quantity
 	var tidbStorage resource.Quantity
-	if tc.Spec.TiDB != nil && tc.Spec.TiDB.Requests != nil {
-		if cpu := tc.Spec.TiDB.Requests.Cpu(); cpu != nil {
+	if tc.Spec.TiDB != nil {
+		requests := effectiveRequests(tc.Spec.TiDB.Sizing, tc.Spec.TiDB.ResourceRequirements)
+		if cpu := requests.Cpu(); cpu != nil {
 			tidbCPU = *cpu
 		}
-		if mem := tc.Spec.TiDB.Requests.Memory(); mem != nil {
+		if mem := requests.Memory(); mem != nil {
 			tidbMemory = *mem
 		}
-		if st, ok := tc.Spec.TiDB.Requests[v1.ResourceStorage]; ok {
+		if st, ok := requests[v1.ResourceStorage]; ok {
 			tidbStorage = st
 		}
 	}
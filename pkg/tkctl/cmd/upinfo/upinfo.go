@@ -144,7 +144,8 @@ func (o *UpInfoOptions) Run() error {
 		return err
 	}
 	podList, err := o.KubeCli.CoreV1().Pods(o.Namespace).List(context.TODO(), metav1.ListOptions{
-		LabelSelector: label.New().Instance(tc.Name).TiDB().String(),
+		// the tidb warm standby pods and the pods of the tidb groups are not the replicas of the StatefulSet
+		LabelSelector: fmt.Sprintf("%s,!%s,!%s", label.New().Instance(tc.Name).TiDB(), label.TiDBWarmStandbyLabelKey, label.TiDBGroupLabelKey),
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,198 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	httputil "github.com/pingcap/tidb-operator/pkg/util/http"
+	"k8s.io/klog/v2"
+)
+
+const (
+	otlpTracesPath = "/v1/traces"
+	serviceName    = "tidb-controller-manager"
+	scopeName      = "github.com/pingcap/tidb-operator/pkg/tracing"
+	// spanKindInternal is SPAN_KIND_INTERNAL in the OTLP protocol
+	spanKindInternal = 1
+
+	exportTimeout = 10 * time.Second
+	// exportQueueSize is the max number of batches waiting to be exported,
+	// the spans are dropped if the queue is full
+	exportQueueSize = 1024
+)
+
+// otlpExporter exports the spans to an OTLP/HTTP endpoint with the JSON encoding
+// in background, so that reconciliation is not blocked by the collector.
+// It speaks the OTLP wire format directly as the OpenTelemetry SDK is not a dependency
+// of tidb-operator yet, any OTLP collector accepting OTLP/HTTP can receive the traces.
+type otlpExporter struct {
+	url        string
+	httpClient *http.Client
+	queue      chan []*Span
+}
+
+var _ Exporter = &otlpExporter{}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+	e := &otlpExporter{
+		url:        url,
+		httpClient: &http.Client{Timeout: exportTimeout},
+		queue:      make(chan []*Span, exportQueueSize),
+	}
+	go e.run()
+	return e
+}
+
+func (e *otlpExporter) Export(spans []*Span) {
+	select {
+	case e.queue <- spans:
+	default:
+		klog.Warningf("otlp exporter: queue is full, drop %d spans", len(spans))
+	}
+}
+
+func (e *otlpExporter) run() {
+	for spans := range e.queue {
+		if err := e.export(spans); err != nil {
+			klog.Warningf("otlp exporter: failed to export %d spans to %s: %v", len(spans), e.url, err)
+		}
+	}
+}
+
+func (e *otlpExporter) export(spans []*Span) error {
+	body, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httputil.DeferClose(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s: %v", res.Status, httputil.ReadErrorBody(res.Body))
+	}
+	return nil
+}
+
+// The types below are the JSON encoding of ExportTraceServiceRequest in the OTLP protocol,
+// see https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64     `json:"endTimeUnixNano,string"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func newExportRequest(spans []*Span) *exportRequest {
+	svc := serviceName
+	ss := scopeSpans{Scope: scope{Name: scopeName}}
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.SpanID[:]),
+			Name:              span.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: uint64(span.Start.UnixNano()),
+			EndTimeUnixNano:   uint64(span.End.UnixNano()),
+		}
+		if span.ParentSpanID != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.ParentSpanID[:])
+		}
+		for _, attr := range span.Attributes {
+			s.Attributes = append(s.Attributes, keyValue{Key: attr.Key, Value: toAnyValue(attr.Value)})
+		}
+		ss.Spans = append(ss.Spans, s)
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{
+			{
+				Resource: resource{
+					Attributes: []keyValue{{Key: "service.name", Value: anyValue{StringValue: &svc}}},
+				},
+				ScopeSpans: []scopeSpans{ss},
+			},
+		},
+	}
+}
+
+func toAnyValue(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int, int32, int64:
+		// int64 is encoded as a decimal string in the JSON encoding of protobuf
+		s := fmt.Sprintf("%d", v)
+		return anyValue{IntValue: &s}
+	default:
+		s := fmt.Sprintf("%v", v)
+		return anyValue{StringValue: &s}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodPost))
+		g.Expect(r.URL.Path).To(Equal(otlpTracesPath))
+		g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+		body, err := io.ReadAll(r.Body)
+		g.Expect(err).NotTo(HaveOccurred())
+		req := map[string]interface{}{}
+		g.Expect(json.Unmarshal(body, &req)).To(Succeed())
+		received <- req
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	start := time.Unix(100, 0)
+	span := &Span{
+		TraceID:      [16]byte{1},
+		SpanID:       [8]byte{2},
+		ParentSpanID: [8]byte{3},
+		Name:         "restart tc-tikv-0",
+		Start:        start,
+		End:          start.Add(time.Second),
+		Attributes:   []Attribute{String("k8s.pod.name", "tc-tikv-0"), Int("pod.ordinal", 0)},
+	}
+	newOTLPExporter(server.URL + "/").Export([]*Span{span})
+
+	var req map[string]interface{}
+	g.Eventually(received, 5*time.Second).Should(Receive(&req))
+	expected := `{
+	"resourceSpans": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "tidb-controller-manager"}}]},
+		"scopeSpans": [{
+			"scope": {"name": "github.com/pingcap/tidb-operator/pkg/tracing"},
+			"spans": [{
+				"traceId": "01000000000000000000000000000000",
+				"spanId": "0200000000000000",
+				"parentSpanId": "0300000000000000",
+				"name": "restart tc-tikv-0",
+				"kind": 1,
+				"startTimeUnixNano": "100000000000",
+				"endTimeUnixNano": "101000000000",
+				"attributes": [
+					{"key": "k8s.pod.name", "value": {"stringValue": "tc-tikv-0"}},
+					{"key": "pod.ordinal", "value": {"intValue": "0"}}
+				]
+			}]
+		}]
+	}]
+}`
+	expectedReq := map[string]interface{}{}
+	g.Expect(json.Unmarshal([]byte(expected), &expectedReq)).To(Succeed())
+	g.Expect(req).To(Equal(expectedReq))
+}
+
+func TestNewOTLPExporterURL(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for endpoint, url := range map[string]string{
+		"http://collector:4318":            "http://collector:4318/v1/traces",
+		"http://collector:4318/":           "http://collector:4318/v1/traces",
+		"http://collector:4318/v1/traces":  "http://collector:4318/v1/traces",
+		"https://collector/otlp/v1/traces": "https://collector/otlp/v1/traces",
+	} {
+		g.Expect(newOTLPExporter(endpoint).url).To(Equal(url))
+	}
+}
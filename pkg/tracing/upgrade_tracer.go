@@ -0,0 +1,275 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+)
+
+// UpgradeTracer records the upgrades of TidbClusters as traces. Each cluster upgrade is a trace
+// with a root span for the cluster, a child span for each component and a grandchild span for
+// each pod restart.
+// All methods are idempotent as they are called in every reconciliation.
+//
+// The spans are kept in memory until they end. Their IDs are derived from the UID and the
+// generation of the TidbCluster, so after a failover of tidb-controller-manager the new leader
+// recreates the ongoing spans with the same IDs and ends them, the start time of a recreated span
+// is the time it is recreated though.
+type UpgradeTracer interface {
+	// StartComponent starts the span of the component, and the span of the cluster
+	// if it is not started yet. The attributes are only used when the span is started.
+	StartComponent(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, attrs ...Attribute)
+	// EndComponent ends the span of the component and the spans of its pods
+	EndComponent(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType)
+	// StartPod starts the span of the pod if the span of the component is started
+	StartPod(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podName string, attrs ...Attribute)
+	// EndPod ends the span of the pod
+	EndPod(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podName string)
+	// SyncCluster ends the spans of the components which are not upgrading any more according to
+	// tc status, and the span of the cluster if none of its components is being upgraded.
+	SyncCluster(tc *v1alpha1.TidbCluster)
+}
+
+// Attribute is a key-value pair attached to a span, the value is a string, bool or an integer
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span is a finished or ongoing span
+type Span struct {
+	TraceID      [16]byte
+	SpanID       [8]byte
+	ParentSpanID [8]byte
+	Name         string
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+}
+
+// Exporter exports the finished spans
+type Exporter interface {
+	Export(spans []*Span)
+}
+
+// NewUpgradeTracer returns an UpgradeTracer exporting the traces to the OTLP/HTTP endpoint,
+// a no-op UpgradeTracer is returned if the endpoint is empty.
+func NewUpgradeTracer(endpoint string) UpgradeTracer {
+	if endpoint == "" {
+		return &noopTracer{}
+	}
+	return newTracer(newOTLPExporter(endpoint))
+}
+
+type componentTrace struct {
+	span *Span
+	pods map[string]*Span
+}
+
+type clusterTrace struct {
+	root       *Span
+	components map[v1alpha1.MemberType]*componentTrace
+}
+
+type tracer struct {
+	mu       sync.Mutex
+	exporter Exporter
+	clusters map[string]*clusterTrace
+	now      func() time.Time
+}
+
+var _ UpgradeTracer = &tracer{}
+
+func newTracer(exporter Exporter) *tracer {
+	return &tracer{
+		exporter: exporter,
+		clusters: map[string]*clusterTrace{},
+		now:      time.Now,
+	}
+}
+
+func clusterKey(tc *v1alpha1.TidbCluster) string {
+	return fmt.Sprintf("%s/%s", tc.GetNamespace(), tc.GetName())
+}
+
+func (t *tracer) StartComponent(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, attrs ...Attribute) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ct, ok := t.clusters[clusterKey(tc)]
+	if !ok {
+		root := &Span{
+			Name:  "upgrade " + clusterKey(tc),
+			Start: t.now(),
+			Attributes: []Attribute{
+				String("tidbcluster.namespace", tc.GetNamespace()),
+				String("tidbcluster.name", tc.GetName()),
+				String("tidbcluster.version", tc.Spec.Version),
+			},
+		}
+		id := sha256.Sum256([]byte(fmt.Sprintf("%s/%d", tc.GetUID(), tc.GetGeneration())))
+		copy(root.TraceID[:], id[:16])
+		copy(root.SpanID[:], id[16:24])
+		ct = &clusterTrace{
+			root:       root,
+			components: map[v1alpha1.MemberType]*componentTrace{},
+		}
+		t.clusters[clusterKey(tc)] = ct
+	}
+	if _, ok := ct.components[memberType]; ok {
+		return
+	}
+	ct.components[memberType] = &componentTrace{
+		span: t.newChild(ct.root, "upgrade "+memberType.String(),
+			append([]Attribute{String("component", memberType.String())}, attrs...)),
+		pods: map[string]*Span{},
+	}
+}
+
+func (t *tracer) EndComponent(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ct, ok := t.clusters[clusterKey(tc)]
+	if !ok {
+		return
+	}
+	t.endComponent(ct, memberType)
+}
+
+func (t *tracer) StartPod(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podName string, attrs ...Attribute) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	comp := t.component(tc, memberType)
+	if comp == nil {
+		return
+	}
+	if _, ok := comp.pods[podName]; ok {
+		return
+	}
+	comp.pods[podName] = t.newChild(comp.span, "restart "+podName,
+		append([]Attribute{String("k8s.pod.name", podName)}, attrs...))
+}
+
+func (t *tracer) EndPod(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType, podName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	comp := t.component(tc, memberType)
+	if comp == nil {
+		return
+	}
+	if span, ok := comp.pods[podName]; ok {
+		t.end(span)
+		delete(comp.pods, podName)
+	}
+}
+
+func (t *tracer) SyncCluster(tc *v1alpha1.TidbCluster) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := clusterKey(tc)
+	ct, ok := t.clusters[key]
+	if !ok {
+		return
+	}
+
+	upgrading := map[v1alpha1.MemberType]bool{}
+	if tc.DeletionTimestamp == nil {
+		for _, status := range v1alpha1.ComponentStatusFromTC(tc) {
+			if status.GetPhase() == v1alpha1.UpgradePhase {
+				upgrading[status.GetMemberType()] = true
+			}
+		}
+	}
+	for memberType := range ct.components {
+		if !upgrading[memberType] {
+			t.endComponent(ct, memberType)
+		}
+	}
+	if len(ct.components) == 0 && len(upgrading) == 0 {
+		t.end(ct.root)
+		delete(t.clusters, key)
+	}
+}
+
+func (t *tracer) component(tc *v1alpha1.TidbCluster, memberType v1alpha1.MemberType) *componentTrace {
+	ct, ok := t.clusters[clusterKey(tc)]
+	if !ok {
+		return nil
+	}
+	return ct.components[memberType]
+}
+
+func (t *tracer) endComponent(ct *clusterTrace, memberType v1alpha1.MemberType) {
+	comp, ok := ct.components[memberType]
+	if !ok {
+		return
+	}
+	for _, span := range comp.pods {
+		t.end(span)
+	}
+	t.end(comp.span)
+	delete(ct.components, memberType)
+}
+
+func (t *tracer) newChild(parent *Span, name string, attrs []Attribute) *Span {
+	span := &Span{
+		TraceID:      parent.TraceID,
+		ParentSpanID: parent.SpanID,
+		Name:         name,
+		Start:        t.now(),
+		Attributes:   attrs,
+	}
+	id := sha256.Sum256([]byte(fmt.Sprintf("%x/%x/%s", parent.TraceID, parent.SpanID, name)))
+	copy(span.SpanID[:], id[:8])
+	return span
+}
+
+func (t *tracer) end(span *Span) {
+	span.End = t.now()
+	t.exporter.Export([]*Span{span})
+}
+
+type noopTracer struct{}
+
+var _ UpgradeTracer = &noopTracer{}
+
+func (n *noopTracer) StartComponent(_ *v1alpha1.TidbCluster, _ v1alpha1.MemberType, _ ...Attribute) {}
+
+func (n *noopTracer) EndComponent(_ *v1alpha1.TidbCluster, _ v1alpha1.MemberType) {}
+
+func (n *noopTracer) StartPod(_ *v1alpha1.TidbCluster, _ v1alpha1.MemberType, _ string, _ ...Attribute) {
+}
+
+func (n *noopTracer) EndPod(_ *v1alpha1.TidbCluster, _ v1alpha1.MemberType, _ string) {}
+
+func (n *noopTracer) SyncCluster(_ *v1alpha1.TidbCluster) {}
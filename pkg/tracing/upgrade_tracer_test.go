@@ -0,0 +1,126 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeExporter struct {
+	spans []*Span
+}
+
+func (e *fakeExporter) Export(spans []*Span) {
+	e.spans = append(e.spans, spans...)
+}
+
+func (e *fakeExporter) names() []string {
+	names := []string{}
+	for _, span := range e.spans {
+		names = append(names, span.Name)
+	}
+	return names
+}
+
+func TestUpgradeTracer(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	exporter := &fakeExporter{}
+	tr := newTracer(exporter)
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	tc := &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "tc", UID: "uid", Generation: 1},
+		Spec: v1alpha1.TidbClusterSpec{
+			Version: "v5.4.0",
+			PD:      &v1alpha1.PDSpec{},
+			TiKV:    &v1alpha1.TiKVSpec{},
+		},
+	}
+
+	// pods are ignored before the component is started
+	tr.StartPod(tc, v1alpha1.PDMemberType, "tc-pd-0")
+	g.Expect(tr.clusters).To(BeEmpty())
+
+	tc.Status.PD.Phase = v1alpha1.UpgradePhase
+	tr.StartComponent(tc, v1alpha1.PDMemberType, String("image.to", "pingcap/pd:v5.4.0"))
+	tr.StartComponent(tc, v1alpha1.PDMemberType, String("image.to", "ignored"))
+	tr.StartPod(tc, v1alpha1.PDMemberType, "tc-pd-1", Int("pod.ordinal", 1))
+	tr.StartPod(tc, v1alpha1.PDMemberType, "tc-pd-1", Int("pod.ordinal", 1))
+	tr.SyncCluster(tc)
+	g.Expect(exporter.spans).To(BeEmpty())
+
+	tr.EndPod(tc, v1alpha1.PDMemberType, "tc-pd-1")
+	tr.EndPod(tc, v1alpha1.PDMemberType, "tc-pd-1")
+	tr.StartPod(tc, v1alpha1.PDMemberType, "tc-pd-0", Int("pod.ordinal", 0))
+	g.Expect(exporter.names()).To(Equal([]string{"restart tc-pd-1"}))
+
+	// pd is done and tikv starts in the same sync, the cluster span is kept
+	tc.Status.PD.Phase = v1alpha1.NormalPhase
+	tc.Status.TiKV.Phase = v1alpha1.UpgradePhase
+	tr.StartComponent(tc, v1alpha1.TiKVMemberType)
+	tr.SyncCluster(tc)
+	g.Expect(exporter.names()).To(Equal([]string{"restart tc-pd-1", "restart tc-pd-0", "upgrade pd"}))
+
+	tr.EndComponent(tc, v1alpha1.TiKVMemberType)
+	tr.SyncCluster(tc)
+	g.Expect(exporter.spans).To(HaveLen(4))
+
+	tc.Status.TiKV.Phase = v1alpha1.NormalPhase
+	tr.SyncCluster(tc)
+	g.Expect(exporter.names()).To(Equal([]string{"restart tc-pd-1", "restart tc-pd-0", "upgrade pd", "upgrade tikv", "upgrade ns/tc"}))
+	g.Expect(tr.clusters).To(BeEmpty())
+
+	pod, pd, tikv, root := exporter.spans[0], exporter.spans[2], exporter.spans[3], exporter.spans[4]
+	for _, span := range exporter.spans {
+		g.Expect(span.TraceID).To(Equal(root.TraceID))
+		g.Expect(span.End.After(span.Start)).To(BeTrue())
+	}
+	g.Expect(root.ParentSpanID).To(Equal([8]byte{}))
+	g.Expect(root.Attributes).To(ContainElement(String("tidbcluster.version", "v5.4.0")))
+	g.Expect(pd.ParentSpanID).To(Equal(root.SpanID))
+	g.Expect(pd.Attributes).To(Equal([]Attribute{String("component", "pd"), String("image.to", "pingcap/pd:v5.4.0")}))
+	g.Expect(tikv.ParentSpanID).To(Equal(root.SpanID))
+	g.Expect(pod.ParentSpanID).To(Equal(pd.SpanID))
+	g.Expect(pod.Attributes).To(Equal([]Attribute{String("k8s.pod.name", "tc-pd-1"), Int("pod.ordinal", 1)}))
+
+	// a new upgrade is a new trace
+	tc.Generation++
+	tc.Status.PD.Phase = v1alpha1.UpgradePhase
+	tr.StartComponent(tc, v1alpha1.PDMemberType)
+	g.Expect(tr.clusters["ns/tc"].root.TraceID).NotTo(Equal(root.TraceID))
+
+	// the spans are recreated with the same IDs after a failover
+	failover := newTracer(&fakeExporter{})
+	failover.StartComponent(tc, v1alpha1.PDMemberType)
+	g.Expect(failover.clusters["ns/tc"].root.TraceID).To(Equal(tr.clusters["ns/tc"].root.TraceID))
+	g.Expect(failover.clusters["ns/tc"].root.SpanID).To(Equal(tr.clusters["ns/tc"].root.SpanID))
+	g.Expect(failover.clusters["ns/tc"].components[v1alpha1.PDMemberType].span.SpanID).
+		To(Equal(tr.clusters["ns/tc"].components[v1alpha1.PDMemberType].span.SpanID))
+
+	// all spans are ended when the cluster is deleted
+	tc.DeletionTimestamp = &metav1.Time{Time: now}
+	tr.SyncCluster(tc)
+	g.Expect(exporter.spans).To(HaveLen(7))
+	g.Expect(tr.clusters).To(BeEmpty())
+}
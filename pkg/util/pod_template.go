@@ -0,0 +1,165 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	defaultTerminationGracePeriodSeconds = int64(corev1.DefaultTerminationGracePeriodSeconds)
+	defaultVolumeMode                    = corev1.SecretVolumeSourceDefaultMode
+	defaultProbeTimeoutSeconds           = int32(1)
+	defaultProbePeriodSeconds            = int32(10)
+	defaultProbeSuccessThreshold         = int32(1)
+	defaultProbeFailureThreshold         = int32(3)
+)
+
+// PodTemplateEqual compares the pod templates ignoring the fields set to the values defaulted by the
+// API server, so that the templates generated by tidb-operator are still equal to the ones applied
+// before after the API server or the client types start to default new fields.
+func PodTemplateEqual(a, b corev1.PodTemplateSpec) bool {
+	a.Spec = withoutDefaultedFields(a.Spec)
+	b.Spec = withoutDefaultedFields(b.Spec)
+	return apiequality.Semantic.DeepEqual(a, b)
+}
+
+// PodSpecEqual compares the pod specs ignoring the fields set to the values defaulted by the API server
+func PodSpecEqual(a, b corev1.PodSpec) bool {
+	return apiequality.Semantic.DeepEqual(withoutDefaultedFields(a), withoutDefaultedFields(b))
+}
+
+// withoutDefaultedFields returns a copy of the pod spec with the fields equal to the values defaulted
+// by the API server cleared, see SetDefaults_PodSpec and the related funcs of the core/v1 API.
+// The fields tidb-operator sets to other values are kept and compared.
+func withoutDefaultedFields(spec corev1.PodSpec) corev1.PodSpec {
+	spec = *spec.DeepCopy()
+	if spec.DNSPolicy == corev1.DNSClusterFirst {
+		spec.DNSPolicy = ""
+	}
+	if spec.RestartPolicy == corev1.RestartPolicyAlways {
+		spec.RestartPolicy = ""
+	}
+	if spec.TerminationGracePeriodSeconds != nil && *spec.TerminationGracePeriodSeconds == defaultTerminationGracePeriodSeconds {
+		spec.TerminationGracePeriodSeconds = nil
+	}
+	if spec.SchedulerName == corev1.DefaultSchedulerName {
+		spec.SchedulerName = ""
+	}
+	if spec.EnableServiceLinks != nil && *spec.EnableServiceLinks == corev1.DefaultEnableServiceLinks {
+		spec.EnableServiceLinks = nil
+	}
+	if spec.SecurityContext != nil && apiequality.Semantic.DeepEqual(*spec.SecurityContext, corev1.PodSecurityContext{}) {
+		spec.SecurityContext = nil
+	}
+	for i := range spec.InitContainers {
+		clearContainerDefaults(&spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		clearContainerDefaults(&spec.Containers[i])
+	}
+	for i := range spec.Volumes {
+		clearVolumeDefaults(&spec.Volumes[i].VolumeSource)
+	}
+	return spec
+}
+
+func clearContainerDefaults(c *corev1.Container) {
+	if c.TerminationMessagePath == corev1.TerminationMessagePathDefault {
+		c.TerminationMessagePath = ""
+	}
+	if c.TerminationMessagePolicy == corev1.TerminationMessageReadFile {
+		c.TerminationMessagePolicy = ""
+	}
+	if c.ImagePullPolicy == defaultImagePullPolicy(c.Image) {
+		c.ImagePullPolicy = ""
+	}
+	for i := range c.Ports {
+		if c.Ports[i].Protocol == corev1.ProtocolTCP {
+			c.Ports[i].Protocol = ""
+		}
+	}
+	for i := range c.Env {
+		if ref := c.Env[i].ValueFrom; ref != nil && ref.FieldRef != nil && ref.FieldRef.APIVersion == "v1" {
+			ref.FieldRef.APIVersion = ""
+		}
+	}
+	for _, probe := range []*corev1.Probe{c.LivenessProbe, c.ReadinessProbe, c.StartupProbe} {
+		clearProbeDefaults(probe)
+	}
+}
+
+func clearProbeDefaults(p *corev1.Probe) {
+	if p == nil {
+		return
+	}
+	if p.TimeoutSeconds == defaultProbeTimeoutSeconds {
+		p.TimeoutSeconds = 0
+	}
+	if p.PeriodSeconds == defaultProbePeriodSeconds {
+		p.PeriodSeconds = 0
+	}
+	if p.SuccessThreshold == defaultProbeSuccessThreshold {
+		p.SuccessThreshold = 0
+	}
+	if p.FailureThreshold == defaultProbeFailureThreshold {
+		p.FailureThreshold = 0
+	}
+	if p.HTTPGet != nil && p.HTTPGet.Scheme == corev1.URISchemeHTTP {
+		p.HTTPGet.Scheme = ""
+	}
+}
+
+func clearVolumeDefaults(v *corev1.VolumeSource) {
+	clearMode := func(mode **int32) {
+		if *mode != nil && **mode == defaultVolumeMode {
+			*mode = nil
+		}
+	}
+	if v.ConfigMap != nil {
+		clearMode(&v.ConfigMap.DefaultMode)
+	}
+	if v.Secret != nil {
+		clearMode(&v.Secret.DefaultMode)
+	}
+	if v.DownwardAPI != nil {
+		clearMode(&v.DownwardAPI.DefaultMode)
+		for i := range v.DownwardAPI.Items {
+			if ref := v.DownwardAPI.Items[i].FieldRef; ref != nil && ref.APIVersion == "v1" {
+				ref.APIVersion = ""
+			}
+		}
+	}
+	if v.Projected != nil {
+		clearMode(&v.Projected.DefaultMode)
+	}
+}
+
+// defaultImagePullPolicy returns the image pull policy defaulted by the API server for the image
+func defaultImagePullPolicy(image string) corev1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	name := image
+	if idx := strings.LastIndex(image, "/"); idx >= 0 {
+		name = image[idx+1:]
+	}
+	if idx := strings.LastIndex(name, ":"); idx < 0 || name[idx+1:] == "latest" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestPodSpecEqual(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newSpec := func() corev1.PodSpec {
+		return corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:  "tidb",
+				Image: "pingcap/tidb:v6.1.0",
+				Ports: []corev1.ContainerPort{{Name: "server", ContainerPort: 4000}},
+				Env: []corev1.EnvVar{{
+					Name:      "NAMESPACE",
+					ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+				}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(4000)}},
+				},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "config",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}},
+			}},
+		}
+	}
+
+	// the API server defaults the fields
+	defaulted := newSpec()
+	defaulted.DNSPolicy = corev1.DNSClusterFirst
+	defaulted.RestartPolicy = corev1.RestartPolicyAlways
+	defaulted.SchedulerName = corev1.DefaultSchedulerName
+	defaulted.TerminationGracePeriodSeconds = pointer.Int64Ptr(corev1.DefaultTerminationGracePeriodSeconds)
+	defaulted.EnableServiceLinks = pointer.BoolPtr(true)
+	defaulted.SecurityContext = &corev1.PodSecurityContext{}
+	c := &defaulted.Containers[0]
+	c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	c.ImagePullPolicy = corev1.PullIfNotPresent
+	c.Ports[0].Protocol = corev1.ProtocolTCP
+	c.Env[0].ValueFrom.FieldRef.APIVersion = "v1"
+	c.ReadinessProbe.TimeoutSeconds = 1
+	c.ReadinessProbe.PeriodSeconds = 10
+	c.ReadinessProbe.SuccessThreshold = 1
+	c.ReadinessProbe.FailureThreshold = 3
+	defaulted.Volumes[0].ConfigMap.DefaultMode = pointer.Int32Ptr(corev1.ConfigMapVolumeSourceDefaultMode)
+	g.Expect(PodSpecEqual(newSpec(), defaulted)).To(BeTrue())
+	g.Expect(PodTemplateEqual(corev1.PodTemplateSpec{Spec: defaulted}, corev1.PodTemplateSpec{Spec: newSpec()})).To(BeTrue())
+
+	// the fields set to the values other than the defaults are compared
+	changed := newSpec()
+	changed.EnableServiceLinks = pointer.BoolPtr(false)
+	g.Expect(PodSpecEqual(newSpec(), changed)).To(BeFalse())
+	changed = newSpec()
+	changed.Containers[0].ImagePullPolicy = corev1.PullAlways
+	g.Expect(PodSpecEqual(newSpec(), changed)).To(BeFalse())
+	changed = newSpec()
+	changed.Containers[0].Image = "pingcap/tidb:v6.1.1"
+	g.Expect(PodSpecEqual(newSpec(), changed)).To(BeFalse())
+
+	// the spec is not mutated
+	g.Expect(defaulted.EnableServiceLinks).NotTo(BeNil())
+	g.Expect(defaulted.Containers[0].TerminationMessagePath).To(Equal(corev1.TerminationMessagePathDefault))
+}
+
+func TestDefaultImagePullPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(defaultImagePullPolicy("pingcap/tidb")).To(Equal(corev1.PullAlways))
+	g.Expect(defaultImagePullPolicy("pingcap/tidb:latest")).To(Equal(corev1.PullAlways))
+	g.Expect(defaultImagePullPolicy("localhost:5000/pingcap/tidb")).To(Equal(corev1.PullAlways))
+	g.Expect(defaultImagePullPolicy("localhost:5000/pingcap/tidb:v6.1.0")).To(Equal(corev1.PullIfNotPresent))
+	g.Expect(defaultImagePullPolicy("pingcap/tidb@sha256:abcd")).To(Equal(corev1.PullIfNotPresent))
+}
@@ -26,6 +26,8 @@ const (
 	Ready = "Ready"
 	// StatefulSetNotUpToDate is added when one of statefulsets is not up to date.
 	StatfulSetNotUpToDate = "StatefulSetNotUpToDate"
+	// PausedStatefulSetNotUpToDate is added when only the statefulsets of paused components are not up to date.
+	PausedStatefulSetNotUpToDate = "PausedStatefulSetNotUpToDate"
 	// PDUnhealthy is added when one of pd members is unhealthy.
 	PDUnhealthy = "PDUnhealthy"
 	// TiKVStoreNotUp is added when one of tikv stores is not up.
@@ -34,6 +36,14 @@ const (
 	TiDBUnhealthy = "TiDBUnhealthy"
 	// TiFlashStoreNotUp is added when one of tiflash stores is not up.
 	TiFlashStoreNotUp = "TiFlashStoreNotUp"
+	// StorageOverThreshold is added when the used storage of one of the pods exceeds the warning threshold.
+	StorageOverThreshold = "StorageOverThreshold"
+	// StorageUnderThreshold is added when the used storage of all the pods is below the warning threshold.
+	StorageUnderThreshold = "StorageUnderThreshold"
+	// PDBootstrapTimedOut is added when PD does not become healthy within the bootstrap timeout.
+	PDBootstrapTimedOut = "PDBootstrapTimedOut"
+	// TiDBPodNotReadyTimedOut is added when an upgraded TiDB pod is not ready within the upgrade readiness timeout.
+	TiDBPodNotReadyTimedOut = "TiDBPodNotReadyTimedOut"
 )
 
 // NewTidbClusterCondition creates a new tidbcluster condition.
@@ -74,6 +84,14 @@ func SetTidbClusterCondition(status *v1alpha1.TidbClusterStatus, condition v1alp
 	status.Conditions = append(newConditions, condition)
 }
 
+// RemoveTidbClusterCondition removes the condition with the provided type.
+func RemoveTidbClusterCondition(status *v1alpha1.TidbClusterStatus, condType v1alpha1.TidbClusterConditionType) {
+	if GetTidbClusterCondition(*status, condType) == nil {
+		return
+	}
+	status.Conditions = filterOutCondition(status.Conditions, condType)
+}
+
 // filterOutCondition returns a new slice of tidbcluster conditions without conditions with the provided type.
 func filterOutCondition(conditions []v1alpha1.TidbClusterCondition, condType v1alpha1.TidbClusterConditionType) []v1alpha1.TidbClusterCondition {
 	var newConditions []v1alpha1.TidbClusterCondition
@@ -47,6 +47,7 @@ var (
 	ClusterClientTLSPath   = "/var/lib/cluster-client-tls"
 	ClusterAssetsTLSPath   = "/var/lib/cluster-assets-tls"
 	TiDBClientTLSPath      = "/var/lib/tidb-client-tls"
+	TiDBServerCAPath       = "/var/lib/tidb-server-ca"
 	BRBinPath              = "/var/lib/br-bin"
 	DumplingBinPath        = "/var/lib/dumpling-bin"
 	LightningBinPath       = "/var/lib/lightning-bin"
@@ -201,6 +202,18 @@ func TiDBServerTLSSecretName(tcName string) string {
 	return fmt.Sprintf("%s-tidb-server-secret", tcName)
 }
 
+// CheckCASecret checks the secret exists and contains the ca.crt to verify the TLS server certificate
+func CheckCASecret(secretLister corelisterv1.SecretLister, ns, name string) error {
+	secret, err := secretLister.Secrets(ns).Get(name)
+	if err != nil {
+		return fmt.Errorf("get secret %s/%s failed, err: %v", ns, name, err)
+	}
+	if _, ok := secret.Data[corev1.ServiceAccountRootCAKey]; !ok {
+		return fmt.Errorf("secret %s/%s does not contain key %s", ns, name, corev1.ServiceAccountRootCAKey)
+	}
+	return nil
+}
+
 // SortEnvByName implements sort.Interface to sort env list by name.
 type SortEnvByName []corev1.EnvVar
 
@@ -396,7 +409,7 @@ func StatefulSetEqual(new apps.StatefulSet, old apps.StatefulSet) bool {
 		tmpTemplate := oldConfig.Template.DeepCopy()
 		delete(tmpTemplate.Annotations, LastAppliedConfigAnnotation)
 		return apiequality.Semantic.DeepEqual(oldConfig.Replicas, new.Spec.Replicas) &&
-			apiequality.Semantic.DeepEqual(*tmpTemplate, new.Spec.Template) &&
+			PodTemplateEqual(*tmpTemplate, new.Spec.Template) &&
 			apiequality.Semantic.DeepEqual(oldConfig.UpdateStrategy, new.Spec.UpdateStrategy)
 	}
 	return false
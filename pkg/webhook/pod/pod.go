@@ -0,0 +1,185 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/openshift/generic-admission-server/pkg/apiserver"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
+	operatorutil "github.com/pingcap/tidb-operator/pkg/util"
+	"github.com/pingcap/tidb-operator/pkg/webhook/util"
+	admission "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+var (
+	deserializer runtime.Decoder = util.Codecs.UniversalDeserializer()
+)
+
+// PodAdmissionControl pins the PD and TiKV pods to the nodes of their ordinals in spec.pd.nodeBindings and
+// spec.tikv.nodeBindings by the required node affinity when they are created. The StatefulSet renders the
+// same pod template for all the ordinals, so the affinity of each ordinal can only be injected here.
+type PodAdmissionControl struct {
+	lock        sync.RWMutex
+	initialized bool
+	// operator client interface
+	operatorCli versioned.Interface
+}
+
+var _ apiserver.MutatingAdmissionHook = &PodAdmissionControl{}
+
+func NewPodAdmissionControl() *PodAdmissionControl {
+	return &PodAdmissionControl{}
+}
+
+func (pc *PodAdmissionControl) MutatingResource() (plural schema.GroupVersionResource, singular string) {
+	return schema.GroupVersionResource{
+			Group:    "admission.tidb.pingcap.com",
+			Version:  "v1alpha1",
+			Resource: "podmutations",
+		},
+		"podmutation"
+}
+
+func (pc *PodAdmissionControl) Admit(ar *admission.AdmissionRequest) *admission.AdmissionResponse {
+	pc.lock.RLock()
+	defer pc.lock.RUnlock()
+	if !pc.initialized {
+		return &admission.AdmissionResponse{
+			Allowed: false,
+		}
+	}
+	if ar.Operation != admission.Create {
+		return util.ARSuccess()
+	}
+
+	pod := &corev1.Pod{}
+	if _, _, err := deserializer.Decode(ar.Object.Raw, nil, pod); err != nil {
+		err = fmt.Errorf("pod %s/%s, decode request failed, err: %v", ar.Namespace, ar.Name, err)
+		klog.Error(err)
+		return util.ARFail(err)
+	}
+	namespace := ar.Namespace
+	name := pod.Name
+	if name == "" {
+		name = ar.Name
+	}
+
+	l := label.Label(pod.Labels)
+	if !l.IsManagedByTiDBOperator() {
+		return util.ARSuccess()
+	}
+	var memberType v1alpha1.MemberType
+	switch {
+	case l.IsPD():
+		memberType = v1alpha1.PDMemberType
+	case l.IsTiKV():
+		memberType = v1alpha1.TiKVMemberType
+	default:
+		return util.ARSuccess()
+	}
+	tcName := l[label.InstanceLabelKey]
+	if tcName == "" || name == "" {
+		return util.ARSuccess()
+	}
+
+	tc, err := pc.operatorCli.PingcapV1alpha1().TidbClusters(namespace).Get(context.TODO(), tcName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return util.ARSuccess()
+	}
+	if err != nil {
+		err := fmt.Errorf("get tidbcluster %s/%s failed, pod %s, err %v", namespace, tcName, name, err)
+		klog.Error(err)
+		return util.ARFail(err)
+	}
+	if len(tc.NodeBindings(memberType)) == 0 {
+		return util.ARSuccess()
+	}
+	ordinal, err := operatorutil.GetOrdinalFromPodName(name)
+	if err != nil {
+		err := fmt.Errorf("pod %s/%s, parse the ordinal failed, err: %v", namespace, name, err)
+		klog.Error(err)
+		return util.ARFail(err)
+	}
+	binding := tc.NodeBinding(memberType, ordinal)
+	if binding == nil {
+		klog.Warningf("pod %s/%s is not pinned, the ordinal %d is not bound in spec.%s.nodeBindings", namespace, name, ordinal, memberType)
+		return util.ARSuccess()
+	}
+
+	original := pod.DeepCopy()
+	pinToNodes(pod, binding.NodeSelectorTerm())
+	patch, err := util.CreateJsonPatch(original, pod)
+	if err != nil {
+		err := fmt.Errorf("pod %s/%s, create the patch failed, err: %v", namespace, name, err)
+		klog.Error(err)
+		return util.ARFail(err)
+	}
+	klog.Infof("pin pod %s/%s to the nodes of the binding of the ordinal %d", namespace, name, ordinal)
+	return util.ARPatch(patch)
+}
+
+// Initialize implements AdmissionHook.Initialize interface. It's is called as
+// a post-start hook.
+func (pc *PodAdmissionControl) Initialize(cfg *rest.Config, stopCh <-chan struct{}) error {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	cli, err := versioned.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	pc.operatorCli = cli
+
+	pc.initialized = true
+	return nil
+}
+
+// pinToNodes adds the requirements of the term to the required node affinity of the pod. The terms of the
+// required node affinity are ORed, so the requirements are ANDed into each of the existing terms to keep
+// both the existing affinity and the binding.
+func pinToNodes(pod *corev1.Pod, term corev1.NodeSelectorTerm) {
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(required.NodeSelectorTerms) == 0 {
+		required.NodeSelectorTerms = []corev1.NodeSelectorTerm{term}
+		return
+	}
+	for i := range required.NodeSelectorTerms {
+		t := &required.NodeSelectorTerms[i]
+		t.MatchExpressions = append(t.MatchExpressions, term.MatchExpressions...)
+		t.MatchFields = append(t.MatchFields, term.MatchFields...)
+	}
+}
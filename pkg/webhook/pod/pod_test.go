@@ -0,0 +1,224 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	. "github.com/onsi/gomega"
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned/fake"
+	"github.com/pingcap/tidb-operator/pkg/webhook/util"
+	admission "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newPod(name string, l label.Label) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Pod",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: corev1.NamespaceDefault,
+			Labels:    l,
+		},
+	}
+}
+
+func newTidbCluster() *v1alpha1.TidbCluster {
+	return &v1alpha1.TidbCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: corev1.NamespaceDefault,
+		},
+		Spec: v1alpha1.TidbClusterSpec{
+			PD: &v1alpha1.PDSpec{
+				NodeBindings: []v1alpha1.NodeBinding{
+					{Ordinal: 0, NodeName: "node-a"},
+					{Ordinal: 1, NodeSelector: map[string]string{"disk": "ssd"}},
+				},
+			},
+			TiKV: &v1alpha1.TiKVSpec{},
+		},
+	}
+}
+
+// admit returns the response of the creation of the pod and the pod patched by it
+func admit(g *GomegaWithT, pod *corev1.Pod, tc *v1alpha1.TidbCluster, operation admission.Operation) (*admission.AdmissionResponse, *corev1.Pod) {
+	cli := fake.NewSimpleClientset()
+	if tc != nil {
+		_, err := cli.PingcapV1alpha1().TidbClusters(tc.Namespace).Create(context.TODO(), tc, metav1.CreateOptions{})
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	ac := NewPodAdmissionControl()
+	ac.initialized = true
+	ac.operatorCli = cli
+
+	jsonInfo, ok := runtime.SerializerInfoForMediaType(util.Codecs.SupportedMediaTypes(), runtime.ContentTypeJSON)
+	g.Expect(ok).To(BeTrue())
+	buf := bytes.Buffer{}
+	g.Expect(util.Codecs.EncoderForVersion(jsonInfo.Serializer, corev1.SchemeGroupVersion).Encode(pod, &buf)).To(Succeed())
+	resp := ac.Admit(&admission.AdmissionRequest{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: buf.Bytes()},
+	})
+	if len(resp.Patch) == 0 {
+		return resp, pod
+	}
+
+	patch, err := jsonpatch.DecodePatch(resp.Patch)
+	g.Expect(err).NotTo(HaveOccurred())
+	patched, err := patch.Apply(buf.Bytes())
+	g.Expect(err).NotTo(HaveOccurred())
+	result := &corev1.Pod{}
+	g.Expect(json.Unmarshal(patched, result)).To(Succeed())
+	return resp, result
+}
+
+func TestPodAdmissionControlPin(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// the pod is pinned to the node by its name
+	resp, pod := admit(g, newPod("foo-pd-0", label.New().Instance("foo").PD()), newTidbCluster(), admission.Create)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(*resp.PatchType).To(Equal(admission.PatchTypeJSONPatch))
+	g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(&corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node-a"}}},
+		}},
+	}))
+
+	// the binding is ANDed into each of the existing terms and the preferred affinity is kept
+	origin := newPod("foo-pd-1", label.New().Instance("foo").PD())
+	preferred := []corev1.PreferredSchedulingTerm{{
+		Weight:     10,
+		Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"z1"}}}},
+	}}
+	origin.Spec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"r1"}}}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"r2"}}}},
+				},
+			},
+			PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+		},
+	}
+	disk := corev1.NodeSelectorRequirement{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"ssd"}}
+	resp, pod = admit(g, origin, newTidbCluster(), admission.Create)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(Equal(&corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"r1"}}, disk}},
+			{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "rack", Operator: corev1.NodeSelectorOpIn, Values: []string{"r2"}}, disk}},
+		},
+	}))
+	g.Expect(pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(Equal(preferred))
+
+	// the TiKV pods are pinned by spec.tikv.nodeBindings
+	tc := newTidbCluster()
+	tc.Spec.TiKV.NodeBindings = []v1alpha1.NodeBinding{{Ordinal: 2, NodeName: "node-c"}}
+	resp, pod = admit(g, newPod("foo-tikv-2", label.New().Instance("foo").TiKV()), tc, admission.Create)
+	g.Expect(resp.Allowed).To(BeTrue())
+	g.Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields[0].Values).To(Equal([]string{"node-c"}))
+}
+
+func TestPodAdmissionControlSkip(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		tc          *v1alpha1.TidbCluster
+		operation   admission.Operation
+		wantAllowed bool
+	}{
+		{
+			name:        "non-create operation",
+			pod:         newPod("foo-pd-0", label.New().Instance("foo").PD()),
+			tc:          newTidbCluster(),
+			operation:   admission.Update,
+			wantAllowed: true,
+		},
+		{
+			name:        "not managed by tidb-operator",
+			pod:         newPod("foo-pd-0", label.Label{label.ComponentLabelKey: label.PDLabelVal, label.InstanceLabelKey: "foo"}),
+			tc:          newTidbCluster(),
+			operation:   admission.Create,
+			wantAllowed: true,
+		},
+		{
+			name:        "not pd or tikv",
+			pod:         newPod("foo-tidb-0", label.New().Instance("foo").TiDB()),
+			tc:          newTidbCluster(),
+			operation:   admission.Create,
+			wantAllowed: true,
+		},
+		{
+			name:        "the cluster does not exist",
+			pod:         newPod("foo-pd-0", label.New().Instance("foo").PD()),
+			operation:   admission.Create,
+			wantAllowed: true,
+		},
+		{
+			name:        "no bindings",
+			pod:         newPod("foo-tikv-0", label.New().Instance("foo").TiKV()),
+			tc:          newTidbCluster(),
+			operation:   admission.Create,
+			wantAllowed: true,
+		},
+		{
+			name:        "the ordinal is not bound",
+			pod:         newPod("foo-pd-2", label.New().Instance("foo").PD()),
+			tc:          newTidbCluster(),
+			operation:   admission.Create,
+			wantAllowed: true,
+		},
+		{
+			name:        "invalid ordinal",
+			pod:         newPod("foo-pd-x", label.New().Instance("foo").PD()),
+			tc:          newTidbCluster(),
+			operation:   admission.Create,
+			wantAllowed: false,
+		},
+	}
+	for _, tt := range tests {
+		resp, pod := admit(g, tt.pod, tt.tc, tt.operation)
+		g.Expect(resp.Allowed).To(Equal(tt.wantAllowed), tt.name)
+		g.Expect(resp.Patch).To(BeEmpty(), tt.name)
+		g.Expect(pod.Spec.Affinity).To(BeNil(), tt.name)
+	}
+}
+
+func TestMutatingResource(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	gvr, singular := NewPodAdmissionControl().MutatingResource()
+	g.Expect(gvr.Group).To(Equal("admission.tidb.pingcap.com"))
+	g.Expect(gvr.Version).To(Equal("v1alpha1"))
+	g.Expect(gvr.Resource).To(Equal("podmutations"))
+	g.Expect(singular).To(Equal("podmutation"))
+}
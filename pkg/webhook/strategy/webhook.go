@@ -16,12 +16,17 @@ package strategy
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/openshift/generic-admission-server/pkg/apiserver"
+	"github.com/pingcap/tidb-operator/pkg/registry"
 	"github.com/pingcap/tidb-operator/pkg/webhook/util"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 )
@@ -71,20 +76,32 @@ func (w *StrategyAdmissionHook) Validate(ar *admissionv1beta1.AdmissionRequest)
 		return util.ARFail(err)
 	}
 	var allErr field.ErrorList
+	var old runtime.Object
+	if rs, ok := s.(registry.RawValidationStrategy); ok {
+		allErr = append(allErr, rs.ValidateRaw(context.TODO(), ar.Object.Raw)...)
+	}
 	if ar.Operation == admissionv1beta1.Create {
-		allErr = s.Validate(context.TODO(), obj)
+		allErr = append(allErr, s.Validate(context.TODO(), obj)...)
 	} else {
-		old := s.NewObject()
+		old = s.NewObject()
 		if err := json.Unmarshal(ar.OldObject.Raw, old); err != nil {
 			klog.Errorf("admission validating failed: cannot unmarshal %s to %T", ar.Kind, old)
 			return util.ARFail(err)
 		}
-		allErr = s.ValidateUpdate(context.TODO(), obj, old)
+		allErr = append(allErr, s.ValidateUpdate(context.TODO(), obj, old)...)
 	}
 	if len(allErr) > 0 {
 		return util.ARFail(allErr.ToAggregate())
 	}
-	return util.ARSuccess()
+	resp := util.ARSuccess()
+	if ws, ok := s.(registry.WarningStrategy); ok {
+		if ar.Operation == admissionv1beta1.Create {
+			resp.Warnings = ws.WarningsOnCreate(context.TODO(), obj)
+		} else {
+			resp.Warnings = ws.WarningsOnUpdate(context.TODO(), obj, old)
+		}
+	}
+	return resp
 }
 
 func (w *StrategyAdmissionHook) Admit(ar *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
@@ -118,6 +135,20 @@ func (w *StrategyAdmissionHook) Admit(ar *admissionv1beta1.AdmissionRequest) *ad
 	return util.ARPatch(patch)
 }
 
+// Initialize starts the informer of the StorageClasses validated by TidbClusterStrategy
 func (w *StrategyAdmissionHook) Initialize(cfg *rest.Config, stopCh <-chan struct{}) error {
+	kubeCli, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubeCli, 0)
+	scLister := informerFactory.Storage().V1().StorageClasses().Lister()
+	informerFactory.Start(stopCh)
+	for typ, synced := range informerFactory.WaitForCacheSync(stopCh) {
+		if !synced {
+			return fmt.Errorf("failed to sync the cache of %v", typ)
+		}
+	}
+	w.registry.Register(registry.TidbClusterStrategy{StorageClasses: scLister})
 	return nil
 }
@@ -78,6 +78,15 @@ type Config struct {
 	E2EImage string `yaml:"e2e_image" json:"e2e_image"`
 
 	PreloadImages bool `yaml:"preload_images" json:"preload_images"`
+	KeepImages    bool `yaml:"keep_images" json:"keep_images"`
+	// the container runtime of the host of the kind cluster, docker or containerd, detected if empty
+	PreloadImagesRuntime string `yaml:"preload_images_runtime" json:"preload_images_runtime"`
+	// only log the commands the images would be preloaded by, without pulling or loading them
+	PreloadImagesDryRun bool `yaml:"preload_images_dry_run" json:"preload_images_dry_run"`
+	// tag the preloaded images with the same ID as an image loaded before on the nodes instead of loading them again
+	PreloadImagesDedupDigests bool `yaml:"preload_images_dedup_digests" json:"preload_images_dedup_digests"`
+	// the name of the kind cluster the images are preloaded into
+	KindClusterName string `yaml:"kind_cluster_name" json:"kind_cluster_name"`
 
 	OperatorKiller utiloperator.OperatorKillerConfig
 }
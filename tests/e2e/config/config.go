@@ -17,6 +17,7 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"time"
 
 	"github.com/pingcap/tidb-operator/tests"
@@ -47,12 +48,26 @@ func RegisterTiDBOperatorFlags(flags *flag.FlagSet) {
 	flags.StringVar(&TestConfig.OperatorRepoUrl, "operator-repo-url", "https://github.com/pingcap/tidb-operator.git", "tidb-operator repo url used")
 	flags.StringVar(&TestConfig.ChartDir, "chart-dir", "", "chart dir")
 	flags.BoolVar(&TestConfig.PreloadImages, "preload-images", false, "if set, preload images in the bootstrap of e2e process")
+	flags.StringVar(&TestConfig.PreloadImagesRuntime, "preload-images-runtime", "", "the container runtime of the host of the kind cluster the images are preloaded by, docker or containerd, detected if empty")
+	flags.BoolVar(&TestConfig.PreloadImagesDryRun, "preload-images-dry-run", false, "if set with --preload-images, log the commands the images would be preloaded by without running them")
+	flags.BoolVar(&TestConfig.PreloadImagesDedupDigests, "preload-images-dedup-digests", false, "if set with --preload-images, inspect the IDs of the pulled images and tag the images with the same ID as an image loaded before on the nodes instead of loading them again")
+	flags.BoolVar(&TestConfig.KeepImages, "keep-images", false, "if set, keep the preloaded images on the host to speed up the next preload")
+	flags.StringVar(&TestConfig.KindClusterName, "kind-cluster-name", defaultKindClusterName(), "the name of the kind cluster the images are preloaded into, defaults to $KIND_CLUSTER_NAME or tidb-operator")
 	flags.StringVar(&TestConfig.BackupImage, "backup-image", "", "backup image")
 	flags.BoolVar(&TestConfig.OperatorKiller.Enabled, "operator-killer", false, "whether to enable operator kill")
 	flags.DurationVar(&TestConfig.OperatorKiller.Interval, "operator-killer-interval", 5*time.Minute, "interval between operator kills")
 	flags.Float64Var(&TestConfig.OperatorKiller.JitterFactor, "operator-killer-jitter-factor", 1, "factor used to jitter operator kills")
 }
 
+// defaultKindClusterName returns the name of the kind cluster in $KIND_CLUSTER_NAME, which is also respected by
+// the kind CLI, or the name of the cluster created by hack/e2e.sh by default
+func defaultKindClusterName() string {
+	if name := os.Getenv("KIND_CLUSTER_NAME"); name != "" {
+		return name
+	}
+	return "tidb-operator"
+}
+
 func AfterReadingAllFlags() error {
 	if TestConfig.OperatorRepoDir == "" {
 		operatorRepo, err := ioutil.TempDir("", "tidb-operator")
@@ -262,9 +262,15 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
 	framework.Logf("====== e2e configuration ======")
 	framework.Logf("%s", e2econfig.TestConfig.MustPrettyPrintJSON())
 	// preload images
-	if e2econfig.TestConfig.PreloadImages {
+	if e2econfig.TestConfig.PreloadImages && e2econfig.TestConfig.PreloadImagesDryRun {
+		ginkgo.By("Planning the preload of images")
+		if _, err := utilimage.PreloadImagesDryRun(e2econfig.TestConfig.KindClusterName, e2econfig.TestConfig.PreloadImagesRuntime, e2econfig.TestConfig.KeepImages); err != nil {
+			framework.Failf("failed to plan the pre-load of images: %v", err)
+		}
+	} else if e2econfig.TestConfig.PreloadImages {
 		ginkgo.By("Preloading images")
-		if err := utilimage.PreloadImages(); err != nil {
+		if err := utilimage.PreloadImages(e2econfig.TestConfig.KindClusterName, e2econfig.TestConfig.PreloadImagesRuntime, e2econfig.TestConfig.KeepImages,
+			e2econfig.TestConfig.PreloadImagesDedupDigests); err != nil {
 			framework.Failf("failed to pre-load images: %v", err)
 		}
 	}
@@ -60,6 +60,7 @@ import (
 	e2eframework "github.com/pingcap/tidb-operator/tests/e2e/framework"
 	remotewrite "github.com/pingcap/tidb-operator/tests/e2e/remotewrite"
 	utile2e "github.com/pingcap/tidb-operator/tests/e2e/util"
+	utilchaos "github.com/pingcap/tidb-operator/tests/e2e/util/chaos"
 	utilginkgo "github.com/pingcap/tidb-operator/tests/e2e/util/ginkgo"
 	utilimage "github.com/pingcap/tidb-operator/tests/e2e/util/image"
 	utilpod "github.com/pingcap/tidb-operator/tests/e2e/util/pod"
@@ -2157,6 +2158,41 @@ var _ = ginkgo.Describe("TiDBCluster", func() {
 			})
 		}
 
+		ginkgo.It("should converge when pods are killed during the upgrade", func() {
+			ginkgo.By("Deploy initial tc")
+			tc := fixture.GetTidbCluster(ns, "upgrade-chaos", utilimage.TiDBLatestPrev)
+			tc.Spec.TiDB.Replicas = 3
+			utiltc.MustCreateTCWithComponentsReady(genericCli, oa, tc, 15*time.Minute, 10*time.Second)
+
+			ginkgo.By("Update tc version")
+			err := controller.GuaranteedUpdate(genericCli, tc, func() error {
+				tc.Spec.Version = utilimage.TiDBLatest
+				return nil
+			})
+			framework.ExpectNoError(err, "failed to update tc version to %q", utilimage.TiDBLatest)
+
+			ginkgo.By("Kill an upgraded TiKV pod during the upgrade")
+			utilchaos.MustKillPodDuringUpgrade(c, cli, tc, utilchaos.UpgradeChaos{
+				Component:         v1alpha1.TiKVMemberType,
+				Target:            utilchaos.KillUpgradedPod,
+				AfterUpgradedPods: 1,
+				Timeout:           15 * time.Minute,
+				PollInterval:      5 * time.Second,
+			})
+
+			ginkgo.By("Kill a pending TiDB pod during the upgrade")
+			utilchaos.MustKillPodDuringUpgrade(c, cli, tc, utilchaos.UpgradeChaos{
+				Component:         v1alpha1.TiDBMemberType,
+				Target:            utilchaos.KillPendingPod,
+				AfterUpgradedPods: 1,
+				Timeout:           15 * time.Minute,
+				PollInterval:      5 * time.Second,
+			})
+
+			err = oa.WaitForTidbClusterReady(tc, 15*time.Minute, 10*time.Second)
+			framework.ExpectNoError(err, "failed to wait for TidbCluster %s/%s components ready", ns, tc.Name)
+		})
+
 		// upgrdae testing for specific versions
 		utilginkgo.ContextWhenFocus("Specific Version", func() {
 			configureV4x0x9 := func(tc *v1alpha1.TidbCluster) {
@@ -0,0 +1,207 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/pingcap/tidb-operator/pkg/apis/label"
+	"github.com/pingcap/tidb-operator/pkg/apis/pingcap/v1alpha1"
+	"github.com/pingcap/tidb-operator/pkg/client/clientset/versioned"
+	utiltc "github.com/pingcap/tidb-operator/tests/e2e/util/tidbcluster"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/framework/log"
+)
+
+// KillTarget decides which pod is killed during the upgrade
+type KillTarget string
+
+const (
+	// KillUpgradedPod kills a pod that has already been upgraded to the update revision
+	KillUpgradedPod KillTarget = "Upgraded"
+	// KillPendingPod kills a pod that is still waiting to be upgraded
+	KillPendingPod KillTarget = "Pending"
+)
+
+// UpgradeChaos describes a pod kill injected into an upgrade of a component
+type UpgradeChaos struct {
+	// Component is the component to inject the chaos into
+	Component v1alpha1.MemberType
+	// Target is which kind of pod to kill
+	Target KillTarget
+	// AfterUpgradedPods is the number of upgraded pods to wait for before killing the pod
+	AfterUpgradedPods int32
+	// Timeout and PollInterval are used by every wait in the chaos
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// MustKillPodDuringUpgrade kills a pod of the component during the upgrade and
+// waits for the upgrade to converge
+func MustKillPodDuringUpgrade(c kubernetes.Interface, cli versioned.Interface, tc *v1alpha1.TidbCluster, chaos UpgradeChaos) {
+	podName, err := KillPodDuringUpgrade(c, cli, tc, chaos)
+	framework.ExpectNoError(err, "failed to kill %s pod of %s during the upgrade of tc %s/%s", chaos.Target, chaos.Component, tc.Namespace, tc.Name)
+	err = WaitForUpgradeConverged(cli, tc, chaos.Component, chaos.Timeout, chaos.PollInterval)
+	framework.ExpectNoError(err, "failed to wait for %s of tc %s/%s to converge after pod %s is killed", chaos.Component, tc.Namespace, tc.Name, podName)
+}
+
+// KillPodDuringUpgrade waits for the component to be upgrading with at least chaos.AfterUpgradedPods
+// pods upgraded, then kills a random pod matching chaos.Target and returns its name.
+// The upgrade must be triggered by the caller before. It fails as soon as the component leaves
+// the upgrade phase before a pod is killed, e.g. the rollout finishes between two polls.
+func KillPodDuringUpgrade(c kubernetes.Interface, cli versioned.Interface, tc *v1alpha1.TidbCluster, chaos UpgradeChaos) (string, error) {
+	if lastPhase, err := utiltc.WaitForComponentPhase(cli, tc, chaos.Component, v1alpha1.UpgradePhase, chaos.Timeout, chaos.PollInterval); err != nil {
+		return "", fmt.Errorf("wait for %s to be upgrading failed, last phase is %s: %v", chaos.Component, lastPhase, err)
+	}
+
+	var victim *v1.Pod
+	err := wait.PollImmediate(chaos.PollInterval, chaos.Timeout, func() (bool, error) {
+		latest, err := cli.PingcapV1alpha1().TidbClusters(tc.Namespace).Get(context.TODO(), tc.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Logf("failed to get tc %s/%s: %v", tc.Namespace, tc.Name, err)
+			return false, nil
+		}
+		if phase := componentPhase(latest, chaos.Component); phase != v1alpha1.UpgradePhase {
+			return false, fmt.Errorf("%s left the upgrade phase before a %s pod is killed, phase is %s", chaos.Component, chaos.Target, phase)
+		}
+		upgraded, pending, err := listPodsByRevision(c, latest, chaos.Component)
+		if err != nil {
+			log.Logf("failed to list pods of %s: %v", chaos.Component, err)
+			return false, nil
+		}
+		if int32(len(upgraded)) < chaos.AfterUpgradedPods {
+			log.Logf("%d pods of %s are upgraded, waiting for %d", len(upgraded), chaos.Component, chaos.AfterUpgradedPods)
+			return false, nil
+		}
+
+		candidates := upgraded
+		if chaos.Target == KillPendingPod {
+			candidates = pending
+		}
+		if len(candidates) == 0 {
+			log.Logf("no %s pod of %s to kill", chaos.Target, chaos.Component)
+			return false, nil
+		}
+		victim = &candidates[rand.Intn(len(candidates))]
+		return true, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("wait for a %s pod of %s failed: %v", chaos.Target, chaos.Component, err)
+	}
+
+	log.Logf("kill %s pod %s/%s of %s during the upgrade", chaos.Target, victim.Namespace, victim.Name, chaos.Component)
+	err = c.CoreV1().Pods(victim.Namespace).Delete(context.TODO(), victim.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: new(int64),
+	})
+	if err != nil {
+		return "", fmt.Errorf("delete pod %s/%s failed: %v", victim.Namespace, victim.Name, err)
+	}
+	return victim.Name, nil
+}
+
+// WaitForUpgradeConverged waits for the component to leave the upgrade phase with all pods
+// running the update revision
+func WaitForUpgradeConverged(cli versioned.Interface, tc *v1alpha1.TidbCluster, comp v1alpha1.MemberType, timeout, pollInterval time.Duration) error {
+	lastPhase, err := utiltc.WaitForComponentPhase(cli, tc, comp, v1alpha1.NormalPhase, timeout, pollInterval)
+	if err != nil {
+		return fmt.Errorf("wait for %s to be normal failed, last phase is %s: %v", comp, lastPhase, err)
+	}
+
+	return utiltc.WaitForTCCondition(cli, tc.Namespace, tc.Name, timeout, pollInterval, func(tc *v1alpha1.TidbCluster) (bool, error) {
+		status := statefulSetStatus(tc, comp)
+		if status == nil {
+			return false, nil
+		}
+		if status.CurrentRevision != status.UpdateRevision || status.UpdatedReplicas != status.Replicas {
+			log.Logf("%s is not converged, current revision %s, update revision %s, updated replicas %d/%d",
+				comp, status.CurrentRevision, status.UpdateRevision, status.UpdatedReplicas, status.Replicas)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// listPodsByRevision splits the pods of the component into upgraded and pending ones
+// by the update revision recorded in tc status
+func listPodsByRevision(c kubernetes.Interface, tc *v1alpha1.TidbCluster, comp v1alpha1.MemberType) ([]v1.Pod, []v1.Pod, error) {
+	status := statefulSetStatus(tc, comp)
+	if status == nil {
+		return nil, nil, fmt.Errorf("statefulset status of %s is not found", comp)
+	}
+
+	selector := label.New().Instance(tc.Name).Component(comp.String())
+	pods, err := c.CoreV1().Pods(tc.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var upgraded, pending []v1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Labels[apps.ControllerRevisionHashLabelKey] == status.UpdateRevision {
+			upgraded = append(upgraded, pod)
+		} else {
+			pending = append(pending, pod)
+		}
+	}
+	return upgraded, pending, nil
+}
+
+func statefulSetStatus(tc *v1alpha1.TidbCluster, comp v1alpha1.MemberType) *apps.StatefulSetStatus {
+	switch comp {
+	case v1alpha1.PDMemberType:
+		return tc.Status.PD.StatefulSet
+	case v1alpha1.TiKVMemberType:
+		return tc.Status.TiKV.StatefulSet
+	case v1alpha1.TiDBMemberType:
+		return tc.Status.TiDB.StatefulSet
+	case v1alpha1.TiFlashMemberType:
+		return tc.Status.TiFlash.StatefulSet
+	case v1alpha1.TiCDCMemberType:
+		return tc.Status.TiCDC.StatefulSet
+	case v1alpha1.PumpMemberType:
+		return tc.Status.Pump.StatefulSet
+	}
+	return nil
+}
+
+func componentPhase(tc *v1alpha1.TidbCluster, comp v1alpha1.MemberType) v1alpha1.MemberPhase {
+	switch comp {
+	case v1alpha1.PDMemberType:
+		return tc.Status.PD.Phase
+	case v1alpha1.TiKVMemberType:
+		return tc.Status.TiKV.Phase
+	case v1alpha1.TiDBMemberType:
+		return tc.Status.TiDB.Phase
+	case v1alpha1.TiFlashMemberType:
+		return tc.Status.TiFlash.Phase
+	case v1alpha1.TiCDCMemberType:
+		return tc.Status.TiCDC.Phase
+	case v1alpha1.PumpMemberType:
+		return tc.Status.Pump.Phase
+	}
+	return ""
+}
@@ -14,6 +14,7 @@
 package image
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os/exec"
@@ -21,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/ghodss/yaml"
+	"github.com/pingcap/tidb-operator/pkg/util/cmpver"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kubernetes/test/e2e/framework"
 	"k8s.io/kubernetes/test/e2e/framework/log"
@@ -41,6 +43,8 @@ const (
 	TiDBV5x0x0 = "v5.0.0"
 	TiDBV5x0x2 = "v5.0.2"
 	TiDBV5x3   = "v5.3.0"
+	// TiFlashTiCDCFirstVersion is the first version of TiDB with the images of tiflash and ticdc
+	TiFlashTiCDCFirstVersion = "v4.0.0"
 
 	PrometheusImage               = "prom/prometheus"
 	PrometheusVersion             = "v2.27.1"
@@ -60,24 +64,47 @@ const (
 	HelperImage                   = "alpine:3.16.0"
 )
 
+// ListImages returns the images of the default versions of TiDB and the images in ListChartImages
 func ListImages() []string {
-	images := []string{}
 	versions := make([]string, 0)
 	versions = append(versions, TiDBPreviousVersions...)
 	versions = append(versions, TiDBLatestPrev)
 	versions = append(versions, TiDBLatest)
 	versions = append(versions, TiDBNightlyVersion)
+	images := ListImagesForVersions(versions)
+	images = append(images, ListChartImages()...)
+	return sets.NewString(images...).List()
+}
+
+// ListImagesForVersions returns the images of pd, tidb, tikv, tidb-binlog, tiflash and ticdc of exactly the
+// versions, e.g. only the versions of an upgrade path are preloaded by the tests of the upgrade path. The images
+// of tiflash and ticdc are published since v4.0.0, so they are skipped for the older versions.
+func ListImagesForVersions(versions []string) []string {
+	images := []string{}
 	for _, v := range versions {
 		images = append(images, fmt.Sprintf("pingcap/pd:%s", v))
 		images = append(images, fmt.Sprintf("pingcap/tidb:%s", v))
 		images = append(images, fmt.Sprintf("pingcap/tikv:%s", v))
 		images = append(images, fmt.Sprintf("pingcap/tidb-binlog:%s", v))
+		// the versions which are not semantic, e.g. the custom tags, are assumed to have the images
+		if older, err := cmpver.Compare(v, cmpver.Less, TiFlashTiCDCFirstVersion); err == nil && older {
+			continue
+		}
+		images = append(images, fmt.Sprintf("pingcap/tiflash:%s", v))
+		images = append(images, fmt.Sprintf("pingcap/ticdc:%s", v))
 	}
+	return sets.NewString(images...).List()
+}
+
+// ListChartImages returns the images of the monitor and the images in the values of the tidb-operator
+// and tidb-cluster charts, which are used whatever the versions of TiDB are
+func ListChartImages() []string {
+	images := []string{}
 	images = append(images, fmt.Sprintf("%s:%s", PrometheusImage, PrometheusVersion))
 	images = append(images, fmt.Sprintf("%s:%s", TiDBMonitorReloaderImage, TiDBMonitorReloaderVersion))
 	images = append(images, fmt.Sprintf("%s:%s", TiDBMonitorInitializerImage, TiDBMonitorInitializerVersion))
 	images = append(images, fmt.Sprintf("%s:%s", GrafanaImage, GrafanaVersion))
-	imagesFromOperator, err := readImagesFromValues(filepath.Join(framework.TestContext.RepoRoot, "charts/tidb-operator/values.yaml"), sets.NewString(".advancedStatefulset.image", ".admissionWebhook.jobImage"))
+	imagesFromOperator, err := readImagesFromValues(filepath.Join(framework.TestContext.RepoRoot, "charts/tidb-operator/values.yaml"), sets.NewString(".advancedStatefulset.image"))
 	framework.ExpectNoError(err, "failed to read images from values in charts/tidb-operator/values.yaml")
 
 	images = append(images, imagesFromOperator...)
@@ -92,16 +119,42 @@ func ListImages() []string {
 // values represents a collection of chart values.
 type values map[string]interface{}
 
+// walkValues calls fn with each of the values and the values nested in them, the key of a value is the keys of
+// the maps it is in joined by ".", and a value in a list is keyed by the list suffixed with "[]", e.g. the images
+// of the containers in the list of additionalContainers are keyed by ".additionalContainers[].image".
 func walkValues(vals values, parentKey string, fn func(k string, v interface{})) {
 	for k, v := range vals {
-		fn(parentKey+"."+k, v)
-		valsMap, ok := v.(map[string]interface{})
-		if ok {
-			walkValues(valsMap, parentKey+"."+k, fn)
+		walkValue(parentKey+"."+k, v, fn)
+	}
+}
+
+func walkValue(key string, v interface{}, fn func(k string, v interface{})) {
+	fn(key, v)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		walkValues(v, key, fn)
+	case []interface{}:
+		for _, elem := range v {
+			walkValue(key+"[]", elem, fn)
 		}
 	}
 }
 
+// MissingKeysError is returned by readImagesFromValues if some of the keys requested are not found in the values,
+// e.g. the keys are renamed or moved by a refactor of the chart, so that the images of them are not skipped silently
+type MissingKeysError struct {
+	// File is the values file read
+	File string
+	// Keys are the keys requested but not found, sorted
+	Keys []string
+}
+
+func (e *MissingKeysError) Error() string {
+	return fmt.Sprintf("keys %s are not found in the values in %s", strings.Join(e.Keys, ", "), e.File)
+}
+
+// readImagesFromValues returns the images of the keys in the values file, or all the images in it if keys is nil.
+// A *MissingKeysError is returned along with the images found if some of the keys are not in the values.
 func readImagesFromValues(f string, keys sets.String) ([]string, error) {
 	var vals values
 	data, err := ioutil.ReadFile(f)
@@ -116,14 +169,20 @@ func readImagesFromValues(f string, keys sets.String) ([]string, error) {
 		vals = values{}
 	}
 	images := []string{}
+	matched := sets.NewString()
 	walkValues(vals, "", func(k string, v interface{}) {
 		if keys != nil && !keys.Has(k) {
 			return
 		}
-		if image, ok := v.(string); ok {
+		matched.Insert(k)
+		// the templated values are rendered by helm to whatever the other values are, not the images to preload
+		if image, ok := v.(string); ok && !strings.Contains(image, "{{") {
 			images = append(images, image)
 		}
 	})
+	if missing := keys.Difference(matched); missing.Len() > 0 {
+		return images, &MissingKeysError{File: f, Keys: missing.List()}
+	}
 	return images, nil
 }
 
@@ -138,42 +197,365 @@ func nsenter(args ...string) ([]byte, error) {
 	return exec.Command("nsenter", nsenter_args...).CombinedOutput()
 }
 
+const (
+	// RuntimeDocker is the container runtime of the hosts running the kind nodes by docker
+	RuntimeDocker = "docker"
+	// RuntimeContainerd is the container runtime of the hosts running the kind nodes by containerd
+	// directly, the images are managed by nerdctl and the nodes are run by the nerdctl provider of kind
+	RuntimeContainerd = "containerd"
+)
+
+// containerRuntime builds the commands run on the host of the kind cluster for a container runtime
+type containerRuntime string
+
+// detectRuntime returns docker if the docker daemon is available on the host, or containerd
+// if nerdctl is, docker is preferred since it is what kind uses by default
+func detectRuntime() (containerRuntime, error) {
+	if _, err := nsenter("docker", "info"); err == nil {
+		return RuntimeDocker, nil
+	}
+	output, err := nsenter("nerdctl", "info")
+	if err != nil {
+		return "", fmt.Errorf("neither docker nor containerd is available on the host: %v, output: %s", err, output)
+	}
+	return RuntimeContainerd, nil
+}
+
+// cli returns the command line tool managing the images and the containers of the runtime
+func (r containerRuntime) cli() string {
+	if r == RuntimeContainerd {
+		return "nerdctl"
+	}
+	return "docker"
+}
+
+// kind returns the kind command run with the provider of the runtime
+func (r containerRuntime) kind(kindBin string, args ...string) []string {
+	cmd := []string{kindBin}
+	if r == RuntimeContainerd {
+		cmd = []string{"env", "KIND_EXPERIMENTAL_PROVIDER=nerdctl", kindBin}
+	}
+	return append(cmd, args...)
+}
+
+func (r containerRuntime) pull(image string) []string {
+	return []string{r.cli(), "pull", image}
+}
+
+func (r containerRuntime) remove(image string) []string {
+	return []string{r.cli(), "rmi", image}
+}
+
+// listNodeImages returns the command listing the images in the image store of a kind node
+func (r containerRuntime) listNodeImages(node string) []string {
+	return []string{r.cli(), "exec", node, "crictl", "images", "-o", "json"}
+}
+
+// inspectID returns the command printing the ID of an image pulled on the host, i.e. the digest of its config,
+// which is the same for the tags of the same image
+func (r containerRuntime) inspectID(image string) []string {
+	return []string{r.cli(), "inspect", "--format", "{{.Id}}", image}
+}
+
+// tagNodeImage returns the command tagging an image in the image store of a kind node
+func (r containerRuntime) tagNodeImage(node string, source string, target string) []string {
+	return []string{r.cli(), "exec", node, "ctr", "-n", "k8s.io", "images", "tag", normalizeImage(source), normalizeImage(target)}
+}
+
+// load returns the commands loading an image pulled on the host into the nodes of the kind cluster.
+// kind loads the images by docker save with docker-image, so the image is saved into an archive
+// and loaded with image-archive for containerd
+func (r containerRuntime) load(kindBin string, clusterName string, load imageLoad) [][]string {
+	nodes := strings.Join(load.nodes, ",")
+	if r != RuntimeContainerd {
+		return [][]string{r.kind(kindBin, "load", "docker-image", "--name", clusterName, "--nodes", nodes, load.image)}
+	}
+	archive := imageArchive(load.image)
+	return [][]string{
+		{r.cli(), "save", "-o", archive, load.image},
+		r.kind(kindBin, "load", "image-archive", "--name", clusterName, "--nodes", nodes, archive),
+		{"rm", "-f", archive},
+	}
+}
+
+// imageArchive returns the path on the host the image is saved into before it is loaded
+func imageArchive(image string) string {
+	return filepath.Join("/tmp", "preload-"+strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)+".tar")
+}
+
+// preloadKindBin is the kind binary built by hack/e2e.sh, relative to the root of the repo
+const preloadKindBin = "./output/bin/kind"
+
+// preloadPlan is the images to load into the nodes of a kind cluster which do not have them
+type preloadPlan struct {
+	runtime     containerRuntime
+	clusterName string
+	nodes       []string
+	images      []string
+	loads       []imageLoad
+}
+
+// newPreloadPlan resolves the container runtime, the worker nodes of the kind cluster and the images in ListImages
+// missing on them
+func newPreloadPlan(clusterName string, runtime string) (*preloadPlan, error) {
+	var r containerRuntime
+	switch runtime {
+	case RuntimeDocker, RuntimeContainerd:
+		r = containerRuntime(runtime)
+	case "":
+		detected, err := detectRuntime()
+		if err != nil {
+			return nil, err
+		}
+		r = detected
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %q, supported: %s, %s", runtime, RuntimeDocker, RuntimeContainerd)
+	}
+	log.Logf("preloadImages, container runtime of the host: %s", r)
+
+	images := ListImages()
+	output, err := nsenter(r.kind(preloadKindBin, "get", "clusters")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kind clusters: %v, output: %s", err, output)
+	}
+	clusters := parseKindOutput(output)
+	if !sets.NewString(clusters...).Has(clusterName) {
+		return nil, fmt.Errorf("kind cluster %q does not exist, existing clusters: %v", clusterName, clusters)
+	}
+	output, err = nsenter(r.kind(preloadKindBin, "get", "nodes", "--name", clusterName)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes of kind cluster %q: %v, output: %s", clusterName, err, output)
+	}
+	nodes := workerNodes(parseKindOutput(output))
+
+	nodeImages := map[string]sets.String{}
+	for _, node := range nodes {
+		present, err := listNodeImages(r, node)
+		if err != nil {
+			// treat the node as empty and load all images into it
+			log.Logf("WARNING: preloadImages, error listing images on node %s: %v", node, err)
+			present = sets.NewString()
+		}
+		nodeImages[node] = present
+	}
+	loads := imageLoadPlan(images, nodes, nodeImages)
+	log.Logf("preloadImages, %d of %d images are present on all nodes %v and skipped", len(images)-len(loads), len(images), nodes)
+	return &preloadPlan{runtime: r, clusterName: clusterName, nodes: nodes, images: images, loads: loads}, nil
+}
+
+// workerNodes returns the nodes of a kind cluster except the control plane nodes
+func workerNodes(nodes []string) []string {
+	workers := []string{}
+	for _, node := range nodes {
+		if strings.HasSuffix(node, "-control-plane") {
+			continue
+		}
+		workers = append(workers, node)
+	}
+	return workers
+}
+
+// pullCommand returns the command pulling the image of the load on the host
+func (p *preloadPlan) pullCommand(load imageLoad) []string {
+	return p.runtime.pull(load.image)
+}
+
+// loadCommands returns the commands loading the image of the load into its nodes
+func (p *preloadPlan) loadCommands(load imageLoad) [][]string {
+	return p.runtime.load(preloadKindBin, p.clusterName, load)
+}
+
+// removeCommand returns the command removing the image of the load from the host after it is loaded
+func (p *preloadPlan) removeCommand(load imageLoad) []string {
+	return p.runtime.remove(load.image)
+}
+
+// commands returns all the commands PreloadImages runs for the plan in order
+func (p *preloadPlan) commands(keepImages bool) [][]string {
+	cmds := [][]string{}
+	for _, load := range p.loads {
+		cmds = append(cmds, p.pullCommand(load))
+		cmds = append(cmds, p.loadCommands(load)...)
+	}
+	if keepImages {
+		return cmds
+	}
+	for _, load := range p.loads {
+		cmds = append(cmds, p.removeCommand(load))
+	}
+	return cmds
+}
+
+// digestLoads is the images loaded in a run of PreloadImages keyed by their IDs, so that an image with the same ID as
+// an image already loaded, e.g. a release listed by different tags, is tagged on the nodes instead of loaded again
+type digestLoads map[string]imageLoad
+
+// tagCommands returns the commands tagging the image of the load on its nodes as the loaded image with the same ID, it
+// returns false if no image with the ID is loaded into all the nodes of the load
+func (d digestLoads) tagCommands(r containerRuntime, id string, load imageLoad) ([][]string, bool) {
+	loaded, ok := d[id]
+	if !ok || !sets.NewString(loaded.nodes...).HasAll(load.nodes...) {
+		return nil, false
+	}
+	cmds := [][]string{}
+	for _, node := range load.nodes {
+		cmds = append(cmds, r.tagNodeImage(node, loaded.image, load.image))
+	}
+	return cmds, true
+}
+
+// loadOrTag returns the commands loading the image of the load into its nodes, or tagging it on the nodes if
+// dedupDigests is true and the image with the same ID is already loaded into them in this run. The image is
+// loaded if its ID can not be inspected.
+func (p *preloadPlan) loadOrTag(load imageLoad, dedupDigests bool, loaded digestLoads) [][]string {
+	if !dedupDigests {
+		return p.loadCommands(load)
+	}
+	output, err := nsenter(p.runtime.inspectID(load.image)...)
+	if err != nil {
+		log.Logf("WARNING: preloadImages, error inspecting image %s: %v, output: %s", load.image, err, output)
+		return p.loadCommands(load)
+	}
+	id := strings.TrimSpace(string(output))
+	if cmds, ok := loaded.tagCommands(p.runtime, id, load); ok {
+		log.Logf("preloadImages, image %s is loaded as %s with the same ID %s, tag it on nodes %v", load.image, loaded[id].image, id, load.nodes)
+		return cmds
+	}
+	loaded[id] = load
+	return p.loadCommands(load)
+}
+
 // PreloadImages pre-loads images into the e2e cluster.
 // This is used to speed up the e2e process.
-// NOTE: it supports kind only right now
-func PreloadImages() error {
-	images := ListImages()
-	// TODO: make it configurable
-	cluster := "tidb-operator"
-	kindBin := "./output/bin/kind"
-	output, err := nsenter(kindBin, "get", "nodes", "--name", cluster)
+// Each image is only loaded into the nodes which do not have it, and the pulled
+// images are removed from the host after loaded unless keepImages is true.
+// runtime is the container runtime of the host, docker or containerd, it is
+// detected if empty.
+// If dedupDigests is true, the ID of each image is inspected after it is pulled,
+// and the images with the same ID as an image loaded before are tagged on the
+// nodes instead of loaded again.
+// NOTE: it supports kind only right now, clusterName is the name of the kind cluster
+func PreloadImages(clusterName string, runtime string, keepImages bool, dedupDigests bool) error {
+	plan, err := newPreloadPlan(clusterName, runtime)
 	if err != nil {
 		return err
 	}
-	nodes := []string{}
-	for _, l := range strings.Split(string(output), "\n") {
-		l = strings.TrimSpace(l)
-		if l == "" {
+	loaded := digestLoads{}
+	for _, load := range plan.loads {
+		if _, err := nsenter(plan.pullCommand(load)...); err != nil {
+			log.Logf("ERROR: preloadImages, error pulling image %s", load.image)
 			continue
 		}
-		if strings.HasSuffix(l, "-control-plane") {
-			continue
+		log.Logf("preloadImages, load image %s into nodes %v", load.image, load.nodes)
+		for _, cmd := range plan.loadOrTag(load, dedupDigests, loaded) {
+			if output, err := nsenter(cmd...); err != nil {
+				return fmt.Errorf("failed to load image %s: %v, output: %s", load.image, err, output)
+			}
 		}
-		nodes = append(nodes, l)
 	}
-	for _, image := range images {
-		if _, err := nsenter("docker", "pull", image); err != nil {
-			log.Logf("ERROR: preloadImages, error pulling image %s", image)
+	if keepImages {
+		return nil
+	}
+	for _, load := range plan.loads {
+		if _, err := nsenter(plan.removeCommand(load)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PreloadImagesDryRun resolves the nodes and the images as PreloadImages does, and logs and returns the commands
+// PreloadImages would run on the host without running them, e.g. to debug which images are loaded into which nodes.
+// Only the read-only commands listing the kind clusters, the nodes and the images on the nodes are run. The images
+// deduplicated by their IDs are only known after they are pulled, so they are planned to be loaded.
+func PreloadImagesDryRun(clusterName string, runtime string, keepImages bool) ([]string, error) {
+	plan, err := newPreloadPlan(clusterName, runtime)
+	if err != nil {
+		return nil, err
+	}
+	cmds := []string{}
+	for _, cmd := range plan.commands(keepImages) {
+		c := strings.Join(cmd, " ")
+		log.Logf("preloadImages, dry run: %s", c)
+		cmds = append(cmds, c)
+	}
+	return cmds, nil
+}
+
+// parseKindOutput returns the names of the clusters or the nodes listed by kind, the messages
+// printed in the combined output, e.g. "No kind clusters found.", are dropped
+func parseKindOutput(output []byte) []string {
+	names := []string{}
+	for _, l := range strings.Split(string(output), "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.ContainsAny(l, " \t") {
 			continue
 		}
-		if _, err := nsenter(kindBin, "load", "docker-image", "--name", cluster, "--nodes", strings.Join(nodes, ","), image); err != nil {
-			return err
+		names = append(names, l)
+	}
+	return names
+}
+
+// listNodeImages lists the images in the image store of a kind node
+func listNodeImages(r containerRuntime, node string) (sets.String, error) {
+	output, err := nsenter(r.listNodeImages(node)...)
+	if err != nil {
+		return nil, fmt.Errorf("%v, output: %s", err, output)
+	}
+	var list struct {
+		Images []struct {
+			RepoTags []string `json:"repoTags"`
+		} `json:"images"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, err
+	}
+	images := sets.NewString()
+	for _, image := range list.Images {
+		for _, tag := range image.RepoTags {
+			images.Insert(normalizeImage(tag))
 		}
 	}
+	return images, nil
+}
+
+// imageLoad is an image to load and the nodes it is missing on
+type imageLoad struct {
+	image string
+	nodes []string
+}
+
+// imageLoadPlan returns the images to load and, for each image, the nodes which do not have it,
+// so that a node added to the cluster only gets the images it is missing
+func imageLoadPlan(images []string, nodes []string, nodeImages map[string]sets.String) []imageLoad {
+	plan := []imageLoad{}
 	for _, image := range images {
-		if _, err := nsenter("docker", "rmi", image); err != nil {
-			return err
+		name := normalizeImage(image)
+		load := imageLoad{image: image}
+		for _, node := range nodes {
+			if !nodeImages[node].Has(name) {
+				load.nodes = append(load.nodes, node)
+			}
+		}
+		if len(load.nodes) > 0 {
+			plan = append(plan, load)
 		}
 	}
-	return nil
+	return plan
+}
+
+// normalizeImage returns the fully qualified reference of an image, e.g.
+// "alpine:3.16.0" is normalized to "docker.io/library/alpine:3.16.0",
+// which is the form the container runtime of the nodes reports.
+func normalizeImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		image = "docker.io/library/" + image
+	} else if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		image = "docker.io/" + image
+	}
+	if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") && !strings.Contains(image, "@") {
+		image += ":latest"
+	}
+	return image
 }
@@ -25,10 +25,11 @@ import (
 
 func TestReadImagesFromValues(t *testing.T) {
 	tests := []struct {
-		name       string
-		values     string
-		keys       sets.String
-		wantImages []string
+		name        string
+		values      string
+		keys        sets.String
+		wantImages  []string
+		wantMissing []string
 	}{
 		{
 			name: "basic",
@@ -61,6 +62,55 @@ foo:
 				"busybox:latest",
 			},
 		},
+		{
+			name: "list",
+			values: `
+images:
+- pingcap/tidb:v3.0.4
+- busybox:latest
+foo:
+  additionalContainers:
+  - name: bar
+    image: pingcap/tidb-monitor-reloader:v1.0.1
+  - name: baz
+    image: alpine:3.16.0
+`,
+			keys: sets.NewString(".images[]", ".foo.additionalContainers[].image"),
+			wantImages: []string{
+				"pingcap/tidb:v3.0.4",
+				"busybox:latest",
+				"pingcap/tidb-monitor-reloader:v1.0.1",
+				"alpine:3.16.0",
+			},
+		},
+		{
+			name: "templated",
+			values: `
+image: pingcap/tidb:v3.0.4
+foo:
+  image: "{{ .Values.image }}"
+  initContainers:
+  - image: "busybox:{{ .Values.busyboxVersion }}"
+`,
+			keys: nil,
+			wantImages: []string{
+				"pingcap/tidb:v3.0.4",
+			},
+		},
+		{
+			name: "missing",
+			values: `
+image: pingcap/tidb:v3.0.4
+foo:
+  image: "{{ .Values.image }}"
+  additionalContainers: []
+`,
+			keys: sets.NewString(".image", ".foo.image", ".foo.iamge", ".bar.image", ".foo.additionalContainers[].image"),
+			wantImages: []string{
+				"pingcap/tidb:v3.0.4",
+			},
+			wantMissing: []string{".bar.image", ".foo.additionalContainers[].image", ".foo.iamge"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -75,8 +125,14 @@ foo:
 				t.Fatal(err)
 			}
 			got, err := readImagesFromValues(tmpfile.Name(), tt.keys)
-			if err != nil {
-				t.Error(err)
+			if tt.wantMissing == nil {
+				if err != nil {
+					t.Error(err)
+				}
+			} else if missingErr, ok := err.(*MissingKeysError); !ok {
+				t.Errorf("expected a *MissingKeysError, got %v", err)
+			} else if diff := cmp.Diff(tt.wantMissing, missingErr.Keys); diff != "" {
+				t.Errorf("unexpected missing keys (-want, +got): %s", diff)
 			}
 			sort.Strings(got)
 			sort.Strings(tt.wantImages)
@@ -86,3 +142,284 @@ foo:
 		})
 	}
 }
+
+func TestListImagesForVersions(t *testing.T) {
+	want := []string{
+		"pingcap/pd:v5.3.0",
+		"pingcap/pd:v5.4.0",
+		"pingcap/ticdc:v5.3.0",
+		"pingcap/ticdc:v5.4.0",
+		"pingcap/tidb-binlog:v5.3.0",
+		"pingcap/tidb-binlog:v5.4.0",
+		"pingcap/tidb:v5.3.0",
+		"pingcap/tidb:v5.4.0",
+		"pingcap/tiflash:v5.3.0",
+		"pingcap/tiflash:v5.4.0",
+		"pingcap/tikv:v5.3.0",
+		"pingcap/tikv:v5.4.0",
+	}
+	got := ListImagesForVersions([]string{TiDBLatest, TiDBLatestPrev, TiDBLatest})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+	// the images of tiflash and ticdc are skipped for the versions before them
+	want = []string{
+		"pingcap/pd:nightly",
+		"pingcap/pd:v3.0.20",
+		"pingcap/ticdc:nightly",
+		"pingcap/tidb-binlog:nightly",
+		"pingcap/tidb-binlog:v3.0.20",
+		"pingcap/tidb:nightly",
+		"pingcap/tidb:v3.0.20",
+		"pingcap/tiflash:nightly",
+		"pingcap/tikv:nightly",
+		"pingcap/tikv:v3.0.20",
+	}
+	got = ListImagesForVersions([]string{TiDBV3, TiDBNightlyVersion})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+	if got := ListImagesForVersions(nil); len(got) != 0 {
+		t.Errorf("unexpected images for no versions: %v", got)
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	tests := map[string]string{
+		"alpine:3.16.0":                    "docker.io/library/alpine:3.16.0",
+		"alpine":                           "docker.io/library/alpine:latest",
+		"pingcap/tidb:v5.4.0":              "docker.io/pingcap/tidb:v5.4.0",
+		"docker.io/pingcap/tidb:v5.4.0":    "docker.io/pingcap/tidb:v5.4.0",
+		"quay.io/prometheus/prometheus":    "quay.io/prometheus/prometheus:latest",
+		"localhost:5000/tidb-operator:e2e": "localhost:5000/tidb-operator:e2e",
+	}
+	for image, want := range tests {
+		if got := normalizeImage(image); got != want {
+			t.Errorf("normalizeImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestImageLoadPlan(t *testing.T) {
+	images := []string{"pingcap/pd:v5.4.0", "pingcap/tikv:v5.4.0", "alpine:3.16.0"}
+	nodes := []string{"worker", "worker2", "worker3"}
+	all := sets.NewString("docker.io/pingcap/pd:v5.4.0", "docker.io/pingcap/tikv:v5.4.0", "docker.io/library/alpine:3.16.0")
+	tests := []struct {
+		name       string
+		nodeImages map[string]sets.String
+		wantPlan   []imageLoad
+	}{
+		{
+			name: "present on all nodes",
+			nodeImages: map[string]sets.String{
+				"worker":  all,
+				"worker2": all,
+				"worker3": all,
+			},
+			wantPlan: []imageLoad{},
+		},
+		{
+			name: "node added",
+			nodeImages: map[string]sets.String{
+				"worker":  all,
+				"worker2": all,
+				"worker3": sets.NewString(),
+			},
+			wantPlan: []imageLoad{
+				{image: "pingcap/pd:v5.4.0", nodes: []string{"worker3"}},
+				{image: "pingcap/tikv:v5.4.0", nodes: []string{"worker3"}},
+				{image: "alpine:3.16.0", nodes: []string{"worker3"}},
+			},
+		},
+		{
+			name: "missing on some nodes",
+			nodeImages: map[string]sets.String{
+				"worker":  all,
+				"worker2": sets.NewString("docker.io/pingcap/pd:v5.4.0"),
+				"worker3": sets.NewString("docker.io/pingcap/pd:v5.4.0", "docker.io/library/alpine:3.16.0"),
+			},
+			wantPlan: []imageLoad{
+				{image: "pingcap/tikv:v5.4.0", nodes: []string{"worker2", "worker3"}},
+				{image: "alpine:3.16.0", nodes: []string{"worker2"}},
+			},
+		},
+		{
+			name:       "empty nodes",
+			nodeImages: map[string]sets.String{},
+			wantPlan: []imageLoad{
+				{image: "pingcap/pd:v5.4.0", nodes: nodes},
+				{image: "pingcap/tikv:v5.4.0", nodes: nodes},
+				{image: "alpine:3.16.0", nodes: nodes},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := imageLoadPlan(images, nodes, tt.nodeImages)
+			if diff := cmp.Diff(tt.wantPlan, got, cmp.AllowUnexported(imageLoad{})); diff != "" {
+				t.Errorf("unexpected (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestParseKindOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "clusters",
+			output: "kind\ntidb-op-pr-1234\n",
+			want:   []string{"kind", "tidb-op-pr-1234"},
+		},
+		{
+			name:   "no clusters",
+			output: "No kind clusters found.\n",
+			want:   []string{},
+		},
+		{
+			name:   "nodes with messages",
+			output: "enabling experimental podman provider\ntidb-operator-control-plane\ntidb-operator-worker\n\n",
+			want:   []string{"tidb-operator-control-plane", "tidb-operator-worker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseKindOutput([]byte(tt.output))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestContainerRuntimeLoad(t *testing.T) {
+	load := imageLoad{image: "pingcap/pd:v5.4.0", nodes: []string{"worker", "worker2"}}
+	tests := []struct {
+		name    string
+		runtime containerRuntime
+		want    [][]string
+	}{
+		{
+			name:    "docker",
+			runtime: RuntimeDocker,
+			want: [][]string{
+				{"./output/bin/kind", "load", "docker-image", "--name", "tidb-operator", "--nodes", "worker,worker2", "pingcap/pd:v5.4.0"},
+			},
+		},
+		{
+			name:    "containerd",
+			runtime: RuntimeContainerd,
+			want: [][]string{
+				{"nerdctl", "save", "-o", "/tmp/preload-pingcap_pd_v5.4.0.tar", "pingcap/pd:v5.4.0"},
+				{"env", "KIND_EXPERIMENTAL_PROVIDER=nerdctl", "./output/bin/kind", "load", "image-archive", "--name", "tidb-operator", "--nodes", "worker,worker2", "/tmp/preload-pingcap_pd_v5.4.0.tar"},
+				{"rm", "-f", "/tmp/preload-pingcap_pd_v5.4.0.tar"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.runtime.load("./output/bin/kind", "tidb-operator", load)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected (-want, +got): %s", diff)
+			}
+			if diff := cmp.Diff([]string{tt.runtime.cli(), "exec", "worker", "crictl", "images", "-o", "json"}, tt.runtime.listNodeImages("worker")); diff != "" {
+				t.Errorf("unexpected (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestPreloadPlanCommands(t *testing.T) {
+	plan := &preloadPlan{
+		runtime:     RuntimeDocker,
+		clusterName: "tidb-operator",
+		nodes:       []string{"worker", "worker2"},
+		loads: []imageLoad{
+			{image: "pingcap/pd:v5.4.0", nodes: []string{"worker", "worker2"}},
+			{image: "alpine:3.16.0", nodes: []string{"worker2"}},
+		},
+	}
+	loads := [][]string{
+		{"docker", "pull", "pingcap/pd:v5.4.0"},
+		{"./output/bin/kind", "load", "docker-image", "--name", "tidb-operator", "--nodes", "worker,worker2", "pingcap/pd:v5.4.0"},
+		{"docker", "pull", "alpine:3.16.0"},
+		{"./output/bin/kind", "load", "docker-image", "--name", "tidb-operator", "--nodes", "worker2", "alpine:3.16.0"},
+	}
+	if diff := cmp.Diff(loads, plan.commands(true)); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+	// the images are removed from the host after all of them are loaded
+	want := append(loads, []string{"docker", "rmi", "pingcap/pd:v5.4.0"}, []string{"docker", "rmi", "alpine:3.16.0"})
+	if diff := cmp.Diff(want, plan.commands(false)); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+
+	// the images are not loaded into the control plane
+	nodes := workerNodes([]string{"tidb-operator-control-plane", "tidb-operator-worker", "tidb-operator-worker2"})
+	if diff := cmp.Diff([]string{"tidb-operator-worker", "tidb-operator-worker2"}, nodes); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+
+	// nothing is run if all the images are present on all the nodes
+	plan.loads = nil
+	if diff := cmp.Diff([][]string{}, plan.commands(false)); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+}
+
+func TestDigestLoadsTagCommands(t *testing.T) {
+	loaded := digestLoads{
+		"sha256:54e0": {image: "pingcap/tidb:v5.4.0", nodes: []string{"worker", "worker2"}},
+	}
+	tests := []struct {
+		name string
+		id   string
+		load imageLoad
+		want [][]string
+		ok   bool
+	}{
+		{
+			name: "the image with the same ID is loaded into the nodes",
+			id:   "sha256:54e0",
+			load: imageLoad{image: "pingcap/tidb:latest", nodes: []string{"worker2"}},
+			want: [][]string{
+				{"docker", "exec", "worker2", "ctr", "-n", "k8s.io", "images", "tag", "docker.io/pingcap/tidb:v5.4.0", "docker.io/pingcap/tidb:latest"},
+			},
+			ok: true,
+		},
+		{
+			name: "the image with the same ID is not loaded into all the nodes",
+			id:   "sha256:54e0",
+			load: imageLoad{image: "pingcap/tidb:latest", nodes: []string{"worker2", "worker3"}},
+			ok:   false,
+		},
+		{
+			name: "no image with the same ID is loaded",
+			id:   "sha256:5300",
+			load: imageLoad{image: "pingcap/tidb:v5.3.0", nodes: []string{"worker"}},
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := loaded.tagCommands(RuntimeDocker, tt.id, tt.load)
+			if ok != tt.ok {
+				t.Errorf("expect ok %v, got %v", tt.ok, ok)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected (-want, +got): %s", diff)
+			}
+		})
+	}
+
+	if diff := cmp.Diff([]string{"nerdctl", "inspect", "--format", "{{.Id}}", "alpine:3.16.0"}, containerRuntime(RuntimeContainerd).inspectID("alpine:3.16.0")); diff != "" {
+		t.Errorf("unexpected (-want, +got): %s", diff)
+	}
+}
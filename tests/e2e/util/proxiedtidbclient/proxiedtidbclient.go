@@ -46,6 +46,14 @@ func (p *proxiedTiDBClient) GetInfo(tc *v1alpha1.TidbCluster, ordinal int32) (*c
 	panic("implement when necessary")
 }
 
+func (p *proxiedTiDBClient) GetConnectivity(tc *v1alpha1.TidbCluster, ordinal int32) (*controller.TiDBConnectivity, error) {
+	panic("implement when necessary")
+}
+
+func (p *proxiedTiDBClient) GetConnectionCount(tc *v1alpha1.TidbCluster, ordinal int32) (int32, error) {
+	panic("implement when necessary")
+}
+
 func (p *proxiedTiDBClient) GetSettings(tc *v1alpha1.TidbCluster, ordinal int32) (*config.Config, error) {
 	tcName := tc.GetName()
 	ns := tc.GetNamespace()